@@ -31,6 +31,7 @@ import (
 	"github.com/jaegertracing/jaeger/cmd/agent/app/reporter"
 	"github.com/jaegertracing/jaeger/cmd/agent/app/servers"
 	"github.com/jaegertracing/jaeger/cmd/agent/app/servers/thriftudp"
+	"github.com/jaegertracing/jaeger/pkg/configreload"
 	"github.com/jaegertracing/jaeger/ports"
 	zipkinThrift "github.com/jaegertracing/jaeger/thrift-gen/agent"
 )
@@ -73,8 +74,12 @@ type CollectorProxy interface {
 type Builder struct {
 	Processors []ProcessorConfiguration `yaml:"processors"`
 	HTTPServer HTTPServerConfiguration  `yaml:"httpServer"`
+	Zipkin     ZipkinConfiguration      `yaml:"zipkin"`
+	// ConfigReload configures how often the configuration file is checked for changes besides SIGHUP
+	ConfigReload configreload.Options `yaml:"configReload"`
 
 	reporters []reporter.Reporter
+	traffic   *reporter.TrafficReporter
 }
 
 // ProcessorConfiguration holds config for a processor that receives spans from Server
@@ -90,11 +95,19 @@ type ServerConfiguration struct {
 	QueueSize     int    `yaml:"queueSize"`
 	MaxPacketSize int    `yaml:"maxPacketSize"`
 	HostPort      string `yaml:"hostPort" validate:"nonzero"`
+	// SocketBufferSize is the receive buffer requested for the UDP socket; the OS default is kept if zero
+	SocketBufferSize int `yaml:"socketBufferSize"`
+	// UnixSocketPath is the path of a unix stream socket also receiving the spans, disabled if empty
+	UnixSocketPath string `yaml:"unixSocketPath"`
 }
 
 // HTTPServerConfiguration holds config for a server providing sampling strategies and baggage restrictions to clients
 type HTTPServerConfiguration struct {
 	HostPort string `yaml:"hostPort" validate:"nonzero"`
+	// AcceptSpans makes the server accept spans as Jaeger Thrift-JSON and OTLP/HTTP JSON
+	AcceptSpans bool `yaml:"acceptSpans"`
+	// SamplingCacheFile keeps the last sampling strategies to serve them when the collectors are unreachable
+	SamplingCacheFile string `yaml:"samplingCacheFile"`
 }
 
 // WithReporter adds auxiliary reporters.
@@ -105,15 +118,21 @@ func (b *Builder) WithReporter(r ...reporter.Reporter) *Builder {
 
 // CreateAgent creates the Agent
 func (b *Builder) CreateAgent(primaryProxy CollectorProxy, logger *zap.Logger, mFactory metrics.Factory) (*Agent, error) {
-	r := b.getReporter(primaryProxy)
-	processors, err := b.getProcessors(r, mFactory, logger)
+	b.traffic = reporter.WrapWithTraffic(b.getReporter(primaryProxy), mFactory)
+	processors, err := b.getProcessors(b.traffic, mFactory, logger)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create processors: %w", err)
 	}
-	server := b.HTTPServer.getHTTPServer(primaryProxy.GetManager(), mFactory)
+	server := b.HTTPServer.getHTTPServer(primaryProxy.GetManager(), b.traffic, mFactory, logger)
 	return NewAgent(processors, server, logger), nil
 }
 
+// TrafficHandler returns the handler summarizing the traffic received from each service
+// by the agent created by CreateAgent.
+func (b *Builder) TrafficHandler() http.Handler {
+	return b.traffic
+}
+
 func (b *Builder) getReporter(primaryProxy CollectorProxy) reporter.Reporter {
 	if len(b.reporters) == 0 {
 		return primaryProxy.GetReporter()
@@ -149,16 +168,35 @@ func (b *Builder) getProcessors(rep reporter.Reporter, mFactory metrics.Factory,
 			return nil, fmt.Errorf("cannot create Thrift Processor: %w", err)
 		}
 		retMe[idx] = processor
+		if cfg.Server.UnixSocketPath != "" {
+			processor, err := cfg.getUnixSocketProcessor(metrics, protoFactory, handler, logger)
+			if err != nil {
+				return nil, fmt.Errorf("cannot create Thrift Processor: %w", err)
+			}
+			retMe = append(retMe, processor)
+		}
 	}
-	return retMe, nil
+	zipkinProcessors, err := b.Zipkin.getProcessors(rep, mFactory, logger)
+	if err != nil {
+		return nil, err
+	}
+	return append(retMe, zipkinProcessors...), nil
 }
 
-// GetHTTPServer creates an HTTP server that provides sampling strategies and baggage restrictions to client libraries.
-func (c HTTPServerConfiguration) getHTTPServer(manager configmanager.ClientConfigManager, mFactory metrics.Factory) *http.Server {
+// GetHTTPServer creates an HTTP server that provides sampling strategies and baggage restrictions to client libraries,
+// and optionally accepts spans passed to the reporter.
+func (c HTTPServerConfiguration) getHTTPServer(manager configmanager.ClientConfigManager, rep reporter.Reporter, mFactory metrics.Factory, logger *zap.Logger) *http.Server {
 	if c.HostPort == "" {
 		c.HostPort = defaultHTTPServerHostPort
 	}
-	return httpserver.NewHTTPServer(c.HostPort, manager, mFactory)
+	var spanHandler *httpserver.SpanHandler
+	if c.AcceptSpans {
+		spanHandler = httpserver.NewSpanHandler(rep, logger, mFactory)
+	}
+	if c.SamplingCacheFile != "" {
+		manager = configmanager.WrapWithCache(manager, c.SamplingCacheFile, logger, mFactory)
+	}
+	return httpserver.NewHTTPServer(c.HostPort, manager, mFactory, spanHandler)
 }
 
 // GetThriftProcessor gets a TBufferedServer backed Processor using the collector configuration
@@ -170,14 +208,34 @@ func (c *ProcessorConfiguration) GetThriftProcessor(
 ) (processors.Processor, error) {
 	c.applyDefaults()
 
-	server, err := c.Server.getUDPServer(mFactory)
+	server, err := c.Server.getUDPServer(mFactory, logger)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create UDP Server: %w", err)
 	}
+	server.SetDroppedPacketClassifier(processors.ServiceName(factory))
 
 	return processors.NewThriftProcessor(server, c.Workers, mFactory, factory, handler, logger)
 }
 
+// getUnixSocketProcessor gets a Processor of the messages received on the unix socket of the server configuration
+func (c *ProcessorConfiguration) getUnixSocketProcessor(
+	mFactory metrics.Factory,
+	factory thrift.TProtocolFactory,
+	handler processors.AgentProcessor,
+	logger *zap.Logger,
+) (processors.Processor, error) {
+	c.applyDefaults()
+	c.Server.applyDefaults()
+
+	server, err := servers.NewUnixServer(c.Server.UnixSocketPath, c.Server.QueueSize, c.Server.MaxPacketSize, mFactory)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create unix socket Server: %w", err)
+	}
+	logger.Info("Receiving spans on unix socket", zap.String("path", c.Server.UnixSocketPath),
+		zap.String("model", string(c.Model)), zap.String("protocol", string(c.Protocol)))
+	return processors.NewThriftProcessor(server, c.Workers, mFactory, factory, handler, logger)
+}
+
 func (c *ProcessorConfiguration) applyDefaults() {
 	c.Workers = defaultInt(c.Workers, defaultServerWorkers)
 }
@@ -188,7 +246,7 @@ func (c *ServerConfiguration) applyDefaults() {
 }
 
 // getUDPServer gets a TBufferedServer backed server using the server configuration
-func (c *ServerConfiguration) getUDPServer(mFactory metrics.Factory) (servers.Server, error) {
+func (c *ServerConfiguration) getUDPServer(mFactory metrics.Factory, logger *zap.Logger) (*servers.TBufferedServer, error) {
 	c.applyDefaults()
 
 	if c.HostPort == "" {
@@ -198,6 +256,16 @@ func (c *ServerConfiguration) getUDPServer(mFactory metrics.Factory) (servers.Se
 	if err != nil {
 		return nil, fmt.Errorf("cannot create UDPServerTransport: %w", err)
 	}
+	if c.SocketBufferSize > 0 {
+		size, err := transport.SetSocketBufferSize(c.SocketBufferSize)
+		if err != nil {
+			return nil, fmt.Errorf("cannot set the UDP socket buffer size: %w", err)
+		}
+		if size < c.SocketBufferSize {
+			logger.Warn("The UDP socket buffer is smaller than requested, the kernel may need a higher limit, e.g. net.core.rmem_max on Linux",
+				zap.String("host-port", c.HostPort), zap.Int("requested", c.SocketBufferSize), zap.Int("actual", size))
+		}
+	}
 
 	return servers.NewTBufferedServer(transport, c.QueueSize, c.MaxPacketSize, mFactory)
 }
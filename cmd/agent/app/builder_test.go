@@ -20,6 +20,9 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -113,6 +116,7 @@ func TestBuilderWithExtraReporter(t *testing.T) {
 	agent, err := cfg.CreateAgent(fakeCollectorProxy{}, zap.NewNop(), metrics.NullFactory)
 	assert.NoError(t, err)
 	assert.NotNil(t, agent)
+	assert.NotNil(t, cfg.TrafficHandler())
 }
 
 func TestBuilderWithProcessorErrors(t *testing.T) {
@@ -125,7 +129,7 @@ func TestBuilderWithProcessorErrors(t *testing.T) {
 	}{
 		{protocol: Protocol("bad"), err: "cannot find protocol factory for protocol bad"},
 		{protocol: compactProtocol, model: Model("bad"), err: "cannot find agent processor for data model bad"},
-		{protocol: compactProtocol, model: jaegerModel, err: "no host:port provided for udp server: {QueueSize:1000 MaxPacketSize:65000 HostPort:}"},
+		{protocol: compactProtocol, model: jaegerModel, err: "no host:port provided for udp server: {QueueSize:1000 MaxPacketSize:65000 HostPort: SocketBufferSize:0 UnixSocketPath:}"},
 		{protocol: compactProtocol, model: zipkinModel, hostPort: "bad-host-port", errContains: "bad-host-port"},
 	}
 	for _, tc := range testCases {
@@ -151,6 +155,34 @@ func TestBuilderWithProcessorErrors(t *testing.T) {
 	}
 }
 
+func TestBuilderWithUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agent")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cfg := &Builder{
+		Processors: []ProcessorConfiguration{
+			{
+				Model:    jaegerModel,
+				Protocol: compactProtocol,
+				Server: ServerConfiguration{
+					HostPort:       "127.0.0.1:0",
+					UnixSocketPath: filepath.Join(dir, "agent.sock"),
+				},
+			},
+		},
+	}
+	processors, err := cfg.getProcessors(fakeCollectorProxy{}, metrics.NullFactory, zap.NewNop())
+	require.NoError(t, err)
+	require.Len(t, processors, 2)
+	for _, p := range processors {
+		p.Stop()
+	}
+
+	cfg.Processors[0].Server.UnixSocketPath = dir
+	_, err = cfg.getProcessors(fakeCollectorProxy{}, metrics.NullFactory, zap.NewNop())
+	assert.EqualError(t, err, "cannot create Thrift Processor: cannot create unix socket Server: cannot listen on "+dir+": the file exists and is not a socket")
+}
+
 func TestMultipleCollectorProxies(t *testing.T) {
 	b := Builder{}
 	ra := fakeCollectorProxy{}
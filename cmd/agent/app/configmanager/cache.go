@@ -0,0 +1,218 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configmanager
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/thrift-gen/baggage"
+	"github.com/jaegertracing/jaeger/thrift-gen/sampling"
+)
+
+// cacheMetrics holds metrics related to the sampling strategies cache
+type cacheMetrics struct {
+	// Number of sampling strategies served from the cache because the collector failed to respond
+	Fallbacks metrics.Counter `metric:"sampling-cache.fallbacks"`
+
+	// Number of failures to write the cache file
+	WriteFailures metrics.Counter `metric:"sampling-cache.write-failures"`
+}
+
+const (
+	// maxCachedServices bounds the strategies kept, the least recently requested services are evicted first
+	maxCachedServices = 10000
+	// saveDelay is how long the changes are collected before the file is written, off the request path
+	saveDelay = time.Second
+)
+
+// CachingManager is a decorator that keeps the last sampling strategy received for each service
+// in a file, and returns it when the wrapped manager fails, e.g. when the agent starts while
+// the collectors are unreachable.
+type CachingManager struct {
+	wrapped     ClientConfigManager
+	path        string
+	logger      *zap.Logger
+	metrics     cacheMetrics
+	maxServices int
+	saveDelay   time.Duration
+
+	lock       sync.Mutex
+	strategies map[string]*list.Element
+	order      *list.List // cachedStrategy, the least recently requested first
+	saveTimer  *time.Timer
+
+	// writeLock serializes the writes of the file
+	writeLock sync.Mutex
+}
+
+type cachedStrategy struct {
+	service  string
+	strategy *sampling.SamplingStrategyResponse
+}
+
+// WrapWithCache wraps ClientConfigManager and loads the strategies previously saved to the file.
+// A missing or unreadable file only starts the cache empty.
+func WrapWithCache(manager ClientConfigManager, path string, logger *zap.Logger, mFactory metrics.Factory) *CachingManager {
+	m := &CachingManager{
+		wrapped:     manager,
+		path:        path,
+		logger:      logger,
+		maxServices: maxCachedServices,
+		saveDelay:   saveDelay,
+		strategies:  make(map[string]*list.Element),
+		order:       list.New(),
+	}
+	metrics.Init(&m.metrics, mFactory, nil)
+	strategies, err := loadCache(path)
+	if err != nil {
+		logger.Warn("Ignoring the sampling strategies cache", zap.String("file", path), zap.Error(err))
+	} else if strategies != nil {
+		services := make([]string, 0, len(strategies))
+		for service := range strategies {
+			services = append(services, service)
+		}
+		sort.Strings(services)
+		for _, service := range services {
+			m.putLocked(service, strategies[service])
+		}
+		logger.Info("Loaded sampling strategies cache", zap.String("file", path), zap.Int("services", len(m.strategies)))
+	}
+	return m
+}
+
+// GetSamplingStrategy returns the sampling strategy from the wrapped manager, or the cached one if it fails.
+func (m *CachingManager) GetSamplingStrategy(ctx context.Context, serviceName string) (*sampling.SamplingStrategyResponse, error) {
+	r, err := m.wrapped.GetSamplingStrategy(ctx, serviceName)
+	if err != nil {
+		m.lock.Lock()
+		var cached *sampling.SamplingStrategyResponse
+		if e, ok := m.strategies[serviceName]; ok {
+			m.order.MoveToBack(e)
+			cached = e.Value.(*cachedStrategy).strategy
+		}
+		m.lock.Unlock()
+		if cached == nil {
+			return nil, err
+		}
+		m.metrics.Fallbacks.Inc(1)
+		m.logger.Debug("Using cached sampling strategy", zap.String("service", serviceName), zap.Error(err))
+		return cached, nil
+	}
+	m.update(serviceName, r)
+	return r, nil
+}
+
+// GetBaggageRestrictions delegates to the wrapped manager.
+func (m *CachingManager) GetBaggageRestrictions(ctx context.Context, serviceName string) ([]*baggage.BaggageRestriction, error) {
+	return m.wrapped.GetBaggageRestrictions(ctx, serviceName)
+}
+
+// update caches the strategy and schedules the write of the file if it changed.
+func (m *CachingManager) update(serviceName string, r *sampling.SamplingStrategyResponse) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if e, ok := m.strategies[serviceName]; ok {
+		m.order.MoveToBack(e)
+		if reflect.DeepEqual(e.Value.(*cachedStrategy).strategy, r) {
+			return
+		}
+	}
+	m.putLocked(serviceName, r)
+	if m.saveTimer == nil {
+		m.saveTimer = time.AfterFunc(m.saveDelay, m.save)
+	}
+}
+
+func (m *CachingManager) putLocked(serviceName string, r *sampling.SamplingStrategyResponse) {
+	if e, ok := m.strategies[serviceName]; ok {
+		e.Value.(*cachedStrategy).strategy = r
+		return
+	}
+	m.strategies[serviceName] = m.order.PushBack(&cachedStrategy{service: serviceName, strategy: r})
+	for len(m.strategies) > m.maxServices {
+		evicted := m.order.Remove(m.order.Front()).(*cachedStrategy)
+		delete(m.strategies, evicted.service)
+	}
+}
+
+// save writes the strategies cached at this time to the file.
+func (m *CachingManager) save() {
+	m.lock.Lock()
+	if m.saveTimer != nil {
+		m.saveTimer.Stop()
+		m.saveTimer = nil
+	}
+	strategies := make(map[string]*sampling.SamplingStrategyResponse, len(m.strategies))
+	for service, e := range m.strategies {
+		strategies[service] = e.Value.(*cachedStrategy).strategy
+	}
+	m.lock.Unlock()
+
+	m.writeLock.Lock()
+	defer m.writeLock.Unlock()
+	if err := writeCache(m.path, strategies); err != nil {
+		m.metrics.WriteFailures.Inc(1)
+		m.logger.Error("Failed to write the sampling strategies cache", zap.String("file", m.path), zap.Error(err))
+	}
+}
+
+// writeCache replaces the file atomically so that a crash never leaves a truncated cache behind.
+func writeCache(path string, strategies map[string]*sampling.SamplingStrategyResponse) error {
+	data, err := json.Marshal(strategies)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func loadCache(path string) (map[string]*sampling.SamplingStrategyResponse, error) {
+	data, err := ioutil.ReadFile(filepath.Clean(path))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var strategies map[string]*sampling.SamplingStrategyResponse
+	if err := json.Unmarshal(data, &strategies); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal sampling strategies: %w", err)
+	}
+	return strategies, nil
+}
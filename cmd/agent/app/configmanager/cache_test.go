@@ -0,0 +1,176 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configmanager
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/thrift-gen/baggage"
+	"github.com/jaegertracing/jaeger/thrift-gen/sampling"
+)
+
+type collectorManager struct {
+	unavailable bool
+	rate        float64
+}
+
+func (m *collectorManager) GetSamplingStrategy(_ context.Context, _ string) (*sampling.SamplingStrategyResponse, error) {
+	if m.unavailable {
+		return nil, errors.New("collector unavailable")
+	}
+	return &sampling.SamplingStrategyResponse{
+		StrategyType:          sampling.SamplingStrategyType_PROBABILISTIC,
+		ProbabilisticSampling: &sampling.ProbabilisticSamplingStrategy{SamplingRate: m.rate},
+	}, nil
+}
+
+func (m *collectorManager) GetBaggageRestrictions(_ context.Context, _ string) ([]*baggage.BaggageRestriction, error) {
+	return []*baggage.BaggageRestriction{{BaggageKey: "foo"}}, nil
+}
+
+func tempCacheFile(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "sampling-cache")
+	require.NoError(t, err)
+	return filepath.Join(dir, "strategies.json"), func() { os.RemoveAll(dir) }
+}
+
+func TestCachingManager(t *testing.T) {
+	path, cleanup := tempCacheFile(t)
+	defer cleanup()
+
+	collector := &collectorManager{rate: 0.5}
+	mFactory := metricstest.NewFactory(time.Microsecond)
+	mgr := WrapWithCache(collector, path, zap.NewNop(), mFactory)
+	mgr.saveDelay = time.Hour
+	s, err := mgr.GetSamplingStrategy(context.Background(), "frontend")
+	require.NoError(t, err)
+	assert.Equal(t, 0.5, s.ProbabilisticSampling.SamplingRate)
+	mgr.save()
+
+	collector.unavailable = true
+	s, err = mgr.GetSamplingStrategy(context.Background(), "frontend")
+	require.NoError(t, err)
+	assert.Equal(t, 0.5, s.ProbabilisticSampling.SamplingRate)
+	_, err = mgr.GetSamplingStrategy(context.Background(), "backend")
+	assert.EqualError(t, err, "collector unavailable")
+	mFactory.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "sampling-cache.fallbacks", Value: 1})
+
+	b, err := mgr.GetBaggageRestrictions(context.Background(), "frontend")
+	require.NoError(t, err)
+	assert.Len(t, b, 1)
+
+	// a restarted agent serves the strategies saved by the previous one
+	restarted := WrapWithCache(&collectorManager{unavailable: true}, path, zap.NewNop(), metricstest.NewFactory(time.Microsecond))
+	s, err = restarted.GetSamplingStrategy(context.Background(), "frontend")
+	require.NoError(t, err)
+	assert.Equal(t, sampling.SamplingStrategyType_PROBABILISTIC, s.StrategyType)
+	assert.Equal(t, 0.5, s.ProbabilisticSampling.SamplingRate)
+
+	// the cache is refreshed once the collector is back
+	collector.unavailable = false
+	collector.rate = 0.1
+	_, err = mgr.GetSamplingStrategy(context.Background(), "frontend")
+	require.NoError(t, err)
+	mgr.save()
+	strategies, err := loadCache(path)
+	require.NoError(t, err)
+	assert.Equal(t, 0.1, strategies["frontend"].ProbabilisticSampling.SamplingRate)
+}
+
+func TestCachingManagerInvalidFile(t *testing.T) {
+	path, cleanup := tempCacheFile(t)
+	defer cleanup()
+	require.NoError(t, ioutil.WriteFile(path, []byte("not json"), 0600))
+
+	mgr := WrapWithCache(&collectorManager{unavailable: true}, path, zap.NewNop(), metricstest.NewFactory(time.Microsecond))
+	_, err := mgr.GetSamplingStrategy(context.Background(), "frontend")
+	assert.EqualError(t, err, "collector unavailable")
+}
+
+func TestCachingManagerWriteFailure(t *testing.T) {
+	mFactory := metricstest.NewFactory(time.Microsecond)
+	mgr := WrapWithCache(&collectorManager{rate: 1}, "/not/there/strategies.json", zap.NewNop(), mFactory)
+	mgr.saveDelay = time.Hour
+	s, err := mgr.GetSamplingStrategy(context.Background(), "frontend")
+	require.NoError(t, err)
+	assert.NotNil(t, s)
+	mgr.save()
+	mFactory.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "sampling-cache.write-failures", Value: 1})
+}
+
+func TestCachingManagerSavesInBackground(t *testing.T) {
+	path, cleanup := tempCacheFile(t)
+	defer cleanup()
+
+	mgr := WrapWithCache(&collectorManager{rate: 0.5}, path, zap.NewNop(), metricstest.NewFactory(time.Microsecond))
+	mgr.saveDelay = 10 * time.Millisecond
+	for _, service := range []string{"frontend", "backend"} {
+		_, err := mgr.GetSamplingStrategy(context.Background(), service)
+		require.NoError(t, err)
+	}
+	_, err := os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "the file is not written on the request path")
+
+	var strategies map[string]*sampling.SamplingStrategyResponse
+	for i := 0; i < 100 && len(strategies) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+		strategies, _ = loadCache(path)
+	}
+	assert.Len(t, strategies, 2)
+}
+
+func TestCachingManagerBounded(t *testing.T) {
+	path, cleanup := tempCacheFile(t)
+	defer cleanup()
+
+	collector := &collectorManager{rate: 0.5}
+	mgr := WrapWithCache(collector, path, zap.NewNop(), metricstest.NewFactory(time.Microsecond))
+	mgr.saveDelay = time.Hour
+	mgr.maxServices = 2
+	get := func(service string) error {
+		_, err := mgr.GetSamplingStrategy(context.Background(), service)
+		return err
+	}
+	require.NoError(t, get("a"))
+	require.NoError(t, get("b"))
+	// a fallback to the cache counts as a use
+	collector.unavailable = true
+	require.NoError(t, get("a"))
+	collector.unavailable = false
+	require.NoError(t, get("c"))
+
+	collector.unavailable = true
+	assert.NoError(t, get("a"))
+	assert.NoError(t, get("c"))
+	assert.EqualError(t, get("b"), "collector unavailable")
+
+	mgr.save()
+	strategies, err := loadCache(path)
+	require.NoError(t, err)
+	assert.Len(t, strategies, 2)
+	assert.Contains(t, strategies, "a")
+	assert.Contains(t, strategies, "c")
+}
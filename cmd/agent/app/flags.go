@@ -22,6 +22,7 @@ import (
 
 	"github.com/spf13/viper"
 
+	"github.com/jaegertracing/jaeger/cmd/all-in-one/setupcontext"
 	"github.com/jaegertracing/jaeger/ports"
 )
 
@@ -30,8 +31,15 @@ const (
 	suffixServerQueueSize     = "server-queue-size"
 	suffixServerMaxPacketSize = "server-max-packet-size"
 	suffixServerHostPort      = "server-host-port"
+	suffixServerSocketBuffer  = "server-socket-buffer-size"
+	suffixServerUnixSocket    = "server-unix-socket"
 	// HTTPServerHostPort is the flag for HTTP endpoint
-	HTTPServerHostPort = "http-server.host-port"
+	HTTPServerHostPort    = "http-server.host-port"
+	configReloadInterval  = "agent.config-reload.interval"
+	httpServerAcceptSpans = "http-server.accept-spans"
+	samplingCacheFile     = "http-server.sampling-cache-file"
+	zipkinHTTPHostPort    = "zipkin.http.host-port"
+	zipkinUDPHostPort     = "zipkin.udp.host-port"
 )
 
 var defaultProcessors = []struct {
@@ -52,6 +60,20 @@ func AddFlags(flags *flag.FlagSet) {
 		flags.Int(prefix+suffixServerQueueSize, defaultQueueSize, "length of the queue for the UDP server")
 		flags.Int(prefix+suffixServerMaxPacketSize, defaultMaxPacketSize, "max packet size for the UDP server")
 		flags.String(prefix+suffixServerHostPort, ":"+strconv.Itoa(p.port), "host:port for the UDP server")
+		flags.Int(prefix+suffixServerSocketBuffer, 0, "receive buffer size in bytes requested for the UDP socket, the OS default if 0")
+		flags.String(prefix+suffixServerUnixSocket, "", "path of a unix socket also receiving spans, each message preceded by its 4-byte big-endian length as with TFramedTransport; "+
+			"unlike UDP, clients are slowed down rather than spans dropped when the agent is busy. Disabled if empty")
+	}
+	flags.Bool(httpServerAcceptSpans, false, "Accept spans on the http server as Jaeger Thrift-JSON on /api/traces and as OTLP/HTTP JSON on /v1/traces, "+
+		"for processes that cannot emit spans over UDP")
+	flags.String(samplingCacheFile, "", "File where the last sampling strategy of each service is saved, "+
+		"to be served when the collectors cannot be reached, including after a restart. Empty disables the cache")
+	flags.String(zipkinHTTPHostPort, "", "host:port of the http server accepting Zipkin v1 and v2 spans on /api/v1/spans and /api/v2/spans, "+
+		"as JSON, Thrift or Protobuf. Disabled if empty")
+	flags.String(zipkinUDPHostPort, "", "host:port of the UDP server accepting lists of Zipkin Thrift spans, encoded with TBinaryProtocol as by the Zipkin libraries. Disabled if empty")
+	if !setupcontext.IsAllInOne() {
+		flags.Duration(configReloadInterval, 0, "How often the file given with --config-file is checked for changes, besides on SIGHUP; "+
+			"collector host:ports and agent tags are applied without a restart, other changes are only logged. Zero only reloads on SIGHUP")
 	}
 	AddOTELFlags(flags)
 }
@@ -73,10 +95,17 @@ func (b *Builder) InitFromViper(v *viper.Viper) *Builder {
 		p.Server.QueueSize = v.GetInt(prefix + suffixServerQueueSize)
 		p.Server.MaxPacketSize = v.GetInt(prefix + suffixServerMaxPacketSize)
 		p.Server.HostPort = portNumToHostPort(v.GetString(prefix + suffixServerHostPort))
+		p.Server.SocketBufferSize = v.GetInt(prefix + suffixServerSocketBuffer)
+		p.Server.UnixSocketPath = v.GetString(prefix + suffixServerUnixSocket)
 		b.Processors = append(b.Processors, *p)
 	}
 
 	b.HTTPServer.HostPort = portNumToHostPort(v.GetString(HTTPServerHostPort))
+	b.HTTPServer.AcceptSpans = v.GetBool(httpServerAcceptSpans)
+	b.HTTPServer.SamplingCacheFile = v.GetString(samplingCacheFile)
+	b.Zipkin.HTTPHostPort = portNumToHostPort(v.GetString(zipkinHTTPHostPort))
+	b.Zipkin.UDPHostPort = portNumToHostPort(v.GetString(zipkinUDPHostPort))
+	b.ConfigReload.Interval = v.GetDuration(configReloadInterval)
 	return b
 }
 
@@ -18,6 +18,7 @@ package app
 import (
 	"flag"
 	"testing"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -40,14 +41,27 @@ func TestBindFlags(t *testing.T) {
 		"--processor.jaeger-binary.server-max-packet-size=4242",
 		"--processor.jaeger-binary.server-queue-size=42",
 		"--processor.jaeger-binary.workers=42",
+		"--processor.jaeger-binary.server-socket-buffer-size=4194304",
+		"--processor.jaeger-binary.server-unix-socket=/var/run/jaeger/agent.sock",
+		"--agent.config-reload.interval=30s",
+		"--http-server.accept-spans=true",
+		"--http-server.sampling-cache-file=/var/lib/jaeger/sampling.json",
+		"--zipkin.http.host-port=9411",
+		"--zipkin.udp.host-port=localhost:9410",
 	})
 	require.NoError(t, err)
 
 	b.InitFromViper(v)
 	assert.Equal(t, 3, len(b.Processors))
 	assert.Equal(t, ":8080", b.HTTPServer.HostPort)
+	assert.True(t, b.HTTPServer.AcceptSpans)
+	assert.Equal(t, "/var/lib/jaeger/sampling.json", b.HTTPServer.SamplingCacheFile)
+	assert.Equal(t, ZipkinConfiguration{HTTPHostPort: ":9411", UDPHostPort: "localhost:9410"}, b.Zipkin)
 	assert.Equal(t, ":1111", b.Processors[2].Server.HostPort)
 	assert.Equal(t, 4242, b.Processors[2].Server.MaxPacketSize)
 	assert.Equal(t, 42, b.Processors[2].Server.QueueSize)
 	assert.Equal(t, 42, b.Processors[2].Workers)
+	assert.Equal(t, 4194304, b.Processors[2].Server.SocketBufferSize)
+	assert.Equal(t, "/var/run/jaeger/agent.sock", b.Processors[2].Server.UnixSocketPath)
+	assert.Equal(t, 30*time.Second, b.ConfigReload.Interval)
 }
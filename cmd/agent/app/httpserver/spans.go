@@ -0,0 +1,171 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpserver
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/gorilla/mux"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/cmd/agent/app/reporter"
+	"github.com/jaegertracing/jaeger/model/converter/otlp"
+	jConverter "github.com/jaegertracing/jaeger/model/converter/thrift/jaeger"
+	"github.com/jaegertracing/jaeger/thrift-gen/jaeger"
+)
+
+const (
+	thriftJSONContentType = "application/vnd.apache.thrift.json"
+	jsonContentType       = "application/json"
+)
+
+// SpanHandler accepts spans over HTTP from processes that cannot emit them over UDP,
+// e.g. browser applications proxied locally or shell scripts, and passes them to the agent reporter.
+type SpanHandler struct {
+	reporter reporter.Reporter
+	logger   *zap.Logger
+	metrics  struct {
+		// Number of batches received as Jaeger Thrift-JSON
+		ThriftJSONBatches metrics.Counter `metric:"http-server.requests" tags:"type=spans-thrift-json"`
+
+		// Number of batches received as OTLP/HTTP JSON
+		OTLPJSONBatches metrics.Counter `metric:"http-server.requests" tags:"type=spans-otlp-json"`
+
+		// Number of bad span submissions
+		BadRequest metrics.Counter `metric:"http-server.errors" tags:"status=4xx,source=spans"`
+
+		// Number of span submissions the reporter failed to forward to the collector
+		ReporterFailures metrics.Counter `metric:"http-server.errors" tags:"status=5xx,source=reporter"`
+	}
+}
+
+// NewSpanHandler creates a handler of span submissions.
+func NewSpanHandler(reporter reporter.Reporter, logger *zap.Logger, mFactory metrics.Factory) *SpanHandler {
+	h := &SpanHandler{reporter: reporter, logger: logger}
+	metrics.MustInit(&h.metrics, mFactory, nil)
+	return h
+}
+
+// RegisterRoutes registers the span submission routes with a mux router, using the paths of the collector.
+func (h *SpanHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/traces", h.saveThriftJSON).Methods(http.MethodPost)
+	router.HandleFunc("/v1/traces", h.saveOTLPJSON).Methods(http.MethodPost)
+}
+
+func (h *SpanHandler) saveThriftJSON(w http.ResponseWriter, r *http.Request) {
+	body, ok := h.readBody(w, r, thriftJSONContentType, jsonContentType)
+	if !ok {
+		return
+	}
+	transport := thrift.NewTMemoryBufferLen(len(body))
+	transport.Write(body)
+	batch := &jaeger.Batch{}
+	if err := batch.Read(thrift.NewTJSONProtocol(transport)); err != nil {
+		h.badRequest(w, fmt.Sprintf("Unable to process request body: %v", err))
+		return
+	}
+	h.metrics.ThriftJSONBatches.Inc(1)
+	if err := h.reporter.EmitBatch(r.Context(), batch); err != nil {
+		h.reporterFailure(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *SpanHandler) saveOTLPJSON(w http.ResponseWriter, r *http.Request) {
+	body, ok := h.readBody(w, r, jsonContentType)
+	if !ok {
+		return
+	}
+	req, err := otlp.UnmarshalJSON(body)
+	if err != nil {
+		h.badRequest(w, fmt.Sprintf("Unable to process request body: %v", err))
+		return
+	}
+	batches, err := otlp.ToDomain(req)
+	if err != nil {
+		h.badRequest(w, fmt.Sprintf("Unable to process request body: %v", err))
+		return
+	}
+	h.metrics.OTLPJSONBatches.Inc(1)
+	for _, batch := range batches {
+		jBatch := &jaeger.Batch{
+			Process: jConverter.FromDomainProcess(batch.Process),
+			Spans:   jConverter.FromDomain(batch.Spans),
+		}
+		if err := h.reporter.EmitBatch(r.Context(), jBatch); err != nil {
+			h.reporterFailure(w, err)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", jsonContentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("{}"))
+}
+
+// readBody returns the request body if its content type is one of the accepted ones, decompressing it if needed.
+func (h *SpanHandler) readBody(w http.ResponseWriter, r *http.Request, contentTypes ...string) ([]byte, bool) {
+	defer r.Body.Close()
+	contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		h.badRequest(w, fmt.Sprintf("Cannot parse content type: %v", err))
+		return nil, false
+	}
+	accepted := false
+	for _, ct := range contentTypes {
+		accepted = accepted || contentType == ct
+	}
+	if !accepted {
+		h.metrics.BadRequest.Inc(1)
+		http.Error(w, fmt.Sprintf("Unsupported content type: %v", contentType), http.StatusUnsupportedMediaType)
+		return nil, false
+	}
+
+	var body io.Reader = r.Body
+	if strings.Contains(r.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			h.badRequest(w, fmt.Sprintf("Unable to process request body: %v", err))
+			return nil, false
+		}
+		defer gz.Close()
+		body = gz
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		h.badRequest(w, fmt.Sprintf("Unable to process request body: %v", err))
+		return nil, false
+	}
+	return data, true
+}
+
+func (h *SpanHandler) badRequest(w http.ResponseWriter, msg string) {
+	h.metrics.BadRequest.Inc(1)
+	http.Error(w, msg, http.StatusBadRequest)
+}
+
+func (h *SpanHandler) reporterFailure(w http.ResponseWriter, err error) {
+	h.metrics.ReporterFailures.Inc(1)
+	h.logger.Error("Could not forward spans received over HTTP", zap.Error(err))
+	http.Error(w, fmt.Sprintf("Cannot submit spans: %v", err), http.StatusServiceUnavailable)
+}
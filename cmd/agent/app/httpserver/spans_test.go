@@ -0,0 +1,183 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpserver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/thrift-gen/jaeger"
+	"github.com/jaegertracing/jaeger/thrift-gen/zipkincore"
+)
+
+type recordingReporter struct {
+	batches []*jaeger.Batch
+	err     error
+}
+
+func (r *recordingReporter) EmitZipkinBatch(_ context.Context, _ []*zipkincore.Span) error {
+	return errors.New("not implemented")
+}
+
+func (r *recordingReporter) EmitBatch(_ context.Context, batch *jaeger.Batch) error {
+	r.batches = append(r.batches, batch)
+	return r.err
+}
+
+const otlpJSON = `{"resourceSpans": [{
+  "resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "script"}}]},
+  "scopeSpans": [{"spans": [{
+    "traceId": "00000000000000010000000000000002",
+    "spanId": "0000000000000003",
+    "name": "backup",
+    "startTimeUnixNano": "1000000000",
+    "endTimeUnixNano": "3000000000"
+  }]}]
+}]}`
+
+func newTestSpanServer(rep *recordingReporter) (*httptest.Server, *metricstest.Factory) {
+	mf := metricstest.NewFactory(0)
+	r := mux.NewRouter()
+	NewSpanHandler(rep, zap.NewNop(), mf).RegisterRoutes(r)
+	return httptest.NewServer(r), mf
+}
+
+func thriftJSON(t *testing.T, batch *jaeger.Batch) []byte {
+	transport := thrift.NewTMemoryBuffer()
+	protocol := thrift.NewTJSONProtocol(transport)
+	require.NoError(t, batch.Write(protocol))
+	require.NoError(t, protocol.Flush(context.Background()))
+	return transport.Bytes()
+}
+
+func post(t *testing.T, url, contentType string, body []byte, headers ...string) *http.Response {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	for i := 0; i < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp
+}
+
+func TestSpanHandlerThriftJSON(t *testing.T) {
+	rep := &recordingReporter{}
+	server, mf := newTestSpanServer(rep)
+	defer server.Close()
+
+	batch := &jaeger.Batch{
+		Process: &jaeger.Process{ServiceName: "browser"},
+		Spans:   []*jaeger.Span{{TraceIdLow: 1, SpanId: 2, OperationName: "click", StartTime: 10, Duration: 5}},
+	}
+	for _, contentType := range []string{thriftJSONContentType, jsonContentType + "; charset=utf-8"} {
+		resp := post(t, server.URL+"/api/traces", contentType, thriftJSON(t, batch))
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	}
+	require.Len(t, rep.batches, 2)
+	assert.Equal(t, batch, rep.batches[0])
+
+	resp := post(t, server.URL+"/api/traces", thriftJSONContentType, []byte("{"))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp = post(t, server.URL+"/api/traces", "application/x-thrift", thriftJSON(t, batch))
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "http-server.requests", Tags: map[string]string{"type": "spans-thrift-json"}, Value: 2},
+		metricstest.ExpectedMetric{Name: "http-server.errors", Tags: map[string]string{"status": "4xx", "source": "spans"}, Value: 2},
+	)
+}
+
+func TestSpanHandlerOTLPJSON(t *testing.T) {
+	rep := &recordingReporter{}
+	server, mf := newTestSpanServer(rep)
+	defer server.Close()
+
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	_, err := gz.Write([]byte(otlpJSON))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	resp := post(t, server.URL+"/v1/traces", jsonContentType, []byte(otlpJSON))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = post(t, server.URL+"/v1/traces", jsonContentType, gzipped.Bytes(), "Content-Encoding", "gzip")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	require.Len(t, rep.batches, 2)
+	assert.Equal(t, "script", rep.batches[0].Process.ServiceName)
+	require.Len(t, rep.batches[0].Spans, 1)
+	span := rep.batches[0].Spans[0]
+	assert.Equal(t, "backup", span.OperationName)
+	assert.Equal(t, int64(1), span.TraceIdHigh)
+	assert.Equal(t, int64(2), span.TraceIdLow)
+	assert.Equal(t, int64(3), span.SpanId)
+	assert.Equal(t, int64(2000000), span.Duration)
+
+	resp = post(t, server.URL+"/v1/traces", jsonContentType, []byte(`{"resourceSpans": [{"scopeSpans": [{"spans": [{"traceId": "xyz"}]}]}]}`))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp = post(t, server.URL+"/v1/traces", jsonContentType, []byte(otlpJSON), "Content-Encoding", "gzip")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp = post(t, server.URL+"/v1/traces", "application/x-protobuf", nil)
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+	resp = post(t, server.URL+"/v1/traces", "", nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "http-server.requests", Tags: map[string]string{"type": "spans-otlp-json"}, Value: 2},
+		metricstest.ExpectedMetric{Name: "http-server.errors", Tags: map[string]string{"status": "4xx", "source": "spans"}, Value: 4},
+	)
+}
+
+func TestSpanHandlerReporterFailure(t *testing.T) {
+	rep := &recordingReporter{err: errors.New("collector unavailable")}
+	server, mf := newTestSpanServer(rep)
+	defer server.Close()
+
+	resp := post(t, server.URL+"/api/traces", thriftJSONContentType, thriftJSON(t, &jaeger.Batch{Process: &jaeger.Process{ServiceName: "browser"}}))
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	resp = post(t, server.URL+"/v1/traces", jsonContentType, []byte(otlpJSON))
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "http-server.errors", Tags: map[string]string{"status": "5xx", "source": "reporter"}, Value: 2},
+	)
+}
+
+func TestHTTPServerWithSpanHandler(t *testing.T) {
+	rep := &recordingReporter{}
+	s := NewHTTPServer(":1", nil, metricstest.NewFactory(0), NewSpanHandler(rep, zap.NewNop(), metricstest.NewFactory(0)))
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/traces", bytes.NewReader([]byte(otlpJSON)))
+	req.Header.Set("Content-Type", jsonContentType)
+	s.Handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "{}", w.Body.String())
+	assert.Len(t, rep.batches, 1)
+}
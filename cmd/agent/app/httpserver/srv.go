@@ -26,8 +26,9 @@ import (
 )
 
 // NewHTTPServer creates a new server that hosts an HTTP/JSON endpoint for clients
-// to query for sampling strategies and baggage restrictions.
-func NewHTTPServer(hostPort string, manager configmanager.ClientConfigManager, mFactory metrics.Factory) *http.Server {
+// to query for sampling strategies and baggage restrictions. If spanHandler is not nil,
+// the server also accepts spans.
+func NewHTTPServer(hostPort string, manager configmanager.ClientConfigManager, mFactory metrics.Factory, spanHandler *SpanHandler) *http.Server {
 	handler := clientcfghttp.NewHTTPHandler(clientcfghttp.HTTPHandlerParams{
 		ConfigManager:          manager,
 		MetricsFactory:         mFactory,
@@ -35,5 +36,8 @@ func NewHTTPServer(hostPort string, manager configmanager.ClientConfigManager, m
 	})
 	r := mux.NewRouter()
 	handler.RegisterRoutes(r)
+	if spanHandler != nil {
+		spanHandler.RegisterRoutes(r)
+	}
 	return &http.Server{Addr: hostPort, Handler: r}
 }
@@ -22,6 +22,6 @@ import (
 )
 
 func TestHTTPServer(t *testing.T) {
-	s := NewHTTPServer(":1", nil, nil)
+	s := NewHTTPServer(":1", nil, nil, nil)
 	assert.NotNil(t, s)
 }
@@ -0,0 +1,50 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpserver
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/jaegertracing/jaeger/cmd/agent/app/reporter"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/handler"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/zipkin"
+	"github.com/jaegertracing/jaeger/thrift-gen/zipkincore"
+)
+
+// NewZipkinHandler creates a handler of the span submissions of the Zipkin HTTP API, on /api/v1/spans
+// and /api/v2/spans as with the collector, passing the spans to the reporter.
+func NewZipkinHandler(reporter reporter.Reporter) http.Handler {
+	r := mux.NewRouter()
+	zipkin.NewAPIHandler(&zipkinSpansHandler{reporter: reporter}, nil).RegisterRoutes(r)
+	return r
+}
+
+type zipkinSpansHandler struct {
+	reporter reporter.Reporter
+}
+
+func (h *zipkinSpansHandler) SubmitZipkinBatch(spans []*zipkincore.Span, _ handler.SubmitBatchOptions) ([]*zipkincore.Response, error) {
+	if err := h.reporter.EmitZipkinBatch(context.Background(), spans); err != nil {
+		return nil, err
+	}
+	responses := make([]*zipkincore.Response, len(spans))
+	for i := range responses {
+		responses[i] = &zipkincore.Response{Ok: true}
+	}
+	return responses, nil
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpserver
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/cmd/agent/app/testutils"
+)
+
+const zipkinV2JSON = `[{"id": "0000000000000002", "traceId": "0000000000000001", "name": "get",
+  "timestamp": 1, "duration": 10, "localEndpoint": {"serviceName": "frontend"}}]`
+
+func TestZipkinHandler(t *testing.T) {
+	rep := testutils.NewInMemoryReporter()
+	server := httptest.NewServer(NewZipkinHandler(rep))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/api/v2/spans", "application/json", bytes.NewBufferString(zipkinV2JSON))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	require.Len(t, rep.ZipkinSpans(), 1)
+	assert.Equal(t, "get", rep.ZipkinSpans()[0].Name)
+
+	resp, err = http.Post(server.URL+"/api/v2/spans", "application/json", bytes.NewBufferString("[{"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestZipkinHandlerReporterFailure(t *testing.T) {
+	server := httptest.NewServer(NewZipkinHandler(&recordingReporter{}))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/api/v2/spans", "application/json", bytes.NewBufferString(zipkinV2JSON))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
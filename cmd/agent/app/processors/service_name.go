@@ -0,0 +1,164 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processors
+
+import (
+	"errors"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/jaegertracing/jaeger/cmd/agent/app/customtransport"
+)
+
+// UnknownService is returned by ServiceName when a packet cannot be attributed to a service.
+const UnknownService = "unknown"
+
+var errFieldNotFound = errors.New("field not found")
+
+// Field ids of the Thrift IDL of the Agent service, see jaeger-idl/thrift/agent.thrift.
+const (
+	emitBatchArgsBatch      = 1
+	batchProcess            = 1
+	processServiceName      = 1
+	emitZipkinArgsSpans     = 1
+	zipkinSpanAnnotations   = 6
+	zipkinSpanBinaryAnnots  = 8
+	zipkinAnnotationHost    = 3
+	zipkinBinaryAnnotHost   = 4
+	zipkinEndpointService   = 3
+	emitZipkinBatchFunction = "emitZipkinBatch"
+)
+
+// ServiceName returns a function reading the name of the service that emitted a packet of the Agent
+// Thrift service, e.g. to attribute the packets dropped by the agent. Only the fields leading to the
+// name are decoded.
+func ServiceName(factory thrift.TProtocolFactory) func(payload []byte) string {
+	return func(payload []byte) string {
+		trans := &customtransport.TBufferedReadTransport{}
+		trans.Write(payload)
+		protocol := factory.GetProtocol(trans)
+		name, _, _, err := protocol.ReadMessageBegin()
+		if err != nil {
+			return UnknownService
+		}
+		var service string
+		if name == emitZipkinBatchFunction {
+			service, err = zipkinServiceName(protocol)
+		} else {
+			err = findField(protocol, emitBatchArgsBatch, batchProcess, processServiceName)
+			if err == nil {
+				service, err = protocol.ReadString()
+			}
+		}
+		if err != nil || service == "" {
+			return UnknownService
+		}
+		return service
+	}
+}
+
+// zipkinServiceName reads the service name of the host of the first annotation, or binary annotation, of the first span.
+func zipkinServiceName(protocol thrift.TProtocol) (string, error) {
+	if err := findField(protocol, emitZipkinArgsSpans); err != nil {
+		return "", err
+	}
+	if _, size, err := protocol.ReadListBegin(); err != nil || size == 0 {
+		return "", err
+	}
+	if _, err := protocol.ReadStructBegin(); err != nil {
+		return "", err
+	}
+	for {
+		_, fieldType, id, err := protocol.ReadFieldBegin()
+		if err != nil || fieldType == thrift.STOP {
+			return "", err
+		}
+		if (id == zipkinSpanAnnotations || id == zipkinSpanBinaryAnnots) && fieldType == thrift.LIST {
+			hostField := int16(zipkinAnnotationHost)
+			if id == zipkinSpanBinaryAnnots {
+				hostField = zipkinBinaryAnnotHost
+			}
+			if service, err := firstAnnotationService(protocol, hostField); err != nil || service != "" {
+				return service, err
+			}
+			continue
+		}
+		if err := protocol.Skip(fieldType); err != nil {
+			return "", err
+		}
+	}
+}
+
+// firstAnnotationService reads the list of annotations until one has a host with a service name.
+// The list is fully consumed when none has one.
+func firstAnnotationService(protocol thrift.TProtocol, hostField int16) (string, error) {
+	_, size, err := protocol.ReadListBegin()
+	if err != nil {
+		return "", err
+	}
+	for i := 0; i < size; i++ {
+		if _, err := protocol.ReadStructBegin(); err != nil {
+			return "", err
+		}
+		for {
+			_, fieldType, id, err := protocol.ReadFieldBegin()
+			if err != nil {
+				return "", err
+			}
+			if fieldType == thrift.STOP {
+				break
+			}
+			if id == hostField && fieldType == thrift.STRUCT {
+				if err := findField(protocol, zipkinEndpointService); err == nil {
+					return protocol.ReadString()
+				}
+				return "", err
+			}
+			if err := protocol.Skip(fieldType); err != nil {
+				return "", err
+			}
+		}
+		// the compact protocol tracks the field ids of the nested structs
+		if err := protocol.ReadStructEnd(); err != nil {
+			return "", err
+		}
+	}
+	return "", protocol.ReadListEnd()
+}
+
+// findField enters the nested structs along the path of field ids, skipping the other fields.
+func findField(protocol thrift.TProtocol, path ...int16) error {
+	for _, fieldID := range path {
+		if _, err := protocol.ReadStructBegin(); err != nil {
+			return err
+		}
+		for {
+			_, fieldType, id, err := protocol.ReadFieldBegin()
+			if err != nil {
+				return err
+			}
+			if fieldType == thrift.STOP {
+				return errFieldNotFound
+			}
+			if id == fieldID {
+				break
+			}
+			if err := protocol.Skip(fieldType); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
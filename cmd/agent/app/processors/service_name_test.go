@@ -0,0 +1,99 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processors
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/thrift-gen/agent"
+	"github.com/jaegertracing/jaeger/thrift-gen/jaeger"
+	"github.com/jaegertracing/jaeger/thrift-gen/zipkincore"
+)
+
+func agentPacket(t *testing.T, factory thrift.TProtocolFactory, emit func(client *agent.AgentClient) error) []byte {
+	buf := thrift.NewTMemoryBuffer()
+	require.NoError(t, emit(agent.NewAgentClientFactory(buf, factory)))
+	return buf.Bytes()
+}
+
+func TestServiceName(t *testing.T) {
+	host := &zipkincore.Endpoint{ServiceName: "spring"}
+	tests := []struct {
+		name     string
+		emit     func(client *agent.AgentClient) error
+		expected string
+	}{
+		{
+			name: "jaeger",
+			emit: func(client *agent.AgentClient) error {
+				return client.EmitBatch(context.Background(), &jaeger.Batch{
+					Process: &jaeger.Process{ServiceName: "frontend", Tags: []*jaeger.Tag{{Key: "ip", VType: jaeger.TagType_STRING}}},
+					Spans:   []*jaeger.Span{{OperationName: "op"}},
+				})
+			},
+			expected: "frontend",
+		},
+		{
+			name: "zipkin annotation",
+			emit: func(client *agent.AgentClient) error {
+				return client.EmitZipkinBatch(context.Background(), []*zipkincore.Span{{
+					Name:              "op",
+					BinaryAnnotations: []*zipkincore.BinaryAnnotation{{Key: "k", Value: []byte("v")}},
+					Annotations:       []*zipkincore.Annotation{{Value: "cs"}, {Value: "cr", Host: host}},
+				}})
+			},
+			expected: "spring",
+		},
+		{
+			name: "zipkin binary annotation",
+			emit: func(client *agent.AgentClient) error {
+				return client.EmitZipkinBatch(context.Background(), []*zipkincore.Span{{
+					Name:              "op",
+					Annotations:       []*zipkincore.Annotation{{Value: "cs"}},
+					BinaryAnnotations: []*zipkincore.BinaryAnnotation{{Key: "lc", Value: []byte("v"), Host: host}},
+				}})
+			},
+			expected: "spring",
+		},
+		{
+			name: "zipkin without host",
+			emit: func(client *agent.AgentClient) error {
+				return client.EmitZipkinBatch(context.Background(), []*zipkincore.Span{{Name: "op"}})
+			},
+			expected: UnknownService,
+		},
+		{
+			name: "zipkin without spans",
+			emit: func(client *agent.AgentClient) error {
+				return client.EmitZipkinBatch(context.Background(), nil)
+			},
+			expected: UnknownService,
+		},
+	}
+	for _, factory := range []thrift.TProtocolFactory{thrift.NewTCompactProtocolFactory(), thrift.NewTBinaryProtocolFactoryDefault()} {
+		serviceName := ServiceName(factory)
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				assert.Equal(t, test.expected, serviceName(agentPacket(t, factory, test.emit)))
+			})
+		}
+		assert.Equal(t, UnknownService, serviceName([]byte{1, 2, 3}))
+	}
+}
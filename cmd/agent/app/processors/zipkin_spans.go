@@ -0,0 +1,59 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processors
+
+import (
+	"context"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/jaegertracing/jaeger/cmd/agent/app/reporter"
+	"github.com/jaegertracing/jaeger/thrift-gen/zipkincore"
+)
+
+// ZipkinSpansProcessor is an AgentProcessor of messages holding a bare list of Zipkin Thrift spans,
+// as encoded by the Zipkin instrumentation libraries, rather than a call of the Agent Thrift service.
+type ZipkinSpansProcessor struct {
+	reporter reporter.Reporter
+}
+
+// NewZipkinSpansProcessor creates a ZipkinSpansProcessor passing the spans to the reporter.
+func NewZipkinSpansProcessor(reporter reporter.Reporter) *ZipkinSpansProcessor {
+	return &ZipkinSpansProcessor{reporter: reporter}
+}
+
+// Process reads the spans of one message and emits them as a single batch.
+func (p *ZipkinSpansProcessor) Process(ctx context.Context, iprot, _ thrift.TProtocol) (bool, thrift.TException) {
+	_, size, err := iprot.ReadListBegin()
+	if err != nil {
+		return false, thrift.NewTProtocolException(err)
+	}
+	// the size is not used to preallocate the spans as it is not validated against the length of the message
+	var spans []*zipkincore.Span
+	for i := 0; i < size; i++ {
+		span := &zipkincore.Span{}
+		if err := span.Read(iprot); err != nil {
+			return false, thrift.NewTProtocolException(err)
+		}
+		spans = append(spans, span)
+	}
+	if err := iprot.ReadListEnd(); err != nil {
+		return false, thrift.NewTProtocolException(err)
+	}
+	if err := p.reporter.EmitZipkinBatch(ctx, spans); err != nil {
+		return false, thrift.NewTApplicationException(thrift.INTERNAL_ERROR, err.Error())
+	}
+	return true, nil
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processors
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/cmd/agent/app/testutils"
+	"github.com/jaegertracing/jaeger/thrift-gen/jaeger"
+	"github.com/jaegertracing/jaeger/thrift-gen/zipkincore"
+)
+
+type failingZipkinReporter struct{}
+
+func (failingZipkinReporter) EmitZipkinBatch(context.Context, []*zipkincore.Span) error {
+	return errors.New("collector unavailable")
+}
+
+func (failingZipkinReporter) EmitBatch(context.Context, *jaeger.Batch) error {
+	return nil
+}
+
+func zipkinSpansMessage(t *testing.T, spans ...*zipkincore.Span) *thrift.TMemoryBuffer {
+	buffer := thrift.NewTMemoryBuffer()
+	protocol := binaryFactory.GetProtocol(buffer)
+	require.NoError(t, protocol.WriteListBegin(thrift.STRUCT, len(spans)))
+	for _, span := range spans {
+		require.NoError(t, span.Write(protocol))
+	}
+	require.NoError(t, protocol.WriteListEnd())
+	return buffer
+}
+
+func TestZipkinSpansProcessor(t *testing.T) {
+	rep := testutils.NewInMemoryReporter()
+	p := NewZipkinSpansProcessor(rep)
+	protocol := binaryFactory.GetProtocol(zipkinSpansMessage(t, &zipkincore.Span{Name: "a"}, &zipkincore.Span{Name: "b"}))
+	ok, err := p.Process(context.Background(), protocol, protocol)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	require.Len(t, rep.ZipkinSpans(), 2)
+	assert.Equal(t, "b", rep.ZipkinSpans()[1].Name)
+}
+
+func TestZipkinSpansProcessorErrors(t *testing.T) {
+	buffer := zipkinSpansMessage(t, &zipkincore.Span{Name: "a"})
+	truncated := thrift.NewTMemoryBuffer()
+	truncated.Write(buffer.Bytes()[:buffer.Len()-2])
+	protocol := binaryFactory.GetProtocol(truncated)
+	ok, err := NewZipkinSpansProcessor(testutils.NewInMemoryReporter()).Process(context.Background(), protocol, protocol)
+	assert.False(t, ok)
+	assert.Error(t, err)
+
+	protocol = binaryFactory.GetProtocol(zipkinSpansMessage(t, &zipkincore.Span{Name: "a"}))
+	ok, err = NewZipkinSpansProcessor(failingZipkinReporter{}).Process(context.Background(), protocol, protocol)
+	assert.False(t, ok)
+	assert.EqualError(t, err, "collector unavailable")
+}
@@ -16,11 +16,19 @@ package app
 
 import (
 	"github.com/jaegertracing/jaeger/cmd/agent/app/reporter/grpc"
+	"github.com/jaegertracing/jaeger/cmd/agent/app/reporter/otlp"
 )
 
 // GRPCCollectorProxyBuilder creates CollectorProxyBuilder for GRPC reporter
 func GRPCCollectorProxyBuilder(builder *grpc.ConnBuilder) CollectorProxyBuilder {
 	return func(opts ProxyBuilderOptions) (proxy CollectorProxy, err error) {
-		return grpc.NewCollectorProxy(builder, opts.AgentTags, opts.Metrics, opts.Logger)
+		return grpc.NewCollectorProxy(builder, opts.Options, opts.Metrics, opts.Logger)
+	}
+}
+
+// OTLPCollectorProxyBuilder creates CollectorProxyBuilder for OTLP reporter
+func OTLPCollectorProxyBuilder(builder *otlp.ConnBuilder) CollectorProxyBuilder {
+	return func(opts ProxyBuilderOptions) (proxy CollectorProxy, err error) {
+		return otlp.NewCollectorProxy(builder, opts.Options, opts.Metrics, opts.Logger)
 	}
 }
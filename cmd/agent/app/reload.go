@@ -0,0 +1,88 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/spf13/viper"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/pkg/configreload"
+)
+
+// keys of the configuration applied by the collector proxy without a restart
+var collectorProxyKeys = []string{"reporter.grpc.host-port", "agent.tags", "jaeger.tags"}
+
+// ConfigWatcher reloads the collector proxy when the configuration file changes or the agent receives SIGHUP.
+type ConfigWatcher struct {
+	watcher *configreload.Watcher
+	logger  *zap.Logger
+	signals chan os.Signal
+	stop    chan struct{}
+	done    sync.WaitGroup
+}
+
+// NewConfigWatcher starts watching the configuration file viper was loaded from.
+// It returns nil if the agent was started without a configuration file.
+func NewConfigWatcher(opts configreload.Options, v *viper.Viper, proxy CollectorProxy, logger *zap.Logger, mFactory metrics.Factory) (*ConfigWatcher, error) {
+	if v.ConfigFileUsed() == "" {
+		return nil, nil
+	}
+	watcher, err := configreload.NewWatcher(opts, v, logger, mFactory)
+	if err != nil {
+		return nil, err
+	}
+	if reloadable, ok := proxy.(configreload.Reloadable); ok {
+		watcher.Register("collector_proxy", reloadable, collectorProxyKeys...)
+	}
+	w := &ConfigWatcher{
+		watcher: watcher,
+		logger:  logger,
+		signals: make(chan os.Signal, 1),
+		stop:    make(chan struct{}),
+	}
+	signal.Notify(w.signals, syscall.SIGHUP)
+	w.done.Add(1)
+	go w.handleSignals()
+	watcher.Start()
+	logger.Info("Configuration reload enabled", zap.String("file", v.ConfigFileUsed()), zap.Duration("interval", opts.Interval))
+	return w, nil
+}
+
+func (w *ConfigWatcher) handleSignals() {
+	defer w.done.Done()
+	for {
+		select {
+		case <-w.signals:
+			w.logger.Info("Received SIGHUP, reloading the configuration file")
+			w.watcher.Check()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// Close stops watching the configuration file and handling SIGHUP.
+func (w *ConfigWatcher) Close() error {
+	signal.Stop(w.signals)
+	close(w.stop)
+	w.done.Wait()
+	return w.watcher.Close()
+}
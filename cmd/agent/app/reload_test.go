@@ -0,0 +1,73 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/pkg/configreload"
+)
+
+type reloadableCollectorProxy struct {
+	fakeCollectorProxy
+	reloads chan string
+}
+
+func (p reloadableCollectorProxy) Reload(v *viper.Viper) error {
+	p.reloads <- v.GetString("agent.tags")
+	return nil
+}
+
+func TestConfigWatcherWithoutConfigFile(t *testing.T) {
+	w, err := NewConfigWatcher(configreload.Options{}, viper.New(), fakeCollectorProxy{}, zap.NewNop(), metrics.NullFactory)
+	require.NoError(t, err)
+	assert.Nil(t, w)
+}
+
+func TestConfigWatcherReloadsOnSIGHUP(t *testing.T) {
+	f, err := ioutil.TempFile("", "agent-*.yaml")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	require.NoError(t, f.Close())
+	require.NoError(t, ioutil.WriteFile(f.Name(), []byte("agent:\n  tags: zone=a\n"), 0600))
+	v := viper.New()
+	v.SetConfigFile(f.Name())
+	require.NoError(t, v.ReadInConfig())
+
+	proxy := reloadableCollectorProxy{reloads: make(chan string, 1)}
+	w, err := NewConfigWatcher(configreload.Options{}, v, proxy, zap.NewNop(), metrics.NullFactory)
+	require.NoError(t, err)
+	require.NotNil(t, w)
+
+	require.NoError(t, ioutil.WriteFile(f.Name(), []byte("agent:\n  tags: zone=b\n"), 0600))
+	w.signals <- syscall.SIGHUP
+	select {
+	case tags := <-proxy.reloads:
+		assert.Equal(t, "zone=b", tags)
+	case <-time.After(5 * time.Second):
+		t.Fatal("configuration was not reloaded on SIGHUP")
+	}
+	assert.NoError(t, w.Close())
+}
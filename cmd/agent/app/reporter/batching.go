@@ -0,0 +1,200 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reporter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/thrift-gen/jaeger"
+	"github.com/jaegertracing/jaeger/thrift-gen/zipkincore"
+)
+
+// BatchingOptions configures the coalescing of client batches into larger submissions.
+type BatchingOptions struct {
+	// MaxBytes is the approximate Thrift size at which the spans of a process are submitted.
+	// Batching is disabled when it is not positive.
+	MaxBytes int `yaml:"maxBytes"`
+	// FlushInterval is the longest time spans wait in the agent before being submitted.
+	FlushInterval time.Duration `yaml:"flushInterval"`
+}
+
+// Enabled returns true if client batches must be coalesced.
+func (o BatchingOptions) Enabled() bool {
+	return o.MaxBytes > 0
+}
+
+type batchingMetrics struct {
+	// Number of submissions because the spans of a process reached the maximum size
+	SizeFlushes metrics.Counter `metric:"batching.flushes" tags:"trigger=size"`
+
+	// Number of submissions because the flush interval elapsed
+	IntervalFlushes metrics.Counter `metric:"batching.flushes" tags:"trigger=interval"`
+
+	// Number of submissions when the reporter is closed
+	ShutdownFlushes metrics.Counter `metric:"batching.flushes" tags:"trigger=shutdown"`
+
+	// Number of client batches coalesced into submissions
+	BatchesCoalesced metrics.Counter `metric:"batching.batches_coalesced"`
+}
+
+type pendingBatch struct {
+	process *jaeger.Process
+	spans   []*jaeger.Span
+	size    int
+}
+
+// BatchingReporter is a decorator that coalesces the Jaeger batches of the same process
+// and submits them once they reach BatchingOptions.MaxBytes or when the flush interval elapses.
+// Zipkin batches are submitted immediately.
+type BatchingReporter struct {
+	params   BatchingReporterParams
+	metrics  batchingMetrics
+	protocol thrift.TProtocolFactory
+
+	lock    sync.Mutex
+	pending map[string]*pendingBatch
+
+	shutdown  chan struct{}
+	done      sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// BatchingReporterParams is used as input to WrapWithBatching.
+type BatchingReporterParams struct {
+	Reporter       Reporter        // required
+	Options        BatchingOptions // required
+	Logger         *zap.Logger     // required
+	MetricsFactory metrics.Factory // required
+}
+
+// WrapWithBatching creates BatchingReporter.
+func WrapWithBatching(params BatchingReporterParams) *BatchingReporter {
+	if params.Options.FlushInterval <= 0 {
+		params.Options.FlushInterval = defaultBatchFlushInterval
+	}
+	r := &BatchingReporter{
+		params:   params,
+		protocol: thrift.NewTCompactProtocolFactory(),
+		pending:  make(map[string]*pendingBatch),
+		shutdown: make(chan struct{}),
+	}
+	metrics.MustInit(&r.metrics, params.MetricsFactory.Namespace(metrics.NSOptions{Name: "reporter"}), nil)
+	r.done.Add(1)
+	go r.flushLoop()
+	return r
+}
+
+// EmitZipkinBatch delegates to underlying Reporter.
+func (r *BatchingReporter) EmitZipkinBatch(ctx context.Context, spans []*zipkincore.Span) error {
+	return r.params.Reporter.EmitZipkinBatch(ctx, spans)
+}
+
+// EmitBatch adds the spans to the pending batch of their process, and submits it
+// if it became larger than the maximum size. Submission errors are only logged.
+func (r *BatchingReporter) EmitBatch(ctx context.Context, batch *jaeger.Batch) error {
+	key, size := r.processKey(batch.Process)
+	for _, span := range batch.Spans {
+		size += r.thriftSize(span)
+	}
+
+	r.lock.Lock()
+	pending, ok := r.pending[key]
+	if !ok {
+		pending = &pendingBatch{process: batch.Process}
+		r.pending[key] = pending
+	}
+	pending.spans = append(pending.spans, batch.Spans...)
+	pending.size += size
+	r.metrics.BatchesCoalesced.Inc(1)
+	if pending.size < r.params.Options.MaxBytes {
+		r.lock.Unlock()
+		return nil
+	}
+	delete(r.pending, key)
+	r.lock.Unlock()
+
+	r.metrics.SizeFlushes.Inc(1)
+	r.submit(ctx, pending)
+	return nil
+}
+
+// Close stops the periodic flushes and submits the pending spans.
+func (r *BatchingReporter) Close() {
+	r.closeOnce.Do(func() {
+		close(r.shutdown)
+		r.done.Wait()
+		r.flushAll(r.metrics.ShutdownFlushes)
+	})
+}
+
+func (r *BatchingReporter) flushLoop() {
+	defer r.done.Done()
+	ticker := time.NewTicker(r.params.Options.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.flushAll(r.metrics.IntervalFlushes)
+		case <-r.shutdown:
+			return
+		}
+	}
+}
+
+func (r *BatchingReporter) flushAll(counter metrics.Counter) {
+	r.lock.Lock()
+	pending := r.pending
+	r.pending = make(map[string]*pendingBatch)
+	r.lock.Unlock()
+
+	for _, batch := range pending {
+		counter.Inc(1)
+		r.submit(context.Background(), batch)
+	}
+}
+
+func (r *BatchingReporter) submit(ctx context.Context, pending *pendingBatch) {
+	batch := &jaeger.Batch{Process: pending.process, Spans: pending.spans}
+	if err := r.params.Reporter.EmitBatch(ctx, batch); err != nil {
+		r.params.Logger.Error("Could not submit coalesced batch", zap.Int("spans", len(pending.spans)), zap.Error(err))
+	}
+}
+
+// processKey returns the Thrift encoding of the process, which identifies it among the pending batches, and its size.
+func (r *BatchingReporter) processKey(process *jaeger.Process) (string, int) {
+	if process == nil {
+		return "", 0
+	}
+	buf := thrift.NewTMemoryBuffer()
+	if err := process.Write(r.protocol.GetProtocol(buf)); err != nil {
+		// cannot happen when writing to memory, but never coalesce processes that could not be encoded
+		return process.String(), 0
+	}
+	return buf.String(), buf.Len()
+}
+
+func (r *BatchingReporter) thriftSize(span *jaeger.Span) int {
+	buf := thrift.NewTMemoryBuffer()
+	if err := span.Write(r.protocol.GetProtocol(buf)); err != nil {
+		return 0
+	}
+	return buf.Len()
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reporter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/cmd/agent/app/testutils"
+	"github.com/jaegertracing/jaeger/thrift-gen/jaeger"
+	"github.com/jaegertracing/jaeger/thrift-gen/zipkincore"
+)
+
+type batchRecorder struct {
+	mux     sync.Mutex
+	batches []*jaeger.Batch
+	err     error
+}
+
+func (r *batchRecorder) EmitZipkinBatch(context.Context, []*zipkincore.Span) error {
+	return nil
+}
+
+func (r *batchRecorder) EmitBatch(_ context.Context, batch *jaeger.Batch) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.batches = append(r.batches, batch)
+	return r.err
+}
+
+func (r *batchRecorder) getBatches() []*jaeger.Batch {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return r.batches
+}
+
+func newTestBatchingReporter(wrapped Reporter, opts BatchingOptions) (*BatchingReporter, *metricstest.Factory) {
+	mFactory := metricstest.NewFactory(time.Microsecond)
+	return WrapWithBatching(BatchingReporterParams{
+		Reporter:       wrapped,
+		Options:        opts,
+		Logger:         zap.NewNop(),
+		MetricsFactory: mFactory,
+	}), mFactory
+}
+
+func TestBatchingOptionsEnabled(t *testing.T) {
+	assert.False(t, BatchingOptions{}.Enabled())
+	assert.True(t, BatchingOptions{MaxBytes: 1}.Enabled())
+}
+
+func TestBatchingReporter_MaxBytes(t *testing.T) {
+	recorder := &batchRecorder{}
+	r, mFactory := newTestBatchingReporter(recorder, BatchingOptions{MaxBytes: 300, FlushInterval: time.Hour})
+	defer r.Close()
+
+	frontend := &jaeger.Process{ServiceName: "frontend", Tags: []*jaeger.Tag{{Key: "ip", VType: jaeger.TagType_STRING}}}
+	backend := &jaeger.Process{ServiceName: "backend"}
+	span := &jaeger.Span{OperationName: "a rather long operation name to fill the batch faster"}
+	for i := 0; i < 3; i++ {
+		require.NoError(t, r.EmitBatch(context.Background(), &jaeger.Batch{Process: frontend, Spans: []*jaeger.Span{span}}))
+		require.NoError(t, r.EmitBatch(context.Background(), &jaeger.Batch{Process: backend, Spans: []*jaeger.Span{span}}))
+	}
+	assert.Empty(t, recorder.getBatches())
+
+	// equal processes coming from different client batches are coalesced
+	sameFrontend := &jaeger.Process{ServiceName: "frontend", Tags: []*jaeger.Tag{{Key: "ip", VType: jaeger.TagType_STRING}}}
+	require.NoError(t, r.EmitBatch(context.Background(), &jaeger.Batch{Process: sameFrontend, Spans: []*jaeger.Span{span, span}}))
+
+	batches := recorder.getBatches()
+	require.Len(t, batches, 1)
+	assert.Equal(t, frontend, batches[0].Process)
+	assert.Len(t, batches[0].Spans, 5)
+	mFactory.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "reporter.batching.flushes", Tags: map[string]string{"trigger": "size"}, Value: 1},
+		metricstest.ExpectedMetric{Name: "reporter.batching.batches_coalesced", Value: 7},
+	)
+
+	r.Close()
+	batches = recorder.getBatches()
+	require.Len(t, batches, 2)
+	assert.Equal(t, backend, batches[1].Process)
+	assert.Len(t, batches[1].Spans, 3)
+	mFactory.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "reporter.batching.flushes", Tags: map[string]string{"trigger": "shutdown"}, Value: 1},
+	)
+}
+
+func TestBatchingReporter_FlushInterval(t *testing.T) {
+	recorder := &batchRecorder{err: errors.New("collector unavailable")}
+	r, mFactory := newTestBatchingReporter(recorder, BatchingOptions{MaxBytes: 1 << 20, FlushInterval: time.Millisecond})
+	defer r.Close()
+
+	require.NoError(t, r.EmitBatch(context.Background(), &jaeger.Batch{Spans: []*jaeger.Span{{OperationName: "op"}}}))
+	for i := 0; i < 1000 && len(recorder.getBatches()) == 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	batches := recorder.getBatches()
+	require.Len(t, batches, 1)
+	assert.Nil(t, batches[0].Process)
+	counters, _ := mFactory.Snapshot()
+	assert.EqualValues(t, 1, counters["reporter.batching.flushes|trigger=interval"])
+}
+
+func TestBatchingReporter_Zipkin(t *testing.T) {
+	inMemory := testutils.NewInMemoryReporter()
+	r, _ := newTestBatchingReporter(inMemory, BatchingOptions{MaxBytes: 1 << 20})
+	defer r.Close()
+
+	require.NoError(t, r.EmitZipkinBatch(context.Background(), []*zipkincore.Span{{Name: "op"}}))
+	assert.Len(t, inMemory.ZipkinSpans(), 1)
+	assert.Equal(t, defaultBatchFlushInterval, r.params.Options.FlushInterval)
+}
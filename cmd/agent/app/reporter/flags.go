@@ -17,6 +17,7 @@ package reporter
 import (
 	"flag"
 	"fmt"
+	"time"
 
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
@@ -31,8 +32,15 @@ const (
 	// AgentTagsDeprecated is a configuration property name for adding process tags to incoming spans.
 	AgentTagsDeprecated = "jaeger.tags"
 	agentTags           = "agent.tags"
+	batchMaxBytes       = "reporter.batch.max-bytes"
+	batchFlushInterval  = "reporter.batch.flush-interval"
+	rateLimitSpans      = "reporter.rate-limit.spans-per-second"
 	// GRPC is name of gRPC reporter.
 	GRPC Type = "grpc"
+	// OTLP is name of the reporter exporting to an OTLP gRPC endpoint.
+	OTLP Type = "otlp"
+
+	defaultBatchFlushInterval = time.Second
 )
 
 // Type defines type of reporter.
@@ -42,20 +50,30 @@ type Type string
 type Options struct {
 	ReporterType Type
 	AgentTags    map[string]string
+	MetadataTags MetadataTagsOptions
+	Batching     BatchingOptions
+	RateLimiting RateLimitingOptions
 }
 
 // AddFlags adds flags for Options.
 func AddFlags(flags *flag.FlagSet) {
-	flags.String(reporterType, string(GRPC), fmt.Sprintf("Reporter type to use e.g. %s", string(GRPC)))
+	flags.String(reporterType, string(GRPC), fmt.Sprintf("Reporter type to use e.g. %s, %s", string(GRPC), string(OTLP)))
+	flags.Int(batchMaxBytes, 0, "The size in bytes at which the spans received from the clients of a service are submitted together; 0 submits the client batches as received")
+	flags.Duration(batchFlushInterval, defaultBatchFlushInterval, "The longest time spans are held by the agent to be submitted together, see --"+batchMaxBytes)
+	flags.Float64(rateLimitSpans, 0, "The maximum number of spans per second forwarded for each client process, identified by its client-uuid tag or its service name; 0 disables the limit")
 	if !setupcontext.IsAllInOne() {
 		flags.String(AgentTagsDeprecated, "", "(deprecated) see --"+agentTags)
 		flags.String(agentTags, "", "One or more tags to be added to the Process tags of all spans passing through this agent. Ex: key1=value1,key2=${envVar:defaultValue}")
+		addMetadataTagsFlags(flags)
 	}
 }
 
 // InitFromViper initializes Options with properties retrieved from Viper.
 func (b *Options) InitFromViper(v *viper.Viper, logger *zap.Logger) *Options {
 	b.ReporterType = Type(v.GetString(reporterType))
+	b.Batching.MaxBytes = v.GetInt(batchMaxBytes)
+	b.Batching.FlushInterval = v.GetDuration(batchFlushInterval)
+	b.RateLimiting.SpansPerSecond = v.GetFloat64(rateLimitSpans)
 	if !setupcontext.IsAllInOne() {
 		if len(v.GetString(AgentTagsDeprecated)) > 0 {
 			logger.Warn("Using deprecated configuration", zap.String("option", AgentTagsDeprecated))
@@ -64,6 +82,17 @@ func (b *Options) InitFromViper(v *viper.Viper, logger *zap.Logger) *Options {
 		if len(v.GetString(agentTags)) > 0 {
 			b.AgentTags = flags.ParseJaegerTags(v.GetString(agentTags))
 		}
+		b.MetadataTags.initFromViper(v)
+		// the tags set explicitly take precedence over the discovered ones
+		for k, val := range b.MetadataTags.Tags(logger) {
+			if _, ok := b.AgentTags[k]; ok {
+				continue
+			}
+			if b.AgentTags == nil {
+				b.AgentTags = make(map[string]string)
+			}
+			b.AgentTags[k] = val
+		}
 	}
 	return b
 }
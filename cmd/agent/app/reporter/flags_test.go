@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -46,6 +47,43 @@ func TestBindFlags_NoJaegerTags(t *testing.T) {
 	b.InitFromViper(v, zap.NewNop())
 	assert.Equal(t, Type("grpc"), b.ReporterType)
 	assert.Len(t, b.AgentTags, 0)
+	assert.Equal(t, BatchingOptions{FlushInterval: time.Second}, b.Batching)
+	assert.False(t, b.RateLimiting.Enabled())
+}
+
+func TestBindFlags_Batching(t *testing.T) {
+	v := viper.New()
+	command := cobra.Command{}
+	flags := &flag.FlagSet{}
+	AddFlags(flags)
+	command.PersistentFlags().AddGoFlagSet(flags)
+	v.BindPFlags(command.PersistentFlags())
+
+	err := command.ParseFlags([]string{
+		"--reporter.batch.max-bytes=65000",
+		"--reporter.batch.flush-interval=200ms",
+	})
+	require.NoError(t, err)
+
+	b := new(Options).InitFromViper(v, zap.NewNop())
+	assert.Equal(t, BatchingOptions{MaxBytes: 65000, FlushInterval: 200 * time.Millisecond}, b.Batching)
+}
+
+func TestBindFlags_RateLimiting(t *testing.T) {
+	v := viper.New()
+	command := cobra.Command{}
+	flags := &flag.FlagSet{}
+	AddFlags(flags)
+	command.PersistentFlags().AddGoFlagSet(flags)
+	v.BindPFlags(command.PersistentFlags())
+
+	err := command.ParseFlags([]string{
+		"--reporter.rate-limit.spans-per-second=250",
+	})
+	require.NoError(t, err)
+
+	b := new(Options).InitFromViper(v, zap.NewNop())
+	assert.Equal(t, RateLimitingOptions{SpansPerSecond: 250}, b.RateLimiting)
 }
 
 func TestBindFlags(t *testing.T) {
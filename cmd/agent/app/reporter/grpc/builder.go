@@ -17,31 +17,76 @@ package grpc
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	grpc_retry "github.com/grpc-ecosystem/go-grpc-middleware/retry"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding/gzip"
 	"google.golang.org/grpc/resolver"
 	"google.golang.org/grpc/resolver/manual"
 
+	"github.com/jaegertracing/jaeger/pkg/compression/zstd"
 	"github.com/jaegertracing/jaeger/pkg/config/tlscfg"
 	"github.com/jaegertracing/jaeger/pkg/discovery"
 	"github.com/jaegertracing/jaeger/pkg/discovery/grpcresolver"
 )
 
+const (
+	compressionNone = "none"
+
+	// DiscoveryStatic connects to the collectors of CollectorHostPorts
+	DiscoveryStatic = "static"
+	// DiscoveryDNSSRV resolves the collectors from the DNS SRV records of DNSSRVName
+	DiscoveryDNSSRV = "dns-srv"
+	// DiscoveryConsul resolves the collectors from the healthy instances of ConsulService
+	DiscoveryConsul = "consul"
+
+	defaultDiscoveryRefreshInterval = 30 * time.Second
+)
+
 // ConnBuilder Struct to hold configurations
 type ConnBuilder struct {
 	// CollectorHostPorts is list of host:port Jaeger Collectors.
 	CollectorHostPorts []string `yaml:"collectorHostPorts"`
+	// FailoverHostPorts are groups of collectors used in order when the collectors above are unavailable.
+	FailoverHostPorts [][]string `yaml:"failoverHostPorts"`
 
 	MaxRetry uint
+	// RetryCodes are the names of the gRPC status codes retried, e.g. UNAVAILABLE; the retry middleware defaults are used if empty
+	RetryCodes []string `yaml:"retryCodes"`
+	// RetryBackoff is the base of the exponential backoff between retries
+	RetryBackoff time.Duration `yaml:"retryBackoff"`
+	// RetryJitter is the fraction of the backoff randomly added or removed
+	RetryJitter float64 `yaml:"retryJitter"`
+	// Compression is the name of the compressor of the requests, none, gzip or zstd
+	Compression string `yaml:"compression"`
+	// ProxyURL is the HTTP CONNECT or SOCKS5 proxy of the connections to the collectors, e.g. socks5://proxy:1080
+	ProxyURL string `yaml:"proxyURL"`
 	TLS      tlscfg.Options
 
 	DiscoveryMinPeers int
 	Notifier          discovery.Notifier
 	Discoverer        discovery.Discoverer
+
+	// DiscoveryMode is one of static, dns-srv or consul; the collectors of the other modes are refreshed
+	// every DiscoveryRefreshInterval
+	DiscoveryMode            string        `yaml:"discoveryMode"`
+	DiscoveryRefreshInterval time.Duration `yaml:"discoveryRefreshInterval"`
+	DNSSRVName               string        `yaml:"dnsSrvName"`
+	ConsulAddress            string        `yaml:"consulAddress"`
+	ConsulService            string        `yaml:"consulService"`
+
+	// ReloadableHostPorts resolves even a single collector host:port through a resolver,
+	// so that UpdateCollectorHostPorts can change the collectors of the connection
+	ReloadableHostPorts bool
+
+	resolver          *manual.Resolver
+	discoveryNotifier *discovery.PeriodicNotifier
+	tlsWatcher        *tlscfg.ConfigWatcher
 }
 
 // NewConnBuilder creates a new grpc connection builder.
@@ -53,35 +98,57 @@ func NewConnBuilder() *ConnBuilder {
 func (b *ConnBuilder) CreateConnection(logger *zap.Logger) (*grpc.ClientConn, error) {
 	var dialOptions []grpc.DialOption
 	var dialTarget string
+	retryOptions, err := b.retryOptions()
+	if err != nil {
+		return nil, err
+	}
+	dialOptions = append(dialOptions, grpc.WithUnaryInterceptor(grpc_retry.UnaryClientInterceptor(retryOptions...)))
+	switch b.Compression {
+	case "", compressionNone:
+	case gzip.Name, zstd.Name:
+		dialOptions = append(dialOptions, grpc.WithDefaultCallOptions(grpc.UseCompressor(b.Compression)))
+	default:
+		return nil, fmt.Errorf("unsupported compression %q, expecting %q, %q or %q", b.Compression, compressionNone, gzip.Name, zstd.Name)
+	}
+	if b.ProxyURL != "" {
+		dialer, err := proxyDialer(b.ProxyURL)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Agent is connecting to the collectors through a proxy", zap.String("proxy", proxyAddress(b.ProxyURL)))
+		dialOptions = append(dialOptions, grpc.WithContextDialer(dialer))
+	}
 	if b.TLS.Enabled { // user requested a secure connection
 		logger.Info("Agent requested secure grpc connection to collector(s)")
-		tlsConf, err := b.TLS.Config()
+		// the certificates are reloaded when they are renewed on disk
+		watcher, err := b.TLS.NewConfigWatcher(logger)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load TLS config: %w", err)
 		}
-
-		creds := credentials.NewTLS(tlsConf)
-		dialOptions = append(dialOptions, grpc.WithTransportCredentials(creds))
+		b.tlsWatcher = watcher
+		dialOptions = append(dialOptions, grpc.WithTransportCredentials(NewReloadingCredentials(watcher)))
 	} else { // insecure connection
 		logger.Info("Agent requested insecure grpc connection to collector(s)")
 		dialOptions = append(dialOptions, grpc.WithInsecure())
 	}
 
+	if err := b.initDiscovery(logger); err != nil {
+		b.release()
+		return nil, err
+	}
 	if b.Notifier != nil && b.Discoverer != nil {
 		logger.Info("Using external discovery service with roundrobin load balancer")
 		grpcResolver := grpcresolver.New(b.Notifier, b.Discoverer, logger, b.DiscoveryMinPeers)
 		dialTarget = grpcResolver.Scheme() + ":///round_robin"
 	} else {
 		if b.CollectorHostPorts == nil {
+			b.release()
 			return nil, errors.New("at least one collector hostPort address is required when resolver is not available")
 		}
-		if len(b.CollectorHostPorts) > 1 {
+		if len(b.CollectorHostPorts) > 1 || b.ReloadableHostPorts {
 			r, _ := manual.GenerateAndRegisterManualResolver()
-			var resolvedAddrs []resolver.Address
-			for _, addr := range b.CollectorHostPorts {
-				resolvedAddrs = append(resolvedAddrs, resolver.Address{Addr: addr})
-			}
-			r.InitialState(resolver.State{Addresses: resolvedAddrs})
+			r.InitialState(resolver.State{Addresses: resolvedAddresses(b.CollectorHostPorts)})
+			b.resolver = r
 			dialTarget = r.Scheme() + ":///round_robin"
 			logger.Info("Agent is connecting to a static list of collectors", zap.String("dialTarget", dialTarget), zap.String("collector hosts", strings.Join(b.CollectorHostPorts, ",")))
 		} else {
@@ -89,6 +156,88 @@ func (b *ConnBuilder) CreateConnection(logger *zap.Logger) (*grpc.ClientConn, er
 		}
 	}
 	dialOptions = append(dialOptions, grpc.WithDefaultServiceConfig(grpcresolver.GRPCServiceConfig))
-	dialOptions = append(dialOptions, grpc.WithUnaryInterceptor(grpc_retry.UnaryClientInterceptor(grpc_retry.WithMax(b.MaxRetry))))
-	return grpc.Dial(dialTarget, dialOptions...)
+	conn, err := grpc.Dial(dialTarget, dialOptions...)
+	if err != nil {
+		b.release()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// initDiscovery sets the Notifier and the Discoverer polling the discovery system of the DiscoveryMode.
+func (b *ConnBuilder) initDiscovery(logger *zap.Logger) error {
+	var discoverer discovery.Discoverer
+	switch b.DiscoveryMode {
+	case "", DiscoveryStatic:
+		return nil
+	case DiscoveryDNSSRV:
+		if b.DNSSRVName == "" {
+			return errors.New("a DNS SRV name is required, see --" + discoveryDNSSRVName)
+		}
+		discoverer = discovery.NewDNSSRVDiscoverer(b.DNSSRVName)
+	case DiscoveryConsul:
+		if b.ConsulAddress == "" || b.ConsulService == "" {
+			return fmt.Errorf("a Consul address and service are required, see --%s and --%s", discoveryConsulAddress, discoveryConsulService)
+		}
+		discoverer = discovery.NewConsulDiscoverer(b.ConsulAddress, b.ConsulService)
+	default:
+		return fmt.Errorf("unknown discovery mode %q, expecting %q, %q or %q", b.DiscoveryMode, DiscoveryStatic, DiscoveryDNSSRV, DiscoveryConsul)
+	}
+	interval := b.DiscoveryRefreshInterval
+	if interval <= 0 {
+		interval = defaultDiscoveryRefreshInterval
+	}
+	b.discoveryNotifier = discovery.NewPeriodicNotifier(discoverer, interval, logger)
+	b.discoveryNotifier.Start()
+	b.Notifier, b.Discoverer = b.discoveryNotifier, discoverer
+	return nil
+}
+
+// release stops the discovery and the reloading of the TLS files started by CreateConnection.
+func (b *ConnBuilder) release() {
+	if b.discoveryNotifier != nil {
+		b.discoveryNotifier.Stop()
+	}
+	if b.tlsWatcher != nil {
+		b.tlsWatcher.Close()
+	}
+}
+
+func (b *ConnBuilder) retryOptions() ([]grpc_retry.CallOption, error) {
+	options := []grpc_retry.CallOption{grpc_retry.WithMax(b.MaxRetry)}
+	if len(b.RetryCodes) > 0 {
+		retryCodes := make([]codes.Code, len(b.RetryCodes))
+		for i, name := range b.RetryCodes {
+			if err := retryCodes[i].UnmarshalJSON([]byte(strconv.Quote(strings.ToUpper(strings.TrimSpace(name))))); err != nil {
+				return nil, fmt.Errorf("invalid retry code %q", name)
+			}
+		}
+		options = append(options, grpc_retry.WithCodes(retryCodes...))
+	}
+	if b.RetryBackoff > 0 {
+		options = append(options, grpc_retry.WithBackoff(grpc_retry.BackoffExponentialWithJitter(b.RetryBackoff, b.RetryJitter)))
+	}
+	return options, nil
+}
+
+// UpdateCollectorHostPorts changes the static list of collectors of the connection created by CreateConnection.
+func (b *ConnBuilder) UpdateCollectorHostPorts(hostPorts []string, logger *zap.Logger) error {
+	if b.resolver == nil {
+		return errors.New("collector host:ports can only be changed on connections to a static list of collectors")
+	}
+	if len(hostPorts) == 0 {
+		return errors.New("at least one collector hostPort address is required when resolver is not available")
+	}
+	b.resolver.UpdateState(resolver.State{Addresses: resolvedAddresses(hostPorts)})
+	b.CollectorHostPorts = hostPorts
+	logger.Info("Agent is connecting to a new static list of collectors", zap.String("collector hosts", strings.Join(hostPorts, ",")))
+	return nil
+}
+
+func resolvedAddresses(hostPorts []string) []resolver.Address {
+	var resolvedAddrs []resolver.Address
+	for _, addr := range hostPorts {
+		resolvedAddrs = append(resolvedAddrs, resolver.Address{Addr: addr})
+	}
+	return resolvedAddrs
 }
@@ -15,8 +15,12 @@ package grpc
 
 import (
 	"context"
+	"fmt"
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -26,9 +30,12 @@ import (
 	"github.com/uber/jaeger-lib/metrics/metricstest"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
 	yaml "gopkg.in/yaml.v2"
 
+	"github.com/jaegertracing/jaeger/cmd/agent/app/reporter"
 	"github.com/jaegertracing/jaeger/pkg/config/tlscfg"
 	"github.com/jaegertracing/jaeger/pkg/discovery"
 	"github.com/jaegertracing/jaeger/proto-gen/api_v2"
@@ -132,6 +139,143 @@ func TestBuilderWithCollectors(t *testing.T) {
 	}
 }
 
+type flakySpanHandler struct {
+	mockSpanHandler
+	failures int32
+}
+
+func (h *flakySpanHandler) PostSpans(c context.Context, r *api_v2.PostSpansRequest) (*api_v2.PostSpansResponse, error) {
+	if atomic.AddInt32(&h.failures, -1) >= 0 {
+		return nil, status.Error(codes.Unavailable, "collector is restarting")
+	}
+	return h.mockSpanHandler.PostSpans(c, r)
+}
+
+func TestBuilderCompressionAndRetry(t *testing.T) {
+	tests := []struct {
+		name          string
+		builder       ConnBuilder
+		failures      int32
+		expectedError string
+		expectedCalls int
+	}{
+		{
+			name:          "gzip compression",
+			builder:       ConnBuilder{Compression: "gzip"},
+			expectedCalls: 1,
+		},
+		{
+			name:          "zstd compression",
+			builder:       ConnBuilder{Compression: "zstd"},
+			expectedCalls: 1,
+		},
+		{
+			name:          "retry with backoff",
+			builder:       ConnBuilder{MaxRetry: 3, RetryCodes: []string{"unavailable"}, RetryBackoff: time.Millisecond, RetryJitter: 0.1},
+			failures:      2,
+			expectedCalls: 1,
+		},
+		{
+			name:          "code not retried",
+			builder:       ConnBuilder{MaxRetry: 3, RetryCodes: []string{"RESOURCE_EXHAUSTED"}},
+			failures:      1,
+			expectedError: "rpc error: code = Unavailable desc = collector is restarting",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := &flakySpanHandler{failures: test.failures}
+			s, addr := initializeGRPCTestServer(t, func(s *grpc.Server) {
+				api_v2.RegisterCollectorServiceServer(s, handler)
+			})
+			defer s.Stop()
+
+			builder := test.builder
+			builder.CollectorHostPorts = []string{addr.String()}
+			conn, err := builder.CreateConnection(zap.NewNop())
+			require.NoError(t, err)
+			defer conn.Close()
+			r := NewReporter(conn, nil, zap.NewNop())
+			err = r.EmitBatch(context.Background(), &jaeger.Batch{Process: &jaeger.Process{ServiceName: "foo"}})
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Len(t, handler.getRequests(), test.expectedCalls)
+		})
+	}
+}
+
+func TestBuilderInvalidCallOptions(t *testing.T) {
+	builder := &ConnBuilder{CollectorHostPorts: []string{"localhost:0000"}, Compression: "brotli"}
+	_, err := builder.CreateConnection(zap.NewNop())
+	assert.EqualError(t, err, `unsupported compression "brotli", expecting "none", "gzip" or "zstd"`)
+
+	builder = &ConnBuilder{CollectorHostPorts: []string{"localhost:0000"}, RetryCodes: []string{"UNAVAILABLE", "SOMETIMES"}}
+	_, err = builder.CreateConnection(zap.NewNop())
+	assert.EqualError(t, err, `invalid retry code "SOMETIMES"`)
+}
+
+func TestBuilderConsulDiscovery(t *testing.T) {
+	handler := &mockSpanHandler{}
+	s, addr := initializeGRPCTestServer(t, func(s *grpc.Server) {
+		api_v2.RegisterCollectorServiceServer(s, handler)
+	})
+	defer s.Stop()
+	host, port, err := net.SplitHostPort(addr.String())
+	require.NoError(t, err)
+	consul := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/health/service/jaeger-collector", r.URL.Path)
+		fmt.Fprintf(w, `[{"Node": {"Address": %q}, "Service": {"Port": %s}}]`, host, port)
+	}))
+	defer consul.Close()
+
+	builder := &ConnBuilder{
+		DiscoveryMode:            DiscoveryConsul,
+		DiscoveryRefreshInterval: time.Millisecond,
+		ConsulAddress:            consul.URL,
+		ConsulService:            "jaeger-collector",
+		DiscoveryMinPeers:        3,
+	}
+	proxy, err := NewCollectorProxy(builder, reporter.Options{}, metrics.NullFactory, zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, proxy.GetReporter().EmitBatch(context.Background(), &jaeger.Batch{Process: &jaeger.Process{ServiceName: "foo"}}))
+	assert.Len(t, handler.getRequests(), 1)
+	require.NoError(t, proxy.Close())
+}
+
+func TestBuilderDiscoveryErrors(t *testing.T) {
+	tests := []struct {
+		builder       ConnBuilder
+		expectedError string
+	}{
+		{
+			builder:       ConnBuilder{DiscoveryMode: "zookeeper"},
+			expectedError: `unknown discovery mode "zookeeper", expecting "static", "dns-srv" or "consul"`,
+		},
+		{
+			builder:       ConnBuilder{DiscoveryMode: DiscoveryDNSSRV},
+			expectedError: "a DNS SRV name is required, see --reporter.grpc.discovery.dns-srv.name",
+		},
+		{
+			builder:       ConnBuilder{DiscoveryMode: DiscoveryConsul, ConsulAddress: "http://localhost:8500"},
+			expectedError: "a Consul address and service are required, see --reporter.grpc.discovery.consul.address and --reporter.grpc.discovery.consul.service",
+		},
+		{
+			builder:       ConnBuilder{DiscoveryMode: DiscoveryConsul, ConsulAddress: "http://localhost:1", ConsulService: "jaeger-collector"},
+			expectedError: "failed to query Consul for service jaeger-collector",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.builder.DiscoveryMode, func(t *testing.T) {
+			_, err := test.builder.CreateConnection(zap.NewNop())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.expectedError)
+		})
+	}
+}
+
 func TestProxyBuilder(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -173,7 +317,7 @@ func TestProxyBuilder(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			proxy, err := NewCollectorProxy(test.grpcBuilder, nil, metrics.NullFactory, zap.NewNop())
+			proxy, err := NewCollectorProxy(test.grpcBuilder, reporter.Options{}, metrics.NullFactory, zap.NewNop())
 			if test.expectError {
 				require.Error(t, err)
 			} else {
@@ -320,7 +464,7 @@ func TestProxyClientTLS(t *testing.T) {
 			}
 			proxy, err := NewCollectorProxy(
 				grpcBuilder,
-				nil,
+				reporter.Options{},
 				mFactory,
 				zap.NewNop())
 
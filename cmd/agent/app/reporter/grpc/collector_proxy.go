@@ -15,6 +15,9 @@
 package grpc
 
 import (
+	"reflect"
+
+	"github.com/spf13/viper"
 	"github.com/uber/jaeger-lib/metrics"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
@@ -26,29 +29,63 @@ import (
 
 // ProxyBuilder holds objects communicating with collector
 type ProxyBuilder struct {
-	reporter *reporter.ClientMetricsReporter
-	manager  configmanager.ClientConfigManager
-	conn     *grpc.ClientConn
+	reporter     *reporter.ClientMetricsReporter
+	batching     *reporter.BatchingReporter
+	failover     *failover
+	manager      configmanager.ClientConfigManager
+	conn         *grpc.ClientConn
+	builder      *ConnBuilder
+	grpcReporter *Reporter
+	logger       *zap.Logger
 }
 
 // NewCollectorProxy creates ProxyBuilder
-func NewCollectorProxy(builder *ConnBuilder, agentTags map[string]string, mFactory metrics.Factory, logger *zap.Logger) (*ProxyBuilder, error) {
+func NewCollectorProxy(builder *ConnBuilder, opts reporter.Options, mFactory metrics.Factory, logger *zap.Logger) (*ProxyBuilder, error) {
 	conn, err := builder.CreateConnection(logger)
 	if err != nil {
 		return nil, err
 	}
 	grpcMetrics := mFactory.Namespace(metrics.NSOptions{Name: "", Tags: map[string]string{"protocol": "grpc"}})
-	r1 := NewReporter(conn, agentTags, logger)
-	r2 := reporter.WrapWithMetrics(r1, grpcMetrics)
+	r1 := NewReporter(conn, opts.AgentTags, logger)
+	var rep reporter.Reporter = r1
+	var manager configmanager.ClientConfigManager = grpcManager.NewConfigManager(conn)
+	var groups *failover
+	if len(builder.FailoverHostPorts) > 0 {
+		primary := &collectorGroup{hostPorts: builder.CollectorHostPorts, conn: conn, reporter: r1, manager: grpcManager.NewConfigManager(conn)}
+		if groups, err = newFailover(builder, primary, opts.AgentTags, logger); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		rep, manager = groups, groups
+	}
+	var r2 reporter.Reporter = reporter.WrapWithMetrics(rep, grpcMetrics)
+	var batching *reporter.BatchingReporter
+	if opts.Batching.Enabled() {
+		batching = reporter.WrapWithBatching(reporter.BatchingReporterParams{
+			Reporter:       r2,
+			Options:        opts.Batching,
+			Logger:         logger,
+			MetricsFactory: mFactory,
+		})
+		r2 = batching
+	}
+	if opts.RateLimiting.Enabled() {
+		r2 = reporter.WrapWithRateLimiting(r2, opts.RateLimiting, mFactory)
+	}
 	r3 := reporter.WrapWithClientMetrics(reporter.ClientMetricsReporterParams{
 		Reporter:       r2,
 		Logger:         logger,
 		MetricsFactory: mFactory,
 	})
 	return &ProxyBuilder{
-		conn:     conn,
-		reporter: r3,
-		manager:  configmanager.WrapWithMetrics(grpcManager.NewConfigManager(conn), grpcMetrics),
+		conn:         conn,
+		reporter:     r3,
+		batching:     batching,
+		manager:      configmanager.WrapWithMetrics(manager, grpcMetrics),
+		failover:     groups,
+		builder:      builder,
+		grpcReporter: r1,
+		logger:       logger,
 	}, nil
 }
 
@@ -67,8 +104,37 @@ func (b ProxyBuilder) GetManager() configmanager.ClientConfigManager {
 	return b.manager
 }
 
+// Reload applies the collector host:ports and the agent tags currently configured in viper.
+// Changing the collectors requires a connection created with ConnBuilder.ReloadableHostPorts.
+func (b ProxyBuilder) Reload(v *viper.Viper) error {
+	hostPorts := new(ConnBuilder).InitFromViper(v).CollectorHostPorts
+	if !reflect.DeepEqual(hostPorts, b.builder.CollectorHostPorts) {
+		if err := b.builder.UpdateCollectorHostPorts(hostPorts, b.logger); err != nil {
+			return err
+		}
+	}
+	agentTags := new(reporter.Options).InitFromViper(v, b.logger).AgentTags
+	if b.failover != nil {
+		b.failover.SetAgentTags(agentTags)
+	} else {
+		b.grpcReporter.SetAgentTags(agentTags)
+	}
+	return nil
+}
+
 // Close closes connections used by proxy.
 func (b ProxyBuilder) Close() error {
+	defer b.builder.release()
 	b.reporter.Close()
+	if b.batching != nil {
+		// submit the pending spans before closing the connection
+		b.batching.Close()
+	}
+	if b.failover != nil {
+		if err := b.failover.closeFailoverGroups(); err != nil {
+			b.conn.Close()
+			return err
+		}
+	}
 	return b.conn.Close()
 }
@@ -21,12 +21,15 @@ import (
 	"testing"
 	"time"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/uber/jaeger-lib/metrics/metricstest"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 
+	"github.com/jaegertracing/jaeger/cmd/agent/app/reporter"
+	"github.com/jaegertracing/jaeger/model"
 	"github.com/jaegertracing/jaeger/proto-gen/api_v2"
 	"github.com/jaegertracing/jaeger/thrift-gen/jaeger"
 )
@@ -46,7 +49,7 @@ func TestMultipleCollectors(t *testing.T) {
 	defer s2.Stop()
 
 	mFactory := metricstest.NewFactory(time.Microsecond)
-	proxy, err := NewCollectorProxy(&ConnBuilder{CollectorHostPorts: []string{addr1.String(), addr2.String()}}, nil, mFactory, zap.NewNop())
+	proxy, err := NewCollectorProxy(&ConnBuilder{CollectorHostPorts: []string{addr1.String(), addr2.String()}}, reporter.Options{}, mFactory, zap.NewNop())
 	require.NoError(t, err)
 	require.NotNil(t, proxy)
 	assert.NotNil(t, proxy.GetReporter())
@@ -71,6 +74,62 @@ func TestMultipleCollectors(t *testing.T) {
 	require.Nil(t, proxy.Close())
 }
 
+func TestProxyBuilderReload(t *testing.T) {
+	spanHandler1 := &mockSpanHandler{}
+	s1, addr1 := initializeGRPCTestServer(t, func(s *grpc.Server) {
+		api_v2.RegisterCollectorServiceServer(s, spanHandler1)
+	})
+	defer s1.Stop()
+	spanHandler2 := &mockSpanHandler{}
+	s2, addr2 := initializeGRPCTestServer(t, func(s *grpc.Server) {
+		api_v2.RegisterCollectorServiceServer(s, spanHandler2)
+	})
+	defer s2.Stop()
+
+	builder := &ConnBuilder{CollectorHostPorts: []string{addr1.String()}, ReloadableHostPorts: true}
+	proxy, err := NewCollectorProxy(builder, reporter.Options{AgentTags: map[string]string{"zone": "a"}}, metricstest.NewFactory(time.Microsecond), zap.NewNop())
+	require.NoError(t, err)
+	defer proxy.Close()
+	batch := func() *jaeger.Batch {
+		return &jaeger.Batch{Spans: []*jaeger.Span{{OperationName: "op"}}, Process: &jaeger.Process{ServiceName: "service"}}
+	}
+	require.NoError(t, proxy.GetReporter().EmitBatch(context.Background(), batch()))
+	require.Len(t, spanHandler1.getRequests(), 1)
+	assert.Equal(t, []model.KeyValue{model.String("zone", "a")}, spanHandler1.getRequests()[0].Batch.Process.Tags)
+
+	v := viper.New()
+	v.Set("reporter.grpc.host-port", addr2.String())
+	v.Set("agent.tags", "zone=b")
+	require.NoError(t, proxy.Reload(v))
+	assert.Equal(t, []string{addr2.String()}, builder.CollectorHostPorts)
+
+	// the balancer switches to the new collector asynchronously
+	for i := 0; i < 100 && len(spanHandler2.getRequests()) == 0; i++ {
+		require.NoError(t, proxy.GetReporter().EmitBatch(context.Background(), batch()))
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.NotEmpty(t, spanHandler2.getRequests())
+	assert.Equal(t, []model.KeyValue{model.String("zone", "b")}, spanHandler2.getRequests()[0].Batch.Process.Tags)
+}
+
+func TestProxyBuilderReloadErrors(t *testing.T) {
+	addr := "localhost:2"
+	proxy, err := NewCollectorProxy(&ConnBuilder{CollectorHostPorts: []string{addr}}, reporter.Options{}, metricstest.NewFactory(time.Microsecond), zap.NewNop())
+	require.NoError(t, err)
+	defer proxy.Close()
+
+	v := viper.New()
+	v.Set("reporter.grpc.host-port", "localhost:1")
+	assert.EqualError(t, proxy.Reload(v), "collector host:ports can only be changed on connections to a static list of collectors")
+
+	builder := &ConnBuilder{CollectorHostPorts: []string{addr}, ReloadableHostPorts: true}
+	proxy, err = NewCollectorProxy(builder, reporter.Options{}, metricstest.NewFactory(time.Microsecond), zap.NewNop())
+	require.NoError(t, err)
+	defer proxy.Close()
+	assert.EqualError(t, proxy.Reload(viper.New()), "at least one collector hostPort address is required when resolver is not available")
+	assert.Equal(t, []string{addr}, builder.CollectorHostPorts)
+}
+
 func initializeGRPCTestServer(t *testing.T, beforeServe func(server *grpc.Server), opts ...grpc.ServerOption) (*grpc.Server, net.Addr) {
 	server := grpc.NewServer(opts...)
 	lis, err := net.Listen("tcp", "localhost:0")
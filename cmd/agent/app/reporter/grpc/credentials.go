@@ -0,0 +1,70 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc/credentials"
+
+	"github.com/jaegertracing/jaeger/pkg/config/tlscfg"
+)
+
+// reloadingCredentials performs the TLS handshake of every new connection with the latest configuration
+// of the watcher, so that renewed certificates are used without recreating the gRPC connection.
+type reloadingCredentials struct {
+	watcher    *tlscfg.ConfigWatcher
+	serverName string
+}
+
+// NewReloadingCredentials creates gRPC transport credentials from the TLS configuration of the watcher.
+func NewReloadingCredentials(watcher *tlscfg.ConfigWatcher) credentials.TransportCredentials {
+	return &reloadingCredentials{watcher: watcher}
+}
+
+func (c *reloadingCredentials) current() credentials.TransportCredentials {
+	creds := credentials.NewTLS(c.watcher.Config())
+	if c.serverName != "" {
+		creds.OverrideServerName(c.serverName)
+	}
+	return creds
+}
+
+// ClientHandshake implements credentials.TransportCredentials.
+func (c *reloadingCredentials) ClientHandshake(ctx context.Context, authority string, rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return c.current().ClientHandshake(ctx, authority, rawConn)
+}
+
+// ServerHandshake implements credentials.TransportCredentials.
+func (c *reloadingCredentials) ServerHandshake(rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return c.current().ServerHandshake(rawConn)
+}
+
+// Info implements credentials.TransportCredentials.
+func (c *reloadingCredentials) Info() credentials.ProtocolInfo {
+	return c.current().Info()
+}
+
+// Clone implements credentials.TransportCredentials.
+func (c *reloadingCredentials) Clone() credentials.TransportCredentials {
+	return &reloadingCredentials{watcher: c.watcher, serverName: c.serverName}
+}
+
+// OverrideServerName implements credentials.TransportCredentials.
+func (c *reloadingCredentials) OverrideServerName(serverName string) error {
+	c.serverName = serverName
+	return nil
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/pkg/config/tlscfg"
+)
+
+func TestReloadingCredentials(t *testing.T) {
+	options := tlscfg.Options{
+		Enabled:  true,
+		CAPath:   "testdata/testCA.pem",
+		CertPath: "testdata/client.jaeger.io-client.pem",
+		KeyPath:  "testdata/client.jaeger.io-client-key.pem",
+	}
+	watcher, err := options.NewConfigWatcher(zap.NewNop())
+	require.NoError(t, err)
+	defer watcher.Close()
+
+	creds := NewReloadingCredentials(watcher)
+	assert.Equal(t, "tls", creds.Info().SecurityProtocol)
+
+	require.NoError(t, creds.OverrideServerName("collector.jaeger.io"))
+	clone := creds.Clone()
+	assert.Equal(t, "collector.jaeger.io", clone.Info().ServerName)
+	require.NoError(t, clone.OverrideServerName("other.jaeger.io"))
+	assert.Equal(t, "collector.jaeger.io", creds.Info().ServerName)
+}
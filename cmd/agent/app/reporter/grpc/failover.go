@@ -0,0 +1,164 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
+
+	grpcManager "github.com/jaegertracing/jaeger/cmd/agent/app/configmanager/grpc"
+	"github.com/jaegertracing/jaeger/pkg/multierror"
+	"github.com/jaegertracing/jaeger/thrift-gen/baggage"
+	thrift "github.com/jaegertracing/jaeger/thrift-gen/jaeger"
+	"github.com/jaegertracing/jaeger/thrift-gen/sampling"
+	"github.com/jaegertracing/jaeger/thrift-gen/zipkincore"
+)
+
+// collectorGroup is a list of collectors sharing a connection, e.g. the collectors of one cluster.
+type collectorGroup struct {
+	hostPorts []string
+	builder   *ConnBuilder
+	conn      *grpc.ClientConn
+	reporter  *Reporter
+	manager   *grpcManager.SamplingManager
+}
+
+// failover sends the requests to the first group, in priority order, whose connection is ready,
+// and tries the next groups when a group is unavailable. As the connections of the other groups
+// keep reconnecting in the background, requests go back to a group as soon as it recovers.
+// It implements both reporter.Reporter and configmanager.ClientConfigManager.
+type failover struct {
+	groups []*collectorGroup
+	active int32
+	logger *zap.Logger
+}
+
+// newFailover creates the connections of the failover groups, the primary group using the connection already created.
+func newFailover(builder *ConnBuilder, primary *collectorGroup, agentTags map[string]string, logger *zap.Logger) (*failover, error) {
+	f := &failover{groups: []*collectorGroup{primary}, logger: logger}
+	for _, hostPorts := range builder.FailoverHostPorts {
+		groupBuilder := &ConnBuilder{
+			CollectorHostPorts: hostPorts,
+			MaxRetry:           builder.MaxRetry,
+			RetryCodes:         builder.RetryCodes,
+			RetryBackoff:       builder.RetryBackoff,
+			RetryJitter:        builder.RetryJitter,
+			Compression:        builder.Compression,
+			ProxyURL:           builder.ProxyURL,
+			TLS:                builder.TLS,
+		}
+		conn, err := groupBuilder.CreateConnection(logger)
+		if err != nil {
+			f.closeFailoverGroups()
+			return nil, err
+		}
+		f.groups = append(f.groups, &collectorGroup{
+			hostPorts: hostPorts,
+			builder:   groupBuilder,
+			conn:      conn,
+			reporter:  NewReporter(conn, agentTags, logger),
+			manager:   grpcManager.NewConfigManager(conn),
+		})
+	}
+	return f, nil
+}
+
+// EmitBatch implements EmitBatch() of Reporter
+func (f *failover) EmitBatch(ctx context.Context, b *thrift.Batch) error {
+	return f.do(func(g *collectorGroup) error {
+		return g.reporter.EmitBatch(ctx, b)
+	})
+}
+
+// EmitZipkinBatch implements EmitZipkinBatch() of Reporter
+func (f *failover) EmitZipkinBatch(ctx context.Context, zSpans []*zipkincore.Span) error {
+	return f.do(func(g *collectorGroup) error {
+		return g.reporter.EmitZipkinBatch(ctx, zSpans)
+	})
+}
+
+// GetSamplingStrategy implements GetSamplingStrategy() of ClientConfigManager
+func (f *failover) GetSamplingStrategy(ctx context.Context, serviceName string) (r *sampling.SamplingStrategyResponse, err error) {
+	err = f.do(func(g *collectorGroup) error {
+		r, err = g.manager.GetSamplingStrategy(ctx, serviceName)
+		return err
+	})
+	return r, err
+}
+
+// GetBaggageRestrictions implements GetBaggageRestrictions() of ClientConfigManager
+func (f *failover) GetBaggageRestrictions(ctx context.Context, serviceName string) ([]*baggage.BaggageRestriction, error) {
+	return f.groups[0].manager.GetBaggageRestrictions(ctx, serviceName)
+}
+
+// SetAgentTags replaces the agent tags of the reporters of all groups.
+func (f *failover) SetAgentTags(agentTags map[string]string) {
+	for _, g := range f.groups {
+		g.reporter.SetAgentTags(agentTags)
+	}
+}
+
+func (f *failover) do(request func(g *collectorGroup) error) error {
+	var errs []error
+	for _, i := range f.order() {
+		err := request(f.groups[i])
+		if err == nil {
+			if previous := atomic.SwapInt32(&f.active, int32(i)); previous != int32(i) {
+				f.logger.Info("Switched collector group",
+					zap.String("from", strings.Join(f.groups[previous].hostPorts, ",")),
+					zap.String("to", strings.Join(f.groups[i].hostPorts, ",")))
+			}
+			return nil
+		}
+		if status.Code(err) != codes.Unavailable {
+			return err
+		}
+		errs = append(errs, err)
+	}
+	return multierror.Wrap(errs)
+}
+
+// order returns the indexes of the groups with a ready connection first, each part in priority order.
+func (f *failover) order() []int {
+	ready := make([]int, 0, len(f.groups))
+	var others []int
+	for i, g := range f.groups {
+		if g.conn.GetState() == connectivity.Ready {
+			ready = append(ready, i)
+		} else {
+			others = append(others, i)
+		}
+	}
+	return append(ready, others...)
+}
+
+// closeFailoverGroups closes the connections created by newFailover; the primary connection belongs to ProxyBuilder.
+func (f *failover) closeFailoverGroups() error {
+	var errs []error
+	for _, g := range f.groups[1:] {
+		if err := g.conn.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		g.builder.release()
+	}
+	return multierror.Wrap(errs)
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/jaegertracing/jaeger/cmd/agent/app/reporter"
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/proto-gen/api_v2"
+	"github.com/jaegertracing/jaeger/thrift-gen/jaeger"
+	"github.com/jaegertracing/jaeger/thrift-gen/zipkincore"
+)
+
+type mockSamplingHandler struct {
+	err error
+}
+
+func (h *mockSamplingHandler) GetSamplingStrategy(context.Context, *api_v2.SamplingStrategyParameters) (*api_v2.SamplingStrategyResponse, error) {
+	if h.err != nil {
+		return nil, h.err
+	}
+	return &api_v2.SamplingStrategyResponse{StrategyType: api_v2.SamplingStrategyType_RATE_LIMITING, RateLimitingSampling: &api_v2.RateLimitingSamplingStrategy{MaxTracesPerSecond: 5}}, nil
+}
+
+func TestFailoverAndFailback(t *testing.T) {
+	// the primary collector only starts after the agent, on an address reserved here
+	primaryListener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	primaryAddr := primaryListener.Addr().String()
+	require.NoError(t, primaryListener.Close())
+	primaryHandler := &mockSpanHandler{}
+	primary := grpc.NewServer()
+	api_v2.RegisterCollectorServiceServer(primary, primaryHandler)
+	defer primary.Stop()
+
+	secondaryHandler := &mockSpanHandler{}
+	secondary, secondaryAddr := initializeGRPCTestServer(t, func(s *grpc.Server) {
+		api_v2.RegisterCollectorServiceServer(s, secondaryHandler)
+		api_v2.RegisterSamplingManagerServer(s, &mockSamplingHandler{})
+	})
+	defer secondary.Stop()
+
+	builder := &ConnBuilder{
+		CollectorHostPorts: []string{primaryAddr},
+		FailoverHostPorts:  [][]string{{"localhost:2"}, {secondaryAddr.String()}},
+	}
+	proxy, err := NewCollectorProxy(builder, reporter.Options{AgentTags: map[string]string{"zone": "a"}}, metricstest.NewFactory(time.Microsecond), zap.NewNop())
+	require.NoError(t, err)
+	defer proxy.Close()
+	require.Len(t, proxy.failover.groups, 3)
+
+	batch := &jaeger.Batch{Spans: []*jaeger.Span{{OperationName: "op"}}, Process: &jaeger.Process{ServiceName: "service"}}
+	for i := 0; i < 1000 && len(secondaryHandler.getRequests()) == 0; i++ {
+		proxy.GetReporter().EmitBatch(context.Background(), batch)
+		time.Sleep(time.Millisecond)
+	}
+	requests := secondaryHandler.getRequests()
+	require.NotEmpty(t, requests)
+	assert.Equal(t, []model.KeyValue{model.String("zone", "a")}, requests[0].Batch.Process.Tags)
+	assert.Empty(t, primaryHandler.getRequests())
+
+	s, err := proxy.GetManager().GetSamplingStrategy(context.Background(), "service")
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, s.RateLimitingSampling.MaxTracesPerSecond)
+
+	primaryListener, err = net.Listen("tcp", primaryAddr)
+	require.NoError(t, err)
+	go primary.Serve(primaryListener)
+	// the connection to the primary collector is retried with a backoff of about a second
+	for i := 0; i < 10000 && len(primaryHandler.getRequests()) == 0; i++ {
+		proxy.GetReporter().EmitZipkinBatch(context.Background(), []*zipkincore.Span{{
+			Name:        "op",
+			Annotations: []*zipkincore.Annotation{{Value: zipkincore.CLIENT_SEND, Host: &zipkincore.Endpoint{ServiceName: "service"}}},
+		}})
+		time.Sleep(time.Millisecond)
+	}
+	assert.NotEmpty(t, primaryHandler.getRequests())
+}
+
+func TestFailoverOnlyWhenUnavailable(t *testing.T) {
+	primary, primaryAddr := initializeGRPCTestServer(t, func(s *grpc.Server) {
+		api_v2.RegisterSamplingManagerServer(s, &mockSamplingHandler{err: status.Error(codes.InvalidArgument, "no such service")})
+	})
+	defer primary.Stop()
+	secondary, secondaryAddr := initializeGRPCTestServer(t, func(s *grpc.Server) {
+		api_v2.RegisterSamplingManagerServer(s, &mockSamplingHandler{})
+	})
+	defer secondary.Stop()
+
+	builder := &ConnBuilder{CollectorHostPorts: []string{primaryAddr.String()}, FailoverHostPorts: [][]string{{secondaryAddr.String()}}}
+	proxy, err := NewCollectorProxy(builder, reporter.Options{}, metricstest.NewFactory(time.Microsecond), zap.NewNop())
+	require.NoError(t, err)
+	defer proxy.Close()
+
+	_, err = proxy.GetManager().GetSamplingStrategy(context.Background(), "service")
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = proxy.GetManager().GetBaggageRestrictions(context.Background(), "service")
+	assert.EqualError(t, err, "baggage not implemented")
+}
+
+func TestFailoverAllUnavailable(t *testing.T) {
+	builder := &ConnBuilder{CollectorHostPorts: []string{"localhost:2"}, FailoverHostPorts: [][]string{{"localhost:2"}}}
+	proxy, err := NewCollectorProxy(builder, reporter.Options{}, metricstest.NewFactory(time.Microsecond), zap.NewNop())
+	require.NoError(t, err)
+	defer proxy.Close()
+
+	err = proxy.failover.EmitBatch(context.Background(), &jaeger.Batch{Process: &jaeger.Process{ServiceName: "service"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "code = Unavailable")
+}
@@ -17,6 +17,7 @@ package grpc
 import (
 	"flag"
 	"testing"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -25,16 +26,65 @@ import (
 )
 
 func TestBindFlags(t *testing.T) {
+	defaultRetryCodes := []string{"UNAVAILABLE", "RESOURCE_EXHAUSTED"}
+	defaultConsulAddress := "http://127.0.0.1:8500"
 	tests := []struct {
 		cOpts    []string
 		expected *ConnBuilder
 	}{
 		{cOpts: []string{"--reporter.grpc.host-port=localhost:1111", "--reporter.grpc.retry.max=15"},
-			expected: &ConnBuilder{CollectorHostPorts: []string{"localhost:1111"}, MaxRetry: 15, DiscoveryMinPeers: 3}},
+			expected: &ConnBuilder{CollectorHostPorts: []string{"localhost:1111"}, MaxRetry: 15, DiscoveryMinPeers: 3,
+				RetryCodes: defaultRetryCodes, RetryBackoff: 50 * time.Millisecond, RetryJitter: 0.1, Compression: "none",
+				DiscoveryMode: "static", DiscoveryRefreshInterval: 30 * time.Second, ConsulAddress: defaultConsulAddress, ConsulService: "jaeger-collector"}},
 		{cOpts: []string{"--reporter.grpc.host-port=localhost:1111,localhost:2222"},
-			expected: &ConnBuilder{CollectorHostPorts: []string{"localhost:1111", "localhost:2222"}, MaxRetry: defaultMaxRetry, DiscoveryMinPeers: 3}},
+			expected: &ConnBuilder{CollectorHostPorts: []string{"localhost:1111", "localhost:2222"}, MaxRetry: defaultMaxRetry, DiscoveryMinPeers: 3,
+				RetryCodes: defaultRetryCodes, RetryBackoff: 50 * time.Millisecond, RetryJitter: 0.1, Compression: "none",
+				DiscoveryMode: "static", DiscoveryRefreshInterval: 30 * time.Second, ConsulAddress: defaultConsulAddress, ConsulService: "jaeger-collector"}},
 		{cOpts: []string{"--reporter.grpc.host-port=localhost:1111,localhost:2222", "--reporter.grpc.discovery.min-peers=5"},
-			expected: &ConnBuilder{CollectorHostPorts: []string{"localhost:1111", "localhost:2222"}, MaxRetry: defaultMaxRetry, DiscoveryMinPeers: 5}},
+			expected: &ConnBuilder{CollectorHostPorts: []string{"localhost:1111", "localhost:2222"}, MaxRetry: defaultMaxRetry, DiscoveryMinPeers: 5,
+				RetryCodes: defaultRetryCodes, RetryBackoff: 50 * time.Millisecond, RetryJitter: 0.1, Compression: "none",
+				DiscoveryMode: "static", DiscoveryRefreshInterval: 30 * time.Second, ConsulAddress: defaultConsulAddress, ConsulService: "jaeger-collector"}},
+		{cOpts: []string{"--reporter.grpc.host-port=localhost:1111", "--reporter.grpc.failover.host-port=localhost:2222,localhost:3333; localhost:4444;"},
+			expected: &ConnBuilder{
+				CollectorHostPorts: []string{"localhost:1111"},
+				FailoverHostPorts:  [][]string{{"localhost:2222", "localhost:3333"}, {"localhost:4444"}},
+				MaxRetry:           defaultMaxRetry,
+				DiscoveryMinPeers:  3,
+				RetryCodes:         defaultRetryCodes,
+				RetryBackoff:       50 * time.Millisecond,
+				RetryJitter:        0.1,
+				Compression:        "none",
+				DiscoveryMode:      "static", DiscoveryRefreshInterval: 30 * time.Second, ConsulAddress: defaultConsulAddress, ConsulService: "jaeger-collector",
+			}},
+		{cOpts: []string{"--reporter.grpc.host-port=localhost:1111", "--reporter.grpc.compression=gzip",
+			"--reporter.grpc.proxy-url=socks5://proxy:1080", "--reporter.grpc.retry.codes=UNAVAILABLE", "--reporter.grpc.retry.backoff=1s", "--reporter.grpc.retry.jitter=0.5"},
+			expected: &ConnBuilder{
+				CollectorHostPorts: []string{"localhost:1111"},
+				MaxRetry:           defaultMaxRetry,
+				DiscoveryMinPeers:  3,
+				RetryCodes:         []string{"UNAVAILABLE"},
+				RetryBackoff:       time.Second,
+				RetryJitter:        0.5,
+				Compression:        "gzip",
+				ProxyURL:           "socks5://proxy:1080",
+				DiscoveryMode:      "static", DiscoveryRefreshInterval: 30 * time.Second, ConsulAddress: defaultConsulAddress, ConsulService: "jaeger-collector",
+			}},
+		{cOpts: []string{"--reporter.grpc.discovery=consul", "--reporter.grpc.discovery.min-peers=2", "--reporter.grpc.discovery.refresh-interval=10s",
+			"--reporter.grpc.discovery.consul.address=http://consul:8500", "--reporter.grpc.discovery.consul.service=collector",
+			"--reporter.grpc.discovery.dns-srv.name=_grpc._tcp.collector"},
+			expected: &ConnBuilder{
+				MaxRetry:                 defaultMaxRetry,
+				DiscoveryMinPeers:        2,
+				RetryCodes:               defaultRetryCodes,
+				RetryBackoff:             50 * time.Millisecond,
+				RetryJitter:              0.1,
+				Compression:              "none",
+				DiscoveryMode:            "consul",
+				DiscoveryRefreshInterval: 10 * time.Second,
+				DNSSRVName:               "_grpc._tcp.collector",
+				ConsulAddress:            "http://consul:8500",
+				ConsulService:            "collector",
+			}},
 	}
 	for _, test := range tests {
 		v := viper.New()
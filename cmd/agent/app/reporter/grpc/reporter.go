@@ -17,6 +17,7 @@ package grpc
 
 import (
 	"context"
+	"sync/atomic"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
@@ -33,19 +34,25 @@ import (
 // Reporter reports data to collector over gRPC.
 type Reporter struct {
 	collector api_v2.CollectorServiceClient
-	agentTags []model.KeyValue
+	agentTags atomic.Value // holds []model.KeyValue
 	logger    *zap.Logger
 	sanitizer zipkin2.Sanitizer
 }
 
 // NewReporter creates gRPC reporter.
 func NewReporter(conn *grpc.ClientConn, agentTags map[string]string, logger *zap.Logger) *Reporter {
-	return &Reporter{
+	r := &Reporter{
 		collector: api_v2.NewCollectorServiceClient(conn),
-		agentTags: makeModelKeyValue(agentTags),
 		logger:    logger,
 		sanitizer: zipkin2.NewChainedSanitizer(zipkin2.StandardSanitizers...),
 	}
+	r.SetAgentTags(agentTags)
+	return r
+}
+
+// SetAgentTags replaces the tags added to the Process of the spans sent from now on.
+func (r *Reporter) SetAgentTags(agentTags map[string]string) {
+	r.agentTags.Store(makeModelKeyValue(agentTags))
 }
 
 // EmitBatch implements EmitBatch() of Reporter
@@ -66,7 +73,7 @@ func (r *Reporter) EmitZipkinBatch(ctx context.Context, zSpans []*zipkincore.Spa
 }
 
 func (r *Reporter) send(ctx context.Context, spans []*model.Span, process *model.Process) error {
-	spans, process = addProcessTags(spans, process, r.agentTags)
+	spans, process = addProcessTags(spans, process, r.agentTags.Load().([]model.KeyValue))
 	batch := model.Batch{Spans: spans, Process: process}
 	req := &api_v2.PostSpansRequest{Batch: batch}
 	_, err := r.collector.PostSpans(ctx, req)
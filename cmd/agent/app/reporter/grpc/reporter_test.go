@@ -163,6 +163,13 @@ func TestReporter_AddProcessTags_JaegerBatch(t *testing.T) {
 	assert.Equal(t, expectedProcess, actualProcess)
 }
 
+func TestReporter_SetAgentTags(t *testing.T) {
+	r := NewReporter(nil, map[string]string{"zone": "a"}, zap.NewNop())
+	assert.Equal(t, []model.KeyValue{model.String("zone", "a")}, r.agentTags.Load())
+	r.SetAgentTags(nil)
+	assert.Empty(t, r.agentTags.Load())
+}
+
 func TestReporter_MakeModelKeyValue(t *testing.T) {
 	expectedTags := []model.KeyValue{model.String("key", "value")}
 	stringTags := map[string]string{"key": "value"}
@@ -0,0 +1,166 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reporter
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+const (
+	metadataTagsPrefix        = "agent.metadata-tags"
+	metadataHostname          = metadataTagsPrefix + ".hostname"
+	metadataIP                = metadataTagsPrefix + ".ip"
+	metadataZone              = metadataTagsPrefix + ".zone"
+	metadataKubernetes        = metadataTagsPrefix + ".kubernetes"
+	metadataKubernetesLabels  = metadataTagsPrefix + ".kubernetes-labels-file"
+	kubernetesLabelTagPrefix  = "k8s.pod.label."
+	kubernetesNodeNameEnvVar  = "NODE_NAME"
+	kubernetesPodNameEnvVar   = "POD_NAME"
+	kubernetesNamespaceEnvVar = "POD_NAMESPACE"
+)
+
+// kubernetesTagsFromEnv maps the environment variables usually populated with the downward API to tags.
+var kubernetesTagsFromEnv = map[string]string{
+	kubernetesNodeNameEnvVar:  "k8s.node.name",
+	kubernetesPodNameEnvVar:   "k8s.pod.name",
+	kubernetesNamespaceEnvVar: "k8s.namespace.name",
+}
+
+// overridden in tests
+var (
+	hostname       = os.Hostname
+	interfaceAddrs = net.InterfaceAddrs
+)
+
+// MetadataTagsOptions selects the infrastructure metadata added to the Process tags of all spans.
+type MetadataTagsOptions struct {
+	Hostname bool   `yaml:"hostname"`
+	IP       bool   `yaml:"ip"`
+	Zone     string `yaml:"zone"`
+	// Kubernetes adds the node, pod and namespace names found in the NODE_NAME, POD_NAME and POD_NAMESPACE variables.
+	Kubernetes bool `yaml:"kubernetes"`
+	// KubernetesLabelsFile is the downward API file with the labels of the pod, e.g. /etc/podinfo/labels.
+	KubernetesLabelsFile string `yaml:"kubernetesLabelsFile"`
+}
+
+func addMetadataTagsFlags(flags *flag.FlagSet) {
+	flags.Bool(metadataHostname, false, "Add the host name of the agent as the 'hostname' tag")
+	flags.Bool(metadataIP, false, "Add the first non-loopback IP address of the agent as the 'ip' tag")
+	flags.String(metadataZone, "", "The availability zone of the agent, added as the 'zone' tag")
+	flags.Bool(metadataKubernetes, false, "Add the Kubernetes node, pod and namespace names as tags, read from the "+
+		kubernetesNodeNameEnvVar+", "+kubernetesPodNameEnvVar+" and "+kubernetesNamespaceEnvVar+" environment variables when set")
+	flags.String(metadataKubernetesLabels, "", "The downward API file with the pod labels, added as '"+kubernetesLabelTagPrefix+"<label>' tags")
+}
+
+func (o *MetadataTagsOptions) initFromViper(v *viper.Viper) {
+	o.Hostname = v.GetBool(metadataHostname)
+	o.IP = v.GetBool(metadataIP)
+	o.Zone = v.GetString(metadataZone)
+	o.Kubernetes = v.GetBool(metadataKubernetes)
+	o.KubernetesLabelsFile = v.GetString(metadataKubernetesLabels)
+}
+
+// Tags discovers the selected metadata. Metadata that cannot be discovered is skipped with a warning.
+func (o MetadataTagsOptions) Tags(logger *zap.Logger) map[string]string {
+	tags := make(map[string]string)
+	if o.Hostname {
+		if name, err := hostname(); err != nil {
+			logger.Warn("Cannot determine the host name", zap.Error(err))
+		} else {
+			tags["hostname"] = name
+		}
+	}
+	if o.IP {
+		if ip, err := firstNonLoopbackIP(); err != nil {
+			logger.Warn("Cannot determine the IP address", zap.Error(err))
+		} else if ip != "" {
+			tags["ip"] = ip
+		}
+	}
+	if o.Zone != "" {
+		tags["zone"] = o.Zone
+	}
+	if o.Kubernetes {
+		for env, tag := range kubernetesTagsFromEnv {
+			if value := os.Getenv(env); value != "" {
+				tags[tag] = value
+			}
+		}
+	}
+	if o.KubernetesLabelsFile != "" {
+		labels, err := readKubernetesLabels(o.KubernetesLabelsFile)
+		if err != nil {
+			logger.Warn("Cannot read the pod labels", zap.String("file", o.KubernetesLabelsFile), zap.Error(err))
+		}
+		for k, v := range labels {
+			tags[kubernetesLabelTagPrefix+k] = v
+		}
+	}
+	return tags
+}
+
+func firstNonLoopbackIP() (string, error) {
+	addrs, err := interfaceAddrs()
+	if err != nil {
+		return "", err
+	}
+	var ipv6 string
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			return ipNet.IP.String(), nil
+		}
+		if ipv6 == "" {
+			ipv6 = ipNet.IP.String()
+		}
+	}
+	return ipv6, nil
+}
+
+// readKubernetesLabels parses the key="value" lines written by the downward API.
+func readKubernetesLabels(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	labels := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		kv := strings.SplitN(strings.TrimSpace(scanner.Text()), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value, err := strconv.Unquote(kv[1])
+		if err != nil {
+			value = kv[1]
+		}
+		labels[kv[0]] = value
+	}
+	return labels, scanner.Err()
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reporter
+
+import (
+	"errors"
+	"flag"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func stubHost(name string, nameErr error, addrs []net.Addr, addrsErr error) func() {
+	oldHostname, oldInterfaceAddrs := hostname, interfaceAddrs
+	hostname = func() (string, error) { return name, nameErr }
+	interfaceAddrs = func() ([]net.Addr, error) { return addrs, addrsErr }
+	return func() {
+		hostname, interfaceAddrs = oldHostname, oldInterfaceAddrs
+	}
+}
+
+func ipNet(ip string) net.Addr {
+	return &net.IPNet{IP: net.ParseIP(ip), Mask: net.CIDRMask(24, 32)}
+}
+
+func TestMetadataTags(t *testing.T) {
+	defer stubHost("node-1", nil, []net.Addr{ipNet("127.0.0.1"), ipNet("fe80::1"), ipNet("2001:db8::1"), ipNet("10.0.0.7")}, nil)()
+	os.Setenv(kubernetesNodeNameEnvVar, "worker-3")
+	defer os.Unsetenv(kubernetesNodeNameEnvVar)
+	os.Setenv(kubernetesPodNameEnvVar, "hotrod-5d8f")
+	defer os.Unsetenv(kubernetesPodNameEnvVar)
+
+	dir, err := ioutil.TempDir("", "podinfo")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	labels := filepath.Join(dir, "labels")
+	require.NoError(t, ioutil.WriteFile(labels, []byte("app=\"hotrod\"\npod-template-hash=\"5d8f\"\ninvalid\n"), 0600))
+
+	tags := MetadataTagsOptions{
+		Hostname:             true,
+		IP:                   true,
+		Zone:                 "us-east-1a",
+		Kubernetes:           true,
+		KubernetesLabelsFile: labels,
+	}.Tags(zap.NewNop())
+	assert.Equal(t, map[string]string{
+		"hostname":                        "node-1",
+		"ip":                              "10.0.0.7",
+		"zone":                            "us-east-1a",
+		"k8s.node.name":                   "worker-3",
+		"k8s.pod.name":                    "hotrod-5d8f",
+		"k8s.pod.label.app":               "hotrod",
+		"k8s.pod.label.pod-template-hash": "5d8f",
+	}, tags)
+
+	assert.Empty(t, MetadataTagsOptions{}.Tags(zap.NewNop()))
+}
+
+func TestMetadataTagsIPv6Only(t *testing.T) {
+	defer stubHost("", nil, []net.Addr{ipNet("::1"), ipNet("2001:db8::1")}, nil)()
+	assert.Equal(t, map[string]string{"ip": "2001:db8::1"}, MetadataTagsOptions{IP: true}.Tags(zap.NewNop()))
+}
+
+func TestMetadataTagsErrors(t *testing.T) {
+	defer stubHost("", errors.New("no hostname"), nil, errors.New("no interfaces"))()
+	tags := MetadataTagsOptions{
+		Hostname:             true,
+		IP:                   true,
+		KubernetesLabelsFile: "/not/there",
+	}.Tags(zap.NewNop())
+	assert.Empty(t, tags)
+}
+
+func TestBindFlags_MetadataTags(t *testing.T) {
+	defer stubHost("node-1", nil, nil, nil)()
+	v := viper.New()
+	command := cobra.Command{}
+	flags := &flag.FlagSet{}
+	AddFlags(flags)
+	command.PersistentFlags().AddGoFlagSet(flags)
+	v.BindPFlags(command.PersistentFlags())
+
+	err := command.ParseFlags([]string{
+		"--agent.tags=zone=explicit",
+		"--agent.metadata-tags.hostname=true",
+		"--agent.metadata-tags.zone=us-east-1a",
+	})
+	require.NoError(t, err)
+
+	b := new(Options).InitFromViper(v, zap.NewNop())
+	assert.Equal(t, MetadataTagsOptions{Hostname: true, Zone: "us-east-1a"}, b.MetadataTags)
+	assert.Equal(t, map[string]string{"zone": "explicit", "hostname": "node-1"}, b.AgentTags)
+}
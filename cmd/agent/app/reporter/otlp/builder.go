@@ -0,0 +1,78 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	reportergrpc "github.com/jaegertracing/jaeger/cmd/agent/app/reporter/grpc"
+	"github.com/jaegertracing/jaeger/pkg/config/tlscfg"
+)
+
+// ConnBuilder Struct to hold configurations
+type ConnBuilder struct {
+	// Endpoint is the host:port of the OTLP gRPC receiver.
+	Endpoint string `yaml:"endpoint"`
+	// Headers are sent as gRPC metadata with every export request, e.g. for authentication.
+	Headers map[string]string `yaml:"headers"`
+	// Timeout bounds the duration of a single export request.
+	Timeout time.Duration `yaml:"timeout"`
+
+	TLS tlscfg.Options
+
+	tlsWatcher *tlscfg.ConfigWatcher
+}
+
+// NewConnBuilder creates a new ConnBuilder
+func NewConnBuilder() *ConnBuilder {
+	return &ConnBuilder{Timeout: defaultTimeout}
+}
+
+// CreateConnection creates the gRPC connection to the OTLP endpoint.
+func (b *ConnBuilder) CreateConnection(logger *zap.Logger) (*grpc.ClientConn, error) {
+	if b.Endpoint == "" {
+		return nil, errors.New("an OTLP endpoint is required, see --" + endpoint)
+	}
+	var dialOptions []grpc.DialOption
+	if b.TLS.Enabled {
+		watcher, err := b.TLS.NewConfigWatcher(logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS config: %w", err)
+		}
+		b.tlsWatcher = watcher
+		dialOptions = append(dialOptions, grpc.WithTransportCredentials(reportergrpc.NewReloadingCredentials(watcher)))
+	} else {
+		dialOptions = append(dialOptions, grpc.WithInsecure())
+	}
+	logger.Info("Agent is exporting spans to an OTLP endpoint", zap.String("endpoint", b.Endpoint))
+	conn, err := grpc.Dial(b.Endpoint, dialOptions...)
+	if err != nil {
+		b.release()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// release stops the reloading of the TLS files started by CreateConnection.
+func (b *ConnBuilder) release() {
+	if b.tlsWatcher != nil {
+		b.tlsWatcher.Close()
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/pkg/config/tlscfg"
+)
+
+func TestCreateConnection(t *testing.T) {
+	_, err := NewConnBuilder().CreateConnection(zap.NewNop())
+	assert.EqualError(t, err, "an OTLP endpoint is required, see --reporter.otlp.endpoint")
+
+	b := &ConnBuilder{Endpoint: "localhost:4317", TLS: tlscfg.Options{Enabled: true, CAPath: "/not/there"}}
+	_, err = b.CreateConnection(zap.NewNop())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to load TLS config")
+
+	conn, err := (&ConnBuilder{Endpoint: "localhost:4317"}).CreateConnection(zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:4317", conn.Target())
+	require.NoError(t, conn.Close())
+}
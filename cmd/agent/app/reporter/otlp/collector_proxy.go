@@ -0,0 +1,113 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"context"
+	"errors"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/jaegertracing/jaeger/cmd/agent/app/configmanager"
+	"github.com/jaegertracing/jaeger/cmd/agent/app/reporter"
+	"github.com/jaegertracing/jaeger/thrift-gen/baggage"
+	"github.com/jaegertracing/jaeger/thrift-gen/sampling"
+)
+
+var errNoConfigManager = errors.New("sampling strategies and baggage restrictions are not available from an OTLP endpoint")
+
+// ProxyBuilder holds objects communicating with the OTLP endpoint
+type ProxyBuilder struct {
+	reporter *reporter.ClientMetricsReporter
+	batching *reporter.BatchingReporter
+	manager  configmanager.ClientConfigManager
+	conn     *grpc.ClientConn
+	builder  *ConnBuilder
+}
+
+// NewCollectorProxy creates ProxyBuilder
+func NewCollectorProxy(builder *ConnBuilder, opts reporter.Options, mFactory metrics.Factory, logger *zap.Logger) (*ProxyBuilder, error) {
+	conn, err := builder.CreateConnection(logger)
+	if err != nil {
+		return nil, err
+	}
+	otlpMetrics := mFactory.Namespace(metrics.NSOptions{Name: "", Tags: map[string]string{"protocol": "otlp"}})
+	r1 := NewReporter(conn, builder, opts.AgentTags, logger)
+	var r2 reporter.Reporter = reporter.WrapWithMetrics(r1, otlpMetrics)
+	var batching *reporter.BatchingReporter
+	if opts.Batching.Enabled() {
+		batching = reporter.WrapWithBatching(reporter.BatchingReporterParams{
+			Reporter:       r2,
+			Options:        opts.Batching,
+			Logger:         logger,
+			MetricsFactory: mFactory,
+		})
+		r2 = batching
+	}
+	if opts.RateLimiting.Enabled() {
+		r2 = reporter.WrapWithRateLimiting(r2, opts.RateLimiting, mFactory)
+	}
+	r3 := reporter.WrapWithClientMetrics(reporter.ClientMetricsReporterParams{
+		Reporter:       r2,
+		Logger:         logger,
+		MetricsFactory: mFactory,
+	})
+	return &ProxyBuilder{
+		conn:     conn,
+		reporter: r3,
+		batching: batching,
+		manager:  configmanager.WrapWithMetrics(noConfigManager{}, otlpMetrics),
+		builder:  builder,
+	}, nil
+}
+
+// GetConn returns grpc conn
+func (b ProxyBuilder) GetConn() *grpc.ClientConn {
+	return b.conn
+}
+
+// GetReporter returns Reporter
+func (b ProxyBuilder) GetReporter() reporter.Reporter {
+	return b.reporter
+}
+
+// GetManager returns manager. OTLP has no equivalent of the collector's sampling
+// and baggage APIs, so the clients of the agent keep their local defaults.
+func (b ProxyBuilder) GetManager() configmanager.ClientConfigManager {
+	return b.manager
+}
+
+// Close closes connections used by proxy.
+func (b ProxyBuilder) Close() error {
+	defer b.builder.release()
+	b.reporter.Close()
+	if b.batching != nil {
+		// submit the pending spans before closing the connection
+		b.batching.Close()
+	}
+	return b.conn.Close()
+}
+
+type noConfigManager struct{}
+
+func (noConfigManager) GetSamplingStrategy(context.Context, string) (*sampling.SamplingStrategyResponse, error) {
+	return nil, errNoConfigManager
+}
+
+func (noConfigManager) GetBaggageRestrictions(context.Context, string) ([]*baggage.BaggageRestriction, error) {
+	return nil, errNoConfigManager
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/cmd/agent/app/reporter"
+	"github.com/jaegertracing/jaeger/thrift-gen/jaeger"
+)
+
+var _ io.Closer = (*ProxyBuilder)(nil)
+
+func TestCollectorProxy(t *testing.T) {
+	service := &mockTraceService{}
+	s, addr := initializeOTLPTestServer(t, service)
+	defer s.Stop()
+
+	mFactory := metricstest.NewFactory(time.Microsecond)
+	proxy, err := NewCollectorProxy(&ConnBuilder{Endpoint: addr.String()}, reporter.Options{}, mFactory, zap.NewNop())
+	require.NoError(t, err)
+	assert.NotNil(t, proxy.GetConn())
+
+	err = proxy.GetReporter().EmitBatch(context.Background(), &jaeger.Batch{Spans: []*jaeger.Span{{OperationName: "op"}}, Process: &jaeger.Process{ServiceName: "service"}})
+	require.NoError(t, err)
+	requests, _ := service.getRequests()
+	assert.Len(t, requests, 1)
+	mFactory.AssertCounterMetrics(t, metricstest.ExpectedMetric{
+		Name:  "reporter.batches.submitted",
+		Tags:  map[string]string{"format": "jaeger", "protocol": "otlp"},
+		Value: 1,
+	})
+
+	_, err = proxy.GetManager().GetSamplingStrategy(context.Background(), "service")
+	assert.Equal(t, errNoConfigManager, err)
+	_, err = proxy.GetManager().GetBaggageRestrictions(context.Background(), "service")
+	assert.Equal(t, errNoConfigManager, err)
+	mFactory.AssertCounterMetrics(t, metricstest.ExpectedMetric{
+		Name:  "collector-proxy",
+		Tags:  map[string]string{"endpoint": "sampling", "protocol": "otlp", "result": "err"},
+		Value: 1,
+	})
+	require.NoError(t, proxy.Close())
+}
+
+func TestCollectorProxyWithoutEndpoint(t *testing.T) {
+	proxy, err := NewCollectorProxy(NewConnBuilder(), reporter.Options{}, metricstest.NewFactory(time.Microsecond), zap.NewNop())
+	require.Error(t, err)
+	assert.Nil(t, proxy)
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"flag"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/jaegertracing/jaeger/cmd/flags"
+	"github.com/jaegertracing/jaeger/pkg/config/tlscfg"
+)
+
+const (
+	otlpPrefix     = "reporter.otlp"
+	endpoint       = otlpPrefix + ".endpoint"
+	headers        = otlpPrefix + ".headers"
+	timeout        = otlpPrefix + ".timeout"
+	defaultTimeout = 5 * time.Second
+)
+
+var tlsFlagsConfig = tlscfg.ClientFlagsConfig{
+	Prefix:         otlpPrefix,
+	ShowEnabled:    true,
+	ShowServerName: true,
+}
+
+// AddFlags adds flags for ConnBuilder.
+func AddFlags(flags *flag.FlagSet) {
+	flags.String(endpoint, "", "The host:port of the OTLP gRPC endpoint, e.g. an OpenTelemetry Collector, to which the spans are exported")
+	flags.String(headers, "", "One or more headers sent with every export request. Ex: key1=value1,key2=${envVar:defaultValue}")
+	flags.Duration(timeout, defaultTimeout, "The maximum time an export request may take")
+	tlsFlagsConfig.AddFlags(flags)
+}
+
+// InitFromViper initializes ConnBuilder with properties retrieved from Viper.
+func (b *ConnBuilder) InitFromViper(v *viper.Viper) *ConnBuilder {
+	b.Endpoint = v.GetString(endpoint)
+	b.Headers = flags.ParseJaegerTags(v.GetString(headers))
+	b.Timeout = v.GetDuration(timeout)
+	b.TLS = tlsFlagsConfig.InitFromViper(v)
+	return b
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	zipkin2 "github.com/jaegertracing/jaeger/cmd/collector/app/sanitizer/zipkin"
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/model/converter/otlp"
+	jConverter "github.com/jaegertracing/jaeger/model/converter/thrift/jaeger"
+	"github.com/jaegertracing/jaeger/model/converter/thrift/zipkin"
+	thrift "github.com/jaegertracing/jaeger/thrift-gen/jaeger"
+	"github.com/jaegertracing/jaeger/thrift-gen/zipkincore"
+)
+
+// Reporter exports spans to an OTLP gRPC endpoint.
+type Reporter struct {
+	client    otlp.TraceServiceClient
+	agentTags []model.KeyValue
+	headers   metadata.MD
+	timeout   time.Duration
+	logger    *zap.Logger
+	sanitizer zipkin2.Sanitizer
+}
+
+// NewReporter creates OTLP reporter.
+func NewReporter(conn *grpc.ClientConn, builder *ConnBuilder, agentTags map[string]string, logger *zap.Logger) *Reporter {
+	return &Reporter{
+		client:    otlp.NewTraceServiceClient(conn),
+		agentTags: makeModelKeyValue(agentTags),
+		headers:   metadata.New(builder.Headers),
+		timeout:   builder.Timeout,
+		logger:    logger,
+		sanitizer: zipkin2.NewChainedSanitizer(zipkin2.StandardSanitizers...),
+	}
+}
+
+// EmitBatch implements EmitBatch() of Reporter
+func (r *Reporter) EmitBatch(ctx context.Context, b *thrift.Batch) error {
+	return r.send(ctx, jConverter.ToDomain(b.Spans, nil), jConverter.ToDomainProcess(b.Process))
+}
+
+// EmitZipkinBatch implements EmitZipkinBatch() of Reporter
+func (r *Reporter) EmitZipkinBatch(ctx context.Context, zSpans []*zipkincore.Span) error {
+	for i := range zSpans {
+		zSpans[i] = r.sanitizer.Sanitize(zSpans[i])
+	}
+	trace, err := zipkin.ToDomain(zSpans)
+	if err != nil {
+		return err
+	}
+	return r.send(ctx, trace.Spans, nil)
+}
+
+func (r *Reporter) send(ctx context.Context, spans []*model.Span, process *model.Process) error {
+	if process != nil {
+		process.Tags = append(process.Tags, r.agentTags...)
+	}
+	for _, span := range spans {
+		if span.Process != nil {
+			span.Process.Tags = append(span.Process.Tags, r.agentTags...)
+		}
+	}
+	req := otlp.FromDomain([]*model.Batch{{Spans: spans, Process: process}})
+	if len(r.headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, r.headers)
+	}
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+	if _, err := r.client.Export(ctx, req); err != nil {
+		r.logger.Error("Could not export spans over OTLP", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func makeModelKeyValue(agentTags map[string]string) []model.KeyValue {
+	tags := make([]model.KeyValue, 0, len(agentTags))
+	for k, v := range agentTags {
+		tags = append(tags, model.String(k, v))
+	}
+	return tags
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/jaegertracing/jaeger/model/converter/otlp"
+	"github.com/jaegertracing/jaeger/thrift-gen/jaeger"
+	"github.com/jaegertracing/jaeger/thrift-gen/zipkincore"
+)
+
+type mockTraceService struct {
+	mux      sync.Mutex
+	requests []*otlp.ExportTraceServiceRequest
+	metadata []metadata.MD
+	err      error
+}
+
+func (h *mockTraceService) Export(ctx context.Context, r *otlp.ExportTraceServiceRequest) (*otlp.ExportTraceServiceResponse, error) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	md, _ := metadata.FromIncomingContext(ctx)
+	h.requests = append(h.requests, r)
+	h.metadata = append(h.metadata, md)
+	return &otlp.ExportTraceServiceResponse{}, h.err
+}
+
+func (h *mockTraceService) getRequests() ([]*otlp.ExportTraceServiceRequest, []metadata.MD) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	return h.requests, h.metadata
+}
+
+func initializeOTLPTestServer(t *testing.T, service *mockTraceService) (*grpc.Server, net.Addr) {
+	server := grpc.NewServer()
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	otlp.RegisterTraceServiceServer(server, service)
+	go func() {
+		require.NoError(t, server.Serve(lis))
+	}()
+	return server, lis.Addr()
+}
+
+func newTestReporter(t *testing.T, addr net.Addr, builder *ConnBuilder, agentTags map[string]string) (*Reporter, *grpc.ClientConn) {
+	builder.Endpoint = addr.String()
+	conn, err := builder.CreateConnection(zap.NewNop())
+	require.NoError(t, err)
+	return NewReporter(conn, builder, agentTags, zap.NewNop()), conn
+}
+
+func TestReporter_EmitBatch(t *testing.T) {
+	service := &mockTraceService{}
+	s, addr := initializeOTLPTestServer(t, service)
+	defer s.Stop()
+	rep, conn := newTestReporter(t, addr, &ConnBuilder{Headers: map[string]string{"api-key": "secret"}, Timeout: time.Second}, map[string]string{"agent": "a"})
+	defer conn.Close()
+
+	err := rep.EmitBatch(context.Background(), &jaeger.Batch{
+		Process: &jaeger.Process{ServiceName: "frontend"},
+		Spans:   []*jaeger.Span{{TraceIdLow: 1, SpanId: 2, OperationName: "GET /"}},
+	})
+	require.NoError(t, err)
+
+	requests, md := service.getRequests()
+	require.Len(t, requests, 1)
+	assert.Equal(t, []string{"secret"}, md[0].Get("api-key"))
+	rs := requests[0].ResourceSpans
+	require.Len(t, rs, 1)
+	assert.Equal(t, []*otlp.KeyValue{
+		{Key: "service.name", Value: &otlp.AnyValue{Value: &otlp.AnyValueString{StringValue: "frontend"}}},
+		{Key: "agent", Value: &otlp.AnyValue{Value: &otlp.AnyValueString{StringValue: "a"}}},
+	}, rs[0].Resource.Attributes)
+	require.Len(t, rs[0].ScopeSpans, 1)
+	require.Len(t, rs[0].ScopeSpans[0].Spans, 1)
+	assert.Equal(t, "GET /", rs[0].ScopeSpans[0].Spans[0].Name)
+}
+
+func TestReporter_EmitZipkinBatch(t *testing.T) {
+	service := &mockTraceService{}
+	s, addr := initializeOTLPTestServer(t, service)
+	defer s.Stop()
+	rep, conn := newTestReporter(t, addr, NewConnBuilder(), nil)
+	defer conn.Close()
+
+	err := rep.EmitZipkinBatch(context.Background(), []*zipkincore.Span{{
+		ID:          2,
+		TraceID:     1,
+		Name:        "op",
+		Annotations: []*zipkincore.Annotation{{Value: zipkincore.CLIENT_SEND, Host: &zipkincore.Endpoint{ServiceName: "spring"}}},
+	}})
+	require.NoError(t, err)
+
+	requests, md := service.getRequests()
+	require.Len(t, requests, 1)
+	assert.Empty(t, md[0].Get("api-key"))
+	rs := requests[0].ResourceSpans
+	require.Len(t, rs, 1)
+	assert.Equal(t, &otlp.AnyValue{Value: &otlp.AnyValueString{StringValue: "spring"}}, rs[0].Resource.Attributes[0].Value)
+	assert.Equal(t, otlp.SpanKindClient, rs[0].ScopeSpans[0].Spans[0].Kind)
+}
+
+func TestReporter_EmitErrors(t *testing.T) {
+	service := &mockTraceService{err: errors.New("unavailable")}
+	s, addr := initializeOTLPTestServer(t, service)
+	defer s.Stop()
+	rep, conn := newTestReporter(t, addr, NewConnBuilder(), nil)
+	defer conn.Close()
+
+	err := rep.EmitBatch(context.Background(), &jaeger.Batch{Process: &jaeger.Process{ServiceName: "svc"}, Spans: []*jaeger.Span{{}}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unavailable")
+
+	err = rep.EmitZipkinBatch(context.Background(), []*zipkincore.Span{{
+		BinaryAnnotations: []*zipkincore.BinaryAnnotation{{Key: "foo", AnnotationType: zipkincore.AnnotationType_BOOL, Value: []byte{1, 2}}},
+	}})
+	require.Error(t, err)
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reporter
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/jaegertracing/jaeger/thrift-gen/jaeger"
+	"github.com/jaegertracing/jaeger/thrift-gen/zipkincore"
+)
+
+// maxRateLimitedClients bounds the number of token buckets kept by the rate limiter,
+// the clients exceeding it share a single bucket.
+const maxRateLimitedClients = 10000
+
+// RateLimitingOptions configures the per-client limit of the spans forwarded by the agent.
+type RateLimitingOptions struct {
+	// SpansPerSecond is the rate at which the spans of each client process are forwarded.
+	// Rate limiting is disabled when it is not positive.
+	SpansPerSecond float64 `yaml:"spansPerSecond"`
+}
+
+// Enabled returns true if the spans of the clients must be rate limited.
+func (o RateLimitingOptions) Enabled() bool {
+	return o.SpansPerSecond > 0
+}
+
+type rateLimitingMetrics struct {
+	// Number of spans dropped because their client process exceeded the rate limit
+	DroppedSpans metrics.Counter `metric:"rate_limit.spans.dropped"`
+}
+
+// clientBucket allows bursts up to one second worth of spans.
+type clientBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimitingReporter is a decorator that forwards at most RateLimitingOptions.SpansPerSecond spans
+// of each client process, identified by the client-uuid process tag or by the service name,
+// so that a single process cannot use the whole capacity of the agent. Spans over the limit are dropped.
+type RateLimitingReporter struct {
+	reporter Reporter
+	rate     float64
+	capacity float64
+	metrics  rateLimitingMetrics
+
+	lock    sync.Mutex
+	buckets map[string]*clientBucket
+	timeNow func() time.Time
+}
+
+// WrapWithRateLimiting creates RateLimitingReporter.
+func WrapWithRateLimiting(r Reporter, opts RateLimitingOptions, mFactory metrics.Factory) *RateLimitingReporter {
+	rl := &RateLimitingReporter{
+		reporter: r,
+		rate:     opts.SpansPerSecond,
+		capacity: math.Max(opts.SpansPerSecond, 1),
+		buckets:  make(map[string]*clientBucket),
+		timeNow:  time.Now,
+	}
+	metrics.MustInit(&rl.metrics, mFactory.Namespace(metrics.NSOptions{Name: "reporter"}), nil)
+	return rl
+}
+
+// EmitZipkinBatch forwards the spans within the limit of the service of the first span.
+func (r *RateLimitingReporter) EmitZipkinBatch(ctx context.Context, spans []*zipkincore.Span) error {
+	if len(spans) == 0 {
+		return r.reporter.EmitZipkinBatch(ctx, spans)
+	}
+	n := r.take(zipkinServiceName(spans[0]), len(spans))
+	if n == 0 {
+		return nil
+	}
+	return r.reporter.EmitZipkinBatch(ctx, spans[:n])
+}
+
+// EmitBatch forwards the spans within the limit of the process of the batch.
+func (r *RateLimitingReporter) EmitBatch(ctx context.Context, batch *jaeger.Batch) error {
+	if len(batch.Spans) == 0 {
+		return r.reporter.EmitBatch(ctx, batch)
+	}
+	key := clientUUID(batch)
+	if key == "" && batch.Process != nil {
+		key = batch.Process.ServiceName
+	}
+	n := r.take(key, len(batch.Spans))
+	if n == 0 {
+		return nil
+	}
+	if n < len(batch.Spans) {
+		batch = &jaeger.Batch{Process: batch.Process, Spans: batch.Spans[:n], SeqNo: batch.SeqNo, Stats: batch.Stats}
+	}
+	return r.reporter.EmitBatch(ctx, batch)
+}
+
+// take deducts up to n spans from the bucket of the client and returns how many were deducted.
+func (r *RateLimitingReporter) take(key string, n int) int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	now := r.timeNow()
+	b := r.bucketLocked(key, now)
+	b.tokens = math.Min(r.capacity, b.tokens+now.Sub(b.last).Seconds()*r.rate)
+	b.last = now
+	allowed := n
+	if float64(n) > b.tokens {
+		allowed = int(b.tokens)
+		r.metrics.DroppedSpans.Inc(int64(n - allowed))
+	}
+	b.tokens -= float64(allowed)
+	return allowed
+}
+
+func (r *RateLimitingReporter) bucketLocked(key string, now time.Time) *clientBucket {
+	if b, ok := r.buckets[key]; ok {
+		return b
+	}
+	if len(r.buckets) >= maxRateLimitedClients {
+		// a bucket refilled to capacity holds no state, it is recreated on the next batch of its client
+		for k, b := range r.buckets {
+			if b.tokens+now.Sub(b.last).Seconds()*r.rate >= r.capacity {
+				delete(r.buckets, k)
+			}
+		}
+		if len(r.buckets) >= maxRateLimitedClients {
+			key = ""
+			if b, ok := r.buckets[key]; ok {
+				return b
+			}
+		}
+	}
+	b := &clientBucket{tokens: r.capacity, last: now}
+	r.buckets[key] = b
+	return b
+}
+
+func zipkinServiceName(span *zipkincore.Span) string {
+	for _, a := range span.Annotations {
+		if a.Host != nil && a.Host.ServiceName != "" {
+			return a.Host.ServiceName
+		}
+	}
+	for _, a := range span.BinaryAnnotations {
+		if a.Host != nil && a.Host.ServiceName != "" {
+			return a.Host.ServiceName
+		}
+	}
+	return ""
+}
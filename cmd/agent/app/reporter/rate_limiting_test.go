@@ -0,0 +1,111 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reporter
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+
+	"github.com/jaegertracing/jaeger/cmd/agent/app/testutils"
+	"github.com/jaegertracing/jaeger/thrift-gen/jaeger"
+	"github.com/jaegertracing/jaeger/thrift-gen/zipkincore"
+)
+
+func newTestRateLimitingReporter(spansPerSecond float64) (*RateLimitingReporter, *testutils.InMemoryReporter, *metricstest.Factory, *time.Time) {
+	inMemory := testutils.NewInMemoryReporter()
+	mFactory := metricstest.NewFactory(time.Microsecond)
+	r := WrapWithRateLimiting(inMemory, RateLimitingOptions{SpansPerSecond: spansPerSecond}, mFactory)
+	now := time.Unix(1000, 0)
+	r.timeNow = func() time.Time { return now }
+	return r, inMemory, mFactory, &now
+}
+
+func spansOf(n int) []*jaeger.Span {
+	spans := make([]*jaeger.Span, n)
+	for i := range spans {
+		spans[i] = &jaeger.Span{OperationName: strconv.Itoa(i)}
+	}
+	return spans
+}
+
+func TestRateLimitingOptionsEnabled(t *testing.T) {
+	assert.False(t, RateLimitingOptions{}.Enabled())
+	assert.True(t, RateLimitingOptions{SpansPerSecond: 0.5}.Enabled())
+}
+
+func TestRateLimitingReporter_PerClient(t *testing.T) {
+	r, inMemory, mFactory, now := newTestRateLimitingReporter(10)
+	uuid := "client-uuid"
+	noisy := &jaeger.Process{ServiceName: "svc", Tags: []*jaeger.Tag{{Key: "client-uuid", VType: jaeger.TagType_STRING, VStr: &uuid}}}
+	quiet := &jaeger.Process{ServiceName: "svc"}
+
+	require.NoError(t, r.EmitBatch(context.Background(), &jaeger.Batch{Process: noisy, Spans: spansOf(8)}))
+	require.NoError(t, r.EmitBatch(context.Background(), &jaeger.Batch{Process: noisy, Spans: spansOf(8)}))
+	require.NoError(t, r.EmitBatch(context.Background(), &jaeger.Batch{Process: noisy, Spans: spansOf(8)}))
+	// the process without client-uuid is limited by its service name, independently of the noisy client
+	require.NoError(t, r.EmitBatch(context.Background(), &jaeger.Batch{Process: quiet, Spans: spansOf(5)}))
+
+	assert.Len(t, inMemory.Spans(), 15)
+	mFactory.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "reporter.rate_limit.spans.dropped", Value: 14})
+
+	*now = now.Add(500 * time.Millisecond)
+	require.NoError(t, r.EmitBatch(context.Background(), &jaeger.Batch{Process: noisy, Spans: spansOf(8)}))
+	assert.Len(t, inMemory.Spans(), 20)
+	mFactory.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "reporter.rate_limit.spans.dropped", Value: 17})
+
+	// the bucket never holds more than one second worth of spans
+	*now = now.Add(time.Hour)
+	require.NoError(t, r.EmitBatch(context.Background(), &jaeger.Batch{Process: noisy, Spans: spansOf(20)}))
+	assert.Len(t, inMemory.Spans(), 30)
+}
+
+func TestRateLimitingReporter_Zipkin(t *testing.T) {
+	r, inMemory, mFactory, _ := newTestRateLimitingReporter(0.5)
+	span := &zipkincore.Span{Annotations: []*zipkincore.Annotation{{Host: &zipkincore.Endpoint{ServiceName: "frontend"}}}}
+	other := &zipkincore.Span{BinaryAnnotations: []*zipkincore.BinaryAnnotation{{Host: &zipkincore.Endpoint{ServiceName: "backend"}}}}
+
+	require.NoError(t, r.EmitZipkinBatch(context.Background(), []*zipkincore.Span{span, span}))
+	require.NoError(t, r.EmitZipkinBatch(context.Background(), []*zipkincore.Span{span}))
+	require.NoError(t, r.EmitZipkinBatch(context.Background(), []*zipkincore.Span{other}))
+	require.NoError(t, r.EmitZipkinBatch(context.Background(), nil))
+
+	assert.Len(t, inMemory.ZipkinSpans(), 2)
+	mFactory.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "reporter.rate_limit.spans.dropped", Value: 2})
+}
+
+func TestRateLimitingReporter_MaxClients(t *testing.T) {
+	r, inMemory, _, now := newTestRateLimitingReporter(1)
+	for i := 0; i < maxRateLimitedClients; i++ {
+		require.NoError(t, r.EmitBatch(context.Background(), &jaeger.Batch{Process: &jaeger.Process{ServiceName: strconv.Itoa(i)}, Spans: spansOf(1)}))
+	}
+	assert.Len(t, r.buckets, maxRateLimitedClients)
+
+	// the new clients share a bucket while all the others are busy
+	require.NoError(t, r.EmitBatch(context.Background(), &jaeger.Batch{Process: &jaeger.Process{ServiceName: "new1"}, Spans: spansOf(1)}))
+	require.NoError(t, r.EmitBatch(context.Background(), &jaeger.Batch{Process: &jaeger.Process{ServiceName: "new2"}, Spans: spansOf(1)}))
+	assert.Len(t, inMemory.Spans(), maxRateLimitedClients+1)
+
+	// idle buckets are released
+	*now = now.Add(time.Second)
+	require.NoError(t, r.EmitBatch(context.Background(), &jaeger.Batch{Process: &jaeger.Process{ServiceName: "new2"}, Spans: spansOf(1)}))
+	assert.Len(t, r.buckets, 1)
+	assert.Len(t, inMemory.Spans(), maxRateLimitedClients+2)
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/jaegertracing/jaeger/thrift-gen/jaeger"
+	"github.com/jaegertracing/jaeger/thrift-gen/zipkincore"
+)
+
+const (
+	// maxTrafficServices bounds the cardinality of the per-service traffic metrics and summary
+	maxTrafficServices = 100
+	otherServices      = "other"
+)
+
+// ServiceTraffic is the traffic received from the clients of a service.
+type ServiceTraffic struct {
+	Service string `json:"service"`
+	Batches int64  `json:"batches"`
+	Spans   int64  `json:"spans"`
+	// Bytes is the size of the spans encoded with the Thrift compact protocol
+	Bytes int64 `json:"bytes"`
+}
+
+// TrafficSummary is the traffic per service since the agent started.
+type TrafficSummary struct {
+	Since    time.Time        `json:"since"`
+	Services []ServiceTraffic `json:"services"`
+}
+
+type serviceTraffic struct {
+	ServiceTraffic
+	batches metrics.Counter
+	spans   metrics.Counter
+	bytes   metrics.Counter
+}
+
+// TrafficReporter is a decorator that accounts the batches, spans and bytes received from each service,
+// so that node owners can attribute the resource usage of the agent. It serves the summary over HTTP.
+type TrafficReporter struct {
+	reporter Reporter
+	mFactory metrics.Factory
+	protocol thrift.TProtocolFactory
+	since    time.Time
+
+	lock     sync.Mutex
+	services map[string]*serviceTraffic
+}
+
+// WrapWithTraffic creates TrafficReporter.
+func WrapWithTraffic(r Reporter, mFactory metrics.Factory) *TrafficReporter {
+	return &TrafficReporter{
+		reporter: r,
+		mFactory: mFactory.Namespace(metrics.NSOptions{Name: "reporter"}),
+		protocol: thrift.NewTCompactProtocolFactory(),
+		since:    time.Now(),
+		services: make(map[string]*serviceTraffic),
+	}
+}
+
+// EmitZipkinBatch accounts the spans of each service of the batch and delegates to the underlying Reporter.
+func (r *TrafficReporter) EmitZipkinBatch(ctx context.Context, spans []*zipkincore.Span) error {
+	type traffic struct{ spans, bytes int64 }
+	perService := make(map[string]*traffic)
+	for _, span := range spans {
+		service := zipkinServiceName(span)
+		t, ok := perService[service]
+		if !ok {
+			t = &traffic{}
+			perService[service] = t
+		}
+		t.spans++
+		t.bytes += r.thriftSize(span)
+	}
+	for service, t := range perService {
+		r.record(service, t.spans, t.bytes)
+	}
+	return r.reporter.EmitZipkinBatch(ctx, spans)
+}
+
+// EmitBatch accounts the spans of the process of the batch and delegates to the underlying Reporter.
+func (r *TrafficReporter) EmitBatch(ctx context.Context, batch *jaeger.Batch) error {
+	var service string
+	if batch.Process != nil {
+		service = batch.Process.ServiceName
+	}
+	r.record(service, int64(len(batch.Spans)), r.thriftSize(batch))
+	return r.reporter.EmitBatch(ctx, batch)
+}
+
+func (r *TrafficReporter) record(service string, spans, bytes int64) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	t, ok := r.services[service]
+	if !ok {
+		if len(r.services) >= maxTrafficServices {
+			service = otherServices
+			t, ok = r.services[service]
+		}
+		if !ok {
+			t = r.newServiceTraffic(service)
+			r.services[service] = t
+		}
+	}
+	t.Batches++
+	t.Spans += spans
+	t.Bytes += bytes
+	t.batches.Inc(1)
+	t.spans.Inc(spans)
+	t.bytes.Inc(bytes)
+}
+
+func (r *TrafficReporter) newServiceTraffic(service string) *serviceTraffic {
+	tags := map[string]string{"service": service}
+	return &serviceTraffic{
+		ServiceTraffic: ServiceTraffic{Service: service},
+		batches:        r.mFactory.Counter(metrics.Options{Name: "traffic.batches", Tags: tags}),
+		spans:          r.mFactory.Counter(metrics.Options{Name: "traffic.spans", Tags: tags}),
+		bytes:          r.mFactory.Counter(metrics.Options{Name: "traffic.bytes", Tags: tags}),
+	}
+}
+
+// Summary returns the traffic of the services, largest first.
+func (r *TrafficReporter) Summary() TrafficSummary {
+	r.lock.Lock()
+	services := make([]ServiceTraffic, 0, len(r.services))
+	for _, t := range r.services {
+		services = append(services, t.ServiceTraffic)
+	}
+	r.lock.Unlock()
+	sort.Slice(services, func(i, j int) bool {
+		if services[i].Bytes != services[j].Bytes {
+			return services[i].Bytes > services[j].Bytes
+		}
+		return services[i].Service < services[j].Service
+	})
+	return TrafficSummary{Since: r.since, Services: services}
+}
+
+// ServeHTTP writes the summary as JSON.
+func (r *TrafficReporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(r.Summary())
+}
+
+func (r *TrafficReporter) thriftSize(s thrift.TStruct) int64 {
+	buf := thrift.NewTMemoryBuffer()
+	if err := s.Write(r.protocol.GetProtocol(buf)); err != nil {
+		return 0
+	}
+	return int64(buf.Len())
+}
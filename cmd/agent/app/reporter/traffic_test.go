@@ -0,0 +1,80 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+
+	"github.com/jaegertracing/jaeger/cmd/agent/app/testutils"
+	"github.com/jaegertracing/jaeger/thrift-gen/jaeger"
+	"github.com/jaegertracing/jaeger/thrift-gen/zipkincore"
+)
+
+func TestTrafficReporter(t *testing.T) {
+	inMemory := testutils.NewInMemoryReporter()
+	mFactory := metricstest.NewFactory(time.Microsecond)
+	r := WrapWithTraffic(inMemory, mFactory)
+
+	batch := &jaeger.Batch{Process: &jaeger.Process{ServiceName: "frontend"}, Spans: spansOf(3)}
+	require.NoError(t, r.EmitBatch(context.Background(), batch))
+	require.NoError(t, r.EmitBatch(context.Background(), batch))
+	frontend := &zipkincore.Span{Annotations: []*zipkincore.Annotation{{Host: &zipkincore.Endpoint{ServiceName: "frontend"}}}}
+	backend := &zipkincore.Span{Annotations: []*zipkincore.Annotation{{Host: &zipkincore.Endpoint{ServiceName: "backend"}}}}
+	require.NoError(t, r.EmitZipkinBatch(context.Background(), []*zipkincore.Span{frontend, backend, backend}))
+	assert.Len(t, inMemory.Spans(), 6)
+	assert.Len(t, inMemory.ZipkinSpans(), 3)
+
+	batchSize := r.thriftSize(batch)
+	frontendSize, backendSize := r.thriftSize(frontend), r.thriftSize(backend)
+	summary := r.Summary()
+	assert.Equal(t, []ServiceTraffic{
+		{Service: "frontend", Batches: 3, Spans: 7, Bytes: 2*batchSize + frontendSize},
+		{Service: "backend", Batches: 1, Spans: 2, Bytes: 2 * backendSize},
+	}, summary.Services)
+	mFactory.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "reporter.traffic.batches", Tags: map[string]string{"service": "frontend"}, Value: 3},
+		metricstest.ExpectedMetric{Name: "reporter.traffic.spans", Tags: map[string]string{"service": "frontend"}, Value: 7},
+		metricstest.ExpectedMetric{Name: "reporter.traffic.bytes", Tags: map[string]string{"service": "backend"}, Value: int(2 * backendSize)},
+	)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/traffic", nil))
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var served TrafficSummary
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &served))
+	assert.Equal(t, summary.Services, served.Services)
+	assert.True(t, summary.Since.Equal(served.Since))
+}
+
+func TestTrafficReporter_MaxServices(t *testing.T) {
+	r := WrapWithTraffic(testutils.NewInMemoryReporter(), metricstest.NewFactory(time.Microsecond))
+	for i := 0; i < maxTrafficServices+2; i++ {
+		require.NoError(t, r.EmitBatch(context.Background(), &jaeger.Batch{Process: &jaeger.Process{ServiceName: strconv.Itoa(i)}, Spans: spansOf(1)}))
+	}
+	services := r.Summary().Services
+	assert.Len(t, services, maxTrafficServices+1)
+	assert.Equal(t, otherServices, services[0].Service)
+	assert.EqualValues(t, 2, services[0].Batches)
+	assert.EqualValues(t, 2, services[0].Spans)
+}
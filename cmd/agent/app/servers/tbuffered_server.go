@@ -18,11 +18,24 @@ package servers
 import (
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
 	"github.com/uber/jaeger-lib/metrics"
 )
 
+const (
+	kernelDropsInterval = 10 * time.Second
+	// maxDropServices bounds the cardinality of the per-service dropped packets counters
+	maxDropServices = 100
+	otherServices   = "other"
+)
+
+// kernelDropsReader is implemented by transports able to report the packets dropped by the kernel.
+type kernelDropsReader interface {
+	KernelDrops() (uint64, error)
+}
+
 // TBufferedServer is a custom thrift server that reads traffic using the transport provided
 // and places messages into a buffered channel to be processed by the processor provided
 type TBufferedServer struct {
@@ -35,7 +48,14 @@ type TBufferedServer struct {
 	serving       uint32
 	transport     thrift.TTransport
 	readBufPool   *sync.Pool
-	metrics       struct {
+	mFactory      metrics.Factory
+	stopped       chan struct{}
+
+	classifier      func(payload []byte) string
+	dropsLock       sync.Mutex
+	dropsPerService map[string]metrics.Counter
+
+	metrics struct {
 		// Size of the current server queue
 		QueueSize metrics.Gauge `metric:"thrift.udp.server.queue_size"`
 
@@ -45,6 +65,9 @@ type TBufferedServer struct {
 		// Number of packets dropped by server
 		PacketsDropped metrics.Counter `metric:"thrift.udp.server.packets.dropped"`
 
+		// Number of packets dropped by the kernel before the server could read them
+		KernelPacketsDropped metrics.Counter `metric:"thrift.udp.server.kernel.packets.dropped"`
+
 		// Number of packets processed by server
 		PacketsProcessed metrics.Counter `metric:"thrift.udp.server.packets.processed"`
 
@@ -73,14 +96,26 @@ func NewTBufferedServer(
 		maxQueueSize:  maxQueueSize,
 		maxPacketSize: maxPacketSize,
 		readBufPool:   readBufPool,
+		mFactory:      mFactory,
+		stopped:       make(chan struct{}),
 	}
 	metrics.Init(&res.metrics, mFactory, nil)
 	return res, nil
 }
 
+// SetDroppedPacketClassifier makes the server count the packets it drops per emitting service,
+// as returned by classifier, to show which clients overrun the agent. It must be called before Serve.
+func (s *TBufferedServer) SetDroppedPacketClassifier(classifier func(payload []byte) string) {
+	s.classifier = classifier
+	s.dropsPerService = make(map[string]metrics.Counter)
+}
+
 // Serve initiates the readers and starts serving traffic
 func (s *TBufferedServer) Serve() {
 	atomic.StoreUint32(&s.serving, 1)
+	if reader, ok := s.transport.(kernelDropsReader); ok {
+		go s.reportKernelDrops(reader, kernelDropsInterval)
+	}
 	for s.IsServing() {
 		readBuf := s.readBufPool.Get().(*ReadBuf)
 		n, err := s.transport.Read(readBuf.bytes)
@@ -93,6 +128,9 @@ func (s *TBufferedServer) Serve() {
 				s.updateQueueSize(1)
 			default:
 				s.metrics.PacketsDropped.Inc(1)
+				if s.classifier != nil {
+					s.serviceDropsCounter(s.classifier(readBuf.GetBytes())).Inc(1)
+				}
 			}
 		} else {
 			s.metrics.ReadError.Inc(1)
@@ -100,6 +138,49 @@ func (s *TBufferedServer) Serve() {
 	}
 }
 
+func (s *TBufferedServer) serviceDropsCounter(service string) metrics.Counter {
+	s.dropsLock.Lock()
+	defer s.dropsLock.Unlock()
+	if c, ok := s.dropsPerService[service]; ok {
+		return c
+	}
+	if len(s.dropsPerService) >= maxDropServices {
+		service = otherServices
+		if c, ok := s.dropsPerService[service]; ok {
+			return c
+		}
+	}
+	c := s.mFactory.Counter(metrics.Options{
+		Name: "thrift.udp.server.client.packets.dropped",
+		Tags: map[string]string{"service": service},
+	})
+	s.dropsPerService[service] = c
+	return c
+}
+
+// reportKernelDrops periodically adds the packets dropped by the kernel since the last check to the counter.
+func (s *TBufferedServer) reportKernelDrops(reader kernelDropsReader, interval time.Duration) {
+	var last uint64
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			drops, err := reader.KernelDrops()
+			if err != nil {
+				// the kernel does not expose the drops of this socket, e.g. not on Linux
+				return
+			}
+			if drops > last {
+				s.metrics.KernelPacketsDropped.Inc(int64(drops - last))
+			}
+			last = drops
+		case <-s.stopped:
+			return
+		}
+	}
+}
+
 func (s *TBufferedServer) updateQueueSize(delta int64) {
 	atomic.AddInt64(&s.queueSize, delta)
 	s.metrics.QueueSize.Update(atomic.LoadInt64(&s.queueSize))
@@ -114,6 +195,7 @@ func (s *TBufferedServer) IsServing() bool {
 // emptied by the readers
 func (s *TBufferedServer) Stop() {
 	atomic.StoreUint32(&s.serving, 0)
+	close(s.stopped)
 	s.transport.Close()
 	close(s.dataChan)
 }
@@ -17,6 +17,8 @@ package servers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -50,6 +52,7 @@ func testTBufferedServer(t *testing.T, queueSize int, testDroppedPackets bool) {
 	maxPacketSize := 65000
 	server, err := NewTBufferedServer(transport, queueSize, maxPacketSize, metricsFactory)
 	require.NoError(t, err)
+	server.SetDroppedPacketClassifier(func(payload []byte) string { return "frontend" })
 	go server.Serve()
 	defer server.Stop()
 	time.Sleep(10 * time.Millisecond) // wait for server to start serving
@@ -74,6 +77,7 @@ func testTBufferedServer(t *testing.T, queueSize int, testDroppedPackets bool) {
 		for i := 0; i < 50; i++ {
 			c, _ := metricsFactory.Snapshot()
 			if c["thrift.udp.server.packets.dropped"] == 1 {
+				assert.EqualValues(t, 1, c["thrift.udp.server.client.packets.dropped|service=frontend"])
 				return
 			}
 			time.Sleep(time.Millisecond)
@@ -110,3 +114,49 @@ func testTBufferedServer(t *testing.T, queueSize int, testDroppedPackets bool) {
 		metricstest.ExpectedMetric{Name: "thrift.udp.server.queue_size", Value: 0},
 	)
 }
+
+type kernelDropsResult struct {
+	drops uint64
+	err   error
+}
+
+type fakeKernelDrops chan kernelDropsResult
+
+func (f fakeKernelDrops) KernelDrops() (uint64, error) {
+	r := <-f
+	return r.drops, r.err
+}
+
+func TestTBufferedServerKernelDrops(t *testing.T) {
+	metricsFactory := metricstest.NewFactory(0)
+	server, err := NewTBufferedServer(nil, 1, 10, metricsFactory)
+	require.NoError(t, err)
+	reader := make(fakeKernelDrops)
+	done := make(chan struct{})
+	go func() {
+		server.reportKernelDrops(reader, time.Millisecond)
+		close(done)
+	}()
+	reader <- kernelDropsResult{drops: 3}
+	reader <- kernelDropsResult{drops: 5}
+	reader <- kernelDropsResult{drops: 5}
+	// the loop stops once the kernel cannot report the drops
+	reader <- kernelDropsResult{err: errors.New("unsupported")}
+	<-done
+	metricsFactory.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "thrift.udp.server.kernel.packets.dropped", Value: 5})
+}
+
+func TestTBufferedServerDropsPerServiceCardinality(t *testing.T) {
+	metricsFactory := metricstest.NewFactory(0)
+	server, err := NewTBufferedServer(nil, 1, 10, metricsFactory)
+	require.NoError(t, err)
+	server.SetDroppedPacketClassifier(func(payload []byte) string { return string(payload) })
+	for i := 0; i < maxDropServices+2; i++ {
+		server.serviceDropsCounter(fmt.Sprintf("service-%d", i)).Inc(1)
+	}
+	server.serviceDropsCounter("service-0").Inc(1)
+	c, _ := metricsFactory.Snapshot()
+	assert.EqualValues(t, 2, c["thrift.udp.server.client.packets.dropped|service=service-0"])
+	assert.EqualValues(t, 2, c["thrift.udp.server.client.packets.dropped|service=other"])
+	assert.Len(t, server.dropsPerService, maxDropServices+1)
+}
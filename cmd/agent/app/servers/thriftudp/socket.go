@@ -0,0 +1,56 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package thriftudp
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// SetSocketBufferSize asks the kernel for a receive buffer of the given size and returns the size
+// actually granted, which may be lower, e.g. when capped by net.core.rmem_max on Linux.
+func (p *TUDPTransport) SetSocketBufferSize(size int) (int, error) {
+	if err := p.conn.SetReadBuffer(size); err != nil {
+		return 0, err
+	}
+	return p.socketBufferSize(size)
+}
+
+// KernelDrops returns the number of packets dropped by the kernel since the socket was opened,
+// usually because its receive buffer was full.
+func (p *TUDPTransport) KernelDrops() (uint64, error) {
+	return p.kernelDrops()
+}
+
+// parseProcNetUDPDrops finds the drops counter of the socket with the given inode in the
+// content of /proc/net/udp or /proc/net/udp6.
+func parseProcNetUDPDrops(data []byte, inode uint64) (uint64, bool) {
+	const (
+		inodeField = 9
+		dropsField = 12
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) <= dropsField || fields[inodeField] != strconv.FormatUint(inode, 10) {
+			continue
+		}
+		drops, err := strconv.ParseUint(fields[dropsField], 10, 64)
+		return drops, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package thriftudp
+
+import (
+	"fmt"
+	"io/ioutil"
+	"syscall"
+)
+
+// socketBufferSize reads back SO_RCVBUF, which Linux reports doubled to account for its bookkeeping overhead.
+func (p *TUDPTransport) socketBufferSize(int) (int, error) {
+	var size int
+	err := p.control(func(fd int) error {
+		var err error
+		size, err = syscall.GetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_RCVBUF)
+		return err
+	})
+	return size / 2, err
+}
+
+func (p *TUDPTransport) kernelDrops() (uint64, error) {
+	var stat syscall.Stat_t
+	if err := p.control(func(fd int) error {
+		return syscall.Fstat(fd, &stat)
+	}); err != nil {
+		return 0, err
+	}
+	for _, file := range []string{"/proc/net/udp", "/proc/net/udp6"} {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return 0, err
+		}
+		if drops, ok := parseProcNetUDPDrops(data, stat.Ino); ok {
+			return drops, nil
+		}
+	}
+	return 0, fmt.Errorf("socket inode %d not found in /proc/net/udp", stat.Ino)
+}
+
+func (p *TUDPTransport) control(f func(fd int) error) error {
+	rawConn, err := p.conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var ferr error
+	if err := rawConn.Control(func(fd uintptr) {
+		ferr = f(int(fd))
+	}); err != nil {
+		return err
+	}
+	return ferr
+}
@@ -0,0 +1,30 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !linux
+
+package thriftudp
+
+import "errors"
+
+var errKernelDropsUnsupported = errors.New("kernel UDP drops are only available on Linux")
+
+// socketBufferSize cannot read back the size granted by the kernel, and assumes the requested one.
+func (p *TUDPTransport) socketBufferSize(requested int) (int, error) {
+	return requested, nil
+}
+
+func (p *TUDPTransport) kernelDrops() (uint64, error) {
+	return 0, errKernelDropsUnsupported
+}
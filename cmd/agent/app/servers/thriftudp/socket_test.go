@@ -0,0 +1,54 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package thriftudp
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProcNetUDPDrops(t *testing.T) {
+	data := []byte(`   sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  121: 00000000:1A91 00000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 48213 2 0000000000000000 0
+  122: 0100007F:1A92 00000000:0000 07 00000000:00000D00 00:00000000 00000000     0        0 48214 2 0000000000000000 17
+`)
+	drops, ok := parseProcNetUDPDrops(data, 48214)
+	assert.True(t, ok)
+	assert.EqualValues(t, 17, drops)
+
+	_, ok = parseProcNetUDPDrops(data, 1)
+	assert.False(t, ok)
+}
+
+func TestSocketBufferSizeAndKernelDrops(t *testing.T) {
+	transport, err := NewTUDPServerTransport("127.0.0.1:0")
+	require.NoError(t, err)
+	defer transport.Close()
+
+	size, err := transport.SetSocketBufferSize(64 * 1024)
+	require.NoError(t, err)
+	assert.True(t, size > 0)
+
+	drops, err := transport.KernelDrops()
+	if runtime.GOOS != "linux" {
+		assert.Error(t, err)
+		return
+	}
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, drops)
+}
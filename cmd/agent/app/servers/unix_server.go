@@ -0,0 +1,220 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servers
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/uber/jaeger-lib/metrics"
+)
+
+// UnixServer receives Thrift messages from clients connected to a unix stream socket and places them
+// into a buffered channel to be processed by the processor provided. Each message is preceded by its
+// length as a 4-byte big-endian integer, as written by thrift.TFramedTransport. Unlike TBufferedServer,
+// messages are never dropped: the server stops reading from the clients while the queue is full,
+// which blocks their writes once the socket buffers are full.
+type UnixServer struct {
+	// NB. queueLength HAS to be at the top of the struct or it will SIGSEV for certain architectures.
+	// See https://github.com/golang/go/issues/13868
+	queueSize     int64
+	connections   int64
+	dataChan      chan *ReadBuf
+	maxPacketSize int
+	serving       uint32
+	listener      net.Listener
+	readBufPool   *sync.Pool
+	stopped       chan struct{}
+
+	lock     sync.Mutex
+	conns    map[net.Conn]struct{}
+	handlers sync.WaitGroup
+
+	metrics struct {
+		// Size of the current server queue
+		QueueSize metrics.Gauge `metric:"thrift.unix.server.queue_size"`
+
+		// Number of connected clients
+		Connections metrics.Gauge `metric:"thrift.unix.server.connections"`
+
+		// Size (in bytes) of messages received by server
+		MessageSize metrics.Gauge `metric:"thrift.unix.server.message_size"`
+
+		// Number of messages processed by server
+		MessagesProcessed metrics.Counter `metric:"thrift.unix.server.messages.processed"`
+
+		// Number of connections closed because of a malformed or too large message
+		ReadError metrics.Counter `metric:"thrift.unix.server.read.errors"`
+	}
+}
+
+// NewUnixServer creates a UnixServer listening on the socket at path. A socket left at path,
+// e.g. by a previous agent that did not stop cleanly, is removed.
+func NewUnixServer(
+	path string,
+	maxQueueSize int,
+	maxPacketSize int,
+	mFactory metrics.Factory,
+) (*UnixServer, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("cannot listen on %s: the file exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("cannot remove the stale socket %s: %w", path, err)
+		}
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot listen on unix socket %s: %w", path, err)
+	}
+	res := &UnixServer{
+		dataChan:      make(chan *ReadBuf, maxQueueSize),
+		maxPacketSize: maxPacketSize,
+		listener:      listener,
+		readBufPool: &sync.Pool{
+			New: func() interface{} {
+				return &ReadBuf{bytes: make([]byte, maxPacketSize)}
+			},
+		},
+		stopped: make(chan struct{}),
+		conns:   make(map[net.Conn]struct{}),
+	}
+	metrics.Init(&res.metrics, mFactory, nil)
+	return res, nil
+}
+
+// Addr returns the address of the socket.
+func (s *UnixServer) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Serve accepts the clients until the server is stopped.
+func (s *UnixServer) Serve() {
+	atomic.StoreUint32(&s.serving, 1)
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() && s.IsServing() {
+				continue
+			}
+			return
+		}
+		if !s.track(conn) {
+			conn.Close()
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+// track registers the connection to be closed by Stop, it returns false if the server is stopped.
+func (s *UnixServer) track(conn net.Conn) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.IsServing() {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	s.handlers.Add(1)
+	s.metrics.Connections.Update(atomic.AddInt64(&s.connections, 1))
+	return true
+}
+
+func (s *UnixServer) handle(conn net.Conn) {
+	defer func() {
+		s.lock.Lock()
+		delete(s.conns, conn)
+		s.lock.Unlock()
+		conn.Close()
+		s.metrics.Connections.Update(atomic.AddInt64(&s.connections, -1))
+		s.handlers.Done()
+	}()
+	var header [4]byte
+	for {
+		if _, err := io.ReadFull(conn, header[:]); err != nil {
+			// io.EOF when the client disconnects between messages
+			if err != io.EOF && s.IsServing() {
+				s.metrics.ReadError.Inc(1)
+			}
+			return
+		}
+		size := int(binary.BigEndian.Uint32(header[:]))
+		if size > s.maxPacketSize {
+			// the stream cannot be resynchronized after a message that is skipped
+			s.metrics.ReadError.Inc(1)
+			return
+		}
+		readBuf := s.readBufPool.Get().(*ReadBuf)
+		if _, err := io.ReadFull(conn, readBuf.bytes[:size]); err != nil {
+			s.readBufPool.Put(readBuf)
+			if s.IsServing() {
+				s.metrics.ReadError.Inc(1)
+			}
+			return
+		}
+		readBuf.n = size
+		s.metrics.MessageSize.Update(int64(size))
+		select {
+		case s.dataChan <- readBuf:
+			s.metrics.MessagesProcessed.Inc(1)
+			s.updateQueueSize(1)
+		case <-s.stopped:
+			s.readBufPool.Put(readBuf)
+			return
+		}
+	}
+}
+
+func (s *UnixServer) updateQueueSize(delta int64) {
+	atomic.AddInt64(&s.queueSize, delta)
+	s.metrics.QueueSize.Update(atomic.LoadInt64(&s.queueSize))
+}
+
+// IsServing indicates whether the server is currently serving traffic
+func (s *UnixServer) IsServing() bool {
+	return atomic.LoadUint32(&s.serving) == 1
+}
+
+// Stop closes the socket and the connections of the clients, and waits until
+// the messages already read are queued
+func (s *UnixServer) Stop() {
+	s.lock.Lock()
+	atomic.StoreUint32(&s.serving, 0)
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.lock.Unlock()
+	close(s.stopped)
+	s.listener.Close()
+	s.handlers.Wait()
+	close(s.dataChan)
+}
+
+// DataChan returns the data chan of the server
+func (s *UnixServer) DataChan() chan *ReadBuf {
+	return s.dataChan
+}
+
+// DataRecd is called by the consumers every time they read a data item from DataChan
+func (s *UnixServer) DataRecd(buf *ReadBuf) {
+	s.updateQueueSize(-1)
+	s.readBufPool.Put(buf)
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servers
+
+import (
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	athrift "github.com/apache/thrift/lib/go/thrift"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+
+	"github.com/jaegertracing/jaeger/cmd/agent/app/customtransport"
+	"github.com/jaegertracing/jaeger/thrift-gen/agent"
+	"github.com/jaegertracing/jaeger/thrift-gen/zipkincore"
+)
+
+func newTestUnixServer(t *testing.T, queueSize int) (*UnixServer, *metricstest.Factory, func()) {
+	dir, err := ioutil.TempDir("", "unix-server")
+	require.NoError(t, err)
+	metricsFactory := metricstest.NewFactory(0)
+	server, err := NewUnixServer(filepath.Join(dir, "agent.sock"), queueSize, 1000, metricsFactory)
+	require.NoError(t, err)
+	go server.Serve()
+	return server, metricsFactory, func() {
+		server.Stop()
+		os.RemoveAll(dir)
+	}
+}
+
+func newUnixAgentClient(t *testing.T, addr net.Addr) (*agent.AgentClient, athrift.TTransport) {
+	conn, err := net.Dial("unix", addr.String())
+	require.NoError(t, err)
+	transport := athrift.NewTFramedTransport(athrift.NewStreamTransportRW(conn))
+	return agent.NewAgentClientFactory(transport, athrift.NewTCompactProtocolFactory()), transport
+}
+
+func TestUnixServer(t *testing.T) {
+	server, metricsFactory, closer := newTestUnixServer(t, 1)
+	defer closer()
+	client, transport := newUnixAgentClient(t, server.Addr())
+	defer transport.Close()
+
+	// the queue holds a single message, the client is blocked rather than the next messages dropped
+	emitted := make(chan error)
+	go func() {
+		for i := 0; i < 3; i++ {
+			span := zipkincore.NewSpan()
+			span.Name = string(rune('a' + i))
+			if err := client.EmitZipkinBatch(context.Background(), []*zipkincore.Span{span}); err != nil {
+				emitted <- err
+				return
+			}
+		}
+		emitted <- nil
+	}()
+
+	for i := 0; i < 3; i++ {
+		readBuf := <-server.DataChan()
+		inMemory := &customtransport.TBufferedReadTransport{}
+		inMemory.Write(readBuf.GetBytes())
+		server.DataRecd(readBuf)
+
+		protoFact := athrift.NewTCompactProtocolFactory()
+		protocol := protoFact.GetProtocol(inMemory)
+		protocol.ReadMessageBegin()
+		args := agent.NewAgentEmitZipkinBatchArgs()
+		require.NoError(t, args.Read(protocol))
+		require.Len(t, args.Spans, 1)
+		assert.Equal(t, string(rune('a'+i)), args.Spans[0].Name)
+	}
+	require.NoError(t, <-emitted)
+	// the metrics are updated once the messages are queued
+	assert.Eventually(t, func() bool {
+		c, g := metricsFactory.Snapshot()
+		return c["thrift.unix.server.messages.processed"] == 3 && g["thrift.unix.server.queue_size"] == 0
+	}, 5*time.Second, time.Millisecond)
+	metricsFactory.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "thrift.unix.server.messages.processed", Value: 3})
+	metricsFactory.AssertGaugeMetrics(t, metricstest.ExpectedMetric{Name: "thrift.unix.server.queue_size", Value: 0})
+}
+
+func TestUnixServerMessageTooLarge(t *testing.T) {
+	server, metricsFactory, closer := newTestUnixServer(t, 1)
+	defer closer()
+	conn, err := net.Dial("unix", server.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], 1001)
+	_, err = conn.Write(header[:])
+	require.NoError(t, err)
+
+	// the server closes the connection
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = conn.Read(header[:])
+	assert.Error(t, err)
+	assert.NotContains(t, err.Error(), "timeout")
+	metricsFactory.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "thrift.unix.server.read.errors", Value: 1})
+}
+
+func TestUnixServerStaleSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "unix-server")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "agent.sock")
+
+	// a socket left by a process that did not clean up
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+
+	server, err := NewUnixServer(path, 1, 1000, metricstest.NewFactory(0))
+	require.NoError(t, err)
+	go server.Serve()
+	server.Stop()
+
+	file := filepath.Join(dir, "file")
+	require.NoError(t, ioutil.WriteFile(file, nil, 0600))
+	_, err = NewUnixServer(file, 1, 1000, metricstest.NewFactory(0))
+	assert.EqualError(t, err, "cannot listen on "+file+": the file exists and is not a socket")
+}
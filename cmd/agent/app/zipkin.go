@@ -0,0 +1,95 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/cmd/agent/app/httpserver"
+	"github.com/jaegertracing/jaeger/cmd/agent/app/processors"
+	"github.com/jaegertracing/jaeger/cmd/agent/app/reporter"
+)
+
+// zipkinSpansModel is the model of the messages holding a bare list of Zipkin Thrift spans
+const zipkinSpansModel Model = "zipkin-spans"
+
+// ZipkinConfiguration holds config for the servers receiving spans from Zipkin instrumentation libraries
+type ZipkinConfiguration struct {
+	// HTTPHostPort is the address of the Zipkin HTTP API, accepting Zipkin v1 and v2 spans, disabled if empty
+	HTTPHostPort string `yaml:"httpHostPort"`
+	// UDPHostPort is the address of the UDP server receiving lists of Zipkin Thrift spans, disabled if empty
+	UDPHostPort string `yaml:"udpHostPort"`
+}
+
+// getProcessors creates the processors of the enabled Zipkin servers
+func (c ZipkinConfiguration) getProcessors(rep reporter.Reporter, mFactory metrics.Factory, logger *zap.Logger) ([]processors.Processor, error) {
+	var retMe []processors.Processor
+	if c.HTTPHostPort != "" {
+		listener, err := net.Listen("tcp", c.HTTPHostPort)
+		if err != nil {
+			return nil, fmt.Errorf("cannot listen for Zipkin HTTP spans: %w", err)
+		}
+		server := &http.Server{Handler: httpserver.NewZipkinHandler(rep)}
+		retMe = append(retMe, &httpProcessor{server: server, listener: listener, logger: logger})
+	}
+	if c.UDPHostPort != "" {
+		cfg := ProcessorConfiguration{
+			Model:    zipkinSpansModel,
+			Protocol: binaryProtocol,
+			Server:   ServerConfiguration{HostPort: c.UDPHostPort},
+		}
+		metrics := mFactory.Namespace(metrics.NSOptions{Name: "", Tags: map[string]string{
+			"protocol": string(cfg.Protocol),
+			"model":    string(cfg.Model),
+		}})
+		processor, err := cfg.GetThriftProcessor(metrics, protocolFactoryMap[cfg.Protocol], processors.NewZipkinSpansProcessor(rep), logger)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create Zipkin Thrift Processor: %w", err)
+		}
+		retMe = append(retMe, processor)
+	}
+	return retMe, nil
+}
+
+// httpProcessor serves an HTTP API receiving spans
+type httpProcessor struct {
+	server   *http.Server
+	listener net.Listener
+	logger   *zap.Logger
+}
+
+// Serve implements processors.Processor
+func (p *httpProcessor) Serve() {
+	p.logger.Info("Starting Zipkin HTTP server", zap.String("addr", p.listener.Addr().String()))
+	if err := p.server.Serve(p.listener); err != http.ErrServerClosed {
+		p.logger.Error("Zipkin HTTP server failure", zap.Error(err))
+	}
+}
+
+// Stop implements processors.Processor
+func (p *httpProcessor) Stop() {
+	timeout, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.server.Shutdown(timeout); err != nil {
+		p.logger.Error("failed to close Zipkin HTTP server", zap.Error(err))
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/cmd/agent/app/testutils"
+	"github.com/jaegertracing/jaeger/thrift-gen/zipkincore"
+)
+
+func freeUDPPort(t *testing.T) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+	return conn.LocalAddr().String()
+}
+
+func TestZipkinServers(t *testing.T) {
+	udpHostPort := freeUDPPort(t)
+	cfg := ZipkinConfiguration{HTTPHostPort: "127.0.0.1:0", UDPHostPort: udpHostPort}
+	rep := testutils.NewInMemoryReporter()
+	processors, err := cfg.getProcessors(rep, metrics.NullFactory, zap.NewNop())
+	require.NoError(t, err)
+	require.Len(t, processors, 2)
+	for _, p := range processors {
+		go p.Serve()
+		defer p.Stop()
+	}
+
+	url := "http://" + processors[0].(*httpProcessor).listener.Addr().String() + "/api/v2/spans"
+	body := `[{"id": "0000000000000002", "traceId": "0000000000000001", "name": "get", "timestamp": 1, "duration": 10, "localEndpoint": {"serviceName": "frontend"}}]`
+	resp, err := http.Post(url, "application/json", bytes.NewBufferString(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	require.Len(t, rep.ZipkinSpans(), 1)
+	assert.Equal(t, "get", rep.ZipkinSpans()[0].Name)
+
+	buffer := thrift.NewTMemoryBuffer()
+	protocol := thrift.NewTBinaryProtocolTransport(buffer)
+	span := &zipkincore.Span{TraceID: 1, ID: 3, Name: "post"}
+	require.NoError(t, protocol.WriteListBegin(thrift.STRUCT, 1))
+	require.NoError(t, span.Write(protocol))
+	require.NoError(t, protocol.WriteListEnd())
+	conn, err := net.Dial("udp", udpHostPort)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write(buffer.Bytes())
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return len(rep.ZipkinSpans()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "post", rep.ZipkinSpans()[1].Name)
+}
+
+func TestZipkinServersErrors(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	_, err = ZipkinConfiguration{HTTPHostPort: listener.Addr().String()}.getProcessors(testutils.NewInMemoryReporter(), metrics.NullFactory, zap.NewNop())
+	assert.Contains(t, err.Error(), "cannot listen for Zipkin HTTP spans")
+
+	_, err = ZipkinConfiguration{UDPHostPort: "127.0.0.1:-1"}.getProcessors(testutils.NewInMemoryReporter(), metrics.NullFactory, zap.NewNop())
+	assert.Contains(t, err.Error(), "cannot create Zipkin Thrift Processor")
+
+	processors, err := ZipkinConfiguration{}.getProcessors(testutils.NewInMemoryReporter(), metrics.NullFactory, zap.NewNop())
+	require.NoError(t, err)
+	assert.Empty(t, processors)
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/jaegertracing/jaeger/cmd/agent/app"
 	"github.com/jaegertracing/jaeger/cmd/agent/app/reporter"
 	"github.com/jaegertracing/jaeger/cmd/agent/app/reporter/grpc"
+	"github.com/jaegertracing/jaeger/cmd/agent/app/reporter/otlp"
 	"github.com/jaegertracing/jaeger/cmd/docs"
 	"github.com/jaegertracing/jaeger/cmd/flags"
 	"github.com/jaegertracing/jaeger/pkg/config"
@@ -55,8 +56,11 @@ func main() {
 
 			rOpts := new(reporter.Options).InitFromViper(v, logger)
 			grpcBuilder := grpc.NewConnBuilder().InitFromViper(v)
+			// the collectors can be changed in the configuration file while running
+			grpcBuilder.ReloadableHostPorts = v.ConfigFileUsed() != ""
 			builders := map[reporter.Type]app.CollectorProxyBuilder{
 				reporter.GRPC: app.GRPCCollectorProxyBuilder(grpcBuilder),
+				reporter.OTLP: app.OTLPCollectorProxyBuilder(otlp.NewConnBuilder().InitFromViper(v)),
 			}
 			cp, err := app.CreateCollectorProxy(app.ProxyBuilderOptions{
 				Options: *rOpts,
@@ -75,11 +79,21 @@ func main() {
 				return fmt.Errorf("unable to initialize Jaeger Agent: %w", err)
 			}
 
+			svc.Admin.Handle("/traffic", builder.TrafficHandler())
+
 			logger.Info("Starting agent")
 			if err := agent.Run(); err != nil {
 				return fmt.Errorf("failed to run the agent: %w", err)
 			}
+			configWatcher, err := app.NewConfigWatcher(builder.ConfigReload, v, cp, logger,
+				mFactory.Namespace(metrics.NSOptions{Name: "config_reload"}))
+			if err != nil {
+				return fmt.Errorf("unable to watch the configuration file: %w", err)
+			}
 			svc.RunAndThen(func() {
+				if configWatcher != nil {
+					configWatcher.Close()
+				}
 				agent.Stop()
 				cp.Close()
 			})
@@ -97,6 +111,7 @@ func main() {
 		app.AddFlags,
 		reporter.AddFlags,
 		grpc.AddFlags,
+		otlp.AddFlags,
 	)
 
 	if err := command.Execute(); err != nil {
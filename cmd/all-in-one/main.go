@@ -39,13 +39,16 @@ import (
 	"github.com/jaegertracing/jaeger/cmd/env"
 	"github.com/jaegertracing/jaeger/cmd/flags"
 	queryApp "github.com/jaegertracing/jaeger/cmd/query/app"
-	"github.com/jaegertracing/jaeger/cmd/query/app/querysvc"
 	"github.com/jaegertracing/jaeger/pkg/config"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
 	"github.com/jaegertracing/jaeger/pkg/version"
+	metricsPlugin "github.com/jaegertracing/jaeger/plugin/metrics"
 	ss "github.com/jaegertracing/jaeger/plugin/sampling/strategystore"
 	"github.com/jaegertracing/jaeger/plugin/storage"
 	"github.com/jaegertracing/jaeger/ports"
 	"github.com/jaegertracing/jaeger/storage/dependencystore"
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
+	"github.com/jaegertracing/jaeger/storage/readcache"
 	"github.com/jaegertracing/jaeger/storage/spanstore"
 	storageMetrics "github.com/jaegertracing/jaeger/storage/spanstore/metrics"
 )
@@ -68,6 +71,10 @@ func main() {
 	if err != nil {
 		log.Fatalf("Cannot initialize sampling strategy store factory: %v", err)
 	}
+	metricsReaderFactory, err := metricsPlugin.NewFactory(metricsPlugin.FactoryConfigFromEnv())
+	if err != nil {
+		log.Fatalf("Cannot initialize metrics storage factory: %v", err)
+	}
 
 	v := viper.New()
 	command := &cobra.Command{
@@ -125,8 +132,12 @@ by default uses only in-memory database.`,
 				SpanWriter:     spanWriter,
 				StrategyStore:  strategyStore,
 				HealthCheck:    svc.HC(),
+				Config:         v,
 			})
-			c.Start(cOpts)
+			if err := c.Start(cOpts); err != nil {
+				logger.Fatal("Failed to start collector", zap.Error(err))
+			}
+			c.RegisterAdminHandlers(svc.Admin)
 
 			// agent
 			grpcBuilder.CollectorHostPorts = append(grpcBuilder.CollectorHostPorts, cOpts.CollectorGRPCHostPort)
@@ -145,17 +156,43 @@ by default uses only in-memory database.`,
 			agent := startAgent(cp, aOpts, logger, metricsFactory)
 
 			// query
+			metricsReaderFactory.InitFromViper(v)
+			if err := metricsReaderFactory.Initialize(logger); err != nil {
+				logger.Fatal("Failed to init metrics storage factory", zap.Error(err))
+			}
+			metricsReader, err := metricsReaderFactory.CreateMetricsReader()
+			if err != nil {
+				logger.Fatal("Failed to create metrics reader", zap.Error(err))
+			}
+			queryServiceOptions := qOpts.BuildQueryServiceOptions(storageFactory, logger)
+			if queryServiceOptions.Authorizer, err = qOpts.BuildAuthorizer(); err != nil {
+				logger.Fatal("Failed to load the authorization rules", zap.Error(err))
+			}
+			if queryServiceOptions.Auditor, err = qOpts.BuildAuditor(); err != nil {
+				logger.Fatal("Failed to open the audit log", zap.Error(err))
+			}
 			querySrv := startQuery(
-				svc, qOpts, qOpts.BuildQueryServiceOptions(storageFactory, logger),
-				spanReader, dependencyReader,
+				svc, qOpts, queryServiceOptions,
+				spanReader, dependencyReader, metricsReader,
 				rootMetricsFactory, metricsFactory,
 			)
+			archiver, err := qOpts.BuildAutoArchiver(spanReader, queryServiceOptions, logger,
+				metricsFactory.Namespace(metrics.NSOptions{Name: "query"}))
+			if err != nil {
+				logger.Fatal("Failed to load the automatic archiving rules", zap.Error(err))
+			}
+			if archiver != nil {
+				archiver.Start()
+			}
 
 			svc.RunAndThen(func() {
 				agent.Stop()
 				cp.Close()
 				c.Close()
 				querySrv.Close()
+				if archiver != nil {
+					archiver.Close()
+				}
 				if closer, ok := spanWriter.(io.Closer); ok {
 					err := closer.Close()
 					if err != nil {
@@ -183,6 +220,7 @@ by default uses only in-memory database.`,
 		collectorApp.AddFlags,
 		queryApp.AddFlags,
 		strategyStoreFactory.AddFlags,
+		metricsReaderFactory.AddFlags,
 	)
 
 	if err := command.Execute(); err != nil {
@@ -217,12 +255,17 @@ func startQuery(
 	queryOpts *querysvc.QueryServiceOptions,
 	spanReader spanstore.Reader,
 	depReader dependencystore.Reader,
+	metricsReader metricsstore.Reader,
 	rootFactory metrics.Factory,
 	baseFactory metrics.Factory,
 ) *queryApp.Server {
-	spanReader = storageMetrics.NewReadMetricsDecorator(spanReader, baseFactory.Namespace(metrics.NSOptions{Name: "query"}))
+	queryMetricsFactory := baseFactory.Namespace(metrics.NSOptions{Name: "query"})
+	spanReader = storageMetrics.NewReadMetricsDecorator(spanReader, queryMetricsFactory)
+	spanReader = readcache.NewSpanReader(spanReader, qOpts.ReadCache, queryMetricsFactory)
+	depReader = readcache.NewDependencyReader(depReader, qOpts.ReadCache, queryMetricsFactory)
 	qs := querysvc.NewQueryService(spanReader, depReader, *queryOpts)
-	server, err := queryApp.NewServer(svc.Logger, qs, qOpts, opentracing.GlobalTracer())
+	metricsQueryService := querysvc.NewMetricsQueryService(metricsReader, qs)
+	server, err := queryApp.NewServer(svc.Logger, qs, metricsQueryService, qOpts, queryMetricsFactory, opentracing.GlobalTracer())
 	if err != nil {
 		svc.Logger.Fatal("Could not start jaeger-query service", zap.Error(err))
 	}
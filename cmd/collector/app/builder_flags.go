@@ -20,17 +20,47 @@ import (
 
 	"github.com/spf13/viper"
 
+	"github.com/jaegertracing/jaeger/cmd/collector/app/circuitbreaker"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/clockskew"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/dedup"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/filter"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/k8sattributes"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/peerrouting"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/ratelimit"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/redaction"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/spanlimits"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/spanmetrics"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/spillover"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/tailsampling"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/tenantquota"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/zpages"
 	"github.com/jaegertracing/jaeger/cmd/flags"
+	"github.com/jaegertracing/jaeger/pkg/auth"
 	"github.com/jaegertracing/jaeger/pkg/config/tlscfg"
+	"github.com/jaegertracing/jaeger/pkg/configreload"
+	"github.com/jaegertracing/jaeger/pkg/queue"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
 	"github.com/jaegertracing/jaeger/ports"
 )
 
 const (
 	collectorDynQueueSizeMemory = "collector.queue-size-memory"
+	collectorDynQueueSizeFrac   = "collector.queue-size-memory-fraction"
 	collectorQueueSize          = "collector.queue-size"
 	collectorNumWorkers         = "collector.num-workers"
+	collectorQueuePersistence   = "collector.queue.persistence"
+	collectorQueueSegmentSize   = "collector.queue.persistence-segment-size-mib"
+	collectorQueueMaxSize       = "collector.queue.persistence-max-size-mib"
+	collectorQueueMaxAge        = "collector.queue.persistence-max-age"
+	collectorQueueCheckpoint    = "collector.queue.persistence-checkpoint-interval"
+	collectorConfigReload       = "collector.config-reload.interval"
 	collectorHTTPPort           = "collector.http-port"
 	collectorGRPCPort           = "collector.grpc-port"
+	collectorGRPCReflection     = "collector.grpc.reflection"
+	collectorGRPCChannelz       = "collector.grpc.channelz"
+	collectorGRPCWeb            = "collector.grpc.web"
+	collectorHTTPAllowedOrigins = "collector.http.cors.allowed-origins"
+	collectorHTTPAllowedHeaders = "collector.http.cors.allowed-headers"
 	// CollectorHTTPHostPort is the flag for collector HTTP port
 	CollectorHTTPHostPort = "collector.http-server.host-port"
 	// CollectorGRPCHostPort is the flag for collector gRPC port
@@ -41,6 +71,12 @@ const (
 	collectorTags                 = "collector.tags"
 	collectorZipkinAllowedOrigins = "collector.zipkin.allowed-origins"
 	collectorZipkinAllowedHeaders = "collector.zipkin.allowed-headers"
+	// CollectorOTLPGRPCHostPort is the flag for the OTLP gRPC receiver
+	CollectorOTLPGRPCHostPort = "collector.otlp.grpc.host-port"
+	// CollectorOTLPHTTPHostPort is the flag for the OTLP HTTP receiver
+	CollectorOTLPHTTPHostPort = "collector.otlp.http.host-port"
+	// CollectorOpenCensusHostPort is the flag for the OpenCensus agent protocol receiver
+	CollectorOpenCensusHostPort = "collector.opencensus.host-port"
 
 	collectorHTTPPortWarning       = "(deprecated, will be removed after 2020-06-30 or in release v1.20.0, whichever is later)"
 	collectorGRPCPortWarning       = "(deprecated, will be removed after 2020-06-30 or in release v1.20.0, whichever is later)"
@@ -53,20 +89,46 @@ var tlsFlagsConfig = tlscfg.ServerFlagsConfig{
 	ShowClientCA: true,
 }
 
+var authFlagsConfig = auth.FlagsConfig{
+	Prefix: "collector",
+}
+
+var adminAuthFlagsConfig = auth.FlagsConfig{
+	Prefix: "collector.admin",
+}
+
 // CollectorOptions holds configuration for collector
 type CollectorOptions struct {
 	// DynQueueSizeMemory determines how much memory to use for the queue
 	DynQueueSizeMemory uint
+	// DynQueueSizeMemoryFraction is the fraction of the available memory to use for the queue, overrides DynQueueSizeMemory
+	DynQueueSizeMemoryFraction float64
 	// QueueSize is the size of collector's queue
 	QueueSize int
 	// NumWorkers is the number of internal workers in a collector
 	NumWorkers int
+	// PersistentQueue configures the disk-backed queue, used instead of the in-memory queue when a directory is set
+	PersistentQueue queue.PersistentQueueOptions
 	// CollectorHTTPHostPort is the host:port address that the collector service listens in on for http requests
 	CollectorHTTPHostPort string
 	// CollectorGRPCHostPort is the host:port address that the collector service listens in on for gRPC requests
 	CollectorGRPCHostPort string
+	// GRPCReflection registers the gRPC server reflection service on the collector's gRPC servers
+	GRPCReflection bool
+	// GRPCChannelz registers the channelz service on the collector's gRPC servers
+	GRPCChannelz bool
+	// GRPCWeb serves gRPC-Web requests from browsers on the collector's gRPC port
+	GRPCWeb bool
+	// HTTPAllowedOrigins is a list of origins a cross-domain request to the HTTP, OTLP HTTP and gRPC-Web endpoints can be executed from
+	HTTPAllowedOrigins string
+	// HTTPAllowedHeaders is a list of headers the HTTP, OTLP HTTP and gRPC-Web endpoints allow the client to use with cross-domain requests
+	HTTPAllowedHeaders string
 	// TLS configures secure transport
 	TLS tlscfg.Options
+	// Auth configures the bearer token authentication of span submissions
+	Auth auth.Options
+	// AdminAuth configures the bearer token authentication of the collector admin endpoints, e.g. drain
+	AdminAuth auth.Options
 	// CollectorTags is the string representing collector tags to append to each and every span
 	CollectorTags map[string]string
 	// CollectorZipkinHTTPHostPort is the host:port address that the Zipkin collector service listens in on for http requests
@@ -75,6 +137,44 @@ type CollectorOptions struct {
 	CollectorZipkinAllowedOrigins string
 	// CollectorZipkinAllowedHeaders is a list of headers that the Zipkin collector service allowes the client to use with cross-domain requests
 	CollectorZipkinAllowedHeaders string
+	// CollectorOTLPGRPCHostPort is the host:port address that the OTLP gRPC receiver listens in on, empty to disable it
+	CollectorOTLPGRPCHostPort string
+	// CollectorOTLPHTTPHostPort is the host:port address that the OTLP HTTP receiver listens in on, empty to disable it
+	CollectorOTLPHTTPHostPort string
+	// CollectorOpenCensusHostPort is the host:port address that the OpenCensus receiver listens in on, empty to disable it
+	CollectorOpenCensusHostPort string
+	// SpanFilter configures the rules used to drop spans before they are queued
+	SpanFilter filter.Options
+	// ConfigReload configures the reloading of span filter rules and sampling strategies when the configuration file changes
+	ConfigReload configreload.Options
+	// RateLimit configures the per-service limits on received spans
+	RateLimit ratelimit.Options
+	// TenantQuota configures the per-tenant span and byte quotas, requires multi-tenancy
+	TenantQuota tenantquota.Options
+	// Tenancy configures the tenant header accepted on the span ingestion endpoints
+	Tenancy tenancy.Options
+	// Redaction configures the rules used to hash or remove sensitive tags before spans are saved
+	Redaction redaction.Options
+	// CircuitBreaker configures the shedding of span writes while the storage is failing
+	CircuitBreaker circuitbreaker.Options
+	// Spillover configures where spans rejected by the storage are kept until they can be replayed
+	Spillover spillover.Options
+	// TailSampling configures the optional tail sampling stage in front of the span writer
+	TailSampling tailsampling.Options
+	// ClockSkew configures the correction of clock skew between parent and child spans before they are written
+	ClockSkew clockskew.Options
+	// Dedup configures the suppression of duplicate spans
+	Dedup dedup.Options
+	// SpanLimits configures the size limits of the spans and what happens to spans exceeding them
+	SpanLimits spanlimits.Options
+	// ZPages configures the span viewer pages of the admin server
+	ZPages zpages.Options
+	// K8sAttributes configures the enrichment of spans with the Kubernetes metadata of the submitting pod
+	K8sAttributes k8sattributes.Options
+	// PeerRouting configures the forwarding of spans to the collector owning their trace
+	PeerRouting peerrouting.Options
+	// SpanMetrics configures the computation of request, error and duration metrics from the spans
+	SpanMetrics spanmetrics.Options
 }
 
 // AddFlags adds flags for CollectorOptions
@@ -85,9 +185,45 @@ func AddFlags(flags *flag.FlagSet) {
 	flags.Int(collectorGRPCPort, 0, collectorGRPCPortWarning+" see --"+CollectorGRPCHostPort)
 	flags.Int(collectorZipkinHTTPPort, 0, collectorZipkinHTTPPortWarning+" see --"+CollectorZipkinHTTPHostPort)
 	flags.Uint(collectorDynQueueSizeMemory, 0, "(experimental) The max memory size in MiB to use for the dynamic queue.")
+	flags.Float64(collectorDynQueueSizeFrac, 0, "(experimental) The fraction (e.g. 0.5) of the memory available to the collector, "+
+		"given by its cgroup limit or the physical memory, to use for the dynamic queue; overrides --"+collectorDynQueueSizeMemory+" when set")
+	flags.String(collectorQueuePersistence, "", "(experimental) Directory of a disk-backed queue used instead of the in-memory queue, "+
+		"so that spans survive restarts and storage outages; disabled if empty")
+	flags.Uint(collectorQueueSegmentSize, queue.DefaultSegmentSize/1024/1024, "The size in MiB of the persistent queue segment files")
+	flags.Uint(collectorQueueMaxSize, 1024, "The max disk size in MiB of the persistent queue, new spans are dropped when reached; 0 means unlimited")
+	flags.Duration(collectorQueueMaxAge, 0, "Discard segments of the persistent queue that were not consumed within this duration; 0 keeps them forever")
+	flags.Duration(collectorQueueCheckpoint, queue.DefaultCheckpointInterval, "How often the read position of the persistent queue is synced to disk; "+
+		"spans consumed since the last checkpoint are replayed after a crash")
 	flags.String(collectorTags, "", "One or more tags to be added to the Process tags of all spans passing through this collector. Ex: key1=value1,key2=${envVar:defaultValue}")
 	flags.String(collectorZipkinAllowedOrigins, "*", "Comma separated list of allowed origins for the Zipkin collector service, default accepts all")
 	flags.String(collectorZipkinAllowedHeaders, "content-type", "Comma separated list of allowed headers for the Zipkin collector service, default content-type")
+	flags.Bool(collectorGRPCReflection, false, "Register the gRPC server reflection service on the collector's gRPC servers, e.g. for grpcurl")
+	flags.Bool(collectorGRPCChannelz, false, "Register the channelz service on the collector's gRPC servers to inspect their connections")
+	flags.Bool(collectorGRPCWeb, false, "Also accept gRPC-Web requests over HTTP/1.1 on the collector's gRPC port, e.g. from browser instrumentation; not supported with TLS")
+	flags.String(collectorHTTPAllowedOrigins, "", "Comma separated list of origins allowed to send spans to the HTTP, OTLP HTTP and gRPC-Web endpoints, empty disables cross-origin requests")
+	flags.String(collectorHTTPAllowedHeaders, "content-type", "Comma separated list of headers allowed on cross-origin requests to the HTTP, OTLP HTTP and gRPC-Web endpoints")
+	flags.String(CollectorOTLPGRPCHostPort, "", "The host:port (e.g. 127.0.0.1:4317 or :4317) of the collector's OTLP gRPC receiver, disabled if empty; uses the --collector.grpc.tls.* settings")
+	flags.String(CollectorOTLPHTTPHostPort, "", "The host:port (e.g. 127.0.0.1:4318 or :4318) of the collector's OTLP HTTP receiver, disabled if empty")
+	flags.String(CollectorOpenCensusHostPort, "", "The host:port (e.g. 127.0.0.1:55678 or :55678) of the collector's OpenCensus agent protocol receiver, disabled if empty; uses the --collector.grpc.tls.* settings")
+	filter.AddFlags(flags)
+	flags.Duration(collectorConfigReload, 0, "How often the file given with --config-file is checked for changes; span filter rules and sampling strategies "+
+		"are applied without a restart, other changes are only logged. Zero disables reloading")
+	ratelimit.AddFlags(flags)
+	tenantquota.AddFlags(flags)
+	tenancy.AddFlags(flags)
+	authFlagsConfig.AddFlags(flags)
+	adminAuthFlagsConfig.AddFlags(flags)
+	redaction.AddFlags(flags)
+	circuitbreaker.AddFlags(flags)
+	spillover.AddFlags(flags)
+	tailsampling.AddFlags(flags)
+	clockskew.AddFlags(flags)
+	dedup.AddFlags(flags)
+	spanlimits.AddFlags(flags)
+	spanmetrics.AddFlags(flags)
+	zpages.AddFlags(flags)
+	k8sattributes.AddFlags(flags)
+	peerrouting.AddFlags(flags)
 	AddOTELJaegerFlags(flags)
 	AddOTELZipkinFlags(flags)
 }
@@ -107,14 +243,57 @@ func AddOTELZipkinFlags(flags *flag.FlagSet) {
 // InitFromViper initializes CollectorOptions with properties from viper
 func (cOpts *CollectorOptions) InitFromViper(v *viper.Viper) *CollectorOptions {
 	cOpts.DynQueueSizeMemory = v.GetUint(collectorDynQueueSizeMemory) * 1024 * 1024 // we receive in MiB and store in bytes
+	cOpts.DynQueueSizeMemoryFraction = v.GetFloat64(collectorDynQueueSizeFrac)
 	cOpts.QueueSize = v.GetInt(collectorQueueSize)
 	cOpts.NumWorkers = v.GetInt(collectorNumWorkers)
+	cOpts.PersistentQueue = queue.PersistentQueueOptions{
+		Directory:   v.GetString(collectorQueuePersistence),
+		SegmentSize: int64(v.GetUint(collectorQueueSegmentSize)) * 1024 * 1024,
+		MaxSize:     int64(v.GetUint(collectorQueueMaxSize)) * 1024 * 1024,
+		MaxAge:      v.GetDuration(collectorQueueMaxAge),
+
+		CheckpointInterval: v.GetDuration(collectorQueueCheckpoint),
+	}
 	cOpts.CollectorHTTPHostPort = ports.GetAddressFromCLIOptions(v.GetInt(collectorHTTPPort), v.GetString(CollectorHTTPHostPort))
 	cOpts.CollectorGRPCHostPort = ports.GetAddressFromCLIOptions(v.GetInt(collectorGRPCPort), v.GetString(CollectorGRPCHostPort))
+	cOpts.GRPCReflection = v.GetBool(collectorGRPCReflection)
+	cOpts.GRPCChannelz = v.GetBool(collectorGRPCChannelz)
+	cOpts.GRPCWeb = v.GetBool(collectorGRPCWeb)
+	cOpts.HTTPAllowedOrigins = v.GetString(collectorHTTPAllowedOrigins)
+	cOpts.HTTPAllowedHeaders = v.GetString(collectorHTTPAllowedHeaders)
 	cOpts.CollectorZipkinHTTPHostPort = ports.GetAddressFromCLIOptions(v.GetInt(collectorZipkinHTTPPort), v.GetString(CollectorZipkinHTTPHostPort))
 	cOpts.CollectorTags = flags.ParseJaegerTags(v.GetString(collectorTags))
 	cOpts.CollectorZipkinAllowedOrigins = v.GetString(collectorZipkinAllowedOrigins)
 	cOpts.CollectorZipkinAllowedHeaders = v.GetString(collectorZipkinAllowedHeaders)
+	cOpts.CollectorOTLPGRPCHostPort = optionalHostPort(v.GetString(CollectorOTLPGRPCHostPort))
+	cOpts.CollectorOTLPHTTPHostPort = optionalHostPort(v.GetString(CollectorOTLPHTTPHostPort))
+	cOpts.CollectorOpenCensusHostPort = optionalHostPort(v.GetString(CollectorOpenCensusHostPort))
 	cOpts.TLS = tlsFlagsConfig.InitFromViper(v)
+	cOpts.Auth = authFlagsConfig.InitFromViper(v)
+	cOpts.AdminAuth = adminAuthFlagsConfig.InitFromViper(v)
+	cOpts.SpanFilter.InitFromViper(v)
+	cOpts.ConfigReload.Interval = v.GetDuration(collectorConfigReload)
+	cOpts.RateLimit.InitFromViper(v)
+	cOpts.TenantQuota.InitFromViper(v)
+	cOpts.Tenancy = tenancy.InitFromViper(v)
+	cOpts.Redaction.InitFromViper(v)
+	cOpts.CircuitBreaker.InitFromViper(v)
+	cOpts.Spillover.InitFromViper(v)
+	cOpts.TailSampling.InitFromViper(v)
+	cOpts.ClockSkew.InitFromViper(v)
+	cOpts.Dedup.InitFromViper(v)
+	cOpts.SpanLimits.InitFromViper(v)
+	cOpts.SpanMetrics.InitFromViper(v)
+	cOpts.ZPages.InitFromViper(v)
+	cOpts.K8sAttributes.InitFromViper(v)
+	cOpts.PeerRouting.InitFromViper(v)
 	return cOpts
 }
+
+// optionalHostPort normalizes the address of a server that is disabled when the address is empty
+func optionalHostPort(hostPort string) string {
+	if hostPort == "" {
+		return ""
+	}
+	return ports.GetAddressFromCLIOptions(0, hostPort)
+}
@@ -16,6 +16,7 @@ package app
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -51,3 +52,146 @@ func TestCollectorOptionsWithFlags_CheckFullHostPort(t *testing.T) {
 	assert.Equal(t, "127.0.0.1:1234", c.CollectorGRPCHostPort)
 	assert.Equal(t, "0.0.0.0:3456", c.CollectorZipkinHTTPHostPort)
 }
+
+func TestCollectorOptionsWithFlags_CheckOTLPHostPort(t *testing.T) {
+	c := &CollectorOptions{}
+	v, command := config.Viperize(AddFlags)
+	c.InitFromViper(v)
+	assert.Equal(t, "", c.CollectorOTLPGRPCHostPort)
+	assert.Equal(t, "", c.CollectorOTLPHTTPHostPort)
+
+	command.ParseFlags([]string{
+		"--collector.otlp.grpc.host-port=4317",
+		"--collector.otlp.http.host-port=127.0.0.1:4318",
+	})
+	c.InitFromViper(v)
+	assert.Equal(t, ":4317", c.CollectorOTLPGRPCHostPort)
+	assert.Equal(t, "127.0.0.1:4318", c.CollectorOTLPHTTPHostPort)
+}
+
+func TestCollectorOptionsWithFlags_CheckOpenCensusHostPort(t *testing.T) {
+	c := &CollectorOptions{}
+	v, command := config.Viperize(AddFlags)
+	c.InitFromViper(v)
+	assert.Equal(t, "", c.CollectorOpenCensusHostPort)
+
+	command.ParseFlags([]string{"--collector.opencensus.host-port=55678"})
+	c.InitFromViper(v)
+	assert.Equal(t, ":55678", c.CollectorOpenCensusHostPort)
+}
+
+func TestCollectorOptionsWithFlags_CheckGRPCIntrospection(t *testing.T) {
+	c := &CollectorOptions{}
+	v, command := config.Viperize(AddFlags)
+	c.InitFromViper(v)
+	assert.False(t, c.GRPCReflection)
+	assert.False(t, c.GRPCChannelz)
+
+	command.ParseFlags([]string{
+		"--collector.grpc.reflection=true",
+		"--collector.grpc.channelz=true",
+	})
+	c.InitFromViper(v)
+	assert.True(t, c.GRPCReflection)
+	assert.True(t, c.GRPCChannelz)
+}
+
+func TestCollectorOptionsWithFlags_CheckGRPCWeb(t *testing.T) {
+	c := &CollectorOptions{}
+	v, command := config.Viperize(AddFlags)
+	c.InitFromViper(v)
+	assert.False(t, c.GRPCWeb)
+	assert.Equal(t, "", c.HTTPAllowedOrigins)
+
+	command.ParseFlags([]string{
+		"--collector.grpc.web=true",
+		"--collector.http.cors.allowed-origins=https://example.com",
+		"--collector.http.cors.allowed-headers=content-type,traceparent",
+	})
+	c.InitFromViper(v)
+	assert.True(t, c.GRPCWeb)
+	assert.Equal(t, "https://example.com", c.HTTPAllowedOrigins)
+	assert.Equal(t, "content-type,traceparent", c.HTTPAllowedHeaders)
+}
+
+func TestCollectorOptionsWithFlags_CheckTailSampling(t *testing.T) {
+	c := &CollectorOptions{}
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{
+		"--collector.tail-sampling.enabled=true",
+		"--collector.tail-sampling.decision-wait=5s",
+		"--collector.tail-sampling.latency-threshold=2s",
+		"--collector.tail-sampling.probabilistic-ratio=0.1",
+	})
+	c.InitFromViper(v)
+	assert.True(t, c.TailSampling.Enabled)
+	assert.Equal(t, 5*time.Second, c.TailSampling.DecisionWait)
+	assert.Equal(t, 2*time.Second, c.TailSampling.LatencyThreshold)
+	assert.True(t, c.TailSampling.SampleErrors)
+	assert.Equal(t, 0.1, c.TailSampling.ProbabilisticRatio)
+}
+
+func TestCollectorOptionsWithFlags_CheckClockSkew(t *testing.T) {
+	c := &CollectorOptions{}
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{
+		"--collector.clock-skew.max-adjustment=30s",
+	})
+	c.InitFromViper(v)
+	assert.True(t, c.ClockSkew.Enabled())
+	assert.Equal(t, 30*time.Second, c.ClockSkew.MaxAdjustment)
+}
+
+func TestCollectorOptionsWithFlags_CheckConfigReload(t *testing.T) {
+	c := &CollectorOptions{}
+	v, command := config.Viperize(AddFlags)
+	c.InitFromViper(v)
+	assert.False(t, c.ConfigReload.Enabled())
+
+	command.ParseFlags([]string{"--collector.config-reload.interval=10s"})
+	c.InitFromViper(v)
+	assert.True(t, c.ConfigReload.Enabled())
+	assert.Equal(t, 10*time.Second, c.ConfigReload.Interval)
+}
+
+func TestCollectorOptionsWithFlags_CheckPersistentQueue(t *testing.T) {
+	c := &CollectorOptions{}
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{
+		"--collector.queue.persistence=/var/lib/jaeger/queue",
+		"--collector.queue.persistence-max-size-mib=10",
+		"--collector.queue.persistence-max-age=1h",
+		"--collector.queue.persistence-checkpoint-interval=5s",
+	})
+	c.InitFromViper(v)
+	assert.Equal(t, "/var/lib/jaeger/queue", c.PersistentQueue.Directory)
+	assert.EqualValues(t, 64*1024*1024, c.PersistentQueue.SegmentSize)
+	assert.EqualValues(t, 10*1024*1024, c.PersistentQueue.MaxSize)
+	assert.Equal(t, time.Hour, c.PersistentQueue.MaxAge)
+	assert.Equal(t, 5*time.Second, c.PersistentQueue.CheckpointInterval)
+}
+
+func TestCollectorOptionsWithFlags_CheckQueueSizeMemoryFraction(t *testing.T) {
+	c := &CollectorOptions{}
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{
+		"--collector.queue-size-memory=100",
+		"--collector.queue-size-memory-fraction=0.25",
+	})
+	c.InitFromViper(v)
+	assert.EqualValues(t, 100*1024*1024, c.DynQueueSizeMemory)
+	assert.Equal(t, 0.25, c.DynQueueSizeMemoryFraction)
+}
+
+func TestCollectorOptionsWithFlags_CheckSpanMetrics(t *testing.T) {
+	c := &CollectorOptions{}
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{
+		"--collector.span-metrics.enabled=true",
+		"--collector.span-metrics.remote-write-url=http://prometheus:9090/api/v1/write",
+	})
+	c.InitFromViper(v)
+	assert.True(t, c.SpanMetrics.Enabled)
+	assert.Equal(t, "http://prometheus:9090/api/v1/write", c.SpanMetrics.RemoteWriteURL)
+	assert.Equal(t, 15*time.Second, c.SpanMetrics.RemoteWriteInterval)
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package circuitbreaker
+
+import (
+	"flag"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	failureThreshold = "collector.circuit-breaker.failure-threshold"
+	openDuration     = "collector.circuit-breaker.open-duration"
+
+	defaultOpenDuration = 10 * time.Second
+)
+
+// Options configures the circuit breaker in front of the span writer.
+type Options struct {
+	// FailureThreshold is the number of consecutive failed writes that opens the breaker, 0 disables the breaker
+	FailureThreshold int
+	// OpenDuration is how long the breaker sheds writes before letting a probe write through
+	OpenDuration time.Duration
+}
+
+// AddFlags adds flags for circuit breaker Options
+func AddFlags(flags *flag.FlagSet) {
+	flags.Int(failureThreshold, 0, "(experimental) The number of consecutive failed span writes after which writes are shed "+
+		"without calling the storage, until a probe write succeeds; disabled if 0")
+	flags.Duration(openDuration, defaultOpenDuration, "How long the circuit breaker sheds span writes before probing the storage again")
+}
+
+// InitFromViper initializes Options with properties from viper
+func (o *Options) InitFromViper(v *viper.Viper) *Options {
+	o.FailureThreshold = v.GetInt(failureThreshold)
+	o.OpenDuration = v.GetDuration(openDuration)
+	return o
+}
+
+// Enabled returns true if the circuit breaker is configured
+func (o Options) Enabled() bool {
+	return o.FailureThreshold > 0
+}
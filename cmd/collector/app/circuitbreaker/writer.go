@@ -0,0 +1,173 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package circuitbreaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+// ErrOpen is returned instead of writing a span while the breaker is open.
+var ErrOpen = errors.New("circuit breaker is open, span write was shed")
+
+// State is the state of the circuit breaker.
+type State int
+
+const (
+	// Closed lets every write through to the storage
+	Closed State = iota
+	// Open sheds every write until the open duration elapsed
+	Open
+	// HalfOpen lets a single probe write through and sheds the others
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+type breakerMetrics struct {
+	Written metrics.Counter `metric:"spans" tags:"result=written"`
+	Failed  metrics.Counter `metric:"spans" tags:"result=failed"`
+	Shed    metrics.Counter `metric:"spans" tags:"result=shed"`
+	Opened  metrics.Counter `metric:"transitions" tags:"state=open"`
+	Closed  metrics.Counter `metric:"transitions" tags:"state=closed"`
+	State   metrics.Gauge   `metric:"state"`
+}
+
+// Writer is a span Writer that stops calling the wrapped writer after sustained failures,
+// and probes it with a single write once in a while until it recovers.
+type Writer struct {
+	writer           spanstore.Writer
+	failureThreshold int
+	openDuration     time.Duration
+	logger           *zap.Logger
+	metrics          breakerMetrics
+	now              func() time.Time
+
+	lock     sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewWriter wraps the writer in a circuit breaker.
+func NewWriter(writer spanstore.Writer, opts Options, logger *zap.Logger, metricsFactory metrics.Factory) *Writer {
+	if opts.OpenDuration <= 0 {
+		opts.OpenDuration = defaultOpenDuration
+	}
+	w := &Writer{
+		writer:           writer,
+		failureThreshold: opts.FailureThreshold,
+		openDuration:     opts.OpenDuration,
+		logger:           logger,
+		now:              time.Now,
+	}
+	metrics.Init(&w.metrics, metricsFactory, nil)
+	return w
+}
+
+// WriteSpan writes the span to the wrapped writer, unless the breaker is open.
+func (w *Writer) WriteSpan(span *model.Span) error {
+	if !w.allow() {
+		w.metrics.Shed.Inc(1)
+		return ErrOpen
+	}
+	err := w.writer.WriteSpan(span)
+	w.record(err)
+	if err != nil {
+		w.metrics.Failed.Inc(1)
+		return err
+	}
+	w.metrics.Written.Inc(1)
+	return nil
+}
+
+// State returns the current state of the breaker.
+func (w *Writer) State() State {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.state
+}
+
+func (w *Writer) allow() bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	switch w.state {
+	case Open:
+		if w.now().Sub(w.openedAt) < w.openDuration {
+			return false
+		}
+		w.setState(HalfOpen)
+		w.probing = true
+		return true
+	case HalfOpen:
+		if w.probing {
+			return false
+		}
+		w.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (w *Writer) record(err error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	probe := w.state == HalfOpen && w.probing
+	if probe {
+		w.probing = false
+	}
+	if err == nil {
+		w.failures = 0
+		if w.state != Closed && probe {
+			w.logger.Info("Span storage recovered, closing the circuit breaker")
+			w.metrics.Closed.Inc(1)
+			w.setState(Closed)
+		}
+		return
+	}
+	w.failures++
+	if probe || (w.state == Closed && w.failures >= w.failureThreshold) {
+		if w.state == Closed {
+			w.logger.Warn("Span storage is failing, opening the circuit breaker",
+				zap.Int("consecutive-failures", w.failures), zap.Duration("open-duration", w.openDuration), zap.Error(err))
+		}
+		w.metrics.Opened.Inc(1)
+		w.openedAt = w.now()
+		w.setState(Open)
+	}
+}
+
+func (w *Writer) setState(state State) {
+	w.state = state
+	w.metrics.State.Update(int64(state))
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package circuitbreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/config"
+)
+
+type fakeWriter struct {
+	err   error
+	calls int
+}
+
+func (w *fakeWriter) WriteSpan(span *model.Span) error {
+	w.calls++
+	return w.err
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestWriter(inner *fakeWriter, mf *metricstest.Factory) (*Writer, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	w := NewWriter(inner, Options{FailureThreshold: 3, OpenDuration: time.Second}, zap.NewNop(), mf)
+	w.now = clock.Now
+	return w, clock
+}
+
+func TestOptionsWithFlags(t *testing.T) {
+	v, command := config.Viperize(AddFlags)
+	opts := new(Options).InitFromViper(v)
+	assert.False(t, opts.Enabled())
+	assert.Equal(t, defaultOpenDuration, opts.OpenDuration)
+
+	command.ParseFlags([]string{
+		"--collector.circuit-breaker.failure-threshold=5",
+		"--collector.circuit-breaker.open-duration=1m",
+	})
+	opts.InitFromViper(v)
+	assert.True(t, opts.Enabled())
+	assert.Equal(t, 5, opts.FailureThreshold)
+	assert.Equal(t, time.Minute, opts.OpenDuration)
+}
+
+func TestWriterOpensAfterConsecutiveFailures(t *testing.T) {
+	inner := &fakeWriter{}
+	mf := metricstest.NewFactory(0)
+	w, _ := newTestWriter(inner, mf)
+
+	assert.NoError(t, w.WriteSpan(&model.Span{}))
+	inner.err = errors.New("storage unavailable")
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, inner.err, w.WriteSpan(&model.Span{}))
+		assert.Equal(t, Closed, w.State())
+	}
+	// a success resets the count of consecutive failures
+	inner.err = nil
+	assert.NoError(t, w.WriteSpan(&model.Span{}))
+	inner.err = errors.New("storage unavailable")
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, inner.err, w.WriteSpan(&model.Span{}))
+	}
+	assert.Equal(t, Open, w.State())
+
+	calls := inner.calls
+	assert.Equal(t, ErrOpen, w.WriteSpan(&model.Span{}))
+	assert.Equal(t, calls, inner.calls)
+
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "spans", Tags: map[string]string{"result": "written"}, Value: 2},
+		metricstest.ExpectedMetric{Name: "spans", Tags: map[string]string{"result": "failed"}, Value: 5},
+		metricstest.ExpectedMetric{Name: "spans", Tags: map[string]string{"result": "shed"}, Value: 1},
+		metricstest.ExpectedMetric{Name: "transitions", Tags: map[string]string{"state": "open"}, Value: 1},
+	)
+	mf.AssertGaugeMetrics(t, metricstest.ExpectedMetric{Name: "state", Value: int(Open)})
+}
+
+func TestWriterProbesForRecovery(t *testing.T) {
+	inner := &fakeWriter{err: errors.New("storage unavailable")}
+	mf := metricstest.NewFactory(0)
+	w, clock := newTestWriter(inner, mf)
+	for i := 0; i < 3; i++ {
+		w.WriteSpan(&model.Span{})
+	}
+	assert.Equal(t, Open, w.State())
+
+	// a failed probe opens the breaker for another open duration
+	clock.now = clock.now.Add(time.Second)
+	assert.Equal(t, inner.err, w.WriteSpan(&model.Span{}))
+	assert.Equal(t, Open, w.State())
+	assert.Equal(t, ErrOpen, w.WriteSpan(&model.Span{}))
+
+	clock.now = clock.now.Add(time.Second)
+	inner.err = nil
+	assert.NoError(t, w.WriteSpan(&model.Span{}))
+	assert.Equal(t, Closed, w.State())
+	assert.NoError(t, w.WriteSpan(&model.Span{}))
+
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "transitions", Tags: map[string]string{"state": "open"}, Value: 2},
+		metricstest.ExpectedMetric{Name: "transitions", Tags: map[string]string{"state": "closed"}, Value: 1},
+	)
+	mf.AssertGaugeMetrics(t, metricstest.ExpectedMetric{Name: "state", Value: int(Closed)})
+}
+
+func TestWriterShedsWhileProbing(t *testing.T) {
+	w, _ := newTestWriter(&fakeWriter{}, metricstest.NewFactory(0))
+	w.state = HalfOpen
+	assert.True(t, w.allow())
+	assert.False(t, w.allow())
+	w.record(nil)
+	assert.Equal(t, Closed, w.State())
+	assert.True(t, w.allow())
+}
+
+func TestStateString(t *testing.T) {
+	assert.Equal(t, "closed", Closed.String())
+	assert.Equal(t, "open", Open.String())
+	assert.Equal(t, "half-open", HalfOpen.String())
+}
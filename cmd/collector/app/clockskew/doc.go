@@ -0,0 +1,22 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package clockskew implements an optional collector stage that corrects clock skew
+// between parent and child spans before they are written, so that the correction is
+// stored once instead of being recomputed by the query service on every read.
+//
+// Parent and child spans are usually reported by different hosts and at different
+// times, so the spans of each trace are buffered for a short window and adjusted
+// together. Spans arriving after their trace has been flushed are written unchanged.
+package clockskew
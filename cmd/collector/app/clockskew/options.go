@@ -0,0 +1,62 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clockskew
+
+import (
+	"flag"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	maxAdjustment = "collector.clock-skew.max-adjustment"
+	bufferWait    = "collector.clock-skew.buffer-wait"
+	maxTraces     = "collector.clock-skew.max-traces"
+
+	defaultBufferWait = 5 * time.Second
+	defaultMaxTraces  = 10000
+)
+
+// Options controls the write-time clock skew correction of the collector.
+type Options struct {
+	// MaxAdjustment is the largest correction applied to a span, 0 disables the stage
+	MaxAdjustment time.Duration
+	// BufferWait is how long spans of a trace are buffered, counting from its first span, before being adjusted
+	BufferWait time.Duration
+	// MaxTraces is the maximum number of traces held in memory; the oldest trace is flushed early when exceeded
+	MaxTraces int
+}
+
+// AddFlags adds flags for clock skew Options
+func AddFlags(flags *flag.FlagSet) {
+	flags.Duration(maxAdjustment, 0, "(experimental) The maximum delta by which span timestamps may be adjusted at ingestion time "+
+		"to correct clock skew between parent and child spans; 0 disables the adjustment")
+	flags.Duration(bufferWait, defaultBufferWait, "How long to buffer spans of a trace, counting from its first span, before correcting clock skew")
+	flags.Int(maxTraces, defaultMaxTraces, "The maximum number of traces buffered for clock skew correction; the oldest traces are flushed early when the limit is reached")
+}
+
+// InitFromViper initializes Options with properties from viper
+func (o *Options) InitFromViper(v *viper.Viper) *Options {
+	o.MaxAdjustment = v.GetDuration(maxAdjustment)
+	o.BufferWait = v.GetDuration(bufferWait)
+	o.MaxTraces = v.GetInt(maxTraces)
+	return o
+}
+
+// Enabled returns true if spans should be adjusted before being written.
+func (o *Options) Enabled() bool {
+	return o.MaxAdjustment > 0
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clockskew
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jaegertracing/jaeger/pkg/config"
+)
+
+func TestOptionsWithFlags(t *testing.T) {
+	v, command := config.Viperize(AddFlags)
+	opts := new(Options).InitFromViper(v)
+	assert.False(t, opts.Enabled())
+	assert.Equal(t, defaultBufferWait, opts.BufferWait)
+	assert.Equal(t, defaultMaxTraces, opts.MaxTraces)
+
+	command.ParseFlags([]string{
+		"--collector.clock-skew.max-adjustment=1s",
+		"--collector.clock-skew.buffer-wait=3s",
+		"--collector.clock-skew.max-traces=10",
+	})
+	opts.InitFromViper(v)
+	assert.True(t, opts.Enabled())
+	assert.Equal(t, time.Second, opts.MaxAdjustment)
+	assert.Equal(t, 3*time.Second, opts.BufferWait)
+	assert.Equal(t, 10, opts.MaxTraces)
+}
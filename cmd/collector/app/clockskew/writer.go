@@ -0,0 +1,315 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clockskew
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/cmd/collector/app/processor"
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/model/adjuster"
+	"github.com/jaegertracing/jaeger/pkg/cache"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+type clockSkewMetrics struct {
+	TracesAdjusted  metrics.Counter `metric:"traces" tags:"result=adjusted"`
+	TracesUnchanged metrics.Counter `metric:"traces" tags:"result=unchanged"`
+	TracesSkipped   metrics.Counter `metric:"traces" tags:"result=skipped"`
+	TracesEvicted   metrics.Counter `metric:"traces_evicted"`
+	SpansAdjusted   metrics.Counter `metric:"spans_adjusted"`
+	LateSpans       metrics.Counter `metric:"late_spans"`
+	SpansFailed     metrics.Counter `metric:"spans_failed"`
+	BufferedTraces  metrics.Gauge   `metric:"buffered_traces"`
+}
+
+type traceBuffer struct {
+	traceID   model.TraceID
+	firstSeen time.Time
+	spans     []*model.Span
+	saved     []func(err error)
+	element   *list.Element
+}
+
+// Writer is a span Writer that buffers spans per trace for a short window and corrects
+// clock skew between parent and child spans before forwarding them.
+type Writer struct {
+	spanWriter spanstore.Writer
+	adjuster   adjuster.Adjuster
+	bufferWait time.Duration
+	maxTraces  int
+	logger     *zap.Logger
+	metrics    clockSkewMetrics
+
+	lock   sync.Mutex
+	traces map[model.TraceID]*traceBuffer
+	order  *list.List // traces in the order of their first span
+
+	// flushed remembers recently flushed traces so that late spans are not buffered again
+	flushed cache.Cache
+
+	timeNow func() time.Time
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewWriter creates a Writer correcting clock skew of spans before writing them to spanWriter.
+func NewWriter(spanWriter spanstore.Writer, opts Options, logger *zap.Logger, metricsFactory metrics.Factory) *Writer {
+	if opts.BufferWait <= 0 {
+		opts.BufferWait = defaultBufferWait
+	}
+	if opts.MaxTraces <= 0 {
+		opts.MaxTraces = defaultMaxTraces
+	}
+	w := &Writer{
+		spanWriter: spanWriter,
+		adjuster:   adjuster.ClockSkew(opts.MaxAdjustment),
+		bufferWait: opts.BufferWait,
+		maxTraces:  opts.MaxTraces,
+		logger:     logger,
+		traces:     make(map[model.TraceID]*traceBuffer),
+		order:      list.New(),
+		flushed:    cache.NewLRU(opts.MaxTraces),
+		timeNow:    time.Now,
+		stopCh:     make(chan struct{}),
+	}
+	metrics.Init(&w.metrics, metricsFactory, nil)
+	logger.Info("Write-time clock skew correction enabled",
+		zap.Duration("max-adjustment", opts.MaxAdjustment),
+		zap.Duration("buffer-wait", w.bufferWait),
+		zap.Int("max-traces", w.maxTraces))
+	return w
+}
+
+// Start launches the background routine that flushes traces whose window has elapsed.
+func (w *Writer) Start() {
+	interval := w.bufferWait / 10
+	if interval < 100*time.Millisecond {
+		interval = 100 * time.Millisecond
+	}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w.flushExpired()
+			case <-w.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// WriteSpan buffers the span until its trace is flushed, or writes it directly if the trace was already flushed.
+// The failures to save the buffered spans are counted and returned by Flush, use WriteSpanDeferred to learn
+// whether each span was saved.
+func (w *Writer) WriteSpan(span *model.Span) error {
+	if w.flushed.Get(span.TraceID.String()) != nil {
+		w.metrics.LateSpans.Inc(1)
+		return w.write(span, nil)
+	}
+	w.buffer(span, nil)
+	return nil
+}
+
+// WriteSpanDeferred buffers the span like WriteSpan, and calls saved with the result of writing
+// the span once its trace is flushed.
+func (w *Writer) WriteSpanDeferred(span *model.Span, saved func(err error)) {
+	if w.flushed.Get(span.TraceID.String()) != nil {
+		w.metrics.LateSpans.Inc(1)
+		w.write(span, saved)
+		return
+	}
+	w.buffer(span, saved)
+}
+
+func (w *Writer) buffer(span *model.Span, saved func(err error)) {
+	var evicted *traceBuffer
+	w.lock.Lock()
+	tb, ok := w.traces[span.TraceID]
+	if !ok {
+		if len(w.traces) >= w.maxTraces {
+			evicted = w.removeLocked(w.order.Front())
+		}
+		tb = &traceBuffer{traceID: span.TraceID, firstSeen: w.timeNow()}
+		tb.element = w.order.PushBack(tb)
+		w.traces[span.TraceID] = tb
+	}
+	tb.spans = append(tb.spans, span)
+	tb.saved = append(tb.saved, saved)
+	w.lock.Unlock()
+
+	if evicted != nil {
+		w.metrics.TracesEvicted.Inc(1)
+		w.flush(evicted)
+	}
+}
+
+// Close flushes all buffered traces immediately and stops the background routine.
+func (w *Writer) Close() error {
+	close(w.stopCh)
+	w.wg.Wait()
+	return w.Flush()
+}
+
+// Flush writes all buffered traces immediately, e.g. when the collector is drained,
+// and returns the first error of the spans that could not be saved.
+func (w *Writer) Flush() error {
+	w.lock.Lock()
+	var remaining []*traceBuffer
+	for w.order.Len() > 0 {
+		remaining = append(remaining, w.removeLocked(w.order.Front()))
+	}
+	w.metrics.BufferedTraces.Update(0)
+	w.lock.Unlock()
+
+	var firstErr error
+	for _, tb := range remaining {
+		if err := w.flush(tb); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (w *Writer) flushExpired() {
+	deadline := w.timeNow().Add(-w.bufferWait)
+	var expired []*traceBuffer
+	w.lock.Lock()
+	for e := w.order.Front(); e != nil && !e.Value.(*traceBuffer).firstSeen.After(deadline); e = w.order.Front() {
+		expired = append(expired, w.removeLocked(e))
+	}
+	w.metrics.BufferedTraces.Update(int64(len(w.traces)))
+	w.lock.Unlock()
+
+	for _, tb := range expired {
+		w.flush(tb)
+	}
+}
+
+func (w *Writer) removeLocked(e *list.Element) *traceBuffer {
+	tb := w.order.Remove(e).(*traceBuffer)
+	delete(w.traces, tb.traceID)
+	return tb
+}
+
+// flush adjusts and writes the spans of a trace, returning the first error
+func (w *Writer) flush(tb *traceBuffer) error {
+	w.flushed.Put(tb.traceID.String(), true)
+	w.adjust(tb.spans)
+	var firstErr error
+	for i, span := range tb.spans {
+		if err := w.write(span, tb.saved[i]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// deferredWriter is a span writer buffering the spans, e.g. the tail sampler, which
+// reports whether each span was saved once it is written to the next writer.
+type deferredWriter interface {
+	WriteSpanDeferred(span *model.Span, saved func(err error))
+}
+
+// write writes the span to the next writer. When the span has a saved callback and the next
+// writer buffers spans too, the callback is passed down and the error is only reported to it.
+func (w *Writer) write(span *model.Span, saved func(err error)) error {
+	if dw, ok := w.spanWriter.(deferredWriter); ok && saved != nil {
+		dw.WriteSpanDeferred(span, func(err error) {
+			w.written(span, err)
+			saved(err)
+		})
+		return nil
+	}
+	err := w.spanWriter.WriteSpan(span)
+	w.written(span, err)
+	if saved != nil {
+		saved(err)
+	}
+	return err
+}
+
+func (w *Writer) written(span *model.Span, err error) {
+	if err != nil && !errors.Is(err, processor.ErrSpanDropped) {
+		w.metrics.SpansFailed.Inc(1)
+		w.logger.Error("Failed to save clock skew adjusted span", zap.Error(err), zap.Stringer("trace-id", span.TraceID))
+	}
+}
+
+// adjust corrects the timestamps of spans in place. Traces with duplicate span IDs,
+// such as Zipkin shared spans, are left alone, because resolving the duplicates
+// requires rewriting span IDs which should not be persisted.
+//
+// The buffered spans are often an incomplete trace, so the warnings recorded by the
+// adjuster are only kept on the spans it actually moved; the query service reports
+// the remaining issues once the whole trace is read.
+func (w *Writer) adjust(spans []*model.Span) {
+	if len(spans) < 2 || hasDuplicateSpanIDs(spans) {
+		w.metrics.TracesSkipped.Inc(1)
+		return
+	}
+	starts := make([]time.Time, len(spans))
+	warnings := make([]int, len(spans))
+	for i, span := range spans {
+		starts[i] = span.StartTime
+		warnings[i] = len(span.Warnings)
+	}
+	if _, err := w.adjuster.Adjust(&model.Trace{Spans: spans}); err != nil {
+		w.logger.Error("Failed to correct clock skew", zap.Error(err), zap.Stringer("trace-id", spans[0].TraceID))
+		return
+	}
+	adjusted := 0
+	for i, span := range spans {
+		if !span.StartTime.Equal(starts[i]) {
+			adjusted++
+			continue
+		}
+		if len(span.Warnings) == warnings[i] {
+			continue
+		}
+		if warnings[i] == 0 {
+			span.Warnings = nil
+		} else {
+			span.Warnings = span.Warnings[:warnings[i]]
+		}
+	}
+	if adjusted == 0 {
+		w.metrics.TracesUnchanged.Inc(1)
+		return
+	}
+	w.metrics.TracesAdjusted.Inc(1)
+	w.metrics.SpansAdjusted.Inc(int64(adjusted))
+}
+
+func hasDuplicateSpanIDs(spans []*model.Span) bool {
+	ids := make(map[model.SpanID]struct{}, len(spans))
+	for _, span := range spans {
+		if _, ok := ids[span.SpanID]; ok {
+			return true
+		}
+		ids[span.SpanID] = struct{}{}
+	}
+	return false
+}
@@ -0,0 +1,258 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clockskew
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/cmd/collector/app/processor"
+	"github.com/jaegertracing/jaeger/model"
+)
+
+type fakeWriter struct {
+	lock  sync.Mutex
+	spans []*model.Span
+	err   error
+}
+
+func (w *fakeWriter) WriteSpan(span *model.Span) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	w.spans = append(w.spans, span)
+	return nil
+}
+
+func (w *fakeWriter) count() int {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return len(w.spans)
+}
+
+var baseTime = time.Unix(1000, 0)
+
+func makeSpan(traceID uint64, spanID, parentID model.SpanID, ip string, start, duration time.Duration) *model.Span {
+	span := &model.Span{
+		TraceID:   model.NewTraceID(0, traceID),
+		SpanID:    spanID,
+		StartTime: baseTime.Add(start),
+		Duration:  duration,
+		Process:   model.NewProcess("svc", []model.KeyValue{model.String("ip", ip)}),
+	}
+	if parentID != 0 {
+		span.References = []model.SpanRef{model.NewChildOfRef(span.TraceID, parentID)}
+	}
+	return span
+}
+
+func newTestWriter(opts Options) (*Writer, *fakeWriter, *metricstest.Factory, *time.Time) {
+	writer := &fakeWriter{}
+	mf := metricstest.NewFactory(0)
+	w := NewWriter(writer, opts, zap.NewNop(), mf)
+	now := time.Unix(1000, 0)
+	w.timeNow = func() time.Time { return now }
+	return w, writer, mf, &now
+}
+
+func TestWriterAdjustsChildFromSkewedHost(t *testing.T) {
+	w, writer, mf, now := newTestWriter(Options{MaxAdjustment: time.Minute, BufferWait: time.Second})
+
+	// the child reports first and its host clock is 10s behind the parent's host
+	child := makeSpan(1, 2, 1, "10.0.0.2", -10*time.Second+10*time.Millisecond, 80*time.Millisecond)
+	grandChild := makeSpan(1, 3, 2, "10.0.0.2", -10*time.Second+20*time.Millisecond, 10*time.Millisecond)
+	parent := makeSpan(1, 1, 0, "10.0.0.1", 0, 100*time.Millisecond)
+	require.NoError(t, w.WriteSpan(child))
+	require.NoError(t, w.WriteSpan(grandChild))
+	require.NoError(t, w.WriteSpan(parent))
+	w.flushExpired()
+	assert.Equal(t, 0, writer.count(), "nothing is written before the window elapses")
+
+	*now = now.Add(time.Second)
+	w.flushExpired()
+	require.Equal(t, 3, writer.count())
+	assert.Equal(t, baseTime.Add(10*time.Millisecond), child.StartTime)
+	assert.Equal(t, baseTime.Add(20*time.Millisecond), grandChild.StartTime, "descendants on the same host move with the child")
+	assert.Equal(t, baseTime, parent.StartTime)
+	assert.Len(t, child.Warnings, 1)
+	assert.Empty(t, parent.Warnings)
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "traces", Tags: map[string]string{"result": "adjusted"}, Value: 1},
+		metricstest.ExpectedMetric{Name: "spans_adjusted", Value: 2},
+	)
+}
+
+func TestWriterRespectsMaxAdjustment(t *testing.T) {
+	w, writer, mf, _ := newTestWriter(Options{MaxAdjustment: time.Second})
+
+	child := makeSpan(1, 2, 1, "10.0.0.2", -time.Minute, 80*time.Millisecond)
+	parent := makeSpan(1, 1, 0, "10.0.0.1", 0, 100*time.Millisecond)
+	require.NoError(t, w.WriteSpan(child))
+	require.NoError(t, w.WriteSpan(parent))
+	require.NoError(t, w.Close())
+
+	require.Equal(t, 2, writer.count())
+	assert.Equal(t, baseTime.Add(-time.Minute), child.StartTime)
+	assert.Empty(t, child.Warnings, "warnings of spans that were not moved are left to the query service")
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "traces", Tags: map[string]string{"result": "unchanged"}, Value: 1},
+	)
+}
+
+func TestWriterKeepsIncompleteTracesUntouched(t *testing.T) {
+	w, writer, mf, _ := newTestWriter(Options{MaxAdjustment: time.Minute})
+
+	orphan := makeSpan(1, 2, 1, "10.0.0.2", 0, time.Millisecond)
+	sibling := makeSpan(1, 3, 1, "10.0.0.3", time.Hour, time.Millisecond)
+	orphan.Warnings = []string{"existing"}
+	require.NoError(t, w.WriteSpan(orphan))
+	require.NoError(t, w.WriteSpan(sibling))
+	require.NoError(t, w.Close())
+
+	require.Equal(t, 2, writer.count())
+	assert.Equal(t, []string{"existing"}, orphan.Warnings)
+	assert.Nil(t, sibling.Warnings)
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "traces", Tags: map[string]string{"result": "unchanged"}, Value: 1},
+	)
+}
+
+func TestWriterSkipsDuplicateSpanIDs(t *testing.T) {
+	w, writer, mf, _ := newTestWriter(Options{MaxAdjustment: time.Minute})
+
+	// Zipkin shared span: client and server sides reported under the same span ID
+	client := makeSpan(1, 1, 0, "10.0.0.1", 0, 100*time.Millisecond)
+	server := makeSpan(1, 1, 0, "10.0.0.2", -10*time.Second, 80*time.Millisecond)
+	require.NoError(t, w.WriteSpan(client))
+	require.NoError(t, w.WriteSpan(server))
+	require.NoError(t, w.Close())
+
+	require.Equal(t, 2, writer.count())
+	assert.Equal(t, baseTime.Add(-10*time.Second), server.StartTime)
+	assert.Empty(t, server.Warnings)
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "traces", Tags: map[string]string{"result": "skipped"}, Value: 1},
+	)
+}
+
+func TestWriterLateSpans(t *testing.T) {
+	w, writer, mf, now := newTestWriter(Options{MaxAdjustment: time.Minute, BufferWait: time.Second})
+
+	require.NoError(t, w.WriteSpan(makeSpan(1, 1, 0, "10.0.0.1", 0, time.Millisecond)))
+	*now = now.Add(time.Second)
+	w.flushExpired()
+	require.Equal(t, 1, writer.count())
+
+	require.NoError(t, w.WriteSpan(makeSpan(1, 2, 1, "10.0.0.2", 0, time.Millisecond)))
+	assert.Equal(t, 2, writer.count(), "late spans are written immediately")
+	mf.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "late_spans", Value: 1})
+}
+
+func TestWriterEvictsOldestTrace(t *testing.T) {
+	w, writer, mf, _ := newTestWriter(Options{MaxAdjustment: time.Minute, MaxTraces: 1})
+
+	require.NoError(t, w.WriteSpan(makeSpan(1, 1, 0, "10.0.0.1", 0, time.Millisecond)))
+	require.NoError(t, w.WriteSpan(makeSpan(2, 1, 0, "10.0.0.1", 0, time.Millisecond)))
+	assert.Equal(t, 1, writer.count())
+	mf.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "traces_evicted", Value: 1})
+
+	require.NoError(t, w.Close())
+	assert.Equal(t, 2, writer.count())
+}
+
+func TestWriterFlush(t *testing.T) {
+	w, writer, _, _ := newTestWriter(Options{MaxAdjustment: time.Minute, BufferWait: time.Hour})
+	w.Start()
+	defer w.Close()
+
+	require.NoError(t, w.WriteSpan(makeSpan(1, 1, 0, "10.0.0.1", 0, time.Millisecond)))
+	require.NoError(t, w.WriteSpan(makeSpan(2, 1, 0, "10.0.0.1", 0, time.Millisecond)))
+	assert.Equal(t, 0, writer.count())
+	require.NoError(t, w.Flush())
+	assert.Equal(t, 2, writer.count())
+}
+
+func TestWriterSaveFailures(t *testing.T) {
+	w, writer, mf, _ := newTestWriter(Options{MaxAdjustment: time.Minute, BufferWait: time.Hour})
+	writer.err = errors.New("storage down")
+
+	var results []error
+	saved := func(err error) { results = append(results, err) }
+	w.WriteSpanDeferred(makeSpan(1, 1, 0, "10.0.0.1", 0, time.Millisecond), saved)
+	require.NoError(t, w.WriteSpan(makeSpan(1, 2, 1, "10.0.0.1", 0, time.Millisecond)))
+	assert.Empty(t, results, "the result is only known once the trace is flushed")
+
+	assert.Equal(t, writer.err, w.Flush())
+	assert.Equal(t, []error{writer.err}, results)
+	mf.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "spans_failed", Value: 2})
+
+	// the late spans are written directly
+	assert.Equal(t, writer.err, w.WriteSpan(makeSpan(1, 3, 1, "10.0.0.1", 0, time.Millisecond)))
+	writer.err = nil
+	w.WriteSpanDeferred(makeSpan(1, 4, 1, "10.0.0.1", 0, time.Millisecond), saved)
+	assert.Equal(t, []error{nil}, results[1:])
+}
+
+type deferringWriter struct {
+	fakeWriter
+	saved []func(err error)
+}
+
+func (w *deferringWriter) WriteSpanDeferred(span *model.Span, saved func(err error)) {
+	w.saved = append(w.saved, saved)
+}
+
+func TestWriterPassesSavedToDeferredWriter(t *testing.T) {
+	writer := &deferringWriter{}
+	mf := metricstest.NewFactory(0)
+	w := NewWriter(writer, Options{MaxAdjustment: time.Minute, BufferWait: time.Hour}, zap.NewNop(), mf)
+
+	var results []error
+	saved := func(err error) { results = append(results, err) }
+	w.WriteSpanDeferred(makeSpan(1, 1, 0, "10.0.0.1", 0, time.Millisecond), saved)
+	w.WriteSpanDeferred(makeSpan(1, 2, 1, "10.0.0.1", 0, time.Millisecond), saved)
+	require.NoError(t, w.WriteSpan(makeSpan(1, 3, 1, "10.0.0.1", 0, time.Millisecond)))
+	require.NoError(t, w.Flush())
+	assert.Equal(t, 1, writer.count(), "spans without a callback are written directly")
+	require.Len(t, writer.saved, 2)
+	assert.Empty(t, results, "the result is only known once the next writer reports it")
+
+	writer.saved[0](nil)
+	writer.saved[1](processor.ErrSpanDropped)
+	assert.Equal(t, []error{nil, processor.ErrSpanDropped}, results)
+	mf.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "spans_failed", Value: 0})
+}
+
+func TestWriterStartFlushesInBackground(t *testing.T) {
+	writer := &fakeWriter{}
+	w := NewWriter(writer, Options{MaxAdjustment: time.Minute, BufferWait: time.Millisecond}, zap.NewNop(), metricstest.NewFactory(0))
+	w.Start()
+	defer w.Close()
+
+	require.NoError(t, w.WriteSpan(makeSpan(1, 1, 0, "10.0.0.1", 0, time.Millisecond)))
+	for i := 0; i < 100 && writer.count() == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, 1, writer.count())
+}
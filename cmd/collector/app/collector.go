@@ -16,17 +16,40 @@ package app
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
+	"github.com/spf13/viper"
 	"github.com/uber/jaeger-lib/metrics"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 
+	"github.com/jaegertracing/jaeger/cmd/collector/app/circuitbreaker"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/clockskew"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/dedup"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/dropped"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/filter"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/k8sattributes"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/peerrouting"
 	"github.com/jaegertracing/jaeger/cmd/collector/app/processor"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/ratelimit"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/redaction"
 	"github.com/jaegertracing/jaeger/cmd/collector/app/sampling/strategystore"
 	"github.com/jaegertracing/jaeger/cmd/collector/app/server"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/spanlimits"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/spanmetrics"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/spillover"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/tailsampling"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/tenantquota"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/zpages"
+	"github.com/jaegertracing/jaeger/cmd/flags"
+	"github.com/jaegertracing/jaeger/pkg/auth"
+	"github.com/jaegertracing/jaeger/pkg/configreload"
 	"github.com/jaegertracing/jaeger/pkg/healthcheck"
+	"github.com/jaegertracing/jaeger/pkg/queue"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
 	"github.com/jaegertracing/jaeger/storage/spanstore"
 )
 
@@ -39,13 +62,28 @@ type Collector struct {
 	spanWriter     spanstore.Writer
 	strategyStore  strategystore.StrategyStore
 	hCheck         *healthcheck.HealthCheck
+	config         *viper.Viper
 	spanProcessor  processor.SpanProcessor
 	spanHandlers   *SpanHandlers
+	tailSampler    *tailsampling.Processor
+	clockSkew      *clockskew.Writer
+	spillover      *spillover.Writer
+	droppedSpans   *dropped.Tracker
+	adminAuth      *auth.Authenticator
+	spanMetrics    *spanmetrics.RemoteWriter
+	zpages         *zpages.Recorder
+	k8sEnricher    *k8sattributes.Enricher
+	peerRouter     *peerrouting.Processor
+	spanFilter     *filter.Filter
+	configWatcher  *configreload.Watcher
 
 	// state, read only
-	hServer    *http.Server
-	zkServer   *http.Server
-	grpcServer *grpc.Server
+	hServer        *http.Server
+	zkServer       *http.Server
+	grpcServer     *grpc.Server
+	otlpGRPCServer *grpc.Server
+	otlpHTTPServer *http.Server
+	ocServer       *grpc.Server
 }
 
 // CollectorParams to construct a new Jaeger Collector.
@@ -56,6 +94,8 @@ type CollectorParams struct {
 	SpanWriter     spanstore.Writer
 	StrategyStore  strategystore.StrategyStore
 	HealthCheck    *healthcheck.HealthCheck
+	// Config is the viper instance the options were read from, required to reload the configuration file
+	Config *viper.Viper
 }
 
 // New constructs a new collector component, ready to be started
@@ -67,27 +107,259 @@ func New(params *CollectorParams) *Collector {
 		spanWriter:     params.SpanWriter,
 		strategyStore:  params.StrategyStore,
 		hCheck:         params.HealthCheck,
+		config:         params.Config,
 	}
 }
 
 // Start the component and underlying dependencies
-func (c *Collector) Start(builderOpts *CollectorOptions) error {
+func (c *Collector) Start(builderOpts *CollectorOptions) (err error) {
+	defer func() {
+		if err != nil {
+			c.stopStages()
+		}
+	}()
+	c.droppedSpans = dropped.NewTracker(c.metricsFactory.Namespace(metrics.NSOptions{Name: "dropped_by_svc"}))
+	spanWriter := c.spanWriter
+	if builderOpts.CircuitBreaker.Enabled() {
+		c.logger.Info("Span writer circuit breaker enabled",
+			zap.Int("failure-threshold", builderOpts.CircuitBreaker.FailureThreshold),
+			zap.Duration("open-duration", builderOpts.CircuitBreaker.OpenDuration))
+		// the spillover, when enabled, keeps the spans shed by the breaker
+		spanWriter = circuitbreaker.NewWriter(
+			spanWriter,
+			builderOpts.CircuitBreaker,
+			c.logger,
+			c.metricsFactory.Namespace(metrics.NSOptions{Name: "circuit_breaker"}),
+		)
+	}
+	if builderOpts.Spillover.Directory != "" {
+		spilloverWriter, err := spillover.NewWriter(
+			spanWriter,
+			builderOpts.Spillover,
+			c.logger,
+			c.metricsFactory.Namespace(metrics.NSOptions{Name: "spillover"}),
+		)
+		if err != nil {
+			return err
+		}
+		c.spillover = spilloverWriter
+		spanWriter = spilloverWriter
+	}
+	if builderOpts.TailSampling.Enabled {
+		if !builderOpts.PeerRouting.Enabled() {
+			c.logger.Info("Tail sampling without peer routing: with several collectors, " +
+				"the policies other than probabilistic only see the spans of a trace received by each collector")
+		}
+		c.tailSampler = tailsampling.NewProcessor(
+			spanWriter,
+			builderOpts.TailSampling,
+			c.logger,
+			c.metricsFactory.Namespace(metrics.NSOptions{Name: "tail_sampling"}),
+		)
+		c.tailSampler.Start()
+		spanWriter = c.tailSampler
+	}
+	if builderOpts.ClockSkew.Enabled() {
+		// adjusted before tail sampling so that latency policies see corrected timestamps
+		c.clockSkew = clockskew.NewWriter(
+			spanWriter,
+			builderOpts.ClockSkew,
+			c.logger,
+			c.metricsFactory.Namespace(metrics.NSOptions{Name: "clock_skew"}),
+		)
+		c.clockSkew.Start()
+		spanWriter = c.clockSkew
+	}
+
 	handlerBuilder := &SpanHandlerBuilder{
-		SpanWriter:     c.spanWriter,
+		SpanWriter:     spanWriter,
 		CollectorOpts:  *builderOpts,
 		Logger:         c.logger,
 		MetricsFactory: c.metricsFactory,
+		DroppedSpans:   c.droppedSpans,
+	}
+	if frac := builderOpts.DynQueueSizeMemoryFraction; frac > 0 {
+		if frac > 1 {
+			return fmt.Errorf("the queue memory fraction must be between 0 and 1, got %v", frac)
+		}
+		available, err := availableMemory()
+		if err != nil {
+			return err
+		}
+		handlerBuilder.CollectorOpts.DynQueueSizeMemory = uint(frac * float64(available))
+		c.logger.Info("Sizing the queue from the available memory",
+			zap.Uint64("available-memory-mib", available/1024/1024),
+			zap.Float64("fraction", frac))
+	}
+	if builderOpts.SpanMetrics.Enabled {
+		spanMetricsFactory := c.metricsFactory.Namespace(metrics.NSOptions{Name: "span_metrics"})
+		aggregator, err := spanmetrics.NewAggregator(builderOpts.SpanMetrics, spanMetricsFactory)
+		if err != nil {
+			return err
+		}
+		handlerBuilder.PreSave = aggregator.ProcessSpan
+		if builderOpts.SpanMetrics.RemoteWriteURL != "" {
+			c.spanMetrics = spanmetrics.NewRemoteWriter(builderOpts.SpanMetrics, aggregator, c.logger, spanMetricsFactory)
+			c.spanMetrics.Start()
+		}
+		c.logger.Info("Span metrics enabled", zap.String("remote-write-url", builderOpts.SpanMetrics.RemoteWriteURL))
+	}
+	if builderOpts.ZPages.Enabled {
+		c.zpages = zpages.NewRecorder(builderOpts.ZPages)
+		if handlerBuilder.PreSave != nil {
+			handlerBuilder.PreSave = ChainedProcessSpan(handlerBuilder.PreSave, c.zpages.ProcessSpan)
+		} else {
+			handlerBuilder.PreSave = c.zpages.ProcessSpan
+		}
+	}
+	if builderOpts.K8sAttributes.Enabled {
+		enricher, err := k8sattributes.NewEnricher(builderOpts.K8sAttributes, c.logger,
+			c.metricsFactory.Namespace(metrics.NSOptions{Name: "k8s_attributes"}))
+		if err != nil {
+			return err
+		}
+		enricher.Start()
+		c.k8sEnricher = enricher
+		handlerBuilder.EnrichSpans = enricher.Enrich
+		c.logger.Info("Kubernetes metadata enrichment enabled", zap.Bool("pod-lookup", builderOpts.K8sAttributes.PodLookup))
+	}
+	if builderOpts.Tenancy.Enabled {
+		handlerBuilder.TenancyMgr = tenancy.NewManager(&builderOpts.Tenancy)
+		c.logger.Info("Multi-tenancy enabled", zap.String("header", handlerBuilder.TenancyMgr.Header))
+	}
+	if builderOpts.SpanFilter.Enabled() {
+		cfg, err := builderOpts.SpanFilter.Config()
+		if err != nil {
+			return err
+		}
+		spanFilter, err := filter.NewFilter(cfg, c.metricsFactory.Namespace(metrics.NSOptions{Name: "span_filter"}))
+		if err != nil {
+			return err
+		}
+		c.logger.Info("Span filter enabled", zap.String("rules-file", builderOpts.SpanFilter.RulesFile), zap.Int("rules", len(cfg.Rules)))
+		c.spanFilter = spanFilter
+		handlerBuilder.SpanFilter = spanFilter.Keep
+	}
+	if builderOpts.Dedup.Enabled {
+		deduplicator := dedup.NewDeduplicator(builderOpts.Dedup, c.metricsFactory.Namespace(metrics.NSOptions{Name: "dedup"}))
+		c.logger.Info("Duplicate span suppression enabled", zap.Duration("window", builderOpts.Dedup.Window))
+		handlerBuilder.Deduplicator = deduplicator.Keep
+		handlerBuilder.ForgetDuplicate = deduplicator.Forget
+	}
+	if builderOpts.SpanLimits.Enabled() {
+		enforcer, err := spanlimits.NewEnforcer(builderOpts.SpanLimits, c.metricsFactory.Namespace(metrics.NSOptions{Name: "span_limits"}))
+		if err != nil {
+			return err
+		}
+		c.logger.Info("Span limits enabled",
+			zap.Int("max-tag-value-length", builderOpts.SpanLimits.MaxTagValueLength),
+			zap.Int("max-logs", builderOpts.SpanLimits.MaxLogs),
+			zap.Int("max-span-size", builderOpts.SpanLimits.MaxSpanSize),
+			zap.String("policy", builderOpts.SpanLimits.Policy))
+		// the limits are only enforced on the spans kept by the span filter
+		handlerBuilder.SpanFilter = ChainedFilterSpan(handlerBuilder.spanFilter(), enforcer.Enforce)
+	}
+	if builderOpts.Redaction.RulesFile != "" {
+		cfg, err := redaction.LoadConfig(builderOpts.Redaction.RulesFile)
+		if err != nil {
+			return err
+		}
+		redactor, err := redaction.NewSanitizer(cfg, c.metricsFactory.Namespace(metrics.NSOptions{Name: "redaction"}))
+		if err != nil {
+			return err
+		}
+		c.logger.Info("Redaction enabled", zap.String("rules-file", builderOpts.Redaction.RulesFile), zap.Int("rules", len(cfg.Rules)))
+		handlerBuilder.Sanitizer = redactor
+	}
+	if builderOpts.RateLimit.Enabled() {
+		limiter, err := ratelimit.NewLimiter(builderOpts.RateLimit, c.metricsFactory.Namespace(metrics.NSOptions{Name: "rate_limit"}))
+		if err != nil {
+			return err
+		}
+		handlerBuilder.RateLimiter = limiter.Allow
+	}
+	if builderOpts.TenantQuota.Enabled() {
+		if !builderOpts.Tenancy.Enabled {
+			return errors.New("tenant quotas require multi-tenancy to be enabled")
+		}
+		quotas, err := tenantquota.NewQuotas(builderOpts.TenantQuota, c.metricsFactory.Namespace(metrics.NSOptions{Name: "tenant_quota"}))
+		if err != nil {
+			return err
+		}
+		c.logger.Info("Tenant quotas enabled", zap.String("enforcement", builderOpts.TenantQuota.Enforcement))
+		handlerBuilder.TenantQuota = quotas.Allow
+	}
+	if builderOpts.PersistentQueue.Directory != "" {
+		droppedSpans := c.metricsFactory.Namespace(metrics.NSOptions{Name: "persistent_queue"}).Counter(metrics.Options{Name: "spans_dropped"})
+		persistentQueue, err := queue.NewPersistentQueue(builderOpts.PersistentQueue, func(count int) {
+			droppedSpans.Inc(int64(count))
+		})
+		if err != nil {
+			return err
+		}
+		c.logger.Info("Using persistent span queue",
+			zap.String("directory", builderOpts.PersistentQueue.Directory),
+			zap.Int("recovered-spans", persistentQueue.Size()))
+		handlerBuilder.PersistentQueue = persistentQueue
+	}
+
+	var authenticator *auth.Authenticator
+	if builderOpts.Auth.Enabled() {
+		a, err := auth.NewAuthenticator(builderOpts.Auth, c.logger, c.metricsFactory.Namespace(metrics.NSOptions{Name: "auth"}))
+		if err != nil {
+			return err
+		}
+		authenticator = a
+	}
+	if builderOpts.AdminAuth.Enabled() {
+		a, err := auth.NewAuthenticator(builderOpts.AdminAuth, c.logger, c.metricsFactory.Namespace(metrics.NSOptions{Name: "admin_auth"}))
+		if err != nil {
+			return err
+		}
+		c.adminAuth = a
 	}
 
 	c.spanProcessor = handlerBuilder.BuildSpanProcessor()
-	c.spanHandlers = handlerBuilder.BuildHandlers(c.spanProcessor)
+	spanProcessor := c.spanProcessor
+	if builderOpts.PeerRouting.Enabled() {
+		tenantHeader := ""
+		if handlerBuilder.TenancyMgr != nil {
+			tenantHeader = handlerBuilder.TenancyMgr.Header
+		}
+		router, err := peerrouting.NewProcessor(c.spanProcessor, builderOpts.PeerRouting, tenantHeader, c.logger,
+			c.metricsFactory.Namespace(metrics.NSOptions{Name: "peer_routing"}))
+		if err != nil {
+			return err
+		}
+		router.Start()
+		c.peerRouter = router
+		spanProcessor = router
+	}
+	c.spanHandlers = handlerBuilder.BuildHandlers(spanProcessor)
+
+	// the last step that can fail, the servers are only started once the pipeline is complete
+	if builderOpts.ConfigReload.Enabled() {
+		if err := c.startConfigWatcher(builderOpts.ConfigReload); err != nil {
+			return err
+		}
+	}
 
+	cors := server.CORSOptions{
+		AllowedOrigins: builderOpts.HTTPAllowedOrigins,
+		AllowedHeaders: builderOpts.HTTPAllowedHeaders,
+	}
 	if grpcServer, err := server.StartGRPCServer(&server.GRPCServerParams{
-		HostPort:      builderOpts.CollectorGRPCHostPort,
-		Handler:       c.spanHandlers.GRPCHandler,
-		TLSConfig:     builderOpts.TLS,
-		SamplingStore: c.strategyStore,
-		Logger:        c.logger,
+		HostPort:       builderOpts.CollectorGRPCHostPort,
+		Handler:        c.spanHandlers.GRPCHandler,
+		TLSConfig:      builderOpts.TLS,
+		SamplingStore:  c.strategyStore,
+		Logger:         c.logger,
+		Authenticator:  authenticator,
+		MetricsFactory: c.metricsFactory,
+		Reflection:     builderOpts.GRPCReflection,
+		Channelz:       builderOpts.GRPCChannelz,
+		GRPCWeb:        builderOpts.GRPCWeb,
+		CORS:           cors,
 	}); err != nil {
 		c.logger.Fatal("could not start gRPC collector", zap.Error(err))
 	} else {
@@ -101,6 +373,9 @@ func (c *Collector) Start(builderOpts *CollectorOptions) error {
 		MetricsFactory: c.metricsFactory,
 		SamplingStore:  c.strategyStore,
 		Logger:         c.logger,
+		TenancyMgr:     handlerBuilder.TenancyMgr,
+		Authenticator:  authenticator,
+		CORS:           cors,
 	}); err != nil {
 		c.logger.Fatal("could not start the HTTP server", zap.Error(err))
 	} else {
@@ -113,17 +388,118 @@ func (c *Collector) Start(builderOpts *CollectorOptions) error {
 		AllowedHeaders: builderOpts.CollectorZipkinAllowedHeaders,
 		AllowedOrigins: builderOpts.CollectorZipkinAllowedOrigins,
 		Logger:         c.logger,
+		TenancyMgr:     handlerBuilder.TenancyMgr,
+		Authenticator:  authenticator,
 	}); err != nil {
 		c.logger.Fatal("could not start the Zipkin server", zap.Error(err))
 	} else {
 		c.zkServer = zkServer
 	}
 
+	otlpParams := &server.OTLPServerParams{
+		TLSConfig:      builderOpts.TLS,
+		GRPCHostPort:   builderOpts.CollectorOTLPGRPCHostPort,
+		HTTPHostPort:   builderOpts.CollectorOTLPHTTPHostPort,
+		Handler:        c.spanHandlers.OTLPHandler,
+		HealthCheck:    c.hCheck,
+		Logger:         c.logger,
+		Authenticator:  authenticator,
+		MetricsFactory: c.metricsFactory,
+		Reflection:     builderOpts.GRPCReflection,
+		Channelz:       builderOpts.GRPCChannelz,
+		CORS:           cors,
+	}
+	if otlpGRPCServer, err := server.StartOTLPGRPCServer(otlpParams); err != nil {
+		c.logger.Fatal("could not start the OTLP gRPC receiver", zap.Error(err))
+	} else {
+		c.otlpGRPCServer = otlpGRPCServer
+	}
+	if otlpHTTPServer, err := server.StartOTLPHTTPServer(otlpParams); err != nil {
+		c.logger.Fatal("could not start the OTLP HTTP receiver", zap.Error(err))
+	} else {
+		c.otlpHTTPServer = otlpHTTPServer
+	}
+
+	if ocServer, err := server.StartOpenCensusServer(&server.OpenCensusServerParams{
+		TLSConfig:      builderOpts.TLS,
+		HostPort:       builderOpts.CollectorOpenCensusHostPort,
+		Handler:        c.spanHandlers.OpenCensusHandler,
+		Logger:         c.logger,
+		Authenticator:  authenticator,
+		MetricsFactory: c.metricsFactory,
+		Reflection:     builderOpts.GRPCReflection,
+		Channelz:       builderOpts.GRPCChannelz,
+	}); err != nil {
+		c.logger.Fatal("could not start the OpenCensus receiver", zap.Error(err))
+	} else {
+		c.ocServer = ocServer
+	}
+
+	return nil
+}
+
+// stopStages stops the background routines started by Start when a later step fails.
+func (c *Collector) stopStages() {
+	if c.peerRouter != nil {
+		c.peerRouter.Close()
+		c.peerRouter = nil
+	}
+	if c.spanProcessor != nil {
+		c.spanProcessor.Close()
+		c.spanProcessor = nil
+	}
+	if c.k8sEnricher != nil {
+		c.k8sEnricher.Close()
+		c.k8sEnricher = nil
+	}
+	if c.spanMetrics != nil {
+		c.spanMetrics.Close()
+		c.spanMetrics = nil
+	}
+	if c.clockSkew != nil {
+		c.clockSkew.Close()
+		c.clockSkew = nil
+	}
+	if c.tailSampler != nil {
+		c.tailSampler.Close()
+		c.tailSampler = nil
+	}
+	if c.spillover != nil {
+		c.spillover.Close()
+		c.spillover = nil
+	}
+}
+
+// startConfigWatcher reloads the components supporting it when the configuration file changes.
+// Components disabled at startup can only be enabled with a restart.
+func (c *Collector) startConfigWatcher(opts configreload.Options) error {
+	if c.config == nil {
+		return errors.New("reloading the configuration requires the collector to be created with its viper configuration")
+	}
+	watcher, err := configreload.NewWatcher(opts, c.config, c.logger,
+		c.metricsFactory.Namespace(metrics.NSOptions{Name: "config_reload"}))
+	if err != nil {
+		return err
+	}
+	if c.spanFilter != nil {
+		watcher.Register("span_filter", configreload.ReloadFunc(filter.Reloader(c.spanFilter)), "collector.span-filter.")
+	}
+	// the static strategy store can switch to another strategies file, the adaptive one needs a restart
+	if store, ok := c.strategyStore.(configreload.Reloadable); ok {
+		watcher.Register("sampling", store, "sampling.strategies-file")
+	}
+	watcher.Start()
+	c.configWatcher = watcher
+	c.logger.Info("Configuration reload enabled", zap.String("file", c.config.ConfigFileUsed()), zap.Duration("interval", opts.Interval))
 	return nil
 }
 
 // Close the component and all its underlying dependencies
 func (c *Collector) Close() error {
+	if c.configWatcher != nil {
+		c.configWatcher.Close()
+	}
+
 	// gRPC server
 	if c.grpcServer != nil {
 		c.grpcServer.GracefulStop()
@@ -149,13 +525,83 @@ func (c *Collector) Close() error {
 		defer cancel()
 	}
 
-	if err := c.spanProcessor.Close(); err != nil {
-		c.logger.Error("failed to close span processor.", zap.Error(err))
+	// OTLP receivers
+	if c.otlpGRPCServer != nil {
+		c.otlpGRPCServer.GracefulStop()
+	}
+	if c.otlpHTTPServer != nil {
+		timeout, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := c.otlpHTTPServer.Shutdown(timeout)
+		if err != nil {
+			c.logger.Error("failed to stop the OTLP HTTP server", zap.Error(err))
+		}
+		defer cancel()
+	}
+
+	// OpenCensus receiver
+	if c.ocServer != nil {
+		c.ocServer.GracefulStop()
+	}
+
+	if c.peerRouter != nil {
+		c.peerRouter.Close()
+	}
+
+	if c.spanProcessor != nil {
+		if err := c.spanProcessor.Close(); err != nil {
+			c.logger.Error("failed to close span processor.", zap.Error(err))
+		}
+	}
+
+	if c.k8sEnricher != nil {
+		c.k8sEnricher.Close()
+	}
+
+	// push the final values of the span metrics
+	if c.spanMetrics != nil {
+		if err := c.spanMetrics.Close(); err != nil {
+			c.logger.Error("failed to close span metrics writer", zap.Error(err))
+		}
+	}
+
+	// flush traces still waiting for clock skew correction
+	if c.clockSkew != nil {
+		if err := c.clockSkew.Close(); err != nil {
+			c.logger.Error("failed to close clock skew writer", zap.Error(err))
+		}
+	}
+
+	// flush traces still waiting for a tail sampling decision
+	if c.tailSampler != nil {
+		if err := c.tailSampler.Close(); err != nil {
+			c.logger.Error("failed to close tail sampler", zap.Error(err))
+		}
+	}
+
+	// keep spans not yet replayed on disk for the next run
+	if c.spillover != nil {
+		if err := c.spillover.Close(); err != nil {
+			c.logger.Error("failed to close spillover writer", zap.Error(err))
+		}
 	}
 
 	return nil
 }
 
+// RegisterAdminHandlers registers the collector endpoints on the admin server. It must be called after Start.
+// The endpoints changing the state of the collector are only registered when admin authentication is configured.
+func (c *Collector) RegisterAdminHandlers(admin *flags.AdminServer) {
+	admin.Handle("/collector/dropped-spans", c.droppedSpans)
+	if c.zpages != nil {
+		admin.Handle(zpages.TracezPath, c.zpages)
+	}
+	if c.adminAuth == nil {
+		c.logger.Info("Admin authentication is not configured, the drain endpoint is disabled")
+		return
+	}
+	admin.Handle("/collector/drain", c.adminAuth.HTTPHandler(http.HandlerFunc(c.drainHandler)))
+}
+
 // SpanHandlers returns span handlers used by the Collector.
 func (c *Collector) SpanHandlers() *SpanHandlers {
 	return c.spanHandlers
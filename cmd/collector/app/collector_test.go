@@ -17,13 +17,19 @@ package app
 import (
 	"context"
 	"io"
+	"io/ioutil"
+	"os"
 	"testing"
 	"time"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/uber/jaeger-lib/metrics/metricstest"
 	"go.uber.org/zap"
 
+	"github.com/jaegertracing/jaeger/cmd/collector/app/tailsampling"
+	"github.com/jaegertracing/jaeger/pkg/configreload"
 	"github.com/jaegertracing/jaeger/pkg/healthcheck"
 	"github.com/jaegertracing/jaeger/thrift-gen/sampling"
 )
@@ -55,6 +61,58 @@ func TestNewCollector(t *testing.T) {
 	assert.NoError(t, c.Close())
 }
 
+func TestCollectorStartStopsStagesOnError(t *testing.T) {
+	c := New(&CollectorParams{
+		ServiceName:    "collector",
+		Logger:         zap.NewNop(),
+		MetricsFactory: metricstest.NewFactory(time.Hour),
+		SpanWriter:     &fakeSpanWriter{},
+		StrategyStore:  &mockStrategyStore{},
+		HealthCheck:    healthcheck.New(),
+	})
+	collectorOpts := &CollectorOptions{
+		TailSampling:               tailsampling.Options{Enabled: true, DecisionWait: time.Second, MaxTraces: 10},
+		DynQueueSizeMemoryFraction: 2,
+	}
+
+	assert.EqualError(t, c.Start(collectorOpts), "the queue memory fraction must be between 0 and 1, got 2")
+	assert.Nil(t, c.tailSampler)
+}
+
+func TestCollectorConfigReload(t *testing.T) {
+	f, err := ioutil.TempFile("", "collector-*.yaml")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("collector:\n  span-filter:\n    rules:\n      - action: drop\n        expression: service == \"noisy\"\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	v := viper.New()
+	v.SetConfigFile(f.Name())
+	require.NoError(t, v.ReadInConfig())
+
+	params := &CollectorParams{
+		ServiceName:    "collector",
+		Logger:         zap.NewNop(),
+		MetricsFactory: metricstest.NewFactory(time.Hour),
+		SpanWriter:     &fakeSpanWriter{},
+		StrategyStore:  &mockStrategyStore{},
+		HealthCheck:    healthcheck.New(),
+	}
+	collectorOpts := &CollectorOptions{ConfigReload: configreload.Options{Interval: time.Hour}}
+	collectorOpts.SpanFilter.InitFromViper(v)
+
+	c := New(params)
+	assert.EqualError(t, c.Start(collectorOpts), "reloading the configuration requires the collector to be created with its viper configuration")
+	require.NoError(t, c.Close())
+
+	params.Config = v
+	c = New(params)
+	require.NoError(t, c.Start(collectorOpts))
+	assert.NotNil(t, c.spanFilter)
+	assert.NotNil(t, c.configWatcher)
+	assert.NoError(t, c.Close())
+}
+
 type mockStrategyStore struct {
 }
 
@@ -0,0 +1,140 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dedup
+
+import (
+	"container/list"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+type spanKey struct {
+	traceID model.TraceID
+	spanID  model.SpanID
+	hash    uint64
+}
+
+type seenSpan struct {
+	key       spanKey
+	firstSeen time.Time
+}
+
+type dedupMetrics struct {
+	Duplicates   metrics.Counter `metric:"spans_duplicate"`
+	Remembered   metrics.Gauge   `metric:"remembered_spans"`
+	HashFailures metrics.Counter `metric:"hash_failures"`
+}
+
+// Deduplicator remembers the spans received within a sliding window and rejects the spans
+// identical to one of them. Spans are identified by trace ID, span ID and a hash of their
+// content, so that different spans sharing IDs, e.g. both sides of a Zipkin shared span, are kept.
+type Deduplicator struct {
+	window   time.Duration
+	maxSpans int
+	metrics  dedupMetrics
+	timeNow  func() time.Time
+
+	lock  sync.Mutex
+	spans map[spanKey]*list.Element
+	order *list.List // spans in the order they were first seen
+}
+
+// NewDeduplicator creates a Deduplicator from the options.
+func NewDeduplicator(opts Options, metricsFactory metrics.Factory) *Deduplicator {
+	d := &Deduplicator{
+		window:   opts.Window,
+		maxSpans: opts.MaxSpans,
+		timeNow:  time.Now,
+		spans:    make(map[spanKey]*list.Element),
+		order:    list.New(),
+	}
+	if d.window <= 0 {
+		d.window = defaultWindow
+	}
+	if d.maxSpans <= 0 {
+		d.maxSpans = defaultMaxSpans
+	}
+	metrics.Init(&d.metrics, metricsFactory, nil)
+	return d
+}
+
+// Keep returns false if an identical span was received within the window.
+func (d *Deduplicator) Keep(span *model.Span) bool {
+	key, ok := d.key(span)
+	if !ok {
+		// a span that cannot be hashed is never considered a duplicate
+		d.metrics.HashFailures.Inc(1)
+		return true
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	now := d.timeNow()
+	d.expireLocked(now)
+	if _, ok := d.spans[key]; ok {
+		d.metrics.Duplicates.Inc(1)
+		return false
+	}
+	if d.order.Len() >= d.maxSpans {
+		d.removeLocked(d.order.Front())
+	}
+	d.spans[key] = d.order.PushBack(&seenSpan{key: key, firstSeen: now})
+	d.metrics.Remembered.Update(int64(d.order.Len()))
+	return true
+}
+
+// Forget removes a span kept by Keep, so that its retry is not rejected when it could not be processed.
+// The span must have the content it had when it was kept.
+func (d *Deduplicator) Forget(span *model.Span) {
+	key, ok := d.key(span)
+	if !ok {
+		return
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if e, ok := d.spans[key]; ok {
+		d.removeLocked(e)
+		d.metrics.Remembered.Update(int64(d.order.Len()))
+	}
+}
+
+func (d *Deduplicator) key(span *model.Span) (spanKey, bool) {
+	data, err := span.Marshal()
+	if err != nil {
+		return spanKey{}, false
+	}
+	h := fnv.New64a()
+	h.Write(data)
+	return spanKey{traceID: span.TraceID, spanID: span.SpanID, hash: h.Sum64()}, true
+}
+
+func (d *Deduplicator) expireLocked(now time.Time) {
+	for e := d.order.Front(); e != nil; e = d.order.Front() {
+		if now.Sub(e.Value.(*seenSpan).firstSeen) < d.window {
+			return
+		}
+		d.removeLocked(e)
+	}
+}
+
+func (d *Deduplicator) removeLocked(e *list.Element) {
+	d.order.Remove(e)
+	delete(d.spans, e.Value.(*seenSpan).key)
+}
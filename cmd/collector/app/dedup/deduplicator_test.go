@@ -0,0 +1,88 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dedup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/config"
+)
+
+func makeSpan(spanID uint64, op string) *model.Span {
+	return &model.Span{
+		TraceID:       model.NewTraceID(1, 2),
+		SpanID:        model.NewSpanID(spanID),
+		OperationName: op,
+		Process:       &model.Process{ServiceName: "frontend"},
+	}
+}
+
+func TestDeduplicator(t *testing.T) {
+	mf := metricstest.NewFactory(0)
+	d := NewDeduplicator(Options{Window: time.Minute}, mf)
+	now := time.Unix(1000, 0)
+	d.timeNow = func() time.Time { return now }
+
+	assert.True(t, d.Keep(makeSpan(1, "a")))
+	assert.False(t, d.Keep(makeSpan(1, "a")), "retried span")
+	assert.True(t, d.Keep(makeSpan(1, "b")), "same IDs with a different content")
+	assert.True(t, d.Keep(makeSpan(2, "a")))
+
+	now = now.Add(30 * time.Second)
+	assert.False(t, d.Keep(makeSpan(1, "a")))
+
+	now = now.Add(30 * time.Second)
+	assert.True(t, d.Keep(makeSpan(1, "a")), "the window starts when the span is first seen")
+
+	mf.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "spans_duplicate", Value: 2})
+	mf.AssertGaugeMetrics(t, metricstest.ExpectedMetric{Name: "remembered_spans", Value: 1})
+}
+
+func TestDeduplicatorForget(t *testing.T) {
+	mf := metricstest.NewFactory(0)
+	d := NewDeduplicator(Options{}, mf)
+	assert.True(t, d.Keep(makeSpan(1, "a")))
+	assert.True(t, d.Keep(makeSpan(2, "a")))
+
+	d.Forget(makeSpan(1, "a"))
+	d.Forget(makeSpan(2, "b"))
+	assert.True(t, d.Keep(makeSpan(1, "a")), "the forgotten span is kept again")
+	assert.False(t, d.Keep(makeSpan(2, "a")), "a span with a different content is not forgotten")
+	mf.AssertGaugeMetrics(t, metricstest.ExpectedMetric{Name: "remembered_spans", Value: 2})
+}
+
+func TestDeduplicatorMaxSpans(t *testing.T) {
+	d := NewDeduplicator(Options{MaxSpans: 2}, metricstest.NewFactory(0))
+	assert.True(t, d.Keep(makeSpan(1, "a")))
+	assert.True(t, d.Keep(makeSpan(2, "a")))
+	assert.True(t, d.Keep(makeSpan(3, "a")))
+	assert.False(t, d.Keep(makeSpan(3, "a")))
+	assert.True(t, d.Keep(makeSpan(1, "a")), "the oldest span is forgotten")
+}
+
+func TestOptionsWithFlags(t *testing.T) {
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{
+		"--collector.dedup.enabled=true",
+		"--collector.dedup.window=5m",
+	})
+	opts := new(Options).InitFromViper(v)
+	assert.Equal(t, Options{Enabled: true, Window: 5 * time.Minute, MaxSpans: defaultMaxSpans}, *opts)
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dedup
+
+import (
+	"flag"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	enabled  = "collector.dedup.enabled"
+	window   = "collector.dedup.window"
+	maxSpans = "collector.dedup.max-spans"
+
+	defaultWindow   = time.Minute
+	defaultMaxSpans = 100000
+)
+
+// Options controls the suppression of duplicate spans.
+type Options struct {
+	// Enabled turns on the suppression of duplicate spans
+	Enabled bool
+	// Window is how long a span is remembered after it was first received
+	Window time.Duration
+	// MaxSpans is the maximum number of spans remembered; the oldest spans are forgotten early when exceeded
+	MaxSpans int
+}
+
+// AddFlags adds flags for deduplication Options
+func AddFlags(flags *flag.FlagSet) {
+	flags.Bool(enabled, false, "Drop spans identical to a span received within the deduplication window, e.g. when clients retry batches")
+	flags.Duration(window, defaultWindow, "How long received spans are remembered to detect duplicates")
+	flags.Int(maxSpans, defaultMaxSpans, "The maximum number of spans remembered to detect duplicates; the oldest spans are forgotten early when the limit is reached")
+}
+
+// InitFromViper initializes Options with properties from viper
+func (o *Options) InitFromViper(v *viper.Viper) *Options {
+	o.Enabled = v.GetBool(enabled)
+	o.Window = v.GetDuration(window)
+	o.MaxSpans = v.GetInt(maxSpans)
+	return o
+}
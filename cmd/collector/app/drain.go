@@ -0,0 +1,119 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/pkg/healthcheck"
+)
+
+// defaultDrainTimeout is how long the drain endpoint waits for the queue to be flushed when no timeout is given
+const defaultDrainTimeout = 30 * time.Second
+
+type drainer interface {
+	Drain(ctx context.Context) error
+	Resume()
+}
+
+// Drain marks the collector as unavailable, stops accepting new spans and blocks until the queued
+// spans are written to storage or the context is done. The spans buffered by the writers are
+// flushed: the traces held for clock skew correction and tail sampling are written without waiting
+// for the rest of their spans, and the spans spilled to disk must have been replayed.
+// The collector accepts spans again after Resume.
+func (c *Collector) Drain(ctx context.Context) error {
+	if c.hCheck != nil {
+		c.hCheck.Set(healthcheck.Unavailable)
+	}
+	if d, ok := c.spanProcessor.(drainer); ok {
+		if err := d.Drain(ctx); err != nil {
+			return err
+		}
+	}
+	// the writers are flushed in the order of the chain, the outermost first
+	if c.clockSkew != nil {
+		if err := c.clockSkew.Flush(); err != nil {
+			return err
+		}
+	}
+	if c.tailSampler != nil {
+		c.tailSampler.Flush()
+	}
+	if c.spillover != nil {
+		return c.spillover.Drain(ctx)
+	}
+	return nil
+}
+
+// Resume accepts spans again after Drain and marks the collector as available.
+func (c *Collector) Resume() {
+	if d, ok := c.spanProcessor.(drainer); ok {
+		d.Resume()
+	}
+	if c.hCheck != nil {
+		c.hCheck.Ready()
+	}
+}
+
+type drainResponse struct {
+	Drained bool   `json:"drained"`
+	Error   string `json:"error,omitempty"`
+}
+
+// drainHandler drains the collector on POST requests, and resumes it on DELETE requests.
+// The deadline of the drain can be set with the "timeout" query parameter as a duration, e.g. ?timeout=1m.
+func (c *Collector) drainHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodDelete {
+		c.Resume()
+		c.logger.Info("Collector resumed")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost+", "+http.MethodDelete)
+		http.Error(w, "the collector is drained with a POST request and resumed with a DELETE request", http.StatusMethodNotAllowed)
+		return
+	}
+	timeout := defaultDrainTimeout
+	if t := r.URL.Query().Get("timeout"); t != "" {
+		parsed, err := time.ParseDuration(t)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "timeout must be a positive duration", http.StatusBadRequest)
+			return
+		}
+		timeout = parsed
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	c.logger.Info("Draining the collector", zap.Duration("timeout", timeout))
+	resp := drainResponse{Drained: true}
+	code := http.StatusOK
+	if err := c.Drain(ctx); err != nil {
+		c.logger.Warn("Failed to drain the collector", zap.Error(err))
+		resp = drainResponse{Error: err.Error()}
+		code = http.StatusGatewayTimeout
+	} else {
+		c.logger.Info("Collector drained")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/cmd/collector/app/clockskew"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/processor"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/tailsampling"
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/healthcheck"
+)
+
+type fakeDrainer struct {
+	processor.SpanProcessor
+	wait    time.Duration
+	resumed bool
+}
+
+func (d *fakeDrainer) ProcessSpans([]*model.Span, processor.SpansOptions) ([]bool, error) {
+	return nil, nil
+}
+
+func (d *fakeDrainer) Drain(ctx context.Context) error {
+	select {
+	case <-time.After(d.wait):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *fakeDrainer) Resume() {
+	d.resumed = true
+}
+
+type recordingWriter struct {
+	sync.Mutex
+	spans int
+}
+
+func (w *recordingWriter) WriteSpan(*model.Span) error {
+	w.Lock()
+	defer w.Unlock()
+	w.spans++
+	return nil
+}
+
+func TestCollectorDrainFlushesWriters(t *testing.T) {
+	writer := &recordingWriter{}
+	tailSampler := tailsampling.NewProcessor(writer, tailsampling.Options{DecisionWait: time.Hour, SampleErrors: true},
+		zap.NewNop(), metrics.NullFactory)
+	clockSkew := clockskew.NewWriter(tailSampler, clockskew.Options{BufferWait: time.Hour}, zap.NewNop(), metrics.NullFactory)
+	c := &Collector{
+		logger:        zap.NewNop(),
+		spanProcessor: &fakeDrainer{},
+		tailSampler:   tailSampler,
+		clockSkew:     clockSkew,
+	}
+	span := &model.Span{TraceID: model.NewTraceID(0, 1), Tags: []model.KeyValue{model.Bool("error", true)}}
+	require.NoError(t, clockSkew.WriteSpan(span))
+
+	require.NoError(t, c.Drain(context.Background()))
+	assert.Equal(t, 1, writer.spans, "the span buffered by the clock skew writer then the tail sampler is written")
+}
+
+func TestCollectorDrainHandler(t *testing.T) {
+	testCases := []struct {
+		name    string
+		method  string
+		query   string
+		wait    time.Duration
+		code    int
+		drained bool
+	}{
+		{name: "drained", method: http.MethodPost, code: http.StatusOK, drained: true},
+		{name: "deadline exceeded", method: http.MethodPost, query: "?timeout=10ms", wait: time.Second, code: http.StatusGatewayTimeout},
+		{name: "invalid timeout", method: http.MethodPost, query: "?timeout=soon", code: http.StatusBadRequest},
+		{name: "wrong method", method: http.MethodGet, code: http.StatusMethodNotAllowed},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			hc := healthcheck.New()
+			hc.Ready()
+			c := &Collector{
+				logger:        zap.NewNop(),
+				hCheck:        hc,
+				spanProcessor: &fakeDrainer{wait: test.wait},
+			}
+			w := httptest.NewRecorder()
+			c.drainHandler(w, httptest.NewRequest(test.method, "/collector/drain"+test.query, nil))
+			require.Equal(t, test.code, w.Code)
+			if test.code == http.StatusOK || test.code == http.StatusGatewayTimeout {
+				var resp drainResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, test.drained, resp.Drained)
+				assert.Equal(t, healthcheck.Unavailable, hc.Get())
+			}
+		})
+	}
+}
+
+func TestCollectorResumeHandler(t *testing.T) {
+	hc := healthcheck.New()
+	hc.Set(healthcheck.Unavailable)
+	drainer := &fakeDrainer{}
+	c := &Collector{
+		logger:        zap.NewNop(),
+		hCheck:        hc,
+		spanProcessor: drainer,
+	}
+	w := httptest.NewRecorder()
+	c.drainHandler(w, httptest.NewRequest(http.MethodDelete, "/collector/drain", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.True(t, drainer.resumed)
+	assert.Equal(t, healthcheck.Ready, hc.Get())
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dropped
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+// Reason describes why the collector dropped a span.
+type Reason string
+
+const (
+	// QueueFull is used for spans that did not fit in the span queue
+	QueueFull Reason = "queue_full"
+	// RateLimited is used for spans of batches rejected by the ingestion rate limits
+	RateLimited Reason = "rate_limited"
+	// QuotaExceeded is used for spans of batches rejected by the quota of their tenant
+	QuotaExceeded Reason = "quota_exceeded"
+	// Rejected is used for spans rejected by the span filter
+	Rejected Reason = "rejected"
+	// Deduplicated is used for duplicates of spans received shortly before
+	Deduplicated Reason = "deduplicated"
+
+	// otherServices is the shared entry for services seen after the number of tracked services reaches maxServices
+	otherServices = "other-services"
+	maxServices   = 4000
+
+	// defaultLimit is the number of services returned by the admin endpoint when no limit is given
+	defaultLimit = 10
+)
+
+var reasons = []Reason{QueueFull, RateLimited, QuotaExceeded, Rejected, Deduplicated}
+
+// Entry is the count of dropped spans of one service.
+type Entry struct {
+	Service string            `json:"service"`
+	Total   uint64            `json:"total"`
+	Reasons map[Reason]uint64 `json:"reasons"`
+}
+
+type serviceCounts struct {
+	counts   map[Reason]uint64
+	counters map[Reason]metrics.Counter
+}
+
+// Tracker counts dropped spans by service and reason, and reports them as metrics
+// and through an admin endpoint listing the services losing the most spans.
+// A nil Tracker ignores all records.
+type Tracker struct {
+	lock           sync.Mutex
+	services       map[string]*serviceCounts
+	metricsFactory metrics.Factory
+}
+
+// NewTracker creates a Tracker emitting the spans_dropped{svc,reason} counter to the metrics factory.
+func NewTracker(metricsFactory metrics.Factory) *Tracker {
+	return &Tracker{
+		services:       make(map[string]*serviceCounts),
+		metricsFactory: metricsFactory,
+	}
+}
+
+// RecordSpan records one dropped span under the service of its process.
+func (t *Tracker) RecordSpan(span *model.Span, reason Reason) {
+	t.Record(span.Process.GetServiceName(), reason, 1)
+}
+
+// Record records count dropped spans of the service.
+func (t *Tracker) Record(service string, reason Reason, count int) {
+	if t == nil || count <= 0 {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	s := t.serviceLocked(service)
+	s.counts[reason] += uint64(count)
+	s.counter(reason, service, t.metricsFactory).Inc(int64(count))
+}
+
+func (t *Tracker) serviceLocked(service string) *serviceCounts {
+	if s, ok := t.services[service]; ok {
+		return s
+	}
+	if len(t.services) >= maxServices {
+		service = otherServices
+		if s, ok := t.services[service]; ok {
+			return s
+		}
+	}
+	s := &serviceCounts{
+		counts:   make(map[Reason]uint64),
+		counters: make(map[Reason]metrics.Counter),
+	}
+	t.services[service] = s
+	return s
+}
+
+func (s *serviceCounts) counter(reason Reason, service string, factory metrics.Factory) metrics.Counter {
+	if c, ok := s.counters[reason]; ok {
+		return c
+	}
+	c := factory.Counter(metrics.Options{
+		Name: "spans_dropped",
+		Tags: map[string]string{"svc": service, "reason": string(reason)},
+	})
+	s.counters[reason] = c
+	return c
+}
+
+// Top returns up to n services with the most dropped spans, in decreasing order.
+func (t *Tracker) Top(n int) []Entry {
+	if t == nil {
+		return []Entry{}
+	}
+	t.lock.Lock()
+	entries := make([]Entry, 0, len(t.services))
+	for svc, s := range t.services {
+		e := Entry{Service: svc, Reasons: make(map[Reason]uint64, len(s.counts))}
+		for _, r := range reasons {
+			if c := s.counts[r]; c > 0 {
+				e.Reasons[r] = c
+				e.Total += c
+			}
+		}
+		entries = append(entries, e)
+	}
+	t.lock.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Total != entries[j].Total {
+			return entries[i].Total > entries[j].Total
+		}
+		return entries[i].Service < entries[j].Service
+	})
+	if n >= 0 && len(entries) > n {
+		entries = entries[:n]
+	}
+	return entries
+}
+
+// ServeHTTP writes the services with the most dropped spans as JSON. The number of services
+// is controlled by the "limit" query parameter, and is 10 by default. A limit of 0 returns all services.
+func (t *Tracker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	limit := defaultLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		parsed, err := strconv.Atoi(l)
+		if err != nil || parsed < 0 {
+			http.Error(w, "limit must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+	if limit == 0 {
+		limit = -1
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Services []Entry `json:"services"`
+	}{Services: t.Top(limit)})
+}
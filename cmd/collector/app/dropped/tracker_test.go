@@ -0,0 +1,105 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dropped
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+func TestTracker(t *testing.T) {
+	mf := metricstest.NewFactory(0)
+	tr := NewTracker(mf)
+	tr.Record("frontend", QueueFull, 3)
+	tr.Record("frontend", RateLimited, 2)
+	tr.Record("backend", Rejected, 1)
+	tr.Record("backend", QueueFull, 0)
+	tr.RecordSpan(&model.Span{Process: &model.Process{ServiceName: "db"}}, QueueFull)
+	tr.RecordSpan(&model.Span{Process: &model.Process{ServiceName: "db"}}, QueueFull)
+
+	assert.Equal(t, []Entry{
+		{Service: "frontend", Total: 5, Reasons: map[Reason]uint64{QueueFull: 3, RateLimited: 2}},
+		{Service: "db", Total: 2, Reasons: map[Reason]uint64{QueueFull: 2}},
+		{Service: "backend", Total: 1, Reasons: map[Reason]uint64{Rejected: 1}},
+	}, tr.Top(10))
+	assert.Len(t, tr.Top(1), 1)
+
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "spans_dropped", Tags: map[string]string{"svc": "frontend", "reason": "queue_full"}, Value: 3},
+		metricstest.ExpectedMetric{Name: "spans_dropped", Tags: map[string]string{"svc": "frontend", "reason": "rate_limited"}, Value: 2},
+		metricstest.ExpectedMetric{Name: "spans_dropped", Tags: map[string]string{"svc": "db", "reason": "queue_full"}, Value: 2},
+	)
+}
+
+func TestTrackerNil(t *testing.T) {
+	var tr *Tracker
+	tr.Record("frontend", QueueFull, 1)
+	assert.Empty(t, tr.Top(10))
+}
+
+func TestTrackerMaxServices(t *testing.T) {
+	tr := NewTracker(metricstest.NewFactory(0))
+	for i := 0; i < maxServices+10; i++ {
+		tr.Record(fmt.Sprintf("svc-%d", i), QueueFull, 1)
+	}
+	top := tr.Top(1)
+	require.Len(t, top, 1)
+	assert.Equal(t, Entry{Service: otherServices, Total: 10, Reasons: map[Reason]uint64{QueueFull: 10}}, top[0])
+}
+
+func TestTrackerServeHTTP(t *testing.T) {
+	tr := NewTracker(metricstest.NewFactory(0))
+	for i := 0; i < 15; i++ {
+		tr.Record(fmt.Sprintf("svc-%02d", i), Rejected, i+1)
+	}
+
+	testCases := []struct {
+		query    string
+		status   int
+		services int
+	}{
+		{query: "", status: http.StatusOK, services: 10},
+		{query: "?limit=3", status: http.StatusOK, services: 3},
+		{query: "?limit=0", status: http.StatusOK, services: 15},
+		{query: "?limit=-1", status: http.StatusBadRequest},
+		{query: "?limit=x", status: http.StatusBadRequest},
+	}
+	for _, test := range testCases {
+		t.Run(test.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tr.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+test.query, nil))
+			require.Equal(t, test.status, w.Code)
+			if test.status != http.StatusOK {
+				return
+			}
+			var resp struct {
+				Services []Entry `json:"services"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Len(t, resp.Services, test.services)
+			assert.Equal(t, "svc-14", resp.Services[0].Service)
+			assert.Equal(t, uint64(15), resp.Services[0].Total)
+		})
+	}
+}
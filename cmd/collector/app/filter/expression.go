@@ -0,0 +1,391 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+// Expression is a compiled rule condition evaluated against a span.
+//
+// The language supports the fields service, operation, duration and tag.<key>
+// (span tags first, then process tags), compared with ==, !=, =~, !~ (regular
+// expressions), <, <=, > and >=; string literals are double quoted and durations
+// use the time.ParseDuration format. Conditions are combined with &&, || and !,
+// and grouped with parentheses, e.g.
+//
+//	service == "frontend" && (operation =~ "^/health" || duration < 1ms)
+type Expression interface {
+	Eval(span *model.Span) bool
+}
+
+// Parse compiles an expression.
+func Parse(expr string) (Expression, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.tokens[p.pos].text, p.tokens[p.pos].offset)
+	}
+	return e, nil
+}
+
+type tokenKind int
+
+const (
+	tokenIdent tokenKind = iota
+	tokenString
+	tokenLiteral
+	tokenOperator
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	offset int
+}
+
+var operators = []string{"&&", "||", "==", "!=", "=~", "!~", "<=", ">=", "<", ">", "!", "(", ")"}
+
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"':
+			end := i + 1
+			for ; end < len(expr) && expr[end] != '"'; end++ {
+				if expr[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(expr) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			s, err := strconv.Unquote(expr[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d: %w", i, err)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: s, offset: i})
+			i = end + 1
+		case isWordChar(c):
+			end := i
+			for end < len(expr) && isWordChar(rune(expr[end])) {
+				end++
+			}
+			kind := tokenIdent
+			if unicode.IsDigit(c) || c == '-' {
+				kind = tokenLiteral
+			}
+			tokens = append(tokens, token{kind: kind, text: expr[i:end], offset: i})
+			i = end
+		default:
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(expr[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, offset: i})
+			i += len(op)
+		}
+	}
+	return tokens, nil
+}
+
+func isWordChar(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || strings.ContainsRune("_.-/:", c)
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peekOperator(op string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenOperator && p.tokens[p.pos].text == op
+}
+
+func (p *parser) next() (token, error) {
+	if p.pos >= len(p.tokens) {
+		return token{}, fmt.Errorf("unexpected end of expression")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	return t, nil
+}
+
+func (p *parser) parseOr() (Expression, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekOperator("||") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expression, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekOperator("&&") {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Expression, error) {
+	if p.peekOperator("!") {
+		p.pos++
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{e}, nil
+	}
+	if p.peekOperator("(") {
+		p.pos++
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peekOperator(")") {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return e, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Expression, error) {
+	field, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if field.kind != tokenIdent {
+		return nil, fmt.Errorf("expecting a field name at position %d, got %q", field.offset, field.text)
+	}
+	op, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if op.kind != tokenOperator {
+		return nil, fmt.Errorf("expecting an operator at position %d, got %q", op.offset, op.text)
+	}
+	value, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if value.kind != tokenString && value.kind != tokenLiteral {
+		return nil, fmt.Errorf("expecting a value at position %d, got %q", value.offset, value.text)
+	}
+
+	switch {
+	case field.text == "service":
+		return newStringComparison(func(s *model.Span) string { return s.Process.GetServiceName() }, op, value)
+	case field.text == "operation":
+		return newStringComparison(func(s *model.Span) string { return s.OperationName }, op, value)
+	case field.text == "duration":
+		return newDurationComparison(op, value)
+	case strings.HasPrefix(field.text, "tag.") && len(field.text) > len("tag."):
+		return newTagComparison(field.text[len("tag."):], op, value)
+	}
+	return nil, fmt.Errorf("unknown field %q at position %d", field.text, field.offset)
+}
+
+type orExpr struct{ left, right Expression }
+
+func (e orExpr) Eval(span *model.Span) bool { return e.left.Eval(span) || e.right.Eval(span) }
+
+type andExpr struct{ left, right Expression }
+
+func (e andExpr) Eval(span *model.Span) bool { return e.left.Eval(span) && e.right.Eval(span) }
+
+type notExpr struct{ e Expression }
+
+func (e notExpr) Eval(span *model.Span) bool { return !e.e.Eval(span) }
+
+type stringComparison struct {
+	get   func(*model.Span) string
+	match func(string) bool
+}
+
+func (e stringComparison) Eval(span *model.Span) bool { return e.match(e.get(span)) }
+
+func newStringMatcher(op, value token) (func(string) bool, error) {
+	switch op.text {
+	case "==":
+		return func(s string) bool { return s == value.text }, nil
+	case "!=":
+		return func(s string) bool { return s != value.text }, nil
+	case "=~", "!~":
+		re, err := regexp.Compile(value.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression at position %d: %w", value.offset, err)
+		}
+		if op.text == "!~" {
+			return func(s string) bool { return !re.MatchString(s) }, nil
+		}
+		return re.MatchString, nil
+	}
+	return nil, fmt.Errorf("operator %q at position %d is not supported for strings", op.text, op.offset)
+}
+
+func newStringComparison(get func(*model.Span) string, op, value token) (Expression, error) {
+	match, err := newStringMatcher(op, value)
+	if err != nil {
+		return nil, err
+	}
+	return stringComparison{get: get, match: match}, nil
+}
+
+func compareOrdered(op string, cmp int) (bool, bool) {
+	switch op {
+	case "==":
+		return cmp == 0, true
+	case "!=":
+		return cmp != 0, true
+	case "<":
+		return cmp < 0, true
+	case "<=":
+		return cmp <= 0, true
+	case ">":
+		return cmp > 0, true
+	case ">=":
+		return cmp >= 0, true
+	}
+	return false, false
+}
+
+type durationComparison struct {
+	op    string
+	value time.Duration
+}
+
+func (e durationComparison) Eval(span *model.Span) bool {
+	cmp := 0
+	if span.Duration < e.value {
+		cmp = -1
+	} else if span.Duration > e.value {
+		cmp = 1
+	}
+	result, _ := compareOrdered(e.op, cmp)
+	return result
+}
+
+func newDurationComparison(op, value token) (Expression, error) {
+	if _, ok := compareOrdered(op.text, 0); !ok {
+		return nil, fmt.Errorf("operator %q at position %d is not supported for durations", op.text, op.offset)
+	}
+	d, err := time.ParseDuration(value.text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid duration at position %d: %w", value.offset, err)
+	}
+	return durationComparison{op: op.text, value: d}, nil
+}
+
+type tagComparison struct {
+	key string
+	// exactly one of match or number is used, depending on the operator
+	match  func(string) bool
+	op     string
+	number float64
+}
+
+func (e tagComparison) Eval(span *model.Span) bool {
+	tag, ok := model.KeyValues(span.Tags).FindByKey(e.key)
+	if !ok && span.Process != nil {
+		tag, ok = model.KeyValues(span.Process.Tags).FindByKey(e.key)
+	}
+	if e.match != nil {
+		if !ok {
+			return e.match("")
+		}
+		return e.match(tag.AsString())
+	}
+	if !ok {
+		return false
+	}
+	var n float64
+	switch tag.VType {
+	case model.Int64Type:
+		n = float64(tag.Int64())
+	case model.Float64Type:
+		n = tag.Float64()
+	default:
+		parsed, err := strconv.ParseFloat(tag.AsString(), 64)
+		if err != nil {
+			return false
+		}
+		n = parsed
+	}
+	cmp := 0
+	if n < e.number {
+		cmp = -1
+	} else if n > e.number {
+		cmp = 1
+	}
+	result, _ := compareOrdered(e.op, cmp)
+	return result
+}
+
+func newTagComparison(key string, op, value token) (Expression, error) {
+	switch op.text {
+	case "<", "<=", ">", ">=":
+		n, err := strconv.ParseFloat(value.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number at position %d: %w", value.offset, err)
+		}
+		return tagComparison{key: key, op: op.text, number: n}, nil
+	}
+	match, err := newStringMatcher(op, value)
+	if err != nil {
+		return nil, err
+	}
+	return tagComparison{key: key, match: match}, nil
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+func TestParseAndEval(t *testing.T) {
+	span := &model.Span{
+		OperationName: "/health",
+		Duration:      5 * time.Millisecond,
+		Tags: model.KeyValues{
+			model.Int64("http.status_code", 200),
+			model.String("http.method", "GET"),
+		},
+		Process: &model.Process{
+			ServiceName: "frontend",
+			Tags:        model.KeyValues{model.String("hostname", "web-1")},
+		},
+	}
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{`service == "frontend"`, true},
+		{`service != "frontend"`, false},
+		{`operation =~ "^/heal"`, true},
+		{`operation !~ "^/heal"`, false},
+		{`duration < 10ms`, true},
+		{`duration >= 10ms`, false},
+		{`duration == 5ms`, true},
+		{`tag.http.status_code == "200"`, true},
+		{`tag.http.status_code >= 500`, false},
+		{`tag.http.status_code < 300`, true},
+		{`tag.http.method < 1`, false},
+		{`tag.missing == ""`, true},
+		{`tag.missing > 1`, false},
+		{`tag.hostname =~ "^web-"`, true},
+		{`service == "frontend" && operation == "/login"`, false},
+		{`service == "frontend" && (operation == "/login" || duration < 1s)`, true},
+		{`!(service == "frontend")`, false},
+		{`service == "other" || !tag.http.method == "POST"`, true},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			e, err := Parse(test.expr)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, e.Eval(span))
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{``, "unexpected end of expression"},
+		{`service ==`, "unexpected end of expression"},
+		{`service == "frontend`, "unterminated string at position 11"},
+		{`service # "x"`, "unexpected character '#' at position 8"},
+		{`"x" == service`, `expecting a field name at position 0, got "x"`},
+		{`service frontend`, `expecting an operator at position 8, got "frontend"`},
+		{`service == (`, `expecting a value at position 11, got "("`},
+		{`host == "x"`, `unknown field "host" at position 0`},
+		{`service < "x"`, `operator "<" at position 8 is not supported for strings`},
+		{`service =~ "("`, "invalid regular expression at position 11"},
+		{`duration =~ 1s`, `operator "=~" at position 9 is not supported for durations`},
+		{`duration > 1x`, "invalid duration at position 11"},
+		{`tag.x > "a"`, "invalid number at position 8"},
+		{`(service == "x"`, "missing closing parenthesis"},
+		{`service == "x" )`, `unexpected ")" at position 15`},
+		{`!`, "unexpected end of expression"},
+		{`service == "x" && `, "unexpected end of expression"},
+		{`service == "x" || `, "unexpected end of expression"},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			_, err := Parse(test.expr)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.err)
+		})
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+const (
+	// ActionDrop rejects spans matching the rule
+	ActionDrop = "drop"
+	// ActionKeep accepts spans matching the rule, skipping the rules that follow
+	ActionKeep = "keep"
+)
+
+// Config is the content of the span filter rules file or of the inline rules of the configuration file.
+type Config struct {
+	Rules []RuleConfig `json:"rules"`
+}
+
+// RuleConfig describes a single filtering rule.
+type RuleConfig struct {
+	Name       string `json:"name"`
+	Action     string `json:"action"`
+	Expression string `json:"expression"`
+}
+
+type rule struct {
+	name    string
+	keep    bool
+	expr    Expression
+	matched metrics.Counter
+}
+
+// Filter applies the rules in order and stops at the first one matching the span.
+// Spans not matching any rule are kept.
+type Filter struct {
+	metricsFactory metrics.Factory
+	rules          atomic.Value // holds []rule
+}
+
+// LoadConfig reads the rules from a JSON file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open span filter rules file: %w", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal span filter rules: %w", err)
+	}
+	return &cfg, nil
+}
+
+// NewFilter compiles the rules and creates a counter of matched spans for each of them.
+func NewFilter(cfg *Config, metricsFactory metrics.Factory) (*Filter, error) {
+	f := &Filter{metricsFactory: metricsFactory}
+	if err := f.Update(cfg); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Update replaces the rules of a running filter. The previous rules remain in use if the new ones are invalid.
+func (f *Filter) Update(cfg *Config) error {
+	var rules []rule
+	names := make(map[string]bool)
+	for i, rc := range cfg.Rules {
+		name := rc.Name
+		if name == "" {
+			name = fmt.Sprintf("rule-%d", i)
+		}
+		if names[name] {
+			return fmt.Errorf("duplicate span filter rule name %q", name)
+		}
+		names[name] = true
+		if rc.Action != ActionDrop && rc.Action != ActionKeep {
+			return fmt.Errorf("span filter rule %q: unknown action %q, expecting %q or %q", name, rc.Action, ActionDrop, ActionKeep)
+		}
+		expr, err := Parse(rc.Expression)
+		if err != nil {
+			return fmt.Errorf("span filter rule %q: %w", name, err)
+		}
+		rules = append(rules, rule{
+			name: name,
+			keep: rc.Action == ActionKeep,
+			expr: expr,
+			matched: f.metricsFactory.Counter(metrics.Options{
+				Name: "spans",
+				Tags: map[string]string{"rule": name, "action": rc.Action},
+			}),
+		})
+	}
+	f.rules.Store(rules)
+	return nil
+}
+
+// Keep returns false if the span must be dropped. Its signature matches app.FilterSpan.
+func (f *Filter) Keep(span *model.Span) bool {
+	for _, r := range f.rules.Load().([]rule) {
+		if r.expr.Eval(span) {
+			r.matched.Inc(1)
+			return r.keep
+		}
+	}
+	return true
+}
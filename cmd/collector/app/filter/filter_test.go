@@ -0,0 +1,160 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/config"
+)
+
+func TestLoadConfig(t *testing.T) {
+	f, err := ioutil.TempFile("", "span-filter")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`{"rules":[{"name":"health","action":"drop","expression":"operation == \"/health\""}]}`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	cfg, err := LoadConfig(f.Name())
+	require.NoError(t, err)
+	assert.Equal(t, &Config{Rules: []RuleConfig{{Name: "health", Action: ActionDrop, Expression: `operation == "/health"`}}}, cfg)
+
+	_, err = LoadConfig("/does/not/exist")
+	assert.Contains(t, err.Error(), "failed to open span filter rules file")
+
+	require.NoError(t, ioutil.WriteFile(f.Name(), []byte("{"), 0600))
+	_, err = LoadConfig(f.Name())
+	assert.Contains(t, err.Error(), "failed to unmarshal span filter rules")
+}
+
+func TestFilter(t *testing.T) {
+	mf := metricstest.NewFactory(0)
+	f, err := NewFilter(&Config{Rules: []RuleConfig{
+		{Name: "keep-errors", Action: ActionKeep, Expression: `tag.error == "true"`},
+		{Name: "health", Action: ActionDrop, Expression: `operation =~ "^/health"`},
+		{Action: ActionDrop, Expression: `service == "noisy"`},
+	}}, mf)
+	require.NoError(t, err)
+
+	process := &model.Process{ServiceName: "frontend"}
+	assert.True(t, f.Keep(&model.Span{OperationName: "/health", Process: process, Tags: model.KeyValues{model.Bool("error", true)}}))
+	assert.False(t, f.Keep(&model.Span{OperationName: "/health", Process: process}))
+	assert.False(t, f.Keep(&model.Span{OperationName: "/health/live", Process: process}))
+	assert.False(t, f.Keep(&model.Span{OperationName: "/api", Process: &model.Process{ServiceName: "noisy"}}))
+	assert.True(t, f.Keep(&model.Span{OperationName: "/api", Process: process}))
+
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "spans", Tags: map[string]string{"rule": "keep-errors", "action": "keep"}, Value: 1},
+		metricstest.ExpectedMetric{Name: "spans", Tags: map[string]string{"rule": "health", "action": "drop"}, Value: 2},
+		metricstest.ExpectedMetric{Name: "spans", Tags: map[string]string{"rule": "rule-2", "action": "drop"}, Value: 1},
+	)
+}
+
+func TestNewFilterErrors(t *testing.T) {
+	tests := []struct {
+		rules []RuleConfig
+		err   string
+	}{
+		{[]RuleConfig{{Name: "a", Action: "delete", Expression: `service == "x"`}}, `span filter rule "a": unknown action "delete"`},
+		{[]RuleConfig{{Name: "a", Action: ActionDrop, Expression: `service`}}, `span filter rule "a": unexpected end of expression`},
+		{[]RuleConfig{
+			{Name: "a", Action: ActionDrop, Expression: `service == "x"`},
+			{Name: "a", Action: ActionDrop, Expression: `service == "y"`},
+		}, `duplicate span filter rule name "a"`},
+	}
+	for _, test := range tests {
+		_, err := NewFilter(&Config{Rules: test.rules}, metrics.NullFactory)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), test.err)
+	}
+}
+
+func TestFilterUpdate(t *testing.T) {
+	f, err := NewFilter(&Config{Rules: []RuleConfig{
+		{Name: "health", Action: ActionDrop, Expression: `operation == "/health"`},
+	}}, metrics.NullFactory)
+	require.NoError(t, err)
+	health := &model.Span{OperationName: "/health", Process: &model.Process{ServiceName: "frontend"}}
+	assert.False(t, f.Keep(health))
+
+	err = f.Update(&Config{Rules: []RuleConfig{{Name: "bad", Action: ActionDrop, Expression: `service`}}})
+	assert.Contains(t, err.Error(), `span filter rule "bad"`)
+	assert.False(t, f.Keep(health), "previous rules must remain in use")
+
+	require.NoError(t, f.Update(&Config{}))
+	assert.True(t, f.Keep(health))
+}
+
+func TestOptionsInlineRules(t *testing.T) {
+	rulesFile, err := ioutil.TempFile("", "span-filter")
+	require.NoError(t, err)
+	defer os.Remove(rulesFile.Name())
+	_, err = rulesFile.WriteString(`{"rules":[{"name":"health","action":"drop","expression":"operation == \"/health\""}]}`)
+	require.NoError(t, err)
+	require.NoError(t, rulesFile.Close())
+
+	v, _ := config.Viperize(AddFlags)
+	v.SetConfigType("yaml")
+	require.NoError(t, v.ReadConfig(strings.NewReader(`
+collector:
+  span-filter:
+    rules-file: `+rulesFile.Name()+`
+    rules:
+      - name: noisy
+        action: drop
+        expression: service == "noisy"
+`)))
+	opts := new(Options).InitFromViper(v)
+	assert.True(t, opts.Enabled())
+	cfg, err := opts.Config()
+	require.NoError(t, err)
+	assert.Equal(t, []RuleConfig{
+		{Name: "health", Action: ActionDrop, Expression: `operation == "/health"`},
+		{Name: "noisy", Action: ActionDrop, Expression: `service == "noisy"`},
+	}, cfg.Rules)
+
+	f, err := NewFilter(&Config{}, metrics.NullFactory)
+	require.NoError(t, err)
+	require.NoError(t, Reloader(f)(v))
+	assert.False(t, f.Keep(&model.Span{OperationName: "/api", Process: &model.Process{ServiceName: "noisy"}}))
+
+	require.NoError(t, v.ReadConfig(strings.NewReader(`
+collector:
+  span-filter:
+    rules: invalid
+`)))
+	opts = new(Options).InitFromViper(v)
+	assert.True(t, opts.Enabled())
+	_, err = opts.Config()
+	assert.Contains(t, err.Error(), "failed to decode inline span filter rules")
+	assert.Contains(t, Reloader(f)(v).Error(), "failed to load span filter rules")
+}
+
+func TestOptionsWithFlags(t *testing.T) {
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{"--collector.span-filter.rules-file=/etc/jaeger/rules.json"})
+	opts := new(Options).InitFromViper(v)
+	assert.Equal(t, "/etc/jaeger/rules.json", opts.RulesFile)
+}
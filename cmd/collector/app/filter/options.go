@@ -0,0 +1,86 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	rulesFile = "collector.span-filter.rules-file"
+	// rules can only be set in the configuration file, as a list of name, action and expression entries
+	rules = "collector.span-filter.rules"
+)
+
+// Options holds the configuration of the span filter.
+type Options struct {
+	// RulesFile is the path to a JSON file with the filtering rules
+	RulesFile string
+	// Rules are the filtering rules listed in the configuration file, applied after the rules of RulesFile
+	Rules []RuleConfig
+
+	rulesErr error
+}
+
+// AddFlags adds flags for span filter Options
+func AddFlags(flags *flag.FlagSet) {
+	flags.String(rulesFile, "", "Path to a JSON file with rules to drop or keep spans based on service, operation, tags and duration, "+
+		`e.g. {"rules":[{"name":"health","action":"drop","expression":"operation == \"/health\""}]}`)
+}
+
+// InitFromViper initializes Options with properties from viper
+func (o *Options) InitFromViper(v *viper.Viper) *Options {
+	o.RulesFile = v.GetString(rulesFile)
+	o.Rules = nil
+	// an invalid list is reported by Config, as InitFromViper cannot fail
+	o.rulesErr = v.UnmarshalKey(rules, &o.Rules)
+	return o
+}
+
+// Enabled returns true if any filtering rule is configured.
+func (o *Options) Enabled() bool {
+	return o.RulesFile != "" || len(o.Rules) > 0 || o.rulesErr != nil
+}
+
+// Config loads the rules of RulesFile and appends the inline Rules.
+func (o *Options) Config() (*Config, error) {
+	if o.rulesErr != nil {
+		return nil, fmt.Errorf("failed to decode inline span filter rules: %w", o.rulesErr)
+	}
+	cfg := &Config{}
+	if o.RulesFile != "" {
+		fileCfg, err := LoadConfig(o.RulesFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Rules = append(cfg.Rules, fileCfg.Rules...)
+	}
+	cfg.Rules = append(cfg.Rules, o.Rules...)
+	return cfg, nil
+}
+
+// Reloader returns a function replacing the rules of the filter with the ones currently configured in viper.
+func Reloader(f *Filter) func(v *viper.Viper) error {
+	return func(v *viper.Viper) error {
+		cfg, err := new(Options).InitFromViper(v).Config()
+		if err != nil {
+			return fmt.Errorf("failed to load span filter rules: %w", err)
+		}
+		return f.Update(cfg)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/jaegertracing/jaeger/cmd/collector/app/processor"
+)
+
+// HTTPClientInfo describes the client that sent the HTTP request
+func HTTPClientInfo(r *http.Request) processor.ClientInfo {
+	return processor.ClientInfo{
+		IP:       hostIP(r.RemoteAddr),
+		Metadata: r.Header.Get,
+	}
+}
+
+// GRPCClientInfo describes the client of the gRPC call
+func GRPCClientInfo(ctx context.Context) processor.ClientInfo {
+	var client processor.ClientInfo
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		client.IP = hostIP(p.Addr.String())
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		client.Metadata = func(key string) string {
+			if values := md.Get(key); len(values) > 0 {
+				return values[0]
+			}
+			return ""
+		}
+	}
+	return client
+}
+
+func hostIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return ""
+	}
+	return host
+}
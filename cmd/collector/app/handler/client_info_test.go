@@ -0,0 +1,49 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestHTTPClientInfo(t *testing.T) {
+	r := httptest.NewRequest("POST", "/api/traces", nil)
+	r.RemoteAddr = "10.0.0.1:3456"
+	r.Header.Set("X-K8s-Pod-Name", "frontend-1")
+	client := HTTPClientInfo(r)
+	assert.Equal(t, "10.0.0.1", client.IP)
+	assert.Equal(t, "frontend-1", client.Get("x-k8s-pod-name"))
+	assert.Equal(t, "", client.Get("x-k8s-namespace"))
+}
+
+func TestGRPCClientInfo(t *testing.T) {
+	client := GRPCClientInfo(context.Background())
+	assert.Equal(t, "", client.IP)
+	assert.Equal(t, "", client.Get("x-k8s-pod-name"))
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("fd00::1"), Port: 14250}})
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-k8s-pod-name", "frontend-1"))
+	client = GRPCClientInfo(ctx)
+	assert.Equal(t, "fd00::1", client.IP)
+	assert.Equal(t, "frontend-1", client.Get("X-K8s-Pod-Name"))
+	assert.Equal(t, "", client.Get("x-k8s-namespace"))
+}
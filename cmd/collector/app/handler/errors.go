@@ -0,0 +1,57 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/jaegertracing/jaeger/cmd/collector/app/processor"
+)
+
+// SubmitErrorHTTPStatus returns the HTTP status code for an error returned by the span processor,
+// falling back to the given code for errors other than throttling and draining.
+func SubmitErrorHTTPStatus(err error, fallback int) int {
+	if errors.Is(err, processor.ErrRateLimited) {
+		return http.StatusTooManyRequests
+	}
+	if errors.Is(err, processor.ErrDraining) {
+		return http.StatusServiceUnavailable
+	}
+	return fallback
+}
+
+// WriteSubmitError writes an HTTP error response for an error returned by the span processor.
+func WriteSubmitError(w http.ResponseWriter, message string, err error, fallback int) {
+	code := SubmitErrorHTTPStatus(err, fallback)
+	if code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable {
+		w.Header().Set("Retry-After", "1")
+	}
+	http.Error(w, message, code)
+}
+
+// submitErrorGRPC converts an error returned by the span processor to a gRPC status error.
+func submitErrorGRPC(err error) error {
+	if errors.Is(err, processor.ErrRateLimited) {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if errors.Is(err, processor.ErrDraining) {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return err
+}
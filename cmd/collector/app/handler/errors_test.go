@@ -0,0 +1,60 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/jaegertracing/jaeger/cmd/collector/app/processor"
+)
+
+func TestSubmitErrorHTTPStatus(t *testing.T) {
+	assert.Equal(t, http.StatusTooManyRequests, SubmitErrorHTTPStatus(processor.ErrRateLimited, http.StatusInternalServerError))
+	assert.Equal(t, http.StatusTooManyRequests, SubmitErrorHTTPStatus(fmt.Errorf("wrapped: %w", processor.ErrRateLimited), http.StatusInternalServerError))
+	assert.Equal(t, http.StatusServiceUnavailable, SubmitErrorHTTPStatus(processor.ErrDraining, http.StatusInternalServerError))
+	assert.Equal(t, http.StatusInternalServerError, SubmitErrorHTTPStatus(errors.New("boom"), http.StatusInternalServerError))
+}
+
+func TestWriteSubmitError(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteSubmitError(w, "throttled", processor.ErrRateLimited, http.StatusInternalServerError)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	w = httptest.NewRecorder()
+	WriteSubmitError(w, "draining", processor.ErrDraining, http.StatusInternalServerError)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	w = httptest.NewRecorder()
+	WriteSubmitError(w, "failed", errors.New("boom"), http.StatusInternalServerError)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Empty(t, w.Header().Get("Retry-After"))
+}
+
+func TestSubmitErrorGRPC(t *testing.T) {
+	assert.Equal(t, codes.ResourceExhausted, status.Code(submitErrorGRPC(processor.ErrRateLimited)))
+	assert.Equal(t, codes.Unavailable, status.Code(submitErrorGRPC(processor.ErrDraining)))
+	err := errors.New("boom")
+	assert.Equal(t, err, submitErrorGRPC(err))
+}
@@ -25,9 +25,13 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	"github.com/jaegertracing/jaeger/cmd/collector/app/processor"
 	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
 	"github.com/jaegertracing/jaeger/proto-gen/api_v2"
 )
 
@@ -35,12 +39,14 @@ type mockSpanProcessor struct {
 	expectedError error
 	mux           sync.Mutex
 	spans         []*model.Span
+	tenant        string
 }
 
 func (p *mockSpanProcessor) ProcessSpans(spans []*model.Span, opts processor.SpansOptions) ([]bool, error) {
 	p.mux.Lock()
 	defer p.mux.Unlock()
 	p.spans = append(p.spans, spans...)
+	p.tenant = opts.Tenant
 	oks := make([]bool, len(spans))
 	return oks, p.expectedError
 }
@@ -82,7 +88,7 @@ func newClient(t *testing.T, addr net.Addr) (api_v2.CollectorServiceClient, *grp
 func TestPostSpans(t *testing.T) {
 	processor := &mockSpanProcessor{}
 	server, addr := initializeGRPCTestServer(t, func(s *grpc.Server) {
-		handler := NewGRPCHandler(zap.NewNop(), processor)
+		handler := NewGRPCHandler(zap.NewNop(), processor, nil)
 		api_v2.RegisterCollectorServiceServer(s, handler)
 	})
 	defer server.Stop()
@@ -114,7 +120,7 @@ func TestPostSpansWithError(t *testing.T) {
 	expectedError := errors.New("test-error")
 	processor := &mockSpanProcessor{expectedError: expectedError}
 	server, addr := initializeGRPCTestServer(t, func(s *grpc.Server) {
-		handler := NewGRPCHandler(zap.NewNop(), processor)
+		handler := NewGRPCHandler(zap.NewNop(), processor, nil)
 		api_v2.RegisterCollectorServiceServer(s, handler)
 	})
 	defer server.Stop()
@@ -134,3 +140,39 @@ func TestPostSpansWithError(t *testing.T) {
 	require.Contains(t, err.Error(), expectedError.Error())
 	require.Len(t, processor.getSpans(), 1)
 }
+
+func TestPostSpansWithTenant(t *testing.T) {
+	processor := &mockSpanProcessor{}
+	tenancyMgr := tenancy.NewManager(&tenancy.Options{Enabled: true, Tenants: []string{"acme"}})
+	server, addr := initializeGRPCTestServer(t, func(s *grpc.Server) {
+		api_v2.RegisterCollectorServiceServer(s, NewGRPCHandler(zap.NewNop(), processor, tenancyMgr))
+	})
+	defer server.Stop()
+	client, conn := newClient(t, addr)
+	defer conn.Close()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		code codes.Code
+	}{
+		{name: "missing tenant", ctx: context.Background(), code: codes.Unauthenticated},
+		{name: "unknown tenant", ctx: metadata.AppendToOutgoingContext(context.Background(), tenancy.DefaultHeader, "megacorp"), code: codes.PermissionDenied},
+		{name: "valid tenant", ctx: metadata.AppendToOutgoingContext(context.Background(), tenancy.DefaultHeader, "acme"), code: codes.OK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			processor.reset()
+			_, err := client.PostSpans(test.ctx, &api_v2.PostSpansRequest{
+				Batch: model.Batch{Spans: []*model.Span{{OperationName: "fake-operation"}}},
+			})
+			assert.Equal(t, test.code, status.Code(err))
+			if test.code == codes.OK {
+				assert.Len(t, processor.getSpans(), 1)
+				assert.Equal(t, "acme", processor.tenant)
+			} else {
+				assert.Empty(t, processor.getSpans())
+			}
+		})
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/gorilla/mux"
 
 	"github.com/jaegertracing/jaeger/cmd/collector/app/processor"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
 	tJaeger "github.com/jaegertracing/jaeger/thrift-gen/jaeger"
 )
 
@@ -43,14 +44,17 @@ var (
 // APIHandler handles all HTTP calls to the collector
 type APIHandler struct {
 	jaegerBatchesHandler JaegerBatchesHandler
+	tenancyMgr           *tenancy.Manager
 }
 
 // NewAPIHandler returns a new APIHandler
 func NewAPIHandler(
 	jaegerBatchesHandler JaegerBatchesHandler,
+	tenancyMgr *tenancy.Manager,
 ) *APIHandler {
 	return &APIHandler{
 		jaegerBatchesHandler: jaegerBatchesHandler,
+		tenancyMgr:           tenancyMgr,
 	}
 }
 
@@ -61,6 +65,13 @@ func (aH *APIHandler) RegisterRoutes(router *mux.Router) {
 
 // SaveSpan submits the span provided in the request body to the JaegerBatchesHandler
 func (aH *APIHandler) SaveSpan(w http.ResponseWriter, r *http.Request) {
+	tenant, err := aH.tenancyMgr.GetValidHTTPTenant(r)
+	if err != nil {
+		r.Body.Close()
+		http.Error(w, err.Error(), tenancy.HTTPStatus(err))
+		return
+	}
+
 	bodyBytes, err := ioutil.ReadAll(r.Body)
 	r.Body.Close()
 	if err != nil {
@@ -87,9 +98,9 @@ func (aH *APIHandler) SaveSpan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	batches := []*tJaeger.Batch{batch}
-	opts := SubmitBatchOptions{InboundTransport: processor.HTTPTransport}
+	opts := SubmitBatchOptions{InboundTransport: processor.HTTPTransport, Tenant: tenant, Client: HTTPClientInfo(r)}
 	if _, err = aH.jaegerBatchesHandler.SubmitBatches(batches, opts); err != nil {
-		http.Error(w, fmt.Sprintf("Cannot submit Jaeger batch: %v", err), http.StatusInternalServerError)
+		WriteSubmitError(w, fmt.Sprintf("Cannot submit Jaeger batch: %v", err), err, http.StatusInternalServerError)
 		return
 	}
 
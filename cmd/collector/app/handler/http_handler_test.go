@@ -32,6 +32,7 @@ import (
 	jaegerClient "github.com/uber/jaeger-client-go"
 	"github.com/uber/jaeger-client-go/transport"
 
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
 	"github.com/jaegertracing/jaeger/thrift-gen/jaeger"
 )
 
@@ -41,12 +42,14 @@ type mockJaegerHandler struct {
 	err     error
 	mux     sync.Mutex
 	batches []*jaeger.Batch
+	tenant  string
 }
 
-func (p *mockJaegerHandler) SubmitBatches(batches []*jaeger.Batch, _ SubmitBatchOptions) ([]*jaeger.BatchSubmitResponse, error) {
+func (p *mockJaegerHandler) SubmitBatches(batches []*jaeger.Batch, opts SubmitBatchOptions) ([]*jaeger.BatchSubmitResponse, error) {
 	p.mux.Lock()
 	defer p.mux.Unlock()
 	p.batches = append(p.batches, batches...)
+	p.tenant = opts.Tenant
 	return nil, p.err
 }
 
@@ -58,7 +61,7 @@ func (p *mockJaegerHandler) getBatches() []*jaeger.Batch {
 
 func initializeTestServer(err error) (*httptest.Server, *APIHandler) {
 	r := mux.NewRouter()
-	handler := NewAPIHandler(&mockJaegerHandler{err: err})
+	handler := NewAPIHandler(&mockJaegerHandler{err: err}, nil)
 	handler.RegisterRoutes(r)
 	return httptest.NewServer(r), handler
 }
@@ -159,7 +162,7 @@ func TestMalformedFormat(t *testing.T) {
 }
 
 func TestCannotReadBodyFromRequest(t *testing.T) {
-	handler := NewAPIHandler(&mockJaegerHandler{})
+	handler := NewAPIHandler(&mockJaegerHandler{}, nil)
 	req, err := http.NewRequest(http.MethodPost, "whatever", &errReader{})
 	assert.NoError(t, err)
 	rw := dummyResponseWriter{}
@@ -210,3 +213,35 @@ func postBytes(contentType, urlStr string, bodyBytes []byte) (int, string, error
 	}
 	return res.StatusCode, string(body), nil
 }
+
+func TestSaveSpanWithTenant(t *testing.T) {
+	batch := &jaeger.Batch{Process: &jaeger.Process{ServiceName: "serviceName"}, Spans: []*jaeger.Span{{OperationName: "opName"}}}
+	someBytes, err := thrift.NewTSerializer().Write(context.Background(), batch)
+	assert.NoError(t, err)
+
+	jaegerHandler := &mockJaegerHandler{}
+	handler := NewAPIHandler(jaegerHandler, tenancy.NewManager(&tenancy.Options{Enabled: true, Tenants: []string{"acme"}}))
+	tests := []struct {
+		name   string
+		tenant string
+		code   int
+	}{
+		{name: "missing tenant", code: http.StatusUnauthorized},
+		{name: "unknown tenant", tenant: "megacorp", code: http.StatusForbidden},
+		{name: "valid tenant", tenant: "acme", code: http.StatusAccepted},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/traces", bytes.NewReader(someBytes))
+			req.Header.Set("Content-Type", "application/x-thrift")
+			if test.tenant != "" {
+				req.Header.Set(tenancy.DefaultHeader, test.tenant)
+			}
+			w := httptest.NewRecorder()
+			handler.SaveSpan(w, req)
+			assert.Equal(t, test.code, w.Code)
+		})
+	}
+	assert.Len(t, jaegerHandler.getBatches(), 1)
+	assert.Equal(t, "acme", jaegerHandler.tenant)
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"io"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/jaegertracing/jaeger/cmd/collector/app/processor"
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/model/converter/opencensus"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
+)
+
+// OpenCensusHandler accepts spans over the OpenCensus agent protocol and passes them to the span processor,
+// so that services instrumented with OpenCensus can report to Jaeger without an OpenCensus agent.
+type OpenCensusHandler struct {
+	logger        *zap.Logger
+	spanProcessor processor.SpanProcessor
+	tenancyMgr    *tenancy.Manager
+}
+
+// NewOpenCensusHandler returns a new OpenCensusHandler.
+func NewOpenCensusHandler(logger *zap.Logger, spanProcessor processor.SpanProcessor, tenancyMgr *tenancy.Manager) *OpenCensusHandler {
+	return &OpenCensusHandler{
+		logger:        logger,
+		spanProcessor: spanProcessor,
+		tenancyMgr:    tenancyMgr,
+	}
+}
+
+// Export implements the Export stream of the OpenCensus agent TraceService. The node and
+// resource of a message apply to the following messages of the stream that omit them.
+func (h *OpenCensusHandler) Export(stream opencensus.TraceServiceExportServer) error {
+	ctx := stream.Context()
+	tenant, err := h.tenancyMgr.GetValidGRPCTenant(ctx)
+	if err != nil {
+		return err
+	}
+	client := GRPCClientInfo(ctx)
+
+	var node *opencensus.Node
+	var resource *opencensus.Resource
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if req.Node != nil {
+			node = req.Node
+		}
+		if req.Resource != nil {
+			resource = req.Resource
+		}
+		batches, err := opencensus.ToDomain(node, resource, req.Spans)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		if err := h.processBatches(batches, tenant, client); err != nil {
+			return submitErrorGRPC(err)
+		}
+	}
+}
+
+// Config implements the Config stream of the OpenCensus agent TraceService. Jaeger does not
+// push trace configs to the libraries, the stream is only drained until the client closes it.
+func (h *OpenCensusHandler) Config(stream opencensus.TraceServiceConfigServer) error {
+	for {
+		if _, err := stream.Recv(); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func (h *OpenCensusHandler) processBatches(batches []*model.Batch, tenant string, client processor.ClientInfo) error {
+	var spans []*model.Span
+	for _, batch := range batches {
+		spans = append(spans, batch.Spans...)
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+	_, err := h.spanProcessor.ProcessSpans(spans, processor.SpansOptions{
+		InboundTransport: processor.GRPCTransport,
+		SpanFormat:       processor.OpenCensusSpanFormat,
+		Tenant:           tenant,
+		Client:           client,
+	})
+	if err != nil {
+		h.logger.Error("cannot process OpenCensus spans", zap.Error(err))
+	}
+	return err
+}
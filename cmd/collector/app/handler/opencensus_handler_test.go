@@ -0,0 +1,138 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/jaegertracing/jaeger/model/converter/opencensus"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
+)
+
+var (
+	validOCTraceID = []byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 2}
+	validOCSpanID  = []byte{0, 0, 0, 0, 0, 0, 0, 3}
+)
+
+func ocSpan(traceID []byte, name string) *opencensus.Span {
+	return &opencensus.Span{TraceID: traceID, SpanID: validOCSpanID, Name: &opencensus.TruncatableString{Value: name}}
+}
+
+func startOpenCensusTestServer(t *testing.T, processor *mockSpanProcessor, tenancyMgr *tenancy.Manager) (*grpc.Server, opencensus.TraceServiceClient, *grpc.ClientConn) {
+	server, addr := initializeGRPCTestServer(t, func(s *grpc.Server) {
+		opencensus.RegisterTraceServiceServer(s, NewOpenCensusHandler(zap.NewNop(), processor, tenancyMgr))
+	})
+	conn, err := grpc.Dial(addr.String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	return server, opencensus.NewTraceServiceClient(conn), conn
+}
+
+// exportOC sends the requests on a new Export stream and returns the status the stream ended with
+func exportOC(t *testing.T, ctx context.Context, client opencensus.TraceServiceClient, reqs ...*opencensus.ExportTraceServiceRequest) error {
+	stream, err := client.Export(ctx)
+	require.NoError(t, err)
+	for _, req := range reqs {
+		if err := stream.Send(req); err != nil {
+			break
+		}
+	}
+	require.NoError(t, stream.CloseSend())
+	_, err = stream.Recv()
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+func TestOpenCensusExport(t *testing.T) {
+	processor := &mockSpanProcessor{}
+	server, client, conn := startOpenCensusTestServer(t, processor, nil)
+	defer server.Stop()
+	defer conn.Close()
+
+	err := exportOC(t, context.Background(), client,
+		&opencensus.ExportTraceServiceRequest{
+			Node:  &opencensus.Node{ServiceInfo: &opencensus.ServiceInfo{Name: "legacy"}},
+			Spans: []*opencensus.Span{ocSpan(validOCTraceID, "first")},
+		},
+		&opencensus.ExportTraceServiceRequest{
+			Spans: []*opencensus.Span{ocSpan(validOCTraceID, "second")},
+		},
+	)
+	require.NoError(t, err)
+	spans := processor.getSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "first", spans[0].OperationName)
+	assert.Equal(t, "second", spans[1].OperationName)
+	assert.Equal(t, "legacy", spans[1].Process.ServiceName, "the node of the first message applies to the stream")
+}
+
+func TestOpenCensusExportErrors(t *testing.T) {
+	processor := &mockSpanProcessor{}
+	server, client, conn := startOpenCensusTestServer(t, processor, nil)
+	defer server.Stop()
+	defer conn.Close()
+
+	err := exportOC(t, context.Background(), client, &opencensus.ExportTraceServiceRequest{
+		Spans: []*opencensus.Span{ocSpan([]byte{1}, "invalid")},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	processor.expectedError = errors.New("queue full")
+	err = exportOC(t, context.Background(), client, &opencensus.ExportTraceServiceRequest{
+		Spans: []*opencensus.Span{ocSpan(validOCTraceID, "op")},
+	})
+	assert.Contains(t, err.Error(), "queue full")
+}
+
+func TestOpenCensusExportTenancy(t *testing.T) {
+	processor := &mockSpanProcessor{}
+	tenancyMgr := tenancy.NewManager(&tenancy.Options{Enabled: true})
+	server, client, conn := startOpenCensusTestServer(t, processor, tenancyMgr)
+	defer server.Stop()
+	defer conn.Close()
+	req := &opencensus.ExportTraceServiceRequest{Spans: []*opencensus.Span{ocSpan(validOCTraceID, "op")}}
+
+	err := exportOC(t, context.Background(), client, req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), tenancyMgr.Header, "acme")
+	require.NoError(t, exportOC(t, ctx, client, req))
+	assert.Equal(t, "acme", processor.tenant)
+}
+
+func TestOpenCensusConfig(t *testing.T) {
+	server, _, conn := startOpenCensusTestServer(t, &mockSpanProcessor{}, nil)
+	defer server.Stop()
+	defer conn.Close()
+
+	stream, err := conn.NewStream(context.Background(), &grpc.StreamDesc{ServerStreams: true, ClientStreams: true},
+		"/opencensus.proto.agent.trace.v1.TraceService/Config")
+	require.NoError(t, err)
+	require.NoError(t, stream.SendMsg(&opencensus.CurrentLibraryConfig{Node: &opencensus.Node{}}))
+	require.NoError(t, stream.CloseSend())
+	assert.Equal(t, io.EOF, stream.RecvMsg(&opencensus.UpdatedLibraryConfig{}))
+}
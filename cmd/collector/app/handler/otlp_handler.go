@@ -0,0 +1,164 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/jaegertracing/jaeger/cmd/collector/app/processor"
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/model/converter/otlp"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
+)
+
+const (
+	otlpProtobufContentType = "application/x-protobuf"
+	otlpJSONContentType     = "application/json"
+)
+
+// OTLPHandler accepts spans in the OpenTelemetry protocol over gRPC and HTTP
+// and passes them to the span processor.
+type OTLPHandler struct {
+	logger        *zap.Logger
+	spanProcessor processor.SpanProcessor
+	tenancyMgr    *tenancy.Manager
+}
+
+// NewOTLPHandler returns a new OTLPHandler.
+func NewOTLPHandler(logger *zap.Logger, spanProcessor processor.SpanProcessor, tenancyMgr *tenancy.Manager) *OTLPHandler {
+	return &OTLPHandler{
+		logger:        logger,
+		spanProcessor: spanProcessor,
+		tenancyMgr:    tenancyMgr,
+	}
+}
+
+// Export implements OTLP gRPC TraceService.
+func (h *OTLPHandler) Export(ctx context.Context, req *otlp.ExportTraceServiceRequest) (*otlp.ExportTraceServiceResponse, error) {
+	tenant, err := h.tenancyMgr.GetValidGRPCTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	batches, err := otlp.ToDomain(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := h.processBatches(batches, processor.GRPCTransport, tenant, GRPCClientInfo(ctx)); err != nil {
+		return nil, submitErrorGRPC(err)
+	}
+	return &otlp.ExportTraceServiceResponse{}, nil
+}
+
+// RegisterRoutes registers OTLP/HTTP routes on the given router.
+func (h *OTLPHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/v1/traces", h.SaveSpans).Methods(http.MethodPost)
+}
+
+// SaveSpans handles OTLP/HTTP export requests in either protobuf or JSON encoding.
+func (h *OTLPHandler) SaveSpans(w http.ResponseWriter, r *http.Request) {
+	var body io.Reader = r.Body
+	defer r.Body.Close()
+	tenant, err := h.tenancyMgr.GetValidHTTPTenant(r)
+	if err != nil {
+		http.Error(w, err.Error(), tenancy.HTTPStatus(err))
+		return
+	}
+	if strings.Contains(r.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(UnableToReadBodyErrFormat, err), http.StatusBadRequest)
+			return
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	bodyBytes, err := ioutil.ReadAll(body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(UnableToReadBodyErrFormat, err), http.StatusInternalServerError)
+		return
+	}
+
+	contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Cannot parse content type: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	req := &otlp.ExportTraceServiceRequest{}
+	switch contentType {
+	case otlpProtobufContentType:
+		err = proto.Unmarshal(bodyBytes, req)
+	case otlpJSONContentType:
+		req, err = otlp.UnmarshalJSON(bodyBytes)
+	default:
+		http.Error(w, fmt.Sprintf("Unsupported content type: %v", contentType), http.StatusUnsupportedMediaType)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf(UnableToReadBodyErrFormat, err), http.StatusBadRequest)
+		return
+	}
+
+	batches, err := otlp.ToDomain(req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(UnableToReadBodyErrFormat, err), http.StatusBadRequest)
+		return
+	}
+	if err := h.processBatches(batches, processor.HTTPTransport, tenant, HTTPClientInfo(r)); err != nil {
+		WriteSubmitError(w, fmt.Sprintf("Cannot submit OTLP batch: %v", err), err, http.StatusServiceUnavailable)
+		return
+	}
+
+	// ExportTraceServiceResponse has no fields, so its encoding is empty in protobuf and {} in JSON.
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	if contentType == otlpJSONContentType {
+		w.Write([]byte("{}"))
+	}
+}
+
+func (h *OTLPHandler) processBatches(batches []*model.Batch, transport processor.InboundTransport, tenant string, client processor.ClientInfo) error {
+	var spans []*model.Span
+	for _, batch := range batches {
+		spans = append(spans, batch.Spans...)
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+	_, err := h.spanProcessor.ProcessSpans(spans, processor.SpansOptions{
+		InboundTransport: transport,
+		SpanFormat:       processor.OTLPSpanFormat,
+		Tenant:           tenant,
+		Client:           client,
+	})
+	if err != nil {
+		h.logger.Error("cannot process OTLP spans", zap.Error(err))
+	}
+	return err
+}
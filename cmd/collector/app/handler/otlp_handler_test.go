@@ -0,0 +1,174 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/jaegertracing/jaeger/model/converter/otlp"
+)
+
+func otlpTestRequest(traceID []byte) *otlp.ExportTraceServiceRequest {
+	return &otlp.ExportTraceServiceRequest{
+		ResourceSpans: []*otlp.ResourceSpans{{
+			Resource: &otlp.Resource{Attributes: []*otlp.KeyValue{
+				{Key: "service.name", Value: &otlp.AnyValue{Value: &otlp.AnyValueString{StringValue: "otlp-service"}}},
+			}},
+			ScopeSpans: []*otlp.ScopeSpans{{
+				Spans: []*otlp.Span{{
+					TraceID: traceID,
+					SpanID:  []byte{0, 0, 0, 0, 0, 0, 0, 1},
+					Name:    "otlp-op",
+				}},
+			}},
+		}},
+	}
+}
+
+var validOTLPTraceID = []byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 2}
+
+const otlpTestJSON = `{"resourceSpans":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"otlp-service"}}]},` +
+	`"scopeSpans":[{"spans":[{"traceId":"00000000000000010000000000000002","spanId":"0000000000000001","name":"otlp-op"}]}]}]}`
+
+func initializeOTLPTestServer(processor *mockSpanProcessor) *httptest.Server {
+	r := mux.NewRouter()
+	NewOTLPHandler(zap.NewNop(), processor, nil).RegisterRoutes(r)
+	return httptest.NewServer(r)
+}
+
+func postOTLP(t *testing.T, url string, contentType string, headers map[string]string, body []byte) (*http.Response, string) {
+	req, err := http.NewRequest(http.MethodPost, url+"/v1/traces", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	res, err := httpClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	resBody, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	return res, string(resBody)
+}
+
+func TestOTLPHTTPProtobuf(t *testing.T) {
+	processor := &mockSpanProcessor{}
+	server := initializeOTLPTestServer(processor)
+	defer server.Close()
+
+	body, err := proto.Marshal(otlpTestRequest(validOTLPTraceID))
+	require.NoError(t, err)
+	res, _ := postOTLP(t, server.URL, "application/x-protobuf", nil, body)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "application/x-protobuf", res.Header.Get("Content-Type"))
+
+	spans := processor.getSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "otlp-op", spans[0].OperationName)
+	assert.Equal(t, "otlp-service", spans[0].Process.ServiceName)
+}
+
+func TestOTLPHTTPJSON(t *testing.T) {
+	processor := &mockSpanProcessor{}
+	server := initializeOTLPTestServer(processor)
+	defer server.Close()
+
+	var gzBody bytes.Buffer
+	gz := gzip.NewWriter(&gzBody)
+	_, err := gz.Write([]byte(otlpTestJSON))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	res, body := postOTLP(t, server.URL, "application/json", map[string]string{"Content-Encoding": "gzip"}, gzBody.Bytes())
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "{}", body)
+	require.Len(t, processor.getSpans(), 1)
+	assert.Equal(t, "otlp-service", processor.getSpans()[0].Process.ServiceName)
+}
+
+func TestOTLPHTTPErrors(t *testing.T) {
+	processor := &mockSpanProcessor{}
+	server := initializeOTLPTestServer(processor)
+	defer server.Close()
+
+	badID, err := proto.Marshal(otlpTestRequest([]byte{1}))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		contentType string
+		headers     map[string]string
+		body        []byte
+		status      int
+	}{
+		{contentType: "application/thrift", body: []byte("{}"), status: http.StatusUnsupportedMediaType},
+		{contentType: "application/json;;", body: []byte("{}"), status: http.StatusBadRequest},
+		{contentType: "application/json", body: []byte("{"), status: http.StatusBadRequest},
+		{contentType: "application/x-protobuf", body: []byte{0xff}, status: http.StatusBadRequest},
+		{contentType: "application/x-protobuf", body: badID, status: http.StatusBadRequest},
+		{contentType: "application/json", headers: map[string]string{"Content-Encoding": "gzip"}, body: []byte("{}"), status: http.StatusBadRequest},
+	}
+	for _, tc := range testCases {
+		res, _ := postOTLP(t, server.URL, tc.contentType, tc.headers, tc.body)
+		assert.Equal(t, tc.status, res.StatusCode, tc.contentType)
+	}
+	assert.Empty(t, processor.getSpans())
+
+	processor.expectedError = errors.New("queue full")
+	res, body := postOTLP(t, server.URL, "application/json", nil, []byte(otlpTestJSON))
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	assert.Contains(t, body, "queue full")
+}
+
+func TestOTLPGRPCExport(t *testing.T) {
+	processor := &mockSpanProcessor{}
+	server, addr := initializeGRPCTestServer(t, func(s *grpc.Server) {
+		otlp.RegisterTraceServiceServer(s, NewOTLPHandler(zap.NewNop(), processor, nil))
+	})
+	defer server.Stop()
+	conn, err := grpc.Dial(addr.String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	export := func(req *otlp.ExportTraceServiceRequest) error {
+		return conn.Invoke(context.Background(), "/opentelemetry.proto.collector.trace.v1.TraceService/Export", req, &otlp.ExportTraceServiceResponse{})
+	}
+
+	require.NoError(t, export(otlpTestRequest(validOTLPTraceID)))
+	require.Len(t, processor.getSpans(), 1)
+	assert.Equal(t, "otlp-op", processor.getSpans()[0].OperationName)
+
+	err = export(otlpTestRequest([]byte{1}))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	processor.expectedError = errors.New("queue full")
+	err = export(otlpTestRequest(validOTLPTraceID))
+	assert.Contains(t, err.Error(), "queue full")
+}
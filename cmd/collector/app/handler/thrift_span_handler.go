@@ -30,6 +30,10 @@ import (
 // SubmitBatchOptions are passed to Submit methods of the handlers.
 type SubmitBatchOptions struct {
 	InboundTransport processor.InboundTransport
+	// Tenant is the validated tenant of the request, empty unless multi-tenancy is enabled
+	Tenant string
+	// Client describes who sent the request
+	Client processor.ClientInfo
 }
 
 // ZipkinSpansHandler consumes and handles zipkin spans
@@ -68,6 +72,8 @@ func (jbh *jaegerBatchesHandler) SubmitBatches(batches []*jaeger.Batch, options
 		oks, err := jbh.modelProcessor.ProcessSpans(mSpans, processor.SpansOptions{
 			InboundTransport: options.InboundTransport,
 			SpanFormat:       processor.JaegerSpanFormat,
+			Tenant:           options.Tenant,
+			Client:           options.Client,
 		})
 		if err != nil {
 			jbh.logger.Error("Collector failed to process span batch", zap.Error(err))
@@ -115,6 +121,8 @@ func (h *zipkinSpanHandler) SubmitZipkinBatch(spans []*zipkincore.Span, options
 	bools, err := h.modelProcessor.ProcessSpans(mSpans, processor.SpansOptions{
 		InboundTransport: options.InboundTransport,
 		SpanFormat:       processor.ZipkinSpanFormat,
+		Tenant:           options.Tenant,
+		Client:           options.Client,
 	})
 	if err != nil {
 		h.logger.Error("Collector failed to process Zipkin span batch", zap.Error(err))
@@ -0,0 +1,125 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sattributes
+
+import (
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/cmd/collector/app/processor"
+	"github.com/jaegertracing/jaeger/model"
+)
+
+const (
+	// HeaderNamespace is the request header or gRPC metadata key giving the namespace of the client pod
+	HeaderNamespace = "x-k8s-namespace"
+	// HeaderPodName is the request header or gRPC metadata key giving the name of the client pod
+	HeaderPodName = "x-k8s-pod-name"
+	// HeaderNodeName is the request header or gRPC metadata key giving the node of the client pod
+	HeaderNodeName = "x-k8s-node-name"
+
+	// NamespaceTag is the process tag holding the namespace of the pod
+	NamespaceTag = "k8s.namespace.name"
+	// PodNameTag is the process tag holding the name of the pod
+	PodNameTag = "k8s.pod.name"
+	// NodeNameTag is the process tag holding the node of the pod
+	NodeNameTag = "k8s.node.name"
+)
+
+type enricherMetrics struct {
+	FromHeaders metrics.Counter `metric:"spans_enriched" tags:"source=headers"`
+	FromLookup  metrics.Counter `metric:"spans_enriched" tags:"source=pod_lookup"`
+	Unresolved  metrics.Counter `metric:"spans_unresolved"`
+}
+
+// Enricher adds the Kubernetes metadata of the submitting pod to the process of the spans.
+type Enricher struct {
+	pods    *podCache
+	metrics enricherMetrics
+}
+
+// NewEnricher creates an Enricher; the pod lookup only starts with Start.
+func NewEnricher(opts Options, logger *zap.Logger, metricsFactory metrics.Factory) (*Enricher, error) {
+	e := &Enricher{}
+	metrics.Init(&e.metrics, metricsFactory, nil)
+	if opts.PodLookup {
+		pods, err := newPodCache(opts, logger)
+		if err != nil {
+			return nil, err
+		}
+		e.pods = pods
+	}
+	return e, nil
+}
+
+// Start lists the pods in the background, if the pod lookup is enabled.
+func (e *Enricher) Start() {
+	if e.pods != nil {
+		e.pods.start()
+	}
+}
+
+// Close stops listing the pods.
+func (e *Enricher) Close() error {
+	if e.pods != nil {
+		e.pods.stop()
+	}
+	return nil
+}
+
+// Enrich adds the metadata of the client pod to the spans of a batch. The pod headers sent
+// by the client take precedence over the lookup of the client IP, and the tags already set
+// on a process are kept.
+func (e *Enricher) Enrich(spans []*model.Span, client processor.ClientInfo) {
+	p := pod{
+		Namespace: client.Get(HeaderNamespace),
+		Name:      client.Get(HeaderPodName),
+		Node:      client.Get(HeaderNodeName),
+	}
+	counter := e.metrics.FromHeaders
+	if p.Name == "" {
+		var ok bool
+		if e.pods != nil && client.IP != "" {
+			p, ok = e.pods.lookup(client.IP)
+		}
+		if !ok {
+			e.metrics.Unresolved.Inc(int64(len(spans)))
+			return
+		}
+		counter = e.metrics.FromLookup
+	}
+	for _, span := range spans {
+		if span.Process == nil {
+			span.Process = &model.Process{}
+		}
+		addTag(span.Process, NamespaceTag, p.Namespace)
+		addTag(span.Process, PodNameTag, p.Name)
+		addTag(span.Process, NodeNameTag, p.Node)
+	}
+	counter.Inc(int64(len(spans)))
+}
+
+// addTag adds the tag unless it is empty or already set; the spans of a batch may share their process
+func addTag(process *model.Process, key, value string) {
+	if value == "" {
+		return
+	}
+	for _, tag := range process.Tags {
+		if tag.Key == key {
+			return
+		}
+	}
+	process.Tags = append(process.Tags, model.String(key, value))
+}
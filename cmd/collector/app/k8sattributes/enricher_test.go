@@ -0,0 +1,117 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sattributes
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/cmd/collector/app/processor"
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/config"
+)
+
+func headers(values map[string]string) func(string) string {
+	return func(key string) string {
+		return values[key]
+	}
+}
+
+func TestOptionsWithFlags(t *testing.T) {
+	v, command := config.Viperize(AddFlags)
+	opts := new(Options).InitFromViper(v)
+	assert.False(t, opts.Enabled)
+	assert.True(t, opts.PodLookup)
+	assert.Equal(t, defaultRefreshInterval, opts.RefreshInterval)
+
+	command.ParseFlags([]string{
+		"--collector.k8s-attributes.enabled=true",
+		"--collector.k8s-attributes.pod-lookup=false",
+		"--collector.k8s-attributes.api-server=https://k8s:6443",
+	})
+	opts.InitFromViper(v)
+	assert.True(t, opts.Enabled)
+	assert.False(t, opts.PodLookup)
+	assert.Equal(t, "https://k8s:6443", opts.APIServer)
+}
+
+func TestEnrichFromHeaders(t *testing.T) {
+	mf := metricstest.NewFactory(0)
+	e, err := NewEnricher(Options{}, zap.NewNop(), mf)
+	require.NoError(t, err)
+
+	// the spans of a batch share their process
+	process := &model.Process{ServiceName: "svc", Tags: []model.KeyValue{model.String(NodeNameTag, "set-by-client")}}
+	spans := []*model.Span{{Process: process}, {Process: process}, {}}
+	e.Enrich(spans, processor.ClientInfo{Metadata: headers(map[string]string{
+		HeaderNamespace: "default",
+		HeaderPodName:   "frontend-1",
+		HeaderNodeName:  "node-1",
+	})})
+
+	assert.Equal(t, []model.KeyValue{
+		model.String(NodeNameTag, "set-by-client"),
+		model.String(NamespaceTag, "default"),
+		model.String(PodNameTag, "frontend-1"),
+	}, process.Tags)
+	assert.Equal(t, []model.KeyValue{
+		model.String(NamespaceTag, "default"),
+		model.String(PodNameTag, "frontend-1"),
+		model.String(NodeNameTag, "node-1"),
+	}, spans[2].Process.Tags)
+	mf.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "spans_enriched", Tags: map[string]string{"source": "headers"}, Value: 3})
+}
+
+func TestEnrichFromPodLookup(t *testing.T) {
+	mf := metricstest.NewFactory(0)
+	e, err := NewEnricher(Options{}, zap.NewNop(), mf)
+	require.NoError(t, err)
+	e.pods = &podCache{byIP: map[string]pod{"10.0.0.1": {Namespace: "default", Name: "frontend-1", Node: "node-1"}}}
+
+	span := &model.Span{Process: &model.Process{ServiceName: "svc"}}
+	e.Enrich([]*model.Span{span}, processor.ClientInfo{IP: "10.0.0.1"})
+	assert.Equal(t, []model.KeyValue{
+		model.String(NamespaceTag, "default"),
+		model.String(PodNameTag, "frontend-1"),
+		model.String(NodeNameTag, "node-1"),
+	}, span.Process.Tags)
+
+	unknown := &model.Span{Process: &model.Process{ServiceName: "svc"}}
+	e.Enrich([]*model.Span{unknown}, processor.ClientInfo{IP: "10.0.0.2"})
+	e.Enrich([]*model.Span{unknown}, processor.ClientInfo{})
+	assert.Empty(t, unknown.Process.Tags)
+
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "spans_enriched", Tags: map[string]string{"source": "pod_lookup"}, Value: 1},
+		metricstest.ExpectedMetric{Name: "spans_unresolved", Value: 2},
+	)
+}
+
+func TestNewEnricherOutsideCluster(t *testing.T) {
+	host := os.Getenv("KUBERNETES_SERVICE_HOST")
+	os.Unsetenv("KUBERNETES_SERVICE_HOST")
+	defer os.Setenv("KUBERNETES_SERVICE_HOST", host)
+	_, err := NewEnricher(Options{PodLookup: true}, zap.NewNop(), metricstest.NewFactory(0))
+	assert.Error(t, err)
+
+	e, err := NewEnricher(Options{PodLookup: true, APIServer: "http://localhost:1"}, zap.NewNop(), metricstest.NewFactory(0))
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:1", e.pods.url)
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sattributes
+
+import (
+	"flag"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	enabled         = "collector.k8s-attributes.enabled"
+	podLookup       = "collector.k8s-attributes.pod-lookup"
+	apiServer       = "collector.k8s-attributes.api-server"
+	refreshInterval = "collector.k8s-attributes.refresh-interval"
+
+	defaultRefreshInterval = 30 * time.Second
+)
+
+// Options controls the enrichment of spans with the Kubernetes metadata of the pod that submitted them.
+type Options struct {
+	// Enabled turns on the enrichment
+	Enabled bool
+	// PodLookup resolves the pod from the client IP when the client did not send the pod headers
+	PodLookup bool
+	// APIServer is the URL of the Kubernetes API server, the in-cluster address is used when empty
+	APIServer string
+	// RefreshInterval is how often the pods are listed from the Kubernetes API
+	RefreshInterval time.Duration
+}
+
+// AddFlags adds flags for Kubernetes enrichment Options
+func AddFlags(flags *flag.FlagSet) {
+	flags.Bool(enabled, false, "(experimental) Add the k8s.namespace.name, k8s.pod.name and k8s.node.name process tags to spans, "+
+		"from the "+HeaderNamespace+", "+HeaderPodName+" and "+HeaderNodeName+" request headers, e.g. set from the downward API")
+	flags.Bool(podLookup, true, "Resolve the pod from the client IP using the Kubernetes API when the request has no pod headers; "+
+		"the service account needs to list pods")
+	flags.String(apiServer, "", "The URL of the Kubernetes API server, defaults to the in-cluster address")
+	flags.Duration(refreshInterval, defaultRefreshInterval, "How often the pods are listed from the Kubernetes API")
+}
+
+// InitFromViper initializes Options with properties from viper
+func (o *Options) InitFromViper(v *viper.Viper) *Options {
+	o.Enabled = v.GetBool(enabled)
+	o.PodLookup = v.GetBool(podLookup)
+	o.APIServer = v.GetString(apiServer)
+	o.RefreshInterval = v.GetDuration(refreshInterval)
+	return o
+}
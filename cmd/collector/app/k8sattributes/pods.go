@@ -0,0 +1,205 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sattributes
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	listPageSize      = 500
+)
+
+// pod is the metadata attached to the spans
+type pod struct {
+	Namespace string
+	Name      string
+	Node      string
+}
+
+// podCache keeps the pods of the cluster by IP, listing them periodically from the Kubernetes API
+type podCache struct {
+	url       string
+	tokenFile string
+	client    *http.Client
+	interval  time.Duration
+	logger    *zap.Logger
+
+	lock   sync.RWMutex
+	byIP   map[string]pod
+	stopCh chan struct{}
+	done   chan struct{}
+}
+
+func newPodCache(opts Options, logger *zap.Logger) (*podCache, error) {
+	apiURL := opts.APIServer
+	if apiURL == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, errors.New("not running in a Kubernetes cluster, the API server address is required for the pod lookup")
+		}
+		apiURL = "https://" + net.JoinHostPort(host, port)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if ca, err := ioutil.ReadFile(serviceAccountDir + "/ca.crt"); err == nil {
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(ca)
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	interval := opts.RefreshInterval
+	if interval <= 0 {
+		interval = defaultRefreshInterval
+	}
+	return &podCache{
+		url:       strings.TrimSuffix(apiURL, "/"),
+		tokenFile: serviceAccountDir + "/token",
+		client:    &http.Client{Transport: transport, Timeout: time.Minute},
+		interval:  interval,
+		logger:    logger,
+		byIP:      make(map[string]pod),
+		stopCh:    make(chan struct{}),
+		done:      make(chan struct{}),
+	}, nil
+}
+
+func (c *podCache) start() {
+	go func() {
+		defer close(c.done)
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			if err := c.refresh(); err != nil {
+				c.logger.Error("Failed to list the Kubernetes pods", zap.Error(err))
+			}
+			select {
+			case <-ticker.C:
+			case <-c.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+func (c *podCache) stop() {
+	close(c.stopCh)
+	<-c.done
+}
+
+func (c *podCache) lookup(ip string) (pod, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	p, ok := c.byIP[ip]
+	return p, ok
+}
+
+type podList struct {
+	Metadata struct {
+		Continue string `json:"continue"`
+	} `json:"metadata"`
+	Items []struct {
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Spec struct {
+			NodeName    string `json:"nodeName"`
+			HostNetwork bool   `json:"hostNetwork"`
+		} `json:"spec"`
+		Status struct {
+			PodIP  string `json:"podIP"`
+			PodIPs []struct {
+				IP string `json:"ip"`
+			} `json:"podIPs"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// refresh replaces the cached pods with the running pods of the cluster.
+// The pods on the host network are skipped, their IP is the IP of their node.
+func (c *podCache) refresh() error {
+	byIP := make(map[string]pod)
+	continueToken := ""
+	for {
+		list, err := c.list(continueToken)
+		if err != nil {
+			return err
+		}
+		for _, item := range list.Items {
+			if item.Spec.HostNetwork {
+				continue
+			}
+			p := pod{Namespace: item.Metadata.Namespace, Name: item.Metadata.Name, Node: item.Spec.NodeName}
+			if item.Status.PodIP != "" {
+				byIP[item.Status.PodIP] = p
+			}
+			for _, ip := range item.Status.PodIPs {
+				byIP[ip.IP] = p
+			}
+		}
+		if list.Metadata.Continue == "" {
+			break
+		}
+		continueToken = list.Metadata.Continue
+	}
+	c.lock.Lock()
+	c.byIP = byIP
+	c.lock.Unlock()
+	return nil
+}
+
+func (c *podCache) list(continueToken string) (*podList, error) {
+	query := url.Values{}
+	query.Set("fieldSelector", "status.phase=Running")
+	query.Set("limit", fmt.Sprint(listPageSize))
+	if continueToken != "" {
+		query.Set("continue", continueToken)
+	}
+	req, err := http.NewRequest(http.MethodGet, c.url+"/api/v1/pods?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	// the projected service account tokens are rotated, read the token again for every request
+	if token, err := ioutil.ReadFile(c.tokenFile); err == nil {
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("listing pods failed with status %d", res.StatusCode)
+	}
+	list := &podList{}
+	if err := json.NewDecoder(res.Body).Decode(list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
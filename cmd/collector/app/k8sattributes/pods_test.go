@@ -0,0 +1,97 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sattributes
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const firstPage = `{"metadata":{"continue":"page-2"},"items":[
+	{"metadata":{"name":"frontend-1","namespace":"default"},"spec":{"nodeName":"node-1"},
+	 "status":{"podIP":"10.0.0.1","podIPs":[{"ip":"10.0.0.1"},{"ip":"fd00::1"}]}},
+	{"metadata":{"name":"kube-proxy","namespace":"kube-system"},"spec":{"nodeName":"node-1","hostNetwork":true},
+	 "status":{"podIP":"192.168.0.1"}}
+]}`
+
+const secondPage = `{"metadata":{},"items":[
+	{"metadata":{"name":"backend-1","namespace":"shop"},"spec":{"nodeName":"node-2"},"status":{"podIP":"10.0.0.2"}}
+]}`
+
+func TestPodCacheRefresh(t *testing.T) {
+	tokenFile, err := ioutil.TempFile("", "token")
+	require.NoError(t, err)
+	defer os.Remove(tokenFile.Name())
+	tokenFile.WriteString("secret\n")
+	tokenFile.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/pods" || r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		assert.Equal(t, "status.phase=Running", r.URL.Query().Get("fieldSelector"))
+		if r.URL.Query().Get("continue") == "page-2" {
+			w.Write([]byte(secondPage))
+			return
+		}
+		w.Write([]byte(firstPage))
+	}))
+	defer server.Close()
+
+	cache, err := newPodCache(Options{APIServer: server.URL + "/", RefreshInterval: time.Hour}, zap.NewNop())
+	require.NoError(t, err)
+	cache.tokenFile = tokenFile.Name()
+	cache.start()
+	defer cache.stop()
+
+	for i := 0; i < 200; i++ {
+		if _, ok := cache.lookup("10.0.0.2"); ok {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	p, ok := cache.lookup("10.0.0.2")
+	require.True(t, ok)
+	assert.Equal(t, pod{Namespace: "shop", Name: "backend-1", Node: "node-2"}, p)
+	p, ok = cache.lookup("fd00::1")
+	require.True(t, ok)
+	assert.Equal(t, "frontend-1", p.Name)
+	_, ok = cache.lookup("192.168.0.1")
+	assert.False(t, ok)
+}
+
+func TestPodCacheRefreshError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	cache, err := newPodCache(Options{APIServer: server.URL}, zap.NewNop())
+	require.NoError(t, err)
+	cache.byIP["10.0.0.1"] = pod{Name: "frontend-1"}
+	assert.EqualError(t, cache.refresh(), "listing pods failed with status 403")
+	// the pods listed before are kept
+	_, ok := cache.lookup("10.0.0.1")
+	assert.True(t, ok)
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var (
+	// memory limits of the container, cgroup v2 then v1, to allow overriding in unit tests
+	cgroupMemoryLimitFiles = []string{
+		"/sys/fs/cgroup/memory.max",
+		"/sys/fs/cgroup/memory/memory.limit_in_bytes",
+	}
+	meminfoFile = "/proc/meminfo"
+)
+
+// availableMemory returns the memory limit of the collector's cgroup, or the physical
+// memory of the host when the cgroup is unlimited or cannot be read.
+func availableMemory() (uint64, error) {
+	total, err := physicalMemory()
+	if err != nil {
+		return 0, err
+	}
+	for _, path := range cgroupMemoryLimitFiles {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		limit, err := strconv.ParseUint(string(bytes.TrimSpace(data)), 10, 64)
+		if err != nil {
+			// "max" means unlimited in cgroup v2
+			continue
+		}
+		// unlimited cgroups v1 report a huge value rather than "max"
+		if limit > 0 && limit < total {
+			return limit, nil
+		}
+	}
+	return total, nil
+}
+
+func physicalMemory() (uint64, error) {
+	f, err := os.Open(meminfoFile)
+	if err != nil {
+		return 0, fmt.Errorf("cannot determine the available memory: %w", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("cannot parse MemTotal in %s: %w", meminfoFile, err)
+			}
+			return kb * 1024, nil
+		}
+	}
+	return 0, fmt.Errorf("no MemTotal in %s", meminfoFile)
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAvailableMemory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "memory")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+		return path
+	}
+
+	savedLimits, savedMeminfo := cgroupMemoryLimitFiles, meminfoFile
+	defer func() {
+		cgroupMemoryLimitFiles, meminfoFile = savedLimits, savedMeminfo
+	}()
+	meminfoFile = write("meminfo", "MemTotal:        4194304 kB\nMemFree:         1024 kB\n")
+
+	tests := []struct {
+		name     string
+		limits   []string
+		expected uint64
+	}{
+		{name: "no cgroup", limits: []string{filepath.Join(dir, "missing")}, expected: 4 << 30},
+		{name: "cgroup v2 unlimited", limits: []string{write("v2-max", "max\n")}, expected: 4 << 30},
+		{name: "cgroup v2 limit", limits: []string{write("v2", "1073741824\n")}, expected: 1 << 30},
+		{name: "cgroup v1 unlimited", limits: []string{write("v1-max", "9223372036854771712\n")}, expected: 4 << 30},
+		{name: "cgroup v1 limit", limits: []string{filepath.Join(dir, "missing"), write("v1", "536870912\n")}, expected: 512 << 20},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cgroupMemoryLimitFiles = test.limits
+			available, err := availableMemory()
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, available)
+		})
+	}
+
+	meminfoFile = write("bad-meminfo", "MemFree: 1024 kB\n")
+	_, err = availableMemory()
+	assert.EqualError(t, err, "no MemTotal in "+meminfoFile)
+	meminfoFile = filepath.Join(dir, "missing")
+	_, err = availableMemory()
+	assert.Error(t, err)
+}
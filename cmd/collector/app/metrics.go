@@ -110,15 +110,19 @@ type SpanCounts struct {
 	ReceivedBySvc metricsBySvc
 	// RejectedBySvc is the number of spans we rejected (usually due to blacklisting) by-service.
 	RejectedBySvc metricsBySvc
+	// DeduplicatedBySvc is the number of duplicate spans we dropped by-service.
+	DeduplicatedBySvc metricsBySvc
 }
 
 // NewSpanProcessorMetrics returns a SpanProcessorMetrics
 func NewSpanProcessorMetrics(serviceMetrics metrics.Factory, hostMetrics metrics.Factory, otherFormatTypes []processor.SpanFormat) *SpanProcessorMetrics {
 	spanCounts := SpanCountsByFormat{
-		processor.ZipkinSpanFormat:  newCountsByTransport(serviceMetrics, processor.ZipkinSpanFormat),
-		processor.JaegerSpanFormat:  newCountsByTransport(serviceMetrics, processor.JaegerSpanFormat),
-		processor.ProtoSpanFormat:   newCountsByTransport(serviceMetrics, processor.ProtoSpanFormat),
-		processor.UnknownSpanFormat: newCountsByTransport(serviceMetrics, processor.UnknownSpanFormat),
+		processor.ZipkinSpanFormat:     newCountsByTransport(serviceMetrics, processor.ZipkinSpanFormat),
+		processor.JaegerSpanFormat:     newCountsByTransport(serviceMetrics, processor.JaegerSpanFormat),
+		processor.ProtoSpanFormat:      newCountsByTransport(serviceMetrics, processor.ProtoSpanFormat),
+		processor.OTLPSpanFormat:       newCountsByTransport(serviceMetrics, processor.OTLPSpanFormat),
+		processor.OpenCensusSpanFormat: newCountsByTransport(serviceMetrics, processor.OpenCensusSpanFormat),
+		processor.UnknownSpanFormat:    newCountsByTransport(serviceMetrics, processor.UnknownSpanFormat),
 	}
 	for _, otherFormatType := range otherFormatTypes {
 		spanCounts[otherFormatType] = newCountsByTransport(serviceMetrics, otherFormatType)
@@ -203,8 +207,9 @@ func newCountsByTransport(factory metrics.Factory, format processor.SpanFormat)
 func newCounts(factory metrics.Factory, transport processor.InboundTransport) SpanCounts {
 	factory = factory.Namespace(metrics.NSOptions{Tags: map[string]string{"transport": string(transport)}})
 	return SpanCounts{
-		RejectedBySvc: newMetricsBySvc(factory, "rejected"),
-		ReceivedBySvc: newMetricsBySvc(factory, "received"),
+		RejectedBySvc:     newMetricsBySvc(factory, "rejected"),
+		ReceivedBySvc:     newMetricsBySvc(factory, "received"),
+		DeduplicatedBySvc: newMetricsBySvc(factory, "deduplicated"),
 	}
 }
 
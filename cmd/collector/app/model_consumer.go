@@ -16,6 +16,7 @@
 package app
 
 import (
+	"github.com/jaegertracing/jaeger/cmd/collector/app/processor"
 	"github.com/jaegertracing/jaeger/model"
 )
 
@@ -28,6 +29,15 @@ type ProcessSpans func(spans []*model.Span)
 // FilterSpan decides whether to allow or disallow a span
 type FilterSpan func(span *model.Span) bool
 
+// LimitSpans decides which spans of a batch are within the ingestion rate limits
+type LimitSpans func(spans []*model.Span) []bool
+
+// LimitTenantSpans decides whether a batch of spans is within the ingestion quota of its tenant
+type LimitTenantSpans func(tenant string, spans []*model.Span) bool
+
+// EnrichSpans adds to a batch of spans what is known about the client that submitted them
+type EnrichSpans func(spans []*model.Span, client processor.ClientInfo)
+
 // ChainedProcessSpan chains spanProcessors as a single ProcessSpan call
 func ChainedProcessSpan(spanProcessors ...ProcessSpan) ProcessSpan {
 	return func(span *model.Span) {
@@ -36,3 +46,16 @@ func ChainedProcessSpan(spanProcessors ...ProcessSpan) ProcessSpan {
 		}
 	}
 }
+
+// ChainedFilterSpan chains filters as a single FilterSpan call, allowing spans allowed by all the filters.
+// The filters are called in order until one of them disallows the span.
+func ChainedFilterSpan(filters ...FilterSpan) FilterSpan {
+	return func(span *model.Span) bool {
+		for _, filter := range filters {
+			if !filter(span) {
+				return false
+			}
+		}
+		return true
+	}
+}
@@ -33,3 +33,16 @@ func TestChainedProcessSpan(t *testing.T) {
 	assert.True(t, happened1)
 	assert.True(t, happened2)
 }
+
+func TestChainedFilterSpan(t *testing.T) {
+	called := false
+	allow := func(span *model.Span) bool { return true }
+	deny := func(span *model.Span) bool { return false }
+	record := func(span *model.Span) bool { called = true; return true }
+	assert.True(t, ChainedFilterSpan(allow, record)(&model.Span{}))
+	assert.True(t, called)
+
+	called = false
+	assert.False(t, ChainedFilterSpan(deny, record)(&model.Span{}))
+	assert.False(t, called, "filters after a rejection are not called")
+}
@@ -19,9 +19,11 @@ import (
 	"github.com/uber/jaeger-lib/metrics"
 	"go.uber.org/zap"
 
+	"github.com/jaegertracing/jaeger/cmd/collector/app/dropped"
 	"github.com/jaegertracing/jaeger/cmd/collector/app/processor"
 	"github.com/jaegertracing/jaeger/cmd/collector/app/sanitizer"
 	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/queue"
 )
 
 const (
@@ -39,6 +41,8 @@ type options struct {
 	sanitizer          sanitizer.SanitizeSpan
 	preSave            ProcessSpan
 	spanFilter         FilterSpan
+	deduplicator       FilterSpan
+	forgetDuplicate    ProcessSpan
 	numWorkers         int
 	blockingSubmit     bool
 	queueSize          int
@@ -47,6 +51,11 @@ type options struct {
 	reportBusy         bool
 	extraFormatTypes   []processor.SpanFormat
 	collectorTags      map[string]string
+	persistentQueue    *queue.PersistentQueue
+	rateLimiter        LimitSpans
+	tenantQuota        LimitTenantSpans
+	droppedSpans       *dropped.Tracker
+	enrichSpans        EnrichSpans
 }
 
 // Option is a function that sets some option on StorageBuilder.
@@ -104,6 +113,21 @@ func (options) SpanFilter(spanFilter FilterSpan) Option {
 	}
 }
 
+// Deduplicator creates an Option that initializes the function rejecting duplicate spans
+func (options) Deduplicator(deduplicator FilterSpan) Option {
+	return func(b *options) {
+		b.deduplicator = deduplicator
+	}
+}
+
+// ForgetDuplicate creates an Option that initializes the function forgetting the spans kept by the
+// deduplicator that could not be queued, so that their retries are not rejected as duplicates
+func (options) ForgetDuplicate(forget ProcessSpan) Option {
+	return func(b *options) {
+		b.forgetDuplicate = forget
+	}
+}
+
 // NumWorkers creates an Option that initializes the number of queue consumers AKA workers
 func (options) NumWorkers(numWorkers int) Option {
 	return func(b *options) {
@@ -160,6 +184,41 @@ func (options) CollectorTags(extraTags map[string]string) Option {
 	}
 }
 
+// PersistentQueue creates an Option that spools spans to the given disk-backed queue instead of the in-memory queue
+func (options) PersistentQueue(q *queue.PersistentQueue) Option {
+	return func(b *options) {
+		b.persistentQueue = q
+	}
+}
+
+// RateLimiter creates an Option that initializes the function rejecting batches over the rate limits
+func (options) RateLimiter(rateLimiter LimitSpans) Option {
+	return func(b *options) {
+		b.rateLimiter = rateLimiter
+	}
+}
+
+// TenantQuota creates an Option that initializes the function rejecting batches over the quota of their tenant
+func (options) TenantQuota(tenantQuota LimitTenantSpans) Option {
+	return func(b *options) {
+		b.tenantQuota = tenantQuota
+	}
+}
+
+// EnrichSpans creates an Option that initializes the function adding the client metadata to the accepted batches
+func (options) EnrichSpans(enrichSpans EnrichSpans) Option {
+	return func(b *options) {
+		b.enrichSpans = enrichSpans
+	}
+}
+
+// DroppedSpans creates an Option that records the spans dropped by the processor per service
+func (options) DroppedSpans(tracker *dropped.Tracker) Option {
+	return func(b *options) {
+		b.droppedSpans = tracker
+	}
+}
+
 func (o options) apply(opts ...Option) options {
 	ret := options{}
 	for _, opt := range opts {
@@ -183,9 +242,21 @@ func (o options) apply(opts ...Option) options {
 	if ret.preSave == nil {
 		ret.preSave = func(span *model.Span) {}
 	}
+	if ret.enrichSpans == nil {
+		ret.enrichSpans = func(spans []*model.Span, client processor.ClientInfo) {}
+	}
+	if ret.rateLimiter == nil {
+		ret.rateLimiter = func(spans []*model.Span) []bool { return nil }
+	}
+	if ret.tenantQuota == nil {
+		ret.tenantQuota = func(tenant string, spans []*model.Span) bool { return true }
+	}
 	if ret.spanFilter == nil {
 		ret.spanFilter = func(span *model.Span) bool { return true }
 	}
+	if ret.deduplicator == nil {
+		ret.deduplicator = func(span *model.Span) bool { return true }
+	}
 	if ret.numWorkers == 0 {
 		ret.numWorkers = DefaultNumWorkers
 	}
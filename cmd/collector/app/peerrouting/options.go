@@ -0,0 +1,95 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peerrouting
+
+import (
+	"flag"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/jaegertracing/jaeger/pkg/config/tlscfg"
+)
+
+const (
+	configPrefix    = "collector.peer-routing"
+	peers           = configPrefix + ".peers"
+	self            = configPrefix + ".self"
+	resolveInterval = configPrefix + ".resolve-interval"
+	forwardTimeout  = configPrefix + ".forward-timeout"
+	queueSize       = configPrefix + ".queue-size"
+
+	defaultResolveInterval = 30 * time.Second
+	defaultForwardTimeout  = 5 * time.Second
+	defaultQueueSize       = 1000
+)
+
+var tlsFlagsConfig = tlscfg.ClientFlagsConfig{
+	Prefix:         configPrefix,
+	ShowEnabled:    true,
+	ShowServerName: true,
+}
+
+// Options configures the forwarding of spans to the collector owning their trace.
+type Options struct {
+	// Peers are the gRPC host:port addresses of the collectors of the cluster, including this one.
+	// A host resolving to several addresses, e.g. a headless Kubernetes service, adds a peer per address.
+	Peers []string
+	// Self is the address of this collector as it appears among the resolved peers, detected from the local interfaces if empty
+	Self string
+	// ResolveInterval is how often the peer hosts are resolved again
+	ResolveInterval time.Duration
+	// ForwardTimeout is the timeout of a batch forwarded to a peer
+	ForwardTimeout time.Duration
+	// QueueSize is the maximum number of batches waiting to be forwarded to each peer
+	QueueSize int
+	// TLS configures the connections to the peers
+	TLS tlscfg.Options
+}
+
+// AddFlags adds flags for peer routing Options
+func AddFlags(flags *flag.FlagSet) {
+	flags.String(peers, "", "(experimental) Comma-separated list of the gRPC host:port of the collectors of the cluster, including this one; "+
+		"every span is forwarded to the collector owning its trace ID, so that tail sampling and aggregations see whole traces. "+
+		"A host resolving to several addresses, e.g. a headless service, adds each address as a peer; only the peer addresses "+
+		"are trusted to submit forwarded batches. Disabled if empty")
+	flags.String(self, "", "The host:port of this collector as resolved among the peers, detected from the network interfaces if empty")
+	flags.Duration(resolveInterval, defaultResolveInterval, "How often the peer hosts are resolved to pick up membership changes")
+	flags.Duration(forwardTimeout, defaultForwardTimeout, "The timeout of forwarding a batch of spans to a peer, the spans are processed locally when it fails")
+	flags.Int(queueSize, defaultQueueSize, "The maximum number of batches waiting to be forwarded to each peer, the spans are processed locally when it is full")
+	tlsFlagsConfig.AddFlags(flags)
+}
+
+// InitFromViper initializes Options with properties from viper
+func (o *Options) InitFromViper(v *viper.Viper) *Options {
+	o.Peers = nil
+	for _, peer := range strings.Split(v.GetString(peers), ",") {
+		if peer = strings.TrimSpace(peer); peer != "" {
+			o.Peers = append(o.Peers, peer)
+		}
+	}
+	o.Self = v.GetString(self)
+	o.ResolveInterval = v.GetDuration(resolveInterval)
+	o.ForwardTimeout = v.GetDuration(forwardTimeout)
+	o.QueueSize = v.GetInt(queueSize)
+	o.TLS = tlsFlagsConfig.InitFromViper(v)
+	return o
+}
+
+// Enabled returns true if peers are configured
+func (o Options) Enabled() bool {
+	return len(o.Peers) > 0
+}
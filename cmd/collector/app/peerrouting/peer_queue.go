@@ -0,0 +1,104 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peerrouting
+
+import (
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/cmd/collector/app/processor"
+	"github.com/jaegertracing/jaeger/model"
+)
+
+// forwardItem is a batch of spans owned by a peer, waiting to be forwarded
+type forwardItem struct {
+	spans   []*model.Span
+	options processor.SpansOptions
+}
+
+// peerQueue holds the batches waiting to be forwarded to a peer
+type peerQueue struct {
+	peer  string
+	items chan forwardItem
+	// left is set when the peer is no longer a member, its remaining batches are then processed locally
+	left atomic.Bool
+}
+
+// offer queues the batch, returning false if the queue is full
+func (q *peerQueue) offer(item forwardItem) bool {
+	select {
+	case q.items <- item:
+		return true
+	default:
+		return false
+	}
+}
+
+// enqueue queues the batch for the peer, starting the queue of the peer if needed.
+// It returns false if the batch cannot be queued, e.g. because the queue is full.
+func (p *Processor) enqueue(peer string, item forwardItem) bool {
+	p.lock.RLock()
+	if q, ok := p.queues[peer]; ok {
+		defer p.lock.RUnlock()
+		return q.offer(item)
+	}
+	p.lock.RUnlock()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.closed {
+		return false
+	}
+	q, ok := p.queues[peer]
+	if !ok {
+		q = p.startQueue(peer)
+		p.queues[peer] = q
+	}
+	return q.offer(item)
+}
+
+// startQueue starts the workers forwarding the batches of the queue until it is closed
+func (p *Processor) startQueue(peer string) *peerQueue {
+	q := &peerQueue{peer: peer, items: make(chan forwardItem, p.opts.QueueSize)}
+	for i := 0; i < forwardWorkers; i++ {
+		p.forwarding.Add(1)
+		go func() {
+			defer p.forwarding.Done()
+			for item := range q.items {
+				p.forwardOrProcess(q, item)
+			}
+		}()
+	}
+	return q
+}
+
+// forwardOrProcess forwards the batch to the peer, or processes it locally when it fails or the peer left
+func (p *Processor) forwardOrProcess(q *peerQueue, item forwardItem) {
+	count := int64(len(item.spans))
+	if !q.left.Load() {
+		err := p.forward(q.peer, item.spans, item.options)
+		if err == nil {
+			p.metrics.Forwarded.Inc(count)
+			return
+		}
+		p.metrics.ForwardFailed.Inc(count)
+		p.logger.Debug("Failed to forward spans, processing them locally", zap.String("peer", q.peer), zap.Error(err))
+	}
+	p.metrics.Local.Inc(count)
+	if _, err := p.local.ProcessSpans(item.spans, item.options); err != nil {
+		p.metrics.FallbackFailed.Inc(count)
+		p.logger.Error("Failed to process the spans that could not be forwarded", zap.String("peer", q.peer), zap.Error(err))
+	}
+}
@@ -0,0 +1,371 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peerrouting
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/jaegertracing/jaeger/cmd/collector/app/k8sattributes"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/processor"
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/proto-gen/api_v2"
+)
+
+const (
+	// ForwardedHeader is the gRPC metadata key marking the batches forwarded by a peer, which are
+	// processed by the receiving collector without being routed again. It is only trusted from
+	// the addresses of the resolved peers.
+	ForwardedHeader = "x-jaeger-forwarded"
+	// ForwardedForHeader is the gRPC metadata key carrying the IP of the client that submitted the forwarded batch.
+	ForwardedForHeader = "x-jaeger-forwarded-for"
+
+	authorizationHeader = "authorization"
+
+	// forwardWorkers is the number of batches forwarded concurrently to each peer
+	forwardWorkers = 4
+)
+
+// clientHeaders are the metadata of the client forwarded along, for the receiving collector
+// to enrich the spans as if they were submitted to it directly.
+var clientHeaders = []string{
+	authorizationHeader,
+	k8sattributes.HeaderNamespace,
+	k8sattributes.HeaderPodName,
+	k8sattributes.HeaderNodeName,
+}
+
+type routerMetrics struct {
+	Forwarded      metrics.Counter `metric:"spans_forwarded" tags:"result=ok"`
+	ForwardFailed  metrics.Counter `metric:"spans_forwarded" tags:"result=err"`
+	QueueFull      metrics.Counter `metric:"spans_forwarded" tags:"result=queue_full"`
+	Local          metrics.Counter `metric:"spans_local"`
+	FallbackFailed metrics.Counter `metric:"spans_fallback_failed"`
+	Untrusted      metrics.Counter `metric:"spans_forwarded_untrusted"`
+	Members        metrics.Gauge   `metric:"members"`
+	ResolveChanges metrics.Counter `metric:"membership_changes"`
+}
+
+// Processor is a span processor that forwards each span to the collector owning its trace ID,
+// and passes the spans owned by this collector to the local span processor. The spans owned by
+// the peers are forwarded in the background through a bounded queue per peer, and only once the
+// local processor accepted the spans of the batch, so that a client retrying a rejected batch does
+// not get its spans forwarded twice. The spans that cannot be queued or forwarded are processed
+// locally. The tenant, the bearer token, the Kubernetes pod headers and the IP of the client are
+// forwarded along.
+type Processor struct {
+	local        processor.SpanProcessor
+	opts         Options
+	tenantHeader string
+	dialOptions  []grpc.DialOption
+	logger       *zap.Logger
+	metrics      routerMetrics
+	resolver     resolver
+	localAddrs   func() ([]net.Addr, error)
+
+	lock   sync.RWMutex
+	ring   *ring
+	conns  map[string]*grpc.ClientConn
+	queues map[string]*peerQueue
+	closed bool
+
+	// forwarding tracks the workers of the peer queues, including the queues of the peers that left
+	forwarding sync.WaitGroup
+
+	stopCh chan struct{}
+	done   chan struct{}
+}
+
+// NewProcessor resolves the peers and creates the routing Processor in front of the local span processor.
+// tenantHeader is the metadata key carrying the tenant of the forwarded spans, when multi-tenancy is enabled.
+func NewProcessor(local processor.SpanProcessor, opts Options, tenantHeader string, logger *zap.Logger, metricsFactory metrics.Factory) (*Processor, error) {
+	dialOption := grpc.WithInsecure()
+	if opts.TLS.Enabled {
+		tlsCfg, err := opts.TLS.Config()
+		if err != nil {
+			return nil, err
+		}
+		dialOption = grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg))
+	}
+	if opts.ResolveInterval <= 0 {
+		opts.ResolveInterval = defaultResolveInterval
+	}
+	if opts.ForwardTimeout <= 0 {
+		opts.ForwardTimeout = defaultForwardTimeout
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultQueueSize
+	}
+	p := &Processor{
+		local:        local,
+		opts:         opts,
+		tenantHeader: tenantHeader,
+		dialOptions:  []grpc.DialOption{dialOption},
+		logger:       logger,
+		resolver:     net.DefaultResolver,
+		localAddrs:   net.InterfaceAddrs,
+		ring:         &ring{},
+		conns:        make(map[string]*grpc.ClientConn),
+		queues:       make(map[string]*peerQueue),
+		stopCh:       make(chan struct{}),
+	}
+	metrics.Init(&p.metrics, metricsFactory, nil)
+	p.refresh()
+	return p, nil
+}
+
+// Start resolves the peers periodically in the background.
+func (p *Processor) Start() {
+	p.done = make(chan struct{})
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(p.opts.ResolveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.refresh()
+			case <-p.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// refresh resolves the peers and closes the connections to the members that left. The batches
+// still queued for the members that left are processed locally.
+func (p *Processor) refresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), p.opts.ResolveInterval)
+	defer cancel()
+	members := resolveMembers(ctx, p.resolver, p.opts.Peers)
+	self := p.opts.Self
+	if self == "" {
+		if addrs, err := p.localAddrs(); err == nil {
+			self = findSelf(members, addrs)
+		}
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if equalMembers(p.ring.members, members) && p.ring.self == self {
+		return
+	}
+	if p.ring.members != nil {
+		p.metrics.ResolveChanges.Inc(1)
+	}
+	p.ring = &ring{members: members, self: self}
+	p.metrics.Members.Update(int64(len(members)))
+	current := make(map[string]struct{}, len(members))
+	for _, member := range members {
+		current[member] = struct{}{}
+	}
+	for member, conn := range p.conns {
+		if _, ok := current[member]; !ok {
+			conn.Close()
+			delete(p.conns, member)
+		}
+	}
+	for member, q := range p.queues {
+		if _, ok := current[member]; !ok {
+			q.left.Store(true)
+			close(q.items)
+			delete(p.queues, member)
+		}
+	}
+	if self == "" {
+		p.logger.Warn("This collector is not among the resolved peers, all spans are forwarded", zap.Strings("peers", members))
+	} else {
+		p.logger.Info("Peer routing members updated", zap.Strings("peers", members), zap.String("self", self))
+	}
+}
+
+func equalMembers(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ProcessSpans implements processor.SpanProcessor.
+func (p *Processor) ProcessSpans(spans []*model.Span, options processor.SpansOptions) ([]bool, error) {
+	p.lock.RLock()
+	r := p.ring
+	p.lock.RUnlock()
+	if options.Client.Get(ForwardedHeader) != "" {
+		if r.hasMemberIP(options.Client.IP) {
+			if ip := options.Client.Get(ForwardedForHeader); ip != "" {
+				options.Client.IP = ip
+			}
+			return p.local.ProcessSpans(spans, options)
+		}
+		// a client cannot skip the routing nor pretend to submit on behalf of another client
+		p.metrics.Untrusted.Inc(int64(len(spans)))
+		options.Client.Metadata = withoutForwarding(options.Client.Metadata)
+	}
+
+	var local []int
+	remote := make(map[string][]int)
+	for i, span := range spans {
+		owner := r.owner(span.TraceID)
+		if owner == "" || owner == r.self {
+			local = append(local, i)
+		} else {
+			remote[owner] = append(remote[owner], i)
+		}
+	}
+
+	oks := make([]bool, len(spans))
+	if len(local) > 0 {
+		if err := p.processLocally(spans, local, options, oks); err != nil {
+			return nil, err
+		}
+	}
+	var fallback []int
+	for owner, indices := range remote {
+		batch := make([]*model.Span, len(indices))
+		for j, i := range indices {
+			batch[j] = spans[i]
+		}
+		if !p.enqueue(owner, forwardItem{spans: batch, options: options}) {
+			p.metrics.QueueFull.Inc(int64(len(batch)))
+			fallback = append(fallback, indices...)
+			continue
+		}
+		for _, i := range indices {
+			oks[i] = true
+		}
+	}
+	if len(fallback) > 0 {
+		// the other spans of the batch are already accepted, so the failure is only reported
+		// through their results rather than as an error the client would retry the batch for
+		if err := p.processLocally(spans, fallback, options, oks); err != nil {
+			p.metrics.FallbackFailed.Inc(int64(len(fallback)))
+			p.logger.Error("Failed to process the spans that could not be queued for forwarding", zap.Error(err))
+		}
+	}
+	return oks, nil
+}
+
+// processLocally passes the spans at the given indices to the local processor and records their results in oks
+func (p *Processor) processLocally(spans []*model.Span, indices []int, options processor.SpansOptions, oks []bool) error {
+	batch := make([]*model.Span, len(indices))
+	for j, i := range indices {
+		batch[j] = spans[i]
+	}
+	p.metrics.Local.Inc(int64(len(batch)))
+	localOks, err := p.local.ProcessSpans(batch, options)
+	if err != nil {
+		return err
+	}
+	for j, i := range indices {
+		if j < len(localOks) {
+			oks[i] = localOks[j]
+		}
+	}
+	return nil
+}
+
+func (p *Processor) forward(peer string, spans []*model.Span, options processor.SpansOptions) error {
+	conn, err := p.conn(peer)
+	if err != nil {
+		return err
+	}
+	md := metadata.Pairs(ForwardedHeader, "true")
+	if options.Tenant != "" && p.tenantHeader != "" {
+		md.Set(p.tenantHeader, options.Tenant)
+	}
+	if options.Client.IP != "" {
+		md.Set(ForwardedForHeader, options.Client.IP)
+	}
+	for _, key := range clientHeaders {
+		if value := options.Client.Get(key); value != "" {
+			md.Set(key, value)
+		}
+	}
+	ctx, cancel := context.WithTimeout(metadata.NewOutgoingContext(context.Background(), md), p.opts.ForwardTimeout)
+	defer cancel()
+	_, err = api_v2.NewCollectorServiceClient(conn).PostSpans(ctx, &api_v2.PostSpansRequest{
+		Batch: model.Batch{Spans: spans},
+	})
+	return err
+}
+
+// withoutForwarding hides the forwarding headers of a client that is not a peer
+func withoutForwarding(metadata func(key string) string) func(key string) string {
+	return func(key string) string {
+		if key == ForwardedHeader || key == ForwardedForHeader || metadata == nil {
+			return ""
+		}
+		return metadata(key)
+	}
+}
+
+func (p *Processor) conn(peer string) (*grpc.ClientConn, error) {
+	p.lock.RLock()
+	conn, ok := p.conns[peer]
+	p.lock.RUnlock()
+	if ok {
+		return conn, nil
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if conn, ok := p.conns[peer]; ok {
+		return conn, nil
+	}
+	conn, err := grpc.Dial(peer, p.dialOptions...)
+	if err != nil {
+		return nil, err
+	}
+	p.conns[peer] = conn
+	return conn, nil
+}
+
+// Close stops resolving the peers, waits for the queued batches to be forwarded, and closes the
+// connections to the peers; the local processor is left open.
+func (p *Processor) Close() error {
+	close(p.stopCh)
+	if p.done != nil {
+		<-p.done
+	}
+	p.lock.Lock()
+	p.closed = true
+	for peer, q := range p.queues {
+		close(q.items)
+		delete(p.queues, peer)
+	}
+	p.lock.Unlock()
+	p.forwarding.Wait()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for peer, conn := range p.conns {
+		conn.Close()
+		delete(p.conns, peer)
+	}
+	return nil
+}
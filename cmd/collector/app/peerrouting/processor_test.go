@@ -0,0 +1,301 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peerrouting
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/jaegertracing/jaeger/cmd/collector/app/handler"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/k8sattributes"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/processor"
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
+	"github.com/jaegertracing/jaeger/proto-gen/api_v2"
+)
+
+type recordingProcessor struct {
+	lock    sync.Mutex
+	spans   []*model.Span
+	options []processor.SpansOptions
+	err     error
+}
+
+func (p *recordingProcessor) ProcessSpans(spans []*model.Span, options processor.SpansOptions) ([]bool, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.err != nil {
+		return nil, p.err
+	}
+	p.spans = append(p.spans, spans...)
+	p.options = append(p.options, options)
+	oks := make([]bool, len(spans))
+	for i := range oks {
+		oks[i] = true
+	}
+	return oks, nil
+}
+
+func (p *recordingProcessor) Close() error {
+	return nil
+}
+
+func (p *recordingProcessor) received() ([]*model.Span, []processor.SpansOptions) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.spans, p.options
+}
+
+// startPeer starts a collector gRPC server accepting the forwarded spans
+func startPeer(t *testing.T, received processor.SpanProcessor) (string, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	tenancyMgr := tenancy.NewManager(&tenancy.Options{Enabled: true, Header: "x-tenant"})
+	api_v2.RegisterCollectorServiceServer(server, handler.NewGRPCHandler(zap.NewNop(), received, tenancyMgr))
+	go server.Serve(listener)
+	return listener.Addr().String(), server.Stop
+}
+
+func newTestProcessor(t *testing.T, local processor.SpanProcessor, self string, peers ...string) (*Processor, *metricstest.Factory) {
+	mf := metricstest.NewFactory(0)
+	p, err := NewProcessor(local, Options{Peers: peers, Self: self}, "x-tenant", zap.NewNop(), mf)
+	require.NoError(t, err)
+	return p, mf
+}
+
+func TestProcessorRoutesByTraceID(t *testing.T) {
+	remote := &recordingProcessor{}
+	peer, stop := startPeer(t, remote)
+	defer stop()
+	local := &recordingProcessor{}
+	self := "127.0.0.1:1"
+	p, mf := newTestProcessor(t, local, self, self, peer)
+
+	var spans []*model.Span
+	owners := make(map[model.TraceID]string)
+	for i := uint64(1); i <= 20; i++ {
+		traceID := model.NewTraceID(0, i*7919)
+		spans = append(spans, &model.Span{TraceID: traceID, Process: &model.Process{ServiceName: "svc"}})
+		owners[traceID] = p.ring.owner(traceID)
+	}
+	headers := map[string]string{authorizationHeader: "Bearer secret", k8sattributes.HeaderPodName: "frontend-1"}
+	client := processor.ClientInfo{IP: "10.1.2.3", Metadata: func(key string) string { return headers[key] }}
+	oks, err := p.ProcessSpans(spans, processor.SpansOptions{Tenant: "acme", Client: client})
+	require.NoError(t, err)
+	assert.Len(t, oks, len(spans))
+	for _, ok := range oks {
+		assert.True(t, ok)
+	}
+	// the queued batches are forwarded before Close returns
+	require.NoError(t, p.Close())
+
+	localSpans, _ := local.received()
+	remoteSpans, remoteOptions := remote.received()
+	assert.Equal(t, len(spans), len(localSpans)+len(remoteSpans))
+	assert.NotEmpty(t, localSpans)
+	assert.NotEmpty(t, remoteSpans)
+	for _, span := range localSpans {
+		assert.Equal(t, self, owners[span.TraceID])
+	}
+	for _, span := range remoteSpans {
+		assert.Equal(t, peer, owners[span.TraceID])
+	}
+	require.Len(t, remoteOptions, 1)
+	assert.Equal(t, "acme", remoteOptions[0].Tenant)
+	assert.Equal(t, "true", remoteOptions[0].Client.Get(ForwardedHeader))
+	assert.Equal(t, "Bearer secret", remoteOptions[0].Client.Get(authorizationHeader))
+	assert.Equal(t, "frontend-1", remoteOptions[0].Client.Get(k8sattributes.HeaderPodName))
+	assert.Equal(t, "10.1.2.3", remoteOptions[0].Client.Get(ForwardedForHeader))
+
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "spans_forwarded", Tags: map[string]string{"result": "ok"}, Value: len(remoteSpans)},
+		metricstest.ExpectedMetric{Name: "spans_local", Value: len(localSpans)},
+	)
+	mf.AssertGaugeMetrics(t, metricstest.ExpectedMetric{Name: "members", Value: 2})
+}
+
+func TestProcessorForwardedSpansStayLocal(t *testing.T) {
+	spans := []*model.Span{{TraceID: model.NewTraceID(0, 1)}, {TraceID: model.NewTraceID(0, 2)}, {TraceID: model.NewTraceID(0, 3)}}
+	headers := map[string]string{ForwardedHeader: "true", ForwardedForHeader: "10.1.2.3", k8sattributes.HeaderPodName: "frontend-1"}
+	metadata := func(key string) string { return headers[key] }
+
+	tests := []struct {
+		name      string
+		clientIP  string
+		expectIP  string
+		untrusted int
+	}{
+		{name: "from a peer", clientIP: "127.0.0.2", expectIP: "10.1.2.3"},
+		{name: "from another client", clientIP: "10.9.9.9", expectIP: "10.9.9.9", untrusted: 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			local := &recordingProcessor{}
+			p, mf := newTestProcessor(t, local, "127.0.0.1:1", "127.0.0.1:1")
+			p.ring.members = append(p.ring.members, "127.0.0.2:14250")
+			// the spans owned by the other peer are processed locally as it does not listen
+			_, err := p.ProcessSpans(spans, processor.SpansOptions{Client: processor.ClientInfo{IP: test.clientIP, Metadata: metadata}})
+			require.NoError(t, err)
+			require.NoError(t, p.Close())
+			localSpans, options := local.received()
+			assert.Len(t, localSpans, 3)
+			for _, o := range options {
+				assert.Equal(t, test.expectIP, o.Client.IP)
+				assert.Equal(t, "frontend-1", o.Client.Get(k8sattributes.HeaderPodName))
+				if test.untrusted > 0 {
+					assert.Equal(t, "", o.Client.Get(ForwardedHeader))
+					assert.Equal(t, "", o.Client.Get(ForwardedForHeader))
+				}
+			}
+			mf.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "spans_forwarded_untrusted", Value: test.untrusted})
+		})
+	}
+}
+
+// closedPeer returns the address of a peer that does not listen
+func closedPeer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	peer := listener.Addr().String()
+	listener.Close()
+	return peer
+}
+
+func TestProcessorFallsBackToLocal(t *testing.T) {
+	local := &recordingProcessor{}
+	p, mf := newTestProcessor(t, local, "", closedPeer(t))
+
+	spans := []*model.Span{{TraceID: model.NewTraceID(0, 1)}, {TraceID: model.NewTraceID(0, 2)}}
+	oks, err := p.ProcessSpans(spans, processor.SpansOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, true}, oks)
+	require.NoError(t, p.Close())
+	localSpans, _ := local.received()
+	assert.Len(t, localSpans, 2)
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "spans_forwarded", Tags: map[string]string{"result": "err"}, Value: 2},
+		metricstest.ExpectedMetric{Name: "spans_local", Value: 2},
+	)
+}
+
+func TestProcessorLocalFailureSkipsForwarding(t *testing.T) {
+	remote := &recordingProcessor{}
+	peer, stop := startPeer(t, remote)
+	defer stop()
+	local := &recordingProcessor{err: errors.New("queue full")}
+	self := "127.0.0.1:1"
+	p, _ := newTestProcessor(t, local, self, self, peer)
+
+	var spans []*model.Span
+	for i := uint64(1); i <= 20; i++ {
+		spans = append(spans, &model.Span{TraceID: model.NewTraceID(0, i*7919)})
+	}
+	_, err := p.ProcessSpans(spans, processor.SpansOptions{})
+	assert.Equal(t, local.err, err)
+	require.NoError(t, p.Close())
+	remoteSpans, _ := remote.received()
+	assert.Empty(t, remoteSpans, "the batch is retried by the client, so none of its spans is forwarded")
+}
+
+func TestProcessorQueueFull(t *testing.T) {
+	local := &recordingProcessor{}
+	peer := closedPeer(t)
+	p, mf := newTestProcessor(t, local, "", peer)
+	// a queue without workers stays full
+	q := &peerQueue{peer: peer, items: make(chan forwardItem, 1)}
+	q.items <- forwardItem{}
+	p.queues[peer] = q
+
+	spans := []*model.Span{{TraceID: model.NewTraceID(0, 1)}, {TraceID: model.NewTraceID(0, 2)}}
+	oks, err := p.ProcessSpans(spans, processor.SpansOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, true}, oks)
+	localSpans, _ := local.received()
+	assert.Len(t, localSpans, 2, "the spans that cannot be queued are processed locally right away")
+	mf.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "spans_forwarded", Tags: map[string]string{"result": "queue_full"}, Value: 2})
+
+	local.err = errors.New("queue full")
+	oks, err = p.ProcessSpans(spans, processor.SpansOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{false, false}, oks)
+	mf.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "spans_fallback_failed", Value: 2})
+	delete(p.queues, peer)
+	require.NoError(t, p.Close())
+}
+
+func TestProcessorPeerLeft(t *testing.T) {
+	remote := &recordingProcessor{}
+	peer, stop := startPeer(t, remote)
+	defer stop()
+	local := &recordingProcessor{}
+	p, mf := newTestProcessor(t, local, "", peer)
+	q := &peerQueue{peer: peer, items: make(chan forwardItem, 1)}
+	p.queues[peer] = q
+	spans := []*model.Span{{TraceID: model.NewTraceID(0, 1)}}
+	_, err := p.ProcessSpans(spans, processor.SpansOptions{})
+	require.NoError(t, err)
+
+	// the peer leaves before the queued batch is forwarded
+	p.opts.Peers = []string{"127.0.0.1:1"}
+	p.refresh()
+	assert.Empty(t, p.queues)
+	assert.True(t, q.left.Load())
+	for item := range q.items {
+		p.forwardOrProcess(q, item)
+	}
+	localSpans, _ := local.received()
+	assert.Len(t, localSpans, 1)
+	remoteSpans, _ := remote.received()
+	assert.Empty(t, remoteSpans)
+	mf.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "spans_forwarded", Tags: map[string]string{"result": "ok"}, Value: 0})
+	require.NoError(t, p.Close())
+}
+
+func TestProcessorRefresh(t *testing.T) {
+	p, mf := newTestProcessor(t, &recordingProcessor{}, "", "collectors:14250")
+	defer p.Close()
+	p.resolver = fakeResolver{"collectors": {"10.0.0.1", "10.0.0.2"}}
+	p.localAddrs = func() ([]net.Addr, error) {
+		return []net.Addr{&net.IPNet{IP: net.ParseIP("10.0.0.2")}}, nil
+	}
+	_, err := p.conn("collectors:14250")
+	require.NoError(t, err)
+
+	p.refresh()
+	assert.Equal(t, []string{"10.0.0.1:14250", "10.0.0.2:14250"}, p.ring.members)
+	assert.Equal(t, "10.0.0.2:14250", p.ring.self)
+	// the connection to the member that left is closed
+	assert.Empty(t, p.conns)
+	mf.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "membership_changes", Value: 1})
+	mf.AssertGaugeMetrics(t, metricstest.ExpectedMetric{Name: "members", Value: 2})
+
+	p.refresh()
+	mf.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "membership_changes", Value: 1})
+}
+
+func TestProcessorStartClose(t *testing.T) {
+	p, _ := newTestProcessor(t, &recordingProcessor{}, "", "127.0.0.1:1")
+	p.Start()
+	assert.NoError(t, p.Close())
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peerrouting
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"net"
+	"sort"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+// ring assigns the traces to the members with rendezvous hashing,
+// so that a membership change only moves the traces of the members added or removed.
+type ring struct {
+	members []string
+	self    string
+}
+
+// owner returns the member owning the trace
+func (r *ring) owner(traceID model.TraceID) string {
+	var key [16]byte
+	binary.BigEndian.PutUint64(key[:8], traceID.High)
+	binary.BigEndian.PutUint64(key[8:], traceID.Low)
+	var best string
+	var bestScore uint64
+	for _, member := range r.members {
+		h := fnv.New64a()
+		h.Write([]byte(member))
+		h.Write(key[:])
+		if score := mix(h.Sum64()); best == "" || score > bestScore {
+			best, bestScore = member, score
+		}
+	}
+	return best
+}
+
+// mix is the finalizer of MurmurHash3, FNV alone spreads poorly the members differing only by a few characters
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// hasMemberIP tells whether the IP is the address of a member
+func (r *ring) hasMemberIP(ip string) bool {
+	client := net.ParseIP(ip)
+	if client == nil {
+		return false
+	}
+	for _, member := range r.members {
+		host, _, err := net.SplitHostPort(member)
+		if err != nil {
+			continue
+		}
+		if memberIP := net.ParseIP(host); memberIP != nil && memberIP.Equal(client) {
+			return true
+		}
+	}
+	return false
+}
+
+type resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// resolveMembers expands the peer hosts to their addresses; a peer that cannot be resolved is kept as is
+func resolveMembers(ctx context.Context, r resolver, peers []string) []string {
+	seen := make(map[string]struct{})
+	var members []string
+	add := func(member string) {
+		if _, ok := seen[member]; !ok {
+			seen[member] = struct{}{}
+			members = append(members, member)
+		}
+	}
+	for _, peer := range peers {
+		host, port, err := net.SplitHostPort(peer)
+		if err != nil || net.ParseIP(host) != nil {
+			add(peer)
+			continue
+		}
+		addrs, err := r.LookupHost(ctx, host)
+		if err != nil || len(addrs) == 0 {
+			add(peer)
+			continue
+		}
+		for _, addr := range addrs {
+			add(net.JoinHostPort(addr, port))
+		}
+	}
+	sort.Strings(members)
+	return members
+}
+
+// findSelf returns the member listening on one of the local addresses
+func findSelf(members []string, localAddrs []net.Addr) string {
+	local := make(map[string]struct{})
+	for _, addr := range localAddrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() {
+			local[ipNet.IP.String()] = struct{}{}
+		}
+	}
+	for _, member := range members {
+		host, _, err := net.SplitHostPort(member)
+		if err != nil {
+			continue
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			if _, ok := local[ip.String()]; ok {
+				return member
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peerrouting
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/config"
+)
+
+type fakeResolver map[string][]string
+
+func (r fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	addrs, ok := r[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return addrs, nil
+}
+
+func TestOptionsWithFlags(t *testing.T) {
+	v, command := config.Viperize(AddFlags)
+	opts := new(Options).InitFromViper(v)
+	assert.False(t, opts.Enabled())
+	assert.Equal(t, defaultResolveInterval, opts.ResolveInterval)
+	assert.Equal(t, defaultForwardTimeout, opts.ForwardTimeout)
+	assert.Equal(t, defaultQueueSize, opts.QueueSize)
+
+	command.ParseFlags([]string{
+		"--collector.peer-routing.peers=collector-0:14250, collector-1:14250,",
+		"--collector.peer-routing.self=collector-0:14250",
+		"--collector.peer-routing.tls.enabled=true",
+	})
+	opts.InitFromViper(v)
+	assert.True(t, opts.Enabled())
+	assert.Equal(t, []string{"collector-0:14250", "collector-1:14250"}, opts.Peers)
+	assert.Equal(t, "collector-0:14250", opts.Self)
+	assert.True(t, opts.TLS.Enabled)
+}
+
+func TestRingOwner(t *testing.T) {
+	r := &ring{members: []string{"a:1", "b:1", "c:1"}}
+	owned := make(map[string]int)
+	owners := make(map[model.TraceID]string)
+	for i := uint64(1); i <= 3000; i++ {
+		traceID := model.NewTraceID(i*7919, i*104729)
+		owner := r.owner(traceID)
+		assert.Equal(t, owner, r.owner(traceID))
+		owners[traceID] = owner
+		owned[owner]++
+	}
+	for _, member := range r.members {
+		assert.InDelta(t, 1000, owned[member], 200, member)
+	}
+
+	// only the traces of the removed member move
+	smaller := &ring{members: []string{"a:1", "c:1"}}
+	for traceID, owner := range owners {
+		if owner != "b:1" {
+			assert.Equal(t, owner, smaller.owner(traceID))
+		}
+	}
+
+	assert.Equal(t, "", (&ring{}).owner(model.NewTraceID(1, 2)))
+}
+
+func TestResolveMembers(t *testing.T) {
+	r := fakeResolver{"collectors": {"10.0.0.2", "10.0.0.1"}}
+	members := resolveMembers(context.Background(), r, []string{"collectors:14250", "10.0.0.3:14250", "unknown:14250", "10.0.0.1:14250", "bad"})
+	assert.Equal(t, []string{"10.0.0.1:14250", "10.0.0.2:14250", "10.0.0.3:14250", "bad", "unknown:14250"}, members)
+}
+
+func TestFindSelf(t *testing.T) {
+	addrs := []net.Addr{
+		&net.IPNet{IP: net.ParseIP("127.0.0.1")},
+		&net.IPNet{IP: net.ParseIP("10.0.0.2")},
+	}
+	assert.Equal(t, "10.0.0.2:14250", findSelf([]string{"10.0.0.1:14250", "10.0.0.2:14250", "collector:14250"}, addrs))
+	assert.Equal(t, "", findSelf([]string{"10.0.0.1:14250", "127.0.0.1:14250"}, addrs))
+}
+
+func TestRingHasMemberIP(t *testing.T) {
+	r := &ring{members: []string{"10.0.0.1:14250", "[::1]:14250", "collector:14250", "bad"}}
+	assert.True(t, r.hasMemberIP("10.0.0.1"))
+	assert.True(t, r.hasMemberIP("0:0:0:0:0:0:0:1"))
+	assert.False(t, r.hasMemberIP("10.0.0.2"))
+	assert.False(t, r.hasMemberIP("collector"))
+	assert.False(t, r.hasMemberIP(""))
+}
+
+func TestEqualMembers(t *testing.T) {
+	assert.True(t, equalMembers(nil, []string{}))
+	assert.True(t, equalMembers([]string{"a"}, []string{"a"}))
+	assert.False(t, equalMembers([]string{"a"}, []string{"b"}))
+	assert.False(t, equalMembers([]string{"a"}, []string{"a", "b"}))
+}
@@ -15,15 +15,47 @@
 package processor
 
 import (
+	"errors"
 	"io"
 
 	"github.com/jaegertracing/jaeger/model"
 )
 
+// ErrRateLimited is returned by ProcessSpans when a batch is rejected because it exceeds the ingestion rate limits,
+// or because none of its spans are within the rate limits of their services.
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// ErrDraining is returned by ProcessSpans when the collector is being drained and no longer accepts spans.
+var ErrDraining = errors.New("collector is draining")
+
+// ErrSpanDropped is reported to the saved callbacks of deferred span writers when a buffered span
+// is intentionally not written, e.g. because its trace was not tail sampled.
+var ErrSpanDropped = errors.New("span dropped by sampling")
+
 // SpansOptions additional options passed to processor along with the spans.
 type SpansOptions struct {
 	SpanFormat       SpanFormat
 	InboundTransport InboundTransport
+	// Tenant is the validated tenant of the spans, empty unless multi-tenancy is enabled
+	Tenant string
+	// Client describes who submitted the spans
+	Client ClientInfo
+}
+
+// ClientInfo describes the client that submitted the spans, as far as the transport tells.
+type ClientInfo struct {
+	// IP is the address of the client, empty if unknown
+	IP string
+	// Metadata returns the first value of a request header or gRPC metadata key, nil if unavailable
+	Metadata func(key string) string
+}
+
+// Get returns the value of the metadata key, empty if unavailable.
+func (c ClientInfo) Get(key string) string {
+	if c.Metadata == nil {
+		return ""
+	}
+	return c.Metadata(key)
 }
 
 // SpanProcessor handles model spans
@@ -55,6 +87,10 @@ const (
 	ZipkinSpanFormat SpanFormat = "zipkin"
 	// ProtoSpanFormat is for Jaeger protobuf Spans.
 	ProtoSpanFormat SpanFormat = "proto"
+	// OTLPSpanFormat is for OpenTelemetry protocol spans.
+	OTLPSpanFormat SpanFormat = "otlp"
+	// OpenCensusSpanFormat is for spans received over the OpenCensus agent protocol.
+	OpenCensusSpanFormat SpanFormat = "opencensus"
 	// UnknownSpanFormat is the fallback/catch-all category.
 	UnknownSpanFormat SpanFormat = "unknown"
 )
@@ -0,0 +1,193 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+const (
+	// otherServices is the metrics tag and the shared bucket for services without an explicit limit,
+	// once the number of tracked services reaches maxServices
+	otherServices = "other-services"
+	maxServices   = 4000
+)
+
+// tokenBucket allows bursts up to one second worth of spans. A batch larger than the burst
+// is accepted when the bucket is full and leaves the bucket in debt, so that large batches
+// from agents are not rejected forever.
+type tokenBucket struct {
+	rate     float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(rate float64, now time.Time) *tokenBucket {
+	capacity := rate
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &tokenBucket{rate: rate, capacity: capacity, tokens: capacity, last: now}
+}
+
+func (b *tokenBucket) canTake(n float64) bool {
+	return b.tokens >= n || b.tokens >= b.capacity
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+}
+
+// Limiter enforces spans-per-second limits keyed by service name.
+type Limiter struct {
+	defaultRate float64
+	limits      map[string]float64
+
+	lock    sync.Mutex
+	buckets map[string]*tokenBucket
+
+	overLimit      map[string]metrics.Counter
+	metricsFactory metrics.Factory
+	timeNow        func() time.Time
+}
+
+// NewLimiter creates a Limiter from the options.
+func NewLimiter(opts Options, metricsFactory metrics.Factory) (*Limiter, error) {
+	limits, err := parseServiceLimits(opts.ServiceLimits)
+	if err != nil {
+		return nil, err
+	}
+	l := &Limiter{
+		defaultRate:    opts.SpansPerSecond,
+		limits:         limits,
+		buckets:        make(map[string]*tokenBucket),
+		overLimit:      make(map[string]metrics.Counter),
+		metricsFactory: metricsFactory,
+		timeNow:        time.Now,
+	}
+	for svc := range limits {
+		l.overLimit[svc] = l.newCounter(svc)
+	}
+	l.overLimit[otherServices] = l.newCounter(otherServices)
+	return l, nil
+}
+
+func parseServiceLimits(s string) (map[string]float64, error) {
+	limits := make(map[string]float64)
+	if s == "" {
+		return limits, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("invalid service rate limit %q, expecting service=spans-per-second", pair)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+		if err != nil || rate < 0 {
+			return nil, fmt.Errorf("invalid service rate limit %q, expecting service=spans-per-second", pair)
+		}
+		limits[strings.TrimSpace(kv[0])] = rate
+	}
+	return limits, nil
+}
+
+func (l *Limiter) newCounter(svc string) metrics.Counter {
+	return l.metricsFactory.Counter(metrics.Options{Name: "spans_over_limit", Tags: map[string]string{"svc": svc}})
+}
+
+// Allow returns, for each span of the batch, whether it fits within the limit of its service.
+// The spans of a service are accepted or rejected together: they are deducted from the limit
+// of the service if they all fit, otherwise all of them are rejected and nothing is deducted.
+// Spans of services that are within their limits are accepted regardless of the other services.
+func (l *Limiter) Allow(spans []*model.Span) []bool {
+	counts := make(map[string]int)
+	for _, span := range spans {
+		counts[span.Process.GetServiceName()]++
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := l.timeNow()
+	buckets := make(map[*tokenBucket]float64, len(counts))
+	for svc, n := range counts {
+		if b := l.bucketLocked(svc, now); b != nil {
+			buckets[b] += float64(n)
+		}
+	}
+	rejected := make(map[*tokenBucket]bool)
+	for b, n := range buckets {
+		b.refill(now)
+		if b.canTake(n) {
+			b.tokens -= n
+		} else {
+			rejected[b] = true
+		}
+	}
+	if len(rejected) > 0 {
+		for svc, n := range counts {
+			if rejected[l.bucketLocked(svc, now)] {
+				l.counterFor(svc).Inc(int64(n))
+			}
+		}
+	}
+	allowed := make([]bool, len(spans))
+	for i, span := range spans {
+		allowed[i] = !rejected[l.bucketLocked(span.Process.GetServiceName(), now)]
+	}
+	return allowed
+}
+
+// bucketLocked returns the bucket of the service, or nil if the service is unlimited.
+func (l *Limiter) bucketLocked(svc string, now time.Time) *tokenBucket {
+	rate, explicit := l.limits[svc]
+	if !explicit {
+		rate = l.defaultRate
+	}
+	if rate <= 0 {
+		return nil
+	}
+	if b, ok := l.buckets[svc]; ok {
+		return b
+	}
+	if !explicit && len(l.buckets) >= maxServices {
+		svc = otherServices
+		if b, ok := l.buckets[svc]; ok {
+			return b
+		}
+	}
+	b := newTokenBucket(rate, now)
+	l.buckets[svc] = b
+	return b
+}
+
+func (l *Limiter) counterFor(svc string) metrics.Counter {
+	if c, ok := l.overLimit[svc]; ok {
+		return c
+	}
+	return l.overLimit[otherServices]
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/config"
+)
+
+func makeSpans(svc string, n int) []*model.Span {
+	spans := make([]*model.Span, n)
+	for i := range spans {
+		spans[i] = &model.Span{Process: &model.Process{ServiceName: svc}}
+	}
+	return spans
+}
+
+func TestLimiter(t *testing.T) {
+	mf := metricstest.NewFactory(0)
+	l, err := NewLimiter(Options{SpansPerSecond: 10, ServiceLimits: "frontend=2, backend=0"}, mf)
+	require.NoError(t, err)
+	now := time.Unix(1000, 0)
+	l.timeNow = func() time.Time { return now }
+
+	assert.Equal(t, []bool{true, true}, l.Allow(makeSpans("frontend", 2)))
+	assert.Equal(t, []bool{false}, l.Allow(makeSpans("frontend", 1)))
+	assert.NotContains(t, l.Allow(makeSpans("backend", 1000)), false, "0 means unlimited")
+	assert.NotContains(t, l.Allow(makeSpans("other", 10)), false)
+	assert.Equal(t, []bool{false, true}, l.Allow(append(makeSpans("other", 1), makeSpans("backend", 1)...)))
+
+	now = now.Add(500 * time.Millisecond)
+	assert.Equal(t, []bool{true}, l.Allow(makeSpans("frontend", 1)))
+	assert.NotContains(t, l.Allow(makeSpans("other", 5)), false)
+
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "spans_over_limit", Tags: map[string]string{"svc": "frontend"}, Value: 1},
+		metricstest.ExpectedMetric{Name: "spans_over_limit", Tags: map[string]string{"svc": "other-services"}, Value: 1},
+	)
+}
+
+func TestLimiterRejectsOverLimitService(t *testing.T) {
+	l, err := NewLimiter(Options{ServiceLimits: "a=5,b=5"}, metricstest.NewFactory(0))
+	require.NoError(t, err)
+	now := time.Unix(1000, 0)
+	l.timeNow = func() time.Time { return now }
+
+	assert.NotContains(t, l.Allow(makeSpans("b", 4)), false)
+	assert.Equal(t, []bool{true, true, true, false, false, false}, l.Allow(append(makeSpans("a", 3), makeSpans("b", 3)...)))
+	assert.Equal(t, []bool{true, true}, l.Allow(makeSpans("a", 2)), "the accepted spans of service a were deducted")
+	assert.Equal(t, []bool{false}, l.Allow(makeSpans("a", 1)))
+	assert.Equal(t, []bool{true}, l.Allow(makeSpans("b", 1)), "the rejected spans of service b were not deducted")
+}
+
+func TestLimiterLargeBatch(t *testing.T) {
+	l, err := NewLimiter(Options{SpansPerSecond: 10}, metricstest.NewFactory(0))
+	require.NoError(t, err)
+	now := time.Unix(1000, 0)
+	l.timeNow = func() time.Time { return now }
+
+	assert.NotContains(t, l.Allow(makeSpans("svc", 30)), false, "a batch larger than the burst passes when the bucket is full")
+	now = now.Add(2 * time.Second)
+	assert.Equal(t, []bool{false}, l.Allow(makeSpans("svc", 1)), "the bucket is still in debt")
+	now = now.Add(time.Second)
+	assert.Equal(t, []bool{true}, l.Allow(makeSpans("svc", 1)))
+}
+
+func TestLimiterTooManyServices(t *testing.T) {
+	l, err := NewLimiter(Options{SpansPerSecond: 1}, metricstest.NewFactory(0))
+	require.NoError(t, err)
+	for i := 0; i < maxServices; i++ {
+		l.buckets[string(rune(i))] = newTokenBucket(1, time.Now())
+	}
+	assert.Equal(t, []bool{true}, l.Allow(makeSpans("new-1", 1)))
+	assert.Equal(t, []bool{false}, l.Allow(makeSpans("new-2", 1)), "new services share a bucket")
+}
+
+func TestParseServiceLimitsErrors(t *testing.T) {
+	for _, s := range []string{"frontend", "=1", "a=x", "a=-1"} {
+		_, err := NewLimiter(Options{ServiceLimits: s}, metricstest.NewFactory(0))
+		assert.Error(t, err, s)
+	}
+}
+
+func TestOptionsWithFlags(t *testing.T) {
+	v, command := config.Viperize(AddFlags)
+	opts := new(Options).InitFromViper(v)
+	assert.False(t, opts.Enabled())
+
+	command.ParseFlags([]string{
+		"--collector.rate-limit.spans-per-second=100",
+		"--collector.rate-limit.services=frontend=10",
+	})
+	opts.InitFromViper(v)
+	assert.True(t, opts.Enabled())
+	assert.Equal(t, 100.0, opts.SpansPerSecond)
+	assert.Equal(t, "frontend=10", opts.ServiceLimits)
+}
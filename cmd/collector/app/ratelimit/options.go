@@ -0,0 +1,53 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"flag"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	spansPerSecond = "collector.rate-limit.spans-per-second"
+	serviceLimits  = "collector.rate-limit.services"
+)
+
+// Options configures the rate limits applied to spans received by the collector.
+type Options struct {
+	// SpansPerSecond is the limit applied to each service without an explicit limit, 0 means unlimited
+	SpansPerSecond float64
+	// ServiceLimits holds explicit limits as a comma-separated list of service=spans-per-second
+	ServiceLimits string
+}
+
+// AddFlags adds flags for rate limiting Options
+func AddFlags(flags *flag.FlagSet) {
+	flags.Float64(spansPerSecond, 0, "The maximum number of spans per second accepted from each service without an explicit limit, 0 means unlimited; "+
+		"the spans of a service exceeding its limit are dropped, and batches with only such spans are rejected with HTTP 429 or gRPC RESOURCE_EXHAUSTED")
+	flags.String(serviceLimits, "", "Comma-separated list of per-service limits in spans per second, overriding --"+spansPerSecond+", 0 means unlimited. Ex: frontend=100,backend=500")
+}
+
+// InitFromViper initializes Options with properties from viper
+func (o *Options) InitFromViper(v *viper.Viper) *Options {
+	o.SpansPerSecond = v.GetFloat64(spansPerSecond)
+	o.ServiceLimits = v.GetString(serviceLimits)
+	return o
+}
+
+// Enabled returns true if any limit is configured
+func (o *Options) Enabled() bool {
+	return o.SpansPerSecond > 0 || o.ServiceLimits != ""
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redaction
+
+import (
+	"flag"
+
+	"github.com/spf13/viper"
+)
+
+const rulesFile = "collector.redaction.rules-file"
+
+// Options holds the configuration of the redaction stage.
+type Options struct {
+	// RulesFile is the path to a JSON file with the redaction rules, empty disables redaction
+	RulesFile string
+}
+
+// AddFlags adds flags for redaction Options
+func AddFlags(flags *flag.FlagSet) {
+	flags.String(rulesFile, "", "Path to a JSON file with rules to hash or remove span tags, process tags and log fields by key and value patterns, "+
+		`e.g. {"hash_salt":"s3cr3t","rules":[{"key":"^user\\.email$","action":"hash"},{"value":"^\\d{16}$","action":"remove"}]}`)
+}
+
+// InitFromViper initializes Options with properties from viper
+func (o *Options) InitFromViper(v *viper.Viper) *Options {
+	o.RulesFile = v.GetString(rulesFile)
+	return o
+}
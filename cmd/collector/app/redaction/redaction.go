@@ -0,0 +1,182 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redaction
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/jaegertracing/jaeger/cmd/collector/app/sanitizer"
+	"github.com/jaegertracing/jaeger/model"
+)
+
+const (
+	// ActionHash replaces the value of a matching tag with its salted SHA-256 hash
+	ActionHash = "hash"
+	// ActionRemove removes matching tags altogether
+	ActionRemove = "remove"
+
+	hashPrefix = "sha256:"
+)
+
+// Config is the content of the redaction rules file.
+type Config struct {
+	// HashSalt is prepended to values before hashing them
+	HashSalt string       `json:"hash_salt"`
+	Rules    []RuleConfig `json:"rules"`
+}
+
+// RuleConfig describes which tags are redacted and how. A tag matches when its key matches
+// the Key regular expression and its value matches the Value regular expression;
+// an empty expression matches anything, but at least one must be set.
+type RuleConfig struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Action string `json:"action"`
+}
+
+type rule struct {
+	key    *regexp.Regexp
+	value  *regexp.Regexp
+	remove bool
+}
+
+type redactionMetrics struct {
+	TagsHashed  metrics.Counter `metric:"tags" tags:"action=hash"`
+	TagsRemoved metrics.Counter `metric:"tags" tags:"action=remove"`
+}
+
+type redactor struct {
+	salt    string
+	rules   []rule
+	metrics redactionMetrics
+}
+
+// LoadConfig reads the redaction rules from a JSON file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open redaction rules file: %w", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal redaction rules: %w", err)
+	}
+	return &cfg, nil
+}
+
+// NewSanitizer creates a sanitizer that redacts span tags, process tags and log fields
+// matching the rules, before spans reach storage.
+func NewSanitizer(cfg *Config, metricsFactory metrics.Factory) (sanitizer.SanitizeSpan, error) {
+	r := &redactor{salt: cfg.HashSalt}
+	for i, rc := range cfg.Rules {
+		if rc.Key == "" && rc.Value == "" {
+			return nil, fmt.Errorf("redaction rule %d: key or value pattern is required", i)
+		}
+		if rc.Action != ActionHash && rc.Action != ActionRemove {
+			return nil, fmt.Errorf("redaction rule %d: unknown action %q, expecting %q or %q", i, rc.Action, ActionHash, ActionRemove)
+		}
+		var rl rule
+		var err error
+		if rc.Key != "" {
+			if rl.key, err = regexp.Compile(rc.Key); err != nil {
+				return nil, fmt.Errorf("redaction rule %d: invalid key pattern: %w", i, err)
+			}
+		}
+		if rc.Value != "" {
+			if rl.value, err = regexp.Compile(rc.Value); err != nil {
+				return nil, fmt.Errorf("redaction rule %d: invalid value pattern: %w", i, err)
+			}
+		}
+		rl.remove = rc.Action == ActionRemove
+		r.rules = append(r.rules, rl)
+	}
+	metrics.Init(&r.metrics, metricsFactory, nil)
+	return r.Sanitize, nil
+}
+
+// Sanitize redacts the span. Redacted tags are written to new slices, and the process,
+// which is shared by all the spans of a batch, is copied before its tags are replaced.
+func (r *redactor) Sanitize(span *model.Span) *model.Span {
+	span.Tags, _ = r.redact(span.Tags)
+	for i := range span.Logs {
+		span.Logs[i].Fields, _ = r.redact(span.Logs[i].Fields)
+	}
+	if span.Process != nil {
+		if tags, redacted := r.redact(span.Process.Tags); redacted {
+			process := *span.Process
+			process.Tags = tags
+			span.Process = &process
+		}
+	}
+	return span
+}
+
+// redact returns the tags with the matching ones hashed or removed, and whether any matched.
+// The input slice is never modified.
+func (r *redactor) redact(tags []model.KeyValue) ([]model.KeyValue, bool) {
+	var kept []model.KeyValue
+	for i := range tags {
+		rl := r.match(&tags[i])
+		if rl == nil {
+			if kept != nil {
+				kept = append(kept, tags[i])
+			}
+			continue
+		}
+		if kept == nil {
+			kept = make([]model.KeyValue, i, len(tags))
+			copy(kept, tags[:i])
+		}
+		tag := tags[i]
+		switch {
+		case rl.remove:
+			r.metrics.TagsRemoved.Inc(1)
+		default:
+			r.metrics.TagsHashed.Inc(1)
+			kept = append(kept, model.String(tag.Key, r.hash(tag.AsString())))
+		}
+	}
+	if kept == nil {
+		return tags, false
+	}
+	return kept, true
+}
+
+func (r *redactor) match(tag *model.KeyValue) *rule {
+	for i := range r.rules {
+		rl := &r.rules[i]
+		if rl.key != nil && !rl.key.MatchString(tag.Key) {
+			continue
+		}
+		if rl.value != nil && !rl.value.MatchString(tag.AsString()) {
+			continue
+		}
+		return rl
+	}
+	return nil
+}
+
+func (r *redactor) hash(value string) string {
+	sum := sha256.Sum256([]byte(r.salt + value))
+	return hashPrefix + hex.EncodeToString(sum[:])
+}
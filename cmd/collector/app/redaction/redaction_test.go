@@ -0,0 +1,142 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redaction
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/config"
+)
+
+func TestLoadConfig(t *testing.T) {
+	f, err := ioutil.TempFile("", "redaction")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`{"hash_salt":"salt","rules":[{"key":"^user\\.email$","action":"hash"}]}`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	cfg, err := LoadConfig(f.Name())
+	require.NoError(t, err)
+	assert.Equal(t, &Config{HashSalt: "salt", Rules: []RuleConfig{{Key: `^user\.email$`, Action: ActionHash}}}, cfg)
+
+	_, err = LoadConfig("/does/not/exist")
+	assert.Contains(t, err.Error(), "failed to open redaction rules file")
+
+	require.NoError(t, ioutil.WriteFile(f.Name(), []byte("{"), 0600))
+	_, err = LoadConfig(f.Name())
+	assert.Contains(t, err.Error(), "failed to unmarshal redaction rules")
+}
+
+func TestSanitizer(t *testing.T) {
+	mf := metricstest.NewFactory(0)
+	sanitize, err := NewSanitizer(&Config{
+		HashSalt: "salt",
+		Rules: []RuleConfig{
+			{Key: `^user\.email$`, Action: ActionHash},
+			{Value: `^\d{16}$`, Action: ActionRemove},
+			{Key: `^password$`, Action: ActionRemove},
+		},
+	}, mf)
+	require.NoError(t, err)
+
+	salted := &redactor{salt: "salt"}
+	span := &model.Span{
+		Tags: model.KeyValues{
+			model.String("user.email", "jane@example.com"),
+			model.String("card", "4111111111111111"),
+			model.Int64("http.status_code", 200),
+		},
+		Logs: []model.Log{{Fields: model.KeyValues{
+			model.String("event", "login"),
+			model.String("password", "hunter2"),
+		}}},
+		Process: &model.Process{Tags: model.KeyValues{model.String("user.email", "ops@example.com")}},
+	}
+	span = sanitize(span)
+
+	assert.Equal(t, model.KeyValues{
+		model.String("user.email", salted.hash("jane@example.com")),
+		model.Int64("http.status_code", 200),
+	}, model.KeyValues(span.Tags))
+	assert.Equal(t, model.KeyValues{model.String("event", "login")}, model.KeyValues(span.Logs[0].Fields))
+	assert.Equal(t, model.KeyValues{model.String("user.email", salted.hash("ops@example.com"))}, model.KeyValues(span.Process.Tags))
+	assert.Contains(t, span.Tags[0].VStr, hashPrefix)
+	assert.NotEqual(t, (&redactor{}).hash("jane@example.com"), span.Tags[0].VStr, "hash must depend on the salt")
+
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "tags", Tags: map[string]string{"action": "hash"}, Value: 2},
+		metricstest.ExpectedMetric{Name: "tags", Tags: map[string]string{"action": "remove"}, Value: 2},
+	)
+}
+
+func TestSanitizerSharedProcess(t *testing.T) {
+	sanitize, err := NewSanitizer(&Config{
+		Rules: []RuleConfig{{Key: `^user\.email$`, Action: ActionHash}},
+	}, metrics.NullFactory)
+	require.NoError(t, err)
+
+	process := &model.Process{ServiceName: "svc", Tags: model.KeyValues{model.String("user.email", "ops@example.com")}}
+	spans := []*model.Span{{Process: process}, {Process: process}}
+	var wg sync.WaitGroup
+	for _, span := range spans {
+		wg.Add(1)
+		go func(span *model.Span) {
+			defer wg.Done()
+			sanitize(span)
+		}(span)
+	}
+	wg.Wait()
+
+	expected := model.KeyValues{model.String("user.email", (&redactor{}).hash("ops@example.com"))}
+	for _, span := range spans {
+		assert.Equal(t, expected, model.KeyValues(span.Process.Tags))
+		assert.Equal(t, "svc", span.Process.ServiceName)
+	}
+	assert.Equal(t, "ops@example.com", process.Tags[0].VStr, "the shared process must not be modified")
+}
+
+func TestNewSanitizerErrors(t *testing.T) {
+	tests := []struct {
+		rule RuleConfig
+		err  string
+	}{
+		{RuleConfig{Action: ActionHash}, "redaction rule 0: key or value pattern is required"},
+		{RuleConfig{Key: "a", Action: "mask"}, `redaction rule 0: unknown action "mask"`},
+		{RuleConfig{Key: "(", Action: ActionHash}, "redaction rule 0: invalid key pattern"},
+		{RuleConfig{Value: "(", Action: ActionHash}, "redaction rule 0: invalid value pattern"},
+	}
+	for _, test := range tests {
+		_, err := NewSanitizer(&Config{Rules: []RuleConfig{test.rule}}, metrics.NullFactory)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), test.err)
+	}
+}
+
+func TestOptionsWithFlags(t *testing.T) {
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{"--collector.redaction.rules-file=/etc/jaeger/redaction.json"})
+	opts := new(Options).InitFromViper(v)
+	assert.Equal(t, "/etc/jaeger/redaction.json", opts.RulesFile)
+}
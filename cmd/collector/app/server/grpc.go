@@ -15,16 +15,25 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"net/http"
+	"strings"
 
+	"github.com/soheilhy/cmux"
+	"github.com/uber/jaeger-lib/metrics"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
+	channelz "google.golang.org/grpc/channelz/service"
+	"google.golang.org/grpc/reflection"
 
 	"github.com/jaegertracing/jaeger/cmd/collector/app/handler"
 	"github.com/jaegertracing/jaeger/cmd/collector/app/sampling"
 	"github.com/jaegertracing/jaeger/cmd/collector/app/sampling/strategystore"
+	"github.com/jaegertracing/jaeger/pkg/auth"
+	// the agents may compress their batches with zstd
+	_ "github.com/jaegertracing/jaeger/pkg/compression/zstd"
 	"github.com/jaegertracing/jaeger/pkg/config/tlscfg"
 	"github.com/jaegertracing/jaeger/proto-gen/api_v2"
 )
@@ -37,25 +46,32 @@ type GRPCServerParams struct {
 	SamplingStore strategystore.StrategyStore
 	Logger        *zap.Logger
 	OnError       func(error)
+	// Authenticator, if set, requires a bearer token on span submission, sampling stays public
+	Authenticator *auth.Authenticator
+	// MetricsFactory reports the clients rejected by the TLS allowlist, optional
+	MetricsFactory metrics.Factory
+	// Reflection registers the gRPC server reflection service
+	Reflection bool
+	// Channelz registers the channelz service
+	Channelz bool
+	// GRPCWeb also serves gRPC-Web requests sent over HTTP/1.1 on the same port
+	GRPCWeb bool
+	// CORS configures cross-origin gRPC-Web requests
+	CORS CORSOptions
 }
 
+const postSpansMethod = "/jaeger.api_v2.CollectorService/PostSpans"
+
 // StartGRPCServer based on the given parameters
 func StartGRPCServer(params *GRPCServerParams) (*grpc.Server, error) {
-	var server *grpc.Server
-
-	if params.TLSConfig.Enabled {
-		// user requested a server with TLS, setup creds
-		tlsCfg, err := params.TLSConfig.Config()
-		if err != nil {
-			return nil, err
-		}
-
-		creds := credentials.NewTLS(tlsCfg)
-		server = grpc.NewServer(grpc.Creds(creds))
-	} else {
-		// server without TLS
-		server = grpc.NewServer()
+	if params.GRPCWeb && params.TLSConfig.Enabled {
+		return nil, errors.New("gRPC-Web cannot be enabled together with TLS on the gRPC server, terminate TLS in front of the collector instead")
 	}
+	opts, err := serverOptions(params)
+	if err != nil {
+		return nil, err
+	}
+	server := grpc.NewServer(opts...)
 
 	listener, err := net.Listen("tcp", params.HostPort)
 	if err != nil {
@@ -69,19 +85,81 @@ func StartGRPCServer(params *GRPCServerParams) (*grpc.Server, error) {
 	return server, nil
 }
 
+func serverOptions(params *GRPCServerParams) ([]grpc.ServerOption, error) {
+	var opts []grpc.ServerOption
+
+	if params.TLSConfig.Enabled {
+		// user requested a server with TLS, setup creds
+		creds, err := tlsServerOption(params.TLSConfig, "grpc", params.MetricsFactory, params.Logger)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, creds)
+	}
+	if params.Authenticator != nil {
+		opts = append(opts, grpc.UnaryInterceptor(params.Authenticator.UnaryServerInterceptor(postSpansMethod)))
+	}
+	return opts, nil
+}
+
 func serveGRPC(server *grpc.Server, listener net.Listener, params *GRPCServerParams) error {
 	api_v2.RegisterCollectorServiceServer(server, params.Handler)
 	api_v2.RegisterSamplingManagerServer(server, sampling.NewGRPCHandler(params.SamplingStore))
+	registerIntrospection(server, params.Reflection, params.Channelz)
 
-	params.Logger.Info("Starting jaeger-collector gRPC server", zap.String("grpc.host-port", params.HostPort))
+	grpcListener := listener
+	if params.GRPCWeb {
+		grpcListener = serveGRPCWeb(server, listener, params)
+	}
+
+	params.Logger.Info("Starting jaeger-collector gRPC server", zap.String("grpc.host-port", params.HostPort), zap.Bool("grpc-web", params.GRPCWeb))
 	go func() {
-		if err := server.Serve(listener); err != nil {
+		if err := server.Serve(grpcListener); err != nil {
 			params.Logger.Error("Could not launch gRPC service", zap.Error(err))
 			if params.OnError != nil {
 				params.OnError(err)
 			}
 		}
+		if params.GRPCWeb {
+			// stopping the gRPC server only closes the multiplexed listener
+			listener.Close()
+		}
 	}()
 
 	return nil
 }
+
+// serveGRPCWeb splits the connections of listener between HTTP/2 gRPC, returned for the
+// gRPC server, and HTTP/1.1 gRPC-Web, translated by an HTTP server running until listener is closed.
+func serveGRPCWeb(server *grpc.Server, listener net.Listener, params *GRPCServerParams) net.Listener {
+	cmuxServer := cmux.New(listener)
+	grpcListener := cmuxServer.MatchWithWriters(
+		cmux.HTTP2MatchHeaderFieldSendSettings("content-type", "application/grpc"),
+		cmux.HTTP2MatchHeaderFieldSendSettings("content-type", "application/grpc+proto"),
+	)
+	httpListener := cmuxServer.Match(cmux.HTTP1Fast())
+
+	httpServer := &http.Server{Handler: params.CORS.Handler(grpcWebHandler(server), grpcWebHeaders...)}
+	go func() {
+		if err := httpServer.Serve(httpListener); err != nil && err != cmux.ErrListenerClosed {
+			params.Logger.Error("Could not launch gRPC-Web service", zap.Error(err))
+		}
+	}()
+	go func() {
+		// TODO: Remove string comparison when https://github.com/soheilhy/cmux/pull/69 is merged
+		if err := cmuxServer.Serve(); err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
+			params.Logger.Error("Could not multiplex gRPC and gRPC-Web connections", zap.Error(err))
+		}
+	}()
+	return grpcListener
+}
+
+// registerIntrospection registers the optional services used by tools like grpcurl and channelz
+func registerIntrospection(server *grpc.Server, reflectionEnabled, channelzEnabled bool) {
+	if reflectionEnabled {
+		reflection.Register(server)
+	}
+	if channelzEnabled {
+		channelz.RegisterChannelzServiceToServer(server)
+	}
+}
@@ -26,6 +26,11 @@ import (
 	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zaptest/observer"
 	"google.golang.org/grpc"
+	channelzpb "google.golang.org/grpc/channelz/grpc_channelz_v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
 
 	"github.com/jaegertracing/jaeger/cmd/collector/app/handler"
@@ -37,7 +42,7 @@ func TestFailToListen(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	server, err := StartGRPCServer(&GRPCServerParams{
 		HostPort:      ":-1",
-		Handler:       handler.NewGRPCHandler(logger, &mockSpanProcessor{}),
+		Handler:       handler.NewGRPCHandler(logger, &mockSpanProcessor{}, nil),
 		SamplingStore: &mockSamplingStore{},
 		Logger:        logger,
 	})
@@ -54,7 +59,7 @@ func TestFailServe(t *testing.T) {
 
 	logger := zap.New(core)
 	serveGRPC(grpc.NewServer(), lis, &GRPCServerParams{
-		Handler:       handler.NewGRPCHandler(logger, &mockSpanProcessor{}),
+		Handler:       handler.NewGRPCHandler(logger, &mockSpanProcessor{}, nil),
 		SamplingStore: &mockSamplingStore{},
 		Logger:        logger,
 		OnError: func(e error) {
@@ -69,7 +74,7 @@ func TestFailServe(t *testing.T) {
 func TestSpanCollector(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	params := &GRPCServerParams{
-		Handler:       handler.NewGRPCHandler(logger, &mockSpanProcessor{}),
+		Handler:       handler.NewGRPCHandler(logger, &mockSpanProcessor{}, nil),
 		SamplingStore: &mockSamplingStore{},
 		Logger:        logger,
 	}
@@ -92,3 +97,96 @@ func TestSpanCollector(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, response)
 }
+
+func TestSpanCollectorAuthentication(t *testing.T) {
+	authenticator, cleanup := newTestAuthenticator(t, "secret")
+	defer cleanup()
+	logger := zap.NewNop()
+	params := &GRPCServerParams{
+		Handler:       handler.NewGRPCHandler(logger, &mockSpanProcessor{}, nil),
+		SamplingStore: &mockSamplingStore{},
+		Logger:        logger,
+		Authenticator: authenticator,
+	}
+	opts, err := serverOptions(params)
+	require.NoError(t, err)
+	server := grpc.NewServer(opts...)
+	defer server.Stop()
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	require.NoError(t, serveGRPC(server, listener, params))
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	c := api_v2.NewCollectorServiceClient(conn)
+	_, err = c.PostSpans(context.Background(), &api_v2.PostSpansRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+	_, err = c.PostSpans(ctx, &api_v2.PostSpansRequest{})
+	assert.NoError(t, err)
+}
+
+func TestSpanCollectorIntrospection(t *testing.T) {
+	logger := zap.NewNop()
+	params := &GRPCServerParams{
+		Handler:       handler.NewGRPCHandler(logger, &mockSpanProcessor{}, nil),
+		SamplingStore: &mockSamplingStore{},
+		Logger:        logger,
+		Reflection:    true,
+		Channelz:      true,
+	}
+	server := grpc.NewServer()
+	defer server.Stop()
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	require.NoError(t, serveGRPC(server, listener, params))
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_ListServices{},
+	}))
+	res, err := stream.Recv()
+	require.NoError(t, err)
+	var services []string
+	for _, s := range res.GetListServicesResponse().GetService() {
+		services = append(services, s.Name)
+	}
+	assert.Contains(t, services, "jaeger.api_v2.CollectorService")
+	assert.Contains(t, services, "jaeger.api_v2.SamplingManager")
+	assert.Contains(t, services, "grpc.channelz.v1.Channelz")
+
+	servers, err := channelzpb.NewChannelzClient(conn).GetServers(context.Background(), &channelzpb.GetServersRequest{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, servers.Server)
+}
+
+func TestSpanCollectorNoIntrospection(t *testing.T) {
+	logger := zap.NewNop()
+	params := &GRPCServerParams{
+		Handler:       handler.NewGRPCHandler(logger, &mockSpanProcessor{}, nil),
+		SamplingStore: &mockSamplingStore{},
+		Logger:        logger,
+	}
+	server := grpc.NewServer()
+	defer server.Stop()
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	require.NoError(t, serveGRPC(server, listener, params))
+
+	assert.NotContains(t, server.GetServiceInfo(), "grpc.reflection.v1alpha.ServerReflection")
+	assert.NotContains(t, server.GetServiceInfo(), "grpc.channelz.v1.Channelz")
+}
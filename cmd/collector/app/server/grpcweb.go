@@ -0,0 +1,211 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/rs/cors"
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+)
+
+const (
+	grpcWebContentType     = "application/grpc-web"
+	grpcWebTextContentType = "application/grpc-web-text"
+	grpcContentType        = "application/grpc"
+
+	// grpcWebTrailerFlag marks the frame carrying the trailers at the end of a gRPC-Web response
+	grpcWebTrailerFlag = 0x80
+)
+
+// grpcWebHeaders are the request headers sent by gRPC-Web clients, allowed on CORS requests in addition to the configured ones
+var grpcWebHeaders = []string{"Content-Type", "X-Grpc-Web", "X-User-Agent", "Grpc-Timeout", "Authorization"}
+
+// grpcWebExposedHeaders are the response headers that browsers must let gRPC-Web clients read
+var grpcWebExposedHeaders = []string{"Grpc-Status", "Grpc-Message", "Grpc-Status-Details-Bin"}
+
+// CORSOptions configures cross-origin requests on the HTTP endpoints of the collector.
+type CORSOptions struct {
+	// AllowedOrigins is a comma separated list of origins allowed to submit spans, CORS is disabled when empty
+	AllowedOrigins string
+	// AllowedHeaders is a comma separated list of request headers allowed on cross-origin requests
+	AllowedHeaders string
+}
+
+// Handler wraps h with the CORS policy, or returns h unchanged when no origin is allowed.
+func (o CORSOptions) Handler(h http.Handler, extraHeaders ...string) http.Handler {
+	if o.AllowedOrigins == "" {
+		return h
+	}
+	headers := append(splitList(o.AllowedHeaders), extraHeaders...)
+	c := cors.New(cors.Options{
+		AllowedOrigins: splitList(o.AllowedOrigins),
+		AllowedMethods: []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders: headers,
+		ExposedHeaders: grpcWebExposedHeaders,
+	})
+	return c.Handler(h)
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(strings.ReplaceAll(s, " ", ""), ",") {
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// grpcWebHandler translates gRPC-Web requests, as sent by browsers over HTTP/1.1,
+// into regular gRPC requests served by server. Only unary calls are supported,
+// which is all that span submission needs.
+func grpcWebHandler(server *grpc.Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType := r.Header.Get("Content-Type")
+		if r.Method != http.MethodPost || !strings.HasPrefix(contentType, grpcWebContentType) {
+			http.Error(w, "only gRPC-Web requests are served on this port over HTTP/1.1", http.StatusUnsupportedMediaType)
+			return
+		}
+		text := strings.HasPrefix(contentType, grpcWebTextContentType)
+
+		req := r.Clone(r.Context())
+		req.ProtoMajor, req.ProtoMinor, req.Proto = 2, 0, "HTTP/2.0"
+		req.Header.Set("Content-Type", grpcContentType+grpcWebSubtype(contentType))
+		req.Header.Del("Content-Length")
+		req.ContentLength = -1
+		if text {
+			req.Body = ioutil.NopCloser(base64.NewDecoder(base64.StdEncoding, r.Body))
+		}
+
+		rw := newGRPCWebResponseWriter(w, contentType, text)
+		server.ServeHTTP(rw, req)
+		rw.finish()
+	})
+}
+
+// grpcWebSubtype returns the codec suffix of a gRPC-Web content type, such as "+proto".
+func grpcWebSubtype(contentType string) string {
+	contentType = strings.TrimPrefix(contentType, grpcWebTextContentType)
+	contentType = strings.TrimPrefix(contentType, grpcWebContentType)
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return contentType
+}
+
+// grpcWebResponseWriter receives the response of the gRPC server and writes it
+// in the gRPC-Web format, where the trailers follow the messages as a final frame.
+type grpcWebResponseWriter struct {
+	w           http.ResponseWriter
+	body        io.Writer
+	encoder     io.WriteCloser
+	header      http.Header
+	contentType string
+	wroteHeader bool
+}
+
+func newGRPCWebResponseWriter(w http.ResponseWriter, contentType string, text bool) *grpcWebResponseWriter {
+	rw := &grpcWebResponseWriter{
+		w:           w,
+		body:        w,
+		header:      make(http.Header),
+		contentType: contentType,
+	}
+	if text {
+		rw.encoder = base64.NewEncoder(base64.StdEncoding, w)
+		rw.body = rw.encoder
+	}
+	return rw
+}
+
+func (rw *grpcWebResponseWriter) Header() http.Header {
+	return rw.header
+}
+
+func (rw *grpcWebResponseWriter) WriteHeader(code int) {
+	if rw.wroteHeader {
+		return
+	}
+	rw.wroteHeader = true
+	h := rw.w.Header()
+	for k, v := range rw.header {
+		if k == "Trailer" || strings.HasPrefix(k, http2.TrailerPrefix) {
+			continue
+		}
+		h[k] = v
+	}
+	h.Set("Content-Type", rw.contentType)
+	h.Del("Content-Length")
+	rw.w.WriteHeader(code)
+}
+
+func (rw *grpcWebResponseWriter) Write(b []byte) (int, error) {
+	rw.WriteHeader(http.StatusOK)
+	return rw.body.Write(b)
+}
+
+// Flush is required by the gRPC server; the text format is only flushed in finish,
+// because base64 output cannot be flushed in the middle of a quantum.
+func (rw *grpcWebResponseWriter) Flush() {
+	rw.WriteHeader(http.StatusOK)
+	if rw.encoder == nil {
+		if f, ok := rw.w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+}
+
+// finish writes the trailers set by the gRPC server as the last frame of the response.
+func (rw *grpcWebResponseWriter) finish() {
+	rw.WriteHeader(http.StatusOK)
+	trailers := make(http.Header)
+	for _, k := range rw.header["Trailer"] {
+		if v, ok := rw.header[http.CanonicalHeaderKey(k)]; ok {
+			trailers[k] = v
+		}
+	}
+	for k, v := range rw.header {
+		if strings.HasPrefix(k, http2.TrailerPrefix) {
+			trailers[strings.TrimPrefix(k, http2.TrailerPrefix)] = v
+		}
+	}
+
+	var buf bytes.Buffer
+	for k, vv := range trailers {
+		for _, v := range vv {
+			buf.WriteString(strings.ToLower(k) + ": " + v + "\r\n")
+		}
+	}
+
+	frame := make([]byte, 5, 5+buf.Len())
+	frame[0] = grpcWebTrailerFlag
+	binary.BigEndian.PutUint32(frame[1:], uint32(buf.Len()))
+	frame = append(frame, buf.Bytes()...)
+	rw.body.Write(frame)
+	if rw.encoder != nil {
+		rw.encoder.Close()
+	}
+	if f, ok := rw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/jaegertracing/jaeger/cmd/collector/app/handler"
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/config/tlscfg"
+	"github.com/jaegertracing/jaeger/proto-gen/api_v2"
+)
+
+func grpcWebFrame(t *testing.T, msg proto.Message) []byte {
+	data, err := proto.Marshal(msg)
+	require.NoError(t, err)
+	frame := make([]byte, 5, 5+len(data))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(data)))
+	return append(frame, data...)
+}
+
+// grpcWebTrailers returns the trailers found in the last frame of a gRPC-Web response body
+func grpcWebTrailers(t *testing.T, body []byte) map[string]string {
+	trailers := make(map[string]string)
+	for len(body) >= 5 {
+		length := binary.BigEndian.Uint32(body[1:5])
+		require.True(t, len(body) >= 5+int(length), "truncated frame")
+		if body[0] == grpcWebTrailerFlag {
+			for _, line := range strings.Split(string(body[5:5+length]), "\r\n") {
+				if kv := strings.SplitN(line, ": ", 2); len(kv) == 2 {
+					trailers[kv[0]] = kv[1]
+				}
+			}
+		}
+		body = body[5+length:]
+	}
+	return trailers
+}
+
+func startGRPCWebServer(t *testing.T, params *GRPCServerParams) (string, func()) {
+	logger := zap.NewNop()
+	params.HostPort = "localhost:0"
+	params.Handler = handler.NewGRPCHandler(logger, &mockSpanProcessor{}, nil)
+	params.SamplingStore = &mockSamplingStore{}
+	params.Logger = logger
+	params.GRPCWeb = true
+
+	opts, err := serverOptions(params)
+	require.NoError(t, err)
+	server := grpc.NewServer(opts...)
+	listener, err := net.Listen("tcp", params.HostPort)
+	require.NoError(t, err)
+	require.NoError(t, serveGRPC(server, listener, params))
+	return listener.Addr().String(), server.Stop
+}
+
+func TestGRPCWebPostSpans(t *testing.T) {
+	addr, stop := startGRPCWebServer(t, &GRPCServerParams{})
+	defer stop()
+	body := grpcWebFrame(t, &api_v2.PostSpansRequest{
+		Batch: model.Batch{Spans: []*model.Span{{OperationName: "fake-operation"}}},
+	})
+
+	testCases := []struct {
+		contentType string
+		encode      func([]byte) []byte
+		decode      func([]byte) []byte
+	}{
+		{
+			contentType: "application/grpc-web+proto",
+			encode:      func(b []byte) []byte { return b },
+			decode:      func(b []byte) []byte { return b },
+		},
+		{
+			contentType: "application/grpc-web-text",
+			encode:      func(b []byte) []byte { return []byte(base64.StdEncoding.EncodeToString(b)) },
+			decode: func(b []byte) []byte {
+				decoded, err := base64.StdEncoding.DecodeString(string(b))
+				require.NoError(t, err)
+				return decoded
+			},
+		},
+	}
+	for _, test := range testCases {
+		t.Run(test.contentType, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "http://"+addr+postSpansMethod, bytes.NewReader(test.encode(body)))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", test.contentType)
+			req.Header.Set("X-Grpc-Web", "1")
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, test.contentType, resp.Header.Get("Content-Type"))
+			respBody, err := ioutil.ReadAll(resp.Body)
+			require.NoError(t, err)
+			trailers := grpcWebTrailers(t, test.decode(respBody))
+			assert.Equal(t, "0", trailers["grpc-status"])
+		})
+	}
+
+	// regular gRPC clients share the port
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = api_v2.NewCollectorServiceClient(conn).PostSpans(context.Background(), &api_v2.PostSpansRequest{})
+	require.NoError(t, err)
+}
+
+func TestGRPCWebAuthentication(t *testing.T) {
+	authenticator, cleanup := newTestAuthenticator(t, "secret")
+	defer cleanup()
+	addr, stop := startGRPCWebServer(t, &GRPCServerParams{Authenticator: authenticator})
+	defer stop()
+
+	for token, expected := range map[string]string{"": "16", "Bearer secret": "0"} {
+		req, err := http.NewRequest(http.MethodPost, "http://"+addr+postSpansMethod, bytes.NewReader(grpcWebFrame(t, &api_v2.PostSpansRequest{})))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/grpc-web")
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		respBody, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, expected, grpcWebTrailers(t, respBody)["grpc-status"], token)
+	}
+}
+
+func TestGRPCWebCORS(t *testing.T) {
+	addr, stop := startGRPCWebServer(t, &GRPCServerParams{
+		CORS: CORSOptions{AllowedOrigins: "http://example.com"},
+	})
+	defer stop()
+
+	req, err := http.NewRequest(http.MethodOptions, "http://"+addr+postSpansMethod, nil)
+	require.NoError(t, err)
+	req.Header.Set("Origin", "http://example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "http://example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, strings.ToLower(resp.Header.Get("Access-Control-Allow-Headers")), "x-grpc-web")
+}
+
+func TestGRPCWebRejectsOtherRequests(t *testing.T) {
+	w := httptest.NewRecorder()
+	grpcWebHandler(grpc.NewServer()).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+}
+
+func TestGRPCWebWithTLS(t *testing.T) {
+	server, err := StartGRPCServer(&GRPCServerParams{
+		HostPort:  "localhost:0",
+		GRPCWeb:   true,
+		TLSConfig: tlscfg.Options{Enabled: true},
+		Logger:    zap.NewNop(),
+	})
+	assert.Nil(t, server)
+	assert.Error(t, err)
+}
+
+func TestCORSOptions(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	assert.NotNil(t, CORSOptions{}.Handler(next))
+
+	h := CORSOptions{AllowedOrigins: "*", AllowedHeaders: "content-type, x-custom"}.Handler(next)
+	req := httptest.NewRequest(http.MethodOptions, "/api/traces", nil)
+	req.Header.Set("Origin", "http://example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "x-custom")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "X-Custom", w.Header().Get("Access-Control-Allow-Headers"))
+}
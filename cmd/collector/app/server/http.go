@@ -24,9 +24,11 @@ import (
 
 	"github.com/jaegertracing/jaeger/cmd/collector/app/handler"
 	"github.com/jaegertracing/jaeger/cmd/collector/app/sampling/strategystore"
+	"github.com/jaegertracing/jaeger/pkg/auth"
 	clientcfgHandler "github.com/jaegertracing/jaeger/pkg/clientcfg/clientcfghttp"
 	"github.com/jaegertracing/jaeger/pkg/healthcheck"
 	"github.com/jaegertracing/jaeger/pkg/recoveryhandler"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
 )
 
 // HTTPServerParams to construct a new Jaeger Collector HTTP Server
@@ -37,6 +39,11 @@ type HTTPServerParams struct {
 	MetricsFactory metrics.Factory
 	HealthCheck    *healthcheck.HealthCheck
 	Logger         *zap.Logger
+	TenancyMgr     *tenancy.Manager
+	// Authenticator, if set, requires a bearer token on span submission, sampling stays public
+	Authenticator *auth.Authenticator
+	// CORS configures cross-origin span submission
+	CORS CORSOptions
 }
 
 // StartHTTPServer based on the given parameters
@@ -56,8 +63,6 @@ func StartHTTPServer(params *HTTPServerParams) (*http.Server, error) {
 
 func serveHTTP(server *http.Server, listener net.Listener, params *HTTPServerParams) {
 	r := mux.NewRouter()
-	apiHandler := handler.NewAPIHandler(params.Handler)
-	apiHandler.RegisterRoutes(r)
 
 	cfgHandler := clientcfgHandler.NewHTTPHandler(clientcfgHandler.HTTPHandlerParams{
 		ConfigManager: &clientcfgHandler.ConfigManager{
@@ -70,8 +75,18 @@ func serveHTTP(server *http.Server, listener net.Listener, params *HTTPServerPar
 	})
 	cfgHandler.RegisterRoutes(r)
 
+	// span routes are registered last, so that the authentication of their subrouter
+	// only applies to requests that did not match the sampling routes
+	spansRouter := r
+	if params.Authenticator != nil {
+		spansRouter = r.NewRoute().Subrouter()
+		spansRouter.Use(params.Authenticator.HTTPHandler)
+	}
+	apiHandler := handler.NewAPIHandler(params.Handler, params.TenancyMgr)
+	apiHandler.RegisterRoutes(spansRouter)
+
 	recoveryHandler := recoveryhandler.NewRecoveryHandler(params.Logger, true)
-	server.Handler = recoveryHandler(r)
+	server.Handler = params.CORS.Handler(recoveryHandler(r))
 	go func() {
 		if err := server.Serve(listener); err != nil {
 			if err != http.ErrServerClosed {
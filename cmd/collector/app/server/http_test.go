@@ -0,0 +1,90 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/cmd/collector/app/handler"
+	"github.com/jaegertracing/jaeger/pkg/auth"
+	"github.com/jaegertracing/jaeger/pkg/healthcheck"
+)
+
+func newTestAuthenticator(t *testing.T, token string) (*auth.Authenticator, func()) {
+	f, err := ioutil.TempFile("", "tokens")
+	require.NoError(t, err)
+	_, err = f.WriteString(token + "\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	a, err := auth.NewAuthenticator(auth.Options{TokensFile: f.Name()}, zap.NewNop(), nil)
+	require.NoError(t, err)
+	return a, func() { os.Remove(f.Name()) }
+}
+
+func TestHTTPServerAuthentication(t *testing.T) {
+	authenticator, cleanup := newTestAuthenticator(t, "secret")
+	defer cleanup()
+	logger := zap.NewNop()
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	server := &http.Server{}
+	serveHTTP(server, listener, &HTTPServerParams{
+		Handler:       handler.NewJaegerSpanHandler(logger, &mockSpanProcessor{}),
+		SamplingStore: &mockSamplingStore{},
+		HealthCheck:   healthcheck.New(),
+		Logger:        logger,
+		Authenticator: authenticator,
+	})
+	defer server.Close()
+	baseURL := "http://" + listener.Addr().String()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		code   int
+	}{
+		{name: "sampling is public", method: http.MethodGet, path: "/api/sampling?service=foo", code: http.StatusOK},
+		{name: "spans without token", method: http.MethodPost, path: "/api/traces", code: http.StatusUnauthorized},
+		{name: "spans with wrong token", method: http.MethodPost, path: "/api/traces", token: "wrong", code: http.StatusUnauthorized},
+		// the empty body is rejected by the handler after authentication
+		{name: "spans with token", method: http.MethodPost, path: "/api/traces", token: "secret", code: http.StatusBadRequest},
+		{name: "unknown route", method: http.MethodGet, path: "/unknown", token: "secret", code: http.StatusNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(test.method, baseURL+test.path, bytes.NewReader(nil))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/x-thrift")
+			if test.token != "" {
+				req.Header.Set("Authorization", "Bearer "+test.token)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, test.code, resp.StatusCode)
+		})
+	}
+}
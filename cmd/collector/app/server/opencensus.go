@@ -0,0 +1,80 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/jaegertracing/jaeger/cmd/collector/app/handler"
+	"github.com/jaegertracing/jaeger/model/converter/opencensus"
+	"github.com/jaegertracing/jaeger/pkg/auth"
+	"github.com/jaegertracing/jaeger/pkg/config/tlscfg"
+)
+
+// OpenCensusServerParams to construct the OpenCensus agent protocol receiver of the Jaeger Collector
+type OpenCensusServerParams struct {
+	TLSConfig tlscfg.Options
+	HostPort  string
+	Handler   *handler.OpenCensusHandler
+	Logger    *zap.Logger
+	// Authenticator, if set, requires a bearer token when opening export streams
+	Authenticator *auth.Authenticator
+	// MetricsFactory reports the clients rejected by the TLS allowlist, optional
+	MetricsFactory metrics.Factory
+	// Reflection registers the gRPC server reflection service on the OpenCensus receiver
+	Reflection bool
+	// Channelz registers the channelz service on the OpenCensus receiver
+	Channelz bool
+}
+
+// StartOpenCensusServer starts the OpenCensus agent protocol receiver, unless its host:port is empty
+func StartOpenCensusServer(params *OpenCensusServerParams) (*grpc.Server, error) {
+	if params.HostPort == "" {
+		return nil, nil
+	}
+
+	var opts []grpc.ServerOption
+	if params.TLSConfig.Enabled {
+		creds, err := tlsServerOption(params.TLSConfig, "opencensus", params.MetricsFactory, params.Logger)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, creds)
+	}
+	if params.Authenticator != nil {
+		opts = append(opts, grpc.StreamInterceptor(params.Authenticator.StreamServerInterceptor()))
+	}
+	server := grpc.NewServer(opts...)
+
+	listener, err := net.Listen("tcp", params.HostPort)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on OpenCensus port: %w", err)
+	}
+	opencensus.RegisterTraceServiceServer(server, params.Handler)
+	registerIntrospection(server, params.Reflection, params.Channelz)
+
+	params.Logger.Info("Starting OpenCensus receiver", zap.String("host-port", params.HostPort))
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			params.Logger.Error("Could not launch OpenCensus receiver", zap.Error(err))
+		}
+	}()
+	return server, nil
+}
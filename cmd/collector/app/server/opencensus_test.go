@@ -0,0 +1,75 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/jaegertracing/jaeger/cmd/collector/app/handler"
+	"github.com/jaegertracing/jaeger/model/converter/opencensus"
+)
+
+func TestOpenCensusServerDisabled(t *testing.T) {
+	server, err := StartOpenCensusServer(&OpenCensusServerParams{Logger: zap.NewNop()})
+	assert.NoError(t, err)
+	assert.Nil(t, server)
+}
+
+func TestOpenCensusServerFailToListen(t *testing.T) {
+	params := &OpenCensusServerParams{HostPort: ":-1", Logger: zap.NewNop()}
+	_, err := StartOpenCensusServer(params)
+	assert.EqualError(t, err, "failed to listen on OpenCensus port: listen tcp: address -1: invalid port")
+}
+
+func TestOpenCensusServerExport(t *testing.T) {
+	logger := zap.NewNop()
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	hostPort := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	server, err := StartOpenCensusServer(&OpenCensusServerParams{
+		HostPort: hostPort,
+		Handler:  handler.NewOpenCensusHandler(logger, &mockSpanProcessor{}, nil),
+		Logger:   logger,
+	})
+	require.NoError(t, err)
+	defer server.Stop()
+
+	conn, err := grpc.Dial(hostPort, grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+	stream, err := opencensus.NewTraceServiceClient(conn).Export(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&opencensus.ExportTraceServiceRequest{
+		Node: &opencensus.Node{ServiceInfo: &opencensus.ServiceInfo{Name: "frontend"}},
+		Spans: []*opencensus.Span{{
+			TraceID: []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+			SpanID:  []byte{0, 0, 0, 0, 0, 0, 0, 2},
+			Name:    &opencensus.TruncatableString{Value: "GET /"},
+		}},
+	}))
+	require.NoError(t, stream.CloseSend())
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	// OpenTelemetry SDKs compress export requests with gzip by default
+	_ "google.golang.org/grpc/encoding/gzip"
+
+	"github.com/jaegertracing/jaeger/cmd/collector/app/handler"
+	"github.com/jaegertracing/jaeger/model/converter/otlp"
+	"github.com/jaegertracing/jaeger/pkg/auth"
+	"github.com/jaegertracing/jaeger/pkg/config/tlscfg"
+	"github.com/jaegertracing/jaeger/pkg/healthcheck"
+	"github.com/jaegertracing/jaeger/pkg/recoveryhandler"
+)
+
+// OTLPServerParams to construct the OpenTelemetry protocol receivers of the Jaeger Collector
+type OTLPServerParams struct {
+	TLSConfig    tlscfg.Options
+	GRPCHostPort string
+	HTTPHostPort string
+	Handler      *handler.OTLPHandler
+	HealthCheck  *healthcheck.HealthCheck
+	Logger       *zap.Logger
+	// Authenticator, if set, requires a bearer token on every export request
+	Authenticator *auth.Authenticator
+	// MetricsFactory reports the clients rejected by the TLS allowlist, optional
+	MetricsFactory metrics.Factory
+	// Reflection registers the gRPC server reflection service on the OTLP gRPC receiver
+	Reflection bool
+	// Channelz registers the channelz service on the OTLP gRPC receiver
+	Channelz bool
+	// CORS configures cross-origin requests to the OTLP HTTP receiver
+	CORS CORSOptions
+}
+
+// StartOTLPGRPCServer starts the OTLP/gRPC receiver, unless its host:port is empty
+func StartOTLPGRPCServer(params *OTLPServerParams) (*grpc.Server, error) {
+	if params.GRPCHostPort == "" {
+		return nil, nil
+	}
+
+	var opts []grpc.ServerOption
+	if params.TLSConfig.Enabled {
+		creds, err := tlsServerOption(params.TLSConfig, "otlp_grpc", params.MetricsFactory, params.Logger)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, creds)
+	}
+	if params.Authenticator != nil {
+		opts = append(opts, grpc.UnaryInterceptor(params.Authenticator.UnaryServerInterceptor()))
+	}
+	server := grpc.NewServer(opts...)
+
+	listener, err := net.Listen("tcp", params.GRPCHostPort)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on OTLP gRPC port: %w", err)
+	}
+	otlp.RegisterTraceServiceServer(server, params.Handler)
+	registerIntrospection(server, params.Reflection, params.Channelz)
+
+	params.Logger.Info("Starting OTLP gRPC receiver", zap.String("grpc.host-port", params.GRPCHostPort))
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			params.Logger.Error("Could not launch OTLP gRPC receiver", zap.Error(err))
+		}
+	}()
+	return server, nil
+}
+
+// StartOTLPHTTPServer starts the OTLP/HTTP receiver, unless its host:port is empty
+func StartOTLPHTTPServer(params *OTLPServerParams) (*http.Server, error) {
+	if params.HTTPHostPort == "" {
+		return nil, nil
+	}
+
+	params.Logger.Info("Starting OTLP HTTP receiver", zap.String("http host-port", params.HTTPHostPort))
+	listener, err := net.Listen("tcp", params.HTTPHostPort)
+	if err != nil {
+		return nil, err
+	}
+
+	r := mux.NewRouter()
+	params.Handler.RegisterRoutes(r)
+	var handler http.Handler = r
+	if params.Authenticator != nil {
+		handler = params.Authenticator.HTTPHandler(handler)
+	}
+	recoveryHandler := recoveryhandler.NewRecoveryHandler(params.Logger, true)
+	server := &http.Server{Addr: params.HTTPHostPort, Handler: params.CORS.Handler(recoveryHandler(handler))}
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			if err != http.ErrServerClosed {
+				params.Logger.Fatal("Could not start OTLP HTTP receiver", zap.Error(err))
+			}
+		}
+		params.HealthCheck.Set(healthcheck.Unavailable)
+	}()
+	return server, nil
+}
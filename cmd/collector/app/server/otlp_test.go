@@ -0,0 +1,61 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/cmd/collector/app/handler"
+	"github.com/jaegertracing/jaeger/pkg/healthcheck"
+)
+
+func TestOTLPServersDisabled(t *testing.T) {
+	params := &OTLPServerParams{Logger: zap.NewNop()}
+	grpcServer, err := StartOTLPGRPCServer(params)
+	assert.NoError(t, err)
+	assert.Nil(t, grpcServer)
+	httpServer, err := StartOTLPHTTPServer(params)
+	assert.NoError(t, err)
+	assert.Nil(t, httpServer)
+}
+
+func TestOTLPServersStart(t *testing.T) {
+	logger := zap.NewNop()
+	params := &OTLPServerParams{
+		GRPCHostPort: ":0",
+		HTTPHostPort: ":0",
+		Handler:      handler.NewOTLPHandler(logger, &mockSpanProcessor{}, nil),
+		HealthCheck:  healthcheck.New(),
+		Logger:       logger,
+	}
+	grpcServer, err := StartOTLPGRPCServer(params)
+	require.NoError(t, err)
+	defer grpcServer.Stop()
+	httpServer, err := StartOTLPHTTPServer(params)
+	require.NoError(t, err)
+	defer httpServer.Close()
+}
+
+func TestOTLPServersFailToListen(t *testing.T) {
+	params := &OTLPServerParams{GRPCHostPort: ":-1", HTTPHostPort: ":-1", Logger: zap.NewNop()}
+	_, err := StartOTLPGRPCServer(params)
+	assert.EqualError(t, err, "failed to listen on OTLP gRPC port: listen tcp: address -1: invalid port")
+	_, err = StartOTLPHTTPServer(params)
+	assert.Error(t, err)
+}
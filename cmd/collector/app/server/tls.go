@@ -0,0 +1,45 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/jaegertracing/jaeger/pkg/config/tlscfg"
+)
+
+// tlsServerOption returns the gRPC credentials of a TLS server, counting the clients
+// rejected by the allowlist of client certificates under the given endpoint name.
+func tlsServerOption(opts tlscfg.Options, endpoint string, metricsFactory metrics.Factory, logger *zap.Logger) (grpc.ServerOption, error) {
+	tlsCfg, err := opts.Config()
+	if err != nil {
+		return nil, err
+	}
+	if metricsFactory == nil {
+		metricsFactory = metrics.NullFactory
+	}
+	rejected := metricsFactory.Counter(metrics.Options{
+		Name: "tls_rejected_clients",
+		Tags: map[string]string{"endpoint": endpoint},
+	})
+	tlscfg.OnRejectedClient(tlsCfg, func(err error) {
+		rejected.Inc(1)
+		logger.Warn("Rejected TLS client", zap.String("endpoint", endpoint), zap.Error(err))
+	})
+	return grpc.Creds(credentials.NewTLS(tlsCfg)), nil
+}
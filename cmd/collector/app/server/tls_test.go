@@ -0,0 +1,40 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/pkg/config/tlscfg"
+)
+
+func TestTLSServerOption(t *testing.T) {
+	_, err := tlsServerOption(tlscfg.Options{Enabled: true, ClientAllowedOUs: []string{"tracing"}}, "grpc", nil, zap.NewNop())
+	assert.EqualError(t, err, "an allowlist of client certificates requires a client CA")
+
+	opt, err := tlsServerOption(tlscfg.Options{
+		Enabled:           true,
+		CertPath:          "../../../../pkg/config/tlscfg/testdata/test-cert.pem",
+		KeyPath:           "../../../../pkg/config/tlscfg/testdata/test-key.pem",
+		ClientCAPath:      "../../../../pkg/config/tlscfg/testdata/testCA.pem",
+		ClientAllowedSANs: []string{"client.jaeger.io"},
+	}, "grpc", nil, zap.NewNop())
+	require.NoError(t, err)
+	assert.NotNil(t, opt)
+}
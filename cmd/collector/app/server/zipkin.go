@@ -25,8 +25,10 @@ import (
 
 	"github.com/jaegertracing/jaeger/cmd/collector/app/handler"
 	"github.com/jaegertracing/jaeger/cmd/collector/app/zipkin"
+	"github.com/jaegertracing/jaeger/pkg/auth"
 	"github.com/jaegertracing/jaeger/pkg/healthcheck"
 	"github.com/jaegertracing/jaeger/pkg/recoveryhandler"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
 )
 
 // ZipkinServerParams to construct a new Jaeger Collector Zipkin Server
@@ -37,6 +39,9 @@ type ZipkinServerParams struct {
 	AllowedHeaders string
 	HealthCheck    *healthcheck.HealthCheck
 	Logger         *zap.Logger
+	TenancyMgr     *tenancy.Manager
+	// Authenticator, if set, requires a bearer token on every span submission
+	Authenticator *auth.Authenticator
 }
 
 // StartZipkinServer based on the given parameters
@@ -60,7 +65,7 @@ func StartZipkinServer(params *ZipkinServerParams) (*http.Server, error) {
 
 func serveZipkin(server *http.Server, listener net.Listener, params *ZipkinServerParams) {
 	r := mux.NewRouter()
-	zHandler := zipkin.NewAPIHandler(params.Handler)
+	zHandler := zipkin.NewAPIHandler(params.Handler, params.TenancyMgr)
 	zHandler.RegisterRoutes(r)
 
 	origins := strings.Split(strings.ReplaceAll(params.AllowedOrigins, " ", ""), ",")
@@ -72,8 +77,12 @@ func serveZipkin(server *http.Server, listener net.Listener, params *ZipkinServe
 		AllowedHeaders: headers,
 	})
 
+	var handler http.Handler = r
+	if params.Authenticator != nil {
+		handler = params.Authenticator.HTTPHandler(handler)
+	}
 	recoveryHandler := recoveryhandler.NewRecoveryHandler(params.Logger, true)
-	server.Handler = cors.Handler(recoveryHandler(r))
+	server.Handler = cors.Handler(recoveryHandler(handler))
 	go func(listener net.Listener, server *http.Server) {
 		if err := server.Serve(listener); err != nil {
 			params.Logger.Fatal("Could not launch Zipkin server", zap.Error(err))
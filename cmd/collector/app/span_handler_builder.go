@@ -21,10 +21,14 @@ import (
 	"github.com/uber/jaeger-lib/metrics"
 	"go.uber.org/zap"
 
+	"github.com/jaegertracing/jaeger/cmd/collector/app/dropped"
 	"github.com/jaegertracing/jaeger/cmd/collector/app/handler"
 	"github.com/jaegertracing/jaeger/cmd/collector/app/processor"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/sanitizer"
 	zs "github.com/jaegertracing/jaeger/cmd/collector/app/sanitizer/zipkin"
 	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/queue"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
 	"github.com/jaegertracing/jaeger/storage/spanstore"
 )
 
@@ -34,6 +38,28 @@ type SpanHandlerBuilder struct {
 	CollectorOpts  CollectorOptions
 	Logger         *zap.Logger
 	MetricsFactory metrics.Factory
+	// SpanFilter rejects spans before they are queued, all spans are accepted when nil
+	SpanFilter FilterSpan
+	// Deduplicator drops the duplicates of spans received shortly before, optional
+	Deduplicator FilterSpan
+	// ForgetDuplicate forgets the spans kept by the Deduplicator that could not be queued, optional
+	ForgetDuplicate ProcessSpan
+	// Sanitizer is applied to every span before it is saved, optional
+	Sanitizer sanitizer.SanitizeSpan
+	// PersistentQueue replaces the in-memory queue of the span processor when set
+	PersistentQueue *queue.PersistentQueue
+	// RateLimiter rejects the spans of the services exceeding their ingestion limits, optional
+	RateLimiter LimitSpans
+	// TenantQuota rejects or throttles batches exceeding the ingestion quota of their tenant, optional
+	TenantQuota LimitTenantSpans
+	// TenancyMgr validates the tenant of incoming requests, tenancy is disabled when nil
+	TenancyMgr *tenancy.Manager
+	// PreSave is called on every span before it is saved, optional
+	PreSave ProcessSpan
+	// DroppedSpans records the spans dropped by the span processor per service, optional
+	DroppedSpans *dropped.Tracker
+	// EnrichSpans adds the client metadata to the batches accepted by the span processor, optional
+	EnrichSpans EnrichSpans
 }
 
 // SpanHandlers holds instances to the span handlers built by the SpanHandlerBuilder
@@ -41,6 +67,8 @@ type SpanHandlers struct {
 	ZipkinSpansHandler   handler.ZipkinSpansHandler
 	JaegerBatchesHandler handler.JaegerBatchesHandler
 	GRPCHandler          *handler.GRPCHandler
+	OTLPHandler          *handler.OTLPHandler
+	OpenCensusHandler    *handler.OpenCensusHandler
 }
 
 // BuildSpanProcessor builds the span processor to be used with the handlers
@@ -54,22 +82,33 @@ func (b *SpanHandlerBuilder) BuildSpanProcessor() processor.SpanProcessor {
 		Options.ServiceMetrics(svcMetrics),
 		Options.HostMetrics(hostMetrics),
 		Options.Logger(b.logger()),
-		Options.SpanFilter(defaultSpanFilter),
+		Options.SpanFilter(b.spanFilter()),
+		Options.Deduplicator(b.Deduplicator),
+		Options.ForgetDuplicate(b.ForgetDuplicate),
+		Options.Sanitizer(b.Sanitizer),
 		Options.NumWorkers(b.CollectorOpts.NumWorkers),
 		Options.QueueSize(b.CollectorOpts.QueueSize),
 		Options.CollectorTags(b.CollectorOpts.CollectorTags),
 		Options.DynQueueSizeWarmup(uint(b.CollectorOpts.QueueSize)), // same as queue size for now
 		Options.DynQueueSizeMemory(b.CollectorOpts.DynQueueSizeMemory),
+		Options.PersistentQueue(b.PersistentQueue),
+		Options.RateLimiter(b.RateLimiter),
+		Options.TenantQuota(b.TenantQuota),
+		Options.DroppedSpans(b.DroppedSpans),
+		Options.PreSave(b.PreSave),
+		Options.EnrichSpans(b.EnrichSpans),
 	)
 
 }
 
-// BuildHandlers builds span handlers (Zipkin, Jaeger)
+// BuildHandlers builds span handlers (Zipkin, Jaeger, OTLP, OpenCensus)
 func (b *SpanHandlerBuilder) BuildHandlers(spanProcessor processor.SpanProcessor) *SpanHandlers {
 	return &SpanHandlers{
 		handler.NewZipkinSpanHandler(b.Logger, spanProcessor, zs.NewChainedSanitizer(zs.StandardSanitizers...)),
 		handler.NewJaegerSpanHandler(b.Logger, spanProcessor),
-		handler.NewGRPCHandler(b.Logger, spanProcessor),
+		handler.NewGRPCHandler(b.Logger, spanProcessor, b.TenancyMgr),
+		handler.NewOTLPHandler(b.Logger, spanProcessor, b.TenancyMgr),
+		handler.NewOpenCensusHandler(b.Logger, spanProcessor, b.TenancyMgr),
 	}
 }
 
@@ -77,6 +116,13 @@ func defaultSpanFilter(*model.Span) bool {
 	return true
 }
 
+func (b *SpanHandlerBuilder) spanFilter() FilterSpan {
+	if b.SpanFilter == nil {
+		return defaultSpanFilter
+	}
+	return b.SpanFilter
+}
+
 func (b *SpanHandlerBuilder) logger() *zap.Logger {
 	if b.Logger == nil {
 		return zap.NewNop()
@@ -24,6 +24,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/jaegertracing/jaeger/cmd/flags"
+	"github.com/jaegertracing/jaeger/model"
 	"github.com/jaegertracing/jaeger/pkg/config"
 	"github.com/jaegertracing/jaeger/plugin/storage/memory"
 )
@@ -42,6 +43,10 @@ func TestNewSpanHandlerBuilder(t *testing.T) {
 	}
 	assert.NotNil(t, builder.logger())
 	assert.NotNil(t, builder.metricsFactory())
+	assert.True(t, builder.spanFilter()(nil))
+
+	builder.SpanFilter = func(*model.Span) bool { return false }
+	assert.False(t, builder.spanFilter()(nil))
 
 	builder = &SpanHandlerBuilder{
 		SpanWriter:     spanWriter,
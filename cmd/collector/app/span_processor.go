@@ -16,6 +16,9 @@
 package app
 
 import (
+	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -23,10 +26,12 @@ import (
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
 
+	"github.com/jaegertracing/jaeger/cmd/collector/app/dropped"
 	"github.com/jaegertracing/jaeger/cmd/collector/app/processor"
 	"github.com/jaegertracing/jaeger/cmd/collector/app/sanitizer"
 	"github.com/jaegertracing/jaeger/model"
 	"github.com/jaegertracing/jaeger/pkg/queue"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
 	"github.com/jaegertracing/jaeger/storage/spanstore"
 )
 
@@ -36,14 +41,26 @@ const (
 
 	// if the new queue size isn't 20% bigger than the previous one, don't change
 	minRequiredChange = 1.2
+
+	// how often Drain checks whether the queue is empty
+	drainPollInterval = 50 * time.Millisecond
 )
 
 type spanProcessor struct {
 	queue              *queue.BoundedQueue
+	persistentQueue    *queue.PersistentQueue // used instead of queue when set
 	queueResizeMu      sync.Mutex
 	metrics            *SpanProcessorMetrics
 	preProcessSpans    ProcessSpans
 	filterSpan         FilterSpan             // filter is called before the sanitizer but after preProcessSpans
+	deduplicate        FilterSpan             // deduplicate is called on the spans accepted by filterSpan
+	forgetDuplicate    ProcessSpan            // forgetDuplicate is called on the deduplicated spans that could not be queued, optional
+	rateLimiter        LimitSpans             // rateLimiter is called on the whole batch before filtering, nil results accept all spans
+	tenantQuota        LimitTenantSpans       // tenantQuota is called on the batches accepted by the rateLimiter
+	droppedSpans       *dropped.Tracker       // droppedSpans counts spans dropped by service
+	enrichSpans        EnrichSpans            // enrichSpans is called on the batches accepted by the rateLimiter
+	draining           *atomic.Bool           // draining rejects new spans once set
+	inFlight           *atomic.Int64          // inFlight counts spans accepted by the in-memory queue and not yet processed
 	sanitizer          sanitizer.SanitizeSpan // sanitizer is called before processSpan
 	processSpan        ProcessSpan
 	logger             *zap.Logger
@@ -56,6 +73,10 @@ type spanProcessor struct {
 	bytesProcessed     *atomic.Uint64
 	spansProcessed     *atomic.Uint64
 	stopCh             chan struct{}
+
+	// counters at the previous resize of the queue, guarded by queueResizeMu
+	lastBytesProcessed uint64
+	lastSpansProcessed uint64
 }
 
 type queueItem struct {
@@ -70,14 +91,27 @@ func NewSpanProcessor(
 ) processor.SpanProcessor {
 	sp := newSpanProcessor(spanWriter, opts...)
 
-	sp.queue.StartConsumers(sp.numWorkers, func(item interface{}) {
-		value := item.(*queueItem)
-		sp.processItemFromQueue(value)
-	})
+	if sp.persistentQueue != nil {
+		sp.persistentQueue.StartConsumers(sp.numWorkers, func(data []byte) {
+			item, err := decodeQueueItem(data)
+			if err != nil {
+				sp.logger.Error("Failed to decode span from the persistent queue", zap.Error(err))
+				sp.metrics.SpansDropped.Inc(1)
+				return
+			}
+			sp.processItemFromQueue(item)
+		})
+	} else {
+		sp.queue.StartConsumers(sp.numWorkers, func(item interface{}) {
+			value := item.(*queueItem)
+			sp.processItemFromQueue(value)
+			sp.inFlight.Dec()
+		})
+	}
 
 	sp.background(1*time.Second, sp.updateGauges)
 
-	if sp.dynQueueSizeMemory > 0 {
+	if sp.dynQueueSizeMemory > 0 && sp.persistentQueue == nil {
 		sp.background(1*time.Minute, sp.updateQueueSize)
 	}
 
@@ -92,15 +126,25 @@ func newSpanProcessor(spanWriter spanstore.Writer, opts ...Option) *spanProcesso
 		options.extraFormatTypes)
 	droppedItemHandler := func(item interface{}) {
 		handlerMetrics.SpansDropped.Inc(1)
+		if qi, ok := item.(*queueItem); ok {
+			options.droppedSpans.RecordSpan(qi.span, dropped.QueueFull)
+		}
 	}
 	boundedQueue := queue.NewBoundedQueue(options.queueSize, droppedItemHandler)
 
 	sp := spanProcessor{
 		queue:              boundedQueue,
+		persistentQueue:    options.persistentQueue,
 		metrics:            handlerMetrics,
 		logger:             options.logger,
 		preProcessSpans:    options.preProcessSpans,
 		filterSpan:         options.spanFilter,
+		deduplicate:        options.deduplicator,
+		forgetDuplicate:    options.forgetDuplicate,
+		rateLimiter:        options.rateLimiter,
+		tenantQuota:        options.tenantQuota,
+		droppedSpans:       options.droppedSpans,
+		enrichSpans:        options.enrichSpans,
 		sanitizer:          options.sanitizer,
 		reportBusy:         options.reportBusy,
 		numWorkers:         options.numWorkers,
@@ -111,6 +155,8 @@ func newSpanProcessor(spanWriter spanstore.Writer, opts ...Option) *spanProcesso
 		dynQueueSizeWarmup: options.dynQueueSizeWarmup,
 		bytesProcessed:     atomic.NewUint64(0),
 		spansProcessed:     atomic.NewUint64(0),
+		draining:           atomic.NewBool(false),
+		inFlight:           atomic.NewInt64(0),
 	}
 
 	processSpanFuncs := []ProcessSpan{options.preSave, sp.saveSpan}
@@ -129,10 +175,48 @@ func newSpanProcessor(spanWriter spanstore.Writer, opts ...Option) *spanProcesso
 func (sp *spanProcessor) Close() error {
 	close(sp.stopCh)
 	sp.queue.Stop()
+	if sp.persistentQueue != nil {
+		return sp.persistentQueue.Stop()
+	}
 
 	return nil
 }
 
+// Drain stops accepting new spans and blocks until the queued spans are processed or the context is done.
+func (sp *spanProcessor) Drain(ctx context.Context) error {
+	sp.draining.Store(true)
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for sp.pendingSpans() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// Resume accepts new spans again after Drain.
+func (sp *spanProcessor) Resume() {
+	sp.draining.Store(false)
+}
+
+// pendingSpans returns the number of spans waiting in the queue or being processed
+func (sp *spanProcessor) pendingSpans() int {
+	if sp.persistentQueue != nil {
+		return sp.persistentQueue.Pending()
+	}
+	return int(sp.inFlight.Load())
+}
+
+// deferredWriter is a span writer buffering the spans, e.g. the clock skew writer, which
+// reports whether each span was saved once it is written to the next writer. The spans
+// dropped on purpose, e.g. by tail sampling, are reported with processor.ErrSpanDropped.
+type deferredWriter interface {
+	WriteSpanDeferred(span *model.Span, saved func(err error))
+}
+
 func (sp *spanProcessor) saveSpan(span *model.Span) {
 	if nil == span.Process {
 		sp.logger.Error("process is empty for the span")
@@ -141,13 +225,23 @@ func (sp *spanProcessor) saveSpan(span *model.Span) {
 	}
 
 	startTime := time.Now()
-	if err := sp.spanWriter.WriteSpan(span); err != nil {
-		sp.logger.Error("Failed to save span", zap.Error(err))
-		sp.metrics.SavedErrBySvc.ReportServiceNameForSpan(span)
+	saved := func(err error) {
+		if errors.Is(err, processor.ErrSpanDropped) {
+			sp.logger.Debug("Span dropped by the collector",
+				zap.Stringer("trace-id", span.TraceID), zap.Stringer("span-id", span.SpanID))
+		} else if err != nil {
+			sp.logger.Error("Failed to save span", zap.Error(err))
+			sp.metrics.SavedErrBySvc.ReportServiceNameForSpan(span)
+		} else {
+			sp.logger.Debug("Span written to the storage by the collector",
+				zap.Stringer("trace-id", span.TraceID), zap.Stringer("span-id", span.SpanID))
+			sp.metrics.SavedOkBySvc.ReportServiceNameForSpan(span)
+		}
+	}
+	if w, ok := sp.spanWriter.(deferredWriter); ok {
+		w.WriteSpanDeferred(span, saved)
 	} else {
-		sp.logger.Debug("Span written to the storage by the collector",
-			zap.Stringer("trace-id", span.TraceID), zap.Stringer("span-id", span.SpanID))
-		sp.metrics.SavedOkBySvc.ReportServiceNameForSpan(span)
+		saved(sp.spanWriter.WriteSpan(span))
 	}
 	sp.metrics.SaveLatency.Record(time.Since(startTime))
 }
@@ -160,9 +254,30 @@ func (sp *spanProcessor) countSpan(span *model.Span) {
 func (sp *spanProcessor) ProcessSpans(mSpans []*model.Span, options processor.SpansOptions) ([]bool, error) {
 	sp.preProcessSpans(mSpans)
 	sp.metrics.BatchSize.Update(int64(len(mSpans)))
+	if sp.draining.Load() {
+		return nil, processor.ErrDraining
+	}
+	accepted := mSpans
+	allowed := sp.rateLimiter(mSpans)
+	if allowed != nil {
+		var err error
+		if accepted, err = sp.dropRateLimited(mSpans, allowed); err != nil {
+			return nil, err
+		}
+	}
+	if !sp.tenantQuota(options.Tenant, accepted) {
+		for _, span := range accepted {
+			sp.droppedSpans.RecordSpan(span, dropped.QuotaExceeded)
+		}
+		return nil, processor.ErrRateLimited
+	}
+	sp.enrichSpans(accepted, options.Client)
 	retMe := make([]bool, len(mSpans))
 	for i, mSpan := range mSpans {
-		ok := sp.enqueueSpan(mSpan, options.SpanFormat, options.InboundTransport)
+		if allowed != nil && !allowed[i] {
+			continue
+		}
+		ok := sp.enqueueSpan(mSpan, options.SpanFormat, options.InboundTransport, options.Tenant)
 		if !ok && sp.reportBusy {
 			return nil, fmt.Errorf("server busy")
 		}
@@ -171,6 +286,23 @@ func (sp *spanProcessor) ProcessSpans(mSpans []*model.Span, options processor.Sp
 	return retMe, nil
 }
 
+// dropRateLimited returns the spans within the rate limits of their services,
+// and rejects the batch if there are none.
+func (sp *spanProcessor) dropRateLimited(mSpans []*model.Span, allowed []bool) ([]*model.Span, error) {
+	kept := make([]*model.Span, 0, len(mSpans))
+	for i, span := range mSpans {
+		if allowed[i] {
+			kept = append(kept, span)
+		} else {
+			sp.droppedSpans.RecordSpan(span, dropped.RateLimited)
+		}
+	}
+	if len(kept) == 0 && len(mSpans) > 0 {
+		return nil, processor.ErrRateLimited
+	}
+	return kept, nil
+}
+
 func (sp *spanProcessor) processItemFromQueue(item *queueItem) {
 	sp.processSpan(sp.sanitizer(item.span))
 	sp.metrics.InQueueLatency.Record(time.Since(item.queuedTime))
@@ -183,14 +315,25 @@ func (sp *spanProcessor) addCollectorTags(span *model.Span) {
 	}
 }
 
-func (sp *spanProcessor) enqueueSpan(span *model.Span, originalFormat processor.SpanFormat, transport processor.InboundTransport) bool {
+func (sp *spanProcessor) enqueueSpan(span *model.Span, originalFormat processor.SpanFormat, transport processor.InboundTransport, tenant string) bool {
 	spanCounts := sp.metrics.GetCountsForFormat(originalFormat, transport)
 	spanCounts.ReceivedBySvc.ReportServiceNameForSpan(span)
 
 	if !sp.filterSpan(span) {
 		spanCounts.RejectedBySvc.ReportServiceNameForSpan(span)
+		sp.droppedSpans.RecordSpan(span, dropped.Rejected)
 		return true // as in "not dropped", because it's actively rejected
 	}
+	if !sp.deduplicate(span) {
+		spanCounts.DeduplicatedBySvc.ReportServiceNameForSpan(span)
+		sp.droppedSpans.RecordSpan(span, dropped.Deduplicated)
+		return true
+	}
+	var original *model.Span
+	if sp.forgetDuplicate != nil {
+		// the tags are appended below, the copy keeps the content hashed by the deduplicator
+		original = shallowCopy(span)
+	}
 
 	//add format tag
 	span.Tags = append(span.Tags, model.String("internal.span.format", string(originalFormat)))
@@ -198,11 +341,80 @@ func (sp *spanProcessor) enqueueSpan(span *model.Span, originalFormat processor.
 	// append the collector tags
 	sp.addCollectorTags(span)
 
+	if tenant != "" {
+		tenancy.SetSpanTenant(span, tenant)
+	} else {
+		tenancy.RemoveSpanTenant(span)
+	}
+
+	if !sp.produce(span) {
+		if original != nil {
+			sp.forgetDuplicate(original)
+		}
+		return false
+	}
+	return true
+}
+
+// shallowCopy returns a copy of the span and of its process, sharing their tags.
+func shallowCopy(span *model.Span) *model.Span {
+	copied := *span
+	if span.Process != nil {
+		process := *span.Process
+		copied.Process = &process
+	}
+	return &copied
+}
+
+// produce adds the span to the queue, and returns false if the queue is full.
+func (sp *spanProcessor) produce(span *model.Span) bool {
 	item := &queueItem{
 		queuedTime: time.Now(),
 		span:       span,
 	}
-	return sp.queue.Produce(item)
+	if sp.persistentQueue != nil {
+		data, err := encodeQueueItem(item)
+		if err != nil {
+			sp.logger.Error("Failed to encode span for the persistent queue", zap.Error(err))
+			sp.metrics.SpansDropped.Inc(1)
+			return false
+		}
+		if !sp.persistentQueue.Produce(data) {
+			sp.droppedSpans.RecordSpan(span, dropped.QueueFull)
+			return false
+		}
+		return true
+	}
+	sp.inFlight.Inc()
+	if !sp.queue.Produce(item) {
+		sp.inFlight.Dec()
+		return false
+	}
+	return true
+}
+
+// encodeQueueItem serializes the item as the enqueue time in nanoseconds followed by the span in protobuf
+func encodeQueueItem(item *queueItem) ([]byte, error) {
+	data := make([]byte, 8+item.span.Size())
+	binary.BigEndian.PutUint64(data, uint64(item.queuedTime.UnixNano()))
+	if _, err := item.span.MarshalTo(data[8:]); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func decodeQueueItem(data []byte) (*queueItem, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("persistent queue item too short: %d bytes", len(data))
+	}
+	span := &model.Span{}
+	if err := span.Unmarshal(data[8:]); err != nil {
+		return nil, err
+	}
+	return &queueItem{
+		queuedTime: time.Unix(0, int64(binary.BigEndian.Uint64(data))),
+		span:       span,
+	}, nil
 }
 
 func (sp *spanProcessor) background(reportPeriod time.Duration, callback func()) {
@@ -229,15 +441,22 @@ func (sp *spanProcessor) updateQueueSize() {
 		return
 	}
 
-	if sp.spansProcessed.Load() < uint64(sp.dynQueueSizeWarmup) {
-		return
-	}
-
 	sp.queueResizeMu.Lock()
 	defer sp.queueResizeMu.Unlock()
 
-	// first, we get the average size of a span, by dividing the bytes processed by num of spans
-	average := sp.bytesProcessed.Load() / sp.spansProcessed.Load()
+	// first, we get the average size of a span, by dividing the bytes processed by num of spans.
+	// Only the spans seen since the previous resize are considered, so that the queue follows
+	// the drift of the span sizes, and at least warmup spans are needed to get a stable average.
+	bytesProcessed, spansProcessed := sp.bytesProcessed.Load(), sp.spansProcessed.Load()
+	spans := spansProcessed - sp.lastSpansProcessed
+	if spans == 0 || spans < uint64(sp.dynQueueSizeWarmup) {
+		return
+	}
+	average := (bytesProcessed - sp.lastBytesProcessed) / spans
+	sp.lastBytesProcessed, sp.lastSpansProcessed = bytesProcessed, spansProcessed
+	if average == 0 {
+		return
+	}
 
 	// finally, we divide the available memory by the average size of a span
 	idealQueueSize := float64(sp.dynQueueSizeMemory / uint(average))
@@ -265,6 +484,10 @@ func (sp *spanProcessor) updateQueueSize() {
 
 func (sp *spanProcessor) updateGauges() {
 	sp.metrics.SpansBytes.Update(int64(sp.bytesProcessed.Load()))
+	if sp.persistentQueue != nil {
+		sp.metrics.QueueLength.Update(int64(sp.persistentQueue.Size()))
+		return
+	}
 	sp.metrics.QueueLength.Update(int64(sp.queue.Size()))
 	sp.metrics.QueueCapacity.Update(int64(sp.queue.Capacity()))
 }
@@ -16,22 +16,31 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/uber/jaeger-lib/metrics"
 	"github.com/uber/jaeger-lib/metrics/metricstest"
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
 
+	"github.com/jaegertracing/jaeger/cmd/collector/app/clockskew"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/dedup"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/dropped"
 	"github.com/jaegertracing/jaeger/cmd/collector/app/handler"
 	"github.com/jaegertracing/jaeger/cmd/collector/app/processor"
 	zipkinSanitizer "github.com/jaegertracing/jaeger/cmd/collector/app/sanitizer/zipkin"
+	"github.com/jaegertracing/jaeger/cmd/collector/app/tailsampling"
 	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/queue"
 	"github.com/jaegertracing/jaeger/pkg/testutils"
 	"github.com/jaegertracing/jaeger/thrift-gen/jaeger"
 	zc "github.com/jaegertracing/jaeger/thrift-gen/zipkincore"
@@ -269,6 +278,50 @@ func TestSpanProcessorErrors(t *testing.T) {
 	mb.AssertCounterMetrics(t, expected...)
 }
 
+func TestSpanProcessorDeferredWriter(t *testing.T) {
+	w := clockskew.NewWriter(&fakeSpanWriter{err: fmt.Errorf("some-error")},
+		clockskew.Options{MaxAdjustment: time.Second, BufferWait: time.Hour}, zap.NewNop(), metrics.NullFactory)
+	mb := metricstest.NewFactory(time.Hour)
+	p := NewSpanProcessor(w,
+		Options.ServiceMetrics(mb.Namespace(metrics.NSOptions{Name: "service", Tags: nil})),
+		Options.QueueSize(1),
+	).(*spanProcessor)
+
+	_, err := p.ProcessSpans([]*model.Span{{Process: &model.Process{ServiceName: "x"}}},
+		processor.SpansOptions{SpanFormat: processor.JaegerSpanFormat})
+	require.NoError(t, err)
+	assert.NoError(t, p.Close())
+	counters, _ := mb.Snapshot()
+	for name := range counters {
+		assert.NotContains(t, name, "saved-by-svc", "the spans are not saved until the clock skew writer is flushed")
+	}
+
+	assert.Error(t, w.Close())
+	mb.AssertCounterMetrics(t, metricstest.ExpectedMetric{
+		Name: "service.spans.saved-by-svc|debug=false|result=err|svc=x", Value: 1,
+	})
+}
+
+func TestSpanProcessorTailSampledDrops(t *testing.T) {
+	w := tailsampling.NewProcessor(&fakeSpanWriter{}, tailsampling.Options{DecisionWait: time.Hour, SampleErrors: true},
+		zap.NewNop(), metrics.NullFactory)
+	mb := metricstest.NewFactory(time.Hour)
+	p := NewSpanProcessor(w,
+		Options.ServiceMetrics(mb.Namespace(metrics.NSOptions{Name: "service", Tags: nil})),
+		Options.QueueSize(1),
+	).(*spanProcessor)
+
+	_, err := p.ProcessSpans([]*model.Span{{Process: &model.Process{ServiceName: "x"}}},
+		processor.SpansOptions{SpanFormat: processor.JaegerSpanFormat})
+	require.NoError(t, err)
+	assert.NoError(t, p.Close())
+	assert.NoError(t, w.Close())
+	counters, _ := mb.Snapshot()
+	for name := range counters {
+		assert.NotContains(t, name, "saved-by-svc", "the spans of traces not sampled are neither saved nor failed")
+	}
+}
+
 type blockingWriter struct {
 	sync.Mutex
 }
@@ -474,6 +527,29 @@ func TestUpdateDynQueueSize(t *testing.T) {
 	}
 }
 
+func TestUpdateDynQueueSizeFollowsDrift(t *testing.T) {
+	oneMiB := uint(1024 * 1024)
+	p := newSpanProcessor(&fakeSpanWriter{}, Options.QueueSize(100), Options.DynQueueSizeWarmup(1000), Options.DynQueueSizeMemory(oneMiB))
+
+	// 1000 spans of 1KiB
+	p.spansProcessed.Store(1000)
+	p.bytesProcessed.Store(1000 * 1024)
+	p.updateQueueSize()
+	assert.EqualValues(t, 1024, p.queue.Capacity())
+
+	// too few new spans to compute a new average
+	p.spansProcessed.Store(1500)
+	p.bytesProcessed.Store(1000*1024 + 500*10*1024)
+	p.updateQueueSize()
+	assert.EqualValues(t, 1024, p.queue.Capacity())
+
+	// spans grew to 10KiB, the lifetime average would be 7KiB
+	p.spansProcessed.Store(2000)
+	p.bytesProcessed.Store(1000*1024 + 1000*10*1024)
+	p.updateQueueSize()
+	assert.EqualValues(t, 102, p.queue.Capacity())
+}
+
 func TestUpdateQueueSizeNoActivityYet(t *testing.T) {
 	w := &fakeSpanWriter{}
 	p := newSpanProcessor(w, Options.QueueSize(1), Options.DynQueueSizeWarmup(1), Options.DynQueueSizeMemory(1))
@@ -504,3 +580,309 @@ func TestStartDynQueueSizeUpdater(t *testing.T) {
 
 	assert.EqualValues(t, 104857, p.queue.Capacity())
 }
+
+type recordingSpanWriter struct {
+	lock  sync.Mutex
+	spans []*model.Span
+}
+
+func (w *recordingSpanWriter) WriteSpan(span *model.Span) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.spans = append(w.spans, span)
+	return nil
+}
+
+func (w *recordingSpanWriter) count() int {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return len(w.spans)
+}
+
+func TestSpanProcessorWithPersistentQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "collector-queue")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	openQueue := func() *queue.PersistentQueue {
+		q, err := queue.NewPersistentQueue(queue.PersistentQueueOptions{Directory: dir}, nil)
+		require.NoError(t, err)
+		return q
+	}
+
+	// spans queued while the processor has no workers left are replayed by the next instance
+	q := openQueue()
+	p := newSpanProcessor(&fakeSpanWriter{}, Options.PersistentQueue(q))
+	res, err := p.ProcessSpans([]*model.Span{
+		{OperationName: "a", Process: &model.Process{ServiceName: "x"}},
+		{OperationName: "b", Process: &model.Process{ServiceName: "x"}},
+	}, processor.SpansOptions{SpanFormat: processor.JaegerSpanFormat})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, true}, res)
+	assert.Equal(t, 2, q.Size())
+	require.NoError(t, p.Close())
+
+	w := &recordingSpanWriter{}
+	p2 := NewSpanProcessor(w, Options.PersistentQueue(openQueue()), Options.NumWorkers(1)).(*spanProcessor)
+	for i := 0; i < 100 && w.count() < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Equal(t, 2, w.count())
+	assert.Equal(t, "a", w.spans[0].OperationName)
+	assert.Equal(t, "x", w.spans[0].Process.ServiceName)
+	assert.NoError(t, p2.Close())
+}
+
+func TestDecodeQueueItem(t *testing.T) {
+	queuedTime := time.Unix(0, 1000)
+	data, err := encodeQueueItem(&queueItem{queuedTime: queuedTime, span: &model.Span{OperationName: "op"}})
+	require.NoError(t, err)
+	item, err := decodeQueueItem(data)
+	require.NoError(t, err)
+	assert.Equal(t, queuedTime, item.queuedTime)
+	assert.Equal(t, "op", item.span.OperationName)
+
+	_, err = decodeQueueItem([]byte{1})
+	assert.EqualError(t, err, "persistent queue item too short: 1 bytes")
+	_, err = decodeQueueItem(append(data[:8:8], 0xff))
+	assert.Error(t, err)
+}
+
+// rejectService returns a rate limiter rejecting the spans of the given service.
+func rejectService(svc string) LimitSpans {
+	return func(spans []*model.Span) []bool {
+		allowed := make([]bool, len(spans))
+		for i, span := range spans {
+			allowed[i] = span.Process.ServiceName != svc
+		}
+		return allowed
+	}
+}
+
+func TestSpanProcessorRateLimited(t *testing.T) {
+	w := &recordingSpanWriter{}
+	p := NewSpanProcessor(w, Options.QueueSize(10), Options.RateLimiter(rejectService("over-limit"))).(*spanProcessor)
+	defer p.Close()
+
+	res, err := p.ProcessSpans([]*model.Span{
+		{Process: &model.Process{ServiceName: "x"}},
+		{Process: &model.Process{ServiceName: "over-limit"}},
+		{Process: &model.Process{ServiceName: "x"}},
+	}, processor.SpansOptions{})
+	assert.NoError(t, err, "only the spans of the service over its limit are rejected")
+	assert.Equal(t, []bool{true, false, true}, res)
+	res, err = p.ProcessSpans([]*model.Span{
+		{Process: &model.Process{ServiceName: "over-limit"}},
+		{Process: &model.Process{ServiceName: "over-limit"}},
+	}, processor.SpansOptions{})
+	assert.Equal(t, processor.ErrRateLimited, err)
+	assert.Nil(t, res)
+}
+
+func TestSpanProcessorTenantQuota(t *testing.T) {
+	w := &recordingSpanWriter{}
+	var tenants []string
+	p := NewSpanProcessor(w, Options.TenantQuota(func(tenant string, spans []*model.Span) bool {
+		tenants = append(tenants, tenant)
+		return tenant != "over-quota"
+	})).(*spanProcessor)
+	defer p.Close()
+
+	_, err := p.ProcessSpans([]*model.Span{{Process: &model.Process{ServiceName: "x"}}}, processor.SpansOptions{Tenant: "acme"})
+	assert.NoError(t, err)
+	res, err := p.ProcessSpans([]*model.Span{{Process: &model.Process{ServiceName: "x"}}}, processor.SpansOptions{Tenant: "over-quota"})
+	assert.Equal(t, processor.ErrRateLimited, err)
+	assert.Nil(t, res)
+	assert.Equal(t, []string{"acme", "over-quota"}, tenants)
+}
+
+func TestSpanProcessorWithTenant(t *testing.T) {
+	w := &recordingSpanWriter{}
+	p := NewSpanProcessor(w, Options.NumWorkers(1), Options.QueueSize(2)).(*spanProcessor)
+
+	// the tenant tag sent by the client is replaced by the validated tenant
+	process := &model.Process{ServiceName: "x", Tags: []model.KeyValue{model.String("tenant", "spoofed")}}
+	_, err := p.ProcessSpans([]*model.Span{
+		{OperationName: "a", Process: process},
+		{OperationName: "b", Process: process},
+	}, processor.SpansOptions{SpanFormat: processor.ProtoSpanFormat, Tenant: "acme"})
+	require.NoError(t, err)
+	for i := 0; i < 100 && w.count() < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.NoError(t, p.Close())
+
+	require.Equal(t, 2, w.count())
+	for _, span := range w.spans {
+		assert.Equal(t, []model.KeyValue{model.String("tenant", "acme")}, span.Process.Tags)
+	}
+}
+
+func TestSpanProcessorWithoutTenancy(t *testing.T) {
+	w := &recordingSpanWriter{}
+	p := NewSpanProcessor(w, Options.NumWorkers(1), Options.QueueSize(2)).(*spanProcessor)
+
+	// the tenant tag sent by the client is not trusted when tenancy is disabled
+	process := &model.Process{ServiceName: "x", Tags: []model.KeyValue{model.String("tenant", "spoofed"), model.String("hostname", "h")}}
+	_, err := p.ProcessSpans([]*model.Span{{OperationName: "a", Process: process}}, processor.SpansOptions{SpanFormat: processor.ProtoSpanFormat})
+	require.NoError(t, err)
+	require.NoError(t, p.Close())
+
+	require.Len(t, w.spans, 1)
+	assert.Equal(t, []model.KeyValue{model.String("hostname", "h")}, w.spans[0].Process.Tags)
+}
+
+func TestSpanProcessorDroppedSpans(t *testing.T) {
+	tracker := dropped.NewTracker(metrics.NullFactory)
+	p := NewSpanProcessor(&recordingSpanWriter{},
+		Options.QueueSize(10),
+		Options.DroppedSpans(tracker),
+		Options.RateLimiter(rejectService("chatty")),
+		Options.SpanFilter(func(span *model.Span) bool { return span.Process.ServiceName != "blocked" }),
+	).(*spanProcessor)
+	defer p.Close()
+
+	_, err := p.ProcessSpans([]*model.Span{
+		{Process: &model.Process{ServiceName: "chatty"}},
+		{Process: &model.Process{ServiceName: "chatty"}},
+	}, processor.SpansOptions{})
+	assert.Equal(t, processor.ErrRateLimited, err)
+	_, err = p.ProcessSpans([]*model.Span{{Process: &model.Process{ServiceName: "blocked"}}}, processor.SpansOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []dropped.Entry{
+		{Service: "chatty", Total: 2, Reasons: map[dropped.Reason]uint64{dropped.RateLimited: 2}},
+		{Service: "blocked", Total: 1, Reasons: map[dropped.Reason]uint64{dropped.Rejected: 1}},
+	}, tracker.Top(10))
+}
+
+func TestSpanProcessorDeduplicator(t *testing.T) {
+	mb := metricstest.NewFactory(time.Hour)
+	tracker := dropped.NewTracker(metrics.NullFactory)
+	seen := make(map[model.SpanID]bool)
+	p := NewSpanProcessor(&recordingSpanWriter{},
+		Options.QueueSize(10),
+		Options.ServiceMetrics(mb),
+		Options.DroppedSpans(tracker),
+		Options.Deduplicator(func(span *model.Span) bool {
+			duplicate := seen[span.SpanID]
+			seen[span.SpanID] = true
+			return !duplicate
+		}),
+	).(*spanProcessor)
+	defer p.Close()
+
+	res, err := p.ProcessSpans([]*model.Span{
+		{SpanID: 1, Process: &model.Process{ServiceName: "x"}},
+		{SpanID: 1, Process: &model.Process{ServiceName: "x"}},
+	}, processor.SpansOptions{SpanFormat: processor.ProtoSpanFormat})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, true}, res)
+
+	mb.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "spans.deduplicated|debug=false|format=proto|svc=x|transport=unknown", Value: 1},
+		metricstest.ExpectedMetric{Name: "spans.rejected|debug=false|format=proto|svc=x|transport=unknown", Value: 0},
+	)
+	assert.Equal(t, []dropped.Entry{
+		{Service: "x", Total: 1, Reasons: map[dropped.Reason]uint64{dropped.Deduplicated: 1}},
+	}, tracker.Top(10))
+}
+
+func TestSpanProcessorDeduplicatorQueueFull(t *testing.T) {
+	w := &recordingSpanWriter{}
+	d := dedup.NewDeduplicator(dedup.Options{}, metrics.NullFactory)
+	p := NewSpanProcessor(w,
+		Options.NumWorkers(1),
+		Options.QueueSize(1),
+		Options.ReportBusy(true),
+		Options.Deduplicator(d.Keep),
+		Options.ForgetDuplicate(d.Forget),
+	).(*spanProcessor)
+	defer p.Close()
+
+	span := func(id uint64) *model.Span {
+		return &model.Span{TraceID: model.NewTraceID(0, 1), SpanID: model.NewSpanID(id), Process: &model.Process{ServiceName: "x"}}
+	}
+	options := processor.SpansOptions{SpanFormat: processor.ProtoSpanFormat}
+
+	// the worker blocks on the first span, and the second span fills the queue
+	w.lock.Lock()
+	_, err := p.ProcessSpans([]*model.Span{span(1)}, options)
+	require.NoError(t, err)
+	for p.queue.Size() != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	_, err = p.ProcessSpans([]*model.Span{span(2)}, options)
+	require.NoError(t, err)
+	_, err = p.ProcessSpans([]*model.Span{span(3)}, options)
+	assert.EqualError(t, err, "server busy")
+	w.lock.Unlock()
+	for p.queue.Size() != 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the retry of the span rejected by the full queue is not a duplicate, unlike the retry of a queued span
+	res, err := p.ProcessSpans([]*model.Span{span(3), span(2)}, options)
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, true}, res)
+	require.Eventually(t, func() bool { return w.count() == 3 }, time.Second, time.Millisecond)
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	var ids []model.SpanID
+	for _, span := range w.spans {
+		ids = append(ids, span.SpanID)
+	}
+	assert.ElementsMatch(t, []model.SpanID{1, 2, 3}, ids)
+}
+
+func TestSpanProcessorEnrichSpans(t *testing.T) {
+	var enriched []string
+	p := NewSpanProcessor(&recordingSpanWriter{},
+		Options.QueueSize(10),
+		Options.RateLimiter(rejectService("chatty")),
+		Options.EnrichSpans(func(spans []*model.Span, client processor.ClientInfo) {
+			for range spans {
+				enriched = append(enriched, client.IP)
+			}
+		}),
+	).(*spanProcessor)
+	defer p.Close()
+
+	client := processor.ClientInfo{IP: "10.0.0.1"}
+	_, err := p.ProcessSpans([]*model.Span{
+		{Process: &model.Process{ServiceName: "chatty"}},
+		{Process: &model.Process{ServiceName: "chatty"}},
+	}, processor.SpansOptions{Client: client})
+	assert.Equal(t, processor.ErrRateLimited, err)
+	_, err = p.ProcessSpans([]*model.Span{{Process: &model.Process{ServiceName: "x"}}}, processor.SpansOptions{Client: client})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1"}, enriched)
+}
+
+func TestSpanProcessorDrain(t *testing.T) {
+	w := &blockingWriter{}
+	p := NewSpanProcessor(w, Options.NumWorkers(1), Options.QueueSize(10)).(*spanProcessor)
+	defer p.Close()
+
+	w.Lock()
+	_, err := p.ProcessSpans([]*model.Span{
+		{Process: &model.Process{ServiceName: "x"}},
+		{Process: &model.Process{ServiceName: "x"}},
+	}, processor.SpansOptions{})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, p.Drain(ctx))
+
+	_, err = p.ProcessSpans([]*model.Span{{Process: &model.Process{ServiceName: "x"}}}, processor.SpansOptions{})
+	assert.Equal(t, processor.ErrDraining, err)
+
+	w.Unlock()
+	require.NoError(t, p.Drain(context.Background()))
+	assert.Equal(t, 0, p.pendingSpans())
+
+	p.Resume()
+	_, err = p.ProcessSpans([]*model.Span{{Process: &model.Process{ServiceName: "x"}}}, processor.SpansOptions{})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,212 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanlimits
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+const (
+	// PolicyTruncate shortens the spans exceeding a limit and tags them with TruncatedTag
+	PolicyTruncate = "truncate"
+	// PolicyReject rejects the spans exceeding a limit
+	PolicyReject = "reject"
+
+	// TruncatedTag lists the limits a truncated span exceeded, e.g. "tag_value,logs"
+	TruncatedTag = "jaeger.truncated"
+
+	limitTagValue = "tag_value"
+	limitLogs     = "logs"
+	limitSpanSize = "span_size"
+
+	// otherServices is the metrics tag of the services seen after the number of tracked services reaches maxServices
+	otherServices = "other-services"
+	maxServices   = 4000
+)
+
+type serviceCounters struct {
+	truncated metrics.Counter
+	rejected  metrics.Counter
+}
+
+// Enforcer applies the size limits to spans.
+type Enforcer struct {
+	maxTagValueLength int
+	maxLogs           int
+	maxSpanSize       int
+	reject            bool
+
+	metricsFactory metrics.Factory
+	lock           sync.Mutex
+	services       map[string]*serviceCounters
+}
+
+// NewEnforcer creates an Enforcer emitting the spans_truncated{svc} and spans_rejected{svc} counters.
+func NewEnforcer(opts Options, metricsFactory metrics.Factory) (*Enforcer, error) {
+	if opts.Policy != PolicyTruncate && opts.Policy != PolicyReject {
+		return nil, fmt.Errorf("invalid span limits policy %q, expecting %s or %s", opts.Policy, PolicyTruncate, PolicyReject)
+	}
+	return &Enforcer{
+		maxTagValueLength: opts.MaxTagValueLength,
+		maxLogs:           opts.MaxLogs,
+		maxSpanSize:       opts.MaxSpanSize,
+		reject:            opts.Policy == PolicyReject,
+		metricsFactory:    metricsFactory,
+		services:          make(map[string]*serviceCounters),
+	}, nil
+}
+
+// Enforce returns false if the span must be rejected. With the truncate policy, spans exceeding
+// the limits are shortened in place, and only rejected if removing all logs is not enough to fit
+// within the maximum span size.
+func (e *Enforcer) Enforce(span *model.Span) bool {
+	if e.reject {
+		if e.exceeded(span) {
+			e.countersFor(span).rejected.Inc(1)
+			return false
+		}
+		return true
+	}
+
+	var truncated []string
+	if e.maxTagValueLength > 0 {
+		n := e.truncateValues(span.Tags)
+		if span.Process != nil {
+			n += e.truncateValues(span.Process.Tags)
+		}
+		for i := range span.Logs {
+			n += e.truncateValues(span.Logs[i].Fields)
+		}
+		if n > 0 {
+			truncated = append(truncated, limitTagValue)
+		}
+	}
+	if e.maxLogs > 0 && len(span.Logs) > e.maxLogs {
+		span.Logs = span.Logs[:e.maxLogs]
+		truncated = append(truncated, limitLogs)
+	}
+	if len(truncated) > 0 {
+		span.Tags = append(span.Tags, model.String(TruncatedTag, strings.Join(truncated, ",")))
+	}
+	// the size is checked last so that it includes the truncated tag
+	if e.maxSpanSize > 0 && span.Size() > e.maxSpanSize {
+		tag := model.String(TruncatedTag, strings.Join(append(truncated, limitSpanSize), ","))
+		if len(truncated) > 0 {
+			span.Tags[len(span.Tags)-1] = tag
+		} else {
+			span.Tags = append(span.Tags, tag)
+		}
+		truncated = append(truncated, limitSpanSize)
+		// the logs are dropped starting from the most recent, as they are the only optional part of the span
+		for len(span.Logs) > 0 && span.Size() > e.maxSpanSize {
+			span.Logs = span.Logs[:len(span.Logs)-1]
+		}
+		if span.Size() > e.maxSpanSize {
+			e.countersFor(span).rejected.Inc(1)
+			return false
+		}
+	}
+	if len(truncated) > 0 {
+		e.countersFor(span).truncated.Inc(1)
+	}
+	return true
+}
+
+func (e *Enforcer) exceeded(span *model.Span) bool {
+	if e.maxLogs > 0 && len(span.Logs) > e.maxLogs {
+		return true
+	}
+	if e.maxSpanSize > 0 && span.Size() > e.maxSpanSize {
+		return true
+	}
+	if e.maxTagValueLength > 0 {
+		if e.tooLong(span.Tags) || (span.Process != nil && e.tooLong(span.Process.Tags)) {
+			return true
+		}
+		for i := range span.Logs {
+			if e.tooLong(span.Logs[i].Fields) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (e *Enforcer) tooLong(kvs []model.KeyValue) bool {
+	for i := range kvs {
+		if len(kvs[i].VStr) > e.maxTagValueLength || len(kvs[i].VBinary) > e.maxTagValueLength {
+			return true
+		}
+	}
+	return false
+}
+
+// truncateValues shortens the values longer than the limit and returns how many were shortened.
+func (e *Enforcer) truncateValues(kvs []model.KeyValue) int {
+	n := 0
+	for i := range kvs {
+		kv := &kvs[i]
+		if len(kv.VStr) > e.maxTagValueLength {
+			kv.VStr = truncateString(kv.VStr, e.maxTagValueLength)
+			n++
+		}
+		if len(kv.VBinary) > e.maxTagValueLength {
+			kv.VBinary = kv.VBinary[:e.maxTagValueLength]
+			n++
+		}
+	}
+	return n
+}
+
+// truncateString cuts s to at most max bytes without splitting a multi-byte character.
+func truncateString(s string, max int) string {
+	s = s[:max]
+	for i := 0; i < utf8.UTFMax-1 && len(s) > 0; i++ {
+		if r, size := utf8.DecodeLastRuneInString(s); r != utf8.RuneError || size != 1 {
+			break
+		}
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+func (e *Enforcer) countersFor(span *model.Span) *serviceCounters {
+	svc := span.Process.GetServiceName()
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if c, ok := e.services[svc]; ok {
+		return c
+	}
+	if len(e.services) >= maxServices {
+		svc = otherServices
+		if c, ok := e.services[svc]; ok {
+			return c
+		}
+	}
+	tags := map[string]string{"svc": svc}
+	c := &serviceCounters{
+		truncated: e.metricsFactory.Counter(metrics.Options{Name: "spans_truncated", Tags: tags}),
+		rejected:  e.metricsFactory.Counter(metrics.Options{Name: "spans_rejected", Tags: tags}),
+	}
+	e.services[svc] = c
+	return c
+}
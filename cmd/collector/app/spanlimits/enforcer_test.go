@@ -0,0 +1,130 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanlimits
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/config"
+)
+
+func makeSpan(logs int) *model.Span {
+	span := &model.Span{
+		OperationName: "op",
+		Tags:          []model.KeyValue{model.String("short", "ok")},
+		Process:       &model.Process{ServiceName: "frontend"},
+	}
+	for i := 0; i < logs; i++ {
+		span.Logs = append(span.Logs, model.Log{Fields: []model.KeyValue{model.String("event", strings.Repeat("x", 20))}})
+	}
+	return span
+}
+
+func truncatedTag(span *model.Span) string {
+	tag, _ := model.KeyValues(span.Tags).FindByKey(TruncatedTag)
+	return tag.AsString()
+}
+
+func TestEnforcerTruncate(t *testing.T) {
+	mf := metricstest.NewFactory(0)
+	e, err := NewEnforcer(Options{MaxTagValueLength: 5, MaxLogs: 2, Policy: PolicyTruncate}, mf)
+	require.NoError(t, err)
+
+	span := makeSpan(3)
+	span.Tags = append(span.Tags, model.String("sql", "SELECT * FROM spans"), model.Binary("blob", []byte("0123456789")))
+	assert.True(t, e.Enforce(span))
+	assert.Len(t, span.Logs, 2)
+	assert.Equal(t, "xxxxx", span.Logs[0].Fields[0].VStr)
+	assert.Equal(t, "ok", span.Tags[0].VStr)
+	assert.Equal(t, "SELEC", span.Tags[1].VStr)
+	assert.Equal(t, []byte("01234"), span.Tags[2].VBinary)
+	assert.Equal(t, "tag_value,logs", truncatedTag(span))
+
+	span = makeSpan(0)
+	assert.True(t, e.Enforce(span))
+	assert.Equal(t, "", truncatedTag(span), "spans within the limits are not annotated")
+
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "spans_truncated", Tags: map[string]string{"svc": "frontend"}, Value: 1},
+	)
+}
+
+func TestEnforcerTruncateSpanSize(t *testing.T) {
+	// the truncated tag must fit as well
+	withTag := makeSpan(1)
+	withTag.Tags = append(withTag.Tags, model.String(TruncatedTag, "span_size"))
+	maxSize := withTag.Size()
+	e, err := NewEnforcer(Options{MaxSpanSize: maxSize, Policy: PolicyTruncate}, metricstest.NewFactory(0))
+	require.NoError(t, err)
+
+	span := makeSpan(5)
+	assert.True(t, e.Enforce(span))
+	assert.Len(t, span.Logs, 1)
+	assert.Equal(t, "span_size", truncatedTag(span))
+	assert.LessOrEqual(t, span.Size(), maxSize)
+
+	span = makeSpan(0)
+	span.Tags = append(span.Tags, model.String("huge", strings.Repeat("x", maxSize)))
+	assert.False(t, e.Enforce(span), "spans too large without logs are rejected")
+}
+
+func TestEnforcerReject(t *testing.T) {
+	mf := metricstest.NewFactory(0)
+	e, err := NewEnforcer(Options{MaxTagValueLength: 5, MaxLogs: 2, Policy: PolicyReject}, mf)
+	require.NoError(t, err)
+
+	assert.True(t, e.Enforce(makeSpan(0)))
+	assert.False(t, e.Enforce(makeSpan(3)))
+	span := makeSpan(0)
+	span.Process.Tags = []model.KeyValue{model.String("hostname", "very-long-hostname")}
+	assert.False(t, e.Enforce(span))
+	span = makeSpan(1)
+	assert.False(t, e.Enforce(span), "the log field is too long")
+	assert.Equal(t, strings.Repeat("x", 20), span.Logs[0].Fields[0].VStr, "rejected spans are not modified")
+
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "spans_rejected", Tags: map[string]string{"svc": "frontend"}, Value: 3},
+	)
+}
+
+func TestNewEnforcerInvalidPolicy(t *testing.T) {
+	_, err := NewEnforcer(Options{MaxLogs: 1, Policy: "drop"}, metricstest.NewFactory(0))
+	assert.EqualError(t, err, `invalid span limits policy "drop", expecting truncate or reject`)
+}
+
+func TestTruncateString(t *testing.T) {
+	assert.Equal(t, "abc", truncateString("abcdef", 3))
+	assert.Equal(t, "a", truncateString("a世界", 3), "a cut multi-byte character is removed")
+	assert.Equal(t, "a世", truncateString("a世界", 4))
+}
+
+func TestOptionsWithFlags(t *testing.T) {
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{
+		"--collector.span-limits.max-tag-value-length=1024",
+		"--collector.span-limits.max-logs=100",
+		"--collector.span-limits.max-span-size=65536",
+	})
+	opts := new(Options).InitFromViper(v)
+	assert.Equal(t, Options{MaxTagValueLength: 1024, MaxLogs: 100, MaxSpanSize: 65536, Policy: PolicyTruncate}, *opts)
+	assert.True(t, opts.Enabled())
+	assert.False(t, (&Options{Policy: PolicyReject}).Enabled())
+}
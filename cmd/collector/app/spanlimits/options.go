@@ -0,0 +1,62 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanlimits
+
+import (
+	"flag"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	maxTagValueLength = "collector.span-limits.max-tag-value-length"
+	maxLogs           = "collector.span-limits.max-logs"
+	maxSpanSize       = "collector.span-limits.max-span-size"
+	policy            = "collector.span-limits.policy"
+)
+
+// Options configures the limits applied to the spans received by the collector.
+type Options struct {
+	// MaxTagValueLength is the maximum length in bytes of string and binary tag and log field values, 0 means unlimited
+	MaxTagValueLength int
+	// MaxLogs is the maximum number of logs of a span, 0 means unlimited
+	MaxLogs int
+	// MaxSpanSize is the maximum size in bytes of a span encoded in protobuf, 0 means unlimited
+	MaxSpanSize int
+	// Policy is what happens to spans exceeding a limit, either PolicyTruncate or PolicyReject
+	Policy string
+}
+
+// AddFlags adds flags for span limits Options
+func AddFlags(flags *flag.FlagSet) {
+	flags.Int(maxTagValueLength, 0, "The maximum length in bytes of span tag, process tag and log field values, 0 means unlimited")
+	flags.Int(maxLogs, 0, "The maximum number of logs of a span, 0 means unlimited")
+	flags.Int(maxSpanSize, 0, "The maximum size in bytes of a span, 0 means unlimited")
+	flags.String(policy, PolicyTruncate, "What to do with spans exceeding a limit: "+PolicyTruncate+" the span and tag it with "+TruncatedTag+", or "+PolicyReject+" it")
+}
+
+// InitFromViper initializes Options with properties from viper
+func (o *Options) InitFromViper(v *viper.Viper) *Options {
+	o.MaxTagValueLength = v.GetInt(maxTagValueLength)
+	o.MaxLogs = v.GetInt(maxLogs)
+	o.MaxSpanSize = v.GetInt(maxSpanSize)
+	o.Policy = v.GetString(policy)
+	return o
+}
+
+// Enabled returns true if any limit is configured
+func (o *Options) Enabled() bool {
+	return o.MaxTagValueLength > 0 || o.MaxLogs > 0 || o.MaxSpanSize > 0
+}
@@ -0,0 +1,194 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanmetrics
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+const (
+	// otherSeries is the service and operation of the spans exceeding the number of tracked series
+	otherSeries = "other"
+
+	statusError = "STATUS_CODE_ERROR"
+	statusOK    = "STATUS_CODE_OK"
+	statusUnset = "STATUS_CODE_UNSET"
+
+	spanKindUnspecified = "SPAN_KIND_UNSPECIFIED"
+
+	otelStatusCodeTag = "otel.status_code"
+)
+
+type seriesKey struct {
+	service   string
+	operation string
+	spanKind  string
+	status    string
+}
+
+func (k seriesKey) tags() map[string]string {
+	return map[string]string{
+		"service_name": k.service,
+		"operation":    k.operation,
+		"span_kind":    k.spanKind,
+		"status_code":  k.status,
+	}
+}
+
+type series struct {
+	calls    metrics.Counter
+	duration metrics.Histogram
+
+	// cumulative values for remote-write, bucketCounts are not cumulative
+	count        uint64
+	sumSeconds   float64
+	bucketCounts []uint64
+}
+
+// Aggregator computes calls and duration metrics by service, operation, span kind and status code.
+type Aggregator struct {
+	metricsFactory metrics.Factory
+	buckets        []float64 // upper bounds in seconds
+	maxSeries      int
+
+	lock   sync.Mutex
+	series map[seriesKey]*series
+}
+
+// NewAggregator creates an Aggregator emitting the calls counter and the duration_seconds
+// histogram to the metrics factory.
+func NewAggregator(opts Options, metricsFactory metrics.Factory) (*Aggregator, error) {
+	durations, err := parseBuckets(opts.DurationBuckets)
+	if err != nil {
+		return nil, err
+	}
+	buckets := make([]float64, len(durations))
+	for i, d := range durations {
+		buckets[i] = d.Seconds()
+	}
+	maxSeries := opts.MaxSeries
+	if maxSeries <= 0 {
+		maxSeries = defaultMaxSeries
+	}
+	return &Aggregator{
+		metricsFactory: metricsFactory,
+		buckets:        buckets,
+		maxSeries:      maxSeries,
+		series:         make(map[seriesKey]*series),
+	}, nil
+}
+
+// ProcessSpan records the span in the metrics of its service and operation.
+func (a *Aggregator) ProcessSpan(span *model.Span) {
+	key := seriesKey{
+		service:   span.Process.GetServiceName(),
+		operation: span.OperationName,
+		spanKind:  spanKind(span),
+		status:    statusCode(span),
+	}
+	seconds := span.Duration.Seconds()
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	s := a.seriesLocked(key)
+	s.count++
+	s.sumSeconds += seconds
+	s.bucketCounts[sort.SearchFloat64s(a.buckets, seconds)]++
+	s.calls.Inc(1)
+	s.duration.Record(seconds)
+}
+
+func (a *Aggregator) seriesLocked(key seriesKey) *series {
+	if s, ok := a.series[key]; ok {
+		return s
+	}
+	if len(a.series) >= a.maxSeries {
+		key.service, key.operation = otherSeries, otherSeries
+		if s, ok := a.series[key]; ok {
+			return s
+		}
+	}
+	tags := key.tags()
+	s := &series{
+		calls: a.metricsFactory.Counter(metrics.Options{
+			Name: "calls",
+			Tags: tags,
+			Help: "Number of spans by service, operation, span kind and status code",
+		}),
+		duration: a.metricsFactory.Histogram(metrics.HistogramOptions{
+			Name:    "duration_seconds",
+			Tags:    tags,
+			Help:    "Duration of the spans by service, operation, span kind and status code",
+			Buckets: a.buckets,
+		}),
+		// one more bucket for the spans above the last bound
+		bucketCounts: make([]uint64, len(a.buckets)+1),
+	}
+	a.series[key] = s
+	return s
+}
+
+// snapshot returns the current values of the metrics as Prometheus time series.
+func (a *Aggregator) snapshot() []timeSeries {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	ts := make([]timeSeries, 0, len(a.series)*(len(a.buckets)+4))
+	for key, s := range a.series {
+		tags := key.tags()
+		ts = append(ts,
+			newTimeSeries("calls_total", tags, "", float64(s.count)),
+			newTimeSeries("duration_seconds_count", tags, "", float64(s.count)),
+			newTimeSeries("duration_seconds_sum", tags, "", s.sumSeconds),
+		)
+		var cumulative uint64
+		for i, b := range a.buckets {
+			cumulative += s.bucketCounts[i]
+			ts = append(ts, newTimeSeries("duration_seconds_bucket", tags, strconv.FormatFloat(b, 'f', -1, 64), float64(cumulative)))
+		}
+		ts = append(ts, newTimeSeries("duration_seconds_bucket", tags, "+Inf", float64(s.count)))
+	}
+	return ts
+}
+
+func spanKind(span *model.Span) string {
+	kind, ok := span.GetSpanKind()
+	if !ok || kind == "" {
+		return spanKindUnspecified
+	}
+	return "SPAN_KIND_" + strings.ToUpper(kind)
+}
+
+func statusCode(span *model.Span) string {
+	tags := model.KeyValues(span.Tags)
+	if tag, ok := tags.FindByKey("error"); ok && tag.AsString() == "true" {
+		return statusError
+	}
+	if tag, ok := tags.FindByKey(otelStatusCodeTag); ok {
+		switch strings.ToUpper(tag.AsString()) {
+		case "ERROR":
+			return statusError
+		case "OK":
+			return statusOK
+		}
+	}
+	return statusUnset
+}
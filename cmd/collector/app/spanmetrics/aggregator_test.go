@@ -0,0 +1,103 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanmetrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+func makeSpan(svc, op string, duration time.Duration, tags ...model.KeyValue) *model.Span {
+	return &model.Span{
+		OperationName: op,
+		Duration:      duration,
+		Tags:          tags,
+		Process:       &model.Process{ServiceName: svc},
+	}
+}
+
+func TestAggregator(t *testing.T) {
+	mf := metricstest.NewFactory(0)
+	a, err := NewAggregator(Options{DurationBuckets: "10ms,100ms"}, mf)
+	require.NoError(t, err)
+
+	a.ProcessSpan(makeSpan("frontend", "GET /", 5*time.Millisecond, model.String("span.kind", "server")))
+	a.ProcessSpan(makeSpan("frontend", "GET /", 50*time.Millisecond, model.String("span.kind", "server")))
+	a.ProcessSpan(makeSpan("frontend", "GET /", time.Second, model.String("span.kind", "server"), model.Bool("error", true)))
+	a.ProcessSpan(makeSpan("backend", "query", time.Millisecond, model.String("otel.status_code", "OK")))
+
+	serverOK := map[string]string{"service_name": "frontend", "operation": "GET /", "span_kind": "SPAN_KIND_SERVER", "status_code": statusUnset}
+	serverErr := map[string]string{"service_name": "frontend", "operation": "GET /", "span_kind": "SPAN_KIND_SERVER", "status_code": statusError}
+	backend := map[string]string{"service_name": "backend", "operation": "query", "span_kind": spanKindUnspecified, "status_code": statusOK}
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "calls", Tags: serverOK, Value: 2},
+		metricstest.ExpectedMetric{Name: "calls", Tags: serverErr, Value: 1},
+		metricstest.ExpectedMetric{Name: "calls", Tags: backend, Value: 1},
+	)
+
+	bySeries := make(map[string]float64)
+	for _, ts := range a.snapshot() {
+		var name, le, status, svc string
+		for _, l := range ts.labels {
+			switch l.name {
+			case "__name__":
+				name = l.value
+			case "le":
+				le = l.value
+			case "status_code":
+				status = l.value
+			case "service_name":
+				svc = l.value
+			}
+		}
+		bySeries[svc+"/"+status+"/"+name+"/"+le] = ts.value
+	}
+	assert.Equal(t, 2.0, bySeries["frontend/"+statusUnset+"/calls_total/"])
+	assert.Equal(t, 1.0, bySeries["frontend/"+statusUnset+"/duration_seconds_bucket/0.01"])
+	assert.Equal(t, 2.0, bySeries["frontend/"+statusUnset+"/duration_seconds_bucket/0.1"])
+	assert.Equal(t, 2.0, bySeries["frontend/"+statusUnset+"/duration_seconds_bucket/+Inf"])
+	assert.Equal(t, 0.0, bySeries["frontend/"+statusError+"/duration_seconds_bucket/0.1"])
+	assert.Equal(t, 1.0, bySeries["frontend/"+statusError+"/duration_seconds_bucket/+Inf"])
+	assert.InDelta(t, 0.055, bySeries["frontend/"+statusUnset+"/duration_seconds_sum/"], 1e-9)
+	assert.Equal(t, 1.0, bySeries["backend/"+statusOK+"/duration_seconds_count/"])
+}
+
+func TestAggregatorMaxSeries(t *testing.T) {
+	mf := metricstest.NewFactory(0)
+	a, err := NewAggregator(Options{DurationBuckets: "1s", MaxSeries: 1}, mf)
+	require.NoError(t, err)
+	a.ProcessSpan(makeSpan("frontend", "a", time.Millisecond))
+	a.ProcessSpan(makeSpan("frontend", "b", time.Millisecond))
+	a.ProcessSpan(makeSpan("backend", "c", time.Millisecond))
+
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "calls", Tags: map[string]string{
+			"service_name": otherSeries, "operation": otherSeries, "span_kind": spanKindUnspecified, "status_code": statusUnset,
+		}, Value: 2},
+	)
+}
+
+func TestNewAggregatorInvalidBuckets(t *testing.T) {
+	for _, buckets := range []string{"fast", "100ms,10ms", "-1s"} {
+		_, err := NewAggregator(Options{DurationBuckets: buckets}, metricstest.NewFactory(0))
+		assert.Error(t, err, buckets)
+	}
+}
@@ -0,0 +1,22 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package spanmetrics implements an optional collector stage computing request, error
+// and duration (RED) metrics per service and operation from the received spans.
+//
+// The metrics are exposed with the other collector metrics, e.g. on the Prometheus
+// endpoint of the admin server, and can also be pushed with the Prometheus remote-write
+// protocol. Every span reaching the collector is counted, including spans later dropped
+// by tail sampling, so that the metrics reflect all the traffic.
+package spanmetrics
@@ -0,0 +1,89 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanmetrics
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	enabled             = "collector.span-metrics.enabled"
+	durationBuckets     = "collector.span-metrics.duration-buckets"
+	maxSeries           = "collector.span-metrics.max-series"
+	remoteWriteURL      = "collector.span-metrics.remote-write-url"
+	remoteWriteInterval = "collector.span-metrics.remote-write-interval"
+
+	defaultDurationBuckets     = "2ms,4ms,6ms,8ms,10ms,50ms,100ms,200ms,400ms,800ms,1s,1.4s,2s,5s,10s,15s"
+	defaultMaxSeries           = 10000
+	defaultRemoteWriteInterval = 15 * time.Second
+)
+
+// Options controls the span metrics stage of the collector.
+type Options struct {
+	// Enabled turns on the computation of span metrics
+	Enabled bool
+	// DurationBuckets is a comma-separated list of the upper bounds of the span duration histogram buckets
+	DurationBuckets string
+	// MaxSeries is the maximum number of service, operation, kind and status combinations tracked;
+	// spans of additional combinations are counted under the "other" service and operation
+	MaxSeries int
+	// RemoteWriteURL is the Prometheus remote-write endpoint the metrics are pushed to, disabled when empty
+	RemoteWriteURL string
+	// RemoteWriteInterval is how often the metrics are pushed to RemoteWriteURL
+	RemoteWriteInterval time.Duration
+}
+
+// AddFlags adds flags for span metrics Options
+func AddFlags(flags *flag.FlagSet) {
+	flags.Bool(enabled, false, "Compute request, error and duration metrics per service and operation from the received spans")
+	flags.String(durationBuckets, defaultDurationBuckets, "Comma-separated upper bounds of the span duration histogram buckets, in increasing order")
+	flags.Int(maxSeries, defaultMaxSeries, "The maximum number of service, operation, span kind and status combinations tracked by the span metrics")
+	flags.String(remoteWriteURL, "", "URL of a Prometheus remote-write endpoint the span metrics are pushed to, e.g. http://prometheus:9090/api/v1/write")
+	flags.Duration(remoteWriteInterval, defaultRemoteWriteInterval, "How often the span metrics are pushed to the remote-write endpoint")
+}
+
+// InitFromViper initializes Options with properties from viper
+func (o *Options) InitFromViper(v *viper.Viper) *Options {
+	o.Enabled = v.GetBool(enabled)
+	o.DurationBuckets = v.GetString(durationBuckets)
+	o.MaxSeries = v.GetInt(maxSeries)
+	o.RemoteWriteURL = v.GetString(remoteWriteURL)
+	o.RemoteWriteInterval = v.GetDuration(remoteWriteInterval)
+	return o
+}
+
+func parseBuckets(s string) ([]time.Duration, error) {
+	var buckets []time.Duration
+	for _, b := range strings.Split(s, ",") {
+		b = strings.TrimSpace(b)
+		if b == "" {
+			continue
+		}
+		d, err := time.ParseDuration(b)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid span metrics duration bucket %q, expecting a positive duration", b)
+		}
+		if len(buckets) > 0 && d <= buckets[len(buckets)-1] {
+			return nil, fmt.Errorf("span metrics duration buckets must be in increasing order, got %v after %v", d, buckets[len(buckets)-1])
+		}
+		buckets = append(buckets, d)
+	}
+	return buckets, nil
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanmetrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jaegertracing/jaeger/pkg/config"
+)
+
+func TestOptionsWithFlags(t *testing.T) {
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{
+		"--collector.span-metrics.enabled=true",
+		"--collector.span-metrics.duration-buckets=10ms,1s",
+		"--collector.span-metrics.max-series=100",
+		"--collector.span-metrics.remote-write-url=http://prometheus:9090/api/v1/write",
+		"--collector.span-metrics.remote-write-interval=1m",
+	})
+	opts := new(Options).InitFromViper(v)
+	assert.Equal(t, Options{
+		Enabled:             true,
+		DurationBuckets:     "10ms,1s",
+		MaxSeries:           100,
+		RemoteWriteURL:      "http://prometheus:9090/api/v1/write",
+		RemoteWriteInterval: time.Minute,
+	}, *opts)
+}
+
+func TestDefaultDurationBuckets(t *testing.T) {
+	buckets, err := parseBuckets(defaultDurationBuckets)
+	assert.NoError(t, err)
+	assert.Len(t, buckets, 16)
+	assert.Equal(t, 2*time.Millisecond, buckets[0])
+	assert.Equal(t, 15*time.Second, buckets[15])
+}
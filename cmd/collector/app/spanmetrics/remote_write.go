@@ -0,0 +1,191 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanmetrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+)
+
+const remoteWriteTimeout = 10 * time.Second
+
+type label struct {
+	name  string
+	value string
+}
+
+type timeSeries struct {
+	labels []label // sorted by name
+	value  float64
+}
+
+func newTimeSeries(name string, tags map[string]string, le string, value float64) timeSeries {
+	labels := make([]label, 0, len(tags)+2)
+	labels = append(labels, label{name: "__name__", value: name})
+	for k, v := range tags {
+		labels = append(labels, label{name: k, value: v})
+	}
+	if le != "" {
+		labels = append(labels, label{name: "le", value: le})
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].name < labels[j].name })
+	return timeSeries{labels: labels, value: value}
+}
+
+type remoteWriteMetrics struct {
+	Pushes      metrics.Counter `metric:"remote_write" tags:"result=ok"`
+	PushFailure metrics.Counter `metric:"remote_write" tags:"result=err"`
+}
+
+// RemoteWriter periodically pushes the metrics of an Aggregator to a Prometheus remote-write endpoint.
+type RemoteWriter struct {
+	url        string
+	interval   time.Duration
+	aggregator *Aggregator
+	client     *http.Client
+	logger     *zap.Logger
+	metrics    remoteWriteMetrics
+	timeNow    func() time.Time
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewRemoteWriter creates a RemoteWriter for the endpoint configured in the options.
+func NewRemoteWriter(opts Options, aggregator *Aggregator, logger *zap.Logger, metricsFactory metrics.Factory) *RemoteWriter {
+	interval := opts.RemoteWriteInterval
+	if interval <= 0 {
+		interval = defaultRemoteWriteInterval
+	}
+	w := &RemoteWriter{
+		url:        opts.RemoteWriteURL,
+		interval:   interval,
+		aggregator: aggregator,
+		client:     &http.Client{Timeout: remoteWriteTimeout},
+		logger:     logger,
+		timeNow:    time.Now,
+		stopCh:     make(chan struct{}),
+	}
+	metrics.Init(&w.metrics, metricsFactory, nil)
+	return w
+}
+
+// Start pushes the metrics in the background until Close is called.
+func (w *RemoteWriter) Start() {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w.pushAndLog()
+			case <-w.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the background pushes after a last push of the metrics.
+func (w *RemoteWriter) Close() error {
+	close(w.stopCh)
+	w.wg.Wait()
+	w.pushAndLog()
+	return nil
+}
+
+func (w *RemoteWriter) pushAndLog() {
+	if err := w.push(); err != nil {
+		w.metrics.PushFailure.Inc(1)
+		w.logger.Warn("Failed to push span metrics", zap.String("url", w.url), zap.Error(err))
+		return
+	}
+	w.metrics.Pushes.Inc(1)
+}
+
+func (w *RemoteWriter) push() error {
+	series := w.aggregator.snapshot()
+	if len(series) == 0 {
+		return nil
+	}
+	body := snappy.Encode(nil, encodeWriteRequest(series, w.timeNow()))
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("remote-write endpoint returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+// encodeWriteRequest encodes the time series as a prometheus.WriteRequest protobuf message,
+// with a single sample per series.
+func encodeWriteRequest(series []timeSeries, now time.Time) []byte {
+	timestamp := now.UnixNano() / int64(time.Millisecond)
+	req := proto.NewBuffer(nil)
+	for _, ts := range series {
+		msg := proto.NewBuffer(nil)
+		for _, l := range ts.labels {
+			lbl := proto.NewBuffer(nil)
+			encodeBytes(lbl, 1, []byte(l.name))
+			encodeBytes(lbl, 2, []byte(l.value))
+			encodeBytes(msg, 1, lbl.Bytes())
+		}
+		sample := proto.NewBuffer(nil)
+		sample.EncodeVarint(1<<3 | wireFixed64)
+		sample.EncodeFixed64(math.Float64bits(ts.value))
+		sample.EncodeVarint(2<<3 | wireVarint)
+		sample.EncodeVarint(uint64(timestamp))
+		encodeBytes(msg, 2, sample.Bytes())
+		encodeBytes(req, 1, msg.Bytes())
+	}
+	return req.Bytes()
+}
+
+func encodeBytes(b *proto.Buffer, field uint64, value []byte) {
+	b.EncodeVarint(field<<3 | wireBytes)
+	b.EncodeRawBytes(value)
+}
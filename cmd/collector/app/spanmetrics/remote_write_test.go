@@ -0,0 +1,123 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanmetrics
+
+import (
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+	"go.uber.org/zap"
+)
+
+// decodeWriteRequest decodes the messages written by encodeWriteRequest
+func decodeWriteRequest(t *testing.T, data []byte) []timeSeries {
+	var series []timeSeries
+	req := proto.NewBuffer(data)
+	for {
+		if _, err := req.DecodeVarint(); err != nil {
+			break // end of the message
+		}
+		raw, err := req.DecodeRawBytes(false)
+		require.NoError(t, err)
+
+		var ts timeSeries
+		msg := proto.NewBuffer(raw)
+		for {
+			key, err := msg.DecodeVarint()
+			if err != nil {
+				break
+			}
+			field, err := msg.DecodeRawBytes(false)
+			require.NoError(t, err)
+			inner := proto.NewBuffer(field)
+			switch key >> 3 {
+			case 1:
+				var l label
+				inner.DecodeVarint()
+				name, _ := inner.DecodeRawBytes(true)
+				inner.DecodeVarint()
+				value, _ := inner.DecodeRawBytes(true)
+				l.name, l.value = string(name), string(value)
+				ts.labels = append(ts.labels, l)
+			case 2:
+				inner.DecodeVarint()
+				bits, err := inner.DecodeFixed64()
+				require.NoError(t, err)
+				ts.value = math.Float64frombits(bits)
+			}
+		}
+		series = append(series, ts)
+	}
+	return series
+}
+
+func TestRemoteWriter(t *testing.T) {
+	received := make(chan []timeSeries, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		data, err := snappy.Decode(nil, body)
+		require.NoError(t, err)
+		received <- decodeWriteRequest(t, data)
+	}))
+	defer server.Close()
+
+	a, err := NewAggregator(Options{DurationBuckets: "1s"}, metricstest.NewFactory(0))
+	require.NoError(t, err)
+	a.ProcessSpan(makeSpan("frontend", "GET /", time.Millisecond))
+
+	mf := metricstest.NewFactory(0)
+	w := NewRemoteWriter(Options{RemoteWriteURL: server.URL, RemoteWriteInterval: 10 * time.Millisecond}, a, zap.NewNop(), mf)
+	w.Start()
+	var series []timeSeries
+	select {
+	case series = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no remote-write request received")
+	}
+	require.NoError(t, w.Close())
+
+	assert.ElementsMatch(t, a.snapshot(), series)
+	mf.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "remote_write", Tags: map[string]string{"result": "err"}, Value: 0})
+}
+
+func TestRemoteWriterError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	a, err := NewAggregator(Options{DurationBuckets: "1s"}, metricstest.NewFactory(0))
+	require.NoError(t, err)
+	a.ProcessSpan(makeSpan("frontend", "GET /", time.Millisecond))
+
+	mf := metricstest.NewFactory(0)
+	w := NewRemoteWriter(Options{RemoteWriteURL: server.URL}, a, zap.NewNop(), mf)
+	assert.EqualError(t, w.push(), "remote-write endpoint returned 400 Bad Request: out of order sample")
+	w.Start()
+	require.NoError(t, w.Close())
+	mf.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "remote_write", Tags: map[string]string{"result": "err"}, Value: 1})
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spillover
+
+import (
+	"flag"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	directory      = "collector.spillover.directory"
+	maxSize        = "collector.spillover.max-size-mib"
+	replayInterval = "collector.spillover.replay-interval"
+
+	defaultReplayInterval = 5 * time.Second
+)
+
+// Options configures the spillover of spans that could not be written to the primary storage.
+type Options struct {
+	// Directory holds the spilled spans, empty disables the spillover
+	Directory string
+	// MaxSize is the maximum size in bytes of the spilled spans, 0 means unlimited
+	MaxSize int64
+	// ReplayInterval is how long to wait before retrying a span the primary storage rejected again
+	ReplayInterval time.Duration
+}
+
+// AddFlags adds flags for spillover Options
+func AddFlags(flags *flag.FlagSet) {
+	flags.String(directory, "", "(experimental) Directory where spans that fail to be written to the storage are spilled instead of being dropped, "+
+		"and replayed from once the storage recovers; disabled if empty")
+	flags.Uint(maxSize, 1024, "The max disk size in MiB of the spilled spans, new failed spans are dropped when reached; 0 means unlimited")
+	flags.Duration(replayInterval, defaultReplayInterval, "How long to wait before retrying to replay a spilled span when the storage is still failing")
+}
+
+// InitFromViper initializes Options with properties from viper
+func (o *Options) InitFromViper(v *viper.Viper) *Options {
+	o.Directory = v.GetString(directory)
+	o.MaxSize = int64(v.GetUint(maxSize)) * 1024 * 1024
+	o.ReplayInterval = v.GetDuration(replayInterval)
+	return o
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spillover
+
+import (
+	"context"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/queue"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+// drainPollInterval is how often Drain checks whether the spilled spans were replayed
+const drainPollInterval = 50 * time.Millisecond
+
+type spilloverMetrics struct {
+	Spilled        metrics.Counter `metric:"spans" tags:"result=spilled"`
+	Replayed       metrics.Counter `metric:"spans" tags:"result=replayed"`
+	Dropped        metrics.Counter `metric:"spans" tags:"result=dropped"`
+	ReplayFailures metrics.Counter `metric:"replay_failures"`
+	Backlog        metrics.Gauge   `metric:"backlog"`
+}
+
+// Writer is a span Writer that spills spans rejected by the primary writer to disk,
+// and replays them to the primary writer in the background until they are accepted.
+type Writer struct {
+	primary        spanstore.Writer
+	spool          *queue.PersistentQueue
+	replayInterval time.Duration
+	logger         *zap.Logger
+	metrics        spilloverMetrics
+	stopCh         chan struct{}
+}
+
+// NewWriter opens the spillover directory; spans spilled by a previous run are replayed.
+func NewWriter(primary spanstore.Writer, opts Options, logger *zap.Logger, metricsFactory metrics.Factory) (*Writer, error) {
+	if opts.ReplayInterval <= 0 {
+		opts.ReplayInterval = defaultReplayInterval
+	}
+	w := &Writer{
+		primary:        primary,
+		replayInterval: opts.ReplayInterval,
+		logger:         logger,
+		stopCh:         make(chan struct{}),
+	}
+	metrics.Init(&w.metrics, metricsFactory, nil)
+	spool, err := queue.NewPersistentQueue(queue.PersistentQueueOptions{
+		Directory: opts.Directory,
+		MaxSize:   opts.MaxSize,
+	}, func(count int) {
+		w.metrics.Dropped.Inc(int64(count))
+	})
+	if err != nil {
+		return nil, err
+	}
+	w.spool = spool
+	if backlog := spool.Size(); backlog > 0 {
+		logger.Info("Replaying spilled spans", zap.String("directory", opts.Directory), zap.Int("spans", backlog))
+	}
+	// a single consumer keeps the replay order and pauses the replay while the storage is failing
+	spool.StartConsumers(1, w.replay)
+	return w, nil
+}
+
+// WriteSpan writes the span to the primary writer, or spills it to disk if the primary writer fails.
+func (w *Writer) WriteSpan(span *model.Span) error {
+	err := w.primary.WriteSpan(span)
+	if err == nil {
+		return nil
+	}
+	data, marshalErr := span.Marshal()
+	if marshalErr != nil || !w.spool.Produce(data) {
+		return err
+	}
+	w.logger.Debug("Spilled span after storage failure", zap.Error(err), zap.Stringer("trace-id", span.TraceID))
+	w.metrics.Spilled.Inc(1)
+	w.metrics.Backlog.Update(int64(w.spool.Size()))
+	return nil
+}
+
+func (w *Writer) replay(data []byte) {
+	span := &model.Span{}
+	if err := span.Unmarshal(data); err != nil {
+		w.logger.Error("Failed to decode spilled span", zap.Error(err))
+		w.metrics.Dropped.Inc(1)
+		return
+	}
+	for {
+		select {
+		case <-w.stopCh:
+			// keep the span for the next run
+			if !w.spool.Produce(data) {
+				w.metrics.Dropped.Inc(1)
+			}
+			return
+		default:
+		}
+		err := w.primary.WriteSpan(span)
+		if err == nil {
+			w.metrics.Replayed.Inc(1)
+			w.metrics.Backlog.Update(int64(w.spool.Size()))
+			return
+		}
+		w.metrics.ReplayFailures.Inc(1)
+		select {
+		case <-time.After(w.replayInterval):
+		case <-w.stopCh:
+		}
+	}
+}
+
+// Drain blocks until the spilled spans are replayed or the context is done.
+func (w *Writer) Drain(ctx context.Context) error {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for w.spool.Pending() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// Close stops the replay; spans not yet replayed remain on disk.
+func (w *Writer) Close() error {
+	close(w.stopCh)
+	return w.spool.Stop()
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spillover
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/config"
+)
+
+type flakyWriter struct {
+	lock    sync.Mutex
+	failing bool
+	spans   []string
+}
+
+func (w *flakyWriter) WriteSpan(span *model.Span) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.failing {
+		return errors.New("storage unavailable")
+	}
+	w.spans = append(w.spans, span.OperationName)
+	return nil
+}
+
+func (w *flakyWriter) setFailing(failing bool) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.failing = failing
+}
+
+func (w *flakyWriter) written() []string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return append([]string(nil), w.spans...)
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	for i := 0; i < 200 && !condition(); i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	require.True(t, condition())
+}
+
+func TestWriterSpillsAndReplays(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spillover")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	primary := &flakyWriter{}
+	mf := metricstest.NewFactory(0)
+	w, err := NewWriter(primary, Options{Directory: dir, ReplayInterval: time.Millisecond}, zap.NewNop(), mf)
+	require.NoError(t, err)
+
+	require.NoError(t, w.WriteSpan(&model.Span{OperationName: "a"}))
+	primary.setFailing(true)
+	require.NoError(t, w.WriteSpan(&model.Span{OperationName: "b"}))
+	require.NoError(t, w.WriteSpan(&model.Span{OperationName: "c"}))
+	waitFor(t, func() bool {
+		c, _ := mf.Snapshot()
+		return c["replay_failures"] > 0
+	})
+	assert.Equal(t, []string{"a"}, primary.written())
+
+	primary.setFailing(false)
+	waitFor(t, func() bool { return len(primary.written()) == 3 })
+	assert.Equal(t, []string{"a", "b", "c"}, primary.written())
+	require.NoError(t, w.Close())
+
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "spans", Tags: map[string]string{"result": "spilled"}, Value: 2},
+		metricstest.ExpectedMetric{Name: "spans", Tags: map[string]string{"result": "replayed"}, Value: 2},
+	)
+}
+
+func TestWriterKeepsSpansAcrossRestarts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spillover")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	primary := &flakyWriter{failing: true}
+	w, err := NewWriter(primary, Options{Directory: dir, ReplayInterval: time.Hour}, zap.NewNop(), metricstest.NewFactory(0))
+	require.NoError(t, err)
+	require.NoError(t, w.WriteSpan(&model.Span{OperationName: "a"}))
+	require.NoError(t, w.WriteSpan(&model.Span{OperationName: "b"}))
+	require.NoError(t, w.Close())
+
+	primary.setFailing(false)
+	w, err = NewWriter(primary, Options{Directory: dir}, zap.NewNop(), metricstest.NewFactory(0))
+	require.NoError(t, err)
+	waitFor(t, func() bool { return len(primary.written()) == 2 })
+	assert.ElementsMatch(t, []string{"a", "b"}, primary.written())
+	require.NoError(t, w.Close())
+}
+
+func TestWriterDrain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spillover")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	primary := &flakyWriter{failing: true}
+	w, err := NewWriter(primary, Options{Directory: dir, ReplayInterval: time.Millisecond}, zap.NewNop(), metricstest.NewFactory(0))
+	require.NoError(t, err)
+	defer w.Close()
+	require.NoError(t, w.WriteSpan(&model.Span{OperationName: "a"}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, w.Drain(ctx), "the spilled span cannot be replayed")
+
+	primary.setFailing(false)
+	require.NoError(t, w.Drain(context.Background()))
+	assert.Equal(t, []string{"a"}, primary.written())
+}
+
+func TestWriterSpoolFull(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spillover")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	primary := &flakyWriter{failing: true}
+	mf := metricstest.NewFactory(0)
+	w, err := NewWriter(primary, Options{Directory: dir, MaxSize: 1}, zap.NewNop(), mf)
+	require.NoError(t, err)
+	assert.EqualError(t, w.WriteSpan(&model.Span{OperationName: "a"}), "storage unavailable")
+	require.NoError(t, w.Close())
+	mf.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "spans", Tags: map[string]string{"result": "dropped"}, Value: 1})
+}
+
+func TestNewWriterInvalidDirectory(t *testing.T) {
+	f, err := ioutil.TempFile("", "spillover")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = NewWriter(&flakyWriter{}, Options{Directory: f.Name()}, zap.NewNop(), metricstest.NewFactory(0))
+	assert.Error(t, err)
+}
+
+func TestOptionsWithFlags(t *testing.T) {
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{
+		"--collector.spillover.directory=/var/lib/jaeger/spillover",
+		"--collector.spillover.max-size-mib=2",
+	})
+	opts := new(Options).InitFromViper(v)
+	assert.Equal(t, "/var/lib/jaeger/spillover", opts.Directory)
+	assert.EqualValues(t, 2*1024*1024, opts.MaxSize)
+	assert.Equal(t, defaultReplayInterval, opts.ReplayInterval)
+}
@@ -0,0 +1,23 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tailsampling implements an optional collector stage that buffers the spans
+// of each trace for a decision window and only writes traces accepted by a policy.
+//
+// Error and latency policies need to see every span of a trace, so with several
+// collectors the spans of a trace must reach the same instance: enable the peer routing
+// stage (--collector.peer-routing.peers), which forwards every span to the collector
+// owning its trace ID. The probabilistic policy hashes the trace ID and therefore yields
+// the same decision on every collector, with or without peer routing.
+package tailsampling
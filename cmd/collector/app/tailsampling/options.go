@@ -0,0 +1,79 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tailsampling
+
+import (
+	"flag"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	enabled            = "collector.tail-sampling.enabled"
+	decisionWait       = "collector.tail-sampling.decision-wait"
+	maxTraces          = "collector.tail-sampling.max-traces"
+	sampleErrors       = "collector.tail-sampling.errors"
+	latencyThreshold   = "collector.tail-sampling.latency-threshold"
+	probabilisticRatio = "collector.tail-sampling.probabilistic-ratio"
+	hashSalt           = "collector.tail-sampling.hash-salt"
+
+	defaultDecisionWait = 10 * time.Second
+	defaultMaxTraces    = 50000
+)
+
+// Options controls the tail sampling stage of the collector.
+type Options struct {
+	// Enabled turns on buffering of spans and sampling of complete traces
+	Enabled bool
+	// DecisionWait is how long spans of a trace are buffered, counting from its first span, before deciding
+	DecisionWait time.Duration
+	// MaxTraces is the maximum number of traces held in memory; the oldest trace is decided early when exceeded
+	MaxTraces int
+	// SampleErrors keeps traces with at least one span tagged with error=true
+	SampleErrors bool
+	// LatencyThreshold keeps traces lasting at least this long, 0 disables the policy
+	LatencyThreshold time.Duration
+	// ProbabilisticRatio keeps this ratio (between 0 and 1) of the traces not matched by other policies
+	ProbabilisticRatio float64
+	// HashSalt is used when hashing trace IDs for the probabilistic policy
+	HashSalt string
+}
+
+// AddFlags adds flags for tail sampling Options
+func AddFlags(flags *flag.FlagSet) {
+	flags.Bool(enabled, false, "(experimental) Buffer spans per trace and apply tail sampling policies before writing to storage; "+
+		"with several collectors, the spans of a trace only reach the same collector when peer routing is enabled "+
+		"(--collector.peer-routing.peers)")
+	flags.Duration(decisionWait, defaultDecisionWait, "How long to buffer spans of a trace, counting from its first span, before making the sampling decision")
+	flags.Int(maxTraces, defaultMaxTraces, "The maximum number of traces buffered in memory; the oldest traces are decided early when the limit is reached")
+	flags.Bool(sampleErrors, true, "Keep traces containing at least one span with the error tag")
+	flags.Duration(latencyThreshold, 0, "Keep traces whose end-to-end duration is at least this long, 0 disables the policy")
+	flags.Float64(probabilisticRatio, 0, "Ratio (between 0 and 1) of the remaining traces to keep; "+
+		"the decision is derived from a hash of the trace ID, so it is consistent across collectors")
+	flags.String(hashSalt, "", "Salt used when hashing trace IDs for the probabilistic policy")
+}
+
+// InitFromViper initializes Options with properties from viper
+func (o *Options) InitFromViper(v *viper.Viper) *Options {
+	o.Enabled = v.GetBool(enabled)
+	o.DecisionWait = v.GetDuration(decisionWait)
+	o.MaxTraces = v.GetInt(maxTraces)
+	o.SampleErrors = v.GetBool(sampleErrors)
+	o.LatencyThreshold = v.GetDuration(latencyThreshold)
+	o.ProbabilisticRatio = v.GetFloat64(probabilisticRatio)
+	o.HashSalt = v.GetString(hashSalt)
+	return o
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tailsampling
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jaegertracing/jaeger/pkg/config"
+)
+
+func TestOptionsWithFlags(t *testing.T) {
+	v, command := config.Viperize(AddFlags)
+	opts := new(Options).InitFromViper(v)
+	assert.False(t, opts.Enabled)
+	assert.Equal(t, defaultDecisionWait, opts.DecisionWait)
+	assert.Equal(t, defaultMaxTraces, opts.MaxTraces)
+	assert.True(t, opts.SampleErrors)
+
+	command.ParseFlags([]string{
+		"--collector.tail-sampling.enabled=true",
+		"--collector.tail-sampling.max-traces=10",
+		"--collector.tail-sampling.errors=false",
+		"--collector.tail-sampling.hash-salt=salt",
+	})
+	opts.InitFromViper(v)
+	assert.True(t, opts.Enabled)
+	assert.Equal(t, 10, opts.MaxTraces)
+	assert.False(t, opts.SampleErrors)
+	assert.Equal(t, "salt", opts.HashSalt)
+	assert.Equal(t, time.Duration(0), opts.LatencyThreshold)
+}
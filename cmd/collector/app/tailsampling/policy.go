@@ -0,0 +1,106 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tailsampling
+
+import (
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+// Policy decides whether a buffered trace should be written to storage.
+type Policy interface {
+	// Name identifies the policy in metrics
+	Name() string
+	// ShouldSample returns true if the trace must be kept
+	ShouldSample(spans []*model.Span) bool
+}
+
+// NewErrorPolicy keeps traces containing at least one span tagged with error=true.
+func NewErrorPolicy() Policy {
+	return errorPolicy{}
+}
+
+type errorPolicy struct{}
+
+func (errorPolicy) Name() string { return "error" }
+
+func (errorPolicy) ShouldSample(spans []*model.Span) bool {
+	for _, span := range spans {
+		if tag, ok := model.KeyValues(span.Tags).FindByKey("error"); ok {
+			if tag.VType == model.BoolType && tag.Bool() || tag.VType == model.StringType && tag.VStr == "true" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NewLatencyPolicy keeps traces whose end-to-end duration is at least the threshold.
+func NewLatencyPolicy(threshold time.Duration) Policy {
+	return latencyPolicy{threshold: threshold}
+}
+
+type latencyPolicy struct {
+	threshold time.Duration
+}
+
+func (latencyPolicy) Name() string { return "latency" }
+
+func (p latencyPolicy) ShouldSample(spans []*model.Span) bool {
+	var start, end time.Time
+	for i, span := range spans {
+		spanEnd := span.StartTime.Add(span.Duration)
+		if i == 0 || span.StartTime.Before(start) {
+			start = span.StartTime
+		}
+		if i == 0 || spanEnd.After(end) {
+			end = spanEnd
+		}
+	}
+	return end.Sub(start) >= p.threshold
+}
+
+// NewProbabilisticPolicy keeps the given ratio of traces based on a hash of the trace ID,
+// which makes the decision identical on every collector that sees a span of the trace.
+func NewProbabilisticPolicy(ratio float64, hashSalt string) Policy {
+	return probabilisticPolicy{sampler: spanstore.NewSampler(ratio, hashSalt)}
+}
+
+type probabilisticPolicy struct {
+	sampler *spanstore.Sampler
+}
+
+func (probabilisticPolicy) Name() string { return "probabilistic" }
+
+func (p probabilisticPolicy) ShouldSample(spans []*model.Span) bool {
+	return len(spans) > 0 && p.sampler.ShouldSample(spans[0])
+}
+
+// PoliciesFromOptions builds the list of policies enabled in the options.
+func PoliciesFromOptions(opts Options) []Policy {
+	var policies []Policy
+	if opts.SampleErrors {
+		policies = append(policies, NewErrorPolicy())
+	}
+	if opts.LatencyThreshold > 0 {
+		policies = append(policies, NewLatencyPolicy(opts.LatencyThreshold))
+	}
+	if opts.ProbabilisticRatio > 0 {
+		policies = append(policies, NewProbabilisticPolicy(opts.ProbabilisticRatio, opts.HashSalt))
+	}
+	return policies
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tailsampling
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+func TestErrorPolicy(t *testing.T) {
+	p := NewErrorPolicy()
+	assert.Equal(t, "error", p.Name())
+	assert.False(t, p.ShouldSample([]*model.Span{{}}))
+	assert.True(t, p.ShouldSample([]*model.Span{{}, {Tags: model.KeyValues{model.Bool("error", true)}}}))
+	assert.True(t, p.ShouldSample([]*model.Span{{Tags: model.KeyValues{model.String("error", "true")}}}))
+	assert.False(t, p.ShouldSample([]*model.Span{{Tags: model.KeyValues{model.Bool("error", false)}}}))
+}
+
+func TestLatencyPolicy(t *testing.T) {
+	p := NewLatencyPolicy(time.Second)
+	assert.Equal(t, "latency", p.Name())
+	start := time.Unix(100, 0)
+	spans := []*model.Span{
+		{StartTime: start.Add(100 * time.Millisecond), Duration: 500 * time.Millisecond},
+		{StartTime: start, Duration: 200 * time.Millisecond},
+	}
+	assert.False(t, p.ShouldSample(spans))
+	spans = append(spans, &model.Span{StartTime: start.Add(800 * time.Millisecond), Duration: 200 * time.Millisecond})
+	assert.True(t, p.ShouldSample(spans))
+}
+
+func TestProbabilisticPolicy(t *testing.T) {
+	assert.True(t, NewProbabilisticPolicy(1, "").ShouldSample([]*model.Span{{TraceID: model.NewTraceID(1, 2)}}))
+	p := NewProbabilisticPolicy(0.5, "salt")
+	assert.Equal(t, "probabilistic", p.Name())
+	assert.False(t, p.ShouldSample(nil))
+	// the decision only depends on the trace ID
+	sampled := 0
+	for i := uint64(0); i < 1000; i++ {
+		span := &model.Span{TraceID: model.NewTraceID(i*0x9E3779B97F4A7C15, i*0xBF58476D1CE4E5B9)}
+		decision := p.ShouldSample([]*model.Span{span})
+		assert.Equal(t, decision, NewProbabilisticPolicy(0.5, "salt").ShouldSample([]*model.Span{span}))
+		if decision {
+			sampled++
+		}
+	}
+	assert.InDelta(t, 500, sampled, 100)
+}
+
+func TestPoliciesFromOptions(t *testing.T) {
+	assert.Empty(t, PoliciesFromOptions(Options{}))
+	policies := PoliciesFromOptions(Options{SampleErrors: true, LatencyThreshold: time.Second, ProbabilisticRatio: 0.1})
+	var names []string
+	for _, p := range policies {
+		names = append(names, p.Name())
+	}
+	assert.Equal(t, []string{"error", "latency", "probabilistic"}, names)
+}
@@ -0,0 +1,276 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tailsampling
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/cmd/collector/app/processor"
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/cache"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+type tailSamplingMetrics struct {
+	TracesSampled    metrics.Counter `metric:"traces" tags:"result=sampled"`
+	TracesNotSampled metrics.Counter `metric:"traces" tags:"result=not_sampled"`
+	TracesEvicted    metrics.Counter `metric:"traces_evicted"`
+	SpansDropped     metrics.Counter `metric:"spans_dropped"`
+	LateSpans        metrics.Counter `metric:"late_spans"`
+	BufferedTraces   metrics.Gauge   `metric:"buffered_traces"`
+}
+
+type traceBuffer struct {
+	traceID   model.TraceID
+	firstSeen time.Time
+	spans     []*model.Span
+	saved     []func(err error)
+	element   *list.Element
+}
+
+// Processor is a span Writer that buffers spans per trace for a decision window and
+// only forwards traces accepted by at least one of the sampling policies.
+type Processor struct {
+	spanWriter   spanstore.Writer
+	policies     []Policy
+	decisionWait time.Duration
+	maxTraces    int
+	logger       *zap.Logger
+	metrics      tailSamplingMetrics
+	policyHits   map[string]metrics.Counter
+
+	lock   sync.Mutex
+	traces map[model.TraceID]*traceBuffer
+	order  *list.List // traces in the order of their first span
+
+	// decisions remembers recent decisions so that spans arriving late follow them
+	decisions cache.Cache
+
+	timeNow func() time.Time
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewProcessor creates a tail sampling Processor writing sampled traces to spanWriter.
+func NewProcessor(spanWriter spanstore.Writer, opts Options, logger *zap.Logger, metricsFactory metrics.Factory) *Processor {
+	if opts.DecisionWait <= 0 {
+		opts.DecisionWait = defaultDecisionWait
+	}
+	if opts.MaxTraces <= 0 {
+		opts.MaxTraces = defaultMaxTraces
+	}
+	p := &Processor{
+		spanWriter:   spanWriter,
+		policies:     PoliciesFromOptions(opts),
+		decisionWait: opts.DecisionWait,
+		maxTraces:    opts.MaxTraces,
+		logger:       logger,
+		policyHits:   make(map[string]metrics.Counter),
+		traces:       make(map[model.TraceID]*traceBuffer),
+		order:        list.New(),
+		decisions:    cache.NewLRU(opts.MaxTraces),
+		timeNow:      time.Now,
+		stopCh:       make(chan struct{}),
+	}
+	metrics.Init(&p.metrics, metricsFactory, nil)
+	for _, policy := range p.policies {
+		p.policyHits[policy.Name()] = metricsFactory.Counter(metrics.Options{
+			Name: "sampled_by_policy",
+			Tags: map[string]string{"policy": policy.Name()},
+		})
+	}
+	logger.Info("Tail sampling enabled", zap.Duration("decision-wait", p.decisionWait), zap.Int("max-traces", p.maxTraces), zap.Int("policies", len(p.policies)))
+	return p
+}
+
+// Start launches the background routine that decides traces whose window has elapsed.
+func (p *Processor) Start() {
+	interval := p.decisionWait / 10
+	if interval < 100*time.Millisecond {
+		interval = 100 * time.Millisecond
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.decideExpired()
+			case <-p.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// WriteSpan buffers the span until its trace is decided, or applies an earlier decision for late spans.
+// Buffered spans and dropped late spans are reported as written, use WriteSpanDeferred to learn
+// whether each span was saved.
+func (p *Processor) WriteSpan(span *model.Span) error {
+	decided, sampled := p.buffer(span, nil)
+	if !decided {
+		return nil
+	}
+	p.metrics.LateSpans.Inc(1)
+	if !sampled {
+		p.metrics.SpansDropped.Inc(1)
+		return nil
+	}
+	return p.spanWriter.WriteSpan(span)
+}
+
+// WriteSpanDeferred buffers the span like WriteSpan, and calls saved with the result of writing
+// the span once its trace is decided, or with processor.ErrSpanDropped if the trace is not sampled.
+func (p *Processor) WriteSpanDeferred(span *model.Span, saved func(err error)) {
+	decided, sampled := p.buffer(span, saved)
+	if !decided {
+		return
+	}
+	p.metrics.LateSpans.Inc(1)
+	if !sampled {
+		p.metrics.SpansDropped.Inc(1)
+		saved(processor.ErrSpanDropped)
+		return
+	}
+	saved(p.spanWriter.WriteSpan(span))
+}
+
+// buffer adds the span to its trace, unless the trace was already decided in which case the decision is returned.
+// The decisions are looked up and recorded under the same lock as the buffered traces, so that a span arriving
+// while its trace is being decided either joins the trace or follows the decision.
+func (p *Processor) buffer(span *model.Span, saved func(err error)) (decided bool, sampled bool) {
+	var evicted *traceBuffer
+	var evictedSampled bool
+	p.lock.Lock()
+	if d := p.decisions.Get(span.TraceID.String()); d != nil {
+		p.lock.Unlock()
+		return true, d.(bool)
+	}
+	tb, ok := p.traces[span.TraceID]
+	if !ok {
+		if len(p.traces) >= p.maxTraces {
+			evicted = p.removeLocked(p.order.Front())
+			evictedSampled = p.decideLocked(evicted)
+		}
+		tb = &traceBuffer{traceID: span.TraceID, firstSeen: p.timeNow()}
+		tb.element = p.order.PushBack(tb)
+		p.traces[span.TraceID] = tb
+	}
+	tb.spans = append(tb.spans, span)
+	tb.saved = append(tb.saved, saved)
+	p.lock.Unlock()
+
+	if evicted != nil {
+		p.metrics.TracesEvicted.Inc(1)
+		p.write(evicted, evictedSampled)
+	}
+	return false, false
+}
+
+// Close decides all buffered traces immediately and stops the background routine.
+func (p *Processor) Close() error {
+	close(p.stopCh)
+	p.wg.Wait()
+	p.Flush()
+	return nil
+}
+
+// Flush decides all buffered traces immediately, e.g. when the collector is drained.
+func (p *Processor) Flush() {
+	p.lock.Lock()
+	var remaining []*traceBuffer
+	var sampled []bool
+	for p.order.Len() > 0 {
+		tb := p.removeLocked(p.order.Front())
+		remaining = append(remaining, tb)
+		sampled = append(sampled, p.decideLocked(tb))
+	}
+	p.metrics.BufferedTraces.Update(0)
+	p.lock.Unlock()
+
+	for i, tb := range remaining {
+		p.write(tb, sampled[i])
+	}
+}
+
+func (p *Processor) decideExpired() {
+	deadline := p.timeNow().Add(-p.decisionWait)
+	var expired []*traceBuffer
+	var sampled []bool
+	p.lock.Lock()
+	for e := p.order.Front(); e != nil && !e.Value.(*traceBuffer).firstSeen.After(deadline); e = p.order.Front() {
+		tb := p.removeLocked(e)
+		expired = append(expired, tb)
+		sampled = append(sampled, p.decideLocked(tb))
+	}
+	p.metrics.BufferedTraces.Update(int64(len(p.traces)))
+	p.lock.Unlock()
+
+	for i, tb := range expired {
+		p.write(tb, sampled[i])
+	}
+}
+
+func (p *Processor) removeLocked(e *list.Element) *traceBuffer {
+	tb := p.order.Remove(e).(*traceBuffer)
+	delete(p.traces, tb.traceID)
+	return tb
+}
+
+// decideLocked applies the policies to a trace removed from the buffer and records the decision
+// for its late spans before the lock is released.
+func (p *Processor) decideLocked(tb *traceBuffer) bool {
+	sampled := false
+	for _, policy := range p.policies {
+		if policy.ShouldSample(tb.spans) {
+			p.policyHits[policy.Name()].Inc(1)
+			sampled = true
+			break
+		}
+	}
+	p.decisions.Put(tb.traceID.String(), sampled)
+	return sampled
+}
+
+// write writes the spans of a decided trace, or drops them if it is not sampled
+func (p *Processor) write(tb *traceBuffer, sampled bool) {
+	if !sampled {
+		p.metrics.TracesNotSampled.Inc(1)
+		p.metrics.SpansDropped.Inc(int64(len(tb.spans)))
+		for _, saved := range tb.saved {
+			if saved != nil {
+				saved(processor.ErrSpanDropped)
+			}
+		}
+		return
+	}
+	p.metrics.TracesSampled.Inc(1)
+	for i, span := range tb.spans {
+		err := p.spanWriter.WriteSpan(span)
+		if err != nil {
+			p.logger.Error("Failed to save tail sampled span", zap.Error(err), zap.Stringer("trace-id", span.TraceID))
+		}
+		if tb.saved[i] != nil {
+			tb.saved[i](err)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tailsampling
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/cmd/collector/app/processor"
+	"github.com/jaegertracing/jaeger/model"
+)
+
+type fakeWriter struct {
+	lock  sync.Mutex
+	spans []*model.Span
+}
+
+func (w *fakeWriter) WriteSpan(span *model.Span) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.spans = append(w.spans, span)
+	return nil
+}
+
+func (w *fakeWriter) count() int {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return len(w.spans)
+}
+
+func errorSpan(traceID uint64) *model.Span {
+	return &model.Span{TraceID: model.NewTraceID(0, traceID), Tags: model.KeyValues{model.Bool("error", true)}}
+}
+
+func okSpan(traceID uint64) *model.Span {
+	return &model.Span{TraceID: model.NewTraceID(0, traceID)}
+}
+
+func newTestProcessor(opts Options) (*Processor, *fakeWriter, *metricstest.Factory, *time.Time) {
+	writer := &fakeWriter{}
+	mf := metricstest.NewFactory(0)
+	p := NewProcessor(writer, opts, zap.NewNop(), mf)
+	now := time.Unix(1000, 0)
+	p.timeNow = func() time.Time { return now }
+	return p, writer, mf, &now
+}
+
+func TestProcessorDecidesAfterWindow(t *testing.T) {
+	p, writer, mf, now := newTestProcessor(Options{DecisionWait: time.Second, SampleErrors: true})
+
+	require.NoError(t, p.WriteSpan(okSpan(1)))
+	require.NoError(t, p.WriteSpan(okSpan(2)))
+	require.NoError(t, p.WriteSpan(errorSpan(2)))
+	p.decideExpired()
+	assert.Equal(t, 0, writer.count(), "nothing is decided before the window elapses")
+
+	*now = now.Add(time.Second)
+	p.decideExpired()
+	assert.Equal(t, 2, writer.count())
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "traces", Tags: map[string]string{"result": "sampled"}, Value: 1},
+		metricstest.ExpectedMetric{Name: "traces", Tags: map[string]string{"result": "not_sampled"}, Value: 1},
+		metricstest.ExpectedMetric{Name: "spans_dropped", Value: 1},
+		metricstest.ExpectedMetric{Name: "sampled_by_policy", Tags: map[string]string{"policy": "error"}, Value: 1},
+	)
+	mf.AssertGaugeMetrics(t, metricstest.ExpectedMetric{Name: "buffered_traces", Value: 0})
+
+	// late spans follow the earlier decision
+	require.NoError(t, p.WriteSpan(okSpan(2)))
+	require.NoError(t, p.WriteSpan(errorSpan(1)))
+	assert.Equal(t, 3, writer.count())
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "late_spans", Value: 2},
+		metricstest.ExpectedMetric{Name: "spans_dropped", Value: 2},
+	)
+	require.NoError(t, p.Close())
+}
+
+func TestProcessorEvictsOldestTrace(t *testing.T) {
+	p, writer, mf, _ := newTestProcessor(Options{MaxTraces: 2, SampleErrors: true})
+
+	require.NoError(t, p.WriteSpan(errorSpan(1)))
+	require.NoError(t, p.WriteSpan(okSpan(2)))
+	require.NoError(t, p.WriteSpan(okSpan(3)))
+	assert.Equal(t, 1, writer.count())
+	mf.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "traces_evicted", Value: 1})
+	require.NoError(t, p.Close())
+}
+
+func TestProcessorCloseFlushes(t *testing.T) {
+	p, writer, _, _ := newTestProcessor(Options{DecisionWait: time.Hour, SampleErrors: true})
+	p.Start()
+	require.NoError(t, p.WriteSpan(errorSpan(1)))
+	require.NoError(t, p.WriteSpan(okSpan(1)))
+	require.NoError(t, p.WriteSpan(okSpan(2)))
+	require.NoError(t, p.Close())
+	assert.Equal(t, 2, writer.count())
+}
+
+func TestProcessorFlush(t *testing.T) {
+	p, writer, _, _ := newTestProcessor(Options{DecisionWait: time.Hour, SampleErrors: true})
+	p.Start()
+	defer p.Close()
+	require.NoError(t, p.WriteSpan(errorSpan(1)))
+	require.NoError(t, p.WriteSpan(okSpan(2)))
+	p.Flush()
+	assert.Equal(t, 1, writer.count())
+
+	require.NoError(t, p.WriteSpan(okSpan(1)))
+	assert.Equal(t, 2, writer.count(), "the decision made by the flush applies to late spans")
+}
+
+func TestProcessorWriteSpanDeferred(t *testing.T) {
+	p, writer, _, now := newTestProcessor(Options{DecisionWait: time.Second, SampleErrors: true})
+
+	results := make(map[uint64][]error)
+	saved := func(traceID uint64) func(err error) {
+		return func(err error) { results[traceID] = append(results[traceID], err) }
+	}
+	p.WriteSpanDeferred(errorSpan(1), saved(1))
+	p.WriteSpanDeferred(okSpan(2), saved(2))
+	assert.Empty(t, results, "the result is only known once the trace is decided")
+
+	*now = now.Add(time.Second)
+	p.decideExpired()
+	assert.Equal(t, []error{nil}, results[1])
+	assert.Equal(t, []error{processor.ErrSpanDropped}, results[2], "dropped spans are not reported as saved")
+
+	p.WriteSpanDeferred(okSpan(1), saved(1))
+	p.WriteSpanDeferred(okSpan(2), saved(2))
+	assert.Equal(t, []error{nil, nil}, results[1])
+	assert.Equal(t, []error{processor.ErrSpanDropped, processor.ErrSpanDropped}, results[2])
+	assert.Equal(t, 2, writer.count())
+	require.NoError(t, p.Close())
+}
+
+type blockingPolicy struct {
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (blockingPolicy) Name() string { return "blocking" }
+
+func (b blockingPolicy) ShouldSample(spans []*model.Span) bool {
+	close(b.entered)
+	<-b.release
+	return true
+}
+
+func TestProcessorSpanDuringDecision(t *testing.T) {
+	p, writer, mf, now := newTestProcessor(Options{DecisionWait: time.Second})
+	policy := blockingPolicy{entered: make(chan struct{}), release: make(chan struct{})}
+	p.policies = []Policy{policy}
+	p.policyHits[policy.Name()] = mf.Counter(metrics.Options{Name: "sampled_by_policy"})
+
+	require.NoError(t, p.WriteSpan(okSpan(1)))
+	*now = now.Add(time.Second)
+	decided := make(chan struct{})
+	go func() {
+		p.decideExpired()
+		close(decided)
+	}()
+	<-policy.entered
+
+	written := make(chan error)
+	go func() {
+		written <- p.WriteSpan(okSpan(1))
+	}()
+	close(policy.release)
+	<-decided
+	require.NoError(t, <-written)
+
+	assert.Equal(t, 2, writer.count(), "a span arriving during the decision follows it")
+	mf.AssertGaugeMetrics(t, metricstest.ExpectedMetric{Name: "buffered_traces", Value: 0})
+	mf.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "late_spans", Value: 1})
+	require.NoError(t, p.Close())
+}
+
+func TestProcessorBackgroundDecisions(t *testing.T) {
+	writer := &fakeWriter{}
+	p := NewProcessor(writer, Options{DecisionWait: time.Millisecond, ProbabilisticRatio: 1}, zap.NewNop(), metricstest.NewFactory(0))
+	p.Start()
+	defer p.Close()
+	require.NoError(t, p.WriteSpan(okSpan(1)))
+	for i := 0; i < 100 && writer.count() == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, 1, writer.count())
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tenantquota
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	spansPerSecond       = "collector.tenant-quota.spans-per-second"
+	bytesPerSecond       = "collector.tenant-quota.bytes-per-second"
+	tenantSpansPerSecond = "collector.tenant-quota.tenant-spans-per-second"
+	tenantBytesPerSecond = "collector.tenant-quota.tenant-bytes-per-second"
+	enforcement          = "collector.tenant-quota.enforcement"
+	maxThrottleWait      = "collector.tenant-quota.max-throttle-wait"
+
+	// EnforcementReject rejects the batches exceeding the quota right away
+	EnforcementReject = "reject"
+	// EnforcementThrottle delays the batches exceeding the quota until they fit, up to the max throttle wait
+	EnforcementThrottle = "throttle"
+
+	defaultMaxThrottleWait = time.Second
+)
+
+// Options configures the per-tenant ingestion quotas of the collector.
+type Options struct {
+	// SpansPerSecond is the quota of each tenant without an explicit quota, 0 means unlimited
+	SpansPerSecond float64
+	// BytesPerSecond is the quota of each tenant without an explicit quota, 0 means unlimited
+	BytesPerSecond float64
+	// TenantSpansPerSecond holds explicit quotas as a comma-separated list of tenant=spans-per-second
+	TenantSpansPerSecond string
+	// TenantBytesPerSecond holds explicit quotas as a comma-separated list of tenant=bytes-per-second
+	TenantBytesPerSecond string
+	// Enforcement is either EnforcementReject or EnforcementThrottle
+	Enforcement string
+	// MaxThrottleWait is the longest a batch is delayed when throttling, batches needing more are rejected
+	MaxThrottleWait time.Duration
+}
+
+// AddFlags adds flags for tenant quota Options
+func AddFlags(flags *flag.FlagSet) {
+	flags.Float64(spansPerSecond, 0, "The maximum number of spans per second accepted from each tenant without an explicit quota, 0 means unlimited")
+	flags.Float64(bytesPerSecond, 0, "The maximum number of span bytes per second accepted from each tenant without an explicit quota, 0 means unlimited")
+	flags.String(tenantSpansPerSecond, "", "Comma-separated list of per-tenant quotas in spans per second, overriding --"+spansPerSecond+", 0 means unlimited. Ex: acme=100,globex=500")
+	flags.String(tenantBytesPerSecond, "", "Comma-separated list of per-tenant quotas in bytes per second, overriding --"+bytesPerSecond+", 0 means unlimited. Ex: acme=1048576")
+	flags.String(enforcement, EnforcementReject, fmt.Sprintf("How batches exceeding the quota of their tenant are handled: %q rejects them with HTTP 429 or gRPC RESOURCE_EXHAUSTED, "+
+		"%q delays the request until the batch fits in the quota", EnforcementReject, EnforcementThrottle))
+	flags.Duration(maxThrottleWait, defaultMaxThrottleWait, "The longest a request is delayed when throttling; batches that would need to wait longer are rejected")
+}
+
+// InitFromViper initializes Options with properties from viper
+func (o *Options) InitFromViper(v *viper.Viper) *Options {
+	o.SpansPerSecond = v.GetFloat64(spansPerSecond)
+	o.BytesPerSecond = v.GetFloat64(bytesPerSecond)
+	o.TenantSpansPerSecond = v.GetString(tenantSpansPerSecond)
+	o.TenantBytesPerSecond = v.GetString(tenantBytesPerSecond)
+	o.Enforcement = v.GetString(enforcement)
+	o.MaxThrottleWait = v.GetDuration(maxThrottleWait)
+	return o
+}
+
+// Enabled returns true if any quota is configured
+func (o *Options) Enabled() bool {
+	return o.SpansPerSecond > 0 || o.BytesPerSecond > 0 || o.TenantSpansPerSecond != "" || o.TenantBytesPerSecond != ""
+}
@@ -0,0 +1,233 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tenantquota
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+const (
+	// otherTenants is the metrics tag and the shared quota of tenants without an explicit quota,
+	// once the number of tracked tenants reaches maxTenants
+	otherTenants = "other-tenants"
+	maxTenants   = 1000
+)
+
+// bucket is a token bucket allowing bursts of one second worth of quota. A batch larger
+// than the burst is accepted when the bucket is full and leaves the bucket in debt.
+type bucket struct {
+	rate     float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newBucket(rate float64, now time.Time) *bucket {
+	if rate <= 0 {
+		return nil
+	}
+	capacity := rate
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &bucket{rate: rate, capacity: capacity, tokens: capacity, last: now}
+}
+
+// wait refills the bucket and returns how long it takes until n tokens can be taken.
+func (b *bucket) wait(n float64, now time.Time) time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+	if b.tokens >= n || b.tokens >= b.capacity {
+		return 0
+	}
+	return time.Duration((n - b.tokens) / b.rate * float64(time.Second))
+}
+
+func (b *bucket) take(n float64) {
+	if b != nil {
+		b.tokens -= n
+	}
+}
+
+// tenantMetrics reports the usage of one tenant, suitable for chargeback
+type tenantMetrics struct {
+	AcceptedSpans metrics.Counter `metric:"spans" tags:"result=accepted"`
+	RejectedSpans metrics.Counter `metric:"spans" tags:"result=rejected"`
+	AcceptedBytes metrics.Counter `metric:"bytes" tags:"result=accepted"`
+	RejectedBytes metrics.Counter `metric:"bytes" tags:"result=rejected"`
+	Throttled     metrics.Counter `metric:"throttled_batches"`
+}
+
+type tenantQuota struct {
+	spans   *bucket
+	bytes   *bucket
+	metrics *tenantMetrics
+}
+
+// Quotas enforces spans-per-second and bytes-per-second quotas keyed by tenant.
+type Quotas struct {
+	defaultSpans    float64
+	defaultBytes    float64
+	tenantSpans     map[string]float64
+	tenantBytes     map[string]float64
+	throttle        bool
+	maxThrottleWait time.Duration
+
+	lock    sync.Mutex
+	tenants map[string]*tenantQuota
+
+	metricsFactory metrics.Factory
+	throttleDelay  metrics.Timer
+	timeNow        func() time.Time
+	sleep          func(time.Duration)
+}
+
+// NewQuotas creates Quotas from the options.
+func NewQuotas(opts Options, metricsFactory metrics.Factory) (*Quotas, error) {
+	tenantSpans, err := parseTenantQuotas(opts.TenantSpansPerSecond, "spans-per-second")
+	if err != nil {
+		return nil, err
+	}
+	tenantBytes, err := parseTenantQuotas(opts.TenantBytesPerSecond, "bytes-per-second")
+	if err != nil {
+		return nil, err
+	}
+	switch opts.Enforcement {
+	case "", EnforcementReject, EnforcementThrottle:
+	default:
+		return nil, fmt.Errorf("invalid tenant quota enforcement %q, expecting %q or %q", opts.Enforcement, EnforcementReject, EnforcementThrottle)
+	}
+	if opts.MaxThrottleWait <= 0 {
+		opts.MaxThrottleWait = defaultMaxThrottleWait
+	}
+	q := &Quotas{
+		defaultSpans:    opts.SpansPerSecond,
+		defaultBytes:    opts.BytesPerSecond,
+		tenantSpans:     tenantSpans,
+		tenantBytes:     tenantBytes,
+		throttle:        opts.Enforcement == EnforcementThrottle,
+		maxThrottleWait: opts.MaxThrottleWait,
+		tenants:         make(map[string]*tenantQuota),
+		metricsFactory:  metricsFactory,
+		throttleDelay:   metricsFactory.Timer(metrics.TimerOptions{Name: "throttle_delay"}),
+		timeNow:         time.Now,
+		sleep:           time.Sleep,
+	}
+	return q, nil
+}
+
+func parseTenantQuotas(s string, unit string) (map[string]float64, error) {
+	quotas := make(map[string]float64)
+	if s == "" {
+		return quotas, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("invalid tenant quota %q, expecting tenant=%s", pair, unit)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+		if err != nil || rate < 0 {
+			return nil, fmt.Errorf("invalid tenant quota %q, expecting tenant=%s", pair, unit)
+		}
+		quotas[strings.TrimSpace(kv[0])] = rate
+	}
+	return quotas, nil
+}
+
+// Allow returns true if the batch fits within the quota of the tenant, in which case it is
+// deducted from the quota. When throttling, the call blocks until the batch fits, unless that
+// would take longer than the max throttle wait. Batches without a tenant are not limited.
+func (q *Quotas) Allow(tenant string, spans []*model.Span) bool {
+	if tenant == "" {
+		return true
+	}
+	n := float64(len(spans))
+	size := 0
+	for _, span := range spans {
+		size += span.Size()
+	}
+
+	q.lock.Lock()
+	tq := q.quotaLocked(tenant)
+	now := q.timeNow()
+	wait := tq.spans.wait(n, now)
+	if w := tq.bytes.wait(float64(size), now); w > wait {
+		wait = w
+	}
+	if wait > 0 && (!q.throttle || wait > q.maxThrottleWait) {
+		q.lock.Unlock()
+		tq.metrics.RejectedSpans.Inc(int64(len(spans)))
+		tq.metrics.RejectedBytes.Inc(int64(size))
+		return false
+	}
+	// reserving the tokens before waiting makes the next batches of the tenant wait longer
+	tq.spans.take(n)
+	tq.bytes.take(float64(size))
+	q.lock.Unlock()
+
+	if wait > 0 {
+		tq.metrics.Throttled.Inc(1)
+		q.throttleDelay.Record(wait)
+		q.sleep(wait)
+	}
+	tq.metrics.AcceptedSpans.Inc(int64(len(spans)))
+	tq.metrics.AcceptedBytes.Inc(int64(size))
+	return true
+}
+
+func (q *Quotas) quotaLocked(tenant string) *tenantQuota {
+	if tq, ok := q.tenants[tenant]; ok {
+		return tq
+	}
+	spansRate, explicitSpans := q.tenantSpans[tenant]
+	if !explicitSpans {
+		spansRate = q.defaultSpans
+	}
+	bytesRate, explicitBytes := q.tenantBytes[tenant]
+	if !explicitBytes {
+		bytesRate = q.defaultBytes
+	}
+	key := tenant
+	if !explicitSpans && !explicitBytes && len(q.tenants) >= maxTenants {
+		key = otherTenants
+		if tq, ok := q.tenants[key]; ok {
+			return tq
+		}
+	}
+	now := q.timeNow()
+	tq := &tenantQuota{
+		spans:   newBucket(spansRate, now),
+		bytes:   newBucket(bytesRate, now),
+		metrics: &tenantMetrics{},
+	}
+	metrics.Init(tq.metrics, q.metricsFactory, map[string]string{"tenant": key})
+	q.tenants[key] = tq
+	return tq
+}
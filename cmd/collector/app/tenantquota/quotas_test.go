@@ -0,0 +1,163 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tenantquota
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/config"
+)
+
+func makeSpans(n int) []*model.Span {
+	spans := make([]*model.Span, n)
+	for i := range spans {
+		spans[i] = &model.Span{OperationName: "op", Process: &model.Process{ServiceName: "svc"}}
+	}
+	return spans
+}
+
+func newTestQuotas(t *testing.T, opts Options) (*Quotas, *metricstest.Factory, *time.Time, *time.Duration) {
+	mf := metricstest.NewFactory(0)
+	q, err := NewQuotas(opts, mf)
+	require.NoError(t, err)
+	now := time.Unix(1000, 0)
+	var slept time.Duration
+	q.timeNow = func() time.Time { return now }
+	q.sleep = func(d time.Duration) {
+		slept += d
+		now = now.Add(d)
+	}
+	return q, mf, &now, &slept
+}
+
+func TestQuotasReject(t *testing.T) {
+	q, mf, now, _ := newTestQuotas(t, Options{SpansPerSecond: 10, TenantSpansPerSecond: "acme=2, globex=0"})
+
+	assert.True(t, q.Allow("acme", makeSpans(2)))
+	assert.False(t, q.Allow("acme", makeSpans(1)))
+	assert.True(t, q.Allow("globex", makeSpans(1000)), "0 means unlimited")
+	assert.True(t, q.Allow("initech", makeSpans(10)))
+	assert.False(t, q.Allow("initech", makeSpans(1)))
+	assert.True(t, q.Allow("", makeSpans(1000)), "spans without a tenant are not limited")
+
+	*now = now.Add(500 * time.Millisecond)
+	assert.True(t, q.Allow("acme", makeSpans(1)))
+	assert.True(t, q.Allow("initech", makeSpans(5)))
+
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "spans", Tags: map[string]string{"tenant": "acme", "result": "accepted"}, Value: 3},
+		metricstest.ExpectedMetric{Name: "spans", Tags: map[string]string{"tenant": "acme", "result": "rejected"}, Value: 1},
+		metricstest.ExpectedMetric{Name: "spans", Tags: map[string]string{"tenant": "globex", "result": "accepted"}, Value: 1000},
+		metricstest.ExpectedMetric{Name: "spans", Tags: map[string]string{"tenant": "initech", "result": "accepted"}, Value: 15},
+		metricstest.ExpectedMetric{Name: "spans", Tags: map[string]string{"tenant": "initech", "result": "rejected"}, Value: 1},
+	)
+}
+
+func TestQuotasBytes(t *testing.T) {
+	spanSize := makeSpans(1)[0].Size()
+	q, mf, _, _ := newTestQuotas(t, Options{TenantBytesPerSecond: "acme=" + strconv.Itoa(3*spanSize)})
+
+	assert.True(t, q.Allow("acme", makeSpans(3)))
+	assert.False(t, q.Allow("acme", makeSpans(1)))
+	assert.True(t, q.Allow("globex", makeSpans(100)), "tenants without a quota are unlimited")
+
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "bytes", Tags: map[string]string{"tenant": "acme", "result": "accepted"}, Value: 3 * spanSize},
+		metricstest.ExpectedMetric{Name: "bytes", Tags: map[string]string{"tenant": "acme", "result": "rejected"}, Value: spanSize},
+	)
+}
+
+func TestQuotasThrottle(t *testing.T) {
+	q, mf, _, slept := newTestQuotas(t, Options{
+		TenantSpansPerSecond: "acme=10",
+		Enforcement:          EnforcementThrottle,
+		MaxThrottleWait:      time.Second,
+	})
+
+	assert.True(t, q.Allow("acme", makeSpans(10)))
+	assert.Equal(t, time.Duration(0), *slept)
+	assert.True(t, q.Allow("acme", makeSpans(5)))
+	assert.Equal(t, 500*time.Millisecond, *slept, "the batch waits until it fits in the quota")
+	assert.False(t, q.Allow("acme", makeSpans(20)), "batches needing more than the max wait are rejected")
+	assert.Equal(t, 500*time.Millisecond, *slept)
+
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "throttled_batches", Tags: map[string]string{"tenant": "acme"}, Value: 1},
+		metricstest.ExpectedMetric{Name: "spans", Tags: map[string]string{"tenant": "acme", "result": "accepted"}, Value: 15},
+		metricstest.ExpectedMetric{Name: "spans", Tags: map[string]string{"tenant": "acme", "result": "rejected"}, Value: 20},
+	)
+}
+
+func TestQuotasLargeBatch(t *testing.T) {
+	q, _, now, _ := newTestQuotas(t, Options{SpansPerSecond: 10})
+
+	assert.True(t, q.Allow("acme", makeSpans(30)), "a batch larger than the burst passes when the bucket is full")
+	*now = now.Add(2 * time.Second)
+	assert.False(t, q.Allow("acme", makeSpans(1)), "the bucket is still in debt")
+}
+
+func TestQuotasOtherTenants(t *testing.T) {
+	q, mf, _, _ := newTestQuotas(t, Options{SpansPerSecond: 1000})
+	for i := 0; i < maxTenants; i++ {
+		require.True(t, q.Allow("tenant-"+strconv.Itoa(i), makeSpans(1)))
+	}
+	assert.True(t, q.Allow("late-1", makeSpans(600)))
+	assert.False(t, q.Allow("late-2", makeSpans(600)), "new tenants share a single quota")
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "spans", Tags: map[string]string{"tenant": otherTenants, "result": "accepted"}, Value: 600},
+	)
+}
+
+func TestNewQuotasErrors(t *testing.T) {
+	testCases := []Options{
+		{TenantSpansPerSecond: "acme"},
+		{TenantSpansPerSecond: "=1"},
+		{TenantBytesPerSecond: "acme=-1"},
+		{TenantBytesPerSecond: "acme=x"},
+		{SpansPerSecond: 1, Enforcement: "drop"},
+	}
+	for _, opts := range testCases {
+		_, err := NewQuotas(opts, metricstest.NewFactory(0))
+		assert.Error(t, err, "%+v", opts)
+	}
+}
+
+func TestOptionsWithFlags(t *testing.T) {
+	v, command := config.Viperize(AddFlags)
+	opts := new(Options).InitFromViper(v)
+	assert.False(t, opts.Enabled())
+	assert.Equal(t, EnforcementReject, opts.Enforcement)
+	assert.Equal(t, defaultMaxThrottleWait, opts.MaxThrottleWait)
+
+	command.ParseFlags([]string{
+		"--collector.tenant-quota.bytes-per-second=1000",
+		"--collector.tenant-quota.tenant-spans-per-second=acme=5",
+		"--collector.tenant-quota.enforcement=throttle",
+		"--collector.tenant-quota.max-throttle-wait=2s",
+	})
+	opts.InitFromViper(v)
+	assert.True(t, opts.Enabled())
+	assert.Equal(t, 1000.0, opts.BytesPerSecond)
+	assert.Equal(t, "acme=5", opts.TenantSpansPerSecond)
+	assert.Equal(t, EnforcementThrottle, opts.Enforcement)
+	assert.Equal(t, 2*time.Second, opts.MaxThrottleWait)
+}
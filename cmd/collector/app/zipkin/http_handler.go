@@ -33,6 +33,7 @@ import (
 	"github.com/jaegertracing/jaeger/cmd/collector/app/handler"
 	"github.com/jaegertracing/jaeger/cmd/collector/app/processor"
 	"github.com/jaegertracing/jaeger/model/converter/thrift/zipkin"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
 	zipkinProto "github.com/jaegertracing/jaeger/proto-gen/zipkin"
 	"github.com/jaegertracing/jaeger/swagger-gen/models"
 	"github.com/jaegertracing/jaeger/swagger-gen/restapi"
@@ -44,16 +45,19 @@ import (
 type APIHandler struct {
 	zipkinSpansHandler handler.ZipkinSpansHandler
 	zipkinV2Formats    strfmt.Registry
+	tenancyMgr         *tenancy.Manager
 }
 
 // NewAPIHandler returns a new APIHandler
 func NewAPIHandler(
 	zipkinSpansHandler handler.ZipkinSpansHandler,
+	tenancyMgr *tenancy.Manager,
 ) *APIHandler {
 	swaggerSpec, _ := loads.Analyzed(restapi.SwaggerJSON, "")
 	return &APIHandler{
 		zipkinSpansHandler: zipkinSpansHandler,
 		zipkinV2Formats:    operations.NewZipkinAPI(swaggerSpec).Formats(),
+		tenancyMgr:         tenancyMgr,
 	}
 }
 
@@ -66,6 +70,11 @@ func (aH *APIHandler) RegisterRoutes(router *mux.Router) {
 func (aH *APIHandler) saveSpans(w http.ResponseWriter, r *http.Request) {
 	bRead := r.Body
 	defer r.Body.Close()
+	tenant, err := aH.tenancyMgr.GetValidHTTPTenant(r)
+	if err != nil {
+		http.Error(w, err.Error(), tenancy.HTTPStatus(err))
+		return
+	}
 	if strings.Contains(r.Header.Get("Content-Encoding"), "gzip") {
 		gz, err := gunzip(bRead)
 		if err != nil {
@@ -104,8 +113,8 @@ func (aH *APIHandler) saveSpans(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := aH.saveThriftSpans(tSpans); err != nil {
-		http.Error(w, fmt.Sprintf("Cannot submit Zipkin batch: %v", err), http.StatusInternalServerError)
+	if err := aH.saveThriftSpans(tSpans, tenant, handler.HTTPClientInfo(r)); err != nil {
+		handler.WriteSubmitError(w, fmt.Sprintf("Cannot submit Zipkin batch: %v", err), err, http.StatusInternalServerError)
 		return
 	}
 
@@ -115,6 +124,11 @@ func (aH *APIHandler) saveSpans(w http.ResponseWriter, r *http.Request) {
 func (aH *APIHandler) saveSpansV2(w http.ResponseWriter, r *http.Request) {
 	bRead := r.Body
 	defer r.Body.Close()
+	tenant, err := aH.tenancyMgr.GetValidHTTPTenant(r)
+	if err != nil {
+		http.Error(w, err.Error(), tenancy.HTTPStatus(err))
+		return
+	}
 	if strings.Contains(r.Header.Get("Content-Encoding"), "gzip") {
 		gz, err := gunzip(bRead)
 		if err != nil {
@@ -142,7 +156,8 @@ func (aH *APIHandler) saveSpansV2(w http.ResponseWriter, r *http.Request) {
 	switch contentType {
 	case "application/json":
 		tSpans, err = jsonToThriftSpansV2(bodyBytes, aH.zipkinV2Formats)
-	case "application/x-protobuf":
+	case "application/x-protobuf", "application/protobuf":
+		// Zipkin reporters send the same proto3 ListOfSpans as written to Kafka topics
 		tSpans, err = protoToThriftSpansV2(bodyBytes)
 	default:
 		http.Error(w, "Unsupported Content-Type", http.StatusBadRequest)
@@ -154,8 +169,8 @@ func (aH *APIHandler) saveSpansV2(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err = aH.saveThriftSpans(tSpans); err != nil {
-		http.Error(w, fmt.Sprintf("Cannot submit Zipkin batch: %v", err), http.StatusInternalServerError)
+	if err = aH.saveThriftSpans(tSpans, tenant, handler.HTTPClientInfo(r)); err != nil {
+		handler.WriteSubmitError(w, fmt.Sprintf("Cannot submit Zipkin batch: %v", err), err, http.StatusInternalServerError)
 		return
 	}
 
@@ -199,9 +214,9 @@ func gunzip(r io.ReadCloser) (*gzip.Reader, error) {
 	return gz, nil
 }
 
-func (aH *APIHandler) saveThriftSpans(tSpans []*zipkincore.Span) error {
+func (aH *APIHandler) saveThriftSpans(tSpans []*zipkincore.Span, tenant string, client processor.ClientInfo) error {
 	if len(tSpans) > 0 {
-		opts := handler.SubmitBatchOptions{InboundTransport: processor.HTTPTransport}
+		opts := handler.SubmitBatchOptions{InboundTransport: processor.HTTPTransport, Tenant: tenant, Client: client}
 		if _, err := aH.zipkinSpansHandler.SubmitZipkinBatch(tSpans, opts); err != nil {
 			return err
 		}
@@ -63,7 +63,7 @@ func (p *mockZipkinHandler) getSpans() []*zipkincore.Span {
 
 func initializeTestServer(err error) (*httptest.Server, *APIHandler) {
 	r := mux.NewRouter()
-	handler := NewAPIHandler(&mockZipkinHandler{err: err})
+	handler := NewAPIHandler(&mockZipkinHandler{err: err}, nil)
 	handler.RegisterRoutes(r)
 	return httptest.NewServer(r), handler
 }
@@ -229,7 +229,7 @@ func TestFormatBadBody(t *testing.T) {
 }
 
 func TestCannotReadBodyFromRequest(t *testing.T) {
-	handler := NewAPIHandler(&mockZipkinHandler{})
+	handler := NewAPIHandler(&mockZipkinHandler{}, nil)
 	req, err := http.NewRequest(http.MethodPost, "whatever", &errReader{})
 	assert.NoError(t, err)
 	rw := dummyResponseWriter{}
@@ -324,7 +324,12 @@ func TestSaveProtoSpansV2(t *testing.T) {
 	assert.EqualValues(t, http.StatusBadRequest, statusCode)
 	assert.EqualValues(t, "Unable to process request body: unexpected EOF\n", resBody)
 
-	reqBytes, _ = proto.Marshal(&zipkinProto.ListOfSpans{Spans: []*zipkinProto.Span{{Id: validID, TraceId: validTraceID}}})
+	reqBytes, _ = proto.Marshal(&zipkinProto.ListOfSpans{Spans: []*zipkinProto.Span{{Id: validID, TraceId: validTraceID,
+		LocalEndpoint: &zipkinProto.Endpoint{ServiceName: "foo", Ipv6: randBytesOfLen(16)}}}})
+	statusCode, _, err = postBytes(server.URL+`/api/v2/spans`, reqBytes, createHeader("application/protobuf"))
+	require.NoError(t, err)
+	assert.EqualValues(t, http.StatusAccepted, statusCode)
+
 	handler.zipkinSpansHandler.(*mockZipkinHandler).err = fmt.Errorf("Bad times ahead")
 	statusCode, resBody, err = postBytes(server.URL+`/api/v2/spans`, reqBytes, createHeader("application/x-protobuf"))
 	require.NoError(t, err)
@@ -179,7 +179,11 @@ func protoEndpointV2ToThrift(e *zipkinProto.Endpoint) (*zipkincore.Endpoint, err
 	if lv6 > 0 && lv6 != net.IPv6len {
 		return nil, fmt.Errorf("wrong Ipv6")
 	}
-	ipv4 := binary.BigEndian.Uint32(e.Ipv4)
+	var ipv4 uint32
+	if lv4 > 0 {
+		// IPv6-only endpoints do not have an IPv4 address
+		ipv4 = binary.BigEndian.Uint32(e.Ipv4)
+	}
 	port := port(e.Port)
 	return &zipkincore.Endpoint{
 		ServiceName: e.ServiceName,
@@ -135,3 +135,10 @@ func randBytesOfLen(n int) []byte {
 	rand.Read(b)
 	return b
 }
+
+func TestProtoEndpointV2ToThriftIPv6Only(t *testing.T) {
+	ipv6 := randBytesOfLen(16)
+	e, err := protoEndpointV2ToThrift(&zipkinProto.Endpoint{ServiceName: "foo", Ipv6: ipv6, Port: 8080})
+	require.NoError(t, err)
+	assert.Equal(t, &zipkincore.Endpoint{ServiceName: "foo", Ipv6: ipv6, Port: 8080}, e)
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zpages
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"time"
+)
+
+// TracezPath is the path of the span viewer page on the admin server
+const TracezPath = "/debug/tracez"
+
+type page struct {
+	Now    time.Time     `json:"now"`
+	Rates  []ServiceRate `json:"serviceRates"`
+	Recent []SpanSummary `json:"recentSpans"`
+	Errors []SpanSummary `json:"errorSpans"`
+}
+
+var tracezTemplate = template.Must(template.New("tracez").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>Jaeger Collector - Tracez</title>
+<style>
+body { font-family: sans-serif; font-size: 14px; }
+table { border-collapse: collapse; margin-bottom: 24px; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+tr.error td { background: #fdd; }
+</style>
+</head>
+<body>
+<h1>Tracez</h1>
+<p>Generated at {{.Now.Format "2006-01-02 15:04:05 MST"}}. Also available as <a href="?format=json">JSON</a>.</p>
+<h2>Spans per second by service (last minute)</h2>
+<table>
+<tr><th>Service</th><th>Spans/s</th></tr>
+{{range .Rates}}<tr><td>{{.Service}}</td><td>{{printf "%.2f" .SpansPerSecond}}</td></tr>
+{{else}}<tr><td colspan="2">No spans received</td></tr>
+{{end}}</table>
+{{define "spans"}}<table>
+<tr><th>Received</th><th>Service</th><th>Operation</th><th>Trace ID</th><th>Span ID</th><th>Start</th><th>Duration</th></tr>
+{{range .}}<tr{{if .Error}} class="error"{{end}}><td>{{.ReceivedAt.Format "15:04:05.000"}}</td><td>{{.Service}}</td><td>{{.Operation}}</td><td>{{.TraceID}}</td><td>{{.SpanID}}</td><td>{{.StartTime.Format "15:04:05.000"}}</td><td>{{.Duration}}</td></tr>
+{{else}}<tr><td colspan="7">No spans received</td></tr>
+{{end}}</table>
+{{end}}
+<h2>Recent error spans</h2>
+{{template "spans" .Errors}}
+<h2>Recent spans</h2>
+{{template "spans" .Recent}}
+</body>
+</html>
+`))
+
+// ServeHTTP renders the recently received spans and the span rates of the services,
+// as HTML by default or as JSON with the "format=json" query parameter.
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	p := page{
+		Now:    r.timeNow(),
+		Rates:  r.ServiceRates(),
+		Recent: r.RecentSpans(),
+		Errors: r.ErrorSpans(),
+	}
+	if req.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(p)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tracezTemplate.Execute(w, p); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
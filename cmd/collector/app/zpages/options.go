@@ -0,0 +1,55 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zpages
+
+import (
+	"flag"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	enabled     = "collector.zpages.enabled"
+	recentSpans = "collector.zpages.recent-spans"
+	errorSpans  = "collector.zpages.error-spans"
+
+	defaultRecentSpans = 100
+	defaultErrorSpans  = 50
+)
+
+// Options controls the span viewer pages of the admin server.
+type Options struct {
+	// Enabled turns on the /debug/tracez page on the admin server
+	Enabled bool
+	// RecentSpans is the number of most recently received spans shown
+	RecentSpans int
+	// ErrorSpans is the number of most recently received error spans shown
+	ErrorSpans int
+}
+
+// AddFlags adds flags for zPages Options
+func AddFlags(flags *flag.FlagSet) {
+	flags.Bool(enabled, false, "Show the recently received spans and the per-service span rates on the admin server at "+TracezPath)
+	flags.Int(recentSpans, defaultRecentSpans, "The number of most recently received spans kept in memory for "+TracezPath)
+	flags.Int(errorSpans, defaultErrorSpans, "The number of most recently received error spans kept in memory for "+TracezPath)
+}
+
+// InitFromViper initializes Options with properties from viper
+func (o *Options) InitFromViper(v *viper.Viper) *Options {
+	o.Enabled = v.GetBool(enabled)
+	o.RecentSpans = v.GetInt(recentSpans)
+	o.ErrorSpans = v.GetInt(errorSpans)
+	return o
+}
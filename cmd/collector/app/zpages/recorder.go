@@ -0,0 +1,204 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zpages
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+const (
+	// rateWindow is the number of one-second buckets the span rates are averaged over
+	rateWindow = 60
+
+	// otherServices is the entry of the services seen after the number of tracked services reaches maxServices
+	otherServices = "other-services"
+	maxServices   = 4000
+)
+
+// SpanSummary is the part of a received span shown on the pages.
+type SpanSummary struct {
+	TraceID    string        `json:"traceID"`
+	SpanID     string        `json:"spanID"`
+	Service    string        `json:"service"`
+	Operation  string        `json:"operation"`
+	StartTime  time.Time     `json:"startTime"`
+	Duration   time.Duration `json:"duration"`
+	Error      bool          `json:"error"`
+	ReceivedAt time.Time     `json:"receivedAt"`
+}
+
+// ServiceRate is the average number of spans per second received from a service.
+type ServiceRate struct {
+	Service        string  `json:"service"`
+	SpansPerSecond float64 `json:"spansPerSecond"`
+}
+
+// ring holds the last items added to it.
+type ring struct {
+	items []SpanSummary
+	next  int
+	full  bool
+}
+
+func newRing(size int) *ring {
+	return &ring{items: make([]SpanSummary, size)}
+}
+
+func (r *ring) add(s SpanSummary) {
+	if len(r.items) == 0 {
+		return
+	}
+	r.items[r.next] = s
+	r.next = (r.next + 1) % len(r.items)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// list returns the items, most recent first.
+func (r *ring) list() []SpanSummary {
+	n := r.next
+	if r.full {
+		n = len(r.items)
+	}
+	out := make([]SpanSummary, 0, n)
+	for i := 1; i <= n; i++ {
+		out = append(out, r.items[(r.next-i+len(r.items))%len(r.items)])
+	}
+	return out
+}
+
+// Recorder keeps the recently received spans in memory for the admin pages.
+type Recorder struct {
+	timeNow func() time.Time
+
+	lock    sync.Mutex
+	recent  *ring
+	errors  *ring
+	buckets [rateWindow]map[string]int
+	second  int64 // unix second of the current bucket
+}
+
+// NewRecorder creates a Recorder holding the number of spans given in the options.
+func NewRecorder(opts Options) *Recorder {
+	recentSize, errorSize := opts.RecentSpans, opts.ErrorSpans
+	if recentSize < 0 {
+		recentSize = 0
+	}
+	if errorSize < 0 {
+		errorSize = 0
+	}
+	return &Recorder{
+		timeNow: time.Now,
+		recent:  newRing(recentSize),
+		errors:  newRing(errorSize),
+	}
+}
+
+// ProcessSpan records the span.
+func (r *Recorder) ProcessSpan(span *model.Span) {
+	now := r.timeNow()
+	summary := SpanSummary{
+		TraceID:    span.TraceID.String(),
+		SpanID:     span.SpanID.String(),
+		Service:    span.Process.GetServiceName(),
+		Operation:  span.OperationName,
+		StartTime:  span.StartTime,
+		Duration:   span.Duration,
+		Error:      isError(span),
+		ReceivedAt: now,
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.recent.add(summary)
+	if summary.Error {
+		r.errors.add(summary)
+	}
+	bucket := r.bucketLocked(now)
+	svc := summary.Service
+	if _, ok := bucket[svc]; !ok && len(bucket) >= maxServices {
+		svc = otherServices
+	}
+	bucket[svc]++
+}
+
+// bucketLocked returns the bucket of the current second, clearing the buckets of the seconds without spans.
+func (r *Recorder) bucketLocked(now time.Time) map[string]int {
+	sec := now.Unix()
+	if sec != r.second {
+		elapsed := sec - r.second
+		if elapsed > rateWindow || elapsed < 0 {
+			elapsed = rateWindow
+		}
+		for i := int64(1); i <= elapsed; i++ {
+			r.buckets[(r.second+i)%rateWindow] = nil
+		}
+		r.second = sec
+	}
+	idx := sec % rateWindow
+	if r.buckets[idx] == nil {
+		r.buckets[idx] = make(map[string]int)
+	}
+	return r.buckets[idx]
+}
+
+// RecentSpans returns the most recently received spans, most recent first.
+func (r *Recorder) RecentSpans() []SpanSummary {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.recent.list()
+}
+
+// ErrorSpans returns the most recently received error spans, most recent first.
+func (r *Recorder) ErrorSpans() []SpanSummary {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.errors.list()
+}
+
+// ServiceRates returns the average span rate of each service over the last minute, highest first.
+func (r *Recorder) ServiceRates() []ServiceRate {
+	r.lock.Lock()
+	r.bucketLocked(r.timeNow())
+	totals := make(map[string]int)
+	for _, bucket := range r.buckets {
+		for svc, n := range bucket {
+			totals[svc] += n
+		}
+	}
+	r.lock.Unlock()
+
+	rates := make([]ServiceRate, 0, len(totals))
+	for svc, n := range totals {
+		rates = append(rates, ServiceRate{Service: svc, SpansPerSecond: float64(n) / rateWindow})
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		if rates[i].SpansPerSecond != rates[j].SpansPerSecond {
+			return rates[i].SpansPerSecond > rates[j].SpansPerSecond
+		}
+		return rates[i].Service < rates[j].Service
+	})
+	return rates
+}
+
+func isError(span *model.Span) bool {
+	tag, ok := model.KeyValues(span.Tags).FindByKey("error")
+	return ok && tag.AsString() == "true"
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zpages
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/config"
+)
+
+func makeSpan(svc, op string, spanID uint64, tags ...model.KeyValue) *model.Span {
+	return &model.Span{
+		TraceID:       model.NewTraceID(0, 1),
+		SpanID:        model.NewSpanID(spanID),
+		OperationName: op,
+		Tags:          tags,
+		Process:       &model.Process{ServiceName: svc},
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder(Options{RecentSpans: 2, ErrorSpans: 5})
+	now := time.Unix(1000, 0)
+	r.timeNow = func() time.Time { return now }
+
+	r.ProcessSpan(makeSpan("frontend", "a", 1))
+	r.ProcessSpan(makeSpan("frontend", "b", 2, model.Bool("error", true)))
+	now = now.Add(time.Second)
+	r.ProcessSpan(makeSpan("backend", "c", 3))
+
+	recent := r.RecentSpans()
+	require.Len(t, recent, 2)
+	assert.Equal(t, "c", recent[0].Operation)
+	assert.Equal(t, "b", recent[1].Operation)
+	errors := r.ErrorSpans()
+	require.Len(t, errors, 1)
+	assert.Equal(t, "frontend", errors[0].Service)
+	assert.Equal(t, "0000000000000002", errors[0].SpanID)
+
+	assert.Equal(t, []ServiceRate{
+		{Service: "frontend", SpansPerSecond: 2.0 / rateWindow},
+		{Service: "backend", SpansPerSecond: 1.0 / rateWindow},
+	}, r.ServiceRates())
+
+	now = now.Add(rateWindow * time.Second)
+	assert.Empty(t, r.ServiceRates(), "the rates only cover the last minute")
+}
+
+func TestRecorderDisabledRings(t *testing.T) {
+	r := NewRecorder(Options{RecentSpans: 0, ErrorSpans: -1})
+	r.ProcessSpan(makeSpan("frontend", "a", 1, model.Bool("error", true)))
+	assert.Empty(t, r.RecentSpans())
+	assert.Empty(t, r.ErrorSpans())
+	assert.Len(t, r.ServiceRates(), 1)
+}
+
+func TestRecorderServeHTTP(t *testing.T) {
+	r := NewRecorder(Options{RecentSpans: 10, ErrorSpans: 10})
+	r.ProcessSpan(makeSpan("frontend", "GET <script>", 1, model.Bool("error", true)))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, TracezPath, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), "frontend")
+	assert.Contains(t, w.Body.String(), "GET &lt;script&gt;")
+	assert.Contains(t, w.Body.String(), `class="error"`)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, TracezPath+"?format=json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var p page
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	assert.Len(t, p.Recent, 1)
+	assert.Len(t, p.Errors, 1)
+	assert.Equal(t, "frontend", p.Rates[0].Service)
+}
+
+func TestOptionsWithFlags(t *testing.T) {
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{
+		"--collector.zpages.enabled=true",
+		"--collector.zpages.recent-spans=500",
+	})
+	opts := new(Options).InitFromViper(v)
+	assert.Equal(t, Options{Enabled: true, RecentSpans: 500, ErrorSpans: defaultErrorSpans}, *opts)
+}
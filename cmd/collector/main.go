@@ -90,9 +90,13 @@ func main() {
 				SpanWriter:     spanWriter,
 				StrategyStore:  strategyStore,
 				HealthCheck:    svc.HC(),
+				Config:         v,
 			})
 			collectorOpts := new(app.CollectorOptions).InitFromViper(v)
-			c.Start(collectorOpts)
+			if err := c.Start(collectorOpts); err != nil {
+				logger.Fatal("Failed to start collector", zap.Error(err))
+			}
+			c.RegisterAdminHandlers(svc.Admin)
 
 			svc.RunAndThen(func() {
 				if closer, ok := spanWriter.(io.Closer); ok {
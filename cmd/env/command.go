@@ -21,6 +21,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
+	"github.com/jaegertracing/jaeger/plugin/metrics"
 	"github.com/jaegertracing/jaeger/plugin/storage"
 )
 
@@ -41,7 +42,9 @@ The following configuration options are only available via environment variables
 Multiple backends can be specified as comma-separated list, e.g. "cassandra,elasticsearch"
 (currently only for writing spans). Note that "kafka" is only valid in jaeger-collector;
 it is not a replacement for a proper storage backend, and only used as a buffer for spans
-when Jaeger is deployed in the collector+ingester configuration.
+when Jaeger is deployed in the collector+ingester configuration. The "tiered" type writes
+to a hot and a cold backend and reads from the hot one first, their types are set with the
+--tiered.hot-storage.type and --tiered.cold-storage.type command line options.
 `
 )
 
@@ -61,6 +64,26 @@ func Command() *cobra.Command {
 		"${SPAN_STORAGE_TYPE}",
 		"The type of backend used for service dependencies storage.",
 	)
+	fs.String(
+		storage.SecondarySpanStorageTypeEnvVar,
+		"",
+		"The type of an additional backend receiving a copy of all written spans, e.g. during migrations. "+
+			"It is configured independently of the primary backend with command line options prefixed by \"secondary.\", "+
+			"e.g. --secondary.es.server-urls.",
+	)
+	fs.String(
+		storage.FederatedSpanStorageTypesEnvVar,
+		"",
+		"The comma-separated types of additional backends queried along with the first span storage type, "+
+			"e.g. a cold storage or the clusters of other regions. Their traces are merged and deduplicated. "+
+			"The \"secondary\" type refers to the backend defined by "+storage.SecondarySpanStorageTypeEnvVar+".",
+	)
+	fs.String(
+		metrics.MetricsStorageTypeEnvVar,
+		"",
+		fmt.Sprintf("The type of backend [%s] used to query the span metrics, e.g. for the Monitor tab of the UI. "+
+			"Querying the span metrics is disabled if empty.", strings.Join(metrics.AllStorageTypes, ", ")),
+	)
 	long := fmt.Sprintf(longTemplate, strings.Replace(fs.FlagUsagesWrapped(0), "      --", "\n", -1))
 	return &cobra.Command{
 		Use:   "env",
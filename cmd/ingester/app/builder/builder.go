@@ -23,32 +23,51 @@ import (
 
 	"github.com/jaegertracing/jaeger/cmd/ingester/app"
 	"github.com/jaegertracing/jaeger/cmd/ingester/app/consumer"
+	"github.com/jaegertracing/jaeger/cmd/ingester/app/kinesis"
 	"github.com/jaegertracing/jaeger/cmd/ingester/app/processor"
+	"github.com/jaegertracing/jaeger/cmd/ingester/app/pubsub"
 	kafkaConsumer "github.com/jaegertracing/jaeger/pkg/kafka/consumer"
 	"github.com/jaegertracing/jaeger/plugin/storage/kafka"
 	"github.com/jaegertracing/jaeger/storage/spanstore"
 )
 
-// CreateConsumer creates a new span consumer for the ingester
-func CreateConsumer(logger *zap.Logger, metricsFactory metrics.Factory, spanWriter spanstore.Writer, options app.Options) (*consumer.Consumer, error) {
-	var unmarshaller kafka.Unmarshaller
-	switch options.Encoding {
-	case kafka.EncodingJSON:
-		unmarshaller = kafka.NewJSONUnmarshaller()
-	case kafka.EncodingProto:
-		unmarshaller = kafka.NewProtobufUnmarshaller()
-	case kafka.EncodingZipkinThrift:
-		unmarshaller = kafka.NewZipkinThriftUnmarshaller()
+// Receiver consumes spans from a message broker and writes them to the storage
+type Receiver interface {
+	Start()
+	Close() error
+}
+
+// CreateReceiver creates the span receiver of the source selected in the options
+func CreateReceiver(logger *zap.Logger, metricsFactory metrics.Factory, spanWriter spanstore.Writer, options app.Options) (Receiver, error) {
+	switch options.Source {
+	case app.SourceKafka, "":
+		return CreateConsumer(logger, metricsFactory, spanWriter, options)
+	case app.SourceKinesis:
+		spanProcessor, err := newSpanProcessor(spanWriter, options.Kinesis.Encoding)
+		if err != nil {
+			return nil, err
+		}
+		return kinesis.NewConsumer(options.Kinesis, spanProcessor, logger,
+			metricsFactory.Namespace(metrics.NSOptions{Name: "kinesis-consumer"}))
+	case app.SourcePubSub:
+		spanProcessor, err := newSpanProcessor(spanWriter, options.PubSub.Encoding)
+		if err != nil {
+			return nil, err
+		}
+		return pubsub.NewConsumer(options.PubSub, spanProcessor, logger,
+			metricsFactory.Namespace(metrics.NSOptions{Name: "pubsub-consumer"}))
 	default:
-		return nil, fmt.Errorf(`encoding '%s' not recognised, use one of ("%s")`,
-			options.Encoding, strings.Join(kafka.AllEncodings, "\", \""))
+		return nil, fmt.Errorf(`source '%s' not recognised, use one of ("%s", "%s", "%s")`,
+			options.Source, app.SourceKafka, app.SourceKinesis, app.SourcePubSub)
 	}
+}
 
-	spParams := processor.SpanProcessorParams{
-		Writer:       spanWriter,
-		Unmarshaller: unmarshaller,
+// CreateConsumer creates a new span consumer for the ingester
+func CreateConsumer(logger *zap.Logger, metricsFactory metrics.Factory, spanWriter spanstore.Writer, options app.Options) (*consumer.Consumer, error) {
+	spanProcessor, err := newSpanProcessor(spanWriter, options.Encoding)
+	if err != nil {
+		return nil, err
 	}
-	spanProcessor := processor.NewSpanProcessor(spParams)
 
 	consumerConfig := kafkaConsumer.Configuration{
 		Brokers:              options.Brokers,
@@ -85,3 +104,24 @@ func CreateConsumer(logger *zap.Logger, metricsFactory metrics.Factory, spanWrit
 	}
 	return consumer.New(consumerParams)
 }
+
+func newSpanProcessor(spanWriter spanstore.Writer, encoding string) (*processor.KafkaSpanProcessor, error) {
+	var unmarshaller kafka.Unmarshaller
+	switch encoding {
+	case kafka.EncodingJSON:
+		unmarshaller = kafka.NewJSONUnmarshaller()
+	case kafka.EncodingProto:
+		unmarshaller = kafka.NewProtobufUnmarshaller()
+	case kafka.EncodingZipkinThrift:
+		unmarshaller = kafka.NewZipkinThriftUnmarshaller()
+	default:
+		return nil, fmt.Errorf(`encoding '%s' not recognised, use one of ("%s")`,
+			encoding, strings.Join(kafka.AllEncodings, "\", \""))
+	}
+
+	spParams := processor.SpanProcessorParams{
+		Writer:       spanWriter,
+		Unmarshaller: unmarshaller,
+	}
+	return processor.NewSpanProcessor(spParams), nil
+}
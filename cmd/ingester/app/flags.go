@@ -23,6 +23,8 @@ import (
 
 	"github.com/spf13/viper"
 
+	"github.com/jaegertracing/jaeger/cmd/ingester/app/kinesis"
+	"github.com/jaegertracing/jaeger/cmd/ingester/app/pubsub"
 	"github.com/jaegertracing/jaeger/pkg/kafka/auth"
 	kafkaConsumer "github.com/jaegertracing/jaeger/pkg/kafka/consumer"
 	"github.com/jaegertracing/jaeger/plugin/storage/kafka"
@@ -49,6 +51,8 @@ const (
 	SuffixDeadlockInterval = ".deadlockInterval"
 	// SuffixParallelism is a suffix for the parallelism flag
 	SuffixParallelism = ".parallelism"
+	// SuffixSource is a suffix for the flag selecting where spans are consumed from
+	SuffixSource = ".source"
 	// SuffixHTTPPort is a suffix for the HTTP port
 	SuffixHTTPPort = ".http-port"
 	// DefaultBroker is the default kafka broker
@@ -65,14 +69,26 @@ const (
 	DefaultEncoding = kafka.EncodingProto
 	// DefaultDeadlockInterval is the default deadlock interval
 	DefaultDeadlockInterval = time.Duration(0)
+
+	// SourceKafka consumes spans from a Kafka topic
+	SourceKafka = "kafka"
+	// SourceKinesis consumes spans from an AWS Kinesis stream
+	SourceKinesis = "kinesis"
+	// SourcePubSub consumes spans from a Google Cloud Pub/Sub subscription
+	SourcePubSub = "pubsub"
+	// DefaultSource is the default source of the spans
+	DefaultSource = SourceKafka
 )
 
 // Options stores the configuration options for the Ingester
 type Options struct {
 	kafkaConsumer.Configuration `mapstructure:",squash"`
-	Parallelism                 int           `mapstructure:"parallelism"`
-	Encoding                    string        `mapstructure:"encoding"`
-	DeadlockInterval            time.Duration `mapstructure:"deadlock_interval"`
+	Parallelism                 int             `mapstructure:"parallelism"`
+	Encoding                    string          `mapstructure:"encoding"`
+	DeadlockInterval            time.Duration   `mapstructure:"deadlock_interval"`
+	Source                      string          `mapstructure:"source"`
+	Kinesis                     kinesis.Options `mapstructure:"kinesis"`
+	PubSub                      pubsub.Options  `mapstructure:"pubsub"`
 }
 
 // AddFlags adds flags for Builder
@@ -109,8 +125,14 @@ func AddFlags(flagSet *flag.FlagSet) {
 		ConfigPrefix+SuffixDeadlockInterval,
 		DefaultDeadlockInterval,
 		"Interval to check for deadlocks. If no messages gets processed in given time, ingester app will exit. Value of 0 disables deadlock check.")
+	flagSet.String(
+		ConfigPrefix+SuffixSource,
+		DefaultSource,
+		fmt.Sprintf("Where to consume spans from (%s, %s or %s)", SourceKafka, SourceKinesis, SourcePubSub))
 	// Authentication flags
 	auth.AddFlags(KafkaConsumerConfigPrefix, flagSet)
+	kinesis.AddFlags(flagSet)
+	pubsub.AddFlags(flagSet)
 }
 
 // InitFromViper initializes Builder with properties from viper
@@ -124,6 +146,9 @@ func (o *Options) InitFromViper(v *viper.Viper) {
 
 	o.Parallelism = v.GetInt(ConfigPrefix + SuffixParallelism)
 	o.DeadlockInterval = v.GetDuration(ConfigPrefix + SuffixDeadlockInterval)
+	o.Source = v.GetString(ConfigPrefix + SuffixSource)
+	o.Kinesis.InitFromViper(v)
+	o.PubSub.InitFromViper(v)
 	authenticationOptions := auth.AuthenticationConfig{}
 	authenticationOptions.InitFromViper(KafkaConsumerConfigPrefix, v)
 	o.AuthenticationConfig = authenticationOptions
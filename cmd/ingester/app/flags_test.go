@@ -40,6 +40,9 @@ func TestOptionsWithFlags(t *testing.T) {
 		"--kafka.consumer.protocol-version=1.0.0",
 		"--ingester.parallelism=5",
 		"--ingester.deadlockInterval=2m",
+		"--ingester.source=kinesis",
+		"--kinesis.consumer.stream=spans",
+		"--pubsub.consumer.subscription=projects/p/subscriptions/spans",
 	})
 	o.InitFromViper(v)
 
@@ -51,6 +54,9 @@ func TestOptionsWithFlags(t *testing.T) {
 	assert.Equal(t, 5, o.Parallelism)
 	assert.Equal(t, 2*time.Minute, o.DeadlockInterval)
 	assert.Equal(t, kafka.EncodingJSON, o.Encoding)
+	assert.Equal(t, SourceKinesis, o.Source)
+	assert.Equal(t, "spans", o.Kinesis.Stream)
+	assert.Equal(t, "projects/p/subscriptions/spans", o.PubSub.Subscription)
 }
 
 func TestTLSFlags(t *testing.T) {
@@ -106,4 +112,5 @@ func TestFlagDefaults(t *testing.T) {
 	assert.Equal(t, DefaultParallelism, o.Parallelism)
 	assert.Equal(t, DefaultEncoding, o.Encoding)
 	assert.Equal(t, DefaultDeadlockInterval, o.DeadlockInterval)
+	assert.Equal(t, DefaultSource, o.Source)
 }
@@ -0,0 +1,487 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kinesis
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/aws/aws-sdk-go/service/kinesis/kinesisiface"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/cmd/ingester/app/processor"
+)
+
+type consumerMetrics struct {
+	Messages metrics.Counter `metric:"messages"`
+	Errors   metrics.Counter `metric:"errors"`
+	Shards   metrics.Gauge   `metric:"shards-held"`
+}
+
+// Consumer reads span records from the shards of a Kinesis stream and hands them to the span processor.
+//
+// The consumers of a stream share its shards through leases kept in a DynamoDB table, where they
+// also checkpoint the last record processed in each shard. A shard is read by one consumer at a time,
+// each consumer taking its share of the open shards, and a shard that changes hands is resumed after
+// its checkpoint: the records processed since the last checkpoint are read again. The shards created
+// by a resharding are read once their parents were read to the end.
+type Consumer struct {
+	opts      Options
+	kinesis   kinesisiface.KinesisAPI
+	leases    leaseStore
+	owner     string
+	processor processor.SpanProcessor
+	logger    *zap.Logger
+	metrics   consumerMetrics
+	now       func() time.Time
+
+	lock   sync.Mutex
+	shards map[string]chan struct{}
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+type message []byte
+
+func (m message) Value() []byte {
+	return m
+}
+
+// NewConsumer creates a Consumer of the stream configured in opts, creating its lease table if needed.
+// The AWS credentials are looked up by the default chain of the AWS SDK.
+func NewConsumer(opts Options, spanProcessor processor.SpanProcessor, logger *zap.Logger, metricsFactory metrics.Factory) (*Consumer, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            aws.Config{Region: aws.String(opts.Region)},
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot create the AWS session: %w", err)
+	}
+	leases := &dynamoLeases{client: dynamodb.New(sess, endpointConfig(opts.LeaseEndpoint)), table: opts.LeaseTable}
+	if err := leases.ensureTable(); err != nil {
+		return nil, fmt.Errorf("cannot create the Kinesis lease table %s: %w", opts.LeaseTable, err)
+	}
+	owner, err := ownerID()
+	if err != nil {
+		return nil, err
+	}
+	return newConsumer(opts, kinesis.New(sess, endpointConfig(opts.Endpoint)), leases, owner, spanProcessor, logger, metricsFactory), nil
+}
+
+func newConsumer(opts Options, client kinesisiface.KinesisAPI, leases leaseStore, owner string,
+	spanProcessor processor.SpanProcessor, logger *zap.Logger, metricsFactory metrics.Factory) *Consumer {
+	c := &Consumer{
+		opts:      opts,
+		kinesis:   client,
+		leases:    leases,
+		owner:     owner,
+		processor: spanProcessor,
+		logger:    logger.With(zap.String("stream", opts.Stream), zap.String("owner", owner)),
+		now:       time.Now,
+		shards:    make(map[string]chan struct{}),
+		stopCh:    make(chan struct{}),
+	}
+	metrics.Init(&c.metrics, metricsFactory, nil)
+	return c
+}
+
+func endpointConfig(endpoint string) *aws.Config {
+	config := aws.NewConfig()
+	if endpoint != "" {
+		config = config.WithEndpoint(endpoint)
+	}
+	return config
+}
+
+// ownerID identifies this consumer in the lease table, unique across restarts.
+func ownerID() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+	random := make([]byte, 4)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return hostname + "-" + hex.EncodeToString(random), nil
+}
+
+// Start begins taking leases and consuming shards in the background.
+func (c *Consumer) Start() {
+	c.wg.Add(1)
+	go c.manageLeases()
+}
+
+// Close stops consuming the stream, checkpointing and releasing the leases held, and closes the span processor.
+func (c *Consumer) Close() error {
+	close(c.stopCh)
+	c.wg.Wait()
+	return c.processor.Close()
+}
+
+func (c *Consumer) manageLeases() {
+	defer c.wg.Done()
+	renew := time.NewTicker(c.opts.LeaseDuration / 3)
+	defer renew.Stop()
+	refresh := time.NewTicker(c.opts.ShardsRefreshInterval)
+	defer refresh.Stop()
+	c.refreshLeases()
+	for {
+		select {
+		case <-renew.C:
+			c.renewLeases()
+		case <-refresh.C:
+			c.refreshLeases()
+		case <-c.stopCh:
+			return
+		}
+	}
+}
+
+func (c *Consumer) refreshLeases() {
+	if err := c.takeLeases(); err != nil {
+		c.logger.Error("Failed to take the leases of Kinesis shards", zap.Error(err))
+	}
+}
+
+func (c *Consumer) renewLeases() {
+	c.lock.Lock()
+	shards := make(map[string]chan struct{}, len(c.shards))
+	for shard, stop := range c.shards {
+		shards[shard] = stop
+	}
+	c.lock.Unlock()
+	for shard, stop := range shards {
+		err := c.leases.renew(shard, c.owner, c.now().Add(c.opts.LeaseDuration))
+		if _, lost := err.(leaseLostError); lost {
+			c.logger.Info("Lost the lease of Kinesis shard", zap.String("shard", shard))
+			c.stopShard(shard, stop)
+		} else if err != nil {
+			c.logger.Error("Failed to renew the lease of Kinesis shard", zap.String("shard", shard), zap.Error(err))
+		}
+	}
+}
+
+// takeLeases takes the leases needed for this consumer to hold its share of the open shards:
+// the free or expired leases first, else one lease of the consumer holding the most.
+func (c *Consumer) takeLeases() error {
+	shards, err := c.listShards()
+	if err != nil {
+		return err
+	}
+	list, err := c.leases.list()
+	if err != nil {
+		return err
+	}
+	now := c.now()
+	leases := make(map[string]lease, len(list))
+	for _, l := range list {
+		leases[l.shard] = l
+	}
+	listed := make(map[string]bool, len(shards))
+	for _, shard := range shards {
+		listed[aws.StringValue(shard.ShardId)] = true
+	}
+	c.lock.Lock()
+	consumed := make(map[string]bool, len(c.shards))
+	for shard := range c.shards {
+		consumed[shard] = true
+	}
+	c.lock.Unlock()
+	held := map[string][]lease{c.owner: nil}
+	var free []*kinesis.Shard
+	for _, shard := range shards {
+		l, ok := leases[aws.StringValue(shard.ShardId)]
+		if !ok {
+			l = lease{shard: aws.StringValue(shard.ShardId)}
+		}
+		if l.checkpoint == shardEnd {
+			continue
+		}
+		if consumed[l.shard] {
+			// still consumed here while the lease is being renewed
+			held[c.owner] = append(held[c.owner], l)
+		} else if l.held(now) {
+			held[l.owner] = append(held[l.owner], l)
+		} else if c.parentsEnded(shard, listed, leases) {
+			free = append(free, shard)
+		}
+	}
+	open := len(free)
+	for _, owned := range held {
+		open += len(owned)
+	}
+	target := (open + len(held) - 1) / len(held)
+	for _, shard := range free {
+		if len(held[c.owner]) >= target {
+			return nil
+		}
+		l, ok := leases[aws.StringValue(shard.ShardId)]
+		if !ok {
+			l = lease{shard: aws.StringValue(shard.ShardId)}
+		}
+		if c.takeLease(l, c.startAt(shard, l, listed)) {
+			held[c.owner] = append(held[c.owner], l)
+		}
+	}
+	if len(held[c.owner]) >= target {
+		return nil
+	}
+	// steal a single lease at a time, from the consumer holding the most
+	var busiest string
+	for owner, owned := range held {
+		if owner != c.owner && len(owned) > len(held[busiest]) {
+			busiest = owner
+		}
+	}
+	if len(held[busiest]) <= target {
+		return nil
+	}
+	owned := held[busiest]
+	sort.Slice(owned, func(i, j int) bool { return owned[i].shard < owned[j].shard })
+	l := owned[len(owned)-1]
+	c.takeLease(l, iteratorAfter(l.checkpoint))
+	return nil
+}
+
+// parentsEnded tells whether the parents of a shard still in the stream were read to their end.
+func (c *Consumer) parentsEnded(shard *kinesis.Shard, listed map[string]bool, leases map[string]lease) bool {
+	for _, parent := range []*string{shard.ParentShardId, shard.AdjacentParentShardId} {
+		if id := aws.StringValue(parent); listed[id] && leases[id].checkpoint != shardEnd {
+			return false
+		}
+	}
+	return true
+}
+
+type startPosition struct {
+	iteratorType   string
+	sequenceNumber string
+}
+
+func iteratorAfter(sequenceNumber string) startPosition {
+	if sequenceNumber == "" {
+		return startPosition{iteratorType: kinesis.ShardIteratorTypeTrimHorizon}
+	}
+	return startPosition{iteratorType: kinesis.ShardIteratorTypeAfterSequenceNumber, sequenceNumber: sequenceNumber}
+}
+
+// startAt resumes a shard after its checkpoint. A shard never checkpointed is read from its start
+// when it follows parents still in the stream, so that no span is skipped across a resharding.
+func (c *Consumer) startAt(shard *kinesis.Shard, l lease, listed map[string]bool) startPosition {
+	if l.checkpoint != "" || listed[aws.StringValue(shard.ParentShardId)] || listed[aws.StringValue(shard.AdjacentParentShardId)] {
+		return iteratorAfter(l.checkpoint)
+	}
+	return startPosition{iteratorType: c.opts.IteratorType}
+}
+
+func (c *Consumer) takeLease(l lease, start startPosition) bool {
+	if err := c.leases.take(l, c.owner, c.now().Add(c.opts.LeaseDuration)); err != nil {
+		if _, lost := err.(leaseLostError); !lost {
+			c.logger.Error("Failed to take the lease of Kinesis shard", zap.String("shard", l.shard), zap.Error(err))
+		}
+		return false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	select {
+	case <-c.stopCh:
+		return false
+	default:
+	}
+	stop := make(chan struct{})
+	c.shards[l.shard] = stop
+	c.metrics.Shards.Update(int64(len(c.shards)))
+	c.logger.Info("Starting to consume Kinesis shard", zap.String("shard", l.shard),
+		zap.String("previous-owner", l.owner), zap.String("iterator-type", start.iteratorType))
+	c.wg.Add(1)
+	go c.consumeShard(l.shard, start, stop)
+	return true
+}
+
+// stopShard stops consuming a shard, unless it was taken again meanwhile.
+func (c *Consumer) stopShard(shard string, stop chan struct{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.shards[shard] == stop {
+		close(stop)
+		delete(c.shards, shard)
+		c.metrics.Shards.Update(int64(len(c.shards)))
+	}
+}
+
+func (c *Consumer) consumeShard(shard string, start startPosition, stop chan struct{}) {
+	defer c.wg.Done()
+	defer c.stopShard(shard, stop)
+
+	checkpointed, lastSequenceNumber := start.sequenceNumber, start.sequenceNumber
+	lastCheckpoint := c.now()
+	var iterator *string
+	for {
+		var err error
+		if iterator == nil {
+			iterator, err = c.shardIterator(shard, start, lastSequenceNumber)
+		}
+		var out *kinesis.GetRecordsOutput
+		if err == nil {
+			out, err = c.kinesis.GetRecords(&kinesis.GetRecordsInput{ShardIterator: iterator, Limit: aws.Int64(int64(c.opts.MaxRecords))})
+		}
+		if err != nil {
+			if isErrorCode(err, kinesis.ErrCodeExpiredIteratorException) {
+				iterator = nil
+				continue
+			}
+			c.logger.Error("Failed to read Kinesis shard", zap.String("shard", shard), zap.Error(err))
+			if !c.wait(stop, c.opts.PollInterval) {
+				break
+			}
+			continue
+		}
+		for _, r := range out.Records {
+			c.metrics.Messages.Inc(1)
+			if err := c.processor.Process(message(r.Data)); err != nil {
+				c.metrics.Errors.Inc(1)
+				c.logger.Error("Failed to process Kinesis record", zap.String("shard", shard), zap.Error(err))
+			}
+			lastSequenceNumber = aws.StringValue(r.SequenceNumber)
+		}
+		if out.NextShardIterator == nil {
+			c.logger.Info("Kinesis shard closed", zap.String("shard", shard))
+			if c.checkpoint(shard, shardEnd) {
+				c.release(shard)
+			}
+			return
+		}
+		iterator = out.NextShardIterator
+		if lastSequenceNumber != checkpointed && c.now().Sub(lastCheckpoint) >= c.opts.CheckpointInterval {
+			if !c.checkpoint(shard, lastSequenceNumber) {
+				return
+			}
+			checkpointed, lastCheckpoint = lastSequenceNumber, c.now()
+		}
+		if len(out.Records) == 0 && !c.wait(stop, c.opts.PollInterval) {
+			break
+		}
+		if c.stopped(stop) {
+			break
+		}
+	}
+	select {
+	case <-stop:
+		// the lease was taken by another consumer, which resumes after the last checkpoint
+	default:
+		if lastSequenceNumber == checkpointed || c.checkpoint(shard, lastSequenceNumber) {
+			c.release(shard)
+		}
+	}
+}
+
+func (c *Consumer) shardIterator(shard string, start startPosition, lastSequenceNumber string) (*string, error) {
+	if lastSequenceNumber != "" {
+		start = iteratorAfter(lastSequenceNumber)
+	}
+	input := &kinesis.GetShardIteratorInput{
+		StreamName:        aws.String(c.opts.Stream),
+		ShardId:           aws.String(shard),
+		ShardIteratorType: aws.String(start.iteratorType),
+	}
+	if start.sequenceNumber != "" {
+		input.StartingSequenceNumber = aws.String(start.sequenceNumber)
+	}
+	out, err := c.kinesis.GetShardIterator(input)
+	if err != nil {
+		return nil, err
+	}
+	if out.ShardIterator == nil {
+		return nil, errors.New("kinesis: no shard iterator returned for shard " + shard)
+	}
+	return out.ShardIterator, nil
+}
+
+// checkpoint records the last record processed in a shard, and tells whether the lease is still held.
+func (c *Consumer) checkpoint(shard, sequenceNumber string) bool {
+	err := c.leases.checkpoint(shard, c.owner, sequenceNumber)
+	if _, lost := err.(leaseLostError); lost {
+		c.logger.Info("Lost the lease of Kinesis shard", zap.String("shard", shard))
+		return false
+	}
+	if err != nil {
+		// the records are read again from the previous checkpoint by the next owner
+		c.logger.Error("Failed to checkpoint Kinesis shard", zap.String("shard", shard), zap.Error(err))
+	}
+	return true
+}
+
+func (c *Consumer) release(shard string) {
+	if err := c.leases.release(shard, c.owner); err != nil {
+		c.logger.Error("Failed to release the lease of Kinesis shard", zap.String("shard", shard), zap.Error(err))
+	}
+}
+
+func (c *Consumer) stopped(stop chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	case <-c.stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// wait tells whether the shard should still be consumed after d.
+func (c *Consumer) wait(stop chan struct{}, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-stop:
+		return false
+	case <-c.stopCh:
+		return false
+	}
+}
+
+func (c *Consumer) listShards() ([]*kinesis.Shard, error) {
+	var shards []*kinesis.Shard
+	input := &kinesis.ListShardsInput{StreamName: aws.String(c.opts.Stream)}
+	for {
+		out, err := c.kinesis.ListShards(input)
+		if err != nil {
+			return nil, err
+		}
+		shards = append(shards, out.Shards...)
+		if out.NextToken == nil {
+			return shards, nil
+		}
+		// the stream name must not be set along with the pagination token
+		input = &kinesis.ListShardsInput{NextToken: out.NextToken}
+	}
+}
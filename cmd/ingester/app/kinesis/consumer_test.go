@@ -0,0 +1,386 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kinesis
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/aws/aws-sdk-go/service/kinesis/kinesisiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/cmd/ingester/app/processor"
+	"github.com/jaegertracing/jaeger/pkg/config"
+)
+
+type fakeProcessor struct {
+	lock     sync.Mutex
+	messages []string
+	closed   bool
+}
+
+func (p *fakeProcessor) Process(m processor.Message) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if string(m.Value()) == "bad" {
+		return errors.New("cannot unmarshal")
+	}
+	p.messages = append(p.messages, string(m.Value()))
+	return nil
+}
+
+func (p *fakeProcessor) Close() error {
+	p.closed = true
+	return nil
+}
+
+func (p *fakeProcessor) processed() []string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return append([]string(nil), p.messages...)
+}
+
+type fakeShard struct {
+	parent  string
+	records []string
+	closed  bool
+}
+
+// fakeKinesis serves shards whose iterators are "<shard>/<index of the next record>",
+// the sequence number of a record being its index plus one.
+type fakeKinesis struct {
+	kinesisiface.KinesisAPI
+	shards  map[string]fakeShard
+	order   []string
+	lock    sync.Mutex
+	expired bool
+}
+
+func (f *fakeKinesis) ListShards(input *kinesis.ListShardsInput) (*kinesis.ListShardsOutput, error) {
+	if f.shards == nil {
+		return nil, awserr.New(kinesis.ErrCodeResourceNotFoundException, "Stream spans not found", nil)
+	}
+	out := &kinesis.ListShardsOutput{}
+	// one shard per page
+	i := 0
+	if input.NextToken != nil {
+		i, _ = strconv.Atoi(*input.NextToken)
+	}
+	id := f.order[i]
+	shard := &kinesis.Shard{ShardId: aws.String(id)}
+	if parent := f.shards[id].parent; parent != "" {
+		shard.ParentShardId = aws.String(parent)
+	}
+	out.Shards = []*kinesis.Shard{shard}
+	if i+1 < len(f.order) {
+		out.NextToken = aws.String(strconv.Itoa(i + 1))
+	}
+	return out, nil
+}
+
+func (f *fakeKinesis) GetShardIterator(input *kinesis.GetShardIteratorInput) (*kinesis.GetShardIteratorOutput, error) {
+	shard := f.shards[*input.ShardId]
+	position := 0
+	switch *input.ShardIteratorType {
+	case kinesis.ShardIteratorTypeLatest:
+		position = len(shard.records)
+	case kinesis.ShardIteratorTypeAfterSequenceNumber:
+		position, _ = strconv.Atoi(*input.StartingSequenceNumber)
+	}
+	return &kinesis.GetShardIteratorOutput{ShardIterator: aws.String(fmt.Sprintf("%s/%d", *input.ShardId, position))}, nil
+}
+
+func (f *fakeKinesis) GetRecords(input *kinesis.GetRecordsInput) (*kinesis.GetRecordsOutput, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	parts := strings.Split(*input.ShardIterator, "/")
+	if parts[0] == "shard-1" && !f.expired {
+		f.expired = true
+		return nil, awserr.New(kinesis.ErrCodeExpiredIteratorException, "expired", nil)
+	}
+	shard := f.shards[parts[0]]
+	position, _ := strconv.Atoi(parts[1])
+	out := &kinesis.GetRecordsOutput{}
+	for i := position; i < len(shard.records) && i < position+int(*input.Limit); i++ {
+		out.Records = append(out.Records, &kinesis.Record{Data: []byte(shard.records[i]), SequenceNumber: aws.String(strconv.Itoa(i + 1))})
+	}
+	position += len(out.Records)
+	if !shard.closed || position < len(shard.records) {
+		out.NextShardIterator = aws.String(fmt.Sprintf("%s/%d", parts[0], position))
+	}
+	return out, nil
+}
+
+// memLeases keeps the leases in memory, with the conditions of dynamoLeases
+type memLeases struct {
+	lock   sync.Mutex
+	leases map[string]lease
+}
+
+func newMemLeases(leases ...lease) *memLeases {
+	m := &memLeases{leases: make(map[string]lease)}
+	for _, l := range leases {
+		m.leases[l.shard] = l
+	}
+	return m
+}
+
+func (m *memLeases) get(shard string) lease {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.leases[shard]
+}
+
+func (m *memLeases) set(l lease) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.leases[l.shard] = l
+}
+
+func (m *memLeases) list() ([]lease, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	var leases []lease
+	for _, l := range m.leases {
+		leases = append(leases, l)
+	}
+	return leases, nil
+}
+
+func (m *memLeases) take(previous lease, owner string, expiry time.Time) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	l := m.leases[previous.shard]
+	if l.owner != previous.owner || (l.owner != "" && l.expiry != previous.expiry) {
+		return leaseLostError(previous.shard)
+	}
+	l.shard, l.owner, l.expiry = previous.shard, owner, expiry.UnixNano()/int64(time.Millisecond)
+	m.leases[l.shard] = l
+	return nil
+}
+
+func (m *memLeases) updateOwned(shard, owner string, update func(l *lease)) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	l := m.leases[shard]
+	if l.owner != owner {
+		return leaseLostError(shard)
+	}
+	update(&l)
+	m.leases[shard] = l
+	return nil
+}
+
+func (m *memLeases) renew(shard, owner string, expiry time.Time) error {
+	return m.updateOwned(shard, owner, func(l *lease) { l.expiry = expiry.UnixNano() / int64(time.Millisecond) })
+}
+
+func (m *memLeases) checkpoint(shard, owner, sequenceNumber string) error {
+	return m.updateOwned(shard, owner, func(l *lease) { l.checkpoint = sequenceNumber })
+}
+
+func (m *memLeases) release(shard, owner string) error {
+	return m.updateOwned(shard, owner, func(l *lease) { l.owner, l.expiry = "", 0 })
+}
+
+func newTestConsumer(t *testing.T, opts Options, client kinesisiface.KinesisAPI, leases leaseStore, p processor.SpanProcessor) (*Consumer, *metricstest.Factory) {
+	opts.Stream = "spans"
+	if opts.IteratorType == "" {
+		opts.IteratorType = IteratorLatest
+	}
+	require.NoError(t, opts.validate())
+	mf := metricstest.NewFactory(0)
+	return newConsumer(opts, client, leases, "me", p, zap.NewNop(), mf), mf
+}
+
+func eventually(t *testing.T, condition func() bool) {
+	for i := 0; i < 400 && !condition(); i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	require.True(t, condition())
+}
+
+func TestOptionsWithFlags(t *testing.T) {
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{
+		"--kinesis.consumer.stream=spans",
+		"--kinesis.consumer.region=eu-west-1",
+		"--kinesis.consumer.iterator-type=TRIM_HORIZON",
+		"--kinesis.consumer.encoding=json",
+		"--kinesis.consumer.max-records=10",
+		"--kinesis.consumer.lease-table-endpoint=http://localhost:4566",
+		"--kinesis.consumer.checkpoint-interval=1m",
+	})
+	opts := new(Options).InitFromViper(v)
+	assert.Equal(t, "spans", opts.Stream)
+	assert.Equal(t, "eu-west-1", opts.Region)
+	assert.Equal(t, "", opts.Endpoint)
+	assert.Equal(t, IteratorTrimHorizon, opts.IteratorType)
+	assert.Equal(t, "json", opts.Encoding)
+	assert.Equal(t, 10, opts.MaxRecords)
+	assert.Equal(t, defaultPollInterval, opts.PollInterval)
+	assert.Equal(t, defaultShardsInterval, opts.ShardsRefreshInterval)
+	assert.Equal(t, "", opts.LeaseTable)
+	assert.Equal(t, "http://localhost:4566", opts.LeaseEndpoint)
+	assert.Equal(t, defaultLeaseDuration, opts.LeaseDuration)
+	assert.Equal(t, time.Minute, opts.CheckpointInterval)
+
+	require.NoError(t, opts.validate())
+	assert.Equal(t, "jaeger-ingester-spans", opts.LeaseTable)
+}
+
+func TestNewConsumerErrors(t *testing.T) {
+	_, err := NewConsumer(Options{IteratorType: IteratorLatest}, &fakeProcessor{}, zap.NewNop(), metricstest.NewFactory(0))
+	assert.EqualError(t, err, "the Kinesis stream name is required")
+	_, err = NewConsumer(Options{Stream: "spans", IteratorType: "AT_TIMESTAMP"}, &fakeProcessor{}, zap.NewNop(), metricstest.NewFactory(0))
+	assert.Error(t, err)
+}
+
+func TestConsumer(t *testing.T) {
+	client := &fakeKinesis{
+		shards: map[string]fakeShard{
+			"shard-1": {records: []string{"x", "a", "bad"}},
+			"shard-2": {records: []string{"old"}, closed: true},
+			"shard-3": {parent: "shard-2", records: []string{"c"}},
+		},
+		order: []string{"shard-1", "shard-2", "shard-3"},
+	}
+	leases := newMemLeases(lease{shard: "shard-1", checkpoint: "1"})
+	p := &fakeProcessor{}
+	c, mf := newTestConsumer(t, Options{
+		PollInterval:          time.Millisecond,
+		ShardsRefreshInterval: 5 * time.Millisecond,
+		CheckpointInterval:    time.Hour,
+	}, client, leases, p)
+	c.Start()
+
+	eventually(t, func() bool { return len(p.processed()) == 2 })
+	// shard-1 resumes after its checkpoint, shard-2 is read from LATEST
+	// and its child shard-3 from its start once shard-2 ended
+	assert.Equal(t, []string{"a", "c"}, p.processed())
+	assert.Equal(t, shardEnd, leases.get("shard-2").checkpoint)
+	assert.Equal(t, "", leases.get("shard-2").owner)
+	assert.Equal(t, "me", leases.get("shard-1").owner)
+	assert.Equal(t, "1", leases.get("shard-1").checkpoint)
+
+	require.NoError(t, c.Close())
+	assert.True(t, p.closed)
+	for shard, checkpoint := range map[string]string{"shard-1": "3", "shard-2": shardEnd, "shard-3": "1"} {
+		assert.Equal(t, checkpoint, leases.get(shard).checkpoint, shard)
+		assert.Equal(t, "", leases.get(shard).owner, shard)
+	}
+	counters, gauges := mf.Snapshot()
+	assert.Equal(t, int64(3), counters["messages"])
+	assert.Equal(t, int64(1), counters["errors"])
+	assert.Equal(t, int64(0), gauges["shards-held"])
+}
+
+func TestConsumerCheckpointInterval(t *testing.T) {
+	client := &fakeKinesis{shards: map[string]fakeShard{"shard-0": {records: []string{"a", "b"}}}, order: []string{"shard-0"}}
+	leases := newMemLeases()
+	p := &fakeProcessor{}
+	c, _ := newTestConsumer(t, Options{IteratorType: IteratorTrimHorizon, MaxRecords: 1, PollInterval: time.Millisecond}, client, leases, p)
+	now := time.Now()
+	c.now = func() time.Time {
+		now = now.Add(defaultCheckpoint)
+		return now
+	}
+	c.Start()
+	defer c.Close()
+
+	eventually(t, func() bool { return leases.get("shard-0").checkpoint == "2" })
+	assert.Equal(t, []string{"a", "b"}, p.processed())
+}
+
+func TestConsumerTakeLeases(t *testing.T) {
+	shards := map[string]fakeShard{}
+	var order []string
+	for i := 0; i < 5; i++ {
+		id := fmt.Sprintf("shard-%d", i)
+		shards[id] = fakeShard{}
+		order = append(order, id)
+	}
+	client := &fakeKinesis{shards: shards, order: order}
+	live := time.Now().Add(time.Hour).UnixNano() / int64(time.Millisecond)
+
+	t.Run("free and expired", func(t *testing.T) {
+		leases := newMemLeases(
+			lease{shard: "shard-0", owner: "other", expiry: live},
+			lease{shard: "shard-1", owner: "other", expiry: live},
+			lease{shard: "shard-2", owner: "other", expiry: 1},
+			lease{shard: "shard-4", checkpoint: shardEnd},
+		)
+		c, mf := newTestConsumer(t, Options{}, client, leases, &fakeProcessor{})
+		require.NoError(t, c.takeLeases())
+		assert.Equal(t, "me", leases.get("shard-2").owner)
+		assert.Equal(t, "me", leases.get("shard-3").owner)
+		assert.Equal(t, "other", leases.get("shard-0").owner)
+		assert.Equal(t, "", leases.get("shard-4").owner)
+		_, gauges := mf.Snapshot()
+		assert.Equal(t, int64(2), gauges["shards-held"])
+
+		// the leases held are not taken again
+		require.NoError(t, c.takeLeases())
+		require.NoError(t, c.Close())
+		assert.Equal(t, "", leases.get("shard-2").owner)
+	})
+	t.Run("steal from the busiest", func(t *testing.T) {
+		leases := newMemLeases(
+			lease{shard: "shard-0", owner: "other", expiry: live},
+			lease{shard: "shard-1", owner: "other", expiry: live},
+			lease{shard: "shard-2", owner: "other", expiry: live},
+			lease{shard: "shard-3", owner: "other", expiry: live, checkpoint: "7"},
+		)
+		c, _ := newTestConsumer(t, Options{}, client, leases, &fakeProcessor{})
+		require.NoError(t, c.takeLeases())
+		assert.Equal(t, "me", leases.get("shard-4").owner)
+		assert.Equal(t, "me", leases.get("shard-3").owner)
+		assert.Equal(t, "other", leases.get("shard-2").owner)
+		require.NoError(t, c.Close())
+	})
+	t.Run("lost lease", func(t *testing.T) {
+		leases := newMemLeases()
+		c, mf := newTestConsumer(t, Options{ShardsRefreshInterval: time.Hour, CheckpointInterval: time.Hour}, client, leases, &fakeProcessor{})
+		require.NoError(t, c.takeLeases())
+		for _, shard := range order {
+			require.Equal(t, "me", leases.get(shard).owner)
+		}
+		leases.set(lease{shard: "shard-0", owner: "other", expiry: live})
+		c.renewLeases()
+		eventually(t, func() bool {
+			_, gauges := mf.Snapshot()
+			return gauges["shards-held"] == 4
+		})
+		require.NoError(t, c.Close())
+		assert.Equal(t, "other", leases.get("shard-0").owner)
+		assert.Equal(t, "", leases.get("shard-1").owner)
+	})
+}
+
+func TestConsumerAPIError(t *testing.T) {
+	c, _ := newTestConsumer(t, Options{}, &fakeKinesis{}, newMemLeases(), &fakeProcessor{})
+	assert.EqualError(t, c.takeLeases(), "ResourceNotFoundException: Stream spans not found")
+}
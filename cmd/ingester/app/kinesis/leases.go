@@ -0,0 +1,198 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kinesis
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+const (
+	attrShard      = "shard_id"
+	attrOwner      = "owner"
+	attrExpiry     = "lease_expiry"
+	attrCheckpoint = "checkpoint"
+
+	// shardEnd is the checkpoint of a shard that was read to its end
+	shardEnd = "SHARD_END"
+)
+
+// lease is the state of a shard in the lease table: which consumer holds it until when,
+// and the sequence number of the last record it processed.
+type lease struct {
+	shard      string
+	owner      string
+	expiry     int64
+	checkpoint string
+}
+
+func (l lease) held(now time.Time) bool {
+	return l.owner != "" && l.expiry > now.UnixNano()/int64(time.Millisecond)
+}
+
+// leaseStore keeps the leases of the shards shared by the consumers of a stream. Updates made
+// on behalf of a consumer fail with errLeaseLost once another consumer took the lease.
+type leaseStore interface {
+	list() ([]lease, error)
+	take(previous lease, owner string, expiry time.Time) error
+	renew(shard, owner string, expiry time.Time) error
+	checkpoint(shard, owner, sequenceNumber string) error
+	release(shard, owner string) error
+}
+
+type leaseLostError string
+
+func (e leaseLostError) Error() string {
+	return "the lease of the Kinesis shard " + string(e) + " is held by another consumer"
+}
+
+// dynamoLeases stores the leases in a DynamoDB table keyed by shard id,
+// taking and updating them with conditional writes.
+type dynamoLeases struct {
+	client dynamodbiface.DynamoDBAPI
+	table  string
+}
+
+// ensureTable creates the lease table, billed per request, unless it exists.
+func (d *dynamoLeases) ensureTable() error {
+	_, err := d.client.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String(d.table)})
+	if err == nil {
+		return nil
+	}
+	if !isErrorCode(err, dynamodb.ErrCodeResourceNotFoundException) {
+		return err
+	}
+	_, err = d.client.CreateTable(&dynamodb.CreateTableInput{
+		TableName:            aws.String(d.table),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{{AttributeName: aws.String(attrShard), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)}},
+		KeySchema:            []*dynamodb.KeySchemaElement{{AttributeName: aws.String(attrShard), KeyType: aws.String(dynamodb.KeyTypeHash)}},
+		BillingMode:          aws.String(dynamodb.BillingModePayPerRequest),
+	})
+	// another consumer may have created the table meanwhile
+	if err != nil && !isErrorCode(err, dynamodb.ErrCodeResourceInUseException) {
+		return err
+	}
+	return d.client.WaitUntilTableExists(&dynamodb.DescribeTableInput{TableName: aws.String(d.table)})
+}
+
+func (d *dynamoLeases) list() ([]lease, error) {
+	var leases []lease
+	err := d.client.ScanPages(&dynamodb.ScanInput{TableName: aws.String(d.table), ConsistentRead: aws.Bool(true)},
+		func(page *dynamodb.ScanOutput, _ bool) bool {
+			for _, item := range page.Items {
+				l := lease{shard: stringAttr(item[attrShard]), owner: stringAttr(item[attrOwner]), checkpoint: stringAttr(item[attrCheckpoint])}
+				if v := item[attrExpiry]; v != nil && v.N != nil {
+					l.expiry, _ = strconv.ParseInt(*v.N, 10, 64)
+				}
+				leases = append(leases, l)
+			}
+			return true
+		})
+	return leases, err
+}
+
+// take sets the owner of a lease that is still as it was listed, either free, expired or held by another consumer.
+func (d *dynamoLeases) take(previous lease, owner string, expiry time.Time) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName:                aws.String(d.table),
+		Key:                      shardKey(previous.shard),
+		UpdateExpression:         aws.String("SET #owner = :owner, #expiry = :expiry"),
+		ExpressionAttributeNames: map[string]*string{"#owner": aws.String(attrOwner), "#expiry": aws.String(attrExpiry)},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":owner":  {S: aws.String(owner)},
+			":expiry": millisAttr(expiry),
+		},
+	}
+	if previous.owner == "" {
+		input.ConditionExpression = aws.String("attribute_not_exists(#owner)")
+	} else {
+		input.ConditionExpression = aws.String("#owner = :previous AND #expiry = :previousExpiry")
+		input.ExpressionAttributeValues[":previous"] = &dynamodb.AttributeValue{S: aws.String(previous.owner)}
+		input.ExpressionAttributeValues[":previousExpiry"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(previous.expiry, 10))}
+	}
+	return d.update(previous.shard, input)
+}
+
+func (d *dynamoLeases) renew(shard, owner string, expiry time.Time) error {
+	return d.updateOwned(shard, owner, "SET #expiry = :expiry", map[string]*dynamodb.AttributeValue{":expiry": millisAttr(expiry)})
+}
+
+func (d *dynamoLeases) checkpoint(shard, owner, sequenceNumber string) error {
+	return d.updateOwned(shard, owner, "SET #checkpoint = :checkpoint", map[string]*dynamodb.AttributeValue{":checkpoint": {S: aws.String(sequenceNumber)}})
+}
+
+func (d *dynamoLeases) release(shard, owner string) error {
+	return d.updateOwned(shard, owner, "REMOVE #owner, #expiry", nil)
+}
+
+func (d *dynamoLeases) updateOwned(shard, owner, update string, values map[string]*dynamodb.AttributeValue) error {
+	if values == nil {
+		values = make(map[string]*dynamodb.AttributeValue)
+	}
+	values[":me"] = &dynamodb.AttributeValue{S: aws.String(owner)}
+	return d.update(shard, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(d.table),
+		Key:                 shardKey(shard),
+		UpdateExpression:    aws.String(update),
+		ConditionExpression: aws.String("#owner = :me"),
+		// DynamoDB rejects the names an expression does not use
+		ExpressionAttributeNames:  attributeNames(update + " #owner"),
+		ExpressionAttributeValues: values,
+	})
+}
+
+func (d *dynamoLeases) update(shard string, input *dynamodb.UpdateItemInput) error {
+	_, err := d.client.UpdateItem(input)
+	if isErrorCode(err, dynamodb.ErrCodeConditionalCheckFailedException) {
+		return leaseLostError(shard)
+	}
+	return err
+}
+
+func attributeNames(expression string) map[string]*string {
+	names := make(map[string]*string)
+	for name, attr := range map[string]string{"#owner": attrOwner, "#expiry": attrExpiry, "#checkpoint": attrCheckpoint} {
+		if strings.Contains(expression, name) {
+			names[name] = aws.String(attr)
+		}
+	}
+	return names
+}
+
+func shardKey(shard string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{attrShard: {S: aws.String(shard)}}
+}
+
+func millisAttr(t time.Time) *dynamodb.AttributeValue {
+	return &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10))}
+}
+
+func stringAttr(v *dynamodb.AttributeValue) string {
+	if v == nil || v.S == nil {
+		return ""
+	}
+	return *v.S
+}
+
+func isErrorCode(err error, code string) bool {
+	awsErr, ok := err.(awserr.Error)
+	return ok && awsErr.Code() == code
+}
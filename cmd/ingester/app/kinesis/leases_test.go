@@ -0,0 +1,192 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kinesis
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDynamo struct {
+	dynamodbiface.DynamoDBAPI
+	describeErr error
+	createErr   error
+	created     *dynamodb.CreateTableInput
+	waited      bool
+	items       []map[string]*dynamodb.AttributeValue
+	updates     []*dynamodb.UpdateItemInput
+	updateErr   error
+}
+
+func (f *fakeDynamo) DescribeTable(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+	return &dynamodb.DescribeTableOutput{}, f.describeErr
+}
+
+func (f *fakeDynamo) CreateTable(input *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+	f.created = input
+	return &dynamodb.CreateTableOutput{}, f.createErr
+}
+
+func (f *fakeDynamo) WaitUntilTableExists(*dynamodb.DescribeTableInput) error {
+	f.waited = true
+	return nil
+}
+
+func (f *fakeDynamo) ScanPages(input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool) error {
+	for i, item := range f.items {
+		if !fn(&dynamodb.ScanOutput{Items: []map[string]*dynamodb.AttributeValue{item}}, i == len(f.items)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+func (f *fakeDynamo) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	f.updates = append(f.updates, input)
+	return &dynamodb.UpdateItemOutput{}, f.updateErr
+}
+
+func TestDynamoLeasesEnsureTable(t *testing.T) {
+	notFound := awserr.New(dynamodb.ErrCodeResourceNotFoundException, "not found", nil)
+	tests := []struct {
+		name      string
+		client    *fakeDynamo
+		created   bool
+		expectErr string
+	}{
+		{name: "exists", client: &fakeDynamo{}},
+		{name: "missing", client: &fakeDynamo{describeErr: notFound}, created: true},
+		{
+			name:    "created meanwhile",
+			client:  &fakeDynamo{describeErr: notFound, createErr: awserr.New(dynamodb.ErrCodeResourceInUseException, "in use", nil)},
+			created: true,
+		},
+		{name: "describe error", client: &fakeDynamo{describeErr: errors.New("denied")}, expectErr: "denied"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := (&dynamoLeases{client: test.client, table: "leases"}).ensureTable()
+			if test.expectErr != "" {
+				assert.EqualError(t, err, test.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.created, test.client.created != nil)
+			assert.Equal(t, test.created, test.client.waited)
+			if test.created {
+				assert.Equal(t, dynamodb.BillingModePayPerRequest, *test.client.created.BillingMode)
+				assert.Equal(t, attrShard, *test.client.created.KeySchema[0].AttributeName)
+			}
+		})
+	}
+}
+
+func TestDynamoLeasesList(t *testing.T) {
+	client := &fakeDynamo{items: []map[string]*dynamodb.AttributeValue{
+		{attrShard: {S: aws.String("shard-1")}, attrOwner: {S: aws.String("me")}, attrExpiry: {N: aws.String("1000")}, attrCheckpoint: {S: aws.String("42")}},
+		{attrShard: {S: aws.String("shard-2")}},
+	}}
+	leases, err := (&dynamoLeases{client: client, table: "leases"}).list()
+	require.NoError(t, err)
+	assert.Equal(t, []lease{{shard: "shard-1", owner: "me", expiry: 1000, checkpoint: "42"}, {shard: "shard-2"}}, leases)
+	assert.True(t, leases[0].held(time.Unix(0, 999*int64(time.Millisecond))))
+	assert.False(t, leases[0].held(time.Unix(1, 0)))
+}
+
+func TestDynamoLeasesUpdates(t *testing.T) {
+	client := &fakeDynamo{}
+	leases := &dynamoLeases{client: client, table: "leases"}
+	expiry := time.Unix(2, 0)
+
+	require.NoError(t, leases.take(lease{shard: "shard-1"}, "me", expiry))
+	require.NoError(t, leases.take(lease{shard: "shard-1", owner: "other", expiry: 1000}, "me", expiry))
+	require.NoError(t, leases.renew("shard-1", "me", expiry))
+	require.NoError(t, leases.checkpoint("shard-1", "me", "42"))
+	require.NoError(t, leases.release("shard-1", "me"))
+
+	expected := []struct {
+		update    string
+		condition string
+		names     []string
+		values    map[string]string
+	}{
+		{
+			update:    "SET #owner = :owner, #expiry = :expiry",
+			condition: "attribute_not_exists(#owner)",
+			names:     []string{"#expiry", "#owner"},
+			values:    map[string]string{":owner": "me", ":expiry": "2000"},
+		},
+		{
+			update:    "SET #owner = :owner, #expiry = :expiry",
+			condition: "#owner = :previous AND #expiry = :previousExpiry",
+			names:     []string{"#expiry", "#owner"},
+			values:    map[string]string{":owner": "me", ":expiry": "2000", ":previous": "other", ":previousExpiry": "1000"},
+		},
+		{
+			update:    "SET #expiry = :expiry",
+			condition: "#owner = :me",
+			names:     []string{"#expiry", "#owner"},
+			values:    map[string]string{":me": "me", ":expiry": "2000"},
+		},
+		{
+			update:    "SET #checkpoint = :checkpoint",
+			condition: "#owner = :me",
+			names:     []string{"#checkpoint", "#owner"},
+			values:    map[string]string{":me": "me", ":checkpoint": "42"},
+		},
+		{
+			update:    "REMOVE #owner, #expiry",
+			condition: "#owner = :me",
+			names:     []string{"#expiry", "#owner"},
+			values:    map[string]string{":me": "me"},
+		},
+	}
+	require.Len(t, client.updates, len(expected))
+	for i, update := range client.updates {
+		assert.Equal(t, "leases", *update.TableName)
+		assert.Equal(t, "shard-1", *update.Key[attrShard].S)
+		assert.Equal(t, expected[i].update, *update.UpdateExpression)
+		assert.Equal(t, expected[i].condition, *update.ConditionExpression)
+		var names []string
+		for name := range update.ExpressionAttributeNames {
+			names = append(names, name)
+		}
+		assert.ElementsMatch(t, expected[i].names, names, expected[i].update)
+		values := make(map[string]string)
+		for name, value := range update.ExpressionAttributeValues {
+			values[name] = aws.StringValue(value.S) + aws.StringValue(value.N)
+		}
+		assert.Equal(t, expected[i].values, values, expected[i].update)
+	}
+}
+
+func TestDynamoLeasesLost(t *testing.T) {
+	client := &fakeDynamo{updateErr: awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "failed", nil)}
+	leases := &dynamoLeases{client: client, table: "leases"}
+	err := leases.renew("shard-1", "me", time.Now())
+	assert.Equal(t, leaseLostError("shard-1"), err)
+	assert.EqualError(t, err, "the lease of the Kinesis shard shard-1 is held by another consumer")
+
+	client.updateErr = errors.New("throttled")
+	assert.EqualError(t, leases.checkpoint("shard-1", "me", "42"), "throttled")
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kinesis
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/jaegertracing/jaeger/plugin/storage/kafka"
+)
+
+const (
+	configPrefix         = "kinesis.consumer"
+	suffixStream         = ".stream"
+	suffixRegion         = ".region"
+	suffixEndpoint       = ".endpoint"
+	suffixIteratorType   = ".iterator-type"
+	suffixEncoding       = ".encoding"
+	suffixMaxRecords     = ".max-records"
+	suffixPollInterval   = ".poll-interval"
+	suffixShardsInterval = ".shards-refresh-interval"
+	suffixLeaseTable     = ".lease-table"
+	suffixLeaseEndpoint  = ".lease-table-endpoint"
+	suffixLeaseDuration  = ".lease-duration"
+	suffixCheckpoint     = ".checkpoint-interval"
+
+	// IteratorLatest starts reading the shards at the most recent record
+	IteratorLatest = "LATEST"
+	// IteratorTrimHorizon starts reading the shards at the oldest available record
+	IteratorTrimHorizon = "TRIM_HORIZON"
+
+	defaultRegion         = "us-east-1"
+	defaultMaxRecords     = 1000
+	defaultPollInterval   = time.Second
+	defaultShardsInterval = time.Minute
+	defaultLeaseDuration  = 30 * time.Second
+	defaultCheckpoint     = 10 * time.Second
+	leaseTablePrefix      = "jaeger-ingester-"
+)
+
+// Options configures the consumption of spans from an AWS Kinesis stream.
+// The credentials are looked up by the default chain of the AWS SDK: the environment,
+// the shared credentials file, then the ECS or EC2 role.
+type Options struct {
+	// Stream is the name of the Kinesis stream
+	Stream string
+	// Region is the AWS region of the stream
+	Region string
+	// Endpoint overrides the regional Kinesis endpoint, e.g. for a local emulator
+	Endpoint string
+	// IteratorType is where the consumption of a shard never checkpointed starts, LATEST or TRIM_HORIZON
+	IteratorType string
+	// Encoding is the encoding of the spans in the records
+	Encoding string
+	// MaxRecords is the maximum number of records fetched from a shard at once
+	MaxRecords int
+	// PollInterval is how long to wait before fetching a shard that returned no records
+	PollInterval time.Duration
+	// ShardsRefreshInterval is how often the shards and leases are listed to discover new shards and take leases
+	ShardsRefreshInterval time.Duration
+
+	// LeaseTable is the DynamoDB table keeping the leases and checkpoints of the shards,
+	// jaeger-ingester-<stream> by default
+	LeaseTable string
+	// LeaseEndpoint overrides the regional DynamoDB endpoint of the lease table
+	LeaseEndpoint string
+	// LeaseDuration is how long a lease is held without being renewed, renewed every third of it
+	LeaseDuration time.Duration
+	// CheckpointInterval is how often the last record processed in a shard is checkpointed
+	CheckpointInterval time.Duration
+}
+
+// AddFlags adds flags for Kinesis Options
+func AddFlags(flags *flag.FlagSet) {
+	flags.String(configPrefix+suffixStream, "", "The name of the Kinesis stream to consume from")
+	flags.String(configPrefix+suffixRegion, defaultRegion, "The AWS region of the Kinesis stream")
+	flags.String(configPrefix+suffixEndpoint, "", "The Kinesis endpoint URL, defaults to the endpoint of the region")
+	flags.String(configPrefix+suffixIteratorType, IteratorLatest,
+		fmt.Sprintf("Where to start consuming the shards never checkpointed (%s or %s)", IteratorLatest, IteratorTrimHorizon))
+	flags.String(configPrefix+suffixEncoding, kafka.EncodingProto,
+		fmt.Sprintf(`The encoding of spans ("%s") consumed from Kinesis`, strings.Join(kafka.AllEncodings, "\", \"")))
+	flags.Int(configPrefix+suffixMaxRecords, defaultMaxRecords, "The maximum number of records fetched from a shard at once")
+	flags.Duration(configPrefix+suffixPollInterval, defaultPollInterval, "How long to wait before fetching again a shard that had no new records")
+	flags.Duration(configPrefix+suffixShardsInterval, defaultShardsInterval, "How often to list the shards and leases of the stream to pick up resharding and take leases")
+	flags.String(configPrefix+suffixLeaseTable, "", "The DynamoDB table keeping the shard leases and checkpoints, created if missing; defaults to "+leaseTablePrefix+"<stream>")
+	flags.String(configPrefix+suffixLeaseEndpoint, "", "The DynamoDB endpoint URL of the lease table, defaults to the endpoint of the region")
+	flags.Duration(configPrefix+suffixLeaseDuration, defaultLeaseDuration, "How long a shard lease is held without renewal before another ingester can take it")
+	flags.Duration(configPrefix+suffixCheckpoint, defaultCheckpoint, "How often to checkpoint the last record processed in a shard")
+}
+
+// InitFromViper initializes Options with properties from viper
+func (o *Options) InitFromViper(v *viper.Viper) *Options {
+	o.Stream = v.GetString(configPrefix + suffixStream)
+	o.Region = v.GetString(configPrefix + suffixRegion)
+	o.Endpoint = v.GetString(configPrefix + suffixEndpoint)
+	o.IteratorType = v.GetString(configPrefix + suffixIteratorType)
+	o.Encoding = v.GetString(configPrefix + suffixEncoding)
+	o.MaxRecords = v.GetInt(configPrefix + suffixMaxRecords)
+	o.PollInterval = v.GetDuration(configPrefix + suffixPollInterval)
+	o.ShardsRefreshInterval = v.GetDuration(configPrefix + suffixShardsInterval)
+	o.LeaseTable = v.GetString(configPrefix + suffixLeaseTable)
+	o.LeaseEndpoint = v.GetString(configPrefix + suffixLeaseEndpoint)
+	o.LeaseDuration = v.GetDuration(configPrefix + suffixLeaseDuration)
+	o.CheckpointInterval = v.GetDuration(configPrefix + suffixCheckpoint)
+	return o
+}
+
+// validate checks the options and sets the defaults of the ones left empty.
+func (o *Options) validate() error {
+	if o.Stream == "" {
+		return errors.New("the Kinesis stream name is required")
+	}
+	if o.IteratorType != IteratorLatest && o.IteratorType != IteratorTrimHorizon {
+		return fmt.Errorf("unknown Kinesis iterator type %q, use %s or %s", o.IteratorType, IteratorLatest, IteratorTrimHorizon)
+	}
+	if o.MaxRecords <= 0 {
+		o.MaxRecords = defaultMaxRecords
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = defaultPollInterval
+	}
+	if o.ShardsRefreshInterval <= 0 {
+		o.ShardsRefreshInterval = defaultShardsInterval
+	}
+	if o.LeaseTable == "" {
+		o.LeaseTable = leaseTablePrefix + o.Stream
+	}
+	if o.LeaseDuration <= 0 {
+		o.LeaseDuration = defaultLeaseDuration
+	}
+	if o.CheckpointInterval <= 0 {
+		o.CheckpointInterval = defaultCheckpoint
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+
+	"github.com/jaegertracing/jaeger/cmd/ingester/app/processor"
+)
+
+type consumerMetrics struct {
+	Messages metrics.Counter `metric:"messages"`
+	Errors   metrics.Counter `metric:"errors"`
+}
+
+// Consumer receives span messages from a Pub/Sub subscription and hands them to the span processor.
+// A message is acknowledged once it was processed; the messages that failed are nacked
+// for Pub/Sub to redeliver them, or to move them to the dead letter topic of the subscription.
+type Consumer struct {
+	opts         Options
+	client       *pubsub.Client
+	subscription *pubsub.Subscription
+	processor    processor.SpanProcessor
+	logger       *zap.Logger
+	metrics      consumerMetrics
+	ctx          context.Context
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+}
+
+type message []byte
+
+func (m message) Value() []byte {
+	return m
+}
+
+// NewConsumer creates a Consumer of the subscription configured in opts.
+func NewConsumer(opts Options, spanProcessor processor.SpanProcessor, logger *zap.Logger, metricsFactory metrics.Factory) (*Consumer, error) {
+	parts := strings.Split(opts.Subscription, "/")
+	if len(parts) != 4 || parts[0] != "projects" || parts[2] != "subscriptions" || parts[1] == "" || parts[3] == "" {
+		return nil, fmt.Errorf("the Pub/Sub subscription %q must be of the form projects/<project>/subscriptions/<subscription>", opts.Subscription)
+	}
+	if opts.Endpoint == "" {
+		opts.Endpoint = DefaultEndpoint
+	}
+	if opts.MaxMessages <= 0 {
+		opts.MaxMessages = defaultMaxMessages
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = defaultRetryInterval
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	client, err := pubsub.NewClient(ctx, parts[1], clientOptions(opts)...)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("cannot create the Pub/Sub client: %w", err)
+	}
+	subscription := client.SubscriptionInProject(parts[3], parts[1])
+	subscription.ReceiveSettings.MaxOutstandingMessages = opts.MaxMessages
+	c := &Consumer{
+		opts:         opts,
+		client:       client,
+		subscription: subscription,
+		processor:    spanProcessor,
+		logger:       logger,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+	metrics.Init(&c.metrics, metricsFactory, nil)
+	return c, nil
+}
+
+func clientOptions(opts Options) []option.ClientOption {
+	if opts.Endpoint != DefaultEndpoint {
+		return []option.ClientOption{
+			option.WithEndpoint(opts.Endpoint),
+			option.WithoutAuthentication(),
+			option.WithGRPCDialOption(grpc.WithInsecure()),
+		}
+	}
+	if opts.CredentialsFile != "" {
+		return []option.ClientOption{option.WithCredentialsFile(opts.CredentialsFile)}
+	}
+	return nil
+}
+
+// Start begins receiving the subscription in the background.
+func (c *Consumer) Start() {
+	c.wg.Add(1)
+	go c.receiveLoop()
+}
+
+// Close stops receiving the subscription and closes the span processor.
+func (c *Consumer) Close() error {
+	c.cancel()
+	c.wg.Wait()
+	if err := c.client.Close(); err != nil {
+		c.logger.Error("Failed to close the Pub/Sub client", zap.Error(err))
+	}
+	return c.processor.Close()
+}
+
+func (c *Consumer) receiveLoop() {
+	defer c.wg.Done()
+	for {
+		// Receive returns when the context is cancelled or the subscription failed for good
+		err := c.subscription.Receive(c.ctx, c.handle)
+		if c.ctx.Err() != nil {
+			return
+		}
+		c.logger.Error("Failed to receive Pub/Sub subscription", zap.String("subscription", c.opts.Subscription), zap.Error(err))
+		select {
+		case <-time.After(c.opts.RetryInterval):
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Consumer) handle(_ context.Context, m *pubsub.Message) {
+	c.metrics.Messages.Inc(1)
+	if err := c.processor.Process(message(m.Data)); err != nil {
+		c.metrics.Errors.Inc(1)
+		c.logger.Error("Failed to process Pub/Sub message", zap.String("id", m.ID), zap.Error(err))
+		m.Nack()
+		return
+	}
+	m.Ack()
+}
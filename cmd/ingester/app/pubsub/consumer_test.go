@@ -0,0 +1,159 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/cmd/ingester/app/processor"
+	"github.com/jaegertracing/jaeger/pkg/config"
+)
+
+const testSubscription = "projects/p/subscriptions/spans"
+
+type fakeProcessor struct {
+	lock     sync.Mutex
+	messages []string
+}
+
+func (p *fakeProcessor) Process(m processor.Message) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if string(m.Value()) == "bad" {
+		return errors.New("cannot unmarshal")
+	}
+	p.messages = append(p.messages, string(m.Value()))
+	return nil
+}
+
+func (p *fakeProcessor) Close() error {
+	return nil
+}
+
+func (p *fakeProcessor) processed() []string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return append([]string(nil), p.messages...)
+}
+
+func newFakePubSub(t *testing.T) *pstest.Server {
+	server := pstest.NewServer()
+	ctx := context.Background()
+	client, err := pubsub.NewClient(ctx, "p", clientOptions(Options{Endpoint: server.Addr})...)
+	require.NoError(t, err)
+	defer client.Close()
+	topic, err := client.CreateTopic(ctx, "spans")
+	require.NoError(t, err)
+	_, err = client.CreateSubscription(ctx, "spans", pubsub.SubscriptionConfig{Topic: topic})
+	require.NoError(t, err)
+	return server
+}
+
+func TestOptionsWithFlags(t *testing.T) {
+	v, command := config.Viperize(AddFlags)
+	opts := new(Options).InitFromViper(v)
+	assert.Equal(t, DefaultEndpoint, opts.Endpoint)
+	assert.Equal(t, defaultMaxMessages, opts.MaxMessages)
+
+	command.ParseFlags([]string{
+		"--pubsub.consumer.subscription=" + testSubscription,
+		"--pubsub.consumer.endpoint=localhost:8085",
+		"--pubsub.consumer.credentials-file=/var/key.json",
+		"--pubsub.consumer.encoding=json",
+		"--pubsub.consumer.max-messages=10",
+	})
+	opts.InitFromViper(v)
+	assert.Equal(t, testSubscription, opts.Subscription)
+	assert.Equal(t, "localhost:8085", opts.Endpoint)
+	assert.Equal(t, "/var/key.json", opts.CredentialsFile)
+	assert.Equal(t, "json", opts.Encoding)
+	assert.Equal(t, 10, opts.MaxMessages)
+	assert.Equal(t, defaultRetryInterval, opts.RetryInterval)
+}
+
+func TestNewConsumerInvalidSubscription(t *testing.T) {
+	for _, subscription := range []string{"spans", "projects/p/topics/spans", "projects//subscriptions/spans"} {
+		_, err := NewConsumer(Options{Subscription: subscription}, &fakeProcessor{}, zap.NewNop(), metricstest.NewFactory(0))
+		assert.Error(t, err, subscription)
+	}
+}
+
+func TestNewConsumerCredentialsFile(t *testing.T) {
+	_, err := NewConsumer(Options{Subscription: testSubscription, CredentialsFile: "/does/not/exist.json"},
+		&fakeProcessor{}, zap.NewNop(), metricstest.NewFactory(0))
+	assert.Error(t, err)
+}
+
+func TestClientOptions(t *testing.T) {
+	assert.Len(t, clientOptions(Options{Endpoint: DefaultEndpoint}), 0)
+	assert.Len(t, clientOptions(Options{Endpoint: DefaultEndpoint, CredentialsFile: "key.json"}), 1)
+	assert.Len(t, clientOptions(Options{Endpoint: "localhost:8085", CredentialsFile: "key.json"}), 3)
+}
+
+func TestConsumer(t *testing.T) {
+	server := newFakePubSub(t)
+	defer server.Close()
+	ids := map[string]string{}
+	for _, data := range []string{"a", "bad", "b"} {
+		ids[data] = server.Publish("projects/p/topics/spans", []byte(data), nil)
+	}
+
+	p := &fakeProcessor{}
+	mf := metricstest.NewFactory(0)
+	c, err := NewConsumer(Options{Subscription: testSubscription, Endpoint: server.Addr}, p, zap.NewNop(), mf)
+	require.NoError(t, err)
+	c.Start()
+
+	acked := func() bool {
+		return server.Message(ids["a"]).Acks > 0 && server.Message(ids["b"]).Acks > 0
+	}
+	for i := 0; i < 400 && !acked(); i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	require.NoError(t, c.Close())
+	assert.Equal(t, []string{"a", "b"}, p.processed())
+	assert.True(t, acked())
+	// the message that failed is nacked for redelivery
+	assert.Equal(t, 0, server.Message(ids["bad"]).Acks)
+	counters, _ := mf.Snapshot()
+	assert.Equal(t, counters["errors"]+2, counters["messages"])
+	assert.True(t, counters["errors"] >= 1)
+}
+
+func TestConsumerMissingSubscription(t *testing.T) {
+	server := newFakePubSub(t)
+	defer server.Close()
+
+	c, err := NewConsumer(Options{Subscription: "projects/p/subscriptions/missing", Endpoint: server.Addr, RetryInterval: time.Hour},
+		&fakeProcessor{}, zap.NewNop(), metricstest.NewFactory(0))
+	require.NoError(t, err)
+	err = c.subscription.Receive(context.Background(), c.handle)
+	assert.Error(t, err)
+
+	// the receive loop waits before retrying and stops on close
+	c.Start()
+	require.NoError(t, c.Close())
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsub
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/jaegertracing/jaeger/plugin/storage/kafka"
+)
+
+const (
+	configPrefix      = "pubsub.consumer"
+	suffixSubcription = ".subscription"
+	suffixEndpoint    = ".endpoint"
+	suffixCredentials = ".credentials-file"
+	suffixEncoding    = ".encoding"
+	suffixMaxMessages = ".max-messages"
+	suffixRetry       = ".retry-interval"
+
+	// DefaultEndpoint is the Google Cloud Pub/Sub API endpoint
+	DefaultEndpoint = "pubsub.googleapis.com:443"
+
+	defaultMaxMessages   = 100
+	defaultRetryInterval = time.Second
+)
+
+// Options configures the consumption of spans from a Google Cloud Pub/Sub subscription.
+type Options struct {
+	// Subscription is the full name of the subscription, projects/<project>/subscriptions/<subscription>
+	Subscription string
+	// Endpoint is the host:port of the Pub/Sub API. Any endpoint other than DefaultEndpoint
+	// is taken for an emulator, reached in plain text and without credentials.
+	Endpoint string
+	// CredentialsFile is a service account key file. When empty, the Application Default
+	// Credentials are used (GOOGLE_APPLICATION_CREDENTIALS, gcloud or the GCE metadata server).
+	CredentialsFile string
+	// Encoding is the encoding of the spans in the messages
+	Encoding string
+	// MaxMessages is the maximum number of messages received and not yet acknowledged
+	MaxMessages int
+	// RetryInterval is how long to wait before receiving again after a failure
+	RetryInterval time.Duration
+}
+
+// AddFlags adds flags for Pub/Sub Options
+func AddFlags(flags *flag.FlagSet) {
+	flags.String(configPrefix+suffixSubcription, "", "The subscription to consume from, projects/<project>/subscriptions/<subscription>")
+	flags.String(configPrefix+suffixEndpoint, DefaultEndpoint, "The host:port of the Pub/Sub API; any other endpoint, "+
+		"e.g. localhost:8085, is taken for an emulator and reached in plain text without credentials")
+	flags.String(configPrefix+suffixCredentials, "", "Path to a service account key file; by default the Application Default Credentials are used")
+	flags.String(configPrefix+suffixEncoding, kafka.EncodingProto,
+		fmt.Sprintf(`The encoding of spans ("%s") consumed from Pub/Sub`, strings.Join(kafka.AllEncodings, "\", \"")))
+	flags.Int(configPrefix+suffixMaxMessages, defaultMaxMessages, "The maximum number of messages received and not yet acknowledged")
+	flags.Duration(configPrefix+suffixRetry, defaultRetryInterval, "How long to wait before receiving again after the subscription failed")
+}
+
+// InitFromViper initializes Options with properties from viper
+func (o *Options) InitFromViper(v *viper.Viper) *Options {
+	o.Subscription = v.GetString(configPrefix + suffixSubcription)
+	o.Endpoint = v.GetString(configPrefix + suffixEndpoint)
+	o.CredentialsFile = v.GetString(configPrefix + suffixCredentials)
+	o.Encoding = v.GetString(configPrefix + suffixEncoding)
+	o.MaxMessages = v.GetInt(configPrefix + suffixMaxMessages)
+	o.RetryInterval = v.GetDuration(configPrefix + suffixRetry)
+	return o
+}
@@ -48,8 +48,8 @@ func main() {
 	v := viper.New()
 	command := &cobra.Command{
 		Use:   "jaeger-ingester",
-		Short: "Jaeger ingester consumes from Kafka and writes to storage.",
-		Long:  `Jaeger ingester consumes spans from a particular Kafka topic and writes them to a configured storage.`,
+		Short: "Jaeger ingester consumes from Kafka, Kinesis or Pub/Sub and writes to storage.",
+		Long:  `Jaeger ingester consumes spans from a particular Kafka topic, Kinesis stream or Pub/Sub subscription and writes them to a configured storage.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := svc.Start(v); err != nil {
 				return err
@@ -69,7 +69,7 @@ func main() {
 
 			options := app.Options{}
 			options.InitFromViper(v)
-			consumer, err := builder.CreateConsumer(logger, metricsFactory, spanWriter, options)
+			consumer, err := builder.CreateReceiver(logger, metricsFactory, spanWriter, options)
 			if err != nil {
 				logger.Fatal("Unable to create consumer", zap.Error(err))
 			}
@@ -0,0 +1,113 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/auth"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+// Logger writes the accesses to the traces as JSON lines, one per trace read or search, with the
+// identity and the tenant of the caller.
+type Logger struct {
+	logger *zap.Logger
+}
+
+// NewLogger creates a Logger writing to the output, a file path, "stdout" or "stderr".
+func NewLogger(output string) (*Logger, error) {
+	cfg := zap.Config{
+		Level:    zap.NewAtomicLevelAt(zap.InfoLevel),
+		Encoding: "json",
+		EncoderConfig: zapcore.EncoderConfig{
+			TimeKey:        "ts",
+			MessageKey:     "event",
+			LineEnding:     zapcore.DefaultLineEnding,
+			EncodeTime:     zapcore.ISO8601TimeEncoder,
+			EncodeDuration: zapcore.StringDurationEncoder,
+		},
+		OutputPaths:      []string{output},
+		ErrorOutputPaths: []string{"stderr"},
+	}
+	logger, err := cfg.Build()
+	if err != nil {
+		return nil, fmt.Errorf("cannot open the audit log %q: %w", output, err)
+	}
+	return &Logger{logger: logger}, nil
+}
+
+// TraceRead implements querysvc.Auditor.
+func (l *Logger) TraceRead(ctx context.Context, traceID model.TraceID, err error) {
+	fields := append(callerFields(ctx), zap.String("trace_id", traceID.String()))
+	l.logger.Info("trace-read", append(fields, outcomeFields(err)...)...)
+}
+
+// TracesSearched implements querysvc.Auditor.
+func (l *Logger) TracesSearched(ctx context.Context, query *spanstore.TraceQueryParameters, traces []*model.Trace, err error) {
+	fields := append(callerFields(ctx),
+		zap.String("service", query.ServiceName),
+		zap.String("operation", query.OperationName),
+		zap.Time("start_time_min", query.StartTimeMin),
+		zap.Time("start_time_max", query.StartTimeMax),
+		zap.Int("num_traces", query.NumTraces),
+	)
+	if len(query.Tags) > 0 {
+		fields = append(fields, zap.Any("tags", query.Tags))
+	}
+	if len(query.TagFilters) > 0 {
+		filters := make([]string, len(query.TagFilters))
+		for i, filter := range query.TagFilters {
+			filters[i] = filter.String()
+		}
+		fields = append(fields, zap.Strings("tag_filters", filters))
+	}
+	if query.DurationMin != 0 {
+		fields = append(fields, zap.Duration("duration_min", query.DurationMin))
+	}
+	if query.DurationMax != 0 {
+		fields = append(fields, zap.Duration("duration_max", query.DurationMax))
+	}
+	traceIDs := make([]string, 0, len(traces))
+	for _, trace := range traces {
+		if len(trace.Spans) > 0 {
+			traceIDs = append(traceIDs, trace.Spans[0].TraceID.String())
+		}
+	}
+	fields = append(fields, zap.Strings("trace_ids", traceIDs))
+	l.logger.Info("traces-searched", append(fields, outcomeFields(err)...)...)
+}
+
+// callerFields are the identity and the tenant of the caller, empty when they are not known.
+func callerFields(ctx context.Context) []zap.Field {
+	fields := []zap.Field{zap.String("tenant", tenancy.GetTenant(ctx))}
+	if identity := auth.IdentityFromContext(ctx); identity != nil {
+		return append(fields, zap.String("subject", identity.Subject), zap.Strings("groups", identity.Groups))
+	}
+	return append(fields, zap.String("subject", ""))
+}
+
+func outcomeFields(err error) []zap.Field {
+	if err != nil {
+		return []zap.Field{zap.Bool("success", false), zap.String("error", err.Error())}
+	}
+	return []zap.Field{zap.Bool("success", true)}
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/auth"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+func TestLogger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "audit.log")
+	logger, err := NewLogger(output)
+	require.NoError(t, err)
+
+	ctx := auth.ContextWithIdentity(context.Background(), &auth.Identity{Subject: "alice", Groups: []string{"sre"}})
+	ctx = tenancy.WithTenant(ctx, "acme")
+	logger.TraceRead(ctx, model.NewTraceID(0, 1), nil)
+	filter, err := spanstore.ParseTagFilter("http.url=~/api/.*")
+	require.NoError(t, err)
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	logger.TracesSearched(context.Background(), &spanstore.TraceQueryParameters{
+		ServiceName:  "frontend",
+		Tags:         map[string]string{"error": "true"},
+		TagFilters:   []spanstore.TagFilter{filter},
+		StartTimeMin: start,
+		StartTimeMax: start.Add(time.Hour),
+		DurationMin:  time.Second,
+		NumTraces:    20,
+	}, []*model.Trace{{Spans: []*model.Span{{TraceID: model.NewTraceID(0, 2)}}}, {}}, errors.New("timeout"))
+
+	data, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	var entries []map[string]interface{}
+	for _, line := range lines {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		assert.NotEmpty(t, entry["ts"])
+		delete(entry, "ts")
+		entries = append(entries, entry)
+	}
+	assert.Equal(t, map[string]interface{}{
+		"event":    "trace-read",
+		"tenant":   "acme",
+		"subject":  "alice",
+		"groups":   []interface{}{"sre"},
+		"trace_id": "0000000000000001",
+		"success":  true,
+	}, entries[0])
+	assert.Equal(t, map[string]interface{}{
+		"event":          "traces-searched",
+		"tenant":         "",
+		"subject":        "",
+		"service":        "frontend",
+		"operation":      "",
+		"start_time_min": "2020-06-01T12:00:00.000Z",
+		"start_time_max": "2020-06-01T13:00:00.000Z",
+		"num_traces":     float64(20),
+		"tags":           map[string]interface{}{"error": "true"},
+		"tag_filters":    []interface{}{filter.String()},
+		"duration_min":   "1s",
+		"trace_ids":      []interface{}{"0000000000000002"},
+		"success":        false,
+		"error":          "timeout",
+	}, entries[1])
+}
+
+func TestNewLoggerError(t *testing.T) {
+	_, err := NewLogger("/does/not/exist/audit.log")
+	assert.Error(t, err)
+}
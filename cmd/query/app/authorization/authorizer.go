@@ -0,0 +1,128 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authorization
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jaegertracing/jaeger/pkg/auth"
+)
+
+// anyone matches all the callers, including the anonymous ones, when used as a subject
+const anyone = "*"
+
+// Config is the content of the authorization rules file.
+type Config struct {
+	Rules []RuleConfig `json:"rules"`
+}
+
+// RuleConfig allows the callers with one of the subjects or in one of the groups to read
+// the spans of the services. The services are names, or patterns where * matches any text.
+type RuleConfig struct {
+	Subjects []string `json:"subjects"`
+	Groups   []string `json:"groups"`
+	Services []string `json:"services"`
+}
+
+type rule struct {
+	subjects map[string]bool
+	groups   map[string]bool
+	services *regexp.Regexp
+}
+
+// Authorizer decides which services a caller can read the spans of, based on the identity in
+// the request context. Callers not matching any rule cannot read any service.
+type Authorizer struct {
+	rules []rule
+}
+
+// LoadConfig reads the rules from a JSON file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open authorization rules file: %w", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal authorization rules: %w", err)
+	}
+	return &cfg, nil
+}
+
+// NewAuthorizer compiles the rules.
+func NewAuthorizer(cfg *Config) (*Authorizer, error) {
+	a := &Authorizer{}
+	for i, rc := range cfg.Rules {
+		if len(rc.Subjects) == 0 && len(rc.Groups) == 0 {
+			return nil, fmt.Errorf("authorization rule %d has no subjects or groups", i)
+		}
+		if len(rc.Services) == 0 {
+			return nil, fmt.Errorf("authorization rule %d has no services", i)
+		}
+		patterns := make([]string, len(rc.Services))
+		for j, service := range rc.Services {
+			patterns[j] = strings.Replace(regexp.QuoteMeta(service), `\*`, ".*", -1)
+		}
+		a.rules = append(a.rules, rule{
+			subjects: toSet(rc.Subjects),
+			groups:   toSet(rc.Groups),
+			services: regexp.MustCompile("^(?:" + strings.Join(patterns, "|") + ")$"),
+		})
+	}
+	return a, nil
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+func (r rule) matches(identity *auth.Identity) bool {
+	if r.subjects[anyone] {
+		return true
+	}
+	if identity == nil {
+		return false
+	}
+	if r.subjects[identity.Subject] {
+		return true
+	}
+	for _, group := range identity.Groups {
+		if r.groups[group] {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAllowed returns true if the caller can read the spans of the service.
+func (a *Authorizer) IsAllowed(ctx context.Context, service string) bool {
+	identity := auth.IdentityFromContext(ctx)
+	for _, r := range a.rules {
+		if r.matches(identity) && r.services.MatchString(service) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authorization
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/pkg/auth"
+)
+
+func TestLoadConfig(t *testing.T) {
+	_, err := LoadConfig("/does/not/exist")
+	assert.Contains(t, err.Error(), "failed to open authorization rules file")
+
+	f, err := ioutil.TempFile("", "rules")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`{"rules": [{"groups": ["payments"], "services": ["payment-*", "checkout"]}]}`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	cfg, err := LoadConfig(f.Name())
+	require.NoError(t, err)
+	assert.Equal(t, &Config{Rules: []RuleConfig{
+		{Groups: []string{"payments"}, Services: []string{"payment-*", "checkout"}},
+	}}, cfg)
+
+	require.NoError(t, ioutil.WriteFile(f.Name(), []byte("rules"), 0600))
+	_, err = LoadConfig(f.Name())
+	assert.Contains(t, err.Error(), "failed to unmarshal authorization rules")
+}
+
+func TestNewAuthorizerErrors(t *testing.T) {
+	_, err := NewAuthorizer(&Config{Rules: []RuleConfig{{Services: []string{"a"}}}})
+	assert.EqualError(t, err, "authorization rule 0 has no subjects or groups")
+	_, err = NewAuthorizer(&Config{Rules: []RuleConfig{{Subjects: []string{"alice"}}}})
+	assert.EqualError(t, err, "authorization rule 0 has no services")
+}
+
+func TestAuthorizer(t *testing.T) {
+	a, err := NewAuthorizer(&Config{Rules: []RuleConfig{
+		{Subjects: []string{"*"}, Services: []string{"frontend"}},
+		{Subjects: []string{"alice"}, Services: []string{"*"}},
+		{Groups: []string{"payments"}, Services: []string{"payment-*", "checkout"}},
+	}})
+	require.NoError(t, err)
+
+	anonymous := context.Background()
+	alice := auth.ContextWithIdentity(context.Background(), &auth.Identity{Subject: "alice"})
+	bob := auth.ContextWithIdentity(context.Background(), &auth.Identity{Subject: "bob", Groups: []string{"dev", "payments"}})
+	carol := auth.ContextWithIdentity(context.Background(), &auth.Identity{Subject: "carol"})
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		service string
+		allowed bool
+	}{
+		{name: "anyone", ctx: anonymous, service: "frontend", allowed: true},
+		{name: "anonymous", ctx: anonymous, service: "checkout", allowed: false},
+		{name: "subject", ctx: alice, service: "mysql", allowed: true},
+		{name: "group", ctx: bob, service: "checkout", allowed: true},
+		{name: "group pattern", ctx: bob, service: "payment-gateway", allowed: true},
+		{name: "anchored pattern", ctx: bob, service: "legacy-payment-gateway", allowed: false},
+		{name: "quoted pattern", ctx: bob, service: "checkou.", allowed: false},
+		{name: "no rule", ctx: carol, service: "mysql", allowed: false},
+		{name: "no rule but anyone", ctx: carol, service: "frontend", allowed: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.allowed, a.IsAllowed(test.ctx, test.service))
+		})
+	}
+}
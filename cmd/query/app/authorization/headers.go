@@ -0,0 +1,91 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authorization
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/jaegertracing/jaeger/pkg/auth"
+)
+
+// Headers takes the identity of the callers not authenticated by the query service from the
+// headers set by a trusted authenticating proxy. The groups header is a comma-separated list.
+type Headers struct {
+	User   string
+	Groups string
+}
+
+func (h Headers) identity(get func(key string) string) *auth.Identity {
+	user := get(h.User)
+	if user == "" {
+		return nil
+	}
+	identity := &auth.Identity{Subject: user}
+	if h.Groups != "" {
+		for _, group := range strings.Split(get(h.Groups), ",") {
+			if group = strings.TrimSpace(group); group != "" {
+				identity.Groups = append(identity.Groups, group)
+			}
+		}
+	}
+	return identity
+}
+
+func (h Headers) withIdentity(ctx context.Context, get func(key string) string) context.Context {
+	if auth.IdentityFromContext(ctx) != nil {
+		return ctx
+	}
+	if identity := h.identity(get); identity != nil {
+		return auth.ContextWithIdentity(ctx, identity)
+	}
+	return ctx
+}
+
+func metadataGetter(ctx context.Context) func(key string) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return func(key string) string {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+}
+
+// HTTPHandler returns a handler that adds the identity found in the headers to the request context.
+func (h Headers) HTTPHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(h.withIdentity(r.Context(), r.Header.Get)))
+	})
+}
+
+// UnaryServerInterceptor returns a gRPC interceptor that adds the identity found in the metadata to the context.
+func (h Headers) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(h.withIdentity(ctx, metadataGetter(ctx)), req)
+	}
+}
+
+// StreamServerInterceptor returns a gRPC interceptor that adds the identity found in the metadata to the stream context.
+func (h Headers) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := ss.Context()
+		return handler(srv, auth.WithStreamContext(h.withIdentity(ctx, metadataGetter(ctx)), ss))
+	}
+}
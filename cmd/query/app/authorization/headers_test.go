@@ -0,0 +1,94 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authorization
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/jaegertracing/jaeger/pkg/auth"
+)
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestHeadersHTTPHandler(t *testing.T) {
+	headers := Headers{User: "X-Forwarded-User", Groups: "X-Forwarded-Groups"}
+	var identity *auth.Identity
+	handler := headers.HTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity = auth.IdentityFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/services", nil)
+	req.Header.Set("X-Forwarded-User", "bob")
+	req.Header.Set("X-Forwarded-Groups", "dev, payments,")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, &auth.Identity{Subject: "bob", Groups: []string{"dev", "payments"}}, identity)
+
+	// the groups are ignored without a user
+	req = httptest.NewRequest(http.MethodGet, "/api/services", nil)
+	req.Header.Set("X-Forwarded-Groups", "payments")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Nil(t, identity)
+
+	// the identity authenticated by the query service is kept
+	alice := &auth.Identity{Subject: "alice"}
+	req = httptest.NewRequest(http.MethodGet, "/api/services", nil)
+	req.Header.Set("X-Forwarded-User", "bob")
+	handler.ServeHTTP(httptest.NewRecorder(), req.WithContext(auth.ContextWithIdentity(req.Context(), alice)))
+	assert.Equal(t, alice, identity)
+}
+
+func TestHeadersInterceptors(t *testing.T) {
+	headers := Headers{User: "X-Forwarded-User"}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-forwarded-user", "bob"))
+	bob := &auth.Identity{Subject: "bob"}
+
+	var identity *auth.Identity
+	_, err := headers.UnaryServerInterceptor()(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		identity = auth.IdentityFromContext(ctx)
+		return nil, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, bob, identity)
+
+	identity = nil
+	err = headers.StreamServerInterceptor()(nil, &contextStream{ctx: ctx}, &grpc.StreamServerInfo{}, func(srv interface{}, stream grpc.ServerStream) error {
+		identity = auth.IdentityFromContext(stream.Context())
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, bob, identity)
+
+	_, err = headers.UnaryServerInterceptor()(context.Background(), nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		identity = auth.IdentityFromContext(ctx)
+		return nil, nil
+	})
+	require.NoError(t, err)
+	assert.Nil(t, identity)
+}
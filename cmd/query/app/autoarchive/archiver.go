@@ -0,0 +1,237 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autoarchive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/cache"
+	"github.com/jaegertracing/jaeger/pkg/multierror"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+// archivedTraceIDs is the number of archived trace IDs remembered to not archive them again,
+// e.g. when they match several rules.
+const archivedTraceIDs = 10000
+
+// Config is the content of the automatic archiving rules file.
+type Config struct {
+	Rules []RuleConfig `json:"rules"`
+}
+
+// RuleConfig describes the traces archived by a rule. The traces must have a span of the service
+// matching all the other conditions. Errors is a shortcut for the tag error=true.
+type RuleConfig struct {
+	Name        string            `json:"name"`
+	Service     string            `json:"service"`
+	Operation   string            `json:"operation"`
+	Tags        map[string]string `json:"tags"`
+	Errors      bool              `json:"errors"`
+	MinDuration string            `json:"minDuration"`
+}
+
+// Options configures how often the rules are evaluated.
+type Options struct {
+	// Interval is how often the new traces are searched
+	Interval time.Duration
+	// Delay is how long after they started the traces are searched, to let them complete
+	Delay time.Duration
+	// MaxTraces is the maximum number of traces archived per rule at each interval
+	MaxTraces int
+}
+
+type rule struct {
+	name     string
+	query    spanstore.TraceQueryParameters
+	archived metrics.Counter
+	failed   metrics.Counter
+}
+
+// Archiver periodically searches the traces matching the rules and copies them to the archive storage,
+// so that they are kept longer than the traces of the main storage.
+type Archiver struct {
+	reader   spanstore.Reader
+	writer   spanstore.Writer
+	rules    []rule
+	options  Options
+	logger   *zap.Logger
+	archived *cache.LRU
+	timeNow  func() time.Time
+
+	// end is the end of the period of time of the last search
+	end  time.Time
+	stop chan struct{}
+	done sync.WaitGroup
+}
+
+// LoadConfig reads the rules from a JSON file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open automatic archiving rules file: %w", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal automatic archiving rules: %w", err)
+	}
+	return &cfg, nil
+}
+
+// NewArchiver validates the rules and creates counters of the archived traces for each of them.
+func NewArchiver(
+	cfg *Config,
+	reader spanstore.Reader,
+	writer spanstore.Writer,
+	options Options,
+	logger *zap.Logger,
+	metricsFactory metrics.Factory,
+) (*Archiver, error) {
+	if writer == nil {
+		return nil, errors.New("the automatic archiving rules require an archive storage")
+	}
+	a := &Archiver{
+		reader:   reader,
+		writer:   writer,
+		options:  options,
+		logger:   logger,
+		archived: cache.NewLRU(archivedTraceIDs),
+		timeNow:  time.Now,
+		stop:     make(chan struct{}),
+	}
+	names := make(map[string]bool)
+	for i, rc := range cfg.Rules {
+		name := rc.Name
+		if name == "" {
+			name = fmt.Sprintf("rule-%d", i)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("duplicate automatic archiving rule name %q", name)
+		}
+		names[name] = true
+		if rc.Service == "" {
+			return nil, fmt.Errorf("automatic archiving rule %q has no service", name)
+		}
+		query := spanstore.TraceQueryParameters{
+			ServiceName:   rc.Service,
+			OperationName: rc.Operation,
+			Tags:          make(map[string]string, len(rc.Tags)+1),
+			NumTraces:     options.MaxTraces,
+		}
+		for k, v := range rc.Tags {
+			query.Tags[k] = v
+		}
+		if rc.Errors {
+			query.Tags["error"] = "true"
+		}
+		if rc.MinDuration != "" {
+			d, err := time.ParseDuration(rc.MinDuration)
+			if err != nil {
+				return nil, fmt.Errorf("automatic archiving rule %q: invalid minDuration: %w", name, err)
+			}
+			query.DurationMin = d
+		}
+		tags := map[string]string{"rule": name}
+		a.rules = append(a.rules, rule{
+			name:     name,
+			query:    query,
+			archived: metricsFactory.Counter(metrics.Options{Name: "traces", Tags: tags}),
+			failed:   metricsFactory.Counter(metrics.Options{Name: "errors", Tags: tags}),
+		})
+	}
+	return a, nil
+}
+
+// Start evaluates the rules at every interval, from the traces started when the archiver starts.
+func (a *Archiver) Start() {
+	a.end = a.timeNow().Add(-a.options.Delay)
+	a.done.Add(1)
+	go func() {
+		defer a.done.Done()
+		ticker := time.NewTicker(a.options.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				a.archive(context.Background())
+			case <-a.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops evaluating the rules.
+func (a *Archiver) Close() error {
+	close(a.stop)
+	a.done.Wait()
+	return nil
+}
+
+// archive searches the traces started since the previous search, up to the delay before now.
+func (a *Archiver) archive(ctx context.Context) {
+	start, end := a.end, a.timeNow().Add(-a.options.Delay)
+	if !end.After(start) {
+		return
+	}
+	a.end = end
+	for _, r := range a.rules {
+		query := r.query
+		query.StartTimeMin = start
+		query.StartTimeMax = end
+		traces, err := a.reader.FindTraces(ctx, &query)
+		if err != nil {
+			r.failed.Inc(1)
+			a.logger.Error("Failed to search the traces to archive", zap.String("rule", r.name), zap.Error(err))
+			continue
+		}
+		for _, trace := range traces {
+			if len(trace.Spans) == 0 {
+				continue
+			}
+			traceID := trace.Spans[0].TraceID
+			if a.archived.Get(traceID.String()) != nil {
+				continue
+			}
+			if err := a.archiveTrace(trace); err != nil {
+				r.failed.Inc(1)
+				a.logger.Error("Failed to archive the trace", zap.String("rule", r.name), zap.Stringer("trace-id", traceID), zap.Error(err))
+				continue
+			}
+			a.archived.Put(traceID.String(), true)
+			r.archived.Inc(1)
+		}
+	}
+}
+
+func (a *Archiver) archiveTrace(trace *model.Trace) error {
+	var writeErrors []error
+	for _, span := range trace.Spans {
+		if err := a.writer.WriteSpan(span); err != nil {
+			writeErrors = append(writeErrors, err)
+		}
+	}
+	return multierror.Wrap(writeErrors)
+}
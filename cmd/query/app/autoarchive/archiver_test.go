@@ -0,0 +1,151 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autoarchive
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	spanstoremocks "github.com/jaegertracing/jaeger/storage/spanstore/mocks"
+)
+
+func TestLoadConfig(t *testing.T) {
+	_, err := LoadConfig("/does/not/exist")
+	assert.Contains(t, err.Error(), "failed to open automatic archiving rules file")
+
+	f, err := ioutil.TempFile("", "rules")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`{"rules": [{"name": "slow", "service": "frontend", "tags": {"http.method": "POST"}, "minDuration": "2s"}]}`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	cfg, err := LoadConfig(f.Name())
+	require.NoError(t, err)
+	assert.Equal(t, &Config{Rules: []RuleConfig{
+		{Name: "slow", Service: "frontend", Tags: map[string]string{"http.method": "POST"}, MinDuration: "2s"},
+	}}, cfg)
+
+	require.NoError(t, ioutil.WriteFile(f.Name(), []byte("rules"), 0600))
+	_, err = LoadConfig(f.Name())
+	assert.Contains(t, err.Error(), "failed to unmarshal automatic archiving rules")
+}
+
+func TestNewArchiverErrors(t *testing.T) {
+	newArchiver := func(rules ...RuleConfig) error {
+		_, err := NewArchiver(&Config{Rules: rules}, &spanstoremocks.Reader{}, &spanstoremocks.Writer{}, Options{}, zap.NewNop(), metrics.NullFactory)
+		return err
+	}
+	assert.EqualError(t, newArchiver(RuleConfig{}), `automatic archiving rule "rule-0" has no service`)
+	assert.EqualError(t, newArchiver(RuleConfig{Name: "a", Service: "a"}, RuleConfig{Name: "a", Service: "b"}),
+		`duplicate automatic archiving rule name "a"`)
+	assert.EqualError(t, newArchiver(RuleConfig{Service: "a", MinDuration: "long"}),
+		`automatic archiving rule "rule-0": invalid minDuration: time: invalid duration "long"`)
+
+	_, err := NewArchiver(&Config{}, &spanstoremocks.Reader{}, nil, Options{}, zap.NewNop(), metrics.NullFactory)
+	assert.EqualError(t, err, "the automatic archiving rules require an archive storage")
+}
+
+func testTrace(id uint64, spans int) *model.Trace {
+	trace := &model.Trace{}
+	for i := 1; i <= spans; i++ {
+		trace.Spans = append(trace.Spans, &model.Span{TraceID: model.NewTraceID(0, id), SpanID: model.NewSpanID(uint64(i))})
+	}
+	return trace
+}
+
+func TestArchive(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	reader := &spanstoremocks.Reader{}
+	writer := &spanstoremocks.Writer{}
+	metricsFactory := metricstest.NewFactory(0)
+	a, err := NewArchiver(&Config{Rules: []RuleConfig{
+		{Name: "errors", Service: "frontend", Errors: true},
+		{Name: "slow", Service: "frontend", Operation: "GET /", Tags: map[string]string{"http.method": "GET"}, MinDuration: "1s"},
+		{Name: "broken", Service: "broken"},
+	}}, reader, writer, Options{Interval: time.Minute, Delay: 2 * time.Minute, MaxTraces: 10}, zap.NewNop(), metricsFactory)
+	require.NoError(t, err)
+	a.timeNow = func() time.Time { return now }
+	a.end = now.Add(-10 * time.Minute)
+
+	reader.On("FindTraces", mock.Anything, &spanstore.TraceQueryParameters{
+		ServiceName:  "frontend",
+		Tags:         map[string]string{"error": "true"},
+		StartTimeMin: now.Add(-10 * time.Minute),
+		StartTimeMax: now.Add(-2 * time.Minute),
+		NumTraces:    10,
+	}).Return([]*model.Trace{testTrace(1, 2), testTrace(2, 1), {}}, nil).Once()
+	reader.On("FindTraces", mock.Anything, &spanstore.TraceQueryParameters{
+		ServiceName:   "frontend",
+		OperationName: "GET /",
+		Tags:          map[string]string{"http.method": "GET"},
+		DurationMin:   time.Second,
+		StartTimeMin:  now.Add(-10 * time.Minute),
+		StartTimeMax:  now.Add(-2 * time.Minute),
+		NumTraces:     10,
+	}).Return([]*model.Trace{testTrace(1, 2), testTrace(3, 1)}, nil).Once()
+	reader.On("FindTraces", mock.Anything, mock.MatchedBy(func(query *spanstore.TraceQueryParameters) bool {
+		return query.ServiceName == "broken"
+	})).Return(nil, errors.New("storage failure")).Once()
+	writer.On("WriteSpan", mock.MatchedBy(func(span *model.Span) bool { return span.TraceID.Low != 3 })).Return(nil)
+	writer.On("WriteSpan", mock.Anything).Return(errors.New("write failure"))
+
+	a.archive(context.Background())
+	writer.AssertNumberOfCalls(t, "WriteSpan", 4)
+	metricsFactory.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "traces", Tags: map[string]string{"rule": "errors"}, Value: 2},
+		metricstest.ExpectedMetric{Name: "traces", Tags: map[string]string{"rule": "slow"}, Value: 0},
+		metricstest.ExpectedMetric{Name: "errors", Tags: map[string]string{"rule": "slow"}, Value: 1},
+		metricstest.ExpectedMetric{Name: "errors", Tags: map[string]string{"rule": "broken"}, Value: 1},
+	)
+	assert.Equal(t, now.Add(-2*time.Minute), a.end)
+
+	// the period of time was already searched
+	a.archive(context.Background())
+	reader.AssertExpectations(t)
+}
+
+func TestStartClose(t *testing.T) {
+	reader := &spanstoremocks.Reader{}
+	searched := make(chan struct{}, 1)
+	reader.On("FindTraces", mock.Anything, mock.Anything).Return(nil, nil).Run(func(mock.Arguments) {
+		select {
+		case searched <- struct{}{}:
+		default:
+		}
+	})
+	a, err := NewArchiver(&Config{Rules: []RuleConfig{{Service: "frontend"}}}, reader, &spanstoremocks.Writer{},
+		Options{Interval: time.Millisecond}, zap.NewNop(), metrics.NullFactory)
+	require.NoError(t, err)
+	a.Start()
+	select {
+	case <-searched:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the traces were not searched")
+	}
+	require.NoError(t, a.Close())
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/handlers"
+	"github.com/klauspost/compress/zstd"
+)
+
+const encodingZstd = "zstd"
+
+// zstdEncoders are reused across the responses, as their buffers are large
+var zstdEncoders = sync.Pool{New: func() interface{} {
+	encoder, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+	return encoder
+}}
+
+// compressionHandler compresses the responses with zstd for the clients that accept it,
+// and otherwise with gzip or deflate. The websocket upgrades, e.g. of the live tail, are not compressed.
+func compressionHandler(h http.Handler) http.Handler {
+	compressed := handlers.CompressHandler(h)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if !acceptsEncoding(r, encodingZstd) {
+			compressed.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Content-Encoding", encodingZstd)
+		w.Header().Add("Vary", "Accept-Encoding")
+		r.Header.Del("Accept-Encoding")
+
+		encoder := zstdEncoders.Get().(*zstd.Encoder)
+		encoder.Reset(w)
+		defer func() {
+			encoder.Close()
+			zstdEncoders.Put(encoder)
+		}()
+		h.ServeHTTP(&zstdResponseWriter{ResponseWriter: w, encoder: encoder}, r)
+	})
+}
+
+// acceptsEncoding returns true if the Accept-Encoding header of the request lists the encoding without a zero weight.
+func acceptsEncoding(r *http.Request, encoding string) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		parts := strings.Split(accepted, ";")
+		if strings.TrimSpace(parts[0]) != encoding {
+			continue
+		}
+		for _, param := range parts[1:] {
+			if q := strings.TrimSpace(param); strings.HasPrefix(q, "q=") && strings.Trim(q[2:], "0.") == "" {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+type zstdResponseWriter struct {
+	http.ResponseWriter
+	encoder *zstd.Encoder
+}
+
+func (w *zstdResponseWriter) WriteHeader(code int) {
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *zstdResponseWriter) Write(b []byte) (int, error) {
+	h := w.Header()
+	if h.Get("Content-Type") == "" {
+		h.Set("Content-Type", http.DetectContentType(b))
+	}
+	h.Del("Content-Length")
+	return w.encoder.Write(b)
+}
+
+func (w *zstdResponseWriter) Flush() {
+	w.encoder.Flush()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
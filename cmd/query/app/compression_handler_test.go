@@ -0,0 +1,106 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressionHandler(t *testing.T) {
+	body := bytes.Repeat([]byte(`{"data":["frontend","redis"]}`), 100)
+	handler := compressionHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	get := func(t *testing.T, header http.Header) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		req.Header = header
+		// without the transport transparently decompressing gzip
+		resp, err := (&http.Transport{DisableCompression: true}).RoundTrip(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("zstd", func(t *testing.T) {
+		resp := get(t, http.Header{"Accept-Encoding": {"gzip, zstd"}})
+		defer resp.Body.Close()
+		assert.Equal(t, "zstd", resp.Header.Get("Content-Encoding"))
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		decoder, err := zstd.NewReader(resp.Body)
+		require.NoError(t, err)
+		defer decoder.Close()
+		decoded, err := ioutil.ReadAll(decoder)
+		require.NoError(t, err)
+		assert.Equal(t, body, decoded)
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		resp := get(t, http.Header{"Accept-Encoding": {"gzip, zstd;q=0"}})
+		defer resp.Body.Close()
+		assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+		reader, err := gzip.NewReader(resp.Body)
+		require.NoError(t, err)
+		decoded, err := ioutil.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, body, decoded)
+	})
+
+	t.Run("identity", func(t *testing.T) {
+		resp := get(t, http.Header{})
+		defer resp.Body.Close()
+		assert.Empty(t, resp.Header.Get("Content-Encoding"))
+		decoded, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, body, decoded)
+	})
+
+	t.Run("upgrade", func(t *testing.T) {
+		resp := get(t, http.Header{"Accept-Encoding": {"zstd"}, "Upgrade": {"websocket"}})
+		defer resp.Body.Close()
+		assert.Empty(t, resp.Header.Get("Content-Encoding"))
+	})
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	testCases := []struct {
+		header   string
+		expected bool
+	}{
+		{header: "", expected: false},
+		{header: "zstd", expected: true},
+		{header: "gzip, zstd;q=0.5", expected: true},
+		{header: "gzip, zstd; q=0", expected: false},
+		{header: "zstd;q=0.000", expected: false},
+		{header: "gzip, deflate", expected: false},
+	}
+	for _, tc := range testCases {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Encoding", tc.header)
+		assert.Equal(t, tc.expected, acceptsEncoding(r, "zstd"), tc.header)
+	}
+}
@@ -26,14 +26,24 @@ import (
 	"time"
 
 	"github.com/spf13/viper"
+	"github.com/uber/jaeger-lib/metrics"
 	"go.uber.org/zap"
 
-	"github.com/jaegertracing/jaeger/cmd/query/app/querysvc"
+	"github.com/jaegertracing/jaeger/cmd/query/app/audit"
+	"github.com/jaegertracing/jaeger/cmd/query/app/authorization"
+	"github.com/jaegertracing/jaeger/cmd/query/app/autoarchive"
 	"github.com/jaegertracing/jaeger/model/adjuster"
+	"github.com/jaegertracing/jaeger/pkg/auth"
 	"github.com/jaegertracing/jaeger/pkg/config"
 	"github.com/jaegertracing/jaeger/pkg/config/tlscfg"
+	"github.com/jaegertracing/jaeger/pkg/queryservice"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
+	"github.com/jaegertracing/jaeger/plugin/storage/memory"
 	"github.com/jaegertracing/jaeger/ports"
 	"github.com/jaegertracing/jaeger/storage"
+	"github.com/jaegertracing/jaeger/storage/readcache"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
 )
 
 const (
@@ -43,9 +53,40 @@ const (
 	queryBasePath           = "query.base-path"
 	queryStaticFiles        = "query.static-files"
 	queryUIConfig           = "query.ui-config"
+	queryUIConfigOverrides  = "query.ui-config-overrides"
 	queryTokenPropagation   = "query.bearer-token-propagation"
 	queryAdditionalHeaders  = "query.additional-headers"
 	queryMaxClockSkewAdjust = "query.max-clock-skew-adjustment"
+	queryGRPCReflection     = "query.grpc.reflection"
+	queryGRPCChannelz       = "query.grpc.channelz"
+	queryAuthzRulesFile     = "query.authorization.rules-file"
+	queryAuditLogOutput     = "query.audit.log-output"
+	queryViewsFile          = "query.views-file"
+	queryMaxUploadedTraces  = "query.uploaded-traces.max"
+	queryUploadedTracesTTL  = "query.uploaded-traces.ttl"
+	queryCacheServicesTTL   = "query.cache.services-ttl"
+	queryCacheOperationsTTL = "query.cache.operations-ttl"
+	queryCacheDepsTTL       = "query.cache.dependencies-ttl"
+	queryCacheTracesTTL     = "query.cache.traces-ttl"
+	queryCacheMaxTraces     = "query.cache.max-traces"
+	queryCacheTracesQuiet   = "query.cache.traces-quiet-period"
+	queryLiveTailInterval   = "query.live-tail.poll-interval"
+	queryLiveTailQuiet      = "query.live-tail.quiet-period"
+	queryLiveTailOrigins    = "query.live-tail.allowed-origins"
+	queryTraceMaxAge        = "query.http.trace-max-age"
+	queryAuthzUserHeader    = "query.authorization.user-header"
+	queryAuthzGroupsHeader  = "query.authorization.groups-header"
+	queryAutoArchiveRules   = "query.auto-archive.rules-file"
+	queryAutoArchiveEvery   = "query.auto-archive.interval"
+	queryAutoArchiveDelay   = "query.auto-archive.delay"
+	queryAutoArchiveMax     = "query.auto-archive.max-traces"
+	querySearchMaxLookback  = "query.limits.search.max-lookback"
+	querySearchMaxTraces    = "query.limits.search.max-traces"
+	querySearchTimeout      = "query.limits.search.timeout"
+	queryTraceTimeout       = "query.limits.trace.timeout"
+	queryDepsMaxLookback    = "query.limits.dependencies.max-lookback"
+	queryAdjusters          = "query.adjusters"
+	queryTenantLookback     = "query.multi-tenancy.services-lookback"
 )
 
 var tlsFlagsConfig = tlscfg.ServerFlagsConfig{
@@ -54,6 +95,11 @@ var tlsFlagsConfig = tlscfg.ServerFlagsConfig{
 	ShowClientCA: true,
 }
 
+var authFlagsConfig = auth.FlagsConfig{
+	Prefix:    "query",
+	ShowLogin: true,
+}
+
 // QueryOptions holds configuration for query service
 type QueryOptions struct {
 	// HostPort is the host:port address that the query service listens o n
@@ -64,14 +110,57 @@ type QueryOptions struct {
 	StaticAssets string
 	// UIConfig is the path to a configuration file for the UI
 	UIConfig string
+	// UIConfigOverrides is the path to the overrides of the UI configuration for some tenants, users or groups
+	UIConfigOverrides string
 	// BearerTokenPropagation activate/deactivate bearer token propagation to storage
 	BearerTokenPropagation bool
 	// TLS configures secure transport
 	TLS tlscfg.Options
+	// Auth configures the authentication of the UI and API users, with bearer tokens or an OIDC login
+	Auth auth.Options
+	// AuthorizationRulesFile is the path to the rules restricting the services each caller can read
+	AuthorizationRulesFile string
+	// AuditLogOutput is where the traces read and the searches run by the callers are logged
+	AuditLogOutput string
+	// IdentityHeaders are the headers setting the identity of the callers, when they are authenticated by a proxy
+	IdentityHeaders authorization.Headers
+	// Tenancy requires the callers to identify their tenant, like the collector does, and restricts the results to it
+	Tenancy tenancy.Options
+	// TenantServicesLookback is how far back the spans of the tenants are searched to find their services
+	TenantServicesLookback time.Duration
+	// ViewsFile is the path to the file persisting the saved searches and the pinned traces,
+	// when they are not persisted in the storage backend
+	ViewsFile string
+	// MaxUploadedTraces is the number of traces uploaded for viewing that are kept in memory
+	MaxUploadedTraces int
+	// UploadedTracesTTL is how long the traces uploaded for viewing are kept
+	UploadedTracesTTL time.Duration
+	// ReadCache configures the caching of the services, operations, dependencies and traces read from the storage
+	ReadCache readcache.Options
+	// LiveTailPollInterval is how often the live tail searches the new traces
+	LiveTailPollInterval time.Duration
+	// LiveTailQuietPeriod is how long a trace must not have received spans to be sent by the live tail
+	LiveTailQuietPeriod time.Duration
+	// LiveTailAllowedOrigins are the origins of the web pages, besides the UI, allowed to open the live tail
+	LiveTailAllowedOrigins []string
+	// TraceMaxAge is how long the complete traces returned by the HTTP API may be cached
+	TraceMaxAge time.Duration
+	// AutoArchiveRulesFile is the path to the rules of the traces automatically copied to the archive storage
+	AutoArchiveRulesFile string
+	// AutoArchive configures how often the automatic archiving rules are evaluated
+	AutoArchive autoarchive.Options
+	// Limits restrict the searches, the retrieval of the traces and the dependencies queried from the storage
+	Limits querysvc.QueryLimits
 	// AdditionalHeaders
 	AdditionalHeaders http.Header
 	// MaxClockSkewAdjust is the maximum duration by which jaeger-query will adjust a span
 	MaxClockSkewAdjust time.Duration
+	// Adjusters are the names of the adjusters applied to the traces, in order
+	Adjusters []string
+	// GRPCReflection registers the gRPC server reflection service on the query's gRPC server
+	GRPCReflection bool
+	// GRPCChannelz registers the channelz service on the query's gRPC server
+	GRPCChannelz bool
 }
 
 // AddFlags adds flags for QueryOptions
@@ -82,8 +171,40 @@ func AddFlags(flagSet *flag.FlagSet) {
 	flagSet.String(queryBasePath, "/", "The base path for all HTTP routes, e.g. /jaeger; useful when running behind a reverse proxy")
 	flagSet.String(queryStaticFiles, "", "The directory path override for the static assets for the UI")
 	flagSet.String(queryUIConfig, "", "The path to the UI configuration file in JSON format")
+	flagSet.String(queryUIConfigOverrides, "", "The path to a JSON file with the overrides of the UI configuration for some tenants, users or groups")
 	flagSet.Bool(queryTokenPropagation, false, "Allow propagation of bearer token to be used by storage plugins")
 	flagSet.Duration(queryMaxClockSkewAdjust, time.Second, "The maximum delta by which span timestamps may be adjusted in the UI due to clock skew; set to 0s to disable clock skew adjustments")
+	flagSet.String(queryAdjusters, strings.Join(querysvc.StandardAdjusterNames, ","), "Comma-separated names of the adjusters applied in order to the traces before they are returned, e.g. without "+querysvc.AdjusterClockSkew+" to disable the clock skew adjustments; additional adjusters can be registered by custom builds")
+	flagSet.Bool(queryGRPCReflection, false, "Register the gRPC server reflection service on the query's gRPC server, e.g. for grpcurl")
+	flagSet.Bool(queryGRPCChannelz, false, "Register the channelz service on the query's gRPC server to inspect its connections")
+	authFlagsConfig.AddFlags(flagSet)
+	flagSet.String(queryAuthzRulesFile, "", "Path to a JSON file with the rules mapping the users and groups to the services they can read (if not set, all the services can be read)")
+	flagSet.String(queryAuditLogOutput, "", "Where to log, as JSON lines, the trace IDs read and the searches run by the callers with their identity and tenant: a file path, stdout or stderr (if not set, the accesses are not logged)")
+	flagSet.String(queryAuthzUserHeader, "", "The HTTP header or gRPC metadata with the user name set by a trusted authenticating proxy, used when the request is not authenticated by the query service")
+	flagSet.String(queryViewsFile, "", "Path to a JSON file persisting the saved searches and the pinned traces, used instead of the storage backend (they are disabled if neither is available)")
+	flagSet.Int(queryMaxUploadedTraces, 100, "The maximum number of traces uploaded for viewing kept in memory, the oldest ones are evicted first; set to 0 to disable the uploads")
+	flagSet.Duration(queryUploadedTracesTTL, time.Hour, "How long the traces uploaded for viewing are kept in memory")
+	flagSet.Duration(queryTenantLookback, 48*time.Hour, "How far back the spans of the tenants are searched in the storage shared by the tenants, to return them only their services, operations, dependencies and span metrics")
+	flagSet.Duration(queryCacheServicesTTL, 0, "How long the services read from the storage are cached; set to 0 to disable the caching")
+	flagSet.Duration(queryCacheOperationsTTL, 0, "How long the operations read from the storage are cached; set to 0 to disable the caching")
+	flagSet.Duration(queryCacheDepsTTL, 0, "How long the service dependencies read from the storage are cached; set to 0 to disable the caching")
+	flagSet.Duration(queryCacheTracesTTL, 0, "How long the traces read from the storage are cached; set to 0 to disable the caching")
+	flagSet.Int(queryCacheMaxTraces, 1000, "The maximum number of traces cached, the least recently read ones are evicted first")
+	flagSet.Duration(queryCacheTracesQuiet, readcache.DefaultTracesQuietPeriod, "How long after the end of their last span the traces are cached, the recent ones still receiving spans")
+	flagSet.Duration(queryLiveTailInterval, queryservice.DefaultLiveTailPollInterval, "How often the live tail searches the new traces matching its query")
+	flagSet.Duration(queryLiveTailQuiet, queryservice.DefaultLiveTailQuietPeriod, "How long a trace must not have received spans to be considered complete and sent by the live tail")
+	flagSet.String(queryLiveTailOrigins, "", "Comma-separated origins, e.g. https://dashboard.example.com, of the web pages allowed to open the live tail besides the ones served by the query service")
+	flagSet.Duration(queryTraceMaxAge, 0, "How long the browsers and the caching proxies may cache the complete traces returned by the HTTP API, e.g. for the shared trace links; set to 0 to only let them revalidate the traces. Only the browsers cache the traces when the callers are authenticated, authorized or identify their tenant")
+	flagSet.String(queryAuthzGroupsHeader, "", "The HTTP header or gRPC metadata with the comma-separated groups of the user, set by a trusted authenticating proxy")
+	flagSet.String(queryAutoArchiveRules, "", "Path to a JSON file with the rules of the traces automatically copied to the archive storage, e.g. the traces in error or slower than a threshold (if not set, the traces are only archived from the UI)")
+	flagSet.Duration(queryAutoArchiveEvery, time.Minute, "How often the new traces matching the automatic archiving rules are searched")
+	flagSet.Duration(queryAutoArchiveDelay, time.Minute, "How long after they started the traces are searched by the automatic archiving rules, to let them complete")
+	flagSet.Int(queryAutoArchiveMax, 100, "The maximum number of traces archived by each automatic archiving rule at every interval")
+	flagSet.Duration(querySearchMaxLookback, 0, "The longest period of time a trace search can cover; set to 0 for no limit")
+	flagSet.Int(querySearchMaxTraces, 0, "The maximum number of traces a search can return, also returned when the search does not set a limit; set to 0 for no limit")
+	flagSet.Duration(querySearchTimeout, 0, "How long a trace search can run before it is cancelled; set to 0 for no timeout")
+	flagSet.Duration(queryTraceTimeout, 0, "How long the retrieval of a trace can run before it is cancelled; set to 0 for no timeout")
+	flagSet.Duration(queryDepsMaxLookback, 0, "The longest period of time the service dependencies can be queried for; set to 0 for no limit")
 }
 
 // InitFromViper initializes QueryOptions with properties from viper
@@ -92,9 +213,60 @@ func (qOpts *QueryOptions) InitFromViper(v *viper.Viper, logger *zap.Logger) *Qu
 	qOpts.BasePath = v.GetString(queryBasePath)
 	qOpts.StaticAssets = v.GetString(queryStaticFiles)
 	qOpts.UIConfig = v.GetString(queryUIConfig)
+	qOpts.UIConfigOverrides = v.GetString(queryUIConfigOverrides)
 	qOpts.BearerTokenPropagation = v.GetBool(queryTokenPropagation)
 	qOpts.TLS = tlsFlagsConfig.InitFromViper(v)
+	qOpts.Auth = authFlagsConfig.InitFromViper(v)
+	qOpts.AuthorizationRulesFile = v.GetString(queryAuthzRulesFile)
+	qOpts.AuditLogOutput = v.GetString(queryAuditLogOutput)
+	qOpts.IdentityHeaders = authorization.Headers{
+		User:   v.GetString(queryAuthzUserHeader),
+		Groups: v.GetString(queryAuthzGroupsHeader),
+	}
+	qOpts.Tenancy = tenancy.InitFromViper(v)
+	qOpts.TenantServicesLookback = v.GetDuration(queryTenantLookback)
+	qOpts.ViewsFile = v.GetString(queryViewsFile)
+	qOpts.MaxUploadedTraces = v.GetInt(queryMaxUploadedTraces)
+	qOpts.UploadedTracesTTL = v.GetDuration(queryUploadedTracesTTL)
+	qOpts.ReadCache = readcache.Options{
+		ServicesTTL:     v.GetDuration(queryCacheServicesTTL),
+		OperationsTTL:   v.GetDuration(queryCacheOperationsTTL),
+		DependenciesTTL: v.GetDuration(queryCacheDepsTTL),
+		TracesTTL:       v.GetDuration(queryCacheTracesTTL),
+		MaxTraces:       v.GetInt(queryCacheMaxTraces),
+
+		TracesQuietPeriod: v.GetDuration(queryCacheTracesQuiet),
+	}
+	qOpts.LiveTailPollInterval = v.GetDuration(queryLiveTailInterval)
+	qOpts.LiveTailQuietPeriod = v.GetDuration(queryLiveTailQuiet)
+	if origins := v.GetString(queryLiveTailOrigins); origins != "" {
+		qOpts.LiveTailAllowedOrigins = strings.Split(origins, ",")
+	}
+	qOpts.TraceMaxAge = v.GetDuration(queryTraceMaxAge)
+	qOpts.AutoArchiveRulesFile = v.GetString(queryAutoArchiveRules)
+	qOpts.AutoArchive = autoarchive.Options{
+		Interval:  v.GetDuration(queryAutoArchiveEvery),
+		Delay:     v.GetDuration(queryAutoArchiveDelay),
+		MaxTraces: v.GetInt(queryAutoArchiveMax),
+	}
+	qOpts.Limits = querysvc.QueryLimits{
+		FindTraces: querysvc.Limits{
+			MaxLookback: v.GetDuration(querySearchMaxLookback),
+			MaxResults:  v.GetInt(querySearchMaxTraces),
+			Timeout:     v.GetDuration(querySearchTimeout),
+		},
+		GetTrace:        querysvc.Limits{Timeout: v.GetDuration(queryTraceTimeout)},
+		GetDependencies: querysvc.Limits{MaxLookback: v.GetDuration(queryDepsMaxLookback)},
+	}
 	qOpts.MaxClockSkewAdjust = v.GetDuration(queryMaxClockSkewAdjust)
+	qOpts.Adjusters = nil
+	for _, name := range strings.Split(v.GetString(queryAdjusters), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			qOpts.Adjusters = append(qOpts.Adjusters, name)
+		}
+	}
+	qOpts.GRPCReflection = v.GetBool(queryGRPCReflection)
+	qOpts.GRPCChannelz = v.GetBool(queryGRPCChannelz)
 
 	stringSlice := v.GetStringSlice(queryAdditionalHeaders)
 	headers, err := stringSliceAsHeader(stringSlice)
@@ -106,18 +278,88 @@ func (qOpts *QueryOptions) InitFromViper(v *viper.Viper, logger *zap.Logger) *Qu
 	return qOpts
 }
 
-// BuildQueryServiceOptions creates a QueryServiceOptions struct with appropriate adjusters and archive config
+// BuildQueryServiceOptions creates a QueryServiceOptions struct with appropriate adjusters, archive and view config
 func (qOpts *QueryOptions) BuildQueryServiceOptions(storageFactory storage.Factory, logger *zap.Logger) *querysvc.QueryServiceOptions {
 	opts := &querysvc.QueryServiceOptions{}
 	if !opts.InitArchiveStorage(storageFactory, logger) {
 		logger.Info("Archive storage not initialized")
 	}
+	if qOpts.ViewsFile != "" {
+		viewStore, err := memory.NewFileViewStore(qOpts.ViewsFile)
+		if err != nil {
+			logger.Error("Cannot load the views file", zap.String("file", qOpts.ViewsFile), zap.Error(err))
+		} else {
+			opts.ViewStore = viewStore
+		}
+	} else if !opts.InitViewStorage(storageFactory, logger) {
+		logger.Info("View storage not initialized")
+	}
+	// the readers dedicated to the tenants can only be created for a known list of tenants
+	if qOpts.Tenancy.Enabled && len(qOpts.Tenancy.Tenants) > 0 && !opts.InitTenantStorage(storageFactory, qOpts.Tenancy.Tenants, logger) {
+		logger.Info("Tenant storage not initialized")
+	}
 
-	opts.Adjuster = adjuster.Sequence(querysvc.StandardAdjusters(qOpts.MaxClockSkewAdjust)...)
+	opts.MaxUploadedTraces = qOpts.MaxUploadedTraces
+	opts.UploadedTracesTTL = qOpts.UploadedTracesTTL
+	opts.TenantServicesLookback = qOpts.TenantServicesLookback
+	opts.Limits = qOpts.Limits
+	adjusters, err := querysvc.NamedAdjusters(qOpts.Adjusters, querysvc.AdjusterParams{MaxClockSkewAdjust: qOpts.MaxClockSkewAdjust})
+	if err != nil {
+		logger.Error("Invalid adjusters, using the standard ones", zap.Error(err))
+		adjusters = querysvc.StandardAdjusters(qOpts.MaxClockSkewAdjust)
+	}
+	opts.Adjuster = adjuster.Sequence(adjusters...)
 
 	return opts
 }
 
+// BuildAuthorizer loads the authorization rules, and returns nil when there are none.
+func (qOpts *QueryOptions) BuildAuthorizer() (querysvc.Authorizer, error) {
+	if qOpts.AuthorizationRulesFile == "" {
+		return nil, nil
+	}
+	cfg, err := authorization.LoadConfig(qOpts.AuthorizationRulesFile)
+	if err != nil {
+		return nil, err
+	}
+	authorizer, err := authorization.NewAuthorizer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return authorizer, nil
+}
+
+// BuildAuditor opens the audit log, and returns nil when the accesses to the traces are not audited.
+func (qOpts *QueryOptions) BuildAuditor() (querysvc.Auditor, error) {
+	if qOpts.AuditLogOutput == "" {
+		return nil, nil
+	}
+	auditor, err := audit.NewLogger(qOpts.AuditLogOutput)
+	if err != nil {
+		return nil, err
+	}
+	return auditor, nil
+}
+
+// BuildAutoArchiver loads the automatic archiving rules, and returns nil when there are none.
+// The traces are searched with the span reader and written with the archive span writer of the options.
+func (qOpts *QueryOptions) BuildAutoArchiver(
+	spanReader spanstore.Reader,
+	queryServiceOptions *querysvc.QueryServiceOptions,
+	logger *zap.Logger,
+	metricsFactory metrics.Factory,
+) (*autoarchive.Archiver, error) {
+	if qOpts.AutoArchiveRulesFile == "" {
+		return nil, nil
+	}
+	cfg, err := autoarchive.LoadConfig(qOpts.AutoArchiveRulesFile)
+	if err != nil {
+		return nil, err
+	}
+	return autoarchive.NewArchiver(cfg, spanReader, queryServiceOptions.ArchiveSpanWriter, qOpts.AutoArchive, logger,
+		metricsFactory.Namespace(metrics.NSOptions{Name: "auto_archive"}))
+}
+
 // stringSliceAsHeader parses a slice of strings and returns a http.Header.
 //  Each string in the slice is expected to be in the format "key: value"
 func stringSliceAsHeader(slice []string) (http.Header, error) {
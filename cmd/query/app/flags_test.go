@@ -16,15 +16,26 @@
 package app
 
 import (
+	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
 	"go.uber.org/zap"
 
+	"github.com/jaegertracing/jaeger/cmd/query/app/authorization"
+	"github.com/jaegertracing/jaeger/cmd/query/app/autoarchive"
 	"github.com/jaegertracing/jaeger/pkg/config"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+	"github.com/jaegertracing/jaeger/pkg/testutils"
+	"github.com/jaegertracing/jaeger/plugin/storage/memory"
 	"github.com/jaegertracing/jaeger/storage/mocks"
+	"github.com/jaegertracing/jaeger/storage/readcache"
 	spanstore_mocks "github.com/jaegertracing/jaeger/storage/spanstore/mocks"
 )
 
@@ -47,9 +58,36 @@ func TestQueryBuilderFlags(t *testing.T) {
 		"--query.additional-headers=access-control-allow-origin:blerg",
 		"--query.additional-headers=whatever:thing",
 		"--query.max-clock-skew-adjustment=10s",
+		"--query.grpc.reflection=true",
+		"--query.grpc.channelz=true",
+		"--query.auth.oidc-issuer-url=https://issuer",
+		"--query.auth.oidc-client-id=jaeger",
+		"--query.uploaded-traces.max=10",
+		"--query.uploaded-traces.ttl=5m",
+		"--query.cache.services-ttl=1m",
+		"--query.cache.operations-ttl=2m",
+		"--query.cache.dependencies-ttl=3m",
+		"--query.cache.traces-ttl=4m",
+		"--query.cache.max-traces=50",
+		"--query.cache.traces-quiet-period=6m",
+		"--query.live-tail.poll-interval=2s",
+		"--query.live-tail.quiet-period=30s",
+		"--query.live-tail.allowed-origins=https://a.example.com,http://b.example.com:8080",
+		"--query.http.trace-max-age=24h",
+		"--query.auto-archive.rules-file=rules.json",
+		"--query.auto-archive.interval=2m",
+		"--query.auto-archive.delay=3m",
+		"--query.auto-archive.max-traces=20",
+		"--query.limits.search.max-lookback=24h",
+		"--query.limits.search.max-traces=500",
+		"--query.limits.search.timeout=10s",
+		"--query.limits.trace.timeout=5s",
+		"--query.limits.dependencies.max-lookback=168h",
+		"--query.adjusters=span-references, span-id-deduper",
 	})
 	qOpts := new(QueryOptions).InitFromViper(v, zap.NewNop())
 	assert.Equal(t, "/dev/null", qOpts.StaticAssets)
+	assert.True(t, qOpts.Auth.LoginEnabled())
 	assert.Equal(t, "some.json", qOpts.UIConfig)
 	assert.Equal(t, "/jaeger", qOpts.BasePath)
 	assert.Equal(t, "127.0.0.1:8080", qOpts.HostPort)
@@ -58,6 +96,31 @@ func TestQueryBuilderFlags(t *testing.T) {
 		"Whatever":                    []string{"thing"},
 	}, qOpts.AdditionalHeaders)
 	assert.Equal(t, 10*time.Second, qOpts.MaxClockSkewAdjust)
+	assert.True(t, qOpts.GRPCReflection)
+	assert.True(t, qOpts.GRPCChannelz)
+	assert.Equal(t, 10, qOpts.MaxUploadedTraces)
+	assert.Equal(t, 5*time.Minute, qOpts.UploadedTracesTTL)
+	assert.Equal(t, readcache.Options{
+		ServicesTTL:     time.Minute,
+		OperationsTTL:   2 * time.Minute,
+		DependenciesTTL: 3 * time.Minute,
+		TracesTTL:       4 * time.Minute,
+		MaxTraces:       50,
+
+		TracesQuietPeriod: 6 * time.Minute,
+	}, qOpts.ReadCache)
+	assert.Equal(t, 2*time.Second, qOpts.LiveTailPollInterval)
+	assert.Equal(t, 30*time.Second, qOpts.LiveTailQuietPeriod)
+	assert.Equal(t, []string{"https://a.example.com", "http://b.example.com:8080"}, qOpts.LiveTailAllowedOrigins)
+	assert.Equal(t, 24*time.Hour, qOpts.TraceMaxAge)
+	assert.Equal(t, "rules.json", qOpts.AutoArchiveRulesFile)
+	assert.Equal(t, autoarchive.Options{Interval: 2 * time.Minute, Delay: 3 * time.Minute, MaxTraces: 20}, qOpts.AutoArchive)
+	assert.Equal(t, querysvc.QueryLimits{
+		FindTraces:      querysvc.Limits{MaxLookback: 24 * time.Hour, MaxResults: 500, Timeout: 10 * time.Second},
+		GetTrace:        querysvc.Limits{Timeout: 5 * time.Second},
+		GetDependencies: querysvc.Limits{MaxLookback: 168 * time.Hour},
+	}, qOpts.Limits)
+	assert.Equal(t, []string{querysvc.AdjusterSpanReferences, querysvc.AdjusterSpanIDDeduper}, qOpts.Adjusters)
 }
 
 func TestQueryBuilderBadHeadersFlags(t *testing.T) {
@@ -106,6 +169,8 @@ func TestBuildQueryServiceOptions(t *testing.T) {
 	assert.NotNil(t, qSvcOpts.Adjuster)
 	assert.Nil(t, qSvcOpts.ArchiveSpanReader)
 	assert.Nil(t, qSvcOpts.ArchiveSpanWriter)
+	assert.Equal(t, 100, qSvcOpts.MaxUploadedTraces)
+	assert.Equal(t, time.Hour, qSvcOpts.UploadedTracesTTL)
 
 	comboFactory := struct {
 		*mocks.Factory
@@ -124,3 +189,112 @@ func TestBuildQueryServiceOptions(t *testing.T) {
 	assert.NotNil(t, qSvcOpts.ArchiveSpanReader)
 	assert.NotNil(t, qSvcOpts.ArchiveSpanWriter)
 }
+
+func TestBuildAuthorizer(t *testing.T) {
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{
+		"--query.authorization.user-header=X-Forwarded-User",
+		"--query.authorization.groups-header=X-Forwarded-Groups",
+	})
+	qOpts := new(QueryOptions).InitFromViper(v, zap.NewNop())
+	assert.Equal(t, authorization.Headers{User: "X-Forwarded-User", Groups: "X-Forwarded-Groups"}, qOpts.IdentityHeaders)
+	authorizer, err := qOpts.BuildAuthorizer()
+	require.NoError(t, err)
+	assert.Nil(t, authorizer)
+
+	f, err := ioutil.TempFile("", "rules")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`{"rules": [{"subjects": ["alice"], "services": ["*"]}]}`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	qOpts.AuthorizationRulesFile = f.Name()
+	authorizer, err = qOpts.BuildAuthorizer()
+	require.NoError(t, err)
+	assert.NotNil(t, authorizer)
+
+	qOpts.AuthorizationRulesFile = "/does/not/exist"
+	_, err = qOpts.BuildAuthorizer()
+	assert.Error(t, err)
+}
+
+func TestBuildAuditor(t *testing.T) {
+	v, command := config.Viperize(AddFlags)
+	qOpts := new(QueryOptions).InitFromViper(v, zap.NewNop())
+	auditor, err := qOpts.BuildAuditor()
+	require.NoError(t, err)
+	assert.Nil(t, auditor)
+
+	dir, err := ioutil.TempDir("", "audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	command.ParseFlags([]string{"--query.audit.log-output=" + filepath.Join(dir, "audit.log")})
+	qOpts = new(QueryOptions).InitFromViper(v, zap.NewNop())
+	auditor, err = qOpts.BuildAuditor()
+	require.NoError(t, err)
+	assert.NotNil(t, auditor)
+
+	qOpts.AuditLogOutput = filepath.Join(dir, "does-not-exist", "audit.log")
+	_, err = qOpts.BuildAuditor()
+	assert.Error(t, err)
+}
+
+func TestBuildAutoArchiver(t *testing.T) {
+	v, _ := config.Viperize(AddFlags)
+	qOpts := new(QueryOptions).InitFromViper(v, zap.NewNop())
+	archiver, err := qOpts.BuildAutoArchiver(&spanstore_mocks.Reader{}, &querysvc.QueryServiceOptions{}, zap.NewNop(), metrics.NullFactory)
+	require.NoError(t, err)
+	assert.Nil(t, archiver)
+
+	f, err := ioutil.TempFile("", "rules")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`{"rules": [{"service": "frontend", "errors": true}]}`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	qOpts.AutoArchiveRulesFile = f.Name()
+	archiver, err = qOpts.BuildAutoArchiver(&spanstore_mocks.Reader{}, &querysvc.QueryServiceOptions{
+		ArchiveSpanWriter: &spanstore_mocks.Writer{},
+	}, zap.NewNop(), metrics.NullFactory)
+	require.NoError(t, err)
+	assert.NotNil(t, archiver)
+
+	_, err = qOpts.BuildAutoArchiver(&spanstore_mocks.Reader{}, &querysvc.QueryServiceOptions{}, zap.NewNop(), metrics.NullFactory)
+	assert.EqualError(t, err, "the automatic archiving rules require an archive storage")
+
+	qOpts.AutoArchiveRulesFile = "/does/not/exist"
+	_, err = qOpts.BuildAutoArchiver(&spanstore_mocks.Reader{}, &querysvc.QueryServiceOptions{}, zap.NewNop(), metrics.NullFactory)
+	assert.Error(t, err)
+}
+
+func TestBuildQueryServiceOptionsAdjusters(t *testing.T) {
+	v, _ := config.Viperize(AddFlags)
+	qOpts := new(QueryOptions).InitFromViper(v, zap.NewNop())
+	assert.Equal(t, querysvc.StandardAdjusterNames, qOpts.Adjusters)
+
+	qOpts.Adjusters = []string{"unknown"}
+	logger, logs := testutils.NewLogger()
+	assert.NotNil(t, qOpts.BuildQueryServiceOptions(&mocks.Factory{}, logger).Adjuster)
+	assert.Contains(t, logs.String(), `unknown adjuster \"unknown\"`)
+}
+
+func TestBuildQueryServiceOptionsViews(t *testing.T) {
+	v, _ := config.Viperize(AddFlags)
+	qOpts := new(QueryOptions).InitFromViper(v, zap.NewNop())
+	assert.Nil(t, qOpts.BuildQueryServiceOptions(&mocks.Factory{}, zap.NewNop()).ViewStore)
+
+	memoryFactory := memory.NewFactory()
+	require.NoError(t, memoryFactory.Initialize(metrics.NullFactory, zap.NewNop()))
+	assert.NotNil(t, qOpts.BuildQueryServiceOptions(memoryFactory, zap.NewNop()).ViewStore)
+
+	dir, err := ioutil.TempDir("", "views")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	v, command := config.Viperize(AddFlags)
+	command.ParseFlags([]string{"--query.views-file=" + filepath.Join(dir, "views.json")})
+	qOpts = new(QueryOptions).InitFromViper(v, zap.NewNop())
+	assert.IsType(t, &memory.ViewStore{}, qOpts.BuildQueryServiceOptions(&mocks.Factory{}, zap.NewNop()).ViewStore)
+
+	qOpts.ViewsFile = dir
+	assert.Nil(t, qOpts.BuildQueryServiceOptions(&mocks.Factory{}, zap.NewNop()).ViewStore)
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package graphql implements the /graphql endpoint of the query service, a GraphQL API selecting
+// exactly the fields of the traces, spans, services, operations and dependencies needed by a client.
+// Only queries are supported, with variables, aliases, fragments and the @include and @skip directives;
+// the schema is not introspectable. The queries are rejected when they select more than 500 fields or
+// nest them more than 12 levels deep, the fragments being expanded. Times are microseconds since the epoch
+// and durations microseconds as in the JSON of the HTTP API, while the arguments expect RFC 3339 times
+// and durations such as "1.5s".
+//
+//     type Query {
+//       services: [String]
+//       operations(service: String!, spanKind: String): [Operation]
+//       trace(id: String!): Trace
+//       traces(service: String!, operation: String, tags: [TagFilter], start: String, end: String,
+//              minDuration: String, maxDuration: String, limit: Int): [Trace]
+//       dependencies(endTime: String, lookback: String, service: String): [Dependency]
+//     }
+//     type Trace {
+//       traceID: String
+//       spans(service: String, operation: String, minDuration: String, hasError: Boolean, tags: [TagFilter]): [Span]
+//       rootSpans: [Span]
+//       spanCount: Int
+//       services: [String]
+//       startTime: Int
+//       duration: Int
+//     }
+//     type Span {
+//       traceID: String
+//       spanID: String
+//       parentSpanID: String
+//       operationName: String
+//       serviceName: String
+//       startTime: Int
+//       duration: Int
+//       tags(keys: [String]): [KeyValue]
+//       logs: [Log]
+//       references: [Reference]
+//       process: Process
+//       warnings: [String]
+//       children(service: String, operation: String, minDuration: String, hasError: Boolean, tags: [TagFilter]): [Span]
+//     }
+//     type KeyValue { key: String, type: String, value: String }
+//     type Log { timestamp: Int, fields(keys: [String]): [KeyValue] }
+//     type Reference { refType: String, traceID: String, spanID: String }
+//     type Process { serviceName: String, tags(keys: [String]): [KeyValue] }
+//     type Operation { name: String, spanKind: String }
+//     type Dependency { parent: String, child: String, callCount: Int }
+//     input TagFilter { key: String!, value: String! }
+package graphql
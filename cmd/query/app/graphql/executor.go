@@ -0,0 +1,475 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	// maxDepth is the maximum nesting of the fields selected by a query, e.g. 3 for { trace { spans { spanID } } }.
+	maxDepth = 12
+	// maxFields is the maximum number of fields selected by a query once its fragments are expanded.
+	maxFields = 500
+)
+
+// objectType is a type of the schema whose fields are selected by the queries.
+type objectType struct {
+	name   string
+	fields map[string]*fieldDef
+}
+
+type fieldDef struct {
+	// typ is the type of the objects returned by resolve, nil for scalars and lists of scalars
+	typ  *objectType
+	args []argDef
+	// resolve returns the value of the field of source: a scalar, an object of typ, a []interface{} of them or nil
+	resolve func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error)
+}
+
+type argKind int
+
+const (
+	argString argKind = iota
+	argInt
+	argBoolean
+	argStringList
+	argObjectList
+)
+
+var argKindNames = map[argKind]string{
+	argString:     "String",
+	argInt:        "Int",
+	argBoolean:    "Boolean",
+	argStringList: "[String]",
+	argObjectList: "a list of input objects",
+}
+
+type argDef struct {
+	name     string
+	kind     argKind
+	required bool
+}
+
+// request is a GraphQL request as sent over HTTP.
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// response is the result of a request, the data being absent if the request could not be executed.
+type response struct {
+	Data   *orderedMap   `json:"data,omitempty"`
+	Errors []*queryError `json:"errors,omitempty"`
+}
+
+type queryError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// execute runs the operation of the request on the root type. The response has no data if the request is invalid.
+func execute(ctx context.Context, root *objectType, req request) *response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return &response{Errors: []*queryError{{Message: err.Error()}}}
+	}
+	op, err := selectOperation(doc, req.OperationName)
+	if err != nil {
+		return &response{Errors: []*queryError{{Message: err.Error()}}}
+	}
+	variables := make(map[string]interface{}, len(op.variables))
+	declared := make(map[string]bool, len(op.variables))
+	for _, def := range op.variables {
+		declared[def.name] = true
+		if v, ok := req.Variables[def.name]; ok {
+			variables[def.name] = v
+		} else if def.defaultValue != nil {
+			variables[def.name] = resolveValue(def.defaultValue, nil)
+		}
+	}
+	v := &validator{doc: doc, variables: declared, visiting: make(map[string]bool)}
+	v.validateSelections(root, op.selections)
+	if len(v.errors) > 0 {
+		return &response{Errors: v.errors}
+	}
+	e := &executor{doc: doc, variables: variables}
+	data := e.executeFields(ctx, root, nil, op.selections, nil)
+	return &response{Data: data, Errors: e.errors}
+}
+
+func selectOperation(doc *document, name string) (*operation, error) {
+	var op *operation
+	if name == "" {
+		if len(doc.operations) > 1 {
+			return nil, errors.New("the operation name is required when the document has several operations")
+		}
+		op = doc.operations[0]
+	} else {
+		for _, o := range doc.operations {
+			if o.name == name {
+				op = o
+			}
+		}
+		if op == nil {
+			return nil, fmt.Errorf("unknown operation %q", name)
+		}
+	}
+	if op.kind != "query" {
+		return nil, fmt.Errorf("%s operations are not supported", op.kind)
+	}
+	return op, nil
+}
+
+// validator checks the selections of an operation against the schema before it is executed.
+type validator struct {
+	doc       *document
+	variables map[string]bool
+	visiting  map[string]bool // fragments being validated, to detect cycles
+	errors    []*queryError
+
+	depth  int // nesting of the field being validated
+	fields int // fields validated so far, fragments being expanded
+	// exceeded stops the validation once a limit is exceeded, as expanding the fragments could take forever
+	exceeded bool
+}
+
+func (v *validator) fail(format string, args ...interface{}) {
+	v.errors = append(v.errors, &queryError{Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) validateSelections(typ *objectType, selections []selection) {
+	for _, s := range selections {
+		if v.exceeded {
+			return
+		}
+		switch s := s.(type) {
+		case *field:
+			v.validateDirectives(s.directives)
+			v.validateField(typ, s)
+		case *fragmentSpread:
+			v.validateDirectives(s.directives)
+			f, ok := v.doc.fragments[s.name]
+			if !ok {
+				v.fail("unknown fragment %q", s.name)
+				continue
+			}
+			if v.visiting[s.name] {
+				v.fail("fragment %q spreads itself", s.name)
+				continue
+			}
+			v.visiting[s.name] = true
+			v.validateFragment(typ, f.typeCondition, f.selections)
+			delete(v.visiting, s.name)
+		case *inlineFragment:
+			v.validateDirectives(s.directives)
+			v.validateFragment(typ, s.typeCondition, s.selections)
+		}
+	}
+}
+
+func (v *validator) validateFragment(typ *objectType, typeCondition string, selections []selection) {
+	if typeCondition != "" && typeCondition != typ.name {
+		v.fail("fragment on %s cannot be used on %s", typeCondition, typ.name)
+		return
+	}
+	v.validateSelections(typ, selections)
+}
+
+func (v *validator) validateField(typ *objectType, f *field) {
+	if v.fields++; v.fields > maxFields {
+		v.fail("the query selects more than %d fields", maxFields)
+		v.exceeded = true
+		return
+	}
+	if f.name == "__typename" {
+		if len(f.arguments) > 0 || len(f.selections) > 0 {
+			v.fail("field __typename has no arguments or sub-selections")
+		}
+		return
+	}
+	def, ok := typ.fields[f.name]
+	if !ok {
+		v.fail("unknown field %q on type %s", f.name, typ.name)
+		return
+	}
+	for _, arg := range f.arguments {
+		known := false
+		for _, a := range def.args {
+			known = known || a.name == arg.name
+		}
+		if !known {
+			v.fail("unknown argument %q of field %s.%s", arg.name, typ.name, f.name)
+		}
+		v.validateValue(arg.value)
+	}
+	switch {
+	case def.typ == nil && len(f.selections) > 0:
+		v.fail("field %s.%s is a scalar and has no sub-selections", typ.name, f.name)
+	case def.typ != nil && len(f.selections) == 0:
+		v.fail("field %s.%s of type %s requires a selection of its fields", typ.name, f.name, def.typ.name)
+	case def.typ != nil && v.depth+1 >= maxDepth:
+		v.fail("the query is nested more than %d levels deep", maxDepth)
+		v.exceeded = true
+	case def.typ != nil:
+		v.depth++
+		v.validateSelections(def.typ, f.selections)
+		v.depth--
+	}
+}
+
+func (v *validator) validateDirectives(directives []*directive) {
+	for _, d := range directives {
+		if d.name != "include" && d.name != "skip" {
+			v.fail("unknown directive @%s", d.name)
+		}
+		for _, arg := range d.arguments {
+			v.validateValue(arg.value)
+		}
+	}
+}
+
+func (v *validator) validateValue(val value) {
+	switch val := val.(type) {
+	case variable:
+		if !v.variables[string(val)] {
+			v.fail("variable $%s is not defined", string(val))
+		}
+	case []value:
+		for _, item := range val {
+			v.validateValue(item)
+		}
+	case []*argument:
+		for _, f := range val {
+			v.validateValue(f.value)
+		}
+	}
+}
+
+type executor struct {
+	doc       *document
+	variables map[string]interface{}
+	errors    []*queryError
+}
+
+func (e *executor) fail(path []interface{}, err error) {
+	e.errors = append(e.errors, &queryError{Message: err.Error(), Path: path})
+}
+
+func (e *executor) executeFields(ctx context.Context, typ *objectType, source interface{}, selections []selection, path []interface{}) *orderedMap {
+	result := &orderedMap{values: make(map[string]interface{})}
+	for _, f := range e.collectFields(typ, selections) {
+		key := f.responseKey()
+		fieldPath := appendPath(path, key)
+		if f.name == "__typename" {
+			result.set(key, typ.name)
+			continue
+		}
+		def := typ.fields[f.name]
+		args, err := e.coerceArguments(def, f.arguments)
+		if err != nil {
+			e.fail(fieldPath, err)
+			result.set(key, nil)
+			continue
+		}
+		v, err := def.resolve(ctx, source, args)
+		if err != nil {
+			e.fail(fieldPath, err)
+			result.set(key, nil)
+			continue
+		}
+		result.set(key, e.complete(ctx, def.typ, v, f.selections, fieldPath))
+	}
+	return result
+}
+
+func (e *executor) complete(ctx context.Context, typ *objectType, v interface{}, selections []selection, path []interface{}) interface{} {
+	if typ == nil || v == nil {
+		return v
+	}
+	if list, ok := v.([]interface{}); ok {
+		results := make([]interface{}, len(list))
+		for i, item := range list {
+			results[i] = e.complete(ctx, typ, item, selections, appendPath(path, i))
+		}
+		return results
+	}
+	return e.executeFields(ctx, typ, v, selections, path)
+}
+
+// collectFields returns the fields selected on typ, merging the fields with the same response key
+// and expanding the fragments.
+func (e *executor) collectFields(typ *objectType, selections []selection) []*field {
+	var fields []*field
+	byKey := make(map[string]*field)
+	var collect func(selections []selection)
+	collect = func(selections []selection) {
+		for _, s := range selections {
+			switch s := s.(type) {
+			case *field:
+				if !e.included(s.directives) {
+					continue
+				}
+				if f, ok := byKey[s.responseKey()]; ok {
+					f.selections = append(f.selections, s.selections...)
+					continue
+				}
+				f := *s
+				f.selections = append([]selection(nil), s.selections...)
+				byKey[f.responseKey()] = &f
+				fields = append(fields, &f)
+			case *fragmentSpread:
+				if f := e.doc.fragments[s.name]; e.included(s.directives) && f.typeCondition == typ.name {
+					collect(f.selections)
+				}
+			case *inlineFragment:
+				if e.included(s.directives) && (s.typeCondition == "" || s.typeCondition == typ.name) {
+					collect(s.selections)
+				}
+			}
+		}
+	}
+	collect(selections)
+	return fields
+}
+
+// included applies the @skip and @include directives.
+func (e *executor) included(directives []*directive) bool {
+	for _, d := range directives {
+		var condition bool
+		for _, arg := range d.arguments {
+			if arg.name == "if" {
+				condition, _ = resolveValue(arg.value, e.variables).(bool)
+			}
+		}
+		if (d.name == "skip" && condition) || (d.name == "include" && !condition) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *executor) coerceArguments(def *fieldDef, arguments []*argument) (map[string]interface{}, error) {
+	args := make(map[string]interface{}, len(def.args))
+	for _, arg := range arguments {
+		args[arg.name] = resolveValue(arg.value, e.variables)
+	}
+	for _, a := range def.args {
+		v := args[a.name]
+		if v == nil {
+			if a.required {
+				return nil, fmt.Errorf("argument %q is required", a.name)
+			}
+			delete(args, a.name)
+			continue
+		}
+		coerced, ok := coerce(a.kind, v)
+		if !ok {
+			return nil, fmt.Errorf("argument %q expects %s", a.name, argKindNames[a.kind])
+		}
+		args[a.name] = coerced
+	}
+	return args, nil
+}
+
+// coerce converts a value decoded from the query or from the JSON variables to the Go type of the argument kind.
+func coerce(kind argKind, v interface{}) (interface{}, bool) {
+	switch kind {
+	case argString:
+		s, ok := v.(string)
+		return s, ok
+	case argInt:
+		f, ok := v.(float64)
+		if !ok || f != math.Trunc(f) || f > math.MaxInt32 || f < math.MinInt32 {
+			return nil, false
+		}
+		return int(f), true
+	case argBoolean:
+		b, ok := v.(bool)
+		return b, ok
+	case argStringList:
+		list, ok := v.([]interface{})
+		if !ok {
+			// a single value is accepted as a list of one value
+			list = []interface{}{v}
+		}
+		strings := make([]string, len(list))
+		for i, item := range list {
+			if strings[i], ok = item.(string); !ok {
+				return nil, false
+			}
+		}
+		return strings, true
+	case argObjectList:
+		list, ok := v.([]interface{})
+		if !ok {
+			list = []interface{}{v}
+		}
+		objects := make([]map[string]interface{}, len(list))
+		for i, item := range list {
+			if objects[i], ok = item.(map[string]interface{}); !ok {
+				return nil, false
+			}
+		}
+		return objects, true
+	}
+	return nil, false
+}
+
+func appendPath(path []interface{}, elem interface{}) []interface{} {
+	p := make([]interface{}, len(path), len(path)+1)
+	copy(p, path)
+	return append(p, elem)
+}
+
+// orderedMap is a JSON object keeping the order of the fields of the query, as required by the specification.
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (m *orderedMap) set(key string, value interface{}) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// MarshalJSON implements json.Marshaler
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
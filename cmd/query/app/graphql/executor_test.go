@@ -0,0 +1,188 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testItem struct {
+	id       int
+	children []*testItem
+}
+
+func testRoot() *objectType {
+	itemType := &objectType{name: "Item"}
+	itemType.fields = map[string]*fieldDef{
+		"id": scalar(func(src interface{}) interface{} { return src.(*testItem).id }),
+		"children": {typ: itemType, resolve: func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			var children []interface{}
+			for _, child := range src.(*testItem).children {
+				children = append(children, child)
+			}
+			return children, nil
+		}},
+	}
+	return &objectType{name: "Query", fields: map[string]*fieldDef{
+		"hello": {
+			args: []argDef{{name: "name", kind: argString, required: true}, {name: "times", kind: argInt}, {name: "loud", kind: argBoolean}},
+			resolve: func(_ context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				optional := func(name string) interface{} {
+					if v, ok := args[name]; ok {
+						return v
+					}
+					return "-"
+				}
+				return fmt.Sprintf("hello %v %v %v", args["name"], optional("times"), optional("loud")), nil
+			},
+		},
+		"join": {
+			args: []argDef{{name: "words", kind: argStringList}, {name: "pairs", kind: argObjectList}},
+			resolve: func(_ context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				return fmt.Sprint(args["words"], args["pairs"]), nil
+			},
+		},
+		"item": {typ: itemType, resolve: func(_ context.Context, _ interface{}, _ map[string]interface{}) (interface{}, error) {
+			return &testItem{id: 1, children: []*testItem{{id: 2}, {id: 3, children: []*testItem{{id: 4}}}}}, nil
+		}},
+		"missing": {typ: itemType, resolve: func(_ context.Context, _ interface{}, _ map[string]interface{}) (interface{}, error) {
+			return nil, nil
+		}},
+		"fail": {resolve: func(_ context.Context, _ interface{}, _ map[string]interface{}) (interface{}, error) {
+			return nil, errors.New("storage unavailable")
+		}},
+	}}
+}
+
+func executeJSON(t *testing.T, req request) string {
+	data, err := json.Marshal(execute(context.Background(), testRoot(), req))
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      request
+		expected string
+	}{
+		{
+			name:     "aliases and arguments",
+			req:      request{Query: `{ b: hello(name: "b", times: 2, loud: true) a: hello(name: "a") }`},
+			expected: `{"data":{"b":"hello b 2 true","a":"hello a - -"}}`,
+		},
+		{
+			name:     "variables and defaults",
+			req:      request{Query: `query ($n: String, $t: Int = 3) { hello(name: $n, times: $t) }`, Variables: map[string]interface{}{"n": "v"}},
+			expected: `{"data":{"hello":"hello v 3 -"}}`,
+		},
+		{
+			name:     "nested objects and lists",
+			req:      request{Query: `{ item { id children { id children { id } } } missing { id } }`},
+			expected: `{"data":{"item":{"id":1,"children":[{"id":2,"children":[]},{"id":3,"children":[{"id":4}]}]},"missing":null}}`,
+		},
+		{
+			name:     "fragments and merged fields",
+			req:      request{Query: `{ item { ...ids children { id } ... on Item { children { __typename } } } } fragment ids on Item { id }`},
+			expected: `{"data":{"item":{"id":1,"children":[{"id":2,"__typename":"Item"},{"id":3,"__typename":"Item"}]}}}`,
+		},
+		{
+			name: "directives",
+			req: request{Query: `query ($yes: Boolean) { item { id @skip(if: $yes) children @include(if: $yes) { id } } }`,
+				Variables: map[string]interface{}{"yes": true}},
+			expected: `{"data":{"item":{"children":[{"id":2},{"id":3}]}}}`,
+		},
+		{
+			name:     "list arguments",
+			req:      request{Query: `{ join(words: "one", pairs: [{a: 1}]) }`},
+			expected: `{"data":{"join":"[one] [map[a:1]]"}}`,
+		},
+		{
+			name:     "operation name",
+			req:      request{Query: `query A { hello(name: "a") } query B { hello(name: "b") }`, OperationName: "B"},
+			expected: `{"data":{"hello":"hello b - -"}}`,
+		},
+		{
+			name:     "field errors",
+			req:      request{Query: `{ fail hello(name: null) h: hello(name: "a", times: 1.5) join(words: [1]) item { id } }`},
+			expected: `{"data":{"fail":null,"hello":null,"h":null,"join":null,"item":{"id":1}},"errors":[` +
+				`{"message":"storage unavailable","path":["fail"]},` +
+				`{"message":"argument \"name\" is required","path":["hello"]},` +
+				`{"message":"argument \"times\" expects Int","path":["h"]},` +
+				`{"message":"argument \"words\" expects [String]","path":["join"]}]}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, executeJSON(t, test.req))
+		})
+	}
+}
+
+// chainedFragments returns n fragments, each one selecting the children of the items with the next one.
+func chainedFragments(n int) string {
+	var fragments strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&fragments, " fragment f%d on Item { children { ...f%d } }", i, i+1)
+	}
+	fmt.Fprintf(&fragments, " fragment f%d on Item { id }", n)
+	return fragments.String()
+}
+
+func TestExecuteRequestErrors(t *testing.T) {
+	tests := []struct {
+		req      request
+		expected string
+	}{
+		{req: request{Query: `{`}, expected: "syntax error at line 1, column 2: unexpected end of document"},
+		{req: request{Query: `query A { item { id } } { hello(name: "a") }`}, expected: "the operation name is required when the document has several operations"},
+		{req: request{Query: `query A { item { id } }`, OperationName: "B"}, expected: `unknown operation "B"`},
+		{req: request{Query: `mutation { item { id } }`}, expected: "mutation operations are not supported"},
+		{req: request{Query: `{ unknown }`}, expected: `unknown field "unknown" on type Query`},
+		{req: request{Query: `{ item }`}, expected: "field Query.item of type Item requires a selection of its fields"},
+		{req: request{Query: `{ hello(name: "a") { id } }`}, expected: "field Query.hello is a scalar and has no sub-selections"},
+		{req: request{Query: `{ hello(nom: "a") }`}, expected: `unknown argument "nom" of field Query.hello`},
+		{req: request{Query: `{ hello(name: $name) }`}, expected: "variable $name is not defined"},
+		{req: request{Query: `{ item { ...missing } }`}, expected: `unknown fragment "missing"`},
+		{req: request{Query: `{ item { ...a } } fragment a on Item { children { ...a } }`}, expected: `fragment "a" spreads itself`},
+		{req: request{Query: `{ ...a } fragment a on Item { id }`}, expected: "fragment on Item cannot be used on Query"},
+		{req: request{Query: `{ item { id @deprecated } }`}, expected: "unknown directive @deprecated"},
+		{req: request{Query: `{ __typename(a: 1) }`}, expected: "field __typename has no arguments or sub-selections"},
+		{req: request{Query: "{ item " + strings.Repeat("{ children ", maxDepth) + "{ id }" + strings.Repeat(" }", maxDepth) + " }"},
+			expected: "the query is nested more than 12 levels deep"},
+		{req: request{Query: "{ item { ...f0 } }" + chainedFragments(maxDepth)}, expected: "the query is nested more than 12 levels deep"},
+		{req: request{Query: "{ item { " + strings.Repeat("id ", maxFields) + "} }"}, expected: "the query selects more than 500 fields"},
+		{req: request{Query: "{ item { ...a } } fragment a on Item { children { ...b ...b ...b } } fragment b on Item { children { ...c ...c ...c } } " +
+			"fragment c on Item { children { ...d ...d ...d } } fragment d on Item { children { ...e ...e ...e } } fragment e on Item { " +
+			strings.Repeat("id ", 10) + "}"},
+			expected: "the query selects more than 500 fields"},
+	}
+	for _, test := range tests {
+		t.Run(test.req.Query, func(t *testing.T) {
+			resp := execute(context.Background(), testRoot(), test.req)
+			assert.Nil(t, resp.Data)
+			require.Len(t, resp.Errors, 1)
+			assert.Equal(t, test.expected, resp.Errors[0].Message)
+		})
+	}
+}
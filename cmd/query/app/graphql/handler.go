@@ -0,0 +1,100 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+)
+
+const maxRequestSize = 1 << 20
+
+// Handler serves GraphQL requests, sent with GET or POST as described by https://graphql.org/learn/serving-over-http/.
+type Handler struct {
+	root   *objectType
+	logger *zap.Logger
+}
+
+// NewHandler creates a Handler executing the queries on the query service.
+func NewHandler(queryService *querysvc.QueryService, logger *zap.Logger) *Handler {
+	s := &schema{queryService: queryService, logger: logger, timeNow: time.Now}
+	return &Handler{root: s.queryType(), logger: logger}
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req, err := readRequest(r)
+	if err != nil {
+		h.writeResponse(w, http.StatusBadRequest, &response{Errors: []*queryError{{Message: err.Error()}}})
+		return
+	}
+	resp := execute(r.Context(), h.root, req)
+	status := http.StatusOK
+	if resp.Data == nil {
+		status = http.StatusBadRequest
+	}
+	h.writeResponse(w, status, resp)
+}
+
+func readRequest(r *http.Request) (request, error) {
+	var req request
+	switch r.Method {
+	case http.MethodGet:
+		req.Query = r.FormValue("query")
+		req.OperationName = r.FormValue("operationName")
+		if variables := r.FormValue("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				return req, fmt.Errorf("cannot parse the variables: %w", err)
+			}
+		}
+	case http.MethodPost:
+		body, err := ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, maxRequestSize))
+		if err != nil {
+			return req, fmt.Errorf("cannot read the request: %w", err)
+		}
+		contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if contentType == "application/graphql" {
+			req.Query = string(body)
+		} else if err := json.Unmarshal(body, &req); err != nil {
+			return req, fmt.Errorf("cannot parse the request: %w", err)
+		}
+	default:
+		return req, fmt.Errorf("unsupported method %s, expecting GET or POST", r.Method)
+	}
+	if req.Query == "" {
+		return req, fmt.Errorf("the request has no query")
+	}
+	return req, nil
+}
+
+func (h *Handler) writeResponse(w http.ResponseWriter, status int, resp *response) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		h.logger.Error("Cannot marshal GraphQL response", zap.Error(err))
+		http.Error(w, "cannot marshal the response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
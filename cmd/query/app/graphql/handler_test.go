@@ -0,0 +1,307 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/model/adjuster"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+	depsmocks "github.com/jaegertracing/jaeger/storage/dependencystore/mocks"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	spanstoremocks "github.com/jaegertracing/jaeger/storage/spanstore/mocks"
+)
+
+var (
+	testTraceID   = model.NewTraceID(0, 0x10)
+	testStartTime = time.Unix(1600000000, 0).UTC()
+	testTrace     = &model.Trace{Spans: []*model.Span{
+		{
+			TraceID:       testTraceID,
+			SpanID:        model.NewSpanID(1),
+			OperationName: "GET /",
+			StartTime:     testStartTime,
+			Duration:      100 * time.Millisecond,
+			Tags:          model.KeyValues{model.String("http.method", "GET"), model.Int64("http.status_code", 500)},
+			Process:       model.NewProcess("frontend", []model.KeyValue{model.String("hostname", "web-1")}),
+		},
+		{
+			TraceID:       testTraceID,
+			SpanID:        model.NewSpanID(2),
+			OperationName: "GET",
+			References:    []model.SpanRef{model.NewChildOfRef(testTraceID, model.NewSpanID(1))},
+			StartTime:     testStartTime.Add(10 * time.Millisecond),
+			Duration:      20 * time.Millisecond,
+			Tags:          model.KeyValues{model.Bool("error", true)},
+			Logs:          []model.Log{{Timestamp: testStartTime.Add(15 * time.Millisecond), Fields: model.KeyValues{model.String("event", "timeout")}}},
+			Process:       model.NewProcess("redis", nil),
+		},
+	}}
+)
+
+type testServer struct {
+	server           *httptest.Server
+	spanReader       *spanstoremocks.Reader
+	dependencyReader *depsmocks.Reader
+}
+
+func initializeTestServer(adjust adjuster.Func) *testServer {
+	spanReader := &spanstoremocks.Reader{}
+	dependencyReader := &depsmocks.Reader{}
+	qs := querysvc.NewQueryService(spanReader, dependencyReader, querysvc.QueryServiceOptions{Adjuster: adjust})
+	return &testServer{
+		server:           httptest.NewServer(NewHandler(qs, zap.NewNop())),
+		spanReader:       spanReader,
+		dependencyReader: dependencyReader,
+	}
+}
+
+func noAdjustment(trace *model.Trace) (*model.Trace, error) {
+	return trace, nil
+}
+
+func postQuery(t *testing.T, server *httptest.Server, query string, variables map[string]interface{}) (int, string) {
+	body, err := json.Marshal(request{Query: query, Variables: variables})
+	require.NoError(t, err)
+	resp, err := http.Post(server.URL, "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(data)
+}
+
+func TestTraceQuery(t *testing.T) {
+	ts := initializeTestServer(noAdjustment)
+	defer ts.server.Close()
+	ts.spanReader.On("GetTrace", mock.Anything, testTraceID).Return(testTrace, nil)
+	ts.spanReader.On("GetTrace", mock.Anything, model.NewTraceID(0, 1)).Return(nil, spanstore.ErrTraceNotFound)
+
+	status, body := postQuery(t, ts.server, `query ($id: String!) {
+		trace(id: $id) {
+			traceID spanCount services startTime duration
+			rootSpans {
+				operationName serviceName parentSpanID
+				tags(keys: "http.status_code") { key type value }
+				process { serviceName tags { key value } }
+				children(hasError: true) {
+					spanID parentSpanID duration
+					logs { timestamp fields { key value } }
+					references { refType traceID spanID }
+				}
+			}
+			slow: spans(minDuration: "50ms") { operationName }
+			errors: spans(tags: [{key: "error", value: "true"}], service: "redis", operation: "GET") { spanID }
+		}
+		missing: trace(id: "1") { traceID }
+	}`, map[string]interface{}{"id": testTraceID.String()})
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"data": {
+		"trace": {
+			"traceID": "0000000000000010", "spanCount": 2, "services": ["frontend", "redis"],
+			"startTime": 1600000000000000, "duration": 100000,
+			"rootSpans": [{
+				"operationName": "GET /", "serviceName": "frontend", "parentSpanID": null,
+				"tags": [{"key": "http.status_code", "type": "int64", "value": "500"}],
+				"process": {"serviceName": "frontend", "tags": [{"key": "hostname", "value": "web-1"}]},
+				"children": [{
+					"spanID": "0000000000000002", "parentSpanID": "0000000000000001", "duration": 20000,
+					"logs": [{"timestamp": 1600000000015000, "fields": [{"key": "event", "value": "timeout"}]}],
+					"references": [{"refType": "CHILD_OF", "traceID": "0000000000000010", "spanID": "0000000000000001"}]
+				}]
+			}],
+			"slow": [{"operationName": "GET /"}],
+			"errors": [{"spanID": "0000000000000002"}]
+		},
+		"missing": null
+	}}`, body)
+}
+
+func TestTracesQuery(t *testing.T) {
+	ts := initializeTestServer(func(trace *model.Trace) (*model.Trace, error) {
+		return trace, errors.New("clock skew adjustment failed")
+	})
+	defer ts.server.Close()
+	end := time.Date(2020, 9, 13, 12, 0, 0, 0, time.UTC)
+	ts.spanReader.On("FindTraces", mock.Anything, &spanstore.TraceQueryParameters{
+		ServiceName:   "frontend",
+		OperationName: "GET /",
+		Tags:          map[string]string{"http.method": "GET"},
+		StartTimeMin:  end.Add(-time.Hour),
+		StartTimeMax:  end,
+		DurationMin:   10 * time.Millisecond,
+		DurationMax:   time.Second,
+		NumTraces:     5,
+	}).Return([]*model.Trace{testTrace}, nil)
+
+	status, body := postQuery(t, ts.server, `{
+		traces(service: "frontend", operation: "GET /", tags: {key: "http.method", value: "GET"},
+		       start: "2020-09-13T11:00:00Z", end: "2020-09-13T12:00:00Z", minDuration: "10ms", maxDuration: "1s", limit: 5) {
+			traceID
+		}
+	}`, nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"data": {"traces": [{"traceID": "0000000000000010"}]}}`, body)
+}
+
+func TestServicesOperationsAndDependencies(t *testing.T) {
+	ts := initializeTestServer(noAdjustment)
+	defer ts.server.Close()
+	ts.spanReader.On("GetServices", mock.Anything).Return([]string{"frontend", "redis"}, nil)
+	ts.spanReader.On("GetOperations", mock.Anything, spanstore.OperationQueryParameters{ServiceName: "frontend", SpanKind: "server"}).
+		Return([]spanstore.Operation{{Name: "GET /", SpanKind: "server"}}, nil)
+	end := time.Date(2020, 9, 13, 12, 0, 0, 0, time.UTC)
+	ts.dependencyReader.On("GetDependencies", end, time.Hour).Return([]model.DependencyLink{
+		{Parent: "frontend", Child: "redis", CallCount: 2},
+		{Parent: "frontend", Child: "redis", CallCount: 3},
+		{Parent: "frontend", Child: "mysql", CallCount: 1},
+		{Parent: "batch", Child: "mysql", CallCount: 1},
+	}, nil)
+
+	status, body := postQuery(t, ts.server, `{
+		services
+		operations(service: "frontend", spanKind: "server") { name spanKind }
+		dependencies(endTime: "2020-09-13T12:00:00Z", lookback: "1h", service: "frontend") { parent child callCount }
+	}`, nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"data": {
+		"services": ["frontend", "redis"],
+		"operations": [{"name": "GET /", "spanKind": "server"}],
+		"dependencies": [
+			{"parent": "frontend", "child": "mysql", "callCount": 1},
+			{"parent": "frontend", "child": "redis", "callCount": 5}
+		]
+	}}`, body)
+}
+
+func TestResolverErrors(t *testing.T) {
+	ts := initializeTestServer(noAdjustment)
+	defer ts.server.Close()
+	ts.spanReader.On("GetServices", mock.Anything).Return(nil, errors.New("storage unavailable"))
+	ts.spanReader.On("GetTrace", mock.Anything, testTraceID).Return(testTrace, nil)
+
+	tests := []struct {
+		query   string
+		message string
+	}{
+		{query: `{ services }`, message: "storage unavailable"},
+		{query: `{ trace(id: "xyz") { traceID } }`, message: "cannot parse trace id: strconv.ParseUint: parsing \"xyz\": invalid syntax"},
+		{query: `{ traces(service: "a", limit: 0) { traceID } }`, message: "limit must be positive, got 0"},
+		{query: `{ traces(service: "a", end: "yesterday") { traceID } }`, message: `cannot parse end as an RFC 3339 time: parsing time "yesterday" as "2006-01-02T15:04:05.999999999Z07:00": cannot parse "yesterday" as "2006"`},
+		{query: `{ traces(service: "a", minDuration: "long") { traceID } }`, message: `cannot parse minDuration: time: invalid duration "long"`},
+		{query: `{ traces(service: "a", tags: [{value: "b"}]) { traceID } }`, message: "tags expects objects with a key and a value"},
+		{query: `{ traces(service: "a", tags: [{key: "b"}]) { traceID } }`, message: `tag "b" has no value`},
+		{query: `{ dependencies(lookback: "day") { parent } }`, message: `cannot parse lookback: time: invalid duration "day"`},
+		{query: `{ trace(id: "10") { spans(minDuration: "-") { spanID } } }`, message: `cannot parse minDuration: time: invalid duration "-"`},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			status, body := postQuery(t, ts.server, test.query, nil)
+			assert.Equal(t, http.StatusOK, status)
+			var resp struct {
+				Errors []queryError `json:"errors"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(body), &resp))
+			require.Len(t, resp.Errors, 1, body)
+			assert.Equal(t, test.message, resp.Errors[0].Message)
+		})
+	}
+}
+
+func TestHandlerRequests(t *testing.T) {
+	ts := initializeTestServer(noAdjustment)
+	defer ts.server.Close()
+	ts.spanReader.On("GetServices", mock.Anything).Return([]string{"frontend"}, nil)
+
+	query := url.Values{"query": {"query ($a: Boolean) { services @include(if: $a) }"}, "variables": {`{"a": true}`}}
+	resp, err := http.Get(ts.server.URL + "?" + query.Encode())
+	require.NoError(t, err)
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.JSONEq(t, `{"data": {"services": ["frontend"]}}`, string(body))
+
+	resp, err = http.Post(ts.server.URL, "application/graphql", bytes.NewBufferString("{ services }"))
+	require.NoError(t, err)
+	body, _ = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.JSONEq(t, `{"data": {"services": ["frontend"]}}`, string(body))
+
+	tests := []struct {
+		name    string
+		request func() (*http.Response, error)
+		message string
+	}{
+		{
+			name:    "invalid variables",
+			request: func() (*http.Response, error) { return http.Get(ts.server.URL + "?query=%7Bservices%7D&variables=%7B") },
+			message: "cannot parse the variables: unexpected end of JSON input",
+		},
+		{
+			name:    "invalid body",
+			request: func() (*http.Response, error) { return http.Post(ts.server.URL, "application/json", bytes.NewBufferString("{")) },
+			message: "cannot parse the request: unexpected end of JSON input",
+		},
+		{
+			name:    "no query",
+			request: func() (*http.Response, error) { return http.Get(ts.server.URL) },
+			message: "the request has no query",
+		},
+		{
+			name: "unsupported method",
+			request: func() (*http.Response, error) {
+				req, _ := http.NewRequest(http.MethodPut, ts.server.URL, nil)
+				return http.DefaultClient.Do(req)
+			},
+			message: "unsupported method PUT, expecting GET or POST",
+		},
+		{
+			name:    "invalid query",
+			request: func() (*http.Response, error) { return http.Get(ts.server.URL + "?query=%7Bunknown%7D") },
+			message: `unknown field "unknown" on type Query`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := test.request()
+			require.NoError(t, err)
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			assert.JSONEq(t, `{"errors": [{"message": `+string(mustMarshal(t, test.message))+`}]}`, string(body))
+		})
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return data
+}
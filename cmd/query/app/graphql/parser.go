@@ -0,0 +1,524 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// document is a parsed GraphQL request document.
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	kind       string // query, mutation or subscription
+	name       string
+	variables  []*variableDefinition
+	selections []selection
+}
+
+type variableDefinition struct {
+	name         string
+	defaultValue value
+}
+
+type fragment struct {
+	typeCondition string
+	selections    []selection
+}
+
+// selection is a *field, a *fragmentSpread or an *inlineFragment.
+type selection interface{}
+
+type field struct {
+	alias      string
+	name       string
+	arguments  []*argument
+	directives []*directive
+	selections []selection
+}
+
+// responseKey is the name of the field in the response.
+func (f *field) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+type fragmentSpread struct {
+	name       string
+	directives []*directive
+}
+
+type inlineFragment struct {
+	typeCondition string
+	directives    []*directive
+	selections    []selection
+}
+
+type directive struct {
+	name      string
+	arguments []*argument
+}
+
+type argument struct {
+	name  string
+	value value
+}
+
+// value is a literal of the document: nil, bool, float64, string, enumValue, variable, []value or []*argument for objects.
+type value interface{}
+
+type enumValue string
+
+type variable string
+
+// resolveValue replaces the variables of v with their values, returning values as decoded from JSON.
+func resolveValue(v value, variables map[string]interface{}) interface{} {
+	switch v := v.(type) {
+	case variable:
+		return variables[string(v)]
+	case enumValue:
+		return string(v)
+	case []value:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = resolveValue(item, variables)
+		}
+		return list
+	case []*argument:
+		object := make(map[string]interface{}, len(v))
+		for _, f := range v {
+			object[f.name] = resolveValue(f.value, variables)
+		}
+		return object
+	default:
+		return v
+	}
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunctuator
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind   tokenKind
+	value  string
+	line   int
+	column int
+}
+
+type parser struct {
+	source string
+	pos    int
+	line   int
+	lineAt int // position of the start of the line
+	tok    token
+
+	// nesting counts the selection sets, lists and objects being parsed, bounding the recursion of the parser
+	nesting int
+}
+
+// maxNesting is the maximum nesting of the selection sets and values of a document, checked by the parser
+// while maxDepth is checked after the fragments are expanded.
+const maxNesting = 2 * maxDepth
+
+func (p *parser) enter() {
+	if p.nesting++; p.nesting > maxNesting {
+		p.fail("the document is nested more than %d levels deep", maxNesting)
+	}
+}
+
+func (p *parser) leave() {
+	p.nesting--
+}
+
+// parse parses a GraphQL request document.
+func parse(source string) (doc *document, err error) {
+	p := &parser{source: source, line: 1}
+	defer func() {
+		if r := recover(); r != nil {
+			if syntaxErr, ok := r.(*syntaxError); ok {
+				err = syntaxErr
+				return
+			}
+			panic(r)
+		}
+	}()
+	p.next()
+	doc = &document{fragments: make(map[string]*fragment)}
+	for p.tok.kind != tokenEOF {
+		switch {
+		case p.peek("{"):
+			doc.operations = append(doc.operations, &operation{kind: "query", selections: p.parseSelectionSet()})
+		case p.peek("query"), p.peek("mutation"), p.peek("subscription"):
+			doc.operations = append(doc.operations, p.parseOperation())
+		case p.peek("fragment"):
+			p.next()
+			name := p.expectName()
+			if _, ok := doc.fragments[name]; ok {
+				p.fail("duplicate fragment %q", name)
+			}
+			p.expectKeyword("on")
+			f := &fragment{typeCondition: p.expectName()}
+			p.parseDirectives()
+			f.selections = p.parseSelectionSet()
+			doc.fragments[name] = f
+		default:
+			p.unexpected()
+		}
+	}
+	if len(doc.operations) == 0 {
+		p.fail("the document has no operation")
+	}
+	return doc, nil
+}
+
+type syntaxError struct {
+	line, column int
+	msg          string
+}
+
+func (e *syntaxError) Error() string {
+	return fmt.Sprintf("syntax error at line %d, column %d: %s", e.line, e.column, e.msg)
+}
+
+func (p *parser) fail(format string, args ...interface{}) {
+	panic(&syntaxError{line: p.tok.line, column: p.tok.column, msg: fmt.Sprintf(format, args...)})
+}
+
+func (p *parser) unexpected() {
+	if p.tok.kind == tokenEOF {
+		p.fail("unexpected end of document")
+	}
+	p.fail("unexpected %q", p.tok.value)
+}
+
+func (p *parser) parseOperation() *operation {
+	op := &operation{kind: p.tok.value}
+	p.next()
+	if p.tok.kind == tokenName {
+		op.name = p.expectName()
+	}
+	if p.skip("(") {
+		for !p.skip(")") {
+			p.expect("$")
+			def := &variableDefinition{name: p.expectName()}
+			p.expect(":")
+			p.parseType()
+			if p.skip("=") {
+				def.defaultValue = p.parseValue(true)
+			}
+			op.variables = append(op.variables, def)
+		}
+	}
+	p.parseDirectives()
+	op.selections = p.parseSelectionSet()
+	return op
+}
+
+// parseType skips a type reference, e.g. [String!]!, as variables are coerced by the arguments using them.
+func (p *parser) parseType() {
+	if p.skip("[") {
+		p.parseType()
+		p.expect("]")
+	} else {
+		p.expectName()
+	}
+	p.skip("!")
+}
+
+func (p *parser) parseSelectionSet() []selection {
+	p.expect("{")
+	p.enter()
+	defer p.leave()
+	var selections []selection
+	for !p.skip("}") {
+		selections = append(selections, p.parseSelection())
+	}
+	if len(selections) == 0 {
+		p.fail("empty selection set")
+	}
+	return selections
+}
+
+func (p *parser) parseSelection() selection {
+	if p.skip("...") {
+		if p.tok.kind == tokenName && p.tok.value != "on" {
+			return &fragmentSpread{name: p.expectName(), directives: p.parseDirectives()}
+		}
+		f := &inlineFragment{}
+		if p.skip("on") {
+			f.typeCondition = p.expectName()
+		}
+		f.directives = p.parseDirectives()
+		f.selections = p.parseSelectionSet()
+		return f
+	}
+	f := &field{name: p.expectName()}
+	if p.skip(":") {
+		f.alias, f.name = f.name, p.expectName()
+	}
+	f.arguments = p.parseArguments(false)
+	f.directives = p.parseDirectives()
+	if p.peek("{") {
+		f.selections = p.parseSelectionSet()
+	}
+	return f
+}
+
+func (p *parser) parseArguments(constant bool) []*argument {
+	var arguments []*argument
+	if p.skip("(") {
+		for !p.skip(")") {
+			arg := &argument{name: p.expectName()}
+			p.expect(":")
+			arg.value = p.parseValue(constant)
+			arguments = append(arguments, arg)
+		}
+	}
+	return arguments
+}
+
+func (p *parser) parseDirectives() []*directive {
+	var directives []*directive
+	for p.skip("@") {
+		directives = append(directives, &directive{name: p.expectName(), arguments: p.parseArguments(false)})
+	}
+	return directives
+}
+
+func (p *parser) parseValue(constant bool) value {
+	tok := p.tok
+	switch tok.kind {
+	case tokenInt, tokenFloat:
+		p.next()
+		f, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			p.fail("invalid number %q", tok.value)
+		}
+		return f
+	case tokenString:
+		p.next()
+		return tok.value
+	case tokenName:
+		p.next()
+		switch tok.value {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		}
+		return enumValue(tok.value)
+	}
+	switch {
+	case p.peek("$") && !constant:
+		p.next()
+		return variable(p.expectName())
+	case p.skip("["):
+		p.enter()
+		defer p.leave()
+		list := []value{}
+		for !p.skip("]") {
+			list = append(list, p.parseValue(constant))
+		}
+		return list
+	case p.skip("{"):
+		p.enter()
+		defer p.leave()
+		object := []*argument{}
+		for !p.skip("}") {
+			f := &argument{name: p.expectName()}
+			p.expect(":")
+			f.value = p.parseValue(constant)
+			object = append(object, f)
+		}
+		return object
+	}
+	p.unexpected()
+	return nil
+}
+
+func (p *parser) peek(value string) bool {
+	return (p.tok.kind == tokenPunctuator || p.tok.kind == tokenName) && p.tok.value == value
+}
+
+func (p *parser) skip(value string) bool {
+	if p.peek(value) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(value string) {
+	if !p.skip(value) {
+		p.unexpected()
+	}
+}
+
+func (p *parser) expectKeyword(value string) {
+	if p.tok.kind != tokenName || p.tok.value != value {
+		p.unexpected()
+	}
+	p.next()
+}
+
+func (p *parser) expectName() string {
+	if p.tok.kind != tokenName {
+		p.unexpected()
+	}
+	name := p.tok.value
+	p.next()
+	return name
+}
+
+// next reads the next token, skipping white space, commas and comments.
+func (p *parser) next() {
+	for p.pos < len(p.source) {
+		c := p.source[p.pos]
+		if c == '\n' {
+			p.pos++
+			p.line, p.lineAt = p.line+1, p.pos
+		} else if c == ' ' || c == '\t' || c == '\r' || c == ',' {
+			p.pos++
+		} else if c == '#' {
+			for p.pos < len(p.source) && p.source[p.pos] != '\n' {
+				p.pos++
+			}
+		} else {
+			break
+		}
+	}
+	p.tok = token{line: p.line, column: p.pos - p.lineAt + 1}
+	if p.pos >= len(p.source) {
+		p.tok.kind = tokenEOF
+		return
+	}
+	start := p.pos
+	c := p.source[p.pos]
+	switch {
+	case strings.HasPrefix(p.source[p.pos:], "..."):
+		p.pos += 3
+		p.tok.kind, p.tok.value = tokenPunctuator, "..."
+	case strings.IndexByte("!$():=@[]{}|", c) >= 0:
+		p.pos++
+		p.tok.kind, p.tok.value = tokenPunctuator, string(c)
+	case c == '_' || isLetter(c):
+		for p.pos < len(p.source) && (p.source[p.pos] == '_' || isLetter(p.source[p.pos]) || isDigit(p.source[p.pos])) {
+			p.pos++
+		}
+		p.tok.kind, p.tok.value = tokenName, p.source[start:p.pos]
+	case c == '-' || isDigit(c):
+		p.readNumber()
+	case strings.HasPrefix(p.source[p.pos:], `"""`):
+		p.readBlockString()
+	case c == '"':
+		p.readString()
+	default:
+		p.fail("unexpected character %q", c)
+	}
+}
+
+func (p *parser) readNumber() {
+	start := p.pos
+	p.tok.kind = tokenInt
+	if p.source[p.pos] == '-' {
+		p.pos++
+	}
+	digits := func() {
+		n := p.pos
+		for p.pos < len(p.source) && isDigit(p.source[p.pos]) {
+			p.pos++
+		}
+		if n == p.pos {
+			p.fail("invalid number %q", p.source[start:p.pos])
+		}
+	}
+	digits()
+	if p.pos < len(p.source) && p.source[p.pos] == '.' {
+		p.pos++
+		p.tok.kind = tokenFloat
+		digits()
+	}
+	if p.pos < len(p.source) && (p.source[p.pos] == 'e' || p.source[p.pos] == 'E') {
+		p.pos++
+		p.tok.kind = tokenFloat
+		if p.pos < len(p.source) && (p.source[p.pos] == '+' || p.source[p.pos] == '-') {
+			p.pos++
+		}
+		digits()
+	}
+	p.tok.value = p.source[start:p.pos]
+}
+
+// readString reads a string with the escape sequences of GraphQL, which are those of JSON.
+func (p *parser) readString() {
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.source) && p.source[p.pos] != '"' && p.source[p.pos] != '\n' {
+		if p.source[p.pos] == '\\' {
+			p.pos++
+		}
+		p.pos++
+	}
+	if p.pos >= len(p.source) || p.source[p.pos] != '"' {
+		p.fail("unterminated string")
+	}
+	p.pos++
+	var s string
+	if err := json.Unmarshal([]byte(p.source[start:p.pos]), &s); err != nil {
+		p.fail("invalid string %s", p.source[start:p.pos])
+	}
+	p.tok.kind, p.tok.value = tokenString, s
+}
+
+func (p *parser) readBlockString() {
+	p.pos += 3
+	end := strings.Index(strings.Replace(p.source[p.pos:], `\"""`, "\\\x00\x00\x00", -1), `"""`)
+	if end < 0 {
+		p.fail("unterminated block string")
+	}
+	raw := p.source[p.pos : p.pos+end]
+	p.line += strings.Count(raw, "\n")
+	if i := strings.LastIndexByte(raw, '\n'); i >= 0 {
+		p.lineAt = p.pos + i + 1
+	}
+	p.pos += end + 3
+	p.tok.kind, p.tok.value = tokenString, strings.TrimSpace(strings.Replace(raw, `\"""`, `"""`, -1))
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
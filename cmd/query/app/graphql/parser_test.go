@@ -0,0 +1,106 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	doc, err := parse(`
+		# the traces of a service
+		query Traces($service: String!, $limit: Int = 10) @include(if: true) {
+			slow: traces(service: $service, limit: $limit, tags: [{key: "error", value: "true"}]) {
+				traceID
+				...spanFields
+				... on Trace @skip(if: false) { spanCount }
+			}
+		}
+		fragment spanFields on Trace { spans(minDuration: "1s") { spanID } }
+		{ services }`)
+	require.NoError(t, err)
+	require.Len(t, doc.operations, 2)
+	op := doc.operations[0]
+	assert.Equal(t, "query", op.kind)
+	assert.Equal(t, "Traces", op.name)
+	require.Len(t, op.variables, 2)
+	assert.Equal(t, 10.0, op.variables[1].defaultValue)
+
+	require.Len(t, op.selections, 1)
+	traces := op.selections[0].(*field)
+	assert.Equal(t, "slow", traces.alias)
+	assert.Equal(t, "traces", traces.name)
+	assert.Equal(t, "slow", traces.responseKey())
+	require.Len(t, traces.arguments, 3)
+	assert.Equal(t, variable("service"), traces.arguments[0].value)
+	assert.Equal(t, []interface{}{map[string]interface{}{"key": "error", "value": "true"}},
+		resolveValue(traces.arguments[2].value, nil))
+	require.Len(t, traces.selections, 3)
+	assert.Equal(t, "spanFields", traces.selections[1].(*fragmentSpread).name)
+	inline := traces.selections[2].(*inlineFragment)
+	assert.Equal(t, "Trace", inline.typeCondition)
+	assert.Equal(t, "skip", inline.directives[0].name)
+
+	require.Contains(t, doc.fragments, "spanFields")
+	assert.Equal(t, "Trace", doc.fragments["spanFields"].typeCondition)
+	assert.Equal(t, "query", doc.operations[1].kind)
+}
+
+func TestParseValues(t *testing.T) {
+	doc, err := parse(`{ f(a: -1.5e3, b: "tab\té", c: """ block "quoted" """, d: null, e: FOO, g: [1, true]) }`)
+	require.NoError(t, err)
+	args := doc.operations[0].selections[0].(*field).arguments
+	values := make(map[string]interface{})
+	for _, arg := range args {
+		values[arg.name] = resolveValue(arg.value, nil)
+	}
+	assert.Equal(t, map[string]interface{}{
+		"a": -1500.0,
+		"b": "tab\té",
+		"c": `block "quoted"`,
+		"d": nil,
+		"e": "FOO",
+		"g": []interface{}{1.0, true},
+	}, values)
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{query: "", err: "syntax error at line 1, column 1: the document has no operation"},
+		{query: "{ services", err: "syntax error at line 1, column 11: unexpected end of document"},
+		{query: "{\n  trace(id: ) }", err: `syntax error at line 2, column 13: unexpected ")"`},
+		{query: "{ }", err: "syntax error at line 1, column 4: empty selection set"},
+		{query: `{ f(a: "open) }`, err: "syntax error at line 1, column 8: unterminated string"},
+		{query: "{ f(a: 1.) }", err: `syntax error at line 1, column 8: invalid number "1."`},
+		{query: "{ f % }", err: `syntax error at line 1, column 5: unexpected character '%'`},
+		{query: "fragment a on T { f } fragment a on T { f }", err: `syntax error at line 1, column 34: duplicate fragment "a"`},
+		{query: "query ($a: Int = $b) { f }", err: `syntax error at line 1, column 18: unexpected "$"`},
+		{query: strings.Repeat("{ f ", 25), err: "syntax error at line 1, column 99: the document is nested more than 24 levels deep"},
+		{query: "{ f(a: " + strings.Repeat("[", 25), err: "syntax error at line 1, column 32: the document is nested more than 24 levels deep"},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			_, err := parse(test.query)
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
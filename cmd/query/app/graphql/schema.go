@@ -0,0 +1,526 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+const (
+	defaultTracesLimit                = 100
+	defaultTraceQueryLookbackDuration = 2 * 24 * time.Hour
+	defaultDependencyLookbackDuration = 24 * time.Hour
+)
+
+// traceSource is a trace being resolved, indexing the children of its spans when they are selected.
+type traceSource struct {
+	trace    *model.Trace
+	children map[model.SpanID][]*model.Span
+}
+
+type spanSource struct {
+	span  *model.Span
+	trace *traceSource
+}
+
+func (t *traceSource) spans(filter func(span *model.Span) bool) []interface{} {
+	spans := []interface{}{}
+	for _, span := range t.trace.Spans {
+		if filter(span) {
+			spans = append(spans, &spanSource{span: span, trace: t})
+		}
+	}
+	return spans
+}
+
+func (t *traceSource) childrenOf(spanID model.SpanID) []*model.Span {
+	if t.children == nil {
+		t.children = make(map[model.SpanID][]*model.Span)
+		for _, span := range t.trace.Spans {
+			if parent := span.ParentSpanID(); parent != 0 {
+				t.children[parent] = append(t.children[parent], span)
+			}
+		}
+	}
+	return t.children[spanID]
+}
+
+// schema builds the types of the GraphQL API on the query service.
+type schema struct {
+	queryService *querysvc.QueryService
+	logger       *zap.Logger
+	timeNow      func() time.Time
+}
+
+func (s *schema) queryType() *objectType {
+	keyValueType := &objectType{name: "KeyValue", fields: map[string]*fieldDef{
+		"key": scalar(func(src interface{}) interface{} {
+			return src.(model.KeyValue).Key
+		}),
+		"type": scalar(func(src interface{}) interface{} {
+			return strings.ToLower(src.(model.KeyValue).VType.String())
+		}),
+		"value": {resolve: func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			kv := src.(model.KeyValue)
+			return kv.AsString(), nil
+		}},
+	}}
+	keyValues := func(kvs model.KeyValues, args map[string]interface{}) []interface{} {
+		keys, filtered := args["keys"].([]string)
+		values := []interface{}{}
+		for _, kv := range kvs {
+			if !filtered || containsString(keys, kv.Key) {
+				values = append(values, kv)
+			}
+		}
+		return values
+	}
+	keysArg := []argDef{{name: "keys", kind: argStringList}}
+	processType := &objectType{name: "Process", fields: map[string]*fieldDef{
+		"serviceName": scalar(func(src interface{}) interface{} {
+			return src.(*model.Process).ServiceName
+		}),
+		"tags": {typ: keyValueType, args: keysArg, resolve: func(_ context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+			return keyValues(src.(*model.Process).Tags, args), nil
+		}},
+	}}
+	logType := &objectType{name: "Log", fields: map[string]*fieldDef{
+		"timestamp": scalar(func(src interface{}) interface{} {
+			return model.TimeAsEpochMicroseconds(src.(model.Log).Timestamp)
+		}),
+		"fields": {typ: keyValueType, args: keysArg, resolve: func(_ context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+			return keyValues(src.(model.Log).Fields, args), nil
+		}},
+	}}
+	referenceType := &objectType{name: "Reference", fields: map[string]*fieldDef{
+		"refType": scalar(func(src interface{}) interface{} {
+			return src.(model.SpanRef).RefType.String()
+		}),
+		"traceID": scalar(func(src interface{}) interface{} {
+			return src.(model.SpanRef).TraceID.String()
+		}),
+		"spanID": scalar(func(src interface{}) interface{} {
+			return src.(model.SpanRef).SpanID.String()
+		}),
+	}}
+	spanFilterArgs := []argDef{
+		{name: "service", kind: argString},
+		{name: "operation", kind: argString},
+		{name: "minDuration", kind: argString},
+		{name: "hasError", kind: argBoolean},
+		{name: "tags", kind: argObjectList},
+	}
+	spanType := &objectType{name: "Span"}
+	spanType.fields = map[string]*fieldDef{
+		"traceID": scalar(func(src interface{}) interface{} {
+			return src.(*spanSource).span.TraceID.String()
+		}),
+		"spanID": scalar(func(src interface{}) interface{} {
+			return src.(*spanSource).span.SpanID.String()
+		}),
+		"parentSpanID": {resolve: func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			if parent := src.(*spanSource).span.ParentSpanID(); parent != 0 {
+				return parent.String(), nil
+			}
+			return nil, nil
+		}},
+		"operationName": scalar(func(src interface{}) interface{} {
+			return src.(*spanSource).span.OperationName
+		}),
+		"serviceName": scalar(func(src interface{}) interface{} {
+			return src.(*spanSource).span.Process.ServiceName
+		}),
+		"startTime": scalar(func(src interface{}) interface{} {
+			return model.TimeAsEpochMicroseconds(src.(*spanSource).span.StartTime)
+		}),
+		"duration": scalar(func(src interface{}) interface{} {
+			return model.DurationAsMicroseconds(src.(*spanSource).span.Duration)
+		}),
+		"tags": {typ: keyValueType, args: keysArg, resolve: func(_ context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+			return keyValues(src.(*spanSource).span.Tags, args), nil
+		}},
+		"logs": {typ: logType, resolve: func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			logs := []interface{}{}
+			for _, log := range src.(*spanSource).span.Logs {
+				logs = append(logs, log)
+			}
+			return logs, nil
+		}},
+		"references": {typ: referenceType, resolve: func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			refs := []interface{}{}
+			for _, ref := range src.(*spanSource).span.References {
+				refs = append(refs, ref)
+			}
+			return refs, nil
+		}},
+		"process": {typ: processType, resolve: func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			if process := src.(*spanSource).span.Process; process != nil {
+				return process, nil
+			}
+			return nil, nil
+		}},
+		"warnings": scalar(func(src interface{}) interface{} {
+			return append([]string{}, src.(*spanSource).span.Warnings...)
+		}),
+		"children": {typ: spanType, args: spanFilterArgs, resolve: func(_ context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+			filter, err := spanFilter(args)
+			if err != nil {
+				return nil, err
+			}
+			s := src.(*spanSource)
+			children := []interface{}{}
+			for _, child := range s.trace.childrenOf(s.span.SpanID) {
+				if filter(child) {
+					children = append(children, &spanSource{span: child, trace: s.trace})
+				}
+			}
+			return children, nil
+		}},
+	}
+	traceType := &objectType{name: "Trace", fields: map[string]*fieldDef{
+		"traceID": {resolve: func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			if spans := src.(*traceSource).trace.Spans; len(spans) > 0 {
+				return spans[0].TraceID.String(), nil
+			}
+			return nil, nil
+		}},
+		"spans": {typ: spanType, args: spanFilterArgs, resolve: func(_ context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+			filter, err := spanFilter(args)
+			if err != nil {
+				return nil, err
+			}
+			return src.(*traceSource).spans(filter), nil
+		}},
+		"rootSpans": {typ: spanType, resolve: func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			return src.(*traceSource).spans(func(span *model.Span) bool { return span.ParentSpanID() == 0 }), nil
+		}},
+		"spanCount": scalar(func(src interface{}) interface{} {
+			return len(src.(*traceSource).trace.Spans)
+		}),
+		"services": {resolve: func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			services := []string{}
+			for _, span := range src.(*traceSource).trace.Spans {
+				if span.Process != nil && !containsString(services, span.Process.ServiceName) {
+					services = append(services, span.Process.ServiceName)
+				}
+			}
+			sort.Strings(services)
+			return services, nil
+		}},
+		"startTime": {resolve: func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			start, _ := traceBounds(src.(*traceSource).trace)
+			return model.TimeAsEpochMicroseconds(start), nil
+		}},
+		"duration": {resolve: func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			start, end := traceBounds(src.(*traceSource).trace)
+			return model.DurationAsMicroseconds(end.Sub(start)), nil
+		}},
+	}}
+	operationType := &objectType{name: "Operation", fields: map[string]*fieldDef{
+		"name": scalar(func(src interface{}) interface{} {
+			return src.(spanstore.Operation).Name
+		}),
+		"spanKind": scalar(func(src interface{}) interface{} {
+			return src.(spanstore.Operation).SpanKind
+		}),
+	}}
+	dependencyType := &objectType{name: "Dependency", fields: map[string]*fieldDef{
+		"parent": scalar(func(src interface{}) interface{} {
+			return src.(model.DependencyLink).Parent
+		}),
+		"child": scalar(func(src interface{}) interface{} {
+			return src.(model.DependencyLink).Child
+		}),
+		"callCount": scalar(func(src interface{}) interface{} {
+			return src.(model.DependencyLink).CallCount
+		}),
+	}}
+	return &objectType{name: "Query", fields: map[string]*fieldDef{
+		"services": {resolve: func(ctx context.Context, _ interface{}, _ map[string]interface{}) (interface{}, error) {
+			return s.queryService.GetServices(ctx)
+		}},
+		"operations": {
+			typ:     operationType,
+			args:    []argDef{{name: "service", kind: argString, required: true}, {name: "spanKind", kind: argString}},
+			resolve: s.operations,
+		},
+		"trace": {
+			typ:     traceType,
+			args:    []argDef{{name: "id", kind: argString, required: true}},
+			resolve: s.trace,
+		},
+		"traces": {
+			typ: traceType,
+			args: []argDef{
+				{name: "service", kind: argString, required: true},
+				{name: "operation", kind: argString},
+				{name: "tags", kind: argObjectList},
+				{name: "start", kind: argString},
+				{name: "end", kind: argString},
+				{name: "minDuration", kind: argString},
+				{name: "maxDuration", kind: argString},
+				{name: "limit", kind: argInt},
+			},
+			resolve: s.traces,
+		},
+		"dependencies": {
+			typ: dependencyType,
+			args: []argDef{
+				{name: "endTime", kind: argString},
+				{name: "lookback", kind: argString},
+				{name: "service", kind: argString},
+			},
+			resolve: s.dependencies,
+		},
+	}}
+}
+
+func (s *schema) operations(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+	spanKind, _ := args["spanKind"].(string)
+	operations, err := s.queryService.GetOperations(ctx, spanstore.OperationQueryParameters{
+		ServiceName: args["service"].(string),
+		SpanKind:    spanKind,
+	})
+	if err != nil {
+		return nil, err
+	}
+	results := make([]interface{}, len(operations))
+	for i, operation := range operations {
+		results[i] = operation
+	}
+	return results, nil
+}
+
+func (s *schema) trace(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+	traceID, err := model.TraceIDFromString(args["id"].(string))
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse trace id: %w", err)
+	}
+	trace, err := s.queryService.GetTrace(ctx, traceID)
+	if err == spanstore.ErrTraceNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.adjust(trace), nil
+}
+
+func (s *schema) traces(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+	query := &spanstore.TraceQueryParameters{
+		ServiceName: args["service"].(string),
+		NumTraces:   defaultTracesLimit,
+	}
+	query.OperationName, _ = args["operation"].(string)
+	if limit, ok := args["limit"].(int); ok {
+		if limit <= 0 {
+			return nil, fmt.Errorf("limit must be positive, got %d", limit)
+		}
+		query.NumTraces = limit
+	}
+	tags, err := tagFilters(args)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) > 0 {
+		query.Tags = tags
+	}
+	query.StartTimeMax = s.timeNow()
+	if err := parseTimeArg(args, "end", &query.StartTimeMax); err != nil {
+		return nil, err
+	}
+	query.StartTimeMin = query.StartTimeMax.Add(-defaultTraceQueryLookbackDuration)
+	if err := parseTimeArg(args, "start", &query.StartTimeMin); err != nil {
+		return nil, err
+	}
+	if err := parseDurationArg(args, "minDuration", &query.DurationMin); err != nil {
+		return nil, err
+	}
+	if err := parseDurationArg(args, "maxDuration", &query.DurationMax); err != nil {
+		return nil, err
+	}
+	traces, err := s.queryService.FindTraces(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]interface{}, len(traces))
+	for i, trace := range traces {
+		results[i] = s.adjust(trace)
+	}
+	return results, nil
+}
+
+// adjust applies the adjusters of the query service, as the HTTP API does.
+func (s *schema) adjust(trace *model.Trace) *traceSource {
+	adjusted, err := s.queryService.Adjust(trace)
+	if err != nil {
+		s.logger.Warn("Failed to adjust trace", zap.Error(err))
+	}
+	return &traceSource{trace: adjusted}
+}
+
+func (s *schema) dependencies(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+	endTime := s.timeNow()
+	if err := parseTimeArg(args, "endTime", &endTime); err != nil {
+		return nil, err
+	}
+	lookback := defaultDependencyLookbackDuration
+	if err := parseDurationArg(args, "lookback", &lookback); err != nil {
+		return nil, err
+	}
+	dependencies, err := s.queryService.GetDependencies(ctx, endTime, lookback)
+	if err != nil {
+		return nil, err
+	}
+	service, _ := args["service"].(string)
+	type key struct {
+		parent string
+		child  string
+	}
+	links := make(map[key]uint64)
+	for _, l := range dependencies {
+		if service == "" || l.Parent == service || l.Child == service {
+			links[key{l.Parent, l.Child}] += l.CallCount
+		}
+	}
+	results := make([]interface{}, 0, len(links))
+	for k, callCount := range links {
+		results = append(results, model.DependencyLink{Parent: k.parent, Child: k.child, CallCount: callCount})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i].(model.DependencyLink), results[j].(model.DependencyLink)
+		return a.Parent < b.Parent || (a.Parent == b.Parent && a.Child < b.Child)
+	})
+	return results, nil
+}
+
+// spanFilter returns the filter of the spans of a trace selected by the arguments of the spans field.
+func spanFilter(args map[string]interface{}) (func(span *model.Span) bool, error) {
+	service, _ := args["service"].(string)
+	operation, _ := args["operation"].(string)
+	var minDuration time.Duration
+	if err := parseDurationArg(args, "minDuration", &minDuration); err != nil {
+		return nil, err
+	}
+	hasError, filterErrors := args["hasError"].(bool)
+	tags, err := tagFilters(args)
+	if err != nil {
+		return nil, err
+	}
+	return func(span *model.Span) bool {
+		if service != "" && (span.Process == nil || span.Process.ServiceName != service) {
+			return false
+		}
+		if operation != "" && span.OperationName != operation {
+			return false
+		}
+		if span.Duration < minDuration {
+			return false
+		}
+		if filterErrors && spanHasError(span) != hasError {
+			return false
+		}
+		for key, value := range tags {
+			tag, ok := model.KeyValues(span.Tags).FindByKey(key)
+			if !ok || tag.AsString() != value {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func spanHasError(span *model.Span) bool {
+	tag, ok := model.KeyValues(span.Tags).FindByKey("error")
+	return ok && tag.AsString() == "true"
+}
+
+// tagFilters reads the tags argument, a list of {key, value} objects.
+func tagFilters(args map[string]interface{}) (map[string]string, error) {
+	objects, _ := args["tags"].([]map[string]interface{})
+	tags := make(map[string]string, len(objects))
+	for _, object := range objects {
+		key, ok := object["key"].(string)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("tags expects objects with a key and a value")
+		}
+		value, ok := object["value"].(string)
+		if !ok {
+			return nil, fmt.Errorf("tag %q has no value", key)
+		}
+		tags[key] = value
+	}
+	return tags, nil
+}
+
+func parseTimeArg(args map[string]interface{}, name string, t *time.Time) error {
+	if s, ok := args[name].(string); ok {
+		parsed, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return fmt.Errorf("cannot parse %s as an RFC 3339 time: %w", name, err)
+		}
+		*t = parsed
+	}
+	return nil
+}
+
+func parseDurationArg(args map[string]interface{}, name string, d *time.Duration) error {
+	if s, ok := args[name].(string); ok {
+		parsed, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("cannot parse %s: %w", name, err)
+		}
+		*d = parsed
+	}
+	return nil
+}
+
+func traceBounds(trace *model.Trace) (start, end time.Time) {
+	for i, span := range trace.Spans {
+		if i == 0 || span.StartTime.Before(start) {
+			start = span.StartTime
+		}
+		if spanEnd := span.StartTime.Add(span.Duration); i == 0 || spanEnd.After(end) {
+			end = spanEnd
+		}
+	}
+	return start, end
+}
+
+// scalar defines a field without arguments computed from its source.
+func scalar(get func(src interface{}) interface{}) *fieldDef {
+	return &fieldDef{resolve: func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+		return get(src), nil
+	}}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -17,22 +17,36 @@ package app
 import (
 	"net"
 	"net/http"
+	"path"
 	"strings"
 
-	"github.com/gorilla/handlers"
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/opentracing-contrib/go-stdlib/nethttp"
 	"github.com/opentracing/opentracing-go"
 	"github.com/soheilhy/cmux"
+	"github.com/uber/jaeger-lib/metrics"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	channelz "google.golang.org/grpc/channelz/service"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/reflection"
 
-	"github.com/jaegertracing/jaeger/cmd/query/app/querysvc"
+	"github.com/jaegertracing/jaeger/cmd/query/app/graphql"
+	"github.com/jaegertracing/jaeger/pkg/auth"
 	"github.com/jaegertracing/jaeger/pkg/healthcheck"
 	"github.com/jaegertracing/jaeger/pkg/netutils"
+	"github.com/jaegertracing/jaeger/pkg/queryservice"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
 	"github.com/jaegertracing/jaeger/pkg/recoveryhandler"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
 	"github.com/jaegertracing/jaeger/proto-gen/api_v2"
 )
 
+const (
+	loginRoute  = "/login"
+	logoutRoute = "/logout"
+)
+
 // Server runs HTTP, Mux and a grpc server
 type Server struct {
 	logger       *zap.Logger
@@ -48,8 +62,24 @@ type Server struct {
 }
 
 // NewServer creates and initializes Server
-func NewServer(logger *zap.Logger, querySvc *querysvc.QueryService, options *QueryOptions, tracer opentracing.Tracer) (*Server, error) {
-	grpcServer, err := createGRPCServer(querySvc, options, logger, tracer)
+func NewServer(
+	logger *zap.Logger,
+	querySvc *querysvc.QueryService,
+	metricsQuerySvc querysvc.MetricsQueryService,
+	options *QueryOptions,
+	metricsFactory metrics.Factory,
+	tracer opentracing.Tracer,
+) (*Server, error) {
+	var authenticator *auth.Authenticator
+	if options.Auth.Enabled() {
+		a, err := auth.NewAuthenticator(options.Auth, logger, metricsFactory.Namespace(metrics.NSOptions{Name: "auth"}))
+		if err != nil {
+			return nil, err
+		}
+		authenticator = a
+	}
+
+	grpcServer, err := createGRPCServer(querySvc, options, authenticator, logger, tracer)
 	if err != nil {
 		return nil, err
 	}
@@ -60,7 +90,7 @@ func NewServer(logger *zap.Logger, querySvc *querysvc.QueryService, options *Que
 		queryOptions:       options,
 		tracer:             tracer,
 		grpcServer:         grpcServer,
-		httpServer:         createHTTPServer(querySvc, options, tracer, logger),
+		httpServer:         createHTTPServer(querySvc, metricsQuerySvc, options, authenticator, tracer, logger),
 		unavailableChannel: make(chan healthcheck.Status),
 	}, nil
 }
@@ -70,8 +100,35 @@ func (s Server) HealthCheckStatus() chan healthcheck.Status {
 	return s.unavailableChannel
 }
 
-func createGRPCServer(querySvc *querysvc.QueryService, options *QueryOptions, logger *zap.Logger, tracer opentracing.Tracer) (*grpc.Server, error) {
+func createGRPCServer(
+	querySvc *querysvc.QueryService,
+	options *QueryOptions,
+	authenticator *auth.Authenticator,
+	logger *zap.Logger,
+	tracer opentracing.Tracer,
+) (*grpc.Server, error) {
 	var grpcOpts []grpc.ServerOption
+	// the identity set by the authenticator takes precedence over the identity headers
+	var unaryInterceptors []grpc.UnaryServerInterceptor
+	var streamInterceptors []grpc.StreamServerInterceptor
+	if authenticator != nil {
+		unaryInterceptors = append(unaryInterceptors, authenticator.UnaryServerInterceptor())
+		streamInterceptors = append(streamInterceptors, authenticator.StreamServerInterceptor())
+	}
+	if options.IdentityHeaders.User != "" {
+		unaryInterceptors = append(unaryInterceptors, options.IdentityHeaders.UnaryServerInterceptor())
+		streamInterceptors = append(streamInterceptors, options.IdentityHeaders.StreamServerInterceptor())
+	}
+	if options.Tenancy.Enabled {
+		tenancyMgr := tenancy.NewManager(&options.Tenancy)
+		unaryInterceptors = append(unaryInterceptors, tenancyMgr.UnaryServerInterceptor())
+		streamInterceptors = append(streamInterceptors, tenancyMgr.StreamServerInterceptor())
+	}
+	if len(unaryInterceptors) > 0 {
+		grpcOpts = append(grpcOpts,
+			grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(unaryInterceptors...)),
+			grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(streamInterceptors...)))
+	}
 
 	if options.TLS.Enabled {
 		tlsCfg, err := options.TLS.Config()
@@ -85,32 +142,71 @@ func createGRPCServer(querySvc *querysvc.QueryService, options *QueryOptions, lo
 
 	server := grpc.NewServer(grpcOpts...)
 
-	handler := NewGRPCHandler(querySvc, logger, tracer)
+	handler := queryservice.NewGRPCHandler(querySvc, logger, tracer)
 	api_v2.RegisterQueryServiceServer(server, handler)
+	if options.GRPCReflection {
+		reflection.Register(server)
+	}
+	if options.GRPCChannelz {
+		channelz.RegisterChannelzServiceToServer(server)
+	}
 	return server, nil
 }
 
-func createHTTPServer(querySvc *querysvc.QueryService, queryOpts *QueryOptions, tracer opentracing.Tracer, logger *zap.Logger) *http.Server {
-	apiHandlerOptions := []HandlerOption{
-		HandlerOptions.Logger(logger),
-		HandlerOptions.Tracer(tracer),
+func createHTTPServer(
+	querySvc *querysvc.QueryService,
+	metricsQuerySvc querysvc.MetricsQueryService,
+	queryOpts *QueryOptions,
+	authenticator *auth.Authenticator,
+	tracer opentracing.Tracer,
+	logger *zap.Logger,
+) *http.Server {
+	apiHandlerOptions := []queryservice.HandlerOption{
+		queryservice.HandlerOptions.Logger(logger),
+		queryservice.HandlerOptions.Tracer(tracer),
+		queryservice.HandlerOptions.MetricsQueryService(metricsQuerySvc),
+		queryservice.HandlerOptions.LiveTail(queryOpts.LiveTailPollInterval, queryOpts.LiveTailQuietPeriod),
+		queryservice.HandlerOptions.LiveTailAllowedOrigins(queryOpts.LiveTailAllowedOrigins),
+		queryservice.HandlerOptions.TraceMaxAge(queryOpts.TraceMaxAge),
+	}
+	// the static assets of the UI are served without a tenant
+	tenancyMgr := tenancy.NewManager(&queryOpts.Tenancy)
+	if tenancyMgr.Enabled {
+		apiHandlerOptions = append(apiHandlerOptions, queryservice.HandlerOptions.Tenancy(tenancyMgr))
+	}
+	if varyHeaders := callerHeaders(queryOpts, tenancyMgr); len(varyHeaders) > 0 {
+		apiHandlerOptions = append(apiHandlerOptions, queryservice.HandlerOptions.PrivateTraces(varyHeaders))
 	}
-	apiHandler := NewAPIHandler(
+	apiHandler := queryservice.NewAPIHandler(
 		querySvc,
 		apiHandlerOptions...)
-	r := NewRouter()
+	r := queryservice.NewRouter()
 	if queryOpts.BasePath != "/" {
 		r = r.PathPrefix(queryOpts.BasePath).Subrouter()
 	}
 
 	apiHandler.RegisterRoutes(r)
+	var graphqlHandler http.Handler = graphql.NewHandler(querySvc, logger)
+	if tenancyMgr.Enabled {
+		graphqlHandler = tenancyMgr.HTTPHandler(graphqlHandler)
+	}
+	graphqlHandler = nethttp.Middleware(tracer, graphqlHandler, nethttp.OperationNameFunc(func(r *http.Request) string {
+		return "/graphql"
+	}))
+	r.Handle("/graphql", graphqlHandler).Methods(http.MethodGet, http.MethodPost)
 	RegisterStaticHandler(r, logger, queryOpts)
 	var handler http.Handler = r
+	if queryOpts.IdentityHeaders.User != "" {
+		handler = queryOpts.IdentityHeaders.HTTPHandler(handler)
+	}
+	if authenticator != nil {
+		handler = authenticator.SessionHandler(handler, path.Join(queryOpts.BasePath, loginRoute), path.Join(queryOpts.BasePath, logoutRoute))
+	}
 	handler = additionalHeadersHandler(handler, queryOpts.AdditionalHeaders)
 	if queryOpts.BearerTokenPropagation {
 		handler = bearerTokenPropagationHandler(logger, handler)
 	}
-	handler = handlers.CompressHandler(handler)
+	handler = compressionHandler(handler)
 	recoveryHandler := recoveryhandler.NewRecoveryHandler(logger, true)
 	return &http.Server{
 		Handler: recoveryHandler(handler),
@@ -187,3 +283,21 @@ func (s *Server) Close() {
 	s.httpServer.Close()
 	s.conn.Close()
 }
+
+// callerHeaders returns the headers identifying the caller when the traces are restricted to the services
+// or the tenant of the caller, and nil when all the callers read the same traces.
+func callerHeaders(queryOpts *QueryOptions, tenancyMgr *tenancy.Manager) []string {
+	if !queryOpts.Auth.Enabled() && queryOpts.AuthorizationRulesFile == "" && !tenancyMgr.Enabled {
+		return nil
+	}
+	headers := []string{"Authorization", "Cookie"}
+	for _, header := range []string{queryOpts.IdentityHeaders.User, queryOpts.IdentityHeaders.Groups} {
+		if header != "" {
+			headers = append(headers, header)
+		}
+	}
+	if tenancyMgr.Enabled {
+		headers = append(headers, tenancyMgr.Header)
+	}
+	return headers
+}
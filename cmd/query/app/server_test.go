@@ -16,22 +16,33 @@ package app
 
 import (
 	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"testing"
 	"time"
 
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
 
 	"github.com/jaegertracing/jaeger/cmd/flags"
-	"github.com/jaegertracing/jaeger/cmd/query/app/querysvc"
+	"github.com/jaegertracing/jaeger/cmd/query/app/authorization"
+	"github.com/jaegertracing/jaeger/pkg/auth"
 	"github.com/jaegertracing/jaeger/pkg/config/tlscfg"
 	"github.com/jaegertracing/jaeger/pkg/healthcheck"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
 	"github.com/jaegertracing/jaeger/ports"
 	"github.com/jaegertracing/jaeger/proto-gen/api_v2"
 	depsmocks "github.com/jaegertracing/jaeger/storage/dependencystore/mocks"
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
 	spanstoremocks "github.com/jaegertracing/jaeger/storage/spanstore/mocks"
 )
 
@@ -52,8 +63,8 @@ func TestCreateTLSServerError(t *testing.T) {
 		ClientCAPath: "invalid/path",
 	}
 
-	_, err := NewServer(zap.NewNop(), &querysvc.QueryService{},
-		&QueryOptions{TLS: tlsCfg}, opentracing.NoopTracer{})
+	_, err := NewServer(zap.NewNop(), &querysvc.QueryService{}, metricsstore.NewDisabledReader(),
+		&QueryOptions{TLS: tlsCfg}, metrics.NullFactory, opentracing.NoopTracer{})
 	assert.NotNil(t, err)
 }
 
@@ -69,9 +80,9 @@ func TestServer(t *testing.T) {
 
 	querySvc := querysvc.NewQueryService(spanReader, dependencyReader, querysvc.QueryServiceOptions{})
 
-	server, err := NewServer(flagsSvc.Logger, querySvc,
+	server, err := NewServer(flagsSvc.Logger, querySvc, metricsstore.NewDisabledReader(),
 		&QueryOptions{HostPort: hostPort, BearerTokenPropagation: true},
-		opentracing.NoopTracer{})
+		metrics.NullFactory, opentracing.NoopTracer{})
 	assert.Nil(t, err)
 	assert.NoError(t, server.Start())
 	go func() {
@@ -80,8 +91,10 @@ func TestServer(t *testing.T) {
 		}
 	}()
 
-	client := newGRPCClient(t, hostPort)
-	defer client.conn.Close()
+	conn, err := grpc.Dial(hostPort, grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+	client := api_v2.NewQueryServiceClient(conn)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
@@ -110,7 +123,7 @@ func TestServerGracefulExit(t *testing.T) {
 
 	querySvc := &querysvc.QueryService{}
 	tracer := opentracing.NoopTracer{}
-	server, err := NewServer(flagsSvc.Logger, querySvc, &QueryOptions{HostPort: ports.PortToHostPort(ports.QueryAdminHTTP)}, tracer)
+	server, err := NewServer(flagsSvc.Logger, querySvc, metricsstore.NewDisabledReader(), &QueryOptions{HostPort: ports.PortToHostPort(ports.QueryAdminHTTP)}, metrics.NullFactory, tracer)
 	assert.Nil(t, err)
 	assert.NoError(t, server.Start())
 	go func() {
@@ -137,7 +150,7 @@ func TestServerHandlesPortZero(t *testing.T) {
 
 	querySvc := &querysvc.QueryService{}
 	tracer := opentracing.NoopTracer{}
-	server, err := NewServer(flagsSvc.Logger, querySvc, &QueryOptions{HostPort: ":0"}, tracer)
+	server, err := NewServer(flagsSvc.Logger, querySvc, metricsstore.NewDisabledReader(), &QueryOptions{HostPort: ":0"}, metrics.NullFactory, tracer)
 	assert.Nil(t, err)
 	assert.NoError(t, server.Start())
 	server.Close()
@@ -149,3 +162,83 @@ func TestServerHandlesPortZero(t *testing.T) {
 	port := onlyEntry.ContextMap()["port"]
 	assert.Greater(t, port, int64(0))
 }
+
+func TestServerGraphQL(t *testing.T) {
+	spanReader := &spanstoremocks.Reader{}
+	spanReader.On("GetServices", mock.Anything).Return([]string{"test"}, nil)
+	querySvc := querysvc.NewQueryService(spanReader, &depsmocks.Reader{}, querysvc.QueryServiceOptions{})
+	server := httptest.NewServer(createHTTPServer(querySvc, metricsstore.NewDisabledReader(), &QueryOptions{BasePath: "/jaeger"}, nil, opentracing.NoopTracer{}, zap.NewNop()).Handler)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/jaeger/graphql?query=%7Bservices%7D")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `{"data": {"services": ["test"]}}`, string(body))
+}
+
+func TestServerAuthentication(t *testing.T) {
+	tokensFile, err := ioutil.TempFile("", "tokens")
+	require.NoError(t, err)
+	defer os.Remove(tokensFile.Name())
+	_, err = tokensFile.WriteString("secret\n")
+	require.NoError(t, err)
+	require.NoError(t, tokensFile.Close())
+
+	spanReader := &spanstoremocks.Reader{}
+	spanReader.On("GetServices", mock.Anything).Return([]string{"test"}, nil)
+	querySvc := querysvc.NewQueryService(spanReader, &depsmocks.Reader{}, querysvc.QueryServiceOptions{})
+	authenticator, err := auth.NewAuthenticator(auth.Options{TokensFile: tokensFile.Name()}, zap.NewNop(), metrics.NullFactory)
+	require.NoError(t, err)
+	server := httptest.NewServer(createHTTPServer(querySvc, metricsstore.NewDisabledReader(), &QueryOptions{BasePath: "/jaeger"}, authenticator, opentracing.NoopTracer{}, zap.NewNop()).Handler)
+	defer server.Close()
+
+	for token, code := range map[string]int{"": http.StatusUnauthorized, "other": http.StatusUnauthorized, "secret": http.StatusOK} {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/jaeger/api/services", nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, code, resp.StatusCode, token)
+	}
+}
+
+func TestCallerHeaders(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     QueryOptions
+		expected []string
+	}{
+		{name: "no restriction", opts: QueryOptions{}},
+		{name: "authentication", opts: QueryOptions{Auth: auth.Options{TokensFile: "tokens"}}, expected: []string{"Authorization", "Cookie"}},
+		{
+			name: "authorization",
+			opts: QueryOptions{
+				AuthorizationRulesFile: "rules.json",
+				IdentityHeaders:        authorization.Headers{User: "X-User", Groups: "X-Groups"},
+			},
+			expected: []string{"Authorization", "Cookie", "X-User", "X-Groups"},
+		},
+		{
+			name:     "tenancy",
+			opts:     QueryOptions{Tenancy: tenancy.Options{Enabled: true}},
+			expected: []string{"Authorization", "Cookie", tenancy.DefaultHeader},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, callerHeaders(&tc.opts, tenancy.NewManager(&tc.opts.Tenancy)))
+		})
+	}
+}
+
+func TestCreateServerAuthError(t *testing.T) {
+	_, err := NewServer(zap.NewNop(), &querysvc.QueryService{}, metricsstore.NewDisabledReader(),
+		&QueryOptions{Auth: auth.Options{TokensFile: "/does/not/exist"}}, metrics.NullFactory, opentracing.NoopTracer{})
+	assert.Error(t, err)
+}
@@ -30,6 +30,8 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/jaegertracing/jaeger/cmd/query/app/ui"
+	"github.com/jaegertracing/jaeger/pkg/queryservice"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
 )
 
 var (
@@ -44,9 +46,11 @@ var (
 // RegisterStaticHandler adds handler for static assets to the router.
 func RegisterStaticHandler(r *mux.Router, logger *zap.Logger, qOpts *QueryOptions) {
 	staticHandler, err := NewStaticAssetsHandler(qOpts.StaticAssets, StaticAssetsHandlerOptions{
-		BasePath:     qOpts.BasePath,
-		UIConfigPath: qOpts.UIConfig,
-		Logger:       logger,
+		BasePath:              qOpts.BasePath,
+		UIConfigPath:          qOpts.UIConfig,
+		UIConfigOverridesPath: qOpts.UIConfigOverrides,
+		Tenancy:               tenancy.NewManager(&qOpts.Tenancy),
+		Logger:                logger,
 	})
 
 	if err != nil {
@@ -58,16 +62,23 @@ func RegisterStaticHandler(r *mux.Router, logger *zap.Logger, qOpts *QueryOption
 
 // StaticAssetsHandler handles static assets
 type StaticAssetsHandler struct {
-	options   StaticAssetsHandlerOptions
-	indexHTML atomic.Value // stores []byte
-	assetsFS  http.FileSystem
+	options       StaticAssetsHandlerOptions
+	indexHTML     atomic.Value // stores []byte
+	indexTemplate atomic.Value // stores []byte, index.html before the UI config is set
+	uiConfig      atomic.Value // stores map[string]interface{}
+	overrides     *UIConfigOverrides
+	assetsFS      http.FileSystem
 }
 
 // StaticAssetsHandlerOptions defines options for NewStaticAssetsHandler
 type StaticAssetsHandlerOptions struct {
 	BasePath     string
 	UIConfigPath string
-	Logger       *zap.Logger
+	// UIConfigOverridesPath is the path to the overrides of the UI config for some tenants or callers
+	UIConfigOverridesPath string
+	// Tenancy reads the tenant of the requests for the UI config overrides
+	Tenancy *tenancy.Manager
+	Logger  *zap.Logger
 }
 
 // NewStaticAssetsHandler returns a StaticAssetsHandler
@@ -81,38 +92,64 @@ func NewStaticAssetsHandler(staticAssetsRoot string, options StaticAssetsHandler
 		options.Logger = zap.NewNop()
 	}
 
-	indexHTML, err := loadIndexBytes(assetsFS.Open, options)
-	if err != nil {
-		return nil, err
+	if options.Tenancy == nil {
+		options.Tenancy = tenancy.NewManager(&tenancy.Options{})
 	}
 
 	h := &StaticAssetsHandler{
 		options:  options,
 		assetsFS: assetsFS,
 	}
-
-	h.indexHTML.Store(indexHTML)
+	if options.UIConfigOverridesPath != "" {
+		overrides, err := LoadUIConfigOverrides(options.UIConfigOverridesPath)
+		if err != nil {
+			return nil, err
+		}
+		h.overrides = overrides
+	}
+	if err := h.loadIndex(); err != nil {
+		return nil, err
+	}
 	h.watch()
 
 	return h, nil
 }
 
-func loadIndexBytes(open func(string) (http.File, error), options StaticAssetsHandlerOptions) ([]byte, error) {
-	indexBytes, err := loadIndexHTML(open)
+// loadIndex loads index.html and the UI config, and stores the index with the config.
+func (sH *StaticAssetsHandler) loadIndex() error {
+	indexBytes, err := loadIndexTemplate(sH.assetsFS.Open, sH.options)
 	if err != nil {
-		return nil, fmt.Errorf("cannot load index.html: %w", err)
+		return err
+	}
+	config, err := loadUIConfig(sH.options.UIConfigPath)
+	if err != nil {
+		return err
 	}
+	sH.indexTemplate.Store(indexBytes)
+	sH.uiConfig.Store(config)
+	sH.indexHTML.Store(renderIndex(indexBytes, config))
+	return nil
+}
+
+// renderIndex sets the UI config in index.html, the UI using its default config when there is none.
+func renderIndex(indexBytes []byte, config map[string]interface{}) []byte {
 	configString := "JAEGER_CONFIG = DEFAULT_CONFIG"
-	if config, err := loadUIConfig(options.UIConfigPath); err != nil {
-		return nil, err
-	} else if config != nil {
+	if config != nil {
 		// TODO if we want to support other config formats like YAML, we need to normalize `config` to be
 		// suitable for json.Marshal(). For example, YAML parser may return a map that has keys of type
 		// interface{}, and json.Marshal() is unable to serialize it.
 		bytes, _ := json.Marshal(config)
 		configString = fmt.Sprintf("JAEGER_CONFIG = %v", string(bytes))
 	}
-	indexBytes = configPattern.ReplaceAll(indexBytes, []byte(configString+";"))
+	return configPattern.ReplaceAll(indexBytes, []byte(configString+";"))
+}
+
+// loadIndexTemplate loads index.html with the base path set.
+func loadIndexTemplate(open func(string) (http.File, error), options StaticAssetsHandlerOptions) ([]byte, error) {
+	indexBytes, err := loadIndexHTML(open)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load index.html: %w", err)
+	}
 	if options.BasePath == "" {
 		options.BasePath = "/"
 	}
@@ -144,11 +181,9 @@ func (sH *StaticAssetsHandler) configListener(watcher *fsnotify.Watcher) {
 			}
 			// this will catch events for all files inside the same directory, which is OK if we don't have many changes
 			sH.options.Logger.Info("reloading UI config", zap.String("filename", sH.options.UIConfigPath))
-			content, err := loadIndexBytes(sH.assetsFS.Open, sH.options)
-			if err != nil {
-				sH.options.Logger.Error("error while reloading the UI config", zap.Error(err))
+			if err := sH.loadIndex(); err != nil {
+				sH.options.Logger.Error("error while reloading the UI config, using the last known version", zap.Error(err))
 			}
-			sH.indexHTML.Store(content)
 		case err, ok := <-watcher.Errors:
 			if !ok {
 				return
@@ -238,10 +273,40 @@ func (sH *StaticAssetsHandler) RegisterRoutes(router *mux.Router) {
 	for _, file := range staticRootFiles {
 		router.Path("/" + file).Handler(fileServer)
 	}
+	// the API routes of the handler are protected like the other ones
+	var uiConfigHandler http.Handler = http.HandlerFunc(sH.getUIConfig)
+	if sH.options.Tenancy.Enabled {
+		uiConfigHandler = sH.options.Tenancy.HTTPHandler(uiConfigHandler)
+	}
+	router.Path("/" + queryservice.DefaultAPIPrefix + "/ui-config").Handler(uiConfigHandler).Methods(http.MethodGet)
 	router.NotFoundHandler = http.HandlerFunc(sH.notFound)
 }
 
 func (sH *StaticAssetsHandler) notFound(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.Write(sH.indexHTML.Load().([]byte))
+	if sH.overrides == nil {
+		w.Write(sH.indexHTML.Load().([]byte))
+		return
+	}
+	// the navigations only carry a tenant if a proxy sets it, the default config is used otherwise
+	ctx := r.Context()
+	if tenant, err := sH.options.Tenancy.GetValidHTTPTenant(r); err == nil && tenant != "" {
+		ctx = tenancy.WithTenant(ctx, tenant)
+	}
+	config := sH.overrides.Apply(ctx, sH.uiConfig.Load().(map[string]interface{}))
+	w.Write(renderIndex(sH.indexTemplate.Load().([]byte), config))
+}
+
+// getUIConfig implements the REST API /ui-config, returning the UI config of the caller,
+// or an empty object when the UI uses its default config.
+func (sH *StaticAssetsHandler) getUIConfig(w http.ResponseWriter, r *http.Request) {
+	config := sH.uiConfig.Load().(map[string]interface{})
+	if sH.overrides != nil {
+		config = sH.overrides.Apply(r.Context(), config)
+	}
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(config)
 }
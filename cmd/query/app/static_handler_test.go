@@ -59,7 +59,7 @@ func TestRegisterStaticHandler(t *testing.T) {
 		{basePath: "/", baseURL: "/", expectedBaseHTML: `<base href="/"`},
 		{basePath: "/jaeger", baseURL: "/jaeger/", expectedBaseHTML: `<base href="/jaeger/"`, subroute: true},
 	}
-	httpClient = &http.Client{
+	httpClient := &http.Client{
 		Timeout: 2 * time.Second,
 	}
 	for _, testCase := range testCases {
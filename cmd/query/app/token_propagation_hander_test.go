@@ -76,7 +76,7 @@ func Test_bearTokenPropagationHandler(t *testing.T) {
 			if testCase.sendHeader {
 				req.Header.Add(testCase.headerName, testCase.headerValue)
 			}
-			_, err = httpClient.Do(req)
+			_, err = http.DefaultClient.Do(req)
 			assert.Nil(t, err)
 			stop.Wait()
 		})
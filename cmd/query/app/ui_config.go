@@ -0,0 +1,121 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/jaegertracing/jaeger/pkg/auth"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
+)
+
+var errEmptyOverride = errors.New("a UI configuration override must list tenants, subjects or groups")
+
+// UIConfigOverrides is the content of the UI configuration overrides file, so that a query
+// service can serve the UI of several organizations.
+type UIConfigOverrides struct {
+	Overrides []UIConfigOverride `json:"overrides"`
+}
+
+// UIConfigOverride changes the UI configuration of the callers of one of the tenants, or with one of
+// the subjects or in one of the groups. Its config is merged into the UI configuration, the objects
+// being merged recursively and the other values replaced.
+type UIConfigOverride struct {
+	Tenants  []string               `json:"tenants"`
+	Subjects []string               `json:"subjects"`
+	Groups   []string               `json:"groups"`
+	Config   map[string]interface{} `json:"config"`
+}
+
+// LoadUIConfigOverrides reads the overrides from a JSON file.
+func LoadUIConfigOverrides(path string) (*UIConfigOverrides, error) {
+	data, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the UI config overrides file %v: %w", path, err)
+	}
+	var overrides UIConfigOverrides
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("cannot parse the UI config overrides file %v: %w", path, err)
+	}
+	for _, override := range overrides.Overrides {
+		if len(override.Tenants) == 0 && len(override.Subjects) == 0 && len(override.Groups) == 0 {
+			return nil, errEmptyOverride
+		}
+	}
+	return &overrides, nil
+}
+
+// Apply returns the UI configuration of the caller found in the context, the overrides matching
+// it being merged in their order. The configuration itself is not modified.
+func (o *UIConfigOverrides) Apply(ctx context.Context, config map[string]interface{}) map[string]interface{} {
+	tenant := tenancy.GetTenant(ctx)
+	identity := auth.IdentityFromContext(ctx)
+	for _, override := range o.Overrides {
+		if override.matches(tenant, identity) {
+			config = mergeUIConfig(config, override.Config)
+		}
+	}
+	return config
+}
+
+func (o UIConfigOverride) matches(tenant string, identity *auth.Identity) bool {
+	if tenant != "" && contains(o.Tenants, tenant) {
+		return true
+	}
+	if identity == nil {
+		return false
+	}
+	if contains(o.Subjects, identity.Subject) {
+		return true
+	}
+	for _, group := range identity.Groups {
+		if contains(o.Groups, group) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeUIConfig returns a copy of the base configuration with the values of the override.
+func mergeUIConfig(base, override map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(override))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range override {
+		baseObject, baseIsObject := merged[key].(map[string]interface{})
+		object, isObject := value.(map[string]interface{})
+		if baseIsObject && isObject {
+			merged[key] = mergeUIConfig(baseObject, object)
+		} else {
+			merged[key] = value
+		}
+	}
+	return merged
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/pkg/auth"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
+)
+
+func TestLoadUIConfigOverrides(t *testing.T) {
+	overrides, err := LoadUIConfigOverrides("fixture/ui-config-overrides.json")
+	require.NoError(t, err)
+	assert.Len(t, overrides.Overrides, 2)
+
+	_, err = LoadUIConfigOverrides("fixture/ui-config-overrides-empty.json")
+	assert.Equal(t, errEmptyOverride, err)
+	_, err = LoadUIConfigOverrides("fixture/ui-config-malformed.json")
+	assert.Error(t, err)
+	_, err = LoadUIConfigOverrides("fixture/does-not-exist.json")
+	assert.Error(t, err)
+}
+
+func TestApplyUIConfigOverrides(t *testing.T) {
+	overrides, err := LoadUIConfigOverrides("fixture/ui-config-overrides.json")
+	require.NoError(t, err)
+	base := map[string]interface{}{
+		"dependencies": map[string]interface{}{"menuEnabled": true, "dagMaxNumServices": 100.0},
+		"tracking":     map[string]interface{}{"gaID": "UA-1"},
+	}
+	acme := tenancy.WithTenant(context.Background(), "acme")
+	sre := auth.ContextWithIdentity(context.Background(), &auth.Identity{Subject: "bob", Groups: []string{"dev", "sre"}})
+
+	assert.Equal(t, base, overrides.Apply(context.Background(), base))
+	assert.Equal(t, map[string]interface{}{
+		"dependencies": map[string]interface{}{"menuEnabled": false, "dagMaxNumServices": 100.0},
+		"tracking":     map[string]interface{}{"gaID": "UA-1"},
+		"menu":         []interface{}{map[string]interface{}{"label": "Acme", "url": "https://acme.example.com"}},
+	}, overrides.Apply(acme, base))
+	assert.Equal(t, true, overrides.Apply(sre, base)["archiveEnabled"])
+	assert.Equal(t, true, overrides.Apply(sre, nil)["archiveEnabled"])
+	// the base config is not modified
+	assert.Equal(t, true, base["dependencies"].(map[string]interface{})["menuEnabled"])
+	assert.NotContains(t, base, "archiveEnabled")
+}
+
+func TestUIConfigEndpoint(t *testing.T) {
+	handler, err := NewStaticAssetsHandler("fixture", StaticAssetsHandlerOptions{
+		UIConfigPath:          "fixture/ui-config-menu.json",
+		UIConfigOverridesPath: "fixture/ui-config-overrides.json",
+		Tenancy:               tenancy.NewManager(&tenancy.Options{Enabled: true}),
+	})
+	require.NoError(t, err)
+	r := mux.NewRouter()
+	handler.RegisterRoutes(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	get := func(path string, tenant string) (int, string) {
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		require.NoError(t, err)
+		if tenant != "" {
+			req.Header.Set(tenancy.DefaultHeader, tenant)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	code, body := get("/api/ui-config", "acme")
+	require.Equal(t, http.StatusOK, code)
+	var config map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(body), &config))
+	assert.Equal(t, []interface{}{map[string]interface{}{"label": "Acme", "url": "https://acme.example.com"}}, config["menu"])
+
+	code, body = get("/api/ui-config", "initech")
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "GitHub")
+	code, _ = get("/api/ui-config", "")
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// the index is served without a tenant, with the default config
+	_, body = get("/search", "acme")
+	assert.Contains(t, body, `"label":"Acme"`)
+	_, body = get("/search", "")
+	assert.Contains(t, body, `"label":"GitHub"`)
+}
+
+func TestUIConfigEndpointDefaultConfig(t *testing.T) {
+	handler, err := NewStaticAssetsHandler("fixture", StaticAssetsHandlerOptions{})
+	require.NoError(t, err)
+	r := mux.NewRouter()
+	handler.RegisterRoutes(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/ui-config")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "{}\n", string(body))
+}
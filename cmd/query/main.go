@@ -33,11 +33,14 @@ import (
 	"github.com/jaegertracing/jaeger/cmd/env"
 	"github.com/jaegertracing/jaeger/cmd/flags"
 	"github.com/jaegertracing/jaeger/cmd/query/app"
-	"github.com/jaegertracing/jaeger/cmd/query/app/querysvc"
 	"github.com/jaegertracing/jaeger/pkg/config"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
 	"github.com/jaegertracing/jaeger/pkg/version"
+	metricsPlugin "github.com/jaegertracing/jaeger/plugin/metrics"
 	"github.com/jaegertracing/jaeger/plugin/storage"
 	"github.com/jaegertracing/jaeger/ports"
+	"github.com/jaegertracing/jaeger/storage/readcache"
 	"github.com/jaegertracing/jaeger/storage/spanstore"
 	storageMetrics "github.com/jaegertracing/jaeger/storage/spanstore/metrics"
 )
@@ -49,6 +52,10 @@ func main() {
 	if err != nil {
 		log.Fatalf("Cannot initialize storage factory: %v", err)
 	}
+	metricsReaderFactory, err := metricsPlugin.NewFactory(metricsPlugin.FactoryConfigFromEnv())
+	if err != nil {
+		log.Fatalf("Cannot initialize metrics storage factory: %v", err)
+	}
 
 	v := viper.New()
 	var command = &cobra.Command{
@@ -96,17 +103,39 @@ func main() {
 				logger.Fatal("Failed to create span reader", zap.Error(err))
 			}
 			spanReader = storageMetrics.NewReadMetricsDecorator(spanReader, metricsFactory)
+			spanReader = readcache.NewSpanReader(spanReader, queryOpts.ReadCache, metricsFactory)
 			dependencyReader, err := storageFactory.CreateDependencyReader()
 			if err != nil {
 				logger.Fatal("Failed to create dependency reader", zap.Error(err))
 			}
+			dependencyReader = readcache.NewDependencyReader(dependencyReader, queryOpts.ReadCache, metricsFactory)
 			queryServiceOptions := queryOpts.BuildQueryServiceOptions(storageFactory, logger)
+			if queryServiceOptions.Authorizer, err = queryOpts.BuildAuthorizer(); err != nil {
+				logger.Fatal("Failed to load the authorization rules", zap.Error(err))
+			}
+			if queryServiceOptions.Auditor, err = queryOpts.BuildAuditor(); err != nil {
+				logger.Fatal("Failed to open the audit log", zap.Error(err))
+			}
 			queryService := querysvc.NewQueryService(
 				spanReader,
 				dependencyReader,
 				*queryServiceOptions)
+			archiver, err := queryOpts.BuildAutoArchiver(spanReader, queryServiceOptions, logger, metricsFactory)
+			if err != nil {
+				logger.Fatal("Failed to load the automatic archiving rules", zap.Error(err))
+			}
 
-			server, err := app.NewServer(svc.Logger, queryService, queryOpts, tracer)
+			metricsReaderFactory.InitFromViper(v)
+			if err := metricsReaderFactory.Initialize(logger); err != nil {
+				logger.Fatal("Failed to init metrics storage factory", zap.Error(err))
+			}
+			metricsReader, err := metricsReaderFactory.CreateMetricsReader()
+			if err != nil {
+				logger.Fatal("Failed to create metrics reader", zap.Error(err))
+			}
+
+			metricsQueryService := querysvc.NewMetricsQueryService(metricsReader, queryService)
+			server, err := app.NewServer(svc.Logger, queryService, metricsQueryService, queryOpts, metricsFactory, tracer)
 			if err != nil {
 				logger.Fatal("Failed to create server", zap.Error(err))
 			}
@@ -121,8 +150,15 @@ func main() {
 				logger.Fatal("Could not start servers", zap.Error(err))
 			}
 
+			if archiver != nil {
+				archiver.Start()
+			}
+
 			svc.RunAndThen(func() {
 				server.Close()
+				if archiver != nil {
+					archiver.Close()
+				}
 			})
 			return nil
 		},
@@ -137,7 +173,10 @@ func main() {
 		command,
 		svc.AddFlags,
 		storageFactory.AddFlags,
+		metricsReaderFactory.AddFlags,
 		app.AddFlags,
+		// registered with the collector flags in the all-in-one
+		tenancy.AddFlags,
 	)
 
 	if error := command.Execute(); error != nil {
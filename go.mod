@@ -3,14 +3,21 @@ module github.com/jaegertracing/jaeger
 go 1.13
 
 require (
+	cloud.google.com/go/bigtable v1.3.0
+	cloud.google.com/go/pubsub v1.2.0
+	cloud.google.com/go/storage v1.5.0
 	github.com/AndreasBriese/bbloom v0.0.0-20190825152654-46b345b51c96 // indirect
+	github.com/DATA-DOG/go-sqlmock v1.5.0
 	github.com/DataDog/zstd v1.4.4 // indirect
 	github.com/Shopify/sarama v1.22.2-0.20190604114437-cd910a683f9f
 	github.com/VividCortex/gohistogram v1.0.0 // indirect
+	github.com/alicebob/miniredis/v2 v2.11.4
 	github.com/apache/thrift v0.13.0
 	github.com/asaskevich/govalidator v0.0.0-20200108200545-475eaeb16496 // indirect
+	github.com/aws/aws-sdk-go v1.35.0
 	github.com/bsm/sarama-cluster v2.1.13+incompatible
 	github.com/codahale/hdrhistogram v0.0.0-20161010025455-3a0bb77429bd // indirect
+	github.com/coreos/go-oidc v2.2.1+incompatible
 	github.com/cpuguy83/go-md2man v1.0.10 // indirect
 	github.com/crossdock/crossdock-go v0.0.0-20160816171116-049aabb0122b
 	github.com/dgraph-io/badger v1.5.3
@@ -27,10 +34,12 @@ require (
 	github.com/go-openapi/strfmt v0.19.4
 	github.com/go-openapi/swag v0.19.7
 	github.com/go-openapi/validate v0.19.6
+	github.com/go-redis/redis/v7 v7.2.0
 	github.com/gocql/gocql v0.0.0-20200228163523-cd4b606dd2fb
 	github.com/gogo/googleapis v1.0.1-0.20180501115203-b23578765ee5
 	github.com/gogo/protobuf v1.2.1
 	github.com/golang/protobuf v1.3.4
+	github.com/golang/snappy v0.0.1
 	github.com/gorilla/handlers v1.4.2
 	github.com/gorilla/mux v1.7.4
 	github.com/grpc-ecosystem/go-grpc-middleware v1.2.0
@@ -40,8 +49,10 @@ require (
 	github.com/hashicorp/go-uuid v1.0.2 // indirect
 	github.com/hashicorp/yamux v0.0.0-20190923154419-df201c70410d // indirect
 	github.com/jcmturner/gofork v1.0.0 // indirect
+	github.com/klauspost/compress v1.9.7
 	github.com/kr/pretty v0.2.0
 	github.com/kr/text v0.2.0 // indirect
+	github.com/lib/pq v1.10.9
 	github.com/mailru/easyjson v0.7.1 // indirect
 	github.com/mitchellh/go-testing-interface v1.0.0 // indirect
 	github.com/mjibson/esc v0.2.0
@@ -53,6 +64,7 @@ require (
 	github.com/pelletier/go-toml v1.6.0 // indirect
 	github.com/pierrec/lz4 v2.4.1+incompatible // indirect
 	github.com/pkg/errors v0.9.1 // indirect
+	github.com/pquerna/cachecontrol v0.0.0-20180517163645-1555304b9b35 // indirect
 	github.com/prometheus/client_golang v1.1.0
 	github.com/prometheus/common v0.9.1 // indirect
 	github.com/prometheus/procfs v0.0.10 // indirect
@@ -72,7 +84,9 @@ require (
 	github.com/uber/jaeger-lib v2.2.0+incompatible
 	github.com/vektra/mockery v0.0.0-20181123154057-e78b021dcbb5
 	github.com/wadey/gocovmerge v0.0.0-20160331181800-b5bfa59ec0ad
-	go.mongodb.org/mongo-driver v1.3.0 // indirect
+	github.com/xitongsys/parquet-go v1.5.2
+	github.com/xitongsys/parquet-go-source v0.0.0-20190524061010-2b72cbee77d5
+	go.mongodb.org/mongo-driver v1.3.0
 	go.uber.org/atomic v1.5.1
 	go.uber.org/automaxprocs v1.3.0
 	go.uber.org/multierr v1.4.0 // indirect
@@ -82,11 +96,13 @@ require (
 	golang.org/x/net v0.0.0-20200202094626-16171245cfb2
 	golang.org/x/sys v0.0.0-20200217220822-9197077df867
 	golang.org/x/tools v0.0.0-20200227222343-706bc42d1f0d // indirect
+	google.golang.org/api v0.17.0
 	google.golang.org/genproto v0.0.0-20200218151345-dad8c97a84f5 // indirect
 	google.golang.org/grpc v1.27.1
 	gopkg.in/ini.v1 v1.52.0 // indirect
 	gopkg.in/jcmturner/goidentity.v3 v3.0.0 // indirect
 	gopkg.in/jcmturner/gokrb5.v7 v7.5.0 // indirect
+	gopkg.in/square/go-jose.v2 v2.5.1
 	gopkg.in/yaml.v2 v2.2.8
 	honnef.co/go/tools v0.0.1-2019.2.3
 )
@@ -0,0 +1,185 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package json
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/jaegertracing/jaeger/model"
+	ui "github.com/jaegertracing/jaeger/model/json"
+)
+
+// TraceToDomain converts a json.Trace, e.g. downloaded from the query service, back into model.Trace.
+// The spans either reference a process of the trace or embed their own. The tag values can be
+// decoded with or without json.Decoder.UseNumber, or be the strings produced by FromDomainEmbedProcess.
+func TraceToDomain(trace *ui.Trace) (*model.Trace, error) {
+	processes := make(map[ui.ProcessID]*model.Process, len(trace.Processes))
+	for id, process := range trace.Processes {
+		p, err := processToDomain(process)
+		if err != nil {
+			return nil, fmt.Errorf("invalid process %q: %w", id, err)
+		}
+		processes[id] = p
+	}
+	mTrace := &model.Trace{
+		Spans:    make([]*model.Span, 0, len(trace.Spans)),
+		Warnings: trace.Warnings,
+	}
+	for i := range trace.Spans {
+		span, err := spanToDomain(&trace.Spans[i], processes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid span %q: %w", trace.Spans[i].SpanID, err)
+		}
+		mTrace.Spans = append(mTrace.Spans, span)
+	}
+	return mTrace, nil
+}
+
+func spanToDomain(span *ui.Span, processes map[ui.ProcessID]*model.Process) (*model.Span, error) {
+	traceID, err := model.TraceIDFromString(string(span.TraceID))
+	if err != nil {
+		return nil, err
+	}
+	spanID, err := model.SpanIDFromString(string(span.SpanID))
+	if err != nil {
+		return nil, err
+	}
+	mSpan := &model.Span{
+		TraceID:       traceID,
+		SpanID:        spanID,
+		OperationName: span.OperationName,
+		Flags:         model.Flags(span.Flags),
+		StartTime:     model.EpochMicrosecondsAsTime(span.StartTime),
+		Duration:      model.MicrosecondsAsDuration(span.Duration),
+		Warnings:      span.Warnings,
+	}
+	for _, ref := range span.References {
+		refTraceID, err := model.TraceIDFromString(string(ref.TraceID))
+		if err != nil {
+			return nil, err
+		}
+		refSpanID, err := model.SpanIDFromString(string(ref.SpanID))
+		if err != nil {
+			return nil, err
+		}
+		refType := model.ChildOf
+		if ref.RefType == ui.FollowsFrom {
+			refType = model.FollowsFrom
+		}
+		mSpan.References = append(mSpan.References, model.SpanRef{TraceID: refTraceID, SpanID: refSpanID, RefType: refType})
+	}
+	if len(mSpan.References) == 0 && span.ParentSpanID != "" {
+		parentID, err := model.SpanIDFromString(string(span.ParentSpanID))
+		if err != nil {
+			return nil, err
+		}
+		mSpan.References = []model.SpanRef{model.NewChildOfRef(traceID, parentID)}
+	}
+	if mSpan.Tags, err = keyValuesToDomain(span.Tags); err != nil {
+		return nil, err
+	}
+	for _, log := range span.Logs {
+		fields, err := keyValuesToDomain(log.Fields)
+		if err != nil {
+			return nil, err
+		}
+		mSpan.Logs = append(mSpan.Logs, model.Log{Timestamp: model.EpochMicrosecondsAsTime(log.Timestamp), Fields: fields})
+	}
+	switch {
+	case span.Process != nil:
+		if mSpan.Process, err = processToDomain(*span.Process); err != nil {
+			return nil, err
+		}
+	case processes[span.ProcessID] != nil:
+		mSpan.Process = processes[span.ProcessID]
+	default:
+		return nil, fmt.Errorf("unknown process %q", span.ProcessID)
+	}
+	return mSpan, nil
+}
+
+func processToDomain(process ui.Process) (*model.Process, error) {
+	tags, err := keyValuesToDomain(process.Tags)
+	if err != nil {
+		return nil, err
+	}
+	return model.NewProcess(process.ServiceName, tags), nil
+}
+
+func keyValuesToDomain(kvs []ui.KeyValue) ([]model.KeyValue, error) {
+	if len(kvs) == 0 {
+		return nil, nil
+	}
+	out := make([]model.KeyValue, 0, len(kvs))
+	for _, kv := range kvs {
+		mKV, err := keyValueToDomain(kv)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of tag %q: %w", kv.Key, err)
+		}
+		out = append(out, mKV)
+	}
+	return out, nil
+}
+
+func keyValueToDomain(kv ui.KeyValue) (model.KeyValue, error) {
+	switch kv.Type {
+	case ui.StringType, "":
+		if s, ok := kv.Value.(string); ok {
+			return model.String(kv.Key, s), nil
+		}
+		return model.String(kv.Key, fmt.Sprint(kv.Value)), nil
+	case ui.BoolType:
+		switch v := kv.Value.(type) {
+		case bool:
+			return model.Bool(kv.Key, v), nil
+		case string:
+			b, err := strconv.ParseBool(v)
+			return model.Bool(kv.Key, b), err
+		}
+	case ui.Int64Type:
+		switch v := kv.Value.(type) {
+		case json.Number:
+			i, err := v.Int64()
+			return model.Int64(kv.Key, i), err
+		case float64:
+			return model.Int64(kv.Key, int64(v)), nil
+		case string:
+			i, err := strconv.ParseInt(v, 10, 64)
+			return model.Int64(kv.Key, i), err
+		}
+	case ui.Float64Type:
+		switch v := kv.Value.(type) {
+		case json.Number:
+			f, err := v.Float64()
+			return model.Float64(kv.Key, f), err
+		case float64:
+			return model.Float64(kv.Key, v), nil
+		case string:
+			f, err := strconv.ParseFloat(v, 64)
+			return model.Float64(kv.Key, f), err
+		}
+	case ui.BinaryType:
+		if s, ok := kv.Value.(string); ok {
+			b, err := base64.StdEncoding.DecodeString(s)
+			return model.Binary(kv.Key, b), err
+		}
+	default:
+		return model.KeyValue{}, fmt.Errorf("unknown type %q", kv.Type)
+	}
+	return model.KeyValue{}, fmt.Errorf("unexpected %T value of type %q", kv.Value, kv.Type)
+}
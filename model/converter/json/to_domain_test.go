@@ -0,0 +1,153 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	jModel "github.com/jaegertracing/jaeger/model/json"
+)
+
+func TestTraceToDomain(t *testing.T) {
+	for i := 1; i <= NumberOfFixtures; i++ {
+		domainStr, jsonStr := loadFixturesUI(t, i)
+		var expected model.Trace
+		require.NoError(t, jsonpb.Unmarshal(bytes.NewReader(domainStr), &expected))
+		expected.NormalizeTimestamps()
+		// the JSON model stores the timestamps in microseconds
+		for _, span := range expected.Spans {
+			span.StartTime = span.StartTime.Truncate(time.Microsecond)
+			for j := range span.Logs {
+				span.Logs[j].Timestamp = span.Logs[j].Timestamp.Truncate(time.Microsecond)
+			}
+		}
+
+		for _, useNumber := range []bool{false, true} {
+			decoder := json.NewDecoder(bytes.NewReader(jsonStr))
+			if useNumber {
+				decoder.UseNumber()
+			}
+			var uiTrace jModel.Trace
+			require.NoError(t, decoder.Decode(&uiTrace))
+			trace, err := TraceToDomain(&uiTrace)
+			require.NoError(t, err)
+			trace.NormalizeTimestamps()
+			assert.Equal(t, expected.Spans, trace.Spans, "fixture %d, UseNumber %v", i, useNumber)
+		}
+	}
+}
+
+func TestTraceToDomainEmbeddedProcess(t *testing.T) {
+	for i := 1; i <= NumberOfFixtures; i++ {
+		domainStr, jsonStr := loadFixturesES(t, i)
+		var expected model.Span
+		require.NoError(t, jsonpb.Unmarshal(bytes.NewReader(domainStr), &expected))
+		var uiSpan jModel.Span
+		require.NoError(t, json.Unmarshal(jsonStr, &uiSpan))
+
+		trace, err := TraceToDomain(&jModel.Trace{Spans: []jModel.Span{uiSpan}})
+		require.NoError(t, err)
+		require.Len(t, trace.Spans, 1)
+		trace.NormalizeTimestamps()
+		expected.NormalizeTimestamps()
+		span := trace.Spans[0]
+		assert.Equal(t, expected.TraceID, span.TraceID)
+		assert.Equal(t, expected.Process.ServiceName, span.Process.ServiceName)
+		assert.Equal(t, len(expected.Tags), len(span.Tags))
+		// the tag values were converted to strings, except the binary ones they are restored
+		for j := range expected.Tags {
+			if expected.Tags[j].VType != model.BinaryType {
+				assert.Equal(t, expected.Tags[j], span.Tags[j])
+			}
+		}
+	}
+}
+
+func TestTraceToDomainParentSpanID(t *testing.T) {
+	trace, err := TraceToDomain(&jModel.Trace{
+		Spans: []jModel.Span{{TraceID: "1", SpanID: "2", ParentSpanID: "3", ProcessID: "p1"}},
+		Processes: map[jModel.ProcessID]jModel.Process{
+			"p1": {ServiceName: "frontend"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, model.NewSpanID(3), trace.Spans[0].ParentSpanID())
+	assert.Equal(t, "frontend", trace.Spans[0].Process.ServiceName)
+}
+
+func TestTraceToDomainErrors(t *testing.T) {
+	process := map[jModel.ProcessID]jModel.Process{"p1": {ServiceName: "frontend"}}
+	testCases := []struct {
+		trace jModel.Trace
+		err   string
+	}{
+		{
+			trace: jModel.Trace{Spans: []jModel.Span{{TraceID: "x", SpanID: "2"}}},
+			err:   `invalid span "2": strconv.ParseUint: parsing "x": invalid syntax`,
+		},
+		{
+			trace: jModel.Trace{Spans: []jModel.Span{{TraceID: "1", SpanID: "x"}}},
+			err:   `invalid span "x": strconv.ParseUint: parsing "x": invalid syntax`,
+		},
+		{
+			trace: jModel.Trace{Spans: []jModel.Span{{TraceID: "1", SpanID: "2", ProcessID: "p2"}}, Processes: process},
+			err:   `invalid span "2": unknown process "p2"`,
+		},
+		{
+			trace: jModel.Trace{Spans: []jModel.Span{{TraceID: "1", SpanID: "2", ProcessID: "p1",
+				References: []jModel.Reference{{TraceID: "1", SpanID: "x"}}}}, Processes: process},
+			err: `invalid span "2": strconv.ParseUint: parsing "x": invalid syntax`,
+		},
+		{
+			trace: jModel.Trace{Spans: []jModel.Span{{TraceID: "1", SpanID: "2", ProcessID: "p1",
+				Tags: []jModel.KeyValue{{Key: "k", Type: jModel.Int64Type, Value: true}}}}, Processes: process},
+			err: `invalid span "2": invalid value of tag "k": unexpected bool value of type "int64"`,
+		},
+		{
+			trace: jModel.Trace{Processes: map[jModel.ProcessID]jModel.Process{
+				"p1": {Tags: []jModel.KeyValue{{Key: "k", Type: "foo"}}},
+			}},
+			err: `invalid process "p1": invalid value of tag "k": unknown type "foo"`,
+		},
+	}
+	for _, tc := range testCases {
+		_, err := TraceToDomain(&tc.trace)
+		assert.EqualError(t, err, tc.err)
+	}
+}
+
+func TestKeyValueToDomainFromStrings(t *testing.T) {
+	kvs, err := keyValuesToDomain([]jModel.KeyValue{
+		{Key: "b", Type: jModel.BoolType, Value: "true"},
+		{Key: "i", Type: jModel.Int64Type, Value: "42"},
+		{Key: "f", Type: jModel.Float64Type, Value: "0.5"},
+		{Key: "s", Value: 12.0},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []model.KeyValue{
+		model.Bool("b", true),
+		model.Int64("i", 42),
+		model.Float64("f", 0.5),
+		model.String("s", "12"),
+	}, kvs)
+}
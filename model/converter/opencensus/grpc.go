@@ -0,0 +1,152 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opencensus
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// TraceServiceServer is the server API of the OpenCensus agent trace service.
+type TraceServiceServer interface {
+	Config(TraceServiceConfigServer) error
+	Export(TraceServiceExportServer) error
+}
+
+// TraceServiceConfigServer is the server side of the Config stream.
+type TraceServiceConfigServer interface {
+	Send(*UpdatedLibraryConfig) error
+	Recv() (*CurrentLibraryConfig, error)
+	grpc.ServerStream
+}
+
+// TraceServiceExportServer is the server side of the Export stream.
+type TraceServiceExportServer interface {
+	Send(*ExportTraceServiceResponse) error
+	Recv() (*ExportTraceServiceRequest, error)
+	grpc.ServerStream
+}
+
+// RegisterTraceServiceServer registers the OpenCensus agent trace service with a gRPC server.
+func RegisterTraceServiceServer(s *grpc.Server, srv TraceServiceServer) {
+	s.RegisterService(&traceServiceDesc, srv)
+}
+
+type configServer struct {
+	grpc.ServerStream
+}
+
+func (x *configServer) Send(m *UpdatedLibraryConfig) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *configServer) Recv() (*CurrentLibraryConfig, error) {
+	m := new(CurrentLibraryConfig)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+type exportServer struct {
+	grpc.ServerStream
+}
+
+func (x *exportServer) Send(m *ExportTraceServiceResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *exportServer) Recv() (*ExportTraceServiceRequest, error) {
+	m := new(ExportTraceServiceRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func configHandler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(TraceServiceServer).Config(&configServer{stream})
+}
+
+func exportHandler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(TraceServiceServer).Export(&exportServer{stream})
+}
+
+var traceServiceDesc = grpc.ServiceDesc{
+	ServiceName: "opencensus.proto.agent.trace.v1.TraceService",
+	HandlerType: (*TraceServiceServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Config",
+			Handler:       configHandler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Export",
+			Handler:       exportHandler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "opencensus/proto/agent/trace/v1/trace_service.proto",
+}
+
+// TraceServiceClient is the client API of the OpenCensus agent trace service, limited to the Export stream.
+type TraceServiceClient interface {
+	Export(ctx context.Context, opts ...grpc.CallOption) (TraceServiceExportClient, error)
+}
+
+// TraceServiceExportClient is the client side of the Export stream.
+type TraceServiceExportClient interface {
+	Send(*ExportTraceServiceRequest) error
+	Recv() (*ExportTraceServiceResponse, error)
+	grpc.ClientStream
+}
+
+type traceServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewTraceServiceClient creates a client of the OpenCensus agent trace service.
+func NewTraceServiceClient(cc *grpc.ClientConn) TraceServiceClient {
+	return &traceServiceClient{cc}
+}
+
+func (c *traceServiceClient) Export(ctx context.Context, opts ...grpc.CallOption) (TraceServiceExportClient, error) {
+	stream, err := c.cc.NewStream(ctx, &traceServiceDesc.Streams[1], "/opencensus.proto.agent.trace.v1.TraceService/Export", opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &exportClient{stream}, nil
+}
+
+type exportClient struct {
+	grpc.ClientStream
+}
+
+func (x *exportClient) Send(m *ExportTraceServiceRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *exportClient) Recv() (*ExportTraceServiceResponse, error) {
+	m := new(ExportTraceServiceResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,320 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opencensus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+const (
+	spanKindTag           = "span.kind"
+	errorTag              = "error"
+	statusCodeTag         = "status.code"
+	statusMessageTag      = "status.message"
+	hostnameTag           = "hostname"
+	pidTag                = "pid"
+	startTimeTag          = "opencensus.start_time"
+	languageTag           = "opencensus.language"
+	exporterVersionTag    = "opencensus.exporter_version"
+	coreLibraryVersionTag = "opencensus.core_library_version"
+	resourceTypeTag       = "opencensus.resource_type"
+	messageField          = "message"
+	messageTypeField      = "message.type"
+	messageIDField        = "message.id"
+	uncompressedField     = "message.uncompressed_size"
+	compressedField       = "message.compressed_size"
+
+	// defaultServiceName is reported when the node does not carry a service name
+	defaultServiceName = "unknown_service"
+)
+
+var languages = map[LibraryLanguage]string{
+	1:  "cpp",
+	2:  "c_sharp",
+	3:  "erlang",
+	4:  "go",
+	5:  "java",
+	6:  "nodejs",
+	7:  "php",
+	8:  "python",
+	9:  "ruby",
+	10: "webjs",
+}
+
+// ToDomain transforms the spans of an Export stream message into Jaeger batches, given the node
+// and resource in effect for the stream. Spans carrying their own resource are put in separate batches.
+func ToDomain(node *Node, resource *Resource, spans []*Span) ([]*model.Batch, error) {
+	process := processToDomain(node, resource)
+	batch := &model.Batch{Process: process}
+	var batches []*model.Batch
+	for _, span := range spans {
+		mSpan, err := spanToDomain(span)
+		if err != nil {
+			return nil, err
+		}
+		if span.Resource != nil {
+			spanProcess := processToDomain(node, span.Resource)
+			mSpan.Process = spanProcess
+			batches = append(batches, &model.Batch{Process: spanProcess, Spans: []*model.Span{mSpan}})
+			continue
+		}
+		mSpan.Process = process
+		batch.Spans = append(batch.Spans, mSpan)
+	}
+	if len(batch.Spans) > 0 {
+		batches = append([]*model.Batch{batch}, batches...)
+	}
+	return batches, nil
+}
+
+func processToDomain(node *Node, resource *Resource) *model.Process {
+	process := &model.Process{ServiceName: defaultServiceName}
+	if node != nil {
+		if node.ServiceInfo != nil && node.ServiceInfo.Name != "" {
+			process.ServiceName = node.ServiceInfo.Name
+		}
+		if id := node.Identifier; id != nil {
+			if id.HostName != "" {
+				process.Tags = append(process.Tags, model.String(hostnameTag, id.HostName))
+			}
+			if id.Pid != 0 {
+				process.Tags = append(process.Tags, model.Int64(pidTag, int64(id.Pid)))
+			}
+			if id.StartTimestamp != nil {
+				process.Tags = append(process.Tags, model.String(startTimeTag, timestampToDomain(id.StartTimestamp).Format(time.RFC3339Nano)))
+			}
+		}
+		if lib := node.LibraryInfo; lib != nil {
+			if lang, ok := languages[lib.Language]; ok {
+				process.Tags = append(process.Tags, model.String(languageTag, lang))
+			}
+			if lib.ExporterVersion != "" {
+				process.Tags = append(process.Tags, model.String(exporterVersionTag, lib.ExporterVersion))
+			}
+			if lib.CoreLibraryVersion != "" {
+				process.Tags = append(process.Tags, model.String(coreLibraryVersionTag, lib.CoreLibraryVersion))
+			}
+		}
+		process.Tags = append(process.Tags, stringMapToDomain(node.Attributes)...)
+	}
+	if resource != nil {
+		if resource.Type != "" {
+			process.Tags = append(process.Tags, model.String(resourceTypeTag, resource.Type))
+		}
+		process.Tags = append(process.Tags, stringMapToDomain(resource.Labels)...)
+	}
+	return process
+}
+
+func spanToDomain(span *Span) (*model.Span, error) {
+	traceID, err := traceIDToDomain(span.TraceID)
+	if err != nil {
+		return nil, err
+	}
+	spanID, err := spanIDToDomain(span.SpanID)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []model.SpanRef
+	if len(span.ParentSpanID) > 0 {
+		parentID, err := spanIDToDomain(span.ParentSpanID)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, model.NewChildOfRef(traceID, parentID))
+	}
+	if span.Links != nil {
+		for _, link := range span.Links.Link {
+			linkTraceID, err := traceIDToDomain(link.TraceID)
+			if err != nil {
+				return nil, err
+			}
+			linkSpanID, err := spanIDToDomain(link.SpanID)
+			if err != nil {
+				return nil, err
+			}
+			if link.Type == LinkTypeParent {
+				refs = append(refs, model.NewChildOfRef(linkTraceID, linkSpanID))
+			} else {
+				refs = append(refs, model.NewFollowsFromRef(linkTraceID, linkSpanID))
+			}
+		}
+	}
+
+	startTime := timestampToDomain(span.StartTime)
+	var duration time.Duration
+	if endTime := timestampToDomain(span.EndTime); endTime.After(startTime) {
+		duration = endTime.Sub(startTime)
+	}
+
+	var flags model.Flags
+	flags.SetSampled()
+
+	return &model.Span{
+		TraceID:       traceID,
+		SpanID:        spanID,
+		OperationName: truncatableStringToDomain(span.Name),
+		References:    refs,
+		Flags:         flags,
+		StartTime:     startTime,
+		Duration:      duration,
+		Tags:          spanTagsToDomain(span),
+		Logs:          timeEventsToDomain(span.TimeEvents),
+	}, nil
+}
+
+func spanTagsToDomain(span *Span) []model.KeyValue {
+	tags := attributesToDomain(span.Attributes)
+	switch span.Kind {
+	case SpanKindServer:
+		tags = append(tags, model.String(spanKindTag, "server"))
+	case SpanKindClient:
+		tags = append(tags, model.String(spanKindTag, "client"))
+	}
+	if span.Status != nil {
+		if span.Status.Code != 0 {
+			tags = append(tags, model.Bool(errorTag, true), model.Int64(statusCodeTag, int64(span.Status.Code)))
+		}
+		if span.Status.Message != "" {
+			tags = append(tags, model.String(statusMessageTag, span.Status.Message))
+		}
+	}
+	return tags
+}
+
+func timeEventsToDomain(events *TimeEvents) []model.Log {
+	if events == nil || len(events.TimeEvent) == 0 {
+		return nil
+	}
+	logs := make([]model.Log, 0, len(events.TimeEvent))
+	for _, event := range events.TimeEvent {
+		var fields []model.KeyValue
+		switch {
+		case event.Annotation != nil:
+			if desc := truncatableStringToDomain(event.Annotation.Description); desc != "" {
+				fields = append(fields, model.String(messageField, desc))
+			}
+			fields = append(fields, attributesToDomain(event.Annotation.Attributes)...)
+		case event.MessageEvent != nil:
+			fields = append(fields,
+				model.String(messageTypeField, messageEventTypeToDomain(event.MessageEvent.Type)),
+				model.Int64(messageIDField, int64(event.MessageEvent.ID)),
+				model.Int64(uncompressedField, int64(event.MessageEvent.UncompressedSize)),
+				model.Int64(compressedField, int64(event.MessageEvent.CompressedSize)),
+			)
+		default:
+			continue
+		}
+		logs = append(logs, model.Log{
+			Timestamp: timestampToDomain(event.Time),
+			Fields:    fields,
+		})
+	}
+	return logs
+}
+
+func messageEventTypeToDomain(t MessageEventType) string {
+	switch t {
+	case MessageEventTypeSent:
+		return "SENT"
+	case MessageEventTypeReceived:
+		return "RECEIVED"
+	default:
+		return "UNSPECIFIED"
+	}
+}
+
+// attributesToDomain converts attributes to tags sorted by key, as the attributes are a map.
+func attributesToDomain(attrs *Attributes) []model.KeyValue {
+	if attrs == nil || len(attrs.AttributeMap) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(attrs.AttributeMap))
+	for k := range attrs.AttributeMap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	tags := make([]model.KeyValue, 0, len(keys))
+	for _, k := range keys {
+		tags = append(tags, attributeValueToDomain(k, attrs.AttributeMap[k]))
+	}
+	return tags
+}
+
+func attributeValueToDomain(key string, v *AttributeValue) model.KeyValue {
+	switch {
+	case v == nil:
+		return model.String(key, "")
+	case v.StringValue != nil:
+		return model.String(key, v.StringValue.Value)
+	case v.IntValue != nil:
+		return model.Int64(key, *v.IntValue)
+	case v.BoolValue != nil:
+		return model.Bool(key, *v.BoolValue)
+	case v.DoubleValue != nil:
+		return model.Float64(key, *v.DoubleValue)
+	default:
+		return model.String(key, "")
+	}
+}
+
+func stringMapToDomain(m map[string]string) []model.KeyValue {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	tags := make([]model.KeyValue, 0, len(keys))
+	for _, k := range keys {
+		tags = append(tags, model.String(k, m[k]))
+	}
+	return tags
+}
+
+func truncatableStringToDomain(s *TruncatableString) string {
+	if s == nil {
+		return ""
+	}
+	return s.Value
+}
+
+func timestampToDomain(ts *timestamp.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return time.Unix(ts.Seconds, int64(ts.Nanos)).UTC()
+}
+
+func traceIDToDomain(id []byte) (model.TraceID, error) {
+	if len(id) != 16 {
+		return model.TraceID{}, fmt.Errorf("invalid trace ID length %d, expecting 16 bytes", len(id))
+	}
+	return model.NewTraceID(binary.BigEndian.Uint64(id[:8]), binary.BigEndian.Uint64(id[8:])), nil
+}
+
+func spanIDToDomain(id []byte) (model.SpanID, error) {
+	if len(id) != 8 {
+		return 0, fmt.Errorf("invalid span ID length %d, expecting 8 bytes", len(id))
+	}
+	return model.NewSpanID(binary.BigEndian.Uint64(id)), nil
+}
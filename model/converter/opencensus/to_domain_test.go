@@ -0,0 +1,198 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opencensus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+var (
+	testTraceID = []byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 2}
+	testSpanID  = []byte{0, 0, 0, 0, 0, 0, 0, 3}
+	testParent  = []byte{0, 0, 0, 0, 0, 0, 0, 4}
+	testStart   = time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+)
+
+func ts(t time.Time) *timestamp.Timestamp {
+	return &timestamp.Timestamp{Seconds: t.Unix(), Nanos: int32(t.Nanosecond())}
+}
+
+func intValue(i int64) *AttributeValue {
+	return &AttributeValue{IntValue: &i}
+}
+
+func testRequest() *ExportTraceServiceRequest {
+	retry := true
+	return &ExportTraceServiceRequest{
+		Node: &Node{
+			Identifier:  &ProcessIdentifier{HostName: "host-1", Pid: 42, StartTimestamp: ts(testStart)},
+			LibraryInfo: &LibraryInfo{Language: 5, ExporterVersion: "0.1.0", CoreLibraryVersion: "0.26.0"},
+			ServiceInfo: &ServiceInfo{Name: "frontend"},
+			Attributes:  map[string]string{"zone": "eu-1"},
+		},
+		Resource: &Resource{Type: "k8s", Labels: map[string]string{"pod": "frontend-1"}},
+		Spans: []*Span{{
+			TraceID:      testTraceID,
+			SpanID:       testSpanID,
+			ParentSpanID: testParent,
+			Name:         &TruncatableString{Value: "GET /"},
+			Kind:         SpanKindServer,
+			StartTime:    ts(testStart),
+			EndTime:      ts(testStart.Add(time.Second)),
+			Attributes: &Attributes{AttributeMap: map[string]*AttributeValue{
+				"http.status_code": intValue(500),
+				"attempt":          intValue(0),
+				"retry":            {BoolValue: &retry},
+				"http.url":         {StringValue: &TruncatableString{Value: "/"}},
+			}},
+			TimeEvents: &TimeEvents{TimeEvent: []*TimeEvent{
+				{
+					Time: ts(testStart.Add(time.Millisecond)),
+					Annotation: &Annotation{
+						Description: &TruncatableString{Value: "cache miss"},
+						Attributes:  &Attributes{AttributeMap: map[string]*AttributeValue{"key": {StringValue: &TruncatableString{Value: "k"}}}},
+					},
+				},
+				{
+					Time:         ts(testStart.Add(2 * time.Millisecond)),
+					MessageEvent: &MessageEvent{Type: MessageEventTypeReceived, ID: 1, UncompressedSize: 10, CompressedSize: 5},
+				},
+			}},
+			Links:  &Links{Link: []*Link{{TraceID: testTraceID, SpanID: testParent, Type: LinkTypeChild}}},
+			Status: &Status{Code: 13, Message: "internal error"},
+		}},
+	}
+}
+
+func TestToDomain(t *testing.T) {
+	// go through the wire format to make sure the hand-written messages are encoded correctly
+	data, err := proto.Marshal(testRequest())
+	require.NoError(t, err)
+	req := &ExportTraceServiceRequest{}
+	require.NoError(t, proto.Unmarshal(data, req))
+
+	batches, err := ToDomain(req.Node, req.Resource, req.Spans)
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	process := batches[0].Process
+	assert.Equal(t, "frontend", process.ServiceName)
+	assert.Equal(t, []model.KeyValue{
+		model.String("hostname", "host-1"),
+		model.Int64("pid", 42),
+		model.String("opencensus.start_time", "2020-06-01T12:00:00Z"),
+		model.String("opencensus.language", "java"),
+		model.String("opencensus.exporter_version", "0.1.0"),
+		model.String("opencensus.core_library_version", "0.26.0"),
+		model.String("zone", "eu-1"),
+		model.String("opencensus.resource_type", "k8s"),
+		model.String("pod", "frontend-1"),
+	}, process.Tags)
+
+	require.Len(t, batches[0].Spans, 1)
+	span := batches[0].Spans[0]
+	traceID := model.NewTraceID(1, 2)
+	assert.Equal(t, traceID, span.TraceID)
+	assert.Equal(t, model.NewSpanID(3), span.SpanID)
+	assert.Equal(t, "GET /", span.OperationName)
+	assert.Equal(t, testStart, span.StartTime)
+	assert.Equal(t, time.Second, span.Duration)
+	assert.True(t, span.Flags.IsSampled())
+	assert.Equal(t, process, span.Process)
+	assert.Equal(t, []model.SpanRef{
+		model.NewChildOfRef(traceID, model.NewSpanID(4)),
+		model.NewFollowsFromRef(traceID, model.NewSpanID(4)),
+	}, span.References)
+	assert.Equal(t, model.KeyValues{
+		model.Int64("attempt", 0),
+		model.Int64("http.status_code", 500),
+		model.String("http.url", "/"),
+		model.Bool("retry", true),
+		model.String("span.kind", "server"),
+		model.Bool("error", true),
+		model.Int64("status.code", 13),
+		model.String("status.message", "internal error"),
+	}, model.KeyValues(span.Tags))
+	require.Len(t, span.Logs, 2)
+	assert.Equal(t, testStart.Add(time.Millisecond), span.Logs[0].Timestamp)
+	assert.Equal(t, []model.KeyValue{model.String("message", "cache miss"), model.String("key", "k")}, span.Logs[0].Fields)
+	assert.Equal(t, []model.KeyValue{
+		model.String("message.type", "RECEIVED"),
+		model.Int64("message.id", 1),
+		model.Int64("message.uncompressed_size", 10),
+		model.Int64("message.compressed_size", 5),
+	}, span.Logs[1].Fields)
+}
+
+func TestAttributeValueWireFormat(t *testing.T) {
+	// int_value = 0 of the upstream oneof is still present on the wire
+	v := &AttributeValue{}
+	require.NoError(t, proto.Unmarshal([]byte{0x10, 0x00}, v))
+	require.NotNil(t, v.IntValue)
+	assert.Equal(t, model.Int64("k", 0), attributeValueToDomain("k", v))
+
+	data, err := proto.Marshal(intValue(0))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x10, 0x00}, data)
+}
+
+func TestToDomainSpanResource(t *testing.T) {
+	node := &Node{ServiceInfo: &ServiceInfo{Name: "frontend"}}
+	spans := []*Span{
+		{TraceID: testTraceID, SpanID: testSpanID},
+		{TraceID: testTraceID, SpanID: testParent, Resource: &Resource{Type: "container"}},
+	}
+	batches, err := ToDomain(node, nil, spans)
+	require.NoError(t, err)
+	require.Len(t, batches, 2)
+	assert.Empty(t, batches[0].Process.Tags)
+	assert.Equal(t, []model.KeyValue{model.String("opencensus.resource_type", "container")}, batches[1].Process.Tags)
+	assert.Equal(t, "frontend", batches[1].Process.ServiceName)
+
+	batches, err = ToDomain(nil, nil, spans[1:])
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	assert.Equal(t, defaultServiceName, batches[0].Process.ServiceName)
+}
+
+func TestToDomainErrors(t *testing.T) {
+	testCases := []struct {
+		span *Span
+		err  string
+	}{
+		{span: &Span{TraceID: []byte{1}, SpanID: testSpanID}, err: "invalid trace ID length 1, expecting 16 bytes"},
+		{span: &Span{TraceID: testTraceID, SpanID: []byte{1}}, err: "invalid span ID length 1, expecting 8 bytes"},
+		{span: &Span{TraceID: testTraceID, SpanID: testSpanID, ParentSpanID: []byte{1}}, err: "invalid span ID length 1, expecting 8 bytes"},
+		{
+			span: &Span{TraceID: testTraceID, SpanID: testSpanID, Links: &Links{Link: []*Link{{TraceID: []byte{1}}}}},
+			err:  "invalid trace ID length 1, expecting 16 bytes",
+		},
+		{
+			span: &Span{TraceID: testTraceID, SpanID: testSpanID, Links: &Links{Link: []*Link{{TraceID: testTraceID}}}},
+			err:  "invalid span ID length 0, expecting 8 bytes",
+		},
+	}
+	for _, test := range testCases {
+		_, err := ToDomain(nil, nil, []*Span{test.span})
+		assert.EqualError(t, err, test.err)
+	}
+}
@@ -0,0 +1,379 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opencensus
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/golang/protobuf/ptypes/wrappers"
+)
+
+// The messages below mirror the subset of opencensus-proto (agent/trace/v1, agent/common/v1,
+// trace/v1 and resource/v1) that Jaeger needs. They are declared by hand with the upstream field
+// numbers so that the standard proto codec can (un)marshal them, the same way as the OTLP messages.
+
+// SpanKind is the type of span.
+type SpanKind int32
+
+// Span kinds defined by OpenCensus.
+const (
+	SpanKindUnspecified SpanKind = 0
+	SpanKindServer      SpanKind = 1
+	SpanKindClient      SpanKind = 2
+)
+
+// LibraryLanguage is the language of the OpenCensus library.
+type LibraryLanguage int32
+
+// MessageEventType tells whether a message was sent or received.
+type MessageEventType int32
+
+// Message event types defined by OpenCensus.
+const (
+	MessageEventTypeUnspecified MessageEventType = 0
+	MessageEventTypeSent        MessageEventType = 1
+	MessageEventTypeReceived    MessageEventType = 2
+)
+
+// LinkType is the relationship of a span with a linked span.
+type LinkType int32
+
+// Link types defined by OpenCensus.
+const (
+	LinkTypeUnspecified LinkType = 0
+	LinkTypeChild       LinkType = 1
+	LinkTypeParent      LinkType = 2
+)
+
+// ExportTraceServiceRequest is a message of the Export stream of the OpenCensus agent.
+// Node and Resource may be omitted after the first message, in which case the previous ones apply.
+type ExportTraceServiceRequest struct {
+	Node     *Node     `protobuf:"bytes,1,opt,name=node,proto3"`
+	Spans    []*Span   `protobuf:"bytes,2,rep,name=spans,proto3"`
+	Resource *Resource `protobuf:"bytes,3,opt,name=resource,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *ExportTraceServiceRequest) Reset() { *m = ExportTraceServiceRequest{} }
+
+// String implements proto.Message
+func (m *ExportTraceServiceRequest) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*ExportTraceServiceRequest) ProtoMessage() {}
+
+// ExportTraceServiceResponse is the (empty) response message of the Export stream.
+type ExportTraceServiceResponse struct{}
+
+// Reset implements proto.Message
+func (m *ExportTraceServiceResponse) Reset() { *m = ExportTraceServiceResponse{} }
+
+// String implements proto.Message
+func (m *ExportTraceServiceResponse) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*ExportTraceServiceResponse) ProtoMessage() {}
+
+// CurrentLibraryConfig is sent by the library on the Config stream, the trace config itself is ignored.
+type CurrentLibraryConfig struct {
+	Node *Node `protobuf:"bytes,1,opt,name=node,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *CurrentLibraryConfig) Reset() { *m = CurrentLibraryConfig{} }
+
+// String implements proto.Message
+func (m *CurrentLibraryConfig) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*CurrentLibraryConfig) ProtoMessage() {}
+
+// UpdatedLibraryConfig is sent to the library on the Config stream, the trace config itself is not supported.
+type UpdatedLibraryConfig struct {
+	Node *Node `protobuf:"bytes,1,opt,name=node,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *UpdatedLibraryConfig) Reset() { *m = UpdatedLibraryConfig{} }
+
+// String implements proto.Message
+func (m *UpdatedLibraryConfig) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*UpdatedLibraryConfig) ProtoMessage() {}
+
+// Node identifies the process and library that produced the spans.
+type Node struct {
+	Identifier  *ProcessIdentifier `protobuf:"bytes,1,opt,name=identifier,proto3"`
+	LibraryInfo *LibraryInfo       `protobuf:"bytes,2,opt,name=library_info,json=libraryInfo,proto3"`
+	ServiceInfo *ServiceInfo       `protobuf:"bytes,3,opt,name=service_info,json=serviceInfo,proto3"`
+	Attributes  map[string]string  `protobuf:"bytes,4,rep,name=attributes,proto3" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *Node) Reset() { *m = Node{} }
+
+// String implements proto.Message
+func (m *Node) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*Node) ProtoMessage() {}
+
+// ProcessIdentifier identifies a process on a host.
+type ProcessIdentifier struct {
+	HostName       string               `protobuf:"bytes,1,opt,name=host_name,json=hostName,proto3"`
+	Pid            uint32               `protobuf:"varint,2,opt,name=pid,proto3"`
+	StartTimestamp *timestamp.Timestamp `protobuf:"bytes,3,opt,name=start_timestamp,json=startTimestamp,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *ProcessIdentifier) Reset() { *m = ProcessIdentifier{} }
+
+// String implements proto.Message
+func (m *ProcessIdentifier) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*ProcessIdentifier) ProtoMessage() {}
+
+// LibraryInfo describes the OpenCensus library.
+type LibraryInfo struct {
+	Language           LibraryLanguage `protobuf:"varint,1,opt,name=language,proto3,enum=opencensus.proto.agent.common.v1.LibraryInfo_Language"`
+	ExporterVersion    string          `protobuf:"bytes,2,opt,name=exporter_version,json=exporterVersion,proto3"`
+	CoreLibraryVersion string          `protobuf:"bytes,3,opt,name=core_library_version,json=coreLibraryVersion,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *LibraryInfo) Reset() { *m = LibraryInfo{} }
+
+// String implements proto.Message
+func (m *LibraryInfo) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*LibraryInfo) ProtoMessage() {}
+
+// ServiceInfo holds the name of the service.
+type ServiceInfo struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *ServiceInfo) Reset() { *m = ServiceInfo{} }
+
+// String implements proto.Message
+func (m *ServiceInfo) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*ServiceInfo) ProtoMessage() {}
+
+// Resource describes the entity producing telemetry.
+type Resource struct {
+	Type   string            `protobuf:"bytes,1,opt,name=type,proto3"`
+	Labels map[string]string `protobuf:"bytes,2,rep,name=labels,proto3" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *Resource) Reset() { *m = Resource{} }
+
+// String implements proto.Message
+func (m *Resource) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*Resource) ProtoMessage() {}
+
+// Span is a single operation within a trace.
+type Span struct {
+	TraceID                 []byte                `protobuf:"bytes,1,opt,name=trace_id,json=traceId,proto3"`
+	SpanID                  []byte                `protobuf:"bytes,2,opt,name=span_id,json=spanId,proto3"`
+	ParentSpanID            []byte                `protobuf:"bytes,3,opt,name=parent_span_id,json=parentSpanId,proto3"`
+	Name                    *TruncatableString    `protobuf:"bytes,4,opt,name=name,proto3"`
+	StartTime               *timestamp.Timestamp  `protobuf:"bytes,5,opt,name=start_time,json=startTime,proto3"`
+	EndTime                 *timestamp.Timestamp  `protobuf:"bytes,6,opt,name=end_time,json=endTime,proto3"`
+	Attributes              *Attributes           `protobuf:"bytes,7,opt,name=attributes,proto3"`
+	TimeEvents              *TimeEvents           `protobuf:"bytes,9,opt,name=time_events,json=timeEvents,proto3"`
+	Links                   *Links                `protobuf:"bytes,10,opt,name=links,proto3"`
+	Status                  *Status               `protobuf:"bytes,11,opt,name=status,proto3"`
+	SameProcessAsParentSpan *wrappers.BoolValue   `protobuf:"bytes,12,opt,name=same_process_as_parent_span,json=sameProcessAsParentSpan,proto3"`
+	ChildSpanCount          *wrappers.UInt32Value `protobuf:"bytes,13,opt,name=child_span_count,json=childSpanCount,proto3"`
+	Kind                    SpanKind              `protobuf:"varint,14,opt,name=kind,proto3,enum=opencensus.proto.trace.v1.Span_SpanKind"`
+	Resource                *Resource             `protobuf:"bytes,16,opt,name=resource,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *Span) Reset() { *m = Span{} }
+
+// String implements proto.Message
+func (m *Span) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*Span) ProtoMessage() {}
+
+// TruncatableString is a string that may have been shortened by the library.
+type TruncatableString struct {
+	Value              string `protobuf:"bytes,1,opt,name=value,proto3"`
+	TruncatedByteCount int32  `protobuf:"varint,2,opt,name=truncated_byte_count,json=truncatedByteCount,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *TruncatableString) Reset() { *m = TruncatableString{} }
+
+// String implements proto.Message
+func (m *TruncatableString) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*TruncatableString) ProtoMessage() {}
+
+// Attributes holds the attributes of a span, an annotation or a link.
+type Attributes struct {
+	AttributeMap           map[string]*AttributeValue `protobuf:"bytes,1,rep,name=attribute_map,json=attributeMap,proto3" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	DroppedAttributesCount int32                      `protobuf:"varint,2,opt,name=dropped_attributes_count,json=droppedAttributesCount,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *Attributes) Reset() { *m = Attributes{} }
+
+// String implements proto.Message
+func (m *Attributes) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*Attributes) ProtoMessage() {}
+
+// AttributeValue is the value of an attribute. The upstream oneof is declared as optional
+// fields, which have the same encoding, so that the type of a zero value is preserved.
+type AttributeValue struct {
+	StringValue *TruncatableString `protobuf:"bytes,1,opt,name=string_value,json=stringValue"`
+	IntValue    *int64             `protobuf:"varint,2,opt,name=int_value,json=intValue"`
+	BoolValue   *bool              `protobuf:"varint,3,opt,name=bool_value,json=boolValue"`
+	DoubleValue *float64           `protobuf:"fixed64,4,opt,name=double_value,json=doubleValue"`
+}
+
+// Reset implements proto.Message
+func (m *AttributeValue) Reset() { *m = AttributeValue{} }
+
+// String implements proto.Message
+func (m *AttributeValue) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*AttributeValue) ProtoMessage() {}
+
+// TimeEvents holds the annotations and message events of a span.
+type TimeEvents struct {
+	TimeEvent                 []*TimeEvent `protobuf:"bytes,1,rep,name=time_event,json=timeEvent,proto3"`
+	DroppedAnnotationsCount   int32        `protobuf:"varint,2,opt,name=dropped_annotations_count,json=droppedAnnotationsCount,proto3"`
+	DroppedMessageEventsCount int32        `protobuf:"varint,3,opt,name=dropped_message_events_count,json=droppedMessageEventsCount,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *TimeEvents) Reset() { *m = TimeEvents{} }
+
+// String implements proto.Message
+func (m *TimeEvents) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*TimeEvents) ProtoMessage() {}
+
+// TimeEvent is either an annotation or a message event; the upstream oneof is declared as optional fields.
+type TimeEvent struct {
+	Time         *timestamp.Timestamp `protobuf:"bytes,1,opt,name=time,proto3"`
+	Annotation   *Annotation          `protobuf:"bytes,2,opt,name=annotation"`
+	MessageEvent *MessageEvent        `protobuf:"bytes,3,opt,name=message_event,json=messageEvent"`
+}
+
+// Reset implements proto.Message
+func (m *TimeEvent) Reset() { *m = TimeEvent{} }
+
+// String implements proto.Message
+func (m *TimeEvent) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*TimeEvent) ProtoMessage() {}
+
+// Annotation is a text description with attributes.
+type Annotation struct {
+	Description *TruncatableString `protobuf:"bytes,1,opt,name=description,proto3"`
+	Attributes  *Attributes        `protobuf:"bytes,2,opt,name=attributes,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *Annotation) Reset() { *m = Annotation{} }
+
+// String implements proto.Message
+func (m *Annotation) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*Annotation) ProtoMessage() {}
+
+// MessageEvent describes a message sent or received by the span.
+type MessageEvent struct {
+	Type             MessageEventType `protobuf:"varint,1,opt,name=type,proto3,enum=opencensus.proto.trace.v1.Span_TimeEvent_MessageEvent_Type"`
+	ID               uint64           `protobuf:"varint,2,opt,name=id,proto3"`
+	UncompressedSize uint64           `protobuf:"varint,3,opt,name=uncompressed_size,json=uncompressedSize,proto3"`
+	CompressedSize   uint64           `protobuf:"varint,4,opt,name=compressed_size,json=compressedSize,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *MessageEvent) Reset() { *m = MessageEvent{} }
+
+// String implements proto.Message
+func (m *MessageEvent) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*MessageEvent) ProtoMessage() {}
+
+// Links holds the links of a span to other spans.
+type Links struct {
+	Link              []*Link `protobuf:"bytes,1,rep,name=link,proto3"`
+	DroppedLinksCount int32   `protobuf:"varint,2,opt,name=dropped_links_count,json=droppedLinksCount,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *Links) Reset() { *m = Links{} }
+
+// String implements proto.Message
+func (m *Links) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*Links) ProtoMessage() {}
+
+// Link points to a span of the same or another trace.
+type Link struct {
+	TraceID    []byte      `protobuf:"bytes,1,opt,name=trace_id,json=traceId,proto3"`
+	SpanID     []byte      `protobuf:"bytes,2,opt,name=span_id,json=spanId,proto3"`
+	Type       LinkType    `protobuf:"varint,3,opt,name=type,proto3,enum=opencensus.proto.trace.v1.Span_Link_Type"`
+	Attributes *Attributes `protobuf:"bytes,4,opt,name=attributes,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *Link) Reset() { *m = Link{} }
+
+// String implements proto.Message
+func (m *Link) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*Link) ProtoMessage() {}
+
+// Status is the result of a span, using the gRPC status codes.
+type Status struct {
+	Code    int32  `protobuf:"varint,1,opt,name=code,proto3"`
+	Message string `protobuf:"bytes,2,opt,name=message,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *Status) Reset() { *m = Status{} }
+
+// String implements proto.Message
+func (m *Status) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*Status) ProtoMessage() {}
@@ -0,0 +1,204 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"encoding/binary"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+// FromDomain transforms Jaeger spans into an OTLP export request, the reverse of ToDomain.
+// Spans without their own Process use the process of their batch. Spans sharing a process are
+// grouped into one resource, and within it by the instrumentation scope recorded in their tags.
+func FromDomain(batches []*model.Batch) *ExportTraceServiceRequest {
+	req := &ExportTraceServiceRequest{}
+	var processes []*model.Process
+	for _, batch := range batches {
+		for _, span := range batch.Spans {
+			process := span.Process
+			if process == nil {
+				process = batch.Process
+			}
+			idx := -1
+			for i, p := range processes {
+				if p == process || (p != nil && process != nil && p.Equal(process)) {
+					idx = i
+					break
+				}
+			}
+			if idx < 0 {
+				idx = len(processes)
+				processes = append(processes, process)
+				req.ResourceSpans = append(req.ResourceSpans, &ResourceSpans{Resource: processFromDomain(process)})
+			}
+			addSpan(req.ResourceSpans[idx], span)
+		}
+	}
+	return req
+}
+
+func addSpan(rs *ResourceSpans, span *model.Span) {
+	oSpan, scope := spanFromDomain(span)
+	for _, ss := range rs.ScopeSpans {
+		if ss.Scope.Name == scope.Name && ss.Scope.Version == scope.Version {
+			ss.Spans = append(ss.Spans, oSpan)
+			return
+		}
+	}
+	rs.ScopeSpans = append(rs.ScopeSpans, &ScopeSpans{Scope: scope, Spans: []*Span{oSpan}})
+}
+
+func processFromDomain(process *model.Process) *Resource {
+	resource := &Resource{}
+	if process == nil {
+		return resource
+	}
+	resource.Attributes = make([]*KeyValue, 0, len(process.Tags)+1)
+	resource.Attributes = append(resource.Attributes, stringAttribute(serviceNameAttribute, process.ServiceName))
+	for i := range process.Tags {
+		resource.Attributes = append(resource.Attributes, keyValueFromDomain(&process.Tags[i]))
+	}
+	return resource
+}
+
+func spanFromDomain(span *model.Span) (*Span, *InstrumentationScope) {
+	oSpan := &Span{
+		TraceID:           traceIDFromDomain(span.TraceID),
+		SpanID:            spanIDFromDomain(span.SpanID),
+		Name:              span.OperationName,
+		StartTimeUnixNano: uint64(span.StartTime.UnixNano()),
+		EndTimeUnixNano:   uint64(span.StartTime.Add(span.Duration).UnixNano()),
+		Events:            logsFromDomain(span.Logs),
+	}
+	parentID := span.ParentSpanID()
+	if parentID != 0 {
+		oSpan.ParentSpanID = spanIDFromDomain(parentID)
+	}
+	for _, ref := range span.References {
+		if ref.RefType == model.ChildOf && ref.TraceID == span.TraceID && ref.SpanID == parentID {
+			continue
+		}
+		oSpan.Links = append(oSpan.Links, &Link{TraceID: traceIDFromDomain(ref.TraceID), SpanID: spanIDFromDomain(ref.SpanID)})
+	}
+
+	scope := &InstrumentationScope{}
+	var status Status
+	var isError bool
+	for i := range span.Tags {
+		tag := &span.Tags[i]
+		switch tag.Key {
+		case spanKindTag:
+			oSpan.Kind = spanKindFromDomain(tag.AsString())
+		case errorTag:
+			isError = tag.VType == model.BoolType && tag.Bool() || tag.AsString() == "true"
+		case statusCodeTag:
+			switch tag.AsString() {
+			case "ERROR":
+				status.Code = StatusCodeError
+			case "OK":
+				status.Code = StatusCodeOk
+			}
+		case statusDescTag:
+			status.Message = tag.AsString()
+		case scopeNameTag:
+			scope.Name = tag.AsString()
+		case scopeVersionTag:
+			scope.Version = tag.AsString()
+		case traceStateTag:
+			oSpan.TraceState = tag.AsString()
+		default:
+			oSpan.Attributes = append(oSpan.Attributes, keyValueFromDomain(tag))
+		}
+	}
+	if isError && status.Code == StatusCodeUnset {
+		status.Code = StatusCodeError
+	}
+	if status.Code != StatusCodeUnset || status.Message != "" {
+		oSpan.Status = &status
+	}
+	return oSpan, scope
+}
+
+func spanKindFromDomain(kind string) SpanKind {
+	switch kind {
+	case "internal":
+		return SpanKindInternal
+	case "server":
+		return SpanKindServer
+	case "client":
+		return SpanKindClient
+	case "producer":
+		return SpanKindProducer
+	case "consumer":
+		return SpanKindConsumer
+	default:
+		return SpanKindUnspecified
+	}
+}
+
+func logsFromDomain(logs []model.Log) []*Event {
+	if len(logs) == 0 {
+		return nil
+	}
+	events := make([]*Event, 0, len(logs))
+	for _, log := range logs {
+		event := &Event{TimeUnixNano: uint64(log.Timestamp.UnixNano())}
+		for i := range log.Fields {
+			field := &log.Fields[i]
+			if field.Key == eventNameField && event.Name == "" {
+				event.Name = field.AsString()
+				continue
+			}
+			event.Attributes = append(event.Attributes, keyValueFromDomain(field))
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func keyValueFromDomain(kv *model.KeyValue) *KeyValue {
+	var value isAnyValueValue
+	switch kv.VType {
+	case model.BoolType:
+		value = &AnyValueBool{BoolValue: kv.Bool()}
+	case model.Int64Type:
+		value = &AnyValueInt{IntValue: kv.Int64()}
+	case model.Float64Type:
+		value = &AnyValueDouble{DoubleValue: kv.Float64()}
+	case model.BinaryType:
+		value = &AnyValueBytes{BytesValue: kv.Binary()}
+	default:
+		value = &AnyValueString{StringValue: kv.VStr}
+	}
+	return &KeyValue{Key: kv.Key, Value: &AnyValue{Value: value}}
+}
+
+func stringAttribute(key, value string) *KeyValue {
+	return &KeyValue{Key: key, Value: &AnyValue{Value: &AnyValueString{StringValue: value}}}
+}
+
+func traceIDFromDomain(traceID model.TraceID) []byte {
+	id := make([]byte, 16)
+	binary.BigEndian.PutUint64(id[:8], traceID.High)
+	binary.BigEndian.PutUint64(id[8:], traceID.Low)
+	return id
+}
+
+func spanIDFromDomain(spanID model.SpanID) []byte {
+	id := make([]byte, 8)
+	binary.BigEndian.PutUint64(id, uint64(spanID))
+	return id
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+func TestFromDomainRoundTrip(t *testing.T) {
+	batches, err := ToDomain(testRequest())
+	require.NoError(t, err)
+
+	expected := testRequest()
+	// composite values have no Jaeger equivalent and come back as their JSON representation
+	expected.ResourceSpans[0].ScopeSpans[0].Spans[0].Attributes[3].Value = strValue(`["a","b"]`)
+	assert.Equal(t, expected, FromDomain(batches))
+}
+
+func TestFromDomainGroupsByProcessAndScope(t *testing.T) {
+	frontend := &model.Process{ServiceName: "frontend"}
+	span := func(id uint64, process *model.Process, tags ...model.KeyValue) *model.Span {
+		return &model.Span{
+			TraceID:   model.NewTraceID(0, 1),
+			SpanID:    model.NewSpanID(id),
+			StartTime: testStart,
+			Duration:  time.Millisecond,
+			Process:   process,
+			Tags:      tags,
+		}
+	}
+	req := FromDomain([]*model.Batch{
+		{
+			Process: frontend,
+			Spans: []*model.Span{
+				span(1, nil),
+				span(2, &model.Process{ServiceName: "frontend"}, model.String("otel.scope.name", "http")),
+				span(3, &model.Process{ServiceName: "backend"}, model.Bool("error", true)),
+			},
+		},
+		{Spans: []*model.Span{span(4, nil, model.String("span.kind", "client"))}},
+	})
+
+	require.Len(t, req.ResourceSpans, 3)
+	assert.Equal(t, []*KeyValue{{Key: "service.name", Value: strValue("frontend")}}, req.ResourceSpans[0].Resource.Attributes)
+	require.Len(t, req.ResourceSpans[0].ScopeSpans, 2)
+	assert.Equal(t, &InstrumentationScope{}, req.ResourceSpans[0].ScopeSpans[0].Scope)
+	assert.Equal(t, &InstrumentationScope{Name: "http"}, req.ResourceSpans[0].ScopeSpans[1].Scope)
+
+	backend := req.ResourceSpans[1]
+	assert.Equal(t, []*KeyValue{{Key: "service.name", Value: strValue("backend")}}, backend.Resource.Attributes)
+	assert.Equal(t, &Status{Code: StatusCodeError}, backend.ScopeSpans[0].Spans[0].Status)
+	assert.Nil(t, backend.ScopeSpans[0].Spans[0].ParentSpanID)
+	assert.Equal(t, uint64(testStart.Add(time.Millisecond).UnixNano()), backend.ScopeSpans[0].Spans[0].EndTimeUnixNano)
+
+	// spans without any process form their own resource
+	noProcess := req.ResourceSpans[2]
+	assert.Equal(t, &Resource{}, noProcess.Resource)
+	assert.Equal(t, SpanKindClient, noProcess.ScopeSpans[0].Spans[0].Kind)
+	assert.Empty(t, noProcess.ScopeSpans[0].Spans[0].Attributes)
+}
+
+func TestKeyValueFromDomain(t *testing.T) {
+	tests := []struct {
+		kv       model.KeyValue
+		expected *AnyValue
+	}{
+		{model.String("k", "v"), strValue("v")},
+		{model.Bool("k", true), &AnyValue{Value: &AnyValueBool{BoolValue: true}}},
+		{model.Int64("k", 42), &AnyValue{Value: &AnyValueInt{IntValue: 42}}},
+		{model.Float64("k", 0.5), &AnyValue{Value: &AnyValueDouble{DoubleValue: 0.5}}},
+		{model.Binary("k", []byte{1, 2}), &AnyValue{Value: &AnyValueBytes{BytesValue: []byte{1, 2}}}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, &KeyValue{Key: "k", Value: test.expected}, keyValueFromDomain(&test.kv))
+	}
+}
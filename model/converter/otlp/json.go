@@ -0,0 +1,476 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// UnmarshalJSON decodes an ExportTraceServiceRequest in the OTLP/JSON encoding,
+// where IDs are hex strings, 64-bit integers may be quoted and enums are integers or names.
+func UnmarshalJSON(data []byte) (*ExportTraceServiceRequest, error) {
+	var jReq jsonRequest
+	if err := json.Unmarshal(data, &jReq); err != nil {
+		return nil, err
+	}
+	req := &ExportTraceServiceRequest{}
+	for _, jRS := range jReq.ResourceSpans {
+		rs := &ResourceSpans{SchemaURL: jRS.SchemaURL}
+		if jRS.Resource != nil {
+			attrs, err := jRS.Resource.Attributes.toProto()
+			if err != nil {
+				return nil, err
+			}
+			rs.Resource = &Resource{Attributes: attrs}
+		}
+		for _, jScopes := range [][]jsonScopeSpans{jRS.ScopeSpans, jRS.InstrumentationLibrarySpans} {
+			for _, jSS := range jScopes {
+				ss, err := jSS.toProto()
+				if err != nil {
+					return nil, err
+				}
+				rs.ScopeSpans = append(rs.ScopeSpans, ss)
+			}
+		}
+		req.ResourceSpans = append(req.ResourceSpans, rs)
+	}
+	return req, nil
+}
+
+// MarshalJSON encodes an ExportTraceServiceRequest in the OTLP/JSON encoding, the reverse of UnmarshalJSON.
+func MarshalJSON(req *ExportTraceServiceRequest) ([]byte, error) {
+	jReq := jsonRequest{ResourceSpans: make([]jsonResourceSpans, 0, len(req.ResourceSpans))}
+	for _, rs := range req.ResourceSpans {
+		jRS := jsonResourceSpans{SchemaURL: rs.SchemaURL}
+		if rs.Resource != nil {
+			jRS.Resource = &jsonResource{Attributes: keyValuesToJSON(rs.Resource.Attributes)}
+		}
+		for _, ss := range rs.ScopeSpans {
+			jRS.ScopeSpans = append(jRS.ScopeSpans, scopeSpansToJSON(ss))
+		}
+		jReq.ResourceSpans = append(jReq.ResourceSpans, jRS)
+	}
+	return json.Marshal(jReq)
+}
+
+type jsonRequest struct {
+	ResourceSpans []jsonResourceSpans `json:"resourceSpans"`
+}
+
+type jsonResourceSpans struct {
+	Resource                    *jsonResource    `json:"resource,omitempty"`
+	ScopeSpans                  []jsonScopeSpans `json:"scopeSpans,omitempty"`
+	InstrumentationLibrarySpans []jsonScopeSpans `json:"instrumentationLibrarySpans,omitempty"`
+	SchemaURL                   string           `json:"schemaUrl,omitempty"`
+}
+
+type jsonResource struct {
+	Attributes jsonKeyValues `json:"attributes"`
+}
+
+type jsonScopeSpans struct {
+	Scope                  *jsonScope `json:"scope,omitempty"`
+	InstrumentationLibrary *jsonScope `json:"instrumentationLibrary,omitempty"`
+	Spans                  []jsonSpan `json:"spans"`
+}
+
+type jsonScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type jsonSpan struct {
+	TraceID           string        `json:"traceId"`
+	SpanID            string        `json:"spanId"`
+	TraceState        string        `json:"traceState,omitempty"`
+	ParentSpanID      string        `json:"parentSpanId,omitempty"`
+	Name              string        `json:"name"`
+	Kind              jsonEnum      `json:"kind"`
+	StartTimeUnixNano jsonUint64    `json:"startTimeUnixNano"`
+	EndTimeUnixNano   jsonUint64    `json:"endTimeUnixNano"`
+	Attributes        jsonKeyValues `json:"attributes,omitempty"`
+	Events            []jsonEvent   `json:"events,omitempty"`
+	Links             []jsonLink    `json:"links,omitempty"`
+	Status            *jsonStatus   `json:"status,omitempty"`
+}
+
+type jsonEvent struct {
+	TimeUnixNano jsonUint64    `json:"timeUnixNano"`
+	Name         string        `json:"name"`
+	Attributes   jsonKeyValues `json:"attributes,omitempty"`
+}
+
+type jsonLink struct {
+	TraceID    string        `json:"traceId"`
+	SpanID     string        `json:"spanId"`
+	TraceState string        `json:"traceState,omitempty"`
+	Attributes jsonKeyValues `json:"attributes,omitempty"`
+}
+
+type jsonStatus struct {
+	Message string   `json:"message,omitempty"`
+	Code    jsonEnum `json:"code"`
+}
+
+type jsonKeyValues []jsonKeyValue
+
+type jsonKeyValue struct {
+	Key   string        `json:"key"`
+	Value *jsonAnyValue `json:"value"`
+}
+
+type jsonAnyValue struct {
+	StringValue *string          `json:"stringValue,omitempty"`
+	BoolValue   *bool            `json:"boolValue,omitempty"`
+	IntValue    *jsonInt64       `json:"intValue,omitempty"`
+	DoubleValue *float64         `json:"doubleValue,omitempty"`
+	BytesValue  *string          `json:"bytesValue,omitempty"`
+	ArrayValue  *jsonArrayValue  `json:"arrayValue,omitempty"`
+	KvlistValue *jsonKvlistValue `json:"kvlistValue,omitempty"`
+}
+
+type jsonArrayValue struct {
+	Values []*jsonAnyValue `json:"values"`
+}
+
+type jsonKvlistValue struct {
+	Values jsonKeyValues `json:"values"`
+}
+
+// jsonUint64 accepts both quoted and bare numbers, as the proto3 JSON mapping allows for 64-bit integers.
+type jsonUint64 uint64
+
+func (n *jsonUint64) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		return nil
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*n = jsonUint64(v)
+	return nil
+}
+
+// MarshalJSON quotes the number, as recommended by the proto3 JSON mapping.
+func (n jsonUint64) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(strconv.FormatUint(uint64(n), 10))), nil
+}
+
+// jsonInt64 accepts both quoted and bare numbers, as the proto3 JSON mapping allows for 64-bit integers.
+type jsonInt64 int64
+
+func (n *jsonInt64) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*n = jsonInt64(v)
+	return nil
+}
+
+// MarshalJSON quotes the number, as recommended by the proto3 JSON mapping.
+func (n jsonInt64) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(strconv.FormatInt(int64(n), 10))), nil
+}
+
+// jsonEnum accepts enums either as their integer value or as their proto name.
+type jsonEnum struct {
+	value int32
+	name  string
+}
+
+func (e *jsonEnum) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &e.name)
+	}
+	return json.Unmarshal(data, &e.value)
+}
+
+// MarshalJSON writes the integer value of the enum, as required by the OTLP/JSON encoding.
+func (e jsonEnum) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.value)
+}
+
+var (
+	spanKindNames = map[string]SpanKind{
+		"SPAN_KIND_UNSPECIFIED": SpanKindUnspecified,
+		"SPAN_KIND_INTERNAL":    SpanKindInternal,
+		"SPAN_KIND_SERVER":      SpanKindServer,
+		"SPAN_KIND_CLIENT":      SpanKindClient,
+		"SPAN_KIND_PRODUCER":    SpanKindProducer,
+		"SPAN_KIND_CONSUMER":    SpanKindConsumer,
+	}
+	statusCodeNames = map[string]StatusCode{
+		"STATUS_CODE_UNSET": StatusCodeUnset,
+		"STATUS_CODE_OK":    StatusCodeOk,
+		"STATUS_CODE_ERROR": StatusCodeError,
+	}
+)
+
+func (e jsonEnum) spanKind() (SpanKind, error) {
+	if e.name == "" {
+		return SpanKind(e.value), nil
+	}
+	if kind, ok := spanKindNames[e.name]; ok {
+		return kind, nil
+	}
+	return 0, fmt.Errorf("unknown span kind %q", e.name)
+}
+
+func (e jsonEnum) statusCode() (StatusCode, error) {
+	if e.name == "" {
+		return StatusCode(e.value), nil
+	}
+	if code, ok := statusCodeNames[e.name]; ok {
+		return code, nil
+	}
+	return 0, fmt.Errorf("unknown status code %q", e.name)
+}
+
+func (j jsonScopeSpans) toProto() (*ScopeSpans, error) {
+	ss := &ScopeSpans{}
+	scope := j.Scope
+	if scope == nil {
+		scope = j.InstrumentationLibrary
+	}
+	if scope != nil {
+		ss.Scope = &InstrumentationScope{Name: scope.Name, Version: scope.Version}
+	}
+	for _, jSpan := range j.Spans {
+		span, err := jSpan.toProto()
+		if err != nil {
+			return nil, err
+		}
+		ss.Spans = append(ss.Spans, span)
+	}
+	return ss, nil
+}
+
+func (j jsonSpan) toProto() (*Span, error) {
+	var err error
+	span := &Span{
+		TraceState:        j.TraceState,
+		Name:              j.Name,
+		StartTimeUnixNano: uint64(j.StartTimeUnixNano),
+		EndTimeUnixNano:   uint64(j.EndTimeUnixNano),
+	}
+	if span.TraceID, err = decodeID("trace", j.TraceID); err != nil {
+		return nil, err
+	}
+	if span.SpanID, err = decodeID("span", j.SpanID); err != nil {
+		return nil, err
+	}
+	if span.ParentSpanID, err = decodeID("parent span", j.ParentSpanID); err != nil {
+		return nil, err
+	}
+	if span.Kind, err = j.Kind.spanKind(); err != nil {
+		return nil, err
+	}
+	if span.Attributes, err = j.Attributes.toProto(); err != nil {
+		return nil, err
+	}
+	for _, jEvent := range j.Events {
+		attrs, err := jEvent.Attributes.toProto()
+		if err != nil {
+			return nil, err
+		}
+		span.Events = append(span.Events, &Event{
+			TimeUnixNano: uint64(jEvent.TimeUnixNano),
+			Name:         jEvent.Name,
+			Attributes:   attrs,
+		})
+	}
+	for _, jLink := range j.Links {
+		link := &Link{TraceState: jLink.TraceState}
+		if link.TraceID, err = decodeID("link trace", jLink.TraceID); err != nil {
+			return nil, err
+		}
+		if link.SpanID, err = decodeID("link span", jLink.SpanID); err != nil {
+			return nil, err
+		}
+		if link.Attributes, err = jLink.Attributes.toProto(); err != nil {
+			return nil, err
+		}
+		span.Links = append(span.Links, link)
+	}
+	if j.Status != nil {
+		span.Status = &Status{Message: j.Status.Message}
+		if span.Status.Code, err = j.Status.Code.statusCode(); err != nil {
+			return nil, err
+		}
+	}
+	return span, nil
+}
+
+func (j jsonKeyValues) toProto() ([]*KeyValue, error) {
+	if len(j) == 0 {
+		return nil, nil
+	}
+	kvs := make([]*KeyValue, 0, len(j))
+	for _, jKV := range j {
+		value, err := jKV.Value.toProto()
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of attribute %q: %w", jKV.Key, err)
+		}
+		kvs = append(kvs, &KeyValue{Key: jKV.Key, Value: value})
+	}
+	return kvs, nil
+}
+
+func (j *jsonAnyValue) toProto() (*AnyValue, error) {
+	if j == nil {
+		return nil, nil
+	}
+	switch {
+	case j.StringValue != nil:
+		return &AnyValue{Value: &AnyValueString{StringValue: *j.StringValue}}, nil
+	case j.BoolValue != nil:
+		return &AnyValue{Value: &AnyValueBool{BoolValue: *j.BoolValue}}, nil
+	case j.IntValue != nil:
+		return &AnyValue{Value: &AnyValueInt{IntValue: int64(*j.IntValue)}}, nil
+	case j.DoubleValue != nil:
+		return &AnyValue{Value: &AnyValueDouble{DoubleValue: *j.DoubleValue}}, nil
+	case j.BytesValue != nil:
+		b, err := base64.StdEncoding.DecodeString(*j.BytesValue)
+		if err != nil {
+			return nil, err
+		}
+		return &AnyValue{Value: &AnyValueBytes{BytesValue: b}}, nil
+	case j.ArrayValue != nil:
+		arr := &ArrayValue{}
+		for _, item := range j.ArrayValue.Values {
+			value, err := item.toProto()
+			if err != nil {
+				return nil, err
+			}
+			arr.Values = append(arr.Values, value)
+		}
+		return &AnyValue{Value: &AnyValueArray{ArrayValue: arr}}, nil
+	case j.KvlistValue != nil:
+		values, err := j.KvlistValue.Values.toProto()
+		if err != nil {
+			return nil, err
+		}
+		return &AnyValue{Value: &AnyValueKvlist{KvlistValue: &KeyValueList{Values: values}}}, nil
+	default:
+		return &AnyValue{}, nil
+	}
+}
+
+func scopeSpansToJSON(ss *ScopeSpans) jsonScopeSpans {
+	jSS := jsonScopeSpans{Spans: make([]jsonSpan, 0, len(ss.Spans))}
+	if ss.Scope != nil {
+		jSS.Scope = &jsonScope{Name: ss.Scope.Name, Version: ss.Scope.Version}
+	}
+	for _, span := range ss.Spans {
+		jSS.Spans = append(jSS.Spans, spanToJSON(span))
+	}
+	return jSS
+}
+
+func spanToJSON(span *Span) jsonSpan {
+	jSpan := jsonSpan{
+		TraceID:           hex.EncodeToString(span.TraceID),
+		SpanID:            hex.EncodeToString(span.SpanID),
+		TraceState:        span.TraceState,
+		ParentSpanID:      hex.EncodeToString(span.ParentSpanID),
+		Name:              span.Name,
+		Kind:              jsonEnum{value: int32(span.Kind)},
+		StartTimeUnixNano: jsonUint64(span.StartTimeUnixNano),
+		EndTimeUnixNano:   jsonUint64(span.EndTimeUnixNano),
+		Attributes:        keyValuesToJSON(span.Attributes),
+	}
+	for _, event := range span.Events {
+		jSpan.Events = append(jSpan.Events, jsonEvent{
+			TimeUnixNano: jsonUint64(event.TimeUnixNano),
+			Name:         event.Name,
+			Attributes:   keyValuesToJSON(event.Attributes),
+		})
+	}
+	for _, link := range span.Links {
+		jSpan.Links = append(jSpan.Links, jsonLink{
+			TraceID:    hex.EncodeToString(link.TraceID),
+			SpanID:     hex.EncodeToString(link.SpanID),
+			TraceState: link.TraceState,
+			Attributes: keyValuesToJSON(link.Attributes),
+		})
+	}
+	if span.Status != nil {
+		jSpan.Status = &jsonStatus{Message: span.Status.Message, Code: jsonEnum{value: int32(span.Status.Code)}}
+	}
+	return jSpan
+}
+
+func keyValuesToJSON(kvs []*KeyValue) jsonKeyValues {
+	if len(kvs) == 0 {
+		return nil
+	}
+	jKVs := make(jsonKeyValues, 0, len(kvs))
+	for _, kv := range kvs {
+		jKVs = append(jKVs, jsonKeyValue{Key: kv.Key, Value: anyValueToJSON(kv.Value)})
+	}
+	return jKVs
+}
+
+func anyValueToJSON(value *AnyValue) *jsonAnyValue {
+	j := &jsonAnyValue{}
+	if value == nil {
+		return j
+	}
+	switch v := value.Value.(type) {
+	case *AnyValueString:
+		j.StringValue = &v.StringValue
+	case *AnyValueBool:
+		j.BoolValue = &v.BoolValue
+	case *AnyValueInt:
+		intValue := jsonInt64(v.IntValue)
+		j.IntValue = &intValue
+	case *AnyValueDouble:
+		j.DoubleValue = &v.DoubleValue
+	case *AnyValueBytes:
+		bytesValue := base64.StdEncoding.EncodeToString(v.BytesValue)
+		j.BytesValue = &bytesValue
+	case *AnyValueArray:
+		j.ArrayValue = &jsonArrayValue{Values: make([]*jsonAnyValue, 0, len(v.ArrayValue.Values))}
+		for _, item := range v.ArrayValue.Values {
+			j.ArrayValue.Values = append(j.ArrayValue.Values, anyValueToJSON(item))
+		}
+	case *AnyValueKvlist:
+		j.KvlistValue = &jsonKvlistValue{Values: keyValuesToJSON(v.KvlistValue.Values)}
+	}
+	return j
+}
+
+func decodeID(kind string, id string) ([]byte, error) {
+	if id == "" {
+		return nil, nil
+	}
+	b, err := hex.DecodeString(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s ID %q: %w", kind, id, err)
+	}
+	return b, nil
+}
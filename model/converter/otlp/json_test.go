@@ -0,0 +1,110 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testJSON = `{
+  "resourceSpans": [{
+    "resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "frontend"}}]},
+    "scopeSpans": [{
+      "scope": {"name": "io.opentelemetry.http", "version": "1.0"},
+      "spans": [{
+        "traceId": "00000000000000010000000000000002",
+        "spanId": "0000000000000003",
+        "parentSpanId": "0000000000000004",
+        "name": "GET /",
+        "kind": 2,
+        "startTimeUnixNano": "1590984000000000000",
+        "endTimeUnixNano": 1590984001000000000,
+        "attributes": [
+          {"key": "http.status_code", "value": {"intValue": "500"}},
+          {"key": "payload", "value": {"bytesValue": "AQI="}},
+          {"key": "labels", "value": {"kvlistValue": {"values": [{"key": "a", "value": {"boolValue": true}}]}}}
+        ],
+        "events": [{"timeUnixNano": "1590984000500000000", "name": "retry"}],
+        "links": [{"traceId": "00000000000000010000000000000002", "spanId": "0000000000000004"}],
+        "status": {"code": "STATUS_CODE_ERROR", "message": "internal error"}
+      }]
+    }]
+  }]
+}`
+
+func TestUnmarshalJSON(t *testing.T) {
+	req, err := UnmarshalJSON([]byte(testJSON))
+	require.NoError(t, err)
+	require.Len(t, req.ResourceSpans, 1)
+	require.Len(t, req.ResourceSpans[0].ScopeSpans, 1)
+	ss := req.ResourceSpans[0].ScopeSpans[0]
+	assert.Equal(t, "io.opentelemetry.http", ss.Scope.Name)
+	require.Len(t, ss.Spans, 1)
+
+	span := ss.Spans[0]
+	assert.Equal(t, testTraceID, span.TraceID)
+	assert.Equal(t, testSpanID, span.SpanID)
+	assert.Equal(t, testParent, span.ParentSpanID)
+	assert.Equal(t, SpanKindServer, span.Kind)
+	assert.Equal(t, uint64(1590984000000000000), span.StartTimeUnixNano)
+	assert.Equal(t, uint64(1590984001000000000), span.EndTimeUnixNano)
+	assert.Equal(t, &AnyValueInt{IntValue: 500}, span.Attributes[0].Value.Value)
+	assert.Equal(t, &AnyValueBytes{BytesValue: []byte{1, 2}}, span.Attributes[1].Value.Value)
+	assert.Equal(t, &AnyValueKvlist{KvlistValue: &KeyValueList{Values: []*KeyValue{
+		{Key: "a", Value: &AnyValue{Value: &AnyValueBool{BoolValue: true}}},
+	}}}, span.Attributes[2].Value.Value)
+	assert.Equal(t, "retry", span.Events[0].Name)
+	assert.Equal(t, testParent, span.Links[0].SpanID)
+	assert.Equal(t, &Status{Code: StatusCodeError, Message: "internal error"}, span.Status)
+
+	batches, err := ToDomain(req)
+	require.NoError(t, err)
+	assert.Equal(t, "frontend", batches[0].Process.ServiceName)
+}
+
+func TestUnmarshalJSONErrors(t *testing.T) {
+	testCases := []struct {
+		json string
+		err  string
+	}{
+		{json: `{"resourceSpans": [{"scopeSpans": [{"spans": [{"traceId": "xyz"}]}]}]}`, err: `invalid trace ID "xyz": encoding/hex: invalid byte: U+0078 'x'`},
+		{json: `{"resourceSpans": [{"scopeSpans": [{"spans": [{"kind": "SPAN_KIND_FOO"}]}]}]}`, err: `unknown span kind "SPAN_KIND_FOO"`},
+		{json: `{"resourceSpans": [{"scopeSpans": [{"spans": [{"status": {"code": "FOO"}}]}]}]}`, err: `unknown status code "FOO"`},
+		{json: `{"resourceSpans": [{"scopeSpans": [{"spans": [{"startTimeUnixNano": "abc"}]}]}]}`, err: `strconv.ParseUint: parsing "abc": invalid syntax`},
+		{json: `{"resourceSpans": [{"resource": {"attributes": [{"key": "k", "value": {"bytesValue": "!"}}]}}]}`, err: `invalid value of attribute "k": illegal base64 data at input byte 0`},
+	}
+	for _, tc := range testCases {
+		_, err := UnmarshalJSON([]byte(tc.json))
+		assert.EqualError(t, err, tc.err, tc.json)
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	req, err := UnmarshalJSON([]byte(testJSON))
+	require.NoError(t, err)
+	data, err := MarshalJSON(req)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"startTimeUnixNano":"1590984000000000000"`)
+	assert.Contains(t, string(data), `"kind":2`)
+	assert.Contains(t, string(data), `"intValue":"500"`)
+	assert.NotContains(t, string(data), "null")
+
+	roundTrip, err := UnmarshalJSON(data)
+	require.NoError(t, err)
+	assert.Equal(t, req, roundTrip)
+}
@@ -0,0 +1,283 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+const (
+	// Semantic conventions used when mapping OTLP concepts onto Jaeger tags.
+	serviceNameAttribute = "service.name"
+	spanKindTag          = "span.kind"
+	errorTag             = "error"
+	statusCodeTag        = "otel.status_code"
+	statusDescTag        = "otel.status_description"
+	scopeNameTag         = "otel.scope.name"
+	scopeVersionTag      = "otel.scope.version"
+	traceStateTag        = "w3c.tracestate"
+	eventNameField       = "event"
+
+	// defaultServiceName is reported when the resource does not carry service.name,
+	// following the OpenTelemetry SDK convention.
+	defaultServiceName = "unknown_service"
+)
+
+// ToDomain transforms an OTLP export request into Jaeger batches, one per resource.
+func ToDomain(req *ExportTraceServiceRequest) ([]*model.Batch, error) {
+	batches := make([]*model.Batch, 0, len(req.ResourceSpans))
+	for _, rs := range req.ResourceSpans {
+		batch, err := resourceSpansToDomain(rs)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, batch)
+	}
+	return batches, nil
+}
+
+func resourceSpansToDomain(rs *ResourceSpans) (*model.Batch, error) {
+	process := resourceToDomain(rs.Resource)
+	batch := &model.Batch{Process: process}
+	for _, scopes := range [][]*ScopeSpans{rs.ScopeSpans, rs.InstrumentationLibrarySpans} {
+		for _, ss := range scopes {
+			for _, span := range ss.Spans {
+				mSpan, err := spanToDomain(span, ss.Scope)
+				if err != nil {
+					return nil, err
+				}
+				mSpan.Process = process
+				batch.Spans = append(batch.Spans, mSpan)
+			}
+		}
+	}
+	return batch, nil
+}
+
+func resourceToDomain(resource *Resource) *model.Process {
+	process := &model.Process{ServiceName: defaultServiceName}
+	if resource == nil {
+		return process
+	}
+	for _, attr := range resource.Attributes {
+		if attr.Key == serviceNameAttribute {
+			if sv, ok := attr.GetValue().Value.(*AnyValueString); ok && sv.StringValue != "" {
+				process.ServiceName = sv.StringValue
+				continue
+			}
+		}
+		process.Tags = append(process.Tags, keyValueToDomain(attr))
+	}
+	return process
+}
+
+func spanToDomain(span *Span, scope *InstrumentationScope) (*model.Span, error) {
+	traceID, err := traceIDToDomain(span.TraceID)
+	if err != nil {
+		return nil, err
+	}
+	spanID, err := spanIDToDomain(span.SpanID)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []model.SpanRef
+	if len(span.ParentSpanID) > 0 {
+		parentID, err := spanIDToDomain(span.ParentSpanID)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, model.NewChildOfRef(traceID, parentID))
+	}
+	for _, link := range span.Links {
+		linkTraceID, err := traceIDToDomain(link.TraceID)
+		if err != nil {
+			return nil, err
+		}
+		linkSpanID, err := spanIDToDomain(link.SpanID)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, model.NewFollowsFromRef(linkTraceID, linkSpanID))
+	}
+
+	var duration time.Duration
+	if span.EndTimeUnixNano > span.StartTimeUnixNano {
+		duration = time.Duration(span.EndTimeUnixNano - span.StartTimeUnixNano)
+	}
+
+	var flags model.Flags
+	flags.SetSampled()
+
+	return &model.Span{
+		TraceID:       traceID,
+		SpanID:        spanID,
+		OperationName: span.Name,
+		References:    refs,
+		Flags:         flags,
+		StartTime:     time.Unix(0, int64(span.StartTimeUnixNano)).UTC(),
+		Duration:      duration,
+		Tags:          spanTagsToDomain(span, scope),
+		Logs:          eventsToDomain(span.Events),
+	}, nil
+}
+
+func spanTagsToDomain(span *Span, scope *InstrumentationScope) []model.KeyValue {
+	tags := make([]model.KeyValue, 0, len(span.Attributes)+4)
+	for _, attr := range span.Attributes {
+		tags = append(tags, keyValueToDomain(attr))
+	}
+	if kind := spanKindToDomain(span.Kind); kind != "" {
+		tags = append(tags, model.String(spanKindTag, kind))
+	}
+	if span.Status != nil {
+		switch span.Status.Code {
+		case StatusCodeError:
+			tags = append(tags, model.Bool(errorTag, true), model.String(statusCodeTag, "ERROR"))
+		case StatusCodeOk:
+			tags = append(tags, model.String(statusCodeTag, "OK"))
+		}
+		if span.Status.Message != "" {
+			tags = append(tags, model.String(statusDescTag, span.Status.Message))
+		}
+	}
+	if scope != nil {
+		if scope.Name != "" {
+			tags = append(tags, model.String(scopeNameTag, scope.Name))
+		}
+		if scope.Version != "" {
+			tags = append(tags, model.String(scopeVersionTag, scope.Version))
+		}
+	}
+	if span.TraceState != "" {
+		tags = append(tags, model.String(traceStateTag, span.TraceState))
+	}
+	return tags
+}
+
+func spanKindToDomain(kind SpanKind) string {
+	switch kind {
+	case SpanKindInternal:
+		return "internal"
+	case SpanKindServer:
+		return "server"
+	case SpanKindClient:
+		return "client"
+	case SpanKindProducer:
+		return "producer"
+	case SpanKindConsumer:
+		return "consumer"
+	default:
+		return ""
+	}
+}
+
+func eventsToDomain(events []*Event) []model.Log {
+	if len(events) == 0 {
+		return nil
+	}
+	logs := make([]model.Log, 0, len(events))
+	for _, event := range events {
+		fields := make([]model.KeyValue, 0, len(event.Attributes)+1)
+		if event.Name != "" {
+			fields = append(fields, model.String(eventNameField, event.Name))
+		}
+		for _, attr := range event.Attributes {
+			fields = append(fields, keyValueToDomain(attr))
+		}
+		logs = append(logs, model.Log{
+			Timestamp: time.Unix(0, int64(event.TimeUnixNano)).UTC(),
+			Fields:    fields,
+		})
+	}
+	return logs
+}
+
+func keyValueToDomain(kv *KeyValue) model.KeyValue {
+	if kv.Value == nil || kv.Value.Value == nil {
+		return model.String(kv.Key, "")
+	}
+	switch v := kv.Value.Value.(type) {
+	case *AnyValueString:
+		return model.String(kv.Key, v.StringValue)
+	case *AnyValueBool:
+		return model.Bool(kv.Key, v.BoolValue)
+	case *AnyValueInt:
+		return model.Int64(kv.Key, v.IntValue)
+	case *AnyValueDouble:
+		return model.Float64(kv.Key, v.DoubleValue)
+	case *AnyValueBytes:
+		return model.Binary(kv.Key, v.BytesValue)
+	default:
+		// Jaeger has no composite tag types, store arrays and maps as their JSON representation.
+		out, _ := json.Marshal(anyValueToNative(kv.Value))
+		return model.String(kv.Key, string(out))
+	}
+}
+
+func anyValueToNative(av *AnyValue) interface{} {
+	if av == nil {
+		return nil
+	}
+	switch v := av.Value.(type) {
+	case *AnyValueString:
+		return v.StringValue
+	case *AnyValueBool:
+		return v.BoolValue
+	case *AnyValueInt:
+		return v.IntValue
+	case *AnyValueDouble:
+		return v.DoubleValue
+	case *AnyValueBytes:
+		return v.BytesValue
+	case *AnyValueArray:
+		var values []interface{}
+		if v.ArrayValue != nil {
+			for _, item := range v.ArrayValue.Values {
+				values = append(values, anyValueToNative(item))
+			}
+		}
+		return values
+	case *AnyValueKvlist:
+		values := make(map[string]interface{})
+		if v.KvlistValue != nil {
+			for _, item := range v.KvlistValue.Values {
+				values[item.Key] = anyValueToNative(item.Value)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+func traceIDToDomain(id []byte) (model.TraceID, error) {
+	if len(id) != 16 {
+		return model.TraceID{}, fmt.Errorf("invalid trace ID length %d, expecting 16 bytes", len(id))
+	}
+	return model.NewTraceID(binary.BigEndian.Uint64(id[:8]), binary.BigEndian.Uint64(id[8:])), nil
+}
+
+func spanIDToDomain(id []byte) (model.SpanID, error) {
+	if len(id) != 8 {
+		return 0, fmt.Errorf("invalid span ID length %d, expecting 8 bytes", len(id))
+	}
+	return model.NewSpanID(binary.BigEndian.Uint64(id)), nil
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+var (
+	testTraceID = []byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 2}
+	testSpanID  = []byte{0, 0, 0, 0, 0, 0, 0, 3}
+	testParent  = []byte{0, 0, 0, 0, 0, 0, 0, 4}
+	testStart   = time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+)
+
+func strValue(s string) *AnyValue {
+	return &AnyValue{Value: &AnyValueString{StringValue: s}}
+}
+
+func testRequest() *ExportTraceServiceRequest {
+	return &ExportTraceServiceRequest{
+		ResourceSpans: []*ResourceSpans{{
+			Resource: &Resource{Attributes: []*KeyValue{
+				{Key: "service.name", Value: strValue("frontend")},
+				{Key: "host.name", Value: strValue("host-1")},
+			}},
+			ScopeSpans: []*ScopeSpans{{
+				Scope: &InstrumentationScope{Name: "io.opentelemetry.http", Version: "1.0"},
+				Spans: []*Span{{
+					TraceID:           testTraceID,
+					SpanID:            testSpanID,
+					ParentSpanID:      testParent,
+					Name:              "GET /",
+					Kind:              SpanKindServer,
+					StartTimeUnixNano: uint64(testStart.UnixNano()),
+					EndTimeUnixNano:   uint64(testStart.Add(time.Second).UnixNano()),
+					Attributes: []*KeyValue{
+						{Key: "http.status_code", Value: &AnyValue{Value: &AnyValueInt{IntValue: 500}}},
+						{Key: "retry", Value: &AnyValue{Value: &AnyValueBool{BoolValue: true}}},
+						{Key: "ratio", Value: &AnyValue{Value: &AnyValueDouble{DoubleValue: 0.5}}},
+						{Key: "list", Value: &AnyValue{Value: &AnyValueArray{ArrayValue: &ArrayValue{
+							Values: []*AnyValue{strValue("a"), strValue("b")},
+						}}}},
+					},
+					Events: []*Event{{
+						TimeUnixNano: uint64(testStart.Add(time.Millisecond).UnixNano()),
+						Name:         "exception",
+						Attributes:   []*KeyValue{{Key: "exception.message", Value: strValue("boom")}},
+					}},
+					Links:  []*Link{{TraceID: testTraceID, SpanID: testParent}},
+					Status: &Status{Code: StatusCodeError, Message: "internal error"},
+				}},
+			}},
+		}},
+	}
+}
+
+func TestToDomain(t *testing.T) {
+	// go through the wire format to make sure the hand-written messages are encoded correctly
+	data, err := proto.Marshal(testRequest())
+	require.NoError(t, err)
+	req := &ExportTraceServiceRequest{}
+	require.NoError(t, proto.Unmarshal(data, req))
+
+	batches, err := ToDomain(req)
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+
+	process := batches[0].Process
+	assert.Equal(t, "frontend", process.ServiceName)
+	assert.Equal(t, []model.KeyValue{model.String("host.name", "host-1")}, process.Tags)
+
+	require.Len(t, batches[0].Spans, 1)
+	span := batches[0].Spans[0]
+	traceID := model.NewTraceID(1, 2)
+	assert.Equal(t, traceID, span.TraceID)
+	assert.Equal(t, model.NewSpanID(3), span.SpanID)
+	assert.Equal(t, model.NewSpanID(4), span.ParentSpanID())
+	assert.Equal(t, []model.SpanRef{
+		model.NewChildOfRef(traceID, model.NewSpanID(4)),
+		model.NewFollowsFromRef(traceID, model.NewSpanID(4)),
+	}, span.References)
+	assert.Equal(t, "GET /", span.OperationName)
+	assert.Equal(t, testStart, span.StartTime)
+	assert.Equal(t, time.Second, span.Duration)
+	assert.True(t, span.Flags.IsSampled())
+	assert.Equal(t, process, span.Process)
+	assert.Equal(t, model.KeyValues{
+		model.Int64("http.status_code", 500),
+		model.Bool("retry", true),
+		model.Float64("ratio", 0.5),
+		model.String("list", `["a","b"]`),
+		model.String("span.kind", "server"),
+		model.Bool("error", true),
+		model.String("otel.status_code", "ERROR"),
+		model.String("otel.status_description", "internal error"),
+		model.String("otel.scope.name", "io.opentelemetry.http"),
+		model.String("otel.scope.version", "1.0"),
+	}, model.KeyValues(span.Tags))
+	assert.Equal(t, []model.Log{{
+		Timestamp: testStart.Add(time.Millisecond),
+		Fields: []model.KeyValue{
+			model.String("event", "exception"),
+			model.String("exception.message", "boom"),
+		},
+	}}, span.Logs)
+}
+
+func TestToDomainDefaultServiceName(t *testing.T) {
+	batches, err := ToDomain(&ExportTraceServiceRequest{
+		ResourceSpans: []*ResourceSpans{{
+			InstrumentationLibrarySpans: []*ScopeSpans{{
+				Spans: []*Span{{TraceID: testTraceID, SpanID: testSpanID}},
+			}},
+		}},
+	})
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	assert.Equal(t, "unknown_service", batches[0].Process.ServiceName)
+	assert.Len(t, batches[0].Spans, 1)
+}
+
+func TestToDomainInvalidIDs(t *testing.T) {
+	testCases := []struct {
+		span *Span
+		err  string
+	}{
+		{span: &Span{TraceID: []byte{1}, SpanID: testSpanID}, err: "invalid trace ID length 1, expecting 16 bytes"},
+		{span: &Span{TraceID: testTraceID, SpanID: []byte{1}}, err: "invalid span ID length 1, expecting 8 bytes"},
+		{span: &Span{TraceID: testTraceID, SpanID: testSpanID, ParentSpanID: []byte{1}}, err: "invalid span ID length 1, expecting 8 bytes"},
+		{span: &Span{TraceID: testTraceID, SpanID: testSpanID, Links: []*Link{{TraceID: []byte{1}}}}, err: "invalid trace ID length 1, expecting 16 bytes"},
+	}
+	for _, tc := range testCases {
+		_, err := ToDomain(&ExportTraceServiceRequest{
+			ResourceSpans: []*ResourceSpans{{ScopeSpans: []*ScopeSpans{{Spans: []*Span{tc.span}}}}},
+		})
+		assert.EqualError(t, err, tc.err)
+	}
+}
@@ -0,0 +1,344 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otlp
+
+import (
+	"github.com/golang/protobuf/proto"
+)
+
+// The messages below mirror the subset of opentelemetry-proto (trace/v1, common/v1, resource/v1
+// and collector/trace/v1) that Jaeger needs. They are declared by hand with the upstream field
+// numbers so that the standard proto codec can (un)marshal them without pulling the protobuf
+// APIv2 runtime into the build.
+
+// SpanKind is the type of span.
+type SpanKind int32
+
+// Span kinds defined by OTLP.
+const (
+	SpanKindUnspecified SpanKind = 0
+	SpanKindInternal    SpanKind = 1
+	SpanKindServer      SpanKind = 2
+	SpanKindClient      SpanKind = 3
+	SpanKindProducer    SpanKind = 4
+	SpanKindConsumer    SpanKind = 5
+)
+
+// StatusCode is the status of a span.
+type StatusCode int32
+
+// Status codes defined by OTLP.
+const (
+	StatusCodeUnset StatusCode = 0
+	StatusCodeOk    StatusCode = 1
+	StatusCodeError StatusCode = 2
+)
+
+// ExportTraceServiceRequest is the payload of the OTLP trace export call.
+type ExportTraceServiceRequest struct {
+	ResourceSpans []*ResourceSpans `protobuf:"bytes,1,rep,name=resource_spans,json=resourceSpans,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *ExportTraceServiceRequest) Reset() { *m = ExportTraceServiceRequest{} }
+
+// String implements proto.Message
+func (m *ExportTraceServiceRequest) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*ExportTraceServiceRequest) ProtoMessage() {}
+
+// ExportTraceServiceResponse is the response of the OTLP trace export call.
+type ExportTraceServiceResponse struct{}
+
+// Reset implements proto.Message
+func (m *ExportTraceServiceResponse) Reset() { *m = ExportTraceServiceResponse{} }
+
+// String implements proto.Message
+func (m *ExportTraceServiceResponse) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*ExportTraceServiceResponse) ProtoMessage() {}
+
+// ResourceSpans is a collection of spans produced by a single resource (i.e. a Jaeger process).
+type ResourceSpans struct {
+	Resource   *Resource     `protobuf:"bytes,1,opt,name=resource,proto3"`
+	ScopeSpans []*ScopeSpans `protobuf:"bytes,2,rep,name=scope_spans,json=scopeSpans,proto3"`
+	SchemaURL  string        `protobuf:"bytes,3,opt,name=schema_url,json=schemaUrl,proto3"`
+	// InstrumentationLibrarySpans is the pre-1.0 name of ScopeSpans, still sent by older SDKs.
+	InstrumentationLibrarySpans []*ScopeSpans `protobuf:"bytes,1000,rep,name=instrumentation_library_spans,json=instrumentationLibrarySpans,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *ResourceSpans) Reset() { *m = ResourceSpans{} }
+
+// String implements proto.Message
+func (m *ResourceSpans) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*ResourceSpans) ProtoMessage() {}
+
+// Resource describes the entity producing telemetry.
+type Resource struct {
+	Attributes             []*KeyValue `protobuf:"bytes,1,rep,name=attributes,proto3"`
+	DroppedAttributesCount uint32      `protobuf:"varint,2,opt,name=dropped_attributes_count,json=droppedAttributesCount,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *Resource) Reset() { *m = Resource{} }
+
+// String implements proto.Message
+func (m *Resource) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*Resource) ProtoMessage() {}
+
+// ScopeSpans is a collection of spans produced by a single instrumentation scope.
+type ScopeSpans struct {
+	Scope     *InstrumentationScope `protobuf:"bytes,1,opt,name=scope,proto3"`
+	Spans     []*Span               `protobuf:"bytes,2,rep,name=spans,proto3"`
+	SchemaURL string                `protobuf:"bytes,3,opt,name=schema_url,json=schemaUrl,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *ScopeSpans) Reset() { *m = ScopeSpans{} }
+
+// String implements proto.Message
+func (m *ScopeSpans) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*ScopeSpans) ProtoMessage() {}
+
+// InstrumentationScope identifies the library that produced the spans.
+type InstrumentationScope struct {
+	Name                   string      `protobuf:"bytes,1,opt,name=name,proto3"`
+	Version                string      `protobuf:"bytes,2,opt,name=version,proto3"`
+	Attributes             []*KeyValue `protobuf:"bytes,3,rep,name=attributes,proto3"`
+	DroppedAttributesCount uint32      `protobuf:"varint,4,opt,name=dropped_attributes_count,json=droppedAttributesCount,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *InstrumentationScope) Reset() { *m = InstrumentationScope{} }
+
+// String implements proto.Message
+func (m *InstrumentationScope) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*InstrumentationScope) ProtoMessage() {}
+
+// Span is a single OTLP span.
+type Span struct {
+	TraceID                []byte      `protobuf:"bytes,1,opt,name=trace_id,json=traceId,proto3"`
+	SpanID                 []byte      `protobuf:"bytes,2,opt,name=span_id,json=spanId,proto3"`
+	TraceState             string      `protobuf:"bytes,3,opt,name=trace_state,json=traceState,proto3"`
+	ParentSpanID           []byte      `protobuf:"bytes,4,opt,name=parent_span_id,json=parentSpanId,proto3"`
+	Name                   string      `protobuf:"bytes,5,opt,name=name,proto3"`
+	Kind                   SpanKind    `protobuf:"varint,6,opt,name=kind,proto3,enum=opentelemetry.proto.trace.v1.Span_SpanKind"`
+	StartTimeUnixNano      uint64      `protobuf:"fixed64,7,opt,name=start_time_unix_nano,json=startTimeUnixNano,proto3"`
+	EndTimeUnixNano        uint64      `protobuf:"fixed64,8,opt,name=end_time_unix_nano,json=endTimeUnixNano,proto3"`
+	Attributes             []*KeyValue `protobuf:"bytes,9,rep,name=attributes,proto3"`
+	DroppedAttributesCount uint32      `protobuf:"varint,10,opt,name=dropped_attributes_count,json=droppedAttributesCount,proto3"`
+	Events                 []*Event    `protobuf:"bytes,11,rep,name=events,proto3"`
+	DroppedEventsCount     uint32      `protobuf:"varint,12,opt,name=dropped_events_count,json=droppedEventsCount,proto3"`
+	Links                  []*Link     `protobuf:"bytes,13,rep,name=links,proto3"`
+	DroppedLinksCount      uint32      `protobuf:"varint,14,opt,name=dropped_links_count,json=droppedLinksCount,proto3"`
+	Status                 *Status     `protobuf:"bytes,15,opt,name=status,proto3"`
+	Flags                  uint32      `protobuf:"fixed32,16,opt,name=flags,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *Span) Reset() { *m = Span{} }
+
+// String implements proto.Message
+func (m *Span) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*Span) ProtoMessage() {}
+
+// Event is a time-stamped annotation of a span, the equivalent of a Jaeger log.
+type Event struct {
+	TimeUnixNano           uint64      `protobuf:"fixed64,1,opt,name=time_unix_nano,json=timeUnixNano,proto3"`
+	Name                   string      `protobuf:"bytes,2,opt,name=name,proto3"`
+	Attributes             []*KeyValue `protobuf:"bytes,3,rep,name=attributes,proto3"`
+	DroppedAttributesCount uint32      `protobuf:"varint,4,opt,name=dropped_attributes_count,json=droppedAttributesCount,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *Event) Reset() { *m = Event{} }
+
+// String implements proto.Message
+func (m *Event) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*Event) ProtoMessage() {}
+
+// Link is a pointer from a span to another span, the equivalent of a Jaeger reference.
+type Link struct {
+	TraceID                []byte      `protobuf:"bytes,1,opt,name=trace_id,json=traceId,proto3"`
+	SpanID                 []byte      `protobuf:"bytes,2,opt,name=span_id,json=spanId,proto3"`
+	TraceState             string      `protobuf:"bytes,3,opt,name=trace_state,json=traceState,proto3"`
+	Attributes             []*KeyValue `protobuf:"bytes,4,rep,name=attributes,proto3"`
+	DroppedAttributesCount uint32      `protobuf:"varint,5,opt,name=dropped_attributes_count,json=droppedAttributesCount,proto3"`
+	Flags                  uint32      `protobuf:"fixed32,6,opt,name=flags,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *Link) Reset() { *m = Link{} }
+
+// String implements proto.Message
+func (m *Link) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*Link) ProtoMessage() {}
+
+// Status is the result of the operation represented by a span.
+type Status struct {
+	Message string     `protobuf:"bytes,2,opt,name=message,proto3"`
+	Code    StatusCode `protobuf:"varint,3,opt,name=code,proto3,enum=opentelemetry.proto.trace.v1.Status_StatusCode"`
+}
+
+// Reset implements proto.Message
+func (m *Status) Reset() { *m = Status{} }
+
+// String implements proto.Message
+func (m *Status) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*Status) ProtoMessage() {}
+
+// KeyValue is an attribute of a span, event, link or resource.
+type KeyValue struct {
+	Key   string    `protobuf:"bytes,1,opt,name=key,proto3"`
+	Value *AnyValue `protobuf:"bytes,2,opt,name=value,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *KeyValue) Reset() { *m = KeyValue{} }
+
+// String implements proto.Message
+func (m *KeyValue) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*KeyValue) ProtoMessage() {}
+
+// AnyValue holds exactly one of the value wrappers declared below.
+type AnyValue struct {
+	Value isAnyValueValue `protobuf_oneof:"value"`
+}
+
+type isAnyValueValue interface {
+	isAnyValueValue()
+}
+
+// AnyValueString is the string variant of AnyValue.
+type AnyValueString struct {
+	StringValue string `protobuf:"bytes,1,opt,name=string_value,json=stringValue,proto3,oneof"`
+}
+
+// AnyValueBool is the boolean variant of AnyValue.
+type AnyValueBool struct {
+	BoolValue bool `protobuf:"varint,2,opt,name=bool_value,json=boolValue,proto3,oneof"`
+}
+
+// AnyValueInt is the integer variant of AnyValue.
+type AnyValueInt struct {
+	IntValue int64 `protobuf:"varint,3,opt,name=int_value,json=intValue,proto3,oneof"`
+}
+
+// AnyValueDouble is the floating point variant of AnyValue.
+type AnyValueDouble struct {
+	DoubleValue float64 `protobuf:"fixed64,4,opt,name=double_value,json=doubleValue,proto3,oneof"`
+}
+
+// AnyValueArray is the array variant of AnyValue.
+type AnyValueArray struct {
+	ArrayValue *ArrayValue `protobuf:"bytes,5,opt,name=array_value,json=arrayValue,proto3,oneof"`
+}
+
+// AnyValueKvlist is the key-value list variant of AnyValue.
+type AnyValueKvlist struct {
+	KvlistValue *KeyValueList `protobuf:"bytes,6,opt,name=kvlist_value,json=kvlistValue,proto3,oneof"`
+}
+
+// AnyValueBytes is the binary variant of AnyValue.
+type AnyValueBytes struct {
+	BytesValue []byte `protobuf:"bytes,7,opt,name=bytes_value,json=bytesValue,proto3,oneof"`
+}
+
+func (*AnyValueString) isAnyValueValue() {}
+func (*AnyValueBool) isAnyValueValue()   {}
+func (*AnyValueInt) isAnyValueValue()    {}
+func (*AnyValueDouble) isAnyValueValue() {}
+func (*AnyValueArray) isAnyValueValue()  {}
+func (*AnyValueKvlist) isAnyValueValue() {}
+func (*AnyValueBytes) isAnyValueValue()  {}
+
+// Reset implements proto.Message
+func (m *AnyValue) Reset() { *m = AnyValue{} }
+
+// String implements proto.Message
+func (m *AnyValue) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*AnyValue) ProtoMessage() {}
+
+// XXX_OneofWrappers is used by the proto runtime to discover the oneof variants.
+func (*AnyValue) XXX_OneofWrappers() []interface{} {
+	return []interface{}{
+		(*AnyValueString)(nil),
+		(*AnyValueBool)(nil),
+		(*AnyValueInt)(nil),
+		(*AnyValueDouble)(nil),
+		(*AnyValueArray)(nil),
+		(*AnyValueKvlist)(nil),
+		(*AnyValueBytes)(nil),
+	}
+}
+
+// ArrayValue is a list of values.
+type ArrayValue struct {
+	Values []*AnyValue `protobuf:"bytes,1,rep,name=values,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *ArrayValue) Reset() { *m = ArrayValue{} }
+
+// String implements proto.Message
+func (m *ArrayValue) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*ArrayValue) ProtoMessage() {}
+
+// KeyValueList is a nested list of attributes.
+type KeyValueList struct {
+	Values []*KeyValue `protobuf:"bytes,1,rep,name=values,proto3"`
+}
+
+// Reset implements proto.Message
+func (m *KeyValueList) Reset() { *m = KeyValueList{} }
+
+// String implements proto.Message
+func (m *KeyValueList) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage implements proto.Message
+func (*KeyValueList) ProtoMessage() {}
+
+// GetValue returns the value of the attribute, or an empty value if unset.
+func (m *KeyValue) GetValue() *AnyValue {
+	if m.Value == nil {
+		return &AnyValue{}
+	}
+	return m.Value
+}
@@ -42,6 +42,18 @@ func FromDomainSpan(span *model.Span) *jaeger.Span {
 	return dToJ.transformSpan(span)
 }
 
+// FromDomainProcess takes a model.Process and converts it into a jaeger.Process.
+func FromDomainProcess(process *model.Process) *jaeger.Process {
+	if process == nil {
+		return nil
+	}
+	dToJ := domainToJaegerTransformer{}
+	return &jaeger.Process{
+		ServiceName: process.ServiceName,
+		Tags:        dToJ.convertKeyValuesToTags(process.Tags),
+	}
+}
+
 type domainToJaegerTransformer struct{}
 
 func (d domainToJaegerTransformer) keyValueToTag(kv *model.KeyValue) *jaeger.Tag {
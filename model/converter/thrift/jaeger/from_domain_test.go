@@ -57,6 +57,16 @@ func TestFromDomain(t *testing.T) {
 	assert.Equal(t, modelSpans, newModelSpans)
 }
 
+func TestFromDomainProcess(t *testing.T) {
+	assert.Nil(t, FromDomainProcess(nil))
+
+	process := &model.Process{
+		ServiceName: "frontend",
+		Tags:        model.KeyValues{model.String("hostname", "host-1"), model.Int64("pid", 42)},
+	}
+	assert.Equal(t, process, ToDomainProcess(FromDomainProcess(process)))
+}
+
 func TestKeyValueToTag(t *testing.T) {
 	dToJ := domainToJaegerTransformer{}
 	jaegerTag := dToJ.keyValueToTag(&model.KeyValue{
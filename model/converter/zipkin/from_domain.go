@@ -0,0 +1,184 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/opentracing/opentracing-go/ext"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+const (
+	// ipTagName is the process tag holding the IP of the host, as set by the Jaeger clients
+	ipTagName = "ip"
+	// eventLogFieldKey is the log field holding the message of the logs with a single field
+	eventLogFieldKey = "event"
+)
+
+// Span is a span in the Zipkin v2 JSON model.
+type Span struct {
+	TraceID        string            `json:"traceId"`
+	ID             string            `json:"id"`
+	ParentID       string            `json:"parentId,omitempty"`
+	Name           string            `json:"name,omitempty"`
+	Kind           string            `json:"kind,omitempty"`
+	Timestamp      uint64            `json:"timestamp,omitempty"`
+	Duration       uint64            `json:"duration,omitempty"`
+	Debug          bool              `json:"debug,omitempty"`
+	LocalEndpoint  *Endpoint         `json:"localEndpoint,omitempty"`
+	RemoteEndpoint *Endpoint         `json:"remoteEndpoint,omitempty"`
+	Annotations    []Annotation      `json:"annotations,omitempty"`
+	Tags           map[string]string `json:"tags,omitempty"`
+}
+
+// Endpoint is the network context of a node in the Zipkin v2 JSON model.
+type Endpoint struct {
+	ServiceName string `json:"serviceName,omitempty"`
+	IPv4        string `json:"ipv4,omitempty"`
+	IPv6        string `json:"ipv6,omitempty"`
+	Port        int64  `json:"port,omitempty"`
+}
+
+// Annotation is an event of a span in the Zipkin v2 JSON model.
+type Annotation struct {
+	Timestamp uint64 `json:"timestamp"`
+	Value     string `json:"value"`
+}
+
+var spanKinds = map[string]string{
+	string(ext.SpanKindRPCClientEnum): "CLIENT",
+	string(ext.SpanKindRPCServerEnum): "SERVER",
+	string(ext.SpanKindProducerEnum):  "PRODUCER",
+	string(ext.SpanKindConsumerEnum):  "CONSUMER",
+}
+
+// FromDomain converts the spans of the trace to Zipkin v2 spans, the reverse of the conversion done
+// by the Zipkin endpoints of the collector. The span kind and the peer tags become the kind and the
+// remote endpoint of the spans, and the logs become annotations, JSON-encoded unless they only have
+// an event field. The process tags other than the IP are not kept.
+func FromDomain(trace *model.Trace) []*Span {
+	spans := make([]*Span, 0, len(trace.Spans))
+	for _, span := range trace.Spans {
+		spans = append(spans, spanFromDomain(span))
+	}
+	return spans
+}
+
+func spanFromDomain(span *model.Span) *Span {
+	zSpan := &Span{
+		TraceID:   traceIDFromDomain(span.TraceID),
+		ID:        span.SpanID.String(),
+		Name:      span.OperationName,
+		Timestamp: model.TimeAsEpochMicroseconds(span.StartTime),
+		Duration:  model.DurationAsMicroseconds(span.Duration),
+		Debug:     span.Flags.IsDebug(),
+	}
+	if parentID := span.ParentSpanID(); parentID != 0 {
+		zSpan.ParentID = parentID.String()
+	}
+	if span.Process != nil {
+		zSpan.LocalEndpoint = &Endpoint{ServiceName: span.Process.ServiceName}
+		if ip, ok := model.KeyValues(span.Process.Tags).FindByKey(ipTagName); ok {
+			zSpan.LocalEndpoint.IPv4 = ipv4FromDomain(ip)
+		}
+	}
+	remote := &Endpoint{}
+	for i := range span.Tags {
+		tag := &span.Tags[i]
+		switch tag.Key {
+		case string(ext.SpanKind):
+			zSpan.Kind = spanKinds[tag.AsString()]
+			if zSpan.Kind == "" {
+				addTag(zSpan, tag)
+			}
+		case string(ext.PeerService):
+			remote.ServiceName = tag.AsString()
+		case string(ext.PeerHostIPv4):
+			remote.IPv4 = ipv4FromDomain(*tag)
+		case string(ext.PeerHostIPv6):
+			remote.IPv6 = ipv6FromDomain(*tag)
+		case string(ext.PeerPort):
+			remote.Port = tag.Int64()
+		default:
+			addTag(zSpan, tag)
+		}
+	}
+	if *remote != (Endpoint{}) {
+		zSpan.RemoteEndpoint = remote
+	}
+	for _, log := range span.Logs {
+		zSpan.Annotations = append(zSpan.Annotations, Annotation{
+			Timestamp: model.TimeAsEpochMicroseconds(log.Timestamp),
+			Value:     annotationValue(log.Fields),
+		})
+	}
+	return zSpan
+}
+
+func addTag(zSpan *Span, tag *model.KeyValue) {
+	if zSpan.Tags == nil {
+		zSpan.Tags = make(map[string]string)
+	}
+	zSpan.Tags[tag.Key] = tag.AsString()
+}
+
+func annotationValue(fields []model.KeyValue) string {
+	if len(fields) == 1 && fields[0].Key == eventLogFieldKey {
+		return fields[0].AsString()
+	}
+	values := make(map[string]string, len(fields))
+	for i := range fields {
+		values[fields[i].Key] = fields[i].AsString()
+	}
+	// a map of strings cannot fail to marshal
+	value, _ := json.Marshal(values)
+	return string(value)
+}
+
+// traceIDFromDomain pads the trace IDs to 16 or 32 hex characters, as required by Zipkin.
+func traceIDFromDomain(traceID model.TraceID) string {
+	if traceID.High == 0 {
+		return fmt.Sprintf("%016x", traceID.Low)
+	}
+	return fmt.Sprintf("%016x%016x", traceID.High, traceID.Low)
+}
+
+// ipv4FromDomain accepts both the IPs packed into integers and the dotted strings.
+func ipv4FromDomain(kv model.KeyValue) string {
+	if kv.VType == model.Int64Type {
+		ip := uint32(kv.Int64())
+		return net.IPv4(byte(ip>>24), byte(ip>>16), byte(ip>>8), byte(ip)).String()
+	}
+	if ip := net.ParseIP(kv.AsString()); ip != nil && ip.To4() != nil {
+		return ip.String()
+	}
+	return ""
+}
+
+// ipv6FromDomain accepts both the IPs packed into 16 bytes and the strings.
+func ipv6FromDomain(kv model.KeyValue) string {
+	if kv.VType == model.BinaryType && len(kv.Binary()) == net.IPv6len {
+		return net.IP(kv.Binary()).String()
+	}
+	if ip := net.ParseIP(kv.AsString()); ip != nil && strings.Contains(kv.AsString(), ":") {
+		return ip.String()
+	}
+	return ""
+}
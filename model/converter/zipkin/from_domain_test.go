@@ -0,0 +1,116 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkin
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+func TestFromDomain(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	traceID := model.NewTraceID(0, 0xab)
+	trace := &model.Trace{Spans: []*model.Span{
+		{
+			TraceID:       traceID,
+			SpanID:        model.NewSpanID(1),
+			OperationName: "GET /",
+			StartTime:     start,
+			Duration:      time.Second,
+			Flags:         model.DebugFlag,
+			Tags: []model.KeyValue{
+				model.String("span.kind", "server"),
+				model.Bool("error", true),
+			},
+			Process: model.NewProcess("frontend", []model.KeyValue{
+				model.Int64("ip", 0x7f000001),
+				model.String("hostname", "host"),
+			}),
+		},
+		{
+			TraceID:       traceID,
+			SpanID:        model.NewSpanID(2),
+			OperationName: "SELECT",
+			References:    []model.SpanRef{model.NewChildOfRef(traceID, model.NewSpanID(1))},
+			StartTime:     start.Add(time.Millisecond),
+			Duration:      time.Millisecond,
+			Tags: []model.KeyValue{
+				model.String("span.kind", "client"),
+				model.String("peer.service", "mysql"),
+				model.String("peer.ipv4", "10.0.0.1"),
+				model.Binary("peer.ipv6", net.ParseIP("::1")),
+				model.Int64("peer.port", 3306),
+			},
+			Logs: []model.Log{
+				{Timestamp: start.Add(time.Millisecond), Fields: []model.KeyValue{model.String("event", "query")}},
+				{Timestamp: start.Add(2 * time.Millisecond), Fields: []model.KeyValue{model.Int64("rows", 3)}},
+			},
+			Process: model.NewProcess("api", []model.KeyValue{model.String("ip", "192.168.0.1")}),
+		},
+	}}
+
+	assert.Equal(t, []*Span{
+		{
+			TraceID:       "00000000000000ab",
+			ID:            "0000000000000001",
+			Name:          "GET /",
+			Kind:          "SERVER",
+			Timestamp:     1600000000000000,
+			Duration:      1000000,
+			Debug:         true,
+			LocalEndpoint: &Endpoint{ServiceName: "frontend", IPv4: "127.0.0.1"},
+			Tags:          map[string]string{"error": "true"},
+		},
+		{
+			TraceID:        "00000000000000ab",
+			ID:             "0000000000000002",
+			ParentID:       "0000000000000001",
+			Name:           "SELECT",
+			Kind:           "CLIENT",
+			Timestamp:      1600000000001000,
+			Duration:       1000,
+			LocalEndpoint:  &Endpoint{ServiceName: "api", IPv4: "192.168.0.1"},
+			RemoteEndpoint: &Endpoint{ServiceName: "mysql", IPv4: "10.0.0.1", IPv6: "::1", Port: 3306},
+			Annotations: []Annotation{
+				{Timestamp: 1600000000001000, Value: "query"},
+				{Timestamp: 1600000000002000, Value: `{"rows":"3"}`},
+			},
+		},
+	}, FromDomain(trace))
+}
+
+func TestFromDomainEdgeCases(t *testing.T) {
+	span := spanFromDomain(&model.Span{
+		TraceID: model.NewTraceID(1, 2),
+		SpanID:  model.NewSpanID(3),
+		Tags: []model.KeyValue{
+			model.String("span.kind", "internal"),
+			model.String("peer.ipv4", "not an ip"),
+			model.String("peer.ipv6", "10.0.0.1"),
+		},
+	})
+	assert.Equal(t, "00000000000000010000000000000002", span.TraceID)
+	assert.Equal(t, "", span.Kind)
+	assert.Equal(t, map[string]string{"span.kind": "internal"}, span.Tags)
+	assert.Nil(t, span.LocalEndpoint)
+	assert.Nil(t, span.RemoteEndpoint)
+
+	assert.Equal(t, "::1", ipv6FromDomain(model.String("peer.ipv6", "::1")))
+}
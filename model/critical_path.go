@@ -0,0 +1,98 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"sort"
+	"time"
+)
+
+// CriticalPathSegment is a period of the critical path of a trace spent in a span
+// and not waiting for any of its children.
+type CriticalPathSegment struct {
+	Span     *Span
+	Start    time.Time
+	Duration time.Duration
+}
+
+// CriticalPath returns the chain of spans that determined the end-to-end latency of the trace,
+// as segments in chronological order. Starting from the end of the root span finishing last,
+// the path goes through the child finishing last before each point of time, clipped to its parent,
+// and through the span itself between its children.
+func (t *Trace) CriticalPath() []CriticalPathSegment {
+	ids := make(map[SpanID]bool, len(t.Spans))
+	for _, span := range t.Spans {
+		ids[span.SpanID] = true
+	}
+	children := make(map[SpanID][]*Span)
+	var root *Span
+	for _, span := range t.Spans {
+		if parent := span.ParentSpanID(); parent != 0 && ids[parent] && parent != span.SpanID {
+			children[parent] = append(children[parent], span)
+		} else if root == nil || spanEnd(span).After(spanEnd(root)) {
+			root = span
+		}
+	}
+	if root == nil {
+		return nil
+	}
+	for _, spans := range children {
+		sort.SliceStable(spans, func(i, j int) bool { return spanEnd(spans[i]).After(spanEnd(spans[j])) })
+	}
+
+	var segments []CriticalPathSegment
+	visited := make(map[*Span]bool, len(t.Spans))
+	var walk func(span *Span, start, end time.Time)
+	walk = func(span *Span, start, end time.Time) {
+		visited[span] = true
+		if span.StartTime.After(start) {
+			start = span.StartTime
+		}
+		cursor := end
+		for _, child := range children[span.SpanID] {
+			if visited[child] || !child.StartTime.Before(cursor) {
+				continue
+			}
+			childEnd := spanEnd(child)
+			if childEnd.After(cursor) {
+				childEnd = cursor
+			}
+			if !childEnd.After(start) {
+				continue
+			}
+			if childEnd.Before(cursor) {
+				segments = append(segments, CriticalPathSegment{Span: span, Start: childEnd, Duration: cursor.Sub(childEnd)})
+			}
+			walk(child, start, childEnd)
+			cursor = child.StartTime
+			if cursor.Before(start) {
+				cursor = start
+			}
+		}
+		if cursor.After(start) {
+			segments = append(segments, CriticalPathSegment{Span: span, Start: start, Duration: cursor.Sub(start)})
+		}
+	}
+	walk(root, root.StartTime, spanEnd(root))
+
+	for i, j := 0, len(segments)-1; i < j; i, j = i+1, j-1 {
+		segments[i], segments[j] = segments[j], segments[i]
+	}
+	return segments
+}
+
+func spanEnd(span *Span) time.Time {
+	return span.StartTime.Add(span.Duration)
+}
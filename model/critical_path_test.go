@@ -0,0 +1,78 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+func TestTraceCriticalPath(t *testing.T) {
+	base := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	traceID := model.NewTraceID(0, 1)
+	span := func(id, parent uint64, start, end int) *model.Span {
+		s := &model.Span{
+			TraceID:   traceID,
+			SpanID:    model.NewSpanID(id),
+			StartTime: base.Add(time.Duration(start) * time.Millisecond),
+			Duration:  time.Duration(end-start) * time.Millisecond,
+		}
+		if parent != 0 {
+			s.References = []model.SpanRef{model.NewChildOfRef(traceID, model.NewSpanID(parent))}
+		}
+		return s
+	}
+	a := span(1, 0, 0, 100)
+	b := span(2, 1, 10, 40)
+	c := span(3, 1, 20, 60)
+	d := span(4, 1, 70, 80)
+	e := span(5, 1, 90, 120) // ends after its parent
+	f := span(6, 3, 25, 35)
+	g := span(7, 0, 0, 50)  // another root finishing earlier
+	h := span(8, 1, -10, 5) // starts before its parent
+	trace := &model.Trace{Spans: []*model.Span{g, b, c, h, a, d, e, f}}
+
+	segment := func(s *model.Span, start, end int) model.CriticalPathSegment {
+		return model.CriticalPathSegment{
+			Span:     s,
+			Start:    base.Add(time.Duration(start) * time.Millisecond),
+			Duration: time.Duration(end-start) * time.Millisecond,
+		}
+	}
+	expected := []model.CriticalPathSegment{
+		segment(h, 0, 5),
+		segment(a, 5, 10),
+		segment(b, 10, 20),
+		segment(c, 20, 25),
+		segment(f, 25, 35),
+		segment(c, 35, 60),
+		segment(a, 60, 70),
+		segment(d, 70, 80),
+		segment(a, 80, 90),
+		segment(e, 90, 100),
+	}
+	assert.Equal(t, expected, trace.CriticalPath())
+}
+
+func TestTraceCriticalPathSingleSpan(t *testing.T) {
+	s := &model.Span{SpanID: model.NewSpanID(1), StartTime: time.Unix(10, 0), Duration: time.Second}
+	trace := &model.Trace{Spans: []*model.Span{s}}
+	assert.Equal(t, []model.CriticalPathSegment{{Span: s, Start: s.StartTime, Duration: time.Second}}, trace.CriticalPath())
+	assert.Nil(t, (&model.Trace{}).CriticalPath())
+}
@@ -108,6 +108,28 @@ type DependencyLink struct {
 	Parent    string `json:"parent"`
 	Child     string `json:"child"`
 	CallCount uint64 `json:"callCount"`
+	// Stats are only returned when requested
+	Stats *DependencyLinkStats `json:"stats,omitempty"`
+}
+
+// DependencyLinkStats are the number of requests, of errors and the latency percentiles,
+// in milliseconds, of the calls of a dependency link. The source tells whether they were
+// read from the metrics storage or computed from a sample of the stored spans.
+type DependencyLinkStats struct {
+	Source       string  `json:"source"`
+	RequestCount uint64  `json:"requestCount"`
+	ErrorCount   uint64  `json:"errorCount"`
+	P50          float64 `json:"p50"`
+	P95          float64 `json:"p95"`
+	P99          float64 `json:"p99"`
+}
+
+// DependencyPath is a chain of calls from a service to another. Its call count is the smallest
+// call count of its links.
+type DependencyPath struct {
+	Services  []string         `json:"services"`
+	Links     []DependencyLink `json:"links"`
+	CallCount uint64           `json:"callCount"`
 }
 
 // Operation defines the data in the operation response when query operation by service and span kind
@@ -115,3 +137,131 @@ type Operation struct {
 	Name     string `json:"name"`
 	SpanKind string `json:"spanKind"`
 }
+
+// Statuses of a SpanDiff
+const (
+	SpanDiffAdded     = "added"
+	SpanDiffRemoved   = "removed"
+	SpanDiffChanged   = "changed"
+	SpanDiffUnchanged = "unchanged"
+)
+
+// TraceDiff is the structural difference between the traces A and B. The durations are in microseconds
+// and the deltas are B minus A.
+type TraceDiff struct {
+	TraceIDA      TraceID         `json:"traceIDA"`
+	TraceIDB      TraceID         `json:"traceIDB"`
+	DurationA     uint64          `json:"durationA"`
+	DurationB     uint64          `json:"durationB"`
+	DurationDelta int64           `json:"durationDelta"`
+	Spans         []SpanDiff      `json:"spans"`
+	Operations    []OperationDiff `json:"operations"`
+}
+
+// SpanDiff compares the spans of both traces found at the same path of operations from the root span.
+// The status is changed when the traces do not have the same number of spans at the path.
+type SpanDiff struct {
+	Path          []OperationRef `json:"path"`
+	Status        string         `json:"status"`
+	CountA        int            `json:"countA"`
+	CountB        int            `json:"countB"`
+	DurationA     uint64         `json:"durationA"`
+	DurationB     uint64         `json:"durationB"`
+	DurationDelta int64          `json:"durationDelta"`
+}
+
+// OperationRef identifies an operation of a service
+type OperationRef struct {
+	ServiceName   string `json:"serviceName"`
+	OperationName string `json:"operationName"`
+}
+
+// OperationDiff compares the spans of an operation in both traces, wherever they are in the traces.
+type OperationDiff struct {
+	OperationRef
+	CountA        int    `json:"countA"`
+	CountB        int    `json:"countB"`
+	DurationA     uint64 `json:"durationA"`
+	DurationB     uint64 `json:"durationB"`
+	DurationDelta int64  `json:"durationDelta"`
+}
+
+// FlameGraph is the call tree of the spans of several traces, merged by path of operations from the root
+// spans. The times are in microseconds.
+type FlameGraph struct {
+	TraceCount int               `json:"traceCount"`
+	SpanCount  int               `json:"spanCount"`
+	TotalTime  uint64            `json:"totalTime"`
+	Roots      []*FlameGraphNode `json:"roots"`
+}
+
+// FlameGraphNode aggregates the spans found at the same path in the traces. The self time is the time
+// of the spans not covered by any of their children, and the children are sorted by decreasing total time.
+type FlameGraphNode struct {
+	OperationRef
+	Count      int               `json:"count"`
+	TraceCount int               `json:"traceCount"`
+	TotalTime  uint64            `json:"totalTime"`
+	SelfTime   uint64            `json:"selfTime"`
+	Children   []*FlameGraphNode `json:"children"`
+}
+
+// CriticalPath is the chain of spans that determined the end-to-end latency of a trace.
+// The times are in microseconds.
+type CriticalPath struct {
+	TraceID  TraceID               `json:"traceID"`
+	Duration uint64                `json:"duration"`
+	Segments []CriticalPathSegment `json:"segments"`
+	Spans    []CriticalPathSpan    `json:"spans"`
+}
+
+// CriticalPathSegment is a period of the critical path spent in a span, in chronological order.
+type CriticalPathSegment struct {
+	SpanID    SpanID `json:"spanID"`
+	StartTime uint64 `json:"startTime"`
+	Duration  uint64 `json:"duration"`
+}
+
+// CriticalPathSpan is the contribution of a span to the critical path, as a percentage of its duration.
+type CriticalPathSpan struct {
+	SpanID SpanID `json:"spanID"`
+	OperationRef
+	Duration   uint64  `json:"duration"`
+	Percentage float64 `json:"percentage"`
+}
+
+// TraceStats are aggregates of the spans of a trace. The times are in microseconds, and the depth is
+// the number of spans of the longest chain from a root span.
+type TraceStats struct {
+	TraceID    TraceID        `json:"traceID"`
+	StartTime  uint64         `json:"startTime"`
+	Duration   uint64         `json:"duration"`
+	SpanCount  int            `json:"spanCount"`
+	ErrorCount int            `json:"errorCount"`
+	Depth      int            `json:"depth"`
+	Services   []ServiceStats `json:"services"`
+}
+
+// ServiceStats are the aggregates of the spans of a service in a trace, the self time being the time
+// of the spans not covered by their children.
+type ServiceStats struct {
+	ServiceName string `json:"serviceName"`
+	SpanCount   int    `json:"spanCount"`
+	ErrorCount  int    `json:"errorCount"`
+	SelfTime    uint64 `json:"selfTime"`
+}
+
+// DurationHistogram is the distribution of the durations, in microseconds, of the traces found by a search.
+type DurationHistogram struct {
+	TraceCount int              `json:"traceCount"`
+	Buckets    []DurationBucket `json:"buckets"`
+}
+
+// DurationBucket counts the traces lasting at least Min and less than Max microseconds. The trace IDs are
+// examples of these traces, so that they can be opened from the bucket.
+type DurationBucket struct {
+	Min      uint64    `json:"min"`
+	Max      uint64    `json:"max"`
+	Count    int       `json:"count"`
+	TraceIDs []TraceID `json:"traceIDs"`
+}
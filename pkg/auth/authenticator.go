@@ -0,0 +1,235 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const bearerPrefix = "bearer "
+
+var (
+	// ErrMissingToken is returned when a request does not carry a bearer token
+	ErrMissingToken = errors.New("missing bearer token")
+	// ErrInvalidToken is returned when the bearer token of a request is not accepted
+	ErrInvalidToken = errors.New("invalid bearer token")
+)
+
+type authMetrics struct {
+	Authenticated metrics.Counter `metric:"requests" tags:"result=ok"`
+	MissingToken  metrics.Counter `metric:"requests" tags:"result=missing_token"`
+	InvalidToken  metrics.Counter `metric:"requests" tags:"result=invalid_token"`
+}
+
+// Authenticator verifies the bearer tokens of incoming HTTP and gRPC requests
+// against a list of static tokens and/or an OpenID Connect issuer. With an OIDC
+// client ID, it can also log browsers in with the issuer, see SessionHandler.
+type Authenticator struct {
+	tokens   [][]byte
+	verifier *oidcVerifier
+	login    *login
+	logger   *zap.Logger
+	metrics  authMetrics
+}
+
+// NewAuthenticator creates an Authenticator for the given options.
+func NewAuthenticator(opts Options, logger *zap.Logger, metricsFactory metrics.Factory) (*Authenticator, error) {
+	a := &Authenticator{logger: logger}
+	metrics.Init(&a.metrics, metricsFactory, nil)
+	if opts.TokensFile != "" {
+		tokens, err := loadTokens(opts.TokensFile)
+		if err != nil {
+			return nil, err
+		}
+		a.tokens = tokens
+	}
+	if opts.OIDCIssuerURL != "" {
+		a.verifier = newOIDCVerifier(opts, &http.Client{Timeout: defaultHTTPTimeout})
+	}
+	if opts.OIDCClientID != "" {
+		if a.verifier == nil {
+			return nil, errors.New("the OIDC login requires an OIDC issuer")
+		}
+		l, err := newLogin(opts, a.verifier, logger)
+		if err != nil {
+			return nil, err
+		}
+		a.login = l
+	}
+	return a, nil
+}
+
+func loadTokens(path string) ([][]byte, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tokens file: %w", err)
+	}
+	defer f.Close()
+	var tokens [][]byte
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		token := strings.TrimSpace(scanner.Text())
+		if token == "" || strings.HasPrefix(token, "#") {
+			continue
+		}
+		tokens = append(tokens, []byte(token))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tokens file: %w", err)
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("tokens file %s contains no tokens", path)
+	}
+	return tokens, nil
+}
+
+// Authenticate verifies the bearer token and records the outcome in the metrics.
+func (a *Authenticator) Authenticate(ctx context.Context, token string) error {
+	_, err := a.Identify(ctx, token)
+	return err
+}
+
+// Identify is like Authenticate, but also returns the identity of the caller when the token
+// is issued by the OIDC issuer, or nil for static tokens.
+func (a *Authenticator) Identify(ctx context.Context, token string) (*Identity, error) {
+	identity, err := a.authenticate(ctx, token)
+	return identity, a.record(err)
+}
+
+func (a *Authenticator) record(err error) error {
+	switch {
+	case err == nil:
+		a.metrics.Authenticated.Inc(1)
+	case errors.Is(err, ErrMissingToken):
+		a.metrics.MissingToken.Inc(1)
+	default:
+		a.metrics.InvalidToken.Inc(1)
+		a.logger.Debug("Rejected bearer token", zap.Error(err))
+	}
+	return err
+}
+
+func (a *Authenticator) authenticate(ctx context.Context, token string) (*Identity, error) {
+	if token == "" {
+		return nil, ErrMissingToken
+	}
+	for _, t := range a.tokens {
+		if subtle.ConstantTimeCompare(t, []byte(token)) == 1 {
+			return nil, nil
+		}
+	}
+	if a.verifier == nil {
+		return nil, ErrInvalidToken
+	}
+	claims, err := a.verifier.verifyClaims(ctx, token, a.verifier.audience)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return claims.identity(), nil
+}
+
+// withIdentity returns the context with the identity, if any
+func withIdentity(ctx context.Context, identity *Identity) context.Context {
+	if identity == nil {
+		return ctx
+	}
+	return ContextWithIdentity(ctx, identity)
+}
+
+// tokenFromHeader returns the token of an "Authorization: Bearer <token>" header value
+func tokenFromHeader(header string) string {
+	if len(header) < len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return ""
+	}
+	return strings.TrimSpace(header[len(bearerPrefix):])
+}
+
+// HTTPHandler returns a handler that rejects requests without a valid bearer token
+// with 401 Unauthorized before passing them to the next handler.
+func (a *Authenticator) HTTPHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, err := a.Identify(r.Context(), tokenFromHeader(r.Header.Get("Authorization")))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(withIdentity(r.Context(), identity)))
+	})
+}
+
+// UnaryServerInterceptor returns a gRPC interceptor that rejects calls without a valid bearer
+// token with codes.Unauthenticated. Only the given full method names are protected, or all
+// methods when none are given.
+func (a *Authenticator) UnaryServerInterceptor(methods ...string) grpc.UnaryServerInterceptor {
+	protected := make(map[string]bool, len(methods))
+	for _, m := range methods {
+		protected[m] = true
+	}
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if len(protected) == 0 || protected[info.FullMethod] {
+			identity, err := a.authenticateGRPC(ctx)
+			if err != nil {
+				return nil, err
+			}
+			ctx = withIdentity(ctx, identity)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a gRPC interceptor that rejects streams without a valid bearer
+// token with codes.Unauthenticated. The token is only checked when the stream is opened.
+func (a *Authenticator) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		identity, err := a.authenticateGRPC(ss.Context())
+		if err != nil {
+			return err
+		}
+		if identity != nil {
+			ss = WithStreamContext(ContextWithIdentity(ss.Context(), identity), ss)
+		}
+		return handler(srv, ss)
+	}
+}
+
+func (a *Authenticator) authenticateGRPC(ctx context.Context) (*Identity, error) {
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			token = tokenFromHeader(values[0])
+		}
+	}
+	identity, err := a.Identify(ctx, token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return identity, nil
+}
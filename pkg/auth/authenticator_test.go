@@ -0,0 +1,188 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/jaegertracing/jaeger/pkg/config"
+)
+
+func writeTokens(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "tokens")
+	require.NoError(t, err)
+	_, err = f.WriteString(content)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	return f.Name()
+}
+
+func TestFlags(t *testing.T) {
+	flagsConfig := FlagsConfig{Prefix: "collector"}
+	v, command := config.Viperize(flagsConfig.AddFlags)
+	opts := flagsConfig.InitFromViper(v)
+	assert.False(t, opts.Enabled())
+	assert.Equal(t, defaultJWKSRefreshInterval, opts.OIDCJWKSRefreshInterval)
+
+	require.NoError(t, command.ParseFlags([]string{
+		"--collector.auth.oidc-issuer-url=https://issuer",
+		"--collector.auth.oidc-audience=jaeger",
+		"--collector.auth.oidc-tenant-claim=org",
+	}))
+	opts = flagsConfig.InitFromViper(v)
+	assert.True(t, opts.Enabled())
+	assert.Equal(t, "https://issuer", opts.OIDCIssuerURL)
+	assert.Equal(t, "jaeger", opts.OIDCAudience)
+	assert.Equal(t, "org", opts.OIDCTenantClaim)
+}
+
+func TestLoadTokens(t *testing.T) {
+	_, err := NewAuthenticator(Options{TokensFile: "/does/not/exist"}, zap.NewNop(), nil)
+	assert.Contains(t, err.Error(), "failed to open tokens file")
+
+	empty := writeTokens(t, "# no tokens\n\n")
+	defer os.Remove(empty)
+	_, err = NewAuthenticator(Options{TokensFile: empty}, zap.NewNop(), nil)
+	assert.Contains(t, err.Error(), "contains no tokens")
+}
+
+func TestStaticTokens(t *testing.T) {
+	path := writeTokens(t, "# agents\nsecret-1\n  secret-2  \n")
+	defer os.Remove(path)
+	mf := metricstest.NewFactory(0)
+	a, err := NewAuthenticator(Options{TokensFile: path}, zap.NewNop(), mf)
+	require.NoError(t, err)
+
+	assert.NoError(t, a.Authenticate(context.Background(), "secret-1"))
+	assert.NoError(t, a.Authenticate(context.Background(), "secret-2"))
+	assert.Equal(t, ErrInvalidToken, a.Authenticate(context.Background(), "secret"))
+	assert.Equal(t, ErrMissingToken, a.Authenticate(context.Background(), ""))
+
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "requests", Tags: map[string]string{"result": "ok"}, Value: 2},
+		metricstest.ExpectedMetric{Name: "requests", Tags: map[string]string{"result": "invalid_token"}, Value: 1},
+		metricstest.ExpectedMetric{Name: "requests", Tags: map[string]string{"result": "missing_token"}, Value: 1},
+	)
+}
+
+func TestTokenFromHeader(t *testing.T) {
+	assert.Equal(t, "abc", tokenFromHeader("Bearer abc"))
+	assert.Equal(t, "abc", tokenFromHeader("bearer abc"))
+	assert.Equal(t, "", tokenFromHeader("Basic abc"))
+	assert.Equal(t, "", tokenFromHeader("Bearer"))
+}
+
+func TestHTTPHandler(t *testing.T) {
+	path := writeTokens(t, "secret\n")
+	defer os.Remove(path)
+	a, err := NewAuthenticator(Options{TokensFile: path}, zap.NewNop(), nil)
+	require.NoError(t, err)
+	handler := a.HTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	tests := []struct {
+		header string
+		code   int
+	}{
+		{header: "", code: http.StatusUnauthorized},
+		{header: "Bearer other", code: http.StatusUnauthorized},
+		{header: "Bearer secret", code: http.StatusAccepted},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/traces", nil)
+		if test.header != "" {
+			req.Header.Set("Authorization", test.header)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, test.code, w.Code, test.header)
+		if test.code == http.StatusUnauthorized {
+			assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	path := writeTokens(t, "secret\n")
+	defer os.Remove(path)
+	a, err := NewAuthenticator(Options{TokensFile: path}, zap.NewNop(), nil)
+	require.NoError(t, err)
+	interceptor := a.UnaryServerInterceptor("/svc/Protected")
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	withToken := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer secret"))
+
+	res, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/svc/Protected"}, handler)
+	assert.Nil(t, res)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	res, err = interceptor(withToken, nil, &grpc.UnaryServerInfo{FullMethod: "/svc/Protected"}, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", res)
+
+	res, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/svc/Public"}, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", res)
+
+	_, err = a.UnaryServerInterceptor()(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/svc/Public"}, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	path := writeTokens(t, "secret\n")
+	defer os.Remove(path)
+	a, err := NewAuthenticator(Options{TokensFile: path}, zap.NewNop(), nil)
+	require.NoError(t, err)
+	interceptor := a.StreamServerInterceptor()
+	called := false
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		called = true
+		return nil
+	}
+
+	err = interceptor(nil, &contextStream{ctx: context.Background()}, &grpc.StreamServerInfo{}, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.False(t, called)
+
+	withToken := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer secret"))
+	err = interceptor(nil, &contextStream{ctx: withToken}, &grpc.StreamServerInfo{}, handler)
+	assert.NoError(t, err)
+	assert.True(t, called)
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"flag"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	authPrefix          = ".auth"
+	authTokensFile      = authPrefix + ".tokens-file"
+	authOIDCIssuer      = authPrefix + ".oidc-issuer-url"
+	authOIDCAudience    = authPrefix + ".oidc-audience"
+	authOIDCJWKSURL     = authPrefix + ".oidc-jwks-url"
+	authOIDCJWKSRefresh = authPrefix + ".oidc-jwks-refresh-interval"
+	authOIDCTenantClaim = authPrefix + ".oidc-tenant-claim"
+	authOIDCClientID    = authPrefix + ".oidc-client-id"
+	authOIDCSecret      = authPrefix + ".oidc-client-secret"
+	authOIDCRedirectURL = authPrefix + ".oidc-redirect-url"
+
+	defaultJWKSRefreshInterval = time.Hour
+)
+
+// FlagsConfig describes which CLI flags for bearer token authentication should be generated.
+type FlagsConfig struct {
+	Prefix string
+	// ShowLogin adds the flags of the OIDC login of browsers
+	ShowLogin bool
+}
+
+// Options describes how clients are authenticated. Authentication is disabled when
+// neither static tokens nor an OIDC issuer are configured.
+type Options struct {
+	// TokensFile is the path to a file with one accepted static token per line
+	TokensFile string
+	// OIDCIssuerURL is the issuer of the accepted OIDC tokens
+	OIDCIssuerURL string
+	// OIDCAudience is the audience the OIDC tokens must be issued for, not verified when empty
+	OIDCAudience string
+	// OIDCJWKSURL overrides the JWKS location found with OIDC discovery
+	OIDCJWKSURL string
+	// OIDCJWKSRefreshInterval is how often the signing keys of the issuer are reloaded
+	OIDCJWKSRefreshInterval time.Duration
+	// OIDCTenantClaim is the claim holding the tenant of the callers, the tokens without it are rejected when set
+	OIDCTenantClaim string
+	// OIDCClientID is the client registered at the issuer for the login of browsers, the login is disabled when empty
+	OIDCClientID string
+	// OIDCClientSecret is the secret of the client
+	OIDCClientSecret string
+	// OIDCRedirectURL is the URL of the login callback the issuer redirects browsers to
+	OIDCRedirectURL string
+}
+
+// Enabled returns true if clients must authenticate.
+func (o Options) Enabled() bool {
+	return o.TokensFile != "" || o.OIDCIssuerURL != ""
+}
+
+// LoginEnabled returns true if browsers can log in with the OIDC issuer.
+func (o Options) LoginEnabled() bool {
+	return o.OIDCIssuerURL != "" && o.OIDCClientID != ""
+}
+
+// AddFlags adds flags for authentication to the FlagSet.
+func (c FlagsConfig) AddFlags(flags *flag.FlagSet) {
+	flags.String(c.Prefix+authTokensFile, "", "Path to a file with the accepted bearer tokens, one per line (if neither this nor an OIDC issuer is set, authentication is disabled)")
+	flags.String(c.Prefix+authOIDCIssuer, "", "URL of the OpenID Connect issuer whose JWT bearer tokens are accepted")
+	flags.String(c.Prefix+authOIDCAudience, "", "Audience (aud claim) the OpenID Connect tokens must be issued for")
+	flags.String(c.Prefix+authOIDCJWKSURL, "", "URL of the JSON Web Key Set used to verify the OpenID Connect tokens (discovered from the issuer by default)")
+	flags.Duration(c.Prefix+authOIDCJWKSRefresh, defaultJWKSRefreshInterval, "How often the JSON Web Key Set of the OpenID Connect issuer is reloaded")
+	flags.String(c.Prefix+authOIDCTenantClaim, "", "Claim of the OpenID Connect tokens holding the tenant of the callers, which then takes precedence over the tenant header (if set, the tokens without this claim are rejected)")
+	if c.ShowLogin {
+		flags.String(c.Prefix+authOIDCClientID, "", "Client ID registered at the OpenID Connect issuer for the login of browsers (if not set, browsers must send a bearer token like other clients)")
+		flags.String(c.Prefix+authOIDCSecret, "", "Client secret registered at the OpenID Connect issuer")
+		flags.String(c.Prefix+authOIDCRedirectURL, "", "Public URL of the login callback the OpenID Connect issuer redirects browsers to, e.g. https://jaeger.example.com/login/callback")
+	}
+}
+
+// InitFromViper creates auth.Options populated with values retrieved from Viper.
+func (c FlagsConfig) InitFromViper(v *viper.Viper) Options {
+	return Options{
+		TokensFile:              v.GetString(c.Prefix + authTokensFile),
+		OIDCIssuerURL:           v.GetString(c.Prefix + authOIDCIssuer),
+		OIDCAudience:            v.GetString(c.Prefix + authOIDCAudience),
+		OIDCJWKSURL:             v.GetString(c.Prefix + authOIDCJWKSURL),
+		OIDCJWKSRefreshInterval: v.GetDuration(c.Prefix + authOIDCJWKSRefresh),
+		OIDCTenantClaim:         v.GetString(c.Prefix + authOIDCTenantClaim),
+		OIDCClientID:            v.GetString(c.Prefix + authOIDCClientID),
+		OIDCClientSecret:        v.GetString(c.Prefix + authOIDCSecret),
+		OIDCRedirectURL:         v.GetString(c.Prefix + authOIDCRedirectURL),
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// Identity is the caller of a request, as authenticated by the OIDC issuer of its token
+// or by a trusted proxy.
+type Identity struct {
+	Subject string
+	Groups  []string
+	// Tenant is the tenant asserted by the OIDC issuer, empty when no tenant claim is configured
+	Tenant string
+}
+
+type identityContextKey struct{}
+
+// ContextWithIdentity returns a context carrying the identity of the caller.
+func ContextWithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityContextKey{}, identity)
+}
+
+// IdentityFromContext returns the identity of the caller, or nil when it is not known,
+// e.g. when the caller was authenticated with a static token.
+func IdentityFromContext(ctx context.Context) *Identity {
+	identity, _ := ctx.Value(identityContextKey{}).(*Identity)
+	return identity
+}
+
+// contextServerStream overrides the context of a gRPC stream
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// WithStreamContext returns the stream with the given context, e.g. one carrying an identity.
+func WithStreamContext(ctx context.Context, stream grpc.ServerStream) grpc.ServerStream {
+	return &contextServerStream{ServerStream: stream, ctx: ctx}
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestClaimsGroups(t *testing.T) {
+	tests := []struct {
+		groups string
+		want   []string
+	}{
+		{groups: `["dev","ops"]`, want: []string{"dev", "ops"}},
+		{groups: `"dev"`, want: []string{"dev"}},
+		{groups: `123`},
+		{groups: ``},
+	}
+	for _, test := range tests {
+		claims := jwtClaims{Groups: json.RawMessage(test.groups)}
+		assert.Equal(t, test.want, claims.groups(), test.groups)
+	}
+}
+
+func TestIdentityFromContext(t *testing.T) {
+	assert.Nil(t, IdentityFromContext(context.Background()))
+	identity := &Identity{Subject: "alice"}
+	assert.Equal(t, identity, IdentityFromContext(ContextWithIdentity(context.Background(), identity)))
+}
+
+func TestOIDCIdentity(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	issuer := newTestIssuer(t)
+	defer issuer.server.Close()
+	issuer.setKeys(rsaJWK("rsa", key))
+	token := signRS256(t, "rsa", key, map[string]interface{}{
+		"iss":    issuer.server.URL,
+		"sub":    "alice",
+		"groups": []string{"dev"},
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	})
+	a, err := NewAuthenticator(Options{OIDCIssuerURL: issuer.server.URL}, zap.NewNop(), nil)
+	require.NoError(t, err)
+	want := &Identity{Subject: "alice", Groups: []string{"dev"}}
+
+	var got *Identity
+	handler := a.HTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = IdentityFromContext(r.Context())
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/api/traces", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, want, got)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+	_, err = a.UnaryServerInterceptor()(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		got = IdentityFromContext(ctx)
+		return nil, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	got = nil
+	err = a.StreamServerInterceptor()(nil, &contextStream{ctx: ctx}, &grpc.StreamServerInfo{}, func(srv interface{}, stream grpc.ServerStream) error {
+		got = IdentityFromContext(stream.Context())
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
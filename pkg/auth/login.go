@@ -0,0 +1,304 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	sessionCookieName = "jaeger-session"
+	stateCookieName   = "jaeger-login-state"
+	// stateMaxAge is how long a browser may take to log in at the issuer
+	stateMaxAge = 10 * time.Minute
+	returnParam = "return"
+)
+
+// login implements the OpenID Connect authorization code flow for browsers. The ID token obtained
+// for the client is kept in a session cookie, and verified on each request until it expires.
+type login struct {
+	verifier     *oidcVerifier
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	callbackPath string
+	secure       bool
+	logger       *zap.Logger
+}
+
+func newLogin(opts Options, verifier *oidcVerifier, logger *zap.Logger) (*login, error) {
+	if opts.OIDCRedirectURL == "" {
+		return nil, errors.New("the OIDC login requires a redirect URL")
+	}
+	redirectURL, err := url.Parse(opts.OIDCRedirectURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OIDC redirect URL: %w", err)
+	}
+	if !redirectURL.IsAbs() {
+		return nil, fmt.Errorf("OIDC redirect URL %q is not absolute", opts.OIDCRedirectURL)
+	}
+	callbackPath := redirectURL.Path
+	if callbackPath == "" {
+		callbackPath = "/"
+	}
+	return &login{
+		verifier:     verifier,
+		clientID:     opts.OIDCClientID,
+		clientSecret: opts.OIDCClientSecret,
+		redirectURL:  opts.OIDCRedirectURL,
+		callbackPath: callbackPath,
+		secure:       redirectURL.Scheme == "https",
+		logger:       logger,
+	}, nil
+}
+
+// SessionHandler returns a handler that accepts the session cookie of the browsers that logged in,
+// in addition to the bearer tokens accepted by HTTPHandler. When the OIDC login is enabled, it serves
+// the login at loginPath, the callback at the path of the redirect URL and the logout at logoutPath,
+// and redirects browsers navigating to a page without a valid session to the login.
+func (a *Authenticator) SessionHandler(next http.Handler, loginPath, logoutPath string) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/", a.sessionHandler(next, loginPath))
+	if a.login != nil {
+		mux.HandleFunc(loginPath, a.login.start)
+		mux.HandleFunc(a.login.callbackPath, a.login.callback)
+		mux.HandleFunc(logoutPath, a.login.logout)
+	}
+	return mux
+}
+
+func (a *Authenticator) sessionHandler(next http.Handler, loginPath string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var identity *Identity
+		var err error
+		if header := r.Header.Get("Authorization"); header != "" || a.login == nil {
+			identity, err = a.Identify(r.Context(), tokenFromHeader(header))
+		} else {
+			identity, err = a.authenticateSession(r)
+			a.record(err)
+		}
+		if err == nil {
+			next.ServeHTTP(w, r.WithContext(withIdentity(r.Context(), identity)))
+			return
+		}
+		if a.login != nil && isNavigation(r) {
+			query := url.Values{returnParam: {r.URL.RequestURI()}}
+			http.Redirect(w, r, loginPath+"?"+query.Encode(), http.StatusFound)
+			return
+		}
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	})
+}
+
+// authenticateSession verifies the ID token of the session cookie, which is issued for the client
+func (a *Authenticator) authenticateSession(r *http.Request) (*Identity, error) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil || cookie.Value == "" {
+		return nil, ErrMissingToken
+	}
+	claims, err := a.verifier.verifyClaims(r.Context(), cookie.Value, a.login.clientID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return claims.identity(), nil
+}
+
+// isNavigation returns true if the request loads a page in a browser, rather than calling the API
+func isNavigation(r *http.Request) bool {
+	return r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// returnPath returns the local path to go back to after the login or the logout
+func returnPath(r *http.Request) string {
+	return localPath(r.URL.Query().Get(returnParam))
+}
+
+func localPath(path string) string {
+	// reject absolute and protocol-relative URLs, which would make the login an open redirect
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return "/"
+	}
+	return path
+}
+
+func withQuery(endpoint string, query url.Values) string {
+	if strings.Contains(endpoint, "?") {
+		return endpoint + "&" + query.Encode()
+	}
+	return endpoint + "?" + query.Encode()
+}
+
+// start redirects the browser to the authorization endpoint of the issuer. The state sent to the
+// issuer is also kept in a cookie, to make sure that the callback completes a login of this browser.
+func (l *login) start(w http.ResponseWriter, r *http.Request) {
+	config, err := l.verifier.configuration(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if config.AuthorizationEndpoint == "" {
+		http.Error(w, "OpenID Connect configuration has no authorization_endpoint", http.StatusBadGateway)
+		return
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	state := base64.RawURLEncoding.EncodeToString(nonce)
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookieName,
+		Value:    state + "." + base64.RawURLEncoding.EncodeToString([]byte(returnPath(r))),
+		Path:     l.callbackPath,
+		MaxAge:   int(stateMaxAge / time.Second),
+		HttpOnly: true,
+		Secure:   l.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, withQuery(config.AuthorizationEndpoint, url.Values{
+		"response_type": {"code"},
+		"client_id":     {l.clientID},
+		"redirect_uri":  {l.redirectURL},
+		"scope":         {"openid"},
+		"state":         {state},
+	}), http.StatusFound)
+}
+
+// callback exchanges the authorization code for an ID token and stores it in the session cookie.
+func (l *login) callback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if e := query.Get("error"); e != "" {
+		http.Error(w, fmt.Sprintf("login failed: %s %s", e, query.Get("error_description")), http.StatusUnauthorized)
+		return
+	}
+	var state, returnTo string
+	if cookie, err := r.Cookie(stateCookieName); err == nil {
+		parts := strings.SplitN(cookie.Value, ".", 2)
+		if len(parts) == 2 {
+			state = parts[0]
+			if path, err := base64.RawURLEncoding.DecodeString(parts[1]); err == nil {
+				returnTo = localPath(string(path))
+			}
+		}
+	}
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(query.Get("state"))) != 1 {
+		http.Error(w, "login failed: invalid state", http.StatusBadRequest)
+		return
+	}
+	token, err := l.exchange(r.Context(), query.Get("code"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("login failed: %v", err), http.StatusUnauthorized)
+		return
+	}
+	claims, err := l.verifier.verifyClaims(r.Context(), token, l.clientID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("login failed: %v", err), http.StatusUnauthorized)
+		return
+	}
+	l.logger.Debug("Browser logged in", zap.String("subject", claims.Subject))
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookieName,
+		Path:     l.callbackPath,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   l.secure,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  claims.expiry,
+		HttpOnly: true,
+		Secure:   l.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, returnTo, http.StatusFound)
+}
+
+// exchange calls the token endpoint of the issuer and returns the ID token
+func (l *login) exchange(ctx context.Context, code string) (string, error) {
+	config, err := l.verifier.configuration(ctx)
+	if err != nil {
+		return "", err
+	}
+	if config.TokenEndpoint == "" {
+		return "", errors.New("OpenID Connect configuration has no token_endpoint")
+	}
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {l.redirectURL},
+	}
+	req, err := http.NewRequest(http.MethodPost, config.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(l.clientID), url.QueryEscape(l.clientSecret))
+	resp, err := l.verifier.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var tokens struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	decodeErr := json.NewDecoder(resp.Body).Decode(&tokens)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned %s %s %s", resp.Status, tokens.Error, tokens.ErrorDescription)
+	}
+	if decodeErr != nil {
+		return "", fmt.Errorf("failed to decode token response: %w", decodeErr)
+	}
+	if tokens.IDToken == "" {
+		return "", errors.New("token response has no id_token")
+	}
+	return tokens.IDToken, nil
+}
+
+// logout clears the session cookie, and ends the session at the issuer if it supports it.
+func (l *login) logout(w http.ResponseWriter, r *http.Request) {
+	target := returnPath(r)
+	if config, err := l.verifier.configuration(r.Context()); err == nil && config.EndSessionEndpoint != "" {
+		query := url.Values{"client_id": {l.clientID}}
+		if cookie, err := r.Cookie(sessionCookieName); err == nil && cookie.Value != "" {
+			query.Set("id_token_hint", cookie.Value)
+		}
+		target = withQuery(config.EndSessionEndpoint, query)
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   l.secure,
+	})
+	http.Redirect(w, r, target, http.StatusFound)
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/pkg/config"
+)
+
+func TestLoginFlags(t *testing.T) {
+	flagsConfig := FlagsConfig{Prefix: "query", ShowLogin: true}
+	v, command := config.Viperize(flagsConfig.AddFlags)
+	require.NoError(t, command.ParseFlags([]string{
+		"--query.auth.oidc-issuer-url=https://issuer",
+		"--query.auth.oidc-client-id=jaeger",
+		"--query.auth.oidc-client-secret=secret",
+		"--query.auth.oidc-redirect-url=https://jaeger/login/callback",
+	}))
+	opts := flagsConfig.InitFromViper(v)
+	assert.True(t, opts.LoginEnabled())
+	assert.Equal(t, "jaeger", opts.OIDCClientID)
+	assert.Equal(t, "secret", opts.OIDCClientSecret)
+	assert.Equal(t, "https://jaeger/login/callback", opts.OIDCRedirectURL)
+
+	_, command = config.Viperize(FlagsConfig{Prefix: "collector"}.AddFlags)
+	assert.Nil(t, command.Flags().Lookup("collector.auth.oidc-client-id"))
+}
+
+func TestNewLoginErrors(t *testing.T) {
+	tests := []struct {
+		opts Options
+		err  string
+	}{
+		{opts: Options{OIDCClientID: "jaeger"}, err: "the OIDC login requires an OIDC issuer"},
+		{opts: Options{OIDCIssuerURL: "https://issuer", OIDCClientID: "jaeger"}, err: "the OIDC login requires a redirect URL"},
+		{opts: Options{OIDCIssuerURL: "https://issuer", OIDCClientID: "jaeger", OIDCRedirectURL: "/login/callback"}, err: `OIDC redirect URL "/login/callback" is not absolute`},
+		{opts: Options{OIDCIssuerURL: "https://issuer", OIDCClientID: "jaeger", OIDCRedirectURL: "http://%zz"}, err: "invalid OIDC redirect URL"},
+	}
+	for _, test := range tests {
+		_, err := NewAuthenticator(test.opts, zap.NewNop(), nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), test.err)
+	}
+}
+
+func TestLocalPath(t *testing.T) {
+	assert.Equal(t, "/jaeger/search?service=a", localPath("/jaeger/search?service=a"))
+	assert.Equal(t, "/", localPath(""))
+	assert.Equal(t, "/", localPath("https://evil"))
+	assert.Equal(t, "/", localPath("//evil"))
+	assert.Equal(t, "/", localPath("/\\evil"))
+}
+
+func cookieNamed(resp *http.Response, name string) *http.Cookie {
+	for _, c := range resp.Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestLogin(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	issuer := newTestIssuer(t)
+	defer issuer.server.Close()
+	issuer.setKeys(rsaJWK("rsa", key))
+	exp := time.Now().Add(time.Hour).Unix()
+	issuer.idToken = signRS256(t, "rsa", key, map[string]interface{}{"iss": issuer.server.URL, "aud": "jaeger", "sub": "alice", "exp": exp})
+
+	a, err := NewAuthenticator(Options{
+		OIDCIssuerURL:    issuer.server.URL,
+		OIDCAudience:     "api",
+		OIDCClientID:     "jaeger",
+		OIDCClientSecret: "secret",
+		OIDCRedirectURL:  "https://jaeger.example.com/jaeger/login/callback",
+	}, zap.NewNop(), nil)
+	require.NoError(t, err)
+	handler := a.SessionHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if identity := IdentityFromContext(r.Context()); identity != nil {
+			w.Header().Set("X-Subject", identity.Subject)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}), "/jaeger/login", "/jaeger/logout")
+	serve := func(req *http.Request) *http.Response {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Result()
+	}
+
+	// browsers are sent to the login, API calls are rejected
+	req := httptest.NewRequest(http.MethodGet, "/jaeger/search?service=a", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	resp := serve(req)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "/jaeger/login?return=%2Fjaeger%2Fsearch%3Fservice%3Da", resp.Header.Get("Location"))
+	resp = serve(httptest.NewRequest(http.MethodGet, "/jaeger/api/traces", nil))
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp = serve(httptest.NewRequest(http.MethodGet, "/jaeger/login?return=%2Fjaeger%2Fsearch", nil))
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, issuer.server.URL+"/authorize", location.Scheme+"://"+location.Host+location.Path)
+	assert.Equal(t, "code", location.Query().Get("response_type"))
+	assert.Equal(t, "jaeger", location.Query().Get("client_id"))
+	assert.Equal(t, "https://jaeger.example.com/jaeger/login/callback", location.Query().Get("redirect_uri"))
+	state := location.Query().Get("state")
+	require.NotEmpty(t, state)
+	stateCookie := cookieNamed(resp, stateCookieName)
+	require.NotNil(t, stateCookie)
+	assert.Equal(t, "/jaeger/login/callback", stateCookie.Path)
+	assert.True(t, stateCookie.Secure)
+
+	callback := func(query string, cookie *http.Cookie) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, "/jaeger/login/callback?"+query, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		return serve(req)
+	}
+	assert.Equal(t, http.StatusBadRequest, callback("code=code&state="+state, nil).StatusCode)
+	assert.Equal(t, http.StatusBadRequest, callback("code=code&state=other", stateCookie).StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, callback("error=access_denied", stateCookie).StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, callback("code=other&state="+state, stateCookie).StatusCode)
+
+	resp = callback("code=code&state="+state, stateCookie)
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "/jaeger/search", resp.Header.Get("Location"))
+	session := cookieNamed(resp, sessionCookieName)
+	require.NotNil(t, session)
+	assert.Equal(t, issuer.idToken, session.Value)
+	assert.True(t, session.HttpOnly)
+	assert.Equal(t, exp, session.Expires.Unix())
+
+	// the session is verified for the client, not the audience of the API tokens
+	req = httptest.NewRequest(http.MethodGet, "/jaeger/api/traces", nil)
+	req.AddCookie(session)
+	resp = serve(req)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, "alice", resp.Header.Get("X-Subject"))
+	req = httptest.NewRequest(http.MethodGet, "/jaeger/api/traces", nil)
+	req.Header.Set("Authorization", "Bearer "+issuer.idToken)
+	assert.Equal(t, http.StatusUnauthorized, serve(req).StatusCode)
+	req = httptest.NewRequest(http.MethodGet, "/jaeger/api/traces", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: signRS256(t, "rsa", key, map[string]interface{}{"iss": issuer.server.URL, "aud": "other", "exp": exp})})
+	assert.Equal(t, http.StatusUnauthorized, serve(req).StatusCode)
+
+	req = httptest.NewRequest(http.MethodGet, "/jaeger/logout", nil)
+	req.AddCookie(session)
+	resp = serve(req)
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.True(t, strings.HasPrefix(resp.Header.Get("Location"), issuer.server.URL+"/logout?"))
+	assert.Contains(t, resp.Header.Get("Location"), "id_token_hint="+issuer.idToken)
+	cleared := cookieNamed(resp, sessionCookieName)
+	require.NotNil(t, cleared)
+	assert.True(t, cleared.MaxAge < 0)
+}
+
+func TestSessionHandlerWithoutLogin(t *testing.T) {
+	path := writeTokens(t, "secret\n")
+	defer os.Remove(path)
+	a, err := NewAuthenticator(Options{TokensFile: path}, zap.NewNop(), nil)
+	require.NoError(t, err)
+	handler := a.SessionHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}), "/login", "/logout")
+
+	for _, target := range []string{"/search", "/login"} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Accept", "text/html")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, target)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/search", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+}
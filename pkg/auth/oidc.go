@@ -0,0 +1,324 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc"
+	"gopkg.in/square/go-jose.v2"
+)
+
+const (
+	defaultHTTPTimeout = 10 * time.Second
+	// minKeysRefresh limits how often an unknown key ID triggers a reload of the key set
+	minKeysRefresh = 10 * time.Second
+	// clockSkew is tolerated when checking the exp and nbf claims
+	clockSkew = 30 * time.Second
+)
+
+// supportedSigningAlgs are the asymmetric algorithms accepted in the JWT header
+var supportedSigningAlgs = []string{
+	oidc.RS256, oidc.RS384, oidc.RS512,
+	oidc.ES256, oidc.ES384, oidc.ES512,
+	oidc.PS256, oidc.PS384, oidc.PS512,
+}
+
+// oidcVerifier verifies JWT tokens signed by an OpenID Connect issuer with the keys
+// published in its JSON Web Key Set. The key set is loaded lazily on the first token.
+// Tokens are parsed and their claims validated by go-oidc, and the verifier is the
+// oidc.KeySet that checks their signature with go-jose.
+type oidcVerifier struct {
+	issuer          string
+	audience        string
+	jwksURL         string
+	refreshInterval time.Duration
+	tenantClaim     string
+	client          *http.Client
+	now             func() time.Time
+
+	mux         sync.Mutex
+	keys        map[string]jose.JSONWebKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+
+	configMux sync.Mutex
+	config    *oidcConfiguration
+}
+
+// oidcConfiguration is the part of the OpenID Connect discovery document used by Jaeger
+type oidcConfiguration struct {
+	Issuer                string `json:"issuer"`
+	JWKSURI               string `json:"jwks_uri"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	EndSessionEndpoint    string `json:"end_session_endpoint"`
+}
+
+func newOIDCVerifier(opts Options, client *http.Client) *oidcVerifier {
+	refresh := opts.OIDCJWKSRefreshInterval
+	if refresh <= 0 {
+		refresh = defaultJWKSRefreshInterval
+	}
+	return &oidcVerifier{
+		issuer:          strings.TrimSuffix(opts.OIDCIssuerURL, "/"),
+		audience:        opts.OIDCAudience,
+		jwksURL:         opts.OIDCJWKSURL,
+		refreshInterval: refresh,
+		tenantClaim:     opts.OIDCTenantClaim,
+		client:          client,
+		now:             time.Now,
+	}
+}
+
+type jwtClaims struct {
+	Issuer  string          `json:"iss"`
+	Subject string          `json:"sub"`
+	Groups  json.RawMessage `json:"groups"`
+
+	tenant string
+	expiry time.Time
+}
+
+// groups returns the groups claim, a string or an array of strings
+func (c *jwtClaims) groups() []string {
+	var single string
+	if err := json.Unmarshal(c.Groups, &single); err == nil {
+		return []string{single}
+	}
+	var list []string
+	json.Unmarshal(c.Groups, &list)
+	return list
+}
+
+func (c *jwtClaims) identity() *Identity {
+	return &Identity{Subject: c.Subject, Groups: c.groups(), Tenant: c.tenant}
+}
+
+func (v *oidcVerifier) verify(ctx context.Context, token string) error {
+	_, err := v.verifyClaims(ctx, token, v.audience)
+	return err
+}
+
+// verifyClaims verifies the token like verify, but for the given audience, and returns its claims
+func (v *oidcVerifier) verifyClaims(ctx context.Context, token string, audience string) (*jwtClaims, error) {
+	verifier := oidc.NewVerifier(v.issuer, v, &oidc.Config{
+		ClientID:             audience,
+		SkipClientIDCheck:    audience == "",
+		SupportedSigningAlgs: supportedSigningAlgs,
+		// the issuer is compared below regardless of a trailing slash
+		SkipIssuerCheck: true,
+		Now:             func() time.Time { return v.now().Add(-clockSkew) },
+	})
+	idToken, err := verifier.Verify(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	var claims jwtClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("malformed JWT claims: %w", err)
+	}
+	if strings.TrimSuffix(claims.Issuer, "/") != v.issuer {
+		return nil, fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	}
+	if v.tenantClaim != "" {
+		tenant, err := stringClaim(idToken, v.tenantClaim)
+		if err != nil {
+			return nil, err
+		}
+		claims.tenant = tenant
+	}
+	// go-oidc rejects tokens without the exp claim as expired
+	claims.expiry = idToken.Expiry
+	return &claims, nil
+}
+
+// stringClaim returns the non-empty string value of the claim of the token
+func stringClaim(idToken *oidc.IDToken, name string) (string, error) {
+	var all map[string]interface{}
+	if err := idToken.Claims(&all); err != nil {
+		return "", fmt.Errorf("malformed JWT claims: %w", err)
+	}
+	value, _ := all[name].(string)
+	if value == "" {
+		return "", fmt.Errorf("missing %s claim", name)
+	}
+	return value, nil
+}
+
+// VerifySignature implements oidc.KeySet. The key must be published for signatures, and suit
+// the algorithm of the token: its alg, when set, must be the same, and the key type and curve
+// must match the algorithm.
+func (v *oidcVerifier) VerifySignature(ctx context.Context, token string) ([]byte, error) {
+	jws, err := jose.ParseSigned(token)
+	if err != nil {
+		return nil, fmt.Errorf("malformed JWT: %w", err)
+	}
+	if len(jws.Signatures) != 1 {
+		return nil, errors.New("JWT must have exactly one signature")
+	}
+	header := jws.Signatures[0].Header
+	key, err := v.key(ctx, header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if !keySuits(key, header.Algorithm) {
+		return nil, fmt.Errorf("key %q is not suitable for %s", key.KeyID, header.Algorithm)
+	}
+	payload, err := jws.Verify(&key)
+	if err != nil {
+		return nil, errors.New("invalid JWT signature")
+	}
+	return payload, nil
+}
+
+// keySuits returns true if the key can verify signatures made with the algorithm.
+func keySuits(key jose.JSONWebKey, alg string) bool {
+	if key.Algorithm != "" && key.Algorithm != alg {
+		return false
+	}
+	switch k := key.Key.(type) {
+	case *rsa.PublicKey:
+		return strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS")
+	case *ecdsa.PublicKey:
+		switch alg {
+		case oidc.ES256:
+			return k.Curve == elliptic.P256()
+		case oidc.ES384:
+			return k.Curve == elliptic.P384()
+		case oidc.ES512:
+			return k.Curve == elliptic.P521()
+		}
+	}
+	return false
+}
+
+func (v *oidcVerifier) key(ctx context.Context, kid string) (jose.JSONWebKey, error) {
+	v.mux.Lock()
+	defer v.mux.Unlock()
+	now := v.now()
+	key, ok := v.lookup(kid)
+	stale := v.keys == nil || now.Sub(v.fetchedAt) > v.refreshInterval
+	if (!ok || stale) && now.Sub(v.attemptedAt) > minKeysRefresh {
+		v.attemptedAt = now
+		keys, err := v.fetchKeys(ctx)
+		if err != nil {
+			if v.keys == nil {
+				return jose.JSONWebKey{}, err
+			}
+			// keep using the keys loaded previously
+		} else {
+			v.keys = keys
+			v.fetchedAt = now
+		}
+		key, ok = v.lookup(kid)
+	}
+	if v.keys == nil {
+		return jose.JSONWebKey{}, errors.New("JSON Web Key Set is not loaded")
+	}
+	if !ok {
+		return jose.JSONWebKey{}, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+func (v *oidcVerifier) lookup(kid string) (jose.JSONWebKey, bool) {
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, true
+		}
+	}
+	key, ok := v.keys[kid]
+	return key, ok
+}
+
+func (v *oidcVerifier) getJSON(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// configuration returns the discovery document of the issuer, which is only loaded once it was found valid
+func (v *oidcVerifier) configuration(ctx context.Context) (*oidcConfiguration, error) {
+	v.configMux.Lock()
+	defer v.configMux.Unlock()
+	if v.config != nil {
+		return v.config, nil
+	}
+	var config oidcConfiguration
+	if err := v.getJSON(ctx, v.issuer+"/.well-known/openid-configuration", &config); err != nil {
+		return nil, fmt.Errorf("failed to discover OpenID Connect configuration: %w", err)
+	}
+	if strings.TrimSuffix(config.Issuer, "/") != v.issuer {
+		return nil, fmt.Errorf("discovered issuer %q does not match %q", config.Issuer, v.issuer)
+	}
+	v.config = &config
+	return v.config, nil
+}
+
+func (v *oidcVerifier) fetchKeys(ctx context.Context) (map[string]jose.JSONWebKey, error) {
+	if v.jwksURL == "" {
+		config, err := v.configuration(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if config.JWKSURI == "" {
+			return nil, errors.New("OpenID Connect configuration has no jwks_uri")
+		}
+		v.jwksURL = config.JWKSURI
+	}
+	var jwks struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := v.getJSON(ctx, v.jwksURL, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to load JSON Web Key Set: %w", err)
+	}
+	keys := make(map[string]jose.JSONWebKey, len(jwks.Keys))
+	for _, raw := range jwks.Keys {
+		var key jose.JSONWebKey
+		if err := key.UnmarshalJSON(raw); err != nil {
+			return nil, fmt.Errorf("invalid key in JSON Web Key Set: %w", err)
+		}
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		if !key.IsPublic() {
+			// symmetric keys must not be used to verify the tokens of an issuer
+			continue
+		}
+		keys[key.KeyID] = key
+	}
+	return keys, nil
+}
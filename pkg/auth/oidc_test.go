@@ -0,0 +1,251 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type testIssuer struct {
+	server *httptest.Server
+	mux    sync.Mutex
+	keys   []map[string]string
+	hits   int
+	// idToken is returned by the token endpoint for the authorization code "code"
+	idToken string
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	issuer := &testIssuer{}
+	issuer.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		issuer.mux.Lock()
+		defer issuer.mux.Unlock()
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{
+				"issuer":                 issuer.server.URL,
+				"jwks_uri":               issuer.server.URL + "/keys",
+				"authorization_endpoint": issuer.server.URL + "/authorize",
+				"token_endpoint":         issuer.server.URL + "/token",
+				"end_session_endpoint":   issuer.server.URL + "/logout",
+			})
+		case "/keys":
+			issuer.hits++
+			json.NewEncoder(w).Encode(map[string]interface{}{"keys": issuer.keys})
+		case "/token":
+			if id, secret, _ := r.BasicAuth(); id != "jaeger" || secret != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+				return
+			}
+			if r.PostFormValue("code") != "code" || r.PostFormValue("grant_type") != "authorization_code" {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"id_token": issuer.idToken, "token_type": "Bearer"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return issuer
+}
+
+func (i *testIssuer) setKeys(keys ...map[string]string) {
+	i.mux.Lock()
+	defer i.mux.Unlock()
+	i.keys = keys
+}
+
+func encodeSegment(t *testing.T, v interface{}) string {
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func rsaJWK(kid string, key *rsa.PrivateKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PrivateKey) map[string]string {
+	size := (key.Curve.Params().BitSize + 7) / 8
+	return map[string]string{
+		"kty": "EC",
+		"kid": kid,
+		"crv": key.Curve.Params().Name,
+		"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, size))),
+		"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, size))),
+	}
+}
+
+func signRS256(t *testing.T, kid string, key *rsa.PrivateKey, claims map[string]interface{}) string {
+	signed := encodeSegment(t, map[string]string{"alg": "RS256", "kid": kid}) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func signES256(t *testing.T, kid string, key *ecdsa.PrivateKey, claims map[string]interface{}) string {
+	signed := encodeSegment(t, map[string]string{"alg": "ES256", "kid": kid}) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+	size := (key.Curve.Params().BitSize + 7) / 8
+	signature := make([]byte, 2*size)
+	r.FillBytes(signature[:size])
+	s.FillBytes(signature[size:])
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestOIDCVerifier(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	issuer := newTestIssuer(t)
+	defer issuer.server.Close()
+	rs384JWK := rsaJWK("rs384", rsaKey)
+	rs384JWK["alg"] = "RS384"
+	issuer.setKeys(rsaJWK("rsa", rsaKey), ecJWK("ec", ecKey), ecJWK("p384", p384Key), rs384JWK)
+
+	a, err := NewAuthenticator(Options{OIDCIssuerURL: issuer.server.URL, OIDCAudience: "jaeger"}, zap.NewNop(), nil)
+	require.NoError(t, err)
+
+	exp := time.Now().Add(time.Hour).Unix()
+	valid := map[string]interface{}{"iss": issuer.server.URL, "aud": "jaeger", "exp": exp}
+	tests := []struct {
+		name  string
+		token string
+		err   string
+	}{
+		{name: "RS256", token: signRS256(t, "rsa", rsaKey, valid)},
+		{name: "ES256", token: signES256(t, "ec", ecKey, valid)},
+		{name: "audience list", token: signRS256(t, "rsa", rsaKey, map[string]interface{}{"iss": issuer.server.URL, "aud": []string{"other", "jaeger"}, "exp": exp})},
+		{name: "malformed", token: "abc", err: "malformed jwt"},
+		{name: "wrong signature", token: signRS256(t, "rsa", otherKey, valid), err: "invalid JWT signature"},
+		{name: "unknown key", token: signRS256(t, "other", otherKey, valid), err: `unknown signing key "other"`},
+		{name: "wrong key type", token: signRS256(t, "ec", rsaKey, valid), err: `key "ec" is not suitable for RS256`},
+		{name: "wrong key algorithm", token: signRS256(t, "rs384", rsaKey, valid), err: `key "rs384" is not suitable for RS256`},
+		{name: "wrong key curve", token: signES256(t, "p384", p384Key, valid), err: `key "p384" is not suitable for ES256`},
+		{name: "unsupported algorithm", token: encodeSegment(t, map[string]string{"alg": "HS256", "kid": "rsa"}) + "." + encodeSegment(t, valid) + ".c2ln", err: "unsupported algorithm"},
+		{name: "wrong issuer", token: signRS256(t, "rsa", rsaKey, map[string]interface{}{"iss": "https://evil", "aud": "jaeger", "exp": exp}), err: "unexpected issuer"},
+		{name: "wrong audience", token: signRS256(t, "rsa", rsaKey, map[string]interface{}{"iss": issuer.server.URL, "aud": "other", "exp": exp}), err: `expected audience "jaeger"`},
+		{name: "no expiration", token: signRS256(t, "rsa", rsaKey, map[string]interface{}{"iss": issuer.server.URL, "aud": "jaeger"}), err: "expired"},
+		{name: "expired", token: signRS256(t, "rsa", rsaKey, map[string]interface{}{"iss": issuer.server.URL, "aud": "jaeger", "exp": time.Now().Add(-time.Hour).Unix()}), err: "expired"},
+		{name: "not yet valid", token: signRS256(t, "rsa", rsaKey, map[string]interface{}{"iss": issuer.server.URL, "aud": "jaeger", "exp": exp, "nbf": exp}), err: "nbf"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := a.Authenticate(context.Background(), test.token)
+			if test.err == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.True(t, strings.HasPrefix(err.Error(), ErrInvalidToken.Error()), err.Error())
+				assert.Contains(t, err.Error(), test.err)
+			}
+		})
+	}
+
+	// unknown keys are reloaded at most every minKeysRefresh
+	assert.Equal(t, 1, issuer.hits)
+}
+
+func TestOIDCVerifierTenantClaim(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	issuer := newTestIssuer(t)
+	defer issuer.server.Close()
+	issuer.setKeys(rsaJWK("rsa", key))
+
+	a, err := NewAuthenticator(Options{OIDCIssuerURL: issuer.server.URL, OIDCTenantClaim: "org"}, zap.NewNop(), nil)
+	require.NoError(t, err)
+
+	exp := time.Now().Add(time.Hour).Unix()
+	identity, err := a.Identify(context.Background(), signRS256(t, "rsa", key, map[string]interface{}{"iss": issuer.server.URL, "sub": "alice", "exp": exp, "org": "acme"}))
+	require.NoError(t, err)
+	assert.Equal(t, &Identity{Subject: "alice", Tenant: "acme"}, identity)
+
+	_, err = a.Identify(context.Background(), signRS256(t, "rsa", key, map[string]interface{}{"iss": issuer.server.URL, "sub": "alice", "exp": exp}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing org claim")
+}
+
+func TestOIDCVerifierKeyRotation(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	issuer := newTestIssuer(t)
+	defer issuer.server.Close()
+	issuer.setKeys(rsaJWK("old", oldKey))
+
+	now := time.Now()
+	v := newOIDCVerifier(Options{OIDCIssuerURL: issuer.server.URL, OIDCJWKSURL: issuer.server.URL + "/keys"}, http.DefaultClient)
+	v.now = func() time.Time { return now }
+	claims := map[string]interface{}{"iss": issuer.server.URL, "exp": now.Add(time.Hour).Unix()}
+
+	require.NoError(t, v.verify(context.Background(), signRS256(t, "old", oldKey, claims)))
+
+	issuer.setKeys(rsaJWK("new", newKey))
+	assert.Error(t, v.verify(context.Background(), signRS256(t, "new", newKey, claims)))
+
+	now = now.Add(minKeysRefresh + time.Second)
+	assert.NoError(t, v.verify(context.Background(), signRS256(t, "new", newKey, claims)))
+	assert.Equal(t, 2, issuer.hits)
+}
+
+func TestOIDCVerifierIssuerUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	v := newOIDCVerifier(Options{OIDCIssuerURL: server.URL}, http.DefaultClient)
+	err = v.verify(context.Background(), signRS256(t, "rsa", key, map[string]interface{}{"iss": server.URL, "exp": time.Now().Add(time.Hour).Unix()}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to discover OpenID Connect configuration")
+}
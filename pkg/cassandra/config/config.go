@@ -46,6 +46,15 @@ type Configuration struct {
 	DisableAutoDiscovery bool           `yaml:"disable_auto_discovery" mapstructure:"-"`
 	EnableDependenciesV2 bool           `yaml:"enable_dependencies_v2" mapstructure:"-"`
 	TLS                  tlscfg.Options `mapstructure:"tls"`
+
+	SpeculativeExecution SpeculativeExecution `yaml:"speculative_execution" mapstructure:"speculative_execution"`
+}
+
+// SpeculativeExecution configures the speculative execution of the reads: a read without response after Delay
+// is sent to another host, up to Attempts more times, and the first response is used
+type SpeculativeExecution struct {
+	Attempts int           `yaml:"attempts" mapstructure:"attempts"`
+	Delay    time.Duration `yaml:"delay" mapstructure:"delay"`
 }
 
 // Authenticator holds the authentication properties needed to connect to a Cassandra cluster
@@ -86,6 +95,12 @@ func (c *Configuration) ApplyDefaults(source *Configuration) {
 	if c.SocketKeepAlive == 0 {
 		c.SocketKeepAlive = source.SocketKeepAlive
 	}
+	if c.SpeculativeExecution.Attempts == 0 {
+		c.SpeculativeExecution.Attempts = source.SpeculativeExecution.Attempts
+	}
+	if c.SpeculativeExecution.Delay == 0 {
+		c.SpeculativeExecution.Delay = source.SpeculativeExecution.Delay
+	}
 }
 
 // SessionBuilder creates new cassandra.Session
@@ -95,12 +110,22 @@ type SessionBuilder interface {
 
 // NewSession creates a new Cassandra session
 func (c *Configuration) NewSession() (cassandra.Session, error) {
+	if c.SpeculativeExecution.Attempts > 0 && c.SpeculativeExecution.Delay <= 0 {
+		return nil, fmt.Errorf("the delay of the speculative execution must be positive, got %v", c.SpeculativeExecution.Delay)
+	}
 	cluster := c.NewCluster()
 	session, err := cluster.CreateSession()
 	if err != nil {
 		return nil, err
 	}
-	return gocqlw.WrapCQLSession(session), nil
+	cqlSession := gocqlw.WrapCQLSession(session)
+	if c.SpeculativeExecution.Attempts > 0 {
+		cqlSession = cqlSession.WithSpeculativeExecution(&gocql.SimpleSpeculativeExecution{
+			NumAttempts:  c.SpeculativeExecution.Attempts,
+			TimeoutDelay: c.SpeculativeExecution.Delay,
+		})
+	}
+	return cqlSession, nil
 }
 
 // NewCluster creates a new gocql cluster from the configuration
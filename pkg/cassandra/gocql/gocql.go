@@ -16,6 +16,8 @@
 package gocql
 
 import (
+	"strings"
+
 	"github.com/gocql/gocql"
 
 	"github.com/jaegertracing/jaeger/pkg/cassandra"
@@ -23,7 +25,8 @@ import (
 
 // CQLSession is a wrapper around gocql.Session.
 type CQLSession struct {
-	session *gocql.Session
+	session              *gocql.Session
+	speculativeExecution gocql.SpeculativeExecutionPolicy
 }
 
 // WrapCQLSession creates a Session out of *gocql.Session.
@@ -31,9 +34,20 @@ func WrapCQLSession(session *gocql.Session) CQLSession {
 	return CQLSession{session: session}
 }
 
+// WithSpeculativeExecution returns a copy of the session executing the SELECT queries with the given policy.
+// The other queries are not executed speculatively, as they may not be idempotent.
+func (s CQLSession) WithSpeculativeExecution(policy gocql.SpeculativeExecutionPolicy) CQLSession {
+	s.speculativeExecution = policy
+	return s
+}
+
 // Query delegates to gocql.Session#Query and wraps the result as Query.
 func (s CQLSession) Query(stmt string, values ...interface{}) cassandra.Query {
-	return WrapCQLQuery(s.session.Query(stmt, values...))
+	query := s.session.Query(stmt, values...)
+	if s.speculativeExecution != nil && isSelect(stmt) {
+		query = query.Idempotent(true).SetSpeculativeExecutionPolicy(s.speculativeExecution)
+	}
+	return WrapCQLQuery(query)
 }
 
 // Close delegates to gocql.Session#Close.
@@ -41,6 +55,11 @@ func (s CQLSession) Close() {
 	s.session.Close()
 }
 
+func isSelect(stmt string) bool {
+	stmt = strings.TrimSpace(stmt)
+	return len(stmt) > len("SELECT") && strings.EqualFold(stmt[:len("SELECT")], "SELECT")
+}
+
 // ---
 
 // CQLQuery is a wrapper around gocql.Query.
@@ -88,6 +107,11 @@ func (q CQLQuery) PageSize(n int) cassandra.Query {
 	return WrapCQLQuery(q.query.PageSize(n))
 }
 
+// PageState delegates to gocql.Query#PageState and wraps the result as Query.
+func (q CQLQuery) PageState(state []byte) cassandra.Query {
+	return WrapCQLQuery(q.query.PageState(state))
+}
+
 // ---
 
 // CQLIterator is a wrapper around gocql.Iter.
@@ -109,3 +133,8 @@ func (i CQLIterator) Scan(dest ...interface{}) bool {
 func (i CQLIterator) Close() error {
 	return i.iter.Close()
 }
+
+// PageState delegates to gocql.Iter#PageState.
+func (i CQLIterator) PageState() []byte {
+	return i.iter.PageState()
+}
@@ -0,0 +1,29 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gocql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsSelect(t *testing.T) {
+	assert.True(t, isSelect("SELECT * FROM traces"))
+	assert.True(t, isSelect("\n\t\tselect trace_id\n\t\tFROM tag_index"))
+	assert.False(t, isSelect("INSERT INTO traces(trace_id) VALUES (?)"))
+	assert.False(t, isSelect("SELECT"))
+	assert.False(t, isSelect(""))
+}
@@ -64,10 +64,14 @@ type Query interface {
 	Bind(v ...interface{}) Query
 	Consistency(level Consistency) Query
 	PageSize(int) Query
+	// PageState starts the query at the page returned by Iterator.PageState, and only reads that page.
+	PageState(state []byte) Query
 }
 
 // Iterator is an abstraction of gocql.Iter
 type Iterator interface {
 	Scan(dest ...interface{}) bool
 	Close() error
+	// PageState returns the state of the next page, empty if the page read was the last one.
+	PageState() []byte
 }
@@ -0,0 +1,150 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// Client is an abstraction of the HTTP interface of ClickHouse.
+// The queries take their parameters with the {name:Type} syntax of ClickHouse.
+type Client interface {
+	// Exec runs a statement returning no rows, such as a CREATE TABLE.
+	Exec(ctx context.Context, query string, params map[string]string) error
+	// Insert inserts the rows, encoded in JSON, into the table.
+	Insert(ctx context.Context, table string, rows []interface{}) error
+	// Query runs a SELECT and calls row with each of the rows of the result, encoded in JSON.
+	Query(ctx context.Context, query string, params map[string]string, row func(json.RawMessage) error) error
+}
+
+// HTTPClient is the Client of the HTTP interface of a ClickHouse server.
+type HTTPClient struct {
+	client   *http.Client
+	url      string
+	database string
+	username string
+	password string
+}
+
+// NewHTTPClient creates a Client of the server at the URL running the queries in the database.
+func NewHTTPClient(client *http.Client, serverURL, database, username, password string) *HTTPClient {
+	return &HTTPClient{
+		client:   client,
+		url:      serverURL,
+		database: database,
+		username: username,
+		password: password,
+	}
+}
+
+// Exec implements Client.
+func (c *HTTPClient) Exec(ctx context.Context, query string, params map[string]string) error {
+	resp, err := c.do(ctx, query, params, nil)
+	if err != nil {
+		return err
+	}
+	return resp.Close()
+}
+
+// Insert implements Client. The rows are compressed with gzip.
+func (c *HTTPClient) Insert(ctx context.Context, table string, rows []interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	encoder := json.NewEncoder(zw)
+	for _, row := range rows {
+		if err := encoder.Encode(row); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	resp, err := c.do(ctx, "INSERT INTO "+table+" FORMAT JSONEachRow", nil, &body)
+	if err != nil {
+		return err
+	}
+	return resp.Close()
+}
+
+// Query implements Client.
+func (c *HTTPClient) Query(ctx context.Context, query string, params map[string]string, row func(json.RawMessage) error) error {
+	resp, err := c.do(ctx, query+" FORMAT JSONEachRow", params, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+	scanner := bufio.NewScanner(resp)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if err := row(json.RawMessage(scanner.Bytes())); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// do sends the query, in the body unless the body holds the data of an insert.
+func (c *HTTPClient) do(ctx context.Context, query string, params map[string]string, data io.Reader) (io.ReadCloser, error) {
+	values := url.Values{}
+	values.Set("database", c.database)
+	// the 64 bits integers are returned as numbers rather than strings
+	values.Set("output_format_json_quote_64bit_integers", "0")
+	for name, value := range params {
+		values.Set("param_"+name, value)
+	}
+	body := data
+	if data == nil {
+		body = bytes.NewBufferString(query)
+	} else {
+		values.Set("query", query)
+	}
+	req, err := http.NewRequest(http.MethodPost, c.url+"/?"+values.Encode(), body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if data != nil {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if c.username != "" {
+		req.Header.Set("X-ClickHouse-User", c.username)
+		req.Header.Set("X-ClickHouse-Key", c.password)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("ClickHouse returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type request struct {
+	header http.Header
+	query  map[string][]string
+	body   string
+}
+
+func withServer(t *testing.T, status int, response string, test func(client *HTTPClient, requests *[]request)) {
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			require.NoError(t, err)
+			body = zr
+		}
+		data, err := ioutil.ReadAll(body)
+		require.NoError(t, err)
+		requests = append(requests, request{header: r.Header, query: r.URL.Query(), body: string(data)})
+		w.WriteHeader(status)
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+	test(NewHTTPClient(server.Client(), server.URL, "jaeger", "user", "secret"), &requests)
+}
+
+func TestExec(t *testing.T) {
+	withServer(t, http.StatusOK, "", func(client *HTTPClient, requests *[]request) {
+		require.NoError(t, client.Exec(context.Background(), "DROP TABLE t", map[string]string{"x": "1"}))
+		require.Len(t, *requests, 1)
+		r := (*requests)[0]
+		assert.Equal(t, "DROP TABLE t", r.body)
+		assert.Equal(t, []string{"jaeger"}, r.query["database"])
+		assert.Equal(t, []string{"1"}, r.query["param_x"])
+		assert.Equal(t, "user", r.header.Get("X-ClickHouse-User"))
+		assert.Equal(t, "secret", r.header.Get("X-ClickHouse-Key"))
+	})
+}
+
+func TestInsert(t *testing.T) {
+	withServer(t, http.StatusOK, "", func(client *HTTPClient, requests *[]request) {
+		require.NoError(t, client.Insert(context.Background(), "t", nil))
+		assert.Empty(t, *requests)
+
+		rows := []interface{}{map[string]int{"a": 1}, map[string]int{"a": 2}}
+		require.NoError(t, client.Insert(context.Background(), "t", rows))
+		require.Len(t, *requests, 1)
+		r := (*requests)[0]
+		assert.Equal(t, []string{"INSERT INTO t FORMAT JSONEachRow"}, r.query["query"])
+		assert.Equal(t, "{\"a\":1}\n{\"a\":2}\n", r.body)
+	})
+}
+
+func TestInsertEncodingError(t *testing.T) {
+	withServer(t, http.StatusOK, "", func(client *HTTPClient, requests *[]request) {
+		assert.Error(t, client.Insert(context.Background(), "t", []interface{}{make(chan int)}))
+		assert.Empty(t, *requests)
+	})
+}
+
+func TestQuery(t *testing.T) {
+	withServer(t, http.StatusOK, "{\"a\":1}\n\n{\"a\":2}\n", func(client *HTTPClient, requests *[]request) {
+		var values []int
+		err := client.Query(context.Background(), "SELECT a FROM t", nil, func(row json.RawMessage) error {
+			var v struct{ A int }
+			if err := json.Unmarshal(row, &v); err != nil {
+				return err
+			}
+			values = append(values, v.A)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 2}, values)
+		assert.Equal(t, "SELECT a FROM t FORMAT JSONEachRow", (*requests)[0].body)
+		assert.Equal(t, []string{"0"}, (*requests)[0].query["output_format_json_quote_64bit_integers"])
+
+		err = client.Query(context.Background(), "SELECT a FROM t", nil, func(row json.RawMessage) error {
+			return assert.AnError
+		})
+		assert.Equal(t, assert.AnError, err)
+	})
+}
+
+func TestErrorStatus(t *testing.T) {
+	withServer(t, http.StatusBadRequest, "Code: 62. Syntax error\n", func(client *HTTPClient, requests *[]request) {
+		assert.EqualError(t, client.Exec(context.Background(), "SELEC", nil), "ClickHouse returned 400 Bad Request: Code: 62. Syntax error")
+		assert.Error(t, client.Insert(context.Background(), "t", []interface{}{1}))
+		assert.Error(t, client.Query(context.Background(), "SELEC", nil, nil))
+	})
+}
+
+func TestConnectionError(t *testing.T) {
+	client := NewHTTPClient(http.DefaultClient, "http://127.0.0.1:0", "jaeger", "", "")
+	assert.Error(t, client.Exec(context.Background(), "SELECT 1", nil))
+	client = NewHTTPClient(http.DefaultClient, "http://in valid", "jaeger", "", "")
+	assert.Error(t, client.Exec(context.Background(), "SELECT 1", nil))
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jaegertracing/jaeger/pkg/clickhouse"
+	"github.com/jaegertracing/jaeger/pkg/config/tlscfg"
+)
+
+// Configuration describes the configuration properties needed to connect to a ClickHouse server.
+type Configuration struct {
+	ServerURL          string         `mapstructure:"server_url"`
+	Database           string         `mapstructure:"database"`
+	Username           string         `mapstructure:"username"`
+	Password           string         `mapstructure:"password" json:"-"`
+	Timeout            time.Duration  `mapstructure:"timeout"`
+	TLS                tlscfg.Options `mapstructure:"tls"`
+	SpansTable         string         `mapstructure:"spans_table"`
+	OperationsTable    string         `mapstructure:"operations_table"`
+	CreateSchema       bool           `mapstructure:"create_schema"`
+	TTL                time.Duration  `mapstructure:"ttl"`
+	BatchSize          int            `mapstructure:"batch_size"`
+	BatchFlushInterval time.Duration  `mapstructure:"batch_flush_interval"`
+	// TagsAsColumns are the keys of the tags stored in dedicated materialized columns, for faster searches.
+	TagsAsColumns []string `mapstructure:"tags_as_columns"`
+}
+
+// NewClient creates a new ClickHouse client.
+func (c *Configuration) NewClient() (clickhouse.Client, error) {
+	if c.ServerURL == "" {
+		return nil, errors.New("no server specified")
+	}
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	if c.TLS.Enabled {
+		tlsConfig, err := c.TLS.Config()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	httpClient := &http.Client{Transport: transport, Timeout: c.Timeout}
+	return clickhouse.NewHTTPClient(httpClient, c.ServerURL, c.Database, c.Username, c.Password), nil
+}
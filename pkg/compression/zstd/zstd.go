@@ -0,0 +1,96 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package zstd registers a zstd compressor with gRPC, for clients to compress the calls
+// with grpc.UseCompressor(zstd.Name). Importing it is enough for a server to accept them.
+package zstd
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc/encoding"
+)
+
+// Name is the name of the zstd compressor.
+const Name = "zstd"
+
+// maxDecodedSize bounds the memory used to decompress a message
+const maxDecodedSize = 64 << 20
+
+func init() {
+	encoding.RegisterCompressor(newCompressor())
+}
+
+type compressor struct {
+	encoders sync.Pool
+	decoder  *zstd.Decoder
+}
+
+func newCompressor() *compressor {
+	// a nil reader only decodes with DecodeAll, which is safe for concurrent use
+	decoder, err := zstd.NewReader(nil, zstd.WithDecoderMaxMemory(maxDecodedSize))
+	if err != nil {
+		panic(err)
+	}
+	c := &compressor{decoder: decoder}
+	c.encoders.New = func() interface{} {
+		encoder, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			panic(err)
+		}
+		return encoder
+	}
+	return c
+}
+
+// Name implements encoding.Compressor.
+func (c *compressor) Name() string {
+	return Name
+}
+
+// Compress implements encoding.Compressor.
+func (c *compressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	encoder := c.encoders.Get().(*zstd.Encoder)
+	encoder.Reset(w)
+	return &writer{Encoder: encoder, pool: &c.encoders}, nil
+}
+
+// Decompress implements encoding.Compressor.
+func (c *compressor) Decompress(r io.Reader) (io.Reader, error) {
+	compressed, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	decompressed, err := c.decoder.DecodeAll(compressed, nil)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(decompressed), nil
+}
+
+// writer returns the encoder to the pool once the message is compressed
+type writer struct {
+	*zstd.Encoder
+	pool *sync.Pool
+}
+
+func (w *writer) Close() error {
+	err := w.Encoder.Close()
+	w.pool.Put(w.Encoder)
+	return err
+}
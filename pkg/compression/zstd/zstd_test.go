@@ -0,0 +1,57 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zstd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/encoding"
+)
+
+func TestCompressorRegistered(t *testing.T) {
+	c := encoding.GetCompressor(Name)
+	require.NotNil(t, c)
+	assert.Equal(t, Name, c.Name())
+}
+
+func TestCompressorRoundTrip(t *testing.T) {
+	c := newCompressor()
+	message := []byte(strings.Repeat("span ", 1000))
+	for i := 0; i < 3; i++ {
+		var compressed bytes.Buffer
+		w, err := c.Compress(&compressed)
+		require.NoError(t, err)
+		_, err = w.Write(message)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		assert.True(t, compressed.Len() < len(message))
+
+		r, err := c.Decompress(&compressed)
+		require.NoError(t, err)
+		decompressed, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, message, decompressed)
+	}
+}
+
+func TestCompressorInvalidInput(t *testing.T) {
+	_, err := newCompressor().Decompress(strings.NewReader("not zstd"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlscfg
+
+import (
+	"crypto/tls"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// kubernetesDataDir is the symlink swapped by Kubernetes when the files of a mounted secret are updated
+const kubernetesDataDir = "..data"
+
+// ConfigWatcher rebuilds the TLS configuration when the CA, certificate or key files change,
+// e.g. when short-lived certificates are renewed. The previous configuration remains in use
+// if the new files cannot be loaded.
+type ConfigWatcher struct {
+	options Options
+	logger  *zap.Logger
+	watcher *fsnotify.Watcher
+	files   map[string]bool // base names of the watched files
+	config  atomic.Value    // holds *tls.Config
+	done    sync.WaitGroup
+}
+
+// NewConfigWatcher loads the TLS configuration and watches its files until Close is called.
+func (p Options) NewConfigWatcher(logger *zap.Logger) (*ConfigWatcher, error) {
+	cfg, err := p.Config()
+	if err != nil {
+		return nil, err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a watcher for the TLS files: %w", err)
+	}
+	w := &ConfigWatcher{
+		options: p,
+		logger:  logger,
+		watcher: watcher,
+		files:   make(map[string]bool),
+	}
+	w.config.Store(cfg)
+	// the directories are watched because the files are usually replaced rather than written
+	dirs := make(map[string]bool)
+	for _, path := range []string{p.CAPath, p.CertPath, p.KeyPath, p.ClientCAPath} {
+		if path == "" {
+			continue
+		}
+		w.files[filepath.Base(path)] = true
+		dirs[filepath.Dir(path)] = true
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return nil, fmt.Errorf("failed to watch the TLS files in %s: %w", dir, err)
+		}
+	}
+	w.done.Add(1)
+	go w.watch()
+	return w, nil
+}
+
+// Config returns the latest TLS configuration.
+func (w *ConfigWatcher) Config() *tls.Config {
+	return w.config.Load().(*tls.Config)
+}
+
+// Close stops watching the files.
+func (w *ConfigWatcher) Close() error {
+	err := w.watcher.Close()
+	w.done.Wait()
+	return err
+}
+
+func (w *ConfigWatcher) watch() {
+	defer w.done.Done()
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			name := filepath.Base(event.Name)
+			if event.Op == fsnotify.Chmod || (!w.files[name] && name != kubernetesDataDir) {
+				continue
+			}
+			w.reload()
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			w.logger.Error("Failed to watch the TLS files", zap.Error(err))
+		}
+	}
+}
+
+func (w *ConfigWatcher) reload() {
+	cfg, err := w.options.Config()
+	if err != nil {
+		// e.g. the certificate was replaced but not the key yet
+		w.logger.Warn("Failed to reload the TLS files, using the previous ones", zap.Error(err))
+		return
+	}
+	w.config.Store(cfg)
+	w.logger.Info("Reloaded the TLS files", zap.String("cert", w.options.CertPath), zap.String("ca", w.options.CAPath))
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlscfg
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// writeCertificate writes a self-signed certificate and its key into dir.
+func writeCertificate(t *testing.T, dir, commonName string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	// written then renamed into place, as done by the tools renewing certificates
+	for name, block := range map[string]*pem.Block{
+		"cert.pem": {Type: "CERTIFICATE", Bytes: der},
+		"key.pem":  {Type: "EC PRIVATE KEY", Bytes: keyDER},
+	} {
+		tmp := filepath.Join(dir, "."+name)
+		require.NoError(t, ioutil.WriteFile(tmp, pem.EncodeToMemory(block), 0600))
+		require.NoError(t, os.Rename(tmp, filepath.Join(dir, name)))
+	}
+}
+
+func certificateCommonName(t *testing.T, w *ConfigWatcher) string {
+	certs := w.Config().Certificates
+	require.Len(t, certs, 1)
+	cert, err := x509.ParseCertificate(certs[0].Certificate[0])
+	require.NoError(t, err)
+	return cert.Subject.CommonName
+}
+
+func TestConfigWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlscfg")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	writeCertificate(t, dir, "first")
+
+	options := Options{
+		CAPath:   "testdata/testCA.pem",
+		CertPath: filepath.Join(dir, "cert.pem"),
+		KeyPath:  filepath.Join(dir, "key.pem"),
+	}
+	w, err := options.NewConfigWatcher(zap.NewNop())
+	require.NoError(t, err)
+	defer w.Close()
+	assert.Equal(t, "first", certificateCommonName(t, w))
+
+	writeCertificate(t, dir, "second")
+	assert.Eventually(t, func() bool {
+		return certificateCommonName(t, w) == "second"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// an invalid key keeps the previous configuration
+	require.NoError(t, ioutil.WriteFile(options.KeyPath, []byte("invalid"), 0600))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, "second", certificateCommonName(t, w))
+}
+
+func TestConfigWatcherErrors(t *testing.T) {
+	_, err := Options{CAPath: "testdata/not/valid"}.NewConfigWatcher(zap.NewNop())
+	assert.Contains(t, err.Error(), "failed to load CA")
+}
@@ -16,6 +16,7 @@ package tlscfg
 
 import (
 	"flag"
+	"strings"
 
 	"github.com/spf13/viper"
 )
@@ -31,6 +32,8 @@ const (
 	tlsClientCA       = tlsPrefix + ".client-ca"
 	tlsClientCAOld    = tlsPrefix + ".client.ca"
 	tlsSkipHostVerify = tlsPrefix + ".skip-host-verify"
+	tlsClientSANs     = tlsPrefix + ".client-allowed-sans"
+	tlsClientOUs      = tlsPrefix + ".client-allowed-ous"
 )
 
 // ClientFlagsConfig describes which CLI flags for TLS client should be generated.
@@ -72,6 +75,10 @@ func (c ServerFlagsConfig) AddFlags(flags *flag.FlagSet) {
 	flags.String(c.Prefix+tlsKey, "", "Path to a TLS Private Key file, used to identify this server to clients")
 	flags.String(c.Prefix+tlsClientCA, "", "Path to a TLS CA (Certification Authority) file used to verify certificates presented by clients (if unset, all clients are permitted)")
 	flags.String(c.Prefix+tlsClientCAOld, "", "(deprecated) see --"+c.Prefix+tlsClientCA)
+	if c.ShowClientCA {
+		flags.String(c.Prefix+tlsClientSANs, "", "Comma-separated list of Subject Alternative Names (DNS names, e.g. *.example.com, IPs, emails or URIs) of the client certificates allowed to connect, requires --"+c.Prefix+tlsClientCA+" (if unset, all verified clients are permitted)")
+		flags.String(c.Prefix+tlsClientOUs, "", "Comma-separated list of Organizational Units of the client certificates allowed to connect, requires --"+c.Prefix+tlsClientCA+" (if unset, all verified clients are permitted)")
+	}
 }
 
 // InitFromViper creates tls.Config populated with values retrieved from Viper.
@@ -112,6 +119,19 @@ func (c ServerFlagsConfig) InitFromViper(v *viper.Viper) Options {
 			// using legacy flag
 			p.ClientCAPath = s
 		}
+		p.ClientAllowedSANs = splitList(v.GetString(c.Prefix + tlsClientSANs))
+		p.ClientAllowedOUs = splitList(v.GetString(c.Prefix + tlsClientOUs))
 	}
 	return p
 }
+
+// splitList splits a comma-separated list, returning nil when it is empty
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -122,3 +122,27 @@ func TestServerFlags(t *testing.T) {
 		})
 	}
 }
+
+func TestServerFlagsClientAllowlist(t *testing.T) {
+	v := viper.New()
+	command := cobra.Command{}
+	flagSet := &flag.FlagSet{}
+	flagCfg := ServerFlagsConfig{
+		Prefix:       "prefix",
+		ShowEnabled:  true,
+		ShowClientCA: true,
+	}
+	flagCfg.AddFlags(flagSet)
+	command.PersistentFlags().AddGoFlagSet(flagSet)
+	v.BindPFlags(command.PersistentFlags())
+
+	err := command.ParseFlags([]string{
+		"--prefix.tls.client-ca=client-ca-file",
+		"--prefix.tls.client-allowed-sans=agent.jaeger.svc, *.example.com",
+		"--prefix.tls.client-allowed-ous=tracing",
+	})
+	require.NoError(t, err)
+	tlsOpts := flagCfg.InitFromViper(v)
+	assert.Equal(t, []string{"agent.jaeger.svc", "*.example.com"}, tlsOpts.ClientAllowedSANs)
+	assert.Equal(t, []string{"tracing"}, tlsOpts.ClientAllowedOUs)
+}
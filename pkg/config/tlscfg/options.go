@@ -17,9 +17,11 @@ package tlscfg
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
+	"strings"
 )
 
 // Options describes the configuration properties for TLS Connections.
@@ -31,8 +33,16 @@ type Options struct {
 	ServerName     string `mapstructure:"server_name"` // only for client-side TLS config
 	ClientCAPath   string `mapstructure:"client_ca"`   // only for server-side TLS config for client auth
 	SkipHostVerify bool   `mapstructure:"skip_host_verify"`
+	// ClientAllowedSANs and ClientAllowedOUs restrict which verified client certificates are accepted,
+	// a certificate is accepted if any of its SANs or OUs is listed; only for server-side TLS config
+	ClientAllowedSANs []string `mapstructure:"client_allowed_sans"`
+	ClientAllowedOUs  []string `mapstructure:"client_allowed_ous"`
 }
 
+// ErrClientNotAllowed is returned during the TLS handshake when the client certificate
+// does not match the allowed SANs and OUs.
+var ErrClientNotAllowed = errors.New("client certificate is not allowed")
+
 var systemCertPool = x509.SystemCertPool // to allow overriding in unit test
 
 // Config loads TLS certificates and returns a TLS Config.
@@ -68,9 +78,84 @@ func (p Options) Config() (*tls.Config, error) {
 		tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
 	}
 
+	if len(p.ClientAllowedSANs) > 0 || len(p.ClientAllowedOUs) > 0 {
+		if p.ClientCAPath == "" {
+			return nil, fmt.Errorf("an allowlist of client certificates requires a client CA")
+		}
+		tlsCfg.VerifyPeerCertificate = p.verifyClientAllowed
+	}
+
 	return tlsCfg, nil
 }
 
+// verifyClientAllowed checks the leaf of the chain verified against the client CA
+func (p Options) verifyClientAllowed(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+	if len(verifiedChains) == 0 || len(verifiedChains[0]) == 0 {
+		return ErrClientNotAllowed
+	}
+	cert := verifiedChains[0][0]
+	for _, allowed := range p.ClientAllowedSANs {
+		if matchSAN(cert, allowed) {
+			return nil
+		}
+	}
+	for _, allowed := range p.ClientAllowedOUs {
+		for _, ou := range cert.Subject.OrganizationalUnit {
+			if ou == allowed {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrClientNotAllowed, cert.Subject)
+}
+
+// matchSAN returns true if any DNS name, IP address, email address or URI of the certificate
+// equals allowed. A DNS entry of the form *.example.com matches a single leftmost label.
+func matchSAN(cert *x509.Certificate, allowed string) bool {
+	for _, name := range cert.DNSNames {
+		if strings.EqualFold(name, allowed) {
+			return true
+		}
+		if strings.HasPrefix(allowed, "*.") {
+			if i := strings.IndexByte(name, '.'); i > 0 && strings.EqualFold(name[i:], allowed[1:]) {
+				return true
+			}
+		}
+	}
+	for _, ip := range cert.IPAddresses {
+		if ip.String() == allowed {
+			return true
+		}
+	}
+	for _, email := range cert.EmailAddresses {
+		if email == allowed {
+			return true
+		}
+	}
+	for _, uri := range cert.URIs {
+		if uri.String() == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// OnRejectedClient wraps the verification of client certificates of the TLS config, if any,
+// to call fn for every client rejected because it is not in the allowlist.
+func OnRejectedClient(tlsCfg *tls.Config, fn func(err error)) {
+	verify := tlsCfg.VerifyPeerCertificate
+	if verify == nil {
+		return
+	}
+	tlsCfg.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		err := verify(rawCerts, verifiedChains)
+		if err != nil {
+			fn(err)
+		}
+		return err
+	}
+}
+
 func (p Options) loadCertPool() (*x509.CertPool, error) {
 	if len(p.CAPath) == 0 { // no truststore given, use SystemCertPool
 		certPool, err := systemCertPool()
@@ -15,8 +15,13 @@
 package tlscfg
 
 import (
+	"crypto/tls"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -116,6 +121,20 @@ func TestOptionsToConfig(t *testing.T) {
 				ClientCAPath: "testdata/testCA.pem",
 			},
 		},
+		{
+			name: "should pass with allowed client SANs",
+			options: Options{
+				ClientCAPath:      "testdata/testCA.pem",
+				ClientAllowedSANs: []string{"client.jaeger.io"},
+			},
+		},
+		{
+			name: "should fail with allowed client OUs without Client CA",
+			options: Options{
+				ClientAllowedOUs: []string{"tracing"},
+			},
+			expectError: "requires a client CA",
+		},
 	}
 
 	for _, test := range tests {
@@ -140,3 +159,54 @@ func TestOptionsToConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestVerifyClientAllowed(t *testing.T) {
+	spiffe, err := url.Parse("spiffe://cluster.local/ns/default/sa/agent")
+	require.NoError(t, err)
+	cert := &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "agent", OrganizationalUnit: []string{"tracing"}},
+		DNSNames:       []string{"agent.jaeger.svc"},
+		IPAddresses:    []net.IP{net.ParseIP("10.0.0.1")},
+		EmailAddresses: []string{"agent@example.com"},
+		URIs:           []*url.URL{spiffe},
+	}
+	tests := []struct {
+		name    string
+		options Options
+		allowed bool
+	}{
+		{name: "DNS name", options: Options{ClientAllowedSANs: []string{"AGENT.jaeger.svc"}}, allowed: true},
+		{name: "DNS wildcard", options: Options{ClientAllowedSANs: []string{"*.jaeger.svc"}}, allowed: true},
+		{name: "DNS wildcard matches a single label", options: Options{ClientAllowedSANs: []string{"*.svc"}}, allowed: false},
+		{name: "IP address", options: Options{ClientAllowedSANs: []string{"10.0.0.1"}}, allowed: true},
+		{name: "email", options: Options{ClientAllowedSANs: []string{"agent@example.com"}}, allowed: true},
+		{name: "URI", options: Options{ClientAllowedSANs: []string{"spiffe://cluster.local/ns/default/sa/agent"}}, allowed: true},
+		{name: "OU", options: Options{ClientAllowedSANs: []string{"other"}, ClientAllowedOUs: []string{"tracing"}}, allowed: true},
+		{name: "no match", options: Options{ClientAllowedSANs: []string{"other"}, ClientAllowedOUs: []string{"billing"}}, allowed: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.options.verifyClientAllowed(nil, [][]*x509.Certificate{{cert}})
+			if test.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, ErrClientNotAllowed))
+			}
+		})
+	}
+	assert.Equal(t, ErrClientNotAllowed, Options{}.verifyClientAllowed(nil, nil))
+}
+
+func TestOnRejectedClient(t *testing.T) {
+	cfg := &tls.Config{}
+	OnRejectedClient(cfg, func(error) { t.Fatal("must not be called") })
+	assert.Nil(t, cfg.VerifyPeerCertificate)
+
+	cfg, err := Options{ClientCAPath: "testdata/testCA.pem", ClientAllowedOUs: []string{"billing"}}.Config()
+	require.NoError(t, err)
+	var rejected []error
+	OnRejectedClient(cfg, func(err error) { rejected = append(rejected, err) })
+	cert := &x509.Certificate{Subject: pkix.Name{OrganizationalUnit: []string{"tracing"}}}
+	assert.Error(t, cfg.VerifyPeerCertificate(nil, [][]*x509.Certificate{{cert}}))
+	assert.Len(t, rejected, 1)
+}
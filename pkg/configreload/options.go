@@ -1,5 +1,4 @@
-// Copyright (c) 2019 The Jaeger Authors.
-// Copyright (c) 2017 Uber Technologies, Inc.
+// Copyright (c) 2020 The Jaeger Authors.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -13,22 +12,19 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package querysvc
+package configreload
 
 import (
 	"time"
-
-	"github.com/jaegertracing/jaeger/model/adjuster"
 )
 
-// StandardAdjusters is a list of model adjusters applied by the query service
-// before returning the data to the API clients.
-func StandardAdjusters(maxClockSkewAdjust time.Duration) []adjuster.Adjuster {
-	return []adjuster.Adjuster{
-		adjuster.SpanIDDeduper(),
-		adjuster.ClockSkew(maxClockSkewAdjust),
-		adjuster.IPTagAdjuster(),
-		adjuster.SortLogFields(),
-		adjuster.SpanReferences(),
-	}
+// Options controls the reloading of the configuration file.
+type Options struct {
+	// Interval is how often the configuration file is checked for changes, zero disables reloading
+	Interval time.Duration
+}
+
+// Enabled returns true if the configuration file must be watched.
+func (o Options) Enabled() bool {
+	return o.Interval > 0
 }
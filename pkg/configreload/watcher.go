@@ -0,0 +1,228 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configreload
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+)
+
+// Reloadable is implemented by components able to apply a new configuration while running.
+type Reloadable interface {
+	// Reload applies the configuration read from viper, the previous one must remain in use on error
+	Reload(v *viper.Viper) error
+}
+
+// ReloadFunc adapts a function to the Reloadable interface.
+type ReloadFunc func(v *viper.Viper) error
+
+// Reload implements Reloadable
+func (f ReloadFunc) Reload(v *viper.Viper) error {
+	return f(v)
+}
+
+type component struct {
+	name     string
+	prefixes []string
+	reload   Reloadable
+	results  map[string]metrics.Counter
+}
+
+// Watcher periodically re-reads the configuration file from viper and reloads the components
+// owning the keys that changed. Changes of keys no component owns require a restart and are only logged.
+type Watcher struct {
+	opts           Options
+	v              *viper.Viper
+	file           string
+	logger         *zap.Logger
+	metricsFactory metrics.Factory
+
+	components      []*component
+	lastContent     string
+	lastSettings    map[string]interface{}
+	restartRequired metrics.Counter
+	readErrors      metrics.Counter
+
+	mu   sync.Mutex // serializes the checks of the ticker and of Check
+	stop chan struct{}
+	done sync.WaitGroup
+}
+
+// NewWatcher creates a watcher of the configuration file viper was loaded from.
+func NewWatcher(opts Options, v *viper.Viper, logger *zap.Logger, metricsFactory metrics.Factory) (*Watcher, error) {
+	file := v.ConfigFileUsed()
+	if file == "" {
+		return nil, fmt.Errorf("reloading the configuration requires a configuration file, see --config-file")
+	}
+	content, err := ioutil.ReadFile(filepath.Clean(file))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration file: %w", err)
+	}
+	return &Watcher{
+		opts:            opts,
+		v:               v,
+		file:            file,
+		logger:          logger,
+		metricsFactory:  metricsFactory,
+		lastContent:     string(content),
+		lastSettings:    settings(v),
+		restartRequired: metricsFactory.Counter(metrics.Options{Name: "restart_required"}),
+		readErrors:      metricsFactory.Counter(metrics.Options{Name: "read_errors"}),
+		stop:            make(chan struct{}),
+	}, nil
+}
+
+// Register makes the component reload when a key starting with one of the prefixes changes.
+// It must be called before Start.
+func (w *Watcher) Register(name string, reload Reloadable, prefixes ...string) {
+	results := make(map[string]metrics.Counter)
+	for _, result := range []string{"ok", "failed"} {
+		results[result] = w.metricsFactory.Counter(metrics.Options{
+			Name: "reloads",
+			Tags: map[string]string{"component": name, "result": result},
+		})
+	}
+	w.components = append(w.components, &component{name: name, prefixes: prefixes, reload: reload, results: results})
+}
+
+// Start checks the configuration file in the background, unless the interval is zero.
+func (w *Watcher) Start() {
+	if w.opts.Interval <= 0 {
+		return
+	}
+	w.done.Add(1)
+	go func() {
+		defer w.done.Done()
+		ticker := time.NewTicker(w.opts.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w.Check()
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops watching the configuration file.
+func (w *Watcher) Close() error {
+	close(w.stop)
+	w.done.Wait()
+	return nil
+}
+
+// Check re-reads the configuration file immediately, e.g. when the process receives SIGHUP.
+func (w *Watcher) Check() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	content, err := ioutil.ReadFile(filepath.Clean(w.file))
+	if err != nil {
+		w.readErrors.Inc(1)
+		w.logger.Error("failed to read configuration file", zap.String("file", w.file), zap.Error(err))
+		return
+	}
+	if string(content) == w.lastContent {
+		return
+	}
+	// viper keeps the previous configuration when the new one cannot be parsed
+	if err := w.v.ReadInConfig(); err != nil {
+		w.readErrors.Inc(1)
+		w.logger.Error("failed to parse configuration file, keeping the previous configuration", zap.String("file", w.file), zap.Error(err))
+		return
+	}
+	w.lastContent = string(content)
+	current := settings(w.v)
+	changed := changedKeys(w.lastSettings, current)
+	w.lastSettings = current
+	w.apply(changed)
+}
+
+func (w *Watcher) apply(changed []string) {
+	var unowned []string
+	reloaded := make(map[*component]bool)
+	for _, key := range changed {
+		owned := false
+		for _, c := range w.components {
+			if c.owns(key) {
+				owned = true
+				reloaded[c] = true
+			}
+		}
+		if !owned {
+			unowned = append(unowned, key)
+		}
+	}
+	for _, c := range w.components {
+		if !reloaded[c] {
+			continue
+		}
+		if err := c.reload.Reload(w.v); err != nil {
+			c.results["failed"].Inc(1)
+			w.logger.Error("failed to reload configuration, keeping the previous one", zap.String("component", c.name), zap.Error(err))
+			continue
+		}
+		c.results["ok"].Inc(1)
+		w.logger.Info("Reloaded configuration", zap.String("component", c.name))
+	}
+	if len(unowned) > 0 {
+		w.restartRequired.Inc(int64(len(unowned)))
+		w.logger.Warn("Configuration changes require a restart to take effect", zap.Strings("keys", unowned))
+	}
+}
+
+func (c *component) owns(key string) bool {
+	for _, prefix := range c.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func settings(v *viper.Viper) map[string]interface{} {
+	s := make(map[string]interface{})
+	for _, key := range v.AllKeys() {
+		s[key] = v.Get(key)
+	}
+	return s
+}
+
+func changedKeys(previous, current map[string]interface{}) []string {
+	var changed []string
+	for key, value := range current {
+		if old, ok := previous[key]; !ok || !reflect.DeepEqual(old, value) {
+			changed = append(changed, key)
+		}
+	}
+	for key := range previous {
+		if _, ok := current[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
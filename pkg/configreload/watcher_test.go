@@ -0,0 +1,153 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configreload
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type recordingReloader struct {
+	values []string
+	err    error
+}
+
+func (r *recordingReloader) Reload(v *viper.Viper) error {
+	r.values = append(r.values, v.GetString("collector.span-filter.rules-file"))
+	return r.err
+}
+
+func writeConfig(t *testing.T, file, content string) {
+	require.NoError(t, ioutil.WriteFile(file, []byte(content), 0600))
+}
+
+func newTestWatcher(t *testing.T, content string) (*Watcher, string, *metricstest.Factory, *observer.ObservedLogs, func()) {
+	f, err := ioutil.TempFile("", "collector-*.yaml")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	writeConfig(t, f.Name(), content)
+
+	v := viper.New()
+	v.SetDefault("collector.queue-size", 2000)
+	v.SetConfigFile(f.Name())
+	require.NoError(t, v.ReadInConfig())
+
+	core, logs := observer.New(zap.InfoLevel)
+	mf := metricstest.NewFactory(0)
+	w, err := NewWatcher(Options{Interval: time.Hour}, v, zap.New(core), mf)
+	require.NoError(t, err)
+	return w, f.Name(), mf, logs, func() { os.Remove(f.Name()) }
+}
+
+func TestWatcherReloadsOwnedKeys(t *testing.T) {
+	w, file, mf, logs, cleanup := newTestWatcher(t, "collector:\n  span-filter:\n    rules-file: a.json\n")
+	defer cleanup()
+	filter := &recordingReloader{}
+	sampling := &recordingReloader{}
+	w.Register("span_filter", filter, "collector.span-filter.")
+	w.Register("sampling", sampling, "sampling.strategies-file")
+
+	// unchanged content is not reloaded
+	w.Check()
+	assert.Empty(t, filter.values)
+
+	writeConfig(t, file, "collector:\n  span-filter:\n    rules-file: b.json\n  queue-size: 100\n")
+	w.Check()
+	assert.Equal(t, []string{"b.json"}, filter.values)
+	assert.Empty(t, sampling.values)
+	assert.Len(t, logs.FilterMessage("Configuration changes require a restart to take effect").All(), 1)
+	assert.Equal(t, []interface{}{"collector.queue-size"},
+		logs.FilterMessage("Configuration changes require a restart to take effect").All()[0].ContextMap()["keys"])
+
+	// removing the key reverts to its default, which is a change too
+	writeConfig(t, file, "collector:\n  queue-size: 100\n")
+	w.Check()
+	assert.Equal(t, []string{"b.json", ""}, filter.values)
+
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "reloads", Tags: map[string]string{"component": "span_filter", "result": "ok"}, Value: 2},
+		metricstest.ExpectedMetric{Name: "restart_required", Value: 1},
+	)
+}
+
+func TestWatcherReloadFailure(t *testing.T) {
+	w, file, mf, logs, cleanup := newTestWatcher(t, "collector:\n  span-filter:\n    rules-file: a.json\n")
+	defer cleanup()
+	w.Register("span_filter", &recordingReloader{err: errors.New("bad rules")}, "collector.span-filter.")
+
+	writeConfig(t, file, "collector:\n  span-filter:\n    rules-file: b.json\n")
+	w.Check()
+	assert.Len(t, logs.FilterMessage("failed to reload configuration, keeping the previous one").All(), 1)
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "reloads", Tags: map[string]string{"component": "span_filter", "result": "failed"}, Value: 1},
+	)
+}
+
+func TestWatcherInvalidFile(t *testing.T) {
+	w, file, mf, logs, cleanup := newTestWatcher(t, "collector:\n  span-filter:\n    rules-file: a.json\n")
+	defer cleanup()
+	filter := &recordingReloader{}
+	w.Register("span_filter", filter, "collector.span-filter.")
+
+	writeConfig(t, file, "collector: [")
+	w.Check()
+	assert.Len(t, logs.FilterMessage("failed to parse configuration file, keeping the previous configuration").All(), 1)
+	assert.Equal(t, "a.json", w.v.GetString("collector.span-filter.rules-file"))
+
+	require.NoError(t, os.Remove(file))
+	w.Check()
+	assert.Len(t, logs.FilterMessage("failed to read configuration file").All(), 1)
+	assert.Empty(t, filter.values)
+	mf.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "read_errors", Value: 2})
+}
+
+func TestWatcherStartClose(t *testing.T) {
+	w, file, _, _, cleanup := newTestWatcher(t, "collector:\n  span-filter:\n    rules-file: a.json\n")
+	defer cleanup()
+	reloaded := make(chan struct{}, 1)
+	w.Register("span_filter", ReloadFunc(func(v *viper.Viper) error {
+		reloaded <- struct{}{}
+		return nil
+	}), "collector.span-filter.")
+	w.opts.Interval = time.Millisecond
+	w.Start()
+	writeConfig(t, file, "collector:\n  span-filter:\n    rules-file: b.json\n")
+	select {
+	case <-reloaded:
+	case <-time.After(5 * time.Second):
+		t.Fatal("configuration was not reloaded")
+	}
+	require.NoError(t, w.Close())
+}
+
+func TestNewWatcherErrors(t *testing.T) {
+	_, err := NewWatcher(Options{Interval: time.Second}, viper.New(), zap.NewNop(), metricstest.NewFactory(0))
+	assert.EqualError(t, err, "reloading the configuration requires a configuration file, see --config-file")
+
+	v := viper.New()
+	v.SetConfigFile("/does/not/exist.yaml")
+	_, err = NewWatcher(Options{Interval: time.Second}, v, zap.NewNop(), metricstest.NewFactory(0))
+	assert.Contains(t, err.Error(), "failed to read configuration file")
+}
@@ -0,0 +1,251 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosmosdb
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// apiVersion is the version of the REST API of Cosmos DB
+const apiVersion = "2018-12-31"
+
+// Container is the definition of a container, its documents expire after DefaultTTL since their last write if set.
+type Container struct {
+	ID               string
+	PartitionKeyPath string
+	DefaultTTL       time.Duration
+}
+
+// Parameter is a named parameter of a query, e.g. @service.
+type Parameter struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+// Query is a query of the SQL API, with its parameters.
+type Query struct {
+	Query      string      `json:"query"`
+	Parameters []Parameter `json:"parameters"`
+}
+
+// Client is a client of the SQL API of a database of Azure Cosmos DB.
+type Client interface {
+	// CreateDatabase creates the database if it does not exist.
+	CreateDatabase(ctx context.Context) error
+	// CreateContainer creates the container if it does not exist, an existing container is not updated.
+	CreateContainer(ctx context.Context, container Container) error
+	// Upsert creates or replaces the document in the partition of the container.
+	Upsert(ctx context.Context, container, partitionKey string, document interface{}) error
+	// Query decodes the documents returned by the query on the partition into the slice pointed to by results.
+	Query(ctx context.Context, container, partitionKey string, query Query, results interface{}) error
+	// QueryAll decodes the documents returned by the query on all the partitions into the slice pointed to by results.
+	// The gateway only runs the cross-partition queries without ordering nor aggregation.
+	QueryAll(ctx context.Context, container string, query Query, results interface{}) error
+}
+
+// Error is an error returned by Cosmos DB.
+type Error struct {
+	StatusCode int
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("cosmos db error %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+type restClient struct {
+	endpoint   string
+	key        []byte
+	database   string
+	httpClient *http.Client
+	// now is the time of the requests, it is replaced by the tests
+	now func() time.Time
+}
+
+// NewClient returns a Client of the database of the account of the endpoint, authorized by the primary or the
+// secondary key of the account encoded in base64.
+func NewClient(endpoint, key, database string, httpClient *http.Client) (Client, error) {
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key of Cosmos DB: %w", err)
+	}
+	return &restClient{
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		key:        decoded,
+		database:   database,
+		httpClient: httpClient,
+		now:        time.Now,
+	}, nil
+}
+
+func (c *restClient) CreateDatabase(ctx context.Context) error {
+	_, err := c.do(ctx, "dbs", "", "dbs", map[string]interface{}{"id": c.database}, nil)
+	return ignoreConflict(err)
+}
+
+func (c *restClient) CreateContainer(ctx context.Context, container Container) error {
+	body := map[string]interface{}{
+		"id": container.ID,
+		"partitionKey": map[string]interface{}{
+			"paths": []string{container.PartitionKeyPath},
+			"kind":  "Hash",
+		},
+	}
+	if container.DefaultTTL > 0 {
+		body["defaultTtl"] = int64(container.DefaultTTL / time.Second)
+	}
+	databaseLink := "dbs/" + c.database
+	_, err := c.do(ctx, "colls", databaseLink, databaseLink+"/colls", body, nil)
+	return ignoreConflict(err)
+}
+
+func (c *restClient) Upsert(ctx context.Context, container, partitionKey string, document interface{}) error {
+	headers, err := partitionKeyHeaders(partitionKey)
+	if err != nil {
+		return err
+	}
+	headers["x-ms-documentdb-is-upsert"] = "True"
+	containerLink := c.containerLink(container)
+	_, err = c.do(ctx, "docs", containerLink, containerLink+"/docs", document, headers)
+	return err
+}
+
+func (c *restClient) Query(ctx context.Context, container, partitionKey string, query Query, results interface{}) error {
+	headers, err := partitionKeyHeaders(partitionKey)
+	if err != nil {
+		return err
+	}
+	return c.query(ctx, container, query, headers, results)
+}
+
+func (c *restClient) QueryAll(ctx context.Context, container string, query Query, results interface{}) error {
+	return c.query(ctx, container, query, map[string]string{"x-ms-documentdb-query-enablecrosspartition": "True"}, results)
+}
+
+// query reads all the pages of the query, then decodes their documents together.
+func (c *restClient) query(ctx context.Context, container string, query Query, headers map[string]string, results interface{}) error {
+	if query.Parameters == nil {
+		query.Parameters = []Parameter{}
+	}
+	headers["x-ms-documentdb-isquery"] = "True"
+	headers["Content-Type"] = "application/query+json"
+	containerLink := c.containerLink(container)
+	var documents []json.RawMessage
+	for {
+		response, err := c.do(ctx, "docs", containerLink, containerLink+"/docs", query, headers)
+		if err != nil {
+			return err
+		}
+		var page struct {
+			Documents []json.RawMessage `json:"Documents"`
+		}
+		if err := json.Unmarshal(response.body, &page); err != nil {
+			return fmt.Errorf("cannot decode the documents: %w", err)
+		}
+		documents = append(documents, page.Documents...)
+		continuation := response.header.Get("x-ms-continuation")
+		if continuation == "" {
+			break
+		}
+		headers["x-ms-continuation"] = continuation
+	}
+	data, err := json.Marshal(documents)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, results)
+}
+
+func (c *restClient) containerLink(container string) string {
+	return "dbs/" + c.database + "/colls/" + container
+}
+
+type response struct {
+	header http.Header
+	body   []byte
+}
+
+// do posts the body to the path, authorized for the resource type and the link of the resource, and returns the
+// response or the Error of Cosmos DB.
+func (c *restClient) do(ctx context.Context, resourceType, resourceLink, path string, body interface{}, headers map[string]string) (*response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest(http.MethodPost, c.endpoint+"/"+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	date := strings.ToLower(c.now().UTC().Format(http.TimeFormat))
+	request.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	request.Header.Set("x-ms-date", date)
+	request.Header.Set("x-ms-version", apiVersion)
+	request.Header.Set("Authorization", c.authorization(http.MethodPost, resourceType, resourceLink, date))
+	httpResponse, err := c.httpClient.Do(request.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+	responseBody, err := ioutil.ReadAll(httpResponse.Body)
+	if err != nil {
+		return nil, err
+	}
+	if httpResponse.StatusCode >= 300 {
+		cosmosErr := &Error{StatusCode: httpResponse.StatusCode}
+		// the errors without body are only described by their status code
+		json.Unmarshal(responseBody, cosmosErr)
+		return nil, cosmosErr
+	}
+	return &response{header: httpResponse.Header, body: responseBody}, nil
+}
+
+// authorization returns the token signing the request with the master key of the account.
+func (c *restClient) authorization(verb, resourceType, resourceLink, date string) string {
+	payload := strings.ToLower(verb) + "\n" + strings.ToLower(resourceType) + "\n" + resourceLink + "\n" + date + "\n\n"
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(payload))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	return url.QueryEscape("type=master&ver=1.0&sig=" + signature)
+}
+
+func partitionKeyHeaders(partitionKey string) (map[string]string, error) {
+	value, err := json.Marshal([]string{partitionKey})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"x-ms-documentdb-partitionkey": string(value)}, nil
+}
+
+func ignoreConflict(err error) error {
+	if cosmosErr, ok := err.(*Error); ok && cosmosErr.StatusCode == http.StatusConflict {
+		return nil
+	}
+	return err
+}
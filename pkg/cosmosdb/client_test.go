@@ -0,0 +1,176 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosmosdb
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testKey = base64.StdEncoding.EncodeToString([]byte("key"))
+
+type request struct {
+	path   string
+	header http.Header
+	body   map[string]interface{}
+}
+
+// newTestClient returns a client of a server recording the requests and replying with the handler.
+func newTestClient(t *testing.T, handler func(w http.ResponseWriter, r *http.Request)) (Client, *[]request, func()) {
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &body))
+		requests = append(requests, request{path: r.URL.Path, header: r.Header, body: body})
+		handler(w, r)
+	}))
+	client, err := NewClient(server.URL+"/", testKey, "jaeger", server.Client())
+	require.NoError(t, err)
+	client.(*restClient).now = func() time.Time {
+		return time.Date(2020, 9, 13, 12, 26, 40, 0, time.UTC)
+	}
+	return client, &requests, server.Close
+}
+
+func TestNewClientInvalidKey(t *testing.T) {
+	_, err := NewClient("https://account.documents.azure.com", "not base64", "jaeger", http.DefaultClient)
+	assert.Error(t, err)
+}
+
+func TestAuthorization(t *testing.T) {
+	client, err := NewClient("https://account.documents.azure.com", testKey, "jaeger", http.DefaultClient)
+	require.NoError(t, err)
+	// signed with the payload "post\ndocs\ndbs/jaeger/colls/spans\nsun, 13 sep 2020 12:26:40 gmt\n\n"
+	assert.Equal(t,
+		"type%3Dmaster%26ver%3D1.0%26sig%3D%2BLVrtaNZtq%2FxZqWKxmp1D0M6eSZCVf%2FpzjHpL5BJXIY%3D",
+		client.(*restClient).authorization("POST", "docs", "dbs/jaeger/colls/spans", "sun, 13 sep 2020 12:26:40 gmt"))
+}
+
+func TestCreateDatabaseAndContainer(t *testing.T) {
+	client, requests, closer := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		// the database exists already
+		if r.URL.Path == "/dbs" {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+	defer closer()
+
+	require.NoError(t, client.CreateDatabase(context.Background()))
+	require.NoError(t, client.CreateContainer(context.Background(), Container{ID: "spans", PartitionKeyPath: "/traceID", DefaultTTL: time.Hour}))
+	require.NoError(t, client.CreateContainer(context.Background(), Container{ID: "operations", PartitionKeyPath: "/service"}))
+
+	require.Len(t, *requests, 3)
+	assert.Equal(t, "/dbs", (*requests)[0].path)
+	assert.Equal(t, map[string]interface{}{"id": "jaeger"}, (*requests)[0].body)
+	assert.Equal(t, "/dbs/jaeger/colls", (*requests)[1].path)
+	assert.Equal(t, map[string]interface{}{
+		"id":           "spans",
+		"partitionKey": map[string]interface{}{"paths": []interface{}{"/traceID"}, "kind": "Hash"},
+		"defaultTtl":   float64(3600),
+	}, (*requests)[1].body)
+	assert.NotContains(t, (*requests)[2].body, "defaultTtl")
+	assert.Equal(t, "sun, 13 sep 2020 12:26:40 gmt", (*requests)[1].header.Get("x-ms-date"))
+	assert.Equal(t, apiVersion, (*requests)[1].header.Get("x-ms-version"))
+	assert.Contains(t, (*requests)[1].header.Get("Authorization"), "type%3Dmaster")
+}
+
+func TestUpsert(t *testing.T) {
+	client, requests, closer := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	defer closer()
+
+	require.NoError(t, client.Upsert(context.Background(), "spans", "trace1", map[string]string{"id": "span1"}))
+	require.Len(t, *requests, 1)
+	assert.Equal(t, "/dbs/jaeger/colls/spans/docs", (*requests)[0].path)
+	assert.Equal(t, `["trace1"]`, (*requests)[0].header.Get("x-ms-documentdb-partitionkey"))
+	assert.Equal(t, "True", (*requests)[0].header.Get("x-ms-documentdb-is-upsert"))
+	assert.Equal(t, map[string]interface{}{"id": "span1"}, (*requests)[0].body)
+}
+
+func TestQuery(t *testing.T) {
+	client, requests, closer := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-ms-continuation") == "" {
+			w.Header().Set("x-ms-continuation", "next")
+			w.Write([]byte(`{"Documents":[{"id":"a"},{"id":"b"}]}`))
+			return
+		}
+		w.Write([]byte(`{"Documents":[{"id":"c"}]}`))
+	})
+	defer closer()
+
+	var documents []struct {
+		ID string `json:"id"`
+	}
+	query := Query{
+		Query:      "SELECT c.id FROM c WHERE c.service = @service",
+		Parameters: []Parameter{{Name: "@service", Value: "svc"}},
+	}
+	require.NoError(t, client.Query(context.Background(), "index", "svc", query, &documents))
+	require.Len(t, documents, 3)
+	assert.Equal(t, "c", documents[2].ID)
+	require.Len(t, *requests, 2)
+	assert.Equal(t, "/dbs/jaeger/colls/index/docs", (*requests)[0].path)
+	assert.Equal(t, "True", (*requests)[0].header.Get("x-ms-documentdb-isquery"))
+	assert.Equal(t, "application/query+json", (*requests)[0].header.Get("Content-Type"))
+	assert.Equal(t, `["svc"]`, (*requests)[0].header.Get("x-ms-documentdb-partitionkey"))
+	assert.Equal(t, map[string]interface{}{
+		"query":      "SELECT c.id FROM c WHERE c.service = @service",
+		"parameters": []interface{}{map[string]interface{}{"name": "@service", "value": "svc"}},
+	}, (*requests)[0].body)
+	assert.Equal(t, "next", (*requests)[1].header.Get("x-ms-continuation"))
+
+	documents = nil
+	require.NoError(t, client.QueryAll(context.Background(), "index", Query{Query: "SELECT c.id FROM c"}, &documents))
+	assert.Len(t, documents, 3)
+	assert.Equal(t, "True", (*requests)[2].header.Get("x-ms-documentdb-query-enablecrosspartition"))
+	assert.Empty(t, (*requests)[2].header.Get("x-ms-documentdb-partitionkey"))
+	assert.Equal(t, []interface{}{}, (*requests)[2].body["parameters"])
+}
+
+func TestErrors(t *testing.T) {
+	client, _, closer := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/dbs" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code":"Unauthorized","message":"invalid signature"}`))
+			return
+		}
+		w.Write([]byte(`{"Documents":`))
+	})
+	defer closer()
+
+	err := client.CreateDatabase(context.Background())
+	assert.EqualError(t, err, "cosmos db error 401 Unauthorized: invalid signature")
+
+	var documents []interface{}
+	err = client.Query(context.Background(), "spans", "trace1", Query{Query: "SELECT * FROM c"}, &documents)
+	assert.Error(t, err)
+
+	closer()
+	assert.Error(t, client.Upsert(context.Background(), "spans", "trace1", map[string]string{}))
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConsulDiscoverer yields the addresses of the healthy instances of a service registered in Consul.
+type ConsulDiscoverer struct {
+	address string
+	service string
+	client  *http.Client
+}
+
+type consulServiceEntry struct {
+	Node struct {
+		Address string
+	}
+	Service struct {
+		Address string
+		Port    int
+	}
+}
+
+// NewConsulDiscoverer creates a ConsulDiscoverer querying the HTTP API of the Consul agent at address, e.g. http://127.0.0.1:8500.
+func NewConsulDiscoverer(address, service string) *ConsulDiscoverer {
+	return &ConsulDiscoverer{
+		address: strings.TrimSuffix(address, "/"),
+		service: service,
+		client:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Instances implements Discoverer.
+func (d *ConsulDiscoverer) Instances() ([]string, error) {
+	resp, err := d.client.Get(d.address + "/v1/health/service/" + url.PathEscape(d.service) + "?passing=true")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query Consul for service %s: %w", d.service, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to query Consul for service %s: unexpected status %s", d.service, resp.Status)
+	}
+	var entries []consulServiceEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("failed to decode Consul response for service %s: %w", d.service, err)
+	}
+	instances := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		// the service address is optional, in which case the service listens on the node address
+		host := entry.Service.Address
+		if host == "" {
+			host = entry.Node.Address
+		}
+		instances = append(instances, net.JoinHostPort(host, strconv.Itoa(entry.Service.Port)))
+	}
+	return instances, nil
+}
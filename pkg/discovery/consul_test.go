@@ -0,0 +1,55 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsulDiscoverer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/health/service/jaeger-collector":
+			assert.Equal(t, "true", r.URL.Query().Get("passing"))
+			w.Write([]byte(`[
+				{"Node": {"Address": "10.0.0.1"}, "Service": {"Address": "", "Port": 14250}},
+				{"Node": {"Address": "10.0.0.2"}, "Service": {"Address": "10.1.0.2", "Port": 14251}}
+			]`))
+		case "/v1/health/service/invalid":
+			w.Write([]byte(`{`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	instances, err := NewConsulDiscoverer(server.URL+"/", "jaeger-collector").Instances()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1:14250", "10.1.0.2:14251"}, instances)
+
+	_, err = NewConsulDiscoverer(server.URL, "invalid").Instances()
+	assert.Contains(t, err.Error(), "failed to decode Consul response for service invalid")
+
+	_, err = NewConsulDiscoverer(server.URL, "other").Instances()
+	assert.EqualError(t, err, "failed to query Consul for service other: unexpected status 500 Internal Server Error")
+
+	_, err = NewConsulDiscoverer("http://localhost:1", "other").Instances()
+	assert.Contains(t, err.Error(), "failed to query Consul for service other")
+}
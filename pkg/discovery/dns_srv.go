@@ -0,0 +1,53 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const dnsLookupTimeout = 5 * time.Second
+
+// DNSSRVDiscoverer yields the targets of the DNS SRV records of a name,
+// e.g. _grpc._tcp.jaeger-collector.example.com.
+type DNSSRVDiscoverer struct {
+	name      string
+	lookupSRV func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// NewDNSSRVDiscoverer creates a DNSSRVDiscoverer using the default resolver.
+func NewDNSSRVDiscoverer(name string) *DNSSRVDiscoverer {
+	return &DNSSRVDiscoverer{name: name, lookupSRV: net.DefaultResolver.LookupSRV}
+}
+
+// Instances implements Discoverer.
+func (d *DNSSRVDiscoverer) Instances() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dnsLookupTimeout)
+	defer cancel()
+	_, records, err := d.lookupSRV(ctx, "", "", d.name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up SRV records of %s: %w", d.name, err)
+	}
+	instances := make([]string, 0, len(records))
+	for _, srv := range records {
+		instances = append(instances, net.JoinHostPort(strings.TrimSuffix(srv.Target, "."), strconv.Itoa(int(srv.Port))))
+	}
+	return instances, nil
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNSSRVDiscoverer(t *testing.T) {
+	d := NewDNSSRVDiscoverer("_grpc._tcp.collector.example.com")
+	d.lookupSRV = func(_ context.Context, service, proto, name string) (string, []*net.SRV, error) {
+		assert.Equal(t, "", service)
+		assert.Equal(t, "", proto)
+		assert.Equal(t, "_grpc._tcp.collector.example.com", name)
+		return "", []*net.SRV{
+			{Target: "collector-1.example.com.", Port: 14250},
+			{Target: "collector-2.example.com.", Port: 14251},
+		}, nil
+	}
+	instances, err := d.Instances()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"collector-1.example.com:14250", "collector-2.example.com:14251"}, instances)
+
+	d.lookupSRV = func(context.Context, string, string, string) (string, []*net.SRV, error) {
+		return "", nil, errors.New("no such host")
+	}
+	_, err = d.Instances()
+	assert.EqualError(t, err, "failed to look up SRV records of _grpc._tcp.collector.example.com: no such host")
+}
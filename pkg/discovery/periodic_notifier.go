@@ -0,0 +1,94 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// PeriodicNotifier polls a Discoverer and notifies the observers when the set of instances changes.
+// The previous instances are kept when the discovery fails or yields no instances.
+type PeriodicNotifier struct {
+	Dispatcher
+	discoverer Discoverer
+	interval   time.Duration
+	logger     *zap.Logger
+
+	last     []string
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     sync.WaitGroup
+}
+
+// NewPeriodicNotifier creates a PeriodicNotifier, which must be started with Start.
+func NewPeriodicNotifier(discoverer Discoverer, interval time.Duration, logger *zap.Logger) *PeriodicNotifier {
+	return &PeriodicNotifier{
+		discoverer: discoverer,
+		interval:   interval,
+		logger:     logger,
+		stop:       make(chan struct{}),
+	}
+}
+
+// Start begins polling the discoverer in the background.
+func (n *PeriodicNotifier) Start() {
+	n.done.Add(1)
+	go n.poll()
+}
+
+// Stop ends the polling.
+func (n *PeriodicNotifier) Stop() {
+	n.stopOnce.Do(func() {
+		close(n.stop)
+		n.done.Wait()
+	})
+}
+
+func (n *PeriodicNotifier) poll() {
+	defer n.done.Done()
+	ticker := time.NewTicker(n.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			n.refresh()
+		case <-n.stop:
+			return
+		}
+	}
+}
+
+func (n *PeriodicNotifier) refresh() {
+	instances, err := n.discoverer.Instances()
+	if err != nil {
+		n.logger.Error("Failed to discover instances, keeping the previous ones", zap.Error(err))
+		return
+	}
+	if len(instances) == 0 {
+		n.logger.Warn("No instances discovered, keeping the previous ones")
+		return
+	}
+	sort.Strings(instances)
+	if reflect.DeepEqual(instances, n.last) {
+		return
+	}
+	n.last = instances
+	n.Notify(instances)
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type sequenceDiscoverer struct {
+	sync.Mutex
+	results [][]string
+}
+
+func (d *sequenceDiscoverer) Instances() ([]string, error) {
+	d.Lock()
+	defer d.Unlock()
+	if len(d.results) == 0 {
+		return []string{"b", "a"}, nil
+	}
+	instances := d.results[0]
+	d.results = d.results[1:]
+	if instances == nil {
+		return nil, errors.New("discovery unavailable")
+	}
+	return instances, nil
+}
+
+func TestPeriodicNotifier(t *testing.T) {
+	discoverer := &sequenceDiscoverer{results: [][]string{
+		{"b", "a"},
+		nil,
+		{},
+		{"a", "b"},
+		{"c"},
+	}}
+	n := NewPeriodicNotifier(discoverer, time.Millisecond, zap.NewNop())
+	ch := make(chan []string, 10)
+	n.Register(ch)
+	n.Start()
+	defer n.Stop()
+
+	// failures, empty and unchanged results are not notified
+	assert.Equal(t, []string{"a", "b"}, <-ch)
+	assert.Equal(t, []string{"c"}, <-ch)
+	assert.Equal(t, []string{"a", "b"}, <-ch)
+
+	n.Stop()
+	select {
+	case instances := <-ch:
+		t.Fatalf("unexpected notification after the last change: %v", instances)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
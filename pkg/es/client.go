@@ -18,6 +18,7 @@ package es
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/olivere/elastic"
 )
@@ -27,9 +28,21 @@ type Client interface {
 	IndexExists(index string) IndicesExistsService
 	CreateIndex(index string) IndicesCreateService
 	CreateTemplate(id string) TemplateCreateService
+	DeleteTemplate(id string) TemplateDeleteService
+	CreateIndexTemplate(id string) IndexTemplateCreateService
+	CreateComponentTemplate(name string) ComponentTemplateCreateService
+	CreateDataStream(name string) DataStreamCreateService
+	CreateILMPolicy(name string) PolicyCreateService
+	CreateISMPolicy(name string) PolicyCreateService
 	Index() IndexService
 	Search(indices ...string) SearchService
 	MultiSearch() MultiSearchService
+	AsyncSearch(indices ...string) AsyncSearchService
+	GetAsyncSearch(id string) AsyncSearchGetService
+	DeleteAsyncSearch(id string) AsyncSearchDeleteService
+	OpenPointInTime(indices ...string) PointInTimeOpenService
+	PointInTimeSearch(id string) PointInTimeSearchService
+	ClosePointInTime(id string) PointInTimeCloseService
 	io.Closer
 	GetVersion() uint
 }
@@ -51,11 +64,40 @@ type TemplateCreateService interface {
 	Do(ctx context.Context) (*elastic.IndicesPutTemplateResponse, error)
 }
 
+// TemplateDeleteService is an abstraction for deleting a legacy index template
+type TemplateDeleteService interface {
+	Do(ctx context.Context) (*elastic.IndicesDeleteTemplateResponse, error)
+}
+
+// IndexTemplateCreateService is an abstraction for creating a composable index template, required by data streams
+type IndexTemplateCreateService interface {
+	Body(template string) IndexTemplateCreateService
+	Do(ctx context.Context) (*elastic.IndicesPutTemplateResponse, error)
+}
+
+// ComponentTemplateCreateService is an abstraction for creating a component template of composable index templates
+type ComponentTemplateCreateService interface {
+	Body(template string) ComponentTemplateCreateService
+	Do(ctx context.Context) (*elastic.AcknowledgedResponse, error)
+}
+
+// DataStreamCreateService is an abstraction for creating a data stream
+type DataStreamCreateService interface {
+	Do(ctx context.Context) (*elastic.AcknowledgedResponse, error)
+}
+
+// PolicyCreateService is an abstraction for creating an index lifecycle policy
+type PolicyCreateService interface {
+	Body(policy string) PolicyCreateService
+	Do(ctx context.Context) error
+}
+
 // IndexService is an abstraction for elastic BulkService
 type IndexService interface {
 	Index(index string) IndexService
 	Type(typ string) IndexService
 	Id(id string) IndexService
+	OpType(opType string) IndexService
 	BodyJson(body interface{}) IndexService
 	Add()
 }
@@ -75,3 +117,66 @@ type MultiSearchService interface {
 	Index(indices ...string) MultiSearchService
 	Do(ctx context.Context) (*elastic.MultiSearchResult, error)
 }
+
+// AsyncSearchService is an abstraction for submitting a search to the async search API
+type AsyncSearchService interface {
+	Size(size int) AsyncSearchService
+	Aggregation(name string, aggregation elastic.Aggregation) AsyncSearchService
+	IgnoreUnavailable(ignoreUnavailable bool) AsyncSearchService
+	Query(query elastic.Query) AsyncSearchService
+	WaitForCompletionTimeout(timeout time.Duration) AsyncSearchService
+	KeepAlive(keepAlive time.Duration) AsyncSearchService
+	Do(ctx context.Context) (*AsyncSearchResult, error)
+}
+
+// AsyncSearchGetService is an abstraction for polling a search submitted to the async search API
+type AsyncSearchGetService interface {
+	WaitForCompletionTimeout(timeout time.Duration) AsyncSearchGetService
+	Do(ctx context.Context) (*AsyncSearchResult, error)
+}
+
+// AsyncSearchDeleteService is an abstraction for cancelling a search submitted to the async search API
+// and deleting its results
+type AsyncSearchDeleteService interface {
+	Do(ctx context.Context) error
+}
+
+// AsyncSearchResult is the state of a search submitted to the async search API. Response holds
+// the results gathered so far while the search is running, and is partial if some shards failed.
+type AsyncSearchResult struct {
+	ID        string                `json:"id"`
+	IsPartial bool                  `json:"is_partial"`
+	IsRunning bool                  `json:"is_running"`
+	Response  *elastic.SearchResult `json:"response"`
+}
+
+// PointInTimeOpenService is an abstraction for opening a point in time, a view of indices searched consistently by several requests
+type PointInTimeOpenService interface {
+	IgnoreUnavailable(ignoreUnavailable bool) PointInTimeOpenService
+	KeepAlive(keepAlive time.Duration) PointInTimeOpenService
+	Do(ctx context.Context) (string, error)
+}
+
+// PointInTimeSearchService is an abstraction for searching a point in time, paged with search_after
+type PointInTimeSearchService interface {
+	Size(size int) PointInTimeSearchService
+	Aggregation(name string, aggregation elastic.Aggregation) PointInTimeSearchService
+	Query(query elastic.Query) PointInTimeSearchService
+	Sort(field string, ascending bool) PointInTimeSearchService
+	SearchAfter(sortValues ...interface{}) PointInTimeSearchService
+	FetchSource(fields ...string) PointInTimeSearchService
+	KeepAlive(keepAlive time.Duration) PointInTimeSearchService
+	Do(ctx context.Context) (*PointInTimeSearchResult, error)
+}
+
+// PointInTimeSearchResult is the result of a search of a point in time, with the ID of the point in time
+// to use for the next search, which Elasticsearch may have changed.
+type PointInTimeSearchResult struct {
+	ID       string
+	Response *elastic.SearchResult
+}
+
+// PointInTimeCloseService is an abstraction for closing a point in time
+type PointInTimeCloseService interface {
+	Do(ctx context.Context) error
+}
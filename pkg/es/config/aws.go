@@ -0,0 +1,115 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+const (
+	awsServiceOpenSearch = "es"
+	awsServiceServerless = "aoss"
+
+	// serverlessVersion is the Elasticsearch version whose API OpenSearch Serverless is compatible with
+	serverlessVersion = 7
+)
+
+// AWSSigning configures the signing of the requests with AWS Signature Version 4, as required by
+// Amazon OpenSearch Service and OpenSearch Serverless.
+type AWSSigning struct {
+	// Enabled signs the requests with the credentials of the environment of the AWS SDK
+	Enabled bool `mapstructure:"enabled"`
+	// Region is the AWS region of the domain or the collection, the region of the environment if empty
+	Region string `mapstructure:"region"`
+	// Serverless signs the requests for an OpenSearch Serverless collection, and restricts
+	// the client to the APIs it supports
+	Serverless bool `mapstructure:"serverless"`
+}
+
+func (a AWSSigning) service() string {
+	if a.Serverless {
+		return awsServiceServerless
+	}
+	return awsServiceOpenSearch
+}
+
+// newAWSSigningTransport creates a transport signing the requests with the credentials
+// and the region of the environment of the AWS SDK, unless the region is set.
+func newAWSSigningTransport(cfg AWSSigning, wrapped http.RoundTripper) (*awsSigningTransport, error) {
+	config := aws.NewConfig()
+	if cfg.Region != "" {
+		config = config.WithRegion(cfg.Region)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *config,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &awsSigningTransport{
+		credentials: sess.Config.Credentials,
+		region:      aws.StringValue(sess.Config.Region),
+		service:     cfg.service(),
+		wrapped:     wrapped,
+	}, nil
+}
+
+// awsSigningTransport signs the requests with AWS Signature Version 4
+type awsSigningTransport struct {
+	credentials *credentials.Credentials
+	region      string
+	service     string
+	wrapped     http.RoundTripper
+}
+
+func (tr *awsSigningTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	var body []byte
+	var bodyReader io.ReadSeeker
+	if r.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(r.Body); err != nil {
+			return nil, err
+		}
+		if err := r.Body.Close(); err != nil {
+			return nil, err
+		}
+		// the signer sets the body of the signed request
+		bodyReader = bytes.NewReader(body)
+	}
+	signed := r.Clone(r.Context())
+	// the basic auth of the client is replaced by the signature
+	signed.Header.Del("Authorization")
+	if tr.service == awsServiceServerless {
+		// OpenSearch Serverless requires the hash of the payload as a header
+		sum := sha256.Sum256(body)
+		signed.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(sum[:]))
+	}
+	if _, err := v4.NewSigner(tr.credentials).Sign(signed, bodyReader, tr.service, tr.region, time.Now()); err != nil {
+		return nil, err
+	}
+	return tr.wrapped.RoundTrip(signed)
+}
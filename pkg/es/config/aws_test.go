@@ -0,0 +1,108 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestAWSSigningTransport(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        AWSSigning
+		body       string
+		credential string
+		sha256     string
+	}{
+		{
+			name:       "opensearch service",
+			cfg:        AWSSigning{Enabled: true},
+			body:       `{"query":{}}`,
+			credential: "/us-east-1/es/aws4_request",
+		},
+		{
+			name:       "serverless",
+			cfg:        AWSSigning{Enabled: true, Serverless: true},
+			credential: "/us-east-1/aoss/aws4_request",
+			sha256:     "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var received *http.Request
+			var receivedBody string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := ioutil.ReadAll(r.Body)
+				require.NoError(t, err)
+				received, receivedBody = r, string(body)
+			}))
+			defer server.Close()
+
+			transport := &awsSigningTransport{
+				credentials: credentials.NewStaticCredentials("AKID", "SECRET", ""),
+				region:      "us-east-1",
+				service:     test.cfg.service(),
+				wrapped:     http.DefaultTransport,
+			}
+			method, body := http.MethodGet, io.Reader(nil)
+			if test.body != "" {
+				method, body = http.MethodPost, strings.NewReader(test.body)
+			}
+			req, err := http.NewRequest(method, server.URL+"/jaeger-span-*/_search", body)
+			require.NoError(t, err)
+			req.SetBasicAuth("user", "password")
+			res, err := (&http.Client{Transport: transport}).Do(req)
+			require.NoError(t, err)
+			require.NoError(t, res.Body.Close())
+
+			require.NotNil(t, received)
+			authorization := received.Header.Get("Authorization")
+			assert.True(t, strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=AKID/"), authorization)
+			assert.Contains(t, authorization, test.credential)
+			assert.NotEmpty(t, received.Header.Get("X-Amz-Date"))
+			assert.Equal(t, test.sha256, received.Header.Get("X-Amz-Content-Sha256"))
+			assert.Equal(t, test.body, receivedBody)
+			assert.Equal(t, "Basic dXNlcjpwYXNzd29yZA==", req.Header.Get("Authorization"), "the original request is not modified")
+		})
+	}
+}
+
+func TestNewAWSSigningTransport(t *testing.T) {
+	transport, err := newAWSSigningTransport(AWSSigning{Enabled: true, Region: "eu-west-1", Serverless: true}, http.DefaultTransport)
+	require.NoError(t, err)
+	assert.Equal(t, "eu-west-1", transport.region)
+	assert.Equal(t, awsServiceServerless, transport.service)
+}
+
+func TestNewClientServerless(t *testing.T) {
+	c := &Configuration{Servers: []string{"http://localhost:9200"}, AWS: AWSSigning{Serverless: true}}
+	_, err := c.NewClient(zap.NewNop(), nil)
+	assert.EqualError(t, err, "OpenSearch Serverless requires the signing of the requests with AWS Signature Version 4")
+
+	c.AWS.Enabled = true
+	c.IndexLifecycle.Type = "ism"
+	_, err = c.NewClient(zap.NewNop(), nil)
+	assert.EqualError(t, err, "the index lifecycle policies, the async search and the points in time are not supported by OpenSearch Serverless")
+}
@@ -57,11 +57,19 @@ type Configuration struct {
 	BulkActions           int            `mapstructure:"-"`
 	BulkFlushInterval     time.Duration  `mapstructure:"-"`
 	IndexPrefix           string         `mapstructure:"index_prefix"`
+	TenantIndexPrefix     bool           `mapstructure:"tenant_index_prefix"`
 	Tags                  TagsAsFields   `mapstructure:"tags_as_fields"`
+	Storage               Storage        `mapstructure:"storage"`
 	Enabled               bool           `mapstructure:"-"`
 	TLS                   tlscfg.Options `mapstructure:"tls"`
 	UseReadWriteAliases   bool           `mapstructure:"use_aliases"`
+	UseDataStreams        bool           `mapstructure:"use_data_streams"`
+	IndexLifecycle        IndexLifecycle `mapstructure:"index_lifecycle"`
+	AsyncSearch           AsyncSearch    `mapstructure:"async_search"`
+	PointInTime           PointInTime    `mapstructure:"point_in_time"`
+	AWS                   AWSSigning     `mapstructure:"aws"`
 	CreateIndexTemplates  bool           `mapstructure:"create_mappings"`
+	ComposableTemplates   bool           `mapstructure:"composable_templates"`
 	Version               uint           `mapstructure:"version"`
 }
 
@@ -73,8 +81,63 @@ type TagsAsFields struct {
 	AllAsFields bool `mapstructure:"all"`
 	// Dot replacement for tag keys when stored as object fields
 	DotReplacement string `mapstructure:"dot_replacement"`
-	// File path to tag keys which should be stored as object fields
+	// File path to tag keys which should be stored as object fields, or with the strategy following
+	// their key and an equal sign, e.g. http.url=flattened
 	File string `mapstructure:"config_file"`
+	// DefaultStrategy stores the tags whose keys are not in the file as "nested", "object" or "flattened"
+	// fields, nested unless all tags are stored as object fields
+	DefaultStrategy string `mapstructure:"default_strategy"`
+}
+
+// Storage configures how the spans are stored in the indices, to reduce their disk usage.
+// Like the other settings of the index templates, it only applies to the indices created afterwards.
+type Storage struct {
+	// IndexCodec compresses the stored fields of the indices, "default" or "best_compression"
+	IndexCodec string `mapstructure:"index_codec"`
+	// ExcludeDerivedFields leaves the fields derived from other fields of the spans, like startTimeMillis,
+	// out of their source. They are still indexed and searchable.
+	ExcludeDerivedFields bool `mapstructure:"exclude_derived_fields"`
+	// UnindexedFields are the fields of the spans only kept in their source, "logs" and "references".
+	// The tags of the logs of the spans are not searchable when their logs are not indexed.
+	UnindexedFields []string `mapstructure:"unindexed_fields"`
+	// MeasureSize reports the size of the span documents written to the indices
+	MeasureSize bool `mapstructure:"measure_size"`
+}
+
+// IndexLifecycle configures the policy managing the rollover and the deletion of the indices,
+// created at startup and attached to the rollover indices or to the backing indices of the data streams.
+type IndexLifecycle struct {
+	// Type is "ilm" for Elasticsearch index lifecycle management, "ism" for OpenSearch index state
+	// management, empty to leave the indices to external tools
+	Type string `mapstructure:"type"`
+	// PolicyName defaults to jaeger-index-policy, prefixed by the index prefix
+	PolicyName string `mapstructure:"policy_name"`
+	// RolloverMaxAge and RolloverMaxSize are the conditions to roll over the write index, zero values are ignored
+	RolloverMaxAge  time.Duration `mapstructure:"rollover_max_age"`
+	RolloverMaxSize string        `mapstructure:"rollover_max_size"`
+	// DeleteAfter is the age of the indices deleted after their rollover, 0 never deletes them
+	DeleteAfter time.Duration `mapstructure:"delete_after"`
+}
+
+// AsyncSearch configures the async search API used to find the traces, so that the searches
+// over many indices outliving the timeout of the requests still return their partial results.
+type AsyncSearch struct {
+	// Enabled submits the searches of the traces to the async search API, which requires Elasticsearch 7.7 or later
+	Enabled bool `mapstructure:"enabled"`
+	// WaitTimeout is how long a request waits for the search to complete before polling it again,
+	// it must stay below the timeout of the requests
+	WaitTimeout time.Duration `mapstructure:"wait_timeout"`
+	// Budget is how long the search runs before its partial results are returned
+	Budget time.Duration `mapstructure:"budget"`
+}
+
+// PointInTime configures the points in time paged through with search_after to find the traces,
+// so that the searches return any number of traces and the query service pages through them.
+type PointInTime struct {
+	// Enabled finds the traces in a point in time of the indices, which requires Elasticsearch 7.10 or later
+	Enabled bool `mapstructure:"enabled"`
+	// KeepAlive is how long a point in time is kept between the pages of a search
+	KeepAlive time.Duration `mapstructure:"keep_alive"`
 }
 
 // ClientBuilder creates new es.Client
@@ -85,13 +148,22 @@ type ClientBuilder interface {
 	GetMaxSpanAge() time.Duration
 	GetMaxNumSpans() int
 	GetIndexPrefix() string
+	GetTenantIndexPrefix() bool
 	GetTagsFilePath() string
 	GetAllTagsAsFields() bool
 	GetTagDotReplacement() string
+	GetTagDefaultStrategy() string
 	GetUseReadWriteAliases() bool
+	GetUseDataStreams() bool
+	GetIndexLifecycle() IndexLifecycle
+	GetAsyncSearch() AsyncSearch
+	GetPointInTime() PointInTime
+	GetStorage() Storage
+	GetAWSSigning() AWSSigning
 	GetTokenFilePath() string
 	IsStorageEnabled() bool
 	IsCreateIndexTemplates() bool
+	GetComposableTemplates() bool
 	GetVersion() uint
 }
 
@@ -100,6 +172,14 @@ func (c *Configuration) NewClient(logger *zap.Logger, metricsFactory metrics.Fac
 	if len(c.Servers) < 1 {
 		return nil, errors.New("no servers specified")
 	}
+	if c.AWS.Serverless {
+		if !c.AWS.Enabled {
+			return nil, errors.New("OpenSearch Serverless requires the signing of the requests with AWS Signature Version 4")
+		}
+		if c.IndexLifecycle.Type != "" || c.AsyncSearch.Enabled || c.PointInTime.Enabled {
+			return nil, errors.New("the index lifecycle policies, the async search and the points in time are not supported by OpenSearch Serverless")
+		}
+	}
 	options, err := c.getConfigOptions(logger)
 	if err != nil {
 		return nil, err
@@ -161,6 +241,10 @@ func (c *Configuration) NewClient(logger *zap.Logger, metricsFactory metrics.Fac
 		return nil, err
 	}
 
+	if c.Version == 0 && c.AWS.Serverless {
+		// OpenSearch Serverless does not expose its version
+		c.Version = serverlessVersion
+	}
 	if c.Version == 0 {
 		// Determine ElasticSearch Version
 		pingResult, _, err := rawClient.Ping(c.Servers[0]).Do(context.Background())
@@ -216,6 +300,15 @@ func (c *Configuration) ApplyDefaults(source *Configuration) {
 	if !c.SnifferTLSEnabled {
 		c.SnifferTLSEnabled = source.SnifferTLSEnabled
 	}
+	if !c.AWS.Enabled {
+		c.AWS.Enabled = source.AWS.Enabled
+	}
+	if c.AWS.Region == "" {
+		c.AWS.Region = source.AWS.Region
+	}
+	if !c.AWS.Serverless {
+		c.AWS.Serverless = source.AWS.Serverless
+	}
 }
 
 // GetNumShards returns number of shards from Configuration
@@ -243,6 +336,11 @@ func (c *Configuration) GetIndexPrefix() string {
 	return c.IndexPrefix
 }
 
+// GetTenantIndexPrefix indicates whether the spans of each tenant are stored in indices prefixed by the tenant
+func (c *Configuration) GetTenantIndexPrefix() bool {
+	return c.TenantIndexPrefix
+}
+
 // GetTagsFilePath returns a path to file containing tag keys
 func (c *Configuration) GetTagsFilePath() string {
 	return c.Tags.File
@@ -264,11 +362,46 @@ func (c *Configuration) GetTagDotReplacement() string {
 	return c.Tags.DotReplacement
 }
 
+// GetTagDefaultStrategy returns how the tags whose keys have no strategy are stored
+func (c *Configuration) GetTagDefaultStrategy() string {
+	return c.Tags.DefaultStrategy
+}
+
 // GetUseReadWriteAliases indicates whether read alias should be used
 func (c *Configuration) GetUseReadWriteAliases() bool {
 	return c.UseReadWriteAliases
 }
 
+// GetUseDataStreams indicates whether the spans and services are written to data streams
+func (c *Configuration) GetUseDataStreams() bool {
+	return c.UseDataStreams
+}
+
+// GetIndexLifecycle returns the configuration of the index lifecycle policy
+func (c *Configuration) GetIndexLifecycle() IndexLifecycle {
+	return c.IndexLifecycle
+}
+
+// GetAsyncSearch returns the configuration of the async search of the traces
+func (c *Configuration) GetAsyncSearch() AsyncSearch {
+	return c.AsyncSearch
+}
+
+// GetPointInTime returns the configuration of the points in time of the searches of the traces
+func (c *Configuration) GetPointInTime() PointInTime {
+	return c.PointInTime
+}
+
+// GetStorage returns the configuration of the storage of the spans in the indices
+func (c *Configuration) GetStorage() Storage {
+	return c.Storage
+}
+
+// GetAWSSigning returns the configuration of the signing of the requests for Amazon OpenSearch
+func (c *Configuration) GetAWSSigning() AWSSigning {
+	return c.AWS
+}
+
 // GetTokenFilePath returns file path containing the bearer token
 func (c *Configuration) GetTokenFilePath() string {
 	return c.TokenFilePath
@@ -284,14 +417,22 @@ func (c *Configuration) IsCreateIndexTemplates() bool {
 	return c.CreateIndexTemplates
 }
 
+// GetComposableTemplates indicates whether composable index templates are created instead of legacy ones
+func (c *Configuration) GetComposableTemplates() bool {
+	return c.ComposableTemplates
+}
+
 // getConfigOptions wraps the configs to feed to the ElasticSearch client init
 func (c *Configuration) getConfigOptions(logger *zap.Logger) ([]elastic.ClientOptionFunc, error) {
 
-	options := []elastic.ClientOptionFunc{elastic.SetURL(c.Servers...), elastic.SetSniff(c.Sniffer),
+	options := []elastic.ClientOptionFunc{elastic.SetURL(c.Servers...),
+		// OpenSearch Serverless has neither the nodes info API used by sniffing nor the root endpoint
+		// used by the health check.
+		elastic.SetSniff(c.Sniffer && !c.AWS.Serverless),
 		// Disable health check when token from context is allowed, this is because at this time
 		// we don' have a valid token to do the check ad if we don't disable the check the service that
 		// uses this won't start.
-		elastic.SetHealthcheck(!c.AllowTokenFromContext)}
+		elastic.SetHealthcheck(!c.AllowTokenFromContext && !c.AWS.Serverless)}
 	if c.SnifferTLSEnabled {
 		options = append(options, elastic.SetScheme("https"))
 	}
@@ -344,6 +485,13 @@ func (c *Configuration) getConfigOptions(logger *zap.Logger) ([]elastic.ClientOp
 			options = append(options, elastic.SetBasicAuth(c.Username, c.Password))
 		}
 	}
+	if c.AWS.Enabled {
+		signingTransport, err := newAWSSigningTransport(c.AWS, httpClient.Transport)
+		if err != nil {
+			return nil, err
+		}
+		httpClient.Transport = signingTransport
+	}
 	return options, nil
 }
 
@@ -0,0 +1,31 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package es
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/olivere/elastic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsAlreadyExists(t *testing.T) {
+	assert.True(t, IsAlreadyExists(&elastic.Error{Status: 400, Details: &elastic.ErrorDetails{Type: "resource_already_exists_exception"}}))
+	assert.False(t, IsAlreadyExists(&elastic.Error{Status: 400, Details: &elastic.ErrorDetails{Type: "illegal_argument_exception"}}))
+	assert.False(t, IsAlreadyExists(&elastic.Error{Status: 500}))
+	assert.False(t, IsAlreadyExists(errors.New("resource_already_exists_exception")))
+	assert.False(t, IsAlreadyExists(nil))
+}
@@ -28,6 +28,28 @@ type Client struct {
 	mock.Mock
 }
 
+// AsyncSearch provides a mock function with given fields: indices
+func (_m *Client) AsyncSearch(indices ...string) es.AsyncSearchService {
+	_va := make([]interface{}, len(indices))
+	for _i := range indices {
+		_va[_i] = indices[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 es.AsyncSearchService
+	if rf, ok := ret.Get(0).(func(...string) es.AsyncSearchService); ok {
+		r0 = rf(indices...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(es.AsyncSearchService)
+		}
+	}
+
+	return r0
+}
+
 // Close provides a mock function with given fields:
 func (_m *Client) Close() error {
 	ret := _m.Called()
@@ -42,6 +64,86 @@ func (_m *Client) Close() error {
 	return r0
 }
 
+// ClosePointInTime provides a mock function with given fields: id
+func (_m *Client) ClosePointInTime(id string) es.PointInTimeCloseService {
+	ret := _m.Called(id)
+
+	var r0 es.PointInTimeCloseService
+	if rf, ok := ret.Get(0).(func(string) es.PointInTimeCloseService); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(es.PointInTimeCloseService)
+		}
+	}
+
+	return r0
+}
+
+// CreateComponentTemplate provides a mock function with given fields: name
+func (_m *Client) CreateComponentTemplate(name string) es.ComponentTemplateCreateService {
+	ret := _m.Called(name)
+
+	var r0 es.ComponentTemplateCreateService
+	if rf, ok := ret.Get(0).(func(string) es.ComponentTemplateCreateService); ok {
+		r0 = rf(name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(es.ComponentTemplateCreateService)
+		}
+	}
+
+	return r0
+}
+
+// CreateDataStream provides a mock function with given fields: name
+func (_m *Client) CreateDataStream(name string) es.DataStreamCreateService {
+	ret := _m.Called(name)
+
+	var r0 es.DataStreamCreateService
+	if rf, ok := ret.Get(0).(func(string) es.DataStreamCreateService); ok {
+		r0 = rf(name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(es.DataStreamCreateService)
+		}
+	}
+
+	return r0
+}
+
+// CreateILMPolicy provides a mock function with given fields: name
+func (_m *Client) CreateILMPolicy(name string) es.PolicyCreateService {
+	ret := _m.Called(name)
+
+	var r0 es.PolicyCreateService
+	if rf, ok := ret.Get(0).(func(string) es.PolicyCreateService); ok {
+		r0 = rf(name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(es.PolicyCreateService)
+		}
+	}
+
+	return r0
+}
+
+// CreateISMPolicy provides a mock function with given fields: name
+func (_m *Client) CreateISMPolicy(name string) es.PolicyCreateService {
+	ret := _m.Called(name)
+
+	var r0 es.PolicyCreateService
+	if rf, ok := ret.Get(0).(func(string) es.PolicyCreateService); ok {
+		r0 = rf(name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(es.PolicyCreateService)
+		}
+	}
+
+	return r0
+}
+
 // CreateIndex provides a mock function with given fields: index
 func (_m *Client) CreateIndex(index string) es.IndicesCreateService {
 	ret := _m.Called(index)
@@ -58,6 +160,22 @@ func (_m *Client) CreateIndex(index string) es.IndicesCreateService {
 	return r0
 }
 
+// CreateIndexTemplate provides a mock function with given fields: id
+func (_m *Client) CreateIndexTemplate(id string) es.IndexTemplateCreateService {
+	ret := _m.Called(id)
+
+	var r0 es.IndexTemplateCreateService
+	if rf, ok := ret.Get(0).(func(string) es.IndexTemplateCreateService); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(es.IndexTemplateCreateService)
+		}
+	}
+
+	return r0
+}
+
 // CreateTemplate provides a mock function with given fields: id
 func (_m *Client) CreateTemplate(id string) es.TemplateCreateService {
 	ret := _m.Called(id)
@@ -74,6 +192,54 @@ func (_m *Client) CreateTemplate(id string) es.TemplateCreateService {
 	return r0
 }
 
+// DeleteAsyncSearch provides a mock function with given fields: id
+func (_m *Client) DeleteAsyncSearch(id string) es.AsyncSearchDeleteService {
+	ret := _m.Called(id)
+
+	var r0 es.AsyncSearchDeleteService
+	if rf, ok := ret.Get(0).(func(string) es.AsyncSearchDeleteService); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(es.AsyncSearchDeleteService)
+		}
+	}
+
+	return r0
+}
+
+// DeleteTemplate provides a mock function with given fields: id
+func (_m *Client) DeleteTemplate(id string) es.TemplateDeleteService {
+	ret := _m.Called(id)
+
+	var r0 es.TemplateDeleteService
+	if rf, ok := ret.Get(0).(func(string) es.TemplateDeleteService); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(es.TemplateDeleteService)
+		}
+	}
+
+	return r0
+}
+
+// GetAsyncSearch provides a mock function with given fields: id
+func (_m *Client) GetAsyncSearch(id string) es.AsyncSearchGetService {
+	ret := _m.Called(id)
+
+	var r0 es.AsyncSearchGetService
+	if rf, ok := ret.Get(0).(func(string) es.AsyncSearchGetService); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(es.AsyncSearchGetService)
+		}
+	}
+
+	return r0
+}
+
 // GetVersion provides a mock function with given fields:
 func (_m *Client) GetVersion() uint {
 	ret := _m.Called()
@@ -136,6 +302,44 @@ func (_m *Client) MultiSearch() es.MultiSearchService {
 	return r0
 }
 
+// OpenPointInTime provides a mock function with given fields: indices
+func (_m *Client) OpenPointInTime(indices ...string) es.PointInTimeOpenService {
+	_va := make([]interface{}, len(indices))
+	for _i := range indices {
+		_va[_i] = indices[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 es.PointInTimeOpenService
+	if rf, ok := ret.Get(0).(func(...string) es.PointInTimeOpenService); ok {
+		r0 = rf(indices...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(es.PointInTimeOpenService)
+		}
+	}
+
+	return r0
+}
+
+// PointInTimeSearch provides a mock function with given fields: id
+func (_m *Client) PointInTimeSearch(id string) es.PointInTimeSearchService {
+	ret := _m.Called(id)
+
+	var r0 es.PointInTimeSearchService
+	if rf, ok := ret.Get(0).(func(string) es.PointInTimeSearchService); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(es.PointInTimeSearchService)
+		}
+	}
+
+	return r0
+}
+
 // Search provides a mock function with given fields: indices
 func (_m *Client) Search(indices ...string) es.SearchService {
 	_va := make([]interface{}, len(indices))
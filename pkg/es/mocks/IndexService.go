@@ -81,6 +81,22 @@ func (_m *IndexService) Index(index string) es.IndexService {
 	return r0
 }
 
+// OpType provides a mock function with given fields: opType
+func (_m *IndexService) OpType(opType string) es.IndexService {
+	ret := _m.Called(opType)
+
+	var r0 es.IndexService
+	if rf, ok := ret.Get(0).(func(string) es.IndexService); ok {
+		r0 = rf(opType)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(es.IndexService)
+		}
+	}
+
+	return r0
+}
+
 // Type provides a mock function with given fields: typ
 func (_m *IndexService) Type(typ string) es.IndexService {
 	ret := _m.Called(typ)
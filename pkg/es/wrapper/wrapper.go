@@ -17,6 +17,12 @@ package eswrapper
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/olivere/elastic"
 
@@ -57,6 +63,36 @@ func (c ClientWrapper) CreateTemplate(ttype string) es.TemplateCreateService {
 	return WrapESTemplateCreateService(c.client.IndexPutTemplate(ttype))
 }
 
+// DeleteTemplate calls this function to internal client.
+func (c ClientWrapper) DeleteTemplate(id string) es.TemplateDeleteService {
+	return c.client.IndexDeleteTemplate(id)
+}
+
+// CreateIndexTemplate creates a composable index template, the client predates their API.
+func (c ClientWrapper) CreateIndexTemplate(id string) es.IndexTemplateCreateService {
+	return IndexTemplateCreateServiceWrapper{client: c.client, id: id}
+}
+
+// CreateComponentTemplate creates a component template, the client predates their API.
+func (c ClientWrapper) CreateComponentTemplate(name string) es.ComponentTemplateCreateService {
+	return ComponentTemplateCreateServiceWrapper{client: c.client, name: name}
+}
+
+// CreateDataStream creates a data stream, the client predates their API.
+func (c ClientWrapper) CreateDataStream(name string) es.DataStreamCreateService {
+	return DataStreamCreateServiceWrapper{client: c.client, name: name}
+}
+
+// CreateILMPolicy creates or updates an Elasticsearch index lifecycle management policy.
+func (c ClientWrapper) CreateILMPolicy(name string) es.PolicyCreateService {
+	return PolicyCreateServiceWrapper{client: c.client, path: "/_ilm/policy/" + name}
+}
+
+// CreateISMPolicy creates an OpenSearch index state management policy.
+func (c ClientWrapper) CreateISMPolicy(name string) es.PolicyCreateService {
+	return PolicyCreateServiceWrapper{client: c.client, path: "/_plugins/_ism/policies/" + name}
+}
+
 // Index calls this function to internal client.
 func (c ClientWrapper) Index() es.IndexService {
 	r := elastic.NewBulkIndexRequest()
@@ -66,7 +102,7 @@ func (c ClientWrapper) Index() es.IndexService {
 // Search calls this function to internal client.
 func (c ClientWrapper) Search(indices ...string) es.SearchService {
 	searchService := c.client.Search(indices...)
-	if c.esVersion == 7 {
+	if c.esVersion >= 7 {
 		searchService = searchService.RestTotalHitsAsInt(true)
 	}
 	return WrapESSearchService(searchService)
@@ -75,12 +111,47 @@ func (c ClientWrapper) Search(indices ...string) es.SearchService {
 // MultiSearch calls this function to internal client.
 func (c ClientWrapper) MultiSearch() es.MultiSearchService {
 	multiSearchService := c.client.MultiSearch()
-	if c.esVersion == 7 {
+	if c.esVersion >= 7 {
 		multiSearchService = multiSearchService.RestTotalHitsAsInt(true)
 	}
 	return WrapESMultiSearchService(multiSearchService)
 }
 
+// AsyncSearch submits a search to the async search API.
+func (c ClientWrapper) AsyncSearch(indices ...string) es.AsyncSearchService {
+	return AsyncSearchServiceWrapper{
+		client:  c.client,
+		indices: indices,
+		source:  elastic.NewSearchSource(),
+		params:  url.Values{},
+	}
+}
+
+// GetAsyncSearch polls a search submitted to the async search API.
+func (c ClientWrapper) GetAsyncSearch(id string) es.AsyncSearchGetService {
+	return AsyncSearchGetServiceWrapper{client: c.client, id: id, params: url.Values{}}
+}
+
+// DeleteAsyncSearch cancels a search submitted to the async search API and deletes its results.
+func (c ClientWrapper) DeleteAsyncSearch(id string) es.AsyncSearchDeleteService {
+	return AsyncSearchDeleteServiceWrapper{client: c.client, id: id}
+}
+
+// OpenPointInTime opens a point in time of the indices, the client predates its API.
+func (c ClientWrapper) OpenPointInTime(indices ...string) es.PointInTimeOpenService {
+	return PointInTimeOpenServiceWrapper{client: c.client, indices: indices, params: url.Values{}}
+}
+
+// PointInTimeSearch searches a point in time, the client predates its API.
+func (c ClientWrapper) PointInTimeSearch(id string) es.PointInTimeSearchService {
+	return PointInTimeSearchServiceWrapper{client: c.client, id: id, source: elastic.NewSearchSource()}
+}
+
+// ClosePointInTime closes a point in time, the client predates its API.
+func (c ClientWrapper) ClosePointInTime(id string) es.PointInTimeCloseService {
+	return PointInTimeCloseServiceWrapper{client: c.client, id: id}
+}
+
 // Close closes ESClient and flushes all data to the storage.
 func (c ClientWrapper) Close() error {
 	return c.bulkService.Close()
@@ -145,6 +216,366 @@ func (c TemplateCreateServiceWrapper) Do(ctx context.Context) (*elastic.IndicesP
 	return c.mappingCreateService.Do(ctx)
 }
 
+// IndexTemplateCreateServiceWrapper creates a composable index template with the _index_template API.
+type IndexTemplateCreateServiceWrapper struct {
+	client *elastic.Client
+	id     string
+	body   string
+}
+
+// Body sets the body of the index template.
+func (c IndexTemplateCreateServiceWrapper) Body(template string) es.IndexTemplateCreateService {
+	c.body = template
+	return c
+}
+
+// Do puts the index template.
+func (c IndexTemplateCreateServiceWrapper) Do(ctx context.Context) (*elastic.IndicesPutTemplateResponse, error) {
+	res, err := c.client.PerformRequest(ctx, elastic.PerformRequestOptions{
+		Method: http.MethodPut,
+		Path:   "/_index_template/" + c.id,
+		Body:   c.body,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ret := new(elastic.IndicesPutTemplateResponse)
+	if err := json.Unmarshal(res.Body, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// ComponentTemplateCreateServiceWrapper creates a component template with the _component_template API.
+type ComponentTemplateCreateServiceWrapper struct {
+	client *elastic.Client
+	name   string
+	body   string
+}
+
+// Body sets the body of the component template.
+func (c ComponentTemplateCreateServiceWrapper) Body(template string) es.ComponentTemplateCreateService {
+	c.body = template
+	return c
+}
+
+// Do puts the component template.
+func (c ComponentTemplateCreateServiceWrapper) Do(ctx context.Context) (*elastic.AcknowledgedResponse, error) {
+	res, err := c.client.PerformRequest(ctx, elastic.PerformRequestOptions{
+		Method: http.MethodPut,
+		Path:   "/_component_template/" + c.name,
+		Body:   c.body,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ret := new(elastic.AcknowledgedResponse)
+	if err := json.Unmarshal(res.Body, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// DataStreamCreateServiceWrapper creates a data stream with the _data_stream API.
+type DataStreamCreateServiceWrapper struct {
+	client *elastic.Client
+	name   string
+}
+
+// Do creates the data stream.
+func (c DataStreamCreateServiceWrapper) Do(ctx context.Context) (*elastic.AcknowledgedResponse, error) {
+	res, err := c.client.PerformRequest(ctx, elastic.PerformRequestOptions{
+		Method: http.MethodPut,
+		Path:   "/_data_stream/" + c.name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ret := new(elastic.AcknowledgedResponse)
+	if err := json.Unmarshal(res.Body, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// PolicyCreateServiceWrapper puts an index lifecycle policy.
+type PolicyCreateServiceWrapper struct {
+	client *elastic.Client
+	path   string
+	body   string
+}
+
+// Body sets the body of the policy.
+func (c PolicyCreateServiceWrapper) Body(policy string) es.PolicyCreateService {
+	c.body = policy
+	return c
+}
+
+// Do puts the policy.
+func (c PolicyCreateServiceWrapper) Do(ctx context.Context) error {
+	_, err := c.client.PerformRequest(ctx, elastic.PerformRequestOptions{
+		Method: http.MethodPut,
+		Path:   c.path,
+		Body:   c.body,
+	})
+	return err
+}
+
+// AsyncSearchServiceWrapper submits a search with the _async_search API.
+type AsyncSearchServiceWrapper struct {
+	client  *elastic.Client
+	indices []string
+	source  *elastic.SearchSource
+	params  url.Values
+}
+
+// Size sets the number of hits to return.
+func (s AsyncSearchServiceWrapper) Size(size int) es.AsyncSearchService {
+	s.source = s.source.Size(size)
+	return s
+}
+
+// Aggregation adds an aggregation to the search.
+func (s AsyncSearchServiceWrapper) Aggregation(name string, aggregation elastic.Aggregation) es.AsyncSearchService {
+	s.source = s.source.Aggregation(name, aggregation)
+	return s
+}
+
+// IgnoreUnavailable ignores the missing or closed indices.
+func (s AsyncSearchServiceWrapper) IgnoreUnavailable(ignoreUnavailable bool) es.AsyncSearchService {
+	s.params.Set("ignore_unavailable", fmt.Sprint(ignoreUnavailable))
+	return s
+}
+
+// Query sets the query of the search.
+func (s AsyncSearchServiceWrapper) Query(query elastic.Query) es.AsyncSearchService {
+	s.source = s.source.Query(query)
+	return s
+}
+
+// WaitForCompletionTimeout sets how long the submit waits for the search to complete.
+func (s AsyncSearchServiceWrapper) WaitForCompletionTimeout(timeout time.Duration) es.AsyncSearchService {
+	s.params.Set("wait_for_completion_timeout", timeValue(timeout))
+	return s
+}
+
+// KeepAlive sets how long the search and its results are kept.
+func (s AsyncSearchServiceWrapper) KeepAlive(keepAlive time.Duration) es.AsyncSearchService {
+	s.params.Set("keep_alive", timeValue(keepAlive))
+	return s
+}
+
+// Do submits the search.
+func (s AsyncSearchServiceWrapper) Do(ctx context.Context) (*es.AsyncSearchResult, error) {
+	body, err := s.source.Source()
+	if err != nil {
+		return nil, err
+	}
+	return performAsyncSearch(ctx, s.client, elastic.PerformRequestOptions{
+		Method: http.MethodPost,
+		Path:   "/" + strings.Join(s.indices, ",") + "/_async_search",
+		Params: s.params,
+		Body:   body,
+	})
+}
+
+// AsyncSearchGetServiceWrapper polls a search with the _async_search API.
+type AsyncSearchGetServiceWrapper struct {
+	client *elastic.Client
+	id     string
+	params url.Values
+}
+
+// WaitForCompletionTimeout sets how long the poll waits for the search to complete.
+func (s AsyncSearchGetServiceWrapper) WaitForCompletionTimeout(timeout time.Duration) es.AsyncSearchGetService {
+	s.params.Set("wait_for_completion_timeout", timeValue(timeout))
+	return s
+}
+
+// Do polls the search.
+func (s AsyncSearchGetServiceWrapper) Do(ctx context.Context) (*es.AsyncSearchResult, error) {
+	return performAsyncSearch(ctx, s.client, elastic.PerformRequestOptions{
+		Method: http.MethodGet,
+		Path:   "/_async_search/" + url.PathEscape(s.id),
+		Params: s.params,
+	})
+}
+
+// AsyncSearchDeleteServiceWrapper deletes a search with the _async_search API.
+type AsyncSearchDeleteServiceWrapper struct {
+	client *elastic.Client
+	id     string
+}
+
+// Do deletes the search, a search already expired is ignored.
+func (s AsyncSearchDeleteServiceWrapper) Do(ctx context.Context) error {
+	_, err := s.client.PerformRequest(ctx, elastic.PerformRequestOptions{
+		Method:       http.MethodDelete,
+		Path:         "/_async_search/" + url.PathEscape(s.id),
+		IgnoreErrors: []int{http.StatusNotFound},
+	})
+	return err
+}
+
+func performAsyncSearch(ctx context.Context, client *elastic.Client, options elastic.PerformRequestOptions) (*es.AsyncSearchResult, error) {
+	res, err := client.PerformRequest(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	ret := new(es.AsyncSearchResult)
+	if err := json.Unmarshal(res.Body, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// PointInTimeOpenServiceWrapper opens a point in time with the _pit API.
+type PointInTimeOpenServiceWrapper struct {
+	client  *elastic.Client
+	indices []string
+	params  url.Values
+}
+
+// IgnoreUnavailable ignores the missing or closed indices.
+func (s PointInTimeOpenServiceWrapper) IgnoreUnavailable(ignoreUnavailable bool) es.PointInTimeOpenService {
+	s.params.Set("ignore_unavailable", fmt.Sprint(ignoreUnavailable))
+	return s
+}
+
+// KeepAlive sets how long the point in time is kept until the next search.
+func (s PointInTimeOpenServiceWrapper) KeepAlive(keepAlive time.Duration) es.PointInTimeOpenService {
+	s.params.Set("keep_alive", timeValue(keepAlive))
+	return s
+}
+
+// Do opens the point in time and returns its ID.
+func (s PointInTimeOpenServiceWrapper) Do(ctx context.Context) (string, error) {
+	res, err := s.client.PerformRequest(ctx, elastic.PerformRequestOptions{
+		Method: http.MethodPost,
+		Path:   "/" + strings.Join(s.indices, ",") + "/_pit",
+		Params: s.params,
+	})
+	if err != nil {
+		return "", err
+	}
+	var ret struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(res.Body, &ret); err != nil {
+		return "", err
+	}
+	return ret.ID, nil
+}
+
+// PointInTimeSearchServiceWrapper searches a point in time with the _search API.
+type PointInTimeSearchServiceWrapper struct {
+	client    *elastic.Client
+	id        string
+	keepAlive time.Duration
+	source    *elastic.SearchSource
+}
+
+// Size sets the number of hits to return.
+func (s PointInTimeSearchServiceWrapper) Size(size int) es.PointInTimeSearchService {
+	s.source = s.source.Size(size)
+	return s
+}
+
+// Aggregation adds an aggregation to the search.
+func (s PointInTimeSearchServiceWrapper) Aggregation(name string, aggregation elastic.Aggregation) es.PointInTimeSearchService {
+	s.source = s.source.Aggregation(name, aggregation)
+	return s
+}
+
+// Query sets the query of the search.
+func (s PointInTimeSearchServiceWrapper) Query(query elastic.Query) es.PointInTimeSearchService {
+	s.source = s.source.Query(query)
+	return s
+}
+
+// Sort adds a sort order, the hits are then sorted by the shard and document of the point in time.
+func (s PointInTimeSearchServiceWrapper) Sort(field string, ascending bool) es.PointInTimeSearchService {
+	s.source = s.source.Sort(field, ascending)
+	return s
+}
+
+// SearchAfter sets the sort values of the hit after which the hits are returned.
+func (s PointInTimeSearchServiceWrapper) SearchAfter(sortValues ...interface{}) es.PointInTimeSearchService {
+	s.source = s.source.SearchAfter(sortValues...)
+	return s
+}
+
+// FetchSource restricts the source of the hits to the fields.
+func (s PointInTimeSearchServiceWrapper) FetchSource(fields ...string) es.PointInTimeSearchService {
+	s.source = s.source.FetchSourceContext(elastic.NewFetchSourceContext(true).Include(fields...))
+	return s
+}
+
+// KeepAlive extends how long the point in time is kept until the next search.
+func (s PointInTimeSearchServiceWrapper) KeepAlive(keepAlive time.Duration) es.PointInTimeSearchService {
+	s.keepAlive = keepAlive
+	return s
+}
+
+// Do runs the search.
+func (s PointInTimeSearchServiceWrapper) Do(ctx context.Context) (*es.PointInTimeSearchResult, error) {
+	src, err := s.source.TrackTotalHits(false).Source()
+	if err != nil {
+		return nil, err
+	}
+	body, ok := src.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected search source %T", src)
+	}
+	pit := map[string]interface{}{"id": s.id}
+	if s.keepAlive > 0 {
+		pit["keep_alive"] = timeValue(s.keepAlive)
+	}
+	body["pit"] = pit
+	res, err := s.client.PerformRequest(ctx, elastic.PerformRequestOptions{
+		Method: http.MethodPost,
+		Path:   "/_search",
+		Body:   body,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ret := &es.PointInTimeSearchResult{Response: new(elastic.SearchResult)}
+	if err := json.Unmarshal(res.Body, ret.Response); err != nil {
+		return nil, err
+	}
+	var id struct {
+		ID string `json:"pit_id"`
+	}
+	if err := json.Unmarshal(res.Body, &id); err != nil {
+		return nil, err
+	}
+	ret.ID = id.ID
+	return ret, nil
+}
+
+// PointInTimeCloseServiceWrapper closes a point in time with the _pit API.
+type PointInTimeCloseServiceWrapper struct {
+	client *elastic.Client
+	id     string
+}
+
+// Do closes the point in time, a point in time already expired is ignored.
+func (s PointInTimeCloseServiceWrapper) Do(ctx context.Context) error {
+	_, err := s.client.PerformRequest(ctx, elastic.PerformRequestOptions{
+		Method:       http.MethodDelete,
+		Path:         "/_pit",
+		Body:         map[string]string{"id": s.id},
+		IgnoreErrors: []int{http.StatusNotFound},
+	})
+	return err
+}
+
+// timeValue formats a duration as an Elasticsearch time value.
+func timeValue(d time.Duration) string {
+	return fmt.Sprintf("%dms", d.Milliseconds())
+}
+
 // ---
 
 // IndexServiceWrapper is a wrapper around elastic.ESIndexService.
@@ -167,12 +598,17 @@ func (i IndexServiceWrapper) Index(index string) es.IndexService {
 
 // Type calls this function to internal service.
 func (i IndexServiceWrapper) Type(typ string) es.IndexService {
-	if i.esVersion == 7 {
+	if i.esVersion >= 7 {
 		return WrapESIndexService(i.bulkIndexReq, i.bulkService, i.esVersion)
 	}
 	return WrapESIndexService(i.bulkIndexReq.Type(typ), i.bulkService, i.esVersion)
 }
 
+// OpType calls this function to internal service.
+func (i IndexServiceWrapper) OpType(opType string) es.IndexService {
+	return WrapESIndexService(i.bulkIndexReq.OpType(opType), i.bulkService, i.esVersion)
+}
+
 // Add adds the request to bulk service
 func (i IndexServiceWrapper) Add() {
 	i.bulkService.Add(i.bulkIndexReq)
@@ -0,0 +1,119 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrObjectNotFound is returned by Bucket.Get when the object does not exist.
+var ErrObjectNotFound = errors.New("object not found")
+
+// Bucket stores objects by key in an object storage, e.g. S3 or Google Cloud Storage.
+// The keys are slash separated paths.
+type Bucket interface {
+	// Put creates or replaces the object of the key.
+	Put(ctx context.Context, key string, data []byte) error
+	// Get returns the data of the object of the key, or ErrObjectNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// List returns the sorted keys of the objects starting with the prefix.
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// FileBucket stores the objects as the files of a local directory, for development and tests.
+type FileBucket struct {
+	dir string
+}
+
+// NewFileBucket creates a FileBucket storing the objects in the directory.
+func NewFileBucket(dir string) *FileBucket {
+	return &FileBucket{dir: dir}
+}
+
+func (b *FileBucket) path(key string) string {
+	return filepath.Join(b.dir, filepath.FromSlash(key))
+}
+
+// Put implements Bucket#Put, the file is renamed once written for the incomplete objects not to be read.
+func (b *FileBucket) Put(ctx context.Context, key string, data []byte) error {
+	path := b.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// Get implements Bucket#Get
+func (b *FileBucket) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := ioutil.ReadFile(b.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrObjectNotFound
+	}
+	return data, err
+}
+
+// List implements Bucket#List
+func (b *FileBucket) List(ctx context.Context, prefix string) ([]string, error) {
+	// only the directory of the prefix is walked
+	root := b.dir
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		root = b.path(prefix[:i])
+	}
+	var keys []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == root {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".tmp-") {
+			return nil
+		}
+		rel, err := filepath.Rel(b.dir, path)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objectstore
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileBucket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bucket")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	bucket := NewFileBucket(dir)
+	ctx := context.Background()
+
+	keys, err := bucket.List(ctx, "index/2020/")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	require.NoError(t, bucket.Put(ctx, "index/2020/01/b.json", []byte("b")))
+	require.NoError(t, bucket.Put(ctx, "index/2020/01/a.json", []byte("a")))
+	require.NoError(t, bucket.Put(ctx, "index/2020/02/c.json", []byte("c")))
+	require.NoError(t, bucket.Put(ctx, "index/2019/12/d.json", []byte("d")))
+	require.NoError(t, bucket.Put(ctx, "index/2020/01/a.json", []byte("replaced")))
+
+	data, err := bucket.Get(ctx, "index/2020/01/a.json")
+	require.NoError(t, err)
+	assert.Equal(t, "replaced", string(data))
+	_, err = bucket.Get(ctx, "index/2020/01/missing.json")
+	assert.Equal(t, ErrObjectNotFound, err)
+
+	keys, err = bucket.List(ctx, "index/2020/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"index/2020/01/a.json", "index/2020/01/b.json", "index/2020/02/c.json"}, keys)
+	keys, err = bucket.List(ctx, "index/2020/01/a")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"index/2020/01/a.json"}, keys)
+	keys, err = bucket.List(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, keys, 4)
+
+	// the temporary files are not listed
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "index", "2020", "01", ".tmp-1"), nil, 0644))
+	keys, err = bucket.List(ctx, "index/2020/01/")
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+}
+
+func TestFileBucketErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bucket")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "file")
+	require.NoError(t, ioutil.WriteFile(file, nil, 0644))
+
+	// the directory of the objects is a file
+	bucket := NewFileBucket(file)
+	assert.Error(t, bucket.Put(context.Background(), "a/b", nil))
+	_, err = bucket.Get(context.Background(), "a/b")
+	assert.Error(t, err)
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"google.golang.org/api/option"
+
+	"github.com/jaegertracing/jaeger/pkg/objectstore"
+)
+
+// Configuration describes the configuration properties needed to access a bucket of an object storage.
+type Configuration struct {
+	// URL is the URL of the bucket and of the prefix of the keys, e.g. s3://bucket/prefix, gs://bucket/prefix
+	// or file:///var/lib/jaeger for a local directory.
+	URL string `mapstructure:"url"`
+	// S3Region is the AWS region of the S3 bucket, the region of the environment of the AWS SDK if empty.
+	S3Region string `mapstructure:"s3_region"`
+	// S3Endpoint overrides the endpoint of S3, e.g. to use MinIO.
+	S3Endpoint string `mapstructure:"s3_endpoint"`
+	// GCSCredentialsFile is the JSON file of the credentials of a service account, the application default
+	// credentials are used if it is empty.
+	GCSCredentialsFile string `mapstructure:"gcs_credentials_file"`
+}
+
+// NewBucket creates the bucket of the URL.
+func (c *Configuration) NewBucket(ctx context.Context) (objectstore.Bucket, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL of the bucket: %w", err)
+	}
+	prefix := strings.TrimPrefix(u.Path, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	switch u.Scheme {
+	case "s3":
+		config := aws.NewConfig()
+		if c.S3Region != "" {
+			config = config.WithRegion(c.S3Region)
+		}
+		if c.S3Endpoint != "" {
+			// the S3 compatible storages rarely support the virtual hosted buckets
+			config = config.WithEndpoint(c.S3Endpoint).WithS3ForcePathStyle(true)
+		}
+		sess, err := session.NewSessionWithOptions(session.Options{
+			Config:            *config,
+			SharedConfigState: session.SharedConfigEnable,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return objectstore.NewS3Bucket(s3.New(sess), u.Host, prefix), nil
+	case "gs":
+		var opts []option.ClientOption
+		if c.GCSCredentialsFile != "" {
+			opts = append(opts, option.WithCredentialsFile(c.GCSCredentialsFile))
+		}
+		client, err := storage.NewClient(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return objectstore.NewGCSBucket(client, u.Host, prefix), nil
+	case "file":
+		return objectstore.NewFileBucket(u.Path), nil
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q of the URL of the bucket, the schemes are s3, gs and file", u.Scheme)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/pkg/objectstore"
+)
+
+func TestNewBucket(t *testing.T) {
+	testCases := []struct {
+		config   Configuration
+		expected objectstore.Bucket
+	}{
+		{
+			config:   Configuration{URL: "file:///var/lib/jaeger"},
+			expected: objectstore.NewFileBucket("/var/lib/jaeger"),
+		},
+	}
+	for _, testCase := range testCases {
+		bucket, err := testCase.config.NewBucket(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, testCase.expected, bucket)
+	}
+
+	for _, url := range []string{"s3://bucket", "s3://bucket/prefix", "s3://bucket/prefix/"} {
+		c := Configuration{URL: url, S3Region: "eu-west-1", S3Endpoint: "http://localhost:9000"}
+		bucket, err := c.NewBucket(context.Background())
+		require.NoError(t, err)
+		assert.IsType(t, &objectstore.S3Bucket{}, bucket)
+	}
+}
+
+func TestNewBucketErrors(t *testing.T) {
+	testCases := []struct {
+		config   Configuration
+		expected string
+	}{
+		{
+			config:   Configuration{URL: "ftp://host/path"},
+			expected: `unsupported scheme "ftp" of the URL of the bucket, the schemes are s3, gs and file`,
+		},
+		{
+			config:   Configuration{URL: "s3://%zz"},
+			expected: "invalid URL of the bucket",
+		},
+	}
+	for _, testCase := range testCases {
+		_, err := testCase.config.NewBucket(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), testCase.expected)
+	}
+
+	c := Configuration{URL: "gs://bucket/prefix", GCSCredentialsFile: "/does/not/exist.json"}
+	_, err := c.NewBucket(context.Background())
+	assert.Error(t, err)
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objectstore
+
+import (
+	"context"
+	"io/ioutil"
+	"sort"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+)
+
+// GCSBucket stores the objects in a Google Cloud Storage bucket, under a prefix of their keys.
+type GCSBucket struct {
+	bucket *storage.BucketHandle
+	prefix string
+}
+
+// NewGCSBucket creates a GCSBucket.
+func NewGCSBucket(client *storage.Client, bucket, prefix string) *GCSBucket {
+	return &GCSBucket{bucket: client.Bucket(bucket), prefix: prefix}
+}
+
+// Put implements Bucket#Put
+func (b *GCSBucket) Put(ctx context.Context, key string, data []byte) error {
+	writer := b.bucket.Object(b.prefix + key).NewWriter(ctx)
+	if _, err := writer.Write(data); err != nil {
+		writer.Close()
+		return err
+	}
+	return writer.Close()
+}
+
+// Get implements Bucket#Get
+func (b *GCSBucket) Get(ctx context.Context, key string) ([]byte, error) {
+	reader, err := b.bucket.Object(b.prefix + key).NewReader(ctx)
+	if err == storage.ErrObjectNotExist {
+		return nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
+// List implements Bucket#List
+func (b *GCSBucket) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	objects := b.bucket.Objects(ctx, &storage.Query{Prefix: b.prefix + prefix})
+	for {
+		object, err := objects.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, object.Name[len(b.prefix):])
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// S3Bucket stores the objects in an S3 bucket, under a prefix of their keys.
+type S3Bucket struct {
+	client s3iface.S3API
+	bucket string
+	prefix string
+}
+
+// NewS3Bucket creates an S3Bucket.
+func NewS3Bucket(client s3iface.S3API, bucket, prefix string) *S3Bucket {
+	return &S3Bucket{client: client, bucket: bucket, prefix: prefix}
+}
+
+// Put implements Bucket#Put
+func (b *S3Bucket) Put(ctx context.Context, key string, data []byte) error {
+	_, err := b.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.prefix + key),
+		Body:   bytes.NewReader(data),
+	})
+	return err
+}
+
+// Get implements Bucket#Get
+func (b *S3Bucket) Get(ctx context.Context, key string) ([]byte, error) {
+	output, err := b.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.prefix + key),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+		return nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer output.Body.Close()
+	return ioutil.ReadAll(output.Body)
+}
+
+// List implements Bucket#List
+func (b *S3Bucket) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	err := b.client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(b.bucket),
+		Prefix: aws.String(b.prefix + prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			keys = append(keys, aws.StringValue(object.Key)[len(b.prefix):])
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	// S3 lists the keys in order
+	return keys, nil
+}
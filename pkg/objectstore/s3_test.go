@@ -0,0 +1,104 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objectstore
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeS3 struct {
+	s3iface.S3API
+	objects map[string][]byte
+	err     error
+}
+
+func (s *fakeS3) PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	data, err := ioutil.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	s.objects[*input.Bucket+"/"+*input.Key] = data
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (s *fakeS3) GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	data, ok := s.objects[*input.Bucket+"/"+*input.Key]
+	if !ok {
+		return nil, awserr.New(s3.ErrCodeNoSuchKey, "no such key", nil)
+	}
+	return &s3.GetObjectOutput{Body: ioutil.NopCloser(strings.NewReader(string(data)))}, nil
+}
+
+func (s *fakeS3) ListObjectsV2PagesWithContext(ctx aws.Context, input *s3.ListObjectsV2Input, fn func(*s3.ListObjectsV2Output, bool) bool, opts ...request.Option) error {
+	if s.err != nil {
+		return s.err
+	}
+	// a page per object, in order
+	for _, key := range []string{"prefix/a/1", "prefix/a/2", "prefix/b/1"} {
+		if _, ok := s.objects[*input.Bucket+"/"+key]; ok && strings.HasPrefix(key, *input.Prefix) {
+			if !fn(&s3.ListObjectsV2Output{Contents: []*s3.Object{{Key: aws.String(key)}}}, false) {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func TestS3Bucket(t *testing.T) {
+	client := &fakeS3{objects: make(map[string][]byte)}
+	bucket := NewS3Bucket(client, "bucket", "prefix/")
+	ctx := context.Background()
+	for _, key := range []string{"a/1", "a/2", "b/1"} {
+		require.NoError(t, bucket.Put(ctx, key, []byte(key)))
+	}
+	assert.Equal(t, []byte("a/2"), client.objects["bucket/prefix/a/2"])
+
+	data, err := bucket.Get(ctx, "b/1")
+	require.NoError(t, err)
+	assert.Equal(t, "b/1", string(data))
+	_, err = bucket.Get(ctx, "c/1")
+	assert.Equal(t, ErrObjectNotFound, err)
+
+	keys, err := bucket.List(ctx, "a/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/1", "a/2"}, keys)
+}
+
+func TestS3BucketErrors(t *testing.T) {
+	bucket := NewS3Bucket(&fakeS3{err: assert.AnError}, "bucket", "")
+	ctx := context.Background()
+	assert.Equal(t, assert.AnError, bucket.Put(ctx, "a", nil))
+	_, err := bucket.Get(ctx, "a")
+	assert.Equal(t, assert.AnError, err)
+	_, err = bucket.List(ctx, "")
+	assert.Equal(t, assert.AnError, err)
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	ui "github.com/jaegertracing/jaeger/model/json"
+)
+
+// criticalPath returns the critical path of the trace with the contribution of each span,
+// the spans sorted by decreasing contribution.
+func criticalPath(trace *model.Trace) *ui.CriticalPath {
+	path := &ui.CriticalPath{
+		TraceID:  traceIDOf(trace),
+		Segments: []ui.CriticalPathSegment{},
+		Spans:    []ui.CriticalPathSpan{},
+	}
+	var total time.Duration
+	contributions := make(map[*model.Span]time.Duration)
+	var order []*model.Span
+	for _, segment := range trace.CriticalPath() {
+		path.Segments = append(path.Segments, ui.CriticalPathSegment{
+			SpanID:    ui.SpanID(segment.Span.SpanID.String()),
+			StartTime: model.TimeAsEpochMicroseconds(segment.Start),
+			Duration:  model.DurationAsMicroseconds(segment.Duration),
+		})
+		if _, ok := contributions[segment.Span]; !ok {
+			order = append(order, segment.Span)
+		}
+		contributions[segment.Span] += segment.Duration
+		total += segment.Duration
+	}
+	path.Duration = model.DurationAsMicroseconds(total)
+	for _, span := range order {
+		ref := ui.OperationRef{OperationName: span.OperationName}
+		if span.Process != nil {
+			ref.ServiceName = span.Process.ServiceName
+		}
+		contribution := ui.CriticalPathSpan{
+			SpanID:       ui.SpanID(span.SpanID.String()),
+			OperationRef: ref,
+			Duration:     model.DurationAsMicroseconds(contributions[span]),
+		}
+		if total > 0 {
+			contribution.Percentage = 100 * float64(contributions[span]) / float64(total)
+		}
+		path.Spans = append(path.Spans, contribution)
+	}
+	sort.SliceStable(path.Spans, func(i, j int) bool { return path.Spans[i].Duration > path.Spans[j].Duration })
+	return path
+}
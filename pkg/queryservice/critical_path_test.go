@@ -0,0 +1,117 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/model/adjuster"
+	ui "github.com/jaegertracing/jaeger/model/json"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+func criticalPathTrace() *model.Trace {
+	traceID := model.NewTraceID(0, 0xa)
+	return &model.Trace{Spans: []*model.Span{
+		diffSpan(traceID, 1, 0, "frontend", "GET /", 0, 100*time.Millisecond),
+		diffSpan(traceID, 2, 1, "redis", "GET", 10*time.Millisecond, 30*time.Millisecond),
+		diffSpan(traceID, 3, 1, "mysql", "SELECT", 50*time.Millisecond, 10*time.Millisecond),
+	}}
+}
+
+func TestCriticalPath(t *testing.T) {
+	start := model.TimeAsEpochMicroseconds(diffStartTime)
+	segment := func(spanID ui.SpanID, offset, duration uint64) ui.CriticalPathSegment {
+		return ui.CriticalPathSegment{SpanID: spanID, StartTime: start + offset, Duration: duration}
+	}
+	expected := &ui.CriticalPath{
+		TraceID:  "000000000000000a",
+		Duration: 100000,
+		Segments: []ui.CriticalPathSegment{
+			segment("0000000000000001", 0, 10000),
+			segment("0000000000000002", 10000, 30000),
+			segment("0000000000000001", 40000, 10000),
+			segment("0000000000000003", 50000, 10000),
+			segment("0000000000000001", 60000, 40000),
+		},
+		Spans: []ui.CriticalPathSpan{
+			{SpanID: "0000000000000001", OperationRef: ui.OperationRef{ServiceName: "frontend", OperationName: "GET /"}, Duration: 60000, Percentage: 60},
+			{SpanID: "0000000000000002", OperationRef: ui.OperationRef{ServiceName: "redis", OperationName: "GET"}, Duration: 30000, Percentage: 30},
+			{SpanID: "0000000000000003", OperationRef: ui.OperationRef{ServiceName: "mysql", OperationName: "SELECT"}, Duration: 10000, Percentage: 10},
+		},
+	}
+	assert.Equal(t, expected, criticalPath(criticalPathTrace()))
+}
+
+func TestCriticalPathEmptyTrace(t *testing.T) {
+	expected := &ui.CriticalPath{Segments: []ui.CriticalPathSegment{}, Spans: []ui.CriticalPathSpan{}}
+	assert.Equal(t, expected, criticalPath(&model.Trace{}))
+}
+
+func TestCriticalPathHandler(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), model.NewTraceID(0, 0xa)).
+			Return(criticalPathTrace(), nil).Once()
+
+		var response structuredResponse
+		require.NoError(t, getJSON(ts.server.URL+"/api/traces/a/critical-path", &response))
+		data := response.Data.(map[string]interface{})
+		assert.Equal(t, "000000000000000a", data["traceID"])
+		assert.Len(t, data["segments"], 5)
+		assert.Len(t, data["spans"], 3)
+		assert.Empty(t, response.Errors)
+	}, querysvc.QueryServiceOptions{})
+}
+
+func TestCriticalPathHandlerErrors(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), model.NewTraceID(0, 0xb)).
+			Return(nil, spanstore.ErrTraceNotFound).Once()
+		ts.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), model.NewTraceID(0, 0xc)).
+			Return(nil, errStorage).Once()
+
+		var response structuredResponse
+		err := getJSON(ts.server.URL+"/api/traces/b/critical-path", &response)
+		assert.EqualError(t, err, parsedError(404, "trace not found"))
+		err = getJSON(ts.server.URL+"/api/traces/c/critical-path", &response)
+		assert.EqualError(t, err, parsedError(500, errStorageMsg))
+		err = getJSON(ts.server.URL+"/api/traces/x/critical-path", &response)
+		assert.EqualError(t, err, parsedError(400, `strconv.ParseUint: parsing \"x\": invalid syntax`))
+	}, querysvc.QueryServiceOptions{})
+}
+
+func TestCriticalPathHandlerAdjustmentFailure(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("model.TraceID")).
+			Return(criticalPathTrace(), nil)
+
+		var response structuredResponse
+		require.NoError(t, getJSON(ts.server.URL+"/api/traces/a/critical-path", &response))
+		require.Len(t, response.Errors, 1)
+		assert.Equal(t, errAdjustment.Error(), response.Errors[0].Msg)
+
+		require.NoError(t, getJSON(ts.server.URL+"/api/traces/a/critical-path?raw=true", &response))
+		assert.Empty(t, response.Errors)
+	}, querysvc.QueryServiceOptions{Adjuster: adjuster.Func(func(trace *model.Trace) (*model.Trace, error) {
+		return trace, errAdjustment
+	})})
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	ui "github.com/jaegertracing/jaeger/model/json"
+)
+
+const (
+	directionUpstream   = "upstream"
+	directionDownstream = "downstream"
+	directionBoth       = "both"
+)
+
+// parseDirection validates the direction of a focal graph, both by default.
+func parseDirection(direction string) (string, error) {
+	switch direction {
+	case "":
+		return directionBoth, nil
+	case directionUpstream, directionDownstream, directionBoth:
+		return direction, nil
+	default:
+		return "", fmt.Errorf("unable to parse %s: expecting %q, %q or %q", directionParam, directionUpstream, directionDownstream, directionBoth)
+	}
+}
+
+// dependencyGraph indexes the deduplicated dependency links by caller and by callee.
+type dependencyGraph struct {
+	children map[string][]ui.DependencyLink
+	parents  map[string][]ui.DependencyLink
+}
+
+func newDependencyGraph(links []ui.DependencyLink) *dependencyGraph {
+	sorted := make([]ui.DependencyLink, len(links))
+	copy(sorted, links)
+	sortDependencyLinks(sorted)
+	g := &dependencyGraph{
+		children: make(map[string][]ui.DependencyLink),
+		parents:  make(map[string][]ui.DependencyLink),
+	}
+	for _, link := range sorted {
+		g.children[link.Parent] = append(g.children[link.Parent], link)
+		g.parents[link.Child] = append(g.parents[link.Child], link)
+	}
+	return g
+}
+
+// focal returns the links found within depth calls of the service, following the calls it makes
+// when looking downstream and the calls made to it when looking upstream.
+func (g *dependencyGraph) focal(service string, depth int, direction string) []ui.DependencyLink {
+	found := make(map[ui.DependencyLink]bool)
+	if direction != directionUpstream {
+		walkDependencies(service, depth, g.children, func(link ui.DependencyLink) string { return link.Child }, found)
+	}
+	if direction != directionDownstream {
+		walkDependencies(service, depth, g.parents, func(link ui.DependencyLink) string { return link.Parent }, found)
+	}
+	links := make([]ui.DependencyLink, 0, len(found))
+	for link := range found {
+		links = append(links, link)
+	}
+	sortDependencyLinks(links)
+	return links
+}
+
+// walkDependencies visits the services breadth first, adding the links it follows to found.
+func walkDependencies(
+	service string,
+	depth int,
+	edges map[string][]ui.DependencyLink,
+	next func(ui.DependencyLink) string,
+	found map[ui.DependencyLink]bool,
+) {
+	visited := map[string]bool{service: true}
+	frontier := []string{service}
+	for i := 0; i < depth && len(frontier) > 0; i++ {
+		var nextFrontier []string
+		for _, s := range frontier {
+			for _, link := range edges[s] {
+				found[link] = true
+				if n := next(link); !visited[n] {
+					visited[n] = true
+					nextFrontier = append(nextFrontier, n)
+				}
+			}
+		}
+		frontier = nextFrontier
+	}
+}
+
+// paths enumerates the chains of calls from a service to another, without going through
+// a service twice, of at most maxDepth calls. At most limit paths are returned, the shortest first.
+func (g *dependencyGraph) paths(from, to string, maxDepth, limit int) []ui.DependencyPath {
+	paths := []ui.DependencyPath{}
+	visited := map[string]bool{from: true}
+	var links []ui.DependencyLink
+	var walk func(service string)
+	walk = func(service string) {
+		for _, link := range g.children[service] {
+			if len(paths) >= limit {
+				return
+			}
+			if visited[link.Child] {
+				continue
+			}
+			links = append(links, link)
+			if link.Child == to {
+				paths = append(paths, newDependencyPath(links))
+			} else if len(links) < maxDepth {
+				visited[link.Child] = true
+				walk(link.Child)
+				visited[link.Child] = false
+			}
+			links = links[:len(links)-1]
+		}
+	}
+	walk(from)
+	sort.SliceStable(paths, func(i, j int) bool {
+		if len(paths[i].Links) != len(paths[j].Links) {
+			return len(paths[i].Links) < len(paths[j].Links)
+		}
+		return strings.Join(paths[i].Services, "\x00") < strings.Join(paths[j].Services, "\x00")
+	})
+	return paths
+}
+
+func newDependencyPath(links []ui.DependencyLink) ui.DependencyPath {
+	path := ui.DependencyPath{
+		Services:  []string{links[0].Parent},
+		Links:     make([]ui.DependencyLink, len(links)),
+		CallCount: links[0].CallCount,
+	}
+	copy(path.Links, links)
+	for _, link := range links {
+		path.Services = append(path.Services, link.Child)
+		if link.CallCount < path.CallCount {
+			path.CallCount = link.CallCount
+		}
+	}
+	return path
+}
+
+func sortDependencyLinks(links []ui.DependencyLink) {
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].Parent != links[j].Parent {
+			return links[i].Parent < links[j].Parent
+		}
+		return links[i].Child < links[j].Child
+	})
+}
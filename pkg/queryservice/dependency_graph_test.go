@@ -0,0 +1,110 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	ui "github.com/jaegertracing/jaeger/model/json"
+)
+
+// frontend -> api -> {auth, orders}, orders -> {mysql, auth}, batch -> orders
+var graphLinks = []ui.DependencyLink{
+	{Parent: "frontend", Child: "api", CallCount: 100},
+	{Parent: "api", Child: "auth", CallCount: 80},
+	{Parent: "api", Child: "orders", CallCount: 60},
+	{Parent: "orders", Child: "mysql", CallCount: 50},
+	{Parent: "orders", Child: "auth", CallCount: 10},
+	{Parent: "batch", Child: "orders", CallCount: 5},
+}
+
+func TestDependencyGraphFocal(t *testing.T) {
+	g := newDependencyGraph(graphLinks)
+	testCases := []struct {
+		service   string
+		depth     int
+		direction string
+		expected  []ui.DependencyLink
+	}{
+		{
+			service:   "api",
+			depth:     1,
+			direction: directionBoth,
+			expected:  []ui.DependencyLink{graphLinks[1], graphLinks[2], graphLinks[0]},
+		},
+		{
+			service:   "api",
+			depth:     2,
+			direction: directionDownstream,
+			expected:  []ui.DependencyLink{graphLinks[1], graphLinks[2], graphLinks[4], graphLinks[3]},
+		},
+		{
+			service:   "auth",
+			depth:     2,
+			direction: directionUpstream,
+			expected:  []ui.DependencyLink{graphLinks[1], graphLinks[2], graphLinks[5], graphLinks[0], graphLinks[4]},
+		},
+		{
+			service:   "mysql",
+			depth:     1,
+			direction: directionDownstream,
+			expected:  []ui.DependencyLink{},
+		},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, g.focal(tc.service, tc.depth, tc.direction), "%s %d %s", tc.service, tc.depth, tc.direction)
+	}
+}
+
+func TestDependencyGraphFocalCycle(t *testing.T) {
+	g := newDependencyGraph([]ui.DependencyLink{
+		{Parent: "a", Child: "b", CallCount: 1},
+		{Parent: "b", Child: "a", CallCount: 1},
+	})
+	assert.Len(t, g.focal("a", 10, directionBoth), 2)
+}
+
+func TestDependencyGraphPaths(t *testing.T) {
+	g := newDependencyGraph(graphLinks)
+	assert.Equal(t, []ui.DependencyPath{
+		{
+			Services:  []string{"frontend", "api", "auth"},
+			Links:     []ui.DependencyLink{graphLinks[0], graphLinks[1]},
+			CallCount: 80,
+		},
+		{
+			Services:  []string{"frontend", "api", "orders", "auth"},
+			Links:     []ui.DependencyLink{graphLinks[0], graphLinks[2], graphLinks[4]},
+			CallCount: 10,
+		},
+	}, g.paths("frontend", "auth", 5, 100))
+
+	assert.Len(t, g.paths("frontend", "auth", 2, 100), 1)
+	assert.Len(t, g.paths("frontend", "auth", 5, 1), 1)
+	assert.Equal(t, []ui.DependencyPath{}, g.paths("mysql", "frontend", 5, 100))
+}
+
+func TestDependencyGraphPathsCycle(t *testing.T) {
+	g := newDependencyGraph([]ui.DependencyLink{
+		{Parent: "a", Child: "b", CallCount: 1},
+		{Parent: "b", Child: "a", CallCount: 1},
+		{Parent: "b", Child: "c", CallCount: 1},
+	})
+	paths := g.paths("a", "c", 10, 100)
+	assert.Len(t, paths, 1)
+	assert.Equal(t, []string{"a", "b", "c"}, paths[0].Services)
+}
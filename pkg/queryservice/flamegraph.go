@@ -0,0 +1,135 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	ui "github.com/jaegertracing/jaeger/model/json"
+)
+
+// flameGraphNode accumulates the spans found at a path of operations in the traces.
+type flameGraphNode struct {
+	ref        ui.OperationRef
+	count      int
+	traces     map[model.TraceID]bool
+	totalTime  time.Duration
+	selfTime   time.Duration
+	children   map[ui.OperationRef]*flameGraphNode
+	childOrder []ui.OperationRef
+}
+
+func newFlameGraphNode(ref ui.OperationRef) *flameGraphNode {
+	return &flameGraphNode{
+		ref:      ref,
+		traces:   make(map[model.TraceID]bool),
+		children: make(map[ui.OperationRef]*flameGraphNode),
+	}
+}
+
+func (n *flameGraphNode) child(ref ui.OperationRef) *flameGraphNode {
+	c, ok := n.children[ref]
+	if !ok {
+		c = newFlameGraphNode(ref)
+		n.children[ref] = c
+		n.childOrder = append(n.childOrder, ref)
+	}
+	return c
+}
+
+func (n *flameGraphNode) toUI() *ui.FlameGraphNode {
+	return &ui.FlameGraphNode{
+		OperationRef: n.ref,
+		Count:        n.count,
+		TraceCount:   len(n.traces),
+		TotalTime:    model.DurationAsMicroseconds(n.totalTime),
+		SelfTime:     model.DurationAsMicroseconds(n.selfTime),
+		Children:     n.childrenToUI(),
+	}
+}
+
+func (n *flameGraphNode) childrenToUI() []*ui.FlameGraphNode {
+	children := make([]*ui.FlameGraphNode, 0, len(n.childOrder))
+	for _, ref := range n.childOrder {
+		children = append(children, n.children[ref].toUI())
+	}
+	sort.SliceStable(children, func(i, j int) bool { return children[i].TotalTime > children[j].TotalTime })
+	return children
+}
+
+// aggregateTraces merges the call trees of the traces into a flame graph, starting at their root spans.
+func aggregateTraces(traces []*model.Trace) *ui.FlameGraph {
+	root := newFlameGraphNode(ui.OperationRef{})
+	graph := &ui.FlameGraph{TraceCount: len(traces)}
+	var totalTime time.Duration
+	for _, trace := range traces {
+		parents := make(map[*model.Span]*flameGraphNode)
+		walkTrace(trace, func(span *model.Span, children []*model.Span, path []ui.OperationRef) {
+			parent, ok := parents[span]
+			if !ok {
+				parent = root
+			}
+			node := parent.child(path[len(path)-1])
+			node.count++
+			node.traces[span.TraceID] = true
+			node.totalTime += span.Duration
+			node.selfTime += selfTime(span, children)
+			for _, child := range children {
+				parents[child] = node
+			}
+		})
+		totalTime += traceDuration(trace)
+		graph.SpanCount += len(trace.Spans)
+	}
+	graph.TotalTime = model.DurationAsMicroseconds(totalTime)
+	graph.Roots = root.childrenToUI()
+	return graph
+}
+
+// selfTime returns the time of the span not covered by any of its children, so that concurrent children
+// are not counted twice.
+func selfTime(span *model.Span, children []*model.Span) time.Duration {
+	start, end := span.StartTime, span.StartTime.Add(span.Duration)
+	type interval struct{ start, end time.Time }
+	intervals := make([]interval, 0, len(children))
+	for _, child := range children {
+		childStart, childEnd := child.StartTime, child.StartTime.Add(child.Duration)
+		if childStart.Before(start) {
+			childStart = start
+		}
+		if childEnd.After(end) {
+			childEnd = end
+		}
+		if childEnd.After(childStart) {
+			intervals = append(intervals, interval{childStart, childEnd})
+		}
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].start.Before(intervals[j].start) })
+	covered := time.Duration(0)
+	var coveredEnd time.Time
+	for _, iv := range intervals {
+		if iv.start.Before(coveredEnd) {
+			if !iv.end.After(coveredEnd) {
+				continue
+			}
+			iv.start = coveredEnd
+		}
+		covered += iv.end.Sub(iv.start)
+		coveredEnd = iv.end
+	}
+	return span.Duration - covered
+}
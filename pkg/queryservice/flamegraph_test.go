@@ -0,0 +1,151 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/model/adjuster"
+	ui "github.com/jaegertracing/jaeger/model/json"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+func flameGraphTraces() []*model.Trace {
+	idA, idB := model.NewTraceID(0, 0xa), model.NewTraceID(0, 0xb)
+	return []*model.Trace{
+		{Spans: []*model.Span{
+			diffSpan(idA, 1, 0, "frontend", "GET /", 0, 100*time.Millisecond),
+			diffSpan(idA, 2, 1, "mysql", "SELECT", 10*time.Millisecond, 30*time.Millisecond),
+			diffSpan(idA, 3, 1, "redis", "GET", 20*time.Millisecond, 30*time.Millisecond),
+			diffSpan(idA, 4, 1, "redis", "GET", 90*time.Millisecond, 30*time.Millisecond),
+		}},
+		{Spans: []*model.Span{
+			diffSpan(idB, 1, 0, "frontend", "GET /", 0, 50*time.Millisecond),
+			diffSpan(idB, 2, 1, "redis", "GET", 0, 10*time.Millisecond),
+			diffSpan(idB, 3, 0, "cron", "job", 0, 20*time.Millisecond),
+		}},
+	}
+}
+
+func TestAggregateTraces(t *testing.T) {
+	leaf := func(service, operation string, count, traceCount int, total uint64) *ui.FlameGraphNode {
+		return &ui.FlameGraphNode{
+			OperationRef: ui.OperationRef{ServiceName: service, OperationName: operation},
+			Count:        count,
+			TraceCount:   traceCount,
+			TotalTime:    total,
+			SelfTime:     total,
+			Children:     []*ui.FlameGraphNode{},
+		}
+	}
+	expected := &ui.FlameGraph{
+		TraceCount: 2,
+		SpanCount:  7,
+		// the first trace ends with the child ending after its parent
+		TotalTime: 170000,
+		Roots: []*ui.FlameGraphNode{
+			{
+				OperationRef: ui.OperationRef{ServiceName: "frontend", OperationName: "GET /"},
+				Count:        2,
+				TraceCount:   2,
+				TotalTime:    150000,
+				// the overlapping children cover 40ms and the one ending after its parent 10ms of the first trace
+				SelfTime: 90000,
+				Children: []*ui.FlameGraphNode{
+					leaf("redis", "GET", 3, 2, 70000),
+					leaf("mysql", "SELECT", 1, 1, 30000),
+				},
+			},
+			leaf("cron", "job", 1, 1, 20000),
+		},
+	}
+	assert.Equal(t, expected, aggregateTraces(flameGraphTraces()))
+}
+
+func TestAggregateNoTraces(t *testing.T) {
+	assert.Equal(t, &ui.FlameGraph{Roots: []*ui.FlameGraphNode{}}, aggregateTraces(nil))
+}
+
+func TestFlameGraphHandler(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("FindTraces", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+			Return(flameGraphTraces(), nil).Once()
+
+		var response structuredResponse
+		require.NoError(t, getJSON(ts.server.URL+"/api/flamegraph?service=frontend&operation=GET+/", &response))
+		data, err := json.Marshal(response.Data)
+		require.NoError(t, err)
+		var graph ui.FlameGraph
+		require.NoError(t, json.Unmarshal(data, &graph))
+		assert.Equal(t, 2, graph.TraceCount)
+		require.Len(t, graph.Roots, 2)
+		assert.Equal(t, "frontend", graph.Roots[0].ServiceName)
+		assert.Equal(t, uint64(150000), graph.Roots[0].TotalTime)
+		assert.Empty(t, response.Errors)
+	}, querysvc.QueryServiceOptions{})
+}
+
+func TestFlameGraphHandlerByTraceIDs(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), model.NewTraceID(0, 0xa)).
+			Return(flameGraphTraces()[0], nil).Once()
+		ts.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), model.NewTraceID(0, 0xc)).
+			Return(nil, spanstore.ErrTraceNotFound).Once()
+
+		var response structuredResponse
+		require.NoError(t, getJSON(ts.server.URL+"/api/flamegraph?traceID=a&traceID=c", &response))
+		assert.Equal(t, float64(1), response.Data.(map[string]interface{})["traceCount"])
+		require.Len(t, response.Errors, 1)
+		assert.Equal(t, ui.TraceID("000000000000000c"), response.Errors[0].TraceID)
+	}, querysvc.QueryServiceOptions{})
+}
+
+func TestFlameGraphHandlerErrors(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("FindTraces", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+			Return(nil, errStorage).Once()
+
+		var response structuredResponse
+		err := getJSON(ts.server.URL+"/api/flamegraph?service=frontend", &response)
+		assert.EqualError(t, err, parsedError(500, errStorageMsg))
+		err = getJSON(ts.server.URL+"/api/flamegraph", &response)
+		assert.EqualError(t, err, parsedError(400, "parameter 'service' is required"))
+	}, querysvc.QueryServiceOptions{})
+}
+
+func TestFlameGraphHandlerAdjustmentFailure(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("FindTraces", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+			Return(flameGraphTraces(), nil).Once()
+
+		var response structuredResponse
+		require.NoError(t, getJSON(ts.server.URL+"/api/flamegraph?service=frontend", &response))
+		require.Len(t, response.Errors, 2)
+		assert.Equal(t, errAdjustment.Error(), response.Errors[0].Msg)
+		assert.Equal(t, ui.TraceID("000000000000000a"), response.Errors[0].TraceID)
+	}, querysvc.QueryServiceOptions{
+		Adjuster: adjuster.Func(func(trace *model.Trace) (*model.Trace, error) {
+			return trace, errAdjustment
+		}),
+	})
+}
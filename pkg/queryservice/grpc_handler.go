@@ -12,18 +12,22 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package app
+package queryservice
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
 
 	"github.com/opentracing/opentracing-go"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
-	"github.com/jaegertracing/jaeger/cmd/query/app/querysvc"
 	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
 	"github.com/jaegertracing/jaeger/proto-gen/api_v2"
 	"github.com/jaegertracing/jaeger/storage/spanstore"
 )
@@ -32,6 +36,23 @@ const (
 	maxSpanCountInChunk = 10
 
 	msgTraceNotFound = "trace not found"
+
+	// pageTokenMetadata is the request metadata with the token of the requested page of FindTraces,
+	// as the messages of the API have no field for it
+	pageTokenMetadata = "page-token"
+	// nextPageTokenMetadata is the response header with the token of the next page of FindTraces
+	nextPageTokenMetadata = "next-page-token"
+	// tagFilterMetadata are the request metadata with the tag filters of FindTraces, like the tagFilter
+	// parameters of the HTTP API
+	tagFilterMetadata = "tag-filter"
+	// adjustMetadata is the request metadata telling GetTrace to apply the adjusters to the trace,
+	// as the HTTP API does unless the raw trace is requested
+	adjustMetadata = "adjust"
+	// the request metadata of GetDependencies returning the links within depth calls upstream
+	// and/or downstream of the service, like the parameters of the HTTP API
+	serviceMetadata   = "service"
+	depthMetadata     = "depth"
+	directionMetadata = "direction"
 )
 
 // GRPCHandler implements the gRPC endpoint of the query service.
@@ -52,9 +73,16 @@ func NewGRPCHandler(queryService *querysvc.QueryService, logger *zap.Logger, tra
 	return gH
 }
 
-// GetTrace is the gRPC handler to fetch traces based on trace-id.
+// GetTrace is the gRPC handler to fetch traces based on trace-id,
+// the trace is adjusted when the request metadata ask for it.
 func (g *GRPCHandler) GetTrace(r *api_v2.GetTraceRequest, stream api_v2.QueryService_GetTraceServer) error {
 	trace, err := g.queryService.GetTrace(stream.Context(), r.TraceID)
+	if errors.Is(err, querysvc.ErrForbidden) {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	if err := limitStatus(err); err != nil {
+		return err
+	}
 	if err == spanstore.ErrTraceNotFound {
 		g.logger.Error(msgTraceNotFound, zap.Error(err))
 		return status.Errorf(codes.NotFound, "%s: %v", msgTraceNotFound, err)
@@ -63,12 +91,22 @@ func (g *GRPCHandler) GetTrace(r *api_v2.GetTraceRequest, stream api_v2.QuerySer
 		g.logger.Error("failed to fetch spans from the backend", zap.Error(err))
 		return status.Errorf(codes.Internal, "failed to fetch spans from the backend: %v", err)
 	}
+	if adjust, _ := strconv.ParseBool(metadataValue(stream.Context(), adjustMetadata)); adjust {
+		// the adjusters return the trace along with their errors, which only the HTTP API can report
+		trace, err = g.queryService.Adjust(trace)
+		if err != nil {
+			g.logger.Warn("failed to adjust the trace", zap.Error(err))
+		}
+	}
 	return g.sendSpanChunks(trace.Spans, stream.Send)
 }
 
 // ArchiveTrace is the gRPC handler to archive traces.
 func (g *GRPCHandler) ArchiveTrace(ctx context.Context, r *api_v2.ArchiveTraceRequest) (*api_v2.ArchiveTraceResponse, error) {
 	err := g.queryService.ArchiveTrace(ctx, r.TraceID)
+	if errors.Is(err, querysvc.ErrForbidden) {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
 	if err == spanstore.ErrTraceNotFound {
 		g.logger.Error("trace not found", zap.Error(err))
 		return nil, status.Errorf(codes.NotFound, "%s: %v", msgTraceNotFound, err)
@@ -81,7 +119,8 @@ func (g *GRPCHandler) ArchiveTrace(ctx context.Context, r *api_v2.ArchiveTraceRe
 	return &api_v2.ArchiveTraceResponse{}, nil
 }
 
-// FindTraces is the gRPC handler to fetch traces based on TraceQueryParameters.
+// FindTraces is the gRPC handler to fetch traces based on TraceQueryParameters,
+// and on the tag filters and the page token of the request metadata.
 func (g *GRPCHandler) FindTraces(r *api_v2.FindTracesRequest, stream api_v2.QueryService_FindTracesServer) error {
 	query := r.GetQuery()
 	queryParams := spanstore.TraceQueryParameters{
@@ -94,11 +133,33 @@ func (g *GRPCHandler) FindTraces(r *api_v2.FindTracesRequest, stream api_v2.Quer
 		DurationMax:   query.DurationMax,
 		NumTraces:     int(query.SearchDepth),
 	}
-	traces, err := g.queryService.FindTraces(stream.Context(), &queryParams)
+	for _, expr := range metadataValues(stream.Context(), tagFilterMetadata) {
+		tagFilter, err := spanstore.ParseTagFilter(expr)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid %s metadata: %v", tagFilterMetadata, err)
+		}
+		queryParams.TagFilters = append(queryParams.TagFilters, tagFilter)
+	}
+	token := metadataValue(stream.Context(), pageTokenMetadata)
+	traces, nextPageToken, err := g.queryService.FindTracesPage(stream.Context(), &queryParams, token)
+	if errors.Is(err, querysvc.ErrForbidden) {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	if err == querysvc.ErrInvalidPageToken {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := limitStatus(err); err != nil {
+		return err
+	}
 	if err != nil {
 		g.logger.Error("failed when searching for traces", zap.Error(err))
 		return status.Errorf(codes.Internal, "failed when searching for traces: %v", err)
 	}
+	if nextPageToken != "" {
+		if err := stream.SetHeader(metadata.Pairs(nextPageTokenMetadata, nextPageToken)); err != nil {
+			return err
+		}
+	}
 	for _, trace := range traces {
 		if err := g.sendSpanChunks(trace.Spans, stream.Send); err != nil {
 			return err
@@ -142,6 +203,9 @@ func (g *GRPCHandler) GetOperations(
 		ServiceName: r.Service,
 		SpanKind:    r.SpanKind,
 	})
+	if errors.Is(err, querysvc.ErrForbidden) {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
 	if err != nil {
 		g.logger.Error("failed to fetch operations", zap.Error(err))
 		return nil, status.Errorf(codes.Internal, "failed to fetch operations: %v", err)
@@ -161,15 +225,98 @@ func (g *GRPCHandler) GetOperations(
 	}, nil
 }
 
-// GetDependencies is the gRPC handler to fetch dependencies.
+// GetDependencies is the gRPC handler to fetch dependencies. With the depth or direction metadata,
+// it returns the links found within depth calls upstream and/or downstream of the service metadata.
 func (g *GRPCHandler) GetDependencies(ctx context.Context, r *api_v2.GetDependenciesRequest) (*api_v2.GetDependenciesResponse, error) {
 	startTime := r.StartTime
 	endTime := r.EndTime
-	dependencies, err := g.queryService.GetDependencies(startTime, endTime.Sub(startTime))
+	focal, err := parseFocalMetadata(ctx)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	dependencies, err := g.queryService.GetDependencies(ctx, startTime, endTime.Sub(startTime))
+	if err := limitStatus(err); err != nil {
+		return nil, err
+	}
 	if err != nil {
 		g.logger.Error("failed to fetch dependencies", zap.Error(err))
 		return nil, status.Errorf(codes.Internal, "failed to fetch dependencies: %v", err)
 	}
+	if focal != nil {
+		dependencies = focal.links(dependencies)
+	}
 
 	return &api_v2.GetDependenciesResponse{Dependencies: dependencies}, nil
 }
+
+// focalDependencies are the links of GetDependencies within depth calls of the service.
+type focalDependencies struct {
+	service   string
+	depth     int
+	direction string
+}
+
+// parseFocalMetadata returns nil when the request metadata have no depth or direction.
+func parseFocalMetadata(ctx context.Context) (*focalDependencies, error) {
+	depth, direction := metadataValue(ctx, depthMetadata), metadataValue(ctx, directionMetadata)
+	if depth == "" && direction == "" {
+		return nil, nil
+	}
+	focal := &focalDependencies{service: metadataValue(ctx, serviceMetadata), depth: 1}
+	if focal.service == "" {
+		return nil, fmt.Errorf("metadata '%s' is required with '%s' or '%s'", serviceMetadata, depthMetadata, directionMetadata)
+	}
+	if depth != "" {
+		var err error
+		focal.depth, err = strconv.Atoi(depth)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %w", depthMetadata, err)
+		}
+		if focal.depth <= 0 {
+			return nil, fmt.Errorf("%s must be positive", depthMetadata)
+		}
+	}
+	var err error
+	focal.direction, err = parseDirection(direction)
+	if err != nil {
+		return nil, err
+	}
+	return focal, nil
+}
+
+func (f *focalDependencies) links(dependencies []model.DependencyLink) []model.DependencyLink {
+	focal := newDependencyGraph(deduplicateDependencyLinks(dependencies)).focal(f.service, f.depth, f.direction)
+	links := make([]model.DependencyLink, len(focal))
+	for i, link := range focal {
+		links[i] = model.DependencyLink{Parent: link.Parent, Child: link.Child, CallCount: link.CallCount}
+	}
+	return links
+}
+
+// metadataValues returns the values of the request metadata with the given key.
+func metadataValues(ctx context.Context, key string) []string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+	return md.Get(key)
+}
+
+// metadataValue returns the first value of the request metadata with the given key, empty if there is none.
+func metadataValue(ctx context.Context, key string) string {
+	if values := metadataValues(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// limitStatus returns the status of the errors of the query limits, nil for the other errors.
+func limitStatus(err error) error {
+	if errors.Is(err, querysvc.ErrLimitExceeded) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if errors.Is(err, querysvc.ErrQueryTimeout) {
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	return nil
+}
@@ -13,12 +13,13 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package app
+package queryservice
 
 import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"testing"
 	"time"
@@ -30,10 +31,11 @@ import (
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
-	"github.com/jaegertracing/jaeger/cmd/query/app/querysvc"
 	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
 	"github.com/jaegertracing/jaeger/proto-gen/api_v2"
 	depsmocks "github.com/jaegertracing/jaeger/storage/dependencystore/mocks"
 	"github.com/jaegertracing/jaeger/storage/spanstore"
@@ -334,6 +336,45 @@ func TestSearchSuccessGRPC(t *testing.T) {
 	})
 }
 
+func TestSearchPagesGRPC(t *testing.T) {
+	withServerAndClient(t, func(server *grpcServer, client *grpcClient) {
+		start := time.Unix(1600000000, 0)
+		traces := []*model.Trace{
+			{Spans: []*model.Span{{TraceID: model.NewTraceID(0, 1), SpanID: model.NewSpanID(1), StartTime: start}}},
+			{Spans: []*model.Span{{TraceID: model.NewTraceID(0, 2), SpanID: model.NewSpanID(1), StartTime: start.Add(time.Second)}}},
+		}
+		server.spanReader.On("FindTraces", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+			Return(traces, nil)
+		request := &api_v2.FindTracesRequest{Query: &api_v2.TraceQueryParameters{ServiceName: "service", SearchDepth: 1}}
+
+		res, err := client.FindTraces(context.Background(), request)
+		require.NoError(t, err)
+		chunk, err := res.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, model.NewTraceID(0, 2), chunk.Spans[0].TraceID)
+		header, err := res.Header()
+		require.NoError(t, err)
+		tokens := header.Get(nextPageTokenMetadata)
+		require.Len(t, tokens, 1)
+
+		ctx := metadata.AppendToOutgoingContext(context.Background(), pageTokenMetadata, tokens[0])
+		res, err = client.FindTraces(ctx, request)
+		require.NoError(t, err)
+		chunk, err = res.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, model.NewTraceID(0, 1), chunk.Spans[0].TraceID)
+		header, err = res.Header()
+		require.NoError(t, err)
+		assert.Empty(t, header.Get(nextPageTokenMetadata))
+
+		ctx = metadata.AppendToOutgoingContext(context.Background(), pageTokenMetadata, "!")
+		res, err = client.FindTraces(ctx, request)
+		require.NoError(t, err)
+		_, err = res.Recv()
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
 func TestSearchSuccess_SpanStreamingGRPC(t *testing.T) {
 	withServerAndClient(t, func(server *grpcServer, client *grpcClient) {
 
@@ -484,6 +525,111 @@ func TestGetDependenciesFailureGRPC(t *testing.T) {
 	})
 }
 
+func TestGetDependenciesFocalGRPC(t *testing.T) {
+	withServerAndClient(t, func(server *grpcServer, client *grpcClient) {
+		endTs := time.Now().UTC()
+		server.depReader.On("GetDependencies", endTs.Add(-defaultDependencyLookbackDuration), defaultDependencyLookbackDuration).
+			Return([]model.DependencyLink{
+				{Parent: "frontend", Child: "api", CallCount: 10},
+				{Parent: "api", Child: "mysql", CallCount: 5},
+				{Parent: "api", Child: "mysql", CallCount: 7},
+				{Parent: "mysql", Child: "disk", CallCount: 1},
+			}, nil)
+		request := &api_v2.GetDependenciesRequest{
+			StartTime: endTs.Add(-defaultDependencyLookbackDuration),
+			EndTime:   endTs,
+		}
+
+		ctx := metadata.AppendToOutgoingContext(context.Background(),
+			serviceMetadata, "api", depthMetadata, "2", directionMetadata, directionDownstream)
+		res, err := client.GetDependencies(ctx, request)
+		require.NoError(t, err)
+		assert.Equal(t, []model.DependencyLink{
+			{Parent: "api", Child: "mysql", CallCount: 12},
+			{Parent: "mysql", Child: "disk", CallCount: 1},
+		}, res.Dependencies)
+
+		for _, md := range [][]string{
+			{depthMetadata, "2"},
+			{serviceMetadata, "api", depthMetadata, "0"},
+			{serviceMetadata, "api", depthMetadata, "x"},
+			{serviceMetadata, "api", directionMetadata, "sideways"},
+		} {
+			_, err := client.GetDependencies(metadata.AppendToOutgoingContext(context.Background(), md...), request)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err), md)
+		}
+	})
+}
+
+func TestGetTraceAdjustedGRPC(t *testing.T) {
+	withServerAndClient(t, func(server *grpcServer, client *grpcClient) {
+		trace := func() *model.Trace {
+			return &model.Trace{Spans: []*model.Span{{
+				TraceID: mockTraceID,
+				SpanID:  model.NewSpanID(1),
+				Process: &model.Process{},
+				Logs:    []model.Log{{Fields: []model.KeyValue{model.String("b", "x"), model.String("a", "y")}}},
+			}}}
+		}
+		server.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), mockTraceID).Return(trace(), nil).Once()
+		res, err := client.GetTrace(context.Background(), &api_v2.GetTraceRequest{TraceID: mockTraceID})
+		require.NoError(t, err)
+		chunk, err := res.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, "b", chunk.Spans[0].Logs[0].Fields[0].Key, "the traces are not adjusted by default")
+
+		server.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), mockTraceID).Return(trace(), nil).Once()
+		ctx := metadata.AppendToOutgoingContext(context.Background(), adjustMetadata, "true")
+		res, err = client.GetTrace(ctx, &api_v2.GetTraceRequest{TraceID: mockTraceID})
+		require.NoError(t, err)
+		chunk, err = res.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, "a", chunk.Spans[0].Logs[0].Fields[0].Key)
+	})
+}
+
+func TestSearchTagFiltersGRPC(t *testing.T) {
+	withServerAndClient(t, func(server *grpcServer, client *grpcClient) {
+		matching := &model.Trace{Spans: []*model.Span{{
+			TraceID: model.NewTraceID(0, 1),
+			SpanID:  model.NewSpanID(1),
+			Tags:    model.KeyValues{model.Int64("http.status_code", 503)},
+		}}}
+		other := &model.Trace{Spans: []*model.Span{{
+			TraceID: model.NewTraceID(0, 2),
+			SpanID:  model.NewSpanID(1),
+			Tags:    model.KeyValues{model.Int64("http.status_code", 200)},
+		}}}
+		server.spanReader.On("FindTraces", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+			Return([]*model.Trace{matching, other}, nil).Once()
+		request := &api_v2.FindTracesRequest{Query: &api_v2.TraceQueryParameters{ServiceName: "service"}}
+
+		ctx := metadata.AppendToOutgoingContext(context.Background(), tagFilterMetadata, "http.status_code>=500")
+		res, err := client.FindTraces(ctx, request)
+		require.NoError(t, err)
+		chunk, err := res.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, model.NewTraceID(0, 1), chunk.Spans[0].TraceID)
+		_, err = res.Recv()
+		assert.Equal(t, io.EOF, err)
+
+		ctx = metadata.AppendToOutgoingContext(context.Background(), tagFilterMetadata, "http.url=~(")
+		res, err = client.FindTraces(ctx, request)
+		require.NoError(t, err)
+		_, err = res.Recv()
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestLimitStatus(t *testing.T) {
+	err := limitStatus(fmt.Errorf("%w: too long", querysvc.ErrLimitExceeded))
+	assertGRPCError(t, err, codes.InvalidArgument, "query limit exceeded: too long")
+	err = limitStatus(fmt.Errorf("%w: too slow", querysvc.ErrQueryTimeout))
+	assertGRPCError(t, err, codes.DeadlineExceeded, "query timed out: too slow")
+	assert.NoError(t, limitStatus(errStorageGRPC))
+	assert.NoError(t, limitStatus(nil))
+}
+
 func TestSendSpanChunksError(t *testing.T) {
 	g := &GRPCHandler{
 		logger: zap.NewNop(),
@@ -13,7 +13,7 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package app
+package queryservice
 
 import (
 	"sort"
@@ -353,3 +353,97 @@ func TestGetDependenciesLookbackParsingFailure(t *testing.T) {
 	err := getJSON(server.URL+"/api/dependencies?endTs=1476374248550&service=testing&lookback=shazbot", &response)
 	assert.Error(t, err)
 }
+
+func TestGetDependenciesFocal(t *testing.T) {
+	server, _, mock := initializeTestServer()
+	defer server.Close()
+	endTs := time.Unix(0, 1476374248550*millisToNanosMultiplier)
+	mock.On("GetDependencies", endTs, defaultDependencyLookbackDuration).Return([]model.DependencyLink{
+		{Parent: "frontend", Child: "api", CallCount: 10},
+		{Parent: "api", Child: "mysql", CallCount: 5},
+		{Parent: "api", Child: "mysql", CallCount: 7},
+		{Parent: "mysql", Child: "disk", CallCount: 1},
+	}, nil)
+
+	var response struct {
+		Data []ui.DependencyLink `json:"data"`
+	}
+	err := getJSON(server.URL+"/api/dependencies?endTs=1476374248550&service=api&depth=2&direction=downstream", &response)
+	assert.NoError(t, err)
+	assert.Equal(t, []ui.DependencyLink{
+		{Parent: "api", Child: "mysql", CallCount: 12},
+		{Parent: "mysql", Child: "disk", CallCount: 1},
+	}, response.Data)
+
+	err = getJSON(server.URL+"/api/dependencies?endTs=1476374248550&service=mysql&direction=upstream", &response)
+	assert.NoError(t, err)
+	assert.Equal(t, []ui.DependencyLink{{Parent: "api", Child: "mysql", CallCount: 12}}, response.Data)
+}
+
+func TestGetDependenciesFocalErrors(t *testing.T) {
+	server, _, mock := initializeTestServer()
+	defer server.Close()
+	mock.On("GetDependencies", time.Unix(0, 1476374248550*millisToNanosMultiplier), defaultDependencyLookbackDuration).
+		Return([]model.DependencyLink{}, nil)
+
+	for _, query := range []string{
+		"depth=2",
+		"service=api&depth=0",
+		"service=api&depth=x",
+		"service=api&direction=sideways",
+	} {
+		err := getJSON(server.URL+"/api/dependencies?endTs=1476374248550&"+query, nil)
+		if assert.Error(t, err, query) {
+			assert.Contains(t, err.Error(), "400 error from server", query)
+		}
+	}
+}
+
+func TestGetDependencyPaths(t *testing.T) {
+	server, _, mock := initializeTestServer()
+	defer server.Close()
+	endTs := time.Unix(0, 1476374248550*millisToNanosMultiplier)
+	mock.On("GetDependencies", endTs, defaultDependencyLookbackDuration).Return([]model.DependencyLink{
+		{Parent: "frontend", Child: "api", CallCount: 10},
+		{Parent: "api", Child: "mysql", CallCount: 5},
+		{Parent: "frontend", Child: "cache", CallCount: 8},
+		{Parent: "cache", Child: "mysql", CallCount: 2},
+	}, nil)
+
+	var response struct {
+		Data  []ui.DependencyPath `json:"data"`
+		Total int                 `json:"total"`
+		Limit int                 `json:"limit"`
+	}
+	err := getJSON(server.URL+"/api/dependencies/paths?endTs=1476374248550&from=frontend&to=mysql", &response)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, response.Total)
+	assert.Equal(t, defaultDependencyPathLimit, response.Limit)
+	assert.Equal(t, []string{"frontend", "api", "mysql"}, response.Data[0].Services)
+	assert.Equal(t, uint64(5), response.Data[0].CallCount)
+	assert.Equal(t, []string{"frontend", "cache", "mysql"}, response.Data[1].Services)
+
+	err = getJSON(server.URL+"/api/dependencies/paths?endTs=1476374248550&from=frontend&to=mysql&maxDepth=1", &response)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, response.Total)
+}
+
+func TestGetDependencyPathsErrors(t *testing.T) {
+	server, _, mock := initializeTestServer()
+	defer server.Close()
+	mock.On("GetDependencies", time.Unix(0, 1476374248550*millisToNanosMultiplier), defaultDependencyLookbackDuration).
+		Return(nil, errStorage)
+
+	for query, status := range map[string]string{
+		"from=a":                   "400",
+		"from=a&to=b&maxDepth=-1":  "400",
+		"from=a&to=b&limit=x":      "400",
+		"from=a&to=b&lookback=bad": "400",
+		"from=a&to=b":              "500",
+	} {
+		err := getJSON(server.URL+"/api/dependencies/paths?endTs=1476374248550&"+query, nil)
+		if assert.Error(t, err, query) {
+			assert.Contains(t, err.Error(), status+" error from server", query)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/model/adjuster"
+	ui "github.com/jaegertracing/jaeger/model/json"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+func TestDiffTracesHandler(t *testing.T) {
+	idA, idB := model.NewTraceID(0, 0xa), model.NewTraceID(0, 0xb)
+	traceA := &model.Trace{Spans: []*model.Span{diffSpan(idA, 1, 0, "frontend", "GET /", 0, 100*time.Millisecond)}}
+	traceB := &model.Trace{Spans: []*model.Span{diffSpan(idB, 1, 0, "frontend", "GET /", 0, 80*time.Millisecond)}}
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), idA).Return(traceA, nil).Once()
+		ts.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), idB).Return(traceB, nil).Once()
+
+		var response structuredResponse
+		require.NoError(t, getJSON(ts.server.URL+"/api/traces/a/diff/b", &response))
+		data, err := json.Marshal(response.Data)
+		require.NoError(t, err)
+		var diff ui.TraceDiff
+		require.NoError(t, json.Unmarshal(data, &diff))
+		assert.Equal(t, ui.TraceID("000000000000000a"), diff.TraceIDA)
+		assert.Equal(t, int64(-20000), diff.DurationDelta)
+		require.Len(t, diff.Spans, 1)
+		assert.Equal(t, ui.SpanDiffUnchanged, diff.Spans[0].Status)
+		require.Len(t, diff.Operations, 1)
+		assert.Equal(t, "frontend", diff.Operations[0].ServiceName)
+		assert.Empty(t, response.Errors)
+	}, querysvc.QueryServiceOptions{})
+}
+
+func TestDiffTracesHandlerErrors(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), model.NewTraceID(0, 0xa)).
+			Return(&model.Trace{}, nil)
+		ts.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), model.NewTraceID(0, 0xb)).
+			Return(nil, spanstore.ErrTraceNotFound)
+		ts.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), model.NewTraceID(0, 0xc)).
+			Return(nil, errStorage)
+
+		var response structuredResponse
+		err := getJSON(ts.server.URL+"/api/traces/a/diff/b", &response)
+		assert.EqualError(t, err, parsedError(404, "trace 000000000000000b: trace not found"))
+		err = getJSON(ts.server.URL+"/api/traces/a/diff/c", &response)
+		assert.EqualError(t, err, parsedError(500, errStorageMsg))
+		err = getJSON(ts.server.URL+"/api/traces/a/diff/x", &response)
+		assert.EqualError(t, err, parsedError(400, `strconv.ParseUint: parsing \"x\": invalid syntax`))
+		err = getJSON(ts.server.URL+"/api/traces/x/diff/a", &response)
+		assert.EqualError(t, err, parsedError(400, `strconv.ParseUint: parsing \"x\": invalid syntax`))
+	}, querysvc.QueryServiceOptions{})
+}
+
+func TestDiffTracesHandlerAdjustmentFailure(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("model.TraceID")).
+			Return(mockTrace, nil)
+
+		var response structuredResponse
+		require.NoError(t, getJSON(ts.server.URL+"/api/traces/a/diff/b", &response))
+		require.Len(t, response.Errors, 2)
+		assert.Equal(t, errAdjustment.Error(), response.Errors[0].Msg)
+		assert.Equal(t, ui.TraceID("000000000000000b"), response.Errors[1].TraceID)
+
+		require.NoError(t, getJSON(ts.server.URL+"/api/traces/a/diff/b?raw=true", &response))
+		assert.Empty(t, response.Errors)
+	}, querysvc.QueryServiceOptions{
+		Adjuster: adjuster.Func(func(trace *model.Trace) (*model.Trace, error) {
+			return trace, errAdjustment
+		}),
+	})
+}
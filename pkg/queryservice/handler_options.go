@@ -13,13 +13,16 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package app
+package queryservice
 
 import (
 	"time"
 
 	"github.com/opentracing/opentracing-go"
 	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
 )
 
 // HandlerOption is a function that sets some option on the APIHandler
@@ -65,3 +68,51 @@ func (handlerOptions) Tracer(tracer opentracing.Tracer) HandlerOption {
 		apiHandler.tracer = tracer
 	}
 }
+
+// LiveTail creates a HandlerOption that sets how often the live tail searches the new traces, and how long
+// a trace must not have received spans to be sent
+func (handlerOptions) LiveTail(pollInterval, quietPeriod time.Duration) HandlerOption {
+	return func(apiHandler *APIHandler) {
+		apiHandler.liveTail.pollInterval = pollInterval
+		apiHandler.liveTail.quietPeriod = quietPeriod
+	}
+}
+
+// LiveTailAllowedOrigins creates a HandlerOption that sets the origins of the web pages allowed to open the live tail,
+// besides the ones served with the API
+func (handlerOptions) LiveTailAllowedOrigins(origins []string) HandlerOption {
+	return func(apiHandler *APIHandler) {
+		apiHandler.liveTail.allowedOrigins = origins
+	}
+}
+
+// MetricsQueryService creates a HandlerOption that initializes the service providing the span metrics
+func (handlerOptions) MetricsQueryService(metricsQueryService querysvc.MetricsQueryService) HandlerOption {
+	return func(apiHandler *APIHandler) {
+		apiHandler.metricsQueryService = metricsQueryService
+	}
+}
+
+// Tenancy creates a HandlerOption that requires the requests to identify their tenant,
+// the results are then restricted to the tenant
+func (handlerOptions) Tenancy(tenancyMgr *tenancy.Manager) HandlerOption {
+	return func(apiHandler *APIHandler) {
+		apiHandler.tenancyMgr = tenancyMgr
+	}
+}
+
+// TraceMaxAge creates a HandlerOption that sets how long the clients and the caching proxies
+// may cache the traces that look complete
+func (handlerOptions) TraceMaxAge(maxAge time.Duration) HandlerOption {
+	return func(apiHandler *APIHandler) {
+		apiHandler.traceMaxAge = maxAge
+	}
+}
+
+// PrivateTraces creates a HandlerOption that lets only the clients, and not the shared caching proxies,
+// cache the traces, which vary with the headers identifying the caller
+func (handlerOptions) PrivateTraces(varyHeaders []string) HandlerOption {
+	return func(apiHandler *APIHandler) {
+		apiHandler.traceVary = varyHeaders
+	}
+}
@@ -0,0 +1,820 @@
+// Copyright (c) 2019 The Jaeger Authors.
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/opentracing-contrib/go-stdlib/nethttp"
+	"github.com/opentracing/opentracing-go"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	uiconv "github.com/jaegertracing/jaeger/model/converter/json"
+	ui "github.com/jaegertracing/jaeger/model/json"
+	"github.com/jaegertracing/jaeger/pkg/multierror"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+const (
+	traceIDParam      = "traceID"
+	otherTraceIDParam = "otherTraceID"
+	endTsParam        = "endTs"
+	lookbackParam     = "lookback"
+	depthParam        = "depth"
+	directionParam    = "direction"
+	fromParam         = "from"
+	toParam           = "to"
+	maxDepthParam     = "maxDepth"
+	pageTokenParam    = "pageToken"
+
+	defaultDependencyLookbackDuration = time.Hour * 24
+	defaultDependencyPathMaxDepth     = 5
+	defaultDependencyPathLimit        = 100
+	defaultTraceQueryLookbackDuration = time.Hour * 24 * 2
+	DefaultAPIPrefix                  = "api"
+)
+
+// HTTPHandler handles http requests
+type HTTPHandler interface {
+	RegisterRoutes(router *mux.Router)
+}
+
+type structuredResponse struct {
+	Data   interface{}       `json:"data"`
+	Total  int               `json:"total"`
+	Limit  int               `json:"limit"`
+	Offset int               `json:"offset"`
+	Errors []structuredError `json:"errors"`
+	// NextPageToken is passed as the pageToken parameter to get the next page of the search results
+	NextPageToken string `json:"nextPageToken,omitempty"`
+}
+
+type structuredError struct {
+	Code    int        `json:"code,omitempty"`
+	Msg     string     `json:"msg"`
+	TraceID ui.TraceID `json:"traceID,omitempty"`
+}
+
+// NewRouter creates and configures a Gorilla Router.
+func NewRouter() *mux.Router {
+	return mux.NewRouter().UseEncodedPath()
+}
+
+// APIHandler implements the query service public API by registering routes at httpPrefix
+type APIHandler struct {
+	queryService        *querysvc.QueryService
+	metricsQueryService querysvc.MetricsQueryService
+	queryParser         queryParser
+	basePath            string
+	apiPrefix           string
+	logger              *zap.Logger
+	tracer              opentracing.Tracer
+	liveTail            liveTailOptions
+	tenancyMgr          *tenancy.Manager
+	traceMaxAge         time.Duration
+	traceVary           []string
+}
+
+// NewAPIHandler returns an APIHandler
+func NewAPIHandler(queryService *querysvc.QueryService, options ...HandlerOption) *APIHandler {
+	aH := &APIHandler{
+		queryService:        queryService,
+		metricsQueryService: metricsstore.NewDisabledReader(),
+		queryParser: queryParser{
+			traceQueryLookbackDuration: defaultTraceQueryLookbackDuration,
+			timeNow:                    time.Now,
+		},
+	}
+
+	for _, option := range options {
+		option(aH)
+	}
+	if aH.apiPrefix == "" {
+		aH.apiPrefix = DefaultAPIPrefix
+	}
+	if aH.logger == nil {
+		aH.logger = zap.NewNop()
+	}
+	if aH.tracer == nil {
+		aH.tracer = opentracing.NoopTracer{}
+	}
+	if aH.liveTail.pollInterval <= 0 {
+		aH.liveTail.pollInterval = DefaultLiveTailPollInterval
+	}
+	if aH.liveTail.quietPeriod <= 0 {
+		aH.liveTail.quietPeriod = DefaultLiveTailQuietPeriod
+	}
+	return aH
+}
+
+// RegisterRoutes registers routes for this handler on the given router
+func (aH *APIHandler) RegisterRoutes(router *mux.Router) {
+	aH.handleFunc(router, aH.uploadTraces, "/traces/upload").Methods(http.MethodPost)
+	aH.handleFunc(router, aH.tailTraces, "/traces/live").Methods(http.MethodGet)
+	aH.handleFunc(router, aH.searchTraceStats, "/traces/stats").Methods(http.MethodGet)
+	aH.handleFunc(router, aH.durationHistogram, "/traces/histogram").Methods(http.MethodGet)
+	aH.handleFunc(router, aH.getTrace, "/traces/{%s}", traceIDParam).Methods(http.MethodGet)
+	aH.handleFunc(router, aH.diffTraces, "/traces/{%s}/diff/{%s}", traceIDParam, otherTraceIDParam).Methods(http.MethodGet)
+	aH.handleFunc(router, aH.criticalPath, "/traces/{%s}/critical-path", traceIDParam).Methods(http.MethodGet)
+	aH.handleFunc(router, aH.getSpans, "/traces/{%s}/spans", traceIDParam).Methods(http.MethodGet)
+	aH.handleFunc(router, aH.getTraceStats, "/traces/{%s}/stats", traceIDParam).Methods(http.MethodGet)
+	aH.handleFunc(router, aH.archiveTrace, "/archive/{%s}", traceIDParam).Methods(http.MethodPost)
+	aH.handleFunc(router, aH.search, "/traces").Methods(http.MethodGet)
+	aH.handleFunc(router, aH.findSpans, "/spans").Methods(http.MethodGet)
+	aH.handleFunc(router, aH.flameGraph, "/flamegraph").Methods(http.MethodGet)
+	aH.handleFunc(router, aH.getServices, "/services").Methods(http.MethodGet)
+	// TODO change the UI to use this endpoint. Requires ?service= parameter.
+	aH.handleFunc(router, aH.getOperations, "/operations").Methods(http.MethodGet)
+	// TODO - remove this when UI catches up
+	aH.handleFunc(router, aH.getOperationsLegacy, "/services/{%s}/operations", serviceParam).Methods(http.MethodGet)
+	aH.handleFunc(router, aH.dependencies, "/dependencies").Methods(http.MethodGet)
+	aH.handleFunc(router, aH.dependencyPaths, "/dependencies/paths").Methods(http.MethodGet)
+	aH.handleFunc(router, aH.getLatencies, "/metrics/latencies").Methods(http.MethodGet)
+	aH.handleFunc(router, aH.getCallRates, "/metrics/calls").Methods(http.MethodGet)
+	aH.handleFunc(router, aH.getErrorRates, "/metrics/errors").Methods(http.MethodGet)
+	aH.handleFunc(router, aH.getMinStep, "/metrics/minstep").Methods(http.MethodGet)
+	aH.handleFunc(router, aH.getLatencyPercentiles, "/metrics/percentiles").Methods(http.MethodGet)
+	aH.handleFunc(router, aH.getLatencyHeatmap, "/metrics/heatmap").Methods(http.MethodGet)
+	aH.registerViewRoutes(router)
+}
+
+func (aH *APIHandler) handleFunc(
+	router *mux.Router,
+	f func(http.ResponseWriter, *http.Request),
+	route string,
+	args ...interface{},
+) *mux.Route {
+	route = aH.route(route, args...)
+	var handler http.Handler = http.HandlerFunc(f)
+	if aH.tenancyMgr != nil && aH.tenancyMgr.Enabled {
+		handler = aH.tenancyMgr.HTTPHandler(handler)
+	}
+	traceMiddleware := nethttp.Middleware(
+		aH.tracer,
+		handler,
+		nethttp.OperationNameFunc(func(r *http.Request) string {
+			return route
+		}))
+	return router.HandleFunc(route, traceMiddleware.ServeHTTP)
+}
+
+func (aH *APIHandler) route(route string, args ...interface{}) string {
+	args = append([]interface{}{aH.apiPrefix}, args...)
+	return fmt.Sprintf("/%s"+route, args...)
+}
+
+func (aH *APIHandler) getServices(w http.ResponseWriter, r *http.Request) {
+	services, err := aH.queryService.GetServices(r.Context())
+	if aH.handleError(w, err, http.StatusInternalServerError) {
+		return
+	}
+	structuredRes := structuredResponse{
+		Data:  services,
+		Total: len(services),
+	}
+	aH.writeJSON(w, r, &structuredRes)
+}
+
+func (aH *APIHandler) getOperationsLegacy(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	// given how getOperationsLegacy is bound to URL route, serviceParam cannot be empty
+	service, _ := url.QueryUnescape(vars[serviceParam])
+	// for backwards compatibility, we will retrieve operations with all span kind
+	operations, err := aH.queryService.GetOperations(r.Context(),
+		spanstore.OperationQueryParameters{
+			ServiceName: service,
+			// include all kinds
+			SpanKind: "",
+		})
+
+	if aH.handleError(w, err, http.StatusInternalServerError) {
+		return
+	}
+	operationNames := getUniqueOperationNames(operations)
+	structuredRes := structuredResponse{
+		Data:  operationNames,
+		Total: len(operationNames),
+	}
+	aH.writeJSON(w, r, &structuredRes)
+}
+
+func (aH *APIHandler) getOperations(w http.ResponseWriter, r *http.Request) {
+	service := r.FormValue(serviceParam)
+	if service == "" {
+		if aH.handleError(w, ErrServiceParameterRequired, http.StatusBadRequest) {
+			return
+		}
+	}
+	spanKind := r.FormValue(spanKindParam)
+	operations, err := aH.queryService.GetOperations(
+		r.Context(),
+		spanstore.OperationQueryParameters{ServiceName: service, SpanKind: spanKind},
+	)
+
+	if aH.handleError(w, err, http.StatusInternalServerError) {
+		return
+	}
+	data := make([]ui.Operation, len(operations))
+	for i, operation := range operations {
+		data[i] = ui.Operation{
+			Name:     operation.Name,
+			SpanKind: operation.SpanKind,
+		}
+	}
+	structuredRes := structuredResponse{
+		Data:  data,
+		Total: len(operations),
+	}
+	aH.writeJSON(w, r, &structuredRes)
+}
+
+func (aH *APIHandler) search(w http.ResponseWriter, r *http.Request) {
+	tracesFromStorage, nextPageToken, uiErrors, ok := aH.findTraces(w, r)
+	if !ok {
+		return
+	}
+
+	uiTraces := make([]*ui.Trace, len(tracesFromStorage))
+	for i, v := range tracesFromStorage {
+		uiTrace, uiErr := aH.convertModelToUI(v, true)
+		if uiErr != nil {
+			uiErrors = append(uiErrors, *uiErr)
+		}
+		uiTraces[i] = uiTrace
+	}
+
+	structuredRes := structuredResponse{
+		Data:          uiTraces,
+		Errors:        uiErrors,
+		NextPageToken: nextPageToken,
+	}
+	aH.writeJSON(w, r, &structuredRes)
+}
+
+// flameGraph implements the REST API /flamegraph. It runs the same search as /traces
+// and merges the call trees of the traces found.
+func (aH *APIHandler) flameGraph(w http.ResponseWriter, r *http.Request) {
+	tracesFromStorage, _, uiErrors, ok := aH.findTraces(w, r)
+	if !ok {
+		return
+	}
+
+	traces := make([]*model.Trace, len(tracesFromStorage))
+	for i, trace := range tracesFromStorage {
+		adjusted, err := aH.queryService.Adjust(trace)
+		if err != nil {
+			uiErrors = append(uiErrors, structuredError{Msg: err.Error(), TraceID: traceIDOf(trace)})
+		}
+		traces[i] = adjusted
+	}
+
+	structuredRes := structuredResponse{
+		Data:   aggregateTraces(traces),
+		Errors: uiErrors,
+	}
+	aH.writeJSON(w, r, &structuredRes)
+}
+
+// findTraces returns the page of traces matching the search parameters of the request and the token
+// of the next page, or writes the error.
+func (aH *APIHandler) findTraces(w http.ResponseWriter, r *http.Request) ([]*model.Trace, string, []structuredError, bool) {
+	tQuery, err := aH.queryParser.parse(r)
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return nil, "", nil, false
+	}
+
+	var uiErrors []structuredError
+	var tracesFromStorage []*model.Trace
+	var nextPageToken string
+	if len(tQuery.traceIDs) > 0 {
+		tracesFromStorage, uiErrors, err = aH.tracesByIDs(r.Context(), tQuery.traceIDs)
+	} else {
+		tracesFromStorage, nextPageToken, err = aH.queryService.FindTracesPage(r.Context(), &tQuery.TraceQueryParameters, r.FormValue(pageTokenParam))
+	}
+	if err == querysvc.ErrInvalidPageToken {
+		aH.handleError(w, err, http.StatusBadRequest)
+		return nil, "", nil, false
+	}
+	if aH.handleError(w, err, http.StatusInternalServerError) {
+		return nil, "", nil, false
+	}
+	return tracesFromStorage, nextPageToken, uiErrors, true
+}
+
+func (aH *APIHandler) tracesByIDs(ctx context.Context, traceIDs []model.TraceID) ([]*model.Trace, []structuredError, error) {
+	var errors []structuredError
+	retMe := make([]*model.Trace, 0, len(traceIDs))
+	for _, traceID := range traceIDs {
+		if trace, err := aH.queryService.GetTrace(ctx, traceID); err != nil {
+			if err != spanstore.ErrTraceNotFound {
+				return nil, nil, err
+			}
+			errors = append(errors, structuredError{
+				Msg:     err.Error(),
+				TraceID: ui.TraceID(traceID.String()),
+			})
+		} else {
+			retMe = append(retMe, trace)
+		}
+	}
+	return retMe, errors, nil
+}
+
+// dependencies implements the REST API /dependencies. With the depth or direction parameters,
+// it returns the links found within depth calls upstream and/or downstream of the service.
+// With stats=true, the links have the request and error counts and the latency percentiles of their calls.
+func (aH *APIHandler) dependencies(w http.ResponseWriter, r *http.Request) {
+	withStats, err := parseEdgeStats(r)
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	dependencies, ok := aH.getDependencies(w, r)
+	if !ok {
+		return
+	}
+	service := r.FormValue(serviceParam)
+
+	if r.FormValue(depthParam) == "" && r.FormValue(directionParam) == "" {
+		filteredDependencies := aH.filterDependenciesByService(dependencies, service)
+		aH.writeDependencies(w, r, aH.deduplicateDependencies(filteredDependencies), withStats)
+		return
+	}
+
+	if service == "" {
+		aH.handleError(w, fmt.Errorf("parameter '%s' is required with '%s' or '%s'", serviceParam, depthParam, directionParam), http.StatusBadRequest)
+		return
+	}
+	depth, err := parsePositiveInt(r, depthParam, 1)
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	direction, err := parseDirection(r.FormValue(directionParam))
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	graph := newDependencyGraph(aH.deduplicateDependencies(dependencies))
+	aH.writeDependencies(w, r, graph.focal(service, depth, direction), withStats)
+}
+
+// writeDependencies writes the links, with the stats of their calls if requested.
+func (aH *APIHandler) writeDependencies(w http.ResponseWriter, r *http.Request, links []ui.DependencyLink, withStats bool) {
+	if withStats {
+		endTs, lookback, err := parseDependenciesPeriod(r)
+		if aH.handleError(w, err, http.StatusBadRequest) {
+			return
+		}
+		limit, err := parsePositiveInt(r, limitParam, defaultEdgeStatsTraceLimit)
+		if aH.handleError(w, err, http.StatusBadRequest) {
+			return
+		}
+		err = aH.addEdgeStats(r.Context(), links, endTs, lookback, limit)
+		if aH.handleError(w, err, http.StatusInternalServerError) {
+			return
+		}
+	}
+	aH.writeJSON(w, r, &structuredResponse{Data: links})
+}
+
+// dependencyPaths implements the REST API /dependencies/paths.
+// It returns the chains of calls from a service to another, the shortest first.
+func (aH *APIHandler) dependencyPaths(w http.ResponseWriter, r *http.Request) {
+	from, to := r.FormValue(fromParam), r.FormValue(toParam)
+	if from == "" || to == "" {
+		aH.handleError(w, fmt.Errorf("parameters '%s' and '%s' are required", fromParam, toParam), http.StatusBadRequest)
+		return
+	}
+	maxDepth, err := parsePositiveInt(r, maxDepthParam, defaultDependencyPathMaxDepth)
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	limit, err := parsePositiveInt(r, limitParam, defaultDependencyPathLimit)
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	dependencies, ok := aH.getDependencies(w, r)
+	if !ok {
+		return
+	}
+	paths := newDependencyGraph(aH.deduplicateDependencies(dependencies)).paths(from, to, maxDepth, limit)
+	structuredRes := structuredResponse{
+		Data:  paths,
+		Total: len(paths),
+		Limit: limit,
+	}
+	aH.writeJSON(w, r, &structuredRes)
+}
+
+// getDependencies loads the dependency links of the period given by the endTs and lookback parameters.
+func (aH *APIHandler) getDependencies(w http.ResponseWriter, r *http.Request) ([]model.DependencyLink, bool) {
+	endTs, lookback, err := parseDependenciesPeriod(r)
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return nil, false
+	}
+	dependencies, err := aH.queryService.GetDependencies(r.Context(), endTs, lookback)
+	if aH.handleError(w, err, http.StatusInternalServerError) {
+		return nil, false
+	}
+	return dependencies, true
+}
+
+func parsePositiveInt(r *http.Request, param string, defaultValue int) (int, error) {
+	formValue := r.FormValue(param)
+	if formValue == "" {
+		return defaultValue, nil
+	}
+	value, err := strconv.Atoi(formValue)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse %s: %w", param, err)
+	}
+	if value <= 0 {
+		return 0, fmt.Errorf("%s must be positive", param)
+	}
+	return value, nil
+}
+
+// getLatencies implements the REST API /metrics/latencies.
+// It returns the quantile of the latencies of the services, e.g. quantile=0.95.
+func (aH *APIHandler) getLatencies(w http.ResponseWriter, r *http.Request) {
+	params, err := aH.queryParser.parseMetricsQueryParams(r)
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	quantile, err := strconv.ParseFloat(r.FormValue(quantileParam), 64)
+	if err != nil || quantile <= 0 || quantile > 1 {
+		aH.handleError(w, fmt.Errorf("'%s' must be a number between 0 and 1", quantileParam), http.StatusBadRequest)
+		return
+	}
+	family, err := aH.metricsQueryService.GetLatencies(r.Context(), &metricsstore.LatenciesQueryParameters{
+		BaseQueryParameters: params,
+		Quantile:            quantile,
+	})
+	aH.writeMetrics(w, r, family, err)
+}
+
+// getCallRates implements the REST API /metrics/calls.
+func (aH *APIHandler) getCallRates(w http.ResponseWriter, r *http.Request) {
+	params, err := aH.queryParser.parseMetricsQueryParams(r)
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	family, err := aH.metricsQueryService.GetCallRates(r.Context(), &metricsstore.CallRateQueryParameters{
+		BaseQueryParameters: params,
+	})
+	aH.writeMetrics(w, r, family, err)
+}
+
+// getErrorRates implements the REST API /metrics/errors.
+func (aH *APIHandler) getErrorRates(w http.ResponseWriter, r *http.Request) {
+	params, err := aH.queryParser.parseMetricsQueryParams(r)
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	family, err := aH.metricsQueryService.GetErrorRates(r.Context(), &metricsstore.ErrorRateQueryParameters{
+		BaseQueryParameters: params,
+	})
+	aH.writeMetrics(w, r, family, err)
+}
+
+// getMinStep implements the REST API /metrics/minstep.
+// It returns the smallest step, in milliseconds, supported by the metrics storage.
+func (aH *APIHandler) getMinStep(w http.ResponseWriter, r *http.Request) {
+	minStep, err := aH.metricsQueryService.GetMinStepDuration(r.Context(), &metricsstore.MinStepDurationQueryParameters{})
+	if aH.handleMetricsError(w, err) {
+		return
+	}
+	structuredRes := structuredResponse{
+		Data: minStep.Milliseconds(),
+	}
+	aH.writeJSON(w, r, &structuredRes)
+}
+
+func (aH *APIHandler) writeMetrics(w http.ResponseWriter, r *http.Request, family *metricsstore.MetricFamily, err error) {
+	if aH.handleMetricsError(w, err) {
+		return
+	}
+	structuredRes := structuredResponse{
+		Data: family,
+	}
+	aH.writeJSON(w, r, &structuredRes)
+}
+
+// handleMetricsError reports the queries of the span metrics as not implemented when no metrics storage is configured.
+func (aH *APIHandler) handleMetricsError(w http.ResponseWriter, err error) bool {
+	if err == metricsstore.ErrDisabled {
+		return aH.handleError(w, err, http.StatusNotImplemented)
+	}
+	return aH.handleError(w, err, http.StatusInternalServerError)
+}
+
+func (aH *APIHandler) convertModelToUI(trace *model.Trace, adjust bool) (*ui.Trace, *structuredError) {
+	var errors []error
+	if adjust {
+		var err error
+		trace, err = aH.queryService.Adjust(trace)
+		if err != nil {
+			errors = append(errors, err)
+		}
+	}
+	uiTrace := uiconv.FromDomain(trace)
+	var uiError *structuredError
+	if err := multierror.Wrap(errors); err != nil {
+		uiError = &structuredError{
+			Msg:     err.Error(),
+			TraceID: uiTrace.TraceID,
+		}
+	}
+	return uiTrace, uiError
+}
+
+// parseDependenciesPeriod returns the end and the length of the period given by the endTs and lookback parameters.
+func parseDependenciesPeriod(r *http.Request) (time.Time, time.Duration, error) {
+	endTsMillis, err := strconv.ParseInt(r.FormValue(endTsParam), 10, 64)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("unable to parse %s: %w", endTimeParam, err)
+	}
+	var lookback time.Duration
+	if formValue := r.FormValue(lookbackParam); len(formValue) > 0 {
+		lookback, err = time.ParseDuration(formValue + "ms")
+		if err != nil {
+			return time.Time{}, 0, fmt.Errorf("unable to parse %s: %w", lookbackParam, err)
+		}
+	}
+	if lookback == 0 {
+		lookback = defaultDependencyLookbackDuration
+	}
+	return time.Unix(0, 0).Add(time.Duration(endTsMillis) * time.Millisecond), lookback, nil
+}
+
+func (aH *APIHandler) deduplicateDependencies(dependencies []model.DependencyLink) []ui.DependencyLink {
+	return deduplicateDependencyLinks(dependencies)
+}
+
+// deduplicateDependencyLinks sums the call counts of the links between the same services.
+func deduplicateDependencyLinks(dependencies []model.DependencyLink) []ui.DependencyLink {
+	type Key struct {
+		parent string
+		child  string
+	}
+	links := make(map[Key]uint64)
+
+	for _, l := range dependencies {
+		links[Key{l.Parent, l.Child}] += l.CallCount
+	}
+
+	result := make([]ui.DependencyLink, 0, len(links))
+	for k, v := range links {
+		result = append(result, ui.DependencyLink{Parent: k.parent, Child: k.child, CallCount: v})
+	}
+
+	return result
+}
+
+func (aH *APIHandler) filterDependenciesByService(
+	dependencies []model.DependencyLink,
+	service string,
+) []model.DependencyLink {
+	if len(service) == 0 {
+		return dependencies
+	}
+
+	var filteredDependencies []model.DependencyLink
+	for _, dependency := range dependencies {
+		if dependency.Parent == service || dependency.Child == service {
+			filteredDependencies = append(filteredDependencies, dependency)
+		}
+	}
+	return filteredDependencies
+}
+
+// Parses trace ID from URL like /traces/{trace-id}
+func (aH *APIHandler) parseTraceID(w http.ResponseWriter, r *http.Request) (model.TraceID, bool) {
+	vars := mux.Vars(r)
+	traceIDVar := vars[traceIDParam]
+	traceID, err := model.TraceIDFromString(traceIDVar)
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return traceID, false
+	}
+	return traceID, true
+}
+
+// getTrace implements the REST API /traces/{trace-id}
+// It parses trace ID from the path, fetches the trace from QueryService,
+// formats it in the UI JSON format, or in the OTLP or Zipkin format given by the format parameter,
+// and responds to the client. With mode=summary the spans of the UI JSON format have no logs and
+// only their status tags.
+func (aH *APIHandler) getTrace(w http.ResponseWriter, r *http.Request) {
+	traceID, ok := aH.parseTraceID(w, r)
+	if !ok {
+		return
+	}
+	format, err := parseFormat(r)
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	summary, err := parseMode(r)
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	if summary && format != formatJaeger {
+		aH.handleError(w, errSummaryFormat, http.StatusBadRequest)
+		return
+	}
+	trace, err := aH.queryService.GetTrace(r.Context(), traceID)
+	if err == spanstore.ErrTraceNotFound {
+		aH.handleError(w, err, http.StatusNotFound)
+		return
+	}
+	if aH.handleError(w, err, http.StatusInternalServerError) {
+		return
+	}
+	if format != formatJaeger {
+		aH.writeExportedTrace(w, r, trace, format)
+		return
+	}
+
+	var uiErrors []structuredError
+	uiTrace, uiErr := aH.convertModelToUI(trace, shouldAdjust(r))
+	if uiErr != nil {
+		uiErrors = append(uiErrors, *uiErr)
+	}
+	if summary {
+		stripSpanDetails(uiTrace)
+	}
+
+	structuredRes := structuredResponse{
+		Data: []*ui.Trace{
+			uiTrace,
+		},
+		Errors: uiErrors,
+	}
+	aH.writeTraceJSON(w, r, &structuredRes, trace)
+}
+
+// diffTraces implements the REST API /traces/{trace-id}/diff/{other-trace-id}.
+// It compares the structure and the durations of the spans of the other trace to the first one.
+func (aH *APIHandler) diffTraces(w http.ResponseWriter, r *http.Request) {
+	traceID, ok := aH.parseTraceID(w, r)
+	if !ok {
+		return
+	}
+	otherTraceID, err := model.TraceIDFromString(mux.Vars(r)[otherTraceIDParam])
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	var uiErrors []structuredError
+	traces := make([]*model.Trace, 2)
+	for i, id := range []model.TraceID{traceID, otherTraceID} {
+		trace, err := aH.queryService.GetTrace(r.Context(), id)
+		if err == spanstore.ErrTraceNotFound {
+			aH.handleError(w, fmt.Errorf("trace %v: %w", id, err), http.StatusNotFound)
+			return
+		}
+		if aH.handleError(w, err, http.StatusInternalServerError) {
+			return
+		}
+		if shouldAdjust(r) {
+			if trace, err = aH.queryService.Adjust(trace); err != nil {
+				uiErrors = append(uiErrors, structuredError{Msg: err.Error(), TraceID: ui.TraceID(id.String())})
+			}
+		}
+		traces[i] = trace
+	}
+	structuredRes := structuredResponse{
+		Data:   diffTraces(traces[0], traces[1]),
+		Errors: uiErrors,
+	}
+	aH.writeJSON(w, r, &structuredRes)
+}
+
+// criticalPath implements the REST API /traces/{trace-id}/critical-path.
+// It returns the chain of spans that determined the latency of the trace.
+func (aH *APIHandler) criticalPath(w http.ResponseWriter, r *http.Request) {
+	traceID, ok := aH.parseTraceID(w, r)
+	if !ok {
+		return
+	}
+	trace, err := aH.queryService.GetTrace(r.Context(), traceID)
+	if err == spanstore.ErrTraceNotFound {
+		aH.handleError(w, err, http.StatusNotFound)
+		return
+	}
+	if aH.handleError(w, err, http.StatusInternalServerError) {
+		return
+	}
+
+	var uiErrors []structuredError
+	if shouldAdjust(r) {
+		if trace, err = aH.queryService.Adjust(trace); err != nil {
+			uiErrors = append(uiErrors, structuredError{Msg: err.Error(), TraceID: ui.TraceID(traceID.String())})
+		}
+	}
+
+	structuredRes := structuredResponse{
+		Data:   criticalPath(trace),
+		Errors: uiErrors,
+	}
+	aH.writeJSON(w, r, &structuredRes)
+}
+
+func shouldAdjust(r *http.Request) bool {
+	raw := r.FormValue("raw")
+	isRaw, _ := strconv.ParseBool(raw)
+	return !isRaw
+}
+
+// archiveTrace implements the REST API POST:/archive/{trace-id}.
+// It passes the traceID to queryService.ArchiveTrace for writing.
+func (aH *APIHandler) archiveTrace(w http.ResponseWriter, r *http.Request) {
+	traceID, ok := aH.parseTraceID(w, r)
+	if !ok {
+		return
+	}
+
+	// QueryService.ArchiveTrace can now archive this traceID.
+	err := aH.queryService.ArchiveTrace(r.Context(), traceID)
+	if err == spanstore.ErrTraceNotFound {
+		aH.handleError(w, err, http.StatusNotFound)
+		return
+	}
+	if aH.handleError(w, err, http.StatusInternalServerError) {
+		return
+	}
+
+	structuredRes := structuredResponse{
+		Data:   []string{}, // doesn't matter, just want an empty array
+		Errors: []structuredError{},
+	}
+	aH.writeJSON(w, r, &structuredRes)
+}
+
+func (aH *APIHandler) handleError(w http.ResponseWriter, err error, statusCode int) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, querysvc.ErrForbidden) {
+		statusCode = http.StatusForbidden
+	}
+	if errors.Is(err, querysvc.ErrLimitExceeded) {
+		statusCode = http.StatusBadRequest
+	}
+	if errors.Is(err, querysvc.ErrQueryTimeout) {
+		statusCode = http.StatusGatewayTimeout
+	}
+	if statusCode == http.StatusInternalServerError {
+		aH.logger.Error("HTTP handler, Internal Server Error", zap.Error(err))
+	}
+	structuredResp := structuredResponse{
+		Errors: []structuredError{
+			{
+				Code: statusCode,
+				Msg:  err.Error(),
+			},
+		},
+	}
+	resp, _ := json.Marshal(&structuredResp)
+	http.Error(w, string(resp), statusCode)
+	return true
+}
+
+func (aH *APIHandler) writeJSON(w http.ResponseWriter, r *http.Request, response interface{}) {
+	resp := marshalJSON(r, response)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// marshalJSON returns the JSON of the response, indented if the request asks for it.
+func marshalJSON(r *http.Request, response interface{}) []byte {
+	marshall := json.Marshal
+	if prettyPrint := r.FormValue(prettyPrintParam); prettyPrint != "" && prettyPrint != "false" {
+		marshall = func(v interface{}) ([]byte, error) {
+			return json.MarshalIndent(v, "", "    ")
+		}
+	}
+	resp, _ := marshall(response)
+	return resp
+}
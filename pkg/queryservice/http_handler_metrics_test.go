@@ -0,0 +1,163 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
+	metricsmocks "github.com/jaegertracing/jaeger/storage/metricsstore/mocks"
+)
+
+var (
+	metricsTestNow = time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	metricsTestFamily = &metricsstore.MetricFamily{
+		Name: "service_call_rate",
+		Type: metricsstore.MetricTypeGauge,
+		Metrics: []*metricsstore.Metric{
+			{
+				Labels:       []metricsstore.Label{{Name: "service_name", Value: "frontend"}},
+				MetricPoints: []metricsstore.MetricPoint{{Timestamp: metricsTestNow, Value: 1.5}},
+			},
+		},
+	}
+)
+
+func withMetricsTestServer(t *testing.T, doTest func(s *testServer, reader *metricsmocks.Reader)) {
+	reader := &metricsmocks.Reader{}
+	withTestServer(t, func(ts *testServer) {
+		ts.handler.queryParser.timeNow = func() time.Time { return metricsTestNow }
+		doTest(ts, reader)
+	}, querysvc.QueryServiceOptions{}, HandlerOptions.MetricsQueryService(reader))
+}
+
+func TestGetLatencies(t *testing.T) {
+	withMetricsTestServer(t, func(ts *testServer, reader *metricsmocks.Reader) {
+		expected := &metricsstore.LatenciesQueryParameters{
+			BaseQueryParameters: metricsstore.BaseQueryParameters{
+				ServiceNames:     []string{"frontend", "driver"},
+				GroupByOperation: true,
+				SpanKinds:        []string{"server", "client"},
+				EndTime:          time.Unix(1590000000, 0),
+				Lookback:         30 * time.Minute,
+				Step:             30 * time.Second,
+				RatePer:          5 * time.Minute,
+			},
+			Quantile: 0.95,
+		}
+		reader.On("GetLatencies", mock.Anything, expected).Return(metricsTestFamily, nil).Once()
+
+		var response structuredResponse
+		require.NoError(t, getJSON(ts.server.URL+"/api/metrics/latencies?service=frontend&service=driver&quantile=0.95"+
+			"&groupByOperation=true&spanKind=server&spanKind=client&endTs=1590000000000&lookback=1800000&step=30000&ratePer=300000", &response))
+		data := response.Data.(map[string]interface{})
+		assert.Equal(t, "service_call_rate", data["name"])
+		assert.Len(t, data["metrics"], 1)
+		reader.AssertExpectations(t)
+	})
+}
+
+func TestGetCallRatesDefaults(t *testing.T) {
+	withMetricsTestServer(t, func(ts *testServer, reader *metricsmocks.Reader) {
+		expected := &metricsstore.CallRateQueryParameters{
+			BaseQueryParameters: metricsstore.BaseQueryParameters{
+				ServiceNames: []string{"frontend"},
+				SpanKinds:    []string{"server"},
+				EndTime:      metricsTestNow,
+				Lookback:     time.Hour,
+				Step:         time.Minute,
+				RatePer:      10 * time.Minute,
+			},
+		}
+		reader.On("GetCallRates", mock.Anything, expected).Return(metricsTestFamily, nil).Once()
+
+		var response structuredResponse
+		require.NoError(t, getJSON(ts.server.URL+"/api/metrics/calls?service=frontend", &response))
+		reader.AssertExpectations(t)
+	})
+}
+
+func TestGetErrorRates(t *testing.T) {
+	withMetricsTestServer(t, func(ts *testServer, reader *metricsmocks.Reader) {
+		reader.On("GetErrorRates", mock.Anything, mock.AnythingOfType("*metricsstore.ErrorRateQueryParameters")).
+			Return(metricsTestFamily, nil).Once()
+		reader.On("GetErrorRates", mock.Anything, mock.AnythingOfType("*metricsstore.ErrorRateQueryParameters")).
+			Return(nil, errors.New("storage error")).Once()
+
+		var response structuredResponse
+		require.NoError(t, getJSON(ts.server.URL+"/api/metrics/errors?service=frontend", &response))
+		err := getJSON(ts.server.URL+"/api/metrics/errors?service=frontend", &response)
+		assert.EqualError(t, err, parsedError(500, "storage error"))
+	})
+}
+
+func TestGetMinStep(t *testing.T) {
+	withMetricsTestServer(t, func(ts *testServer, reader *metricsmocks.Reader) {
+		reader.On("GetMinStepDuration", mock.Anything, &metricsstore.MinStepDurationQueryParameters{}).
+			Return(5*time.Millisecond, nil).Once()
+
+		var response structuredResponse
+		require.NoError(t, getJSON(ts.server.URL+"/api/metrics/minstep", &response))
+		assert.Equal(t, float64(5), response.Data)
+	})
+}
+
+func TestMetricsQueryErrors(t *testing.T) {
+	testCases := []struct {
+		url string
+		err string
+	}{
+		{url: "/api/metrics/calls", err: "parameter 'service' is required"},
+		{url: "/api/metrics/calls?service=a&groupByOperation=x", err: `unable to parse groupByOperation: strconv.ParseBool: parsing \"x\": invalid syntax`},
+		{url: "/api/metrics/calls?service=a&endTs=x", err: `unable to parse endTs: strconv.ParseInt: parsing \"x\": invalid syntax`},
+		{url: "/api/metrics/errors?service=a&lookback=x", err: `unable to parse lookback: strconv.ParseInt: parsing \"x\": invalid syntax`},
+		{url: "/api/metrics/errors?service=a&step=0", err: "'step' must be a positive number of milliseconds"},
+		{url: "/api/metrics/latencies?service=a&ratePer=-1&quantile=0.5", err: "'ratePer' must be a positive number of milliseconds"},
+		{url: "/api/metrics/latencies?service=a", err: "'quantile' must be a number between 0 and 1"},
+		{url: "/api/metrics/latencies?service=a&quantile=2", err: "'quantile' must be a number between 0 and 1"},
+	}
+	withMetricsTestServer(t, func(ts *testServer, reader *metricsmocks.Reader) {
+		for _, test := range testCases {
+			t.Run(test.url, func(t *testing.T) {
+				var response structuredResponse
+				err := getJSON(ts.server.URL+test.url, &response)
+				assert.EqualError(t, err, parsedError(400, test.err))
+			})
+		}
+	})
+}
+
+func TestMetricsQueryDisabled(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		for _, url := range []string{
+			"/api/metrics/latencies?service=a&quantile=0.5",
+			"/api/metrics/calls?service=a",
+			"/api/metrics/errors?service=a",
+			"/api/metrics/minstep",
+		} {
+			var response structuredResponse
+			err := getJSON(ts.server.URL+url, &response)
+			assert.EqualError(t, err, parsedError(501, metricsstore.ErrDisabled.Error()), url)
+		}
+	}, querysvc.QueryServiceOptions{})
+}
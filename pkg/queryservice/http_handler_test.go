@@ -13,10 +13,11 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package app
+package queryservice
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -36,10 +37,11 @@ import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
-	"github.com/jaegertracing/jaeger/cmd/query/app/querysvc"
 	"github.com/jaegertracing/jaeger/model"
 	"github.com/jaegertracing/jaeger/model/adjuster"
 	ui "github.com/jaegertracing/jaeger/model/json"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
 	depsmocks "github.com/jaegertracing/jaeger/storage/dependencystore/mocks"
 	"github.com/jaegertracing/jaeger/storage/spanstore"
 	spanstoremocks "github.com/jaegertracing/jaeger/storage/spanstore/mocks"
@@ -91,7 +93,7 @@ func initializeTestServerWithHandler(queryOptions querysvc.QueryServiceOptions,
 			[]HandlerOption{
 				HandlerOptions.Logger(zap.NewNop()),
 				// add options for test coverage
-				HandlerOptions.Prefix(defaultAPIPrefix),
+				HandlerOptions.Prefix(DefaultAPIPrefix),
 				HandlerOptions.BasePath("/"),
 				HandlerOptions.QueryLookbackDuration(defaultTraceQueryLookbackDuration),
 			},
@@ -339,6 +341,29 @@ func TestSearchSuccess(t *testing.T) {
 	assert.Len(t, response.Errors, 0)
 }
 
+func TestSearchPages(t *testing.T) {
+	server, readMock, _ := initializeTestServer()
+	defer server.Close()
+	otherTrace := &model.Trace{Spans: []*model.Span{{TraceID: model.NewTraceID(0, 0x456), SpanID: model.NewSpanID(1), Process: &model.Process{}}}}
+	readMock.On("FindTraces", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+		Return([]*model.Trace{mockTrace, otherTrace}, nil)
+
+	var response structuredResponse
+	err := getJSON(server.URL+`/api/traces?service=service&limit=1`, &response)
+	require.NoError(t, err)
+	assert.Len(t, response.Data, 1)
+	require.NotEmpty(t, response.NextPageToken)
+
+	var next structuredResponse
+	err = getJSON(server.URL+`/api/traces?service=service&limit=1&pageToken=`+response.NextPageToken, &next)
+	require.NoError(t, err)
+	assert.Len(t, next.Data, 1)
+	assert.Empty(t, next.NextPageToken)
+
+	err = getJSON(server.URL+`/api/traces?service=service&limit=1&pageToken=!`, &response)
+	assert.EqualError(t, err, parsedError(400, querysvc.ErrInvalidPageToken.Error()))
+}
+
 func TestSearchByTraceIDSuccess(t *testing.T) {
 	server, readMock, _ := initializeTestServer()
 	defer server.Close()
@@ -424,6 +449,23 @@ func TestSearchDBFailure(t *testing.T) {
 	assert.EqualError(t, err, parsedError(500, "whatsamattayou"))
 }
 
+func TestSearchLimits(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("FindTraces", mock.AnythingOfType("*context.timerCtx"), mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+			Run(func(args mock.Arguments) {
+				<-args.Get(0).(context.Context).Done()
+			}).Return(nil, context.DeadlineExceeded)
+
+		var response structuredResponse
+		err := getJSON(ts.server.URL+`/api/traces?service=service&limit=20`, &response)
+		assert.EqualError(t, err, parsedError(400, "query limit exceeded: 20 results requested, more than the maximum of 10"))
+		err = getJSON(ts.server.URL+`/api/traces?service=service&limit=10`, &response)
+		assert.EqualError(t, err, parsedError(504, "query timed out: the search did not complete within 1ms"))
+	}, querysvc.QueryServiceOptions{
+		Limits: querysvc.QueryLimits{FindTraces: querysvc.Limits{MaxResults: 10, Timeout: time.Millisecond}},
+	})
+}
+
 func TestSearchFailures(t *testing.T) {
 	tests := []struct {
 		urlStr string
@@ -617,3 +659,64 @@ func execJSON(req *http.Request, out interface{}) error {
 func parsedError(code int, err string) string {
 	return fmt.Sprintf(`%d error from server: {"data":null,"total":0,"limit":0,"offset":0,"errors":[{"code":%d,"msg":"%s"}]}`+"\n", code, code, err)
 }
+
+type denyAllAuthorizer struct{}
+
+func (denyAllAuthorizer) IsAllowed(ctx context.Context, service string) bool {
+	return false
+}
+
+func TestForbiddenService(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("model.TraceID")).
+			Return(mockTrace, nil).Once()
+
+		var response structuredResponse
+		err := getJSON(ts.server.URL+`/api/traces/123456`, &response)
+		assert.EqualError(t, err, "403 error from server: "+
+			`{"data":null,"total":0,"limit":0,"offset":0,"errors":[{"code":403,"msg":"not authorized to read the spans of the service"}]}`+"\n")
+
+		err = getJSON(ts.server.URL+`/api/operations?service=abc`, &response)
+		assert.Contains(t, err.Error(), "403 error from server")
+	}, querysvc.QueryServiceOptions{Authorizer: denyAllAuthorizer{}})
+}
+
+func TestTenantRequests(t *testing.T) {
+	tenancyMgr := tenancy.NewManager(&tenancy.Options{Enabled: true, Tenants: []string{"acme"}})
+	withTestServer(t, func(ts *testServer) {
+		span := &model.Span{TraceID: mockTraceID, SpanID: model.NewSpanID(1), Process: &model.Process{}}
+		tenancy.SetSpanTenant(span, "acme")
+		ts.spanReader.On("FindTraces", mock.AnythingOfType("*context.valueCtx"), mock.MatchedBy(func(query *spanstore.TraceQueryParameters) bool {
+			return query.Tags[tenancy.TenantTag] == "acme"
+		})).Return([]*model.Trace{{Spans: []*model.Span{span}}}, nil).Once()
+
+		req, err := http.NewRequest(http.MethodGet, ts.server.URL+"/api/traces?service=service", nil)
+		require.NoError(t, err)
+		req.Header.Set(tenancy.DefaultHeader, "acme")
+		var response structuredResponse
+		require.NoError(t, execJSON(req, &response))
+		assert.Len(t, response.Data, 1)
+
+		err = getJSON(ts.server.URL+"/api/traces?service=service", &response)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "401 error from server")
+		}
+		req.Header.Set(tenancy.DefaultHeader, "megacorp")
+		err = execJSON(req, &response)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "403 error from server")
+		}
+
+		// the services of the shared storage are restricted to those with spans of the tenant
+		ts.spanReader.On("GetServices", mock.AnythingOfType("*context.valueCtx")).Return([]string{"service", "other"}, nil)
+		ts.spanReader.On("FindTraces", mock.AnythingOfType("*context.valueCtx"), mock.MatchedBy(func(query *spanstore.TraceQueryParameters) bool {
+			return query.ServiceName == "service" && query.Tags[tenancy.TenantTag] == "acme"
+		})).Return([]*model.Trace{{Spans: []*model.Span{span}}}, nil)
+		ts.spanReader.On("FindTraces", mock.AnythingOfType("*context.valueCtx"), mock.Anything).Return(nil, nil)
+		req, err = http.NewRequest(http.MethodGet, ts.server.URL+"/api/services", nil)
+		require.NoError(t, err)
+		req.Header.Set(tenancy.DefaultHeader, "acme")
+		require.NoError(t, execJSON(req, &response))
+		assert.Equal(t, []interface{}{"service"}, response.Data)
+	}, querysvc.QueryServiceOptions{}, HandlerOptions.Tenancy(tenancyMgr))
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+const (
+	defaultHeatmapTraceLimit = 1000
+	maxHeatmapColumns        = 1000
+)
+
+// defaultHeatmapBounds are the upper bounds, in milliseconds, of the latency buckets of the heatmaps
+// computed from the spans.
+var defaultHeatmapBounds = []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 2000, 5000, 10000}
+
+// latencyHeatmap is returned by /metrics/heatmap. The source tells whether the counts were read from
+// the metrics storage or computed from the stored spans. The bounds are the upper bounds of the latency
+// buckets in milliseconds, each column counting the spans of every bucket over a step, the last count
+// being the one of the spans slower than the last bound.
+type latencyHeatmap struct {
+	Source  string          `json:"source"`
+	Bounds  []float64       `json:"bounds"`
+	Columns []heatmapColumn `json:"columns"`
+}
+
+// heatmapColumn counts the spans of the step ending at the timestamp.
+type heatmapColumn struct {
+	Timestamp time.Time `json:"timestamp"`
+	Counts    []float64 `json:"counts"`
+}
+
+// getLatencyHeatmap implements the REST API /metrics/heatmap.
+// It returns the number of spans per latency bucket and per step of the services, or of one of
+// their operations, over the lookback period. The counts are read from the latency histograms of the
+// metrics storage if there is one, otherwise they are computed from the spans of the most recent
+// traces, up to limit traces per service.
+func (aH *APIHandler) getLatencyHeatmap(w http.ResponseWriter, r *http.Request) {
+	params, err := aH.queryParser.parseMetricsQueryParams(r)
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	if params.Lookback/params.Step >= maxHeatmapColumns {
+		aH.handleError(w, fmt.Errorf("the heatmap cannot have more than %d steps", maxHeatmapColumns), http.StatusBadRequest)
+		return
+	}
+	limit, err := parsePositiveInt(r, limitParam, defaultHeatmapTraceLimit)
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	// the operation is filtered rather than grouped by
+	params.GroupByOperation = false
+	bucketsParams := &metricsstore.LatencyBucketsQueryParameters{
+		BaseQueryParameters: params,
+		OperationName:       r.FormValue(operationParam),
+	}
+
+	heatmap, err := aH.heatmapFromMetrics(r.Context(), bucketsParams)
+	if err == metricsstore.ErrDisabled {
+		heatmap, err = aH.heatmapFromSpans(r.Context(), bucketsParams, limit)
+	}
+	if aH.handleError(w, err, http.StatusInternalServerError) {
+		return
+	}
+	aH.writeJSON(w, r, &structuredResponse{Data: heatmap})
+}
+
+func (aH *APIHandler) heatmapFromMetrics(ctx context.Context, params *metricsstore.LatencyBucketsQueryParameters) (*latencyHeatmap, error) {
+	family, err := aH.metricsQueryService.GetLatencyBuckets(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	// the cumulative counts of the services summed by timestamp and bound
+	cumulative := make(map[time.Time]map[float64]float64)
+	bounds := make(map[float64]bool)
+	for _, metric := range family.Metrics {
+		le, err := strconv.ParseFloat(labelValue(metric.Labels, "le"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid latency bucket: %w", err)
+		}
+		if !math.IsInf(le, 1) {
+			bounds[le] = true
+		}
+		for _, point := range metric.MetricPoints {
+			counts, ok := cumulative[point.Timestamp]
+			if !ok {
+				counts = make(map[float64]float64)
+				cumulative[point.Timestamp] = counts
+			}
+			counts[le] += point.Value
+		}
+	}
+
+	heatmap := &latencyHeatmap{Source: percentilesSourceMetrics, Bounds: make([]float64, 0, len(bounds))}
+	for bound := range bounds {
+		heatmap.Bounds = append(heatmap.Bounds, bound)
+	}
+	sort.Float64s(heatmap.Bounds)
+	allBounds := append(append([]float64{}, heatmap.Bounds...), math.Inf(1))
+	heatmap.Columns = make([]heatmapColumn, 0, len(cumulative))
+	for timestamp, counts := range cumulative {
+		column := heatmapColumn{Timestamp: timestamp, Counts: make([]float64, len(heatmap.Bounds)+1)}
+		var below float64
+		for i, bound := range allBounds {
+			// the extrapolation of the storage can make the difference slightly negative
+			column.Counts[i] = math.Max(counts[bound]-below, 0)
+			below = math.Max(counts[bound], below)
+		}
+		heatmap.Columns = append(heatmap.Columns, column)
+	}
+	sort.Slice(heatmap.Columns, func(i, j int) bool {
+		return heatmap.Columns[i].Timestamp.Before(heatmap.Columns[j].Timestamp)
+	})
+	return heatmap, nil
+}
+
+func (aH *APIHandler) heatmapFromSpans(ctx context.Context, params *metricsstore.LatencyBucketsQueryParameters, limit int) (*latencyHeatmap, error) {
+	start := params.EndTime.Add(-params.Lookback)
+	heatmap := &latencyHeatmap{
+		Source:  percentilesSourceSpans,
+		Bounds:  defaultHeatmapBounds,
+		Columns: make([]heatmapColumn, int((params.Lookback+params.Step-1)/params.Step)),
+	}
+	for i := range heatmap.Columns {
+		heatmap.Columns[i] = heatmapColumn{
+			Timestamp: start.Add(time.Duration(i+1) * params.Step).UTC(),
+			Counts:    make([]float64, len(heatmap.Bounds)+1),
+		}
+	}
+	services := make(map[string]bool, len(params.ServiceNames))
+	for _, service := range params.ServiceNames {
+		services[service] = true
+	}
+	kinds := make(map[string]bool, len(params.SpanKinds))
+	for _, kind := range params.SpanKinds {
+		kinds[kind] = true
+	}
+	// the traces of several of the services are returned by several searches
+	seen := make(map[model.TraceID]bool)
+
+	for _, service := range params.ServiceNames {
+		traces, err := aH.queryService.FindTraces(ctx, &spanstore.TraceQueryParameters{
+			ServiceName:   service,
+			OperationName: params.OperationName,
+			StartTimeMin:  start,
+			StartTimeMax:  params.EndTime,
+			NumTraces:     limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, trace := range traces {
+			if len(trace.Spans) == 0 || seen[trace.Spans[0].TraceID] {
+				continue
+			}
+			seen[trace.Spans[0].TraceID] = true
+			for _, span := range trace.Spans {
+				if span.Process == nil || !services[span.Process.ServiceName] {
+					continue
+				}
+				if params.OperationName != "" && span.OperationName != params.OperationName {
+					continue
+				}
+				if kind, _ := span.GetSpanKind(); !kinds[kind] {
+					continue
+				}
+				if span.StartTime.Before(start) || !span.StartTime.Before(params.EndTime) {
+					continue
+				}
+				column := heatmap.Columns[span.StartTime.Sub(start)/params.Step]
+				millis := float64(span.Duration) / float64(time.Millisecond)
+				column.Counts[sort.SearchFloat64s(heatmap.Bounds, millis)]++
+			}
+		}
+	}
+	return heatmap, nil
+}
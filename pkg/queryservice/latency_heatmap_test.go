@@ -0,0 +1,133 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
+	metricsmocks "github.com/jaegertracing/jaeger/storage/metricsstore/mocks"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+func parseHeatmap(t *testing.T, response structuredResponse) latencyHeatmap {
+	data, err := json.Marshal(response.Data)
+	require.NoError(t, err)
+	var heatmap latencyHeatmap
+	require.NoError(t, json.Unmarshal(data, &heatmap))
+	return heatmap
+}
+
+func TestLatencyHeatmapFromMetrics(t *testing.T) {
+	t1, t2 := metricsTestNow.Add(-time.Minute), metricsTestNow
+	bucket := func(le string, first, second float64) *metricsstore.Metric {
+		return &metricsstore.Metric{
+			Labels:       []metricsstore.Label{{Name: "le", Value: le}, {Name: "service_name", Value: "frontend"}},
+			MetricPoints: []metricsstore.MetricPoint{{Timestamp: t1, Value: first}, {Timestamp: t2, Value: second}},
+		}
+	}
+	withMetricsTestServer(t, func(ts *testServer, reader *metricsmocks.Reader) {
+		reader.On("GetLatencyBuckets", mock.Anything, &metricsstore.LatencyBucketsQueryParameters{
+			BaseQueryParameters: metricsstore.BaseQueryParameters{
+				ServiceNames: []string{"frontend"},
+				SpanKinds:    []string{"server"},
+				EndTime:      metricsTestNow,
+				Lookback:     2 * time.Minute,
+				Step:         time.Minute,
+				RatePer:      10 * time.Minute,
+			},
+			OperationName: "GET /",
+		}).Return(&metricsstore.MetricFamily{Metrics: []*metricsstore.Metric{
+			bucket("+Inf", 10, 4),
+			bucket("250", 6, 1.9),
+			bucket("100", 5, 2),
+		}}, nil).Once()
+
+		var response structuredResponse
+		require.NoError(t, getJSON(ts.server.URL+"/api/metrics/heatmap?service=frontend&operation=GET%20/&lookback=120000&step=60000&groupByOperation=true", &response))
+		assert.Equal(t, latencyHeatmap{
+			Source: percentilesSourceMetrics,
+			Bounds: []float64{100, 250},
+			Columns: []heatmapColumn{
+				{Timestamp: t1, Counts: []float64{5, 1, 4}},
+				{Timestamp: t2, Counts: []float64{2, 0, 2}},
+			},
+		}, parseHeatmap(t, response))
+
+		reader.On("GetLatencyBuckets", mock.Anything, mock.Anything).Return(&metricsstore.MetricFamily{Metrics: []*metricsstore.Metric{
+			{Labels: []metricsstore.Label{{Name: "le", Value: "x"}}},
+		}}, nil).Once()
+		err := getJSON(ts.server.URL+"/api/metrics/heatmap?service=frontend", &response)
+		assert.EqualError(t, err, parsedError(500, `invalid latency bucket: strconv.ParseFloat: parsing \"x\": invalid syntax`))
+
+		reader.On("GetLatencyBuckets", mock.Anything, mock.Anything).Return(nil, errStorage).Once()
+		err = getJSON(ts.server.URL+"/api/metrics/heatmap?service=frontend", &response)
+		assert.EqualError(t, err, parsedError(500, errStorageMsg))
+
+		err = getJSON(ts.server.URL+"/api/metrics/heatmap?service=frontend&lookback=3600000&step=1000", &response)
+		assert.EqualError(t, err, parsedError(400, "the heatmap cannot have more than 1000 steps"))
+		err = getJSON(ts.server.URL+"/api/metrics/heatmap", &response)
+		assert.EqualError(t, err, parsedError(400, "parameter 'service' is required"))
+	})
+}
+
+func TestLatencyHeatmapFromSpans(t *testing.T) {
+	traceID := model.NewTraceID(0, 0xa)
+	span := func(id uint64, service, operation, kind string, start, duration time.Duration) *model.Span {
+		s := diffSpan(traceID, id, 0, service, operation, 0, duration)
+		s.StartTime = metricsTestNow.Add(-start)
+		s.Tags = model.KeyValues{model.String("span.kind", kind)}
+		return s
+	}
+	trace := &model.Trace{Spans: []*model.Span{
+		span(1, "frontend", "GET /", "server", 90*time.Second, 3*time.Millisecond),
+		span(2, "frontend", "GET /", "server", 80*time.Second, 20*time.Millisecond),
+		span(3, "frontend", "GET /", "server", 30*time.Second, 20*time.Second),
+		// other operations, services, kinds or times are ignored
+		span(4, "frontend", "POST /", "server", 30*time.Second, time.Millisecond),
+		span(5, "mysql", "GET /", "server", 30*time.Second, time.Millisecond),
+		span(6, "frontend", "GET /", "client", 30*time.Second, time.Millisecond),
+		span(7, "frontend", "GET /", "server", 3*time.Minute, time.Millisecond),
+	}}
+
+	withTestServer(t, func(ts *testServer) {
+		ts.handler.queryParser.timeNow = func() time.Time { return metricsTestNow }
+		ts.spanReader.On("FindTraces", mock.AnythingOfType("*context.valueCtx"), &spanstore.TraceQueryParameters{
+			ServiceName:   "frontend",
+			OperationName: "GET /",
+			StartTimeMin:  metricsTestNow.Add(-2 * time.Minute),
+			StartTimeMax:  metricsTestNow,
+			NumTraces:     20,
+		}).Return([]*model.Trace{trace}, nil)
+
+		var response structuredResponse
+		require.NoError(t, getJSON(ts.server.URL+"/api/metrics/heatmap?service=frontend&operation=GET%20/&lookback=120000&step=60000&limit=20", &response))
+		heatmap := parseHeatmap(t, response)
+		assert.Equal(t, percentilesSourceSpans, heatmap.Source)
+		assert.Equal(t, defaultHeatmapBounds, heatmap.Bounds)
+		require.Len(t, heatmap.Columns, 2)
+		assert.Equal(t, metricsTestNow.Add(-time.Minute), heatmap.Columns[0].Timestamp)
+		assert.Equal(t, []float64{0, 0, 1, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0}, heatmap.Columns[0].Counts)
+		assert.Equal(t, []float64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}, heatmap.Columns[1].Counts)
+	}, querysvc.QueryServiceOptions{})
+}
@@ -0,0 +1,226 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+const (
+	percentilesSourceMetrics = "metrics"
+	percentilesSourceSpans   = "spans"
+
+	// the labels of the percentiles computed from the spans, the defaults of the Prometheus metrics storage
+	serviceNameLabel = "service_name"
+	operationLabel   = "operation"
+
+	defaultPercentilesTraceLimit = 1000
+)
+
+// percentiles are the quantiles returned by /metrics/percentiles.
+var percentiles = []float64{0.5, 0.95, 0.99}
+
+// latencyPercentilesResponse is returned by /metrics/percentiles. The source tells whether
+// the percentiles were read from the metrics storage or computed from the stored spans.
+type latencyPercentilesResponse struct {
+	Source      string               `json:"source"`
+	Percentiles []latencyPercentiles `json:"percentiles"`
+}
+
+// latencyPercentiles are the percentiles of the latency, in milliseconds, and the ratio of the
+// spans in error of a service, or of an operation of a service.
+type latencyPercentiles struct {
+	Labels     []metricsstore.Label `json:"labels"`
+	P50        float64              `json:"p50"`
+	P95        float64              `json:"p95"`
+	P99        float64              `json:"p99"`
+	ErrorRatio float64              `json:"errorRatio"`
+	// SpanCount is the number of spans the percentiles were computed from, when read from the spans
+	SpanCount int `json:"spanCount,omitempty"`
+}
+
+// getLatencyPercentiles implements the REST API /metrics/percentiles.
+// It returns the p50, p95 and p99 latencies and the error ratios of the services over the
+// lookback period. They are read from the metrics storage if there is one, otherwise they are
+// computed from the spans of the most recent traces, up to limit traces per service.
+func (aH *APIHandler) getLatencyPercentiles(w http.ResponseWriter, r *http.Request) {
+	params, err := aH.queryParser.parseMetricsQueryParams(r)
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	limit, err := parsePositiveInt(r, limitParam, defaultPercentilesTraceLimit)
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	// a single point covering the whole period
+	params.Step = params.Lookback
+	params.RatePer = params.Lookback
+
+	response := latencyPercentilesResponse{Source: percentilesSourceMetrics}
+	response.Percentiles, err = aH.percentilesFromMetrics(r.Context(), params)
+	if err == metricsstore.ErrDisabled {
+		response.Source = percentilesSourceSpans
+		response.Percentiles, err = aH.percentilesFromSpans(r.Context(), params, limit)
+	}
+	if aH.handleError(w, err, http.StatusInternalServerError) {
+		return
+	}
+	aH.writeJSON(w, r, &structuredResponse{Data: response})
+}
+
+func (aH *APIHandler) percentilesFromMetrics(ctx context.Context, params metricsstore.BaseQueryParameters) ([]latencyPercentiles, error) {
+	var results []latencyPercentiles
+	index := make(map[string]int)
+	// result returns the percentiles of the series with the given labels
+	result := func(labels []metricsstore.Label) *latencyPercentiles {
+		parts := make([]string, 0, len(labels))
+		for _, label := range labels {
+			parts = append(parts, label.Name+"="+label.Value)
+		}
+		key := strings.Join(parts, ",")
+		i, ok := index[key]
+		if !ok {
+			i = len(results)
+			index[key] = i
+			results = append(results, latencyPercentiles{Labels: labels})
+		}
+		return &results[i]
+	}
+
+	for i, quantile := range percentiles {
+		family, err := aH.metricsQueryService.GetLatencies(ctx, &metricsstore.LatenciesQueryParameters{
+			BaseQueryParameters: params,
+			Quantile:            quantile,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, metric := range family.Metrics {
+			if value, ok := lastValue(metric); ok {
+				*result(metric.Labels).percentile(i) = value
+			}
+		}
+	}
+	family, err := aH.metricsQueryService.GetErrorRates(ctx, &metricsstore.ErrorRateQueryParameters{
+		BaseQueryParameters: params,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, metric := range family.Metrics {
+		if value, ok := lastValue(metric); ok {
+			result(metric.Labels).ErrorRatio = value
+		}
+	}
+	return results, nil
+}
+
+func (aH *APIHandler) percentilesFromSpans(ctx context.Context, params metricsstore.BaseQueryParameters, limit int) ([]latencyPercentiles, error) {
+	type group struct {
+		labels    []metricsstore.Label
+		durations []time.Duration
+		errors    int
+	}
+	var groups []*group
+	index := make(map[string]*group)
+	kinds := make(map[string]bool, len(params.SpanKinds))
+	for _, kind := range params.SpanKinds {
+		kinds[kind] = true
+	}
+
+	for _, service := range params.ServiceNames {
+		traces, err := aH.queryService.FindTraces(ctx, &spanstore.TraceQueryParameters{
+			ServiceName:  service,
+			StartTimeMin: params.EndTime.Add(-params.Lookback),
+			StartTimeMax: params.EndTime,
+			NumTraces:    limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, trace := range traces {
+			for _, span := range trace.Spans {
+				if span.Process == nil || span.Process.ServiceName != service {
+					continue
+				}
+				if kind, _ := span.GetSpanKind(); !kinds[kind] {
+					continue
+				}
+				labels := []metricsstore.Label{{Name: serviceNameLabel, Value: service}}
+				key := service
+				if params.GroupByOperation {
+					labels = append([]metricsstore.Label{{Name: operationLabel, Value: span.OperationName}}, labels...)
+					key += "\x00" + span.OperationName
+				}
+				g, ok := index[key]
+				if !ok {
+					g = &group{labels: labels}
+					index[key] = g
+					groups = append(groups, g)
+				}
+				g.durations = append(g.durations, span.Duration)
+				if tag, ok := model.KeyValues(span.Tags).FindByKey("error"); ok && tag.AsString() == "true" {
+					g.errors++
+				}
+			}
+		}
+	}
+
+	results := make([]latencyPercentiles, 0, len(groups))
+	for _, g := range groups {
+		sort.Slice(g.durations, func(i, j int) bool { return g.durations[i] < g.durations[j] })
+		result := latencyPercentiles{
+			Labels:     g.labels,
+			ErrorRatio: float64(g.errors) / float64(len(g.durations)),
+			SpanCount:  len(g.durations),
+		}
+		for i, quantile := range percentiles {
+			*result.percentile(i) = nearestRank(g.durations, quantile)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// percentile returns the field holding the i-th quantile of percentiles.
+func (p *latencyPercentiles) percentile(i int) *float64 {
+	return []*float64{&p.P50, &p.P95, &p.P99}[i]
+}
+
+// nearestRank returns the nearest-rank quantile, in milliseconds, of the sorted durations.
+func nearestRank(sorted []time.Duration, quantile float64) float64 {
+	rank := int(math.Ceil(quantile*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return float64(sorted[rank]) / float64(time.Millisecond)
+}
+
+// lastValue returns the most recent value of the series.
+func lastValue(metric *metricsstore.Metric) (float64, bool) {
+	if len(metric.MetricPoints) == 0 {
+		return 0, false
+	}
+	return metric.MetricPoints[len(metric.MetricPoints)-1].Value, true
+}
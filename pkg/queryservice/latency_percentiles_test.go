@@ -0,0 +1,154 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
+	metricsmocks "github.com/jaegertracing/jaeger/storage/metricsstore/mocks"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+func parsePercentiles(t *testing.T, response structuredResponse) latencyPercentilesResponse {
+	data, err := json.Marshal(response.Data)
+	require.NoError(t, err)
+	var percentiles latencyPercentilesResponse
+	require.NoError(t, json.Unmarshal(data, &percentiles))
+	return percentiles
+}
+
+func metricFamily(value float64) *metricsstore.MetricFamily {
+	return &metricsstore.MetricFamily{
+		Metrics: []*metricsstore.Metric{{
+			Labels:       []metricsstore.Label{{Name: "service_name", Value: "frontend"}},
+			MetricPoints: []metricsstore.MetricPoint{{Timestamp: metricsTestNow, Value: value}},
+		}},
+	}
+}
+
+func TestLatencyPercentilesFromMetrics(t *testing.T) {
+	withMetricsTestServer(t, func(ts *testServer, reader *metricsmocks.Reader) {
+		for quantile, value := range map[float64]float64{0.5: 10, 0.95: 40, 0.99: 80} {
+			quantile := quantile
+			reader.On("GetLatencies", mock.Anything, mock.MatchedBy(func(params *metricsstore.LatenciesQueryParameters) bool {
+				return params.Quantile == quantile && params.Step == time.Hour && params.RatePer == time.Hour
+			})).Return(metricFamily(value), nil).Once()
+		}
+		reader.On("GetErrorRates", mock.Anything, mock.Anything).Return(metricFamily(0.25), nil).Once()
+
+		var response structuredResponse
+		require.NoError(t, getJSON(ts.server.URL+"/api/metrics/percentiles?service=frontend", &response))
+		assert.Equal(t, latencyPercentilesResponse{
+			Source: percentilesSourceMetrics,
+			Percentiles: []latencyPercentiles{{
+				Labels:     []metricsstore.Label{{Name: "service_name", Value: "frontend"}},
+				P50:        10,
+				P95:        40,
+				P99:        80,
+				ErrorRatio: 0.25,
+			}},
+		}, parsePercentiles(t, response))
+		reader.AssertExpectations(t)
+	})
+}
+
+func TestLatencyPercentilesFromMetricsFailure(t *testing.T) {
+	withMetricsTestServer(t, func(ts *testServer, reader *metricsmocks.Reader) {
+		reader.On("GetLatencies", mock.Anything, mock.Anything).Return(metricFamily(10), nil)
+		reader.On("GetErrorRates", mock.Anything, mock.Anything).Return(nil, errStorage).Once()
+
+		var response structuredResponse
+		err := getJSON(ts.server.URL+"/api/metrics/percentiles?service=frontend", &response)
+		assert.EqualError(t, err, parsedError(500, errStorageMsg))
+	})
+}
+
+func TestLatencyPercentilesFromSpans(t *testing.T) {
+	traceID := model.NewTraceID(0, 1)
+	var spans []*model.Span
+	for i := 1; i <= 100; i++ {
+		operation := "GET /"
+		if i%2 == 0 {
+			operation = "POST /"
+		}
+		span := diffSpan(traceID, uint64(i), 0, "frontend", operation, 0, time.Duration(i)*time.Millisecond)
+		span.Tags = model.KeyValues{model.String("span.kind", "server")}
+		if i%10 == 0 {
+			span.Tags = append(span.Tags, model.Bool("error", true))
+		}
+		spans = append(spans, span)
+	}
+	// spans of other services or kinds are ignored
+	client := diffSpan(traceID, 101, 0, "frontend", "GET /", 0, time.Second)
+	client.Tags = model.KeyValues{model.String("span.kind", "client")}
+	spans = append(spans, client, diffSpan(traceID, 102, 0, "mysql", "SELECT", 0, time.Second))
+
+	withTestServer(t, func(ts *testServer) {
+		ts.handler.queryParser.timeNow = func() time.Time { return metricsTestNow }
+		ts.spanReader.On("FindTraces", mock.AnythingOfType("*context.valueCtx"), &spanstore.TraceQueryParameters{
+			ServiceName:  "frontend",
+			StartTimeMin: metricsTestNow.Add(-time.Hour),
+			StartTimeMax: metricsTestNow,
+			NumTraces:    20,
+		}).Return([]*model.Trace{{Spans: spans}}, nil)
+
+		var response structuredResponse
+		require.NoError(t, getJSON(ts.server.URL+"/api/metrics/percentiles?service=frontend&limit=20", &response))
+		assert.Equal(t, latencyPercentilesResponse{
+			Source: percentilesSourceSpans,
+			Percentiles: []latencyPercentiles{{
+				Labels:     []metricsstore.Label{{Name: "service_name", Value: "frontend"}},
+				P50:        50,
+				P95:        95,
+				P99:        99,
+				ErrorRatio: 0.1,
+				SpanCount:  100,
+			}},
+		}, parsePercentiles(t, response))
+
+		require.NoError(t, getJSON(ts.server.URL+"/api/metrics/percentiles?service=frontend&limit=20&groupByOperation=true", &response))
+		percentiles := parsePercentiles(t, response).Percentiles
+		require.Len(t, percentiles, 2)
+		assert.Equal(t, []metricsstore.Label{{Name: "operation", Value: "GET /"}, {Name: "service_name", Value: "frontend"}}, percentiles[0].Labels)
+		assert.Equal(t, 49.0, percentiles[0].P50)
+		assert.Equal(t, 0.0, percentiles[0].ErrorRatio)
+		assert.Equal(t, 50, percentiles[1].SpanCount)
+		assert.Equal(t, 0.2, percentiles[1].ErrorRatio)
+	}, querysvc.QueryServiceOptions{})
+}
+
+func TestLatencyPercentilesErrors(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("FindTraces", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+			Return(nil, errStorage)
+
+		var response structuredResponse
+		err := getJSON(ts.server.URL+"/api/metrics/percentiles", &response)
+		assert.EqualError(t, err, parsedError(400, ErrServiceParameterRequired.Error()))
+		err = getJSON(ts.server.URL+"/api/metrics/percentiles?service=frontend&limit=0", &response)
+		assert.EqualError(t, err, parsedError(400, "limit must be positive"))
+		err = getJSON(ts.server.URL+"/api/metrics/percentiles?service=frontend", &response)
+		assert.EqualError(t, err, parsedError(500, errStorageMsg))
+	}, querysvc.QueryServiceOptions{})
+}
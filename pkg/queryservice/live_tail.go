@@ -0,0 +1,213 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+
+	"github.com/jaegertracing/jaeger/model"
+	ui "github.com/jaegertracing/jaeger/model/json"
+	"github.com/jaegertracing/jaeger/pkg/cache"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+const (
+	DefaultLiveTailPollInterval = 5 * time.Second
+	DefaultLiveTailQuietPeriod  = 10 * time.Second
+	// maxLiveTailLookback bounds the time range searched by each poll of a long running live tail
+	maxLiveTailLookback = 10 * time.Minute
+	// maxLiveTailSentTraces is the number of sent trace IDs remembered not to send them twice
+	maxLiveTailSentTraces = 10000
+	// maxLiveTailPages bounds the pages of traces read by each poll, the client is told when it is reached
+	maxLiveTailPages = 10
+)
+
+var errLiveTailTraceIDs = errors.New("the live tail does not accept trace IDs")
+
+// liveTailTruncated is sent to the client when a poll matched more traces than it could read
+const liveTailTruncated = "the live tail search matched more traces than a poll reads, some traces were not sent; " +
+	"narrow the search or raise its limit"
+
+type liveTailOptions struct {
+	pollInterval time.Duration
+	// quietPeriod is how long a trace must not have received spans to be considered complete
+	quietPeriod time.Duration
+
+	// allowedOrigins are the origins, e.g. https://dashboard.example.com, of the web pages allowed to connect
+	// besides the ones served by the query service
+	allowedOrigins []string
+}
+
+// checkOrigin rejects the connections opened by the web pages of other sites, which would otherwise read the
+// traces with the cookies of the user. The clients which are not browsers send no origin and are accepted.
+func (o liveTailOptions) checkOrigin(config *websocket.Config, r *http.Request) error {
+	origin, err := websocket.Origin(config, r)
+	if err != nil {
+		return err
+	}
+	if origin == nil || strings.EqualFold(origin.Host, r.Host) {
+		return nil
+	}
+	for _, allowed := range o.allowedOrigins {
+		if strings.EqualFold(strings.TrimSuffix(strings.TrimSpace(allowed), "/"), origin.Scheme+"://"+origin.Host) {
+			return nil
+		}
+	}
+	return fmt.Errorf("origin %s not allowed", origin)
+}
+
+// tailTraces implements the WebSocket API /traces/live. It accepts the parameters of the search,
+// and pushes the new traces matching them as they complete, without the traces found before.
+func (aH *APIHandler) tailTraces(w http.ResponseWriter, r *http.Request) {
+	tQuery, err := aH.queryParser.parse(r)
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	if len(tQuery.traceIDs) > 0 {
+		aH.handleError(w, errLiveTailTraceIDs, http.StatusBadRequest)
+		return
+	}
+	connectedAt := aH.queryParser.timeNow()
+	server := websocket.Server{
+		Handshake: aH.liveTail.checkOrigin,
+		Handler: func(ws *websocket.Conn) {
+			aH.streamTraces(ws, tQuery, connectedAt)
+		},
+	}
+	server.ServeHTTP(w, r)
+}
+
+func (aH *APIHandler) streamTraces(ws *websocket.Conn, tQuery *traceQueryParameters, connectedAt time.Time) {
+	ctx, cancel := context.WithCancel(ws.Request().Context())
+	defer cancel()
+	go func() {
+		// the client does not send messages, reading only returns when the connection is closed
+		io.Copy(ioutil.Discard, ws)
+		cancel()
+	}()
+
+	sent := cache.NewLRU(maxLiveTailSentTraces)
+	ticker := time.NewTicker(aH.liveTail.pollInterval)
+	defer ticker.Stop()
+	for {
+		if err := aH.sendCompletedTraces(ctx, ws, tQuery, connectedAt, sent); err != nil {
+			if ctx.Err() == nil {
+				aH.logger.Debug("Live tail stopped", zap.Error(err))
+			}
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sendCompletedTraces searches the traces that started after the connection, or shortly before, and sends
+// the ones not sent yet that look complete. The search is read page by page, as many traces as the limit
+// of the query at a time, and the client is told when some traces could not be read. It only returns an
+// error when the message cannot be sent.
+func (aH *APIHandler) sendCompletedTraces(
+	ctx context.Context,
+	ws *websocket.Conn,
+	tQuery *traceQueryParameters,
+	connectedAt time.Time,
+	sent *cache.LRU,
+) error {
+	now := aH.queryParser.timeNow()
+	query := tQuery.TraceQueryParameters
+	query.StartTimeMin = connectedAt.Add(-aH.liveTail.quietPeriod)
+	if lookback := now.Add(-maxLiveTailLookback); query.StartTimeMin.Before(lookback) {
+		query.StartTimeMin = lookback
+	}
+	query.StartTimeMax = now
+	traces, truncated, err := aH.findLiveTailTraces(ctx, &query)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		aH.logger.Error("Live tail search failed", zap.Error(err))
+		return websocket.JSON.Send(ws, &structuredResponse{
+			Errors: []structuredError{{Code: http.StatusInternalServerError, Msg: err.Error()}},
+		})
+	}
+
+	uiTraces := []*ui.Trace{}
+	var uiErrors []structuredError
+	if truncated {
+		uiErrors = append(uiErrors, structuredError{Msg: liveTailTruncated})
+	}
+	completedBefore := now.Add(-aH.liveTail.quietPeriod)
+	for _, trace := range traces {
+		traceID := string(traceIDOf(trace))
+		if traceID == "" || sent.Get(traceID) != nil || traceEndTime(trace).After(completedBefore) {
+			continue
+		}
+		sent.Put(traceID, true)
+		uiTrace, uiErr := aH.convertModelToUI(trace, true)
+		if uiErr != nil {
+			uiErrors = append(uiErrors, *uiErr)
+		}
+		uiTraces = append(uiTraces, uiTrace)
+	}
+	if len(uiTraces) == 0 && len(uiErrors) == 0 {
+		return nil
+	}
+	return websocket.JSON.Send(ws, &structuredResponse{Data: uiTraces, Errors: uiErrors})
+}
+
+// findLiveTailTraces reads the pages of the search, and reports whether some traces were left unread
+// because of the maximum number of pages or the query limits.
+func (aH *APIHandler) findLiveTailTraces(ctx context.Context, query *spanstore.TraceQueryParameters) ([]*model.Trace, bool, error) {
+	var traces []*model.Trace
+	token := ""
+	for page := 0; page < maxLiveTailPages; page++ {
+		found, next, err := aH.queryService.FindTracesPage(ctx, query, token)
+		if err != nil {
+			if page > 0 && errors.Is(err, querysvc.ErrLimitExceeded) {
+				return traces, true, nil
+			}
+			return nil, false, err
+		}
+		traces = append(traces, found...)
+		if next == "" {
+			return traces, false, nil
+		}
+		token = next
+	}
+	return traces, true, nil
+}
+
+func traceEndTime(trace *model.Trace) time.Time {
+	var end time.Time
+	for _, span := range trace.Spans {
+		if spanEnd := span.StartTime.Add(span.Duration); spanEnd.After(end) {
+			end = spanEnd
+		}
+	}
+	return end
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+func dialLiveTail(t *testing.T, ts *testServer, query string) *websocket.Conn {
+	ws, err := dialLiveTailFrom(ts, query, ts.server.URL)
+	require.NoError(t, err)
+	return ws
+}
+
+func dialLiveTailFrom(ts *testServer, query string, origin string) (*websocket.Conn, error) {
+	url := "ws" + strings.TrimPrefix(ts.server.URL, "http") + "/api/traces/live?" + query
+	return websocket.Dial(url, "", origin)
+}
+
+func liveTrace(traceID uint64, end time.Time) *model.Trace {
+	return &model.Trace{Spans: []*model.Span{{
+		TraceID:   model.NewTraceID(0, traceID),
+		SpanID:    model.NewSpanID(1),
+		StartTime: end.Add(-time.Second),
+		Duration:  time.Second,
+		Process:   model.NewProcess("frontend", nil),
+	}}}
+}
+
+func TestLiveTail(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		now := time.Now()
+		complete := liveTrace(1, now.Add(-time.Minute))
+		incomplete := liveTrace(2, now)
+		ts.spanReader.On("FindTraces", mock.Anything, mock.MatchedBy(func(q *spanstore.TraceQueryParameters) bool {
+			// the traces started shortly before the connection are searched
+			return q.ServiceName == "frontend" && q.StartTimeMin.Before(now.Add(-9*time.Second)) && !q.StartTimeMax.Before(now)
+		})).Return([]*model.Trace{complete, incomplete}, nil)
+
+		ws := dialLiveTail(t, ts, "service=frontend")
+		defer ws.Close()
+		var response structuredTraceResponse
+		require.NoError(t, websocket.JSON.Receive(ws, &response))
+		require.Len(t, response.Traces, 1)
+		assert.EqualValues(t, "0000000000000001", response.Traces[0].TraceID)
+
+		// the complete trace is not sent again by the next polls
+		require.NoError(t, ws.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+		err := websocket.JSON.Receive(ws, &response)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "timeout")
+	}, querysvc.QueryServiceOptions{}, HandlerOptions.LiveTail(10*time.Millisecond, 10*time.Second))
+}
+
+func TestLiveTailPages(t *testing.T) {
+	now := time.Now()
+	completed := []*model.Trace{
+		liveTrace(1, now.Add(-time.Minute)),
+		liveTrace(2, now.Add(-2*time.Minute)),
+		liveTrace(3, now.Add(-3*time.Minute)),
+		liveTrace(4, now.Add(-4*time.Minute)),
+	}
+	tests := []struct {
+		name      string
+		limits    querysvc.QueryLimits
+		expected  int
+		truncated bool
+	}{
+		{name: "all pages", expected: 4},
+		{name: "query limits", limits: querysvc.QueryLimits{FindTraces: querysvc.Limits{MaxResults: 2}}, expected: 2, truncated: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withTestServer(t, func(ts *testServer) {
+				ts.spanReader.On("FindTraces", mock.Anything, mock.Anything).Return(completed, nil)
+
+				ws := dialLiveTail(t, ts, "service=frontend&limit=1")
+				defer ws.Close()
+				var response structuredTraceResponse
+				require.NoError(t, websocket.JSON.Receive(ws, &response))
+				assert.Len(t, response.Traces, test.expected, "the traces of every page are sent")
+				if test.truncated {
+					assert.Equal(t, []structuredError{{Msg: liveTailTruncated}}, response.Errors)
+				} else {
+					assert.Empty(t, response.Errors)
+				}
+			}, querysvc.QueryServiceOptions{Limits: test.limits}, HandlerOptions.LiveTail(time.Hour, time.Second))
+		})
+	}
+}
+
+func TestLiveTailSearchError(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("FindTraces", mock.Anything, mock.Anything).Return(nil, errors.New("storage error"))
+
+		ws := dialLiveTail(t, ts, "service=frontend")
+		defer ws.Close()
+		var response structuredTraceResponse
+		require.NoError(t, websocket.JSON.Receive(ws, &response))
+		assert.Empty(t, response.Traces)
+		assert.Equal(t, []structuredError{{Code: http.StatusInternalServerError, Msg: "storage error"}}, response.Errors)
+	}, querysvc.QueryServiceOptions{}, HandlerOptions.LiveTail(time.Hour, time.Second))
+}
+
+func TestLiveTailBadRequest(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		var response structuredResponse
+		err := getJSON(ts.server.URL+"/api/traces/live?service=frontend&limit=x", &response)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "400 error from server")
+
+		err = getJSON(ts.server.URL+"/api/traces/live?traceID=1", &response)
+		assert.EqualError(t, err, parsedError(http.StatusBadRequest, errLiveTailTraceIDs.Error()))
+	}, querysvc.QueryServiceOptions{})
+}
+
+func TestLiveTailOrigin(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("FindTraces", mock.Anything, mock.Anything).Return([]*model.Trace{}, nil)
+
+		_, err := dialLiveTailFrom(ts, "service=frontend", "https://evil.example.com")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "bad status")
+
+		ws, err := dialLiveTailFrom(ts, "service=frontend", "https://dashboard.example.com")
+		require.NoError(t, err)
+		ws.Close()
+	}, querysvc.QueryServiceOptions{}, HandlerOptions.LiveTailAllowedOrigins([]string{"https://other.example.com", " https://Dashboard.example.com/"}))
+}
+
+func TestLiveTailCheckOrigin(t *testing.T) {
+	options := liveTailOptions{allowedOrigins: []string{"https://dashboard.example.com"}}
+	r := httptest.NewRequest(http.MethodGet, "http://jaeger:16686/api/traces/live", nil)
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{origin: "", allowed: true},
+		{origin: "http://jaeger:16686", allowed: true},
+		{origin: "https://dashboard.example.com", allowed: true},
+		{origin: "http://dashboard.example.com", allowed: false},
+		{origin: "http://jaeger", allowed: false},
+		{origin: "https://evil.example.com", allowed: false},
+		{origin: "evil", allowed: false},
+	}
+	for _, test := range tests {
+		t.Run(test.origin, func(t *testing.T) {
+			r.Header.Set("Origin", test.origin)
+			err := options.checkOrigin(&websocket.Config{Version: websocket.ProtocolVersionHybi13}, r)
+			if test.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestLiveTailDefaultOptions(t *testing.T) {
+	aH := NewAPIHandler(&querysvc.QueryService{}, HandlerOptions.LiveTail(0, 0))
+	assert.Equal(t, liveTailOptions{pollInterval: DefaultLiveTailPollInterval, quietPeriod: DefaultLiveTailQuietPeriod}, aH.liveTail)
+}
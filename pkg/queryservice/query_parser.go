@@ -13,7 +13,7 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package app
+package queryservice
 
 import (
 	"encoding/json"
@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
 	"github.com/jaegertracing/jaeger/storage/spanstore"
 )
 
@@ -33,6 +34,7 @@ const (
 	operationParam   = "operation"
 	tagParam         = "tag"
 	tagsParam        = "tags"
+	tagFilterParam   = "tagFilter"
 	startTimeParam   = "start"
 	limitParam       = "limit"
 	minDurationParam = "minDuration"
@@ -41,6 +43,16 @@ const (
 	spanKindParam    = "spanKind"
 	endTimeParam     = "end"
 	prettyPrintParam = "prettyPrint"
+
+	groupByOperationParam = "groupByOperation"
+	quantileParam         = "quantile"
+	stepParam             = "step"
+	ratePerParam          = "ratePer"
+
+	defaultMetricsLookback = time.Hour
+	defaultMetricsStep     = time.Minute
+	defaultMetricsRatePer  = 10 * time.Minute
+	defaultMetricsSpanKind = "server"
 )
 
 var (
@@ -64,7 +76,7 @@ type traceQueryParameters struct {
 // parse takes a request and constructs a model of parameters
 // Trace query syntax:
 //     query ::= param | param '&' query
-//     param ::= service | operation | limit | start | end | minDuration | maxDuration | tag | tags
+//     param ::= service | operation | limit | start | end | minDuration | maxDuration | tag | tags | tagFilter
 //     service ::= 'service=' strValue
 //     operation ::= 'operation=' strValue
 //     limit ::= 'limit=' intValue
@@ -76,6 +88,8 @@ type traceQueryParameters struct {
 //     key := strValue
 //     keyValue := strValue ':' strValue
 //     tags :== 'tags=' jsonMap
+//     tagFilter ::= 'tagFilter=' key | 'tagFilter=!' key | 'tagFilter=' key operator strValue
+//     operator ::= '=' | '!=' | '>' | '>=' | '<' | '<=' | '=~' | '!~' | '~'
 func (p *queryParser) parse(r *http.Request) (*traceQueryParameters, error) {
 	service := r.FormValue(serviceParam)
 	operation := r.FormValue(operationParam)
@@ -94,6 +108,15 @@ func (p *queryParser) parse(r *http.Request) (*traceQueryParameters, error) {
 		return nil, err
 	}
 
+	var tagFilters []spanstore.TagFilter
+	for _, expr := range r.Form[tagFilterParam] {
+		tagFilter, err := spanstore.ParseTagFilter(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid '%s' parameter: %w", tagFilterParam, err)
+		}
+		tagFilters = append(tagFilters, tagFilter)
+	}
+
 	limitParam := r.FormValue(limitParam)
 	limit := defaultQueryLimit
 	if limitParam != "" {
@@ -133,6 +156,7 @@ func (p *queryParser) parse(r *http.Request) (*traceQueryParameters, error) {
 			NumTraces:     limit,
 			DurationMin:   minDuration,
 			DurationMax:   maxDuration,
+			TagFilters:    tagFilters,
 		},
 		traceIDs: traceIDs,
 	}
@@ -143,6 +167,74 @@ func (p *queryParser) parse(r *http.Request) (*traceQueryParameters, error) {
 	return traceQuery, nil
 }
 
+// parseMetricsQueryParams takes a request and constructs the parameters common to the metrics queries
+// Metrics query syntax:
+//     query ::= param | param '&' query
+//     param ::= service | groupByOperation | spanKind | endTs | lookback | step | ratePer
+//     service ::= 'service=' strValue, at least one and possibly repeated
+//     groupByOperation ::= 'groupByOperation=' boolValue, false by default
+//     spanKind ::= 'spanKind=' strValue, possibly repeated, "server" by default
+//     endTs ::= 'endTs=' intValue in unix milliseconds, now by default
+//     lookback ::= 'lookback=' intValue in milliseconds, 1 hour by default
+//     step ::= 'step=' intValue in milliseconds, 1 minute by default
+//     ratePer ::= 'ratePer=' intValue in milliseconds, 10 minutes by default
+func (p *queryParser) parseMetricsQueryParams(r *http.Request) (metricsstore.BaseQueryParameters, error) {
+	if err := r.ParseForm(); err != nil {
+		return metricsstore.BaseQueryParameters{}, err
+	}
+	params := metricsstore.BaseQueryParameters{
+		ServiceNames: r.Form[serviceParam],
+		SpanKinds:    r.Form[spanKindParam],
+		EndTime:      p.timeNow(),
+		Lookback:     defaultMetricsLookback,
+		Step:         defaultMetricsStep,
+		RatePer:      defaultMetricsRatePer,
+	}
+	if len(params.ServiceNames) == 0 {
+		return params, ErrServiceParameterRequired
+	}
+	if len(params.SpanKinds) == 0 {
+		params.SpanKinds = []string{defaultMetricsSpanKind}
+	}
+	if value := r.FormValue(groupByOperationParam); value != "" {
+		groupByOperation, err := strconv.ParseBool(value)
+		if err != nil {
+			return params, fmt.Errorf("unable to parse %s: %w", groupByOperationParam, err)
+		}
+		params.GroupByOperation = groupByOperation
+	}
+	if value := r.FormValue(endTsParam); value != "" {
+		millis, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return params, fmt.Errorf("unable to parse %s: %w", endTsParam, err)
+		}
+		params.EndTime = time.Unix(0, 0).Add(time.Duration(millis) * time.Millisecond)
+	}
+	for _, d := range []struct {
+		param    string
+		duration *time.Duration
+	}{
+		{lookbackParam, &params.Lookback},
+		{stepParam, &params.Step},
+		{ratePerParam, &params.RatePer},
+	} {
+		param := d.param
+		value := r.FormValue(param)
+		if value == "" {
+			continue
+		}
+		millis, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return params, fmt.Errorf("unable to parse %s: %w", param, err)
+		}
+		if millis <= 0 {
+			return params, fmt.Errorf("'%s' must be a positive number of milliseconds", param)
+		}
+		*d.duration = time.Duration(millis) * time.Millisecond
+	}
+	return params, nil
+}
+
 func (p *queryParser) parseTime(param string, r *http.Request) (time.Time, error) {
 	value := r.FormValue(param)
 	if value == "" {
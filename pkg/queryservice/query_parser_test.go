@@ -13,7 +13,7 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package app
+package queryservice
 
 import (
 	"net/http"
@@ -22,6 +22,7 @@ import (
 
 	"github.com/kr/pretty"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/jaegertracing/jaeger/model"
 	"github.com/jaegertracing/jaeger/storage/spanstore"
@@ -166,3 +167,21 @@ func TestParseTraceQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestParseTraceQueryTagFilters(t *testing.T) {
+	request, err := http.NewRequest(http.MethodGet, "x?service=service&tagFilter=error&tagFilter=http.status_code>=500", nil)
+	require.NoError(t, err)
+	parser := &queryParser{timeNow: time.Now}
+	query, err := parser.parse(request)
+	require.NoError(t, err)
+	exists, err := spanstore.NewTagFilter("error", spanstore.TagExists, "")
+	require.NoError(t, err)
+	greater, err := spanstore.NewTagFilter("http.status_code", spanstore.TagGreaterOrEqual, "500")
+	require.NoError(t, err)
+	assert.Equal(t, []spanstore.TagFilter{exists, greater}, query.TagFilters)
+
+	request, err = http.NewRequest(http.MethodGet, "x?service=service&tagFilter=http.status_code>5xx", nil)
+	require.NoError(t, err)
+	_, err = parser.parse(request)
+	assert.EqualError(t, err, `invalid 'tagFilter' parameter: tag filter "http.status_code>5xx" expects a number`)
+}
@@ -0,0 +1,202 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package queryservice embeds the trace querying of jaeger-query in other Go programs, and holds the HTTP API
+// of the UI and the gRPC api_v2 QueryService that jaeger-query serves. A Service reads the traces of any storage
+// factory and serves them with these APIs, without the flags, the UI assets and the listeners of the jaeger-query binary:
+//
+//	factory := memory.NewFactory()
+//	if err := factory.Initialize(metrics.NullFactory, logger); err != nil { ... }
+//	svc, err := queryservice.New(factory, queryservice.Options{Logger: logger, BasePath: "/jaeger"})
+//	if err != nil { ... }
+//	http.Handle("/jaeger/", svc.HTTPHandler())
+//	grpcServer := grpc.NewServer(svc.GRPCServerOptions()...)
+//	svc.RegisterGRPC(grpcServer)
+package queryservice
+
+import (
+	"net/http"
+
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/jaegertracing/jaeger/model/adjuster"
+	"github.com/jaegertracing/jaeger/pkg/auth"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
+	"github.com/jaegertracing/jaeger/proto-gen/api_v2"
+	"github.com/jaegertracing/jaeger/storage"
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
+	"github.com/jaegertracing/jaeger/storage/readcache"
+)
+
+// Options are the optional settings of a Service.
+type Options struct {
+	// Logger defaults to a no-op logger
+	Logger *zap.Logger
+	// Tracer traces the requests, defaults to a no-op tracer
+	Tracer opentracing.Tracer
+	// MetricsFactory creates the metrics of the authentication and of the read cache, defaults to no metrics
+	MetricsFactory metrics.Factory
+	// MetricsReader serves the span metrics, which are disabled when it is nil
+	MetricsReader metricsstore.Reader
+	// Adjusters are applied to the traces before they are returned, the standard ones when nil
+	Adjusters []adjuster.Adjuster
+	// BasePath is the prefix of the HTTP routes, e.g. "/jaeger", the routes being under /api
+	BasePath string
+	// Auth requires the callers to send a bearer token, the OIDC login of browsers being left to the program
+	Auth auth.Options
+	// Authorizer restricts the services the callers can read, all of them are readable when nil
+	Authorizer querysvc.Authorizer
+	// Tenancy requires the callers to identify their tenant and restricts the results to it
+	Tenancy tenancy.Options
+	// Limits restrict the queries of the endpoints, there are no limits by default
+	Limits querysvc.QueryLimits
+	// ReadCache caches the services, operations, dependencies and traces read from the storage, nothing is cached by default
+	ReadCache readcache.Options
+}
+
+// Service queries the traces of a storage factory and serves them with the APIs of jaeger-query.
+type Service struct {
+	queryService *querysvc.QueryService
+	httpHandler  http.Handler
+	grpcHandler  *GRPCHandler
+	grpcOptions  []grpc.ServerOption
+}
+
+// New creates a Service reading the spans and the dependencies of the storage factory, and its archived
+// traces if it supports an archive storage. The factory must have been initialized.
+func New(factory storage.Factory, options Options) (*Service, error) {
+	logger := options.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	tracer := options.Tracer
+	if tracer == nil {
+		tracer = opentracing.NoopTracer{}
+	}
+	metricsFactory := options.MetricsFactory
+	if metricsFactory == nil {
+		metricsFactory = metrics.NullFactory
+	}
+	metricsReader := options.MetricsReader
+	if metricsReader == nil {
+		metricsReader = metricsstore.NewDisabledReader()
+	}
+
+	spanReader, err := factory.CreateSpanReader()
+	if err != nil {
+		return nil, err
+	}
+	dependencyReader, err := factory.CreateDependencyReader()
+	if err != nil {
+		return nil, err
+	}
+	cacheMetrics := metricsFactory.Namespace(metrics.NSOptions{Name: "read_cache"})
+	queryServiceOptions := querysvc.QueryServiceOptions{
+		Authorizer: options.Authorizer,
+		Limits:     options.Limits,
+	}
+	queryServiceOptions.InitArchiveStorage(factory, logger)
+	if options.Tenancy.Enabled && len(options.Tenancy.Tenants) > 0 {
+		queryServiceOptions.InitTenantStorage(factory, options.Tenancy.Tenants, logger)
+	}
+	if options.Adjusters != nil {
+		queryServiceOptions.Adjuster = adjuster.Sequence(options.Adjusters...)
+	}
+	queryService := querysvc.NewQueryService(
+		readcache.NewSpanReader(spanReader, options.ReadCache, cacheMetrics),
+		readcache.NewDependencyReader(dependencyReader, options.ReadCache, cacheMetrics),
+		queryServiceOptions)
+
+	handlerOptions := []HandlerOption{
+		HandlerOptions.Logger(logger),
+		HandlerOptions.Tracer(tracer),
+		HandlerOptions.MetricsQueryService(querysvc.NewMetricsQueryService(metricsReader, queryService)),
+	}
+	var unaryInterceptors []grpc.UnaryServerInterceptor
+	var streamInterceptors []grpc.StreamServerInterceptor
+	var authenticator *auth.Authenticator
+	if options.Auth.Enabled() {
+		if authenticator, err = auth.NewAuthenticator(options.Auth, logger, metricsFactory.Namespace(metrics.NSOptions{Name: "auth"})); err != nil {
+			return nil, err
+		}
+		unaryInterceptors = append(unaryInterceptors, authenticator.UnaryServerInterceptor())
+		streamInterceptors = append(streamInterceptors, authenticator.StreamServerInterceptor())
+	}
+	// the traces vary with the caller when they are restricted to its services or its tenant
+	var varyHeaders []string
+	if authenticator != nil || options.Authorizer != nil {
+		varyHeaders = append(varyHeaders, "Authorization")
+	}
+	if options.Tenancy.Enabled {
+		tenancyMgr := tenancy.NewManager(&options.Tenancy)
+		handlerOptions = append(handlerOptions, HandlerOptions.Tenancy(tenancyMgr))
+		unaryInterceptors = append(unaryInterceptors, tenancyMgr.UnaryServerInterceptor())
+		streamInterceptors = append(streamInterceptors, tenancyMgr.StreamServerInterceptor())
+		varyHeaders = append(varyHeaders, tenancyMgr.Header)
+	}
+	if len(varyHeaders) > 0 {
+		handlerOptions = append(handlerOptions, HandlerOptions.PrivateTraces(varyHeaders))
+	}
+
+	apiHandler := NewAPIHandler(queryService, handlerOptions...)
+	router := NewRouter()
+	if options.BasePath != "" && options.BasePath != "/" {
+		router = router.PathPrefix(options.BasePath).Subrouter()
+	}
+	apiHandler.RegisterRoutes(router)
+	var httpHandler http.Handler = router
+	if authenticator != nil {
+		httpHandler = authenticator.HTTPHandler(httpHandler)
+	}
+
+	svc := &Service{
+		queryService: queryService,
+		httpHandler:  httpHandler,
+		grpcHandler:  NewGRPCHandler(queryService, logger, tracer),
+	}
+	if len(unaryInterceptors) > 0 {
+		svc.grpcOptions = []grpc.ServerOption{
+			grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(unaryInterceptors...)),
+			grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(streamInterceptors...)),
+		}
+	}
+	return svc, nil
+}
+
+// QueryService returns the service querying the storage, to read the traces without the APIs.
+func (s *Service) QueryService() *querysvc.QueryService {
+	return s.queryService
+}
+
+// HTTPHandler returns the handler of the HTTP API.
+func (s *Service) HTTPHandler() http.Handler {
+	return s.httpHandler
+}
+
+// GRPCServerOptions returns the options of the gRPC server the service is registered on, which authenticate
+// the callers and identify their tenant when the service requires it.
+func (s *Service) GRPCServerOptions() []grpc.ServerOption {
+	return s.grpcOptions
+}
+
+// RegisterGRPC registers the api_v2 QueryService on the gRPC server, which must be created
+// with the GRPCServerOptions.
+func (s *Service) RegisterGRPC(server *grpc.Server) {
+	api_v2.RegisterQueryServiceServer(server, s.grpcHandler)
+}
@@ -0,0 +1,193 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/model/adjuster"
+	"github.com/jaegertracing/jaeger/pkg/auth"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
+	"github.com/jaegertracing/jaeger/plugin/storage/memory"
+	"github.com/jaegertracing/jaeger/proto-gen/api_v2"
+)
+
+func newTestFactory(t *testing.T) *memory.Factory {
+	factory := memory.NewFactory()
+	require.NoError(t, factory.Initialize(metrics.NullFactory, zap.NewNop()))
+	writer, err := factory.CreateSpanWriter()
+	require.NoError(t, err)
+	require.NoError(t, writer.WriteSpan(&model.Span{
+		TraceID:       model.NewTraceID(0, 1),
+		SpanID:        model.NewSpanID(1),
+		OperationName: "GET /",
+		StartTime:     time.Unix(1600000000, 0),
+		Duration:      time.Millisecond,
+		Process:       model.NewProcess("frontend", nil),
+	}))
+	return factory
+}
+
+func TestHTTPHandler(t *testing.T) {
+	svc, err := New(newTestFactory(t), Options{BasePath: "/jaeger"})
+	require.NoError(t, err)
+	server := httptest.NewServer(svc.HTTPHandler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/jaeger/api/services")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var response struct {
+		Data []string `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, []string{"frontend"}, response.Data)
+
+	services, err := svc.QueryService().GetServices(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"frontend"}, services)
+}
+
+func TestRegisterGRPC(t *testing.T) {
+	adjusted := false
+	svc, err := New(newTestFactory(t), Options{Adjusters: []adjuster.Adjuster{
+		adjuster.Func(func(trace *model.Trace) (*model.Trace, error) {
+			adjusted = true
+			return trace, nil
+		}),
+	}})
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	svc.RegisterGRPC(server)
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+	client := api_v2.NewQueryServiceClient(conn)
+
+	services, err := client.GetServices(context.Background(), &api_v2.GetServicesRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"frontend"}, services.Services)
+
+	_, err = svc.QueryService().Adjust(&model.Trace{})
+	require.NoError(t, err)
+	assert.True(t, adjusted)
+}
+
+func TestAuthAndTenancy(t *testing.T) {
+	tokens, err := ioutil.TempFile("", "tokens")
+	require.NoError(t, err)
+	defer os.Remove(tokens.Name())
+	_, err = tokens.WriteString("secret\n")
+	require.NoError(t, err)
+	require.NoError(t, tokens.Close())
+
+	factory := newTestFactory(t)
+	writer, err := factory.CreateSpanWriter()
+	require.NoError(t, err)
+	span := &model.Span{
+		TraceID:       model.NewTraceID(0, 2),
+		SpanID:        model.NewSpanID(1),
+		OperationName: "GET /",
+		StartTime:     time.Now(),
+		Duration:      time.Millisecond,
+		Process:       model.NewProcess("acme-frontend", nil),
+	}
+	tenancy.SetSpanTenant(span, "acme")
+	require.NoError(t, writer.WriteSpan(span))
+
+	svc, err := New(factory, Options{
+		Auth:    auth.Options{TokensFile: tokens.Name()},
+		Tenancy: tenancy.Options{Enabled: true},
+		Limits:  querysvc.QueryLimits{FindTraces: querysvc.Limits{MaxResults: 10}},
+	})
+	require.NoError(t, err)
+	server := httptest.NewServer(svc.HTTPHandler())
+	defer server.Close()
+
+	get := func(url, token, tenant string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, server.URL+url, nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if tenant != "" {
+			req.Header.Set(tenancy.DefaultHeader, tenant)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+	resp := get("/api/services", "", "acme")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp = get("/api/services", "secret", "")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp = get("/api/traces?service=acme-frontend&limit=100", "secret", "acme")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = get("/api/services", "secret", "acme")
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var response struct {
+		Data []string `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, []string{"acme-frontend"}, response.Data)
+
+	grpcServer := grpc.NewServer(svc.GRPCServerOptions()...)
+	svc.RegisterGRPC(grpcServer)
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+	client := api_v2.NewQueryServiceClient(conn)
+
+	_, err = client.GetServices(context.Background(), &api_v2.GetServicesRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret", tenancy.DefaultHeader, "acme")
+	services, err := client.GetServices(ctx, &api_v2.GetServicesRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"acme-frontend"}, services.Services)
+}
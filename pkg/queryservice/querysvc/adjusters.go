@@ -0,0 +1,108 @@
+// Copyright (c) 2019 The Jaeger Authors.
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package querysvc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model/adjuster"
+)
+
+// Names of the standard adjusters
+const (
+	AdjusterSpanIDDeduper  = "span-id-deduper"
+	AdjusterClockSkew      = "clock-skew"
+	AdjusterIPTag          = "ip-tag"
+	AdjusterSortLogFields  = "sort-log-fields"
+	AdjusterSpanReferences = "span-references"
+)
+
+// StandardAdjusterNames are the names of the standard adjusters, in the order they are applied by default.
+var StandardAdjusterNames = []string{
+	AdjusterSpanIDDeduper,
+	AdjusterClockSkew,
+	AdjusterIPTag,
+	AdjusterSortLogFields,
+	AdjusterSpanReferences,
+}
+
+// AdjusterParams are the settings of the query service the adjusters can be built with.
+type AdjusterParams struct {
+	MaxClockSkewAdjust time.Duration
+}
+
+// AdjusterFactory creates an adjuster from the settings of the query service.
+type AdjusterFactory func(params AdjusterParams) adjuster.Adjuster
+
+var (
+	adjusterFactoriesMu sync.RWMutex
+	adjusterFactories   = map[string]AdjusterFactory{
+		AdjusterSpanIDDeduper: func(AdjusterParams) adjuster.Adjuster { return adjuster.SpanIDDeduper() },
+		AdjusterClockSkew: func(params AdjusterParams) adjuster.Adjuster {
+			return adjuster.ClockSkew(params.MaxClockSkewAdjust)
+		},
+		AdjusterIPTag:          func(AdjusterParams) adjuster.Adjuster { return adjuster.IPTagAdjuster() },
+		AdjusterSortLogFields:  func(AdjusterParams) adjuster.Adjuster { return adjuster.SortLogFields() },
+		AdjusterSpanReferences: func(AdjusterParams) adjuster.Adjuster { return adjuster.SpanReferences() },
+	}
+)
+
+// RegisterAdjuster makes an additional adjuster available by its name, so that it can be added to the
+// adjusters of the query service with --query.adjusters. It is meant to be called from an init function
+// of a custom build of the query service, and panics if the name is already registered.
+func RegisterAdjuster(name string, factory AdjusterFactory) {
+	adjusterFactoriesMu.Lock()
+	defer adjusterFactoriesMu.Unlock()
+	if _, ok := adjusterFactories[name]; ok {
+		panic(fmt.Sprintf("adjuster %q is already registered", name))
+	}
+	adjusterFactories[name] = factory
+}
+
+// NamedAdjusters returns the adjusters with the given names, in the same order.
+func NamedAdjusters(names []string, params AdjusterParams) ([]adjuster.Adjuster, error) {
+	adjusterFactoriesMu.RLock()
+	defer adjusterFactoriesMu.RUnlock()
+	adjusters := make([]adjuster.Adjuster, 0, len(names))
+	for _, name := range names {
+		factory, ok := adjusterFactories[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown adjuster %q, expecting one of %s", name, strings.Join(registeredAdjusters(), ", "))
+		}
+		adjusters = append(adjusters, factory(params))
+	}
+	return adjusters, nil
+}
+
+func registeredAdjusters() []string {
+	names := make([]string, 0, len(adjusterFactories))
+	for name := range adjusterFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StandardAdjusters is a list of model adjusters applied by the query service
+// before returning the data to the API clients.
+func StandardAdjusters(maxClockSkewAdjust time.Duration) []adjuster.Adjuster {
+	adjusters, _ := NamedAdjusters(StandardAdjusterNames, AdjusterParams{MaxClockSkewAdjust: maxClockSkewAdjust})
+	return adjusters
+}
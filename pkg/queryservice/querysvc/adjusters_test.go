@@ -0,0 +1,69 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package querysvc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/model/adjuster"
+)
+
+func TestStandardAdjusters(t *testing.T) {
+	assert.Len(t, StandardAdjusters(time.Second), len(StandardAdjusterNames))
+}
+
+func TestNamedAdjusters(t *testing.T) {
+	var params []AdjusterParams
+	warning := func(name string) AdjusterFactory {
+		return func(p AdjusterParams) adjuster.Adjuster {
+			params = append(params, p)
+			return adjuster.Func(func(trace *model.Trace) (*model.Trace, error) {
+				trace.Warnings = append(trace.Warnings, name)
+				return trace, nil
+			})
+		}
+	}
+	RegisterAdjuster("test-first", warning("first"))
+	RegisterAdjuster("test-second", warning("second"))
+	defer func() {
+		adjusterFactoriesMu.Lock()
+		defer adjusterFactoriesMu.Unlock()
+		delete(adjusterFactories, "test-first")
+		delete(adjusterFactories, "test-second")
+	}()
+	assert.PanicsWithValue(t, `adjuster "test-first" is already registered`, func() {
+		RegisterAdjuster("test-first", warning("again"))
+	})
+
+	adjusters, err := NamedAdjusters([]string{"test-second", AdjusterSpanIDDeduper, "test-first"}, AdjusterParams{MaxClockSkewAdjust: time.Minute})
+	require.NoError(t, err)
+	require.Len(t, adjusters, 3)
+	assert.Equal(t, []AdjusterParams{{MaxClockSkewAdjust: time.Minute}, {MaxClockSkewAdjust: time.Minute}}, params)
+	trace, err := adjuster.Sequence(adjusters...).Adjust(&model.Trace{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"second", "first"}, trace.Warnings)
+
+	adjusters, err = NamedAdjusters(nil, AdjusterParams{})
+	require.NoError(t, err)
+	assert.Empty(t, adjusters)
+
+	_, err = NamedAdjusters([]string{"clock"}, AdjusterParams{})
+	assert.EqualError(t, err, `unknown adjuster "clock", expecting one of clock-skew, ip-tag, sort-log-fields, span-id-deduper, span-references, test-first, test-second`)
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package querysvc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	depsmocks "github.com/jaegertracing/jaeger/storage/dependencystore/mocks"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	spanstoremocks "github.com/jaegertracing/jaeger/storage/spanstore/mocks"
+)
+
+type auditEntry struct {
+	traceID model.TraceID
+	query   *spanstore.TraceQueryParameters
+	traces  int
+	err     error
+}
+
+// recordingAuditor keeps the accesses to the traces
+type recordingAuditor struct {
+	entries []auditEntry
+}
+
+func (a *recordingAuditor) TraceRead(ctx context.Context, traceID model.TraceID, err error) {
+	a.entries = append(a.entries, auditEntry{traceID: traceID, err: err})
+}
+
+func (a *recordingAuditor) TracesSearched(ctx context.Context, query *spanstore.TraceQueryParameters, traces []*model.Trace, err error) {
+	a.entries = append(a.entries, auditEntry{query: query, traces: len(traces), err: err})
+}
+
+func TestAuditor(t *testing.T) {
+	readStorage := &spanstoremocks.Reader{}
+	auditor := &recordingAuditor{}
+	qs := NewQueryService(readStorage, &depsmocks.Reader{}, QueryServiceOptions{Auditor: auditor})
+
+	readStorage.On("GetTrace", mock.Anything, model.NewTraceID(0, 1)).Return(traceOf(1, "frontend"), nil).Once()
+	readStorage.On("GetTrace", mock.Anything, model.NewTraceID(0, 2)).Return(nil, spanstore.ErrTraceNotFound).Once()
+	query := &spanstore.TraceQueryParameters{ServiceName: "frontend"}
+	readStorage.On("FindTraces", mock.Anything, query).Return([]*model.Trace{traceOf(1, "frontend"), traceOf(3, "frontend")}, nil).Once()
+
+	_, err := qs.GetTrace(context.Background(), model.NewTraceID(0, 1))
+	require.NoError(t, err)
+	_, err = qs.GetTrace(context.Background(), model.NewTraceID(0, 2))
+	assert.Equal(t, spanstore.ErrTraceNotFound, err)
+	_, err = qs.FindTraces(context.Background(), query)
+	require.NoError(t, err)
+
+	assert.Equal(t, []auditEntry{
+		{traceID: model.NewTraceID(0, 1)},
+		{traceID: model.NewTraceID(0, 2), err: spanstore.ErrTraceNotFound},
+		{query: query, traces: 2},
+	}, auditor.entries)
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package querysvc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	depsmocks "github.com/jaegertracing/jaeger/storage/dependencystore/mocks"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	spanstoremocks "github.com/jaegertracing/jaeger/storage/spanstore/mocks"
+)
+
+// serviceAuthorizer allows the same services to all the callers
+type serviceAuthorizer map[string]bool
+
+func (a serviceAuthorizer) IsAllowed(ctx context.Context, service string) bool {
+	return a[service]
+}
+
+func initializeTestServiceWithAuthorizer() (*QueryService, *spanstoremocks.Reader) {
+	readStorage := &spanstoremocks.Reader{}
+	qs := NewQueryService(readStorage, &depsmocks.Reader{}, QueryServiceOptions{
+		Authorizer: serviceAuthorizer{"frontend": true},
+	})
+	return qs, readStorage
+}
+
+func traceOf(traceID uint64, services ...string) *model.Trace {
+	trace := &model.Trace{}
+	for i, service := range services {
+		trace.Spans = append(trace.Spans, &model.Span{
+			TraceID: model.NewTraceID(0, traceID),
+			SpanID:  model.NewSpanID(uint64(i + 1)),
+			Process: model.NewProcess(service, nil),
+		})
+	}
+	return trace
+}
+
+func TestAuthorizedGetServices(t *testing.T) {
+	qs, readMock := initializeTestServiceWithAuthorizer()
+	readMock.On("GetServices", mock.Anything).Return([]string{"frontend", "mysql"}, nil).Once()
+
+	services, err := qs.GetServices(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"frontend"}, services)
+}
+
+func TestAuthorizedGetOperations(t *testing.T) {
+	qs, readMock := initializeTestServiceWithAuthorizer()
+	readMock.On("GetOperations", mock.Anything, spanstore.OperationQueryParameters{ServiceName: "frontend"}).
+		Return([]spanstore.Operation{{Name: "GET /"}}, nil).Once()
+
+	operations, err := qs.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "frontend"})
+	require.NoError(t, err)
+	assert.Len(t, operations, 1)
+
+	_, err = qs.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "mysql"})
+	assert.Equal(t, ErrForbidden, err)
+	_, err = qs.GetOperations(context.Background(), spanstore.OperationQueryParameters{})
+	assert.Equal(t, ErrForbidden, err)
+}
+
+func TestAuthorizedGetTrace(t *testing.T) {
+	qs, readMock := initializeTestServiceWithAuthorizer()
+	readMock.On("GetTrace", mock.Anything, model.NewTraceID(0, 1)).Return(traceOf(1, "frontend", "mysql"), nil).Once()
+	readMock.On("GetTrace", mock.Anything, model.NewTraceID(0, 2)).Return(traceOf(2, "batch", "mysql"), nil).Once()
+
+	// the spans of the services the caller cannot read are removed
+	trace, err := qs.GetTrace(context.Background(), model.NewTraceID(0, 1))
+	require.NoError(t, err)
+	assert.Equal(t, traceOf(1, "frontend"), trace)
+
+	_, err = qs.GetTrace(context.Background(), model.NewTraceID(0, 2))
+	assert.Equal(t, ErrForbidden, err)
+}
+
+func TestAuthorizedFindTraces(t *testing.T) {
+	qs, readMock := initializeTestServiceWithAuthorizer()
+	readMock.On("FindTraces", mock.Anything, mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+		Return([]*model.Trace{traceOf(1, "frontend", "mysql"), traceOf(2, "batch")}, nil).Once()
+
+	traces, err := qs.FindTraces(context.Background(), &spanstore.TraceQueryParameters{ServiceName: "frontend"})
+	require.NoError(t, err)
+	assert.Equal(t, []*model.Trace{traceOf(1, "frontend")}, traces)
+
+	// the tag filters only match the spans the caller can read
+	mixed := traceOf(3, "frontend", "mysql")
+	mixed.Spans[1].Tags = []model.KeyValue{model.String("db.statement", "SELECT")}
+	readMock.On("FindTraces", mock.Anything, mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+		Return([]*model.Trace{mixed}, nil).Once()
+	tagFilter, err := spanstore.ParseTagFilter("db.statement=SELECT")
+	require.NoError(t, err)
+	traces, err = qs.FindTraces(context.Background(), &spanstore.TraceQueryParameters{
+		ServiceName: "frontend",
+		TagFilters:  []spanstore.TagFilter{tagFilter},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, traces)
+
+	_, err = qs.FindTraces(context.Background(), &spanstore.TraceQueryParameters{ServiceName: "batch"})
+	assert.Equal(t, ErrForbidden, err)
+	readMock.AssertNumberOfCalls(t, "FindTraces", 2)
+}
+
+func TestAuthorizedGetDependencies(t *testing.T) {
+	depsMock := &depsmocks.Reader{}
+	qs := NewQueryService(&spanstoremocks.Reader{}, depsMock, QueryServiceOptions{
+		Authorizer: serviceAuthorizer{"frontend": true},
+	})
+	depsMock.On("GetDependencies", mock.Anything, mock.Anything).Return([]model.DependencyLink{
+		{Parent: "frontend", Child: "mysql", CallCount: 1},
+		{Parent: "batch", Child: "frontend", CallCount: 2},
+		{Parent: "batch", Child: "mysql", CallCount: 3},
+	}, nil).Once()
+
+	dependencies, err := qs.GetDependencies(context.Background(), time.Now(), time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, []model.DependencyLink{
+		{Parent: "frontend", Child: "mysql", CallCount: 1},
+		{Parent: "batch", Child: "frontend", CallCount: 2},
+	}, dependencies)
+}
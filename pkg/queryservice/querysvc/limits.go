@@ -0,0 +1,97 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package querysvc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrLimitExceeded occurs when a query goes over the limits configured for its endpoint
+	ErrLimitExceeded = errors.New("query limit exceeded")
+
+	// ErrQueryTimeout occurs when the storage did not answer a query within the timeout of its endpoint
+	ErrQueryTimeout = errors.New("query timed out")
+)
+
+// Limits restrict the queries of an endpoint so that a single query cannot overload the storage.
+// The zero values mean no limit.
+type Limits struct {
+	// MaxLookback is the longest period of time that can be queried
+	MaxLookback time.Duration
+	// MaxResults is the largest number of results that can be requested, and the number of
+	// results returned when the query does not set it
+	MaxResults int
+	// Timeout is how long the storage is queried before giving up
+	Timeout time.Duration
+}
+
+// QueryLimits are the limits of the endpoints of the QueryService. The search enforces all the limits,
+// the retrieval of a trace only has a timeout and the dependencies only a maximum lookback, as the
+// dependency readers do not take a context.
+type QueryLimits struct {
+	FindTraces      Limits
+	GetTrace        Limits
+	GetDependencies Limits
+}
+
+func (l Limits) checkLookback(start, end time.Time) error {
+	if l.MaxLookback <= 0 {
+		return nil
+	}
+	if start.IsZero() || end.IsZero() {
+		return fmt.Errorf("%w: the start and end times must be set, at most %v apart", ErrLimitExceeded, l.MaxLookback)
+	}
+	return l.checkDuration(end.Sub(start))
+}
+
+func (l Limits) checkDuration(lookback time.Duration) error {
+	if l.MaxLookback > 0 && lookback > l.MaxLookback {
+		return fmt.Errorf("%w: the lookback of %v is longer than the maximum of %v", ErrLimitExceeded, lookback, l.MaxLookback)
+	}
+	return nil
+}
+
+// limitResults returns the number of results to request, the maximum when the count is not set.
+func (l Limits) limitResults(count int) (int, error) {
+	if l.MaxResults <= 0 {
+		return count, nil
+	}
+	if count <= 0 {
+		return l.MaxResults, nil
+	}
+	if count > l.MaxResults {
+		return 0, fmt.Errorf("%w: %d results requested, more than the maximum of %d", ErrLimitExceeded, count, l.MaxResults)
+	}
+	return count, nil
+}
+
+// run calls the query with a context cancelled after the timeout, and reports its error as a timeout
+// when the context expired. The storage backends give up the queries when their context is cancelled.
+func (l Limits) run(ctx context.Context, query string, f func(ctx context.Context) error) error {
+	if l.Timeout <= 0 {
+		return f(ctx)
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, l.Timeout)
+	defer cancel()
+	err := f(timeoutCtx)
+	if err != nil && timeoutCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		return fmt.Errorf("%w: %s did not complete within %v", ErrQueryTimeout, query, l.Timeout)
+	}
+	return err
+}
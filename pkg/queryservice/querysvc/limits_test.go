@@ -0,0 +1,131 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package querysvc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	depsmocks "github.com/jaegertracing/jaeger/storage/dependencystore/mocks"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	spanstoremocks "github.com/jaegertracing/jaeger/storage/spanstore/mocks"
+)
+
+var limitsTestEnd = time.Unix(1600000000, 0)
+
+func initializeTestServiceWithLimits(limits QueryLimits) (*QueryService, *spanstoremocks.Reader, *depsmocks.Reader) {
+	readStorage := &spanstoremocks.Reader{}
+	dependencyStorage := &depsmocks.Reader{}
+	qs := NewQueryService(readStorage, dependencyStorage, QueryServiceOptions{Limits: limits})
+	return qs, readStorage, dependencyStorage
+}
+
+// blockUntilCancelled makes the mock call wait for its context to be cancelled, like a storage backend
+// taking too long to answer.
+func blockUntilCancelled(args mock.Arguments) {
+	<-args.Get(0).(context.Context).Done()
+}
+
+func TestFindTracesLimits(t *testing.T) {
+	qs, readMock, _ := initializeTestServiceWithLimits(QueryLimits{
+		FindTraces: Limits{MaxLookback: time.Hour, MaxResults: 100},
+	})
+	readMock.On("FindTraces", mock.Anything, mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+		Return([]*model.Trace{mockTrace}, nil)
+
+	query := &spanstore.TraceQueryParameters{
+		ServiceName:  "service",
+		StartTimeMin: limitsTestEnd.Add(-time.Hour),
+		StartTimeMax: limitsTestEnd,
+	}
+	traces, err := qs.FindTraces(context.Background(), query)
+	require.NoError(t, err)
+	assert.Len(t, traces, 1)
+	// the maximum number of traces is requested when the query has no limit
+	readMock.AssertCalled(t, "FindTraces", mock.Anything, mock.MatchedBy(func(q *spanstore.TraceQueryParameters) bool {
+		return q.NumTraces == 100
+	}))
+	assert.Zero(t, query.NumTraces)
+
+	_, err = qs.FindTraces(context.Background(), &spanstore.TraceQueryParameters{
+		ServiceName:  "service",
+		StartTimeMin: limitsTestEnd.Add(-2 * time.Hour),
+		StartTimeMax: limitsTestEnd,
+	})
+	assert.True(t, errors.Is(err, ErrLimitExceeded))
+	assert.EqualError(t, err, "query limit exceeded: the lookback of 2h0m0s is longer than the maximum of 1h0m0s")
+
+	_, err = qs.FindTraces(context.Background(), &spanstore.TraceQueryParameters{ServiceName: "service", StartTimeMax: limitsTestEnd})
+	assert.EqualError(t, err, "query limit exceeded: the start and end times must be set, at most 1h0m0s apart")
+
+	query.NumTraces = 101
+	_, err = qs.FindTraces(context.Background(), query)
+	assert.EqualError(t, err, "query limit exceeded: 101 results requested, more than the maximum of 100")
+
+	query.NumTraces = 60
+	_, _, err = qs.FindTracesPage(context.Background(), query, "")
+	require.NoError(t, err)
+	_, _, err = qs.FindTracesPage(context.Background(), query, encodePageToken(pageToken{
+		Query: hashQuery(query), StartTimeMin: query.StartTimeMin, StartTimeMax: query.StartTimeMax, Offset: 60,
+	}))
+	assert.EqualError(t, err, "query limit exceeded: 120 results requested, more than the maximum of 100")
+	_, _, err = qs.FindTracesPage(context.Background(), &spanstore.TraceQueryParameters{ServiceName: "service"}, "")
+	assert.True(t, errors.Is(err, ErrLimitExceeded))
+}
+
+func TestFindTracesTimeout(t *testing.T) {
+	qs, readMock, _ := initializeTestServiceWithLimits(QueryLimits{FindTraces: Limits{Timeout: time.Millisecond}})
+	readMock.On("FindTraces", mock.Anything, mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+		Run(blockUntilCancelled).Return(nil, context.DeadlineExceeded)
+
+	_, err := qs.FindTraces(context.Background(), &spanstore.TraceQueryParameters{ServiceName: "service"})
+	assert.True(t, errors.Is(err, ErrQueryTimeout))
+	assert.EqualError(t, err, "query timed out: the search did not complete within 1ms")
+
+	// the cancellation by the caller is not a timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = qs.FindTraces(ctx, &spanstore.TraceQueryParameters{ServiceName: "service"})
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestGetTraceTimeout(t *testing.T) {
+	qs, readMock, _ := initializeTestServiceWithLimits(QueryLimits{GetTrace: Limits{Timeout: time.Millisecond}})
+	readMock.On("GetTrace", mock.Anything, mockTraceID).Run(blockUntilCancelled).Return(nil, context.DeadlineExceeded).Once()
+	readMock.On("GetTrace", mock.Anything, mockTraceID).Return(mockTrace, nil).Once()
+
+	_, err := qs.GetTrace(context.Background(), mockTraceID)
+	assert.EqualError(t, err, "query timed out: the retrieval of the trace did not complete within 1ms")
+	trace, err := qs.GetTrace(context.Background(), mockTraceID)
+	require.NoError(t, err)
+	assert.Equal(t, mockTrace, trace)
+}
+
+func TestGetDependenciesLimits(t *testing.T) {
+	qs, _, depsMock := initializeTestServiceWithLimits(QueryLimits{GetDependencies: Limits{MaxLookback: 24 * time.Hour}})
+	depsMock.On("GetDependencies", limitsTestEnd, 24*time.Hour).Return([]model.DependencyLink{}, nil)
+
+	_, err := qs.GetDependencies(context.Background(), limitsTestEnd, 24*time.Hour)
+	require.NoError(t, err)
+	_, err = qs.GetDependencies(context.Background(), limitsTestEnd, 48*time.Hour)
+	assert.EqualError(t, err, "query limit exceeded: the lookback of 48h0m0s is longer than the maximum of 24h0m0s")
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package querysvc
+
+import (
+	"context"
+	"time"
+
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
+)
+
+// serviceNameLabel is the label of the span metrics with the name of their service.
+const serviceNameLabel = "service_name"
+
+// MetricsQueryService provides the span metrics of the services, such as shown in the Monitor tab of the UI.
+type MetricsQueryService interface {
+	metricsstore.Reader
+}
+
+// NewMetricsQueryService returns the MetricsQueryService reading the span metrics with the reader,
+// only returning the metrics of the services the caller can read with the query service: the services
+// allowed by its authorizer and, since the span metrics are shared by all the tenants, the services of the tenant.
+func NewMetricsQueryService(reader metricsstore.Reader, querySvc *QueryService) MetricsQueryService {
+	return &authorizedMetricsReader{reader: reader, querySvc: querySvc}
+}
+
+type authorizedMetricsReader struct {
+	reader   metricsstore.Reader
+	querySvc *QueryService
+}
+
+// GetLatencies implements metricsstore.Reader.
+func (r *authorizedMetricsReader) GetLatencies(ctx context.Context, params *metricsstore.LatenciesQueryParameters) (*metricsstore.MetricFamily, error) {
+	services, err := r.allowed(ctx, params.ServiceNames)
+	if err != nil {
+		return nil, err
+	}
+	family, err := r.reader.GetLatencies(ctx, params)
+	return r.filter(ctx, services, family, err)
+}
+
+// GetCallRates implements metricsstore.Reader.
+func (r *authorizedMetricsReader) GetCallRates(ctx context.Context, params *metricsstore.CallRateQueryParameters) (*metricsstore.MetricFamily, error) {
+	services, err := r.allowed(ctx, params.ServiceNames)
+	if err != nil {
+		return nil, err
+	}
+	family, err := r.reader.GetCallRates(ctx, params)
+	return r.filter(ctx, services, family, err)
+}
+
+// GetErrorRates implements metricsstore.Reader.
+func (r *authorizedMetricsReader) GetErrorRates(ctx context.Context, params *metricsstore.ErrorRateQueryParameters) (*metricsstore.MetricFamily, error) {
+	services, err := r.allowed(ctx, params.ServiceNames)
+	if err != nil {
+		return nil, err
+	}
+	family, err := r.reader.GetErrorRates(ctx, params)
+	return r.filter(ctx, services, family, err)
+}
+
+// GetLatencyBuckets implements metricsstore.Reader.
+func (r *authorizedMetricsReader) GetLatencyBuckets(ctx context.Context, params *metricsstore.LatencyBucketsQueryParameters) (*metricsstore.MetricFamily, error) {
+	services, err := r.allowed(ctx, params.ServiceNames)
+	if err != nil {
+		return nil, err
+	}
+	family, err := r.reader.GetLatencyBuckets(ctx, params)
+	return r.filter(ctx, services, family, err)
+}
+
+// GetMinStepDuration implements metricsstore.Reader.
+func (r *authorizedMetricsReader) GetMinStepDuration(ctx context.Context, params *metricsstore.MinStepDurationQueryParameters) (time.Duration, error) {
+	return r.reader.GetMinStepDuration(ctx, params)
+}
+
+// allowed returns ErrForbidden if the caller cannot read one of the services, the queries being rejected as
+// GetOperations does rather than silently missing the series of some services. It also returns the services
+// of the tenant of the caller, nil when the caller has no tenant.
+func (r *authorizedMetricsReader) allowed(ctx context.Context, services []string) (map[string]bool, error) {
+	tenantServices, err := r.querySvc.tenantServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, service := range services {
+		if !r.readable(ctx, tenantServices, service) {
+			return nil, ErrForbidden
+		}
+	}
+	return tenantServices, nil
+}
+
+func (r *authorizedMetricsReader) readable(ctx context.Context, tenantServices map[string]bool, service string) bool {
+	if tenantServices != nil && !tenantServices[service] {
+		return false
+	}
+	return r.querySvc.isAllowed(ctx, service)
+}
+
+// filter removes the series of the services the caller cannot read from the result of a query,
+// such as the series of all the services returned when the query has none.
+func (r *authorizedMetricsReader) filter(ctx context.Context, tenantServices map[string]bool, family *metricsstore.MetricFamily, err error) (*metricsstore.MetricFamily, error) {
+	if err != nil || family == nil || (tenantServices == nil && r.querySvc.options.Authorizer == nil) {
+		return family, err
+	}
+	filtered := *family
+	filtered.Metrics = make([]*metricsstore.Metric, 0, len(family.Metrics))
+	for _, metric := range family.Metrics {
+		if r.readable(ctx, tenantServices, metricService(metric)) {
+			filtered.Metrics = append(filtered.Metrics, metric)
+		}
+	}
+	return &filtered, nil
+}
+
+func metricService(metric *metricsstore.Metric) string {
+	for _, label := range metric.Labels {
+		if label.Name == serviceNameLabel {
+			return label.Value
+		}
+	}
+	return ""
+}
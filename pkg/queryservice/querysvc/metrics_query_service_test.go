@@ -0,0 +1,118 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package querysvc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
+	depsmocks "github.com/jaegertracing/jaeger/storage/dependencystore/mocks"
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
+	metricsmocks "github.com/jaegertracing/jaeger/storage/metricsstore/mocks"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	spanstoremocks "github.com/jaegertracing/jaeger/storage/spanstore/mocks"
+)
+
+func serviceMetric(service string) *metricsstore.Metric {
+	return &metricsstore.Metric{Labels: []metricsstore.Label{{Name: "operation", Value: "GET /"}, {Name: "service_name", Value: service}}}
+}
+
+// queryServiceWith returns a query service with the authorizer, and the services of the tenant acme
+func queryServiceWith(authorizer Authorizer, acmeServices ...string) *QueryService {
+	acmeMock := &spanstoremocks.Reader{}
+	acmeMock.On("GetServices", mock.Anything).Return(acmeServices, nil)
+	return NewQueryService(&spanstoremocks.Reader{}, &depsmocks.Reader{}, QueryServiceOptions{
+		Authorizer:        authorizer,
+		TenantSpanReaders: map[string]spanstore.Reader{"acme": acmeMock},
+	})
+}
+
+func TestMetricsQueryServiceWithoutAuthorizer(t *testing.T) {
+	reader := &metricsmocks.Reader{}
+	family := &metricsstore.MetricFamily{Name: "service_call_rate", Metrics: []*metricsstore.Metric{serviceMetric("frontend"), serviceMetric("mysql")}}
+	params := &metricsstore.CallRateQueryParameters{BaseQueryParameters: metricsstore.BaseQueryParameters{ServiceNames: []string{"mysql"}}}
+	reader.On("GetCallRates", mock.Anything, mock.Anything).Return(family, nil)
+	svc := NewMetricsQueryService(reader, queryServiceWith(nil, "frontend"))
+
+	result, err := svc.GetCallRates(context.Background(), params)
+	require.NoError(t, err)
+	assert.Equal(t, family, result)
+
+	// the span metrics are shared by the tenants, so they are restricted to the services of the tenant
+	acme := tenancy.WithTenant(context.Background(), "acme")
+	_, err = svc.GetCallRates(acme, params)
+	assert.Equal(t, ErrForbidden, err)
+	result, err = svc.GetCallRates(acme, &metricsstore.CallRateQueryParameters{})
+	require.NoError(t, err)
+	assert.Equal(t, []*metricsstore.Metric{serviceMetric("frontend")}, result.Metrics)
+}
+
+func TestAuthorizedMetrics(t *testing.T) {
+	reader := &metricsmocks.Reader{}
+	family := &metricsstore.MetricFamily{Name: "service_call_rate", Metrics: []*metricsstore.Metric{serviceMetric("frontend"), serviceMetric("mysql")}}
+	reader.On("GetLatencies", mock.Anything, mock.Anything).Return(family, nil)
+	reader.On("GetCallRates", mock.Anything, mock.Anything).Return(family, nil)
+	reader.On("GetErrorRates", mock.Anything, mock.Anything).Return(family, nil)
+	reader.On("GetLatencyBuckets", mock.Anything, mock.Anything).Return(family, nil)
+	svc := NewMetricsQueryService(reader, queryServiceWith(serviceAuthorizer{"frontend": true}))
+
+	allowed := metricsstore.BaseQueryParameters{ServiceNames: []string{"frontend"}}
+	forbidden := metricsstore.BaseQueryParameters{ServiceNames: []string{"frontend", "mysql"}}
+	queries := map[string]func(params metricsstore.BaseQueryParameters) (*metricsstore.MetricFamily, error){
+		"latencies": func(params metricsstore.BaseQueryParameters) (*metricsstore.MetricFamily, error) {
+			return svc.GetLatencies(context.Background(), &metricsstore.LatenciesQueryParameters{BaseQueryParameters: params})
+		},
+		"calls": func(params metricsstore.BaseQueryParameters) (*metricsstore.MetricFamily, error) {
+			return svc.GetCallRates(context.Background(), &metricsstore.CallRateQueryParameters{BaseQueryParameters: params})
+		},
+		"errors": func(params metricsstore.BaseQueryParameters) (*metricsstore.MetricFamily, error) {
+			return svc.GetErrorRates(context.Background(), &metricsstore.ErrorRateQueryParameters{BaseQueryParameters: params})
+		},
+		"buckets": func(params metricsstore.BaseQueryParameters) (*metricsstore.MetricFamily, error) {
+			return svc.GetLatencyBuckets(context.Background(), &metricsstore.LatencyBucketsQueryParameters{BaseQueryParameters: params})
+		},
+	}
+	for name, query := range queries {
+		t.Run(name, func(t *testing.T) {
+			result, err := query(allowed)
+			require.NoError(t, err)
+			assert.Equal(t, "service_call_rate", result.Name)
+			assert.Equal(t, []*metricsstore.Metric{serviceMetric("frontend")}, result.Metrics)
+			assert.Len(t, family.Metrics, 2, "the family of the reader must not be modified")
+
+			_, err = query(forbidden)
+			assert.Equal(t, ErrForbidden, err)
+		})
+	}
+}
+
+func TestAuthorizedMetricsErrors(t *testing.T) {
+	reader := &metricsmocks.Reader{}
+	reader.On("GetCallRates", mock.Anything, mock.Anything).Return(nil, metricsstore.ErrDisabled)
+	reader.On("GetMinStepDuration", mock.Anything, mock.Anything).Return(time.Second, nil)
+	svc := NewMetricsQueryService(reader, queryServiceWith(serviceAuthorizer{"frontend": true}))
+
+	_, err := svc.GetCallRates(context.Background(), &metricsstore.CallRateQueryParameters{})
+	assert.Equal(t, metricsstore.ErrDisabled, err)
+	step, err := svc.GetMinStepDuration(context.Background(), &metricsstore.MinStepDurationQueryParameters{})
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, step)
+}
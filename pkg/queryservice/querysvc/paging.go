@@ -0,0 +1,212 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package querysvc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+// ErrInvalidPageToken occurs when the page token of a search was not returned by a previous page of the same search.
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// pageToken is where the next page of a search starts. It is encoded as an opaque string,
+// so that its content can change without breaking the clients.
+type pageToken struct {
+	// Query is a hash of the search parameters, so that the token is only accepted for the same search
+	Query string `json:"query"`
+	// StartTimeMin and StartTimeMax are the time window of the first page, which the next pages search
+	StartTimeMin time.Time `json:"start"`
+	StartTimeMax time.Time `json:"end"`
+	Offset       int       `json:"offset"`
+	// Cursor is where the next page starts in the storage, when it pages through the traces itself
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// FindTracesPage runs the search like FindTraces and returns the requested number of traces starting at
+// the page token, the most recent first, and the token of the next page if there are more traces.
+// The storage pages through the traces when it supports it, otherwise the search runs again for each
+// page. The search is not paged when the number of traces is not limited. The pages cannot go past the
+// maximum number of traces of the search limits. The token is rejected if the search parameters other
+// than the number of traces and the time window differ from the ones of the first page. The next pages
+// search the time window of the first page, so that a window relative to the current time, e.g. when
+// the start and end of an HTTP search are omitted, does not move between the pages.
+//
+// Running the search again is best-effort: the pages are offsets in the most recent traces at the time
+// of each request, so the spans written in between into the time window shift the following pages,
+// whose traces may then repeat or be skipped.
+func (qs QueryService) FindTracesPage(
+	ctx context.Context,
+	query *spanstore.TraceQueryParameters,
+	token string,
+) ([]*model.Trace, string, error) {
+	queryHash := hashQuery(query)
+	t, err := decodePageToken(token)
+	if err != nil {
+		return nil, "", err
+	}
+	q := *query
+	if token != "" {
+		if t.Query != queryHash {
+			return nil, "", ErrInvalidPageToken
+		}
+		q.StartTimeMin, q.StartTimeMax = t.StartTimeMin, t.StartTimeMax
+	}
+	query = &q
+	next := pageToken{Query: queryHash, StartTimeMin: query.StartTimeMin, StartTimeMax: query.StartTimeMax}
+	offset := t.Offset
+	limits := qs.options.Limits.FindTraces
+	if err := limits.checkLookback(query.StartTimeMin, query.StartTimeMax); err != nil {
+		return nil, "", err
+	}
+	limit, err := limits.limitResults(query.NumTraces)
+	if err != nil {
+		return nil, "", err
+	}
+	if limit <= 0 {
+		traces, err := qs.findTraces(ctx, query)
+		return traces, "", err
+	}
+	if _, err := limits.limitResults(offset + limit); err != nil {
+		return nil, "", err
+	}
+	traces, cursor, err := qs.findTracesPage(ctx, query, limit, t.Cursor)
+	if err != spanstore.ErrPagingNotSupported {
+		if err != nil || cursor == "" {
+			return traces, "", err
+		}
+		next.Offset, next.Cursor = offset+limit, cursor
+		return traces, encodePageToken(next), nil
+	}
+	if t.Cursor != "" {
+		return nil, "", ErrInvalidPageToken
+	}
+	// one more trace tells whether there is a next page
+	q.NumTraces = offset + limit + 1
+	traces, err = qs.findTraces(ctx, &q)
+	if err != nil {
+		return nil, "", err
+	}
+	sortTracesByStartTime(traces)
+	if offset >= len(traces) {
+		return []*model.Trace{}, "", nil
+	}
+	end := offset + limit
+	if end >= len(traces) {
+		return traces[offset:], "", nil
+	}
+	next.Offset = end
+	return traces[offset:end], encodePageToken(next), nil
+}
+
+// findTracesPage returns the page of the search starting at the storage cursor when the reader pages through
+// the traces itself, ErrPagingNotSupported otherwise.
+func (qs QueryService) findTracesPage(
+	ctx context.Context,
+	query *spanstore.TraceQueryParameters,
+	limit int,
+	cursor string,
+) ([]*model.Trace, string, error) {
+	q := *query
+	q.NumTraces = limit
+	var next string
+	traces, err := qs.searchTracesWith(ctx, &q, func(ctx context.Context, reader spanstore.Reader, query *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
+		var traces []*model.Trace
+		var err error
+		traces, next, err = spanstore.FindTracesPage(ctx, reader, query, cursor)
+		return traces, err
+	})
+	if err == spanstore.ErrPagingNotSupported {
+		return nil, "", err
+	}
+	if err == spanstore.ErrInvalidPageCursor {
+		err = ErrInvalidPageToken
+	}
+	if qs.options.Auditor != nil {
+		qs.options.Auditor.TracesSearched(ctx, &q, traces, err)
+	}
+	return traces, next, err
+}
+
+// sortTracesByStartTime sorts the traces from the most recent, so that the pages of a search do not
+// depend on the order in which the storage returned the traces.
+func sortTracesByStartTime(traces []*model.Trace) {
+	starts := make(map[*model.Trace]time.Time, len(traces))
+	for _, trace := range traces {
+		var start time.Time
+		for i, span := range trace.Spans {
+			if i == 0 || span.StartTime.Before(start) {
+				start = span.StartTime
+			}
+		}
+		starts[trace] = start
+	}
+	sort.SliceStable(traces, func(i, j int) bool {
+		si, sj := starts[traces[i]], starts[traces[j]]
+		if !si.Equal(sj) {
+			return si.After(sj)
+		}
+		return traceIDOf(traces[i]).String() < traceIDOf(traces[j]).String()
+	})
+}
+
+func traceIDOf(trace *model.Trace) model.TraceID {
+	if len(trace.Spans) == 0 {
+		return model.TraceID{}
+	}
+	return trace.Spans[0].TraceID
+}
+
+// hashQuery returns a hash of the search parameters except for the number of traces, which may
+// change between the pages, and the time window, which is kept in the page token.
+func hashQuery(query *spanstore.TraceQueryParameters) string {
+	q := *query
+	q.NumTraces = 0
+	q.StartTimeMin, q.StartTimeMax = time.Time{}, time.Time{}
+	// the tags are a map, which is marshalled with sorted keys
+	data, _ := json.Marshal(q)
+	h := fnv.New64a()
+	h.Write(data)
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+func encodePageToken(token pageToken) string {
+	data, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodePageToken(token string) (pageToken, error) {
+	var t pageToken
+	if token == "" {
+		return t, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return t, ErrInvalidPageToken
+	}
+	if err := json.Unmarshal(data, &t); err != nil || t.Offset < 0 {
+		return t, ErrInvalidPageToken
+	}
+	return t, nil
+}
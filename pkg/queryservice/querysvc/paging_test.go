@@ -0,0 +1,194 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package querysvc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	depsmocks "github.com/jaegertracing/jaeger/storage/dependencystore/mocks"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	spanstoremocks "github.com/jaegertracing/jaeger/storage/spanstore/mocks"
+)
+
+func pagedTraces(n int) []*model.Trace {
+	start := time.Unix(1600000000, 0)
+	traces := make([]*model.Trace, n)
+	for i := range traces {
+		// the storage does not return the traces in order
+		traces[i] = &model.Trace{Spans: []*model.Span{{
+			TraceID:   model.NewTraceID(0, uint64(i+1)),
+			StartTime: start.Add(time.Duration((i*7)%n) * time.Second),
+		}}}
+	}
+	return traces
+}
+
+func TestFindTracesPage(t *testing.T) {
+	qs, readMock, _ := initializeTestService()
+	traces := pagedTraces(5)
+	// the storage returns the most recent traces, from the oldest one
+	readMock.On("FindTraces", mock.Anything, mock.Anything).Return(func(ctx context.Context, q *spanstore.TraceQueryParameters) []*model.Trace {
+		found := append([]*model.Trace(nil), traces...)
+		sortTracesByStartTime(found)
+		if q.NumTraces < len(found) {
+			found = found[:q.NumTraces]
+		}
+		for i, j := 0, len(found)-1; i < j; i, j = i+1, j-1 {
+			found[i], found[j] = found[j], found[i]
+		}
+		return found
+	}, nil)
+
+	var seen []time.Time
+	token := ""
+	for page := 0; page < 3; page++ {
+		res, next, err := qs.FindTracesPage(context.Background(), &spanstore.TraceQueryParameters{NumTraces: 2}, token)
+		require.NoError(t, err)
+		for _, trace := range res {
+			seen = append(seen, trace.Spans[0].StartTime)
+		}
+		if page < 2 {
+			assert.Len(t, res, 2)
+			assert.NotEmpty(t, next)
+		} else {
+			assert.Len(t, res, 1)
+			assert.Empty(t, next)
+		}
+		token = next
+	}
+	require.Len(t, seen, 5)
+	for i := 1; i < len(seen); i++ {
+		assert.True(t, seen[i].Before(seen[i-1]), "the traces are sorted from the most recent")
+	}
+}
+
+func TestFindTracesPageUnlimited(t *testing.T) {
+	qs, readMock, _ := initializeTestService()
+	readMock.On("FindTraces", mock.Anything, &spanstore.TraceQueryParameters{}).Return(pagedTraces(3), nil)
+	res, next, err := qs.FindTracesPage(context.Background(), &spanstore.TraceQueryParameters{}, "")
+	require.NoError(t, err)
+	assert.Len(t, res, 3)
+	assert.Empty(t, next)
+}
+
+func TestFindTracesPagePastTheEnd(t *testing.T) {
+	qs, readMock, _ := initializeTestService()
+	readMock.On("FindTraces", mock.Anything, mock.Anything).Return(pagedTraces(3), nil)
+	query := &spanstore.TraceQueryParameters{NumTraces: 2}
+	res, next, err := qs.FindTracesPage(context.Background(), query, encodePageToken(pageToken{Query: hashQuery(query), Offset: 4}))
+	require.NoError(t, err)
+	assert.Empty(t, res)
+	assert.Empty(t, next)
+}
+
+func TestFindTracesPageErrors(t *testing.T) {
+	qs, readMock, _ := initializeTestService()
+	for _, token := range []string{"!", "bm90IGpzb24", encodePageToken(pageToken{Offset: -1})} {
+		_, _, err := qs.FindTracesPage(context.Background(), &spanstore.TraceQueryParameters{NumTraces: 2}, token)
+		assert.Equal(t, ErrInvalidPageToken, err, token)
+	}
+
+	errStorage := errors.New("storage error")
+	readMock.On("FindTraces", mock.Anything, mock.Anything).Return(nil, errStorage)
+	_, _, err := qs.FindTracesPage(context.Background(), &spanstore.TraceQueryParameters{NumTraces: 2}, "")
+	assert.Equal(t, errStorage, err)
+}
+
+func TestFindTracesPageWithStorageCursor(t *testing.T) {
+	pageMock := &spanstoremocks.PageReader{}
+	qs := NewQueryService(struct {
+		*spanstoremocks.Reader
+		*spanstoremocks.PageReader
+	}{&spanstoremocks.Reader{}, pageMock}, &depsmocks.Reader{}, QueryServiceOptions{})
+	query := &spanstore.TraceQueryParameters{NumTraces: 2}
+	pageMock.On("FindTracesPage", mock.Anything, query, "").Return(pagedTraces(2), "cursor", nil)
+	pageMock.On("FindTracesPage", mock.Anything, query, "cursor").Return(pagedTraces(1), "", nil)
+
+	res, next, err := qs.FindTracesPage(context.Background(), &spanstore.TraceQueryParameters{NumTraces: 2}, "")
+	require.NoError(t, err)
+	assert.Len(t, res, 2)
+	token, err := decodePageToken(next)
+	require.NoError(t, err)
+	assert.Equal(t, pageToken{Query: hashQuery(query), Offset: 2, Cursor: "cursor"}, token)
+
+	res, next, err = qs.FindTracesPage(context.Background(), &spanstore.TraceQueryParameters{NumTraces: 2}, next)
+	require.NoError(t, err)
+	assert.Len(t, res, 1)
+	assert.Empty(t, next)
+
+	pageMock.On("FindTracesPage", mock.Anything, query, "expired").Return(nil, "", spanstore.ErrInvalidPageCursor)
+	_, _, err = qs.FindTracesPage(context.Background(), &spanstore.TraceQueryParameters{NumTraces: 2},
+		encodePageToken(pageToken{Query: hashQuery(query), Cursor: "expired"}))
+	assert.Equal(t, ErrInvalidPageToken, err)
+}
+
+func TestFindTracesPageCursorNotSupported(t *testing.T) {
+	qs, _, _ := initializeTestService()
+	query := &spanstore.TraceQueryParameters{NumTraces: 2}
+	_, _, err := qs.FindTracesPage(context.Background(), query, encodePageToken(pageToken{Query: hashQuery(query), Cursor: "cursor"}))
+	assert.Equal(t, ErrInvalidPageToken, err)
+}
+
+func TestFindTracesPageOtherQuery(t *testing.T) {
+	qs, readMock, _ := initializeTestService()
+	var searched []*spanstore.TraceQueryParameters
+	readMock.On("FindTraces", mock.Anything, mock.Anything).Return(func(ctx context.Context, q *spanstore.TraceQueryParameters) []*model.Trace {
+		searched = append(searched, q)
+		return pagedTraces(5)
+	}, nil)
+	start := time.Unix(1600000000, 0)
+	query := &spanstore.TraceQueryParameters{
+		ServiceName:  "service",
+		Tags:         map[string]string{"a": "1", "b": "2"},
+		StartTimeMin: start,
+		StartTimeMax: start.Add(time.Hour),
+		NumTraces:    2,
+	}
+	_, next, err := qs.FindTracesPage(context.Background(), query, "")
+	require.NoError(t, err)
+	require.NotEmpty(t, next)
+
+	same := *query
+	same.Tags = map[string]string{"b": "2", "a": "1"}
+	same.StartTimeMin = start.Add(time.Minute)
+	same.StartTimeMax = start.Add(time.Hour + time.Minute)
+	same.NumTraces = 3
+	_, _, err = qs.FindTracesPage(context.Background(), &same, next)
+	require.NoError(t, err, "the page size and the time window may change between the pages")
+	require.Len(t, searched, 2)
+	assert.True(t, searched[1].StartTimeMin.Equal(start), "the next pages search the window of the first page")
+	assert.True(t, searched[1].StartTimeMax.Equal(start.Add(time.Hour)))
+
+	other := *query
+	other.ServiceName = "other"
+	_, _, err = qs.FindTracesPage(context.Background(), &other, next)
+	assert.Equal(t, ErrInvalidPageToken, err)
+
+	other = *query
+	other.Tags = map[string]string{"a": "1"}
+	_, _, err = qs.FindTracesPage(context.Background(), &other, next)
+	assert.Equal(t, ErrInvalidPageToken, err)
+
+	_, _, err = qs.FindTracesPage(context.Background(), query, encodePageToken(pageToken{Offset: 2}))
+	assert.Equal(t, ErrInvalidPageToken, err, "the tokens without a query are rejected")
+}
@@ -0,0 +1,415 @@
+// Copyright (c) 2019 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package querysvc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/model/adjuster"
+	"github.com/jaegertracing/jaeger/pkg/cache"
+	"github.com/jaegertracing/jaeger/pkg/multierror"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
+	"github.com/jaegertracing/jaeger/storage"
+	"github.com/jaegertracing/jaeger/storage/dependencystore"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	"github.com/jaegertracing/jaeger/storage/viewstore"
+)
+
+var (
+	errNoArchiveSpanStorage = errors.New("archive span storage was not configured")
+
+	// ErrForbidden occurs when the caller is not authorized to read the spans of a service
+	ErrForbidden = errors.New("not authorized to read the spans of the service")
+)
+
+const (
+	defaultMaxClockSkewAdjust = time.Second
+)
+
+// QueryServiceOptions has optional members of QueryService
+type QueryServiceOptions struct {
+	ArchiveSpanReader spanstore.Reader
+	ArchiveSpanWriter spanstore.Writer
+	Adjuster          adjuster.Adjuster
+	// Authorizer restricts the services the callers can read, all of them are readable when nil
+	Authorizer Authorizer
+	// Auditor records the traces read and the searches run by the callers, they are not recorded when nil
+	Auditor Auditor
+	// ViewStore persists the saved searches and the pinned traces, they are disabled when nil
+	ViewStore viewstore.Store
+	// MaxUploadedTraces is the number of uploaded traces kept in memory, the uploads are disabled when 0
+	MaxUploadedTraces int
+	// UploadedTracesTTL is how long the uploaded traces are kept, one hour by default
+	UploadedTracesTTL time.Duration
+	// Limits restrict the queries of the endpoints, there are no limits by default
+	Limits QueryLimits
+	// TenantSpanReaders are the span readers of the tenants having a dedicated storage, the spans of the
+	// other tenants are read from the shared storage and restricted to the tenant of the caller
+	TenantSpanReaders map[string]spanstore.Reader
+	// TenantServicesLookback is how far back the spans of the tenants are searched in the shared storage to find
+	// their services and operations, two days by default
+	TenantServicesLookback time.Duration
+}
+
+// Authorizer decides whether the caller found in the context can read the spans of a service.
+type Authorizer interface {
+	IsAllowed(ctx context.Context, service string) bool
+}
+
+// Auditor records the accesses of the callers found in the context to the traces, with the error
+// of the access if it failed.
+type Auditor interface {
+	TraceRead(ctx context.Context, traceID model.TraceID, err error)
+	TracesSearched(ctx context.Context, query *spanstore.TraceQueryParameters, traces []*model.Trace, err error)
+}
+
+// QueryService contains span utils required by the query-service.
+type QueryService struct {
+	spanReader       spanstore.Reader
+	dependencyReader dependencystore.Reader
+	options          QueryServiceOptions
+	uploads          *cache.LRU
+}
+
+// NewQueryService returns a new QueryService.
+func NewQueryService(spanReader spanstore.Reader, dependencyReader dependencystore.Reader, options QueryServiceOptions) *QueryService {
+	qsvc := &QueryService{
+		spanReader:       spanReader,
+		dependencyReader: dependencyReader,
+		options:          options,
+	}
+
+	if qsvc.options.Adjuster == nil {
+		qsvc.options.Adjuster = adjuster.Sequence(StandardAdjusters(defaultMaxClockSkewAdjust)...)
+	}
+	if qsvc.options.ViewStore == nil {
+		qsvc.options.ViewStore = viewstore.NewDisabledStore()
+	}
+	if qsvc.options.UploadedTracesTTL <= 0 {
+		qsvc.options.UploadedTracesTTL = defaultUploadedTracesTTL
+	}
+	if qsvc.options.TenantServicesLookback <= 0 {
+		qsvc.options.TenantServicesLookback = defaultTenantServicesLookback
+	}
+	qsvc.uploads = newUploadedTraces(qsvc.options)
+	return qsvc
+}
+
+// GetTrace is the queryService implementation of spanstore.Reader.GetTrace,
+// looking up the archive and then the uploaded traces when the trace is not found.
+func (qs QueryService) GetTrace(ctx context.Context, traceID model.TraceID) (*model.Trace, error) {
+	trace, err := qs.getTrace(ctx, traceID)
+	if qs.options.Auditor != nil {
+		qs.options.Auditor.TraceRead(ctx, traceID, err)
+	}
+	return trace, err
+}
+
+func (qs QueryService) getTrace(ctx context.Context, traceID model.TraceID) (*model.Trace, error) {
+	var trace *model.Trace
+	err := qs.options.Limits.GetTrace.run(ctx, "the retrieval of the trace", func(ctx context.Context) error {
+		var err error
+		reader, tenant := qs.tenantSpanReader(ctx)
+		trace, err = getTenantTrace(ctx, reader, traceID, tenant)
+		if err == spanstore.ErrTraceNotFound && qs.options.ArchiveSpanReader != nil {
+			// the archive storage is shared by all the tenants
+			trace, err = getTenantTrace(ctx, qs.options.ArchiveSpanReader, traceID, tenancy.GetTenant(ctx))
+		}
+		return err
+	})
+	if err == spanstore.ErrTraceNotFound {
+		if uploaded := qs.getUploadedTrace(ctx, traceID); uploaded != nil {
+			trace, err = uploaded, nil
+		}
+	}
+	if err == spanstore.ErrTraceNotFound {
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	if trace = qs.authorizeTrace(ctx, trace); trace == nil {
+		return nil, ErrForbidden
+	}
+	return trace, nil
+}
+
+// GetServices is the queryService implementation of spanstore.Reader.GetServices,
+// only returning the services the caller is authorized to read. The services of the shared
+// storage are not tagged with the tenants, so those returned to a tenant are the ones with
+// spans of the tenant within the tenant services lookback.
+func (qs QueryService) GetServices(ctx context.Context) ([]string, error) {
+	reader, tenant := qs.tenantSpanReader(ctx)
+	services, err := reader.GetServices(ctx)
+	if err == nil && tenant != "" {
+		services, err = qs.sharedTenantServices(ctx, reader, tenant, services)
+	}
+	if err != nil || qs.options.Authorizer == nil {
+		return services, err
+	}
+	allowed := make([]string, 0, len(services))
+	for _, service := range services {
+		if qs.options.Authorizer.IsAllowed(ctx, service) {
+			allowed = append(allowed, service)
+		}
+	}
+	return allowed, nil
+}
+
+// GetOperations is the queryService implementation of spanstore.Reader.GetOperations,
+// the operations returned to the tenants of the shared storage being restricted like by GetServices.
+func (qs QueryService) GetOperations(
+	ctx context.Context,
+	query spanstore.OperationQueryParameters,
+) ([]spanstore.Operation, error) {
+	if !qs.isAllowed(ctx, query.ServiceName) {
+		return nil, ErrForbidden
+	}
+	reader, tenant := qs.tenantSpanReader(ctx)
+	operations, err := reader.GetOperations(ctx, query)
+	if err != nil || tenant == "" {
+		return operations, err
+	}
+	queries := make([]spanstore.TraceQueryParameters, len(operations))
+	for i, operation := range operations {
+		queries[i].ServiceName = query.ServiceName
+		queries[i].OperationName = operation.Name
+	}
+	found, err := qs.findTenantSpans(ctx, reader, tenant, queries)
+	if err != nil {
+		return nil, err
+	}
+	kept := make([]spanstore.Operation, 0, len(operations))
+	for i, operation := range operations {
+		if found[i] {
+			kept = append(kept, operation)
+		}
+	}
+	return kept, nil
+}
+
+// FindTraces is the queryService implementation of spanstore.Reader.FindTraces,
+// also applying the tag filters that the span reader may not support.
+func (qs QueryService) FindTraces(ctx context.Context, query *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
+	limits := qs.options.Limits.FindTraces
+	if err := limits.checkLookback(query.StartTimeMin, query.StartTimeMax); err != nil {
+		return nil, err
+	}
+	numTraces, err := limits.limitResults(query.NumTraces)
+	if err != nil {
+		return nil, err
+	}
+	if numTraces != query.NumTraces {
+		q := *query
+		q.NumTraces = numTraces
+		query = &q
+	}
+	return qs.findTraces(ctx, query)
+}
+
+// findTraces runs the search within the timeout, its other limits must have been checked.
+func (qs QueryService) findTraces(ctx context.Context, query *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
+	traces, err := qs.searchTraces(ctx, query)
+	if qs.options.Auditor != nil {
+		qs.options.Auditor.TracesSearched(ctx, query, traces, err)
+	}
+	return traces, err
+}
+
+func (qs QueryService) searchTraces(ctx context.Context, query *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
+	return qs.searchTracesWith(ctx, query, func(ctx context.Context, reader spanstore.Reader, query *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
+		return reader.FindTraces(ctx, query)
+	})
+}
+
+// tracesFinder finds the traces of a search with the span reader of the caller.
+type tracesFinder func(ctx context.Context, reader spanstore.Reader, query *spanstore.TraceQueryParameters) ([]*model.Trace, error)
+
+// searchTracesWith runs the search with the finder and restricts the traces to those the caller can read.
+func (qs QueryService) searchTracesWith(ctx context.Context, query *spanstore.TraceQueryParameters, find tracesFinder) ([]*model.Trace, error) {
+	if !qs.isAllowed(ctx, query.ServiceName) {
+		return nil, ErrForbidden
+	}
+	reader, tenant := qs.tenantSpanReader(ctx)
+	var traces []*model.Trace
+	err := qs.options.Limits.FindTraces.run(ctx, "the search", func(ctx context.Context) error {
+		var err error
+		traces, err = find(ctx, reader, restrictQuery(query, tenant))
+		return err
+	})
+	if err != nil || (len(query.TagFilters) == 0 && qs.options.Authorizer == nil && tenant == "") {
+		return traces, err
+	}
+	filtered := traces[:0]
+	for _, trace := range traces {
+		if trace = restrictTrace(trace, tenant); trace == nil {
+			continue
+		}
+		if trace = qs.authorizeTrace(ctx, trace); trace == nil {
+			continue
+		}
+		if spanstore.MatchesTrace(trace, query.TagFilters) {
+			filtered = append(filtered, trace)
+		}
+	}
+	return filtered, nil
+}
+
+// getTenantTrace returns the trace restricted to the spans of the tenant, not found if it has none.
+func getTenantTrace(ctx context.Context, reader spanstore.Reader, traceID model.TraceID, tenant string) (*model.Trace, error) {
+	trace, err := reader.GetTrace(ctx, traceID)
+	if err != nil {
+		return nil, err
+	}
+	if trace = restrictTrace(trace, tenant); trace == nil {
+		return nil, spanstore.ErrTraceNotFound
+	}
+	return trace, nil
+}
+
+func (qs QueryService) isAllowed(ctx context.Context, service string) bool {
+	return qs.options.Authorizer == nil || qs.options.Authorizer.IsAllowed(ctx, service)
+}
+
+// authorizeTrace returns the trace with only the spans of the services the caller can read,
+// or nil if there are none. The trace is returned as is when all its spans can be read.
+func (qs QueryService) authorizeTrace(ctx context.Context, trace *model.Trace) *model.Trace {
+	if qs.options.Authorizer == nil {
+		return trace
+	}
+	var spans []*model.Span
+	for _, span := range trace.Spans {
+		if span.Process != nil && qs.options.Authorizer.IsAllowed(ctx, span.Process.ServiceName) {
+			spans = append(spans, span)
+		}
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+	if len(spans) == len(trace.Spans) {
+		return trace
+	}
+	authorized := *trace
+	authorized.Spans = spans
+	return &authorized
+}
+
+// ArchiveTrace is the queryService utility to archive traces.
+func (qs QueryService) ArchiveTrace(ctx context.Context, traceID model.TraceID) error {
+	if qs.options.ArchiveSpanWriter == nil {
+		return errNoArchiveSpanStorage
+	}
+	trace, err := qs.GetTrace(ctx, traceID)
+	if err != nil {
+		return err
+	}
+
+	var writeErrors []error
+	for _, span := range trace.Spans {
+		err := qs.options.ArchiveSpanWriter.WriteSpan(span)
+		if err != nil {
+			writeErrors = append(writeErrors, err)
+		}
+	}
+	return multierror.Wrap(writeErrors)
+}
+
+// Adjust applies adjusters to the trace.
+func (qs QueryService) Adjust(trace *model.Trace) (*model.Trace, error) {
+	return qs.options.Adjuster.Adjust(trace)
+}
+
+// GetDependencies is the queryService implementation of dependencystore.Reader.GetDependencies,
+// only returning the links with a parent or a child the caller is authorized to read. The dependency
+// storage is shared by all the tenants, so the links returned to a tenant are those between two of its services.
+func (qs QueryService) GetDependencies(ctx context.Context, endTs time.Time, lookback time.Duration) ([]model.DependencyLink, error) {
+	if err := qs.options.Limits.GetDependencies.checkDuration(lookback); err != nil {
+		return nil, err
+	}
+	services, err := qs.tenantServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dependencies, err := qs.dependencyReader.GetDependencies(endTs, lookback)
+	if err != nil || (services == nil && qs.options.Authorizer == nil) {
+		return dependencies, err
+	}
+	allowed := make([]model.DependencyLink, 0, len(dependencies))
+	for _, link := range dependencies {
+		if services != nil && (!services[link.Parent] || !services[link.Child]) {
+			continue
+		}
+		if qs.options.Authorizer == nil || qs.options.Authorizer.IsAllowed(ctx, link.Parent) || qs.options.Authorizer.IsAllowed(ctx, link.Child) {
+			allowed = append(allowed, link)
+		}
+	}
+	return allowed, nil
+}
+
+// ViewStore returns the store of the saved searches and the pinned traces, keeping apart those of each tenant.
+func (qs QueryService) ViewStore() viewstore.Store {
+	return tenantViewStore{store: qs.options.ViewStore}
+}
+
+// InitViewStorage tries to initialize the view store if storage factory supports it.
+func (opts *QueryServiceOptions) InitViewStorage(storageFactory storage.Factory, logger *zap.Logger) bool {
+	viewFactory, ok := storageFactory.(storage.ViewFactory)
+	if !ok {
+		logger.Info("View storage not supported by the factory")
+		return false
+	}
+	store, err := viewFactory.CreateViewStore()
+	if err != nil {
+		logger.Error("Cannot init view storage", zap.Error(err))
+		return false
+	}
+	opts.ViewStore = store
+	return true
+}
+
+// InitArchiveStorage tries to initialize archive storage reader/writer if storage factory supports them.
+func (opts *QueryServiceOptions) InitArchiveStorage(storageFactory storage.Factory, logger *zap.Logger) bool {
+	archiveFactory, ok := storageFactory.(storage.ArchiveFactory)
+	if !ok {
+		logger.Info("Archive storage not supported by the factory")
+		return false
+	}
+	reader, err := archiveFactory.CreateArchiveSpanReader()
+	if err == storage.ErrArchiveStorageNotConfigured || err == storage.ErrArchiveStorageNotSupported {
+		logger.Info("Archive storage not created", zap.String("reason", err.Error()))
+		return false
+	}
+	if err != nil {
+		logger.Error("Cannot init archive storage reader", zap.Error(err))
+		return false
+	}
+	writer, err := archiveFactory.CreateArchiveSpanWriter()
+	if err == storage.ErrArchiveStorageNotConfigured || err == storage.ErrArchiveStorageNotSupported {
+		logger.Info("Archive storage not created", zap.String("reason", err.Error()))
+		return false
+	}
+	if err != nil {
+		logger.Error("Cannot init archive storage writer", zap.Error(err))
+		return false
+	}
+	opts.ArchiveSpanReader = reader
+	opts.ArchiveSpanWriter = writer
+	return true
+}
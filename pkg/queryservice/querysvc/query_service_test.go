@@ -22,6 +22,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"github.com/uber/jaeger-lib/metrics"
 	"go.uber.org/zap"
 
@@ -32,6 +33,7 @@ import (
 	depsmocks "github.com/jaegertracing/jaeger/storage/dependencystore/mocks"
 	"github.com/jaegertracing/jaeger/storage/spanstore"
 	spanstoremocks "github.com/jaegertracing/jaeger/storage/spanstore/mocks"
+	"github.com/jaegertracing/jaeger/storage/viewstore"
 )
 
 const millisToNanosMultiplier = int64(time.Millisecond / time.Nanosecond)
@@ -183,6 +185,34 @@ func TestFindTraces(t *testing.T) {
 	assert.Len(t, traces, 1)
 }
 
+// Test QueryService.FindTraces() dropping the traces not matching the tag filters.
+func TestFindTracesWithTagFilters(t *testing.T) {
+	qs, readMock, _ := initializeTestService()
+	errorTrace := &model.Trace{
+		Spans: []*model.Span{
+			{
+				TraceID: model.NewTraceID(0, 1),
+				SpanID:  model.NewSpanID(1),
+				Tags:    model.KeyValues{model.Int64("http.status_code", 503)},
+				Process: &model.Process{},
+			},
+		},
+	}
+	readMock.On("FindTraces", mock.Anything, mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+		Return([]*model.Trace{mockTrace, errorTrace}, nil).Once()
+
+	filter, err := spanstore.ParseTagFilter("http.status_code>=500")
+	require.NoError(t, err)
+	params := &spanstore.TraceQueryParameters{
+		ServiceName: "service",
+		NumTraces:   200,
+		TagFilters:  []spanstore.TagFilter{filter},
+	}
+	traces, err := qs.FindTraces(context.Background(), params)
+	assert.NoError(t, err)
+	assert.Equal(t, []*model.Trace{errorTrace}, traces)
+}
+
 // Test QueryService.ArchiveTrace() with no ArchiveSpanWriter.
 func TestArchiveTraceNoOptions(t *testing.T) {
 	qs, _, _ := initializeTestService()
@@ -259,7 +289,7 @@ func TestGetDependencies(t *testing.T) {
 	endTs := time.Unix(0, 1476374248550*millisToNanosMultiplier)
 	depsMock.On("GetDependencies", endTs, defaultDependencyLookbackDuration).Return(expectedDependencies, nil).Times(1)
 
-	actualDependencies, err := qs.GetDependencies(time.Unix(0, 1476374248550*millisToNanosMultiplier), defaultDependencyLookbackDuration)
+	actualDependencies, err := qs.GetDependencies(context.Background(), time.Unix(0, 1476374248550*millisToNanosMultiplier), defaultDependencyLookbackDuration)
 	assert.NoError(t, err)
 	assert.Equal(t, expectedDependencies, actualDependencies)
 }
@@ -285,8 +315,17 @@ func (*fakeStorageFactory1) CreateDependencyReader() (dependencystore.Reader, er
 func (f *fakeStorageFactory2) CreateArchiveSpanReader() (spanstore.Reader, error) { return f.r, f.rErr }
 func (f *fakeStorageFactory2) CreateArchiveSpanWriter() (spanstore.Writer, error) { return f.w, f.wErr }
 
+type fakeStorageFactory3 struct {
+	fakeStorageFactory1
+	s    viewstore.Store
+	sErr error
+}
+
+func (f *fakeStorageFactory3) CreateViewStore() (viewstore.Store, error) { return f.s, f.sErr }
+
 var _ storage.Factory = new(fakeStorageFactory1)
 var _ storage.ArchiveFactory = new(fakeStorageFactory2)
+var _ storage.ViewFactory = new(fakeStorageFactory3)
 
 func TestInitArchiveStorageErrors(t *testing.T) {
 	opts := &QueryServiceOptions{}
@@ -323,3 +362,16 @@ func TestInitArchiveStorage(t *testing.T) {
 	assert.Equal(t, reader, opts.ArchiveSpanReader)
 	assert.Equal(t, writer, opts.ArchiveSpanWriter)
 }
+
+func TestInitViewStorage(t *testing.T) {
+	opts := &QueryServiceOptions{}
+	logger := zap.NewNop()
+	assert.False(t, opts.InitViewStorage(new(fakeStorageFactory1), logger))
+	assert.False(t, opts.InitViewStorage(&fakeStorageFactory3{sErr: errors.New("error")}, logger))
+	assert.Nil(t, opts.ViewStore)
+	assert.Equal(t, viewstore.NewDisabledStore(), NewQueryService(nil, nil, *opts).options.ViewStore)
+
+	store := viewstore.NewDisabledStore()
+	assert.True(t, opts.InitViewStorage(&fakeStorageFactory3{s: store}, logger))
+	assert.Equal(t, store, opts.ViewStore)
+}
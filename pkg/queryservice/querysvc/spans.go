@@ -0,0 +1,70 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package querysvc
+
+import (
+	"context"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+// FindSpans runs the search like FindTraces and returns the spans of the traces found that match the
+// query themselves: the spans of its service and operation, with its tags, and lasting and starting
+// within its bounds. The spans are returned as they are stored, without adjusting their traces.
+func (qs QueryService) FindSpans(ctx context.Context, query *spanstore.TraceQueryParameters) ([]*model.Span, error) {
+	filters := make([]spanstore.TagFilter, 0, len(query.Tags)+len(query.TagFilters))
+	for key, value := range query.Tags {
+		filter, err := spanstore.NewTagFilter(key, spanstore.TagEquals, value)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+	filters = append(filters, query.TagFilters...)
+
+	traces, err := qs.FindTraces(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	spans := []*model.Span{}
+	for _, trace := range traces {
+		for _, span := range trace.Spans {
+			if matchesSpan(span, query) && spanstore.MatchesSpan(span, filters) {
+				spans = append(spans, span)
+			}
+		}
+	}
+	return spans, nil
+}
+
+// matchesSpan returns true if the span matches the query, except for its tags.
+func matchesSpan(span *model.Span, query *spanstore.TraceQueryParameters) bool {
+	if span.Process == nil || span.Process.ServiceName != query.ServiceName {
+		return false
+	}
+	if query.OperationName != "" && span.OperationName != query.OperationName {
+		return false
+	}
+	if (query.DurationMin != 0 && span.Duration < query.DurationMin) ||
+		(query.DurationMax != 0 && span.Duration > query.DurationMax) {
+		return false
+	}
+	if (!query.StartTimeMin.IsZero() && span.StartTime.Before(query.StartTimeMin)) ||
+		(!query.StartTimeMax.IsZero() && span.StartTime.After(query.StartTimeMax)) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package querysvc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+func TestFindSpans(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	span := func(id uint64, service, operation string, duration time.Duration, tags ...model.KeyValue) *model.Span {
+		return &model.Span{
+			TraceID:       model.NewTraceID(0, 1),
+			SpanID:        model.NewSpanID(id),
+			OperationName: operation,
+			StartTime:     start,
+			Duration:      duration,
+			Tags:          tags,
+			Process:       model.NewProcess(service, nil),
+		}
+	}
+	statement := model.String("db.statement", "SELECT * FROM users")
+	trace := &model.Trace{Spans: []*model.Span{
+		span(1, "frontend", "GET /", time.Second),
+		span(2, "mysql", "SELECT", 50*time.Millisecond, statement, model.Bool("error", true)),
+		span(3, "mysql", "SELECT", 5*time.Millisecond, statement, model.Bool("error", true)),
+		span(4, "mysql", "SELECT", 50*time.Millisecond, model.String("db.statement", "SELECT * FROM orders")),
+		span(5, "mysql", "INSERT", 50*time.Millisecond, statement),
+	}}
+	filter, err := spanstore.ParseTagFilter("db.statement~*users*")
+	require.NoError(t, err)
+	query := &spanstore.TraceQueryParameters{
+		ServiceName:   "mysql",
+		OperationName: "SELECT",
+		Tags:          map[string]string{"error": "true"},
+		TagFilters:    []spanstore.TagFilter{filter},
+		StartTimeMin:  start.Add(-time.Hour),
+		StartTimeMax:  start,
+		DurationMin:   10 * time.Millisecond,
+	}
+
+	qs, readMock, _ := initializeTestService()
+	readMock.On("FindTraces", mock.Anything, query).Return([]*model.Trace{trace}, nil).Once()
+	spans, err := qs.FindSpans(context.Background(), query)
+	require.NoError(t, err)
+	assert.Equal(t, []*model.Span{trace.Spans[1]}, spans)
+
+	errStorage := errors.New("storage error")
+	readMock.On("FindTraces", mock.Anything, mock.Anything).Return(nil, errStorage).Once()
+	_, err = qs.FindSpans(context.Background(), &spanstore.TraceQueryParameters{ServiceName: "mysql"})
+	assert.Equal(t, errStorage, err)
+}
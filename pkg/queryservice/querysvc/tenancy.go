@@ -0,0 +1,300 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package querysvc
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
+	"github.com/jaegertracing/jaeger/storage"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	"github.com/jaegertracing/jaeger/storage/viewstore"
+)
+
+const (
+	defaultTenantServicesLookback = 48 * time.Hour
+	// tenantSearchConcurrency limits the searches run concurrently to find the services and the operations of a tenant
+	tenantSearchConcurrency = 8
+)
+
+// tenantSpanReader returns the span reader of the tenant of the caller, and the tenant the traces
+// it returns must be restricted to. The readers dedicated to a tenant only hold the spans of the
+// tenant, so their traces are not restricted.
+func (qs QueryService) tenantSpanReader(ctx context.Context) (spanstore.Reader, string) {
+	tenant := tenancy.GetTenant(ctx)
+	if tenant == "" {
+		return qs.spanReader, ""
+	}
+	if reader, ok := qs.options.TenantSpanReaders[tenant]; ok {
+		return reader, ""
+	}
+	return qs.spanReader, tenant
+}
+
+// restrictTrace returns the trace with only the spans of the tenant, which the collector recorded
+// in their process tags, or nil if there are none. The trace is returned as is when the tenant is empty.
+func restrictTrace(trace *model.Trace, tenant string) *model.Trace {
+	if tenant == "" {
+		return trace
+	}
+	var spans []*model.Span
+	for _, span := range trace.Spans {
+		if tenancy.GetSpanTenant(span) == tenant {
+			spans = append(spans, span)
+		}
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+	if len(spans) == len(trace.Spans) {
+		return trace
+	}
+	restricted := *trace
+	restricted.Spans = spans
+	return &restricted
+}
+
+// restrictQuery returns the search with the tenant among the tags, so that the storage only returns
+// the traces of the tenant and the limit on their number is not used up by the other tenants.
+func restrictQuery(query *spanstore.TraceQueryParameters, tenant string) *spanstore.TraceQueryParameters {
+	if tenant == "" {
+		return query
+	}
+	q := *query
+	q.Tags = make(map[string]string, len(query.Tags)+1)
+	for k, v := range query.Tags {
+		q.Tags[k] = v
+	}
+	q.Tags[tenancy.TenantTag] = tenant
+	return &q
+}
+
+// tenantServices returns the services of the tenant of the caller, or nil when the caller has no tenant.
+// The services of a dedicated reader all belong to the tenant, those of the shared storage are restricted
+// to the services with spans of the tenant like by GetServices.
+func (qs QueryService) tenantServices(ctx context.Context) (map[string]bool, error) {
+	if tenancy.GetTenant(ctx) == "" {
+		return nil, nil
+	}
+	reader, tenant := qs.tenantSpanReader(ctx)
+	services, err := reader.GetServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tenant != "" {
+		if services, err = qs.sharedTenantServices(ctx, reader, tenant, services); err != nil {
+			return nil, err
+		}
+	}
+	allowed := make(map[string]bool, len(services))
+	for _, service := range services {
+		allowed[service] = true
+	}
+	return allowed, nil
+}
+
+// sharedTenantServices returns the services of the shared storage with spans of the tenant, which the services
+// and the operations of the storage are not tagged with.
+func (qs QueryService) sharedTenantServices(ctx context.Context, reader spanstore.Reader, tenant string, services []string) ([]string, error) {
+	queries := make([]spanstore.TraceQueryParameters, len(services))
+	for i, service := range services {
+		queries[i].ServiceName = service
+	}
+	found, err := qs.findTenantSpans(ctx, reader, tenant, queries)
+	if err != nil {
+		return nil, err
+	}
+	kept := make([]string, 0, len(services))
+	for i, service := range services {
+		if found[i] {
+			kept = append(kept, service)
+		}
+	}
+	return kept, nil
+}
+
+// findTenantSpans runs the searches for a single trace of the tenant within the tenant services lookback,
+// and returns which of them found one.
+func (qs QueryService) findTenantSpans(ctx context.Context, reader spanstore.Reader, tenant string, queries []spanstore.TraceQueryParameters) ([]bool, error) {
+	now := time.Now()
+	found := make([]bool, len(queries))
+	errs := make([]error, len(queries))
+	sem := make(chan struct{}, tenantSearchConcurrency)
+	var wg sync.WaitGroup
+	for i := range queries {
+		query := queries[i]
+		query.StartTimeMin = now.Add(-qs.options.TenantServicesLookback)
+		query.StartTimeMax = now
+		query.NumTraces = 1
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, query *spanstore.TraceQueryParameters) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			// not all the span readers implement FindTraceIDs
+			traces, err := reader.FindTraces(ctx, restrictQuery(query, tenant))
+			found[i], errs[i] = len(traces) > 0, err
+		}(i, &query)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// InitTenantStorage tries to initialize the span readers dedicated to the tenants if storage factory supports them.
+func (opts *QueryServiceOptions) InitTenantStorage(storageFactory storage.Factory, tenants []string, logger *zap.Logger) bool {
+	tenantFactory, ok := storageFactory.(storage.TenantFactory)
+	if !ok {
+		logger.Info("Tenant storage not supported by the factory")
+		return false
+	}
+	readers := make(map[string]spanstore.Reader, len(tenants))
+	for _, tenant := range tenants {
+		reader, err := tenantFactory.CreateTenantSpanReader(tenant)
+		if err == storage.ErrTenantStorageNotSupported {
+			logger.Info("Tenant storage not created", zap.String("reason", err.Error()))
+			return false
+		}
+		if err != nil {
+			logger.Error("Cannot init tenant storage reader", zap.String("tenant", tenant), zap.Error(err))
+			return false
+		}
+		readers[tenant] = reader
+	}
+	opts.TenantSpanReaders = readers
+	return true
+}
+
+// tenantKey returns the key of a view or an uploaded trace of the tenant, the tenant being escaped so that
+// the keys of two tenants never collide.
+func tenantKey(tenant, key string) string {
+	if tenant == "" {
+		return key
+	}
+	return url.PathEscape(tenant) + "/" + key
+}
+
+// tenantViewStore keeps the saved searches and the pinned traces of each tenant apart in the shared store,
+// by prefixing their keys with the tenant of the caller.
+type tenantViewStore struct {
+	store viewstore.Store
+}
+
+// GetSearches implements viewstore.Store
+func (s tenantViewStore) GetSearches(ctx context.Context) ([]*viewstore.SavedSearch, error) {
+	searches, err := s.store.GetSearches(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prefix := tenantKey(tenancy.GetTenant(ctx), "")
+	filtered := make([]*viewstore.SavedSearch, 0, len(searches))
+	for _, search := range searches {
+		if id, ok := trimTenant(search.ID, prefix); ok {
+			copied := *search
+			copied.ID = id
+			filtered = append(filtered, &copied)
+		}
+	}
+	return filtered, nil
+}
+
+// GetSearch implements viewstore.Store
+func (s tenantViewStore) GetSearch(ctx context.Context, id string) (*viewstore.SavedSearch, error) {
+	search, err := s.store.GetSearch(ctx, tenantKey(tenancy.GetTenant(ctx), id))
+	if err != nil {
+		return nil, err
+	}
+	copied := *search
+	copied.ID = id
+	return &copied, nil
+}
+
+// SaveSearch implements viewstore.Store
+func (s tenantViewStore) SaveSearch(ctx context.Context, search *viewstore.SavedSearch) error {
+	copied := *search
+	copied.ID = tenantKey(tenancy.GetTenant(ctx), search.ID)
+	return s.store.SaveSearch(ctx, &copied)
+}
+
+// DeleteSearch implements viewstore.Store
+func (s tenantViewStore) DeleteSearch(ctx context.Context, id string) error {
+	return s.store.DeleteSearch(ctx, tenantKey(tenancy.GetTenant(ctx), id))
+}
+
+// GetPinnedTraces implements viewstore.Store
+func (s tenantViewStore) GetPinnedTraces(ctx context.Context) ([]*viewstore.PinnedTrace, error) {
+	pins, err := s.store.GetPinnedTraces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prefix := tenantKey(tenancy.GetTenant(ctx), "")
+	filtered := make([]*viewstore.PinnedTrace, 0, len(pins))
+	for _, pin := range pins {
+		if traceID, ok := trimTenant(pin.TraceID, prefix); ok {
+			copied := *pin
+			copied.TraceID = traceID
+			filtered = append(filtered, &copied)
+		}
+	}
+	return filtered, nil
+}
+
+// GetPinnedTrace implements viewstore.Store
+func (s tenantViewStore) GetPinnedTrace(ctx context.Context, traceID string) (*viewstore.PinnedTrace, error) {
+	pin, err := s.store.GetPinnedTrace(ctx, tenantKey(tenancy.GetTenant(ctx), traceID))
+	if err != nil {
+		return nil, err
+	}
+	copied := *pin
+	copied.TraceID = traceID
+	return &copied, nil
+}
+
+// PinTrace implements viewstore.Store
+func (s tenantViewStore) PinTrace(ctx context.Context, pin *viewstore.PinnedTrace) error {
+	copied := *pin
+	copied.TraceID = tenantKey(tenancy.GetTenant(ctx), pin.TraceID)
+	return s.store.PinTrace(ctx, &copied)
+}
+
+// UnpinTrace implements viewstore.Store
+func (s tenantViewStore) UnpinTrace(ctx context.Context, traceID string) error {
+	return s.store.UnpinTrace(ctx, tenantKey(tenancy.GetTenant(ctx), traceID))
+}
+
+// trimTenant returns the key of the view without the prefix of the tenant, and false if the view belongs
+// to another tenant. The views saved without a tenant have no prefix.
+func trimTenant(key, prefix string) (string, bool) {
+	if prefix == "" {
+		return key, !strings.Contains(key, "/")
+	}
+	if !strings.HasPrefix(key, prefix) {
+		return "", false
+	}
+	return key[len(prefix):], true
+}
@@ -0,0 +1,276 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package querysvc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
+	"github.com/jaegertracing/jaeger/plugin/storage/memory"
+	"github.com/jaegertracing/jaeger/storage"
+	depsmocks "github.com/jaegertracing/jaeger/storage/dependencystore/mocks"
+	"github.com/jaegertracing/jaeger/storage/mocks"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	spanstoremocks "github.com/jaegertracing/jaeger/storage/spanstore/mocks"
+	"github.com/jaegertracing/jaeger/storage/viewstore"
+)
+
+// tenantTraceOf returns a trace with a span of each of the tenants
+func tenantTraceOf(traceID uint64, tenants ...string) *model.Trace {
+	trace := &model.Trace{}
+	for i, tenant := range tenants {
+		span := &model.Span{
+			TraceID: model.NewTraceID(0, traceID),
+			SpanID:  model.NewSpanID(uint64(i + 1)),
+			Process: model.NewProcess("service", nil),
+		}
+		tenancy.SetSpanTenant(span, tenant)
+		trace.Spans = append(trace.Spans, span)
+	}
+	return trace
+}
+
+func TestTenantGetTrace(t *testing.T) {
+	readMock, archiveMock := &spanstoremocks.Reader{}, &spanstoremocks.Reader{}
+	qs := NewQueryService(readMock, &depsmocks.Reader{}, QueryServiceOptions{ArchiveSpanReader: archiveMock})
+	ctx := tenancy.WithTenant(context.Background(), "acme")
+	readMock.On("GetTrace", mock.Anything, model.NewTraceID(0, 1)).Return(tenantTraceOf(1, "acme", "megacorp"), nil)
+	readMock.On("GetTrace", mock.Anything, model.NewTraceID(0, 2)).Return(tenantTraceOf(2, "megacorp"), nil)
+	archiveMock.On("GetTrace", mock.Anything, model.NewTraceID(0, 2)).Return(tenantTraceOf(2, "megacorp"), nil)
+
+	trace, err := qs.GetTrace(ctx, model.NewTraceID(0, 1))
+	require.NoError(t, err)
+	assert.Equal(t, tenantTraceOf(1, "acme").Spans, trace.Spans)
+	_, err = qs.GetTrace(ctx, model.NewTraceID(0, 2))
+	assert.Equal(t, spanstore.ErrTraceNotFound, err)
+
+	// the traces are not restricted without a tenant
+	trace, err = qs.GetTrace(context.Background(), model.NewTraceID(0, 1))
+	require.NoError(t, err)
+	assert.Len(t, trace.Spans, 2)
+}
+
+func TestTenantFindTraces(t *testing.T) {
+	readMock := &spanstoremocks.Reader{}
+	qs := NewQueryService(readMock, &depsmocks.Reader{}, QueryServiceOptions{})
+	readMock.On("FindTraces", mock.Anything, &spanstore.TraceQueryParameters{
+		ServiceName: "service",
+		Tags:        map[string]string{"http.method": "GET", tenancy.TenantTag: "acme"},
+	}).Return([]*model.Trace{tenantTraceOf(1, "acme"), tenantTraceOf(2, "megacorp")}, nil).Once()
+
+	// the tenant tag of the query cannot select another tenant
+	query := &spanstore.TraceQueryParameters{
+		ServiceName: "service",
+		Tags:        map[string]string{"http.method": "GET", tenancy.TenantTag: "megacorp"},
+	}
+	traces, err := qs.FindTraces(tenancy.WithTenant(context.Background(), "acme"), query)
+	require.NoError(t, err)
+	assert.Equal(t, []*model.Trace{tenantTraceOf(1, "acme")}, traces)
+	assert.Equal(t, "megacorp", query.Tags[tenancy.TenantTag], "the query of the caller must not be modified")
+	readMock.AssertExpectations(t)
+}
+
+// tenantQuery matches the searches of a trace of the tenant within the tenant services lookback
+func tenantQuery(tenant, service, operation string) interface{} {
+	return mock.MatchedBy(func(query *spanstore.TraceQueryParameters) bool {
+		return query.ServiceName == service && query.OperationName == operation && query.Tags[tenancy.TenantTag] == tenant &&
+			query.NumTraces == 1 && query.StartTimeMax.Sub(query.StartTimeMin) == defaultTenantServicesLookback
+	})
+}
+
+func TestTenantSpanReaders(t *testing.T) {
+	sharedMock, acmeMock := &spanstoremocks.Reader{}, &spanstoremocks.Reader{}
+	qs := NewQueryService(sharedMock, &depsmocks.Reader{}, QueryServiceOptions{
+		TenantSpanReaders: map[string]spanstore.Reader{"acme": acmeMock},
+	})
+	acme := tenancy.WithTenant(context.Background(), "acme")
+	acmeMock.On("GetServices", mock.Anything).Return([]string{"acme-service"}, nil)
+	acmeMock.On("GetOperations", mock.Anything, spanstore.OperationQueryParameters{ServiceName: "acme-service"}).
+		Return([]spanstore.Operation{{Name: "GET /"}}, nil)
+	// the spans of the dedicated storage may not be tagged with the tenant
+	acmeMock.On("GetTrace", mock.Anything, model.NewTraceID(0, 1)).Return(traceOf(1, "acme-service"), nil)
+	acmeMock.On("FindTraces", mock.Anything, &spanstore.TraceQueryParameters{ServiceName: "acme-service"}).
+		Return([]*model.Trace{traceOf(1, "acme-service")}, nil)
+	sharedMock.On("GetServices", mock.Anything).Return([]string{"service", "other"}, nil)
+	sharedMock.On("GetOperations", mock.Anything, spanstore.OperationQueryParameters{ServiceName: "service"}).
+		Return([]spanstore.Operation{{Name: "GET /"}, {Name: "POST /"}}, nil)
+	sharedMock.On("FindTraces", mock.Anything, tenantQuery("megacorp", "service", "")).Return([]*model.Trace{tenantTraceOf(2, "megacorp")}, nil)
+	sharedMock.On("FindTraces", mock.Anything, tenantQuery("megacorp", "service", "GET /")).Return([]*model.Trace{tenantTraceOf(2, "megacorp")}, nil)
+	sharedMock.On("FindTraces", mock.Anything, mock.Anything).Return(nil, nil)
+
+	services, err := qs.GetServices(acme)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"acme-service"}, services)
+	operations, err := qs.GetOperations(acme, spanstore.OperationQueryParameters{ServiceName: "acme-service"})
+	require.NoError(t, err)
+	assert.Len(t, operations, 1)
+	trace, err := qs.GetTrace(acme, model.NewTraceID(0, 1))
+	require.NoError(t, err)
+	assert.Len(t, trace.Spans, 1)
+	traces, err := qs.FindTraces(acme, &spanstore.TraceQueryParameters{ServiceName: "acme-service"})
+	require.NoError(t, err)
+	assert.Len(t, traces, 1)
+
+	// the services and the operations of the shared storage are those with spans of the tenant
+	megacorp := tenancy.WithTenant(context.Background(), "megacorp")
+	services, err = qs.GetServices(megacorp)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"service"}, services)
+	operations, err = qs.GetOperations(megacorp, spanstore.OperationQueryParameters{ServiceName: "service"})
+	require.NoError(t, err)
+	assert.Equal(t, []spanstore.Operation{{Name: "GET /"}}, operations)
+	services, err = qs.GetServices(tenancy.WithTenant(context.Background(), "initech"))
+	require.NoError(t, err)
+	assert.Empty(t, services)
+	services, err = qs.GetServices(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"service", "other"}, services)
+}
+
+func TestTenantServicesError(t *testing.T) {
+	readMock := &spanstoremocks.Reader{}
+	qs := NewQueryService(readMock, &depsmocks.Reader{}, QueryServiceOptions{})
+	readMock.On("GetServices", mock.Anything).Return([]string{"service"}, nil)
+	readMock.On("GetOperations", mock.Anything, mock.Anything).Return([]spanstore.Operation{{Name: "GET /"}}, nil)
+	readMock.On("FindTraces", mock.Anything, mock.Anything).Return(nil, errors.New("storage error"))
+	acme := tenancy.WithTenant(context.Background(), "acme")
+
+	_, err := qs.GetServices(acme)
+	assert.EqualError(t, err, "storage error")
+	_, err = qs.GetOperations(acme, spanstore.OperationQueryParameters{ServiceName: "service"})
+	assert.EqualError(t, err, "storage error")
+	_, err = qs.GetDependencies(acme, time.Now(), time.Hour)
+	assert.EqualError(t, err, "storage error")
+}
+
+func TestTenantGetDependencies(t *testing.T) {
+	sharedMock, acmeMock := &spanstoremocks.Reader{}, &spanstoremocks.Reader{}
+	depsMock := &depsmocks.Reader{}
+	qs := NewQueryService(sharedMock, depsMock, QueryServiceOptions{
+		TenantSpanReaders: map[string]spanstore.Reader{"acme": acmeMock},
+	})
+	acmeMock.On("GetServices", mock.Anything).Return([]string{"acme-a", "acme-b"}, nil)
+	sharedMock.On("GetServices", mock.Anything).Return([]string{"acme-a", "mega-a", "mega-b"}, nil)
+	sharedMock.On("FindTraces", mock.Anything, tenantQuery("megacorp", "mega-a", "")).Return([]*model.Trace{tenantTraceOf(1, "megacorp")}, nil)
+	sharedMock.On("FindTraces", mock.Anything, tenantQuery("megacorp", "mega-b", "")).Return([]*model.Trace{tenantTraceOf(1, "megacorp")}, nil)
+	sharedMock.On("FindTraces", mock.Anything, mock.Anything).Return(nil, nil)
+	dependencies := []model.DependencyLink{
+		{Parent: "acme-a", Child: "acme-b"},
+		{Parent: "acme-a", Child: "mega-a"},
+		{Parent: "mega-a", Child: "mega-b"},
+	}
+	depsMock.On("GetDependencies", mock.Anything, mock.Anything).Return(dependencies, nil)
+
+	// the links of a tenant are those between two of its services
+	links, err := qs.GetDependencies(tenancy.WithTenant(context.Background(), "acme"), time.Now(), time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, dependencies[:1], links)
+	links, err = qs.GetDependencies(tenancy.WithTenant(context.Background(), "megacorp"), time.Now(), time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, dependencies[2:], links)
+	links, err = qs.GetDependencies(context.Background(), time.Now(), time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, dependencies, links)
+}
+
+func TestTenantViewStore(t *testing.T) {
+	qs := NewQueryService(&spanstoremocks.Reader{}, &depsmocks.Reader{}, QueryServiceOptions{ViewStore: memory.NewViewStore()})
+	store := qs.ViewStore()
+	acme := tenancy.WithTenant(context.Background(), "acme")
+	// the escaped tenant cannot read the views of another tenant
+	other := tenancy.WithTenant(context.Background(), "acme/x")
+	none := context.Background()
+
+	require.NoError(t, store.SaveSearch(acme, &viewstore.SavedSearch{ID: "x", Name: "acme"}))
+	require.NoError(t, store.SaveSearch(none, &viewstore.SavedSearch{ID: "x", Name: "none"}))
+	require.NoError(t, store.PinTrace(acme, &viewstore.PinnedTrace{TraceID: "1", Note: "acme"}))
+
+	for ctx, name := range map[context.Context]string{acme: "acme", none: "none"} {
+		searches, err := store.GetSearches(ctx)
+		require.NoError(t, err)
+		require.Len(t, searches, 1)
+		assert.Equal(t, "x", searches[0].ID)
+		assert.Equal(t, name, searches[0].Name)
+		search, err := store.GetSearch(ctx, "x")
+		require.NoError(t, err)
+		assert.Equal(t, &viewstore.SavedSearch{ID: "x", Name: name}, search)
+	}
+	searches, err := store.GetSearches(other)
+	require.NoError(t, err)
+	assert.Empty(t, searches)
+	_, err = store.GetSearch(other, "x")
+	assert.Equal(t, viewstore.ErrNotFound, err)
+	assert.Equal(t, viewstore.ErrNotFound, store.DeleteSearch(other, "x"))
+	require.NoError(t, store.DeleteSearch(acme, "x"))
+	_, err = store.GetSearch(acme, "x")
+	assert.Equal(t, viewstore.ErrNotFound, err)
+
+	pins, err := store.GetPinnedTraces(acme)
+	require.NoError(t, err)
+	require.Len(t, pins, 1)
+	assert.Equal(t, "1", pins[0].TraceID)
+	pin, err := store.GetPinnedTrace(acme, "1")
+	require.NoError(t, err)
+	assert.Equal(t, &viewstore.PinnedTrace{TraceID: "1", Note: "acme"}, pin)
+	pins, err = store.GetPinnedTraces(none)
+	require.NoError(t, err)
+	assert.Empty(t, pins)
+	_, err = store.GetPinnedTrace(other, "1")
+	assert.Equal(t, viewstore.ErrNotFound, err)
+	assert.Equal(t, viewstore.ErrNotFound, store.UnpinTrace(none, "1"))
+	require.NoError(t, store.UnpinTrace(acme, "1"))
+
+	disabled := NewQueryService(&spanstoremocks.Reader{}, &depsmocks.Reader{}, QueryServiceOptions{}).ViewStore()
+	_, err = disabled.GetSearches(acme)
+	assert.Equal(t, viewstore.ErrDisabled, err)
+	_, err = disabled.GetPinnedTraces(acme)
+	assert.Equal(t, viewstore.ErrDisabled, err)
+}
+
+// tenantFactory is a storage factory with a span reader per tenant
+type tenantFactory struct {
+	mocks.Factory
+	readers map[string]spanstore.Reader
+	err     error
+}
+
+func (f *tenantFactory) CreateTenantSpanReader(tenant string) (spanstore.Reader, error) {
+	return f.readers[tenant], f.err
+}
+
+func TestInitTenantStorage(t *testing.T) {
+	logger := zap.NewNop()
+	opts := &QueryServiceOptions{}
+	assert.False(t, opts.InitTenantStorage(&mocks.Factory{}, []string{"acme"}, logger))
+
+	reader := &spanstoremocks.Reader{}
+	assert.True(t, opts.InitTenantStorage(&tenantFactory{readers: map[string]spanstore.Reader{"acme": reader}}, []string{"acme"}, logger))
+	assert.Equal(t, map[string]spanstore.Reader{"acme": reader}, opts.TenantSpanReaders)
+
+	for _, err := range []error{storage.ErrTenantStorageNotSupported, errors.New("error")} {
+		opts := &QueryServiceOptions{}
+		assert.False(t, opts.InitTenantStorage(&tenantFactory{err: err}, []string{"acme"}, logger))
+		assert.Nil(t, opts.TenantSpanReaders)
+	}
+}
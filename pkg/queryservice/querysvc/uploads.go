@@ -0,0 +1,102 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package querysvc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/cache"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
+)
+
+// ErrUploadsDisabled occurs when a trace is uploaded but the query service keeps none.
+var ErrUploadsDisabled = errors.New("uploaded traces are disabled")
+
+const defaultUploadedTracesTTL = time.Hour
+
+// UploadedTrace is a trace uploaded by a user, viewable under a temporary trace ID until it expires.
+type UploadedTrace struct {
+	TraceID         model.TraceID
+	OriginalTraceID model.TraceID
+	ExpiresAt       time.Time
+}
+
+func newUploadedTraces(options QueryServiceOptions) *cache.LRU {
+	if options.MaxUploadedTraces <= 0 {
+		return nil
+	}
+	return cache.NewLRUWithOptions(options.MaxUploadedTraces, &cache.Options{TTL: options.UploadedTracesTTL})
+}
+
+// UploadTrace keeps the spans of an external trace in memory under a new random trace ID, so that it
+// can be viewed like the stored traces until it expires or it is evicted by newer uploads. The trace
+// can only be viewed by the tenant of the caller.
+func (qs QueryService) UploadTrace(ctx context.Context, trace *model.Trace) (*UploadedTrace, error) {
+	if qs.uploads == nil {
+		return nil, ErrUploadsDisabled
+	}
+	traceID, err := randomTraceID()
+	if err != nil {
+		return nil, err
+	}
+	uploaded := &UploadedTrace{TraceID: traceID}
+	if len(trace.Spans) > 0 {
+		uploaded.OriginalTraceID = trace.Spans[0].TraceID
+	}
+	copied := &model.Trace{Warnings: trace.Warnings, Spans: make([]*model.Span, len(trace.Spans))}
+	for i, span := range trace.Spans {
+		s := *span
+		s.TraceID = traceID
+		s.References = make([]model.SpanRef, len(span.References))
+		for j, ref := range span.References {
+			if ref.TraceID == uploaded.OriginalTraceID {
+				ref.TraceID = traceID
+			}
+			s.References[j] = ref
+		}
+		copied.Spans[i] = &s
+	}
+	qs.uploads.Put(tenantKey(tenancy.GetTenant(ctx), traceID.String()), copied)
+	uploaded.ExpiresAt = qs.uploads.TimeNow().Add(qs.options.UploadedTracesTTL)
+	return uploaded, nil
+}
+
+func (qs QueryService) getUploadedTrace(ctx context.Context, traceID model.TraceID) *model.Trace {
+	if qs.uploads == nil {
+		return nil
+	}
+	trace, ok := qs.uploads.Get(tenantKey(tenancy.GetTenant(ctx), traceID.String())).(*model.Trace)
+	if !ok {
+		return nil
+	}
+	// the adjusters must not modify the list of spans kept in the cache
+	return &model.Trace{
+		Spans:    append([]*model.Span(nil), trace.Spans...),
+		Warnings: append([]string(nil), trace.Warnings...),
+	}
+}
+
+func randomTraceID() (model.TraceID, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return model.TraceID{}, err
+	}
+	return model.NewTraceID(binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])), nil
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package querysvc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
+	depsmocks "github.com/jaegertracing/jaeger/storage/dependencystore/mocks"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	spanstoremocks "github.com/jaegertracing/jaeger/storage/spanstore/mocks"
+)
+
+func uploadTestTrace() *model.Trace {
+	traceID := model.NewTraceID(0, 0xabc)
+	return &model.Trace{Spans: []*model.Span{
+		{TraceID: traceID, SpanID: model.NewSpanID(1), Process: model.NewProcess("frontend", nil)},
+		{
+			TraceID: traceID,
+			SpanID:  model.NewSpanID(2),
+			Process: model.NewProcess("redis", nil),
+			References: []model.SpanRef{
+				model.NewChildOfRef(traceID, model.NewSpanID(1)),
+				model.NewFollowsFromRef(model.NewTraceID(0, 0xdef), model.NewSpanID(7)),
+			},
+		},
+	}}
+}
+
+func TestUploadTrace(t *testing.T) {
+	readMock := &spanstoremocks.Reader{}
+	qs := NewQueryService(readMock, &depsmocks.Reader{}, QueryServiceOptions{MaxUploadedTraces: 2})
+	assert.Equal(t, defaultUploadedTracesTTL, qs.options.UploadedTracesTTL)
+	now := time.Unix(1600000000, 0)
+	qs.uploads.TimeNow = func() time.Time { return now }
+
+	original := uploadTestTrace()
+	uploaded, err := qs.UploadTrace(context.Background(), original)
+	require.NoError(t, err)
+	assert.Equal(t, model.NewTraceID(0, 0xabc), uploaded.OriginalTraceID)
+	assert.NotEqual(t, uploaded.OriginalTraceID, uploaded.TraceID)
+	assert.Equal(t, now.Add(time.Hour), uploaded.ExpiresAt)
+	// the uploaded spans are copies
+	assert.Equal(t, model.NewTraceID(0, 0xabc), original.Spans[0].TraceID)
+
+	readMock.On("GetTrace", mock.Anything, uploaded.TraceID).Return(nil, spanstore.ErrTraceNotFound)
+	trace, err := qs.GetTrace(context.Background(), uploaded.TraceID)
+	require.NoError(t, err)
+	require.Len(t, trace.Spans, 2)
+	for _, span := range trace.Spans {
+		assert.Equal(t, uploaded.TraceID, span.TraceID)
+	}
+	assert.Equal(t, []model.SpanRef{
+		model.NewChildOfRef(uploaded.TraceID, model.NewSpanID(1)),
+		model.NewFollowsFromRef(model.NewTraceID(0, 0xdef), model.NewSpanID(7)),
+	}, trace.Spans[1].References)
+
+	now = now.Add(2 * time.Hour)
+	_, err = qs.GetTrace(context.Background(), uploaded.TraceID)
+	assert.Equal(t, spanstore.ErrTraceNotFound, err)
+}
+
+func TestUploadTraceForbidden(t *testing.T) {
+	readMock := &spanstoremocks.Reader{}
+	qs := NewQueryService(readMock, &depsmocks.Reader{}, QueryServiceOptions{
+		MaxUploadedTraces: 1,
+		Authorizer:        serviceAuthorizer{"mysql": true},
+	})
+	uploaded, err := qs.UploadTrace(context.Background(), uploadTestTrace())
+	require.NoError(t, err)
+
+	readMock.On("GetTrace", mock.Anything, uploaded.TraceID).Return(nil, spanstore.ErrTraceNotFound)
+	_, err = qs.GetTrace(context.Background(), uploaded.TraceID)
+	assert.Equal(t, ErrForbidden, err)
+}
+
+func TestUploadTraceTenant(t *testing.T) {
+	readMock := &spanstoremocks.Reader{}
+	qs := NewQueryService(readMock, &depsmocks.Reader{}, QueryServiceOptions{MaxUploadedTraces: 1})
+	acme := tenancy.WithTenant(context.Background(), "acme")
+	uploaded, err := qs.UploadTrace(acme, uploadTestTrace())
+	require.NoError(t, err)
+
+	readMock.On("GetTrace", mock.Anything, uploaded.TraceID).Return(nil, spanstore.ErrTraceNotFound)
+	trace, err := qs.GetTrace(acme, uploaded.TraceID)
+	require.NoError(t, err)
+	assert.Len(t, trace.Spans, 2)
+	_, err = qs.GetTrace(tenancy.WithTenant(context.Background(), "megacorp"), uploaded.TraceID)
+	assert.Equal(t, spanstore.ErrTraceNotFound, err)
+	_, err = qs.GetTrace(context.Background(), uploaded.TraceID)
+	assert.Equal(t, spanstore.ErrTraceNotFound, err)
+}
+
+func TestUploadTraceDisabled(t *testing.T) {
+	qs, _, _ := initializeTestService()
+	_, err := qs.UploadTrace(context.Background(), uploadTestTrace())
+	assert.Equal(t, ErrUploadsDisabled, err)
+
+	_, err = QueryService{}.UploadTrace(context.Background(), uploadTestTrace())
+	assert.Equal(t, ErrUploadsDisabled, err)
+}
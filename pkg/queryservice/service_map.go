@@ -0,0 +1,230 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	ui "github.com/jaegertracing/jaeger/model/json"
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+const (
+	edgeStatsParam = "stats"
+
+	defaultEdgeStatsTraceLimit = 1000
+)
+
+// serverSpanKinds are the spans of the metrics of the calls received by the services.
+var serverSpanKinds = []string{"server"}
+
+// parseEdgeStats tells whether the stats of the dependency links are requested.
+func parseEdgeStats(r *http.Request) (bool, error) {
+	value := r.FormValue(edgeStatsParam)
+	if value == "" {
+		return false, nil
+	}
+	withStats, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("unable to parse %s: %w", edgeStatsParam, err)
+	}
+	return withStats, nil
+}
+
+// addEdgeStats sets the stats of the calls of the links over the period. They are read from the
+// metrics storage if there is one, otherwise they are computed from the spans of the most recent
+// traces, up to limit traces per parent service. The links without any call have no stats.
+//
+// The metrics have no caller, so the stats read from them are the ones of the server spans of the
+// child service, whatever the parent.
+func (aH *APIHandler) addEdgeStats(ctx context.Context, links []ui.DependencyLink, endTs time.Time, lookback time.Duration, limit int) error {
+	if len(links) == 0 {
+		return nil
+	}
+	stats, err := aH.edgeStatsFromMetrics(ctx, links, endTs, lookback)
+	if err == metricsstore.ErrDisabled {
+		stats, err = aH.edgeStatsFromSpans(ctx, links, endTs, lookback, limit)
+	}
+	if err != nil {
+		return err
+	}
+	for i := range links {
+		links[i].Stats = stats[edgeKey(links[i])]
+	}
+	return nil
+}
+
+// edgeKey identifies a link whatever its call count and stats.
+func edgeKey(link ui.DependencyLink) ui.DependencyLink {
+	return ui.DependencyLink{Parent: link.Parent, Child: link.Child}
+}
+
+func (aH *APIHandler) edgeStatsFromMetrics(
+	ctx context.Context,
+	links []ui.DependencyLink,
+	endTs time.Time,
+	lookback time.Duration,
+) (map[ui.DependencyLink]*ui.DependencyLinkStats, error) {
+	params := metricsstore.BaseQueryParameters{
+		ServiceNames: uniqueServices(links, func(link ui.DependencyLink) string { return link.Child }),
+		SpanKinds:    serverSpanKinds,
+		EndTime:      endTs,
+		Lookback:     lookback,
+		// a single point covering the whole period
+		Step:    lookback,
+		RatePer: lookback,
+	}
+	percentiles, err := aH.percentilesFromMetrics(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	calls, err := aH.metricsQueryService.GetCallRates(ctx, &metricsstore.CallRateQueryParameters{
+		BaseQueryParameters: params,
+	})
+	if err != nil {
+		return nil, err
+	}
+	requests := make(map[string]float64, len(calls.Metrics))
+	for _, metric := range calls.Metrics {
+		if value, ok := lastValue(metric); ok {
+			requests[labelValue(metric.Labels, serviceNameLabel)] = value * lookback.Seconds()
+		}
+	}
+
+	byService := make(map[string]*ui.DependencyLinkStats, len(percentiles))
+	for _, p := range percentiles {
+		service := labelValue(p.Labels, serviceNameLabel)
+		count := requests[service]
+		byService[service] = &ui.DependencyLinkStats{
+			Source:       percentilesSourceMetrics,
+			RequestCount: uint64(math.Round(count)),
+			ErrorCount:   uint64(math.Round(count * p.ErrorRatio)),
+			P50:          p.P50,
+			P95:          p.P95,
+			P99:          p.P99,
+		}
+	}
+	stats := make(map[ui.DependencyLink]*ui.DependencyLinkStats, len(links))
+	for _, link := range links {
+		if s, ok := byService[link.Child]; ok {
+			stats[edgeKey(link)] = s
+		}
+	}
+	return stats, nil
+}
+
+func (aH *APIHandler) edgeStatsFromSpans(
+	ctx context.Context,
+	links []ui.DependencyLink,
+	endTs time.Time,
+	lookback time.Duration,
+	limit int,
+) (map[ui.DependencyLink]*ui.DependencyLinkStats, error) {
+	type edge struct {
+		durations []time.Duration
+		errors    uint64
+	}
+	edges := make(map[ui.DependencyLink]*edge, len(links))
+	for _, link := range links {
+		edges[edgeKey(link)] = &edge{}
+	}
+	// the traces calling several of the parents are returned by several searches
+	seen := make(map[model.TraceID]bool)
+
+	for _, parent := range uniqueServices(links, func(link ui.DependencyLink) string { return link.Parent }) {
+		traces, err := aH.queryService.FindTraces(ctx, &spanstore.TraceQueryParameters{
+			ServiceName:  parent,
+			StartTimeMin: endTs.Add(-lookback),
+			StartTimeMax: endTs,
+			NumTraces:    limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, trace := range traces {
+			if len(trace.Spans) == 0 || seen[trace.Spans[0].TraceID] {
+				continue
+			}
+			seen[trace.Spans[0].TraceID] = true
+			spans := make(map[model.SpanID]*model.Span, len(trace.Spans))
+			for _, span := range trace.Spans {
+				spans[span.SpanID] = span
+			}
+			for _, span := range trace.Spans {
+				caller, ok := spans[span.ParentSpanID()]
+				if !ok || caller.Process == nil || span.Process == nil {
+					continue
+				}
+				e, ok := edges[ui.DependencyLink{Parent: caller.Process.ServiceName, Child: span.Process.ServiceName}]
+				if !ok {
+					continue
+				}
+				e.durations = append(e.durations, span.Duration)
+				if tag, ok := model.KeyValues(span.Tags).FindByKey("error"); ok && tag.AsString() == "true" {
+					e.errors++
+				}
+			}
+		}
+	}
+
+	stats := make(map[ui.DependencyLink]*ui.DependencyLinkStats, len(edges))
+	for key, e := range edges {
+		if len(e.durations) == 0 {
+			continue
+		}
+		sort.Slice(e.durations, func(i, j int) bool { return e.durations[i] < e.durations[j] })
+		stats[key] = &ui.DependencyLinkStats{
+			Source:       percentilesSourceSpans,
+			RequestCount: uint64(len(e.durations)),
+			ErrorCount:   e.errors,
+			P50:          nearestRank(e.durations, 0.5),
+			P95:          nearestRank(e.durations, 0.95),
+			P99:          nearestRank(e.durations, 0.99),
+		}
+	}
+	return stats, nil
+}
+
+// uniqueServices returns the sorted services of the links.
+func uniqueServices(links []ui.DependencyLink, service func(ui.DependencyLink) string) []string {
+	set := make(map[string]bool, len(links))
+	var services []string
+	for _, link := range links {
+		if name := service(link); !set[name] {
+			set[name] = true
+			services = append(services, name)
+		}
+	}
+	sort.Strings(services)
+	return services
+}
+
+// labelValue returns the value of the label with the given name, empty if there is none.
+func labelValue(labels []metricsstore.Label, name string) string {
+	for _, label := range labels {
+		if label.Name == name {
+			return label.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	ui "github.com/jaegertracing/jaeger/model/json"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
+	metricsmocks "github.com/jaegertracing/jaeger/storage/metricsstore/mocks"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+var edgeStatsEndTs = time.Unix(0, 1476374248550*millisToNanosMultiplier)
+
+func mockEdgeStatsDependencies(ts *testServer) {
+	ts.dependencyReader.On("GetDependencies", edgeStatsEndTs, defaultDependencyLookbackDuration).Return([]model.DependencyLink{
+		{Parent: "frontend", Child: "api", CallCount: 10},
+		{Parent: "api", Child: "mysql", CallCount: 5},
+		{Parent: "frontend", Child: "cache", CallCount: 2},
+	}, nil)
+}
+
+func findTracesOf(service string) interface{} {
+	return mock.MatchedBy(func(query *spanstore.TraceQueryParameters) bool {
+		return query.ServiceName == service &&
+			query.StartTimeMax.Equal(edgeStatsEndTs) &&
+			query.StartTimeMin.Equal(edgeStatsEndTs.Add(-defaultDependencyLookbackDuration)) &&
+			query.NumTraces == 10
+	})
+}
+
+func TestDependencyEdgeStatsFromSpans(t *testing.T) {
+	idA, idB := model.NewTraceID(0, 0xa), model.NewTraceID(0, 0xb)
+	failed := diffSpan(idA, 2, 1, "api", "GET /users", 0, 10*time.Millisecond)
+	failed.Tags = model.KeyValues{model.Bool("error", true)}
+	traceA := &model.Trace{Spans: []*model.Span{
+		diffSpan(idA, 1, 0, "frontend", "GET /", 0, 50*time.Millisecond),
+		failed,
+		diffSpan(idA, 3, 1, "api", "GET /users", 0, 20*time.Millisecond),
+		diffSpan(idA, 4, 3, "mysql", "SELECT", 0, 2*time.Millisecond),
+		// the spans within a service are not calls
+		diffSpan(idA, 5, 3, "api", "serialize", 0, time.Millisecond),
+	}}
+	traceB := &model.Trace{Spans: []*model.Span{
+		diffSpan(idB, 1, 0, "api", "GET /users", 0, 10*time.Millisecond),
+		diffSpan(idB, 2, 1, "mysql", "SELECT", 0, 4*time.Millisecond),
+	}}
+
+	withTestServer(t, func(ts *testServer) {
+		mockEdgeStatsDependencies(ts)
+		ts.spanReader.On("FindTraces", mock.AnythingOfType("*context.valueCtx"), findTracesOf("frontend")).
+			Return([]*model.Trace{traceA}, nil).Once()
+		// traceA is also returned by the search of the api, its calls must not be counted twice
+		ts.spanReader.On("FindTraces", mock.AnythingOfType("*context.valueCtx"), findTracesOf("api")).
+			Return([]*model.Trace{traceA, traceB}, nil).Once()
+
+		var response struct {
+			Data []ui.DependencyLink `json:"data"`
+		}
+		require.NoError(t, getJSON(ts.server.URL+"/api/dependencies?endTs=1476374248550&stats=true&limit=10", &response))
+		assert.ElementsMatch(t, []ui.DependencyLink{
+			{Parent: "frontend", Child: "api", CallCount: 10, Stats: &ui.DependencyLinkStats{
+				Source: percentilesSourceSpans, RequestCount: 2, ErrorCount: 1, P50: 10, P95: 20, P99: 20,
+			}},
+			{Parent: "api", Child: "mysql", CallCount: 5, Stats: &ui.DependencyLinkStats{
+				Source: percentilesSourceSpans, RequestCount: 2, P50: 2, P95: 4, P99: 4,
+			}},
+			{Parent: "frontend", Child: "cache", CallCount: 2},
+		}, response.Data)
+		ts.spanReader.AssertExpectations(t)
+	}, querysvc.QueryServiceOptions{})
+}
+
+func TestDependencyEdgeStatsFromMetrics(t *testing.T) {
+	family := func(values map[string]float64) *metricsstore.MetricFamily {
+		f := &metricsstore.MetricFamily{}
+		for service, value := range values {
+			f.Metrics = append(f.Metrics, &metricsstore.Metric{
+				Labels:       []metricsstore.Label{{Name: "service_name", Value: service}},
+				MetricPoints: []metricsstore.MetricPoint{{Value: value}},
+			})
+		}
+		return f
+	}
+	withMetricsTestServer(t, func(ts *testServer, reader *metricsmocks.Reader) {
+		mockEdgeStatsDependencies(ts)
+		params := metricsstore.BaseQueryParameters{
+			ServiceNames: []string{"api", "cache", "mysql"},
+			SpanKinds:    []string{"server"},
+			EndTime:      edgeStatsEndTs,
+			Lookback:     defaultDependencyLookbackDuration,
+			Step:         defaultDependencyLookbackDuration,
+			RatePer:      defaultDependencyLookbackDuration,
+		}
+		for quantile, value := range map[float64]float64{0.5: 10, 0.95: 40, 0.99: 80} {
+			reader.On("GetLatencies", mock.Anything, &metricsstore.LatenciesQueryParameters{
+				BaseQueryParameters: params,
+				Quantile:            quantile,
+			}).Return(family(map[string]float64{"api": value, "mysql": value / 10}), nil).Once()
+		}
+		reader.On("GetErrorRates", mock.Anything, &metricsstore.ErrorRateQueryParameters{BaseQueryParameters: params}).
+			Return(family(map[string]float64{"api": 0.25, "mysql": 0}), nil).Once()
+		// 1 and 0.5 requests per second over a day
+		reader.On("GetCallRates", mock.Anything, &metricsstore.CallRateQueryParameters{BaseQueryParameters: params}).
+			Return(family(map[string]float64{"api": 1, "mysql": 0.5}), nil).Once()
+
+		var response struct {
+			Data []ui.DependencyLink `json:"data"`
+		}
+		require.NoError(t, getJSON(ts.server.URL+"/api/dependencies?endTs=1476374248550&stats=true", &response))
+		assert.ElementsMatch(t, []ui.DependencyLink{
+			{Parent: "frontend", Child: "api", CallCount: 10, Stats: &ui.DependencyLinkStats{
+				Source: percentilesSourceMetrics, RequestCount: 86400, ErrorCount: 21600, P50: 10, P95: 40, P99: 80,
+			}},
+			{Parent: "api", Child: "mysql", CallCount: 5, Stats: &ui.DependencyLinkStats{
+				Source: percentilesSourceMetrics, RequestCount: 43200, P50: 1, P95: 4, P99: 8,
+			}},
+			{Parent: "frontend", Child: "cache", CallCount: 2},
+		}, response.Data)
+		reader.AssertExpectations(t)
+	})
+}
+
+func TestDependencyEdgeStatsErrors(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		mockEdgeStatsDependencies(ts)
+		ts.spanReader.On("FindTraces", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+			Return(nil, errStorage)
+
+		err := getJSON(ts.server.URL+"/api/dependencies?endTs=1476374248550&stats=sure", nil)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "400 error from server")
+			assert.Contains(t, err.Error(), "unable to parse stats")
+		}
+		err = getJSON(ts.server.URL+"/api/dependencies?endTs=1476374248550&stats=true&limit=0", nil)
+		assert.EqualError(t, err, parsedError(400, "limit must be positive"))
+		err = getJSON(ts.server.URL+"/api/dependencies?endTs=1476374248550&stats=true", nil)
+		assert.EqualError(t, err, parsedError(500, errStorageMsg))
+
+		// the stats are not computed unless requested
+		var response struct {
+			Data []ui.DependencyLink `json:"data"`
+		}
+		require.NoError(t, getJSON(ts.server.URL+"/api/dependencies?endTs=1476374248550&service=api&depth=1&stats=false", &response))
+		assert.Equal(t, []ui.DependencyLink{
+			{Parent: "api", Child: "mysql", CallCount: 5},
+			{Parent: "frontend", Child: "api", CallCount: 10},
+		}, response.Data)
+	}, querysvc.QueryServiceOptions{})
+}
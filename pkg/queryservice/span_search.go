@@ -0,0 +1,47 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"errors"
+	"net/http"
+
+	uiconv "github.com/jaegertracing/jaeger/model/converter/json"
+	ui "github.com/jaegertracing/jaeger/model/json"
+)
+
+var errSpansByTraceID = errors.New("the spans cannot be searched by trace ID, use /traces/{traceID}/spans")
+
+// findSpans implements the REST API /spans. It runs the same search as /traces and returns
+// the spans matching the query with their process, rather than the whole traces.
+func (aH *APIHandler) findSpans(w http.ResponseWriter, r *http.Request) {
+	tQuery, err := aH.queryParser.parse(r)
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	if len(tQuery.traceIDs) > 0 {
+		aH.handleError(w, errSpansByTraceID, http.StatusBadRequest)
+		return
+	}
+	spans, err := aH.queryService.FindSpans(r.Context(), &tQuery.TraceQueryParameters)
+	if aH.handleError(w, err, http.StatusInternalServerError) {
+		return
+	}
+	uiSpans := make([]*ui.Span, len(spans))
+	for i, span := range spans {
+		uiSpans[i] = uiconv.FromDomainEmbedProcess(span)
+	}
+	aH.writeJSON(w, r, &structuredResponse{Data: uiSpans})
+}
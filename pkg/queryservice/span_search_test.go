@@ -0,0 +1,66 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	ui "github.com/jaegertracing/jaeger/model/json"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+)
+
+func TestFindSpansHTTP(t *testing.T) {
+	traceID := model.NewTraceID(0, 0xa)
+	redis := diffSpan(traceID, 2, 1, "redis", "GET", 10*time.Millisecond, 10*time.Millisecond)
+	trace := &model.Trace{Spans: []*model.Span{
+		diffSpan(traceID, 1, 0, "frontend", "GET /", 0, 100*time.Millisecond),
+		redis,
+	}}
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("FindTraces", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+			Return([]*model.Trace{trace}, nil).Once()
+
+		var response structuredResponse
+		require.NoError(t, getJSON(ts.server.URL+"/api/spans?service=redis&start=0", &response))
+		data, err := json.Marshal(response.Data)
+		require.NoError(t, err)
+		var spans []ui.Span
+		require.NoError(t, json.Unmarshal(data, &spans))
+		require.Len(t, spans, 1)
+		assert.Equal(t, ui.TraceID("000000000000000a"), spans[0].TraceID)
+		assert.Equal(t, ui.SpanID("0000000000000002"), spans[0].SpanID)
+		require.Len(t, spans[0].References, 1)
+		assert.Equal(t, ui.SpanID("0000000000000001"), spans[0].References[0].SpanID)
+		require.NotNil(t, spans[0].Process)
+		assert.Equal(t, "redis", spans[0].Process.ServiceName)
+
+		ts.spanReader.On("FindTraces", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+			Return(nil, errStorage).Once()
+		err = getJSON(ts.server.URL+"/api/spans?service=redis", &response)
+		assert.EqualError(t, err, parsedError(500, errStorageMsg))
+
+		err = getJSON(ts.server.URL+"/api/spans?traceID=a", &response)
+		assert.EqualError(t, err, parsedError(400, errSpansByTraceID.Error()))
+		err = getJSON(ts.server.URL+"/api/spans", &response)
+		assert.EqualError(t, err, parsedError(400, "parameter 'service' is required"))
+	}, querysvc.QueryServiceOptions{})
+}
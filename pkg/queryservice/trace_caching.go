@@ -0,0 +1,57 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+// writeTraceJSON writes the response of a trace with an ETag, so that the clients and the caching
+// proxies can revalidate it without downloading it again. The traces that look complete, i.e. that
+// have not received spans for the quiet period of the live tail, do not change anymore: they also
+// have a Last-Modified header, and can be cached for the trace max age. The traces restricted to the
+// caller are private, so that the caching proxies do not return them to the other callers.
+func (aH *APIHandler) writeTraceJSON(w http.ResponseWriter, r *http.Request, response interface{}, trace *model.Trace) {
+	body := marshalJSON(r, response)
+	sum := sha256.Sum256(body)
+	// weak, as the compressed representations of the response differ
+	w.Header().Set("ETag", fmt.Sprintf(`W/"%s"`, hex.EncodeToString(sum[:16])))
+	w.Header().Set("Content-Type", "application/json")
+
+	var cacheControl []string
+	if len(aH.traceVary) > 0 {
+		cacheControl = append(cacheControl, "private")
+		w.Header().Set("Vary", strings.Join(aH.traceVary, ", "))
+	}
+	var lastModified time.Time
+	if end := traceEndTime(trace); end.Before(aH.queryParser.timeNow().Add(-aH.liveTail.quietPeriod)) {
+		lastModified = end
+		if aH.traceMaxAge > 0 {
+			cacheControl = append(cacheControl, fmt.Sprintf("max-age=%d", int64(aH.traceMaxAge/time.Second)))
+		}
+	}
+	if len(cacheControl) > 0 {
+		w.Header().Set("Cache-Control", strings.Join(cacheControl, ", "))
+	}
+	http.ServeContent(w, r, "", lastModified, bytes.NewReader(body))
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+)
+
+func TestGetTraceCacheHeaders(t *testing.T) {
+	traceID := model.NewTraceID(0, 0xa)
+	trace := &model.Trace{Spans: []*model.Span{
+		diffSpan(traceID, 1, 0, "frontend", "GET /", 0, 100*time.Millisecond),
+		diffSpan(traceID, 2, 1, "redis", "GET", 10*time.Millisecond, 10*time.Millisecond),
+	}}
+	traceEnd := diffStartTime.Add(100 * time.Millisecond)
+
+	get := func(t *testing.T, ts *testServer, header http.Header) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, ts.server.URL+"/api/traces/a", nil)
+		require.NoError(t, err)
+		req.Header = header
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	t.Run("complete trace", func(t *testing.T) {
+		withTestServer(t, func(ts *testServer) {
+			ts.handler.queryParser.timeNow = func() time.Time { return traceEnd.Add(time.Hour) }
+			ts.spanReader.On("GetTrace", mock.Anything, traceID).Return(trace, nil)
+
+			resp := get(t, ts, http.Header{})
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			etag := resp.Header.Get("ETag")
+			assert.Regexp(t, `^W/"[0-9a-f]{32}"$`, etag)
+			assert.Equal(t, "max-age=86400", resp.Header.Get("Cache-Control"))
+			assert.Equal(t, traceEnd.UTC().Format(http.TimeFormat), resp.Header.Get("Last-Modified"))
+
+			resp = get(t, ts, http.Header{"If-None-Match": {etag}})
+			assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+			resp = get(t, ts, http.Header{"If-Modified-Since": {traceEnd.Add(time.Second).UTC().Format(http.TimeFormat)}})
+			assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+		}, querysvc.QueryServiceOptions{}, HandlerOptions.TraceMaxAge(24*time.Hour))
+	})
+
+	t.Run("private trace", func(t *testing.T) {
+		withTestServer(t, func(ts *testServer) {
+			ts.handler.queryParser.timeNow = func() time.Time { return traceEnd.Add(time.Hour) }
+			ts.spanReader.On("GetTrace", mock.Anything, traceID).Return(trace, nil)
+
+			resp := get(t, ts, http.Header{})
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, "private, max-age=86400", resp.Header.Get("Cache-Control"))
+			assert.Equal(t, "Authorization, Cookie, x-tenant", resp.Header.Get("Vary"))
+		}, querysvc.QueryServiceOptions{}, HandlerOptions.TraceMaxAge(24*time.Hour),
+			HandlerOptions.PrivateTraces([]string{"Authorization", "Cookie", "x-tenant"}))
+
+		withTestServer(t, func(ts *testServer) {
+			ts.handler.queryParser.timeNow = func() time.Time { return traceEnd.Add(time.Second) }
+			ts.spanReader.On("GetTrace", mock.Anything, traceID).Return(trace, nil)
+
+			resp := get(t, ts, http.Header{})
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, "private", resp.Header.Get("Cache-Control"))
+			assert.Equal(t, "Authorization, Cookie", resp.Header.Get("Vary"))
+		}, querysvc.QueryServiceOptions{}, HandlerOptions.TraceMaxAge(24*time.Hour),
+			HandlerOptions.PrivateTraces([]string{"Authorization", "Cookie"}))
+	})
+
+	t.Run("recent trace", func(t *testing.T) {
+		withTestServer(t, func(ts *testServer) {
+			ts.handler.queryParser.timeNow = func() time.Time { return traceEnd.Add(time.Second) }
+			ts.spanReader.On("GetTrace", mock.Anything, traceID).Return(trace, nil)
+
+			resp := get(t, ts, http.Header{})
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.NotEmpty(t, resp.Header.Get("ETag"))
+			assert.Empty(t, resp.Header.Get("Cache-Control"))
+			assert.Empty(t, resp.Header.Get("Last-Modified"))
+		}, querysvc.QueryServiceOptions{}, HandlerOptions.TraceMaxAge(24*time.Hour))
+	})
+}
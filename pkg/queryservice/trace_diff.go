@@ -0,0 +1,224 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	ui "github.com/jaegertracing/jaeger/model/json"
+)
+
+// spanGroup holds the spans of a trace found at the same path or of the same operation.
+type spanGroup struct {
+	count    int
+	duration time.Duration
+}
+
+// traceSummary groups the spans of a trace by path and by operation.
+type traceSummary struct {
+	paths      map[string]*spanGroup
+	pathOrder  []string
+	pathRefs   map[string][]ui.OperationRef
+	operations map[ui.OperationRef]*spanGroup
+}
+
+func summarizeTrace(trace *model.Trace) *traceSummary {
+	s := &traceSummary{
+		paths:      make(map[string]*spanGroup),
+		pathRefs:   make(map[string][]ui.OperationRef),
+		operations: make(map[ui.OperationRef]*spanGroup),
+	}
+	walkTrace(trace, func(span *model.Span, _ []*model.Span, path []ui.OperationRef) {
+		ref := path[len(path)-1]
+		key := pathKey(path)
+		if _, ok := s.paths[key]; !ok {
+			s.paths[key] = &spanGroup{}
+			s.pathOrder = append(s.pathOrder, key)
+			s.pathRefs[key] = path
+		}
+		s.paths[key].add(span)
+		if _, ok := s.operations[ref]; !ok {
+			s.operations[ref] = &spanGroup{}
+		}
+		s.operations[ref].add(span)
+	})
+	return s
+}
+
+// walkTrace visits the spans of the trace depth-first, the children of a span sorted by start time,
+// with the path of operations from the root span. The spans whose parent is not in the trace are roots.
+func walkTrace(trace *model.Trace, visit func(span *model.Span, children []*model.Span, path []ui.OperationRef)) {
+	ids := make(map[model.SpanID]bool, len(trace.Spans))
+	for _, span := range trace.Spans {
+		ids[span.SpanID] = true
+	}
+	children := make(map[model.SpanID][]*model.Span)
+	var roots []*model.Span
+	for _, span := range trace.Spans {
+		if parent := span.ParentSpanID(); parent != 0 && ids[parent] && parent != span.SpanID {
+			children[parent] = append(children[parent], span)
+		} else {
+			roots = append(roots, span)
+		}
+	}
+	visited := make(map[*model.Span]bool, len(trace.Spans))
+	var walk func(spans []*model.Span, path []ui.OperationRef)
+	walk = func(spans []*model.Span, path []ui.OperationRef) {
+		sort.SliceStable(spans, func(i, j int) bool { return spans[i].StartTime.Before(spans[j].StartTime) })
+		for _, span := range spans {
+			if visited[span] {
+				continue
+			}
+			visited[span] = true
+			ref := ui.OperationRef{OperationName: span.OperationName}
+			if span.Process != nil {
+				ref.ServiceName = span.Process.ServiceName
+			}
+			spanPath := append(append([]ui.OperationRef{}, path...), ref)
+			visit(span, children[span.SpanID], spanPath)
+			walk(children[span.SpanID], spanPath)
+		}
+	}
+	walk(roots, nil)
+}
+
+func (g *spanGroup) add(span *model.Span) {
+	g.count++
+	g.duration += span.Duration
+}
+
+func pathKey(path []ui.OperationRef) string {
+	var b strings.Builder
+	for _, ref := range path {
+		b.WriteString(ref.ServiceName)
+		b.WriteByte(0)
+		b.WriteString(ref.OperationName)
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+// diffTraces compares the trace B to the trace A, matching their spans by their path of operations from the root.
+// The spans are listed in the order of the trace A, followed by those only found in the trace B, and the operations
+// by decreasing difference of duration.
+func diffTraces(a, b *model.Trace) *ui.TraceDiff {
+	summaryA, summaryB := summarizeTrace(a), summarizeTrace(b)
+	durationA, durationB := traceDuration(a), traceDuration(b)
+	diff := &ui.TraceDiff{
+		TraceIDA:      traceIDOf(a),
+		TraceIDB:      traceIDOf(b),
+		DurationA:     model.DurationAsMicroseconds(durationA),
+		DurationB:     model.DurationAsMicroseconds(durationB),
+		DurationDelta: microsDelta(durationA, durationB),
+		Spans:         []ui.SpanDiff{},
+		Operations:    []ui.OperationDiff{},
+	}
+	empty := &spanGroup{}
+	addSpans := func(key string, path []ui.OperationRef) {
+		groupA, inA := summaryA.paths[key]
+		groupB, inB := summaryB.paths[key]
+		status := ui.SpanDiffUnchanged
+		switch {
+		case !inA:
+			status, groupA = ui.SpanDiffAdded, empty
+		case !inB:
+			status, groupB = ui.SpanDiffRemoved, empty
+		case groupA.count != groupB.count:
+			status = ui.SpanDiffChanged
+		}
+		diff.Spans = append(diff.Spans, ui.SpanDiff{
+			Path:          path,
+			Status:        status,
+			CountA:        groupA.count,
+			CountB:        groupB.count,
+			DurationA:     model.DurationAsMicroseconds(groupA.duration),
+			DurationB:     model.DurationAsMicroseconds(groupB.duration),
+			DurationDelta: microsDelta(groupA.duration, groupB.duration),
+		})
+	}
+	for _, key := range summaryA.pathOrder {
+		addSpans(key, summaryA.pathRefs[key])
+	}
+	for _, key := range summaryB.pathOrder {
+		if _, ok := summaryA.paths[key]; !ok {
+			addSpans(key, summaryB.pathRefs[key])
+		}
+	}
+
+	for ref := range summaryB.operations {
+		if _, ok := summaryA.operations[ref]; !ok {
+			summaryA.operations[ref] = empty
+		}
+	}
+	for ref, groupA := range summaryA.operations {
+		groupB, ok := summaryB.operations[ref]
+		if !ok {
+			groupB = empty
+		}
+		diff.Operations = append(diff.Operations, ui.OperationDiff{
+			OperationRef:  ref,
+			CountA:        groupA.count,
+			CountB:        groupB.count,
+			DurationA:     model.DurationAsMicroseconds(groupA.duration),
+			DurationB:     model.DurationAsMicroseconds(groupB.duration),
+			DurationDelta: microsDelta(groupA.duration, groupB.duration),
+		})
+	}
+	sort.Slice(diff.Operations, func(i, j int) bool {
+		x, y := diff.Operations[i], diff.Operations[j]
+		if dx, dy := abs(x.DurationDelta), abs(y.DurationDelta); dx != dy {
+			return dx > dy
+		}
+		if x.ServiceName != y.ServiceName {
+			return x.ServiceName < y.ServiceName
+		}
+		return x.OperationName < y.OperationName
+	})
+	return diff
+}
+
+func traceIDOf(trace *model.Trace) ui.TraceID {
+	if len(trace.Spans) == 0 {
+		return ""
+	}
+	return ui.TraceID(trace.Spans[0].TraceID.String())
+}
+
+func traceDuration(trace *model.Trace) time.Duration {
+	var start, end time.Time
+	for i, span := range trace.Spans {
+		if i == 0 || span.StartTime.Before(start) {
+			start = span.StartTime
+		}
+		if spanEnd := span.StartTime.Add(span.Duration); i == 0 || spanEnd.After(end) {
+			end = spanEnd
+		}
+	}
+	return end.Sub(start)
+}
+
+func microsDelta(a, b time.Duration) int64 {
+	return int64(b/time.Microsecond) - int64(a/time.Microsecond)
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jaegertracing/jaeger/model"
+	ui "github.com/jaegertracing/jaeger/model/json"
+)
+
+var diffStartTime = time.Unix(1600000000, 0)
+
+func diffSpan(traceID model.TraceID, id, parent uint64, service, operation string, start, duration time.Duration) *model.Span {
+	span := &model.Span{
+		TraceID:       traceID,
+		SpanID:        model.NewSpanID(id),
+		OperationName: operation,
+		StartTime:     diffStartTime.Add(start),
+		Duration:      duration,
+		Process:       model.NewProcess(service, nil),
+	}
+	if parent != 0 {
+		span.References = []model.SpanRef{model.NewChildOfRef(traceID, model.NewSpanID(parent))}
+	}
+	return span
+}
+
+func TestDiffTraces(t *testing.T) {
+	idA, idB := model.NewTraceID(0, 0xa), model.NewTraceID(0, 0xb)
+	traceA := &model.Trace{Spans: []*model.Span{
+		diffSpan(idA, 1, 0, "frontend", "GET /", 0, 100*time.Millisecond),
+		diffSpan(idA, 3, 1, "redis", "GET", 30*time.Millisecond, 10*time.Millisecond),
+		diffSpan(idA, 2, 1, "redis", "GET", 10*time.Millisecond, 10*time.Millisecond),
+		diffSpan(idA, 4, 1, "mysql", "SELECT", 50*time.Millisecond, 40*time.Millisecond),
+	}}
+	traceB := &model.Trace{Spans: []*model.Span{
+		diffSpan(idB, 1, 0, "frontend", "GET /", 0, 150*time.Millisecond),
+		diffSpan(idB, 2, 1, "redis", "GET", 10*time.Millisecond, 15*time.Millisecond),
+		diffSpan(idB, 3, 1, "auth", "check", 30*time.Millisecond, 5*time.Millisecond),
+		diffSpan(idB, 4, 3, "redis", "GET", 31*time.Millisecond, 2*time.Millisecond),
+		// the parent of an orphan span is not in the trace, it is compared as a root span
+		diffSpan(idB, 5, 9, "batch", "job", 0, 200*time.Millisecond),
+	}}
+
+	frontend := ui.OperationRef{ServiceName: "frontend", OperationName: "GET /"}
+	redis := ui.OperationRef{ServiceName: "redis", OperationName: "GET"}
+	mysql := ui.OperationRef{ServiceName: "mysql", OperationName: "SELECT"}
+	auth := ui.OperationRef{ServiceName: "auth", OperationName: "check"}
+	batch := ui.OperationRef{ServiceName: "batch", OperationName: "job"}
+	assert.Equal(t, &ui.TraceDiff{
+		TraceIDA:      "000000000000000a",
+		TraceIDB:      "000000000000000b",
+		DurationA:     100000,
+		DurationB:     200000,
+		DurationDelta: 100000,
+		Spans: []ui.SpanDiff{
+			{Path: []ui.OperationRef{frontend}, Status: ui.SpanDiffUnchanged, CountA: 1, CountB: 1, DurationA: 100000, DurationB: 150000, DurationDelta: 50000},
+			{Path: []ui.OperationRef{frontend, redis}, Status: ui.SpanDiffChanged, CountA: 2, CountB: 1, DurationA: 20000, DurationB: 15000, DurationDelta: -5000},
+			{Path: []ui.OperationRef{frontend, mysql}, Status: ui.SpanDiffRemoved, CountA: 1, DurationA: 40000, DurationDelta: -40000},
+			{Path: []ui.OperationRef{frontend, auth}, Status: ui.SpanDiffAdded, CountB: 1, DurationB: 5000, DurationDelta: 5000},
+			{Path: []ui.OperationRef{frontend, auth, redis}, Status: ui.SpanDiffAdded, CountB: 1, DurationB: 2000, DurationDelta: 2000},
+			{Path: []ui.OperationRef{batch}, Status: ui.SpanDiffAdded, CountB: 1, DurationB: 200000, DurationDelta: 200000},
+		},
+		Operations: []ui.OperationDiff{
+			{OperationRef: batch, CountB: 1, DurationB: 200000, DurationDelta: 200000},
+			{OperationRef: frontend, CountA: 1, CountB: 1, DurationA: 100000, DurationB: 150000, DurationDelta: 50000},
+			{OperationRef: mysql, CountA: 1, DurationA: 40000, DurationDelta: -40000},
+			{OperationRef: auth, CountB: 1, DurationB: 5000, DurationDelta: 5000},
+			{OperationRef: redis, CountA: 2, CountB: 2, DurationA: 20000, DurationB: 17000, DurationDelta: -3000},
+		},
+	}, diffTraces(traceA, traceB))
+}
+
+func TestDiffEmptyTraces(t *testing.T) {
+	assert.Equal(t, &ui.TraceDiff{Spans: []ui.SpanDiff{}, Operations: []ui.OperationDiff{}}, diffTraces(&model.Trace{}, &model.Trace{}))
+}
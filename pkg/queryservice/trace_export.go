@@ -0,0 +1,68 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/model/converter/otlp"
+	"github.com/jaegertracing/jaeger/model/converter/zipkin"
+)
+
+const (
+	formatParam = "format"
+
+	formatJaeger = "jaeger"
+	formatOTLP   = "otlp"
+	formatZipkin = "zipkin"
+)
+
+// parseFormat returns the format of the trace download, the Jaeger UI model by default.
+func parseFormat(r *http.Request) (string, error) {
+	switch format := r.FormValue(formatParam); format {
+	case "":
+		return formatJaeger, nil
+	case formatJaeger, formatOTLP, formatZipkin:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unable to parse %s: expecting %q, %q or %q", formatParam, formatJaeger, formatOTLP, formatZipkin)
+	}
+}
+
+// writeExportedTrace writes the trace as an OTLP/JSON export request or as a list of Zipkin v2 spans,
+// without the envelope of the other responses so that it can be fed as is into other tools.
+func (aH *APIHandler) writeExportedTrace(w http.ResponseWriter, r *http.Request, trace *model.Trace, format string) {
+	if shouldAdjust(r) {
+		adjusted, err := aH.queryService.Adjust(trace)
+		if err != nil {
+			aH.logger.Warn("Exporting the trace without all the adjustments", zap.Error(err))
+		}
+		trace = adjusted
+	}
+	if format == formatZipkin {
+		aH.writeJSON(w, r, zipkin.FromDomain(trace))
+		return
+	}
+	data, err := otlp.MarshalJSON(otlp.FromDomain([]*model.Batch{{Spans: trace.Spans}}))
+	if aH.handleError(w, err, http.StatusInternalServerError) {
+		return
+	}
+	aH.writeJSON(w, r, json.RawMessage(data))
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/model/adjuster"
+	"github.com/jaegertracing/jaeger/model/converter/otlp"
+	"github.com/jaegertracing/jaeger/model/converter/zipkin"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+)
+
+func TestGetTraceOTLP(t *testing.T) {
+	server, readMock, _ := initializeTestServer()
+	defer server.Close()
+	readMock.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("model.TraceID")).
+		Return(mockTrace, nil).Once()
+
+	resp, err := http.Get(server.URL + `/api/traces/123456?format=otlp`)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	req, err := otlp.UnmarshalJSON(body)
+	require.NoError(t, err)
+	batches, err := otlp.ToDomain(req)
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	require.Len(t, batches[0].Spans, 2)
+	assert.Equal(t, mockTraceID, batches[0].Spans[0].TraceID)
+}
+
+func TestGetTraceZipkin(t *testing.T) {
+	server, readMock, _ := initializeTestServer()
+	defer server.Close()
+	readMock.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("model.TraceID")).
+		Return(mockTrace, nil).Once()
+
+	var spans []*zipkin.Span
+	require.NoError(t, getJSON(server.URL+`/api/traces/123456?format=zipkin`, &spans))
+	require.Len(t, spans, 2)
+	assert.Equal(t, "000000000001e240", spans[0].TraceID)
+	assert.Equal(t, "0000000000000001", spans[0].ID)
+}
+
+func TestGetTraceExportAdjustmentFailure(t *testing.T) {
+	server, readMock, _, _ := initializeTestServerWithHandler(
+		querysvc.QueryServiceOptions{
+			Adjuster: adjuster.Func(func(trace *model.Trace) (*model.Trace, error) {
+				return trace, errAdjustment
+			}),
+		},
+	)
+	defer server.Close()
+	readMock.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("model.TraceID")).
+		Return(mockTrace, nil).Once()
+
+	var spans []*zipkin.Span
+	require.NoError(t, getJSON(server.URL+`/api/traces/123456?format=zipkin`, &spans))
+	assert.Len(t, spans, 2)
+}
+
+func TestGetTraceBadFormat(t *testing.T) {
+	server, _, _ := initializeTestServer()
+	defer server.Close()
+
+	var response structuredResponse
+	err := getJSON(server.URL+`/api/traces/123456?format=thrift`, &response)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "400 error from server")
+	assert.Contains(t, err.Error(), "unable to parse format")
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"net/http"
+
+	"github.com/jaegertracing/jaeger/model"
+	ui "github.com/jaegertracing/jaeger/model/json"
+)
+
+const (
+	bucketsParam  = "buckets"
+	examplesParam = "examples"
+
+	defaultHistogramBuckets  = 20
+	defaultHistogramExamples = 5
+)
+
+// durationHistogram implements the REST API /traces/histogram. It runs the same search as /traces
+// and returns the distribution of the durations of the traces found, in at most `buckets` buckets
+// of equal width, with up to `examples` trace IDs per bucket.
+func (aH *APIHandler) durationHistogram(w http.ResponseWriter, r *http.Request) {
+	buckets, err := parsePositiveInt(r, bucketsParam, defaultHistogramBuckets)
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	examples, err := parsePositiveInt(r, examplesParam, defaultHistogramExamples)
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	traces, _, uiErrors, ok := aH.findTraces(w, r)
+	if !ok {
+		return
+	}
+	structuredRes := structuredResponse{
+		Data:   buildDurationHistogram(traces, buckets, examples),
+		Errors: uiErrors,
+	}
+	aH.writeJSON(w, r, &structuredRes)
+}
+
+// buildDurationHistogram splits the range of the durations of the traces in buckets of equal width,
+// fewer than requested if the range is too narrow for them to last at least a microsecond.
+func buildDurationHistogram(traces []*model.Trace, buckets, examples int) *ui.DurationHistogram {
+	type traceDurationEntry struct {
+		traceID  ui.TraceID
+		duration uint64
+	}
+	entries := make([]traceDurationEntry, 0, len(traces))
+	for _, trace := range traces {
+		if len(trace.Spans) == 0 {
+			continue
+		}
+		entries = append(entries, traceDurationEntry{
+			traceID:  traceIDOf(trace),
+			duration: model.DurationAsMicroseconds(traceDuration(trace)),
+		})
+	}
+	histogram := &ui.DurationHistogram{TraceCount: len(entries), Buckets: []ui.DurationBucket{}}
+	if len(entries) == 0 {
+		return histogram
+	}
+
+	min, max := entries[0].duration, entries[0].duration
+	for _, entry := range entries {
+		if entry.duration < min {
+			min = entry.duration
+		}
+		if entry.duration > max {
+			max = entry.duration
+		}
+	}
+	span := max - min + 1
+	width := (span + uint64(buckets) - 1) / uint64(buckets)
+	count := (span + width - 1) / width
+
+	histogram.Buckets = make([]ui.DurationBucket, count)
+	for i := range histogram.Buckets {
+		histogram.Buckets[i] = ui.DurationBucket{
+			Min:      min + uint64(i)*width,
+			Max:      min + uint64(i+1)*width,
+			TraceIDs: []ui.TraceID{},
+		}
+	}
+	// the examples of a bucket are the traces returned first by the storage, usually the most recent
+	for _, entry := range entries {
+		bucket := &histogram.Buckets[(entry.duration-min)/width]
+		bucket.Count++
+		if len(bucket.TraceIDs) < examples {
+			bucket.TraceIDs = append(bucket.TraceIDs, entry.traceID)
+		}
+	}
+	return histogram
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	ui "github.com/jaegertracing/jaeger/model/json"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+)
+
+func histogramTraces() []*model.Trace {
+	var traces []*model.Trace
+	for i, duration := range []time.Duration{10, 25, 12, 100, 11} {
+		traceID := model.NewTraceID(0, uint64(i+1))
+		traces = append(traces, &model.Trace{Spans: []*model.Span{
+			diffSpan(traceID, 1, 0, "frontend", "GET /", 0, duration*time.Millisecond),
+		}})
+	}
+	return traces
+}
+
+func TestBuildDurationHistogram(t *testing.T) {
+	assert.Equal(t, &ui.DurationHistogram{
+		TraceCount: 5,
+		Buckets: []ui.DurationBucket{
+			{Min: 10000, Max: 40001, Count: 4, TraceIDs: []ui.TraceID{"0000000000000001", "0000000000000002"}},
+			{Min: 40001, Max: 70002, Count: 0, TraceIDs: []ui.TraceID{}},
+			{Min: 70002, Max: 100003, Count: 1, TraceIDs: []ui.TraceID{"0000000000000004"}},
+		},
+	}, buildDurationHistogram(histogramTraces(), 3, 2))
+
+	// a single duration cannot be split
+	single := buildDurationHistogram(histogramTraces()[:1], 20, 5)
+	assert.Equal(t, []ui.DurationBucket{
+		{Min: 10000, Max: 10001, Count: 1, TraceIDs: []ui.TraceID{"0000000000000001"}},
+	}, single.Buckets)
+
+	assert.Equal(t, &ui.DurationHistogram{Buckets: []ui.DurationBucket{}},
+		buildDurationHistogram([]*model.Trace{{}}, 20, 5))
+}
+
+func TestDurationHistogram(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("FindTraces", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+			Return(histogramTraces(), nil)
+
+		var response structuredResponse
+		require.NoError(t, getJSON(ts.server.URL+"/api/traces/histogram?service=frontend&operation=GET%20/&buckets=3&examples=1", &response))
+		data, err := json.Marshal(response.Data)
+		require.NoError(t, err)
+		var histogram ui.DurationHistogram
+		require.NoError(t, json.Unmarshal(data, &histogram))
+		assert.Equal(t, 5, histogram.TraceCount)
+		require.Len(t, histogram.Buckets, 3)
+		assert.Equal(t, 4, histogram.Buckets[0].Count)
+		assert.Len(t, histogram.Buckets[0].TraceIDs, 1)
+
+		err = getJSON(ts.server.URL+"/api/traces/histogram?service=frontend&buckets=0", &response)
+		assert.EqualError(t, err, parsedError(400, "buckets must be positive"))
+		err = getJSON(ts.server.URL+"/api/traces/histogram", &response)
+		assert.EqualError(t, err, parsedError(400, "parameter 'service' is required"))
+	}, querysvc.QueryServiceOptions{})
+}
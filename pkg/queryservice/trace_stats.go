@@ -0,0 +1,122 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	ui "github.com/jaegertracing/jaeger/model/json"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+// traceStats aggregates the spans of the trace, the services being sorted by decreasing self time.
+func traceStats(trace *model.Trace) *ui.TraceStats {
+	stats := &ui.TraceStats{
+		TraceID:   traceIDOf(trace),
+		SpanCount: len(trace.Spans),
+		Duration:  model.DurationAsMicroseconds(traceDuration(trace)),
+		Services:  []ui.ServiceStats{},
+	}
+	for i, span := range trace.Spans {
+		if start := model.TimeAsEpochMicroseconds(span.StartTime); i == 0 || start < stats.StartTime {
+			stats.StartTime = start
+		}
+	}
+	services := make(map[string]*ui.ServiceStats)
+	var order []string
+	selfTimes := make(map[string]time.Duration)
+	walkTrace(trace, func(span *model.Span, children []*model.Span, path []ui.OperationRef) {
+		service := path[len(path)-1].ServiceName
+		s, ok := services[service]
+		if !ok {
+			s = &ui.ServiceStats{ServiceName: service}
+			services[service] = s
+			order = append(order, service)
+		}
+		s.SpanCount++
+		if tag, ok := model.KeyValues(span.Tags).FindByKey("error"); ok && tag.AsString() == "true" {
+			s.ErrorCount++
+			stats.ErrorCount++
+		}
+		selfTimes[service] += selfTime(span, children)
+		if len(path) > stats.Depth {
+			stats.Depth = len(path)
+		}
+	})
+	for _, service := range order {
+		s := services[service]
+		s.SelfTime = model.DurationAsMicroseconds(selfTimes[service])
+		stats.Services = append(stats.Services, *s)
+	}
+	sort.SliceStable(stats.Services, func(i, j int) bool { return stats.Services[i].SelfTime > stats.Services[j].SelfTime })
+	return stats
+}
+
+// getTraceStats implements the REST API /traces/{trace-id}/stats.
+func (aH *APIHandler) getTraceStats(w http.ResponseWriter, r *http.Request) {
+	traceID, ok := aH.parseTraceID(w, r)
+	if !ok {
+		return
+	}
+	trace, err := aH.queryService.GetTrace(r.Context(), traceID)
+	if err == spanstore.ErrTraceNotFound {
+		aH.handleError(w, err, http.StatusNotFound)
+		return
+	}
+	if aH.handleError(w, err, http.StatusInternalServerError) {
+		return
+	}
+
+	var uiErrors []structuredError
+	if shouldAdjust(r) {
+		if trace, err = aH.queryService.Adjust(trace); err != nil {
+			uiErrors = append(uiErrors, structuredError{Msg: err.Error(), TraceID: ui.TraceID(traceID.String())})
+		}
+	}
+	structuredRes := structuredResponse{
+		Data:   []*ui.TraceStats{traceStats(trace)},
+		Errors: uiErrors,
+	}
+	aH.writeJSON(w, r, &structuredRes)
+}
+
+// searchTraceStats implements the REST API /traces/stats. It runs the same search as /traces
+// and returns the statistics of the traces found instead of their spans.
+func (aH *APIHandler) searchTraceStats(w http.ResponseWriter, r *http.Request) {
+	traces, nextPageToken, uiErrors, ok := aH.findTraces(w, r)
+	if !ok {
+		return
+	}
+	stats := make([]*ui.TraceStats, len(traces))
+	for i, trace := range traces {
+		if shouldAdjust(r) {
+			adjusted, err := aH.queryService.Adjust(trace)
+			if err != nil {
+				uiErrors = append(uiErrors, structuredError{Msg: err.Error(), TraceID: traceIDOf(trace)})
+			}
+			trace = adjusted
+		}
+		stats[i] = traceStats(trace)
+	}
+	structuredRes := structuredResponse{
+		Data:          stats,
+		Errors:        uiErrors,
+		NextPageToken: nextPageToken,
+	}
+	aH.writeJSON(w, r, &structuredRes)
+}
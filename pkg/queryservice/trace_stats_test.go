@@ -0,0 +1,132 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/model/adjuster"
+	ui "github.com/jaegertracing/jaeger/model/json"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+func statsTrace() *model.Trace {
+	traceID := model.NewTraceID(0, 0xa)
+	mysql := diffSpan(traceID, 3, 2, "mysql", "SELECT", 20*time.Millisecond, 35*time.Millisecond)
+	mysql.Tags = model.KeyValues{model.Bool("error", true)}
+	return &model.Trace{Spans: []*model.Span{
+		diffSpan(traceID, 1, 0, "frontend", "GET /", 0, 100*time.Millisecond),
+		diffSpan(traceID, 2, 1, "backend", "GET /api", 10*time.Millisecond, 50*time.Millisecond),
+		mysql,
+		diffSpan(traceID, 4, 1, "backend", "GET /api", 70*time.Millisecond, 10*time.Millisecond),
+	}}
+}
+
+func TestTraceStats(t *testing.T) {
+	assert.Equal(t, &ui.TraceStats{
+		TraceID:    "000000000000000a",
+		StartTime:  model.TimeAsEpochMicroseconds(diffStartTime),
+		Duration:   100000,
+		SpanCount:  4,
+		ErrorCount: 1,
+		Depth:      3,
+		Services: []ui.ServiceStats{
+			{ServiceName: "frontend", SpanCount: 1, SelfTime: 40000},
+			{ServiceName: "mysql", SpanCount: 1, ErrorCount: 1, SelfTime: 35000},
+			{ServiceName: "backend", SpanCount: 2, SelfTime: 25000},
+		},
+	}, traceStats(statsTrace()))
+	assert.Equal(t, &ui.TraceStats{Services: []ui.ServiceStats{}}, traceStats(&model.Trace{}))
+}
+
+func parseTraceStats(t *testing.T, response structuredResponse) []ui.TraceStats {
+	data, err := json.Marshal(response.Data)
+	require.NoError(t, err)
+	var stats []ui.TraceStats
+	require.NoError(t, json.Unmarshal(data, &stats))
+	return stats
+}
+
+func TestGetTraceStats(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), model.NewTraceID(0, 0xa)).
+			Return(statsTrace(), nil)
+		ts.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), model.NewTraceID(0, 0xb)).
+			Return(nil, spanstore.ErrTraceNotFound)
+		ts.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), model.NewTraceID(0, 0xc)).
+			Return(nil, errStorage)
+
+		var response structuredResponse
+		require.NoError(t, getJSON(ts.server.URL+"/api/traces/a/stats", &response))
+		stats := parseTraceStats(t, response)
+		require.Len(t, stats, 1)
+		assert.Equal(t, 4, stats[0].SpanCount)
+		assert.Len(t, stats[0].Services, 3)
+		assert.Empty(t, response.Errors)
+
+		err := getJSON(ts.server.URL+"/api/traces/b/stats", &response)
+		assert.EqualError(t, err, parsedError(404, spanstore.ErrTraceNotFound.Error()))
+		err = getJSON(ts.server.URL+"/api/traces/c/stats", &response)
+		assert.EqualError(t, err, parsedError(500, errStorageMsg))
+		err = getJSON(ts.server.URL+"/api/traces/x/stats", &response)
+		assert.EqualError(t, err, parsedError(400, `strconv.ParseUint: parsing \"x\": invalid syntax`))
+	}, querysvc.QueryServiceOptions{})
+}
+
+func TestGetTraceStatsAdjustmentFailure(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("model.TraceID")).
+			Return(statsTrace(), nil)
+
+		var response structuredResponse
+		require.NoError(t, getJSON(ts.server.URL+"/api/traces/a/stats", &response))
+		require.Len(t, response.Errors, 1)
+		assert.Equal(t, errAdjustment.Error(), response.Errors[0].Msg)
+
+		require.NoError(t, getJSON(ts.server.URL+"/api/traces/a/stats?raw=true", &response))
+		assert.Empty(t, response.Errors)
+	}, querysvc.QueryServiceOptions{
+		Adjuster: adjuster.Func(func(trace *model.Trace) (*model.Trace, error) {
+			return trace, errAdjustment
+		}),
+	})
+}
+
+func TestSearchTraceStats(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("FindTraces", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("*spanstore.TraceQueryParameters")).
+			Return([]*model.Trace{statsTrace(), mockTrace}, nil)
+
+		var response structuredResponse
+		require.NoError(t, getJSON(ts.server.URL+"/api/traces/stats?service=frontend&limit=1", &response))
+		stats := parseTraceStats(t, response)
+		require.Len(t, stats, 1)
+		assert.NotEmpty(t, response.NextPageToken)
+
+		require.NoError(t, getJSON(ts.server.URL+"/api/traces/stats?service=frontend", &response))
+		assert.Len(t, parseTraceStats(t, response), 2)
+
+		err := getJSON(ts.server.URL+"/api/traces/stats", &response)
+		assert.EqualError(t, err, parsedError(400, "parameter 'service' is required"))
+	}, querysvc.QueryServiceOptions{})
+}
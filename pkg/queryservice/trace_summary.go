@@ -0,0 +1,143 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jaegertracing/jaeger/model"
+	ui "github.com/jaegertracing/jaeger/model/json"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+const (
+	modeParam   = "mode"
+	spanIDParam = "spanID"
+
+	modeFull    = "full"
+	modeSummary = "summary"
+)
+
+// summaryTags are the span tags kept in the summary of a trace, they give the status of the spans.
+var summaryTags = map[string]bool{
+	"error":            true,
+	"span.kind":        true,
+	"otel.status_code": true,
+	"http.status_code": true,
+}
+
+var (
+	errSpanIDRequired = errors.New("at least one spanID is required")
+	errSummaryFormat  = fmt.Errorf("%s=%s is only supported by the %s format", modeParam, modeSummary, formatJaeger)
+)
+
+// parseMode returns true if only the summary of the trace is requested.
+func parseMode(r *http.Request) (bool, error) {
+	switch mode := r.FormValue(modeParam); mode {
+	case "", modeFull:
+		return false, nil
+	case modeSummary:
+		return true, nil
+	default:
+		return false, fmt.Errorf("unable to parse %s: expecting %q or %q", modeParam, modeFull, modeSummary)
+	}
+}
+
+// stripSpanDetails removes the logs and the tags other than the status tags from the spans,
+// keeping their IDs, references, names and timings. The spans can then be fetched in full
+// from /traces/{trace-id}/spans.
+func stripSpanDetails(trace *ui.Trace) {
+	for i := range trace.Spans {
+		span := &trace.Spans[i]
+		tags := []ui.KeyValue{}
+		for _, tag := range span.Tags {
+			if summaryTags[tag.Key] {
+				tags = append(tags, tag)
+			}
+		}
+		span.Tags = tags
+		span.Logs = []ui.Log{}
+	}
+}
+
+// selectSpans keeps in the trace the spans with the given IDs and the processes they refer to.
+func selectSpans(trace *ui.Trace, spanIDs []model.SpanID) {
+	selected := make(map[ui.SpanID]bool, len(spanIDs))
+	for _, spanID := range spanIDs {
+		selected[ui.SpanID(spanID.String())] = true
+	}
+	spans := []ui.Span{}
+	processes := make(map[ui.ProcessID]ui.Process)
+	for _, span := range trace.Spans {
+		if !selected[span.SpanID] {
+			continue
+		}
+		spans = append(spans, span)
+		if process, ok := trace.Processes[span.ProcessID]; ok {
+			processes[span.ProcessID] = process
+		}
+	}
+	trace.Spans = spans
+	trace.Processes = processes
+}
+
+// getSpans implements the REST API /traces/{trace-id}/spans?spanID=...
+// It returns the selected spans of the trace with their tags and logs, to fill in the
+// spans of a trace fetched with mode=summary.
+func (aH *APIHandler) getSpans(w http.ResponseWriter, r *http.Request) {
+	traceID, ok := aH.parseTraceID(w, r)
+	if !ok {
+		return
+	}
+	if err := r.ParseForm(); aH.handleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	if len(r.Form[spanIDParam]) == 0 {
+		aH.handleError(w, errSpanIDRequired, http.StatusBadRequest)
+		return
+	}
+	spanIDs := make([]model.SpanID, 0, len(r.Form[spanIDParam]))
+	for _, id := range r.Form[spanIDParam] {
+		spanID, err := model.SpanIDFromString(id)
+		if aH.handleError(w, err, http.StatusBadRequest) {
+			return
+		}
+		spanIDs = append(spanIDs, spanID)
+	}
+	trace, err := aH.queryService.GetTrace(r.Context(), traceID)
+	if err == spanstore.ErrTraceNotFound {
+		aH.handleError(w, err, http.StatusNotFound)
+		return
+	}
+	if aH.handleError(w, err, http.StatusInternalServerError) {
+		return
+	}
+
+	// the whole trace is adjusted so that the spans match the ones of the summary
+	var uiErrors []structuredError
+	uiTrace, uiErr := aH.convertModelToUI(trace, shouldAdjust(r))
+	if uiErr != nil {
+		uiErrors = append(uiErrors, *uiErr)
+	}
+	selectSpans(uiTrace, spanIDs)
+
+	structuredRes := structuredResponse{
+		Data:   []*ui.Trace{uiTrace},
+		Errors: uiErrors,
+	}
+	aH.writeJSON(w, r, &structuredRes)
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	ui "github.com/jaegertracing/jaeger/model/json"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+func detailedTrace() *model.Trace {
+	traceID := model.NewTraceID(0, 0xa)
+	root := diffSpan(traceID, 1, 0, "frontend", "GET /", 0, 100*time.Millisecond)
+	child := diffSpan(traceID, 2, 1, "mysql", "SELECT", 10*time.Millisecond, 50*time.Millisecond)
+	for _, span := range []*model.Span{root, child} {
+		span.Tags = model.KeyValues{model.Bool("error", true), model.String("sql.query", "SELECT *")}
+		span.Logs = []model.Log{{Timestamp: span.StartTime, Fields: model.KeyValues{model.String("event", "retry")}}}
+	}
+	return &model.Trace{Spans: []*model.Span{root, child}}
+}
+
+func TestGetTraceSummary(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), model.NewTraceID(0, 0xa)).
+			Return(detailedTrace(), nil)
+
+		var response structuredTraceResponse
+		require.NoError(t, getJSON(ts.server.URL+"/api/traces/a?mode=summary", &response))
+		require.Len(t, response.Traces, 1)
+		require.Len(t, response.Traces[0].Spans, 2)
+		for _, span := range response.Traces[0].Spans {
+			assert.Equal(t, []ui.KeyValue{{Key: "error", Type: ui.BoolType, Value: true}}, span.Tags)
+			assert.Empty(t, span.Logs)
+			assert.NotZero(t, span.Duration)
+		}
+		assert.Len(t, response.Traces[0].Processes, 2)
+
+		require.NoError(t, getJSON(ts.server.URL+"/api/traces/a?mode=full", &response))
+		assert.Len(t, response.Traces[0].Spans[0].Tags, 2)
+		assert.Len(t, response.Traces[0].Spans[0].Logs, 1)
+
+		err := getJSON(ts.server.URL+"/api/traces/a?mode=tiny", &response)
+		assert.EqualError(t, err, parsedError(400, `unable to parse mode: expecting \"full\" or \"summary\"`))
+		err = getJSON(ts.server.URL+"/api/traces/a?mode=summary&format=zipkin", &response)
+		assert.EqualError(t, err, parsedError(400, errSummaryFormat.Error()))
+	}, querysvc.QueryServiceOptions{})
+}
+
+func TestGetSpans(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), model.NewTraceID(0, 0xa)).
+			Return(detailedTrace(), nil)
+
+		var response structuredTraceResponse
+		require.NoError(t, getJSON(ts.server.URL+"/api/traces/a/spans?spanID=2&spanID=3", &response))
+		require.Len(t, response.Traces, 1)
+		trace := response.Traces[0]
+		require.Len(t, trace.Spans, 1)
+		assert.Equal(t, ui.SpanID("0000000000000002"), trace.Spans[0].SpanID)
+		assert.Len(t, trace.Spans[0].Tags, 2)
+		assert.Len(t, trace.Spans[0].Logs, 1)
+		require.Len(t, trace.Processes, 1)
+		assert.Equal(t, "mysql", trace.Processes[trace.Spans[0].ProcessID].ServiceName)
+	}, querysvc.QueryServiceOptions{})
+}
+
+func TestGetSpansErrors(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		ts.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), model.NewTraceID(0, 0xb)).
+			Return(nil, spanstore.ErrTraceNotFound)
+		ts.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), model.NewTraceID(0, 0xc)).
+			Return(nil, errStorage)
+
+		var response structuredTraceResponse
+		err := getJSON(ts.server.URL+"/api/traces/b/spans", &response)
+		assert.EqualError(t, err, parsedError(400, errSpanIDRequired.Error()))
+		err = getJSON(ts.server.URL+"/api/traces/b/spans?spanID=x", &response)
+		assert.EqualError(t, err, parsedError(400, `strconv.ParseUint: parsing \"x\": invalid syntax`))
+		err = getJSON(ts.server.URL+"/api/traces/x/spans?spanID=1", &response)
+		assert.EqualError(t, err, parsedError(400, `strconv.ParseUint: parsing \"x\": invalid syntax`))
+		err = getJSON(ts.server.URL+"/api/traces/b/spans?spanID=1", &response)
+		assert.EqualError(t, err, parsedError(404, spanstore.ErrTraceNotFound.Error()))
+		err = getJSON(ts.server.URL+"/api/traces/c/spans?spanID=1", &response)
+		assert.EqualError(t, err, parsedError(500, errStorageMsg))
+	}, querysvc.QueryServiceOptions{})
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	jsonConverter "github.com/jaegertracing/jaeger/model/converter/json"
+	"github.com/jaegertracing/jaeger/model/converter/otlp"
+	ui "github.com/jaegertracing/jaeger/model/json"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+)
+
+const maxUploadedTraceSize = 10 << 20
+
+var (
+	errUnknownUploadFormat = errors.New("expecting a Jaeger trace, a Jaeger traces download or an OTLP/JSON export request")
+	errUploadedTraceEmpty  = errors.New("the uploaded trace has no span")
+)
+
+type uploadedTraceResponse struct {
+	TraceID         ui.TraceID `json:"traceID"`
+	OriginalTraceID ui.TraceID `json:"originalTraceID"`
+	ExpiresAt       time.Time  `json:"expiresAt"`
+}
+
+// uploadTraces implements the REST API POST /traces/upload. It accepts a Jaeger trace, the traces downloaded
+// from the Jaeger UI or API, or an OTLP/JSON export request, and returns the temporary IDs to view them with.
+func (aH *APIHandler) uploadTraces(w http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxUploadedTraceSize))
+	if aH.handleError(w, err, http.StatusBadRequest) {
+		return
+	}
+	traces, err := parseUploadedTraces(data)
+	if err != nil {
+		aH.handleError(w, fmt.Errorf("cannot parse the uploaded trace: %w", err), http.StatusBadRequest)
+		return
+	}
+	uploaded := make([]uploadedTraceResponse, 0, len(traces))
+	for _, trace := range traces {
+		u, err := aH.queryService.UploadTrace(r.Context(), trace)
+		if err == querysvc.ErrUploadsDisabled {
+			aH.handleError(w, err, http.StatusNotImplemented)
+			return
+		}
+		if aH.handleError(w, err, http.StatusInternalServerError) {
+			return
+		}
+		uploaded = append(uploaded, uploadedTraceResponse{
+			TraceID:         ui.TraceID(u.TraceID.String()),
+			OriginalTraceID: ui.TraceID(u.OriginalTraceID.String()),
+			ExpiresAt:       u.ExpiresAt,
+		})
+	}
+	aH.writeJSON(w, r, &structuredResponse{Data: uploaded})
+}
+
+// parseUploadedTraces recognizes the format of the upload by its top-level fields.
+func parseUploadedTraces(data []byte) ([]*model.Trace, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	var traces []*model.Trace
+	var err error
+	switch {
+	case fields["resourceSpans"] != nil:
+		traces, err = parseOTLPTraces(data)
+	case fields["data"] != nil:
+		var download struct {
+			Data []*ui.Trace `json:"data"`
+		}
+		if err = decodeUploadedJSON(data, &download); err == nil {
+			traces, err = uiTracesToDomain(download.Data)
+		}
+	case fields["spans"] != nil:
+		var trace ui.Trace
+		if err = decodeUploadedJSON(data, &trace); err == nil {
+			traces, err = uiTracesToDomain([]*ui.Trace{&trace})
+		}
+	default:
+		err = errUnknownUploadFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(traces) == 0 {
+		return nil, errUploadedTraceEmpty
+	}
+	for _, trace := range traces {
+		if len(trace.Spans) == 0 {
+			return nil, errUploadedTraceEmpty
+		}
+	}
+	return traces, nil
+}
+
+// decodeUploadedJSON keeps the numbers of the tags as json.Number, so that large integers are not rounded.
+func decodeUploadedJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+func uiTracesToDomain(uiTraces []*ui.Trace) ([]*model.Trace, error) {
+	traces := make([]*model.Trace, 0, len(uiTraces))
+	for _, uiTrace := range uiTraces {
+		trace, err := jsonConverter.TraceToDomain(uiTrace)
+		if err != nil {
+			return nil, err
+		}
+		traces = append(traces, trace)
+	}
+	return traces, nil
+}
+
+// parseOTLPTraces groups the spans of the export request by trace, in the order they appear.
+func parseOTLPTraces(data []byte) ([]*model.Trace, error) {
+	req, err := otlp.UnmarshalJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	batches, err := otlp.ToDomain(req)
+	if err != nil {
+		return nil, err
+	}
+	var traces []*model.Trace
+	byID := make(map[model.TraceID]*model.Trace)
+	for _, batch := range batches {
+		for _, span := range batch.Spans {
+			if span.Process == nil {
+				span.Process = batch.Process
+			}
+			trace, ok := byID[span.TraceID]
+			if !ok {
+				trace = &model.Trace{}
+				byID[span.TraceID] = trace
+				traces = append(traces, trace)
+			}
+			trace.Spans = append(trace.Spans, span)
+		}
+	}
+	return traces, nil
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	jsonConverter "github.com/jaegertracing/jaeger/model/converter/json"
+	"github.com/jaegertracing/jaeger/model/converter/otlp"
+	ui "github.com/jaegertracing/jaeger/model/json"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+type structuredUploadResponse struct {
+	Data   []uploadedTraceResponse `json:"data"`
+	Errors []structuredError       `json:"errors"`
+}
+
+func uploadTrace(t *testing.T, ts *testServer, body interface{}) []uploadedTraceResponse {
+	var response structuredUploadResponse
+	require.NoError(t, postJSON(ts.server.URL+"/api/traces/upload", body, &response))
+	return response.Data
+}
+
+// viewUploadedTrace reads the uploaded trace, which is not found in the span storage.
+func viewUploadedTrace(t *testing.T, ts *testServer, traceID ui.TraceID) *ui.Trace {
+	id, err := model.TraceIDFromString(string(traceID))
+	require.NoError(t, err)
+	ts.spanReader.On("GetTrace", mock.AnythingOfType("*context.valueCtx"), id).
+		Return(nil, spanstore.ErrTraceNotFound).Once()
+	var response structuredTraceResponse
+	require.NoError(t, getJSON(ts.server.URL+"/api/traces/"+string(traceID), &response))
+	require.Len(t, response.Traces, 1)
+	return response.Traces[0]
+}
+
+func TestUploadTraceJaeger(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		uploaded := uploadTrace(t, ts, jsonConverter.FromDomain(mockTrace))
+		require.Len(t, uploaded, 1)
+		assert.Equal(t, ui.TraceID("000000000001e240"), uploaded[0].OriginalTraceID)
+		assert.NotEqual(t, uploaded[0].OriginalTraceID, uploaded[0].TraceID)
+		assert.False(t, uploaded[0].ExpiresAt.IsZero())
+
+		trace := viewUploadedTrace(t, ts, uploaded[0].TraceID)
+		assert.Equal(t, uploaded[0].TraceID, trace.TraceID)
+		assert.Len(t, trace.Spans, 2)
+	}, querysvc.QueryServiceOptions{MaxUploadedTraces: 10})
+}
+
+func TestUploadTraceJaegerDownload(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		other := &model.Trace{Spans: []*model.Span{
+			{TraceID: model.NewTraceID(0, 1), SpanID: model.NewSpanID(1), Process: model.NewProcess("frontend", nil)},
+		}}
+		uploaded := uploadTrace(t, ts, structuredResponse{Data: []*ui.Trace{
+			jsonConverter.FromDomain(mockTrace),
+			jsonConverter.FromDomain(other),
+		}})
+		require.Len(t, uploaded, 2)
+		assert.Equal(t, ui.TraceID("0000000000000001"), uploaded[1].OriginalTraceID)
+
+		trace := viewUploadedTrace(t, ts, uploaded[1].TraceID)
+		require.Len(t, trace.Spans, 1)
+		assert.Equal(t, "frontend", trace.Processes[trace.Spans[0].ProcessID].ServiceName)
+	}, querysvc.QueryServiceOptions{MaxUploadedTraces: 10})
+}
+
+func TestUploadTraceOTLP(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		process := model.NewProcess("frontend", nil)
+		batch := &model.Batch{Process: process, Spans: []*model.Span{
+			{TraceID: mockTraceID, SpanID: model.NewSpanID(1), Process: process},
+			{TraceID: mockTraceID, SpanID: model.NewSpanID(2), Process: process},
+		}}
+		body, err := otlp.MarshalJSON(otlp.FromDomain([]*model.Batch{batch}))
+		require.NoError(t, err)
+
+		uploaded := uploadTrace(t, ts, json.RawMessage(body))
+		require.Len(t, uploaded, 1)
+		assert.Equal(t, ui.TraceID("000000000001e240"), uploaded[0].OriginalTraceID)
+
+		trace := viewUploadedTrace(t, ts, uploaded[0].TraceID)
+		require.Len(t, trace.Spans, 2)
+		assert.Equal(t, "frontend", trace.Processes[trace.Spans[0].ProcessID].ServiceName)
+	}, querysvc.QueryServiceOptions{MaxUploadedTraces: 10})
+}
+
+func TestUploadTraceErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		body interface{}
+		err  string
+	}{
+		{
+			name: "not an object",
+			body: []string{},
+			err:  "cannot parse the uploaded trace: json: cannot unmarshal array",
+		},
+		{
+			name: "unknown format",
+			body: map[string]string{"foo": "bar"},
+			err:  "cannot parse the uploaded trace: " + errUnknownUploadFormat.Error(),
+		},
+		{
+			name: "no span",
+			body: map[string][]string{"spans": {}},
+			err:  "cannot parse the uploaded trace: " + errUploadedTraceEmpty.Error(),
+		},
+		{
+			name: "no trace",
+			body: map[string][]string{"data": {}},
+			err:  "cannot parse the uploaded trace: " + errUploadedTraceEmpty.Error(),
+		},
+		{
+			name: "invalid span",
+			body: ui.Trace{Spans: []ui.Span{{TraceID: "1", SpanID: "x"}}},
+			err:  `cannot parse the uploaded trace: invalid span \"x\"`,
+		},
+		{
+			name: "invalid OTLP",
+			body: map[string]string{"resourceSpans": "x"},
+			err:  "cannot parse the uploaded trace: ",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			withTestServer(t, func(ts *testServer) {
+				err := postJSON(ts.server.URL+"/api/traces/upload", tc.body, &structuredResponse{})
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), fmt.Sprintf("%d error from server: ", http.StatusBadRequest))
+				assert.Contains(t, err.Error(), tc.err)
+			}, querysvc.QueryServiceOptions{MaxUploadedTraces: 10})
+		})
+	}
+}
+
+func TestUploadTraceDisabled(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		err := postJSON(ts.server.URL+"/api/traces/upload", jsonConverter.FromDomain(mockTrace), &structuredResponse{})
+		assert.EqualError(t, err, parsedError(http.StatusNotImplemented, querysvc.ErrUploadsDisabled.Error()))
+	}, querysvc.QueryServiceOptions{})
+}
@@ -0,0 +1,236 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+
+	"github.com/jaegertracing/jaeger/pkg/auth"
+	"github.com/jaegertracing/jaeger/storage/viewstore"
+)
+
+const searchIDParam = "searchID"
+
+var errSearchNameRequired = errors.New("the name of the saved search is required")
+
+// viewRequest is the body of the requests creating or updating a saved search or a pinned trace.
+type viewRequest struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Query       map[string][]string `json:"query"`
+	Note        string              `json:"note"`
+}
+
+func (aH *APIHandler) registerViewRoutes(router *mux.Router) {
+	aH.handleFunc(router, aH.getSearches, "/views/searches").Methods(http.MethodGet)
+	aH.handleFunc(router, aH.createSearch, "/views/searches").Methods(http.MethodPost)
+	aH.handleFunc(router, aH.getSearch, "/views/searches/{%s}", searchIDParam).Methods(http.MethodGet)
+	aH.handleFunc(router, aH.updateSearch, "/views/searches/{%s}", searchIDParam).Methods(http.MethodPut)
+	aH.handleFunc(router, aH.deleteSearch, "/views/searches/{%s}", searchIDParam).Methods(http.MethodDelete)
+	aH.handleFunc(router, aH.getPinnedTraces, "/views/pinned-traces").Methods(http.MethodGet)
+	aH.handleFunc(router, aH.getPinnedTrace, "/views/pinned-traces/{%s}", traceIDParam).Methods(http.MethodGet)
+	aH.handleFunc(router, aH.pinTrace, "/views/pinned-traces/{%s}", traceIDParam).Methods(http.MethodPut)
+	aH.handleFunc(router, aH.unpinTrace, "/views/pinned-traces/{%s}", traceIDParam).Methods(http.MethodDelete)
+}
+
+func (aH *APIHandler) getSearches(w http.ResponseWriter, r *http.Request) {
+	searches, err := aH.queryService.ViewStore().GetSearches(r.Context())
+	if aH.handleViewError(w, err) {
+		return
+	}
+	structuredRes := structuredResponse{
+		Data:  searches,
+		Total: len(searches),
+	}
+	aH.writeJSON(w, r, &structuredRes)
+}
+
+func (aH *APIHandler) getSearch(w http.ResponseWriter, r *http.Request) {
+	search, err := aH.queryService.ViewStore().GetSearch(r.Context(), mux.Vars(r)[searchIDParam])
+	if aH.handleViewError(w, err) {
+		return
+	}
+	aH.writeJSON(w, r, &structuredResponse{Data: search})
+}
+
+func (aH *APIHandler) createSearch(w http.ResponseWriter, r *http.Request) {
+	req, ok := aH.parseSearchRequest(w, r)
+	if !ok {
+		return
+	}
+	id, err := newSearchID()
+	if aH.handleError(w, err, http.StatusInternalServerError) {
+		return
+	}
+	now := aH.queryParser.timeNow()
+	search := &viewstore.SavedSearch{
+		ID:          id,
+		Name:        req.Name,
+		Description: req.Description,
+		Query:       req.Query,
+		Owner:       subject(r),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if aH.handleViewError(w, aH.queryService.ViewStore().SaveSearch(r.Context(), search)) {
+		return
+	}
+	aH.writeJSON(w, r, &structuredResponse{Data: search})
+}
+
+func (aH *APIHandler) updateSearch(w http.ResponseWriter, r *http.Request) {
+	req, ok := aH.parseSearchRequest(w, r)
+	if !ok {
+		return
+	}
+	store := aH.queryService.ViewStore()
+	search, err := store.GetSearch(r.Context(), mux.Vars(r)[searchIDParam])
+	if aH.handleViewError(w, err) {
+		return
+	}
+	search.Name = req.Name
+	search.Description = req.Description
+	search.Query = req.Query
+	search.UpdatedAt = aH.queryParser.timeNow()
+	if aH.handleViewError(w, store.SaveSearch(r.Context(), search)) {
+		return
+	}
+	aH.writeJSON(w, r, &structuredResponse{Data: search})
+}
+
+func (aH *APIHandler) deleteSearch(w http.ResponseWriter, r *http.Request) {
+	err := aH.queryService.ViewStore().DeleteSearch(r.Context(), mux.Vars(r)[searchIDParam])
+	if aH.handleViewError(w, err) {
+		return
+	}
+	aH.writeJSON(w, r, &structuredResponse{Data: []string{}})
+}
+
+func (aH *APIHandler) getPinnedTraces(w http.ResponseWriter, r *http.Request) {
+	pins, err := aH.queryService.ViewStore().GetPinnedTraces(r.Context())
+	if aH.handleViewError(w, err) {
+		return
+	}
+	structuredRes := structuredResponse{
+		Data:  pins,
+		Total: len(pins),
+	}
+	aH.writeJSON(w, r, &structuredRes)
+}
+
+func (aH *APIHandler) getPinnedTrace(w http.ResponseWriter, r *http.Request) {
+	traceID, ok := aH.parseTraceID(w, r)
+	if !ok {
+		return
+	}
+	pin, err := aH.queryService.ViewStore().GetPinnedTrace(r.Context(), traceID.String())
+	if aH.handleViewError(w, err) {
+		return
+	}
+	aH.writeJSON(w, r, &structuredResponse{Data: pin})
+}
+
+// pinTrace implements the REST API PUT /views/pinned-traces/{trace-id}, with an optional JSON body holding the note.
+func (aH *APIHandler) pinTrace(w http.ResponseWriter, r *http.Request) {
+	traceID, ok := aH.parseTraceID(w, r)
+	if !ok {
+		return
+	}
+	var req viewRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			aH.handleError(w, fmt.Errorf("cannot parse the pinned trace: %w", err), http.StatusBadRequest)
+			return
+		}
+	}
+	pin := &viewstore.PinnedTrace{
+		TraceID:  traceID.String(),
+		Note:     req.Note,
+		Owner:    subject(r),
+		PinnedAt: aH.queryParser.timeNow(),
+	}
+	if aH.handleViewError(w, aH.queryService.ViewStore().PinTrace(r.Context(), pin)) {
+		return
+	}
+	aH.writeJSON(w, r, &structuredResponse{Data: pin})
+}
+
+func (aH *APIHandler) unpinTrace(w http.ResponseWriter, r *http.Request) {
+	traceID, ok := aH.parseTraceID(w, r)
+	if !ok {
+		return
+	}
+	if aH.handleViewError(w, aH.queryService.ViewStore().UnpinTrace(r.Context(), traceID.String())) {
+		return
+	}
+	aH.writeJSON(w, r, &structuredResponse{Data: []string{}})
+}
+
+// parseSearchRequest decodes the saved search, and checks that its query would be accepted by the search endpoint.
+func (aH *APIHandler) parseSearchRequest(w http.ResponseWriter, r *http.Request) (*viewRequest, bool) {
+	var req viewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		aH.handleError(w, fmt.Errorf("cannot parse the saved search: %w", err), http.StatusBadRequest)
+		return nil, false
+	}
+	if req.Name == "" {
+		aH.handleError(w, errSearchNameRequired, http.StatusBadRequest)
+		return nil, false
+	}
+	searchReq := &http.Request{
+		Method: http.MethodGet,
+		URL:    &url.URL{RawQuery: url.Values(req.Query).Encode()},
+	}
+	if _, err := aH.queryParser.parse(searchReq); err != nil {
+		aH.handleError(w, fmt.Errorf("invalid query of the saved search: %w", err), http.StatusBadRequest)
+		return nil, false
+	}
+	return &req, true
+}
+
+// handleViewError reports the views as not implemented when no view storage is configured.
+func (aH *APIHandler) handleViewError(w http.ResponseWriter, err error) bool {
+	switch err {
+	case viewstore.ErrDisabled:
+		return aH.handleError(w, err, http.StatusNotImplemented)
+	case viewstore.ErrNotFound:
+		return aH.handleError(w, err, http.StatusNotFound)
+	}
+	return aH.handleError(w, err, http.StatusInternalServerError)
+}
+
+func newSearchID() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// subject returns the authenticated caller, or an empty string.
+func subject(r *http.Request) string {
+	if identity := auth.IdentityFromContext(r.Context()); identity != nil {
+		return identity.Subject
+	}
+	return ""
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queryservice
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/pkg/auth"
+	"github.com/jaegertracing/jaeger/pkg/queryservice/querysvc"
+	"github.com/jaegertracing/jaeger/plugin/storage/memory"
+	"github.com/jaegertracing/jaeger/storage/viewstore"
+)
+
+type savedSearchResponse struct {
+	Data   viewstore.SavedSearch `json:"data"`
+	Errors []structuredError     `json:"errors"`
+}
+
+type savedSearchesResponse struct {
+	Data  []viewstore.SavedSearch `json:"data"`
+	Total int                     `json:"total"`
+}
+
+type pinnedTraceResponse struct {
+	Data viewstore.PinnedTrace `json:"data"`
+}
+
+type pinnedTracesResponse struct {
+	Data  []viewstore.PinnedTrace `json:"data"`
+	Total int                     `json:"total"`
+}
+
+func sendJSON(method, url, body string, out interface{}) error {
+	req, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+	if err != nil {
+		return err
+	}
+	return execJSON(req, out)
+}
+
+func TestSavedSearches(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		now := time.Unix(1600000000, 0).UTC()
+		ts.handler.queryParser.timeNow = func() time.Time { return now }
+		url := ts.server.URL + "/api/views/searches"
+
+		var created savedSearchResponse
+		err := postJSON(url, map[string]interface{}{
+			"name":  "slow checkouts",
+			"query": map[string][]string{"service": {"checkout"}, "minDuration": {"1s"}},
+		}, &created)
+		require.NoError(t, err)
+		assert.NotEmpty(t, created.Data.ID)
+		assert.Equal(t, "slow checkouts", created.Data.Name)
+		assert.Equal(t, now, created.Data.CreatedAt)
+
+		now = now.Add(time.Minute)
+		var updated savedSearchResponse
+		err = sendJSON(http.MethodPut, url+"/"+created.Data.ID,
+			`{"name": "slow checkouts", "description": "over a second", "query": {"service": ["checkout"], "minDuration": ["1s"]}}`, &updated)
+		require.NoError(t, err)
+		assert.Equal(t, created.Data.ID, updated.Data.ID)
+		assert.Equal(t, "over a second", updated.Data.Description)
+		assert.Equal(t, created.Data.CreatedAt, updated.Data.CreatedAt)
+		assert.Equal(t, now, updated.Data.UpdatedAt)
+
+		var found savedSearchResponse
+		require.NoError(t, getJSON(url+"/"+created.Data.ID, &found))
+		assert.Equal(t, updated.Data, found.Data)
+		var searches savedSearchesResponse
+		require.NoError(t, getJSON(url, &searches))
+		assert.Equal(t, 1, searches.Total)
+
+		require.NoError(t, sendJSON(http.MethodDelete, url+"/"+created.Data.ID, "", nil))
+		err = getJSON(url+"/"+created.Data.ID, &found)
+		assert.Contains(t, err.Error(), "404 error from server")
+		err = sendJSON(http.MethodPut, url+"/"+created.Data.ID, `{"name": "slow checkouts", "query": {"service": ["checkout"]}}`, nil)
+		assert.Contains(t, err.Error(), "404 error from server")
+	}, querysvc.QueryServiceOptions{ViewStore: memory.NewViewStore()})
+}
+
+func TestSavedSearchBadRequests(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		url := ts.server.URL + "/api/views/searches"
+		testCases := []struct {
+			body    string
+			message string
+		}{
+			{body: `{`, message: "cannot parse the saved search"},
+			{body: `{"query": {"service": ["checkout"]}}`, message: "the name of the saved search is required"},
+			{body: `{"name": "bad", "query": {"service": ["checkout"], "minDuration": ["20"]}}`, message: "invalid query of the saved search"},
+		}
+		for _, tc := range testCases {
+			err := sendJSON(http.MethodPost, url, tc.body, nil)
+			require.Error(t, err, tc.body)
+			assert.Contains(t, err.Error(), "400 error from server", tc.body)
+			assert.Contains(t, err.Error(), tc.message, tc.body)
+		}
+	}, querysvc.QueryServiceOptions{ViewStore: memory.NewViewStore()})
+}
+
+func TestPinnedTraces(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		url := ts.server.URL + "/api/views/pinned-traces"
+
+		var pin pinnedTraceResponse
+		require.NoError(t, sendJSON(http.MethodPut, url+"/00000000000000ab", `{"note": "outage"}`, &pin))
+		assert.Equal(t, "00000000000000ab", pin.Data.TraceID)
+		assert.Equal(t, "outage", pin.Data.Note)
+		var pinWithoutNote pinnedTraceResponse
+		require.NoError(t, sendJSON(http.MethodPut, url+"/cd", "", &pinWithoutNote))
+		assert.Equal(t, "", pinWithoutNote.Data.Note)
+
+		var pins pinnedTracesResponse
+		require.NoError(t, getJSON(url, &pins))
+		assert.Equal(t, 2, pins.Total)
+		require.NoError(t, getJSON(url+"/ab", &pin))
+		assert.Equal(t, "outage", pin.Data.Note)
+
+		require.NoError(t, sendJSON(http.MethodDelete, url+"/ab", "", nil))
+		err := sendJSON(http.MethodDelete, url+"/ab", "", nil)
+		assert.Contains(t, err.Error(), "404 error from server")
+		err = getJSON(url+"/ab", &pin)
+		assert.Contains(t, err.Error(), "404 error from server")
+
+		err = sendJSON(http.MethodPut, url+"/cd", "{", nil)
+		assert.Contains(t, err.Error(), "cannot parse the pinned trace")
+		err = sendJSON(http.MethodPut, url+"/xyz", "", nil)
+		assert.Contains(t, err.Error(), "400 error from server")
+	}, querysvc.QueryServiceOptions{ViewStore: memory.NewViewStore()})
+}
+
+func TestViewsDisabled(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		for _, path := range []string{"/api/views/searches", "/api/views/searches/a", "/api/views/pinned-traces", "/api/views/pinned-traces/ab"} {
+			err := getJSON(ts.server.URL+path, nil)
+			require.Error(t, err, path)
+			assert.Contains(t, err.Error(), "501 error from server", path)
+		}
+		err := sendJSON(http.MethodPut, ts.server.URL+"/api/views/pinned-traces/ab", "", nil)
+		assert.Contains(t, err.Error(), "501 error from server")
+		err = sendJSON(http.MethodDelete, ts.server.URL+"/api/views/searches/a", "", nil)
+		assert.Contains(t, err.Error(), "501 error from server")
+	}, querysvc.QueryServiceOptions{})
+}
+
+func TestViewOwner(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Equal(t, "", subject(r))
+	r = r.WithContext(auth.ContextWithIdentity(r.Context(), &auth.Identity{Subject: "alice"}))
+	assert.Equal(t, "alice", subject(r))
+}
+
+func TestHandleViewError(t *testing.T) {
+	withTestServer(t, func(ts *testServer) {
+		w := httptest.NewRecorder()
+		assert.True(t, ts.handler.handleViewError(w, fmt.Errorf("storage error")))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.False(t, ts.handler.handleViewError(httptest.NewRecorder(), nil))
+	}, querysvc.QueryServiceOptions{})
+}
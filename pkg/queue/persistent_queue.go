@@ -0,0 +1,506 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	segmentSuffix = ".seg"
+	cursorFile    = "cursor"
+	headerSize    = 8 // record length and CRC32 checksum, 4 bytes each
+
+	// DefaultSegmentSize is the size of a segment file after which a new segment is started
+	DefaultSegmentSize = 64 * 1024 * 1024
+	// DefaultCheckpointInterval is how often the read position is saved to disk
+	DefaultCheckpointInterval = time.Second
+)
+
+// PersistentQueueOptions configures a PersistentQueue.
+type PersistentQueueOptions struct {
+	// Directory holds the segment files, it is created if it does not exist
+	Directory string
+	// SegmentSize is the size in bytes after which the current segment file is rotated
+	SegmentSize int64
+	// MaxSize is the maximum size in bytes of all segments, new items are rejected when reached; 0 means unlimited
+	MaxSize int64
+	// MaxAge discards segments that were not consumed within this duration; 0 keeps them forever
+	MaxAge time.Duration
+	// CheckpointInterval is how often the read position and the current segment are synced to disk
+	CheckpointInterval time.Duration
+}
+
+type segment struct {
+	id         int64
+	path       string
+	created    time.Time
+	size       int64 // bytes of valid records
+	items      int
+	readOffset int64
+	readItems  int
+	inflight   []int64 // offsets of the items handed to consumers that have not returned yet, in read order
+	reader     *os.File
+}
+
+func (s *segment) fullyRead() bool {
+	return s.readItems >= s.items
+}
+
+// PersistentQueue is a producer-consumer queue spooling items to segmented files on disk,
+// so that items survive restarts of the process and long outages of the consumers.
+//
+// Items are appended to the newest segment and consumed from the oldest one; a segment
+// is deleted once all its items have been consumed. The position of the oldest item not
+// yet consumed is saved periodically and on Stop, and unconsumed items are replayed when
+// the queue is reopened. After a crash the items consumed since the last checkpoint are
+// replayed, so an item may be delivered more than once.
+type PersistentQueue struct {
+	options        PersistentQueueOptions
+	onDroppedItems func(count int)
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	segments  []*segment // oldest first, the last one is being written
+	writer    *os.File
+	totalSize int64
+	size      int
+	stopped   bool // consumers must exit
+	closed    bool // no more items are accepted
+	stopWG    sync.WaitGroup
+	timeNow   func() time.Time
+
+	checkpointStop chan struct{}
+	checkpointDone chan struct{}
+}
+
+// NewPersistentQueue opens the queue in the given directory, recovering the items left by a previous run.
+// The optional callback is invoked with the number of items dropped because the queue is full,
+// segments expired or were found corrupted.
+func NewPersistentQueue(options PersistentQueueOptions, onDroppedItems func(count int)) (*PersistentQueue, error) {
+	if options.SegmentSize <= 0 {
+		options.SegmentSize = DefaultSegmentSize
+	}
+	if options.CheckpointInterval <= 0 {
+		options.CheckpointInterval = DefaultCheckpointInterval
+	}
+	if err := os.MkdirAll(options.Directory, 0700); err != nil {
+		return nil, fmt.Errorf("cannot create queue directory: %w", err)
+	}
+	if onDroppedItems == nil {
+		onDroppedItems = func(int) {}
+	}
+	q := &PersistentQueue{
+		options:        options,
+		onDroppedItems: onDroppedItems,
+		timeNow:        time.Now,
+		checkpointStop: make(chan struct{}),
+		checkpointDone: make(chan struct{}),
+	}
+	q.cond = sync.NewCond(&q.mu)
+	if err := q.recover(); err != nil {
+		return nil, err
+	}
+	var nextID int64
+	if n := len(q.segments); n > 0 {
+		nextID = q.segments[n-1].id + 1
+	}
+	if err := q.rotate(nextID); err != nil {
+		return nil, err
+	}
+	go q.checkpointLoop()
+	return q, nil
+}
+
+func (q *PersistentQueue) segmentPath(id int64) string {
+	return filepath.Join(q.options.Directory, fmt.Sprintf("%020d%s", id, segmentSuffix))
+}
+
+func (q *PersistentQueue) recover() error {
+	files, err := ioutil.ReadDir(q.options.Directory)
+	if err != nil {
+		return fmt.Errorf("cannot list queue directory: %w", err)
+	}
+	cursorID, cursorOffset := q.readCursor()
+	for _, f := range files {
+		name := f.Name()
+		if !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		id, err := strconv.ParseInt(strings.TrimSuffix(name, segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		if id < cursorID {
+			// fully consumed before the last shutdown
+			_ = os.Remove(q.segmentPath(id))
+			continue
+		}
+		s := &segment{id: id, path: q.segmentPath(id), created: f.ModTime()}
+		if err := scanSegment(s, cursorOffset, id == cursorID); err != nil {
+			return err
+		}
+		q.segments = append(q.segments, s)
+		q.totalSize += s.size
+		q.size += s.items - s.readItems
+	}
+	sort.Slice(q.segments, func(i, j int) bool { return q.segments[i].id < q.segments[j].id })
+	return nil
+}
+
+// scanSegment counts the valid records of a segment, ignoring a trailing partial record,
+// and marks the records before the cursor offset as read.
+func scanSegment(s *segment, cursorOffset int64, hasCursor bool) error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return fmt.Errorf("cannot open queue segment: %w", err)
+	}
+	defer f.Close()
+	for {
+		data, err := readRecord(f, s.size)
+		if err != nil {
+			break
+		}
+		if hasCursor && s.size < cursorOffset {
+			s.readItems++
+			s.readOffset = s.size + headerSize + int64(len(data))
+		}
+		s.size += headerSize + int64(len(data))
+		s.items++
+	}
+	return nil
+}
+
+func readRecord(f io.ReaderAt, offset int64) ([]byte, error) {
+	var header [headerSize]byte
+	if _, err := f.ReadAt(header[:], offset); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header[0:4])
+	data := make([]byte, length)
+	if _, err := f.ReadAt(data, offset+headerSize); err != nil {
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, fmt.Errorf("checksum mismatch at offset %d", offset)
+	}
+	return data, nil
+}
+
+func (q *PersistentQueue) readCursor() (int64, int64) {
+	data, err := ioutil.ReadFile(filepath.Join(q.options.Directory, cursorFile))
+	if err != nil {
+		return 0, 0
+	}
+	var id, offset int64
+	if _, err := fmt.Sscanf(string(data), "%d %d", &id, &offset); err != nil {
+		return 0, 0
+	}
+	return id, offset
+}
+
+// cursorLocked returns the position of the oldest item that has not been consumed yet.
+func (q *PersistentQueue) cursorLocked() (int64, int64) {
+	if len(q.segments) == 0 {
+		return 0, 0
+	}
+	s := q.segments[0]
+	if len(s.inflight) > 0 {
+		return s.id, s.inflight[0]
+	}
+	return s.id, s.readOffset
+}
+
+// writeCursor durably replaces the cursor file: the new content is synced to a temporary file
+// which is then renamed over the cursor file, so that a crash leaves either the old or the new position.
+func (q *PersistentQueue) writeCursor(id, offset int64) error {
+	path := filepath.Join(q.options.Directory, cursorFile)
+	tmp, err := os.OpenFile(filepath.Clean(path+".tmp"), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(tmp, "%d %d", id, offset); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	dir, err := os.Open(q.options.Directory)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+func (q *PersistentQueue) checkpointLoop() {
+	defer close(q.checkpointDone)
+	ticker := time.NewTicker(q.options.CheckpointInterval)
+	defer ticker.Stop()
+	lastID, lastOffset := int64(-1), int64(-1)
+	for {
+		select {
+		case <-q.checkpointStop:
+			return
+		case <-ticker.C:
+		}
+		q.mu.Lock()
+		// the items produced so far are synced too, so that they are not lost if the host crashes
+		_ = q.writer.Sync()
+		id, offset := q.cursorLocked()
+		q.mu.Unlock()
+		if id == lastID && offset == lastOffset {
+			continue
+		}
+		if err := q.writeCursor(id, offset); err == nil {
+			lastID, lastOffset = id, offset
+		}
+	}
+}
+
+// rotate closes the current segment and starts writing to a new one, must be called with the lock held.
+func (q *PersistentQueue) rotate(id int64) error {
+	if q.writer != nil {
+		if err := q.writer.Sync(); err != nil {
+			return err
+		}
+		if err := q.writer.Close(); err != nil {
+			return err
+		}
+	}
+	path := q.segmentPath(id)
+	f, err := os.OpenFile(filepath.Clean(path), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("cannot create queue segment: %w", err)
+	}
+	q.writer = f
+	q.segments = append(q.segments, &segment{id: id, path: path, created: q.timeNow()})
+	return nil
+}
+
+// Produce appends an item to the queue, returning false if the item could not be stored.
+func (q *PersistentQueue) Produce(item []byte) bool {
+	recordSize := int64(headerSize + len(item))
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return false
+	}
+	if q.options.MaxSize > 0 && q.totalSize+recordSize > q.options.MaxSize {
+		q.onDroppedItems(1)
+		return false
+	}
+	current := q.segments[len(q.segments)-1]
+	if current.size > 0 && current.size+recordSize > q.options.SegmentSize {
+		if err := q.rotate(current.id + 1); err != nil {
+			q.onDroppedItems(1)
+			return false
+		}
+		current = q.segments[len(q.segments)-1]
+	}
+	record := make([]byte, recordSize)
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(item)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(item))
+	copy(record[headerSize:], item)
+	if _, err := q.writer.Write(record); err != nil {
+		q.onDroppedItems(1)
+		return false
+	}
+	current.size += recordSize
+	current.items++
+	q.totalSize += recordSize
+	q.size++
+	q.cond.Signal()
+	return true
+}
+
+// StartConsumers starts a given number of goroutines consuming items from the queue
+// and passing them into the consumer callback. An item is only removed from disk after
+// the callback has returned.
+func (q *PersistentQueue) StartConsumers(num int, consumer func(item []byte)) {
+	for i := 0; i < num; i++ {
+		q.stopWG.Add(1)
+		go func() {
+			defer q.stopWG.Done()
+			for {
+				item, s, offset, ok := q.next()
+				if !ok {
+					return
+				}
+				consumer(item)
+				q.done(s, offset)
+			}
+		}()
+	}
+}
+
+func (q *PersistentQueue) next() ([]byte, *segment, int64, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for {
+		if q.stopped {
+			return nil, nil, 0, false
+		}
+		q.removeConsumedLocked()
+		for i, s := range q.segments {
+			if s.fullyRead() {
+				continue
+			}
+			writing := i == len(q.segments)-1
+			if !writing && q.options.MaxAge > 0 && q.timeNow().Sub(s.created) > q.options.MaxAge {
+				q.discardLocked(s)
+				continue
+			}
+			offset := s.readOffset
+			item, err := q.readLocked(s)
+			if err != nil {
+				q.discardLocked(s)
+				continue
+			}
+			s.inflight = append(s.inflight, offset)
+			return item, s, offset, true
+		}
+		q.cond.Wait()
+	}
+}
+
+func (q *PersistentQueue) readLocked(s *segment) ([]byte, error) {
+	if s.reader == nil {
+		f, err := os.Open(s.path)
+		if err != nil {
+			return nil, err
+		}
+		s.reader = f
+	}
+	item, err := readRecord(s.reader, s.readOffset)
+	if err != nil {
+		return nil, err
+	}
+	s.readOffset += headerSize + int64(len(item))
+	s.readItems++
+	q.size--
+	return item, nil
+}
+
+// discardLocked drops the unread items of a segment.
+func (q *PersistentQueue) discardLocked(s *segment) {
+	dropped := s.items - s.readItems
+	s.readItems = s.items
+	s.readOffset = s.size
+	q.size -= dropped
+	q.onDroppedItems(dropped)
+}
+
+func (q *PersistentQueue) done(s *segment, offset int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, o := range s.inflight {
+		if o == offset {
+			s.inflight = append(s.inflight[:i], s.inflight[i+1:]...)
+			break
+		}
+	}
+	q.removeConsumedLocked()
+}
+
+// removeConsumedLocked deletes the oldest segments whose items have all been consumed.
+func (q *PersistentQueue) removeConsumedLocked() {
+	for len(q.segments) > 1 {
+		s := q.segments[0]
+		if !s.fullyRead() || len(s.inflight) > 0 {
+			return
+		}
+		if s.reader != nil {
+			_ = s.reader.Close()
+		}
+		_ = os.Remove(s.path)
+		q.totalSize -= s.size
+		q.segments = q.segments[1:]
+	}
+}
+
+// Stop waits for the consumers to finish their current items, saves the read position
+// and closes the files. Unconsumed items remain on disk. Consumers may still produce
+// items, e.g. to put back an item they could not process, until they return.
+func (q *PersistentQueue) Stop() error {
+	q.mu.Lock()
+	q.stopped = true
+	q.cond.Broadcast()
+	q.mu.Unlock()
+	q.stopWG.Wait()
+	close(q.checkpointStop)
+	<-q.checkpointDone
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.removeConsumedLocked()
+	for _, s := range q.segments {
+		if s.reader != nil {
+			_ = s.reader.Close()
+		}
+	}
+	if err := q.writer.Sync(); err != nil {
+		return err
+	}
+	if err := q.writer.Close(); err != nil {
+		return err
+	}
+	return q.writeCursor(q.cursorLocked())
+}
+
+// Size returns the number of items waiting to be consumed
+func (q *PersistentQueue) Size() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.size
+}
+
+// Pending returns the number of items waiting to be consumed or being consumed
+func (q *PersistentQueue) Pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	pending := q.size
+	for _, s := range q.segments {
+		pending += len(s.inflight)
+	}
+	return pending
+}
+
+// SizeBytes returns the size of the segments on disk
+func (q *PersistentQueue) SizeBytes() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.totalSize
+}
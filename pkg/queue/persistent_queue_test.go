@@ -0,0 +1,242 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tempQueueDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "persistent-queue")
+	require.NoError(t, err)
+	return dir
+}
+
+type collectedItems struct {
+	sync.Mutex
+	items []string
+}
+
+func (c *collectedItems) add(item []byte) {
+	c.Lock()
+	defer c.Unlock()
+	c.items = append(c.items, string(item))
+}
+
+func (c *collectedItems) snapshot() []string {
+	c.Lock()
+	defer c.Unlock()
+	return append([]string(nil), c.items...)
+}
+
+func (c *collectedItems) waitFor(t *testing.T, n int) {
+	for i := 0; i < 200 && len(c.snapshot()) < n; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	require.Len(t, c.snapshot(), n)
+}
+
+func TestPersistentQueue(t *testing.T) {
+	dir := tempQueueDir(t)
+	defer os.RemoveAll(dir)
+
+	q, err := NewPersistentQueue(PersistentQueueOptions{Directory: dir, SegmentSize: 30}, nil)
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		assert.True(t, q.Produce([]byte(fmt.Sprintf("item-%d", i))))
+	}
+	assert.Equal(t, 10, q.Size())
+	assert.Equal(t, 10, q.Pending())
+	assert.EqualValues(t, 10*(headerSize+6), q.SizeBytes())
+
+	consumed := &collectedItems{}
+	q.StartConsumers(1, consumed.add)
+	consumed.waitFor(t, 10)
+	assert.Equal(t, "item-0", consumed.snapshot()[0])
+	assert.Equal(t, "item-9", consumed.snapshot()[9])
+	assert.Equal(t, 0, q.Size())
+	for i := 0; i < 200 && q.Pending() > 0; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.Equal(t, 0, q.Pending())
+	require.NoError(t, q.Stop())
+	assert.False(t, q.Produce([]byte("late")))
+
+	segments, err := filepath.Glob(filepath.Join(dir, "*"+segmentSuffix))
+	require.NoError(t, err)
+	assert.Len(t, segments, 1, "consumed segments are deleted")
+}
+
+func TestPersistentQueueReplaysAfterRestart(t *testing.T) {
+	dir := tempQueueDir(t)
+	defer os.RemoveAll(dir)
+
+	q, err := NewPersistentQueue(PersistentQueueOptions{Directory: dir, SegmentSize: 30}, nil)
+	require.NoError(t, err)
+	consumed := &collectedItems{}
+	block := make(chan struct{})
+	q.StartConsumers(1, func(item []byte) {
+		consumed.add(item)
+		if len(consumed.snapshot()) == 3 {
+			<-block
+		}
+	})
+	for i := 0; i < 6; i++ {
+		require.True(t, q.Produce([]byte(fmt.Sprintf("item-%d", i))))
+	}
+	consumed.waitFor(t, 3)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(block)
+	}()
+	require.NoError(t, q.Stop())
+
+	q, err = NewPersistentQueue(PersistentQueueOptions{Directory: dir, SegmentSize: 30}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, q.Size())
+	replayed := &collectedItems{}
+	q.StartConsumers(2, replayed.add)
+	replayed.waitFor(t, 3)
+	assert.ElementsMatch(t, []string{"item-3", "item-4", "item-5"}, replayed.snapshot())
+	require.NoError(t, q.Stop())
+}
+
+func TestPersistentQueueRecoversFromCrash(t *testing.T) {
+	dir := tempQueueDir(t)
+	defer os.RemoveAll(dir)
+
+	q, err := NewPersistentQueue(PersistentQueueOptions{Directory: dir}, nil)
+	require.NoError(t, err)
+	require.True(t, q.Produce([]byte("item-0")))
+	require.True(t, q.Produce([]byte("item-1")))
+	// simulate a crash in the middle of writing a record
+	_, err = q.writer.Write([]byte{0, 0, 0, 42})
+	require.NoError(t, err)
+	require.NoError(t, q.writer.Close())
+
+	dropped := 0
+	q, err = NewPersistentQueue(PersistentQueueOptions{Directory: dir}, func(count int) { dropped += count })
+	require.NoError(t, err)
+	assert.Equal(t, 2, q.Size())
+	consumed := &collectedItems{}
+	q.StartConsumers(1, consumed.add)
+	consumed.waitFor(t, 2)
+	require.NoError(t, q.Stop())
+	assert.Equal(t, 0, dropped)
+}
+
+func TestPersistentQueueCheckpoint(t *testing.T) {
+	dir := tempQueueDir(t)
+	defer os.RemoveAll(dir)
+
+	q, err := NewPersistentQueue(PersistentQueueOptions{Directory: dir, CheckpointInterval: time.Millisecond}, nil)
+	require.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		require.True(t, q.Produce([]byte(fmt.Sprintf("item-%d", i))))
+	}
+	consumed := &collectedItems{}
+	release := make(chan struct{})
+	q.StartConsumers(1, func(item []byte) {
+		if string(item) == "item-2" {
+			<-release
+		}
+		consumed.add(item)
+	})
+	consumed.waitFor(t, 2)
+
+	// the checkpoint points to item-2, which is still being consumed
+	expected := fmt.Sprintf("0 %d", 2*(headerSize+len("item-0")))
+	var cursor []byte
+	for i := 0; i < 200; i++ {
+		if cursor, _ = ioutil.ReadFile(filepath.Join(dir, cursorFile)); string(cursor) == expected {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	require.Equal(t, expected, string(cursor))
+
+	// simulate a crash by reopening a copy of the directory while the queue is running
+	crashDir := tempQueueDir(t)
+	defer os.RemoveAll(crashDir)
+	for _, name := range []string{cursorFile, "00000000000000000000" + segmentSuffix} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(crashDir, name), data, 0600))
+	}
+	recovered, err := NewPersistentQueue(PersistentQueueOptions{Directory: crashDir}, nil)
+	require.NoError(t, err)
+	replayed := &collectedItems{}
+	recovered.StartConsumers(1, replayed.add)
+	replayed.waitFor(t, 2)
+	assert.Equal(t, []string{"item-2", "item-3"}, replayed.snapshot())
+	require.NoError(t, recovered.Stop())
+
+	close(release)
+	consumed.waitFor(t, 4)
+	require.NoError(t, q.Stop())
+}
+
+func TestPersistentQueueMaxSize(t *testing.T) {
+	dir := tempQueueDir(t)
+	defer os.RemoveAll(dir)
+
+	dropped := 0
+	q, err := NewPersistentQueue(PersistentQueueOptions{Directory: dir, MaxSize: 2 * (headerSize + 6)}, func(count int) { dropped += count })
+	require.NoError(t, err)
+	assert.True(t, q.Produce([]byte("item-0")))
+	assert.True(t, q.Produce([]byte("item-1")))
+	assert.False(t, q.Produce([]byte("item-2")))
+	assert.Equal(t, 1, dropped)
+	require.NoError(t, q.Stop())
+}
+
+func TestPersistentQueueMaxAge(t *testing.T) {
+	dir := tempQueueDir(t)
+	defer os.RemoveAll(dir)
+
+	dropped := 0
+	q, err := NewPersistentQueue(PersistentQueueOptions{Directory: dir, SegmentSize: 20, MaxAge: time.Minute}, func(count int) { dropped += count })
+	require.NoError(t, err)
+	now := time.Now()
+	assert.True(t, q.Produce([]byte("item-0")))
+	assert.True(t, q.Produce([]byte("item-1")))
+	now = now.Add(time.Hour)
+	q.timeNow = func() time.Time { return now }
+	assert.True(t, q.Produce([]byte("item-2")))
+
+	consumed := &collectedItems{}
+	q.StartConsumers(1, consumed.add)
+	consumed.waitFor(t, 1)
+	assert.Equal(t, []string{"item-2"}, consumed.snapshot())
+	assert.Equal(t, 2, dropped)
+	require.NoError(t, q.Stop())
+}
+
+func TestPersistentQueueInvalidDirectory(t *testing.T) {
+	f, err := ioutil.TempFile("", "not-a-dir")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = NewPersistentQueue(PersistentQueueOptions{Directory: f.Name()}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tenancy
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/grpc"
+
+	"github.com/jaegertracing/jaeger/pkg/auth"
+)
+
+type tenantContextKey struct{}
+
+// WithTenant returns a context carrying the tenant of the request.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+// GetTenant returns the tenant of the request, empty when tenancy is disabled.
+func GetTenant(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantContextKey{}).(string)
+	return tenant
+}
+
+// HTTPHandler returns a handler that adds the tenant of the requests to their context,
+// and rejects the requests whose tenant is missing or not allowed.
+func (tc *Manager) HTTPHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant, err := tc.GetValidHTTPTenant(r)
+		if err != nil {
+			http.Error(w, err.Error(), HTTPStatus(err))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithTenant(r.Context(), tenant)))
+	})
+}
+
+// UnaryServerInterceptor returns a gRPC interceptor that adds the tenant of the requests to their context,
+// and rejects the requests whose tenant is missing or not allowed.
+func (tc *Manager) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		tenant, err := tc.GetValidGRPCTenant(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(WithTenant(ctx, tenant), req)
+	}
+}
+
+// StreamServerInterceptor returns a gRPC interceptor that adds the tenant of the streams to their context,
+// and rejects the streams whose tenant is missing or not allowed.
+func (tc *Manager) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		tenant, err := tc.GetValidGRPCTenant(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, auth.WithStreamContext(WithTenant(ss.Context(), tenant), ss))
+	}
+}
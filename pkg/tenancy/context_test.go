@@ -0,0 +1,90 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tenancy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestTenantContext(t *testing.T) {
+	assert.Equal(t, "", GetTenant(context.Background()))
+	assert.Equal(t, "acme", GetTenant(WithTenant(context.Background(), "acme")))
+}
+
+func TestHTTPHandler(t *testing.T) {
+	mgr := NewManager(&Options{Enabled: true, Tenants: []string{"acme"}})
+	var tenant string
+	handler := mgr.HTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant = GetTenant(r.Context())
+	}))
+
+	for header, code := range map[string]int{"acme": http.StatusOK, "megacorp": http.StatusForbidden, "": http.StatusUnauthorized} {
+		tenant = ""
+		r := httptest.NewRequest(http.MethodGet, "/api/traces", nil)
+		if header != "" {
+			r.Header.Set(DefaultHeader, header)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, code, w.Code, header)
+		if code == http.StatusOK {
+			assert.Equal(t, "acme", tenant)
+		} else {
+			assert.Equal(t, "", tenant, "the handler must not be called")
+		}
+	}
+}
+
+func TestServerInterceptors(t *testing.T) {
+	mgr := NewManager(&Options{Enabled: true})
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(DefaultHeader, "acme"))
+
+	res, err := mgr.UnaryServerInterceptor()(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return GetTenant(ctx), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "acme", res)
+	_, err = mgr.UnaryServerInterceptor()(context.Background(), nil, &grpc.UnaryServerInfo{}, nil)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	var tenant string
+	err = mgr.StreamServerInterceptor()(nil, &contextServerStream{ctx: ctx}, &grpc.StreamServerInfo{}, func(srv interface{}, stream grpc.ServerStream) error {
+		tenant = GetTenant(stream.Context())
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "acme", tenant)
+	err = mgr.StreamServerInterceptor()(nil, &contextServerStream{ctx: context.Background()}, &grpc.StreamServerInfo{}, nil)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tenancy
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	flagEnabled = "multi-tenancy.enabled"
+	flagHeader  = "multi-tenancy.header"
+	flagTenants = "multi-tenancy.tenants"
+
+	// DefaultHeader is the HTTP header, or gRPC metadata key, carrying the tenant
+	DefaultHeader = "x-tenant"
+)
+
+// Options describes the configuration properties for multi-tenancy
+type Options struct {
+	// Enabled requires every request to identify its tenant
+	Enabled bool
+	// Header is the HTTP header or gRPC metadata key carrying the tenant
+	Header string
+	// Tenants is the allowlist of tenants, any tenant is accepted when empty
+	Tenants []string
+}
+
+// AddFlags adds flags for tenancy to the FlagSet.
+func AddFlags(flags *flag.FlagSet) {
+	flags.Bool(flagEnabled, false, "Enable tenancy header when receiving or querying")
+	flags.String(flagHeader, DefaultHeader, "HTTP header carrying tenant")
+	flags.String(flagTenants, "",
+		"comma-separated list of allowed values for --"+flagHeader+" header. (If not supplied, tenants are not restricted)")
+}
+
+// InitFromViper creates tenancy.Options populated with values retrieved from Viper.
+func InitFromViper(v *viper.Viper) Options {
+	var p Options
+	p.Enabled = v.GetBool(flagEnabled)
+	p.Header = v.GetString(flagHeader)
+	tenants := v.GetString(flagTenants)
+	if len(tenants) != 0 {
+		p.Tenants = strings.Split(tenants, ",")
+	} else {
+		p.Tenants = []string{}
+	}
+	return p
+}
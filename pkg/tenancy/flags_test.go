@@ -0,0 +1,57 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tenancy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jaegertracing/jaeger/pkg/config"
+)
+
+func TestTenancyFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		cmd      []string
+		expected Options
+	}{
+		{
+			name:     "defaults",
+			cmd:      []string{},
+			expected: Options{Header: DefaultHeader, Tenants: []string{}},
+		},
+		{
+			name: "enabled with allowlist",
+			cmd: []string{
+				"--multi-tenancy.enabled=true",
+				"--multi-tenancy.header=jaeger-tenant",
+				"--multi-tenancy.tenants=acme,hardware-store",
+			},
+			expected: Options{
+				Enabled: true,
+				Header:  "jaeger-tenant",
+				Tenants: []string{"acme", "hardware-store"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v, command := config.Viperize(AddFlags)
+			assert.NoError(t, command.ParseFlags(test.cmd))
+			assert.Equal(t, test.expected, InitFromViper(v))
+		})
+	}
+}
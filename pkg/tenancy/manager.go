@@ -0,0 +1,223 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/auth"
+)
+
+// TenantTag is the process tag holding the tenant of the spans received by the collector.
+const TenantTag = "tenant"
+
+var (
+	// ErrMissingTenant is returned when tenancy is enabled and a request does not carry a tenant
+	ErrMissingTenant = errors.New("missing tenant header")
+	// ErrUnknownTenant is returned when the tenant of a request is not in the allowlist
+	ErrUnknownTenant = errors.New("unknown tenant")
+	// ErrTenantMismatch is returned when the tenant header of a request is not the tenant of its authenticated caller
+	ErrTenantMismatch = errors.New("tenant header does not match the tenant of the caller")
+)
+
+// Manager can check tenant usage for multi-tenant Jaeger configurations.
+// A nil Manager behaves as if tenancy was disabled.
+type Manager struct {
+	Enabled bool
+	Header  string
+	guard   guard
+}
+
+// guard verifies a valid tenant when tenancy is enabled
+type guard interface {
+	Valid(candidate string) bool
+}
+
+// NewManager creates a tenancy.Manager for given tenancy.Options.
+func NewManager(options *Options) *Manager {
+	// Default header value (although set by CLI flags, this helps tests and API users)
+	header := options.Header
+	if header == "" && options.Enabled {
+		header = DefaultHeader
+	}
+	return &Manager{
+		Enabled: options.Enabled,
+		Header:  header,
+		guard:   tenancyGuardFactory(options),
+	}
+}
+
+// Valid returns true if the tenant is allowed
+func (tc *Manager) Valid(tenant string) bool {
+	return tc.guard.Valid(tenant)
+}
+
+type tenantDontCare bool
+
+func (tenantDontCare) Valid(candidate string) bool {
+	return true
+}
+
+type tenantList struct {
+	tenants map[string]bool
+}
+
+func (tl *tenantList) Valid(candidate string) bool {
+	_, ok := tl.tenants[candidate]
+	return ok
+}
+
+func newTenantList(tenants []string) *tenantList {
+	tenantMap := make(map[string]bool)
+	for _, tenant := range tenants {
+		tenantMap[strings.TrimSpace(tenant)] = true
+	}
+
+	return &tenantList{
+		tenants: tenantMap,
+	}
+}
+
+func tenancyGuardFactory(options *Options) guard {
+	// Three cases
+	// - no tenancy
+	// - tenancy, but no guarding by tenant
+	// - tenancy, with guarding by a list
+
+	if !options.Enabled || len(options.Tenants) == 0 {
+		return tenantDontCare(true)
+	}
+
+	return newTenantList(options.Tenants)
+}
+
+// validate checks the tenant of a request, returning it or an error. The tenant asserted by
+// the authenticated identity of the caller, if any, takes precedence over the tenant header,
+// which must then be either missing or the same.
+func (tc *Manager) validate(ctx context.Context, tenant string) (string, error) {
+	if identity := auth.IdentityFromContext(ctx); identity != nil && identity.Tenant != "" {
+		if tenant != "" && tenant != identity.Tenant {
+			return "", ErrTenantMismatch
+		}
+		tenant = identity.Tenant
+	}
+	if tenant == "" {
+		return "", ErrMissingTenant
+	}
+	if !tc.Valid(tenant) {
+		return "", ErrUnknownTenant
+	}
+	return tenant, nil
+}
+
+// GetValidHTTPTenant returns the tenant of an HTTP request, or an error if tenancy is enabled and
+// the tenant is missing or not allowed. It returns an empty tenant when tenancy is disabled.
+func (tc *Manager) GetValidHTTPTenant(r *http.Request) (string, error) {
+	if tc == nil || !tc.Enabled {
+		return "", nil
+	}
+	return tc.validate(r.Context(), r.Header.Get(tc.Header))
+}
+
+// HTTPStatus returns the HTTP status code for an error returned by GetValidHTTPTenant
+func HTTPStatus(err error) int {
+	if errors.Is(err, ErrUnknownTenant) || errors.Is(err, ErrTenantMismatch) {
+		return http.StatusForbidden
+	}
+	return http.StatusUnauthorized
+}
+
+// GetValidGRPCTenant returns the tenant from the incoming gRPC metadata, or a gRPC status error
+// if tenancy is enabled and the tenant is missing or not allowed.
+func (tc *Manager) GetValidGRPCTenant(ctx context.Context) (string, error) {
+	if tc == nil || !tc.Enabled {
+		return "", nil
+	}
+	var tenant string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(tc.Header); len(values) > 0 {
+			if len(values) > 1 {
+				return "", status.Errorf(codes.PermissionDenied, "extra tenant header")
+			}
+			tenant = values[0]
+		}
+	}
+	tenant, err := tc.validate(ctx, tenant)
+	if errors.Is(err, ErrUnknownTenant) || errors.Is(err, ErrTenantMismatch) {
+		return "", status.Error(codes.PermissionDenied, err.Error())
+	}
+	if err != nil {
+		return "", status.Error(codes.Unauthenticated, err.Error())
+	}
+	return tenant, nil
+}
+
+// SetSpanTenant records the tenant in the process tags of the span, replacing any tenant
+// tag sent by the client so that it cannot impersonate another tenant. The process, which is
+// shared by all the spans of a batch, is copied before its tags are replaced.
+func SetSpanTenant(span *model.Span, tenant string) {
+	var process model.Process
+	if span.Process != nil {
+		process = *span.Process
+	}
+	process.Tags = append(withoutTenant(process.Tags, 1), model.String(TenantTag, tenant))
+	span.Process = &process
+}
+
+// RemoveSpanTenant removes any tenant tag sent by the client from the process tags of the span,
+// so that it is not mistaken for a validated tenant when tenancy is disabled. The process is
+// copied like by SetSpanTenant, and left as is when it has no tenant tag.
+func RemoveSpanTenant(span *model.Span) {
+	if span.Process == nil {
+		return
+	}
+	if _, ok := model.KeyValues(span.Process.Tags).FindByKey(TenantTag); !ok {
+		return
+	}
+	process := *span.Process
+	process.Tags = withoutTenant(process.Tags, 0)
+	span.Process = &process
+}
+
+// withoutTenant returns a new slice with the tags but the tenant tags, with room for extra tags.
+func withoutTenant(tags []model.KeyValue, extra int) []model.KeyValue {
+	kept := make([]model.KeyValue, 0, len(tags)+extra)
+	for _, tag := range tags {
+		if tag.Key != TenantTag {
+			kept = append(kept, tag)
+		}
+	}
+	return kept
+}
+
+// GetSpanTenant returns the tenant recorded in the process tags of the span, if any,
+// so that storage writers can route spans by tenant.
+func GetSpanTenant(span *model.Span) string {
+	if span.Process == nil {
+		return ""
+	}
+	if tag, ok := model.KeyValues(span.Process.Tags).FindByKey(TenantTag); ok {
+		return tag.AsString()
+	}
+	return ""
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tenancy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/auth"
+)
+
+func TestTenancyValidity(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		tenant  string
+		valid   bool
+	}{
+		{name: "no tenancy", options: Options{}, tenant: "acme", valid: true},
+		{name: "tenancy without allowlist", options: Options{Enabled: true}, tenant: "acme", valid: true},
+		{name: "allowed tenant", options: Options{Enabled: true, Tenants: []string{"acme", " megacorp"}}, tenant: "megacorp", valid: true},
+		{name: "unknown tenant", options: Options{Enabled: true, Tenants: []string{"megacorp"}}, tenant: "acme", valid: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tc := NewManager(&test.options)
+			assert.Equal(t, test.valid, tc.Valid(test.tenant))
+		})
+	}
+}
+
+func TestNewManagerDefaultHeader(t *testing.T) {
+	assert.Equal(t, DefaultHeader, NewManager(&Options{Enabled: true}).Header)
+	assert.Equal(t, "", NewManager(&Options{}).Header)
+}
+
+func TestGetValidHTTPTenant(t *testing.T) {
+	tests := []struct {
+		name     string
+		mgr      *Manager
+		header   string
+		identity *auth.Identity
+		tenant   string
+		err      error
+		httpCode int
+	}{
+		{name: "nil manager", mgr: nil, header: "acme"},
+		{name: "disabled", mgr: NewManager(&Options{}), header: "acme"},
+		{name: "valid", mgr: NewManager(&Options{Enabled: true, Tenants: []string{"acme"}}), header: "acme", tenant: "acme"},
+		{name: "missing", mgr: NewManager(&Options{Enabled: true}), err: ErrMissingTenant, httpCode: http.StatusUnauthorized},
+		{name: "unknown", mgr: NewManager(&Options{Enabled: true, Tenants: []string{"acme"}}), header: "megacorp", err: ErrUnknownTenant, httpCode: http.StatusForbidden},
+		{name: "identity", mgr: NewManager(&Options{Enabled: true}), identity: &auth.Identity{Subject: "alice", Tenant: "acme"}, tenant: "acme"},
+		{name: "identity and same header", mgr: NewManager(&Options{Enabled: true}), header: "acme", identity: &auth.Identity{Subject: "alice", Tenant: "acme"}, tenant: "acme"},
+		{name: "identity and other header", mgr: NewManager(&Options{Enabled: true}), header: "megacorp", identity: &auth.Identity{Subject: "alice", Tenant: "acme"}, err: ErrTenantMismatch, httpCode: http.StatusForbidden},
+		{name: "identity of unknown tenant", mgr: NewManager(&Options{Enabled: true, Tenants: []string{"megacorp"}}), identity: &auth.Identity{Subject: "alice", Tenant: "acme"}, err: ErrUnknownTenant, httpCode: http.StatusForbidden},
+		{name: "identity without tenant", mgr: NewManager(&Options{Enabled: true}), header: "acme", identity: &auth.Identity{Subject: "alice"}, tenant: "acme"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/traces", nil)
+			if test.identity != nil {
+				r = r.WithContext(auth.ContextWithIdentity(r.Context(), test.identity))
+			}
+			if test.header != "" {
+				r.Header.Set(DefaultHeader, test.header)
+			}
+			tenant, err := test.mgr.GetValidHTTPTenant(r)
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.tenant, tenant)
+			if err != nil {
+				assert.Equal(t, test.httpCode, HTTPStatus(err))
+			}
+		})
+	}
+}
+
+func TestGetValidGRPCTenant(t *testing.T) {
+	tests := []struct {
+		name     string
+		mgr      *Manager
+		md       metadata.MD
+		identity *auth.Identity
+		tenant   string
+		code     codes.Code
+	}{
+		{name: "nil manager", mgr: nil, md: metadata.Pairs(DefaultHeader, "acme")},
+		{name: "disabled", mgr: NewManager(&Options{}), md: metadata.Pairs(DefaultHeader, "acme")},
+		{name: "valid", mgr: NewManager(&Options{Enabled: true}), md: metadata.Pairs(DefaultHeader, "acme"), tenant: "acme"},
+		{name: "no metadata", mgr: NewManager(&Options{Enabled: true}), code: codes.Unauthenticated},
+		{name: "missing", mgr: NewManager(&Options{Enabled: true}), md: metadata.Pairs("other", "acme"), code: codes.Unauthenticated},
+		{name: "unknown", mgr: NewManager(&Options{Enabled: true, Tenants: []string{"megacorp"}}), md: metadata.Pairs(DefaultHeader, "acme"), code: codes.PermissionDenied},
+		{name: "extra", mgr: NewManager(&Options{Enabled: true}), md: metadata.Pairs(DefaultHeader, "acme", DefaultHeader, "megacorp"), code: codes.PermissionDenied},
+		{name: "identity", mgr: NewManager(&Options{Enabled: true}), identity: &auth.Identity{Subject: "alice", Tenant: "acme"}, tenant: "acme"},
+		{name: "identity and other header", mgr: NewManager(&Options{Enabled: true}), md: metadata.Pairs(DefaultHeader, "megacorp"), identity: &auth.Identity{Subject: "alice", Tenant: "acme"}, code: codes.PermissionDenied},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			if test.identity != nil {
+				ctx = auth.ContextWithIdentity(ctx, test.identity)
+			}
+			if test.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, test.md)
+			}
+			tenant, err := test.mgr.GetValidGRPCTenant(ctx)
+			assert.Equal(t, test.tenant, tenant)
+			assert.Equal(t, test.code, status.Code(err))
+		})
+	}
+}
+
+func TestSpanTenant(t *testing.T) {
+	span := &model.Span{}
+	assert.Equal(t, "", GetSpanTenant(span))
+
+	SetSpanTenant(span, "acme")
+	assert.Equal(t, "acme", GetSpanTenant(span))
+
+	span.Process.Tags = append(span.Process.Tags, model.String("hostname", "h"))
+	SetSpanTenant(span, "megacorp")
+	assert.Equal(t, []model.KeyValue{model.String("hostname", "h"), model.String(TenantTag, "megacorp")}, span.Process.Tags)
+	assert.Equal(t, "megacorp", GetSpanTenant(span))
+
+	RemoveSpanTenant(span)
+	assert.Equal(t, []model.KeyValue{model.String("hostname", "h")}, span.Process.Tags)
+	assert.Equal(t, "", GetSpanTenant(span))
+	RemoveSpanTenant(&model.Span{})
+
+	// the process without a tenant tag is not copied
+	process := span.Process
+	RemoveSpanTenant(span)
+	assert.True(t, process == span.Process)
+}
+
+func TestSpanTenantSharedProcess(t *testing.T) {
+	process := &model.Process{ServiceName: "svc", Tags: []model.KeyValue{model.String("hostname", "h"), model.String(TenantTag, "spoofed")}}
+	spans := []*model.Span{{Process: process}, {Process: process}, {Process: process}}
+	var wg sync.WaitGroup
+	for i, span := range spans {
+		wg.Add(1)
+		go func(i int, span *model.Span) {
+			defer wg.Done()
+			if i == 0 {
+				RemoveSpanTenant(span)
+			} else {
+				SetSpanTenant(span, "acme")
+			}
+			// as the writers do while the other spans are processed
+			GetSpanTenant(span)
+		}(i, span)
+	}
+	wg.Wait()
+
+	assert.Equal(t, "", GetSpanTenant(spans[0]))
+	assert.Equal(t, "acme", GetSpanTenant(spans[1]))
+	assert.Equal(t, "acme", GetSpanTenant(spans[2]))
+	assert.Equal(t, []model.KeyValue{model.String("hostname", "h"), model.String(TenantTag, "spoofed")}, process.Tags,
+		"the shared process must not be modified")
+}
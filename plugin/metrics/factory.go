@@ -0,0 +1,85 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/plugin"
+	"github.com/jaegertracing/jaeger/plugin/metrics/prometheus"
+	"github.com/jaegertracing/jaeger/storage"
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
+)
+
+const (
+	prometheusStorageType = "prometheus"
+)
+
+// AllStorageTypes defines all available metrics storage backends
+var AllStorageTypes = []string{prometheusStorageType}
+
+// Factory implements storage.MetricsFactory interface as a meta-factory for the span metrics storage.
+type Factory struct {
+	FactoryConfig
+	factory storage.MetricsFactory
+}
+
+// NewFactory creates the meta-factory.
+func NewFactory(config FactoryConfig) (*Factory, error) {
+	f := &Factory{FactoryConfig: config}
+	switch f.MetricsStorageType {
+	case "":
+	case prometheusStorageType:
+		f.factory = prometheus.NewFactory()
+	default:
+		return nil, fmt.Errorf("unknown metrics storage type %s. Valid types are %v", f.MetricsStorageType, AllStorageTypes)
+	}
+	return f, nil
+}
+
+// Initialize implements storage.MetricsFactory.
+func (f *Factory) Initialize(logger *zap.Logger) error {
+	if f.factory == nil {
+		return nil
+	}
+	return f.factory.Initialize(logger)
+}
+
+// CreateMetricsReader implements storage.MetricsFactory. The reader fails all the queries
+// with metricsstore.ErrDisabled when no metrics storage is configured.
+func (f *Factory) CreateMetricsReader() (metricsstore.Reader, error) {
+	if f.factory == nil {
+		return metricsstore.NewDisabledReader(), nil
+	}
+	return f.factory.CreateMetricsReader()
+}
+
+// AddFlags implements plugin.Configurable
+func (f *Factory) AddFlags(flagSet *flag.FlagSet) {
+	if conf, ok := f.factory.(plugin.Configurable); ok {
+		conf.AddFlags(flagSet)
+	}
+}
+
+// InitFromViper implements plugin.Configurable
+func (f *Factory) InitFromViper(v *viper.Viper) {
+	if conf, ok := f.factory.(plugin.Configurable); ok {
+		conf.InitFromViper(v)
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"os"
+)
+
+const (
+	// MetricsStorageTypeEnvVar is the name of the env var that defines the type of backend used for the span metrics.
+	MetricsStorageTypeEnvVar = "METRICS_STORAGE_TYPE"
+)
+
+// FactoryConfig tells the Factory which type of backend it needs to create for the span metrics.
+type FactoryConfig struct {
+	// MetricsStorageType is empty when querying the metrics is disabled
+	MetricsStorageType string
+}
+
+// FactoryConfigFromEnv reads the type of the metrics backend from the METRICS_STORAGE_TYPE environment variable.
+// Allowed values:
+//   * `prometheus` - built-in
+//
+// Querying the metrics is disabled if the variable is not set.
+func FactoryConfigFromEnv() FactoryConfig {
+	return FactoryConfig{
+		MetricsStorageType: os.Getenv(MetricsStorageTypeEnvVar),
+	}
+}
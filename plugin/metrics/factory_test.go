@@ -0,0 +1,72 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/pkg/config"
+	"github.com/jaegertracing/jaeger/plugin/metrics/prometheus"
+	"github.com/jaegertracing/jaeger/storage"
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
+)
+
+var _ storage.MetricsFactory = new(Factory)
+
+func TestFactoryConfigFromEnv(t *testing.T) {
+	assert.Equal(t, FactoryConfig{}, FactoryConfigFromEnv())
+
+	os.Setenv(MetricsStorageTypeEnvVar, prometheusStorageType)
+	defer os.Unsetenv(MetricsStorageTypeEnvVar)
+	assert.Equal(t, FactoryConfig{MetricsStorageType: prometheusStorageType}, FactoryConfigFromEnv())
+}
+
+func TestDisabledFactory(t *testing.T) {
+	f, err := NewFactory(FactoryConfig{})
+	require.NoError(t, err)
+	v, command := config.Viperize(f.AddFlags)
+	require.NoError(t, command.ParseFlags([]string{}))
+	f.InitFromViper(v)
+	require.NoError(t, f.Initialize(zap.NewNop()))
+
+	reader, err := f.CreateMetricsReader()
+	require.NoError(t, err)
+	_, err = reader.GetMinStepDuration(context.Background(), &metricsstore.MinStepDurationQueryParameters{})
+	assert.Equal(t, metricsstore.ErrDisabled, err)
+}
+
+func TestPrometheusFactory(t *testing.T) {
+	f, err := NewFactory(FactoryConfig{MetricsStorageType: prometheusStorageType})
+	require.NoError(t, err)
+	v, command := config.Viperize(f.AddFlags)
+	require.NoError(t, command.ParseFlags([]string{"--prometheus.server-url=http://prometheus:9090"}))
+	f.InitFromViper(v)
+	require.NoError(t, f.Initialize(zap.NewNop()))
+
+	reader, err := f.CreateMetricsReader()
+	require.NoError(t, err)
+	assert.IsType(t, &prometheus.MetricsReader{}, reader)
+}
+
+func TestUnknownFactory(t *testing.T) {
+	_, err := NewFactory(FactoryConfig{MetricsStorageType: "graphite"})
+	assert.EqualError(t, err, "unknown metrics storage type graphite. Valid types are [prometheus]")
+}
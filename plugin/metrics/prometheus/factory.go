@@ -0,0 +1,68 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"flag"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
+)
+
+// Factory implements storage.MetricsFactory and creates a metrics reader backed by Prometheus.
+type Factory struct {
+	options Options
+	logger  *zap.Logger
+	reader  *MetricsReader
+}
+
+// NewFactory creates a new Factory.
+func NewFactory() *Factory {
+	return &Factory{}
+}
+
+// AddFlags implements plugin.Configurable
+func (f *Factory) AddFlags(flagSet *flag.FlagSet) {
+	AddFlags(flagSet)
+}
+
+// InitFromViper implements plugin.Configurable
+func (f *Factory) InitFromViper(v *viper.Viper) {
+	f.options.InitFromViper(v)
+}
+
+// InitFromOptions initializes factory from the supplied options
+func (f *Factory) InitFromOptions(opts Options) {
+	f.options = opts
+}
+
+// Initialize implements storage.MetricsFactory
+func (f *Factory) Initialize(logger *zap.Logger) error {
+	f.logger = logger
+	reader, err := NewMetricsReader(f.options.Primary, logger)
+	if err != nil {
+		return err
+	}
+	f.reader = reader
+	logger.Info("Prometheus metrics storage initialized", zap.String("server-url", f.options.Primary.ServerURL))
+	return nil
+}
+
+// CreateMetricsReader implements storage.MetricsFactory
+func (f *Factory) CreateMetricsReader() (metricsstore.Reader, error) {
+	return f.reader, nil
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/pkg/config"
+	"github.com/jaegertracing/jaeger/storage"
+)
+
+var _ storage.MetricsFactory = new(Factory)
+
+func TestPrometheusFactory(t *testing.T) {
+	f := NewFactory()
+	v, command := config.Viperize(f.AddFlags)
+	require.NoError(t, command.ParseFlags([]string{}))
+	f.InitFromViper(v)
+	assert.Equal(t, Configuration{
+		ServerURL:      defaultServerURL,
+		ConnectTimeout: defaultConnectTimeout,
+		ServiceLabel:   defaultServiceLabel,
+		OperationLabel: defaultOperationLabel,
+		SpanKindLabel:  defaultSpanKindLabel,
+		StatusLabel:    defaultStatusLabel,
+		ErrorStatus:    defaultErrorStatus,
+	}, f.options.Primary)
+
+	require.NoError(t, f.Initialize(zap.NewNop()))
+	reader, err := f.CreateMetricsReader()
+	require.NoError(t, err)
+	assert.Equal(t, f.reader, reader)
+}
+
+func TestPrometheusFactoryFlags(t *testing.T) {
+	f := NewFactory()
+	v, command := config.Viperize(f.AddFlags)
+	require.NoError(t, command.ParseFlags([]string{
+		"--prometheus.server-url=https://prometheus:9090",
+		"--prometheus.connect-timeout=5s",
+		"--prometheus.query.metric-prefix=jaeger_collector_span_metrics_",
+		"--prometheus.query.job=jaeger-collector",
+		"--prometheus.query.service-label=service",
+		"--prometheus.query.operation-label=span_name",
+		"--prometheus.query.span-kind-label=kind",
+		"--prometheus.query.status-label=status",
+		"--prometheus.query.error-status=ERROR",
+		"--prometheus.tls.enabled=true",
+		"--prometheus.tls.server-name=prometheus.local",
+	}))
+	f.InitFromViper(v)
+	primary := f.options.Primary
+	assert.Equal(t, "https://prometheus:9090", primary.ServerURL)
+	assert.Equal(t, 5*time.Second, primary.ConnectTimeout)
+	assert.Equal(t, "jaeger_collector_span_metrics_", primary.MetricPrefix)
+	assert.Equal(t, "jaeger-collector", primary.Job)
+	assert.Equal(t, "service", primary.ServiceLabel)
+	assert.Equal(t, "span_name", primary.OperationLabel)
+	assert.Equal(t, "kind", primary.SpanKindLabel)
+	assert.Equal(t, "status", primary.StatusLabel)
+	assert.Equal(t, "ERROR", primary.ErrorStatus)
+	assert.True(t, primary.TLS.Enabled)
+	assert.Equal(t, "prometheus.local", primary.TLS.ServerName)
+}
+
+func TestPrometheusFactoryInvalidURL(t *testing.T) {
+	f := NewFactory()
+	f.InitFromOptions(Options{Primary: Configuration{ServerURL: "localhost:9090"}})
+	assert.EqualError(t, f.Initialize(zap.NewNop()), `invalid Prometheus server URL "localhost:9090", expecting an http or https URL`)
+}
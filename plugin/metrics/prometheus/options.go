@@ -0,0 +1,108 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"flag"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/jaegertracing/jaeger/pkg/config/tlscfg"
+)
+
+const (
+	prefix = "prometheus"
+
+	suffixServerURL      = ".server-url"
+	suffixConnectTimeout = ".connect-timeout"
+	suffixMetricPrefix   = ".query.metric-prefix"
+	suffixJob            = ".query.job"
+	suffixServiceLabel   = ".query.service-label"
+	suffixOperationLabel = ".query.operation-label"
+	suffixSpanKindLabel  = ".query.span-kind-label"
+	suffixStatusLabel    = ".query.status-label"
+	suffixErrorStatus    = ".query.error-status"
+
+	defaultServerURL      = "http://localhost:9090"
+	defaultConnectTimeout = 30 * time.Second
+	defaultServiceLabel   = "service_name"
+	defaultOperationLabel = "operation"
+	defaultSpanKindLabel  = "span_kind"
+	defaultStatusLabel    = "status_code"
+	defaultErrorStatus    = "STATUS_CODE_ERROR"
+)
+
+// Configuration describes the Prometheus server and how the span metrics are named in it.
+// The defaults match the metrics of the span metrics stage of the collector sent with remote-write.
+type Configuration struct {
+	ServerURL      string
+	ConnectTimeout time.Duration
+	TLS            tlscfg.Options
+
+	// MetricPrefix is prepended to the names of the calls_total and duration_seconds metrics,
+	// e.g. jaeger_collector_span_metrics_ for the metrics scraped from the collector
+	MetricPrefix string
+	// Job restricts the queries to the series of a Prometheus job, if not empty
+	Job string
+
+	ServiceLabel   string
+	OperationLabel string
+	SpanKindLabel  string
+	StatusLabel    string
+	// ErrorStatus is the value of the status label of the spans in error
+	ErrorStatus string
+}
+
+// Options stores the configuration entries for this metrics storage
+type Options struct {
+	Primary Configuration `mapstructure:",squash"`
+}
+
+func tlsFlagsConfig() tlscfg.ClientFlagsConfig {
+	return tlscfg.ClientFlagsConfig{
+		Prefix:         prefix,
+		ShowEnabled:    true,
+		ShowServerName: true,
+	}
+}
+
+// AddFlags from this storage to the CLI
+func AddFlags(flagSet *flag.FlagSet) {
+	flagSet.String(prefix+suffixServerURL, defaultServerURL, "The URL of the Prometheus compatible server to query the span metrics from")
+	flagSet.Duration(prefix+suffixConnectTimeout, defaultConnectTimeout, "The timeout of the queries to the Prometheus server")
+	flagSet.String(prefix+suffixMetricPrefix, "", "The prefix of the names of the span metrics, e.g. jaeger_collector_span_metrics_ when they are scraped from the collector")
+	flagSet.String(prefix+suffixJob, "", "The Prometheus job of the span metrics, all the jobs are queried if empty")
+	flagSet.String(prefix+suffixServiceLabel, defaultServiceLabel, "The label of the span metrics holding the service name")
+	flagSet.String(prefix+suffixOperationLabel, defaultOperationLabel, "The label of the span metrics holding the operation name")
+	flagSet.String(prefix+suffixSpanKindLabel, defaultSpanKindLabel, "The label of the span metrics holding the span kind")
+	flagSet.String(prefix+suffixStatusLabel, defaultStatusLabel, "The label of the span metrics holding the status code")
+	flagSet.String(prefix+suffixErrorStatus, defaultErrorStatus, "The value of the status label of the spans in error")
+	tlsFlagsConfig().AddFlags(flagSet)
+}
+
+// InitFromViper initializes the options struct with values from Viper
+func (opt *Options) InitFromViper(v *viper.Viper) {
+	opt.Primary.ServerURL = v.GetString(prefix + suffixServerURL)
+	opt.Primary.ConnectTimeout = v.GetDuration(prefix + suffixConnectTimeout)
+	opt.Primary.MetricPrefix = v.GetString(prefix + suffixMetricPrefix)
+	opt.Primary.Job = v.GetString(prefix + suffixJob)
+	opt.Primary.ServiceLabel = v.GetString(prefix + suffixServiceLabel)
+	opt.Primary.OperationLabel = v.GetString(prefix + suffixOperationLabel)
+	opt.Primary.SpanKindLabel = v.GetString(prefix + suffixSpanKindLabel)
+	opt.Primary.StatusLabel = v.GetString(prefix + suffixStatusLabel)
+	opt.Primary.ErrorStatus = v.GetString(prefix + suffixErrorStatus)
+	opt.Primary.TLS = tlsFlagsConfig().InitFromViper(v)
+}
@@ -0,0 +1,322 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
+)
+
+const (
+	callsMetric    = "calls_total"
+	durationMetric = "duration_seconds_bucket"
+
+	// minStep is the resolution of the timestamps of Prometheus
+	minStep = time.Millisecond
+)
+
+// MetricsReader is a metricsstore.Reader querying the span metrics in Prometheus with PromQL.
+type MetricsReader struct {
+	config   Configuration
+	queryURL string
+	client   *http.Client
+	logger   *zap.Logger
+}
+
+// queryResponse is the response of the range queries of the Prometheus HTTP API.
+type queryResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Metric map[string]string    `json:"metric"`
+			Values [][2]json.RawMessage `json:"values"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// NewMetricsReader creates a MetricsReader for the Prometheus server of the configuration.
+func NewMetricsReader(config Configuration, logger *zap.Logger) (*MetricsReader, error) {
+	serverURL, err := url.Parse(config.ServerURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Prometheus server URL: %w", err)
+	}
+	if serverURL.Scheme != "http" && serverURL.Scheme != "https" {
+		return nil, fmt.Errorf("invalid Prometheus server URL %q, expecting an http or https URL", config.ServerURL)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.TLS.Enabled {
+		tlsConfig, err := config.TLS.Config()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	serverURL.Path = strings.TrimSuffix(serverURL.Path, "/") + "/api/v1/query_range"
+	return &MetricsReader{
+		config:   config,
+		queryURL: serverURL.String(),
+		client:   &http.Client{Timeout: config.ConnectTimeout, Transport: transport},
+		logger:   logger,
+	}, nil
+}
+
+// GetLatencies implements metricsstore.Reader.
+func (r *MetricsReader) GetLatencies(ctx context.Context, params *metricsstore.LatenciesQueryParameters) (*metricsstore.MetricFamily, error) {
+	groupBy := r.groupBy(&params.BaseQueryParameters)
+	query := fmt.Sprintf("histogram_quantile(%s, sum(rate(%s[%s])) by (%s)) * 1000",
+		strconv.FormatFloat(params.Quantile, 'f', -1, 64),
+		r.selector(durationMetric, &params.BaseQueryParameters),
+		promDuration(params.RatePer),
+		strings.Join(append(groupBy, "le"), ", "))
+	return r.queryRange(ctx, query, &params.BaseQueryParameters, &metricsstore.MetricFamily{
+		Name: r.familyName(&params.BaseQueryParameters, "latencies"),
+		Help: fmt.Sprintf("%g quantile of the latency in milliseconds, %s", params.Quantile, describeGroups(&params.BaseQueryParameters)),
+	})
+}
+
+// GetCallRates implements metricsstore.Reader.
+func (r *MetricsReader) GetCallRates(ctx context.Context, params *metricsstore.CallRateQueryParameters) (*metricsstore.MetricFamily, error) {
+	query := fmt.Sprintf("sum(rate(%s[%s])) by (%s)",
+		r.selector(callsMetric, &params.BaseQueryParameters),
+		promDuration(params.RatePer),
+		strings.Join(r.groupBy(&params.BaseQueryParameters), ", "))
+	return r.queryRange(ctx, query, &params.BaseQueryParameters, &metricsstore.MetricFamily{
+		Name: r.familyName(&params.BaseQueryParameters, "call_rate"),
+		Help: fmt.Sprintf("calls per second, %s", describeGroups(&params.BaseQueryParameters)),
+	})
+}
+
+// GetErrorRates implements metricsstore.Reader. The rate is 0 rather than missing for the series
+// without errors, and missing when there are no calls.
+func (r *MetricsReader) GetErrorRates(ctx context.Context, params *metricsstore.ErrorRateQueryParameters) (*metricsstore.MetricFamily, error) {
+	groupBy := strings.Join(r.groupBy(&params.BaseQueryParameters), ", ")
+	ratePer := promDuration(params.RatePer)
+	calls := fmt.Sprintf("sum(rate(%s[%s])) by (%s)",
+		r.selector(callsMetric, &params.BaseQueryParameters), ratePer, groupBy)
+	errorCalls := fmt.Sprintf("sum(rate(%s[%s])) by (%s)",
+		r.selector(callsMetric, &params.BaseQueryParameters, matcher(r.config.StatusLabel, "=", r.config.ErrorStatus)), ratePer, groupBy)
+	query := fmt.Sprintf("(%s or %s * 0) / %s", errorCalls, calls, calls)
+	return r.queryRange(ctx, query, &params.BaseQueryParameters, &metricsstore.MetricFamily{
+		Name: r.familyName(&params.BaseQueryParameters, "error_rate"),
+		Help: fmt.Sprintf("ratio of the calls in error, %s", describeGroups(&params.BaseQueryParameters)),
+	})
+}
+
+// GetLatencyBuckets implements metricsstore.Reader. The upper bounds of the buckets are converted
+// from the seconds of the histogram to milliseconds.
+func (r *MetricsReader) GetLatencyBuckets(ctx context.Context, params *metricsstore.LatencyBucketsQueryParameters) (*metricsstore.MetricFamily, error) {
+	groupBy := r.groupBy(&params.BaseQueryParameters)
+	var extra []string
+	if params.OperationName != "" {
+		extra = append(extra, matcher(r.config.OperationLabel, "=", params.OperationName))
+	}
+	query := fmt.Sprintf("sum(increase(%s[%s])) by (%s)",
+		r.selector(durationMetric, &params.BaseQueryParameters, extra...),
+		promDuration(params.Step),
+		strings.Join(append(groupBy, "le"), ", "))
+	family, err := r.queryRange(ctx, query, &params.BaseQueryParameters, &metricsstore.MetricFamily{
+		Name: r.familyName(&params.BaseQueryParameters, "latency_buckets"),
+		Help: fmt.Sprintf("number of calls per step with a latency up to le milliseconds, %s", describeGroups(&params.BaseQueryParameters)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, metric := range family.Metrics {
+		for i, label := range metric.Labels {
+			if label.Name != "le" {
+				continue
+			}
+			seconds, err := strconv.ParseFloat(label.Value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid bucket in the Prometheus response: %w", err)
+			}
+			metric.Labels[i].Value = strconv.FormatFloat(seconds*1000, 'f', -1, 64)
+		}
+	}
+	return family, nil
+}
+
+// GetMinStepDuration implements metricsstore.Reader.
+func (r *MetricsReader) GetMinStepDuration(context.Context, *metricsstore.MinStepDurationQueryParameters) (time.Duration, error) {
+	return minStep, nil
+}
+
+func (r *MetricsReader) groupBy(params *metricsstore.BaseQueryParameters) []string {
+	if params.GroupByOperation {
+		return []string{r.config.ServiceLabel, r.config.OperationLabel}
+	}
+	return []string{r.config.ServiceLabel}
+}
+
+func (r *MetricsReader) familyName(params *metricsstore.BaseQueryParameters, name string) string {
+	if params.GroupByOperation {
+		return "service_operation_" + name
+	}
+	return "service_" + name
+}
+
+func describeGroups(params *metricsstore.BaseQueryParameters) string {
+	if params.GroupByOperation {
+		return "grouped by service and operation"
+	}
+	return "grouped by service"
+}
+
+// selector returns the PromQL selector of the series of the metric matching the parameters.
+func (r *MetricsReader) selector(metric string, params *metricsstore.BaseQueryParameters, extra ...string) string {
+	var matchers []string
+	if r.config.Job != "" {
+		matchers = append(matchers, matcher("job", "=", r.config.Job))
+	}
+	matchers = append(matchers, matcher(r.config.ServiceLabel, "=~", anyOf(params.ServiceNames)))
+	if len(params.SpanKinds) > 0 {
+		kinds := make([]string, len(params.SpanKinds))
+		for i, kind := range params.SpanKinds {
+			kinds[i] = "SPAN_KIND_" + strings.ToUpper(kind)
+		}
+		matchers = append(matchers, matcher(r.config.SpanKindLabel, "=~", anyOf(kinds)))
+	}
+	matchers = append(matchers, extra...)
+	return fmt.Sprintf("%s%s{%s}", r.config.MetricPrefix, metric, strings.Join(matchers, ", "))
+}
+
+func matcher(label, op, value string) string {
+	return label + op + strconv.Quote(value)
+}
+
+// anyOf returns a regular expression matching exactly any of the values.
+func anyOf(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = regexp.QuoteMeta(v)
+	}
+	return strings.Join(quoted, "|")
+}
+
+// promDuration formats the duration for a PromQL range selector.
+func promDuration(d time.Duration) string {
+	return strconv.FormatInt(int64(d/time.Millisecond), 10) + "ms"
+}
+
+func promTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixNano())/float64(time.Second), 'f', 3, 64)
+}
+
+func (r *MetricsReader) queryRange(
+	ctx context.Context,
+	query string,
+	params *metricsstore.BaseQueryParameters,
+	family *metricsstore.MetricFamily,
+) (*metricsstore.MetricFamily, error) {
+	if len(params.ServiceNames) == 0 {
+		return nil, fmt.Errorf("at least one service name is required")
+	}
+	form := url.Values{}
+	form.Set("query", query)
+	form.Set("start", promTime(params.EndTime.Add(-params.Lookback)))
+	form.Set("end", promTime(params.EndTime))
+	form.Set("step", strconv.FormatFloat(params.Step.Seconds(), 'f', -1, 64))
+	req, err := http.NewRequest(http.MethodPost, r.queryURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.logger.Debug("Querying Prometheus", zap.String("query", query))
+	resp, err := r.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query Prometheus: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the Prometheus response: %w", err)
+	}
+	var result queryResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("unexpected Prometheus response with status %s: %w", resp.Status, err)
+	}
+	if result.Status != "success" {
+		return nil, fmt.Errorf("failed to query Prometheus: %s: %s", result.ErrorType, result.Error)
+	}
+	if result.Data.ResultType != "matrix" {
+		return nil, fmt.Errorf("unexpected Prometheus result type %q, expecting a matrix", result.Data.ResultType)
+	}
+
+	family.Type = metricsstore.MetricTypeGauge
+	family.Metrics = make([]*metricsstore.Metric, 0, len(result.Data.Result))
+	for _, series := range result.Data.Result {
+		metric := &metricsstore.Metric{
+			Labels:       make([]metricsstore.Label, 0, len(series.Metric)),
+			MetricPoints: make([]metricsstore.MetricPoint, 0, len(series.Values)),
+		}
+		for name, value := range series.Metric {
+			if name != "__name__" {
+				metric.Labels = append(metric.Labels, metricsstore.Label{Name: name, Value: value})
+			}
+		}
+		sort.Slice(metric.Labels, func(i, j int) bool { return metric.Labels[i].Name < metric.Labels[j].Name })
+		for _, sample := range series.Values {
+			point, err := parseSample(sample)
+			if err != nil {
+				return nil, err
+			}
+			// no value can be computed without calls, e.g. for the error rate
+			if math.IsNaN(point.Value) || math.IsInf(point.Value, 0) {
+				continue
+			}
+			metric.MetricPoints = append(metric.MetricPoints, point)
+		}
+		family.Metrics = append(family.Metrics, metric)
+	}
+	return family, nil
+}
+
+// parseSample parses a [<unix time>, "<value>"] pair of the Prometheus response.
+func parseSample(sample [2]json.RawMessage) (metricsstore.MetricPoint, error) {
+	var timestamp float64
+	if err := json.Unmarshal(sample[0], &timestamp); err != nil {
+		return metricsstore.MetricPoint{}, fmt.Errorf("invalid timestamp in the Prometheus response: %w", err)
+	}
+	var value string
+	if err := json.Unmarshal(sample[1], &value); err != nil {
+		return metricsstore.MetricPoint{}, fmt.Errorf("invalid value in the Prometheus response: %w", err)
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return metricsstore.MetricPoint{}, fmt.Errorf("invalid value in the Prometheus response: %w", err)
+	}
+	seconds, fraction := math.Modf(timestamp)
+	return metricsstore.MetricPoint{
+		Timestamp: time.Unix(int64(seconds), int64(math.Round(fraction*1000))*int64(time.Millisecond)).UTC(),
+		Value:     v,
+	}, nil
+}
@@ -0,0 +1,265 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/pkg/config/tlscfg"
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
+)
+
+const matrixResponse = `{"status":"success","data":{"resultType":"matrix","result":[
+	{"metric":{"service_name":"frontend","operation":"GET /"},"values":[[1590000000,"1.5"],[1590000060.5,"NaN"],[1590000120,"2"]]}
+]}}`
+
+var testEndTime = time.Unix(1590000120, 0)
+
+func testReader(t *testing.T, response string, status int, config Configuration) (*MetricsReader, *url.Values, func()) {
+	form := &url.Values{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/prefix/api/v1/query_range", r.URL.Path)
+		require.NoError(t, r.ParseForm())
+		*form = r.PostForm
+		w.WriteHeader(status)
+		w.Write([]byte(response))
+	}))
+	config.ServerURL = server.URL + "/prefix/"
+	if config.ServiceLabel == "" {
+		config.ServiceLabel = defaultServiceLabel
+		config.OperationLabel = defaultOperationLabel
+		config.SpanKindLabel = defaultSpanKindLabel
+		config.StatusLabel = defaultStatusLabel
+		config.ErrorStatus = defaultErrorStatus
+	}
+	reader, err := NewMetricsReader(config, zap.NewNop())
+	require.NoError(t, err)
+	return reader, form, server.Close
+}
+
+func baseParams(groupByOperation bool) metricsstore.BaseQueryParameters {
+	return metricsstore.BaseQueryParameters{
+		ServiceNames:     []string{"frontend", "my.service"},
+		GroupByOperation: groupByOperation,
+		SpanKinds:        []string{"server"},
+		EndTime:          testEndTime,
+		Lookback:         2 * time.Minute,
+		Step:             time.Minute,
+		RatePer:          10 * time.Minute,
+	}
+}
+
+func TestGetLatencies(t *testing.T) {
+	reader, form, closeServer := testReader(t, matrixResponse, http.StatusOK, Configuration{})
+	defer closeServer()
+
+	family, err := reader.GetLatencies(context.Background(), &metricsstore.LatenciesQueryParameters{
+		BaseQueryParameters: baseParams(true),
+		Quantile:            0.95,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `histogram_quantile(0.95, sum(rate(duration_seconds_bucket{service_name=~"frontend|my\\.service", `+
+		`span_kind=~"SPAN_KIND_SERVER"}[600000ms])) by (service_name, operation, le)) * 1000`, form.Get("query"))
+	assert.Equal(t, "1590000000.000", form.Get("start"))
+	assert.Equal(t, "1590000120.000", form.Get("end"))
+	assert.Equal(t, "60", form.Get("step"))
+
+	assert.Equal(t, &metricsstore.MetricFamily{
+		Name: "service_operation_latencies",
+		Type: metricsstore.MetricTypeGauge,
+		Help: "0.95 quantile of the latency in milliseconds, grouped by service and operation",
+		Metrics: []*metricsstore.Metric{
+			{
+				Labels: []metricsstore.Label{
+					{Name: "operation", Value: "GET /"},
+					{Name: "service_name", Value: "frontend"},
+				},
+				MetricPoints: []metricsstore.MetricPoint{
+					{Timestamp: time.Unix(1590000000, 0).UTC(), Value: 1.5},
+					{Timestamp: time.Unix(1590000120, 0).UTC(), Value: 2},
+				},
+			},
+		},
+	}, family)
+}
+
+func TestGetCallRates(t *testing.T) {
+	reader, form, closeServer := testReader(t, matrixResponse, http.StatusOK, Configuration{
+		MetricPrefix: "jaeger_collector_span_metrics_",
+		Job:          "collector",
+	})
+	defer closeServer()
+
+	family, err := reader.GetCallRates(context.Background(), &metricsstore.CallRateQueryParameters{
+		BaseQueryParameters: baseParams(false),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `sum(rate(jaeger_collector_span_metrics_calls_total{job="collector", service_name=~"frontend|my\\.service", `+
+		`span_kind=~"SPAN_KIND_SERVER"}[600000ms])) by (service_name)`, form.Get("query"))
+	assert.Equal(t, "service_call_rate", family.Name)
+	assert.Equal(t, "calls per second, grouped by service", family.Help)
+	assert.Len(t, family.Metrics, 1)
+}
+
+func TestGetErrorRates(t *testing.T) {
+	reader, form, closeServer := testReader(t, matrixResponse, http.StatusOK, Configuration{})
+	defer closeServer()
+
+	params := baseParams(false)
+	params.SpanKinds = nil
+	family, err := reader.GetErrorRates(context.Background(), &metricsstore.ErrorRateQueryParameters{
+		BaseQueryParameters: params,
+	})
+	require.NoError(t, err)
+	calls := `sum(rate(calls_total{service_name=~"frontend|my\\.service"}[600000ms])) by (service_name)`
+	errors := `sum(rate(calls_total{service_name=~"frontend|my\\.service", status_code="STATUS_CODE_ERROR"}[600000ms])) by (service_name)`
+	assert.Equal(t, "("+errors+" or "+calls+" * 0) / "+calls, form.Get("query"))
+	assert.Equal(t, "service_error_rate", family.Name)
+}
+
+func TestGetLatencyBuckets(t *testing.T) {
+	response := `{"status":"success","data":{"resultType":"matrix","result":[
+		{"metric":{"service_name":"frontend","le":"0.25"},"values":[[1590000120,"3"]]},
+		{"metric":{"service_name":"frontend","le":"+Inf"},"values":[[1590000120,"5"]]}
+	]}}`
+	reader, form, closeServer := testReader(t, response, http.StatusOK, Configuration{})
+	defer closeServer()
+
+	family, err := reader.GetLatencyBuckets(context.Background(), &metricsstore.LatencyBucketsQueryParameters{
+		BaseQueryParameters: baseParams(false),
+		OperationName:       "GET /",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `sum(increase(duration_seconds_bucket{service_name=~"frontend|my\\.service", `+
+		`span_kind=~"SPAN_KIND_SERVER", operation="GET /"}[60000ms])) by (service_name, le)`, form.Get("query"))
+	assert.Equal(t, "service_latency_buckets", family.Name)
+	require.Len(t, family.Metrics, 2)
+	assert.Equal(t, []metricsstore.Label{
+		{Name: "le", Value: "250"},
+		{Name: "service_name", Value: "frontend"},
+	}, family.Metrics[0].Labels)
+	assert.Equal(t, "+Inf", family.Metrics[1].Labels[0].Value)
+
+	reader, _, closeServer = testReader(t, `{"status":"success","data":{"resultType":"matrix","result":[
+		{"metric":{"le":"x"},"values":[]}
+	]}}`, http.StatusOK, Configuration{})
+	defer closeServer()
+	_, err = reader.GetLatencyBuckets(context.Background(), &metricsstore.LatencyBucketsQueryParameters{
+		BaseQueryParameters: baseParams(false),
+	})
+	assert.EqualError(t, err, `invalid bucket in the Prometheus response: strconv.ParseFloat: parsing "x": invalid syntax`)
+}
+
+func TestGetMinStepDuration(t *testing.T) {
+	reader, _, closeServer := testReader(t, matrixResponse, http.StatusOK, Configuration{})
+	defer closeServer()
+
+	step, err := reader.GetMinStepDuration(context.Background(), &metricsstore.MinStepDurationQueryParameters{})
+	require.NoError(t, err)
+	assert.Equal(t, time.Millisecond, step)
+}
+
+func TestQueryErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		response string
+		status   int
+		services []string
+		err      string
+	}{
+		{
+			name:     "no service",
+			response: matrixResponse,
+			status:   http.StatusOK,
+			err:      "at least one service name is required",
+		},
+		{
+			name:     "query error",
+			response: `{"status":"error","errorType":"bad_data","error":"parse error"}`,
+			status:   http.StatusBadRequest,
+			services: []string{"frontend"},
+			err:      "failed to query Prometheus: bad_data: parse error",
+		},
+		{
+			name:     "not JSON",
+			response: "unavailable",
+			status:   http.StatusBadGateway,
+			services: []string{"frontend"},
+			err:      "unexpected Prometheus response with status 502 Bad Gateway: invalid character 'u' looking for beginning of value",
+		},
+		{
+			name:     "not a matrix",
+			response: `{"status":"success","data":{"resultType":"vector","result":[]}}`,
+			status:   http.StatusOK,
+			services: []string{"frontend"},
+			err:      `unexpected Prometheus result type "vector", expecting a matrix`,
+		},
+		{
+			name:     "invalid timestamp",
+			response: `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{},"values":[["x","1"]]}]}}`,
+			status:   http.StatusOK,
+			services: []string{"frontend"},
+			err:      "invalid timestamp in the Prometheus response: json: cannot unmarshal string into Go value of type float64",
+		},
+		{
+			name:     "invalid value",
+			response: `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{},"values":[[1,"x"]]}]}}`,
+			status:   http.StatusOK,
+			services: []string{"frontend"},
+			err:      `invalid value in the Prometheus response: strconv.ParseFloat: parsing "x": invalid syntax`,
+		},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			reader, _, closeServer := testReader(t, test.response, test.status, Configuration{})
+			defer closeServer()
+
+			params := baseParams(false)
+			params.ServiceNames = test.services
+			_, err := reader.GetCallRates(context.Background(), &metricsstore.CallRateQueryParameters{BaseQueryParameters: params})
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestQueryUnreachableServer(t *testing.T) {
+	reader, _, closeServer := testReader(t, matrixResponse, http.StatusOK, Configuration{})
+	closeServer()
+
+	_, err := reader.GetCallRates(context.Background(), &metricsstore.CallRateQueryParameters{BaseQueryParameters: baseParams(false)})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to query Prometheus")
+}
+
+func TestNewMetricsReaderErrors(t *testing.T) {
+	_, err := NewMetricsReader(Configuration{ServerURL: ":"}, zap.NewNop())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid Prometheus server URL")
+
+	_, err = NewMetricsReader(Configuration{
+		ServerURL: "https://localhost:9090",
+		TLS:       tlscfg.Options{Enabled: true, CAPath: "invalid/path"},
+	}, zap.NewNop())
+	assert.Error(t, err)
+}
@@ -25,6 +25,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/spf13/viper"
 	"go.uber.org/zap"
 
 	ss "github.com/jaegertracing/jaeger/cmd/collector/app/sampling/strategystore"
@@ -35,6 +36,7 @@ type strategyStore struct {
 	logger *zap.Logger
 
 	storedStrategies atomic.Value // holds *storedStrategies
+	strategiesFile   atomic.Value // holds string
 
 	ctx        context.Context
 	cancelFunc context.CancelFunc
@@ -54,6 +56,7 @@ func NewStrategyStore(options Options, logger *zap.Logger) (ss.StrategyStore, er
 		cancelFunc: cancelFunc,
 	}
 	h.storedStrategies.Store(defaultStrategies())
+	h.strategiesFile.Store(options.StrategiesFile)
 
 	strategies, err := loadStrategies(options.StrategiesFile)
 	if err != nil {
@@ -62,7 +65,7 @@ func NewStrategyStore(options Options, logger *zap.Logger) (ss.StrategyStore, er
 	h.parseStrategies(strategies)
 
 	if options.ReloadInterval > 0 {
-		go h.autoUpdateStrategies(options.ReloadInterval, options.StrategiesFile)
+		go h.autoUpdateStrategies(options.ReloadInterval)
 	}
 	return h, nil
 }
@@ -83,13 +86,36 @@ func (h *strategyStore) Close() {
 	h.cancelFunc()
 }
 
-func (h *strategyStore) autoUpdateStrategies(interval time.Duration, filePath string) {
+// Reload switches to the strategies file currently configured in viper, e.g. after the
+// configuration file was changed. The defaults are used if the file is no longer set.
+func (h *strategyStore) Reload(v *viper.Viper) error {
+	options := new(Options).InitFromViper(v)
+	strategies, err := loadStrategies(options.StrategiesFile)
+	if err != nil {
+		return err
+	}
+	h.strategiesFile.Store(options.StrategiesFile)
+	if strategies == nil {
+		h.storedStrategies.Store(defaultStrategies())
+		h.logger.Info("No sampling strategies provided, using defaults")
+		return nil
+	}
+	h.parseStrategies(strategies)
+	h.logger.Info("Reloaded sampling strategies", zap.String("file", options.StrategiesFile))
+	return nil
+}
+
+func (h *strategyStore) autoUpdateStrategies(interval time.Duration) {
 	lastValue := ""
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
+			filePath := h.strategiesFile.Load().(string)
+			if filePath == "" {
+				continue
+			}
 			lastValue = h.reloadSamplingStrategyFile(filePath, lastValue)
 		case <-h.ctx.Done():
 			return
@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest/observer"
@@ -323,6 +324,39 @@ func TestAutoUpdateStrategyErrors(t *testing.T) {
 	assert.Len(t, logs.FilterMessage("failed to update sampling strategies from file").All(), 1)
 }
 
+func TestReload(t *testing.T) {
+	ss, err := NewStrategyStore(Options{StrategiesFile: "fixtures/strategies.json"}, zap.NewNop())
+	require.NoError(t, err)
+	store := ss.(*strategyStore)
+	defer store.Close()
+
+	s, err := store.GetSamplingStrategy(context.Background(), "foo")
+	require.NoError(t, err)
+	assert.EqualValues(t, makeResponse(sampling.SamplingStrategyType_PROBABILISTIC, 0.8), *s)
+
+	v := viper.New()
+	v.Set(SamplingStrategiesFile, "fileNotFound.json")
+	assert.EqualError(t, store.Reload(v), "failed to open strategies file: open fileNotFound.json: no such file or directory")
+	s, err = store.GetSamplingStrategy(context.Background(), "foo")
+	require.NoError(t, err)
+	assert.EqualValues(t, makeResponse(sampling.SamplingStrategyType_PROBABILISTIC, 0.8), *s)
+	assert.Equal(t, "fixtures/strategies.json", store.strategiesFile.Load())
+
+	v.Set(SamplingStrategiesFile, "fixtures/service_no_per_operation.json")
+	require.NoError(t, store.Reload(v))
+	assert.Equal(t, "fixtures/service_no_per_operation.json", store.strategiesFile.Load())
+	s, err = store.GetSamplingStrategy(context.Background(), "foo")
+	require.NoError(t, err)
+	assert.Equal(t, store.storedStrategies.Load().(*storedStrategies).defaultStrategy, s)
+	assert.NotNil(t, s.OperationSampling)
+
+	v.Set(SamplingStrategiesFile, "")
+	require.NoError(t, store.Reload(v))
+	s, err = store.GetSamplingStrategy(context.Background(), "foo")
+	require.NoError(t, err)
+	assert.EqualValues(t, defaultStrategyResponse(), s)
+}
+
 func TestServiceNoPerOperationStrategies(t *testing.T) {
 	store, err := NewStrategyStore(Options{StrategiesFile: "fixtures/service_no_per_operation.json"}, zap.NewNop())
 	require.NoError(t, err)
@@ -0,0 +1,82 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dependencystore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/bigtable"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+// ColumnFamily is the column family of the cells of the dependencies table.
+const ColumnFamily = "d"
+
+const linksColumn = "links"
+
+// DependencyStore handles all queries and insertions to Bigtable dependencies
+type DependencyStore struct {
+	table *bigtable.Table
+}
+
+// NewDependencyStore returns a DependencyStore
+func NewDependencyStore(client *bigtable.Client, table string) *DependencyStore {
+	return &DependencyStore{table: client.Open(table)}
+}
+
+// rowKey is the timestamp of the dependencies in microseconds on 16 hexadecimal digits, the keys sort as the timestamps.
+func rowKey(ts time.Time) string {
+	return fmt.Sprintf("%016x", ts.UnixNano()/int64(time.Microsecond))
+}
+
+// WriteDependencies implements dependencystore.Writer#WriteDependencies.
+func (s *DependencyStore) WriteDependencies(ts time.Time, dependencies []model.DependencyLink) error {
+	data, err := json.Marshal(dependencies)
+	if err != nil {
+		return err
+	}
+	mutation := bigtable.NewMutation()
+	mutation.Set(ColumnFamily, linksColumn, bigtable.Time(ts).TruncateToMilliseconds(), data)
+	return s.table.Apply(context.Background(), rowKey(ts), mutation)
+}
+
+// GetDependencies returns all interservice dependencies
+func (s *DependencyStore) GetDependencies(endTs time.Time, lookback time.Duration) ([]model.DependencyLink, error) {
+	var dependencies []model.DependencyLink
+	var decodeErr error
+	rowRange := bigtable.NewRange(rowKey(endTs.Add(-lookback)), rowKey(endTs.Add(time.Microsecond)))
+	err := s.table.ReadRows(context.Background(), rowRange, func(row bigtable.Row) bool {
+		for _, item := range row[ColumnFamily] {
+			var links []model.DependencyLink
+			if decodeErr = json.Unmarshal(item.Value, &links); decodeErr != nil {
+				decodeErr = fmt.Errorf("cannot decode the dependencies: %w", decodeErr)
+				return false
+			}
+			dependencies = append(dependencies, links...)
+		}
+		return true
+	}, bigtable.RowFilter(bigtable.ChainFilters(bigtable.ColumnFilter(linksColumn), bigtable.LatestNFilter(1))))
+	if err != nil {
+		return nil, err
+	}
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+	return dependencies, nil
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dependencystore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigtable"
+	"cloud.google.com/go/bigtable/bttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+func withEmulator(t *testing.T, test func(client *bigtable.Client)) {
+	srv, err := bttest.NewServer("localhost:0")
+	require.NoError(t, err)
+	defer srv.Close()
+	conn, err := grpc.Dial(srv.Addr, grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx := context.Background()
+	admin, err := bigtable.NewAdminClient(ctx, "project", "instance", option.WithGRPCConn(conn))
+	require.NoError(t, err)
+	require.NoError(t, admin.CreateTableFromConf(ctx, &bigtable.TableConf{
+		TableID:  "dependencies",
+		Families: map[string]bigtable.GCPolicy{ColumnFamily: bigtable.MaxVersionsPolicy(1)},
+	}))
+	client, err := bigtable.NewClient(ctx, "project", "instance", option.WithGRPCConn(conn))
+	require.NoError(t, err)
+	test(client)
+}
+
+func TestDependencies(t *testing.T) {
+	withEmulator(t, func(client *bigtable.Client) {
+		store := NewDependencyStore(client, "dependencies")
+		links := func(parent string) []model.DependencyLink {
+			return []model.DependencyLink{{Parent: parent, Child: "db", CallCount: 2}}
+		}
+		require.NoError(t, store.WriteDependencies(time.Unix(100, 0), links("a")))
+		require.NoError(t, store.WriteDependencies(time.Unix(200, 0), links("b")))
+		require.NoError(t, store.WriteDependencies(time.Unix(300, 0), links("c")))
+
+		dependencies, err := store.GetDependencies(time.Unix(200, 0), 100*time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, append(links("a"), links("b")...), dependencies)
+
+		dependencies, err = store.GetDependencies(time.Unix(500, 0), 100*time.Second)
+		require.NoError(t, err)
+		assert.Empty(t, dependencies)
+	})
+}
+
+func TestDependenciesErrors(t *testing.T) {
+	withEmulator(t, func(client *bigtable.Client) {
+		store := NewDependencyStore(client, "missing")
+		assert.Error(t, store.WriteDependencies(time.Unix(100, 0), nil))
+		_, err := store.GetDependencies(time.Unix(100, 0), time.Second)
+		assert.Error(t, err)
+
+		mutation := bigtable.NewMutation()
+		mutation.Set(ColumnFamily, linksColumn, bigtable.Now(), []byte("invalid"))
+		require.NoError(t, client.Open("dependencies").Apply(context.Background(), rowKey(time.Unix(100, 0)), mutation))
+		store = NewDependencyStore(client, "dependencies")
+		_, err = store.GetDependencies(time.Unix(100, 0), time.Second)
+		assert.Error(t, err)
+	})
+}
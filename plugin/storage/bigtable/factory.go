@@ -0,0 +1,135 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigtable
+
+import (
+	"context"
+	"errors"
+	"flag"
+
+	"cloud.google.com/go/bigtable"
+	"github.com/spf13/viper"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+	"google.golang.org/api/option"
+
+	btDepStore "github.com/jaegertracing/jaeger/plugin/storage/bigtable/dependencystore"
+	btSpanStore "github.com/jaegertracing/jaeger/plugin/storage/bigtable/spanstore"
+	"github.com/jaegertracing/jaeger/storage/dependencystore"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+// Factory implements storage.Factory for Bigtable backend.
+type Factory struct {
+	Options *Options
+
+	metricsFactory metrics.Factory
+	logger         *zap.Logger
+	client         *bigtable.Client
+}
+
+// NewFactory creates a new Factory.
+func NewFactory() *Factory {
+	return &Factory{
+		Options: NewOptions(),
+	}
+}
+
+// AddFlags implements plugin.Configurable
+func (f *Factory) AddFlags(flagSet *flag.FlagSet) {
+	f.Options.AddFlags(flagSet)
+}
+
+// InitFromViper implements plugin.Configurable
+func (f *Factory) InitFromViper(v *viper.Viper) {
+	f.Options.InitFromViper(v)
+}
+
+// Initialize implements storage.Factory
+func (f *Factory) Initialize(metricsFactory metrics.Factory, logger *zap.Logger) error {
+	f.metricsFactory, f.logger = metricsFactory, logger
+	if f.Options.Project == "" || f.Options.Instance == "" {
+		return errors.New("the Bigtable project and instance must be set")
+	}
+	ctx := context.Background()
+	var opts []option.ClientOption
+	if f.Options.CredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(f.Options.CredentialsFile))
+	}
+	if f.Options.CreateTables {
+		admin, err := bigtable.NewAdminClient(ctx, f.Options.Project, f.Options.Instance, opts...)
+		if err != nil {
+			return err
+		}
+		defer admin.Close()
+		families := map[string]string{
+			f.spansTable():        btSpanStore.ColumnFamily,
+			f.indexTable():        btSpanStore.ColumnFamily,
+			f.operationsTable():   btSpanStore.ColumnFamily,
+			f.dependenciesTable(): btDepStore.ColumnFamily,
+		}
+		if err := createTables(ctx, admin, families, f.Options.TTL); err != nil {
+			return err
+		}
+		logger.Info("Bigtable tables created", zap.String("prefix", f.Options.TablePrefix))
+	}
+	client, err := bigtable.NewClient(ctx, f.Options.Project, f.Options.Instance, opts...)
+	if err != nil {
+		return err
+	}
+	f.client = client
+	return nil
+}
+
+func (f *Factory) spansTable() string        { return f.Options.TablePrefix + "spans" }
+func (f *Factory) indexTable() string        { return f.Options.TablePrefix + "index" }
+func (f *Factory) operationsTable() string   { return f.Options.TablePrefix + "operations" }
+func (f *Factory) dependenciesTable() string { return f.Options.TablePrefix + "dependencies" }
+
+func (f *Factory) tables() btSpanStore.Tables {
+	return btSpanStore.Tables{
+		Spans:      f.spansTable(),
+		Index:      f.indexTable(),
+		Operations: f.operationsTable(),
+	}
+}
+
+// CreateSpanReader implements storage.Factory
+func (f *Factory) CreateSpanReader() (spanstore.Reader, error) {
+	return btSpanStore.NewSpanReader(f.client, f.tables()), nil
+}
+
+// CreateSpanWriter implements storage.Factory
+func (f *Factory) CreateSpanWriter() (spanstore.Writer, error) {
+	return btSpanStore.NewSpanWriter(btSpanStore.SpanWriterParams{
+		Client:         f.client,
+		Tables:         f.tables(),
+		MetricsFactory: f.metricsFactory,
+		TTL:            f.Options.TTL,
+	}), nil
+}
+
+// CreateDependencyReader implements storage.Factory
+func (f *Factory) CreateDependencyReader() (dependencystore.Reader, error) {
+	return btDepStore.NewDependencyStore(f.client, f.dependenciesTable()), nil
+}
+
+// Close implements io.Closer and closes the connections to Bigtable.
+func (f *Factory) Close() error {
+	if f.client == nil {
+		return nil
+	}
+	return f.client.Close()
+}
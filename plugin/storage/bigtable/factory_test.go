@@ -0,0 +1,110 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigtable
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigtable"
+	"cloud.google.com/go/bigtable/bttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/config"
+	"github.com/jaegertracing/jaeger/storage"
+)
+
+var _ storage.Factory = new(Factory)
+
+// withEmulator runs the test with the clients connecting to an in-memory Bigtable server.
+func withEmulator(t *testing.T, test func()) {
+	srv, err := bttest.NewServer("localhost:0")
+	require.NoError(t, err)
+	defer srv.Close()
+	os.Setenv("BIGTABLE_EMULATOR_HOST", srv.Addr)
+	defer os.Unsetenv("BIGTABLE_EMULATOR_HOST")
+	test()
+}
+
+func newTestFactory(flags ...string) *Factory {
+	f := NewFactory()
+	v, command := config.Viperize(f.AddFlags)
+	command.ParseFlags(append([]string{"--bigtable.project=project", "--bigtable.instance=instance"}, flags...))
+	f.InitFromViper(v)
+	return f
+}
+
+func TestFactory(t *testing.T) {
+	withEmulator(t, func() {
+		f := newTestFactory()
+		require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+		defer f.Close()
+
+		admin, err := bigtable.NewAdminClient(context.Background(), "project", "instance")
+		require.NoError(t, err)
+		defer admin.Close()
+		tables, err := admin.Tables(context.Background())
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"jaeger_spans", "jaeger_index", "jaeger_operations", "jaeger_dependencies"}, tables)
+		info, err := admin.TableInfo(context.Background(), "jaeger_spans")
+		require.NoError(t, err)
+		assert.Equal(t, []bigtable.FamilyInfo{{Name: "d", GCPolicy: "(versions() > 1 || age() > 3d)"}}, info.FamilyInfos)
+
+		// the tables are created again only if missing
+		require.NoError(t, newTestFactory().Initialize(metrics.NullFactory, zap.NewNop()))
+
+		writer, err := f.CreateSpanWriter()
+		require.NoError(t, err)
+		require.NoError(t, writer.WriteSpan(&model.Span{TraceID: model.NewTraceID(0, 1), SpanID: 1, StartTime: time.Now()}))
+		reader, err := f.CreateSpanReader()
+		require.NoError(t, err)
+		trace, err := reader.GetTrace(context.Background(), model.NewTraceID(0, 1))
+		require.NoError(t, err)
+		assert.Len(t, trace.Spans, 1)
+		dependencyReader, err := f.CreateDependencyReader()
+		require.NoError(t, err)
+		assert.NotNil(t, dependencyReader)
+	})
+}
+
+func TestFactoryWithoutTablesCreation(t *testing.T) {
+	withEmulator(t, func() {
+		f := newTestFactory("--bigtable.create-tables=false", "--bigtable.ttl=0")
+		require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+		defer f.Close()
+
+		admin, err := bigtable.NewAdminClient(context.Background(), "project", "instance")
+		require.NoError(t, err)
+		defer admin.Close()
+		tables, err := admin.Tables(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, tables)
+	})
+}
+
+func TestFactoryInitializeErrors(t *testing.T) {
+	f := NewFactory()
+	assert.EqualError(t, f.Initialize(metrics.NullFactory, zap.NewNop()), "the Bigtable project and instance must be set")
+	assert.NoError(t, f.Close())
+
+	f = newTestFactory("--bigtable.credentials-file=/does/not/exist.json")
+	assert.Error(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigtable
+
+import (
+	"flag"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	namespace = "bigtable"
+
+	suffixProject         = ".project"
+	suffixInstance        = ".instance"
+	suffixCredentialsFile = ".credentials-file"
+	suffixTablePrefix     = ".table-prefix"
+	suffixCreateTables    = ".create-tables"
+	suffixTTL             = ".ttl"
+
+	defaultTablePrefix = "jaeger_"
+	defaultTTL         = 72 * time.Hour
+)
+
+// Options contains the Bigtable configs and provides the ability
+// to bind them to command line flags
+type Options struct {
+	Project  string `mapstructure:"project"`
+	Instance string `mapstructure:"instance"`
+	// CredentialsFile is the JSON file of the credentials of a service account, the application default credentials
+	// are used if it is empty.
+	CredentialsFile string `mapstructure:"credentials_file"`
+	// TablePrefix prefixes the names of the spans, index, operations and dependencies tables.
+	TablePrefix  string        `mapstructure:"table_prefix"`
+	CreateTables bool          `mapstructure:"create_tables"`
+	TTL          time.Duration `mapstructure:"ttl"`
+}
+
+// NewOptions creates the Options with the default configuration.
+func NewOptions() *Options {
+	return &Options{
+		TablePrefix:  defaultTablePrefix,
+		CreateTables: true,
+		TTL:          defaultTTL,
+	}
+}
+
+// AddFlags adds flags for Options
+func (opt *Options) AddFlags(flagSet *flag.FlagSet) {
+	flagSet.String(namespace+suffixProject, opt.Project, "The Google Cloud project of the Bigtable instance")
+	flagSet.String(namespace+suffixInstance, opt.Instance, "The Bigtable instance")
+	flagSet.String(namespace+suffixCredentialsFile, opt.CredentialsFile, "The JSON file of the credentials of a service account, by default the application default credentials; the BIGTABLE_EMULATOR_HOST environment variable connects to the emulator instead")
+	flagSet.String(namespace+suffixTablePrefix, opt.TablePrefix, "The prefix of the names of the tables")
+	flagSet.Bool(namespace+suffixCreateTables, opt.CreateTables, "Create the tables which do not exist on startup")
+	flagSet.Duration(namespace+suffixTTL, opt.TTL, "The age of the spans and the dependencies collected by Bigtable, 0 keeps them forever")
+}
+
+// InitFromViper initializes Options with properties from viper
+func (opt *Options) InitFromViper(v *viper.Viper) {
+	opt.Project = v.GetString(namespace + suffixProject)
+	opt.Instance = v.GetString(namespace + suffixInstance)
+	opt.CredentialsFile = v.GetString(namespace + suffixCredentialsFile)
+	opt.TablePrefix = v.GetString(namespace + suffixTablePrefix)
+	opt.CreateTables = v.GetBool(namespace + suffixCreateTables)
+	opt.TTL = v.GetDuration(namespace + suffixTTL)
+}
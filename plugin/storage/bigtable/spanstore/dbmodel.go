@@ -0,0 +1,157 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigtable"
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+// ColumnFamily is the column family of the cells of all the tables.
+const ColumnFamily = "d"
+
+const (
+	// separator separates the fields of the row keys, it does not occur in the names of the services,
+	// the operations and the tags in practice.
+	separator = "\x00"
+
+	durationColumn  = "duration"
+	operationColumn = "operation"
+
+	traceIDLength = 32
+)
+
+// The rows of the index table are keyed by the indexed value first, then by the reversed start time of the span
+// for the most recent spans to be read first, then by the trace ID of the span:
+//
+//	s <service> <reversed start time> <trace ID>
+//	o <service> <operation> <reversed start time> <trace ID>
+//	t <service> <tag key> <tag value> <reversed start time> <trace ID>
+//
+// The rows of the spans table are keyed by the trace ID, a trace is then read from a single row.
+// The rows of the operations table are keyed by <service> <span kind> <operation>.
+
+// traceIDString returns the trace ID always on 32 hexadecimal digits, unlike TraceID.String.
+func traceIDString(traceID model.TraceID) string {
+	return fmt.Sprintf("%016x%016x", traceID.High, traceID.Low)
+}
+
+func serviceIndexPrefix(service string) string {
+	return "s" + separator + service + separator
+}
+
+func operationIndexPrefix(service, operation string) string {
+	return "o" + separator + service + separator + operation + separator
+}
+
+func tagIndexPrefix(service, key, value string) string {
+	return "t" + separator + service + separator + key + separator + value + separator
+}
+
+// timeKey is the start time reversed on 16 hexadecimal digits, the keys of the most recent spans sort first.
+func timeKey(t time.Time) string {
+	return fmt.Sprintf("%016x", uint64(math.MaxInt64)-uint64(t.UnixNano()/int64(time.Microsecond)))
+}
+
+func indexRowKey(prefix string, startTime time.Time, traceID model.TraceID) string {
+	return prefix + timeKey(startTime) + traceIDString(traceID)
+}
+
+// indexRange is the range of the rows of the index prefix with a start time between startTimeMin and startTimeMax.
+func indexRange(prefix string, startTimeMin, startTimeMax time.Time) bigtable.RowRange {
+	return bigtable.NewRange(prefix+timeKey(startTimeMax), prefix+timeKey(startTimeMin.Add(-time.Microsecond)))
+}
+
+func traceIDFromIndexRowKey(key string) (model.TraceID, error) {
+	if len(key) < traceIDLength {
+		return model.TraceID{}, fmt.Errorf("invalid row key of the index %q", key)
+	}
+	return model.TraceIDFromString(key[len(key)-traceIDLength:])
+}
+
+func operationRowKey(service, spanKind, operation string) string {
+	return service + separator + spanKind + separator + operation
+}
+
+func parseOperationRowKey(key string) (service, spanKind, operation string, err error) {
+	fields := strings.SplitN(key, separator, 3)
+	if len(fields) != 3 {
+		return "", "", "", fmt.Errorf("invalid row key of the operations %q", key)
+	}
+	return fields[0], fields[1], fields[2], nil
+}
+
+// encodeDuration encodes the duration in microseconds in big-endian, for the values to sort as the durations.
+func encodeDuration(d time.Duration) []byte {
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, uint64(d/time.Microsecond))
+	return value
+}
+
+// timestamp is the timestamp of the cells of the span: the garbage collection of the cells expires the spans
+// after the TTL since they started.
+func timestamp(span *model.Span) bigtable.Timestamp {
+	return bigtable.Time(span.StartTime).TruncateToMilliseconds()
+}
+
+// spanColumn returns the column of the span in the row of its trace. The spans sharing their ID, e.g. the client
+// and the server spans of Zipkin, are told apart by the hash of their data.
+func spanColumn(spanID model.SpanID, data []byte) string {
+	hash := fnv.New64a()
+	hash.Write(data)
+	return fmt.Sprintf("%s-%016x", spanID, hash.Sum64())
+}
+
+// indexPrefixes returns the prefixes of the index rows of the span, none for the spans without service.
+func indexPrefixes(span *model.Span) []string {
+	if span.Process == nil || span.Process.ServiceName == "" {
+		return nil
+	}
+	service := span.Process.ServiceName
+	prefixes := []string{serviceIndexPrefix(service), operationIndexPrefix(service, span.OperationName)}
+	seen := make(map[string]struct{})
+	addTags := func(kvs model.KeyValues) {
+		for _, kv := range kvs {
+			prefix := tagIndexPrefix(service, kv.Key, kv.AsString())
+			if _, ok := seen[prefix]; !ok {
+				seen[prefix] = struct{}{}
+				prefixes = append(prefixes, prefix)
+			}
+		}
+	}
+	addTags(span.Tags)
+	addTags(span.Process.Tags)
+	for _, log := range span.Logs {
+		addTags(log.Fields)
+	}
+	return prefixes
+}
+
+func toDomain(data []byte) (*model.Span, error) {
+	span := &model.Span{}
+	if err := proto.Unmarshal(data, span); err != nil {
+		return nil, fmt.Errorf("cannot decode the span: %w", err)
+	}
+	return span, nil
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+func TestIndexRowKey(t *testing.T) {
+	traceID := model.NewTraceID(1, 2)
+	start := time.Unix(100, 0)
+	key := indexRowKey(serviceIndexPrefix("svc"), start, traceID)
+	assert.Equal(t, "s\x00svc\x00"+"7ffffffffa0a1eff"+"00000000000000010000000000000002", key)
+
+	parsed, err := traceIDFromIndexRowKey(key)
+	require.NoError(t, err)
+	assert.Equal(t, traceID, parsed)
+	_, err = traceIDFromIndexRowKey("short")
+	assert.EqualError(t, err, `invalid row key of the index "short"`)
+
+	// the most recent spans sort first
+	assert.True(t, timeKey(start.Add(time.Microsecond)) < timeKey(start))
+}
+
+func TestIndexRange(t *testing.T) {
+	prefix := serviceIndexPrefix("svc")
+	min, max := time.Unix(100, 0), time.Unix(200, 0)
+	rowRange := indexRange(prefix, min, max)
+	traceID := model.NewTraceID(0, 1)
+	assert.True(t, rowRange.Contains(indexRowKey(prefix, min, traceID)))
+	assert.True(t, rowRange.Contains(indexRowKey(prefix, max, traceID)))
+	assert.False(t, rowRange.Contains(indexRowKey(prefix, min.Add(-time.Microsecond), traceID)))
+	assert.False(t, rowRange.Contains(indexRowKey(prefix, max.Add(time.Microsecond), traceID)))
+	assert.False(t, rowRange.Contains(indexRowKey(serviceIndexPrefix("svc2"), min, traceID)))
+}
+
+func TestOperationRowKey(t *testing.T) {
+	service, spanKind, operation, err := parseOperationRowKey(operationRowKey("svc", "server", "GET /a"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"svc", "server", "GET /a"}, []string{service, spanKind, operation})
+
+	_, _, _, err = parseOperationRowKey("svc")
+	assert.EqualError(t, err, `invalid row key of the operations "svc"`)
+}
+
+func TestIndexPrefixes(t *testing.T) {
+	assert.Nil(t, indexPrefixes(&model.Span{OperationName: "op"}))
+
+	span := &model.Span{
+		OperationName: "op",
+		Process:       model.NewProcess("svc", []model.KeyValue{model.String("hostname", "h1")}),
+		Tags:          model.KeyValues{model.String("error", "true"), model.Int64("status", 200)},
+		Logs: []model.Log{
+			{Fields: model.KeyValues{model.String("error", "true")}},
+		},
+	}
+	assert.Equal(t, []string{
+		serviceIndexPrefix("svc"),
+		operationIndexPrefix("svc", "op"),
+		tagIndexPrefix("svc", "error", "true"),
+		tagIndexPrefix("svc", "status", "200"),
+		tagIndexPrefix("svc", "hostname", "h1"),
+	}, indexPrefixes(span))
+}
+
+func TestEncodeDuration(t *testing.T) {
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 1, 0}, encodeDuration(256*time.Microsecond))
+	assert.True(t, string(encodeDuration(time.Second)) < string(encodeDuration(time.Minute)))
+}
+
+func TestToDomainError(t *testing.T) {
+	_, err := toDomain([]byte("invalid"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/bigtable"
+	"cloud.google.com/go/bigtable/bttest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+)
+
+var testTables = Tables{Spans: "spans", Index: "index", Operations: "operations"}
+
+// withEmulator runs the test with a client of an in-memory Bigtable server with the test tables.
+func withEmulator(t *testing.T, test func(client *bigtable.Client)) {
+	srv, err := bttest.NewServer("localhost:0")
+	require.NoError(t, err)
+	defer srv.Close()
+	conn, err := grpc.Dial(srv.Addr, grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx := context.Background()
+	admin, err := bigtable.NewAdminClient(ctx, "project", "instance", option.WithGRPCConn(conn))
+	require.NoError(t, err)
+	for _, table := range []string{testTables.Spans, testTables.Index, testTables.Operations} {
+		require.NoError(t, admin.CreateTableFromConf(ctx, &bigtable.TableConf{
+			TableID:  table,
+			Families: map[string]bigtable.GCPolicy{ColumnFamily: bigtable.MaxVersionsPolicy(1)},
+		}))
+	}
+	client, err := bigtable.NewClient(ctx, "project", "instance", option.WithGRPCConn(conn))
+	require.NoError(t, err)
+	test(client)
+}
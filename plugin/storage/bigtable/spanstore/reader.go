@@ -0,0 +1,317 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/bigtable"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+const (
+	defaultNumTraces = 100
+	// limitMultiple is the factor of the number of traces read from each index whose traces are intersected
+	limitMultiple = 3
+)
+
+var (
+	// ErrServiceNameNotSet occurs when attempting to query with an empty service name
+	ErrServiceNameNotSet = errors.New("service Name must be set")
+
+	// ErrMalformedRequestObject occurs when a request object is nil
+	ErrMalformedRequestObject = errors.New("malformed request object")
+
+	// ErrStartAndEndTimeNotSet occurs when start time and end time are not set
+	ErrStartAndEndTimeNotSet = errors.New("start and End Time must be set")
+
+	// ErrStartTimeMinGreaterThanMax occurs when start time min is above start time max
+	ErrStartTimeMinGreaterThanMax = errors.New("start Time Minimum is above Maximum")
+
+	// ErrDurationMinGreaterThanMax occurs when duration min is above duration max
+	ErrDurationMinGreaterThanMax = errors.New("duration Minimum is above Maximum")
+)
+
+// SpanReader can query for and load traces from Bigtable.
+type SpanReader struct {
+	spans      *bigtable.Table
+	index      *bigtable.Table
+	operations *bigtable.Table
+}
+
+// NewSpanReader returns a new SpanReader.
+func NewSpanReader(client *bigtable.Client, tables Tables) *SpanReader {
+	return &SpanReader{
+		spans:      client.Open(tables.Spans),
+		index:      client.Open(tables.Index),
+		operations: client.Open(tables.Operations),
+	}
+}
+
+// GetTrace takes a traceID and returns a Trace associated with that traceID
+func (r *SpanReader) GetTrace(ctx context.Context, traceID model.TraceID) (*model.Trace, error) {
+	row, err := r.spans.ReadRow(ctx, traceIDString(traceID), bigtable.RowFilter(bigtable.LatestNFilter(1)))
+	if err != nil {
+		return nil, err
+	}
+	if len(row[ColumnFamily]) == 0 {
+		return nil, spanstore.ErrTraceNotFound
+	}
+	return rowToTrace(row)
+}
+
+func rowToTrace(row bigtable.Row) (*model.Trace, error) {
+	trace := &model.Trace{}
+	for _, item := range row[ColumnFamily] {
+		span, err := toDomain(item.Value)
+		if err != nil {
+			return nil, err
+		}
+		trace.Spans = append(trace.Spans, span)
+	}
+	return trace, nil
+}
+
+// GetServices returns all services traced by Jaeger
+func (r *SpanReader) GetServices(ctx context.Context) ([]string, error) {
+	services := []string{}
+	var parseErr error
+	err := r.operations.ReadRows(ctx, bigtable.InfiniteRange(""), func(row bigtable.Row) bool {
+		var service string
+		if service, _, _, parseErr = parseOperationRowKey(row.Key()); parseErr != nil {
+			return false
+		}
+		// the rows are sorted by service first
+		if len(services) == 0 || services[len(services)-1] != service {
+			services = append(services, service)
+		}
+		return true
+	}, bigtable.RowFilter(keysOnly()))
+	if err != nil {
+		return nil, err
+	}
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	return services, nil
+}
+
+// GetOperations returns all operations for a specific service traced by Jaeger
+func (r *SpanReader) GetOperations(ctx context.Context, query spanstore.OperationQueryParameters) ([]spanstore.Operation, error) {
+	prefix := query.ServiceName + separator
+	if query.SpanKind != "" {
+		prefix += query.SpanKind + separator
+	}
+	operations := []spanstore.Operation{}
+	var parseErr error
+	err := r.operations.ReadRows(ctx, bigtable.PrefixRange(prefix), func(row bigtable.Row) bool {
+		var spanKind, operation string
+		if _, spanKind, operation, parseErr = parseOperationRowKey(row.Key()); parseErr != nil {
+			return false
+		}
+		operations = append(operations, spanstore.Operation{Name: operation, SpanKind: spanKind})
+		return true
+	}, bigtable.RowFilter(keysOnly()))
+	if err != nil {
+		return nil, err
+	}
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	return operations, nil
+}
+
+// FindTraces retrieves traces that match the traceQuery
+func (r *SpanReader) FindTraces(ctx context.Context, query *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
+	traceIDs, err := r.FindTraceIDs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(traceIDs) == 0 {
+		return []*model.Trace{}, nil
+	}
+	keys := make(bigtable.RowList, len(traceIDs))
+	for i, traceID := range traceIDs {
+		keys[i] = traceIDString(traceID)
+	}
+	found := make(map[string]*model.Trace, len(traceIDs))
+	var decodeErr error
+	err = r.spans.ReadRows(ctx, keys, func(row bigtable.Row) bool {
+		var trace *model.Trace
+		if trace, decodeErr = rowToTrace(row); decodeErr != nil {
+			return false
+		}
+		found[row.Key()] = trace
+		return true
+	}, bigtable.RowFilter(bigtable.LatestNFilter(1)))
+	if err != nil {
+		return nil, err
+	}
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+	// the rows are read in the order of their keys, the traces are returned in the order of their IDs
+	traces := make([]*model.Trace, 0, len(found))
+	for _, key := range keys {
+		// the missing traces were collected since they were found
+		if trace, ok := found[key]; ok {
+			traces = append(traces, trace)
+		}
+	}
+	return traces, nil
+}
+
+// FindTraceIDs retrieves the IDs of the traces that match the traceQuery, the most recent first.
+// The traces are read from the index of the service, or of the operation, filtered by duration by Bigtable;
+// and the traces of the tag equalities are intersected with them, the other tag filters are left to the query service.
+func (r *SpanReader) FindTraceIDs(ctx context.Context, query *spanstore.TraceQueryParameters) ([]model.TraceID, error) {
+	if err := validateQuery(query); err != nil {
+		return nil, err
+	}
+	numTraces := query.NumTraces
+	if numTraces <= 0 {
+		numTraces = defaultNumTraces
+	}
+
+	prefix := serviceIndexPrefix(query.ServiceName)
+	if query.OperationName != "" {
+		prefix = operationIndexPrefix(query.ServiceName, query.OperationName)
+	}
+	var tagPrefixes []string
+	for key, value := range query.Tags {
+		tagPrefixes = append(tagPrefixes, tagIndexPrefix(query.ServiceName, key, value))
+	}
+	for _, filter := range query.TagFilters {
+		if filter.Operator == spanstore.TagEquals {
+			tagPrefixes = append(tagPrefixes, tagIndexPrefix(query.ServiceName, filter.Key, filter.Value))
+		}
+	}
+	// sorted for the reads to be deterministic
+	sort.Strings(tagPrefixes)
+	limit := numTraces
+	if len(tagPrefixes) > 0 {
+		limit *= limitMultiple
+	}
+
+	traceIDs, err := r.readTraceIDs(ctx, indexRange(prefix, query.StartTimeMin, query.StartTimeMax), durationFilter(query), limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, tagPrefix := range tagPrefixes {
+		if len(traceIDs) == 0 {
+			break
+		}
+		tagTraceIDs, err := r.readTraceIDs(ctx, indexRange(tagPrefix, query.StartTimeMin, query.StartTimeMax), keysOnly(), limit)
+		if err != nil {
+			return nil, err
+		}
+		traceIDs = intersect(traceIDs, tagTraceIDs)
+	}
+	if len(traceIDs) > numTraces {
+		traceIDs = traceIDs[:numTraces]
+	}
+	return traceIDs, nil
+}
+
+// readTraceIDs reads the distinct trace IDs of the index range, up to limit.
+func (r *SpanReader) readTraceIDs(ctx context.Context, rowRange bigtable.RowRange, filter bigtable.Filter, limit int) ([]model.TraceID, error) {
+	traceIDs := []model.TraceID{}
+	found := make(map[model.TraceID]struct{})
+	var parseErr error
+	err := r.index.ReadRows(ctx, rowRange, func(row bigtable.Row) bool {
+		var traceID model.TraceID
+		if traceID, parseErr = traceIDFromIndexRowKey(row.Key()); parseErr != nil {
+			return false
+		}
+		if _, ok := found[traceID]; ok {
+			return true
+		}
+		found[traceID] = struct{}{}
+		traceIDs = append(traceIDs, traceID)
+		return len(traceIDs) < limit
+	}, bigtable.RowFilter(filter))
+	if err != nil {
+		return nil, err
+	}
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	return traceIDs, nil
+}
+
+// intersect returns the trace IDs of a which are in b, in the order of a.
+func intersect(a, b []model.TraceID) []model.TraceID {
+	inB := make(map[model.TraceID]struct{}, len(b))
+	for _, traceID := range b {
+		inB[traceID] = struct{}{}
+	}
+	result := a[:0]
+	for _, traceID := range a {
+		if _, ok := inB[traceID]; ok {
+			result = append(result, traceID)
+		}
+	}
+	return result
+}
+
+// durationFilter keeps the index rows of the spans in the duration range of the query.
+func durationFilter(query *spanstore.TraceQueryParameters) bigtable.Filter {
+	if query.DurationMin == 0 && query.DurationMax == 0 {
+		return keysOnly()
+	}
+	var min, max []byte
+	if query.DurationMin != 0 {
+		min = encodeDuration(query.DurationMin)
+	}
+	if query.DurationMax != 0 {
+		// the end of the range is excluded
+		max = encodeDuration(query.DurationMax + time.Microsecond)
+	}
+	return bigtable.ChainFilters(
+		bigtable.ColumnFilter(durationColumn),
+		bigtable.LatestNFilter(1),
+		bigtable.ValueRangeFilter(min, max),
+		bigtable.StripValueFilter(),
+	)
+}
+
+// keysOnly reads a single cell of each row without its value, the rows are told apart by their keys only.
+func keysOnly() bigtable.Filter {
+	return bigtable.ChainFilters(bigtable.CellsPerRowLimitFilter(1), bigtable.StripValueFilter())
+}
+
+func validateQuery(p *spanstore.TraceQueryParameters) error {
+	if p == nil {
+		return ErrMalformedRequestObject
+	}
+	if p.ServiceName == "" {
+		return ErrServiceNameNotSet
+	}
+	if p.StartTimeMin.IsZero() || p.StartTimeMax.IsZero() {
+		return ErrStartAndEndTimeNotSet
+	}
+	if p.StartTimeMax.Before(p.StartTimeMin) {
+		return ErrStartTimeMinGreaterThanMax
+	}
+	if p.DurationMin != 0 && p.DurationMax != 0 && p.DurationMin > p.DurationMax {
+		return ErrDurationMinGreaterThanMax
+	}
+	return nil
+}
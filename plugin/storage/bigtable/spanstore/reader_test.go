@@ -0,0 +1,259 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigtable"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+var (
+	traceID1 = model.NewTraceID(0, 1)
+	traceID2 = model.NewTraceID(0, 2)
+	traceID3 = model.NewTraceID(0, 3)
+)
+
+// withSpans runs the test with a reader of the spans of three traces of the frontend service:
+// traceID1 started at 100s with an error, traceID2 at 200s with a client span and a second server span of 3s,
+// traceID3 at 300s with an error.
+func withSpans(t *testing.T, test func(reader *SpanReader)) {
+	withEmulator(t, func(client *bigtable.Client) {
+		writer := NewSpanWriter(SpanWriterParams{Client: client, Tables: testTables, MetricsFactory: metrics.NullFactory})
+		span2 := testSpan(traceID2, 1, time.Unix(200, 0))
+		span2.OperationName = "GET /orders"
+		span2.Tags = model.KeyValues{model.String("span.kind", "client")}
+		span3 := testSpan(traceID2, 2, time.Unix(201, 0))
+		span3.Duration = 3 * time.Second
+		for _, span := range []*model.Span{
+			testSpan(traceID1, 1, time.Unix(100, 0)),
+			span2,
+			span3,
+			testSpan(traceID3, 1, time.Unix(300, 0)),
+			{TraceID: traceID3, SpanID: 2, StartTime: time.Unix(300, 0)},
+		} {
+			require.NoError(t, writer.WriteSpan(span))
+		}
+		test(NewSpanReader(client, testTables))
+	})
+}
+
+func TestGetTrace(t *testing.T) {
+	withSpans(t, func(reader *SpanReader) {
+		trace, err := reader.GetTrace(context.Background(), traceID2)
+		require.NoError(t, err)
+		require.Len(t, trace.Spans, 2)
+		assert.Equal(t, "GET /orders", trace.Spans[0].OperationName)
+
+		_, err = reader.GetTrace(context.Background(), model.NewTraceID(0, 4))
+		assert.Equal(t, spanstore.ErrTraceNotFound, err)
+	})
+}
+
+func TestGetServicesAndOperations(t *testing.T) {
+	withSpans(t, func(reader *SpanReader) {
+		services, err := reader.GetServices(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"frontend"}, services)
+
+		operations, err := reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "frontend"})
+		require.NoError(t, err)
+		assert.Equal(t, []spanstore.Operation{
+			{Name: "GET /orders", SpanKind: "client"},
+			{Name: "GET /users", SpanKind: "server"},
+		}, operations)
+
+		operations, err = reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "frontend", SpanKind: "server"})
+		require.NoError(t, err)
+		assert.Equal(t, []spanstore.Operation{{Name: "GET /users", SpanKind: "server"}}, operations)
+
+		operations, err = reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "backend"})
+		require.NoError(t, err)
+		assert.Empty(t, operations)
+	})
+}
+
+func TestFindTraceIDs(t *testing.T) {
+	withSpans(t, func(reader *SpanReader) {
+		testCases := []struct {
+			name     string
+			query    spanstore.TraceQueryParameters
+			expected []model.TraceID
+		}{
+			{
+				name:     "service",
+				query:    spanstore.TraceQueryParameters{},
+				expected: []model.TraceID{traceID3, traceID2, traceID1},
+			},
+			{
+				name:     "time range",
+				query:    spanstore.TraceQueryParameters{StartTimeMin: time.Unix(200, 0), StartTimeMax: time.Unix(201, 0)},
+				expected: []model.TraceID{traceID2},
+			},
+			{
+				name:     "operation",
+				query:    spanstore.TraceQueryParameters{OperationName: "GET /users"},
+				expected: []model.TraceID{traceID3, traceID2, traceID1},
+			},
+			{
+				name:     "duration",
+				query:    spanstore.TraceQueryParameters{DurationMin: 2 * time.Second},
+				expected: []model.TraceID{traceID2},
+			},
+			{
+				name:     "maximum duration",
+				query:    spanstore.TraceQueryParameters{DurationMax: time.Second},
+				expected: []model.TraceID{traceID3, traceID2, traceID1},
+			},
+			{
+				name:     "tags",
+				query:    spanstore.TraceQueryParameters{Tags: map[string]string{"error": "true"}},
+				expected: []model.TraceID{traceID3, traceID2, traceID1},
+			},
+			{
+				name: "tag filters",
+				query: spanstore.TraceQueryParameters{
+					Tags: map[string]string{"span.kind": "client"},
+					TagFilters: []spanstore.TagFilter{
+						{Key: "error", Operator: spanstore.TagEquals, Value: "true"},
+						{Key: "error", Operator: spanstore.TagNotEquals, Value: "false"},
+					},
+				},
+				expected: []model.TraceID{traceID2},
+			},
+			{
+				name:     "unknown tag",
+				query:    spanstore.TraceQueryParameters{Tags: map[string]string{"error": "false"}},
+				expected: []model.TraceID{},
+			},
+			{
+				name:     "number of traces",
+				query:    spanstore.TraceQueryParameters{NumTraces: 2},
+				expected: []model.TraceID{traceID3, traceID2},
+			},
+		}
+		for _, testCase := range testCases {
+			t.Run(testCase.name, func(t *testing.T) {
+				query := testCase.query
+				query.ServiceName = "frontend"
+				if query.StartTimeMin.IsZero() {
+					query.StartTimeMin, query.StartTimeMax = time.Unix(0, 0), time.Unix(1000, 0)
+				}
+				traceIDs, err := reader.FindTraceIDs(context.Background(), &query)
+				require.NoError(t, err)
+				assert.Equal(t, testCase.expected, traceIDs)
+			})
+		}
+	})
+}
+
+func TestFindTraces(t *testing.T) {
+	withSpans(t, func(reader *SpanReader) {
+		traces, err := reader.FindTraces(context.Background(), &spanstore.TraceQueryParameters{
+			ServiceName:  "frontend",
+			StartTimeMin: time.Unix(0, 0),
+			StartTimeMax: time.Unix(1000, 0),
+		})
+		require.NoError(t, err)
+		require.Len(t, traces, 3)
+		assert.Equal(t, traceID3, traces[0].Spans[0].TraceID)
+		assert.Len(t, traces[0].Spans, 2)
+		assert.Equal(t, traceID2, traces[1].Spans[0].TraceID)
+		assert.Equal(t, traceID1, traces[2].Spans[0].TraceID)
+
+		traces, err = reader.FindTraces(context.Background(), &spanstore.TraceQueryParameters{
+			ServiceName:  "backend",
+			StartTimeMin: time.Unix(0, 0),
+			StartTimeMax: time.Unix(1000, 0),
+		})
+		require.NoError(t, err)
+		assert.Empty(t, traces)
+
+		_, err = reader.FindTraces(context.Background(), nil)
+		assert.Equal(t, ErrMalformedRequestObject, err)
+	})
+}
+
+func TestValidateQuery(t *testing.T) {
+	start := time.Unix(100, 0)
+	testCases := []struct {
+		query    *spanstore.TraceQueryParameters
+		expected error
+	}{
+		{query: nil, expected: ErrMalformedRequestObject},
+		{query: &spanstore.TraceQueryParameters{}, expected: ErrServiceNameNotSet},
+		{query: &spanstore.TraceQueryParameters{ServiceName: "svc"}, expected: ErrStartAndEndTimeNotSet},
+		{
+			query:    &spanstore.TraceQueryParameters{ServiceName: "svc", StartTimeMin: start, StartTimeMax: start.Add(-time.Second)},
+			expected: ErrStartTimeMinGreaterThanMax,
+		},
+		{
+			query: &spanstore.TraceQueryParameters{
+				ServiceName: "svc", StartTimeMin: start, StartTimeMax: start, DurationMin: time.Second, DurationMax: time.Millisecond,
+			},
+			expected: ErrDurationMinGreaterThanMax,
+		},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, validateQuery(testCase.query))
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	withEmulator(t, func(client *bigtable.Client) {
+		reader := NewSpanReader(client, Tables{Spans: "missing", Index: "missing", Operations: "missing"})
+		ctx := context.Background()
+		_, err := reader.GetTrace(ctx, traceID1)
+		assert.Error(t, err)
+		_, err = reader.GetServices(ctx)
+		assert.Error(t, err)
+		_, err = reader.GetOperations(ctx, spanstore.OperationQueryParameters{ServiceName: "svc"})
+		assert.Error(t, err)
+		_, err = reader.FindTraces(ctx, &spanstore.TraceQueryParameters{
+			ServiceName: "svc", StartTimeMin: time.Unix(0, 0), StartTimeMax: time.Unix(100, 0),
+		})
+		assert.Error(t, err)
+
+		// invalid rows
+		mutation := bigtable.NewMutation()
+		mutation.Set(ColumnFamily, "column", bigtable.Now(), []byte("invalid"))
+		for table, key := range map[string]string{
+			testTables.Spans:      traceIDString(traceID1),
+			testTables.Operations: "invalid" + separator + "server",
+			testTables.Index:      serviceIndexPrefix("svc") + timeKey(time.Unix(50, 0)) + "not a trace ID of 32 characters",
+		} {
+			require.NoError(t, client.Open(table).Apply(ctx, key, mutation))
+		}
+		reader = NewSpanReader(client, testTables)
+		_, err = reader.GetTrace(ctx, traceID1)
+		assert.Error(t, err)
+		_, err = reader.GetServices(ctx)
+		assert.Error(t, err)
+		_, err = reader.GetOperations(ctx, spanstore.OperationQueryParameters{ServiceName: "invalid"})
+		assert.Error(t, err)
+		_, err = reader.FindTraceIDs(ctx, &spanstore.TraceQueryParameters{
+			ServiceName: "svc", StartTimeMin: time.Unix(0, 0), StartTimeMax: time.Unix(100, 0),
+		})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/bigtable"
+	"github.com/gogo/protobuf/proto"
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/cache"
+	storageMetrics "github.com/jaegertracing/jaeger/storage/spanstore/metrics"
+)
+
+// Tables are the names of the tables of the spans.
+type Tables struct {
+	Spans      string
+	Index      string
+	Operations string
+}
+
+// SpanWriterParams holds constructor parameters for NewSpanWriter
+type SpanWriterParams struct {
+	Client         *bigtable.Client
+	Tables         Tables
+	MetricsFactory metrics.Factory
+	// TTL is the age of the cells collected by Bigtable, they are not collected if it is zero.
+	TTL time.Duration
+}
+
+// SpanWriter writes the spans and their index into Bigtable.
+type SpanWriter struct {
+	spans      *bigtable.Table
+	index      *bigtable.Table
+	operations *bigtable.Table
+	// operationsCache are the operations written recently, they are not written again until they are about to be collected
+	operationsCache cache.Cache
+	spansMetrics    *storageMetrics.WriteMetrics
+	indexMetrics    *storageMetrics.WriteMetrics
+}
+
+// NewSpanWriter creates a SpanWriter.
+func NewSpanWriter(p SpanWriterParams) *SpanWriter {
+	operationsTTL := 12 * time.Hour
+	if p.TTL > 0 && p.TTL/2 < operationsTTL {
+		operationsTTL = p.TTL / 2
+	}
+	return &SpanWriter{
+		spans:      p.Client.Open(p.Tables.Spans),
+		index:      p.Client.Open(p.Tables.Index),
+		operations: p.Client.Open(p.Tables.Operations),
+		operationsCache: cache.NewLRUWithOptions(100000, &cache.Options{
+			TTL: operationsTTL,
+		}),
+		spansMetrics: storageMetrics.NewWriteMetrics(p.MetricsFactory, "spans"),
+		indexMetrics: storageMetrics.NewWriteMetrics(p.MetricsFactory, "index"),
+	}
+}
+
+// WriteSpan writes the span into the row of its trace, then its index rows and its operation,
+// unless the operation was written recently.
+func (w *SpanWriter) WriteSpan(span *model.Span) error {
+	ctx := context.Background()
+	data, err := proto.Marshal(span)
+	if err != nil {
+		return err
+	}
+	ts := timestamp(span)
+	mutation := bigtable.NewMutation()
+	mutation.Set(ColumnFamily, spanColumn(span.SpanID, data), ts, data)
+	start := time.Now()
+	err = w.spans.Apply(ctx, traceIDString(span.TraceID), mutation)
+	w.spansMetrics.Emit(err, time.Since(start))
+	if err != nil {
+		return err
+	}
+
+	prefixes := indexPrefixes(span)
+	if len(prefixes) == 0 {
+		return nil
+	}
+	if err := w.writeIndex(ctx, span, ts, prefixes); err != nil {
+		return err
+	}
+	return w.writeOperation(ctx, span)
+}
+
+func (w *SpanWriter) writeIndex(ctx context.Context, span *model.Span, ts bigtable.Timestamp, prefixes []string) error {
+	duration := encodeDuration(span.Duration)
+	keys := make([]string, len(prefixes))
+	mutations := make([]*bigtable.Mutation, len(prefixes))
+	for i, prefix := range prefixes {
+		keys[i] = indexRowKey(prefix, span.StartTime, span.TraceID)
+		mutations[i] = bigtable.NewMutation()
+		mutations[i].Set(ColumnFamily, durationColumn, ts, duration)
+	}
+	start := time.Now()
+	errs, err := w.index.ApplyBulk(ctx, keys, mutations)
+	if err == nil {
+		for _, rowErr := range errs {
+			if rowErr != nil {
+				err = rowErr
+				break
+			}
+		}
+	}
+	w.indexMetrics.Emit(err, time.Since(start))
+	return err
+}
+
+func (w *SpanWriter) writeOperation(ctx context.Context, span *model.Span) error {
+	spanKind, _ := span.GetSpanKind()
+	key := operationRowKey(span.Process.ServiceName, spanKind, span.OperationName)
+	if w.operationsCache.Get(key) != nil {
+		return nil
+	}
+	mutation := bigtable.NewMutation()
+	mutation.Set(ColumnFamily, operationColumn, bigtable.Now().TruncateToMilliseconds(), nil)
+	if err := w.operations.Apply(ctx, key, mutation); err != nil {
+		return err
+	}
+	w.operationsCache.Put(key, true)
+	return nil
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigtable"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+func testSpan(traceID model.TraceID, spanID model.SpanID, start time.Time) *model.Span {
+	return &model.Span{
+		TraceID:       traceID,
+		SpanID:        spanID,
+		OperationName: "GET /users",
+		StartTime:     start,
+		Duration:      time.Second,
+		Tags:          model.KeyValues{model.String("span.kind", "server"), model.String("error", "true")},
+		Process:       model.NewProcess("frontend", nil),
+	}
+}
+
+func TestSpanWriter(t *testing.T) {
+	withEmulator(t, func(client *bigtable.Client) {
+		metricsFactory := metricstest.NewFactory(0)
+		writer := NewSpanWriter(SpanWriterParams{Client: client, Tables: testTables, MetricsFactory: metricsFactory, TTL: time.Hour})
+		start := time.Unix(100, 0)
+		span := testSpan(model.NewTraceID(0, 1), 2, start)
+		require.NoError(t, writer.WriteSpan(span))
+		// the operation is cached
+		require.NoError(t, writer.WriteSpan(span))
+		require.NoError(t, writer.WriteSpan(&model.Span{TraceID: model.NewTraceID(0, 1), SpanID: 3, StartTime: start}))
+
+		ctx := context.Background()
+		row, err := client.Open(testTables.Spans).ReadRow(ctx, "00000000000000000000000000000001")
+		require.NoError(t, err)
+		require.Len(t, row[ColumnFamily], 2)
+		assert.Equal(t, timestamp(span), row[ColumnFamily][0].Timestamp)
+
+		var keys []string
+		err = client.Open(testTables.Index).ReadRows(ctx, bigtable.InfiniteRange(""), func(row bigtable.Row) bool {
+			keys = append(keys, row.Key())
+			assert.Equal(t, encodeDuration(time.Second), row[ColumnFamily][0].Value)
+			return true
+		})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{
+			indexRowKey(serviceIndexPrefix("frontend"), start, span.TraceID),
+			indexRowKey(operationIndexPrefix("frontend", "GET /users"), start, span.TraceID),
+			indexRowKey(tagIndexPrefix("frontend", "span.kind", "server"), start, span.TraceID),
+			indexRowKey(tagIndexPrefix("frontend", "error", "true"), start, span.TraceID),
+		}, keys)
+
+		row, err = client.Open(testTables.Operations).ReadRow(ctx, operationRowKey("frontend", "server", "GET /users"))
+		require.NoError(t, err)
+		assert.Len(t, row[ColumnFamily], 1)
+
+		metricsFactory.AssertCounterMetrics(t,
+			metricstest.ExpectedMetric{Name: "spans.attempts", Value: 3},
+			metricstest.ExpectedMetric{Name: "index.attempts", Value: 2},
+		)
+	})
+}
+
+func TestSpanWriterErrors(t *testing.T) {
+	withEmulator(t, func(client *bigtable.Client) {
+		span := testSpan(model.NewTraceID(0, 1), 2, time.Unix(100, 0))
+		for _, tables := range []Tables{
+			{Spans: "missing", Index: testTables.Index, Operations: testTables.Operations},
+			{Spans: testTables.Spans, Index: "missing", Operations: testTables.Operations},
+			{Spans: testTables.Spans, Index: testTables.Index, Operations: "missing"},
+		} {
+			writer := NewSpanWriter(SpanWriterParams{Client: client, Tables: tables, MetricsFactory: metricstest.NewFactory(0)})
+			assert.Error(t, writer.WriteSpan(span), tables)
+		}
+	})
+}
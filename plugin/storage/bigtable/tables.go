@@ -0,0 +1,53 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigtable
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/bigtable"
+)
+
+// createTables creates the tables which do not exist, with the column family of each table collecting
+// its cells after the TTL, unless it is zero.
+func createTables(ctx context.Context, admin *bigtable.AdminClient, families map[string]string, ttl time.Duration) error {
+	tables, err := admin.Tables(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot list the Bigtable tables: %w", err)
+	}
+	existing := make(map[string]struct{}, len(tables))
+	for _, table := range tables {
+		existing[table] = struct{}{}
+	}
+	policy := bigtable.MaxVersionsPolicy(1)
+	if ttl > 0 {
+		policy = bigtable.UnionPolicy(policy, bigtable.MaxAgePolicy(ttl))
+	}
+	for table, family := range families {
+		if _, ok := existing[table]; ok {
+			continue
+		}
+		err := admin.CreateTableFromConf(ctx, &bigtable.TableConf{
+			TableID:  table,
+			Families: map[string]bigtable.GCPolicy{family: policy},
+		})
+		if err != nil {
+			return fmt.Errorf("cannot create the Bigtable table %s: %w", table, err)
+		}
+	}
+	return nil
+}
@@ -16,8 +16,14 @@
 package cassandra
 
 import (
+	"bufio"
 	"errors"
 	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 	"github.com/uber/jaeger-lib/metrics"
@@ -117,9 +123,49 @@ func (f *Factory) CreateSpanWriter() (spanstore.Writer, error) {
 	if err != nil {
 		return nil, err
 	}
+	if f.Options.ServiceTTLFile != "" {
+		serviceTTL, err := loadServiceTTL(f.Options.ServiceTTLFile)
+		if err != nil {
+			f.logger.Error("Could not load the time to live of the services", zap.Error(err))
+			return nil, err
+		}
+		options = append(options, cSpanStore.ServiceTTL(serviceTTL))
+	}
 	return cSpanStore.NewSpanWriter(f.primarySession, f.Options.SpanStoreWriteCacheTTL, f.primaryMetricsFactory, f.logger, options...), nil
 }
 
+// loadServiceTTL reads the time to live of the spans of the services, one service=ttl per line such as frontend=72h
+func loadServiceTTL(filePath string) (map[string]time.Duration, error) {
+	file, err := os.Open(filepath.Clean(filePath))
+	if err != nil {
+		return nil, err
+	}
+	/* #nosec G307 */
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	serviceTTL := make(map[string]time.Duration)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		i := strings.LastIndex(line, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid service TTL %q, expected service=ttl", line)
+		}
+		ttl, err := time.ParseDuration(strings.TrimSpace(line[i+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid service TTL %q: %w", line, err)
+		}
+		if ttl < time.Second {
+			return nil, fmt.Errorf("invalid service TTL %q, the TTL must be at least one second", line)
+		}
+		serviceTTL[strings.TrimSpace(line[:i])] = ttl
+	}
+	return serviceTTL, scanner.Err()
+}
+
 // CreateDependencyReader implements storage.Factory
 func (f *Factory) CreateDependencyReader() (dependencystore.Reader, error) {
 	version := cDepStore.GetDependencyVersion(f.primarySession)
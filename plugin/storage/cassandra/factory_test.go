@@ -18,6 +18,7 @@ package cassandra
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -203,3 +204,65 @@ func TestNewOptions(t *testing.T) {
 	assert.Equal(t, archiveCfg, o.others[archiveStorageConfig].Configuration)
 	assert.Equal(t, archiveStorageConfig, o.others[archiveStorageConfig].namespace)
 }
+
+func TestServiceTTL(t *testing.T) {
+	f := NewFactory()
+	v, command := config.Viperize(f.AddFlags)
+	command.ParseFlags([]string{"--cassandra.service-ttl.config-file=fixtures/service_ttl_01.txt"})
+	f.InitFromViper(v)
+	assert.Equal(t, "fixtures/service_ttl_01.txt", f.Options.ServiceTTLFile)
+
+	session := &mocks.Session{}
+	query := &mocks.Query{}
+	session.On("Query", mock.AnythingOfType("string"), mock.Anything).Return(query)
+	query.On("Exec").Return(nil)
+	f.primaryConfig = newMockSessionBuilder(session, nil)
+	assert.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+
+	_, err := f.CreateSpanWriter()
+	assert.NoError(t, err)
+
+	f.Options.ServiceTTLFile = "fixtures/service_ttl_02.txt"
+	_, err = f.CreateSpanWriter()
+	assert.EqualError(t, err, `invalid service TTL "batch-jobs", expected service=ttl`)
+}
+
+func TestLoadServiceTTL(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected map[string]time.Duration
+		err      string
+	}{
+		{
+			path: "fixtures/service_ttl_01.txt",
+			expected: map[string]time.Duration{
+				"frontend":   72 * time.Hour,
+				"batch-jobs": 6 * time.Hour,
+				"weird=name": 90 * time.Minute,
+			},
+		},
+		{
+			path: "fixtures/service_ttl_02.txt",
+			err:  `invalid service TTL "batch-jobs", expected service=ttl`,
+		},
+		{
+			path: "fixtures/service_ttl_03.txt",
+			err:  `invalid service TTL "frontend=3d": time: unknown unit "d" in duration "3d"`,
+		},
+		{
+			path: "fixtures/service_ttl_04.txt",
+			err:  "open fixtures/service_ttl_04.txt: no such file or directory",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			serviceTTL, err := loadServiceTTL(test.path)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, serviceTTL)
+		})
+	}
+}
@@ -45,6 +45,8 @@ const (
 	suffixUsername             = ".username"
 	suffixPassword             = ".password"
 	suffixEnableDependenciesV2 = ".enable-dependencies-v2"
+	suffixSpeculativeAttempts  = ".speculative-execution.attempts"
+	suffixSpeculativeDelay     = ".speculative-execution.delay"
 
 	suffixVerifyHost = ".tls.verify-host"
 
@@ -55,6 +57,7 @@ const (
 	suffixIndexLogs              = ".index.logs"
 	suffixIndexTags              = ".index.tags"
 	suffixIndexProcessTags       = ".index.process-tags"
+	suffixServiceTTLFile         = ".service-ttl.config-file"
 )
 
 // Options contains various type of Cassandra configs and provides the ability
@@ -65,6 +68,7 @@ type Options struct {
 	others                 map[string]*namespaceConfig
 	SpanStoreWriteCacheTTL time.Duration `mapstructure:"span_store_write_cache_ttl"`
 	Index                  IndexConfig   `mapstructure:"index"`
+	ServiceTTLFile         string        `mapstructure:"service_ttl_file"`
 }
 
 // IndexConfig configures indexing.
@@ -98,6 +102,9 @@ func NewOptions(primaryNamespace string, otherNamespaces ...string) *Options {
 				ProtoVersion:       4,
 				ConnectionsPerHost: 2,
 				ReconnectInterval:  60 * time.Second,
+				SpeculativeExecution: config.SpeculativeExecution{
+					Delay: 100 * time.Millisecond,
+				},
 			},
 			servers:   "127.0.0.1",
 			namespace: primaryNamespace,
@@ -159,6 +166,11 @@ func (opt *Options) AddFlags(flagSet *flag.FlagSet) {
 		opt.Primary.namespace+suffixIndexProcessTags,
 		!opt.Index.ProcessTags,
 		"Controls process tag indexing. Set to false to disable.")
+	flagSet.String(
+		opt.Primary.namespace+suffixServiceTTLFile,
+		opt.ServiceTTLFile,
+		"Optional path to a file containing the time to live of the spans of some services, one service=ttl per line, e.g. frontend=72h. "+
+			"The spans of the other services expire after the default time to live of the tables.")
 }
 
 func addFlags(flagSet *flag.FlagSet, nsConfig namespaceConfig) {
@@ -235,6 +247,14 @@ func addFlags(flagSet *flag.FlagSet, nsConfig namespaceConfig) {
 		nsConfig.namespace+suffixEnableDependenciesV2,
 		nsConfig.EnableDependenciesV2,
 		"(deprecated) Jaeger will automatically detect the version of the dependencies table")
+	flagSet.Int(
+		nsConfig.namespace+suffixSpeculativeAttempts,
+		nsConfig.SpeculativeExecution.Attempts,
+		"The number of extra attempts of the reads sent to other hosts when the first ones are slow to respond, 0 to disable")
+	flagSet.Duration(
+		nsConfig.namespace+suffixSpeculativeDelay,
+		nsConfig.SpeculativeExecution.Delay,
+		"The time to wait for the response to a read before the next speculative attempt")
 	flagSet.Bool(
 		nsConfig.namespace+suffixVerifyHost,
 		false,
@@ -253,6 +273,7 @@ func (opt *Options) InitFromViper(v *viper.Viper) {
 	opt.Index.Tags = v.GetBool(opt.Primary.namespace + suffixIndexTags)
 	opt.Index.Logs = v.GetBool(opt.Primary.namespace + suffixIndexLogs)
 	opt.Index.ProcessTags = v.GetBool(opt.Primary.namespace + suffixIndexProcessTags)
+	opt.ServiceTTLFile = v.GetString(opt.Primary.namespace + suffixServiceTTLFile)
 }
 
 func tlsFlagsConfig(namespace string) tlscfg.ClientFlagsConfig {
@@ -284,6 +305,8 @@ func (cfg *namespaceConfig) initFromViper(v *viper.Viper) {
 	cfg.Authenticator.Basic.Password = v.GetString(cfg.namespace + suffixPassword)
 	cfg.EnableDependenciesV2 = v.GetBool(cfg.namespace + suffixEnableDependenciesV2)
 	cfg.DisableCompression = v.GetBool(cfg.namespace + suffixDisableCompression)
+	cfg.SpeculativeExecution.Attempts = v.GetInt(cfg.namespace + suffixSpeculativeAttempts)
+	cfg.SpeculativeExecution.Delay = v.GetDuration(cfg.namespace + suffixSpeculativeDelay)
 	cfg.TLS = tlsFlagsConfig.InitFromViper(v)
 
 	if v.IsSet(cfg.namespace + suffixVerifyHost) {
@@ -60,6 +60,8 @@ func TestOptionsWithFlags(t *testing.T) {
 		"--cas.consistency=ONE",
 		"--cas.proto-version=3",
 		"--cas.socket-keep-alive=42s",
+		"--cas.speculative-execution.attempts=2",
+		"--cas.speculative-execution.delay=42ms",
 		"--cas.index.tag-blacklist=blerg, blarg,blorg ",
 		"--cas.index.tag-whitelist=flerg, flarg,florg ",
 		"--cas.index.tags=true",
@@ -77,6 +79,8 @@ func TestOptionsWithFlags(t *testing.T) {
 	assert.Equal(t, "mojave", primary.LocalDC)
 	assert.Equal(t, []string{"1.1.1.1", "2.2.2.2"}, primary.Servers)
 	assert.Equal(t, "ONE", primary.Consistency)
+	assert.Equal(t, 2, primary.SpeculativeExecution.Attempts)
+	assert.Equal(t, 42*time.Millisecond, primary.SpeculativeExecution.Delay)
 	assert.Equal(t, false, primary.EnableDependenciesV2)
 	assert.Equal(t, []string{"blerg", "blarg", "blorg"}, opts.TagIndexBlacklist())
 	assert.Equal(t, []string{"flerg", "flarg", "florg"}, opts.TagIndexWhitelist())
@@ -96,6 +100,8 @@ func TestOptionsWithFlags(t *testing.T) {
 	assert.Equal(t, "", aux.Consistency, "aux storage does not inherit consistency from primary")
 	assert.Equal(t, 3, aux.ProtoVersion)
 	assert.Equal(t, 42*time.Second, aux.SocketKeepAlive)
+	assert.Equal(t, 2, aux.SpeculativeExecution.Attempts)
+	assert.Equal(t, 42*time.Millisecond, aux.SpeculativeExecution.Delay)
 	assert.Equal(t, true, aux.EnableDependenciesV2)
 }
 
@@ -10,6 +10,9 @@ function usage {
     >&2 echo "  DATACENTER         - datacenter name for network topology used in prod (optional in MODE=test)"
     >&2 echo "  TRACE_TTL          - time to live for trace data, in seconds (default: 172800, 2 days)"
     >&2 echo "  DEPENDENCIES_TTL   - time to live for dependencies data, in seconds (default: 0, no TTL)"
+    >&2 echo "  COMPACTION         - compaction strategy of the spans and their indices, twcs or ucs (default: twcs)."
+    >&2 echo "                       ucs, the unified compaction strategy, requires Cassandra 5.0"
+    >&2 echo "  COMPACTION_WINDOW  - window of the twcs compaction, such as 30m, 2h or 1d (default: TRACE_TTL / 30)"
     >&2 echo "  KEYSPACE           - keyspace (default: jaeger_v1_{datacenter})"
     >&2 echo "  REPLICATION_FACTOR - replication factor for prod (default: 2 for prod, 1 for test)"
     >&2 echo ""
@@ -21,6 +24,29 @@ function usage {
 trace_ttl=${TRACE_TTL:-172800}
 dependencies_ttl=${DEPENDENCIES_TTL:-0}
 
+if [[ "$COMPACTION_WINDOW" == "" ]]; then
+    # about 30 windows per TTL, see http://thelastpickle.com/blog/2016/12/08/TWCS-part1.html
+    compaction_window_size=$(( (trace_ttl / 60 + 29) / 30 ))
+    compaction_window_unit="MINUTES"
+elif [[ $COMPACTION_WINDOW =~ ^([0-9]+)([mhd])$ ]]; then
+    compaction_window_size=${BASH_REMATCH[1]}
+    case ${BASH_REMATCH[2]} in
+        m) compaction_window_unit="MINUTES" ;;
+        h) compaction_window_unit="HOURS" ;;
+        d) compaction_window_unit="DAYS" ;;
+    esac
+else
+    usage "invalid COMPACTION_WINDOW=$COMPACTION_WINDOW, expecting a number of minutes, hours or days such as 30m, 2h or 1d"
+fi
+
+if [[ "${COMPACTION:-twcs}" == "twcs" ]]; then
+    compaction="{'class': 'org.apache.cassandra.db.compaction.TimeWindowCompactionStrategy', 'compaction_window_size': '${compaction_window_size}', 'compaction_window_unit': '${compaction_window_unit}'}"
+elif [[ "$COMPACTION" == "ucs" ]]; then
+    compaction="{'class': 'org.apache.cassandra.db.compaction.UnifiedCompactionStrategy'}"
+else
+    usage "invalid COMPACTION=$COMPACTION, expecting 'twcs' or 'ucs'"
+fi
+
 template=$1
 if [[ "$template" == "" ]]; then
     template=$(ls $(dirname $0)/*cql.tmpl | sort | tail -1)
@@ -55,6 +81,7 @@ Using template file $template with parameters:
     replication = ${replication}
     trace_ttl = ${trace_ttl}
     dependencies_ttl = ${dependencies_ttl}
+    compaction = ${compaction}
 EOF
 
 # strip out comments, collapse multiple adjacent empty lines (cat -s), substitute variables
@@ -64,4 +91,5 @@ cat $template | sed \
     -e "s/\${keyspace}/${keyspace}/g"               \
     -e "s/\${replication}/${replication}/g"         \
     -e "s/\${trace_ttl}/${trace_ttl}/g"             \
-    -e "s/\${dependencies_ttl}/${dependencies_ttl}/g" | cat -s
+    -e "s/\${dependencies_ttl}/${dependencies_ttl}/g" \
+    -e "s/\${compaction}/${compaction}/g"           | cat -s
@@ -0,0 +1,393 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	casMetrics "github.com/jaegertracing/jaeger/pkg/cassandra/metrics"
+	"github.com/jaegertracing/jaeger/plugin/storage/cassandra/spanstore/dbmodel"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+// The paged queries read a single partition of an index, in the order of its clustering key, so that the
+// driver can page through them. The rows at a start time tell whether a trace was found by a previous page.
+const (
+	queryPageByServiceName = `
+		SELECT trace_id, start_time
+		FROM service_name_index
+		WHERE bucket = ? AND service_name = ? AND start_time > ? AND start_time < ?`
+	queryRowsByServiceName = `
+		SELECT trace_id
+		FROM service_name_index
+		WHERE bucket IN ` + bucketRange + ` AND service_name = ? AND start_time = ?`
+	queryPageByServiceAndOperationName = `
+		SELECT trace_id, start_time
+		FROM service_operation_index
+		WHERE service_name = ? AND operation_name = ? AND start_time > ? AND start_time < ?`
+	queryRowsByServiceAndOperationName = `
+		SELECT trace_id
+		FROM service_operation_index
+		WHERE service_name = ? AND operation_name = ? AND start_time = ?`
+	queryPageByTag = `
+		SELECT trace_id, start_time
+		FROM tag_index
+		WHERE service_name = ? AND tag_key = ? AND tag_value = ? AND start_time > ? AND start_time < ?`
+	queryRowsByTag = `
+		SELECT trace_id
+		FROM tag_index
+		WHERE service_name = ? AND tag_key = ? AND tag_value = ? AND start_time = ?`
+)
+
+// pageCursor is where the next page of a search starts in the index partitions, encoded as an opaque string.
+type pageCursor struct {
+	// Partitions are where the reading of each partition continues
+	Partitions []partitionCursor `json:"partitions"`
+	// Position is the start time, in microseconds, of the last row read
+	Position int64 `json:"position"`
+	// Tied are the traces of the rows read with the position as start time, which the next page cannot tell
+	// apart by their start time from the rows it reads
+	Tied []string `json:"tied,omitempty"`
+}
+
+// partitionCursor is the page of a partition being read, as returned by the driver, and the number of rows
+// of the page already read.
+type partitionCursor struct {
+	State []byte `json:"state,omitempty"`
+	Read  int    `json:"read,omitempty"`
+	Done  bool   `json:"done,omitempty"`
+}
+
+func encodePageCursor(cursor pageCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodePageCursor(cursor string) (pageCursor, error) {
+	var c pageCursor
+	if cursor == "" {
+		return c, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return c, spanstore.ErrInvalidPageCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil || len(c.Partitions) == 0 {
+		return c, spanstore.ErrInvalidPageCursor
+	}
+	return c, nil
+}
+
+// pagedIndex is the index read by a search, split in partitions the driver can page through.
+type pagedIndex struct {
+	pageQuery string
+	rowsQuery string
+	// partitions are the values of the partition key of each partition read by the page query
+	partitions [][]interface{}
+	// key are the values of the rows query before the start time
+	key     []interface{}
+	metrics *casMetrics.Table
+	// matches tells whether a span is indexed in the partitions
+	matches func(span *model.Span) bool
+}
+
+// pagedIndex returns the index read by the search, or ErrPagingNotSupported when the search reads several
+// indexes and intersects them, or reads the duration index which is not sorted by start time.
+func (s *SpanReader) pagedIndex(tq *spanstore.TraceQueryParameters) (*pagedIndex, error) {
+	if tq.DurationMin != 0 || tq.DurationMax != 0 {
+		return nil, spanstore.ErrPagingNotSupported
+	}
+	ofService := func(span *model.Span) bool {
+		return span.Process != nil && span.Process.ServiceName == tq.ServiceName
+	}
+	switch {
+	case tq.OperationName != "" && len(tq.Tags) == 0:
+		return &pagedIndex{
+			pageQuery:  queryPageByServiceAndOperationName,
+			rowsQuery:  queryRowsByServiceAndOperationName,
+			partitions: [][]interface{}{{tq.ServiceName, tq.OperationName}},
+			key:        []interface{}{tq.ServiceName, tq.OperationName},
+			metrics:    s.metrics.queryServiceOperationIndex,
+			matches: func(span *model.Span) bool {
+				return ofService(span) && span.OperationName == tq.OperationName
+			},
+		}, nil
+	case tq.OperationName == "" && len(tq.Tags) == 1:
+		for k, v := range tq.Tags {
+			return &pagedIndex{
+				pageQuery:  queryPageByTag,
+				rowsQuery:  queryRowsByTag,
+				partitions: [][]interface{}{{tq.ServiceName, k, v}},
+				key:        []interface{}{tq.ServiceName, k, v},
+				metrics:    s.metrics.queryTagIndex,
+				matches:    ofService,
+			}, nil
+		}
+	case tq.OperationName == "" && len(tq.Tags) == 0:
+		partitions := make([][]interface{}, defaultNumBuckets)
+		for bucket := range partitions {
+			partitions[bucket] = []interface{}{bucket, tq.ServiceName}
+		}
+		return &pagedIndex{
+			pageQuery:  queryPageByServiceName,
+			rowsQuery:  queryRowsByServiceName,
+			partitions: partitions,
+			key:        []interface{}{tq.ServiceName},
+			metrics:    s.metrics.queryServiceNameIndex,
+			matches:    ofService,
+		}, nil
+	}
+	return nil, spanstore.ErrPagingNotSupported
+}
+
+// FindTracesPage implements spanstore.PageReader#FindTracesPage for the searches reading a single index, by
+// the service, the operation or a tag, with the paging state of the driver. The other searches return
+// spanstore.ErrPagingNotSupported.
+func (s *SpanReader) FindTracesPage(
+	ctx context.Context,
+	traceQuery *spanstore.TraceQueryParameters,
+	cursor string,
+) ([]*model.Trace, string, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "FindTracesPage")
+	defer span.Finish()
+
+	if err := validateQuery(traceQuery); err != nil {
+		return nil, "", err
+	}
+	index, err := s.pagedIndex(traceQuery)
+	if err != nil {
+		return nil, "", err
+	}
+	c, err := decodePageCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	if c.Partitions == nil {
+		c.Partitions = make([]partitionCursor, len(index.partitions))
+	} else if len(c.Partitions) != len(index.partitions) {
+		return nil, "", spanstore.ErrInvalidPageCursor
+	}
+	if traceQuery.NumTraces == 0 {
+		traceQuery.NumTraces = defaultNumTraces
+	}
+	traces, c, done, err := s.findTracesInPartitions(ctx, traceQuery, index, c)
+	if err != nil || done {
+		return traces, "", err
+	}
+	return traces, encodePageCursor(c), nil
+}
+
+// indexRow is a row of an index read by a page query.
+type indexRow struct {
+	traceID   dbmodel.TraceID
+	startTime int64
+}
+
+// partitionReader reads the rows of a partition a page after the other, from its cursor.
+type partitionReader struct {
+	cursor partitionCursor
+	rows   []indexRow // rows of the current page not read yet
+	next   []byte     // state of the page after the current one
+	read   bool       // whether the current page was read
+}
+
+// findTracesInPartitions reads the rows of the partitions, the most recent first, from the cursor until it finds
+// numTraces traces not found by the previous pages. It returns the traces, the cursor after them, and whether
+// all the rows were read.
+func (s *SpanReader) findTracesInPartitions(
+	ctx context.Context,
+	tq *spanstore.TraceQueryParameters,
+	index *pagedIndex,
+	cursor pageCursor,
+) ([]*model.Trace, pageCursor, bool, error) {
+	// the traces having rows more recent than the start of the page, or tied with it, were found by the previous pages;
+	// the first page has no tied traces as the next pages start after at least one row
+	pageStart, firstPage := cursor.Position, len(cursor.Tied) == 0
+	found := make(map[dbmodel.TraceID]bool)
+	for _, traceID := range cursor.Tied {
+		if id, err := model.TraceIDFromString(traceID); err == nil {
+			found[dbmodel.TraceIDFromDomain(id)] = true
+		}
+	}
+	partitions := make([]*partitionReader, len(index.partitions))
+	for i := range partitions {
+		partitions[i] = &partitionReader{cursor: cursor.Partitions[i]}
+	}
+	pageSize := tq.NumTraces * limitMultiple
+	var traces []*model.Trace
+	for len(traces) < tq.NumTraces {
+		var candidates []dbmodel.TraceID
+		for len(traces)+len(candidates) < tq.NumTraces {
+			p, err := s.nextPartition(tq, index, partitions, pageSize)
+			if err != nil {
+				return nil, cursor, false, err
+			}
+			if p == nil {
+				break
+			}
+			row := p.rows[0]
+			p.rows = p.rows[1:]
+			p.cursor.Read++
+			if row.startTime != cursor.Position {
+				cursor.Tied = nil
+			}
+			cursor.Position = row.startTime
+			if traceID := row.traceID.ToDomain().String(); !containsString(cursor.Tied, traceID) {
+				cursor.Tied = append(cursor.Tied, traceID)
+			}
+			if !found[row.traceID] {
+				found[row.traceID] = true
+				candidates = append(candidates, row.traceID)
+			}
+		}
+		if len(candidates) == 0 {
+			break
+		}
+		for _, traceID := range candidates {
+			trace, err := s.readTrace(ctx, traceID)
+			if err != nil {
+				s.logger.Error("Failure to read trace", zap.String("trace_id", traceID.ToDomain().String()), zap.Error(err))
+				continue
+			}
+			if !firstPage {
+				foundBefore, err := s.foundBefore(ctx, tq, index, trace, traceID, pageStart)
+				if err != nil {
+					return nil, cursor, false, err
+				}
+				if foundBefore {
+					continue
+				}
+			}
+			traces = append(traces, trace)
+		}
+	}
+	done := true
+	for i, p := range partitions {
+		if p.read && len(p.rows) == 0 {
+			p.cursor = partitionCursor{State: p.next, Done: len(p.next) == 0}
+		}
+		cursor.Partitions[i] = p.cursor
+		done = done && p.cursor.Done
+	}
+	return traces, cursor, done, nil
+}
+
+// nextPartition returns the partition with the most recent row not read yet, reading the next pages of the
+// partitions as needed, or nil when all the rows were read.
+func (s *SpanReader) nextPartition(
+	tq *spanstore.TraceQueryParameters,
+	index *pagedIndex,
+	partitions []*partitionReader,
+	pageSize int,
+) (*partitionReader, error) {
+	var next *partitionReader
+	for i, p := range partitions {
+		for len(p.rows) == 0 && !p.cursor.Done {
+			if p.read {
+				p.cursor = partitionCursor{State: p.next, Done: len(p.next) == 0}
+				p.read = false
+				continue
+			}
+			if err := s.readPartitionPage(tq, index, index.partitions[i], p, pageSize); err != nil {
+				return nil, err
+			}
+		}
+		if len(p.rows) > 0 && (next == nil || p.rows[0].startTime > next.rows[0].startTime) {
+			next = p
+		}
+	}
+	return next, nil
+}
+
+// readPartitionPage reads the current page of the partition, without the rows read by the previous pages.
+func (s *SpanReader) readPartitionPage(
+	tq *spanstore.TraceQueryParameters,
+	index *pagedIndex,
+	partition []interface{},
+	p *partitionReader,
+	pageSize int,
+) error {
+	values := append(append([]interface{}(nil), partition...),
+		model.TimeAsEpochMicroseconds(tq.StartTimeMin),
+		model.TimeAsEpochMicroseconds(tq.StartTimeMax))
+	query := s.session.Query(index.pageQuery, values...).PageSize(pageSize).PageState(p.cursor.State)
+	start := time.Now()
+	iter := query.Iter()
+	var rows []indexRow
+	var row indexRow
+	for iter.Scan(&row.traceID, &row.startTime) {
+		rows = append(rows, row)
+	}
+	next := iter.PageState()
+	err := iter.Close()
+	index.metrics.Emit(err, time.Since(start))
+	if err != nil {
+		s.logger.Error("Failed to exec query", zap.Error(err), zap.String("query", query.String()))
+		return err
+	}
+	if p.cursor.Read > len(rows) {
+		return spanstore.ErrInvalidPageCursor
+	}
+	p.rows, p.next, p.read = rows[p.cursor.Read:], next, true
+	return nil
+}
+
+// foundBefore tells whether the trace has rows in the partitions more recent than the start of the page,
+// and was then found by a previous page.
+func (s *SpanReader) foundBefore(
+	ctx context.Context,
+	tq *spanstore.TraceQueryParameters,
+	index *pagedIndex,
+	trace *model.Trace,
+	traceID dbmodel.TraceID,
+	pageStart int64,
+) (bool, error) {
+	checked := make(map[int64]bool)
+	maxStartTime := int64(model.TimeAsEpochMicroseconds(tq.StartTimeMax))
+	for _, span := range trace.Spans {
+		startTime := int64(model.TimeAsEpochMicroseconds(span.StartTime))
+		if startTime <= pageStart || startTime >= maxStartTime || checked[startTime] || !index.matches(span) {
+			continue
+		}
+		checked[startTime] = true
+		query := s.session.Query(index.rowsQuery, append(append([]interface{}(nil), index.key...), startTime)...)
+		querySpan, _ := startSpanForQuery(ctx, "foundBefore", index.rowsQuery)
+		traceIDs, err := s.executeQuery(querySpan, query, index.metrics)
+		querySpan.Finish()
+		if err != nil {
+			return false, err
+		}
+		if _, ok := traceIDs[traceID]; ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,293 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/cassandra"
+	"github.com/jaegertracing/jaeger/plugin/storage/cassandra/spanstore/dbmodel"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+// fakeIndexSession answers the paged queries of the indexes and the queries of the spans of the traces,
+// the page states being the offsets of the pages.
+type fakeIndexSession struct {
+	rows  []fakeIndexRow
+	spans []dbmodel.Span
+	err   error
+}
+
+type fakeIndexRow struct {
+	partition []interface{}
+	row       indexRow
+}
+
+func (s *fakeIndexSession) Query(stmt string, values ...interface{}) cassandra.Query {
+	return &fakeQuery{session: s, stmt: stmt, values: values}
+}
+
+func (s *fakeIndexSession) Close() {}
+
+type fakeQuery struct {
+	session  *fakeIndexSession
+	stmt     string
+	values   []interface{}
+	pageSize int
+	state    []byte
+}
+
+func (q *fakeQuery) Exec() error                                       { return nil }
+func (q *fakeQuery) String() string                                    { return q.stmt }
+func (q *fakeQuery) ScanCAS(dest ...interface{}) (bool, error)         { return false, nil }
+func (q *fakeQuery) Bind(v ...interface{}) cassandra.Query             { q.values = v; return q }
+func (q *fakeQuery) Consistency(cassandra.Consistency) cassandra.Query { return q }
+func (q *fakeQuery) PageSize(n int) cassandra.Query                    { q.pageSize = n; return q }
+func (q *fakeQuery) PageState(state []byte) cassandra.Query            { q.state = state; return q }
+
+func (q *fakeQuery) Iter() cassandra.Iterator {
+	if q.session.err != nil {
+		return &fakeIter{err: q.session.err}
+	}
+	switch q.stmt {
+	case queryPageByServiceName, queryPageByServiceAndOperationName, queryPageByTag:
+		return q.page()
+	case queryRowsByServiceName, queryRowsByServiceAndOperationName, queryRowsByTag:
+		return q.rowsAt()
+	case querySpanByTraceID:
+		iter := &fakeIter{}
+		for _, span := range q.session.spans {
+			if span.TraceID == q.values[0] {
+				iter.rows = append(iter.rows, []interface{}{span.TraceID, span.SpanID, span.ParentID, span.OperationName,
+					span.Flags, span.StartTime, span.Duration, span.Tags, span.Logs, span.Refs, span.Process})
+			}
+		}
+		return iter
+	}
+	return &fakeIter{}
+}
+
+func (q *fakeQuery) page() cassandra.Iterator {
+	partition := fmt.Sprint(q.values[:len(q.values)-2])
+	min, max := q.values[len(q.values)-2].(uint64), q.values[len(q.values)-1].(uint64)
+	var rows []indexRow
+	for _, r := range q.session.rows {
+		if fmt.Sprint(r.partition) == partition && uint64(r.row.startTime) > min && uint64(r.row.startTime) < max {
+			rows = append(rows, r.row)
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].startTime > rows[j].startTime })
+	offset := 0
+	if len(q.state) > 0 {
+		offset, _ = strconv.Atoi(string(q.state))
+	}
+	iter := &fakeIter{}
+	end := offset + q.pageSize
+	if end < len(rows) {
+		iter.state = []byte(strconv.Itoa(end))
+	} else {
+		end = len(rows)
+	}
+	for _, row := range rows[offset:end] {
+		iter.rows = append(iter.rows, []interface{}{row.traceID, row.startTime})
+	}
+	return iter
+}
+
+func (q *fakeQuery) rowsAt() cassandra.Iterator {
+	key, startTime := fmt.Sprint(q.values[:len(q.values)-1]), q.values[len(q.values)-1].(int64)
+	iter := &fakeIter{}
+	for _, r := range q.session.rows {
+		partition := r.partition
+		if q.stmt == queryRowsByServiceName {
+			// all the buckets are read
+			partition = partition[1:]
+		}
+		if fmt.Sprint(partition) == key && r.row.startTime == startTime {
+			iter.rows = append(iter.rows, []interface{}{r.row.traceID})
+		}
+	}
+	return iter
+}
+
+type fakeIter struct {
+	rows  [][]interface{}
+	state []byte
+	err   error
+}
+
+func (i *fakeIter) Scan(dest ...interface{}) bool {
+	if len(i.rows) == 0 {
+		return false
+	}
+	for j, value := range i.rows[0] {
+		reflect.ValueOf(dest[j]).Elem().Set(reflect.ValueOf(value))
+	}
+	i.rows = i.rows[1:]
+	return true
+}
+
+func (i *fakeIter) Close() error      { return i.err }
+func (i *fakeIter) PageState() []byte { return i.state }
+
+var pagingTestStart = time.Unix(1600000000, 0)
+
+// index adds a span of the trace started at the offset, in microseconds, indexed in the partition.
+func (s *fakeIndexSession) index(traceID uint64, offset int64, partition ...interface{}) {
+	id := dbmodel.TraceIDFromDomain(model.NewTraceID(0, traceID))
+	startTime := int64(model.TimeAsEpochMicroseconds(pagingTestStart)) + offset
+	s.rows = append(s.rows, fakeIndexRow{partition: partition, row: indexRow{traceID: id, startTime: startTime}})
+	s.spans = append(s.spans, dbmodel.Span{
+		TraceID:       id,
+		SpanID:        int64(len(s.spans) + 1),
+		OperationName: "GET",
+		StartTime:     startTime,
+		Process:       dbmodel.Process{ServiceName: "frontend"},
+	})
+}
+
+func pagingTestQuery() *spanstore.TraceQueryParameters {
+	return &spanstore.TraceQueryParameters{
+		ServiceName:  "frontend",
+		StartTimeMin: pagingTestStart,
+		StartTimeMax: pagingTestStart.Add(time.Second),
+		NumTraces:    1,
+	}
+}
+
+// readAllPages returns the low bits of the trace IDs of all the pages of the search.
+func readAllPages(t *testing.T, reader *SpanReader, query *spanstore.TraceQueryParameters) []uint64 {
+	var traceIDs []uint64
+	cursor := ""
+	for i := 0; i < 20; i++ {
+		traces, next, err := reader.FindTracesPage(context.Background(), query, cursor)
+		require.NoError(t, err)
+		assert.True(t, len(traces) <= query.NumTraces)
+		for _, trace := range traces {
+			traceIDs = append(traceIDs, trace.Spans[0].TraceID.Low)
+		}
+		if next == "" {
+			return traceIDs
+		}
+		cursor = next
+	}
+	t.Fatal("the pages do not end")
+	return nil
+}
+
+func TestFindTracesPageByService(t *testing.T) {
+	session := &fakeIndexSession{}
+	session.index(1, 100, 0, "frontend")
+	session.index(2, 90, 1, "frontend")
+	// the trace 3 is found by a page, and again by a following one
+	session.index(3, 80, 2, "frontend")
+	session.index(3, 50, 3, "frontend")
+	session.index(4, 70, 0, "frontend")
+	// the traces 5 and 6 are tied, the trace 5 is found again after the end of its page
+	session.index(5, 60, 4, "frontend")
+	session.index(5, 60, 5, "frontend")
+	session.index(6, 60, 6, "frontend")
+	session.index(7, 40, 1, "frontend")
+	// the bucket 0 has more rows than a page of the partition
+	session.index(8, 30, 0, "frontend")
+	session.index(9, 20, 0, "frontend")
+	session.index(10, 10, 0, "frontend")
+	session.index(11, 100, 0, "other")
+	reader := NewSpanReader(session, metrics.NullFactory, zap.NewNop())
+
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, readAllPages(t, reader, pagingTestQuery()))
+
+	query := pagingTestQuery()
+	query.NumTraces = 4
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, readAllPages(t, reader, query))
+
+	query.NumTraces = 100
+	traces, next, err := reader.FindTracesPage(context.Background(), query, "")
+	require.NoError(t, err)
+	assert.Len(t, traces, 10)
+	assert.Empty(t, next)
+}
+
+func TestFindTracesPageByOperationAndTag(t *testing.T) {
+	session := &fakeIndexSession{}
+	session.index(1, 100, "frontend", "GET")
+	session.index(2, 90, "frontend", "GET")
+	session.index(1, 80, "frontend", "GET")
+	session.index(3, 100, "frontend", "http.status_code", "500")
+	session.index(4, 90, "frontend", "http.status_code", "500")
+	reader := NewSpanReader(session, metrics.NullFactory, zap.NewNop())
+
+	query := pagingTestQuery()
+	query.OperationName = "GET"
+	assert.Equal(t, []uint64{1, 2}, readAllPages(t, reader, query))
+
+	query = pagingTestQuery()
+	query.Tags = map[string]string{"http.status_code": "500"}
+	assert.Equal(t, []uint64{3, 4}, readAllPages(t, reader, query))
+}
+
+func TestFindTracesPageNotSupported(t *testing.T) {
+	reader := NewSpanReader(&fakeIndexSession{}, metrics.NullFactory, zap.NewNop())
+	queries := []func(q *spanstore.TraceQueryParameters){
+		func(q *spanstore.TraceQueryParameters) { q.DurationMin = time.Second },
+		func(q *spanstore.TraceQueryParameters) { q.OperationName, q.Tags = "GET", map[string]string{"k": "v"} },
+		func(q *spanstore.TraceQueryParameters) { q.Tags = map[string]string{"k": "v", "k2": "v2"} },
+	}
+	for _, modify := range queries {
+		query := pagingTestQuery()
+		modify(query)
+		_, _, err := reader.FindTracesPage(context.Background(), query, "")
+		assert.Equal(t, spanstore.ErrPagingNotSupported, err)
+	}
+}
+
+func TestFindTracesPageErrors(t *testing.T) {
+	session := &fakeIndexSession{}
+	session.index(1, 100, "frontend", "GET")
+	session.index(2, 90, "frontend", "GET")
+	reader := NewSpanReader(session, metrics.NullFactory, zap.NewNop())
+	query := pagingTestQuery()
+
+	_, _, err := reader.FindTracesPage(context.Background(), query, "not a cursor")
+	assert.Equal(t, spanstore.ErrInvalidPageCursor, err)
+	// the cursor of a search by operation reads a single partition
+	operationQuery := pagingTestQuery()
+	operationQuery.OperationName = "GET"
+	_, next, err := reader.FindTracesPage(context.Background(), operationQuery, "")
+	require.NoError(t, err)
+	require.NotEmpty(t, next)
+	_, _, err = reader.FindTracesPage(context.Background(), query, next)
+	assert.Equal(t, spanstore.ErrInvalidPageCursor, err)
+
+	_, _, err = reader.FindTracesPage(context.Background(), &spanstore.TraceQueryParameters{ServiceName: "frontend"}, "")
+	assert.Equal(t, ErrStartAndEndTimeNotSet, err)
+
+	session.err = errors.New("unavailable")
+	_, _, err = reader.FindTracesPage(context.Background(), query, "")
+	assert.EqualError(t, err, "unavailable")
+}
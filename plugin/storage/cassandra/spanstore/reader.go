@@ -62,6 +62,7 @@ const (
 		SELECT trace_id
 		FROM duration_index
 		WHERE bucket = ? AND service_name = ? AND operation_name = ? AND duration > ? AND duration < ?
+		ORDER BY duration DESC
 		LIMIT ?`
 
 	defaultNumTraces = 100
@@ -62,6 +62,7 @@ func withSpanReader(fn func(r *spanReaderTest)) {
 }
 
 var _ spanstore.Reader = &SpanReader{} // check API conformance
+var _ spanstore.PageReader = &SpanReader{} // check API conformance
 
 func TestSpanReaderGetServices(t *testing.T) {
 	withSpanReader(func(r *spanReaderTest) {
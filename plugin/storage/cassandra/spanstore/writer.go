@@ -95,6 +95,7 @@ type SpanWriter struct {
 	tagFilter            dbmodel.TagFilter
 	storageMode          storageMode
 	indexFilter          dbmodel.IndexFilter
+	serviceTTL           map[string]time.Duration
 }
 
 // NewSpanWriter returns a SpanWriter
@@ -125,6 +126,7 @@ func NewSpanWriter(
 		tagFilter:       opts.tagFilter,
 		storageMode:     opts.storageMode,
 		indexFilter:     opts.indexFilter,
+		serviceTTL:      opts.serviceTTL,
 	}
 }
 
@@ -152,7 +154,7 @@ func (s *SpanWriter) WriteSpan(span *model.Span) error {
 
 func (s *SpanWriter) writeSpan(span *model.Span, ds *dbmodel.Span) error {
 	mainQuery := s.session.Query(
-		insertSpan,
+		s.withTTL(insertSpan, ds),
 		ds.TraceID,
 		ds.SpanID,
 		ds.SpanHash,
@@ -216,7 +218,7 @@ func (s *SpanWriter) indexByTags(span *model.Span, ds *dbmodel.Span) error {
 		// we should introduce retries or just ignore failures imo, retrying each individual tag insertion might be better
 		// we should consider bucketing.
 		if s.shouldIndexTag(v) {
-			insertTagQuery := s.session.Query(tagIndex, ds.TraceID, ds.SpanID, v.ServiceName, ds.StartTime, v.TagKey, v.TagValue)
+			insertTagQuery := s.session.Query(s.withTTL(tagIndex, ds), ds.TraceID, ds.SpanID, v.ServiceName, ds.StartTime, v.TagKey, v.TagValue)
 			if err := s.writerMetrics.tagIndex.Exec(insertTagQuery, s.logger); err != nil {
 				withTagInfo := s.logger.
 					With(zap.String("tag_key", v.TagKey)).
@@ -232,7 +234,7 @@ func (s *SpanWriter) indexByTags(span *model.Span, ds *dbmodel.Span) error {
 }
 
 func (s *SpanWriter) indexByDuration(span *dbmodel.Span, startTime time.Time) error {
-	query := s.session.Query(durationIndex)
+	query := s.session.Query(s.withTTL(durationIndex, span))
 	timeBucket := startTime.Round(durationBucketSize)
 	var err error
 	indexByOperationName := func(operationName string) {
@@ -249,17 +251,25 @@ func (s *SpanWriter) indexByDuration(span *dbmodel.Span, startTime time.Time) er
 
 func (s *SpanWriter) indexByService(span *dbmodel.Span) error {
 	bucketNo := uint64(span.SpanHash) % defaultNumBuckets
-	query := s.session.Query(serviceNameIndex)
+	query := s.session.Query(s.withTTL(serviceNameIndex, span))
 	q := query.Bind(span.Process.ServiceName, bucketNo, span.StartTime, span.TraceID)
 	return s.writerMetrics.serviceNameIndex.Exec(q, s.logger)
 }
 
 func (s *SpanWriter) indexByOperation(span *dbmodel.Span) error {
-	query := s.session.Query(serviceOperationIndex)
+	query := s.session.Query(s.withTTL(serviceOperationIndex, span))
 	q := query.Bind(span.Process.ServiceName, span.OperationName, span.StartTime, span.TraceID)
 	return s.writerMetrics.serviceOperationIndex.Exec(q, s.logger)
 }
 
+// withTTL appends the time to live of the service of the span to the insert statement, if the service has one
+func (s *SpanWriter) withTTL(stmt string, span *dbmodel.Span) string {
+	if ttl, ok := s.serviceTTL[span.ServiceName]; ok {
+		return fmt.Sprintf("%s USING TTL %d", stmt, int64(ttl/time.Second))
+	}
+	return stmt
+}
+
 // shouldIndexTag checks to see if the tag is json or not, if it's UTF8 valid and it's not too large
 func (s *SpanWriter) shouldIndexTag(tag dbmodel.TagInsertion) bool {
 	isJSON := func(s string) bool {
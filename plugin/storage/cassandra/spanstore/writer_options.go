@@ -16,6 +16,8 @@
 package spanstore
 
 import (
+	"time"
+
 	"github.com/jaegertracing/jaeger/plugin/storage/cassandra/spanstore/dbmodel"
 )
 
@@ -27,6 +29,7 @@ type Options struct {
 	tagFilter   dbmodel.TagFilter
 	storageMode storageMode
 	indexFilter dbmodel.IndexFilter
+	serviceTTL  map[string]time.Duration
 }
 
 // TagFilter can be provided to filter any tags that should not be indexed.
@@ -57,6 +60,14 @@ func IndexFilter(indexFilter dbmodel.IndexFilter) Option {
 	}
 }
 
+// ServiceTTL can be provided to store the spans of some services, and their indexes, with their own time to live
+// instead of the default time to live of the tables.
+func ServiceTTL(serviceTTL map[string]time.Duration) Option {
+	return func(o *Options) {
+		o.serviceTTL = serviceTTL
+	}
+}
+
 func applyOptions(opts ...Option) Options {
 	o := Options{}
 	for _, opt := range opts {
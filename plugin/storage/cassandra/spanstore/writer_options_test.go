@@ -17,6 +17,7 @@ package spanstore
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -29,6 +30,12 @@ func TestWriterOptions(t *testing.T) {
 	assert.ObjectsAreEqual(dbmodel.DefaultIndexFilter, opts.indexFilter)
 }
 
+func TestWriterOptions_ServiceTTL(t *testing.T) {
+	assert.Nil(t, applyOptions().serviceTTL)
+	opts := applyOptions(ServiceTTL(map[string]time.Duration{"service-a": time.Hour}))
+	assert.Equal(t, map[string]time.Duration{"service-a": time.Hour}, opts.serviceTTL)
+}
+
 func TestWriterOptions_StorageMode(t *testing.T) {
 	tests := []struct {
 		name     string
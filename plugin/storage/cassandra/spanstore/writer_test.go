@@ -24,6 +24,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"github.com/uber/jaeger-lib/metrics/metricstest"
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
@@ -433,3 +434,37 @@ func TestStorageMode_StoreWithoutIndexing(t *testing.T) {
 		w.session.AssertNotCalled(t, "Query", stringMatcher(serviceNameIndex), matchEverything())
 	}, StoreWithoutIndexing())
 }
+
+func TestSpanWriterServiceTTL(t *testing.T) {
+	for _, service := range []string{"service-a", "service-b"} {
+		withSpanWriter(0, func(w *spanWriterTest) {
+			w.writer.serviceNamesWriter = func(serviceName string) error { return nil }
+			w.writer.operationNamesWriter = func(operation dbmodel.Operation) error { return nil }
+			span := &model.Span{
+				TraceID: model.NewTraceID(0, 1),
+				Process: &model.Process{
+					ServiceName: service,
+				},
+				Tags: model.KeyValues{model.String("x", "y")},
+			}
+
+			var statements []string
+			query := &mocks.Query{}
+			query.On("Bind", matchEverything()).Return(query)
+			query.On("Exec").Return(nil)
+			w.session.On("Query", mock.AnythingOfType("string"), matchEverything()).
+				Run(func(args mock.Arguments) { statements = append(statements, args.String(0)) }).
+				Return(query)
+
+			require.NoError(t, w.writer.WriteSpan(span))
+			assert.Len(t, statements, 5)
+			for _, statement := range statements {
+				if service == "service-a" {
+					assert.True(t, strings.HasSuffix(statement, " USING TTL 3600"), statement)
+				} else {
+					assert.NotContains(t, statement, "USING TTL")
+				}
+			}
+		}, ServiceTTL(map[string]time.Duration{"service-a": time.Hour}))
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dependencystore
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/clickhouse"
+)
+
+// DependencyStore computes the dependencies of the services by joining the spans with their parents.
+type DependencyStore struct {
+	client     clickhouse.Client
+	spansTable string
+}
+
+// NewDependencyStore returns a DependencyStore reading the spans of the table.
+func NewDependencyStore(client clickhouse.Client, spansTable string) *DependencyStore {
+	return &DependencyStore{
+		client:     client,
+		spansTable: spansTable,
+	}
+}
+
+// GetDependencies returns the calls between the services in the time window ending at endTs.
+func (s *DependencyStore) GetDependencies(endTs time.Time, lookback time.Duration) ([]model.DependencyLink, error) {
+	timeRange := "timestamp >= fromUnixTimestamp64Micro({start:Int64}) AND timestamp <= fromUnixTimestamp64Micro({end:Int64})"
+	statement := `SELECT parent.service AS parent, child.service AS child, count() AS call_count
+FROM (SELECT trace_id, parent_span_id, service FROM ` + s.spansTable + ` WHERE parent_span_id != '' AND ` + timeRange + `) AS child
+INNER JOIN (SELECT trace_id, span_id, service FROM ` + s.spansTable + ` WHERE ` + timeRange + `) AS parent
+ON child.trace_id = parent.trace_id AND child.parent_span_id = parent.span_id
+WHERE parent.service != child.service
+GROUP BY parent, child
+ORDER BY parent, child`
+	params := map[string]string{
+		"start": strconv.FormatInt(endTs.Add(-lookback).UnixNano()/int64(time.Microsecond), 10),
+		"end":   strconv.FormatInt(endTs.UnixNano()/int64(time.Microsecond), 10),
+	}
+	dependencies := []model.DependencyLink{}
+	err := s.client.Query(context.Background(), statement, params, func(row json.RawMessage) error {
+		var link struct {
+			Parent    string `json:"parent"`
+			Child     string `json:"child"`
+			CallCount uint64 `json:"call_count"`
+		}
+		if err := json.Unmarshal(row, &link); err != nil {
+			return err
+		}
+		dependencies = append(dependencies, model.DependencyLink{
+			Parent:    link.Parent,
+			Child:     link.Child,
+			CallCount: link.CallCount,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dependencies, nil
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dependencystore
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+type fakeClient struct {
+	query  string
+	params map[string]string
+	rows   []string
+	err    error
+}
+
+func (c *fakeClient) Exec(ctx context.Context, query string, params map[string]string) error {
+	return c.err
+}
+
+func (c *fakeClient) Insert(ctx context.Context, table string, rows []interface{}) error {
+	return c.err
+}
+
+func (c *fakeClient) Query(ctx context.Context, query string, params map[string]string, row func(json.RawMessage) error) error {
+	c.query, c.params = query, params
+	if c.err != nil {
+		return c.err
+	}
+	for _, r := range c.rows {
+		if err := row(json.RawMessage(r)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestGetDependencies(t *testing.T) {
+	client := &fakeClient{rows: []string{
+		`{"parent":"a","child":"b","call_count":3}`,
+		`{"parent":"b","child":"c","call_count":1}`,
+	}}
+	store := NewDependencyStore(client, "spans")
+	dependencies, err := store.GetDependencies(time.Unix(100, 0), 10*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, []model.DependencyLink{
+		{Parent: "a", Child: "b", CallCount: 3},
+		{Parent: "b", Child: "c", CallCount: 1},
+	}, dependencies)
+	assert.Equal(t, map[string]string{"start": "90000000", "end": "100000000"}, client.params)
+	assert.True(t, strings.Contains(client.query, "FROM (SELECT trace_id, parent_span_id, service FROM spans"))
+	assert.True(t, strings.Contains(client.query, "INNER JOIN (SELECT trace_id, span_id, service FROM spans"))
+}
+
+func TestGetDependenciesErrors(t *testing.T) {
+	store := NewDependencyStore(&fakeClient{err: assert.AnError}, "spans")
+	_, err := store.GetDependencies(time.Unix(100, 0), time.Second)
+	assert.Equal(t, assert.AnError, err)
+
+	store = NewDependencyStore(&fakeClient{rows: []string{`[`}}, "spans")
+	_, err = store.GetDependencies(time.Unix(100, 0), time.Second)
+	assert.Error(t, err)
+}
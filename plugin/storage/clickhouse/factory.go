@@ -0,0 +1,120 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"context"
+	"flag"
+
+	"github.com/spf13/viper"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/pkg/clickhouse"
+	chDepStore "github.com/jaegertracing/jaeger/plugin/storage/clickhouse/dependencystore"
+	chSpanStore "github.com/jaegertracing/jaeger/plugin/storage/clickhouse/spanstore"
+	"github.com/jaegertracing/jaeger/storage/dependencystore"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+// Factory implements storage.Factory for ClickHouse backend.
+type Factory struct {
+	Options *Options
+
+	metricsFactory metrics.Factory
+	logger         *zap.Logger
+	client         clickhouse.Client
+	writers        []*chSpanStore.SpanWriter
+}
+
+// NewFactory creates a new Factory.
+func NewFactory() *Factory {
+	return &Factory{
+		Options: NewOptions(),
+	}
+}
+
+// AddFlags implements plugin.Configurable
+func (f *Factory) AddFlags(flagSet *flag.FlagSet) {
+	f.Options.AddFlags(flagSet)
+}
+
+// InitFromViper implements plugin.Configurable
+func (f *Factory) InitFromViper(v *viper.Viper) {
+	f.Options.InitFromViper(v)
+}
+
+// Initialize implements storage.Factory
+func (f *Factory) Initialize(metricsFactory metrics.Factory, logger *zap.Logger) error {
+	f.metricsFactory, f.logger = metricsFactory, logger
+	client, err := f.Options.Configuration.NewClient()
+	if err != nil {
+		return err
+	}
+	f.client = client
+	if f.Options.Configuration.CreateSchema {
+		if err := chSpanStore.CreateSchema(context.Background(), client, f.schema()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *Factory) schema() chSpanStore.Schema {
+	cfg := f.Options.Configuration
+	return chSpanStore.Schema{
+		SpansTable:      cfg.SpansTable,
+		OperationsTable: cfg.OperationsTable,
+		TTL:             cfg.TTL,
+		TagsAsColumns:   cfg.TagsAsColumns,
+	}
+}
+
+// CreateSpanReader implements storage.Factory
+func (f *Factory) CreateSpanReader() (spanstore.Reader, error) {
+	return chSpanStore.NewSpanReader(f.client, f.schema()), nil
+}
+
+// CreateSpanWriter implements storage.Factory
+func (f *Factory) CreateSpanWriter() (spanstore.Writer, error) {
+	cfg := f.Options.Configuration
+	writer := chSpanStore.NewSpanWriter(chSpanStore.SpanWriterParams{
+		Client:             f.client,
+		Logger:             f.logger,
+		MetricsFactory:     f.metricsFactory,
+		SpansTable:         cfg.SpansTable,
+		BatchSize:          cfg.BatchSize,
+		BatchFlushInterval: cfg.BatchFlushInterval,
+	})
+	f.writers = append(f.writers, writer)
+	return writer, nil
+}
+
+// CreateDependencyReader implements storage.Factory
+func (f *Factory) CreateDependencyReader() (dependencystore.Reader, error) {
+	return chDepStore.NewDependencyStore(f.client, f.Options.Configuration.SpansTable), nil
+}
+
+// Close implements io.Closer and inserts the spans remaining in the batches of the writers.
+func (f *Factory) Close() error {
+	var firstErr error
+	for _, writer := range f.writers {
+		if err := writer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	f.writers = nil
+	return firstErr
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/config"
+	"github.com/jaegertracing/jaeger/storage"
+)
+
+var _ storage.Factory = new(Factory)
+var _ io.Closer = new(Factory)
+
+type fakeServer struct {
+	*httptest.Server
+	mux        sync.Mutex
+	statements []string
+	status     int
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	s := &fakeServer{status: http.StatusOK}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		statement := r.URL.Query().Get("query")
+		if statement == "" {
+			body, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			statement = string(body)
+		}
+		s.mux.Lock()
+		defer s.mux.Unlock()
+		s.statements = append(s.statements, statement)
+		w.WriteHeader(s.status)
+	}))
+	return s
+}
+
+func newTestFactory(t *testing.T, serverURL string, flags ...string) *Factory {
+	f := NewFactory()
+	v, command := config.Viperize(f.AddFlags)
+	command.ParseFlags(append([]string{"--clickhouse.server-url=" + serverURL}, flags...))
+	f.InitFromViper(v)
+	return f
+}
+
+func TestFactory(t *testing.T) {
+	server := newFakeServer(t)
+	defer server.Close()
+	f := newTestFactory(t, server.URL, "--clickhouse.tags-as-columns=error", "--clickhouse.batch-size=1")
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	require.Len(t, server.statements, 4)
+	assert.True(t, strings.HasPrefix(server.statements[0], "CREATE TABLE IF NOT EXISTS jaeger_spans"))
+	assert.True(t, strings.HasPrefix(server.statements[3], "ALTER TABLE jaeger_spans ADD COLUMN IF NOT EXISTS tag_error"))
+
+	_, err := f.CreateSpanReader()
+	require.NoError(t, err)
+	_, err = f.CreateDependencyReader()
+	require.NoError(t, err)
+	writer, err := f.CreateSpanWriter()
+	require.NoError(t, err)
+	require.NoError(t, writer.WriteSpan(&model.Span{TraceID: model.NewTraceID(0, 1), SpanID: 1}))
+	assert.Equal(t, "INSERT INTO jaeger_spans FORMAT JSONEachRow", server.statements[4])
+	assert.NoError(t, f.Close())
+}
+
+func TestFactoryWithoutSchemaCreation(t *testing.T) {
+	server := newFakeServer(t)
+	defer server.Close()
+	f := newTestFactory(t, server.URL, "--clickhouse.create-schema=false")
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	assert.Empty(t, server.statements)
+	assert.NoError(t, f.Close())
+}
+
+func TestFactoryInitializeErrors(t *testing.T) {
+	f := newTestFactory(t, "")
+	assert.EqualError(t, f.Initialize(metrics.NullFactory, zap.NewNop()), "no server specified")
+
+	f = newTestFactory(t, "http://127.0.0.1:8123", "--clickhouse.tls.enabled=true", "--clickhouse.tls.ca=/does/not/exist")
+	assert.Error(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+
+	server := newFakeServer(t)
+	defer server.Close()
+	server.status = http.StatusInternalServerError
+	f = newTestFactory(t, server.URL)
+	assert.Error(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+}
+
+func TestFactoryCloseError(t *testing.T) {
+	server := newFakeServer(t)
+	defer server.Close()
+	f := newTestFactory(t, server.URL, "--clickhouse.create-schema=false")
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	writer, err := f.CreateSpanWriter()
+	require.NoError(t, err)
+	require.NoError(t, writer.WriteSpan(&model.Span{TraceID: model.NewTraceID(0, 1), SpanID: 1}))
+	server.mux.Lock()
+	server.status = http.StatusInternalServerError
+	server.mux.Unlock()
+	assert.Error(t, f.Close())
+}
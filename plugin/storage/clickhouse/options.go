@@ -0,0 +1,125 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"flag"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/jaegertracing/jaeger/pkg/clickhouse/config"
+	"github.com/jaegertracing/jaeger/pkg/config/tlscfg"
+)
+
+const (
+	namespace = "clickhouse"
+
+	suffixServerURL          = ".server-url"
+	suffixDatabase           = ".database"
+	suffixUsername           = ".username"
+	suffixPassword           = ".password"
+	suffixTimeout            = ".timeout"
+	suffixSpansTable         = ".spans-table"
+	suffixOperationsTable    = ".operations-table"
+	suffixCreateSchema       = ".create-schema"
+	suffixTTL                = ".ttl"
+	suffixBatchSize          = ".batch-size"
+	suffixBatchFlushInterval = ".batch-flush-interval"
+	suffixTagsAsColumns      = ".tags-as-columns"
+
+	defaultServerURL          = "http://127.0.0.1:8123"
+	defaultDatabase           = "default"
+	defaultTimeout            = 30 * time.Second
+	defaultSpansTable         = "jaeger_spans"
+	defaultOperationsTable    = "jaeger_operations"
+	defaultTTL                = 72 * time.Hour
+	defaultBatchSize          = 10000
+	defaultBatchFlushInterval = 5 * time.Second
+)
+
+// Options contains the ClickHouse configs and provides the ability
+// to bind them to command line flags
+type Options struct {
+	Configuration config.Configuration `mapstructure:",squash"`
+}
+
+// NewOptions creates the Options with the default configuration.
+func NewOptions() *Options {
+	return &Options{
+		Configuration: config.Configuration{
+			ServerURL:          defaultServerURL,
+			Database:           defaultDatabase,
+			Timeout:            defaultTimeout,
+			SpansTable:         defaultSpansTable,
+			OperationsTable:    defaultOperationsTable,
+			CreateSchema:       true,
+			TTL:                defaultTTL,
+			BatchSize:          defaultBatchSize,
+			BatchFlushInterval: defaultBatchFlushInterval,
+		},
+	}
+}
+
+func tlsFlagsConfig() tlscfg.ClientFlagsConfig {
+	return tlscfg.ClientFlagsConfig{
+		Prefix:         namespace,
+		ShowEnabled:    true,
+		ShowServerName: true,
+	}
+}
+
+// AddFlags adds flags for Options
+func (opt *Options) AddFlags(flagSet *flag.FlagSet) {
+	cfg := opt.Configuration
+	flagSet.String(namespace+suffixServerURL, cfg.ServerURL, "The URL of the HTTP interface of the ClickHouse server")
+	flagSet.String(namespace+suffixDatabase, cfg.Database, "The ClickHouse database of the tables")
+	flagSet.String(namespace+suffixUsername, cfg.Username, "The username required by ClickHouse")
+	flagSet.String(namespace+suffixPassword, cfg.Password, "The password required by ClickHouse")
+	flagSet.Duration(namespace+suffixTimeout, cfg.Timeout, "The timeout of the queries and of the inserts")
+	flagSet.String(namespace+suffixSpansTable, cfg.SpansTable, "The table of the spans")
+	flagSet.String(namespace+suffixOperationsTable, cfg.OperationsTable, "The table of the operations of the services, filled from the spans by a materialized view")
+	flagSet.Bool(namespace+suffixCreateSchema, cfg.CreateSchema, "Create the tables on startup, and the materialized columns of the tags")
+	flagSet.Duration(namespace+suffixTTL, cfg.TTL, "How long to keep the spans when creating the tables, 0 keeps them forever")
+	flagSet.Int(namespace+suffixBatchSize, cfg.BatchSize, "The number of spans inserted at once")
+	flagSet.Duration(namespace+suffixBatchFlushInterval, cfg.BatchFlushInterval, "The interval of the inserts of the batches which are not full")
+	flagSet.String(namespace+suffixTagsAsColumns, "", "A comma-separated list of the tag keys stored in materialized columns for faster searches, "+
+		"the column holds the first value of the key in the span tags, the process tags and the log fields")
+	tlsFlagsConfig().AddFlags(flagSet)
+}
+
+// InitFromViper initializes Options with properties from viper
+func (opt *Options) InitFromViper(v *viper.Viper) {
+	cfg := &opt.Configuration
+	cfg.ServerURL = strings.TrimSuffix(v.GetString(namespace+suffixServerURL), "/")
+	cfg.Database = v.GetString(namespace + suffixDatabase)
+	cfg.Username = v.GetString(namespace + suffixUsername)
+	cfg.Password = v.GetString(namespace + suffixPassword)
+	cfg.Timeout = v.GetDuration(namespace + suffixTimeout)
+	cfg.SpansTable = v.GetString(namespace + suffixSpansTable)
+	cfg.OperationsTable = v.GetString(namespace + suffixOperationsTable)
+	cfg.CreateSchema = v.GetBool(namespace + suffixCreateSchema)
+	cfg.TTL = v.GetDuration(namespace + suffixTTL)
+	cfg.BatchSize = v.GetInt(namespace + suffixBatchSize)
+	cfg.BatchFlushInterval = v.GetDuration(namespace + suffixBatchFlushInterval)
+	cfg.TagsAsColumns = nil
+	for _, key := range strings.Split(v.GetString(namespace+suffixTagsAsColumns), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			cfg.TagsAsColumns = append(cfg.TagsAsColumns, key)
+		}
+	}
+	cfg.TLS = tlsFlagsConfig().InitFromViper(v)
+}
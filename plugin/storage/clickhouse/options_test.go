@@ -0,0 +1,79 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jaegertracing/jaeger/pkg/config"
+)
+
+func TestDefaultOptions(t *testing.T) {
+	opts := NewOptions()
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{})
+	opts.InitFromViper(v)
+
+	cfg := opts.Configuration
+	assert.Equal(t, "http://127.0.0.1:8123", cfg.ServerURL)
+	assert.Equal(t, "default", cfg.Database)
+	assert.Equal(t, "jaeger_spans", cfg.SpansTable)
+	assert.Equal(t, "jaeger_operations", cfg.OperationsTable)
+	assert.True(t, cfg.CreateSchema)
+	assert.Equal(t, 72*time.Hour, cfg.TTL)
+	assert.Equal(t, 10000, cfg.BatchSize)
+	assert.Equal(t, 5*time.Second, cfg.BatchFlushInterval)
+	assert.Empty(t, cfg.TagsAsColumns)
+	assert.False(t, cfg.TLS.Enabled)
+}
+
+func TestOptionsWithFlags(t *testing.T) {
+	opts := NewOptions()
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{
+		"--clickhouse.server-url=https://clickhouse:8443/",
+		"--clickhouse.database=tracing",
+		"--clickhouse.username=jaeger",
+		"--clickhouse.password=secret",
+		"--clickhouse.timeout=10s",
+		"--clickhouse.spans-table=spans",
+		"--clickhouse.operations-table=operations",
+		"--clickhouse.create-schema=false",
+		"--clickhouse.ttl=168h",
+		"--clickhouse.batch-size=500",
+		"--clickhouse.batch-flush-interval=1s",
+		"--clickhouse.tags-as-columns=http.status_code, error,",
+		"--clickhouse.tls.enabled=true",
+	})
+	opts.InitFromViper(v)
+
+	cfg := opts.Configuration
+	assert.Equal(t, "https://clickhouse:8443", cfg.ServerURL)
+	assert.Equal(t, "tracing", cfg.Database)
+	assert.Equal(t, "jaeger", cfg.Username)
+	assert.Equal(t, "secret", cfg.Password)
+	assert.Equal(t, 10*time.Second, cfg.Timeout)
+	assert.Equal(t, "spans", cfg.SpansTable)
+	assert.Equal(t, "operations", cfg.OperationsTable)
+	assert.False(t, cfg.CreateSchema)
+	assert.Equal(t, 168*time.Hour, cfg.TTL)
+	assert.Equal(t, 500, cfg.BatchSize)
+	assert.Equal(t, time.Second, cfg.BatchFlushInterval)
+	assert.Equal(t, []string{"http.status_code", "error"}, cfg.TagsAsColumns)
+	assert.True(t, cfg.TLS.Enabled)
+}
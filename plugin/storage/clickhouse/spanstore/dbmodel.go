@@ -0,0 +1,100 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/jsonpb"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+const timestampFormat = "2006-01-02 15:04:05.000000"
+
+// spanRow is a row of the spans table. The span itself is stored in the model column,
+// the other columns index it.
+type spanRow struct {
+	Timestamp    string   `json:"timestamp"`
+	TraceID      string   `json:"trace_id"`
+	SpanID       string   `json:"span_id"`
+	ParentSpanID string   `json:"parent_span_id"`
+	Service      string   `json:"service"`
+	Operation    string   `json:"operation"`
+	SpanKind     string   `json:"span_kind"`
+	Duration     uint64   `json:"duration"`
+	TagKeys      []string `json:"tag_keys"`
+	TagValues    []string `json:"tag_values"`
+	Model        string   `json:"model"`
+}
+
+// traceIDString returns the trace ID always on 32 hexadecimal digits, unlike TraceID.String.
+func traceIDString(traceID model.TraceID) string {
+	return fmt.Sprintf("%016x%016x", traceID.High, traceID.Low)
+}
+
+// formatTimestamp returns the timestamp in the format of the DateTime64 columns in UTC.
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(timestampFormat)
+}
+
+func fromDomain(span *model.Span) (spanRow, error) {
+	var buf bytes.Buffer
+	if err := new(jsonpb.Marshaler).Marshal(&buf, span); err != nil {
+		return spanRow{}, err
+	}
+	row := spanRow{
+		Timestamp: formatTimestamp(span.StartTime),
+		TraceID:   traceIDString(span.TraceID),
+		SpanID:    span.SpanID.String(),
+		Operation: span.OperationName,
+		Duration:  uint64(span.Duration / time.Microsecond),
+		Model:     buf.String(),
+	}
+	if parentID := span.ParentSpanID(); parentID != 0 {
+		row.ParentSpanID = parentID.String()
+	}
+	row.SpanKind, _ = span.GetSpanKind()
+	// the span tags come first, they are the values of the materialized columns when the key is repeated
+	addTags := func(kvs model.KeyValues) {
+		for _, kv := range kvs {
+			row.TagKeys = append(row.TagKeys, kv.Key)
+			row.TagValues = append(row.TagValues, kv.AsString())
+		}
+	}
+	addTags(span.Tags)
+	if span.Process != nil {
+		row.Service = span.Process.ServiceName
+		addTags(span.Process.Tags)
+	}
+	for _, log := range span.Logs {
+		addTags(log.Fields)
+	}
+	if row.TagKeys == nil {
+		row.TagKeys, row.TagValues = []string{}, []string{}
+	}
+	return row, nil
+}
+
+func toDomain(m string) (*model.Span, error) {
+	span := &model.Span{}
+	if err := jsonpb.Unmarshal(strings.NewReader(m), span); err != nil {
+		return nil, fmt.Errorf("cannot decode the span: %w", err)
+	}
+	return span, nil
+}
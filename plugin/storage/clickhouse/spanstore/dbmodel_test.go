@@ -0,0 +1,78 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+func testSpan(traceID model.TraceID, spanID model.SpanID, service string) *model.Span {
+	return &model.Span{
+		TraceID:       traceID,
+		SpanID:        spanID,
+		OperationName: "op",
+		References:    []model.SpanRef{model.NewChildOfRef(traceID, 1)},
+		StartTime:     time.Date(2020, 9, 13, 12, 26, 40, 123456000, time.UTC),
+		Duration:      1500 * time.Microsecond,
+		Tags:          model.KeyValues{model.String("span.kind", "server"), model.Int64("http.status_code", 200)},
+		Process:       model.NewProcess(service, []model.KeyValue{model.String("hostname", "host1")}),
+		Logs: []model.Log{{
+			Timestamp: time.Date(2020, 9, 13, 12, 26, 40, 0, time.UTC),
+			Fields:    model.KeyValues{model.String("event", "retry")},
+		}},
+	}
+}
+
+func TestFromDomain(t *testing.T) {
+	span := testSpan(model.NewTraceID(0, 0x10), 2, "svc")
+	row, err := fromDomain(span)
+	require.NoError(t, err)
+	assert.Equal(t, "2020-09-13 12:26:40.123456", row.Timestamp)
+	assert.Equal(t, "00000000000000000000000000000010", row.TraceID)
+	assert.Equal(t, "0000000000000002", row.SpanID)
+	assert.Equal(t, "0000000000000001", row.ParentSpanID)
+	assert.Equal(t, "svc", row.Service)
+	assert.Equal(t, "op", row.Operation)
+	assert.Equal(t, "server", row.SpanKind)
+	assert.Equal(t, uint64(1500), row.Duration)
+	assert.Equal(t, []string{"span.kind", "http.status_code", "hostname", "event"}, row.TagKeys)
+	assert.Equal(t, []string{"server", "200", "host1", "retry"}, row.TagValues)
+
+	decoded, err := toDomain(row.Model)
+	require.NoError(t, err)
+	assert.Equal(t, span, decoded)
+}
+
+func TestFromDomainRootSpan(t *testing.T) {
+	span := &model.Span{TraceID: model.NewTraceID(1, 2), SpanID: 1, StartTime: time.Unix(0, 0)}
+	row, err := fromDomain(span)
+	require.NoError(t, err)
+	assert.Equal(t, "00000000000000010000000000000002", row.TraceID)
+	assert.Empty(t, row.ParentSpanID)
+	assert.Empty(t, row.Service)
+	assert.Equal(t, []string{}, row.TagKeys)
+	assert.Equal(t, []string{}, row.TagValues)
+}
+
+func TestToDomainError(t *testing.T) {
+	_, err := toDomain("{")
+	assert.Error(t, err)
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// fakeClient records the statements and returns the rows of the queries in order.
+type fakeClient struct {
+	mux      sync.Mutex
+	execs    []string
+	inserts  [][]interface{}
+	queries  []string
+	params   []map[string]string
+	results  [][]string
+	inserted chan struct{}
+	err      error
+}
+
+func (c *fakeClient) Exec(ctx context.Context, query string, params map[string]string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.execs = append(c.execs, query)
+	return c.err
+}
+
+func (c *fakeClient) Insert(ctx context.Context, table string, rows []interface{}) error {
+	c.mux.Lock()
+	c.inserts = append(c.inserts, rows)
+	c.mux.Unlock()
+	if c.inserted != nil {
+		c.inserted <- struct{}{}
+	}
+	return c.err
+}
+
+func (c *fakeClient) Query(ctx context.Context, query string, params map[string]string, row func(json.RawMessage) error) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.queries = append(c.queries, query)
+	c.params = append(c.params, params)
+	if c.err != nil {
+		return c.err
+	}
+	if len(c.results) == 0 {
+		return nil
+	}
+	result := c.results[0]
+	c.results = c.results[1:]
+	for _, r := range result {
+		if err := row(json.RawMessage(r)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
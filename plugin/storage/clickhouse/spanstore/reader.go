@@ -0,0 +1,303 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/clickhouse"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+const defaultNumTraces = 100
+
+var (
+	// ErrMalformedRequestObject occurs when a request object is nil
+	ErrMalformedRequestObject = errors.New("malformed request object")
+
+	// ErrStartAndEndTimeNotSet occurs when start time and end time are not set
+	ErrStartAndEndTimeNotSet = errors.New("start and End Time must be set")
+
+	// ErrStartTimeMinGreaterThanMax occurs when start time min is above start time max
+	ErrStartTimeMinGreaterThanMax = errors.New("start Time Minimum is above Maximum")
+
+	// ErrDurationMinGreaterThanMax occurs when duration min is above duration max
+	ErrDurationMinGreaterThanMax = errors.New("duration Minimum is above Maximum")
+)
+
+// SpanReader can query for and load traces from ClickHouse.
+type SpanReader struct {
+	client          clickhouse.Client
+	spansTable      string
+	operationsTable string
+	// tagColumns are the materialized columns of the tags by key
+	tagColumns map[string]string
+}
+
+// NewSpanReader returns a new SpanReader of the tables of the schema.
+func NewSpanReader(client clickhouse.Client, schema Schema) *SpanReader {
+	tagColumns := make(map[string]string, len(schema.TagsAsColumns))
+	for _, key := range schema.TagsAsColumns {
+		tagColumns[key] = TagColumn(key)
+	}
+	return &SpanReader{
+		client:          client,
+		spansTable:      schema.SpansTable,
+		operationsTable: schema.OperationsTable,
+		tagColumns:      tagColumns,
+	}
+}
+
+// GetTrace takes a traceID and returns a Trace associated with that traceID
+func (r *SpanReader) GetTrace(ctx context.Context, traceID model.TraceID) (*model.Trace, error) {
+	traces, err := r.readTraces(ctx, []model.TraceID{traceID})
+	if err != nil {
+		return nil, err
+	}
+	if len(traces) == 0 {
+		return nil, spanstore.ErrTraceNotFound
+	}
+	return traces[0], nil
+}
+
+// GetServices returns all services traced by Jaeger
+func (r *SpanReader) GetServices(ctx context.Context) ([]string, error) {
+	var services []string
+	err := r.client.Query(ctx, "SELECT DISTINCT service FROM "+r.operationsTable+" ORDER BY service", nil,
+		func(row json.RawMessage) error {
+			var service struct {
+				Service string `json:"service"`
+			}
+			if err := json.Unmarshal(row, &service); err != nil {
+				return err
+			}
+			services = append(services, service.Service)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+// GetOperations returns all operations for a specific service traced by Jaeger
+func (r *SpanReader) GetOperations(ctx context.Context, query spanstore.OperationQueryParameters) ([]spanstore.Operation, error) {
+	q := newSelect()
+	q.where("service = " + q.param(query.ServiceName, "String"))
+	if query.SpanKind != "" {
+		q.where("span_kind = " + q.param(query.SpanKind, "String"))
+	}
+	operations := []spanstore.Operation{}
+	err := r.client.Query(ctx,
+		"SELECT DISTINCT operation, span_kind FROM "+r.operationsTable+q.whereClause()+" ORDER BY operation, span_kind",
+		q.params, func(row json.RawMessage) error {
+			var operation struct {
+				Operation string `json:"operation"`
+				SpanKind  string `json:"span_kind"`
+			}
+			if err := json.Unmarshal(row, &operation); err != nil {
+				return err
+			}
+			operations = append(operations, spanstore.Operation{Name: operation.Operation, SpanKind: operation.SpanKind})
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return operations, nil
+}
+
+// FindTraces retrieves traces that match the traceQuery
+func (r *SpanReader) FindTraces(ctx context.Context, query *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
+	traceIDs, err := r.FindTraceIDs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return r.readTraces(ctx, traceIDs)
+}
+
+// FindTraceIDs retrieves the IDs of the traces that match the traceQuery, the most recent first.
+// The equality and existence tag filters are run by ClickHouse, the others by the query service.
+func (r *SpanReader) FindTraceIDs(ctx context.Context, query *spanstore.TraceQueryParameters) ([]model.TraceID, error) {
+	if err := validateQuery(query); err != nil {
+		return nil, err
+	}
+	q := newSelect()
+	q.where("timestamp >= fromUnixTimestamp64Micro(" + q.param(micros(query.StartTimeMin), "Int64") + ")")
+	q.where("timestamp <= fromUnixTimestamp64Micro(" + q.param(micros(query.StartTimeMax), "Int64") + ")")
+	if query.ServiceName != "" {
+		q.where("service = " + q.param(query.ServiceName, "String"))
+	}
+	if query.OperationName != "" {
+		q.where("operation = " + q.param(query.OperationName, "String"))
+	}
+	if query.DurationMin != 0 {
+		q.where("duration >= " + q.param(strconv.FormatInt(int64(query.DurationMin/time.Microsecond), 10), "UInt64"))
+	}
+	if query.DurationMax != 0 {
+		q.where("duration <= " + q.param(strconv.FormatInt(int64(query.DurationMax/time.Microsecond), 10), "UInt64"))
+	}
+	// sorted for the queries to be deterministic
+	keys := make([]string, 0, len(query.Tags))
+	for key := range query.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		r.whereTagEquals(q, key, query.Tags[key])
+	}
+	for _, filter := range query.TagFilters {
+		switch filter.Operator {
+		case spanstore.TagEquals:
+			r.whereTagEquals(q, filter.Key, filter.Value)
+		case spanstore.TagExists:
+			q.where("has(tag_keys, " + q.param(filter.Key, "String") + ")")
+		}
+	}
+	numTraces := query.NumTraces
+	if numTraces <= 0 {
+		numTraces = defaultNumTraces
+	}
+	statement := "SELECT trace_id FROM " + r.spansTable + q.whereClause() +
+		" GROUP BY trace_id ORDER BY max(timestamp) DESC LIMIT " + q.param(strconv.Itoa(numTraces), "UInt64")
+	var traceIDs []model.TraceID
+	err := r.client.Query(ctx, statement, q.params, func(row json.RawMessage) error {
+		var trace struct {
+			TraceID string `json:"trace_id"`
+		}
+		if err := json.Unmarshal(row, &trace); err != nil {
+			return err
+		}
+		traceID, err := model.TraceIDFromString(trace.TraceID)
+		if err != nil {
+			return err
+		}
+		traceIDs = append(traceIDs, traceID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return traceIDs, nil
+}
+
+func (r *SpanReader) whereTagEquals(q *selectQuery, key, value string) {
+	if column, ok := r.tagColumns[key]; ok {
+		q.where(column + " = " + q.param(value, "String"))
+		return
+	}
+	q.where("arrayExists((k, v) -> k = " + q.param(key, "String") + " AND v = " + q.param(value, "String") +
+		", tag_keys, tag_values)")
+}
+
+// readTraces returns the traces found in the order of their IDs.
+func (r *SpanReader) readTraces(ctx context.Context, traceIDs []model.TraceID) ([]*model.Trace, error) {
+	if len(traceIDs) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, len(traceIDs))
+	for i, traceID := range traceIDs {
+		ids[i] = quote(traceIDString(traceID))
+	}
+	q := newSelect()
+	q.where("trace_id IN " + q.param("["+strings.Join(ids, ",")+"]", "Array(String)"))
+	traces := make(map[model.TraceID]*model.Trace, len(traceIDs))
+	err := r.client.Query(ctx, "SELECT model FROM "+r.spansTable+q.whereClause(), q.params,
+		func(row json.RawMessage) error {
+			var span struct {
+				Model string `json:"model"`
+			}
+			if err := json.Unmarshal(row, &span); err != nil {
+				return err
+			}
+			s, err := toDomain(span.Model)
+			if err != nil {
+				return err
+			}
+			trace, ok := traces[s.TraceID]
+			if !ok {
+				trace = &model.Trace{}
+				traces[s.TraceID] = trace
+			}
+			trace.Spans = append(trace.Spans, s)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*model.Trace, 0, len(traces))
+	for _, traceID := range traceIDs {
+		if trace, ok := traces[traceID]; ok {
+			result = append(result, trace)
+		}
+	}
+	return result, nil
+}
+
+func validateQuery(p *spanstore.TraceQueryParameters) error {
+	if p == nil {
+		return ErrMalformedRequestObject
+	}
+	if p.StartTimeMin.IsZero() || p.StartTimeMax.IsZero() {
+		return ErrStartAndEndTimeNotSet
+	}
+	if p.StartTimeMax.Before(p.StartTimeMin) {
+		return ErrStartTimeMinGreaterThanMax
+	}
+	if p.DurationMin != 0 && p.DurationMax != 0 && p.DurationMin > p.DurationMax {
+		return ErrDurationMinGreaterThanMax
+	}
+	return nil
+}
+
+func micros(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano()/int64(time.Microsecond), 10)
+}
+
+// selectQuery builds the WHERE clause of a query and its parameters.
+type selectQuery struct {
+	conditions []string
+	params     map[string]string
+}
+
+func newSelect() *selectQuery {
+	return &selectQuery{params: make(map[string]string)}
+}
+
+// param adds a parameter and returns its placeholder.
+func (q *selectQuery) param(value, typ string) string {
+	name := fmt.Sprintf("p%d", len(q.params))
+	q.params[name] = value
+	return "{" + name + ":" + typ + "}"
+}
+
+func (q *selectQuery) where(condition string) {
+	q.conditions = append(q.conditions, condition)
+}
+
+func (q *selectQuery) whereClause() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(q.conditions, " AND ")
+}
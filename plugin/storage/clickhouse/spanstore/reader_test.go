@@ -0,0 +1,225 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+var testSchema = Schema{
+	SpansTable:      "spans",
+	OperationsTable: "operations",
+	TagsAsColumns:   []string{"http.status_code"},
+}
+
+func modelRow(t *testing.T, span *model.Span) string {
+	row, err := fromDomain(span)
+	require.NoError(t, err)
+	data, err := json.Marshal(map[string]string{"model": row.Model})
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestGetTrace(t *testing.T) {
+	traceID := model.NewTraceID(0, 1)
+	client := &fakeClient{results: [][]string{{
+		modelRow(t, testSpan(traceID, 1, "a")),
+		modelRow(t, testSpan(traceID, 2, "b")),
+	}}}
+	reader := NewSpanReader(client, testSchema)
+	trace, err := reader.GetTrace(context.Background(), traceID)
+	require.NoError(t, err)
+	assert.Len(t, trace.Spans, 2)
+	assert.Equal(t, "SELECT model FROM spans WHERE trace_id IN {p0:Array(String)}", client.queries[0])
+	assert.Equal(t, map[string]string{"p0": "['00000000000000000000000000000001']"}, client.params[0])
+
+	_, err = reader.GetTrace(context.Background(), traceID)
+	assert.Equal(t, spanstore.ErrTraceNotFound, err)
+}
+
+func TestGetTraceErrors(t *testing.T) {
+	reader := NewSpanReader(&fakeClient{err: assert.AnError}, testSchema)
+	_, err := reader.GetTrace(context.Background(), model.NewTraceID(0, 1))
+	assert.Equal(t, assert.AnError, err)
+
+	reader = NewSpanReader(&fakeClient{results: [][]string{{`{"model":"{"}`}}}, testSchema)
+	_, err = reader.GetTrace(context.Background(), model.NewTraceID(0, 1))
+	assert.Error(t, err)
+
+	reader = NewSpanReader(&fakeClient{results: [][]string{{`[`}}}, testSchema)
+	_, err = reader.GetTrace(context.Background(), model.NewTraceID(0, 1))
+	assert.Error(t, err)
+}
+
+func TestGetServices(t *testing.T) {
+	client := &fakeClient{results: [][]string{{`{"service":"a"}`, `{"service":"b"}`}}}
+	reader := NewSpanReader(client, testSchema)
+	services, err := reader.GetServices(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, services)
+	assert.Equal(t, "SELECT DISTINCT service FROM operations ORDER BY service", client.queries[0])
+
+	reader = NewSpanReader(&fakeClient{err: assert.AnError}, testSchema)
+	_, err = reader.GetServices(context.Background())
+	assert.Equal(t, assert.AnError, err)
+	reader = NewSpanReader(&fakeClient{results: [][]string{{`[`}}}, testSchema)
+	_, err = reader.GetServices(context.Background())
+	assert.Error(t, err)
+}
+
+func TestGetOperations(t *testing.T) {
+	client := &fakeClient{results: [][]string{
+		{`{"operation":"get","span_kind":"server"}`},
+		{},
+	}}
+	reader := NewSpanReader(client, testSchema)
+	operations, err := reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "a"})
+	require.NoError(t, err)
+	assert.Equal(t, []spanstore.Operation{{Name: "get", SpanKind: "server"}}, operations)
+	assert.Equal(t, "SELECT DISTINCT operation, span_kind FROM operations WHERE service = {p0:String} ORDER BY operation, span_kind", client.queries[0])
+
+	operations, err = reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "a", SpanKind: "client"})
+	require.NoError(t, err)
+	assert.Empty(t, operations)
+	assert.Equal(t, "SELECT DISTINCT operation, span_kind FROM operations WHERE service = {p0:String} AND span_kind = {p1:String} ORDER BY operation, span_kind", client.queries[1])
+	assert.Equal(t, map[string]string{"p0": "a", "p1": "client"}, client.params[1])
+
+	reader = NewSpanReader(&fakeClient{err: assert.AnError}, testSchema)
+	_, err = reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "a"})
+	assert.Equal(t, assert.AnError, err)
+	reader = NewSpanReader(&fakeClient{results: [][]string{{`[`}}}, testSchema)
+	_, err = reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "a"})
+	assert.Error(t, err)
+}
+
+func TestFindTraceIDs(t *testing.T) {
+	client := &fakeClient{results: [][]string{{
+		`{"trace_id":"00000000000000000000000000000002"}`,
+		`{"trace_id":"00000000000000010000000000000001"}`,
+	}}}
+	reader := NewSpanReader(client, testSchema)
+	filter, err := spanstore.ParseTagFilter("error")
+	require.NoError(t, err)
+	equals, err := spanstore.ParseTagFilter("peer.service=db")
+	require.NoError(t, err)
+	regex, err := spanstore.ParseTagFilter("http.url=~.*api.*")
+	require.NoError(t, err)
+	traceIDs, err := reader.FindTraceIDs(context.Background(), &spanstore.TraceQueryParameters{
+		ServiceName:   "svc",
+		OperationName: "op",
+		Tags:          map[string]string{"http.status_code": "500", "component": "http"},
+		TagFilters:    []spanstore.TagFilter{filter, equals, regex},
+		StartTimeMin:  time.Unix(1, 0),
+		StartTimeMax:  time.Unix(2, 0),
+		DurationMin:   time.Millisecond,
+		DurationMax:   time.Second,
+		NumTraces:     10,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []model.TraceID{model.NewTraceID(0, 2), model.NewTraceID(1, 1)}, traceIDs)
+	assert.Equal(t, "SELECT trace_id FROM spans WHERE "+
+		"timestamp >= fromUnixTimestamp64Micro({p0:Int64}) AND timestamp <= fromUnixTimestamp64Micro({p1:Int64}) AND "+
+		"service = {p2:String} AND operation = {p3:String} AND duration >= {p4:UInt64} AND duration <= {p5:UInt64} AND "+
+		"arrayExists((k, v) -> k = {p6:String} AND v = {p7:String}, tag_keys, tag_values) AND "+
+		"tag_http_status_code = {p8:String} AND has(tag_keys, {p9:String}) AND "+
+		"arrayExists((k, v) -> k = {p10:String} AND v = {p11:String}, tag_keys, tag_values) "+
+		"GROUP BY trace_id ORDER BY max(timestamp) DESC LIMIT {p12:UInt64}", client.queries[0])
+	assert.Equal(t, map[string]string{
+		"p0": "1000000", "p1": "2000000", "p2": "svc", "p3": "op", "p4": "1000", "p5": "1000000",
+		"p6": "component", "p7": "http", "p8": "500", "p9": "error", "p10": "peer.service", "p11": "db", "p12": "10",
+	}, client.params[0])
+}
+
+func TestFindTraceIDsDefaultLimit(t *testing.T) {
+	client := &fakeClient{}
+	reader := NewSpanReader(client, testSchema)
+	traceIDs, err := reader.FindTraceIDs(context.Background(), &spanstore.TraceQueryParameters{
+		StartTimeMin: time.Unix(1, 0),
+		StartTimeMax: time.Unix(2, 0),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, traceIDs)
+	assert.Equal(t, "100", client.params[0]["p2"])
+}
+
+func TestFindTraceIDsErrors(t *testing.T) {
+	query := &spanstore.TraceQueryParameters{StartTimeMin: time.Unix(1, 0), StartTimeMax: time.Unix(2, 0)}
+	reader := NewSpanReader(&fakeClient{err: assert.AnError}, testSchema)
+	_, err := reader.FindTraceIDs(context.Background(), query)
+	assert.Equal(t, assert.AnError, err)
+	reader = NewSpanReader(&fakeClient{results: [][]string{{`[`}}}, testSchema)
+	_, err = reader.FindTraceIDs(context.Background(), query)
+	assert.Error(t, err)
+	reader = NewSpanReader(&fakeClient{results: [][]string{{`{"trace_id":"xyz"}`}}}, testSchema)
+	_, err = reader.FindTraceIDs(context.Background(), query)
+	assert.Error(t, err)
+	_, err = reader.FindTraces(context.Background(), nil)
+	assert.Equal(t, ErrMalformedRequestObject, err)
+}
+
+func TestValidateQuery(t *testing.T) {
+	start, end := time.Unix(1, 0), time.Unix(2, 0)
+	for _, test := range []struct {
+		query *spanstore.TraceQueryParameters
+		err   error
+	}{
+		{nil, ErrMalformedRequestObject},
+		{&spanstore.TraceQueryParameters{StartTimeMin: start}, ErrStartAndEndTimeNotSet},
+		{&spanstore.TraceQueryParameters{StartTimeMin: end, StartTimeMax: start}, ErrStartTimeMinGreaterThanMax},
+		{&spanstore.TraceQueryParameters{StartTimeMin: start, StartTimeMax: end, DurationMin: time.Second, DurationMax: time.Millisecond}, ErrDurationMinGreaterThanMax},
+		{&spanstore.TraceQueryParameters{StartTimeMin: start, StartTimeMax: end}, nil},
+	} {
+		assert.Equal(t, test.err, validateQuery(test.query))
+	}
+}
+
+func TestFindTraces(t *testing.T) {
+	first, second := model.NewTraceID(0, 1), model.NewTraceID(0, 2)
+	client := &fakeClient{results: [][]string{
+		{`{"trace_id":"00000000000000000000000000000002"}`, `{"trace_id":"00000000000000000000000000000001"}`},
+		{modelRow(t, testSpan(first, 1, "a")), modelRow(t, testSpan(second, 1, "a")), modelRow(t, testSpan(first, 2, "b"))},
+	}}
+	reader := NewSpanReader(client, testSchema)
+	traces, err := reader.FindTraces(context.Background(), &spanstore.TraceQueryParameters{
+		StartTimeMin: time.Unix(1, 0),
+		StartTimeMax: time.Unix(2, 0),
+	})
+	require.NoError(t, err)
+	require.Len(t, traces, 2)
+	// the most recent trace first, as found
+	assert.Equal(t, second, traces[0].Spans[0].TraceID)
+	assert.Len(t, traces[0].Spans, 1)
+	assert.Len(t, traces[1].Spans, 2)
+	assert.Equal(t, map[string]string{
+		"p0": "['00000000000000000000000000000002','00000000000000000000000000000001']",
+	}, client.params[1])
+
+	traces, err = reader.FindTraces(context.Background(), &spanstore.TraceQueryParameters{
+		StartTimeMin: time.Unix(1, 0),
+		StartTimeMax: time.Unix(2, 0),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, traces)
+	assert.Len(t, client.queries, 3)
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jaegertracing/jaeger/pkg/clickhouse"
+)
+
+var invalidColumnChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// Schema describes the tables of the spans and of the operations of the services.
+//
+// The spans table is partitioned by day and the spans expire after the TTL. The operations table
+// is filled by a materialized view of the spans table, the services and operations are read from it.
+type Schema struct {
+	SpansTable      string
+	OperationsTable string
+	// TTL is the retention of the spans, they are kept forever if it is zero.
+	TTL time.Duration
+	// TagsAsColumns are the keys of the tags stored in materialized columns, holding the first value of the key.
+	TagsAsColumns []string
+}
+
+// TagColumn returns the name of the materialized column of the tag key.
+func TagColumn(key string) string {
+	return "tag_" + invalidColumnChars.ReplaceAllString(key, "_")
+}
+
+// quote returns the string literal of the value.
+func quote(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// Statements returns the statements creating the tables, they can run on an existing schema.
+func (s Schema) Statements() []string {
+	var spansTTL, operationsTTL string
+	if s.TTL > 0 {
+		seconds := int64(s.TTL / time.Second)
+		spansTTL = fmt.Sprintf("\nTTL toDateTime(timestamp) + toIntervalSecond(%d)", seconds)
+		operationsTTL = fmt.Sprintf("\nTTL toDateTime(date) + toIntervalSecond(%d)", seconds)
+	}
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS ` + s.SpansTable + ` (
+	timestamp DateTime64(6, 'UTC') CODEC(Delta, ZSTD(1)),
+	trace_id String CODEC(ZSTD(1)),
+	span_id String CODEC(ZSTD(1)),
+	parent_span_id String CODEC(ZSTD(1)),
+	service LowCardinality(String) CODEC(ZSTD(1)),
+	operation LowCardinality(String) CODEC(ZSTD(1)),
+	span_kind LowCardinality(String) CODEC(ZSTD(1)),
+	duration UInt64 CODEC(ZSTD(1)),
+	tag_keys Array(String) CODEC(ZSTD(1)),
+	tag_values Array(String) CODEC(ZSTD(1)),
+	model String CODEC(ZSTD(3)),
+	INDEX idx_trace_id trace_id TYPE bloom_filter(0.001) GRANULARITY 1,
+	INDEX idx_duration duration TYPE minmax GRANULARITY 1
+) ENGINE = MergeTree()
+PARTITION BY toDate(timestamp)
+ORDER BY (service, operation, toStartOfHour(timestamp), trace_id)` + spansTTL,
+		`CREATE TABLE IF NOT EXISTS ` + s.OperationsTable + ` (
+	date Date,
+	service LowCardinality(String),
+	operation LowCardinality(String),
+	span_kind LowCardinality(String),
+	count UInt64
+) ENGINE = SummingMergeTree(count)
+PARTITION BY toYYYYMM(date)
+ORDER BY (service, span_kind, operation, date)` + operationsTTL,
+		`CREATE MATERIALIZED VIEW IF NOT EXISTS ` + s.OperationsTable + `_mv TO ` + s.OperationsTable + `
+AS SELECT toDate(timestamp) AS date, service, operation, span_kind, count() AS count
+FROM ` + s.SpansTable + `
+GROUP BY date, service, operation, span_kind`,
+	}
+	for _, key := range s.TagsAsColumns {
+		statements = append(statements, fmt.Sprintf(
+			"ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s String MATERIALIZED tag_values[indexOf(tag_keys, %s)]",
+			s.SpansTable, TagColumn(key), quote(key)))
+	}
+	return statements
+}
+
+// CreateSchema runs the statements of the schema.
+func CreateSchema(ctx context.Context, client clickhouse.Client, schema Schema) error {
+	for _, statement := range schema.Statements() {
+		if err := client.Exec(ctx, statement, nil); err != nil {
+			return fmt.Errorf("cannot create the ClickHouse schema: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagColumn(t *testing.T) {
+	assert.Equal(t, "tag_http_status_code", TagColumn("http.status_code"))
+	assert.Equal(t, "tag_a_b_c", TagColumn("a'b c"))
+}
+
+func TestQuote(t *testing.T) {
+	assert.Equal(t, `'it\'s a \\ test'`, quote(`it's a \ test`))
+}
+
+func TestSchemaStatements(t *testing.T) {
+	schema := Schema{
+		SpansTable:      "spans",
+		OperationsTable: "operations",
+		TTL:             48 * time.Hour,
+		TagsAsColumns:   []string{"http.status_code"},
+	}
+	statements := schema.Statements()
+	require.Len(t, statements, 4)
+	assert.True(t, strings.HasPrefix(statements[0], "CREATE TABLE IF NOT EXISTS spans ("))
+	assert.Contains(t, statements[0], "PARTITION BY toDate(timestamp)")
+	assert.Contains(t, statements[0], "TTL toDateTime(timestamp) + toIntervalSecond(172800)")
+	assert.True(t, strings.HasPrefix(statements[1], "CREATE TABLE IF NOT EXISTS operations ("))
+	assert.Contains(t, statements[1], "TTL toDateTime(date) + toIntervalSecond(172800)")
+	assert.Contains(t, statements[2], "CREATE MATERIALIZED VIEW IF NOT EXISTS operations_mv TO operations")
+	assert.Equal(t, "ALTER TABLE spans ADD COLUMN IF NOT EXISTS tag_http_status_code String "+
+		"MATERIALIZED tag_values[indexOf(tag_keys, 'http.status_code')]", statements[3])
+
+	schema.TTL = 0
+	schema.TagsAsColumns = nil
+	statements = schema.Statements()
+	require.Len(t, statements, 3)
+	assert.NotContains(t, statements[0], "TTL")
+	assert.NotContains(t, statements[1], "TTL")
+}
+
+func TestCreateSchema(t *testing.T) {
+	client := &fakeClient{}
+	schema := Schema{SpansTable: "spans", OperationsTable: "operations"}
+	require.NoError(t, CreateSchema(context.Background(), client, schema))
+	assert.Equal(t, schema.Statements(), client.execs)
+
+	client = &fakeClient{err: assert.AnError}
+	err := CreateSchema(context.Background(), client, schema)
+	assert.EqualError(t, err, "cannot create the ClickHouse schema: "+assert.AnError.Error())
+	assert.Len(t, client.execs, 1)
+}
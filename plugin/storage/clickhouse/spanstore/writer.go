@@ -0,0 +1,136 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/clickhouse"
+	storageMetrics "github.com/jaegertracing/jaeger/storage/spanstore/metrics"
+)
+
+// SpanWriterParams holds constructor parameters for NewSpanWriter
+type SpanWriterParams struct {
+	Client         clickhouse.Client
+	Logger         *zap.Logger
+	MetricsFactory metrics.Factory
+	SpansTable     string
+	// BatchSize is the number of spans per insert, ClickHouse performs best with large and infrequent inserts.
+	BatchSize int
+	// BatchFlushInterval is the maximum delay of the insert of a span.
+	BatchFlushInterval time.Duration
+}
+
+// SpanWriter inserts the spans into ClickHouse in batches.
+type SpanWriter struct {
+	client     clickhouse.Client
+	logger     *zap.Logger
+	table      string
+	batchSize  int
+	metrics    *storageMetrics.WriteMetrics
+	mux        sync.Mutex
+	batch      []interface{}
+	done       chan struct{}
+	closeOnce  sync.Once
+	background sync.WaitGroup
+}
+
+// NewSpanWriter creates a SpanWriter and starts the periodic flushes of its batch.
+func NewSpanWriter(p SpanWriterParams) *SpanWriter {
+	w := &SpanWriter{
+		client:    p.Client,
+		logger:    p.Logger,
+		table:     p.SpansTable,
+		batchSize: p.BatchSize,
+		metrics:   storageMetrics.NewWriteMetrics(p.MetricsFactory, "batch_insert"),
+		done:      make(chan struct{}),
+	}
+	w.background.Add(1)
+	go w.flushPeriodically(p.BatchFlushInterval)
+	return w
+}
+
+// WriteSpan adds the span to the batch, inserting the batch when it is full.
+func (w *SpanWriter) WriteSpan(span *model.Span) error {
+	row, err := fromDomain(span)
+	if err != nil {
+		return err
+	}
+	w.mux.Lock()
+	w.batch = append(w.batch, row)
+	var batch []interface{}
+	if len(w.batch) >= w.batchSize {
+		batch = w.takeBatch()
+	}
+	w.mux.Unlock()
+	if batch == nil {
+		return nil
+	}
+	return w.insert(batch)
+}
+
+// Close inserts the remaining spans and stops the periodic flushes.
+func (w *SpanWriter) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.done)
+		w.background.Wait()
+	})
+	w.mux.Lock()
+	batch := w.takeBatch()
+	w.mux.Unlock()
+	return w.insert(batch)
+}
+
+// takeBatch must be called with the lock held.
+func (w *SpanWriter) takeBatch() []interface{} {
+	batch := w.batch
+	w.batch = nil
+	return batch
+}
+
+func (w *SpanWriter) flushPeriodically(interval time.Duration) {
+	defer w.background.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.mux.Lock()
+			batch := w.takeBatch()
+			w.mux.Unlock()
+			if err := w.insert(batch); err != nil {
+				w.logger.Error("Failed to insert the spans into ClickHouse", zap.Int("spans", len(batch)), zap.Error(err))
+			}
+		}
+	}
+}
+
+func (w *SpanWriter) insert(batch []interface{}) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	start := time.Now()
+	err := w.client.Insert(context.Background(), w.table, batch)
+	w.metrics.Emit(err, time.Since(start))
+	return err
+}
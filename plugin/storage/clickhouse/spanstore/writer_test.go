@@ -0,0 +1,89 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+func newTestWriter(client *fakeClient, batchSize int, interval time.Duration) (*SpanWriter, *metricstest.Factory) {
+	metricsFactory := metricstest.NewFactory(0)
+	return NewSpanWriter(SpanWriterParams{
+		Client:             client,
+		Logger:             zap.NewNop(),
+		MetricsFactory:     metricsFactory,
+		SpansTable:         "spans",
+		BatchSize:          batchSize,
+		BatchFlushInterval: interval,
+	}), metricsFactory
+}
+
+func TestWriterInsertsFullBatches(t *testing.T) {
+	client := &fakeClient{}
+	writer, metricsFactory := newTestWriter(client, 2, time.Hour)
+	traceID := model.NewTraceID(0, 1)
+
+	require.NoError(t, writer.WriteSpan(testSpan(traceID, 1, "svc")))
+	assert.Empty(t, client.inserts)
+	require.NoError(t, writer.WriteSpan(testSpan(traceID, 2, "svc")))
+	require.Len(t, client.inserts, 1)
+	assert.Len(t, client.inserts[0], 2)
+	assert.Equal(t, "0000000000000002", client.inserts[0][1].(spanRow).SpanID)
+
+	require.NoError(t, writer.WriteSpan(testSpan(traceID, 3, "svc")))
+	require.NoError(t, writer.Close())
+	require.Len(t, client.inserts, 2)
+	assert.Len(t, client.inserts[1], 1)
+	// closing again does nothing
+	require.NoError(t, writer.Close())
+	assert.Len(t, client.inserts, 2)
+
+	metricsFactory.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "batch_insert.attempts", Value: 2},
+		metricstest.ExpectedMetric{Name: "batch_insert.inserts", Value: 2})
+}
+
+func TestWriterFlushesPeriodically(t *testing.T) {
+	client := &fakeClient{inserted: make(chan struct{}, 1)}
+	writer, _ := newTestWriter(client, 100, time.Millisecond)
+	defer writer.Close()
+
+	require.NoError(t, writer.WriteSpan(testSpan(model.NewTraceID(0, 1), 1, "svc")))
+	select {
+	case <-client.inserted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the batch was not inserted")
+	}
+	client.mux.Lock()
+	defer client.mux.Unlock()
+	assert.Len(t, client.inserts[0], 1)
+}
+
+func TestWriterInsertError(t *testing.T) {
+	client := &fakeClient{err: assert.AnError}
+	writer, metricsFactory := newTestWriter(client, 1, time.Hour)
+	defer writer.Close()
+
+	assert.Equal(t, assert.AnError, writer.WriteSpan(testSpan(model.NewTraceID(0, 1), 1, "svc")))
+	metricsFactory.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "batch_insert.errors", Value: 1})
+}
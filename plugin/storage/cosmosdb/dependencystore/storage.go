@@ -0,0 +1,86 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dependencystore
+
+import (
+	"context"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/cosmosdb"
+)
+
+// DependencyStore computes the dependencies of the services from the index of the spans of the time window.
+// The index is queried across its partitions, the cost of the dependencies grows with the size of the index.
+type DependencyStore struct {
+	client         cosmosdb.Client
+	indexContainer string
+}
+
+// NewDependencyStore returns a DependencyStore reading the index of the spans of the container.
+func NewDependencyStore(client cosmosdb.Client, indexContainer string) *DependencyStore {
+	return &DependencyStore{client: client, indexContainer: indexContainer}
+}
+
+type spanDocument struct {
+	TraceID      string `json:"traceID"`
+	SpanID       string `json:"spanID"`
+	ParentSpanID string `json:"parentSpanID"`
+	Service      string `json:"service"`
+}
+
+type spanKey struct {
+	traceID string
+	spanID  string
+}
+
+// GetDependencies returns the calls between the services in the time window ending at endTs.
+func (s *DependencyStore) GetDependencies(endTs time.Time, lookback time.Duration) ([]model.DependencyLink, error) {
+	query := cosmosdb.Query{
+		Query: "SELECT c.traceID, c.spanID, c.parentSpanID, c.service FROM c " +
+			"WHERE c.startTime >= @startTimeMin AND c.startTime <= @startTimeMax",
+		Parameters: []cosmosdb.Parameter{
+			{Name: "@startTimeMin", Value: micros(endTs.Add(-lookback))},
+			{Name: "@startTimeMax", Value: micros(endTs)},
+		},
+	}
+	var documents []spanDocument
+	if err := s.client.QueryAll(context.Background(), s.indexContainer, query, &documents); err != nil {
+		return nil, err
+	}
+	services := make(map[spanKey]string, len(documents))
+	for _, document := range documents {
+		services[spanKey{document.TraceID, document.SpanID}] = document.Service
+	}
+	links := make(map[[2]string]uint64)
+	for _, document := range documents {
+		if document.ParentSpanID == "" {
+			continue
+		}
+		parent, ok := services[spanKey{document.TraceID, document.ParentSpanID}]
+		if ok && parent != document.Service {
+			links[[2]string{parent, document.Service}]++
+		}
+	}
+	dependencies := make([]model.DependencyLink, 0, len(links))
+	for link, count := range links {
+		dependencies = append(dependencies, model.DependencyLink{Parent: link[0], Child: link[1], CallCount: count})
+	}
+	return dependencies, nil
+}
+
+func micros(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Microsecond)
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dependencystore
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/cosmosdb"
+)
+
+type fakeClient struct {
+	cosmosdb.Client
+	container string
+	query     cosmosdb.Query
+	documents []map[string]string
+	err       error
+}
+
+func (c *fakeClient) QueryAll(ctx context.Context, container string, query cosmosdb.Query, results interface{}) error {
+	c.container, c.query = container, query
+	if c.err != nil {
+		return c.err
+	}
+	data, err := json.Marshal(c.documents)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, results)
+}
+
+func span(traceID, spanID, parentSpanID, service string) map[string]string {
+	document := map[string]string{"traceID": traceID, "spanID": spanID, "service": service}
+	if parentSpanID != "" {
+		document["parentSpanID"] = parentSpanID
+	}
+	return document
+}
+
+func TestGetDependencies(t *testing.T) {
+	client := &fakeClient{documents: []map[string]string{
+		span("t1", "b", "a", "frontend"), span("t1", "a", "", "gateway"), span("t1", "c", "b", "db"),
+		// a call within a service and a parent out of the window
+		span("t2", "a", "", "gateway"), span("t2", "b", "a", "gateway"), span("t3", "b", "a", "db"),
+		span("t4", "a", "", "gateway"), span("t4", "b", "a", "frontend"),
+	}}
+	store := NewDependencyStore(client, "span_index")
+	dependencies, err := store.GetDependencies(time.Unix(100, 0), 10*time.Second)
+	require.NoError(t, err)
+	sort.Slice(dependencies, func(i, j int) bool { return dependencies[i].Parent < dependencies[j].Parent })
+	assert.Equal(t, []model.DependencyLink{
+		{Parent: "frontend", Child: "db", CallCount: 1},
+		{Parent: "gateway", Child: "frontend", CallCount: 2},
+	}, dependencies)
+	assert.Equal(t, "span_index", client.container)
+	assert.Equal(t, []cosmosdb.Parameter{
+		{Name: "@startTimeMin", Value: int64(90000000)},
+		{Name: "@startTimeMax", Value: int64(100000000)},
+	}, client.query.Parameters)
+}
+
+func TestGetDependenciesError(t *testing.T) {
+	store := NewDependencyStore(&fakeClient{err: assert.AnError}, "span_index")
+	_, err := store.GetDependencies(time.Unix(100, 0), time.Second)
+	assert.Equal(t, assert.AnError, err)
+}
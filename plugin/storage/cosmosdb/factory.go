@@ -0,0 +1,113 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosmosdb
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+
+	"github.com/spf13/viper"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/pkg/cosmosdb"
+	cosmosDepStore "github.com/jaegertracing/jaeger/plugin/storage/cosmosdb/dependencystore"
+	cosmosSpanStore "github.com/jaegertracing/jaeger/plugin/storage/cosmosdb/spanstore"
+	"github.com/jaegertracing/jaeger/storage/dependencystore"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+// Factory implements storage.Factory for Cosmos DB backend.
+type Factory struct {
+	Options *Options
+
+	metricsFactory metrics.Factory
+	logger         *zap.Logger
+	client         cosmosdb.Client
+
+	// newClient creates the client of Cosmos DB, it is replaced by the tests
+	newClient func(options *Options) (cosmosdb.Client, error)
+}
+
+// NewFactory creates a new Factory.
+func NewFactory() *Factory {
+	return &Factory{
+		Options:   NewOptions(),
+		newClient: newClient,
+	}
+}
+
+func newClient(options *Options) (cosmosdb.Client, error) {
+	if options.Endpoint == "" || options.Key == "" {
+		return nil, errors.New("the endpoint and the key of Cosmos DB must be set")
+	}
+	return cosmosdb.NewClient(options.Endpoint, options.Key, options.Database, &http.Client{Timeout: options.Timeout})
+}
+
+// AddFlags implements plugin.Configurable
+func (f *Factory) AddFlags(flagSet *flag.FlagSet) {
+	f.Options.AddFlags(flagSet)
+}
+
+// InitFromViper implements plugin.Configurable
+func (f *Factory) InitFromViper(v *viper.Viper) {
+	f.Options.InitFromViper(v)
+}
+
+// Initialize implements storage.Factory
+func (f *Factory) Initialize(metricsFactory metrics.Factory, logger *zap.Logger) error {
+	f.metricsFactory, f.logger = metricsFactory, logger
+	client, err := f.newClient(f.Options)
+	if err != nil {
+		return err
+	}
+	if f.Options.CreateContainers {
+		if err := cosmosSpanStore.CreateContainers(context.Background(), client, f.containers(), f.Options.TTL); err != nil {
+			return err
+		}
+	}
+	f.client = client
+	return nil
+}
+
+func (f *Factory) containers() cosmosSpanStore.Containers {
+	return cosmosSpanStore.Containers{
+		Spans:      f.Options.SpansContainer,
+		Index:      f.Options.IndexContainer,
+		Operations: f.Options.OperationsContainer,
+	}
+}
+
+// CreateSpanReader implements storage.Factory
+func (f *Factory) CreateSpanReader() (spanstore.Reader, error) {
+	return cosmosSpanStore.NewSpanReader(f.client, f.containers()), nil
+}
+
+// CreateSpanWriter implements storage.Factory
+func (f *Factory) CreateSpanWriter() (spanstore.Writer, error) {
+	return cosmosSpanStore.NewSpanWriter(cosmosSpanStore.SpanWriterParams{
+		Client:         f.client,
+		Containers:     f.containers(),
+		MetricsFactory: f.metricsFactory,
+		TTL:            f.Options.TTL,
+	}), nil
+}
+
+// CreateDependencyReader implements storage.Factory
+func (f *Factory) CreateDependencyReader() (dependencystore.Reader, error) {
+	return cosmosDepStore.NewDependencyStore(f.client, f.Options.IndexContainer), nil
+}
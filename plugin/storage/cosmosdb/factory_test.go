@@ -0,0 +1,95 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosmosdb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/pkg/config"
+	"github.com/jaegertracing/jaeger/pkg/cosmosdb"
+	"github.com/jaegertracing/jaeger/storage"
+)
+
+var _ storage.Factory = new(Factory)
+
+type fakeClient struct {
+	cosmosdb.Client
+	created []string
+	err     error
+}
+
+func (c *fakeClient) CreateDatabase(ctx context.Context) error {
+	return c.err
+}
+
+func (c *fakeClient) CreateContainer(ctx context.Context, container cosmosdb.Container) error {
+	c.created = append(c.created, container.ID)
+	return nil
+}
+
+func newTestFactory(t *testing.T, client *fakeClient, flags ...string) *Factory {
+	f := NewFactory()
+	v, command := config.Viperize(f.AddFlags)
+	command.ParseFlags(flags)
+	f.InitFromViper(v)
+	f.newClient = func(options *Options) (cosmosdb.Client, error) {
+		assert.Equal(t, f.Options, options)
+		return client, nil
+	}
+	return f
+}
+
+func TestFactory(t *testing.T) {
+	client := &fakeClient{}
+	f := newTestFactory(t, client, "--cosmosdb.spans-container=jaeger_spans")
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	assert.Equal(t, []string{"jaeger_spans", "span_index", "operations"}, client.created)
+
+	_, err := f.CreateSpanReader()
+	require.NoError(t, err)
+	_, err = f.CreateSpanWriter()
+	require.NoError(t, err)
+	_, err = f.CreateDependencyReader()
+	require.NoError(t, err)
+}
+
+func TestFactoryWithoutContainersCreation(t *testing.T) {
+	client := &fakeClient{}
+	f := newTestFactory(t, client, "--cosmosdb.create-containers=false")
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	assert.Empty(t, client.created)
+}
+
+func TestFactoryInitializeErrors(t *testing.T) {
+	f := newTestFactory(t, &fakeClient{err: assert.AnError})
+	assert.Error(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+
+	f = NewFactory()
+	assert.EqualError(t, f.Initialize(metrics.NullFactory, zap.NewNop()), "the endpoint and the key of Cosmos DB must be set")
+}
+
+func TestNewClient(t *testing.T) {
+	_, err := newClient(&Options{Endpoint: "https://account.documents.azure.com", Key: "a2V5", Database: "jaeger"})
+	require.NoError(t, err)
+
+	_, err = newClient(&Options{Endpoint: "https://account.documents.azure.com", Key: "not base64"})
+	assert.Error(t, err)
+}
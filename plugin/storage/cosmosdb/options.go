@@ -0,0 +1,98 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosmosdb
+
+import (
+	"flag"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	namespace = "cosmosdb"
+
+	suffixEndpoint            = ".endpoint"
+	suffixKey                 = ".key"
+	suffixDatabase            = ".database"
+	suffixSpansContainer      = ".spans-container"
+	suffixIndexContainer      = ".index-container"
+	suffixOperationsContainer = ".operations-container"
+	suffixCreateContainers    = ".create-containers"
+	suffixTimeout             = ".timeout"
+	suffixTTL                 = ".ttl"
+
+	defaultDatabase            = "jaeger"
+	defaultSpansContainer      = "spans"
+	defaultIndexContainer      = "span_index"
+	defaultOperationsContainer = "operations"
+	defaultTimeout             = 10 * time.Second
+	defaultTTL                 = 72 * time.Hour
+)
+
+// Options contains the Cosmos DB configs and provides the ability
+// to bind them to command line flags
+type Options struct {
+	// Endpoint is the URI of the account, e.g. https://account.documents.azure.com.
+	Endpoint string `mapstructure:"endpoint"`
+	// Key is the primary or the secondary key of the account.
+	Key                 string        `mapstructure:"key"`
+	Database            string        `mapstructure:"database"`
+	SpansContainer      string        `mapstructure:"spans_container"`
+	IndexContainer      string        `mapstructure:"index_container"`
+	OperationsContainer string        `mapstructure:"operations_container"`
+	CreateContainers    bool          `mapstructure:"create_containers"`
+	Timeout             time.Duration `mapstructure:"timeout"`
+	TTL                 time.Duration `mapstructure:"ttl"`
+}
+
+// NewOptions creates the Options with the default configuration.
+func NewOptions() *Options {
+	return &Options{
+		Database:            defaultDatabase,
+		SpansContainer:      defaultSpansContainer,
+		IndexContainer:      defaultIndexContainer,
+		OperationsContainer: defaultOperationsContainer,
+		CreateContainers:    true,
+		Timeout:             defaultTimeout,
+		TTL:                 defaultTTL,
+	}
+}
+
+// AddFlags adds flags for Options
+func (opt *Options) AddFlags(flagSet *flag.FlagSet) {
+	flagSet.String(namespace+suffixEndpoint, opt.Endpoint, "The URI of the Cosmos DB account, e.g. https://account.documents.azure.com")
+	flagSet.String(namespace+suffixKey, opt.Key, "The primary or the secondary key of the Cosmos DB account")
+	flagSet.String(namespace+suffixDatabase, opt.Database, "The database of the containers")
+	flagSet.String(namespace+suffixSpansContainer, opt.SpansContainer, "The container of the spans, partitioned by trace ID")
+	flagSet.String(namespace+suffixIndexContainer, opt.IndexContainer, "The container of the index of the spans, partitioned by service and hour")
+	flagSet.String(namespace+suffixOperationsContainer, opt.OperationsContainer, "The container of the operations, partitioned by service")
+	flagSet.Bool(namespace+suffixCreateContainers, opt.CreateContainers, "Create the database and the containers which do not exist on startup")
+	flagSet.Duration(namespace+suffixTimeout, opt.Timeout, "The timeout of the requests to Cosmos DB")
+	flagSet.Duration(namespace+suffixTTL, opt.TTL, "How long to keep the documents of the containers created before Cosmos DB expires them, 0 keeps them forever")
+}
+
+// InitFromViper initializes Options with properties from viper
+func (opt *Options) InitFromViper(v *viper.Viper) {
+	opt.Endpoint = v.GetString(namespace + suffixEndpoint)
+	opt.Key = v.GetString(namespace + suffixKey)
+	opt.Database = v.GetString(namespace + suffixDatabase)
+	opt.SpansContainer = v.GetString(namespace + suffixSpansContainer)
+	opt.IndexContainer = v.GetString(namespace + suffixIndexContainer)
+	opt.OperationsContainer = v.GetString(namespace + suffixOperationsContainer)
+	opt.CreateContainers = v.GetBool(namespace + suffixCreateContainers)
+	opt.Timeout = v.GetDuration(namespace + suffixTimeout)
+	opt.TTL = v.GetDuration(namespace + suffixTTL)
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jaegertracing/jaeger/pkg/cosmosdb"
+)
+
+// Containers are the names of the containers of the spans, of the index of the spans and of the operations.
+//
+// The spans are partitioned by trace ID, for a trace to be read from a single partition. The index is partitioned
+// by service and hour of start, the searches read the partitions of their time range from the most recent one.
+// The operations are partitioned by service.
+type Containers struct {
+	Spans      string
+	Index      string
+	Operations string
+}
+
+// CreateContainers creates the database and the containers which do not exist, their documents expire after the
+// TTL since their last write if it is set. The TTL of the existing containers is not updated.
+func CreateContainers(ctx context.Context, client cosmosdb.Client, containers Containers, ttl time.Duration) error {
+	if err := client.CreateDatabase(ctx); err != nil {
+		return fmt.Errorf("cannot create the Cosmos DB database: %w", err)
+	}
+	for _, container := range []cosmosdb.Container{
+		{ID: containers.Spans, PartitionKeyPath: "/traceID", DefaultTTL: ttl},
+		{ID: containers.Index, PartitionKeyPath: "/bucket", DefaultTTL: ttl},
+		{ID: containers.Operations, PartitionKeyPath: "/service", DefaultTTL: ttl},
+	} {
+		if err := client.CreateContainer(ctx, container); err != nil {
+			return fmt.Errorf("cannot create the Cosmos DB container %s: %w", container.ID, err)
+		}
+	}
+	return nil
+}
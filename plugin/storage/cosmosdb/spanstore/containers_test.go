@@ -0,0 +1,44 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/pkg/cosmosdb"
+)
+
+var testContainers = Containers{Spans: "spans", Index: "span_index", Operations: "operations"}
+
+func TestCreateContainers(t *testing.T) {
+	client := &fakeClient{}
+	require.NoError(t, CreateContainers(context.Background(), client, testContainers, time.Hour))
+	assert.Equal(t, 1, client.databases)
+	assert.Equal(t, []cosmosdb.Container{
+		{ID: "spans", PartitionKeyPath: "/traceID", DefaultTTL: time.Hour},
+		{ID: "span_index", PartitionKeyPath: "/bucket", DefaultTTL: time.Hour},
+		{ID: "operations", PartitionKeyPath: "/service", DefaultTTL: time.Hour},
+	}, client.containers)
+}
+
+func TestCreateContainersError(t *testing.T) {
+	err := CreateContainers(context.Background(), &fakeClient{err: assert.AnError}, testContainers, time.Hour)
+	assert.EqualError(t, err, "cannot create the Cosmos DB database: "+assert.AnError.Error())
+}
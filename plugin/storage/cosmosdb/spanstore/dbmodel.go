@@ -0,0 +1,142 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+// bucketSize is the time range of the spans of a partition of the index.
+const bucketSize = time.Hour
+
+// spanDocument is the document of a span in the partition of its trace, the span is in protobuf.
+type spanDocument struct {
+	ID      string `json:"id"`
+	TraceID string `json:"traceID"`
+	Span    []byte `json:"span"`
+}
+
+// indexDocument is the document of a span in the partition of the index of its service and hour of start,
+// with the fields searched.
+type indexDocument struct {
+	ID           string   `json:"id"`
+	Bucket       string   `json:"bucket"`
+	TraceID      string   `json:"traceID"`
+	SpanID       string   `json:"spanID"`
+	ParentSpanID string   `json:"parentSpanID,omitempty"`
+	Service      string   `json:"service"`
+	Operation    string   `json:"operation"`
+	StartTime    int64    `json:"startTime"`
+	Duration     int64    `json:"duration"`
+	Tags         []string `json:"tags,omitempty"`
+}
+
+// operationDocument is the document of an operation in the partition of its service.
+type operationDocument struct {
+	ID        string `json:"id"`
+	Service   string `json:"service"`
+	Operation string `json:"operation"`
+	SpanKind  string `json:"spanKind"`
+}
+
+// traceIDString returns the trace ID always on 32 hexadecimal digits, unlike TraceID.String.
+func traceIDString(traceID model.TraceID) string {
+	return fmt.Sprintf("%016x%016x", traceID.High, traceID.Low)
+}
+
+// bucket returns the partition key of the index of the service for the hour of t.
+func bucket(service string, t time.Time) string {
+	return service + "#" + t.UTC().Truncate(bucketSize).Format("2006010215")
+}
+
+func tagValue(key, value string) string {
+	return key + "=" + value
+}
+
+func micros(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Microsecond)
+}
+
+// operationID returns the ID of the operation, the names of the operations may contain the characters
+// forbidden in the IDs of Cosmos DB.
+func operationID(operation, spanKind string) string {
+	hash := fnv.New64a()
+	hash.Write([]byte(spanKind + "\x00" + operation))
+	return fmt.Sprintf("%016x", hash.Sum64())
+}
+
+// fromDomain returns the document of the span, and the document of its index if it has a service.
+func fromDomain(span *model.Span) (spanDocument, *indexDocument, error) {
+	data, err := proto.Marshal(span)
+	if err != nil {
+		return spanDocument{}, nil, err
+	}
+	// the spans sharing their ID, e.g. the client and the server spans of Zipkin, are told apart by their hash
+	hash := fnv.New64a()
+	hash.Write(data)
+	traceID := traceIDString(span.TraceID)
+	document := spanDocument{
+		ID:      fmt.Sprintf("%s-%016x", span.SpanID, hash.Sum64()),
+		TraceID: traceID,
+		Span:    data,
+	}
+	if span.Process == nil || span.Process.ServiceName == "" {
+		return document, nil, nil
+	}
+	index := &indexDocument{
+		ID:        traceID + "-" + document.ID,
+		Bucket:    bucket(span.Process.ServiceName, span.StartTime),
+		TraceID:   traceID,
+		SpanID:    span.SpanID.String(),
+		Service:   span.Process.ServiceName,
+		Operation: span.OperationName,
+		StartTime: micros(span.StartTime),
+		Duration:  int64(span.Duration / time.Microsecond),
+	}
+	if parentID := span.ParentSpanID(); parentID != 0 {
+		index.ParentSpanID = parentID.String()
+	}
+	tags := make(map[string]struct{})
+	addTags := func(kvs model.KeyValues) {
+		for _, kv := range kvs {
+			tags[tagValue(kv.Key, kv.AsString())] = struct{}{}
+		}
+	}
+	addTags(span.Tags)
+	addTags(span.Process.Tags)
+	for _, log := range span.Logs {
+		addTags(log.Fields)
+	}
+	for tag := range tags {
+		index.Tags = append(index.Tags, tag)
+	}
+	sort.Strings(index.Tags)
+	return document, index, nil
+}
+
+func toDomain(data []byte) (*model.Span, error) {
+	span := &model.Span{}
+	if err := proto.Unmarshal(data, span); err != nil {
+		return nil, fmt.Errorf("cannot decode the span: %w", err)
+	}
+	return span, nil
+}
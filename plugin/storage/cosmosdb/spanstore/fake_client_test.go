@@ -0,0 +1,95 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jaegertracing/jaeger/pkg/cosmosdb"
+)
+
+type upsert struct {
+	container    string
+	partitionKey string
+	document     interface{}
+}
+
+type query struct {
+	container      string
+	partitionKey   string
+	query          cosmosdb.Query
+	crossPartition bool
+}
+
+// fakeClient records the requests and returns the documents of the queries in order.
+type fakeClient struct {
+	databases  int
+	containers []cosmosdb.Container
+	upserts    []upsert
+	queries    []query
+	results    [][]interface{}
+	err        error
+	// upsertErrors are returned by the next upserts
+	upsertErrors []error
+}
+
+func (c *fakeClient) CreateDatabase(ctx context.Context) error {
+	c.databases++
+	return c.err
+}
+
+func (c *fakeClient) CreateContainer(ctx context.Context, container cosmosdb.Container) error {
+	c.containers = append(c.containers, container)
+	return c.err
+}
+
+func (c *fakeClient) Upsert(ctx context.Context, container, partitionKey string, document interface{}) error {
+	c.upserts = append(c.upserts, upsert{container: container, partitionKey: partitionKey, document: document})
+	if len(c.upsertErrors) > 0 {
+		err := c.upsertErrors[0]
+		c.upsertErrors = c.upsertErrors[1:]
+		return err
+	}
+	return c.err
+}
+
+func (c *fakeClient) Query(ctx context.Context, container, partitionKey string, q cosmosdb.Query, results interface{}) error {
+	// the parameters are copied as the reader updates the offset of the pages
+	q.Parameters = append([]cosmosdb.Parameter(nil), q.Parameters...)
+	c.queries = append(c.queries, query{container: container, partitionKey: partitionKey, query: q})
+	return c.decode(results)
+}
+
+func (c *fakeClient) QueryAll(ctx context.Context, container string, q cosmosdb.Query, results interface{}) error {
+	c.queries = append(c.queries, query{container: container, query: q, crossPartition: true})
+	return c.decode(results)
+}
+
+// decode decodes the next documents into results through JSON, as the client of Cosmos DB does.
+func (c *fakeClient) decode(results interface{}) error {
+	if c.err != nil {
+		return c.err
+	}
+	documents := []interface{}{}
+	if len(c.results) > 0 {
+		documents, c.results = c.results[0], c.results[1:]
+	}
+	data, err := json.Marshal(documents)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, results)
+}
@@ -0,0 +1,257 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/cosmosdb"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+const (
+	defaultNumTraces = 100
+
+	// limitMultiple is the number of spans read from a partition of the index, per trace searched, at each page
+	limitMultiple = 3
+)
+
+var (
+	// ErrServiceNameNotSet occurs when attempting to query with an empty service name
+	ErrServiceNameNotSet = errors.New("service Name must be set")
+
+	// ErrMalformedRequestObject occurs when a request object is nil
+	ErrMalformedRequestObject = errors.New("malformed request object")
+
+	// ErrStartAndEndTimeNotSet occurs when start time and end time are not set
+	ErrStartAndEndTimeNotSet = errors.New("start and End Time must be set")
+
+	// ErrStartTimeMinGreaterThanMax occurs when start time min is above start time max
+	ErrStartTimeMinGreaterThanMax = errors.New("start Time Minimum is above Maximum")
+
+	// ErrDurationMinGreaterThanMax occurs when duration min is above duration max
+	ErrDurationMinGreaterThanMax = errors.New("duration Minimum is above Maximum")
+)
+
+// SpanReader can query for and load traces from Cosmos DB.
+type SpanReader struct {
+	client     cosmosdb.Client
+	containers Containers
+}
+
+// NewSpanReader returns a new SpanReader.
+func NewSpanReader(client cosmosdb.Client, containers Containers) *SpanReader {
+	return &SpanReader{client: client, containers: containers}
+}
+
+// GetTrace takes a traceID and returns a Trace associated with that traceID
+func (r *SpanReader) GetTrace(ctx context.Context, traceID model.TraceID) (*model.Trace, error) {
+	var documents []spanDocument
+	err := r.client.Query(ctx, r.containers.Spans, traceIDString(traceID), cosmosdb.Query{Query: "SELECT c.span FROM c"}, &documents)
+	if err != nil {
+		return nil, err
+	}
+	if len(documents) == 0 {
+		return nil, spanstore.ErrTraceNotFound
+	}
+	trace := &model.Trace{}
+	for _, document := range documents {
+		span, err := toDomain(document.Span)
+		if err != nil {
+			return nil, err
+		}
+		trace.Spans = append(trace.Spans, span)
+	}
+	return trace, nil
+}
+
+// GetServices returns all services traced by Jaeger
+func (r *SpanReader) GetServices(ctx context.Context) ([]string, error) {
+	var documents []operationDocument
+	if err := r.client.QueryAll(ctx, r.containers.Operations, cosmosdb.Query{Query: "SELECT c.service FROM c"}, &documents); err != nil {
+		return nil, err
+	}
+	found := make(map[string]struct{})
+	services := []string{}
+	for _, document := range documents {
+		if _, ok := found[document.Service]; !ok {
+			found[document.Service] = struct{}{}
+			services = append(services, document.Service)
+		}
+	}
+	sort.Strings(services)
+	return services, nil
+}
+
+// GetOperations returns all operations for a specific service traced by Jaeger
+func (r *SpanReader) GetOperations(ctx context.Context, query spanstore.OperationQueryParameters) ([]spanstore.Operation, error) {
+	q := cosmosdb.Query{Query: "SELECT c.operation, c.spanKind FROM c"}
+	if query.SpanKind != "" {
+		q.Query += " WHERE c.spanKind = @spanKind"
+		q.Parameters = []cosmosdb.Parameter{{Name: "@spanKind", Value: query.SpanKind}}
+	}
+	var documents []operationDocument
+	if err := r.client.Query(ctx, r.containers.Operations, query.ServiceName, q, &documents); err != nil {
+		return nil, err
+	}
+	operations := make([]spanstore.Operation, 0, len(documents))
+	for _, document := range documents {
+		operations = append(operations, spanstore.Operation{Name: document.Operation, SpanKind: document.SpanKind})
+	}
+	sort.Slice(operations, func(i, j int) bool {
+		if operations[i].Name != operations[j].Name {
+			return operations[i].Name < operations[j].Name
+		}
+		return operations[i].SpanKind < operations[j].SpanKind
+	})
+	return operations, nil
+}
+
+// FindTraces retrieves traces that match the traceQuery
+func (r *SpanReader) FindTraces(ctx context.Context, query *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
+	traceIDs, err := r.FindTraceIDs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	traces := make([]*model.Trace, 0, len(traceIDs))
+	for _, traceID := range traceIDs {
+		trace, err := r.GetTrace(ctx, traceID)
+		if err == spanstore.ErrTraceNotFound {
+			// expired since it was found
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		traces = append(traces, trace)
+	}
+	return traces, nil
+}
+
+// FindTraceIDs retrieves the IDs of the traces that match the traceQuery, the most recent first.
+// The partitions of the index of the service are read by pages from the most recent hour of the time range, the
+// spans are matched by operation, start time, duration and tag equalities. The other tag filters are left to the
+// query service.
+func (r *SpanReader) FindTraceIDs(ctx context.Context, query *spanstore.TraceQueryParameters) ([]model.TraceID, error) {
+	if err := validateQuery(query); err != nil {
+		return nil, err
+	}
+	numTraces := query.NumTraces
+	if numTraces <= 0 {
+		numTraces = defaultNumTraces
+	}
+	pageSize := numTraces * limitMultiple
+	q := buildQuery(query, pageSize)
+	found := make(map[string]struct{})
+	traceIDs := make([]model.TraceID, 0, numTraces)
+	first := query.StartTimeMin.UTC().Truncate(bucketSize)
+	for hour := query.StartTimeMax.UTC().Truncate(bucketSize); !hour.Before(first); hour = hour.Add(-bucketSize) {
+		for offset := 0; ; offset += pageSize {
+			q.Parameters[0].Value = offset
+			var documents []indexDocument
+			if err := r.client.Query(ctx, r.containers.Index, bucket(query.ServiceName, hour), q, &documents); err != nil {
+				return nil, err
+			}
+			for _, document := range documents {
+				if _, ok := found[document.TraceID]; ok {
+					continue
+				}
+				found[document.TraceID] = struct{}{}
+				traceID, err := model.TraceIDFromString(document.TraceID)
+				if err != nil {
+					return nil, err
+				}
+				traceIDs = append(traceIDs, traceID)
+				if len(traceIDs) == numTraces {
+					return traceIDs, nil
+				}
+			}
+			if len(documents) < pageSize {
+				break
+			}
+		}
+	}
+	return traceIDs, nil
+}
+
+// buildQuery returns the query of a page of the spans matching the query in a partition of the index, its first
+// parameter is the offset of the page.
+func buildQuery(query *spanstore.TraceQueryParameters, pageSize int) cosmosdb.Query {
+	parameters := []cosmosdb.Parameter{
+		{Name: "@offset", Value: 0},
+		{Name: "@limit", Value: pageSize},
+		{Name: "@startTimeMin", Value: micros(query.StartTimeMin)},
+		{Name: "@startTimeMax", Value: micros(query.StartTimeMax)},
+	}
+	conditions := []string{"c.startTime >= @startTimeMin", "c.startTime <= @startTimeMax"}
+	if query.OperationName != "" {
+		conditions = append(conditions, "c.operation = @operation")
+		parameters = append(parameters, cosmosdb.Parameter{Name: "@operation", Value: query.OperationName})
+	}
+	if query.DurationMin != 0 {
+		conditions = append(conditions, "c.duration >= @durationMin")
+		parameters = append(parameters, cosmosdb.Parameter{Name: "@durationMin", Value: int64(query.DurationMin / time.Microsecond)})
+	}
+	if query.DurationMax != 0 {
+		conditions = append(conditions, "c.duration <= @durationMax")
+		parameters = append(parameters, cosmosdb.Parameter{Name: "@durationMax", Value: int64(query.DurationMax / time.Microsecond)})
+	}
+	var tags []string
+	for key, value := range query.Tags {
+		tags = append(tags, tagValue(key, value))
+	}
+	for _, filter := range query.TagFilters {
+		if filter.Operator == spanstore.TagEquals {
+			tags = append(tags, tagValue(filter.Key, filter.Value))
+		}
+	}
+	// sorted for the queries to be deterministic
+	sort.Strings(tags)
+	for i, tag := range tags {
+		name := "@tag" + strconv.Itoa(i)
+		conditions = append(conditions, "ARRAY_CONTAINS(c.tags, "+name+")")
+		parameters = append(parameters, cosmosdb.Parameter{Name: name, Value: tag})
+	}
+	return cosmosdb.Query{
+		Query: "SELECT c.traceID FROM c WHERE " + strings.Join(conditions, " AND ") +
+			" ORDER BY c.startTime DESC OFFSET @offset LIMIT @limit",
+		Parameters: parameters,
+	}
+}
+
+func validateQuery(p *spanstore.TraceQueryParameters) error {
+	if p == nil {
+		return ErrMalformedRequestObject
+	}
+	if p.ServiceName == "" {
+		return ErrServiceNameNotSet
+	}
+	if p.StartTimeMin.IsZero() || p.StartTimeMax.IsZero() {
+		return ErrStartAndEndTimeNotSet
+	}
+	if p.StartTimeMax.Before(p.StartTimeMin) {
+		return ErrStartTimeMinGreaterThanMax
+	}
+	if p.DurationMin != 0 && p.DurationMax != 0 && p.DurationMin > p.DurationMax {
+		return ErrDurationMinGreaterThanMax
+	}
+	return nil
+}
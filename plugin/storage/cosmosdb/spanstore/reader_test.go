@@ -0,0 +1,285 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/cosmosdb"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+func spanResult(t *testing.T, span *model.Span) interface{} {
+	document, _, err := fromDomain(span)
+	require.NoError(t, err)
+	return map[string]interface{}{"span": document.Span}
+}
+
+func traceIDResults(traceIDs ...string) []interface{} {
+	var results []interface{}
+	for _, traceID := range traceIDs {
+		results = append(results, map[string]string{"traceID": traceID})
+	}
+	return results
+}
+
+func TestGetTrace(t *testing.T) {
+	traceID := model.NewTraceID(0, 1)
+	client := &fakeClient{results: [][]interface{}{
+		{spanResult(t, testSpan(traceID, 1, "svc")), spanResult(t, testSpan(traceID, 2, "svc"))},
+	}}
+	reader := NewSpanReader(client, testContainers)
+
+	trace, err := reader.GetTrace(context.Background(), traceID)
+	require.NoError(t, err)
+	require.Len(t, trace.Spans, 2)
+	assert.Equal(t, model.SpanID(2), trace.Spans[1].SpanID)
+	assert.Equal(t, query{
+		container:    "spans",
+		partitionKey: "00000000000000000000000000000001",
+		query:        cosmosdb.Query{Query: "SELECT c.span FROM c"},
+	}, client.queries[0])
+
+	_, err = reader.GetTrace(context.Background(), model.NewTraceID(0, 2))
+	assert.Equal(t, spanstore.ErrTraceNotFound, err)
+}
+
+func TestGetTraceErrors(t *testing.T) {
+	reader := NewSpanReader(&fakeClient{err: assert.AnError}, testContainers)
+	_, err := reader.GetTrace(context.Background(), model.NewTraceID(0, 1))
+	assert.Equal(t, assert.AnError, err)
+
+	reader = NewSpanReader(&fakeClient{results: [][]interface{}{{map[string]interface{}{"span": []byte("\xff")}}}}, testContainers)
+	_, err = reader.GetTrace(context.Background(), model.NewTraceID(0, 1))
+	assert.Error(t, err)
+}
+
+func TestGetServices(t *testing.T) {
+	client := &fakeClient{results: [][]interface{}{{
+		map[string]string{"service": "svc2"}, map[string]string{"service": "svc1"}, map[string]string{"service": "svc2"},
+	}}}
+	reader := NewSpanReader(client, testContainers)
+	services, err := reader.GetServices(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"svc1", "svc2"}, services)
+	assert.Equal(t, "operations", client.queries[0].container)
+	assert.True(t, client.queries[0].crossPartition)
+
+	reader = NewSpanReader(&fakeClient{err: assert.AnError}, testContainers)
+	_, err = reader.GetServices(context.Background())
+	assert.Equal(t, assert.AnError, err)
+}
+
+func TestGetOperations(t *testing.T) {
+	client := &fakeClient{results: [][]interface{}{
+		{map[string]string{"operation": "b", "spanKind": "client"}, map[string]string{"operation": "a", "spanKind": "server"}},
+		{map[string]string{"operation": "b", "spanKind": "client"}},
+	}}
+	reader := NewSpanReader(client, testContainers)
+
+	operations, err := reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "svc"})
+	require.NoError(t, err)
+	assert.Equal(t, []spanstore.Operation{{Name: "a", SpanKind: "server"}, {Name: "b", SpanKind: "client"}}, operations)
+	assert.Equal(t, query{
+		container:    "operations",
+		partitionKey: "svc",
+		query:        cosmosdb.Query{Query: "SELECT c.operation, c.spanKind FROM c"},
+	}, client.queries[0])
+
+	operations, err = reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "svc", SpanKind: "client"})
+	require.NoError(t, err)
+	assert.Equal(t, []spanstore.Operation{{Name: "b", SpanKind: "client"}}, operations)
+	assert.Equal(t, cosmosdb.Query{
+		Query:      "SELECT c.operation, c.spanKind FROM c WHERE c.spanKind = @spanKind",
+		Parameters: []cosmosdb.Parameter{{Name: "@spanKind", Value: "client"}},
+	}, client.queries[1].query)
+
+	operations, err = reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "unknown"})
+	require.NoError(t, err)
+	assert.Empty(t, operations)
+
+	client.err = assert.AnError
+	_, err = reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "svc"})
+	assert.Equal(t, assert.AnError, err)
+}
+
+func TestFindTraceIDs(t *testing.T) {
+	client := &fakeClient{results: [][]interface{}{
+		traceIDResults("00000000000000000000000000000003", "00000000000000000000000000000003", "00000000000000000000000000000002"),
+		traceIDResults("00000000000000000000000000000001"),
+		nil,
+		traceIDResults("00000000000000000000000000000001", "00000000000000000000000000000004"),
+	}}
+	reader := NewSpanReader(client, testContainers)
+	traceIDs, err := reader.FindTraceIDs(context.Background(), &spanstore.TraceQueryParameters{
+		ServiceName:   "svc",
+		OperationName: "op",
+		Tags:          map[string]string{"k2": "v2"},
+		TagFilters: []spanstore.TagFilter{
+			{Key: "k1", Operator: spanstore.TagEquals, Value: "v1"},
+			{Key: "k3", Operator: spanstore.TagNotEquals, Value: "v3"},
+		},
+		StartTimeMin: testTime.Add(-2 * time.Hour),
+		StartTimeMax: testTime,
+		DurationMin:  time.Millisecond,
+		DurationMax:  time.Second,
+		NumTraces:    1,
+	})
+	require.NoError(t, err)
+	// the search stops once the traces are found
+	assert.Equal(t, []model.TraceID{model.NewTraceID(0, 3)}, traceIDs)
+	require.Len(t, client.queries, 1)
+
+	client.results = [][]interface{}{
+		traceIDResults("00000000000000000000000000000003", "00000000000000000000000000000003", "00000000000000000000000000000002"),
+		traceIDResults("00000000000000000000000000000001"),
+		traceIDResults("00000000000000000000000000000001", "00000000000000000000000000000004"),
+	}
+	client.queries = nil
+	traceIDs, err = reader.FindTraceIDs(context.Background(), &spanstore.TraceQueryParameters{
+		ServiceName:   "svc",
+		OperationName: "op",
+		Tags:          map[string]string{"k2": "v2"},
+		TagFilters: []spanstore.TagFilter{
+			{Key: "k1", Operator: spanstore.TagEquals, Value: "v1"},
+			{Key: "k3", Operator: spanstore.TagNotEquals, Value: "v3"},
+		},
+		StartTimeMin: testTime.Add(-2 * time.Hour),
+		StartTimeMax: testTime,
+		DurationMin:  time.Millisecond,
+		DurationMax:  time.Second,
+		NumTraces:    10,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []model.TraceID{model.NewTraceID(0, 3), model.NewTraceID(0, 2), model.NewTraceID(0, 1), model.NewTraceID(0, 4)}, traceIDs)
+	// a partition is read from the most recent hour, the trace IDs found in the previous partitions are skipped
+	require.Len(t, client.queries, 3)
+	assert.Equal(t, "span_index", client.queries[0].container)
+	for i, partitionKey := range []string{"svc#2020091312", "svc#2020091311", "svc#2020091310"} {
+		assert.Equal(t, partitionKey, client.queries[i].partitionKey)
+	}
+	assert.Equal(t, cosmosdb.Query{
+		Query: "SELECT c.traceID FROM c WHERE c.startTime >= @startTimeMin AND c.startTime <= @startTimeMax" +
+			" AND c.operation = @operation AND c.duration >= @durationMin AND c.duration <= @durationMax" +
+			" AND ARRAY_CONTAINS(c.tags, @tag0) AND ARRAY_CONTAINS(c.tags, @tag1)" +
+			" ORDER BY c.startTime DESC OFFSET @offset LIMIT @limit",
+		Parameters: []cosmosdb.Parameter{
+			{Name: "@offset", Value: 0},
+			{Name: "@limit", Value: 30},
+			{Name: "@startTimeMin", Value: micros(testTime.Add(-2 * time.Hour))},
+			{Name: "@startTimeMax", Value: micros(testTime)},
+			{Name: "@operation", Value: "op"},
+			{Name: "@durationMin", Value: int64(1000)},
+			{Name: "@durationMax", Value: int64(1000000)},
+			{Name: "@tag0", Value: "k1=v1"},
+			{Name: "@tag1", Value: "k2=v2"},
+		},
+	}, client.queries[0].query)
+}
+
+func TestFindTraceIDsPages(t *testing.T) {
+	// the pages are full with 3 spans per trace searched
+	var page []interface{}
+	for i := 0; i < 6; i++ {
+		page = append(page, map[string]string{"traceID": "00000000000000000000000000000001"})
+	}
+	client := &fakeClient{results: [][]interface{}{page, traceIDResults("00000000000000000000000000000002")}}
+	reader := NewSpanReader(client, testContainers)
+	traceIDs, err := reader.FindTraceIDs(context.Background(), &spanstore.TraceQueryParameters{
+		ServiceName:  "svc",
+		StartTimeMin: testTime,
+		StartTimeMax: testTime,
+		NumTraces:    1,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []model.TraceID{model.NewTraceID(0, 1)}, traceIDs)
+
+	client = &fakeClient{results: [][]interface{}{page, traceIDResults("00000000000000000000000000000002")}}
+	reader = NewSpanReader(client, testContainers)
+	traceIDs, err = reader.FindTraceIDs(context.Background(), &spanstore.TraceQueryParameters{
+		ServiceName:  "svc",
+		StartTimeMin: testTime,
+		StartTimeMax: testTime,
+		NumTraces:    2,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []model.TraceID{model.NewTraceID(0, 1), model.NewTraceID(0, 2)}, traceIDs)
+	require.Len(t, client.queries, 2)
+	assert.Equal(t, cosmosdb.Parameter{Name: "@offset", Value: 6}, client.queries[1].query.Parameters[0])
+}
+
+func TestFindTraces(t *testing.T) {
+	client := &fakeClient{results: [][]interface{}{
+		traceIDResults("00000000000000000000000000000002", "00000000000000000000000000000001"),
+		// the first trace expired since it was found
+		nil,
+		{spanResult(t, testSpan(model.NewTraceID(0, 1), 1, "svc"))},
+	}}
+	reader := NewSpanReader(client, testContainers)
+	traces, err := reader.FindTraces(context.Background(), &spanstore.TraceQueryParameters{
+		ServiceName:  "svc",
+		StartTimeMin: testTime,
+		StartTimeMax: testTime,
+	})
+	require.NoError(t, err)
+	require.Len(t, traces, 1)
+	assert.Equal(t, model.NewTraceID(0, 1), traces[0].Spans[0].TraceID)
+}
+
+func TestFindTracesErrors(t *testing.T) {
+	reader := NewSpanReader(&fakeClient{}, testContainers)
+	testCases := []struct {
+		query *spanstore.TraceQueryParameters
+		err   error
+	}{
+		{query: nil, err: ErrMalformedRequestObject},
+		{query: &spanstore.TraceQueryParameters{}, err: ErrServiceNameNotSet},
+		{query: &spanstore.TraceQueryParameters{ServiceName: "svc"}, err: ErrStartAndEndTimeNotSet},
+		{
+			query: &spanstore.TraceQueryParameters{ServiceName: "svc", StartTimeMin: testTime, StartTimeMax: testTime.Add(-time.Second)},
+			err:   ErrStartTimeMinGreaterThanMax,
+		},
+		{
+			query: &spanstore.TraceQueryParameters{ServiceName: "svc", StartTimeMin: testTime, StartTimeMax: testTime, DurationMin: 2, DurationMax: 1},
+			err:   ErrDurationMinGreaterThanMax,
+		},
+	}
+	for _, testCase := range testCases {
+		_, err := reader.FindTraces(context.Background(), testCase.query)
+		assert.Equal(t, testCase.err, err)
+	}
+
+	query := &spanstore.TraceQueryParameters{ServiceName: "svc", StartTimeMin: testTime, StartTimeMax: testTime}
+	reader = NewSpanReader(&fakeClient{err: assert.AnError}, testContainers)
+	_, err := reader.FindTraces(context.Background(), query)
+	assert.Equal(t, assert.AnError, err)
+
+	reader = NewSpanReader(&fakeClient{results: [][]interface{}{traceIDResults("invalid")}}, testContainers)
+	_, err = reader.FindTraces(context.Background(), query)
+	assert.Error(t, err)
+
+	reader = NewSpanReader(&fakeClient{results: [][]interface{}{
+		traceIDResults("00000000000000000000000000000001"),
+		{map[string]interface{}{"span": []byte("\xff")}},
+	}}, testContainers)
+	_, err = reader.FindTraces(context.Background(), query)
+	assert.Error(t, err)
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/cache"
+	"github.com/jaegertracing/jaeger/pkg/cosmosdb"
+	storageMetrics "github.com/jaegertracing/jaeger/storage/spanstore/metrics"
+)
+
+// SpanWriterParams holds constructor parameters for NewSpanWriter
+type SpanWriterParams struct {
+	Client         cosmosdb.Client
+	Containers     Containers
+	MetricsFactory metrics.Factory
+	// TTL is the retention of the documents, the operations are written again before it expires them.
+	TTL time.Duration
+}
+
+// SpanWriter writes the spans, their index and the operations of their services into Cosmos DB.
+type SpanWriter struct {
+	client     cosmosdb.Client
+	containers Containers
+	// operations are the operations written recently, they are not written again until they are about to expire
+	operations cache.Cache
+	spans      *storageMetrics.WriteMetrics
+}
+
+// NewSpanWriter creates a SpanWriter.
+func NewSpanWriter(p SpanWriterParams) *SpanWriter {
+	operationsTTL := 12 * time.Hour
+	if p.TTL > 0 && p.TTL/2 < operationsTTL {
+		operationsTTL = p.TTL / 2
+	}
+	return &SpanWriter{
+		client:     p.Client,
+		containers: p.Containers,
+		operations: cache.NewLRUWithOptions(100000, &cache.Options{
+			TTL: operationsTTL,
+		}),
+		spans: storageMetrics.NewWriteMetrics(p.MetricsFactory, "spans"),
+	}
+}
+
+// WriteSpan writes the span into the partition of its trace, then its index and its operation if it has a service.
+func (w *SpanWriter) WriteSpan(span *model.Span) error {
+	document, index, err := fromDomain(span)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	start := time.Now()
+	err = w.client.Upsert(ctx, w.containers.Spans, document.TraceID, document)
+	if err == nil && index != nil {
+		err = w.client.Upsert(ctx, w.containers.Index, index.Bucket, index)
+	}
+	w.spans.Emit(err, time.Since(start))
+	if err != nil || index == nil {
+		return err
+	}
+	spanKind, _ := span.GetSpanKind()
+	operation := operationDocument{
+		ID:        operationID(span.OperationName, spanKind),
+		Service:   index.Service,
+		Operation: span.OperationName,
+		SpanKind:  spanKind,
+	}
+	key := operation.Service + "#" + operation.ID
+	if w.operations.Get(key) != nil {
+		return nil
+	}
+	if err := w.client.Upsert(ctx, w.containers.Operations, operation.Service, operation); err != nil {
+		return err
+	}
+	w.operations.Put(key, true)
+	return nil
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+func newTestWriter(client *fakeClient) (*SpanWriter, *metricstest.Factory) {
+	metricsFactory := metricstest.NewFactory(0)
+	return NewSpanWriter(SpanWriterParams{
+		Client:         client,
+		Containers:     testContainers,
+		MetricsFactory: metricsFactory,
+		TTL:            time.Hour,
+	}), metricsFactory
+}
+
+func TestWriteSpan(t *testing.T) {
+	client := &fakeClient{}
+	writer, metricsFactory := newTestWriter(client)
+	traceID := model.NewTraceID(0, 1)
+
+	require.NoError(t, writer.WriteSpan(testSpan(traceID, 1, "svc")))
+	require.NoError(t, writer.WriteSpan(testSpan(traceID, 2, "svc")))
+
+	// the operation is written once while it is cached
+	require.Len(t, client.upserts, 5)
+	assert.Equal(t, "spans", client.upserts[0].container)
+	assert.Equal(t, "00000000000000000000000000000001", client.upserts[0].partitionKey)
+	assert.Equal(t, "span_index", client.upserts[1].container)
+	assert.Equal(t, "svc#2020091312", client.upserts[1].partitionKey)
+	assert.Equal(t, upsert{
+		container:    "operations",
+		partitionKey: "svc",
+		document: operationDocument{
+			ID:        operationID("op", "server"),
+			Service:   "svc",
+			Operation: "op",
+			SpanKind:  "server",
+		},
+	}, client.upserts[2])
+	assert.Equal(t, "spans", client.upserts[3].container)
+	assert.Equal(t, "span_index", client.upserts[4].container)
+
+	metricsFactory.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "spans.attempts", Value: 2},
+		metricstest.ExpectedMetric{Name: "spans.inserts", Value: 2})
+}
+
+func TestWriteSpanWithoutService(t *testing.T) {
+	client := &fakeClient{}
+	writer, _ := newTestWriter(client)
+	require.NoError(t, writer.WriteSpan(&model.Span{TraceID: model.NewTraceID(0, 1), SpanID: 1}))
+	require.Len(t, client.upserts, 1)
+	assert.Equal(t, "spans", client.upserts[0].container)
+}
+
+func TestWriteSpanErrors(t *testing.T) {
+	client := &fakeClient{upsertErrors: []error{nil, assert.AnError}}
+	writer, metricsFactory := newTestWriter(client)
+	assert.Equal(t, assert.AnError, writer.WriteSpan(testSpan(model.NewTraceID(0, 1), 1, "svc")))
+	assert.Len(t, client.upserts, 2)
+	metricsFactory.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "spans.errors", Value: 1})
+
+	client = &fakeClient{upsertErrors: []error{nil, nil, assert.AnError}}
+	writer, _ = newTestWriter(client)
+	assert.Equal(t, assert.AnError, writer.WriteSpan(testSpan(model.NewTraceID(0, 1), 1, "svc")))
+	// the operation is not cached after an error
+	require.NoError(t, writer.WriteSpan(testSpan(model.NewTraceID(0, 1), 1, "svc")))
+	assert.Len(t, client.upserts, 6)
+}
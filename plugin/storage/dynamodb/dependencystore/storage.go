@@ -0,0 +1,109 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dependencystore
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+// DependencyStore computes the dependencies of the services from the spans of the time window.
+// The spans table is scanned, the cost of the dependencies grows with the size of the table.
+type DependencyStore struct {
+	client     dynamodbiface.DynamoDBAPI
+	spansTable string
+}
+
+// NewDependencyStore returns a DependencyStore reading the spans of the table.
+func NewDependencyStore(client dynamodbiface.DynamoDBAPI, spansTable string) *DependencyStore {
+	return &DependencyStore{client: client, spansTable: spansTable}
+}
+
+type spanItem struct {
+	TraceID      string `dynamodbav:"trace_id"`
+	SpanID       string `dynamodbav:"span_id"`
+	ParentSpanID string `dynamodbav:"parent_span_id"`
+	Service      string `dynamodbav:"service"`
+}
+
+type spanKey struct {
+	traceID string
+	spanID  string
+}
+
+// GetDependencies returns the calls between the services in the time window ending at endTs.
+func (s *DependencyStore) GetDependencies(endTs time.Time, lookback time.Duration) ([]model.DependencyLink, error) {
+	services := make(map[spanKey]string)
+	var children []spanItem
+	var decodeErr error
+	err := s.client.ScanPagesWithContext(context.Background(), &dynamodb.ScanInput{
+		TableName:            aws.String(s.spansTable),
+		FilterExpression:     aws.String("#start_time BETWEEN :start_min AND :start_max"),
+		ProjectionExpression: aws.String("#trace_id, #span_id, #parent_span_id, #service"),
+		ExpressionAttributeNames: map[string]*string{
+			"#start_time":     aws.String("start_time"),
+			"#trace_id":       aws.String("trace_id"),
+			"#span_id":        aws.String("span_id"),
+			"#parent_span_id": aws.String("parent_span_id"),
+			"#service":        aws.String("service"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":start_min": micros(endTs.Add(-lookback)),
+			":start_max": micros(endTs),
+		},
+	}, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		var items []spanItem
+		if decodeErr = dynamodbattribute.UnmarshalListOfMaps(page.Items, &items); decodeErr != nil {
+			return false
+		}
+		for _, item := range items {
+			services[spanKey{item.TraceID, item.SpanID}] = item.Service
+			if item.ParentSpanID != "" {
+				children = append(children, item)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+	links := make(map[[2]string]uint64)
+	for _, child := range children {
+		parent, ok := services[spanKey{child.TraceID, child.ParentSpanID}]
+		if ok && parent != child.Service {
+			links[[2]string{parent, child.Service}]++
+		}
+	}
+	dependencies := make([]model.DependencyLink, 0, len(links))
+	for link, count := range links {
+		dependencies = append(dependencies, model.DependencyLink{Parent: link[0], Child: link[1], CallCount: count})
+	}
+	return dependencies, nil
+}
+
+func micros(t time.Time) *dynamodb.AttributeValue {
+	return &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(t.UnixNano()/int64(time.Microsecond), 10))}
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dependencystore
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+type fakeClient struct {
+	dynamodbiface.DynamoDBAPI
+	input *dynamodb.ScanInput
+	pages [][]map[string]*dynamodb.AttributeValue
+	err   error
+}
+
+func (c *fakeClient) ScanPagesWithContext(ctx aws.Context, input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool, opts ...request.Option) error {
+	c.input = input
+	if c.err != nil {
+		return c.err
+	}
+	for i, page := range c.pages {
+		if !fn(&dynamodb.ScanOutput{Items: page}, i == len(c.pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+func span(traceID, spanID, parentSpanID, service string) map[string]*dynamodb.AttributeValue {
+	item := map[string]*dynamodb.AttributeValue{
+		"trace_id": {S: aws.String(traceID)},
+		"span_id":  {S: aws.String(spanID)},
+		"service":  {S: aws.String(service)},
+	}
+	if parentSpanID != "" {
+		item["parent_span_id"] = &dynamodb.AttributeValue{S: aws.String(parentSpanID)}
+	}
+	return item
+}
+
+func TestGetDependencies(t *testing.T) {
+	client := &fakeClient{pages: [][]map[string]*dynamodb.AttributeValue{
+		{span("t1", "b", "a", "frontend"), span("t1", "a", "", "gateway"), span("t1", "c", "b", "db")},
+		// a call within a service and a parent out of the window
+		{span("t2", "a", "", "gateway"), span("t2", "b", "a", "gateway"), span("t3", "b", "a", "db")},
+		{span("t4", "a", "", "gateway"), span("t4", "b", "a", "frontend")},
+	}}
+	store := NewDependencyStore(client, "spans")
+	dependencies, err := store.GetDependencies(time.Unix(100, 0), 10*time.Second)
+	require.NoError(t, err)
+	sort.Slice(dependencies, func(i, j int) bool { return dependencies[i].Parent < dependencies[j].Parent })
+	assert.Equal(t, []model.DependencyLink{
+		{Parent: "frontend", Child: "db", CallCount: 1},
+		{Parent: "gateway", Child: "frontend", CallCount: 2},
+	}, dependencies)
+	assert.Equal(t, "spans", *client.input.TableName)
+	assert.Equal(t, "90000000", *client.input.ExpressionAttributeValues[":start_min"].N)
+	assert.Equal(t, "100000000", *client.input.ExpressionAttributeValues[":start_max"].N)
+}
+
+func TestGetDependenciesErrors(t *testing.T) {
+	store := NewDependencyStore(&fakeClient{err: assert.AnError}, "spans")
+	_, err := store.GetDependencies(time.Unix(100, 0), time.Second)
+	assert.Equal(t, assert.AnError, err)
+
+	store = NewDependencyStore(&fakeClient{pages: [][]map[string]*dynamodb.AttributeValue{
+		{{"trace_id": {BOOL: aws.Bool(true)}}},
+	}}, "spans")
+	_, err = store.GetDependencies(time.Unix(100, 0), time.Second)
+	assert.Error(t, err)
+}
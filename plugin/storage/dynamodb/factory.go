@@ -0,0 +1,124 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dynamodb
+
+import (
+	"context"
+	"flag"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/spf13/viper"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	ddbDepStore "github.com/jaegertracing/jaeger/plugin/storage/dynamodb/dependencystore"
+	ddbSpanStore "github.com/jaegertracing/jaeger/plugin/storage/dynamodb/spanstore"
+	"github.com/jaegertracing/jaeger/storage/dependencystore"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+// Factory implements storage.Factory for DynamoDB backend.
+type Factory struct {
+	Options *Options
+
+	metricsFactory metrics.Factory
+	logger         *zap.Logger
+	client         dynamodbiface.DynamoDBAPI
+
+	// newClient creates the client of DynamoDB, it is replaced by the tests
+	newClient func(options *Options) (dynamodbiface.DynamoDBAPI, error)
+}
+
+// NewFactory creates a new Factory.
+func NewFactory() *Factory {
+	return &Factory{
+		Options:   NewOptions(),
+		newClient: newClient,
+	}
+}
+
+func newClient(options *Options) (dynamodbiface.DynamoDBAPI, error) {
+	config := aws.NewConfig()
+	if options.Region != "" {
+		config = config.WithRegion(options.Region)
+	}
+	if options.Endpoint != "" {
+		config = config.WithEndpoint(options.Endpoint)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *config,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dynamodb.New(sess), nil
+}
+
+// AddFlags implements plugin.Configurable
+func (f *Factory) AddFlags(flagSet *flag.FlagSet) {
+	f.Options.AddFlags(flagSet)
+}
+
+// InitFromViper implements plugin.Configurable
+func (f *Factory) InitFromViper(v *viper.Viper) {
+	f.Options.InitFromViper(v)
+}
+
+// Initialize implements storage.Factory
+func (f *Factory) Initialize(metricsFactory metrics.Factory, logger *zap.Logger) error {
+	f.metricsFactory, f.logger = metricsFactory, logger
+	client, err := f.newClient(f.Options)
+	if err != nil {
+		return err
+	}
+	if f.Options.CreateTables {
+		if err := ddbSpanStore.CreateTables(context.Background(), client, f.tables()); err != nil {
+			return err
+		}
+	}
+	f.client = client
+	return nil
+}
+
+func (f *Factory) tables() ddbSpanStore.Tables {
+	return ddbSpanStore.Tables{
+		Spans:      f.Options.SpansTable,
+		Operations: f.Options.OperationsTable,
+	}
+}
+
+// CreateSpanReader implements storage.Factory
+func (f *Factory) CreateSpanReader() (spanstore.Reader, error) {
+	return ddbSpanStore.NewSpanReader(f.client, f.tables()), nil
+}
+
+// CreateSpanWriter implements storage.Factory
+func (f *Factory) CreateSpanWriter() (spanstore.Writer, error) {
+	return ddbSpanStore.NewSpanWriter(ddbSpanStore.SpanWriterParams{
+		Client:         f.client,
+		Tables:         f.tables(),
+		MetricsFactory: f.metricsFactory,
+		TTL:            f.Options.TTL,
+	}), nil
+}
+
+// CreateDependencyReader implements storage.Factory
+func (f *Factory) CreateDependencyReader() (dependencystore.Reader, error) {
+	return ddbDepStore.NewDependencyStore(f.client, f.Options.SpansTable), nil
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dynamodb
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/pkg/config"
+	"github.com/jaegertracing/jaeger/storage"
+)
+
+var _ storage.Factory = new(Factory)
+
+type fakeClient struct {
+	dynamodbiface.DynamoDBAPI
+	described []string
+	err       error
+}
+
+func (c *fakeClient) DescribeTableWithContext(ctx aws.Context, input *dynamodb.DescribeTableInput, opts ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+	c.described = append(c.described, *input.TableName)
+	return &dynamodb.DescribeTableOutput{}, c.err
+}
+
+func newTestFactory(t *testing.T, client *fakeClient, flags ...string) *Factory {
+	f := NewFactory()
+	v, command := config.Viperize(f.AddFlags)
+	command.ParseFlags(flags)
+	f.InitFromViper(v)
+	f.newClient = func(options *Options) (dynamodbiface.DynamoDBAPI, error) {
+		assert.Equal(t, f.Options, options)
+		return client, nil
+	}
+	return f
+}
+
+func TestFactory(t *testing.T) {
+	client := &fakeClient{}
+	f := newTestFactory(t, client, "--dynamodb.spans-table=spans")
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	assert.Equal(t, []string{"spans", "jaeger_operations"}, client.described)
+
+	_, err := f.CreateSpanReader()
+	require.NoError(t, err)
+	_, err = f.CreateSpanWriter()
+	require.NoError(t, err)
+	_, err = f.CreateDependencyReader()
+	require.NoError(t, err)
+}
+
+func TestFactoryWithoutTablesCreation(t *testing.T) {
+	client := &fakeClient{}
+	f := newTestFactory(t, client, "--dynamodb.create-tables=false")
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	assert.Empty(t, client.described)
+}
+
+func TestFactoryInitializeErrors(t *testing.T) {
+	f := newTestFactory(t, &fakeClient{err: assert.AnError})
+	assert.Error(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+
+	f = newTestFactory(t, nil)
+	f.newClient = func(options *Options) (dynamodbiface.DynamoDBAPI, error) {
+		return nil, assert.AnError
+	}
+	assert.Equal(t, assert.AnError, f.Initialize(metrics.NullFactory, zap.NewNop()))
+}
+
+func TestNewClient(t *testing.T) {
+	client, err := newClient(&Options{Region: "eu-west-1", Endpoint: "http://localhost:8000"})
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8000", client.(*dynamodb.DynamoDB).Endpoint)
+	assert.Equal(t, "eu-west-1", *client.(*dynamodb.DynamoDB).Config.Region)
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dynamodb
+
+import (
+	"flag"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	namespace = "dynamodb"
+
+	suffixRegion          = ".region"
+	suffixEndpoint        = ".endpoint"
+	suffixSpansTable      = ".spans-table"
+	suffixOperationsTable = ".operations-table"
+	suffixCreateTables    = ".create-tables"
+	suffixTTL             = ".ttl"
+
+	defaultSpansTable      = "jaeger_spans"
+	defaultOperationsTable = "jaeger_operations"
+	defaultTTL             = 72 * time.Hour
+)
+
+// Options contains the DynamoDB configs and provides the ability
+// to bind them to command line flags
+type Options struct {
+	// Region is the AWS region of the tables, the region of the environment of the AWS SDK if empty.
+	Region string `mapstructure:"region"`
+	// Endpoint overrides the endpoint of DynamoDB, e.g. to use DynamoDB Local.
+	Endpoint        string        `mapstructure:"endpoint"`
+	SpansTable      string        `mapstructure:"spans_table"`
+	OperationsTable string        `mapstructure:"operations_table"`
+	CreateTables    bool          `mapstructure:"create_tables"`
+	TTL             time.Duration `mapstructure:"ttl"`
+}
+
+// NewOptions creates the Options with the default configuration.
+func NewOptions() *Options {
+	return &Options{
+		SpansTable:      defaultSpansTable,
+		OperationsTable: defaultOperationsTable,
+		CreateTables:    true,
+		TTL:             defaultTTL,
+	}
+}
+
+// AddFlags adds flags for Options
+func (opt *Options) AddFlags(flagSet *flag.FlagSet) {
+	flagSet.String(namespace+suffixRegion, opt.Region, "The AWS region of the tables, by default the region of the environment, e.g. AWS_REGION")
+	flagSet.String(namespace+suffixEndpoint, opt.Endpoint, "The endpoint of DynamoDB if not the endpoint of the region, e.g. http://localhost:8000 for DynamoDB Local")
+	flagSet.String(namespace+suffixSpansTable, opt.SpansTable, "The table of the spans")
+	flagSet.String(namespace+suffixOperationsTable, opt.OperationsTable, "The table of the operations of the services")
+	flagSet.Bool(namespace+suffixCreateTables, opt.CreateTables, "Create the tables which do not exist on startup, billed on demand")
+	flagSet.Duration(namespace+suffixTTL, opt.TTL, "How long to keep the spans before DynamoDB expires them, 0 keeps them forever")
+}
+
+// InitFromViper initializes Options with properties from viper
+func (opt *Options) InitFromViper(v *viper.Viper) {
+	opt.Region = v.GetString(namespace + suffixRegion)
+	opt.Endpoint = v.GetString(namespace + suffixEndpoint)
+	opt.SpansTable = v.GetString(namespace + suffixSpansTable)
+	opt.OperationsTable = v.GetString(namespace + suffixOperationsTable)
+	opt.CreateTables = v.GetBool(namespace + suffixCreateTables)
+	opt.TTL = v.GetDuration(namespace + suffixTTL)
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dynamodb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jaegertracing/jaeger/pkg/config"
+)
+
+func TestDefaultOptions(t *testing.T) {
+	opts := NewOptions()
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags(nil)
+	opts.InitFromViper(v)
+	assert.Equal(t, NewOptions(), opts)
+}
+
+func TestOptionsWithFlags(t *testing.T) {
+	opts := NewOptions()
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{
+		"--dynamodb.region=eu-west-1",
+		"--dynamodb.endpoint=http://localhost:8000",
+		"--dynamodb.spans-table=spans",
+		"--dynamodb.operations-table=operations",
+		"--dynamodb.create-tables=false",
+		"--dynamodb.ttl=24h",
+	})
+	opts.InitFromViper(v)
+	assert.Equal(t, &Options{
+		Region:          "eu-west-1",
+		Endpoint:        "http://localhost:8000",
+		SpansTable:      "spans",
+		OperationsTable: "operations",
+		TTL:             24 * time.Hour,
+	}, opts)
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+// spanItem is an item of the spans table. The span itself is stored in protobuf, the other attributes index it.
+// The service attributes are omitted for the spans without service, which are then not indexed.
+type spanItem struct {
+	TraceID          string   `dynamodbav:"trace_id"`
+	SpanKey          string   `dynamodbav:"span_key"`
+	SpanID           string   `dynamodbav:"span_id"`
+	ParentSpanID     string   `dynamodbav:"parent_span_id,omitempty"`
+	Service          string   `dynamodbav:"service,omitempty"`
+	ServiceOperation string   `dynamodbav:"service_operation,omitempty"`
+	StartTime        int64    `dynamodbav:"start_time"`
+	Duration         int64    `dynamodbav:"duration"`
+	Tags             []string `dynamodbav:"tags,stringset,omitempty"`
+	Span             []byte   `dynamodbav:"span"`
+	ExpiresAt        int64    `dynamodbav:"expires_at,omitempty"`
+}
+
+// operationItem is an item of the operations table.
+type operationItem struct {
+	Service      string `dynamodbav:"service"`
+	OperationKey string `dynamodbav:"operation_key"`
+	Operation    string `dynamodbav:"operation"`
+	SpanKind     string `dynamodbav:"span_kind"`
+	ExpiresAt    int64  `dynamodbav:"expires_at,omitempty"`
+}
+
+// traceIDString returns the trace ID always on 32 hexadecimal digits, unlike TraceID.String.
+func traceIDString(traceID model.TraceID) string {
+	return fmt.Sprintf("%016x%016x", traceID.High, traceID.Low)
+}
+
+func serviceOperation(service, operation string) string {
+	return service + "#" + operation
+}
+
+// tagValue is the element of the tags set of the key and the value.
+func tagValue(key, value string) string {
+	return key + "=" + value
+}
+
+func micros(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Microsecond)
+}
+
+// fromDomain returns the item of the span, expiring after the TTL unless it is zero.
+func fromDomain(span *model.Span, ttl time.Duration) (spanItem, error) {
+	data, err := proto.Marshal(span)
+	if err != nil {
+		return spanItem{}, err
+	}
+	// the spans sharing their ID, e.g. the client and the server spans of Zipkin, are told apart by their hash
+	hash := fnv.New64a()
+	hash.Write(data)
+	item := spanItem{
+		TraceID:   traceIDString(span.TraceID),
+		SpanKey:   fmt.Sprintf("%s#%016x", span.SpanID, hash.Sum64()),
+		SpanID:    span.SpanID.String(),
+		StartTime: micros(span.StartTime),
+		Duration:  int64(span.Duration / time.Microsecond),
+		Span:      data,
+	}
+	if parentID := span.ParentSpanID(); parentID != 0 {
+		item.ParentSpanID = parentID.String()
+	}
+	if span.Process != nil && span.Process.ServiceName != "" {
+		item.Service = span.Process.ServiceName
+		item.ServiceOperation = serviceOperation(item.Service, span.OperationName)
+	}
+	if ttl > 0 {
+		item.ExpiresAt = span.StartTime.Add(ttl).Unix()
+	}
+	tags := make(map[string]struct{})
+	addTags := func(kvs model.KeyValues) {
+		for _, kv := range kvs {
+			tags[tagValue(kv.Key, kv.AsString())] = struct{}{}
+		}
+	}
+	addTags(span.Tags)
+	if span.Process != nil {
+		addTags(span.Process.Tags)
+	}
+	for _, log := range span.Logs {
+		addTags(log.Fields)
+	}
+	for tag := range tags {
+		item.Tags = append(item.Tags, tag)
+	}
+	sort.Strings(item.Tags)
+	return item, nil
+}
+
+func toDomain(data []byte) (*model.Span, error) {
+	span := &model.Span{}
+	if err := proto.Unmarshal(data, span); err != nil {
+		return nil, fmt.Errorf("cannot decode the span: %w", err)
+	}
+	return span, nil
+}
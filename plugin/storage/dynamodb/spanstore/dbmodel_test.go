@@ -0,0 +1,84 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+func testSpan(traceID model.TraceID, spanID model.SpanID, service string) *model.Span {
+	return &model.Span{
+		TraceID:       traceID,
+		SpanID:        spanID,
+		OperationName: "op",
+		References:    []model.SpanRef{model.NewChildOfRef(traceID, 1)},
+		StartTime:     time.Date(2020, 9, 13, 12, 26, 40, 123456000, time.UTC),
+		Duration:      1500 * time.Microsecond,
+		Tags:          model.KeyValues{model.String("span.kind", "server"), model.Int64("http.status_code", 200)},
+		Process:       model.NewProcess(service, []model.KeyValue{model.String("hostname", "host1")}),
+		Logs: []model.Log{{
+			Timestamp: time.Date(2020, 9, 13, 12, 26, 40, 0, time.UTC),
+			Fields:    model.KeyValues{model.String("event", "retry"), model.String("span.kind", "server")},
+		}},
+	}
+}
+
+func TestFromDomain(t *testing.T) {
+	span := testSpan(model.NewTraceID(0, 0x10), 2, "svc")
+	item, err := fromDomain(span, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "00000000000000000000000000000010", item.TraceID)
+	assert.Regexp(t, "^0000000000000002#[0-9a-f]{16}$", item.SpanKey)
+	assert.Equal(t, "0000000000000002", item.SpanID)
+	assert.Equal(t, "0000000000000001", item.ParentSpanID)
+	assert.Equal(t, "svc", item.Service)
+	assert.Equal(t, "svc#op", item.ServiceOperation)
+	assert.Equal(t, span.StartTime.UnixNano()/1000, item.StartTime)
+	assert.Equal(t, int64(1500), item.Duration)
+	assert.Equal(t, span.StartTime.Add(time.Hour).Unix(), item.ExpiresAt)
+	assert.Equal(t, []string{"event=retry", "hostname=host1", "http.status_code=200", "span.kind=server"}, item.Tags)
+
+	decoded, err := toDomain(item.Span)
+	require.NoError(t, err)
+	assert.Equal(t, span, decoded)
+
+	// the key of the spans sharing their ID differs
+	span.Process.ServiceName = "other"
+	other, err := fromDomain(span, 0)
+	require.NoError(t, err)
+	assert.NotEqual(t, item.SpanKey, other.SpanKey)
+	assert.Zero(t, other.ExpiresAt)
+}
+
+func TestFromDomainWithoutService(t *testing.T) {
+	item, err := fromDomain(&model.Span{TraceID: model.NewTraceID(1, 2), SpanID: 1, StartTime: time.Unix(0, 0)}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "00000000000000010000000000000002", item.TraceID)
+	assert.Empty(t, item.ParentSpanID)
+	assert.Empty(t, item.Service)
+	assert.Empty(t, item.ServiceOperation)
+	assert.Empty(t, item.Tags)
+}
+
+func TestToDomainError(t *testing.T) {
+	_, err := toDomain([]byte{0xff})
+	assert.Error(t, err)
+}
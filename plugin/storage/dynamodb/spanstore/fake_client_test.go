@@ -0,0 +1,113 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// fakeClient records the requests and returns the pages of the queries and of the scans in order.
+type fakeClient struct {
+	dynamodbiface.DynamoDBAPI
+
+	puts      []*dynamodb.PutItemInput
+	queries   []*dynamodb.QueryInput
+	scans     []*dynamodb.ScanInput
+	created   []*dynamodb.CreateTableInput
+	ttls      []*dynamodb.UpdateTimeToLiveInput
+	pages     [][]map[string]*dynamodb.AttributeValue
+	existing  map[string]bool
+	err       error
+	putErrors []error
+}
+
+func (c *fakeClient) PutItemWithContext(ctx aws.Context, input *dynamodb.PutItemInput, opts ...request.Option) (*dynamodb.PutItemOutput, error) {
+	c.puts = append(c.puts, input)
+	if len(c.putErrors) > 0 {
+		err := c.putErrors[0]
+		c.putErrors = c.putErrors[1:]
+		return nil, err
+	}
+	return &dynamodb.PutItemOutput{}, c.err
+}
+
+// nextPages returns the pages of a query or of a scan, the pages are separated by nil items.
+func (c *fakeClient) nextPages() [][]map[string]*dynamodb.AttributeValue {
+	var pages [][]map[string]*dynamodb.AttributeValue
+	for len(c.pages) > 0 {
+		page := c.pages[0]
+		c.pages = c.pages[1:]
+		if page == nil {
+			break
+		}
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+func (c *fakeClient) QueryPagesWithContext(ctx aws.Context, input *dynamodb.QueryInput, fn func(*dynamodb.QueryOutput, bool) bool, opts ...request.Option) error {
+	c.queries = append(c.queries, input)
+	if c.err != nil {
+		return c.err
+	}
+	pages := c.nextPages()
+	for i, page := range pages {
+		if !fn(&dynamodb.QueryOutput{Items: page}, i == len(pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+func (c *fakeClient) ScanPagesWithContext(ctx aws.Context, input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool, opts ...request.Option) error {
+	c.scans = append(c.scans, input)
+	if c.err != nil {
+		return c.err
+	}
+	pages := c.nextPages()
+	for i, page := range pages {
+		if !fn(&dynamodb.ScanOutput{Items: page}, i == len(pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+func (c *fakeClient) DescribeTableWithContext(ctx aws.Context, input *dynamodb.DescribeTableInput, opts ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if !c.existing[*input.TableName] {
+		return nil, &dynamodb.ResourceNotFoundException{}
+	}
+	return &dynamodb.DescribeTableOutput{}, nil
+}
+
+func (c *fakeClient) CreateTableWithContext(ctx aws.Context, input *dynamodb.CreateTableInput, opts ...request.Option) (*dynamodb.CreateTableOutput, error) {
+	c.created = append(c.created, input)
+	return &dynamodb.CreateTableOutput{}, nil
+}
+
+func (c *fakeClient) WaitUntilTableExistsWithContext(ctx aws.Context, input *dynamodb.DescribeTableInput, opts ...request.WaiterOption) error {
+	return nil
+}
+
+func (c *fakeClient) UpdateTimeToLiveWithContext(ctx aws.Context, input *dynamodb.UpdateTimeToLiveInput, opts ...request.Option) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	c.ttls = append(c.ttls, input)
+	return &dynamodb.UpdateTimeToLiveOutput{}, nil
+}
@@ -0,0 +1,297 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+const defaultNumTraces = 100
+
+var (
+	// ErrServiceNameNotSet occurs when attempting to query with an empty service name
+	ErrServiceNameNotSet = errors.New("service Name must be set")
+
+	// ErrMalformedRequestObject occurs when a request object is nil
+	ErrMalformedRequestObject = errors.New("malformed request object")
+
+	// ErrStartAndEndTimeNotSet occurs when start time and end time are not set
+	ErrStartAndEndTimeNotSet = errors.New("start and End Time must be set")
+
+	// ErrStartTimeMinGreaterThanMax occurs when start time min is above start time max
+	ErrStartTimeMinGreaterThanMax = errors.New("start Time Minimum is above Maximum")
+
+	// ErrDurationMinGreaterThanMax occurs when duration min is above duration max
+	ErrDurationMinGreaterThanMax = errors.New("duration Minimum is above Maximum")
+)
+
+// SpanReader can query for and load traces from DynamoDB.
+type SpanReader struct {
+	client dynamodbiface.DynamoDBAPI
+	tables Tables
+}
+
+// NewSpanReader returns a new SpanReader.
+func NewSpanReader(client dynamodbiface.DynamoDBAPI, tables Tables) *SpanReader {
+	return &SpanReader{client: client, tables: tables}
+}
+
+// GetTrace takes a traceID and returns a Trace associated with that traceID
+func (r *SpanReader) GetTrace(ctx context.Context, traceID model.TraceID) (*model.Trace, error) {
+	trace := &model.Trace{}
+	var decodeErr error
+	err := r.client.QueryPagesWithContext(ctx, &dynamodb.QueryInput{
+		TableName:                 aws.String(r.tables.Spans),
+		KeyConditionExpression:    aws.String("trace_id = :trace_id"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":trace_id": stringValue(traceIDString(traceID))},
+		ProjectionExpression:      aws.String("#span"),
+		ExpressionAttributeNames:  map[string]*string{"#span": aws.String("span")},
+	}, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			var span *model.Span
+			if span, decodeErr = toDomain(item["span"].B); decodeErr != nil {
+				return false
+			}
+			trace.Spans = append(trace.Spans, span)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+	if len(trace.Spans) == 0 {
+		return nil, spanstore.ErrTraceNotFound
+	}
+	return trace, nil
+}
+
+// GetServices returns all services traced by Jaeger
+func (r *SpanReader) GetServices(ctx context.Context) ([]string, error) {
+	services := make(map[string]struct{})
+	err := r.client.ScanPagesWithContext(ctx, &dynamodb.ScanInput{
+		TableName:                aws.String(r.tables.Operations),
+		ProjectionExpression:     aws.String("#service"),
+		ExpressionAttributeNames: map[string]*string{"#service": aws.String("service")},
+	}, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			if service := item["service"]; service != nil && service.S != nil {
+				services[*service.S] = struct{}{}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, 0, len(services))
+	for service := range services {
+		result = append(result, service)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// GetOperations returns all operations for a specific service traced by Jaeger
+func (r *SpanReader) GetOperations(ctx context.Context, query spanstore.OperationQueryParameters) ([]spanstore.Operation, error) {
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(r.tables.Operations),
+		KeyConditionExpression:    aws.String("#service = :service"),
+		ExpressionAttributeNames:  map[string]*string{"#service": aws.String("service")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":service": stringValue(query.ServiceName)},
+	}
+	if query.SpanKind != "" {
+		input.FilterExpression = aws.String("#span_kind = :span_kind")
+		input.ExpressionAttributeNames["#span_kind"] = aws.String("span_kind")
+		input.ExpressionAttributeValues[":span_kind"] = stringValue(query.SpanKind)
+	}
+	operations := []spanstore.Operation{}
+	var decodeErr error
+	err := r.client.QueryPagesWithContext(ctx, input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		var items []operationItem
+		if decodeErr = dynamodbattribute.UnmarshalListOfMaps(page.Items, &items); decodeErr != nil {
+			return false
+		}
+		for _, item := range items {
+			operations = append(operations, spanstore.Operation{Name: item.Operation, SpanKind: item.SpanKind})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+	return operations, nil
+}
+
+// FindTraces retrieves traces that match the traceQuery
+func (r *SpanReader) FindTraces(ctx context.Context, query *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
+	traceIDs, err := r.FindTraceIDs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	traces := make([]*model.Trace, 0, len(traceIDs))
+	for _, traceID := range traceIDs {
+		trace, err := r.GetTrace(ctx, traceID)
+		if err == spanstore.ErrTraceNotFound {
+			// expired since it was found
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		traces = append(traces, trace)
+	}
+	return traces, nil
+}
+
+// FindTraceIDs retrieves the IDs of the traces that match the traceQuery, the most recent first.
+// The spans are read from the index of the service, or of the operation, in the time range; the duration
+// and the equality of the tags are filtered by DynamoDB, the other tag filters by the query service.
+func (r *SpanReader) FindTraceIDs(ctx context.Context, query *spanstore.TraceQueryParameters) ([]model.TraceID, error) {
+	if err := validateQuery(query); err != nil {
+		return nil, err
+	}
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.tables.Spans),
+		IndexName:              aws.String(serviceIndex),
+		KeyConditionExpression: aws.String("#key = :key AND #start_time BETWEEN :start_min AND :start_max"),
+		ExpressionAttributeNames: map[string]*string{
+			"#key":        aws.String("service"),
+			"#start_time": aws.String("start_time"),
+			"#trace_id":   aws.String("trace_id"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":key":       stringValue(query.ServiceName),
+			":start_min": numberValue(micros(query.StartTimeMin)),
+			":start_max": numberValue(micros(query.StartTimeMax)),
+		},
+		ProjectionExpression: aws.String("#trace_id"),
+		ScanIndexForward:     aws.Bool(false),
+	}
+	if query.OperationName != "" {
+		input.IndexName = aws.String(serviceOperationIndex)
+		input.ExpressionAttributeNames["#key"] = aws.String("service_operation")
+		input.ExpressionAttributeValues[":key"] = stringValue(serviceOperation(query.ServiceName, query.OperationName))
+	}
+	var filters []string
+	if query.DurationMin != 0 || query.DurationMax != 0 {
+		input.ExpressionAttributeNames["#duration"] = aws.String("duration")
+	}
+	if query.DurationMin != 0 {
+		filters = append(filters, "#duration >= :duration_min")
+		input.ExpressionAttributeValues[":duration_min"] = numberValue(int64(query.DurationMin / time.Microsecond))
+	}
+	if query.DurationMax != 0 {
+		filters = append(filters, "#duration <= :duration_max")
+		input.ExpressionAttributeValues[":duration_max"] = numberValue(int64(query.DurationMax / time.Microsecond))
+	}
+	var tags []string
+	for key, value := range query.Tags {
+		tags = append(tags, tagValue(key, value))
+	}
+	for _, filter := range query.TagFilters {
+		if filter.Operator == spanstore.TagEquals {
+			tags = append(tags, tagValue(filter.Key, filter.Value))
+		}
+	}
+	// sorted for the queries to be deterministic
+	sort.Strings(tags)
+	for i, tag := range tags {
+		name := ":tag" + strconv.Itoa(i)
+		filters = append(filters, "contains(#tags, "+name+")")
+		input.ExpressionAttributeValues[name] = stringValue(tag)
+		input.ExpressionAttributeNames["#tags"] = aws.String("tags")
+	}
+	if len(filters) > 0 {
+		input.FilterExpression = aws.String(strings.Join(filters, " AND "))
+	}
+	numTraces := query.NumTraces
+	if numTraces <= 0 {
+		numTraces = defaultNumTraces
+	}
+	var traceIDs []model.TraceID
+	found := make(map[string]struct{})
+	var decodeErr error
+	err := r.client.QueryPagesWithContext(ctx, input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			id := aws.StringValue(item["trace_id"].S)
+			if _, ok := found[id]; ok {
+				continue
+			}
+			found[id] = struct{}{}
+			var traceID model.TraceID
+			if traceID, decodeErr = model.TraceIDFromString(id); decodeErr != nil {
+				return false
+			}
+			traceIDs = append(traceIDs, traceID)
+			if len(traceIDs) == numTraces {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+	return traceIDs, nil
+}
+
+func validateQuery(p *spanstore.TraceQueryParameters) error {
+	if p == nil {
+		return ErrMalformedRequestObject
+	}
+	if p.ServiceName == "" {
+		return ErrServiceNameNotSet
+	}
+	if p.StartTimeMin.IsZero() || p.StartTimeMax.IsZero() {
+		return ErrStartAndEndTimeNotSet
+	}
+	if p.StartTimeMax.Before(p.StartTimeMin) {
+		return ErrStartTimeMinGreaterThanMax
+	}
+	if p.DurationMin != 0 && p.DurationMax != 0 && p.DurationMin > p.DurationMax {
+		return ErrDurationMinGreaterThanMax
+	}
+	return nil
+}
+
+func stringValue(s string) *dynamodb.AttributeValue {
+	return &dynamodb.AttributeValue{S: aws.String(s)}
+}
+
+func numberValue(n int64) *dynamodb.AttributeValue {
+	return &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(n, 10))}
+}
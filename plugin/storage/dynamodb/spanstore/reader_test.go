@@ -0,0 +1,242 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+func spanItems(t *testing.T, spans ...*model.Span) []map[string]*dynamodb.AttributeValue {
+	var items []map[string]*dynamodb.AttributeValue
+	for _, span := range spans {
+		item, err := fromDomain(span, 0)
+		require.NoError(t, err)
+		items = append(items, map[string]*dynamodb.AttributeValue{"span": {B: item.Span}})
+	}
+	return items
+}
+
+func traceIDItems(ids ...string) []map[string]*dynamodb.AttributeValue {
+	var items []map[string]*dynamodb.AttributeValue
+	for _, id := range ids {
+		items = append(items, map[string]*dynamodb.AttributeValue{"trace_id": stringValue(id)})
+	}
+	return items
+}
+
+func TestGetTrace(t *testing.T) {
+	traceID := model.NewTraceID(0, 1)
+	client := &fakeClient{pages: [][]map[string]*dynamodb.AttributeValue{
+		spanItems(t, testSpan(traceID, 1, "a")),
+		spanItems(t, testSpan(traceID, 2, "b")),
+	}}
+	reader := NewSpanReader(client, testTables)
+	trace, err := reader.GetTrace(context.Background(), traceID)
+	require.NoError(t, err)
+	assert.Len(t, trace.Spans, 2)
+	assert.Equal(t, "spans", *client.queries[0].TableName)
+	assert.Equal(t, "00000000000000000000000000000001", *client.queries[0].ExpressionAttributeValues[":trace_id"].S)
+
+	_, err = reader.GetTrace(context.Background(), traceID)
+	assert.Equal(t, spanstore.ErrTraceNotFound, err)
+}
+
+func TestGetTraceErrors(t *testing.T) {
+	reader := NewSpanReader(&fakeClient{err: assert.AnError}, testTables)
+	_, err := reader.GetTrace(context.Background(), model.NewTraceID(0, 1))
+	assert.Equal(t, assert.AnError, err)
+
+	reader = NewSpanReader(&fakeClient{pages: [][]map[string]*dynamodb.AttributeValue{
+		{{"span": {B: []byte{0xff}}}},
+	}}, testTables)
+	_, err = reader.GetTrace(context.Background(), model.NewTraceID(0, 1))
+	assert.Error(t, err)
+}
+
+func TestGetServices(t *testing.T) {
+	client := &fakeClient{pages: [][]map[string]*dynamodb.AttributeValue{
+		{{"service": stringValue("b")}, {"service": stringValue("a")}},
+		{{"service": stringValue("b")}, {}},
+	}}
+	reader := NewSpanReader(client, testTables)
+	services, err := reader.GetServices(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, services)
+	assert.Equal(t, "operations", *client.scans[0].TableName)
+
+	reader = NewSpanReader(&fakeClient{err: assert.AnError}, testTables)
+	_, err = reader.GetServices(context.Background())
+	assert.Equal(t, assert.AnError, err)
+}
+
+func TestGetOperations(t *testing.T) {
+	client := &fakeClient{pages: [][]map[string]*dynamodb.AttributeValue{
+		{{"operation": stringValue("get"), "span_kind": stringValue("server")}},
+		nil,
+		{{"operation": stringValue("get"), "span_kind": stringValue("client")}},
+	}}
+	reader := NewSpanReader(client, testTables)
+	operations, err := reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "a"})
+	require.NoError(t, err)
+	assert.Equal(t, []spanstore.Operation{{Name: "get", SpanKind: "server"}}, operations)
+	assert.Nil(t, client.queries[0].FilterExpression)
+	assert.Equal(t, "a", *client.queries[0].ExpressionAttributeValues[":service"].S)
+
+	operations, err = reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "a", SpanKind: "client"})
+	require.NoError(t, err)
+	assert.Equal(t, []spanstore.Operation{{Name: "get", SpanKind: "client"}}, operations)
+	assert.Equal(t, "#span_kind = :span_kind", *client.queries[1].FilterExpression)
+
+	reader = NewSpanReader(&fakeClient{err: assert.AnError}, testTables)
+	_, err = reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "a"})
+	assert.Equal(t, assert.AnError, err)
+
+	reader = NewSpanReader(&fakeClient{pages: [][]map[string]*dynamodb.AttributeValue{
+		{{"operation": {BOOL: aws.Bool(true)}}},
+	}}, testTables)
+	_, err = reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "a"})
+	assert.Error(t, err)
+}
+
+func TestFindTraceIDs(t *testing.T) {
+	client := &fakeClient{pages: [][]map[string]*dynamodb.AttributeValue{
+		traceIDItems("00000000000000000000000000000002", "00000000000000000000000000000002"),
+		traceIDItems("00000000000000010000000000000001", "00000000000000000000000000000003"),
+	}}
+	reader := NewSpanReader(client, testTables)
+	equals, err := spanstore.ParseTagFilter("peer.service=db")
+	require.NoError(t, err)
+	regex, err := spanstore.ParseTagFilter("http.url=~.*api.*")
+	require.NoError(t, err)
+	traceIDs, err := reader.FindTraceIDs(context.Background(), &spanstore.TraceQueryParameters{
+		ServiceName:   "svc",
+		OperationName: "op",
+		Tags:          map[string]string{"http.status_code": "500"},
+		TagFilters:    []spanstore.TagFilter{equals, regex},
+		StartTimeMin:  time.Unix(1, 0),
+		StartTimeMax:  time.Unix(2, 0),
+		DurationMin:   time.Millisecond,
+		DurationMax:   time.Second,
+		NumTraces:     2,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []model.TraceID{model.NewTraceID(0, 2), model.NewTraceID(1, 1)}, traceIDs)
+
+	query := client.queries[0]
+	assert.Equal(t, serviceOperationIndex, *query.IndexName)
+	assert.False(t, *query.ScanIndexForward)
+	assert.Equal(t, "service_operation", *query.ExpressionAttributeNames["#key"])
+	assert.Equal(t, "svc#op", *query.ExpressionAttributeValues[":key"].S)
+	assert.Equal(t, "1000000", *query.ExpressionAttributeValues[":start_min"].N)
+	assert.Equal(t, "2000000", *query.ExpressionAttributeValues[":start_max"].N)
+	assert.Equal(t, "#duration >= :duration_min AND #duration <= :duration_max AND "+
+		"contains(#tags, :tag0) AND contains(#tags, :tag1)", *query.FilterExpression)
+	assert.Equal(t, "1000", *query.ExpressionAttributeValues[":duration_min"].N)
+	assert.Equal(t, "1000000", *query.ExpressionAttributeValues[":duration_max"].N)
+	assert.Equal(t, "http.status_code=500", *query.ExpressionAttributeValues[":tag0"].S)
+	assert.Equal(t, "peer.service=db", *query.ExpressionAttributeValues[":tag1"].S)
+}
+
+func TestFindTraceIDsOfService(t *testing.T) {
+	client := &fakeClient{}
+	reader := NewSpanReader(client, testTables)
+	traceIDs, err := reader.FindTraceIDs(context.Background(), &spanstore.TraceQueryParameters{
+		ServiceName:  "svc",
+		StartTimeMin: time.Unix(1, 0),
+		StartTimeMax: time.Unix(2, 0),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, traceIDs)
+	assert.Equal(t, serviceIndex, *client.queries[0].IndexName)
+	assert.Equal(t, "svc", *client.queries[0].ExpressionAttributeValues[":key"].S)
+	assert.Nil(t, client.queries[0].FilterExpression)
+}
+
+func TestFindTraceIDsErrors(t *testing.T) {
+	query := &spanstore.TraceQueryParameters{ServiceName: "svc", StartTimeMin: time.Unix(1, 0), StartTimeMax: time.Unix(2, 0)}
+	reader := NewSpanReader(&fakeClient{err: assert.AnError}, testTables)
+	_, err := reader.FindTraceIDs(context.Background(), query)
+	assert.Equal(t, assert.AnError, err)
+	_, err = reader.FindTraces(context.Background(), query)
+	assert.Equal(t, assert.AnError, err)
+
+	reader = NewSpanReader(&fakeClient{pages: [][]map[string]*dynamodb.AttributeValue{traceIDItems("xyz")}}, testTables)
+	_, err = reader.FindTraceIDs(context.Background(), query)
+	assert.Error(t, err)
+}
+
+func TestValidateQuery(t *testing.T) {
+	start, end := time.Unix(1, 0), time.Unix(2, 0)
+	for _, test := range []struct {
+		query *spanstore.TraceQueryParameters
+		err   error
+	}{
+		{nil, ErrMalformedRequestObject},
+		{&spanstore.TraceQueryParameters{StartTimeMin: start, StartTimeMax: end}, ErrServiceNameNotSet},
+		{&spanstore.TraceQueryParameters{ServiceName: "svc", StartTimeMin: start}, ErrStartAndEndTimeNotSet},
+		{&spanstore.TraceQueryParameters{ServiceName: "svc", StartTimeMin: end, StartTimeMax: start}, ErrStartTimeMinGreaterThanMax},
+		{&spanstore.TraceQueryParameters{ServiceName: "svc", StartTimeMin: start, StartTimeMax: end, DurationMin: time.Second, DurationMax: time.Millisecond}, ErrDurationMinGreaterThanMax},
+		{&spanstore.TraceQueryParameters{ServiceName: "svc", StartTimeMin: start, StartTimeMax: end}, nil},
+	} {
+		assert.Equal(t, test.err, validateQuery(test.query))
+	}
+}
+
+func TestFindTraces(t *testing.T) {
+	first, second := model.NewTraceID(0, 1), model.NewTraceID(0, 2)
+	client := &fakeClient{pages: [][]map[string]*dynamodb.AttributeValue{
+		traceIDItems("00000000000000000000000000000002", "00000000000000000000000000000001", "00000000000000000000000000000003"),
+		nil,
+		spanItems(t, testSpan(second, 1, "a")),
+		nil,
+		spanItems(t, testSpan(first, 1, "a"), testSpan(first, 2, "b")),
+		// the third trace expired
+		nil,
+	}}
+	reader := NewSpanReader(client, testTables)
+	traces, err := reader.FindTraces(context.Background(), &spanstore.TraceQueryParameters{
+		ServiceName:  "svc",
+		StartTimeMin: time.Unix(1, 0),
+		StartTimeMax: time.Unix(2, 0),
+	})
+	require.NoError(t, err)
+	require.Len(t, traces, 2)
+	assert.Equal(t, second, traces[0].Spans[0].TraceID)
+	assert.Len(t, traces[1].Spans, 2)
+	assert.Len(t, client.queries, 4)
+
+	client = &fakeClient{pages: [][]map[string]*dynamodb.AttributeValue{
+		traceIDItems("00000000000000000000000000000001"),
+		nil,
+		{{"span": {B: []byte{0xff}}}},
+	}}
+	reader = NewSpanReader(client, testTables)
+	_, err = reader.FindTraces(context.Background(), &spanstore.TraceQueryParameters{
+		ServiceName:  "svc",
+		StartTimeMin: time.Unix(1, 0),
+		StartTimeMax: time.Unix(2, 0),
+	})
+	assert.Error(t, err)
+}
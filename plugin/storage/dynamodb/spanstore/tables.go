@@ -0,0 +1,125 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// The secondary indexes of the spans table, their sort key is the start time of the spans.
+const (
+	serviceIndex          = "service_index"
+	serviceOperationIndex = "service_operation_index"
+)
+
+// Tables are the names of the spans table and of the operations table.
+//
+// The spans are keyed by trace ID, with the secondary indexes used by the searches, and the operations
+// by service. The items of both tables expire with their expires_at attribute.
+type Tables struct {
+	Spans      string
+	Operations string
+}
+
+// CreateTables creates the tables which do not exist, billed on demand, and enables their expiration.
+func CreateTables(ctx context.Context, client dynamodbiface.DynamoDBAPI, tables Tables) error {
+	indexProjection := &dynamodb.Projection{
+		ProjectionType:   aws.String(dynamodb.ProjectionTypeInclude),
+		NonKeyAttributes: aws.StringSlice([]string{"duration", "tags"}),
+	}
+	inputs := []*dynamodb.CreateTableInput{
+		{
+			TableName:   aws.String(tables.Spans),
+			BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+			AttributeDefinitions: []*dynamodb.AttributeDefinition{
+				attribute("trace_id", dynamodb.ScalarAttributeTypeS),
+				attribute("span_key", dynamodb.ScalarAttributeTypeS),
+				attribute("service", dynamodb.ScalarAttributeTypeS),
+				attribute("service_operation", dynamodb.ScalarAttributeTypeS),
+				attribute("start_time", dynamodb.ScalarAttributeTypeN),
+			},
+			KeySchema: keys("trace_id", "span_key"),
+			GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{
+				{
+					IndexName:  aws.String(serviceIndex),
+					KeySchema:  keys("service", "start_time"),
+					Projection: indexProjection,
+				},
+				{
+					IndexName:  aws.String(serviceOperationIndex),
+					KeySchema:  keys("service_operation", "start_time"),
+					Projection: indexProjection,
+				},
+			},
+		},
+		{
+			TableName:   aws.String(tables.Operations),
+			BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+			AttributeDefinitions: []*dynamodb.AttributeDefinition{
+				attribute("service", dynamodb.ScalarAttributeTypeS),
+				attribute("operation_key", dynamodb.ScalarAttributeTypeS),
+			},
+			KeySchema: keys("service", "operation_key"),
+		},
+	}
+	for _, input := range inputs {
+		if err := createTable(ctx, client, input); err != nil {
+			return fmt.Errorf("cannot create the DynamoDB table %s: %w", *input.TableName, err)
+		}
+	}
+	return nil
+}
+
+func createTable(ctx context.Context, client dynamodbiface.DynamoDBAPI, input *dynamodb.CreateTableInput) error {
+	describe := &dynamodb.DescribeTableInput{TableName: input.TableName}
+	_, err := client.DescribeTableWithContext(ctx, describe)
+	if err == nil {
+		return nil
+	}
+	if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != dynamodb.ErrCodeResourceNotFoundException {
+		return err
+	}
+	if _, err := client.CreateTableWithContext(ctx, input); err != nil {
+		return err
+	}
+	if err := client.WaitUntilTableExistsWithContext(ctx, describe); err != nil {
+		return err
+	}
+	_, err = client.UpdateTimeToLiveWithContext(ctx, &dynamodb.UpdateTimeToLiveInput{
+		TableName: input.TableName,
+		TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+			AttributeName: aws.String("expires_at"),
+			Enabled:       aws.Bool(true),
+		},
+	})
+	return err
+}
+
+func attribute(name, typ string) *dynamodb.AttributeDefinition {
+	return &dynamodb.AttributeDefinition{AttributeName: aws.String(name), AttributeType: aws.String(typ)}
+}
+
+func keys(partition, sort string) []*dynamodb.KeySchemaElement {
+	return []*dynamodb.KeySchemaElement{
+		{AttributeName: aws.String(partition), KeyType: aws.String(dynamodb.KeyTypeHash)},
+		{AttributeName: aws.String(sort), KeyType: aws.String(dynamodb.KeyTypeRange)},
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testTables = Tables{Spans: "spans", Operations: "operations"}
+
+func TestCreateTables(t *testing.T) {
+	client := &fakeClient{}
+	require.NoError(t, CreateTables(context.Background(), client, testTables))
+	require.Len(t, client.created, 2)
+	spans := client.created[0]
+	assert.Equal(t, "spans", *spans.TableName)
+	assert.Equal(t, "PAY_PER_REQUEST", *spans.BillingMode)
+	require.Len(t, spans.GlobalSecondaryIndexes, 2)
+	assert.Equal(t, serviceIndex, *spans.GlobalSecondaryIndexes[0].IndexName)
+	assert.Equal(t, "service", *spans.GlobalSecondaryIndexes[0].KeySchema[0].AttributeName)
+	assert.Equal(t, "start_time", *spans.GlobalSecondaryIndexes[0].KeySchema[1].AttributeName)
+	assert.Equal(t, serviceOperationIndex, *spans.GlobalSecondaryIndexes[1].IndexName)
+	assert.Equal(t, "operations", *client.created[1].TableName)
+	require.Len(t, client.ttls, 2)
+	assert.Equal(t, "expires_at", *client.ttls[0].TimeToLiveSpecification.AttributeName)
+	assert.True(t, *client.ttls[1].TimeToLiveSpecification.Enabled)
+}
+
+func TestCreateTablesExisting(t *testing.T) {
+	client := &fakeClient{existing: map[string]bool{"spans": true}}
+	require.NoError(t, CreateTables(context.Background(), client, testTables))
+	require.Len(t, client.created, 1)
+	assert.Equal(t, "operations", *client.created[0].TableName)
+}
+
+func TestCreateTablesError(t *testing.T) {
+	client := &fakeClient{err: assert.AnError}
+	err := CreateTables(context.Background(), client, testTables)
+	assert.EqualError(t, err, "cannot create the DynamoDB table spans: "+assert.AnError.Error())
+	assert.Empty(t, client.created)
+}
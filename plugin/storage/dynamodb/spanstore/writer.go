@@ -0,0 +1,115 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/cache"
+	storageMetrics "github.com/jaegertracing/jaeger/storage/spanstore/metrics"
+)
+
+// SpanWriterParams holds constructor parameters for NewSpanWriter
+type SpanWriterParams struct {
+	Client         dynamodbiface.DynamoDBAPI
+	Tables         Tables
+	MetricsFactory metrics.Factory
+	// TTL is the retention of the spans, they do not expire if it is zero.
+	TTL time.Duration
+}
+
+// SpanWriter puts the spans into DynamoDB.
+type SpanWriter struct {
+	client dynamodbiface.DynamoDBAPI
+	tables Tables
+	ttl    time.Duration
+	// operations are the operations put recently, they are not put again until they are about to expire
+	operations cache.Cache
+	spans      *storageMetrics.WriteMetrics
+}
+
+// NewSpanWriter creates a SpanWriter.
+func NewSpanWriter(p SpanWriterParams) *SpanWriter {
+	operationsTTL := 12 * time.Hour
+	if p.TTL > 0 && p.TTL/2 < operationsTTL {
+		operationsTTL = p.TTL / 2
+	}
+	return &SpanWriter{
+		client: p.Client,
+		tables: p.Tables,
+		ttl:    p.TTL,
+		operations: cache.NewLRUWithOptions(100000, &cache.Options{
+			TTL: operationsTTL,
+		}),
+		spans: storageMetrics.NewWriteMetrics(p.MetricsFactory, "spans"),
+	}
+}
+
+// WriteSpan puts the span and its operation, unless the operation was put recently.
+func (w *SpanWriter) WriteSpan(span *model.Span) error {
+	item, err := fromDomain(span, w.ttl)
+	if err != nil {
+		return err
+	}
+	if err := w.put(w.tables.Spans, item, w.spans); err != nil {
+		return err
+	}
+	if item.Service == "" {
+		return nil
+	}
+	spanKind, _ := span.GetSpanKind()
+	operation := operationItem{
+		Service:      item.Service,
+		OperationKey: span.OperationName + "#" + spanKind,
+		Operation:    span.OperationName,
+		SpanKind:     spanKind,
+	}
+	if w.ttl > 0 {
+		operation.ExpiresAt = time.Now().Add(w.ttl).Unix()
+	}
+	key := operation.Service + "#" + operation.OperationKey
+	if w.operations.Get(key) != nil {
+		return nil
+	}
+	if err := w.put(w.tables.Operations, operation, nil); err != nil {
+		return err
+	}
+	w.operations.Put(key, true)
+	return nil
+}
+
+func (w *SpanWriter) put(table string, item interface{}, writeMetrics *storageMetrics.WriteMetrics) error {
+	attributes, err := dynamodbattribute.MarshalMap(item)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	_, err = w.client.PutItemWithContext(context.Background(), &dynamodb.PutItemInput{
+		TableName: aws.String(table),
+		Item:      attributes,
+	})
+	if writeMetrics != nil {
+		writeMetrics.Emit(err, time.Since(start))
+	}
+	return err
+}
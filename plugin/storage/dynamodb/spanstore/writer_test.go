@@ -0,0 +1,90 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+func newTestWriter(client *fakeClient, ttl time.Duration) (*SpanWriter, *metricstest.Factory) {
+	metricsFactory := metricstest.NewFactory(0)
+	return NewSpanWriter(SpanWriterParams{
+		Client:         client,
+		Tables:         testTables,
+		MetricsFactory: metricsFactory,
+		TTL:            ttl,
+	}), metricsFactory
+}
+
+func TestWriteSpan(t *testing.T) {
+	client := &fakeClient{}
+	writer, metricsFactory := newTestWriter(client, time.Hour)
+	traceID := model.NewTraceID(0, 1)
+
+	require.NoError(t, writer.WriteSpan(testSpan(traceID, 1, "svc")))
+	require.NoError(t, writer.WriteSpan(testSpan(traceID, 2, "svc")))
+	// the operation is put once
+	require.Len(t, client.puts, 3)
+	assert.Equal(t, "spans", *client.puts[0].TableName)
+	assert.Equal(t, "operations", *client.puts[1].TableName)
+	assert.Equal(t, "spans", *client.puts[2].TableName)
+
+	var span spanItem
+	require.NoError(t, dynamodbattribute.UnmarshalMap(client.puts[2].Item, &span))
+	assert.Equal(t, "0000000000000002", span.SpanID)
+	var operation operationItem
+	require.NoError(t, dynamodbattribute.UnmarshalMap(client.puts[1].Item, &operation))
+	assert.Equal(t, "svc", operation.Service)
+	assert.Equal(t, "op#server", operation.OperationKey)
+	assert.Equal(t, "op", operation.Operation)
+	assert.Equal(t, "server", operation.SpanKind)
+	assert.NotZero(t, operation.ExpiresAt)
+
+	metricsFactory.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "spans.attempts", Value: 2},
+		metricstest.ExpectedMetric{Name: "spans.inserts", Value: 2})
+}
+
+func TestWriteSpanWithoutService(t *testing.T) {
+	client := &fakeClient{}
+	writer, _ := newTestWriter(client, 0)
+	require.NoError(t, writer.WriteSpan(&model.Span{TraceID: model.NewTraceID(0, 1), SpanID: 1}))
+	// the span is not indexed and has no operation
+	require.Len(t, client.puts, 1)
+	assert.NotContains(t, client.puts[0].Item, "service")
+	assert.NotContains(t, client.puts[0].Item, "expires_at")
+}
+
+func TestWriteSpanErrors(t *testing.T) {
+	client := &fakeClient{putErrors: []error{assert.AnError, nil, assert.AnError}}
+	writer, metricsFactory := newTestWriter(client, time.Hour)
+	span := testSpan(model.NewTraceID(0, 1), 1, "svc")
+
+	assert.Equal(t, assert.AnError, writer.WriteSpan(span))
+	metricsFactory.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "spans.errors", Value: 1})
+	assert.Equal(t, assert.AnError, writer.WriteSpan(span))
+	// the operation is put with the next span
+	require.NoError(t, writer.WriteSpan(span))
+	assert.Len(t, client.puts, 5)
+	assert.Equal(t, "operations", *client.puts[4].TableName)
+}
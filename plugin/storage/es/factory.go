@@ -17,6 +17,7 @@ package es
 
 import (
 	"bufio"
+	"context"
 	"flag"
 	"fmt"
 	"os"
@@ -33,13 +34,16 @@ import (
 	esDepStore "github.com/jaegertracing/jaeger/plugin/storage/es/dependencystore"
 	"github.com/jaegertracing/jaeger/plugin/storage/es/mappings"
 	esSpanStore "github.com/jaegertracing/jaeger/plugin/storage/es/spanstore"
+	"github.com/jaegertracing/jaeger/plugin/storage/es/spanstore/dbmodel"
+	"github.com/jaegertracing/jaeger/storage"
 	"github.com/jaegertracing/jaeger/storage/dependencystore"
 	"github.com/jaegertracing/jaeger/storage/spanstore"
 )
 
 const (
-	primaryNamespace = "es"
-	archiveNamespace = "es-archive"
+	primaryNamespace   = "es"
+	archiveNamespace   = "es-archive"
+	migrationNamespace = "es-migration"
 )
 
 // Factory implements storage.Factory for Elasticsearch backend.
@@ -53,12 +57,15 @@ type Factory struct {
 	primaryClient es.Client
 	archiveConfig config.ClientBuilder
 	archiveClient es.Client
+	// the spans are also written to and read from the migration cluster or index layout when it is enabled
+	migrationConfig config.ClientBuilder
+	migrationClient es.Client
 }
 
 // NewFactory creates a new Factory.
 func NewFactory() *Factory {
 	return &Factory{
-		Options: NewOptions(primaryNamespace, archiveNamespace),
+		Options: NewOptions(primaryNamespace, archiveNamespace, migrationNamespace),
 	}
 }
 
@@ -72,6 +79,7 @@ func (f *Factory) InitFromViper(v *viper.Viper) {
 	f.Options.InitFromViper(v)
 	f.primaryConfig = f.Options.GetPrimary()
 	f.archiveConfig = f.Options.Get(archiveNamespace)
+	f.migrationConfig = f.Options.Get(migrationNamespace)
 }
 
 // InitFromOptions configures factory from Options struct.
@@ -81,6 +89,9 @@ func (f *Factory) InitFromOptions(o Options) {
 	if cfg := f.Options.Get(archiveNamespace); cfg != nil {
 		f.archiveConfig = cfg
 	}
+	if cfg := f.Options.Get(migrationNamespace); cfg != nil {
+		f.migrationConfig = cfg
+	}
 }
 
 // Initialize implements storage.Factory
@@ -98,17 +109,106 @@ func (f *Factory) Initialize(metricsFactory metrics.Factory, logger *zap.Logger)
 			return fmt.Errorf("failed to create archive Elasticsearch client: %w", err)
 		}
 	}
+	if f.migrationEnabled() {
+		// the bulk requests to the migration target are accounted for separately, so that its failures do not
+		// go unnoticed nor hide those of the primary storage
+		f.migrationClient, err = f.migrationConfig.NewClient(f.migrationLogger(), f.migrationMetrics())
+		if err != nil {
+			return fmt.Errorf("failed to create migration Elasticsearch client: %w", err)
+		}
+	}
 	return nil
 }
 
-// CreateSpanReader implements storage.Factory
+func (f *Factory) migrationEnabled() bool {
+	return f.migrationConfig != nil && f.migrationConfig.IsStorageEnabled()
+}
+
+func (f *Factory) migrationMetrics() metrics.Factory {
+	return f.metricsFactory.Namespace(metrics.NSOptions{Name: "migration"})
+}
+
+func (f *Factory) migrationLogger() *zap.Logger {
+	return f.logger.With(zap.String("storage", "migration"))
+}
+
+// CreateSpanReader implements storage.Factory. During a migration the spans found in the primary
+// storage and in the migration target are merged.
 func (f *Factory) CreateSpanReader() (spanstore.Reader, error) {
-	return createSpanReader(f.metricsFactory, f.logger, f.primaryClient, f.primaryConfig, false)
+	reader, err := createSpanReader(f.metricsFactory, f.logger, f.primaryClient, f.primaryConfig, false)
+	if err != nil || !f.migrationEnabled() {
+		return reader, err
+	}
+	migrationReader, err := createSpanReader(f.migrationMetrics(), f.migrationLogger(), f.migrationClient, f.migrationConfig, false)
+	if err != nil {
+		return nil, err
+	}
+	return spanstore.NewFederatedReader(reader, migrationReader), nil
 }
 
-// CreateSpanWriter implements storage.Factory
+// CreateSpanWriter implements storage.Factory. During a migration the spans are written to both
+// the primary storage and the migration target.
 func (f *Factory) CreateSpanWriter() (spanstore.Writer, error) {
-	return createSpanWriter(f.metricsFactory, f.logger, f.primaryClient, f.primaryConfig, false)
+	writer, err := createSpanWriter(f.metricsFactory, f.logger, f.primaryClient, f.primaryConfig, false)
+	if err != nil || !f.migrationEnabled() {
+		return writer, err
+	}
+	migrationWriter, err := createSpanWriter(f.migrationMetrics(), f.migrationLogger(), f.migrationClient, f.migrationConfig, false)
+	if err != nil {
+		return nil, err
+	}
+	return spanstore.NewCompositeWriter(writer, migrationWriter), nil
+}
+
+// CreateTenantSpanReader implements storage.TenantFactory, the spans of the tenant are read
+// from the indices prefixed by the tenant. During a migration to or from indices per tenant,
+// they are merged with the spans of the shared indices of the other storage.
+func (f *Factory) CreateTenantSpanReader(tenant string) (spanstore.Reader, error) {
+	migrationPerTenant := f.migrationEnabled() && f.migrationConfig.GetTenantIndexPrefix()
+	if !f.primaryConfig.GetTenantIndexPrefix() && !migrationPerTenant {
+		return nil, storage.ErrTenantStorageNotSupported
+	}
+	primaryConfig, err := getTenantConfig(f.primaryConfig, tenant)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := createSpanReader(f.metricsFactory, f.logger, f.primaryClient, primaryConfig, false)
+	if err != nil || !f.migrationEnabled() {
+		return reader, err
+	}
+	migrationConfig, err := getTenantConfig(f.migrationConfig, tenant)
+	if err != nil {
+		return nil, err
+	}
+	migrationReader, err := createSpanReader(f.migrationMetrics(), f.migrationLogger(), f.migrationClient, migrationConfig, false)
+	if err != nil {
+		return nil, err
+	}
+	return spanstore.NewFederatedReader(reader, migrationReader), nil
+}
+
+// getTenantConfig returns the configuration of the indices of the tenant, the shared indices
+// unless they are prefixed by the tenant.
+func getTenantConfig(cfg config.ClientBuilder, tenant string) (config.ClientBuilder, error) {
+	if !cfg.GetTenantIndexPrefix() {
+		return cfg, nil
+	}
+	indexPrefix, err := esSpanStore.TenantIndexPrefix(cfg.GetIndexPrefix(), tenant)
+	if err != nil {
+		return nil, err
+	}
+	return tenantConfig{ClientBuilder: cfg, indexPrefix: indexPrefix}, nil
+}
+
+// tenantConfig is the configuration of the indices of a tenant
+type tenantConfig struct {
+	config.ClientBuilder
+	indexPrefix string
+}
+
+// GetIndexPrefix returns the index prefix of the tenant
+func (c tenantConfig) GetIndexPrefix() string {
+	return c.indexPrefix
 }
 
 // CreateDependencyReader implements storage.Factory
@@ -159,6 +259,10 @@ func createSpanReader(
 	cfg config.ClientBuilder,
 	archive bool,
 ) (spanstore.Reader, error) {
+	tagStrategies, err := getTagStrategies(cfg)
+	if err != nil {
+		return nil, err
+	}
 	return esSpanStore.NewSpanReader(esSpanStore.SpanReaderParams{
 		Client:              client,
 		Logger:              logger,
@@ -168,6 +272,11 @@ func createSpanReader(
 		IndexPrefix:         cfg.GetIndexPrefix(),
 		TagDotReplacement:   cfg.GetTagDotReplacement(),
 		UseReadWriteAliases: cfg.GetUseReadWriteAliases(),
+		UseDataStreams:      cfg.GetUseDataStreams(),
+		AsyncSearch:         cfg.GetAsyncSearch(),
+		PointInTime:         cfg.GetPointInTime(),
+		UnindexedFields:     cfg.GetStorage().UnindexedFields,
+		FlattenedTags:       tagStrategies.Uses(dbmodel.FlattenedTags),
 		Archive:             archive,
 	}), nil
 }
@@ -179,30 +288,67 @@ func createSpanWriter(
 	cfg config.ClientBuilder,
 	archive bool,
 ) (spanstore.Writer, error) {
-	var tags []string
-	if cfg.GetTagsFilePath() != "" {
-		var err error
-		if tags, err = loadTagsFromFile(cfg.GetTagsFilePath()); err != nil {
-			logger.Error("Could not open file with tags", zap.Error(err))
-			return nil, err
-		}
+	tagStrategies, err := getTagStrategies(cfg)
+	if err != nil {
+		logger.Error("Could not load the tag strategies", zap.Error(err))
+		return nil, err
 	}
 
 	spanMapping, serviceMapping := GetSpanServiceMappings(cfg.GetNumShards(), cfg.GetNumReplicas(), client.GetVersion())
+	spanMapping, serviceMapping, err = applyStorage(spanMapping, serviceMapping, cfg.GetStorage(), client.GetVersion())
+	if err != nil {
+		return nil, err
+	}
+	if spanMapping, err = applyTagStrategies(spanMapping, tagStrategies, client.GetVersion()); err != nil {
+		return nil, err
+	}
+	// Elasticsearch 8 deprecates the legacy index templates
+	composable := cfg.GetComposableTemplates() || client.GetVersion() >= 8
+	if composable && client.GetVersion() < 7 {
+		return nil, fmt.Errorf("composable index templates require Elasticsearch 7.8 or later")
+	}
+	var lifecycle *indexLifecycle
+	if !archive && cfg.GetIndexLifecycle().Type != "" {
+		if lifecycle, err = newIndexLifecycle(client, cfg); err != nil {
+			return nil, err
+		}
+		lifecycle.composable = composable
+		if err = lifecycle.createPolicy(context.Background()); err != nil {
+			return nil, err
+		}
+		if cfg.GetUseDataStreams() {
+			if spanMapping, serviceMapping, err = lifecycle.attachToDataStreams(spanMapping, serviceMapping); err != nil {
+				return nil, err
+			}
+		}
+	}
 	writer := esSpanStore.NewSpanWriter(esSpanStore.SpanWriterParams{
 		Client:              client,
 		Logger:              logger,
 		MetricsFactory:      mFactory,
 		IndexPrefix:         cfg.GetIndexPrefix(),
-		AllTagsAsFields:     cfg.GetAllTagsAsFields(),
-		TagKeysAsFields:     tags,
 		TagDotReplacement:   cfg.GetTagDotReplacement(),
 		Archive:             archive,
 		UseReadWriteAliases: cfg.GetUseReadWriteAliases(),
+		UseDataStreams:      cfg.GetUseDataStreams(),
+		Serverless:          cfg.GetAWSSigning().Serverless,
+		TenantIndexPrefix:   cfg.GetTenantIndexPrefix(),
+		MeasureSize:         cfg.GetStorage().MeasureSize,
+		TagStrategies:       tagStrategies,
 	})
 	if cfg.IsCreateIndexTemplates() {
-		err := writer.CreateTemplates(spanMapping, serviceMapping)
-		if err != nil {
+		createTemplates := writer.CreateTemplates
+		if cfg.GetUseDataStreams() && !archive {
+			createTemplates = writer.CreateDataStreams
+		} else if composable {
+			createTemplates = writer.CreateComposableTemplates
+		}
+		if err := createTemplates(spanMapping, serviceMapping); err != nil {
+			return nil, err
+		}
+	}
+	if lifecycle != nil && !cfg.GetUseDataStreams() {
+		if err := lifecycle.bootstrapRolloverIndices(context.Background()); err != nil {
 			return nil, err
 		}
 	}
@@ -211,7 +357,7 @@ func createSpanWriter(
 
 // GetSpanServiceMappings returns span and service mappings
 func GetSpanServiceMappings(shards, replicas int64, esVersion uint) (string, string) {
-	if esVersion == 7 {
+	if esVersion >= 7 {
 		return fixMapping(loadMapping("/jaeger-span-7.json"), shards, replicas),
 			fixMapping(loadMapping("/jaeger-service-7.json"), shards, replicas)
 	}
@@ -221,7 +367,7 @@ func GetSpanServiceMappings(shards, replicas int64, esVersion uint) (string, str
 
 // GetDependenciesMappings returns dependencies mappings
 func GetDependenciesMappings(shards, replicas int64, esVersion uint) string {
-	if esVersion == 7 {
+	if esVersion >= 7 {
 		return fixMapping(loadMapping("/jaeger-dependencies-7.json"), shards, replicas)
 	}
 	return fixMapping(loadMapping("/jaeger-dependencies.json"), shards, replicas)
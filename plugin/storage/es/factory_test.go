@@ -23,6 +23,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -35,14 +36,17 @@ import (
 	escfg "github.com/jaegertracing/jaeger/pkg/es/config"
 	"github.com/jaegertracing/jaeger/pkg/es/mocks"
 	"github.com/jaegertracing/jaeger/storage"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
 )
 
 var _ storage.Factory = new(Factory)
+var _ storage.TenantFactory = new(Factory)
 
 type mockClientBuilder struct {
 	escfg.Configuration
 	err                 error
 	createTemplateError error
+	version             uint
 }
 
 func (m *mockClientBuilder) NewClient(logger *zap.Logger, metricsFactory metrics.Factory) (es.Client, error) {
@@ -52,7 +56,29 @@ func (m *mockClientBuilder) NewClient(logger *zap.Logger, metricsFactory metrics
 		tService.On("Body", mock.Anything).Return(tService)
 		tService.On("Do", context.Background()).Return(nil, m.createTemplateError)
 		c.On("CreateTemplate", mock.Anything).Return(tService)
-		c.On("GetVersion").Return(uint(6))
+		itService := &mocks.IndexTemplateCreateService{}
+		itService.On("Body", mock.Anything).Return(itService)
+		itService.On("Do", context.Background()).Return(nil, m.createTemplateError)
+		c.On("CreateIndexTemplate", mock.Anything).Return(itService)
+		dsService := &mocks.DataStreamCreateService{}
+		dsService.On("Do", context.Background()).Return(nil, nil)
+		c.On("CreateDataStream", mock.Anything).Return(dsService)
+		pService := &mocks.PolicyCreateService{}
+		pService.On("Body", mock.Anything).Return(pService)
+		pService.On("Do", context.Background()).Return(nil)
+		c.On("CreateILMPolicy", mock.Anything).Return(pService)
+		ctService := &mocks.ComponentTemplateCreateService{}
+		ctService.On("Body", mock.Anything).Return(ctService)
+		ctService.On("Do", context.Background()).Return(nil, nil)
+		c.On("CreateComponentTemplate", mock.Anything).Return(ctService)
+		tdService := &mocks.TemplateDeleteService{}
+		tdService.On("Do", context.Background()).Return(nil, nil)
+		c.On("DeleteTemplate", mock.Anything).Return(tdService)
+		version := m.version
+		if version == 0 {
+			version = 6
+		}
+		c.On("GetVersion").Return(version)
 		return c, nil
 	}
 	return nil, m.err
@@ -180,6 +206,98 @@ func TestCreateTemplateError(t *testing.T) {
 	assert.Error(t, err, "template-error")
 }
 
+func TestCreateDataStreams(t *testing.T) {
+	f := NewFactory()
+	primaryConfig := &mockClientBuilder{Configuration: escfg.Configuration{CreateIndexTemplates: true, UseDataStreams: true}}
+	f.primaryConfig = primaryConfig
+	f.archiveConfig = &mockClientBuilder{}
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	w, err := f.CreateSpanWriter()
+	require.NoError(t, err)
+	assert.NotNil(t, w)
+	client := f.primaryClient.(*mocks.Client)
+	client.AssertCalled(t, "CreateIndexTemplate", "jaeger-span-ds")
+	client.AssertCalled(t, "CreateDataStream", "jaeger-service-ds")
+	client.AssertNotCalled(t, "CreateTemplate", mock.Anything)
+
+	primaryConfig.createTemplateError = errors.New("template-error")
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	_, err = f.CreateSpanWriter()
+	assert.EqualError(t, err, "template-error")
+}
+
+func TestCreateComposableTemplates(t *testing.T) {
+	f := NewFactory()
+	primaryConfig := &mockClientBuilder{Configuration: escfg.Configuration{CreateIndexTemplates: true, ComposableTemplates: true}}
+	f.primaryConfig = primaryConfig
+	f.archiveConfig = &mockClientBuilder{}
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	_, err := f.CreateSpanWriter()
+	assert.EqualError(t, err, "composable index templates require Elasticsearch 7.8 or later")
+
+	for _, cfg := range []*mockClientBuilder{
+		{version: 7, Configuration: escfg.Configuration{CreateIndexTemplates: true, ComposableTemplates: true}},
+		{version: 8, Configuration: escfg.Configuration{CreateIndexTemplates: true}},
+	} {
+		f.primaryConfig = cfg
+		require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+		w, err := f.CreateSpanWriter()
+		require.NoError(t, err)
+		assert.NotNil(t, w)
+		client := f.primaryClient.(*mocks.Client)
+		client.AssertCalled(t, "CreateComponentTemplate", "jaeger-span-mappings")
+		client.AssertCalled(t, "CreateIndexTemplate", "jaeger-service")
+		client.AssertCalled(t, "DeleteTemplate", "jaeger-span")
+		client.AssertNotCalled(t, "CreateTemplate", mock.Anything)
+	}
+}
+
+func TestCreateIndexLifecycle(t *testing.T) {
+	f := NewFactory()
+	primaryConfig := &mockClientBuilder{Configuration: escfg.Configuration{
+		UseDataStreams: true,
+		IndexLifecycle: escfg.IndexLifecycle{Type: "foo"},
+	}}
+	f.primaryConfig = primaryConfig
+	f.archiveConfig = &mockClientBuilder{}
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	_, err := f.CreateSpanWriter()
+	assert.EqualError(t, err, `unknown index lifecycle type "foo", valid types are ilm and ism`)
+
+	primaryConfig.IndexLifecycle = escfg.IndexLifecycle{Type: lifecycleILM, RolloverMaxAge: time.Hour}
+	primaryConfig.CreateIndexTemplates = true
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	_, err = f.CreateSpanWriter()
+	require.NoError(t, err)
+	client := f.primaryClient.(*mocks.Client)
+	client.AssertCalled(t, "CreateILMPolicy", "jaeger-index-policy")
+	client.AssertCalled(t, "CreateIndexTemplate", "jaeger-span-ds")
+	client.AssertNotCalled(t, "IndexExists", mock.Anything)
+}
+
+func TestCreateTenantSpanReader(t *testing.T) {
+	f := NewFactory()
+	primaryConfig := &mockClientBuilder{}
+	f.primaryConfig = primaryConfig
+	f.archiveConfig = &mockClientBuilder{}
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	_, err := f.CreateTenantSpanReader("acme")
+	assert.Equal(t, storage.ErrTenantStorageNotSupported, err)
+
+	primaryConfig.TenantIndexPrefix = true
+	primaryConfig.IndexPrefix = "prod"
+	reader, err := f.CreateTenantSpanReader("acme")
+	require.NoError(t, err)
+	assert.NotNil(t, reader)
+	cfg := tenantConfig{ClientBuilder: primaryConfig, indexPrefix: "prod-acme"}
+	assert.Equal(t, "prod-acme", cfg.GetIndexPrefix())
+	assert.True(t, cfg.GetTenantIndexPrefix())
+
+	primaryConfig.IndexPrefix = ""
+	_, err = f.CreateTenantSpanReader("-acme")
+	assert.Error(t, err)
+}
+
 func TestArchiveDisabled(t *testing.T) {
 	f := NewFactory()
 	f.archiveConfig = &mockClientBuilder{Configuration: escfg.Configuration{Enabled: false}}
@@ -205,6 +323,58 @@ func TestArchiveEnabled(t *testing.T) {
 	assert.NotNil(t, r)
 }
 
+func TestMigration(t *testing.T) {
+	f := NewFactory()
+	f.primaryConfig = &mockClientBuilder{}
+	f.archiveConfig = &mockClientBuilder{}
+	f.migrationConfig = &mockClientBuilder{err: errors.New("made-up error"), Configuration: escfg.Configuration{Enabled: true}}
+	assert.EqualError(t, f.Initialize(metrics.NullFactory, zap.NewNop()), "failed to create migration Elasticsearch client: made-up error")
+
+	f.migrationConfig = &mockClientBuilder{Configuration: escfg.Configuration{Enabled: true, IndexPrefix: "new"}}
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	r, err := f.CreateSpanReader()
+	require.NoError(t, err)
+	assert.IsType(t, &spanstore.FederatedReader{}, r)
+	w, err := f.CreateSpanWriter()
+	require.NoError(t, err)
+	assert.IsType(t, &spanstore.CompositeWriter{}, w)
+
+	f.migrationConfig = &mockClientBuilder{
+		Configuration:       escfg.Configuration{Enabled: true, CreateIndexTemplates: true},
+		createTemplateError: errors.New("template-error"),
+	}
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	_, err = f.CreateSpanWriter()
+	assert.EqualError(t, err, "template-error")
+}
+
+func TestMigrationTenantSpanReader(t *testing.T) {
+	f := NewFactory()
+	f.primaryConfig = &mockClientBuilder{}
+	f.archiveConfig = &mockClientBuilder{}
+	migrationConfig := &mockClientBuilder{Configuration: escfg.Configuration{Enabled: true}}
+	f.migrationConfig = migrationConfig
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	_, err := f.CreateTenantSpanReader("acme")
+	assert.Equal(t, storage.ErrTenantStorageNotSupported, err)
+
+	// migrating from the shared indices to the indices per tenant
+	migrationConfig.TenantIndexPrefix = true
+	reader, err := f.CreateTenantSpanReader("acme")
+	require.NoError(t, err)
+	assert.IsType(t, &spanstore.FederatedReader{}, reader)
+	cfg, err := getTenantConfig(f.primaryConfig, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, f.primaryConfig, cfg)
+	cfg, err = getTenantConfig(migrationConfig, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, "acme", cfg.GetIndexPrefix())
+
+	// the migration indices have no index prefix
+	_, err = f.CreateTenantSpanReader("Acme")
+	assert.Error(t, err)
+}
+
 func TestInitFromOptions(t *testing.T) {
 	f := NewFactory()
 	o := Options{Primary: namespaceConfig{Configuration: escfg.Configuration{Servers: []string{"server"}}},
@@ -212,6 +382,7 @@ func TestInitFromOptions(t *testing.T) {
 	f.InitFromOptions(o)
 	assert.Equal(t, o.GetPrimary(), f.primaryConfig)
 	assert.Equal(t, o.Get(archiveNamespace), f.archiveConfig)
+	assert.Equal(t, o.Get(migrationNamespace), f.migrationConfig)
 }
 
 func TestNewOptions(t *testing.T) {
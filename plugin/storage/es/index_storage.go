@@ -0,0 +1,100 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package es
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jaegertracing/jaeger/pkg/es/config"
+)
+
+const (
+	codecDefault         = "default"
+	codecBestCompression = "best_compression"
+)
+
+// derivedSpanFields are the fields of the span documents computed from their other fields
+var derivedSpanFields = []string{"startTimeMillis"}
+
+// unindexableSpanFields are the fields of the span documents that can be kept in their source only,
+// neither the reader nor the UI search the spans on them, except the tags of the logs.
+var unindexableSpanFields = map[string]bool{"logs": true, "references": true}
+
+// applyStorage adds the storage configuration to the span and service templates.
+func applyStorage(spanTemplate, serviceTemplate string, storage config.Storage, esVersion uint) (string, string, error) {
+	if storage.IndexCodec != "" && storage.IndexCodec != codecDefault && storage.IndexCodec != codecBestCompression {
+		return "", "", fmt.Errorf("invalid index codec %q, expected %q or %q", storage.IndexCodec, codecDefault, codecBestCompression)
+	}
+	for _, field := range storage.UnindexedFields {
+		if !unindexableSpanFields[field] {
+			return "", "", fmt.Errorf("the span field %q cannot be left unindexed, expected logs or references", field)
+		}
+	}
+	if storage.IndexCodec == "" && !storage.ExcludeDerivedFields && len(storage.UnindexedFields) == 0 {
+		return spanTemplate, serviceTemplate, nil
+	}
+	spanTemplate, err := applyStorageToTemplate(spanTemplate, storage, esVersion, true)
+	if err != nil {
+		return "", "", err
+	}
+	serviceTemplate, err = applyStorageToTemplate(serviceTemplate, storage, esVersion, false)
+	if err != nil {
+		return "", "", err
+	}
+	return spanTemplate, serviceTemplate, nil
+}
+
+func applyStorageToTemplate(template string, storage config.Storage, esVersion uint, spans bool) (string, error) {
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(template), &parsed); err != nil {
+		return "", fmt.Errorf("invalid index template: %w", err)
+	}
+	if storage.IndexCodec != "" {
+		settings, _ := parsed["settings"].(map[string]interface{})
+		if settings == nil {
+			settings = map[string]interface{}{}
+			parsed["settings"] = settings
+		}
+		settings["index.codec"] = storage.IndexCodec
+	}
+	if spans {
+		mappings, _ := parsed["mappings"].(map[string]interface{})
+		if esVersion < 7 {
+			// the mappings of Elasticsearch 6 are per document type
+			mappings, _ = mappings["span"].(map[string]interface{})
+		}
+		if mappings == nil {
+			return "", fmt.Errorf("invalid index template: no span mappings")
+		}
+		if storage.ExcludeDerivedFields {
+			mappings["_source"] = map[string]interface{}{"excludes": derivedSpanFields}
+		}
+		properties, _ := mappings["properties"].(map[string]interface{})
+		if properties == nil {
+			properties = map[string]interface{}{}
+			mappings["properties"] = properties
+		}
+		for _, field := range storage.UnindexedFields {
+			// the objects that are not enabled are only kept in the source
+			properties[field] = map[string]interface{}{"type": "object", "enabled": false}
+		}
+	}
+	body, err := json.Marshal(parsed)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
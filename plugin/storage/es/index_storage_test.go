@@ -0,0 +1,97 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package es
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/pkg/es/config"
+)
+
+func TestApplyStorage(t *testing.T) {
+	storage := config.Storage{
+		IndexCodec:           "best_compression",
+		ExcludeDerivedFields: true,
+		UnindexedFields:      []string{"logs", "references"},
+	}
+	for _, version := range []uint{6, 7} {
+		spanMapping, serviceMapping := GetSpanServiceMappings(5, 1, version)
+		spanTemplate, serviceTemplate, err := applyStorage(spanMapping, serviceMapping, storage, version)
+		require.NoError(t, err)
+
+		var span, service map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(spanTemplate), &span))
+		require.NoError(t, json.Unmarshal([]byte(serviceTemplate), &service))
+		assert.Equal(t, "best_compression", span["settings"].(map[string]interface{})["index.codec"])
+		assert.Equal(t, "best_compression", service["settings"].(map[string]interface{})["index.codec"])
+		mappings := span["mappings"].(map[string]interface{})
+		if version < 7 {
+			mappings = mappings["span"].(map[string]interface{})
+		}
+		assert.Equal(t, map[string]interface{}{"excludes": []interface{}{"startTimeMillis"}}, mappings["_source"])
+		properties := mappings["properties"].(map[string]interface{})
+		for _, field := range []string{"logs", "references"} {
+			assert.Equal(t, map[string]interface{}{"type": "object", "enabled": false}, properties[field])
+		}
+		assert.Contains(t, properties, "tags")
+		assert.NotContains(t, serviceTemplate, "_source")
+	}
+}
+
+func TestApplyStorageDefaults(t *testing.T) {
+	spanMapping, serviceMapping := GetSpanServiceMappings(5, 1, 7)
+	spanTemplate, serviceTemplate, err := applyStorage(spanMapping, serviceMapping, config.Storage{MeasureSize: true}, 7)
+	require.NoError(t, err)
+	assert.Equal(t, spanMapping, spanTemplate)
+	assert.Equal(t, serviceMapping, serviceTemplate)
+}
+
+func TestApplyStorageErrors(t *testing.T) {
+	spanMapping, serviceMapping := GetSpanServiceMappings(5, 1, 7)
+	testCases := []struct {
+		storage  config.Storage
+		template string
+		err      string
+	}{
+		{
+			storage:  config.Storage{IndexCodec: "lz4"},
+			template: spanMapping,
+			err:      `invalid index codec "lz4", expected "default" or "best_compression"`,
+		},
+		{
+			storage:  config.Storage{UnindexedFields: []string{"tags"}},
+			template: spanMapping,
+			err:      `the span field "tags" cannot be left unindexed, expected logs or references`,
+		},
+		{
+			storage:  config.Storage{IndexCodec: "default"},
+			template: "{",
+			err:      "invalid index template: unexpected end of JSON input",
+		},
+		{
+			storage:  config.Storage{ExcludeDerivedFields: true},
+			template: "{}",
+			err:      "invalid index template: no span mappings",
+		},
+	}
+	for _, test := range testCases {
+		_, _, err := applyStorage(test.template, serviceMapping, test.storage, 7)
+		assert.EqualError(t, err, test.err)
+	}
+}
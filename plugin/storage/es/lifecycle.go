@@ -0,0 +1,268 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package es
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/olivere/elastic"
+
+	"github.com/jaegertracing/jaeger/pkg/es"
+	"github.com/jaegertracing/jaeger/pkg/es/config"
+	esSpanStore "github.com/jaegertracing/jaeger/plugin/storage/es/spanstore"
+)
+
+const (
+	lifecycleILM = "ilm"
+	lifecycleISM = "ism"
+
+	defaultLifecyclePolicyName = "jaeger-index-policy"
+	firstRolloverIndex         = "-000001"
+)
+
+// indexLifecycle creates the index lifecycle policy and attaches it to the span and service indices,
+// either the rollover indices behind the read and write aliases or the backing indices of the data streams.
+type indexLifecycle struct {
+	client         es.Client
+	config         config.IndexLifecycle
+	useDataStreams bool
+	// composable replaces the legacy templates of the rollover indices, ignored when a composable template matches
+	composable bool
+	// indices are the names of the span and service indices without suffix, including the index prefix
+	indices []string
+	// templates are the names of the index templates of the span and service indices
+	templates []string
+}
+
+func newIndexLifecycle(client es.Client, cfg config.ClientBuilder) (*indexLifecycle, error) {
+	lifecycle := cfg.GetIndexLifecycle()
+	if lifecycle.Type != lifecycleILM && lifecycle.Type != lifecycleISM {
+		return nil, fmt.Errorf("unknown index lifecycle type %q, valid types are %s and %s", lifecycle.Type, lifecycleILM, lifecycleISM)
+	}
+	if !cfg.GetUseReadWriteAliases() && !cfg.GetUseDataStreams() {
+		return nil, fmt.Errorf("the index lifecycle policy requires either the read and write aliases or the data streams")
+	}
+	if lifecycle.RolloverMaxAge <= 0 && lifecycle.RolloverMaxSize == "" {
+		return nil, fmt.Errorf("the index lifecycle policy requires a rollover age or size")
+	}
+	prefix := cfg.GetIndexPrefix()
+	if prefix != "" {
+		prefix += "-"
+	}
+	if lifecycle.PolicyName == "" {
+		lifecycle.PolicyName = prefix + defaultLifecyclePolicyName
+	}
+	return &indexLifecycle{
+		client:         client,
+		config:         lifecycle,
+		useDataStreams: cfg.GetUseDataStreams(),
+		indices:        []string{prefix + "jaeger-span", prefix + "jaeger-service"},
+		templates:      []string{"jaeger-span", "jaeger-service"},
+	}, nil
+}
+
+// timeValue formats a duration with the largest time unit of Elasticsearch dividing it.
+func timeValue(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	default:
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
+}
+
+func (l *indexLifecycle) ilmPolicy() map[string]interface{} {
+	rollover := map[string]interface{}{}
+	if l.config.RolloverMaxAge > 0 {
+		rollover["max_age"] = timeValue(l.config.RolloverMaxAge)
+	}
+	if l.config.RolloverMaxSize != "" {
+		rollover["max_size"] = l.config.RolloverMaxSize
+	}
+	phases := map[string]interface{}{
+		"hot": map[string]interface{}{
+			"actions": map[string]interface{}{"rollover": rollover},
+		},
+	}
+	if l.config.DeleteAfter > 0 {
+		phases["delete"] = map[string]interface{}{
+			"min_age": timeValue(l.config.DeleteAfter),
+			"actions": map[string]interface{}{"delete": map[string]interface{}{}},
+		}
+	}
+	return map[string]interface{}{"policy": map[string]interface{}{"phases": phases}}
+}
+
+// ismPolicy returns the OpenSearch policy, attached by its ISM template to the rollover indices and to
+// the backing indices of the data streams when they are created.
+func (l *indexLifecycle) ismPolicy() map[string]interface{} {
+	rollover := map[string]interface{}{}
+	if l.config.RolloverMaxAge > 0 {
+		rollover["min_index_age"] = timeValue(l.config.RolloverMaxAge)
+	}
+	if l.config.RolloverMaxSize != "" {
+		rollover["min_size"] = l.config.RolloverMaxSize
+	}
+	transitions := []interface{}{}
+	if l.config.DeleteAfter > 0 {
+		transitions = append(transitions, map[string]interface{}{
+			"state_name": "delete",
+			"conditions": map[string]interface{}{"min_index_age": timeValue(l.config.DeleteAfter)},
+		})
+	}
+	states := []interface{}{
+		map[string]interface{}{
+			"name":        "hot",
+			"actions":     []interface{}{map[string]interface{}{"rollover": rollover}},
+			"transitions": transitions,
+		},
+	}
+	if l.config.DeleteAfter > 0 {
+		states = append(states, map[string]interface{}{
+			"name":        "delete",
+			"actions":     []interface{}{map[string]interface{}{"delete": map[string]interface{}{}}},
+			"transitions": []interface{}{},
+		})
+	}
+	var patterns []string
+	for _, index := range l.indices {
+		patterns = append(patterns, index+"-0*", ".ds-"+index+"-ds-*")
+	}
+	return map[string]interface{}{
+		"policy": map[string]interface{}{
+			"description":   "Rollover and deletion of the Jaeger indices",
+			"default_state": "hot",
+			"states":        states,
+			"ism_template": []interface{}{
+				map[string]interface{}{"index_patterns": patterns, "priority": 100},
+			},
+		},
+	}
+}
+
+// createPolicy puts the policy. The ISM policies are not updated once created, their update requires their version.
+func (l *indexLifecycle) createPolicy(ctx context.Context) error {
+	if l.config.Type == lifecycleISM {
+		body, err := json.Marshal(l.ismPolicy())
+		if err != nil {
+			return err
+		}
+		err = l.client.CreateISMPolicy(l.config.PolicyName).Body(string(body)).Do(ctx)
+		if elastic.IsConflict(err) {
+			return nil
+		}
+		return err
+	}
+	body, err := json.Marshal(l.ilmPolicy())
+	if err != nil {
+		return err
+	}
+	return l.client.CreateILMPolicy(l.config.PolicyName).Body(string(body)).Do(ctx)
+}
+
+// attachToDataStreams adds the ILM policy to the settings of the span and service templates, which
+// become the templates of the data streams. The ISM policy is attached by its own template.
+func (l *indexLifecycle) attachToDataStreams(spanTemplate, serviceTemplate string) (string, string, error) {
+	if l.config.Type != lifecycleILM {
+		return spanTemplate, serviceTemplate, nil
+	}
+	var templates []string
+	for _, template := range []string{spanTemplate, serviceTemplate} {
+		var parsed map[string]interface{}
+		if err := json.Unmarshal([]byte(template), &parsed); err != nil {
+			return "", "", fmt.Errorf("invalid index template: %w", err)
+		}
+		settings, _ := parsed["settings"].(map[string]interface{})
+		if settings == nil {
+			settings = map[string]interface{}{}
+			parsed["settings"] = settings
+		}
+		settings["index.lifecycle.name"] = l.config.PolicyName
+		body, err := json.Marshal(parsed)
+		if err != nil {
+			return "", "", err
+		}
+		templates = append(templates, string(body))
+	}
+	return templates[0], templates[1], nil
+}
+
+// rolloverTemplate returns the template adding the read alias and the lifecycle settings to the rollover
+// indices. It complements the span or service template, which also matches the daily and archive indices.
+func (l *indexLifecycle) rolloverTemplate(index, template string) (string, error) {
+	settings := map[string]interface{}{}
+	if l.config.Type == lifecycleILM {
+		settings["index.lifecycle.name"] = l.config.PolicyName
+		settings["index.lifecycle.rollover_alias"] = index + "-write"
+	} else {
+		settings["plugins.index_state_management.rollover_alias"] = index + "-write"
+	}
+	aliases := map[string]interface{}{index + "-read": map[string]interface{}{}}
+	if l.composable {
+		// only the composable template with the highest priority applies, it composes the templates of the indices
+		body, err := json.Marshal(map[string]interface{}{
+			"index_patterns": []string{index + "-0*"},
+			"priority":       esSpanStore.IndexTemplatePriority + 1,
+			"composed_of":    esSpanStore.ComponentTemplateNames(template),
+			"template":       map[string]interface{}{"settings": settings, "aliases": aliases},
+		})
+		return string(body), err
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"index_patterns": []string{index + "-0*"},
+		"order":          1,
+		"settings":       settings,
+		"aliases":        aliases,
+	})
+	return string(body), err
+}
+
+// bootstrapRolloverIndices creates the rollover templates, then the first rollover indices
+// with the write aliases if they do not exist yet.
+func (l *indexLifecycle) bootstrapRolloverIndices(ctx context.Context) error {
+	for i, index := range l.indices {
+		template, err := l.rolloverTemplate(index, l.templates[i])
+		if err != nil {
+			return err
+		}
+		if l.composable {
+			_, err = l.client.CreateIndexTemplate(index + "-lifecycle").Body(template).Do(ctx)
+		} else {
+			_, err = l.client.CreateTemplate(index + "-lifecycle").Body(template).Do(ctx)
+		}
+		if err != nil {
+			return err
+		}
+		exists, err := l.client.IndexExists(index + "-write").Do(ctx)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		body := fmt.Sprintf(`{"aliases":{%q:{"is_write_index":true}}}`, index+"-write")
+		if _, err := l.client.CreateIndex(index + firstRolloverIndex).Body(body).Do(ctx); err != nil && !es.IsAlreadyExists(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package es
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/olivere/elastic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	escfg "github.com/jaegertracing/jaeger/pkg/es/config"
+	"github.com/jaegertracing/jaeger/pkg/es/mocks"
+)
+
+func lifecycleConfig(lifecycleType string) *escfg.Configuration {
+	return &escfg.Configuration{
+		UseReadWriteAliases: true,
+		IndexLifecycle: escfg.IndexLifecycle{
+			Type:            lifecycleType,
+			RolloverMaxAge:  24 * time.Hour,
+			RolloverMaxSize: "50gb",
+			DeleteAfter:     7 * 24 * time.Hour,
+		},
+	}
+}
+
+func TestNewIndexLifecycle(t *testing.T) {
+	cfg := lifecycleConfig("foo")
+	_, err := newIndexLifecycle(nil, cfg)
+	assert.EqualError(t, err, `unknown index lifecycle type "foo", valid types are ilm and ism`)
+
+	cfg = lifecycleConfig(lifecycleILM)
+	cfg.UseReadWriteAliases = false
+	_, err = newIndexLifecycle(nil, cfg)
+	assert.EqualError(t, err, "the index lifecycle policy requires either the read and write aliases or the data streams")
+
+	cfg = lifecycleConfig(lifecycleILM)
+	cfg.IndexLifecycle.RolloverMaxAge = 0
+	cfg.IndexLifecycle.RolloverMaxSize = ""
+	_, err = newIndexLifecycle(nil, cfg)
+	assert.EqualError(t, err, "the index lifecycle policy requires a rollover age or size")
+
+	cfg = lifecycleConfig(lifecycleILM)
+	cfg.IndexPrefix = "prod"
+	lifecycle, err := newIndexLifecycle(nil, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "prod-jaeger-index-policy", lifecycle.config.PolicyName)
+	assert.Equal(t, []string{"prod-jaeger-span", "prod-jaeger-service"}, lifecycle.indices)
+
+	cfg.IndexLifecycle.PolicyName = "custom"
+	lifecycle, err = newIndexLifecycle(nil, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "custom", lifecycle.config.PolicyName)
+}
+
+func TestTimeValue(t *testing.T) {
+	assert.Equal(t, "2d", timeValue(48*time.Hour))
+	assert.Equal(t, "36h", timeValue(36*time.Hour))
+	assert.Equal(t, "90m", timeValue(90*time.Minute))
+	assert.Equal(t, "30s", timeValue(30*time.Second))
+}
+
+func TestCreatePolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    *escfg.Configuration
+		method    string
+		body      string
+		doErr     error
+		expectErr string
+	}{
+		{
+			name:   "ilm",
+			config: lifecycleConfig(lifecycleILM),
+			method: "CreateILMPolicy",
+			body:   `{"policy":{"phases":{"delete":{"actions":{"delete":{}},"min_age":"7d"},"hot":{"actions":{"rollover":{"max_age":"1d","max_size":"50gb"}}}}}}`,
+		},
+		{
+			name: "ilm without deletion",
+			config: func() *escfg.Configuration {
+				cfg := lifecycleConfig(lifecycleILM)
+				cfg.IndexLifecycle.DeleteAfter = 0
+				cfg.IndexLifecycle.RolloverMaxSize = ""
+				return cfg
+			}(),
+			method: "CreateILMPolicy",
+			body:   `{"policy":{"phases":{"hot":{"actions":{"rollover":{"max_age":"1d"}}}}}}`,
+		},
+		{
+			name:      "ilm error",
+			config:    lifecycleConfig(lifecycleILM),
+			method:    "CreateILMPolicy",
+			doErr:     errors.New("policy-error"),
+			expectErr: "policy-error",
+		},
+		{
+			name:   "ism",
+			config: lifecycleConfig(lifecycleISM),
+			method: "CreateISMPolicy",
+			body: `{"policy":{"default_state":"hot","description":"Rollover and deletion of the Jaeger indices",` +
+				`"ism_template":[{"index_patterns":["jaeger-span-0*",".ds-jaeger-span-ds-*","jaeger-service-0*",".ds-jaeger-service-ds-*"],"priority":100}],` +
+				`"states":[{"actions":[{"rollover":{"min_index_age":"1d","min_size":"50gb"}}],"name":"hot","transitions":[{"conditions":{"min_index_age":"7d"},"state_name":"delete"}]},` +
+				`{"actions":[{"delete":{}}],"name":"delete","transitions":[]}]}}`,
+		},
+		{
+			name:   "existing ism",
+			config: lifecycleConfig(lifecycleISM),
+			method: "CreateISMPolicy",
+			doErr:  &elastic.Error{Status: 409},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := &mocks.Client{}
+			policyService := &mocks.PolicyCreateService{}
+			if test.body != "" {
+				policyService.On("Body", test.body).Return(policyService)
+			} else {
+				policyService.On("Body", mock.Anything).Return(policyService)
+			}
+			policyService.On("Do", context.Background()).Return(test.doErr)
+			client.On(test.method, "jaeger-index-policy").Return(policyService)
+
+			lifecycle, err := newIndexLifecycle(client, test.config)
+			require.NoError(t, err)
+			err = lifecycle.createPolicy(context.Background())
+			if test.expectErr != "" {
+				assert.EqualError(t, err, test.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAttachToDataStreams(t *testing.T) {
+	cfg := lifecycleConfig(lifecycleILM)
+	cfg.UseDataStreams = true
+	lifecycle, err := newIndexLifecycle(nil, cfg)
+	require.NoError(t, err)
+	span, service, err := lifecycle.attachToDataStreams(`{"settings":{"index.number_of_shards":5}}`, `{}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"settings":{"index.lifecycle.name":"jaeger-index-policy","index.number_of_shards":5}}`, span)
+	assert.Equal(t, `{"settings":{"index.lifecycle.name":"jaeger-index-policy"}}`, service)
+
+	_, _, err = lifecycle.attachToDataStreams("{", `{}`)
+	assert.Error(t, err)
+
+	cfg.IndexLifecycle.Type = lifecycleISM
+	lifecycle, err = newIndexLifecycle(nil, cfg)
+	require.NoError(t, err)
+	span, service, err = lifecycle.attachToDataStreams(`{"settings":{}}`, `{}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"settings":{}}`, span)
+	assert.Equal(t, `{}`, service)
+}
+
+func TestBootstrapRolloverIndices(t *testing.T) {
+	tests := []struct {
+		name          string
+		lifecycleType string
+		exists        bool
+		createErr     error
+		expectErr     string
+		template      string
+	}{
+		{
+			name:          "ilm",
+			lifecycleType: lifecycleILM,
+			template: `{"aliases":{"jaeger-span-read":{}},"index_patterns":["jaeger-span-0*"],"order":1,` +
+				`"settings":{"index.lifecycle.name":"jaeger-index-policy","index.lifecycle.rollover_alias":"jaeger-span-write"}}`,
+		},
+		{
+			name:          "ism",
+			lifecycleType: lifecycleISM,
+			template: `{"aliases":{"jaeger-span-read":{}},"index_patterns":["jaeger-span-0*"],"order":1,` +
+				`"settings":{"plugins.index_state_management.rollover_alias":"jaeger-span-write"}}`,
+		},
+		{name: "existing aliases", lifecycleType: lifecycleILM, exists: true},
+		{
+			name:          "existing index",
+			lifecycleType: lifecycleILM,
+			createErr:     &elastic.Error{Status: 400, Details: &elastic.ErrorDetails{Type: "resource_already_exists_exception"}},
+		},
+		{name: "create error", lifecycleType: lifecycleILM, createErr: errors.New("create-error"), expectErr: "create-error"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := &mocks.Client{}
+			templateService := &mocks.TemplateCreateService{}
+			templateService.On("Body", mock.Anything).Return(templateService)
+			templateService.On("Do", context.Background()).Return(nil, nil)
+			client.On("CreateTemplate", mock.Anything).Return(templateService)
+			existsService := &mocks.IndicesExistsService{}
+			existsService.On("Do", context.Background()).Return(test.exists, nil)
+			client.On("IndexExists", mock.Anything).Return(existsService)
+			createService := &mocks.IndicesCreateService{}
+			createService.On("Body", mock.Anything).Return(createService)
+			createService.On("Do", context.Background()).Return(nil, test.createErr)
+			client.On("CreateIndex", mock.Anything).Return(createService)
+
+			lifecycle, err := newIndexLifecycle(client, lifecycleConfig(test.lifecycleType))
+			require.NoError(t, err)
+			err = lifecycle.bootstrapRolloverIndices(context.Background())
+			if test.expectErr != "" {
+				assert.EqualError(t, err, test.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			client.AssertCalled(t, "CreateTemplate", "jaeger-span-lifecycle")
+			client.AssertCalled(t, "CreateTemplate", "jaeger-service-lifecycle")
+			client.AssertCalled(t, "IndexExists", "jaeger-service-write")
+			if test.template != "" {
+				templateService.AssertCalled(t, "Body", test.template)
+			}
+			if test.exists {
+				client.AssertNotCalled(t, "CreateIndex", mock.Anything)
+			} else {
+				client.AssertCalled(t, "CreateIndex", "jaeger-span-000001")
+				createService.AssertCalled(t, "Body", `{"aliases":{"jaeger-span-write":{"is_write_index":true}}}`)
+			}
+		})
+	}
+}
+
+func TestBootstrapComposableRolloverIndices(t *testing.T) {
+	client := &mocks.Client{}
+	templateService := &mocks.IndexTemplateCreateService{}
+	templateService.On("Body", mock.Anything).Return(templateService)
+	templateService.On("Do", context.Background()).Return(nil, nil)
+	client.On("CreateIndexTemplate", mock.Anything).Return(templateService)
+	existsService := &mocks.IndicesExistsService{}
+	existsService.On("Do", context.Background()).Return(true, nil)
+	client.On("IndexExists", mock.Anything).Return(existsService)
+
+	lifecycle, err := newIndexLifecycle(client, lifecycleConfig(lifecycleILM))
+	require.NoError(t, err)
+	lifecycle.composable = true
+	require.NoError(t, lifecycle.bootstrapRolloverIndices(context.Background()))
+	client.AssertCalled(t, "CreateIndexTemplate", "jaeger-span-lifecycle")
+	client.AssertCalled(t, "CreateIndexTemplate", "jaeger-service-lifecycle")
+	client.AssertNotCalled(t, "CreateTemplate", mock.Anything)
+	templateService.AssertCalled(t, "Body", `{"composed_of":["jaeger-service-settings","jaeger-service-mappings"],`+
+		`"index_patterns":["jaeger-service-0*"],"priority":101,"template":{"aliases":{"jaeger-service-read":{}},`+
+		`"settings":{"index.lifecycle.name":"jaeger-index-policy","index.lifecycle.rollover_alias":"jaeger-service-write"}}}`)
+}
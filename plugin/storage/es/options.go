@@ -44,12 +44,38 @@ const (
 	suffixBulkFlushInterval   = ".bulk.flush-interval"
 	suffixTimeout             = ".timeout"
 	suffixIndexPrefix         = ".index-prefix"
+	suffixTenantIndexPrefix   = ".tenant-index-prefix"
 	suffixTagsAsFields        = ".tags-as-fields"
 	suffixTagsAsFieldsAll     = suffixTagsAsFields + ".all"
 	suffixTagsFile            = suffixTagsAsFields + ".config-file"
 	suffixTagDeDotChar        = suffixTagsAsFields + ".dot-replacement"
+	suffixTagDefaultStrategy  = suffixTagsAsFields + ".default-strategy"
+	suffixStorage             = ".storage"
+	suffixStorageCodec        = suffixStorage + ".index-codec"
+	suffixStorageDerived      = suffixStorage + ".exclude-derived-fields"
+	suffixStorageUnindexed    = suffixStorage + ".unindexed-fields"
+	suffixStorageMeasureSize  = suffixStorage + ".measure-size"
 	suffixReadAlias           = ".use-aliases"
+	suffixDataStreams         = ".use-data-streams"
+	suffixLifecycle           = ".index-lifecycle"
+	suffixLifecycleType       = suffixLifecycle + ".type"
+	suffixLifecyclePolicyName = suffixLifecycle + ".policy-name"
+	suffixLifecycleMaxAge     = suffixLifecycle + ".rollover-max-age"
+	suffixLifecycleMaxSize    = suffixLifecycle + ".rollover-max-size"
+	suffixLifecycleDelete     = suffixLifecycle + ".delete-after"
+	suffixAsyncSearch         = ".async-search"
+	suffixAsyncSearchEnabled  = suffixAsyncSearch + ".enabled"
+	suffixAsyncSearchWait     = suffixAsyncSearch + ".wait-timeout"
+	suffixAsyncSearchBudget   = suffixAsyncSearch + ".budget"
+	suffixPointInTime         = ".point-in-time"
+	suffixPointInTimeEnabled  = suffixPointInTime + ".enabled"
+	suffixPointInTimeKeep     = suffixPointInTime + ".keep-alive"
+	suffixAWS                 = ".aws"
+	suffixAWSEnabled          = suffixAWS + ".enabled"
+	suffixAWSRegion           = suffixAWS + ".region"
+	suffixAWSServerless       = suffixAWS + ".serverless"
 	suffixCreateIndexTemplate = ".create-index-templates"
+	suffixComposableTemplates = ".use-composable-templates"
 	suffixEnabled             = ".enabled"
 	suffixVersion             = ".version"
 
@@ -92,6 +118,18 @@ func NewOptions(primaryNamespace string, otherNamespaces ...string) *Options {
 				Tags: config.TagsAsFields{
 					DotReplacement: "@",
 				},
+				IndexLifecycle: config.IndexLifecycle{
+					RolloverMaxAge:  24 * time.Hour,
+					RolloverMaxSize: "50gb",
+					DeleteAfter:     7 * 24 * time.Hour,
+				},
+				AsyncSearch: config.AsyncSearch{
+					WaitTimeout: time.Second,
+					Budget:      time.Minute,
+				},
+				PointInTime: config.PointInTime{
+					KeepAlive: 5 * time.Minute,
+				},
 				Enabled:              true,
 				CreateIndexTemplates: true,
 				Version:              0,
@@ -205,25 +243,130 @@ func addFlags(flagSet *flag.FlagSet, nsConfig *namespaceConfig) {
 	flagSet.Bool(
 		nsConfig.namespace+suffixTagsAsFieldsAll,
 		nsConfig.Tags.AllAsFields,
-		"(experimental) Store all span and process tags as object fields. If true "+suffixTagsFile+" is ignored. Binary tags are always stored as nested objects.")
+		"(experimental) Store all span and process tags as object fields. If true only the keys of "+suffixTagsFile+" with a strategy are not. Binary tags are always stored as nested objects.")
 	flagSet.String(
 		nsConfig.namespace+suffixTagsFile,
 		nsConfig.Tags.File,
-		"(experimental) Optional path to a file containing tag keys which will be stored as object fields. Each key should be on a separate line.")
+		"(experimental) Optional path to a file containing tag keys which will be stored as object fields. Each key should be on a separate line, "+
+			"optionally followed by an equal sign and the strategy storing it, e.g. http.url=flattened.")
 	flagSet.String(
 		nsConfig.namespace+suffixTagDeDotChar,
 		nsConfig.Tags.DotReplacement,
 		"(experimental) The character used to replace dots (\".\") in tag keys stored as object fields.")
+	flagSet.String(
+		nsConfig.namespace+suffixTagDefaultStrategy,
+		nsConfig.Tags.DefaultStrategy,
+		"(experimental) How the tags are stored unless the file of "+nsConfig.namespace+suffixTagsFile+" has a strategy for their key: "+
+			"\"nested\" documents searchable with regular expressions, \"object\" fields with the dots of the keys replaced, "+
+			"one field of the mappings per key, or a single \"flattened\" field only searchable by exact values, which requires Elasticsearch 7.3 or later. "+
+			"Nested by default, or object if "+nsConfig.namespace+suffixTagsAsFieldsAll+" is set.")
+	flagSet.String(
+		nsConfig.namespace+suffixStorageCodec,
+		nsConfig.Storage.IndexCodec,
+		"The codec compressing the stored fields of the new span and service indices: \"default\" or \"best_compression\", "+
+			"which takes less disk space at the cost of slower reads of the spans. Empty keeps the Elasticsearch default.")
+	flagSet.Bool(
+		nsConfig.namespace+suffixStorageDerived,
+		nsConfig.Storage.ExcludeDerivedFields,
+		"Leave the fields derived from other fields of the spans, like startTimeMillis, out of the _source of the new span indices. "+
+			"They are still searchable, but lost when the indices are reindexed.")
+	flagSet.String(
+		nsConfig.namespace+suffixStorageUnindexed,
+		strings.Join(nsConfig.Storage.UnindexedFields, ","),
+		"The comma-separated list of span fields only kept in the _source of the new span indices, without being indexed: "+
+			"\"logs\" and \"references\". The tags of the logs are no longer searchable when the logs are not indexed.")
+	flagSet.Bool(
+		nsConfig.namespace+suffixStorageMeasureSize,
+		nsConfig.Storage.MeasureSize,
+		"Report the size of the span documents written to Elasticsearch in the span_bytes metric")
 	flagSet.Bool(
 		nsConfig.namespace+suffixReadAlias,
 		nsConfig.UseReadWriteAliases,
 		"Use read and write aliases for indices. Use this option with Elasticsearch rollover "+
 			"API. It requires an external component to create aliases before startup and then performing its management. "+
 			"Note that "+nsConfig.namespace+suffixMaxSpanAge+" is not taken into the account and has to be substituted by external component managing read alias.")
+	if nsConfig.namespace != archiveNamespace {
+		flagSet.Bool(
+			nsConfig.namespace+suffixDataStreams,
+			nsConfig.UseDataStreams,
+			"Write the spans and services to data streams instead of daily indices, requires Elasticsearch 7.9 or later. "+
+				"The data streams and their index templates are created at startup if "+nsConfig.namespace+suffixCreateIndexTemplate+" is set, "+
+				"their rollover and retention are left to index lifecycle policies. The dependencies are still written to daily indices.")
+		flagSet.Bool(
+			nsConfig.namespace+suffixTenantIndexPrefix,
+			nsConfig.TenantIndexPrefix,
+			"Write the spans of each tenant to indices prefixed by the tenant after "+nsConfig.namespace+suffixIndexPrefix+", "+
+				"and read them from these indices for the tenants of the query service. Requires multi-tenancy. "+
+				"The tenants are encoded in the index names, e.g. Acme as _41cme; without an index prefix, they must start with a lower case letter or a digit.")
+		flagSet.String(
+			nsConfig.namespace+suffixLifecycleType,
+			nsConfig.IndexLifecycle.Type,
+			"The index lifecycle policy created at startup and attached to the span and service indices: "+
+				"\"ilm\" for Elasticsearch, \"ism\" for OpenSearch, empty to manage the indices externally. "+
+				"Requires "+nsConfig.namespace+suffixReadAlias+" or "+nsConfig.namespace+suffixDataStreams+", it replaces the rollover and cleaner jobs.")
+		flagSet.String(
+			nsConfig.namespace+suffixLifecyclePolicyName,
+			nsConfig.IndexLifecycle.PolicyName,
+			"The name of the index lifecycle policy, jaeger-index-policy prefixed by the index prefix if empty")
+		flagSet.Duration(
+			nsConfig.namespace+suffixLifecycleMaxAge,
+			nsConfig.IndexLifecycle.RolloverMaxAge,
+			"The age of the write index rolled over by the index lifecycle policy, 0 to roll over on size only")
+		flagSet.String(
+			nsConfig.namespace+suffixLifecycleMaxSize,
+			nsConfig.IndexLifecycle.RolloverMaxSize,
+			"The size of the write index rolled over by the index lifecycle policy, e.g. 50gb; empty to roll over on age only")
+		flagSet.Duration(
+			nsConfig.namespace+suffixLifecycleDelete,
+			nsConfig.IndexLifecycle.DeleteAfter,
+			"The age of the indices deleted by the index lifecycle policy after their rollover, 0 to never delete them")
+		flagSet.Bool(
+			nsConfig.namespace+suffixAsyncSearchEnabled,
+			nsConfig.AsyncSearch.Enabled,
+			"Search the traces with the async search API, requires Elasticsearch 7.7 or later. "+
+				"The searches running longer than "+nsConfig.namespace+suffixAsyncSearchBudget+" return the traces found so far with a warning.")
+		flagSet.Duration(
+			nsConfig.namespace+suffixAsyncSearchWait,
+			nsConfig.AsyncSearch.WaitTimeout,
+			"How long each request of the async search waits for it to complete, it must be lower than "+nsConfig.namespace+suffixTimeout)
+		flagSet.Duration(
+			nsConfig.namespace+suffixAsyncSearchBudget,
+			nsConfig.AsyncSearch.Budget,
+			"How long the async search runs before its partial results are returned")
+		flagSet.Bool(
+			nsConfig.namespace+suffixPointInTimeEnabled,
+			nsConfig.PointInTime.Enabled,
+			"Find the traces by paging with search_after through a point in time of the span indices instead of aggregating them, "+
+				"requires Elasticsearch 7.10 or later. The searches return any number of traces and the query service pages through them.")
+		flagSet.Duration(
+			nsConfig.namespace+suffixPointInTimeKeep,
+			nsConfig.PointInTime.KeepAlive,
+			"How long the point in time of a search is kept between its pages")
+	}
+	flagSet.Bool(
+		nsConfig.namespace+suffixAWSEnabled,
+		nsConfig.AWS.Enabled,
+		"Sign the requests with AWS Signature Version 4 for Amazon OpenSearch Service, "+
+			"using the credentials of the environment, e.g. AWS_ACCESS_KEY_ID or the role of the instance")
+	flagSet.String(
+		nsConfig.namespace+suffixAWSRegion,
+		nsConfig.AWS.Region,
+		"The AWS region of the domain or the collection, by default the region of the environment, e.g. AWS_REGION")
+	flagSet.Bool(
+		nsConfig.namespace+suffixAWSServerless,
+		nsConfig.AWS.Serverless,
+		"The servers are the endpoint of an OpenSearch Serverless time series collection, requires "+nsConfig.namespace+suffixAWSEnabled+". "+
+			"Sniffing, the version detection, the index lifecycle policies, the async search and the points in time are not available.")
 	flagSet.Bool(
 		nsConfig.namespace+suffixCreateIndexTemplate,
 		nsConfig.CreateIndexTemplates,
 		"Create index templates at application startup. Set to false when templates are installed manually.")
+	flagSet.Bool(
+		nsConfig.namespace+suffixComposableTemplates,
+		nsConfig.ComposableTemplates,
+		"Create composable index templates made of component templates instead of the deprecated legacy templates, "+
+			"requires Elasticsearch 7.8 or later and is always the case from Elasticsearch 8. "+
+			"The legacy templates of previous installations are deleted.")
 	flagSet.Uint(
 		nsConfig.namespace+suffixVersion,
 		0,
@@ -232,11 +375,18 @@ func addFlags(flagSet *flag.FlagSet, nsConfig *namespaceConfig) {
 		nsConfig.namespace+suffixSnifferTLSEnabled,
 		nsConfig.SnifferTLSEnabled,
 		"Option to enable TLS when sniffing an Elasticsearch Cluster ; client uses sniffing process to find all nodes automatically, disabled by default")
-	if nsConfig.namespace == archiveNamespace {
+	switch nsConfig.namespace {
+	case archiveNamespace:
 		flagSet.Bool(
 			nsConfig.namespace+suffixEnabled,
 			nsConfig.Enabled,
 			"Enable extra storage")
+	case migrationNamespace:
+		flagSet.Bool(
+			nsConfig.namespace+suffixEnabled,
+			nsConfig.Enabled,
+			"Write the spans to both the primary storage and this one, and merge the spans read from both, "+
+				"to migrate to another cluster or index layout without downtime.")
 	}
 	nsConfig.getTLSFlagsConfig().AddFlags(flagSet)
 }
@@ -266,12 +416,36 @@ func initFromViper(cfg *namespaceConfig, v *viper.Viper) {
 	cfg.BulkFlushInterval = v.GetDuration(cfg.namespace + suffixBulkFlushInterval)
 	cfg.Timeout = v.GetDuration(cfg.namespace + suffixTimeout)
 	cfg.IndexPrefix = v.GetString(cfg.namespace + suffixIndexPrefix)
+	cfg.TenantIndexPrefix = v.GetBool(cfg.namespace + suffixTenantIndexPrefix)
 	cfg.Tags.AllAsFields = v.GetBool(cfg.namespace + suffixTagsAsFieldsAll)
 	cfg.Tags.File = v.GetString(cfg.namespace + suffixTagsFile)
 	cfg.Tags.DotReplacement = v.GetString(cfg.namespace + suffixTagDeDotChar)
+	cfg.Tags.DefaultStrategy = v.GetString(cfg.namespace + suffixTagDefaultStrategy)
+	cfg.Storage.IndexCodec = v.GetString(cfg.namespace + suffixStorageCodec)
+	cfg.Storage.ExcludeDerivedFields = v.GetBool(cfg.namespace + suffixStorageDerived)
+	cfg.Storage.UnindexedFields = nil
+	if fields := stripWhiteSpace(v.GetString(cfg.namespace + suffixStorageUnindexed)); fields != "" {
+		cfg.Storage.UnindexedFields = strings.Split(fields, ",")
+	}
+	cfg.Storage.MeasureSize = v.GetBool(cfg.namespace + suffixStorageMeasureSize)
 	cfg.UseReadWriteAliases = v.GetBool(cfg.namespace + suffixReadAlias)
+	cfg.UseDataStreams = v.GetBool(cfg.namespace + suffixDataStreams)
+	cfg.IndexLifecycle.Type = v.GetString(cfg.namespace + suffixLifecycleType)
+	cfg.IndexLifecycle.PolicyName = v.GetString(cfg.namespace + suffixLifecyclePolicyName)
+	cfg.IndexLifecycle.RolloverMaxAge = v.GetDuration(cfg.namespace + suffixLifecycleMaxAge)
+	cfg.IndexLifecycle.RolloverMaxSize = v.GetString(cfg.namespace + suffixLifecycleMaxSize)
+	cfg.IndexLifecycle.DeleteAfter = v.GetDuration(cfg.namespace + suffixLifecycleDelete)
+	cfg.AsyncSearch.Enabled = v.GetBool(cfg.namespace + suffixAsyncSearchEnabled)
+	cfg.AsyncSearch.WaitTimeout = v.GetDuration(cfg.namespace + suffixAsyncSearchWait)
+	cfg.AsyncSearch.Budget = v.GetDuration(cfg.namespace + suffixAsyncSearchBudget)
+	cfg.PointInTime.Enabled = v.GetBool(cfg.namespace + suffixPointInTimeEnabled)
+	cfg.PointInTime.KeepAlive = v.GetDuration(cfg.namespace + suffixPointInTimeKeep)
+	cfg.AWS.Enabled = v.GetBool(cfg.namespace + suffixAWSEnabled)
+	cfg.AWS.Region = v.GetString(cfg.namespace + suffixAWSRegion)
+	cfg.AWS.Serverless = v.GetBool(cfg.namespace + suffixAWSServerless)
 	cfg.Enabled = v.GetBool(cfg.namespace + suffixEnabled)
 	cfg.CreateIndexTemplates = v.GetBool(cfg.namespace + suffixCreateIndexTemplate)
+	cfg.ComposableTemplates = v.GetBool(cfg.namespace + suffixComposableTemplates)
 	cfg.Version = uint(v.GetInt(cfg.namespace + suffixVersion))
 	// TODO: Need to figure out a better way for do this.
 	cfg.AllowTokenFromContext = v.GetBool(spanstore.StoragePropagationKey)
@@ -55,10 +55,27 @@ func TestOptionsWithFlags(t *testing.T) {
 		"--es.max-span-age=48h",
 		"--es.num-shards=20",
 		"--es.num-replicas=10",
+		"--es.use-data-streams=true",
+		"--es.tenant-index-prefix=true",
+		"--es.index-lifecycle.type=ilm",
+		"--es.index-lifecycle.rollover-max-age=12h",
+		"--es.index-lifecycle.delete-after=0",
+		"--es.async-search.enabled=true",
+		"--es.async-search.budget=2m",
+		"--es.point-in-time.enabled=true",
+		"--es.storage.index-codec=best_compression",
+		"--es.storage.exclude-derived-fields=true",
+		"--es.storage.unindexed-fields=logs, references",
+		"--es.storage.measure-size=true",
+		"--es.tags-as-fields.default-strategy=flattened",
+		"--es.aws.enabled=true",
+		"--es.aws.region=eu-west-1",
+		"--es.use-composable-templates=true",
 		// a couple overrides
 		"--es.aux.server-urls=3.3.3.3, 4.4.4.4",
 		"--es.aux.max-span-age=24h",
 		"--es.aux.num-replicas=10",
+		"--es.aux.aws.serverless=true",
 		"--es.tls.enabled=true",
 		"--es.tls.skip-host-verify=true",
 	})
@@ -71,6 +88,27 @@ func TestOptionsWithFlags(t *testing.T) {
 	assert.Equal(t, 48*time.Hour, primary.MaxSpanAge)
 	assert.True(t, primary.Sniffer)
 	assert.True(t, primary.SnifferTLSEnabled)
+	assert.True(t, primary.UseDataStreams)
+	assert.True(t, primary.TenantIndexPrefix)
+	assert.Equal(t, "ilm", primary.IndexLifecycle.Type)
+	assert.Equal(t, "", primary.IndexLifecycle.PolicyName)
+	assert.Equal(t, 12*time.Hour, primary.IndexLifecycle.RolloverMaxAge)
+	assert.Equal(t, "50gb", primary.IndexLifecycle.RolloverMaxSize)
+	assert.Equal(t, time.Duration(0), primary.IndexLifecycle.DeleteAfter)
+	assert.True(t, primary.AsyncSearch.Enabled)
+	assert.Equal(t, time.Second, primary.AsyncSearch.WaitTimeout)
+	assert.Equal(t, 2*time.Minute, primary.AsyncSearch.Budget)
+	assert.True(t, primary.PointInTime.Enabled)
+	assert.Equal(t, 5*time.Minute, primary.PointInTime.KeepAlive)
+	assert.Equal(t, "best_compression", primary.Storage.IndexCodec)
+	assert.True(t, primary.Storage.ExcludeDerivedFields)
+	assert.Equal(t, []string{"logs", "references"}, primary.Storage.UnindexedFields)
+	assert.True(t, primary.Storage.MeasureSize)
+	assert.Equal(t, "flattened", primary.Tags.DefaultStrategy)
+	assert.True(t, primary.AWS.Enabled)
+	assert.Equal(t, "eu-west-1", primary.AWS.Region)
+	assert.False(t, primary.AWS.Serverless)
+	assert.True(t, primary.ComposableTemplates)
 	assert.Equal(t, true, primary.TLS.Enabled)
 	assert.Equal(t, true, primary.TLS.SkipHostVerify)
 
@@ -82,5 +120,26 @@ func TestOptionsWithFlags(t *testing.T) {
 	assert.Equal(t, int64(10), aux.NumReplicas)
 	assert.Equal(t, 24*time.Hour, aux.MaxSpanAge)
 	assert.True(t, aux.Sniffer)
+	assert.True(t, aux.AWS.Enabled)
+	assert.Equal(t, "eu-west-1", aux.AWS.Region)
+	assert.True(t, aux.AWS.Serverless)
 
 }
+
+func TestOptionsMigration(t *testing.T) {
+	opts := NewOptions(primaryNamespace, archiveNamespace, migrationNamespace)
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{
+		"--es-migration.enabled=true",
+		"--es-migration.server-urls=http://new:9200",
+		"--es-migration.use-data-streams=true",
+	})
+	opts.InitFromViper(v)
+
+	assert.False(t, opts.Get(archiveNamespace).Enabled)
+	migration := opts.Get(migrationNamespace)
+	assert.True(t, migration.Enabled)
+	assert.Equal(t, []string{"http://new:9200"}, migration.Servers)
+	assert.True(t, migration.UseDataStreams)
+	assert.False(t, opts.GetPrimary().UseDataStreams)
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/olivere/elastic"
+	"go.uber.org/zap"
+)
+
+// partialSearchWarning flags the traces found by a search which did not complete
+const partialSearchWarning = "the search of the traces did not complete, some matching traces may be missing"
+
+// findTraceIDsAsync submits the search of the trace IDs to the async search API and polls it until it
+// completes or its budget is spent. The results of a search cut short, or which missed some shards, are partial.
+func (s *SpanReader) findTraceIDsAsync(
+	ctx context.Context,
+	indices []string,
+	query elastic.Query,
+	aggregation elastic.Aggregation,
+) (*elastic.SearchResult, bool, error) {
+	deadline := time.Now().Add(s.asyncSearch.Budget)
+	result, err := s.client.AsyncSearch(indices...).
+		Size(0).
+		Aggregation(traceIDAggregation, aggregation).
+		IgnoreUnavailable(true).
+		Query(query).
+		WaitForCompletionTimeout(s.asyncSearch.WaitTimeout).
+		// the search outliving the budget, e.g. when the deletion below fails, is dropped by Elasticsearch
+		KeepAlive(s.asyncSearch.Budget + s.asyncSearch.WaitTimeout).
+		Do(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	for result.IsRunning {
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			break
+		}
+		if wait > s.asyncSearch.WaitTimeout {
+			wait = s.asyncSearch.WaitTimeout
+		}
+		if result, err = s.client.GetAsyncSearch(result.ID).WaitForCompletionTimeout(wait).Do(ctx); err != nil {
+			return nil, false, err
+		}
+	}
+	if result.ID != "" {
+		// the searches completing within the first wait are not stored
+		if err := s.client.DeleteAsyncSearch(result.ID).Do(ctx); err != nil {
+			s.logger.Warn("Failed to delete the async search", zap.String("id", result.ID), zap.Error(err))
+		}
+	}
+	return result.Response, result.IsRunning || result.IsPartial, nil
+}
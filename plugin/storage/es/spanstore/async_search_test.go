@@ -0,0 +1,191 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/olivere/elastic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/pkg/es"
+	"github.com/jaegertracing/jaeger/pkg/es/config"
+	"github.com/jaegertracing/jaeger/pkg/es/mocks"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+func withAsyncSpanReader(budget time.Duration, fn func(r *spanReaderTest)) {
+	client := &mocks.Client{}
+	fn(&spanReaderTest{
+		client: client,
+		reader: NewSpanReader(SpanReaderParams{
+			Client:            client,
+			Logger:            zap.NewNop(),
+			TagDotReplacement: "@",
+			AsyncSearch: config.AsyncSearch{
+				Enabled:     true,
+				WaitTimeout: time.Second,
+				Budget:      budget,
+			},
+		}),
+	})
+}
+
+func mockAsyncSearchService(r *spanReaderTest) *mock.Call {
+	asyncSearchService := &mocks.AsyncSearchService{}
+	asyncSearchService.On("Size", 0).Return(asyncSearchService)
+	asyncSearchService.On("Aggregation", traceIDAggregation, mock.AnythingOfType("*elastic.TermsAggregation")).Return(asyncSearchService)
+	asyncSearchService.On("IgnoreUnavailable", true).Return(asyncSearchService)
+	asyncSearchService.On("Query", mock.Anything).Return(asyncSearchService)
+	asyncSearchService.On("WaitForCompletionTimeout", time.Second).Return(asyncSearchService)
+	asyncSearchService.On("KeepAlive", mock.AnythingOfType("time.Duration")).Return(asyncSearchService)
+	r.client.On("AsyncSearch", "jaeger-span-2020-05-01").Return(asyncSearchService)
+	return asyncSearchService.On("Do", mock.Anything)
+}
+
+func mockGetAsyncSearchService(r *spanReaderTest, id string) *mock.Call {
+	getService := &mocks.AsyncSearchGetService{}
+	getService.On("WaitForCompletionTimeout", mock.AnythingOfType("time.Duration")).Return(getService)
+	r.client.On("GetAsyncSearch", id).Return(getService)
+	return getService.On("Do", mock.Anything)
+}
+
+func mockDeleteAsyncSearchService(r *spanReaderTest, id string) *mock.Call {
+	deleteService := &mocks.AsyncSearchDeleteService{}
+	r.client.On("DeleteAsyncSearch", id).Return(deleteService)
+	return deleteService.On("Do", mock.Anything)
+}
+
+func traceIDsSearchResult() *elastic.SearchResult {
+	rawMessage := json.RawMessage(`{"buckets": [{"key": "1","doc_count": 16},{"key": "2","doc_count": 16}]}`)
+	return &elastic.SearchResult{Aggregations: elastic.Aggregations{traceIDAggregation: &rawMessage}}
+}
+
+func asyncTraceQuery() *spanstore.TraceQueryParameters {
+	startTime := time.Date(2020, time.May, 1, 12, 0, 0, 0, time.UTC)
+	return &spanstore.TraceQueryParameters{
+		ServiceName:  serviceName,
+		StartTimeMin: startTime,
+		StartTimeMax: startTime.Add(time.Hour),
+	}
+}
+
+func TestFindTraceIDsAsync(t *testing.T) {
+	t.Run("completed at submit", func(t *testing.T) {
+		withAsyncSpanReader(time.Minute, func(r *spanReaderTest) {
+			mockAsyncSearchService(r).Return(&es.AsyncSearchResult{Response: traceIDsSearchResult()}, nil)
+
+			traceIDs, partial, err := r.reader.findTraceIDs(context.Background(), asyncTraceQuery())
+			require.NoError(t, err)
+			assert.False(t, partial)
+			assert.Equal(t, []string{"1", "2"}, traceIDs)
+			r.client.AssertNotCalled(t, "DeleteAsyncSearch", mock.Anything)
+			r.client.AssertNotCalled(t, "Search", mock.Anything)
+		})
+	})
+	t.Run("completed after polling", func(t *testing.T) {
+		withAsyncSpanReader(time.Minute, func(r *spanReaderTest) {
+			mockAsyncSearchService(r).Return(&es.AsyncSearchResult{ID: "search-id", IsRunning: true}, nil)
+			mockGetAsyncSearchService(r, "search-id").Return(&es.AsyncSearchResult{ID: "search-id", Response: traceIDsSearchResult()}, nil)
+			mockDeleteAsyncSearchService(r, "search-id").Return(nil)
+
+			traceIDs, partial, err := r.reader.findTraceIDs(context.Background(), asyncTraceQuery())
+			require.NoError(t, err)
+			assert.False(t, partial)
+			assert.Equal(t, []string{"1", "2"}, traceIDs)
+			r.client.AssertCalled(t, "DeleteAsyncSearch", "search-id")
+		})
+	})
+	t.Run("budget spent", func(t *testing.T) {
+		withAsyncSpanReader(time.Nanosecond, func(r *spanReaderTest) {
+			mockAsyncSearchService(r).Return(&es.AsyncSearchResult{ID: "search-id", IsRunning: true, Response: traceIDsSearchResult()}, nil)
+			mockDeleteAsyncSearchService(r, "search-id").Return(errors.New("delete-error"))
+
+			traceIDs, partial, err := r.reader.findTraceIDs(context.Background(), asyncTraceQuery())
+			require.NoError(t, err)
+			assert.True(t, partial)
+			assert.Equal(t, []string{"1", "2"}, traceIDs)
+			r.client.AssertNotCalled(t, "GetAsyncSearch", mock.Anything)
+		})
+	})
+	t.Run("no results yet", func(t *testing.T) {
+		withAsyncSpanReader(time.Nanosecond, func(r *spanReaderTest) {
+			mockAsyncSearchService(r).Return(&es.AsyncSearchResult{ID: "search-id", IsRunning: true}, nil)
+			mockDeleteAsyncSearchService(r, "search-id").Return(nil)
+
+			traceIDs, partial, err := r.reader.findTraceIDs(context.Background(), asyncTraceQuery())
+			require.NoError(t, err)
+			assert.True(t, partial)
+			assert.Empty(t, traceIDs)
+		})
+	})
+	t.Run("shard failures", func(t *testing.T) {
+		withAsyncSpanReader(time.Minute, func(r *spanReaderTest) {
+			mockAsyncSearchService(r).Return(&es.AsyncSearchResult{IsPartial: true, Response: traceIDsSearchResult()}, nil)
+
+			_, partial, err := r.reader.findTraceIDs(context.Background(), asyncTraceQuery())
+			require.NoError(t, err)
+			assert.True(t, partial)
+		})
+	})
+	t.Run("submit error", func(t *testing.T) {
+		withAsyncSpanReader(time.Minute, func(r *spanReaderTest) {
+			mockAsyncSearchService(r).Return(nil, errors.New("submit-error"))
+
+			_, _, err := r.reader.findTraceIDs(context.Background(), asyncTraceQuery())
+			assert.EqualError(t, err, "search services failed: submit-error")
+		})
+	})
+	t.Run("poll error", func(t *testing.T) {
+		withAsyncSpanReader(time.Minute, func(r *spanReaderTest) {
+			mockAsyncSearchService(r).Return(&es.AsyncSearchResult{ID: "search-id", IsRunning: true}, nil)
+			mockGetAsyncSearchService(r, "search-id").Return(nil, errors.New("poll-error"))
+
+			_, _, err := r.reader.findTraceIDs(context.Background(), asyncTraceQuery())
+			assert.EqualError(t, err, "search services failed: poll-error")
+		})
+	})
+}
+
+func TestFindTracesAsyncPartial(t *testing.T) {
+	hits := []*elastic.SearchHit{{Source: (*json.RawMessage)(&exampleESSpan)}}
+	withAsyncSpanReader(time.Nanosecond, func(r *spanReaderTest) {
+		mockAsyncSearchService(r).Return(&es.AsyncSearchResult{ID: "search-id", IsRunning: true, Response: traceIDsSearchResult()}, nil)
+		mockDeleteAsyncSearchService(r, "search-id").Return(nil)
+		multiSearchService := &mocks.MultiSearchService{}
+		multiSearchService.On("Add", mock.Anything, mock.Anything).Return(multiSearchService)
+		multiSearchService.On("Index", "jaeger-span-2020-05-01").Return(multiSearchService)
+		multiSearchService.On("Do", mock.Anything).Return(&elastic.MultiSearchResult{
+			Responses: []*elastic.SearchResult{{Hits: &elastic.SearchHits{Hits: hits}}},
+		}, nil)
+		r.client.On("MultiSearch").Return(multiSearchService)
+
+		traces, err := r.reader.FindTraces(context.Background(), asyncTraceQuery())
+		require.NoError(t, err)
+		require.Len(t, traces, 1)
+		assert.Equal(t, []string{partialSearchWarning}, traces[0].Warnings)
+
+		traceIDs, err := r.reader.FindTraceIDs(context.Background(), asyncTraceQuery())
+		require.NoError(t, err)
+		assert.Len(t, traceIDs, 2)
+	})
+}
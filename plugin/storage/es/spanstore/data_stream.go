@@ -0,0 +1,77 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jaegertracing/jaeger/plugin/storage/es/spanstore/dbmodel"
+)
+
+const (
+	// opTypeCreate is the only operation accepted by data streams, their documents are append-only
+	opTypeCreate = "create"
+	// timestampField is the field required in the documents of data streams
+	timestampField = "@timestamp"
+)
+
+// dataStreamSpan is a span written to a data stream, with the required timestamp in epoch milliseconds.
+type dataStreamSpan struct {
+	*dbmodel.Span
+	Timestamp uint64 `json:"@timestamp"`
+}
+
+// dataStreamService is a service to operation pair written to a data stream.
+type dataStreamService struct {
+	dbmodel.Service
+	Timestamp uint64 `json:"@timestamp"`
+}
+
+// dataStreamTemplate converts a legacy index template to the composable index template of a data stream,
+// matching only the data stream, or the patterns of the data streams following it, and mapping its timestamp field.
+// It has precedence over the composable index templates of the span and service indices, which match the data stream.
+func dataStreamTemplate(legacyTemplate, dataStream string, patterns ...string) (string, error) {
+	var legacy struct {
+		Settings map[string]interface{} `json:"settings"`
+		Mappings map[string]interface{} `json:"mappings"`
+	}
+	if err := json.Unmarshal([]byte(legacyTemplate), &legacy); err != nil {
+		return "", fmt.Errorf("invalid index template of %s: %w", dataStream, err)
+	}
+	if legacy.Mappings == nil {
+		legacy.Mappings = map[string]interface{}{}
+	}
+	properties, _ := legacy.Mappings["properties"].(map[string]interface{})
+	if properties == nil {
+		properties = map[string]interface{}{}
+		legacy.Mappings["properties"] = properties
+	}
+	properties[timestampField] = map[string]interface{}{"type": "date"}
+	template := map[string]interface{}{
+		"index_patterns": append([]string{dataStream}, patterns...),
+		"data_stream":    map[string]interface{}{},
+		"priority":       IndexTemplatePriority + 1,
+		"template": map[string]interface{}{
+			"settings": legacy.Settings,
+			"mappings": legacy.Mappings,
+		},
+	}
+	body, err := json.Marshal(template)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
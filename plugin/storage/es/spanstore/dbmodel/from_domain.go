@@ -21,19 +21,68 @@ import (
 	"github.com/jaegertracing/jaeger/model"
 )
 
+// TagStrategy is how the tags are stored in the span documents
+type TagStrategy string
+
+const (
+	// NestedTags stores the tags in the nested tags documents, one document per tag
+	NestedTags TagStrategy = "nested"
+	// ObjectTags stores the tags in object fields named after their keys with the dots replaced,
+	// one field of the mappings per key
+	ObjectTags TagStrategy = "object"
+	// FlattenedTags stores the tags in a single flattened field, whatever their keys,
+	// which requires Elasticsearch 7.3 or later
+	FlattenedTags TagStrategy = "flattened"
+)
+
+// TagStrategies are the strategies storing the tags of the span documents
+type TagStrategies struct {
+	// Default is the strategy of the tags whose keys have none, NestedTags if empty
+	Default TagStrategy
+	// Keys are the strategies of the tags by key
+	Keys map[string]TagStrategy
+}
+
+// Uses indicates whether some tags are stored with the strategy
+func (s TagStrategies) Uses(strategy TagStrategy) bool {
+	if s.Default == strategy {
+		return true
+	}
+	for _, keyStrategy := range s.Keys {
+		if keyStrategy == strategy {
+			return true
+		}
+	}
+	return false
+}
+
+func (s TagStrategies) of(key string) TagStrategy {
+	if strategy, ok := s.Keys[key]; ok {
+		return strategy
+	}
+	return s.Default
+}
+
 // NewFromDomain creates FromDomain used to convert model span to db span
 func NewFromDomain(allTagsAsObject bool, tagKeysAsFields []string, tagDotReplacement string) FromDomain {
-	tags := map[string]bool{}
+	strategies := TagStrategies{Default: NestedTags, Keys: map[string]TagStrategy{}}
+	if allTagsAsObject {
+		strategies.Default = ObjectTags
+	}
 	for _, k := range tagKeysAsFields {
-		tags[k] = true
+		strategies.Keys[k] = ObjectTags
 	}
-	return FromDomain{allTagsAsFields: allTagsAsObject, tagKeysAsFields: tags, tagDotReplacement: tagDotReplacement}
+	return NewFromDomainWithStrategies(strategies, tagDotReplacement)
+}
+
+// NewFromDomainWithStrategies creates FromDomain storing the tags with the strategies of their keys
+func NewFromDomainWithStrategies(strategies TagStrategies, tagDotReplacement string) FromDomain {
+	return FromDomain{tagStrategies: strategies, tagDotReplacement: tagDotReplacement}
 }
 
 // FromDomain is used to convert model span to db span
 type FromDomain struct {
-	allTagsAsFields   bool
-	tagKeysAsFields   map[string]bool
+	tagStrategies     TagStrategies
 	tagDotReplacement string
 }
 
@@ -44,7 +93,7 @@ func (fd FromDomain) FromDomainEmbedProcess(span *model.Span) *Span {
 }
 
 func (fd FromDomain) convertSpanInternal(span *model.Span) Span {
-	tags, tagsMap := fd.convertKeyValuesString(span.Tags)
+	tags, tagsMap, flatTags := fd.convertKeyValuesString(span.Tags)
 	return Span{
 		TraceID:         TraceID(span.TraceID.String()),
 		SpanID:          SpanID(span.SpanID.String()),
@@ -55,6 +104,7 @@ func (fd FromDomain) convertSpanInternal(span *model.Span) Span {
 		Duration:        model.DurationAsMicroseconds(span.Duration),
 		Tags:            tags,
 		Tag:             tagsMap,
+		FlatTag:         flatTags,
 		Logs:            fd.convertLogs(span.Logs),
 	}
 }
@@ -85,23 +135,34 @@ func (fd FromDomain) convertRefType(refType model.SpanRefType) ReferenceType {
 	return ChildOf
 }
 
-func (fd FromDomain) convertKeyValuesString(keyValues model.KeyValues) ([]KeyValue, map[string]interface{}) {
-	var tagsMap map[string]interface{}
+func (fd FromDomain) convertKeyValuesString(keyValues model.KeyValues) ([]KeyValue, map[string]interface{}, map[string]interface{}) {
+	var tagsMap, flatTags map[string]interface{}
 	var kvs []KeyValue
 	for _, kv := range keyValues {
-		if kv.GetVType() != model.BinaryType && (fd.allTagsAsFields || fd.tagKeysAsFields[kv.Key]) {
+		// the binary tags are always nested
+		strategy := NestedTags
+		if kv.GetVType() != model.BinaryType {
+			strategy = fd.tagStrategies.of(kv.Key)
+		}
+		switch strategy {
+		case ObjectTags:
 			if tagsMap == nil {
 				tagsMap = map[string]interface{}{}
 			}
 			tagsMap[strings.Replace(kv.Key, ".", fd.tagDotReplacement, -1)] = kv.Value()
-		} else {
+		case FlattenedTags:
+			if flatTags == nil {
+				flatTags = map[string]interface{}{}
+			}
+			flatTags[kv.Key] = kv.Value()
+		default:
 			kvs = append(kvs, convertKeyValue(kv))
 		}
 	}
 	if kvs == nil {
 		kvs = make([]KeyValue, 0)
 	}
-	return kvs, tagsMap
+	return kvs, tagsMap, flatTags
 }
 
 func (fd FromDomain) convertLogs(logs []model.Log) []Log {
@@ -120,11 +181,12 @@ func (fd FromDomain) convertLogs(logs []model.Log) []Log {
 }
 
 func (fd FromDomain) convertProcess(process *model.Process) Process {
-	tags, tagsMap := fd.convertKeyValuesString(process.Tags)
+	tags, tagsMap, flatTags := fd.convertKeyValuesString(process.Tags)
 	return Process{
 		ServiceName: process.ServiceName,
 		Tags:        tags,
 		Tag:         tagsMap,
+		FlatTag:     flatTags,
 	}
 }
 
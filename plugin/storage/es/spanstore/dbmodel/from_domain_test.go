@@ -108,6 +108,39 @@ func TestTagMap(t *testing.T) {
 	assert.Equal(t, tagsMap, dbSpan.Process.Tag)
 }
 
+func TestTagStrategies(t *testing.T) {
+	tags := []model.KeyValue{
+		model.String("foo", "foo"),
+		model.Bool("a", true),
+		model.Int64("b.b", 1),
+		model.Binary("bin", []byte("bin")),
+	}
+	span := model.Span{Tags: tags, Process: &model.Process{Tags: tags}}
+	strategies := TagStrategies{Default: FlattenedTags, Keys: map[string]TagStrategy{"a": ObjectTags, "foo": NestedTags}}
+	assert.True(t, strategies.Uses(NestedTags))
+	assert.True(t, strategies.Uses(FlattenedTags))
+	assert.False(t, TagStrategies{}.Uses(FlattenedTags))
+	converter := NewFromDomainWithStrategies(strategies, ":")
+	dbSpan := converter.FromDomainEmbedProcess(&span)
+
+	for _, process := range []bool{false, true} {
+		nested, object, flattened := dbSpan.Tags, dbSpan.Tag, dbSpan.FlatTag
+		if process {
+			nested, object, flattened = dbSpan.Process.Tags, dbSpan.Process.Tag, dbSpan.Process.FlatTag
+		}
+		require.Len(t, nested, 2)
+		assert.Equal(t, "foo", nested[0].Key)
+		assert.Equal(t, "bin", nested[1].Key, "the binary tags are always nested")
+		assert.Equal(t, map[string]interface{}{"a": true}, object)
+		assert.Equal(t, map[string]interface{}{"b.b": int64(1)}, flattened, "the flattened keys keep their dots")
+	}
+
+	domainSpan, err := NewToDomain(":").SpanToDomain(dbSpan)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, tags, domainSpan.Tags)
+	assert.ElementsMatch(t, tags, domainSpan.Process.Tags)
+}
+
 func TestConvertKeyValueValue(t *testing.T) {
 	longString := `Bender Bending Rodrigues Bender Bending Rodrigues Bender Bending Rodrigues Bender Bending Rodrigues
 	Bender Bending Rodrigues Bender Bending Rodrigues Bender Bending Rodrigues Bender Bending Rodrigues Bender Bending Rodrigues
@@ -61,7 +61,9 @@ type Span struct {
 	Duration        uint64     `json:"duration"` // microseconds
 	Tags            []KeyValue `json:"tags"`
 	// Alternative representation of tags for better kibana support
-	Tag     map[string]interface{} `json:"tag,omitempty"`
+	Tag map[string]interface{} `json:"tag,omitempty"`
+	// Alternative representation of tags in a single flattened field, without dot replacement
+	FlatTag map[string]interface{} `json:"flatTag,omitempty"`
 	Logs    []Log                  `json:"logs"`
 	Process Process                `json:"process,omitempty"`
 }
@@ -79,6 +81,8 @@ type Process struct {
 	Tags        []KeyValue `json:"tags"`
 	// Alternative representation of tags for better kibana support
 	Tag map[string]interface{} `json:"tag,omitempty"`
+	// Alternative representation of tags in a single flattened field, without dot replacement
+	FlatTag map[string]interface{} `json:"flatTag,omitempty"`
 }
 
 // Log is a log emitted in a span
@@ -81,11 +81,16 @@ func (td ToDomain) SpanToDomain(dbSpan *Span) (*model.Span, error) {
 		refs = model.MaybeAddParentSpanID(traceID, parentSpanID, refs)
 	}
 
-	fieldTags, err := td.convertTagFields(dbSpan.Tag)
+	fieldTags, err := td.convertTagFields(dbSpan.Tag, td.ReplaceDotReplacement)
 	if err != nil {
 		return nil, err
 	}
 	tags = append(tags, fieldTags...)
+	flatTags, err := td.convertTagFields(dbSpan.FlatTag, nil)
+	if err != nil {
+		return nil, err
+	}
+	tags = append(tags, flatTags...)
 
 	span := &model.Span{
 		TraceID:       traceID,
@@ -147,10 +152,14 @@ func (td ToDomain) convertKeyValues(tags []KeyValue) ([]model.KeyValue, error) {
 	return retMe, nil
 }
 
-func (td ToDomain) convertTagFields(tagsMap map[string]interface{}) ([]model.KeyValue, error) {
+// convertTagFields converts the tags of an object or flattened field, keyFn restores their keys if not nil
+func (td ToDomain) convertTagFields(tagsMap map[string]interface{}, keyFn func(string) string) ([]model.KeyValue, error) {
 	kvs := make([]model.KeyValue, len(tagsMap))
 	i := 0
 	for k, v := range tagsMap {
+		if keyFn != nil {
+			k = keyFn(k)
+		}
 		tag, err := td.convertTagField(k, v)
 		if err != nil {
 			return nil, err
@@ -161,8 +170,7 @@ func (td ToDomain) convertTagFields(tagsMap map[string]interface{}) ([]model.Key
 	return kvs, nil
 }
 
-func (td ToDomain) convertTagField(k string, v interface{}) (model.KeyValue, error) {
-	dKey := td.ReplaceDotReplacement(k)
+func (td ToDomain) convertTagField(dKey string, v interface{}) (model.KeyValue, error) {
 	switch val := v.(type) {
 	case int64:
 		return model.Int64(dKey, val), nil
@@ -253,11 +261,16 @@ func (td ToDomain) convertProcess(process Process) (*model.Process, error) {
 	if err != nil {
 		return nil, err
 	}
-	fieldTags, err := td.convertTagFields(process.Tag)
+	fieldTags, err := td.convertTagFields(process.Tag, td.ReplaceDotReplacement)
 	if err != nil {
 		return nil, err
 	}
 	tags = append(tags, fieldTags...)
+	flatTags, err := td.convertTagFields(process.FlatTag, nil)
+	if err != nil {
+		return nil, err
+	}
+	tags = append(tags, flatTags...)
 
 	return &model.Process{
 		Tags:        tags,
@@ -335,7 +335,7 @@ func TestTagsMap(t *testing.T) {
 	converter := NewToDomain(":")
 	for i, test := range tests {
 		t.Run(fmt.Sprintf("%d, %s", i, test.fieldTags), func(t *testing.T) {
-			tags, err := converter.convertTagFields(test.fieldTags)
+			tags, err := converter.convertTagFields(test.fieldTags, converter.ReplaceDotReplacement)
 			if err != nil {
 				fmt.Println(err.Error())
 			}
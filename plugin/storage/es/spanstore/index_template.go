@@ -0,0 +1,96 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/olivere/elastic"
+)
+
+// IndexTemplatePriority is the priority of the composable index templates of the span and service indices,
+// the templates matching a subset of these indices, like those of the data streams, have a higher priority.
+const IndexTemplatePriority = 100
+
+// ComponentTemplateNames returns the names of the settings and mappings component templates of an index template.
+func ComponentTemplateNames(template string) []string {
+	return []string{template + "-settings", template + "-mappings"}
+}
+
+// componentTemplates splits a legacy index template into the bodies of its settings and mappings component templates.
+func componentTemplates(legacyTemplate, name string) ([]string, error) {
+	var legacy struct {
+		Settings map[string]interface{} `json:"settings"`
+		Mappings map[string]interface{} `json:"mappings"`
+	}
+	if err := json.Unmarshal([]byte(legacyTemplate), &legacy); err != nil {
+		return nil, fmt.Errorf("invalid index template of %s: %w", name, err)
+	}
+	if legacy.Settings == nil {
+		legacy.Settings = map[string]interface{}{}
+	}
+	if legacy.Mappings == nil {
+		legacy.Mappings = map[string]interface{}{}
+	}
+	var bodies []string
+	for _, template := range []map[string]interface{}{{"settings": legacy.Settings}, {"mappings": legacy.Mappings}} {
+		body, err := json.Marshal(map[string]interface{}{"template": template})
+		if err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, string(body))
+	}
+	return bodies, nil
+}
+
+// CreateComposableTemplates creates the settings and mappings component templates of the span and service indices
+// from the legacy templates, and the composable index templates composed of them. Composable index templates take
+// precedence over the legacy templates matching the same indices, which are deleted to migrate existing installations.
+func (s *SpanWriter) CreateComposableTemplates(spanTemplate, serviceTemplate string) error {
+	if err := s.createComposableTemplate("jaeger-span", spanTemplate); err != nil {
+		return err
+	}
+	return s.createComposableTemplate("jaeger-service", serviceTemplate)
+}
+
+func (s *SpanWriter) createComposableTemplate(name, legacyTemplate string) error {
+	bodies, err := componentTemplates(legacyTemplate, name)
+	if err != nil {
+		return err
+	}
+	components := ComponentTemplateNames(name)
+	for i, component := range components {
+		if _, err := s.client.CreateComponentTemplate(component).Body(bodies[i]).Do(context.Background()); err != nil {
+			return err
+		}
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"index_patterns": []string{"*" + name + "-*"},
+		"priority":       IndexTemplatePriority,
+		"composed_of":    components,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := s.client.CreateIndexTemplate(name).Body(string(body)).Do(context.Background()); err != nil {
+		return err
+	}
+	if _, err := s.client.DeleteTemplate(name).Do(context.Background()); err != nil && !elastic.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
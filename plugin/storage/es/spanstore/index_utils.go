@@ -15,6 +15,9 @@
 package spanstore
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -28,3 +31,29 @@ func indexWithDate(indexPrefix string, date time.Time) string {
 func archiveIndex(indexPrefix, archiveSuffix string) string {
 	return indexPrefix + archiveSuffix
 }
+
+// TenantIndexPrefix returns the index prefix of the spans of the tenant, the index prefix followed by the
+// encoded tenant. The lower case letters and the digits of the tenant are kept, and its other bytes are
+// written as an underscore followed by their hexadecimal value, e.g. Acme as _41cme, so that two tenants
+// never share their indices, nor match the index patterns of the others. Without an index prefix, the
+// tenant must start with a lower case letter or a digit, since the index names cannot start with an underscore.
+func TenantIndexPrefix(indexPrefix, tenant string) (string, error) {
+	if tenant == "" {
+		return "", errors.New("the tenant of the index names is empty")
+	}
+	var encoded strings.Builder
+	for i := 0; i < len(tenant); i++ {
+		if c := tenant[i]; (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			encoded.WriteByte(c)
+		} else {
+			fmt.Fprintf(&encoded, "_%02x", c)
+		}
+	}
+	if indexPrefix != "" {
+		return indexPrefix + indexPrefixSeparator + encoded.String(), nil
+	}
+	if strings.HasPrefix(encoded.String(), "_") {
+		return "", fmt.Errorf("the index names of the tenant %q need an index prefix, as they must start with a lower case letter or a digit", tenant)
+	}
+	return encoded.String(), nil
+}
@@ -0,0 +1,310 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/olivere/elastic"
+	"github.com/opentracing/opentracing-go"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+// pointInTimeBatchSize is the number of spans read by each search of a point in time
+const pointInTimeBatchSize = 1000
+
+// pageCursor is where the next page of a search starts in its point in time, encoded as an opaque string.
+type pageCursor struct {
+	PIT string `json:"pit"`
+	// SearchAfter are the sort values of the last span read, its start time then its position in the point in time
+	SearchAfter []interface{} `json:"after,omitempty"`
+	// Tied are the traces of the spans read with the same start time as the last one, which the next
+	// page cannot tell apart by their start time from the spans it reads
+	Tied []string `json:"tied,omitempty"`
+}
+
+func encodePageCursor(cursor pageCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodePageCursor(cursor string) (pageCursor, error) {
+	var c pageCursor
+	if cursor == "" {
+		return c, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return c, spanstore.ErrInvalidPageCursor
+	}
+	d := json.NewDecoder(bytes.NewReader(data))
+	// the sort values are integers, which must not lose their precision
+	d.UseNumber()
+	if err := d.Decode(&c); err != nil || c.PIT == "" {
+		return c, spanstore.ErrInvalidPageCursor
+	}
+	return c, nil
+}
+
+// FindTracesPage implements spanstore.PageReader#FindTracesPage when the traces are found in points in time,
+// the point in time of a search is closed after its last page, or expires when the following pages are not read.
+func (s *SpanReader) FindTracesPage(
+	ctx context.Context,
+	traceQuery *spanstore.TraceQueryParameters,
+	cursor string,
+) ([]*model.Trace, string, error) {
+	if !s.pointInTime.Enabled {
+		return nil, "", spanstore.ErrPagingNotSupported
+	}
+	span, ctx := opentracing.StartSpanFromContext(ctx, "FindTracesPage")
+	defer span.Finish()
+
+	c, err := decodePageCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := validateQuery(traceQuery); err != nil {
+		return nil, "", err
+	}
+	if traceQuery.NumTraces == 0 {
+		traceQuery.NumTraces = defaultNumTraces
+	}
+	indices := s.timeRangeIndices(s.spanIndexPrefix, traceQuery.StartTimeMin, traceQuery.StartTimeMax)
+	esTraceIDs, c, done, err := s.findTraceIDsInPointInTime(ctx, indices, s.buildFindTraceIDsQuery(traceQuery), traceQuery.NumTraces, c)
+	if err != nil {
+		return nil, "", err
+	}
+	next := encodePageCursor(c)
+	if done {
+		s.closePointInTime(ctx, c.PIT)
+		next = ""
+	}
+	traceIDs, err := convertTraceIDsStringsToModels(esTraceIDs)
+	if err != nil {
+		return nil, "", err
+	}
+	traces, err := s.multiRead(ctx, traceIDs, traceQuery.StartTimeMin, traceQuery.StartTimeMax)
+	if err != nil {
+		return nil, "", err
+	}
+	return sortTraces(traces, traceIDs), next, nil
+}
+
+// findTraceIDsInPointInTime reads the spans matching the query in a point in time, the most recent first, from the
+// cursor until it finds numTraces traces not found by the previous pages, opening the point in time of the indices
+// for the first page. It returns the IDs of the traces, the cursor after them, and whether all spans were read.
+func (s *SpanReader) findTraceIDsInPointInTime(
+	ctx context.Context,
+	indices []string,
+	query elastic.Query,
+	numTraces int,
+	cursor pageCursor,
+) ([]string, pageCursor, bool, error) {
+	if cursor.PIT == "" {
+		pit, err := s.client.OpenPointInTime(indices...).IgnoreUnavailable(true).KeepAlive(s.pointInTime.KeepAlive).Do(ctx)
+		if err != nil {
+			return nil, cursor, false, err
+		}
+		cursor.PIT = pit
+	}
+	// the traces having spans more recent than the start of the page, or tied with it, were found by the previous pages
+	var pageStart interface{}
+	if len(cursor.SearchAfter) > 0 {
+		pageStart = cursor.SearchAfter[0]
+	}
+	var traceIDs []string
+	found := make(map[string]bool)
+	for _, traceID := range cursor.Tied {
+		found[traceID] = true
+	}
+	for len(traceIDs) < numTraces {
+		search := s.client.PointInTimeSearch(cursor.PIT).
+			KeepAlive(s.pointInTime.KeepAlive).
+			Size(pointInTimeBatchSize).
+			Query(query).
+			Sort(startTimeField, false).
+			FetchSource(traceIDField)
+		if len(cursor.SearchAfter) > 0 {
+			search = search.SearchAfter(cursor.SearchAfter...)
+		}
+		result, err := search.Do(ctx)
+		if err != nil {
+			return nil, cursor, false, err
+		}
+		cursor.PIT = result.ID
+		var hits []*elastic.SearchHit
+		if result.Response != nil && result.Response.Hits != nil {
+			hits = result.Response.Hits.Hits
+		}
+		var candidates []string
+		read := 0
+		for _, hit := range hits {
+			if len(traceIDs)+len(candidates) == numTraces {
+				break
+			}
+			read++
+			traceID, err := hitTraceID(hit)
+			if err != nil {
+				return nil, cursor, false, err
+			}
+			if !sameStartTime(hit.Sort, cursor.SearchAfter) {
+				cursor.Tied = nil
+			}
+			cursor.SearchAfter = hit.Sort
+			if !contains(cursor.Tied, traceID) {
+				cursor.Tied = append(cursor.Tied, traceID)
+			}
+			if !found[traceID] {
+				found[traceID] = true
+				candidates = append(candidates, traceID)
+			}
+		}
+		if pageStart != nil && len(candidates) > 0 {
+			if candidates, err = s.excludeFoundTraceIDs(ctx, &cursor, query, pageStart, candidates); err != nil {
+				return nil, cursor, false, err
+			}
+		}
+		traceIDs = append(traceIDs, candidates...)
+		if read == len(hits) && len(hits) < pointInTimeBatchSize {
+			return traceIDs, cursor, true, nil
+		}
+	}
+	return traceIDs, cursor, false, nil
+}
+
+// excludeFoundTraceIDs removes the traces having spans matching the query more recent than the start of the page.
+func (s *SpanReader) excludeFoundTraceIDs(
+	ctx context.Context,
+	cursor *pageCursor,
+	query elastic.Query,
+	pageStart interface{},
+	traceIDs []string,
+) ([]string, error) {
+	terms := make([]interface{}, len(traceIDs))
+	for i, traceID := range traceIDs {
+		terms[i] = traceID
+	}
+	result, err := s.client.PointInTimeSearch(cursor.PIT).
+		KeepAlive(s.pointInTime.KeepAlive).
+		Size(0).
+		Query(elastic.NewBoolQuery().Must(
+			query,
+			elastic.NewTermsQuery(traceIDField, terms...),
+			elastic.NewRangeQuery(startTimeField).Gt(pageStart),
+		)).
+		Aggregation(traceIDAggregation, elastic.NewTermsAggregation().Field(traceIDField).Size(len(traceIDs))).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cursor.PIT = result.ID
+	if result.Response == nil || result.Response.Aggregations == nil {
+		return traceIDs, nil
+	}
+	bucket, found := result.Response.Aggregations.Terms(traceIDAggregation)
+	if !found {
+		return nil, ErrUnableToFindTraceIDAggregation
+	}
+	previous, err := bucketToStringArray(bucket.Buckets)
+	if err != nil {
+		return nil, err
+	}
+	excluded := make(map[string]bool, len(previous))
+	for _, traceID := range previous {
+		excluded[traceID] = true
+	}
+	remaining := traceIDs[:0]
+	for _, traceID := range traceIDs {
+		if !excluded[traceID] {
+			remaining = append(remaining, traceID)
+		}
+	}
+	return remaining, nil
+}
+
+func (s *SpanReader) closePointInTime(ctx context.Context, pit string) {
+	if pit == "" {
+		return
+	}
+	if err := s.client.ClosePointInTime(pit).Do(ctx); err != nil {
+		s.logger.Warn("Failed to close the point in time", zap.Error(err))
+	}
+}
+
+// sameStartTime compares the start times of the sort values of two spans, decoded either from the
+// responses of Elasticsearch or from the cursors.
+func sameStartTime(sort, other []interface{}) bool {
+	if len(sort) == 0 || len(other) == 0 {
+		return false
+	}
+	return sortValue(sort[0]) == sortValue(other[0])
+}
+
+func sortValue(value interface{}) string {
+	if f, ok := value.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func hitTraceID(hit *elastic.SearchHit) (string, error) {
+	var span struct {
+		TraceID string `json:"traceID"`
+	}
+	if hit.Source != nil {
+		if err := json.Unmarshal(*hit.Source, &span); err != nil {
+			return "", err
+		}
+	}
+	if span.TraceID == "" {
+		return "", errors.New("span without trace ID in the point in time")
+	}
+	return span.TraceID, nil
+}
+
+// sortTraces orders the traces like their IDs.
+func sortTraces(traces []*model.Trace, traceIDs []model.TraceID) []*model.Trace {
+	byID := make(map[model.TraceID]*model.Trace, len(traces))
+	for _, trace := range traces {
+		if len(trace.Spans) > 0 {
+			byID[trace.Spans[0].TraceID] = trace
+		}
+	}
+	sorted := make([]*model.Trace, 0, len(traces))
+	for _, traceID := range traceIDs {
+		if trace, ok := byID[traceID]; ok {
+			sorted = append(sorted, trace)
+		}
+	}
+	return sorted
+}
@@ -0,0 +1,259 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olivere/elastic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/es"
+	"github.com/jaegertracing/jaeger/pkg/es/config"
+	"github.com/jaegertracing/jaeger/pkg/es/mocks"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+func withPointInTimeSpanReader(fn func(r *spanReaderTest)) {
+	client := &mocks.Client{}
+	fn(&spanReaderTest{
+		client: client,
+		reader: NewSpanReader(SpanReaderParams{
+			Client:            client,
+			Logger:            zap.NewNop(),
+			TagDotReplacement: "@",
+			PointInTime:       config.PointInTime{Enabled: true, KeepAlive: time.Minute},
+		}),
+	})
+}
+
+// mockPointInTimeSearchService mocks the searches of the point in time, which return the results in turn.
+func mockPointInTimeSearchService(r *spanReaderTest, id string, results ...*es.PointInTimeSearchResult) *mocks.PointInTimeSearchService {
+	searchService := &mocks.PointInTimeSearchService{}
+	for _, method := range []string{"KeepAlive", "Size", "Query", "FetchSource", "SearchAfter"} {
+		searchService.On(method, mock.Anything).Return(searchService)
+	}
+	searchService.On("SearchAfter", mock.Anything, mock.Anything).Return(searchService)
+	searchService.On("Sort", startTimeField, false).Return(searchService)
+	searchService.On("Aggregation", traceIDAggregation, mock.Anything).Return(searchService)
+	for _, result := range results {
+		searchService.On("Do", mock.Anything).Return(result, nil).Once()
+	}
+	r.client.On("PointInTimeSearch", id).Return(searchService)
+	return searchService
+}
+
+func pointInTimeHits(id string, hits ...*elastic.SearchHit) *es.PointInTimeSearchResult {
+	return &es.PointInTimeSearchResult{ID: id, Response: &elastic.SearchResult{Hits: &elastic.SearchHits{Hits: hits}}}
+}
+
+func pointInTimeHit(traceID string, startTime, doc float64) *elastic.SearchHit {
+	source := json.RawMessage(fmt.Sprintf(`{"traceID":%q}`, traceID))
+	return &elastic.SearchHit{Source: &source, Sort: []interface{}{startTime, doc}}
+}
+
+func foundTraceIDs(id string, traceIDs ...string) *es.PointInTimeSearchResult {
+	var buckets []string
+	for _, traceID := range traceIDs {
+		buckets = append(buckets, fmt.Sprintf(`{"key":%q,"doc_count":1}`, traceID))
+	}
+	rawMessage := json.RawMessage(fmt.Sprintf(`{"buckets":[%s]}`, strings.Join(buckets, ",")))
+	return &es.PointInTimeSearchResult{ID: id, Response: &elastic.SearchResult{Aggregations: elastic.Aggregations{traceIDAggregation: &rawMessage}}}
+}
+
+func TestPageCursor(t *testing.T) {
+	cursor := pageCursor{PIT: "pit", SearchAfter: []interface{}{float64(1588334400000000), float64(3)}, Tied: []string{"1"}}
+	decoded, err := decodePageCursor(encodePageCursor(cursor))
+	require.NoError(t, err)
+	assert.Equal(t, "pit", decoded.PIT)
+	assert.Equal(t, []interface{}{json.Number("1588334400000000"), json.Number("3")}, decoded.SearchAfter)
+	assert.Equal(t, []string{"1"}, decoded.Tied)
+	assert.True(t, sameStartTime(cursor.SearchAfter, decoded.SearchAfter))
+
+	decoded, err = decodePageCursor("")
+	require.NoError(t, err)
+	assert.Equal(t, pageCursor{}, decoded)
+	for _, invalid := range []string{"%", encodePageCursor(pageCursor{})} {
+		_, err = decodePageCursor(invalid)
+		assert.Equal(t, spanstore.ErrInvalidPageCursor, err)
+	}
+}
+
+func TestFindTraceIDsInPointInTime(t *testing.T) {
+	query := elastic.NewBoolQuery()
+	t.Run("all spans read", func(t *testing.T) {
+		withPointInTimeSpanReader(func(r *spanReaderTest) {
+			openService := &mocks.PointInTimeOpenService{}
+			openService.On("IgnoreUnavailable", true).Return(openService)
+			openService.On("KeepAlive", time.Minute).Return(openService)
+			openService.On("Do", mock.Anything).Return("pit1", nil)
+			r.client.On("OpenPointInTime", "jaeger-span-2020-05-01").Return(openService)
+			mockPointInTimeSearchService(r, "pit1", pointInTimeHits("pit2",
+				pointInTimeHit("1", 300, 1), pointInTimeHit("1", 200, 2), pointInTimeHit("2", 100, 3)))
+
+			traceIDs, cursor, done, err := r.reader.findTraceIDsInPointInTime(context.Background(), []string{"jaeger-span-2020-05-01"}, query, 2, pageCursor{})
+			require.NoError(t, err)
+			assert.True(t, done)
+			assert.Equal(t, []string{"1", "2"}, traceIDs)
+			assert.Equal(t, "pit2", cursor.PIT)
+		})
+	})
+	t.Run("first page", func(t *testing.T) {
+		withPointInTimeSpanReader(func(r *spanReaderTest) {
+			mockPointInTimeSearchService(r, "pit1", pointInTimeHits("pit2",
+				pointInTimeHit("1", 300, 1), pointInTimeHit("2", 300, 2), pointInTimeHit("3", 100, 3)))
+
+			traceIDs, cursor, done, err := r.reader.findTraceIDsInPointInTime(context.Background(), nil, query, 2, pageCursor{PIT: "pit1"})
+			require.NoError(t, err)
+			assert.False(t, done)
+			assert.Equal(t, []string{"1", "2"}, traceIDs)
+			assert.Equal(t, pageCursor{PIT: "pit2", SearchAfter: []interface{}{float64(300), float64(2)}, Tied: []string{"1", "2"}}, cursor)
+			r.client.AssertNotCalled(t, "OpenPointInTime", mock.Anything)
+		})
+	})
+	t.Run("next page", func(t *testing.T) {
+		withPointInTimeSpanReader(func(r *spanReaderTest) {
+			searchService := mockPointInTimeSearchService(r, "pit1",
+				pointInTimeHits("pit1", pointInTimeHit("1", 300, 3), pointInTimeHit("4", 200, 4), pointInTimeHit("5", 100, 5)),
+				foundTraceIDs("pit1", "4"))
+
+			cursor := pageCursor{PIT: "pit1", SearchAfter: []interface{}{json.Number("300"), json.Number("2")}, Tied: []string{"1", "2"}}
+			traceIDs, cursor, done, err := r.reader.findTraceIDsInPointInTime(context.Background(), nil, query, 2, cursor)
+			require.NoError(t, err)
+			assert.True(t, done)
+			assert.Equal(t, []string{"5"}, traceIDs)
+			assert.Equal(t, []string{"5"}, cursor.Tied)
+			searchService.AssertCalled(t, "SearchAfter", json.Number("300"), json.Number("2"))
+			searchService.AssertCalled(t, "Size", 0)
+		})
+	})
+	t.Run("open error", func(t *testing.T) {
+		withPointInTimeSpanReader(func(r *spanReaderTest) {
+			openService := &mocks.PointInTimeOpenService{}
+			openService.On("IgnoreUnavailable", true).Return(openService)
+			openService.On("KeepAlive", time.Minute).Return(openService)
+			openService.On("Do", mock.Anything).Return("", errors.New("open-error"))
+			r.client.On("OpenPointInTime", mock.Anything).Return(openService)
+
+			_, _, _, err := r.reader.findTraceIDsInPointInTime(context.Background(), []string{"jaeger-span-2020-05-01"}, query, 2, pageCursor{})
+			assert.EqualError(t, err, "open-error")
+		})
+	})
+	t.Run("span without trace ID", func(t *testing.T) {
+		withPointInTimeSpanReader(func(r *spanReaderTest) {
+			mockPointInTimeSearchService(r, "pit1", pointInTimeHits("pit1", &elastic.SearchHit{}))
+
+			_, _, _, err := r.reader.findTraceIDsInPointInTime(context.Background(), nil, query, 2, pageCursor{PIT: "pit1"})
+			assert.EqualError(t, err, "span without trace ID in the point in time")
+		})
+	})
+}
+
+func TestFindTracesPage(t *testing.T) {
+	t.Run("not enabled", func(t *testing.T) {
+		withSpanReader(func(r *spanReaderTest) {
+			_, _, err := r.reader.FindTracesPage(context.Background(), asyncTraceQuery(), "")
+			assert.Equal(t, spanstore.ErrPagingNotSupported, err)
+		})
+	})
+	t.Run("invalid cursor", func(t *testing.T) {
+		withPointInTimeSpanReader(func(r *spanReaderTest) {
+			_, _, err := r.reader.FindTracesPage(context.Background(), asyncTraceQuery(), "%")
+			assert.Equal(t, spanstore.ErrInvalidPageCursor, err)
+		})
+	})
+	hits := []*elastic.SearchHit{{Source: (*json.RawMessage)(&exampleESSpan)}}
+	mockMultiSearch := func(r *spanReaderTest) {
+		multiSearchService := &mocks.MultiSearchService{}
+		multiSearchService.On("Add", mock.Anything).Return(multiSearchService)
+		multiSearchService.On("Index", "jaeger-span-2020-05-01").Return(multiSearchService)
+		multiSearchService.On("Do", mock.Anything).Return(&elastic.MultiSearchResult{
+			Responses: []*elastic.SearchResult{{Hits: &elastic.SearchHits{Hits: hits}}},
+		}, nil)
+		r.client.On("MultiSearch").Return(multiSearchService)
+	}
+	t.Run("more pages", func(t *testing.T) {
+		withPointInTimeSpanReader(func(r *spanReaderTest) {
+			mockPointInTimeSearchService(r, "pit1", pointInTimeHits("pit1", pointInTimeHit("1", 300, 1), pointInTimeHit("2", 200, 2)))
+			mockMultiSearch(r)
+			query := asyncTraceQuery()
+			query.NumTraces = 1
+
+			traces, next, err := r.reader.FindTracesPage(context.Background(), query, encodePageCursor(pageCursor{PIT: "pit1"}))
+			require.NoError(t, err)
+			require.Len(t, traces, 1)
+			assert.Equal(t, model.NewTraceID(0, 1), traces[0].Spans[0].TraceID)
+			cursor, err := decodePageCursor(next)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"1"}, cursor.Tied)
+			r.client.AssertNotCalled(t, "ClosePointInTime", mock.Anything)
+		})
+	})
+	t.Run("last page", func(t *testing.T) {
+		withPointInTimeSpanReader(func(r *spanReaderTest) {
+			mockPointInTimeSearchService(r, "pit1", pointInTimeHits("pit2", pointInTimeHit("1", 300, 1)))
+			closeService := &mocks.PointInTimeCloseService{}
+			closeService.On("Do", mock.Anything).Return(errors.New("close-error"))
+			r.client.On("ClosePointInTime", "pit2").Return(closeService)
+			mockMultiSearch(r)
+
+			traces, next, err := r.reader.FindTracesPage(context.Background(), asyncTraceQuery(), encodePageCursor(pageCursor{PIT: "pit1"}))
+			require.NoError(t, err)
+			assert.Len(t, traces, 1)
+			assert.Empty(t, next)
+			r.client.AssertCalled(t, "ClosePointInTime", "pit2")
+		})
+	})
+}
+
+func TestFindTraceIDsPointInTime(t *testing.T) {
+	withPointInTimeSpanReader(func(r *spanReaderTest) {
+		openService := &mocks.PointInTimeOpenService{}
+		openService.On("IgnoreUnavailable", true).Return(openService)
+		openService.On("KeepAlive", time.Minute).Return(openService)
+		openService.On("Do", mock.Anything).Return("pit1", nil)
+		r.client.On("OpenPointInTime", "jaeger-span-2020-05-01").Return(openService)
+		mockPointInTimeSearchService(r, "pit1", pointInTimeHits("pit2", pointInTimeHit("1", 300, 1), pointInTimeHit("2", 200, 2)))
+		closeService := &mocks.PointInTimeCloseService{}
+		closeService.On("Do", mock.Anything).Return(nil)
+		r.client.On("ClosePointInTime", "pit2").Return(closeService)
+
+		traceIDs, err := r.reader.FindTraceIDs(context.Background(), asyncTraceQuery())
+		require.NoError(t, err)
+		assert.Equal(t, []model.TraceID{model.NewTraceID(0, 1), model.NewTraceID(0, 2)}, traceIDs)
+		r.client.AssertCalled(t, "ClosePointInTime", "pit2")
+		r.client.AssertNotCalled(t, "Search", mock.Anything)
+	})
+}
+
+func TestSortTraces(t *testing.T) {
+	trace := func(id uint64) *model.Trace {
+		return &model.Trace{Spans: []*model.Span{{TraceID: model.NewTraceID(0, id)}}}
+	}
+	traces := sortTraces([]*model.Trace{trace(1), trace(2), {}}, []model.TraceID{model.NewTraceID(0, 2), model.NewTraceID(0, 3), model.NewTraceID(0, 1)})
+	require.Len(t, traces, 2)
+	assert.Equal(t, model.NewTraceID(0, 2), traces[0].Spans[0].TraceID)
+	assert.Equal(t, model.NewTraceID(0, 1), traces[1].Spans[0].TraceID)
+}
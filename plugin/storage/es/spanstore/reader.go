@@ -32,6 +32,7 @@ import (
 
 	"github.com/jaegertracing/jaeger/model"
 	"github.com/jaegertracing/jaeger/pkg/es"
+	"github.com/jaegertracing/jaeger/pkg/es/config"
 	"github.com/jaegertracing/jaeger/plugin/storage/es/spanstore/dbmodel"
 	"github.com/jaegertracing/jaeger/storage/spanstore"
 )
@@ -42,6 +43,7 @@ const (
 	archiveIndexSuffix      = "archive"
 	archiveReadIndexSuffix  = archiveIndexSuffix + "-read"
 	archiveWriteIndexSuffix = archiveIndexSuffix + "-write"
+	dataStreamSuffix        = "ds"
 	traceIDAggregation      = "traceIDs"
 	indexPrefixSeparator    = "-"
 
@@ -54,7 +56,10 @@ const (
 	objectProcessTagsField = "process.tag"
 	nestedTagsField        = "tags"
 	nestedProcessTagsField = "process.tags"
+	logsField              = "logs"
 	nestedLogFieldsField   = "logs.fields"
+	flatTagsField          = "flatTag"
+	flatProcessTagsField   = "process.flatTag"
 	tagKeyField            = "key"
 	tagValueField          = "value"
 
@@ -86,6 +91,8 @@ var (
 	objectTagFieldList = []string{objectTagsField, objectProcessTagsField}
 
 	nestedTagFieldList = []string{nestedTagsField, nestedProcessTagsField, nestedLogFieldsField}
+
+	flatTagFieldList = []string{flatTagsField, flatProcessTagsField}
 )
 
 // SpanReader can query for and load traces from ElasticSearch
@@ -101,6 +108,12 @@ type SpanReader struct {
 	spanConverter           dbmodel.ToDomain
 	timeRangeIndices        timeRangeIndexFn
 	sourceFn                sourceFn
+	asyncSearch             config.AsyncSearch
+	pointInTime             config.PointInTime
+	// nestedTagFields are the nested fields of the tags searched, those of the logs are not when they are not indexed
+	nestedTagFields []string
+	// flatTagFields are the flattened fields of the tags searched, none unless some tags are flattened
+	flatTagFields []string
 }
 
 // SpanReaderParams holds constructor params for NewSpanReader
@@ -114,6 +127,16 @@ type SpanReaderParams struct {
 	TagDotReplacement   string
 	Archive             bool
 	UseReadWriteAliases bool
+	// UseDataStreams reads the spans and services from data streams, the archive is not affected
+	UseDataStreams bool
+	// AsyncSearch searches the traces with the async search API when enabled
+	AsyncSearch config.AsyncSearch
+	// PointInTime finds the traces in points in time when enabled, rather than aggregating them
+	PointInTime config.PointInTime
+	// UnindexedFields are the span fields only kept in the source of the spans, which cannot be searched
+	UnindexedFields []string
+	// FlattenedTags searches the tags stored in flattened fields too
+	FlattenedTags bool
 }
 
 // NewSpanReader returns a new SpanReader with a metrics.
@@ -126,16 +149,36 @@ func NewSpanReader(p SpanReaderParams) *SpanReader {
 		spanIndexPrefix:         indexNames(p.IndexPrefix, spanIndex),
 		serviceIndexPrefix:      indexNames(p.IndexPrefix, serviceIndex),
 		spanConverter:           dbmodel.NewToDomain(p.TagDotReplacement),
-		timeRangeIndices:        getTimeRangeIndexFn(p.Archive, p.UseReadWriteAliases),
+		timeRangeIndices:        getTimeRangeIndexFn(p.Archive, p.UseReadWriteAliases, p.UseDataStreams),
 		sourceFn:                getSourceFn(p.Archive, p.MaxNumSpans),
+		asyncSearch:             p.AsyncSearch,
+		pointInTime:             p.PointInTime,
+		nestedTagFields:         getNestedTagFields(p.UnindexedFields),
+		flatTagFields:           getFlatTagFields(p.FlattenedTags),
+	}
+}
+
+func getFlatTagFields(flattenedTags bool) []string {
+	if flattenedTags {
+		return flatTagFieldList
 	}
+	return nil
+}
+
+func getNestedTagFields(unindexedFields []string) []string {
+	for _, field := range unindexedFields {
+		if field == logsField {
+			return []string{nestedTagsField, nestedProcessTagsField}
+		}
+	}
+	return nestedTagFieldList
 }
 
 type timeRangeIndexFn func(indexName string, startTime time.Time, endTime time.Time) []string
 
 type sourceFn func(query elastic.Query, nextTime uint64) *elastic.SearchSource
 
-func getTimeRangeIndexFn(archive, useReadWriteAliases bool) timeRangeIndexFn {
+func getTimeRangeIndexFn(archive, useReadWriteAliases, useDataStreams bool) timeRangeIndexFn {
 	if archive {
 		var archivePrefix string
 		if useReadWriteAliases {
@@ -147,6 +190,11 @@ func getTimeRangeIndexFn(archive, useReadWriteAliases bool) timeRangeIndexFn {
 			return []string{archiveIndex(indexName, archivePrefix)}
 		}
 	}
+	if useDataStreams {
+		return func(indices string, startTime time.Time, endTime time.Time) []string {
+			return []string{indices + dataStreamSuffix}
+		}
+	}
 	if useReadWriteAliases {
 		return func(indices string, startTime time.Time, endTime time.Time) []string {
 			return []string{indices + "read"}
@@ -286,11 +334,20 @@ func (s *SpanReader) FindTraces(ctx context.Context, traceQuery *spanstore.Trace
 	span, ctx := opentracing.StartSpanFromContext(ctx, "FindTraces")
 	defer span.Finish()
 
-	uniqueTraceIDs, err := s.FindTraceIDs(ctx, traceQuery)
+	uniqueTraceIDs, partial, err := s.findTraceIDModels(ctx, traceQuery)
+	if err != nil {
+		return nil, err
+	}
+	traces, err := s.multiRead(ctx, uniqueTraceIDs, traceQuery.StartTimeMin, traceQuery.StartTimeMax)
 	if err != nil {
 		return nil, err
 	}
-	return s.multiRead(ctx, uniqueTraceIDs, traceQuery.StartTimeMin, traceQuery.StartTimeMax)
+	if partial {
+		for _, trace := range traces {
+			trace.Warnings = append(trace.Warnings, partialSearchWarning)
+		}
+	}
+	return traces, nil
 }
 
 // FindTraceIDs retrieves traces IDs that match the traceQuery
@@ -298,19 +355,29 @@ func (s *SpanReader) FindTraceIDs(ctx context.Context, traceQuery *spanstore.Tra
 	span, ctx := opentracing.StartSpanFromContext(ctx, "FindTraceIDs")
 	defer span.Finish()
 
+	traceIDs, partial, err := s.findTraceIDModels(ctx, traceQuery)
+	if partial {
+		s.logger.Warn(partialSearchWarning)
+	}
+	return traceIDs, err
+}
+
+// findTraceIDModels retrieves the trace IDs that match the traceQuery, and whether they are partial
+func (s *SpanReader) findTraceIDModels(ctx context.Context, traceQuery *spanstore.TraceQueryParameters) ([]model.TraceID, bool, error) {
 	if err := validateQuery(traceQuery); err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	if traceQuery.NumTraces == 0 {
 		traceQuery.NumTraces = defaultNumTraces
 	}
 
-	esTraceIDs, err := s.findTraceIDs(ctx, traceQuery)
+	esTraceIDs, partial, err := s.findTraceIDs(ctx, traceQuery)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	return convertTraceIDsStringsToModels(esTraceIDs)
+	traceIDs, err := convertTraceIDsStringsToModels(esTraceIDs)
+	return traceIDs, partial, err
 }
 
 func (s *SpanReader) multiRead(ctx context.Context, traceIDs []model.TraceID, startTime, endTime time.Time) ([]*model.Trace, error) {
@@ -453,7 +520,7 @@ func validateQuery(p *spanstore.TraceQueryParameters) error {
 	return nil
 }
 
-func (s *SpanReader) findTraceIDs(ctx context.Context, traceQuery *spanstore.TraceQueryParameters) ([]string, error) {
+func (s *SpanReader) findTraceIDs(ctx context.Context, traceQuery *spanstore.TraceQueryParameters) ([]string, bool, error) {
 	childSpan, _ := opentracing.StartSpanFromContext(ctx, "findTraceIDs")
 	defer childSpan.Finish()
 	//  Below is the JSON body to our HTTP GET request to ElasticSearch. This function creates this.
@@ -513,26 +580,41 @@ func (s *SpanReader) findTraceIDs(ctx context.Context, traceQuery *spanstore.Tra
 	boolQuery := s.buildFindTraceIDsQuery(traceQuery)
 	jaegerIndices := s.timeRangeIndices(s.spanIndexPrefix, traceQuery.StartTimeMin, traceQuery.StartTimeMax)
 
-	searchService := s.client.Search(jaegerIndices...).
-		Size(0). // set to 0 because we don't want actual documents.
-		Aggregation(traceIDAggregation, aggregation).
-		IgnoreUnavailable(true).
-		Query(boolQuery)
+	if s.pointInTime.Enabled {
+		traceIDs, cursor, _, err := s.findTraceIDsInPointInTime(ctx, jaegerIndices, boolQuery, traceQuery.NumTraces, pageCursor{})
+		s.closePointInTime(ctx, cursor.PIT)
+		if err != nil {
+			return nil, false, fmt.Errorf("search services failed: %w", err)
+		}
+		return traceIDs, false, nil
+	}
 
-	searchResult, err := searchService.Do(ctx)
+	var searchResult *elastic.SearchResult
+	var partial bool
+	var err error
+	if s.asyncSearch.Enabled {
+		searchResult, partial, err = s.findTraceIDsAsync(ctx, jaegerIndices, boolQuery, aggregation)
+	} else {
+		searchResult, err = s.client.Search(jaegerIndices...).
+			Size(0). // set to 0 because we don't want actual documents.
+			Aggregation(traceIDAggregation, aggregation).
+			IgnoreUnavailable(true).
+			Query(boolQuery).
+			Do(ctx)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("search services failed: %w", err)
+		return nil, false, fmt.Errorf("search services failed: %w", err)
 	}
-	if searchResult.Aggregations == nil {
-		return []string{}, nil
+	if searchResult == nil || searchResult.Aggregations == nil {
+		return []string{}, partial, nil
 	}
 	bucket, found := searchResult.Aggregations.Terms(traceIDAggregation)
 	if !found {
-		return nil, ErrUnableToFindTraceIDAggregation
+		return nil, false, ErrUnableToFindTraceIDAggregation
 	}
 
-	traceIDBuckets := bucket.Buckets
-	return bucketToStringArray(traceIDBuckets)
+	traceIDs, err := bucketToStringArray(bucket.Buckets)
+	return traceIDs, partial, err
 }
 
 func (s *SpanReader) buildTraceIDAggregation(numOfTraces int) elastic.Aggregation {
@@ -577,6 +659,18 @@ func (s *SpanReader) buildFindTraceIDsQuery(traceQuery *spanstore.TraceQueryPara
 		tagQuery := s.buildTagQuery(k, v)
 		boolQuery.Must(tagQuery)
 	}
+
+	for _, f := range traceQuery.TagFilters {
+		tagFilterQuery := s.buildTagFilterQuery(f)
+		if tagFilterQuery == nil {
+			continue
+		}
+		if f.IsNegation() {
+			boolQuery.MustNot(tagFilterQuery)
+		} else {
+			boolQuery.Must(tagFilterQuery)
+		}
+	}
 	return boolQuery
 }
 
@@ -605,19 +699,68 @@ func (s *SpanReader) buildOperationNameQuery(operationName string) elastic.Query
 
 func (s *SpanReader) buildTagQuery(k string, v string) elastic.Query {
 	objectTagListLen := len(objectTagFieldList)
-	queries := make([]elastic.Query, len(nestedTagFieldList)+objectTagListLen)
+	queries := make([]elastic.Query, len(s.nestedTagFields)+objectTagListLen)
 	kd := s.spanConverter.ReplaceDot(k)
 	for i := range objectTagFieldList {
 		queries[i] = s.buildObjectQuery(objectTagFieldList[i], kd, v)
 	}
-	for i := range nestedTagFieldList {
-		queries[i+objectTagListLen] = s.buildNestedQuery(nestedTagFieldList[i], k, v)
+	for i := range s.nestedTagFields {
+		queries[i+objectTagListLen] = s.buildNestedQuery(s.nestedTagFields[i], k, v)
+	}
+	// the flattened fields only support the exact values
+	for _, field := range s.flatTagFields {
+		queries = append(queries, elastic.NewTermQuery(fmt.Sprintf("%s.%s", field, k), v))
 	}
 
 	// but configuration can change over time
 	return elastic.NewBoolQuery().Should(queries...)
 }
 
+// buildTagFilterQuery returns the query of the spans matching the filter, or of the spans not matching
+// its negation, or nil if it cannot be expressed on the tag values stored as strings, e.g. the numeric comparisons.
+func (s *SpanReader) buildTagFilterQuery(f spanstore.TagFilter) elastic.Query {
+	var valueQuery func(field string) elastic.Query
+	switch f.Operator {
+	case spanstore.TagExists, spanstore.TagNotExists:
+	case spanstore.TagEquals, spanstore.TagNotEquals:
+		valueQuery = func(field string) elastic.Query { return elastic.NewTermQuery(field, f.Value) }
+	case spanstore.TagRegex, spanstore.TagNotRegex:
+		valueQuery = func(field string) elastic.Query { return elastic.NewRegexpQuery(field, f.Value) }
+	case spanstore.TagWildcard:
+		valueQuery = func(field string) elastic.Query { return elastic.NewWildcardQuery(field, f.Value) }
+	default:
+		return nil
+	}
+	queries := make([]elastic.Query, 0, len(objectTagFieldList)+len(s.nestedTagFields))
+	kd := s.spanConverter.ReplaceDot(f.Key)
+	for _, field := range objectTagFieldList {
+		keyField := fmt.Sprintf("%s.%s", field, kd)
+		if valueQuery == nil {
+			queries = append(queries, elastic.NewExistsQuery(keyField))
+		} else {
+			queries = append(queries, valueQuery(keyField))
+		}
+	}
+	for _, field := range s.nestedTagFields {
+		tagBoolQuery := elastic.NewBoolQuery().Must(elastic.NewMatchQuery(fmt.Sprintf("%s.%s", field, tagKeyField), f.Key))
+		if valueQuery != nil {
+			tagBoolQuery.Must(valueQuery(fmt.Sprintf("%s.%s", field, tagValueField)))
+		}
+		queries = append(queries, elastic.NewNestedQuery(field, tagBoolQuery))
+	}
+	for _, field := range s.flatTagFields {
+		// the flattened fields support neither the regex nor the wildcard queries
+		keyField := fmt.Sprintf("%s.%s", field, f.Key)
+		switch f.Operator {
+		case spanstore.TagExists, spanstore.TagNotExists:
+			queries = append(queries, elastic.NewExistsQuery(keyField))
+		case spanstore.TagEquals, spanstore.TagNotEquals:
+			queries = append(queries, elastic.NewTermQuery(keyField, f.Value))
+		}
+	}
+	return elastic.NewBoolQuery().Should(queries...)
+}
+
 func (s *SpanReader) buildNestedQuery(field string, k string, v string) elastic.Query {
 	keyField := fmt.Sprintf("%s.%s", field, tagKeyField)
 	valueField := fmt.Sprintf("%s.%s", field, tagValueField)
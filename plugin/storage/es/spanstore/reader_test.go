@@ -170,6 +170,12 @@ func TestSpanReaderIndices(t *testing.T) {
 		{params: SpanReaderParams{Client: client, Logger: logger, MetricsFactory: metricsFactory,
 			IndexPrefix: "foo:", Archive: true, UseReadWriteAliases: true},
 			index: "foo:" + indexPrefixSeparator + spanIndex + archiveReadIndexSuffix},
+		{params: SpanReaderParams{Client: client, Logger: logger, MetricsFactory: metricsFactory,
+			IndexPrefix: "foo:", UseDataStreams: true},
+			index: "foo:-" + spanIndex + dataStreamSuffix},
+		{params: SpanReaderParams{Client: client, Logger: logger, MetricsFactory: metricsFactory,
+			IndexPrefix: "", Archive: true, UseDataStreams: true},
+			index: spanIndex + archiveIndexSuffix},
 	}
 	for _, testCase := range testCases {
 		r := NewSpanReader(testCase.params)
@@ -552,7 +558,8 @@ func returnSearchFunc(typ string, r *spanReaderTest) (interface{}, error) {
 			spanstore.OperationQueryParameters{ServiceName: "someService"},
 		)
 	} else if typ == traceIDAggregation {
-		return r.reader.findTraceIDs(context.Background(), &spanstore.TraceQueryParameters{})
+		traceIDs, _, err := r.reader.findTraceIDs(context.Background(), &spanstore.TraceQueryParameters{})
+		return traceIDs, err
 	}
 	return nil, errors.New("Specify services, operations, traceIDs only")
 }
@@ -944,6 +951,89 @@ func TestSpanReader_buildFindTraceIDsQuery(t *testing.T) {
 	})
 }
 
+func TestSpanReader_buildFindTraceIDsQueryTagFilters(t *testing.T) {
+	withSpanReader(func(r *spanReaderTest) {
+		var filters []spanstore.TagFilter
+		for _, expr := range []string{"error", "!debug", "http.url~*/api/*", "http.status_code>=500"} {
+			f, err := spanstore.ParseTagFilter(expr)
+			require.NoError(t, err)
+			filters = append(filters, f)
+		}
+		traceQuery := &spanstore.TraceQueryParameters{
+			ServiceName: "s",
+			TagFilters:  filters,
+		}
+
+		actual, err := r.reader.buildFindTraceIDsQuery(traceQuery).Source()
+		require.NoError(t, err)
+		// numeric comparisons cannot be pushed down and are left to the query service
+		expectedQuery := elastic.NewBoolQuery().
+			Must(
+				r.reader.buildStartTimeQuery(time.Time{}, time.Time{}),
+				r.reader.buildServiceNameQuery("s"),
+				r.reader.buildTagFilterQuery(filters[0]),
+				r.reader.buildTagFilterQuery(filters[2]),
+			).
+			MustNot(r.reader.buildTagFilterQuery(filters[1]))
+		expected, err := expectedQuery.Source()
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+}
+
+func TestSpanReader_buildTagFilterQuery(t *testing.T) {
+	testCases := []struct {
+		expr     string
+		expected string
+	}{
+		{
+			expr: "bat.foo",
+			expected: `{"bool":{"should":[
+				{"exists":{"field":"tag.bat@foo"}},
+				{"exists":{"field":"process.tag.bat@foo"}},
+				{"nested":{"path":"tags","query":{"bool":{"must":{"match":{"tags.key":{"query":"bat.foo"}}}}}}},
+				{"nested":{"path":"process.tags","query":{"bool":{"must":{"match":{"process.tags.key":{"query":"bat.foo"}}}}}}},
+				{"nested":{"path":"logs.fields","query":{"bool":{"must":{"match":{"logs.fields.key":{"query":"bat.foo"}}}}}}}]}}`,
+		},
+		{
+			expr: "bat.foo!=x",
+			expected: `{"bool":{"should":[
+				{"term":{"tag.bat@foo":"x"}},
+				{"term":{"process.tag.bat@foo":"x"}},
+				{"nested":{"path":"tags","query":{"bool":{"must":[{"match":{"tags.key":{"query":"bat.foo"}}},{"term":{"tags.value":"x"}}]}}}},
+				{"nested":{"path":"process.tags","query":{"bool":{"must":[{"match":{"process.tags.key":{"query":"bat.foo"}}},{"term":{"process.tags.value":"x"}}]}}}},
+				{"nested":{"path":"logs.fields","query":{"bool":{"must":[{"match":{"logs.fields.key":{"query":"bat.foo"}}},{"term":{"logs.fields.value":"x"}}]}}}}]}}`,
+		},
+		{
+			expr: "bat.foo~spo*",
+			expected: `{"bool":{"should":[
+				{"wildcard":{"tag.bat@foo":{"wildcard":"spo*"}}},
+				{"wildcard":{"process.tag.bat@foo":{"wildcard":"spo*"}}},
+				{"nested":{"path":"tags","query":{"bool":{"must":[{"match":{"tags.key":{"query":"bat.foo"}}},{"wildcard":{"tags.value":{"wildcard":"spo*"}}}]}}}},
+				{"nested":{"path":"process.tags","query":{"bool":{"must":[{"match":{"process.tags.key":{"query":"bat.foo"}}},{"wildcard":{"process.tags.value":{"wildcard":"spo*"}}}]}}}},
+				{"nested":{"path":"logs.fields","query":{"bool":{"must":[{"match":{"logs.fields.key":{"query":"bat.foo"}}},{"wildcard":{"logs.fields.value":{"wildcard":"spo*"}}}]}}}}]}}`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.expr, func(t *testing.T) {
+			withSpanReader(func(r *spanReaderTest) {
+				f, err := spanstore.ParseTagFilter(testCase.expr)
+				require.NoError(t, err)
+				actual, err := r.reader.buildTagFilterQuery(f).Source()
+				require.NoError(t, err)
+				actualJSON, err := json.Marshal(actual)
+				require.NoError(t, err)
+				assert.JSONEq(t, testCase.expected, string(actualJSON))
+			})
+		})
+	}
+	withSpanReader(func(r *spanReaderTest) {
+		f, err := spanstore.ParseTagFilter("bat.foo<3")
+		require.NoError(t, err)
+		assert.Nil(t, r.reader.buildTagFilterQuery(f))
+	})
+}
+
 func TestSpanReader_buildDurationQuery(t *testing.T) {
 	expectedStr :=
 		`{ "range":
@@ -1069,6 +1159,56 @@ func TestSpanReader_buildTagRegexEscapedQuery(t *testing.T) {
 	})
 }
 
+func TestSpanReader_buildTagQueryUnindexedLogs(t *testing.T) {
+	reader := NewSpanReader(SpanReaderParams{Logger: zap.NewNop(), UnindexedFields: []string{"references", "logs"}})
+	for _, query := range []elastic.Query{
+		reader.buildTagQuery("bat.foo", "spook"),
+		reader.buildTagFilterQuery(spanstore.TagFilter{Key: "bat.foo", Operator: spanstore.TagEquals, Value: "spook"}),
+	} {
+		actual, err := query.Source()
+		require.NoError(t, err)
+		data, err := json.Marshal(actual)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"path":"process.tags"`)
+		assert.NotContains(t, string(data), nestedLogFieldsField)
+	}
+}
+
+func TestSpanReader_buildTagQueryFlattened(t *testing.T) {
+	reader := NewSpanReader(SpanReaderParams{Logger: zap.NewNop(), TagDotReplacement: "@", FlattenedTags: true})
+	for _, test := range []struct {
+		query    elastic.Query
+		expected []string
+	}{
+		{
+			query:    reader.buildTagQuery("bat.foo", "spook"),
+			expected: []string{`{"term":{"flatTag.bat.foo":"spook"}}`, `{"term":{"process.flatTag.bat.foo":"spook"}}`},
+		},
+		{
+			query:    reader.buildTagFilterQuery(spanstore.TagFilter{Key: "bat.foo", Operator: spanstore.TagNotEquals, Value: "spook"}),
+			expected: []string{`{"term":{"flatTag.bat.foo":"spook"}}`, `{"term":{"process.flatTag.bat.foo":"spook"}}`},
+		},
+		{
+			query:    reader.buildTagFilterQuery(spanstore.TagFilter{Key: "bat.foo", Operator: spanstore.TagExists}),
+			expected: []string{`{"exists":{"field":"flatTag.bat.foo"}}`, `{"exists":{"field":"process.flatTag.bat.foo"}}`},
+		},
+	} {
+		actual, err := test.query.Source()
+		require.NoError(t, err)
+		data, err := json.Marshal(actual)
+		require.NoError(t, err)
+		for _, expected := range test.expected {
+			assert.Contains(t, string(data), expected)
+		}
+	}
+
+	query, err := reader.buildTagFilterQuery(spanstore.TagFilter{Key: "bat.foo", Operator: spanstore.TagRegex, Value: "sp.*"}).Source()
+	require.NoError(t, err)
+	data, err := json.Marshal(query)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), flatTagsField)
+}
+
 func TestSpanReader_GetEmptyIndex(t *testing.T) {
 	withSpanReader(func(r *spanReaderTest) {
 		mockSearchService(r).
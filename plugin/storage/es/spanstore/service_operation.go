@@ -70,9 +70,42 @@ func (s *ServiceOperationStorage) Write(indexName string, jsonSpan *dbmodel.Span
 		OperationName: jsonSpan.OperationName,
 	}
 
+	// the cache is keyed by index too, as the same service is written to the index of each tenant
 	cacheKey := hashCode(service)
-	if !keyInCache(cacheKey, s.serviceCache) {
+	if !keyInCache(indexName+cacheKey, s.serviceCache) {
 		s.client.Index().Index(indexName).Type(serviceType).Id(cacheKey).BodyJson(service).Add()
+		writeCache(indexName+cacheKey, s.serviceCache)
+	}
+}
+
+// WriteWithoutID saves a service to operation pair without a document ID, for the stores rejecting them
+// such as the time series collections of OpenSearch Serverless. The duplicates written after a restart
+// are merged when reading.
+func (s *ServiceOperationStorage) WriteWithoutID(indexName string, jsonSpan *dbmodel.Span) {
+	service := dbmodel.Service{
+		ServiceName:   jsonSpan.Process.ServiceName,
+		OperationName: jsonSpan.OperationName,
+	}
+
+	cacheKey := indexName + hashCode(service)
+	if !keyInCache(cacheKey, s.serviceCache) {
+		s.client.Index().Index(indexName).Type(serviceType).BodyJson(service).Add()
+		writeCache(cacheKey, s.serviceCache)
+	}
+}
+
+// WriteToDataStream saves a service to operation pair to a data stream. The documents of a data stream
+// are append-only, the duplicates written after a restart are merged when reading.
+func (s *ServiceOperationStorage) WriteToDataStream(dataStream string, jsonSpan *dbmodel.Span) {
+	service := dbmodel.Service{
+		ServiceName:   jsonSpan.Process.ServiceName,
+		OperationName: jsonSpan.OperationName,
+	}
+
+	cacheKey := dataStream + hashCode(service)
+	if !keyInCache(cacheKey, s.serviceCache) {
+		doc := &dataStreamService{Service: service, Timestamp: jsonSpan.StartTimeMillis}
+		s.client.Index().Index(dataStream).OpType(opTypeCreate).BodyJson(doc).Add()
 		writeCache(cacheKey, s.serviceCache)
 	}
 }
@@ -18,6 +18,7 @@ package spanstore
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/olivere/elastic"
 	"github.com/stretchr/testify/assert"
@@ -25,6 +26,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/jaegertracing/jaeger/pkg/es/mocks"
+	"github.com/jaegertracing/jaeger/pkg/testutils"
 	"github.com/jaegertracing/jaeger/plugin/storage/es/spanstore/dbmodel"
 	"github.com/jaegertracing/jaeger/storage/spanstore"
 )
@@ -61,9 +63,77 @@ func TestWriteService(t *testing.T) {
 		// test that cache works, will call the index service only once.
 		w.writer.writeService(indexName, jsonSpan)
 		indexService.AssertNumberOfCalls(t, "Add", 1)
+
+		// the service is written once to each index, e.g. of each tenant.
+		tenantIndexName := "acme-jaeger-1995-04-21"
+		indexService.On("Index", stringMatcher(tenantIndexName)).Return(indexService)
+		w.writer.writeService(tenantIndexName, jsonSpan)
+		indexService.AssertNumberOfCalls(t, "Add", 2)
 	})
 }
 
+func TestWriteServiceToDataStream(t *testing.T) {
+	client := &mocks.Client{}
+	logger, _ := testutils.NewLogger()
+	storage := NewServiceOperationStorage(client, logger, time.Hour)
+	indexService := &mocks.IndexService{}
+
+	dataStream := "jaeger-service-ds"
+	expected := &dataStreamService{Service: dbmodel.Service{ServiceName: "service", OperationName: "operation"}, Timestamp: 1000}
+	indexService.On("Index", stringMatcher(dataStream)).Return(indexService)
+	indexService.On("OpType", stringMatcher(opTypeCreate)).Return(indexService)
+	indexService.On("BodyJson", expected).Return(indexService)
+	indexService.On("Add")
+
+	client.On("Index").Return(indexService)
+
+	jsonSpan := &dbmodel.Span{
+		OperationName:   "operation",
+		StartTimeMillis: 1000,
+		Process: dbmodel.Process{
+			ServiceName: "service",
+		},
+	}
+
+	storage.WriteToDataStream(dataStream, jsonSpan)
+	indexService.AssertNumberOfCalls(t, "Add", 1)
+	indexService.AssertNotCalled(t, "Id", mock.Anything)
+
+	// test that cache works, will call the index service only once.
+	storage.WriteToDataStream(dataStream, jsonSpan)
+	indexService.AssertNumberOfCalls(t, "Add", 1)
+}
+
+func TestWriteServiceWithoutID(t *testing.T) {
+	client := &mocks.Client{}
+	logger, _ := testutils.NewLogger()
+	storage := NewServiceOperationStorage(client, logger, time.Hour)
+	indexService := &mocks.IndexService{}
+
+	indexName := "jaeger-service-1995-04-21"
+	indexService.On("Index", stringMatcher(indexName)).Return(indexService)
+	indexService.On("Type", stringMatcher(serviceType)).Return(indexService)
+	indexService.On("BodyJson", dbmodel.Service{ServiceName: "service", OperationName: "operation"}).Return(indexService)
+	indexService.On("Add")
+
+	client.On("Index").Return(indexService)
+
+	jsonSpan := &dbmodel.Span{
+		OperationName: "operation",
+		Process: dbmodel.Process{
+			ServiceName: "service",
+		},
+	}
+
+	storage.WriteWithoutID(indexName, jsonSpan)
+	indexService.AssertNumberOfCalls(t, "Add", 1)
+	indexService.AssertNotCalled(t, "Id", mock.Anything)
+
+	// test that cache works, will call the index service only once.
+	storage.WriteWithoutID(indexName, jsonSpan)
+	indexService.AssertNumberOfCalls(t, "Add", 1)
+}
+
 func TestWriteServiceError(t *testing.T) {
 	withSpanWriter(func(w *spanWriterTest) {
 		indexService := &mocks.IndexService{}
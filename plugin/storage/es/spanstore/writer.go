@@ -17,6 +17,7 @@ package spanstore
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"github.com/uber/jaeger-lib/metrics"
@@ -25,6 +26,7 @@ import (
 	"github.com/jaegertracing/jaeger/model"
 	"github.com/jaegertracing/jaeger/pkg/cache"
 	"github.com/jaegertracing/jaeger/pkg/es"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
 	"github.com/jaegertracing/jaeger/plugin/storage/es/spanstore/dbmodel"
 	storageMetrics "github.com/jaegertracing/jaeger/storage/spanstore/metrics"
 )
@@ -36,6 +38,8 @@ const (
 
 type spanWriterMetrics struct {
 	indexCreate *storageMetrics.WriteMetrics
+	// spanBytes counts the bytes of the span documents written, nil unless their size is measured
+	spanBytes metrics.Counter
 }
 
 type serviceWriter func(string, *dbmodel.Span)
@@ -50,6 +54,11 @@ type SpanWriter struct {
 	serviceWriter    serviceWriter
 	spanConverter    dbmodel.FromDomain
 	spanServiceIndex spanAndServiceIndexFn
+	dataStreams      bool
+	// tenantSpanServiceIndex returns the index names of the spans of a tenant, nil unless the indices are per tenant
+	tenantSpanServiceIndex func(tenant string) (spanAndServiceIndexFn, error)
+	// tenantDataStreams returns the patterns of the data streams of the tenants, nil unless the indices are per tenant
+	tenantDataStreams spanAndServiceIndexFn
 }
 
 // SpanWriterParams holds constructor parameters for NewSpanWriter
@@ -63,6 +72,18 @@ type SpanWriterParams struct {
 	TagDotReplacement   string
 	Archive             bool
 	UseReadWriteAliases bool
+	// UseDataStreams writes the spans and services to data streams, the archive is not affected
+	UseDataStreams bool
+	// Serverless writes the services without document IDs, which OpenSearch Serverless rejects
+	Serverless bool
+	// TenantIndexPrefix writes the spans of the tenants recorded by the collector to indices prefixed by
+	// their tenant, see TenantIndexPrefix. The archive is not affected.
+	TenantIndexPrefix bool
+	// MeasureSize counts the bytes of the span documents written in the span_bytes metric
+	MeasureSize bool
+	// TagStrategies store the tags with the strategies of their keys when its default is set,
+	// instead of AllTagsAsFields and TagKeysAsFields
+	TagStrategies dbmodel.TagStrategies
 }
 
 // NewSpanWriter creates a new SpanWriter for use
@@ -71,22 +92,54 @@ func NewSpanWriter(p SpanWriterParams) *SpanWriter {
 
 	// TODO: Configurable TTL
 	serviceOperationStorage := NewServiceOperationStorage(p.Client, p.Logger, time.Hour*12)
+	dataStreams := p.UseDataStreams && !p.Archive
+	serviceWriter := serviceOperationStorage.Write
+	if dataStreams {
+		serviceWriter = serviceOperationStorage.WriteToDataStream
+	} else if p.Serverless {
+		serviceWriter = serviceOperationStorage.WriteWithoutID
+	}
+	var tenantSpanServiceIndex func(tenant string) (spanAndServiceIndexFn, error)
+	var tenantDataStreams spanAndServiceIndexFn
+	if p.TenantIndexPrefix && !p.Archive {
+		tenantSpanServiceIndex = func(tenant string) (spanAndServiceIndexFn, error) {
+			indexPrefix, err := TenantIndexPrefix(p.IndexPrefix, tenant)
+			if err != nil {
+				return nil, err
+			}
+			return getSpanAndServiceIndexFn(p.Archive, p.UseReadWriteAliases, dataStreams, indexPrefix), nil
+		}
+		if dataStreams {
+			tenantDataStreams = getSpanAndServiceIndexFn(false, false, true, indexNames(p.IndexPrefix, "*"))
+		}
+	}
+	writerMetrics := spanWriterMetrics{
+		indexCreate: storageMetrics.NewWriteMetrics(p.MetricsFactory, "index_create"),
+	}
+	spanConverter := dbmodel.NewFromDomain(p.AllTagsAsFields, p.TagKeysAsFields, p.TagDotReplacement)
+	if p.TagStrategies.Default != "" {
+		spanConverter = dbmodel.NewFromDomainWithStrategies(p.TagStrategies, p.TagDotReplacement)
+	}
+	if p.MeasureSize {
+		writerMetrics.spanBytes = p.MetricsFactory.Counter(metrics.Options{Name: "span_bytes"})
+	}
 	return &SpanWriter{
-		ctx:    ctx,
-		client: p.Client,
-		logger: p.Logger,
-		writerMetrics: spanWriterMetrics{
-			indexCreate: storageMetrics.NewWriteMetrics(p.MetricsFactory, "index_create"),
-		},
-		serviceWriter: serviceOperationStorage.Write,
+		ctx:           ctx,
+		client:        p.Client,
+		logger:        p.Logger,
+		writerMetrics: writerMetrics,
+		serviceWriter: serviceWriter,
 		indexCache: cache.NewLRUWithOptions(
 			5,
 			&cache.Options{
 				TTL: 48 * time.Hour,
 			},
 		),
-		spanConverter:    dbmodel.NewFromDomain(p.AllTagsAsFields, p.TagKeysAsFields, p.TagDotReplacement),
-		spanServiceIndex: getSpanAndServiceIndexFn(p.Archive, p.UseReadWriteAliases, p.IndexPrefix),
+		spanConverter:          spanConverter,
+		spanServiceIndex:       getSpanAndServiceIndexFn(p.Archive, p.UseReadWriteAliases, dataStreams, p.IndexPrefix),
+		dataStreams:            dataStreams,
+		tenantSpanServiceIndex: tenantSpanServiceIndex,
+		tenantDataStreams:      tenantDataStreams,
 	}
 }
 
@@ -103,10 +156,40 @@ func (s *SpanWriter) CreateTemplates(spanTemplate, serviceTemplate string) error
 	return nil
 }
 
+// CreateDataStreams creates the composable index templates of the span and service data streams from
+// the legacy templates, then the data streams themselves so that they can be read before the first write.
+// The templates also match the data streams of the tenants, created on their first write.
+func (s *SpanWriter) CreateDataStreams(spanTemplate, serviceTemplate string) error {
+	spanDataStream, serviceDataStream := s.spanServiceIndex(time.Time{})
+	var spanPatterns, servicePatterns []string
+	if s.tenantDataStreams != nil {
+		spanPattern, servicePattern := s.tenantDataStreams(time.Time{})
+		spanPatterns, servicePatterns = []string{spanPattern}, []string{servicePattern}
+	}
+	if err := s.createDataStream(spanDataStream, spanTemplate, spanPatterns); err != nil {
+		return err
+	}
+	return s.createDataStream(serviceDataStream, serviceTemplate, servicePatterns)
+}
+
+func (s *SpanWriter) createDataStream(dataStream, template string, patterns []string) error {
+	body, err := dataStreamTemplate(template, dataStream, patterns...)
+	if err != nil {
+		return err
+	}
+	if _, err := s.client.CreateIndexTemplate(dataStream).Body(body).Do(context.Background()); err != nil {
+		return err
+	}
+	if _, err := s.client.CreateDataStream(dataStream).Do(context.Background()); err != nil && !es.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
 // spanAndServiceIndexFn returns names of span and service indices
 type spanAndServiceIndexFn func(spanTime time.Time) (string, string)
 
-func getSpanAndServiceIndexFn(archive, useReadWriteAliases bool, prefix string) spanAndServiceIndexFn {
+func getSpanAndServiceIndexFn(archive, useReadWriteAliases, useDataStreams bool, prefix string) spanAndServiceIndexFn {
 	if prefix != "" {
 		prefix += indexPrefixSeparator
 	}
@@ -121,6 +204,11 @@ func getSpanAndServiceIndexFn(archive, useReadWriteAliases bool, prefix string)
 		}
 	}
 
+	if useDataStreams {
+		return func(spanTime time.Time) (string, string) {
+			return spanIndexPrefix + dataStreamSuffix, serviceIndexPrefix + dataStreamSuffix
+		}
+	}
 	if useReadWriteAliases {
 		return func(spanTime time.Time) (string, string) {
 			return spanIndexPrefix + "write", serviceIndexPrefix + "write"
@@ -133,7 +221,16 @@ func getSpanAndServiceIndexFn(archive, useReadWriteAliases bool, prefix string)
 
 // WriteSpan writes a span and its corresponding service:operation in ElasticSearch
 func (s *SpanWriter) WriteSpan(span *model.Span) error {
-	spanIndexName, serviceIndexName := s.spanServiceIndex(span.StartTime)
+	spanServiceIndex := s.spanServiceIndex
+	if s.tenantSpanServiceIndex != nil {
+		if tenant := tenancy.GetSpanTenant(span); tenant != "" {
+			var err error
+			if spanServiceIndex, err = s.tenantSpanServiceIndex(tenant); err != nil {
+				return err
+			}
+		}
+	}
+	spanIndexName, serviceIndexName := spanServiceIndex(span.StartTime)
 	jsonSpan := s.spanConverter.FromDomainEmbedProcess(span)
 	if serviceIndexName != "" {
 		s.writeService(serviceIndexName, jsonSpan)
@@ -160,5 +257,25 @@ func (s *SpanWriter) writeService(indexName string, jsonSpan *dbmodel.Span) {
 }
 
 func (s *SpanWriter) writeSpan(indexName string, jsonSpan *dbmodel.Span) {
-	s.client.Index().Index(indexName).Type(spanType).BodyJson(&jsonSpan).Add()
+	if s.dataStreams {
+		doc := &dataStreamSpan{Span: jsonSpan, Timestamp: jsonSpan.StartTimeMillis}
+		s.client.Index().Index(indexName).OpType(opTypeCreate).BodyJson(s.measureSize(doc)).Add()
+		return
+	}
+	s.client.Index().Index(indexName).Type(spanType).BodyJson(s.measureSize(&jsonSpan)).Add()
+}
+
+// measureSize counts the bytes of the span document when its size is measured, and returns it serialized
+// so that the bulk processor does not serialize it again.
+func (s *SpanWriter) measureSize(doc interface{}) interface{} {
+	if s.writerMetrics.spanBytes == nil {
+		return doc
+	}
+	body, err := json.Marshal(doc)
+	if err != nil {
+		// the bulk processor fails to serialize it too and reports the error
+		return doc
+	}
+	s.writerMetrics.spanBytes.Inc(int64(len(body)))
+	return json.RawMessage(body)
 }
@@ -17,11 +17,13 @@ package spanstore
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/olivere/elastic"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -30,6 +32,7 @@ import (
 
 	"github.com/jaegertracing/jaeger/model"
 	"github.com/jaegertracing/jaeger/pkg/es/mocks"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
 	"github.com/jaegertracing/jaeger/pkg/testutils"
 	"github.com/jaegertracing/jaeger/plugin/storage/es/spanstore/dbmodel"
 	"github.com/jaegertracing/jaeger/storage/spanstore"
@@ -88,6 +91,12 @@ func TestSpanWriterIndices(t *testing.T) {
 		{params: SpanWriterParams{Client: client, Logger: logger, MetricsFactory: metricsFactory,
 			IndexPrefix: "foo:", Archive: true, UseReadWriteAliases: true},
 			indices: []string{"foo:" + indexPrefixSeparator + spanIndex + archiveWriteIndexSuffix, ""}},
+		{params: SpanWriterParams{Client: client, Logger: logger, MetricsFactory: metricsFactory,
+			IndexPrefix: "foo:", UseDataStreams: true},
+			indices: []string{"foo:-" + spanIndex + dataStreamSuffix, "foo:-" + serviceIndex + dataStreamSuffix}},
+		{params: SpanWriterParams{Client: client, Logger: logger, MetricsFactory: metricsFactory,
+			IndexPrefix: "", Archive: true, UseDataStreams: true},
+			indices: []string{spanIndex + archiveIndexSuffix, ""}},
 	}
 	for _, testCase := range testCases {
 		w := NewSpanWriter(testCase.params)
@@ -248,6 +257,207 @@ func TestCreateTemplates(t *testing.T) {
 	}
 }
 
+func TestCreateDataStreams(t *testing.T) {
+	legacyTemplate := `{"index_patterns": "*jaeger-span-*", "settings": {"index.number_of_shards": 5}, "mappings": {"properties": {"traceID": {"type": "keyword"}}}}`
+	tests := []struct {
+		name      string
+		template  string
+		dataErr   error
+		expectErr string
+	}{
+		{name: "created", template: legacyTemplate},
+		{name: "already exists", template: legacyTemplate, dataErr: &elastic.Error{Status: 400, Details: &elastic.ErrorDetails{Type: "resource_already_exists_exception"}}},
+		{name: "data stream error", template: legacyTemplate, dataErr: errors.New("data-stream-error"), expectErr: "data-stream-error"},
+		{name: "invalid template", template: "{", expectErr: "invalid index template of jaeger-s"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := &mocks.Client{}
+			logger, _ := testutils.NewLogger()
+			writer := NewSpanWriter(SpanWriterParams{Client: client, Logger: logger, MetricsFactory: metricstest.NewFactory(0), UseDataStreams: true})
+			var bodies []string
+			tService := &mocks.IndexTemplateCreateService{}
+			tService.On("Body", mock.Anything).Run(func(args mock.Arguments) {
+				bodies = append(bodies, args.String(0))
+			}).Return(tService)
+			tService.On("Do", context.Background()).Return(nil, nil)
+			dService := &mocks.DataStreamCreateService{}
+			dService.On("Do", context.Background()).Return(nil, test.dataErr)
+			client.On("CreateIndexTemplate", mock.Anything).Return(tService)
+			client.On("CreateDataStream", mock.Anything).Return(dService)
+
+			err := writer.CreateDataStreams(test.template, test.template)
+			if test.expectErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			client.AssertCalled(t, "CreateIndexTemplate", "jaeger-span-ds")
+			client.AssertCalled(t, "CreateIndexTemplate", "jaeger-service-ds")
+			client.AssertCalled(t, "CreateDataStream", "jaeger-span-ds")
+			client.AssertCalled(t, "CreateDataStream", "jaeger-service-ds")
+			require.Len(t, bodies, 2)
+			for _, body := range bodies {
+				assert.Contains(t, body, `"data_stream":{}`)
+				assert.Contains(t, body, `"@timestamp":{"type":"date"}`)
+				assert.Contains(t, body, `"index.number_of_shards":5`)
+				assert.Contains(t, body, `"priority":101`)
+			}
+		})
+	}
+}
+
+func TestCreateTenantDataStreams(t *testing.T) {
+	client := &mocks.Client{}
+	logger, _ := testutils.NewLogger()
+	writer := NewSpanWriter(SpanWriterParams{
+		Client:            client,
+		Logger:            logger,
+		MetricsFactory:    metricstest.NewFactory(0),
+		IndexPrefix:       "prod",
+		UseDataStreams:    true,
+		TenantIndexPrefix: true,
+	})
+	tService := &mocks.IndexTemplateCreateService{}
+	tService.On("Body", mock.Anything).Return(tService)
+	tService.On("Do", context.Background()).Return(nil, nil)
+	dService := &mocks.DataStreamCreateService{}
+	dService.On("Do", context.Background()).Return(nil, nil)
+	client.On("CreateIndexTemplate", mock.Anything).Return(tService)
+	client.On("CreateDataStream", mock.Anything).Return(dService)
+
+	require.NoError(t, writer.CreateDataStreams("{}", "{}"))
+	tService.AssertCalled(t, "Body", mock.MatchedBy(func(body string) bool {
+		return strings.Contains(body, `"index_patterns":["prod-jaeger-span-ds","prod-*-jaeger-span-ds"]`)
+	}))
+	tService.AssertCalled(t, "Body", mock.MatchedBy(func(body string) bool {
+		return strings.Contains(body, `"index_patterns":["prod-jaeger-service-ds","prod-*-jaeger-service-ds"]`)
+	}))
+	client.AssertNumberOfCalls(t, "CreateDataStream", 2)
+}
+
+func TestWriteTenantSpan(t *testing.T) {
+	date, err := time.Parse(time.RFC3339, "1995-04-21T22:08:41+00:00")
+	require.NoError(t, err)
+	tenantSpan := &model.Span{
+		OperationName: "operation",
+		Process:       &model.Process{ServiceName: "service"},
+		StartTime:     date,
+	}
+	tenancy.SetSpanTenant(tenantSpan, "Acme Corp")
+	tests := []struct {
+		name         string
+		params       SpanWriterParams
+		span         *model.Span
+		spanIndex    string
+		serviceIndex string
+	}{
+		{
+			name:         "tenant",
+			params:       SpanWriterParams{IndexPrefix: "prod", TenantIndexPrefix: true},
+			span:         tenantSpan,
+			spanIndex:    "prod-_41cme_20_43orp-jaeger-span-1995-04-21",
+			serviceIndex: "prod-_41cme_20_43orp-jaeger-service-1995-04-21",
+		},
+		{
+			name:         "tenant with aliases",
+			params:       SpanWriterParams{IndexPrefix: "prod", TenantIndexPrefix: true, UseReadWriteAliases: true},
+			span:         tenantSpan,
+			spanIndex:    "prod-_41cme_20_43orp-jaeger-span-write",
+			serviceIndex: "prod-_41cme_20_43orp-jaeger-service-write",
+		},
+		{
+			name:         "no tenant",
+			params:       SpanWriterParams{IndexPrefix: "prod", TenantIndexPrefix: true},
+			span:         &model.Span{Process: &model.Process{ServiceName: "service"}, StartTime: date},
+			spanIndex:    "prod-jaeger-span-1995-04-21",
+			serviceIndex: "prod-jaeger-service-1995-04-21",
+		},
+		{
+			name:         "disabled",
+			params:       SpanWriterParams{IndexPrefix: "prod"},
+			span:         tenantSpan,
+			spanIndex:    "prod-jaeger-span-1995-04-21",
+			serviceIndex: "prod-jaeger-service-1995-04-21",
+		},
+		{
+			name:      "archive",
+			params:    SpanWriterParams{IndexPrefix: "prod", TenantIndexPrefix: true, Archive: true},
+			span:      tenantSpan,
+			spanIndex: "prod-jaeger-span-archive",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := &mocks.Client{}
+			indexService := &mocks.IndexService{}
+			indexService.On("Index", mock.Anything).Return(indexService)
+			indexService.On("Type", mock.Anything).Return(indexService)
+			indexService.On("Id", mock.Anything).Return(indexService)
+			indexService.On("BodyJson", mock.Anything).Return(indexService)
+			indexService.On("Add")
+			client.On("Index").Return(indexService)
+			test.params.Client = client
+			test.params.Logger = zap.NewNop()
+			test.params.MetricsFactory = metricstest.NewFactory(0)
+
+			require.NoError(t, NewSpanWriter(test.params).WriteSpan(test.span))
+			indexService.AssertCalled(t, "Index", test.spanIndex)
+			if test.serviceIndex != "" {
+				indexService.AssertCalled(t, "Index", test.serviceIndex)
+			}
+		})
+	}
+}
+
+func TestWriteTenantSpanError(t *testing.T) {
+	tenantSpan := &model.Span{Process: &model.Process{ServiceName: "service"}}
+	tenancy.SetSpanTenant(tenantSpan, "_acme")
+	writer := NewSpanWriter(SpanWriterParams{
+		Client:            &mocks.Client{},
+		Logger:            zap.NewNop(),
+		MetricsFactory:    metricstest.NewFactory(0),
+		TenantIndexPrefix: true,
+	})
+	assert.Error(t, writer.WriteSpan(tenantSpan))
+}
+
+func TestTenantIndexPrefix(t *testing.T) {
+	testCases := []struct {
+		indexPrefix string
+		tenant      string
+		expected    string
+	}{
+		{tenant: "acme", expected: "acme"},
+		{indexPrefix: "prod", tenant: "acme", expected: "acme"},
+		{indexPrefix: "prod", tenant: "Acme Corp/EU*", expected: "_41cme_20_43orp_2f_45_55_2a"},
+		{tenant: "team-a.b_c", expected: "team_2da_2eb_5fc"},
+		{indexPrefix: "prod", tenant: "équipe", expected: "_c3_a9quipe"},
+		// the tenants differing only by their case or their special characters do not share their indices
+		{indexPrefix: "prod", tenant: "Acme", expected: "_41cme"},
+		{tenant: "a/b", expected: "a_2fb"},
+		{tenant: "a_b", expected: "a_5fb"},
+		{tenant: "a_2fb", expected: "a_5f2fb"},
+	}
+	for _, tc := range testCases {
+		indexPrefix, err := TenantIndexPrefix(tc.indexPrefix, tc.tenant)
+		require.NoError(t, err, tc.tenant)
+		if tc.indexPrefix != "" {
+			tc.expected = tc.indexPrefix + "-" + tc.expected
+		}
+		assert.Equal(t, tc.expected, indexPrefix, tc.tenant)
+	}
+
+	// the index names cannot start with an underscore
+	for _, tenant := range []string{"", "Acme", "_acme", "-acme", ".acme"} {
+		_, err := TenantIndexPrefix("", tenant)
+		assert.Error(t, err, tenant)
+	}
+	_, err := TenantIndexPrefix("prod", "")
+	assert.Error(t, err)
+}
+
 func TestSpanIndexName(t *testing.T) {
 	date, err := time.Parse(time.RFC3339, "1995-04-21T22:08:41+00:00")
 	require.NoError(t, err)
@@ -280,6 +490,26 @@ func TestWriteSpanInternal(t *testing.T) {
 	})
 }
 
+func TestWriteSpanInternalDataStream(t *testing.T) {
+	client := &mocks.Client{}
+	logger, logBuffer := testutils.NewLogger()
+	writer := NewSpanWriter(SpanWriterParams{Client: client, Logger: logger, MetricsFactory: metricstest.NewFactory(0), UseDataStreams: true})
+	indexService := &mocks.IndexService{}
+
+	indexName := "jaeger-span-ds"
+	indexService.On("Index", stringMatcher(indexName)).Return(indexService)
+	indexService.On("OpType", stringMatcher(opTypeCreate)).Return(indexService)
+	indexService.On("BodyJson", &dataStreamSpan{Span: &dbmodel.Span{StartTimeMillis: 1000}, Timestamp: 1000}).Return(indexService)
+	indexService.On("Add")
+
+	client.On("Index").Return(indexService)
+
+	writer.writeSpan(indexName, &dbmodel.Span{StartTimeMillis: 1000})
+	indexService.AssertNumberOfCalls(t, "Add", 1)
+	indexService.AssertNotCalled(t, "Type", mock.Anything)
+	assert.Equal(t, "", logBuffer.String())
+}
+
 func TestWriteSpanInternalError(t *testing.T) {
 	withSpanWriter(func(w *spanWriterTest) {
 		indexService := &mocks.IndexService{}
@@ -362,3 +592,91 @@ func stringMatcher(q string) interface{} {
 	}
 	return mock.MatchedBy(matchFunc)
 }
+
+func TestCreateComposableTemplates(t *testing.T) {
+	legacyTemplate := `{"index_patterns": "*jaeger-span-*", "settings": {"index.number_of_shards": 5}, "mappings": {"properties": {"traceID": {"type": "keyword"}}}}`
+	tests := []struct {
+		name      string
+		template  string
+		deleteErr error
+		expectErr string
+	}{
+		{name: "created", template: legacyTemplate},
+		{name: "no legacy template", template: legacyTemplate, deleteErr: &elastic.Error{Status: 404}},
+		{name: "delete error", template: legacyTemplate, deleteErr: errors.New("delete-error"), expectErr: "delete-error"},
+		{name: "invalid template", template: "{", expectErr: "invalid index template of jaeger-span"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := &mocks.Client{}
+			logger, _ := testutils.NewLogger()
+			writer := NewSpanWriter(SpanWriterParams{Client: client, Logger: logger, MetricsFactory: metricstest.NewFactory(0)})
+			cService := &mocks.ComponentTemplateCreateService{}
+			cService.On("Body", mock.Anything).Return(cService)
+			cService.On("Do", context.Background()).Return(nil, nil)
+			tService := &mocks.IndexTemplateCreateService{}
+			tService.On("Body", mock.Anything).Return(tService)
+			tService.On("Do", context.Background()).Return(nil, nil)
+			dService := &mocks.TemplateDeleteService{}
+			dService.On("Do", context.Background()).Return(nil, test.deleteErr)
+			client.On("CreateComponentTemplate", mock.Anything).Return(cService)
+			client.On("CreateIndexTemplate", mock.Anything).Return(tService)
+			client.On("DeleteTemplate", mock.Anything).Return(dService)
+
+			err := writer.CreateComposableTemplates(test.template, test.template)
+			if test.expectErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			client.AssertCalled(t, "CreateComponentTemplate", "jaeger-span-settings")
+			client.AssertCalled(t, "CreateComponentTemplate", "jaeger-service-mappings")
+			cService.AssertCalled(t, "Body", `{"template":{"settings":{"index.number_of_shards":5}}}`)
+			cService.AssertCalled(t, "Body", `{"template":{"mappings":{"properties":{"traceID":{"type":"keyword"}}}}}`)
+			client.AssertCalled(t, "CreateIndexTemplate", "jaeger-span")
+			tService.AssertCalled(t, "Body", `{"composed_of":["jaeger-service-settings","jaeger-service-mappings"],`+
+				`"index_patterns":["*jaeger-service-*"],"priority":100}`)
+			client.AssertCalled(t, "DeleteTemplate", "jaeger-span")
+			client.AssertCalled(t, "DeleteTemplate", "jaeger-service")
+		})
+	}
+}
+
+func TestSpanWriterMeasureSize(t *testing.T) {
+	for _, dataStreams := range []bool{false, true} {
+		client := &mocks.Client{}
+		indexService := &mocks.IndexService{}
+		indexService.On("Index", mock.Anything).Return(indexService)
+		indexService.On("Type", mock.Anything).Return(indexService)
+		indexService.On("OpType", mock.Anything).Return(indexService)
+		indexService.On("Id", mock.Anything).Return(indexService)
+		indexService.On("BodyJson", mock.Anything).Return(indexService)
+		indexService.On("Add")
+		client.On("Index").Return(indexService)
+		metricsFactory := metricstest.NewFactory(0)
+		writer := NewSpanWriter(SpanWriterParams{
+			Client:         client,
+			Logger:         zap.NewNop(),
+			MetricsFactory: metricsFactory,
+			UseDataStreams: dataStreams,
+			MeasureSize:    true,
+		})
+
+		span := &model.Span{TraceID: model.NewTraceID(0, 1), Process: &model.Process{ServiceName: "service"}}
+		require.NoError(t, writer.WriteSpan(span))
+
+		var body json.RawMessage
+		for _, call := range indexService.Calls {
+			if call.Method != "BodyJson" {
+				continue
+			}
+			if doc, ok := call.Arguments.Get(0).(json.RawMessage); ok {
+				body = doc
+			}
+		}
+		require.NotNil(t, body, "the span document is serialized")
+		assert.Contains(t, string(body), `"traceID":"0000000000000001"`)
+		metricsFactory.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "span_bytes", Value: len(body)})
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package es
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jaegertracing/jaeger/pkg/es/config"
+	"github.com/jaegertracing/jaeger/plugin/storage/es/spanstore/dbmodel"
+)
+
+func parseTagStrategy(strategy string) (dbmodel.TagStrategy, bool) {
+	switch s := dbmodel.TagStrategy(strategy); s {
+	case dbmodel.NestedTags, dbmodel.ObjectTags, dbmodel.FlattenedTags:
+		return s, true
+	}
+	return "", false
+}
+
+// getTagStrategies returns the strategies storing the tags, from the default strategy and the tags file.
+// The keys of the file are stored as object fields unless they are followed by their strategy, e.g. http.url=flattened.
+func getTagStrategies(cfg config.ClientBuilder) (dbmodel.TagStrategies, error) {
+	strategies := dbmodel.TagStrategies{Default: dbmodel.NestedTags, Keys: map[string]dbmodel.TagStrategy{}}
+	if cfg.GetAllTagsAsFields() {
+		strategies.Default = dbmodel.ObjectTags
+	}
+	if cfg.GetTagDefaultStrategy() != "" {
+		strategy, ok := parseTagStrategy(cfg.GetTagDefaultStrategy())
+		if !ok {
+			return strategies, fmt.Errorf("invalid tag strategy %q, expected nested, object or flattened", cfg.GetTagDefaultStrategy())
+		}
+		strategies.Default = strategy
+	}
+	if cfg.GetTagsFilePath() == "" {
+		return strategies, nil
+	}
+	lines, err := loadTagsFromFile(cfg.GetTagsFilePath())
+	if err != nil {
+		return strategies, err
+	}
+	for _, line := range lines {
+		key, strategy := line, dbmodel.ObjectTags
+		// the keys can contain equal signs, only a known strategy is split off
+		if i := strings.LastIndex(line, "="); i >= 0 {
+			if s, ok := parseTagStrategy(strings.TrimSpace(line[i+1:])); ok {
+				key, strategy = strings.TrimSpace(line[:i]), s
+			}
+		}
+		strategies.Keys[key] = strategy
+	}
+	return strategies, nil
+}
+
+// applyTagStrategies adds the flattened fields of the span and process tags to the span template
+// when some tags are flattened.
+func applyTagStrategies(spanTemplate string, strategies dbmodel.TagStrategies, esVersion uint) (string, error) {
+	if !strategies.Uses(dbmodel.FlattenedTags) {
+		return spanTemplate, nil
+	}
+	if esVersion < 7 {
+		return "", fmt.Errorf("flattened tags require Elasticsearch 7.3 or later")
+	}
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(spanTemplate), &parsed); err != nil {
+		return "", fmt.Errorf("invalid index template: %w", err)
+	}
+	mappings, _ := parsed["mappings"].(map[string]interface{})
+	properties, _ := mappings["properties"].(map[string]interface{})
+	process, _ := properties["process"].(map[string]interface{})
+	processProperties, _ := process["properties"].(map[string]interface{})
+	if processProperties == nil {
+		return "", fmt.Errorf("invalid index template: no span mappings")
+	}
+	for _, props := range []map[string]interface{}{properties, processProperties} {
+		props["flatTag"] = map[string]interface{}{"type": "flattened", "ignore_above": 256}
+	}
+	body, err := json.Marshal(parsed)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
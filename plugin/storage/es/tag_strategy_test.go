@@ -0,0 +1,129 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package es
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	escfg "github.com/jaegertracing/jaeger/pkg/es/config"
+	"github.com/jaegertracing/jaeger/plugin/storage/es/spanstore/dbmodel"
+)
+
+func TestGetTagStrategies(t *testing.T) {
+	testCases := []struct {
+		name     string
+		tags     escfg.TagsAsFields
+		expected dbmodel.TagStrategies
+		err      string
+	}{
+		{
+			name:     "nested by default",
+			expected: dbmodel.TagStrategies{Default: dbmodel.NestedTags, Keys: map[string]dbmodel.TagStrategy{}},
+		},
+		{
+			name:     "all as fields",
+			tags:     escfg.TagsAsFields{AllAsFields: true},
+			expected: dbmodel.TagStrategies{Default: dbmodel.ObjectTags, Keys: map[string]dbmodel.TagStrategy{}},
+		},
+		{
+			name: "strategies file",
+			tags: escfg.TagsAsFields{DefaultStrategy: "flattened", File: "fixtures/tags_03.txt"},
+			expected: dbmodel.TagStrategies{Default: dbmodel.FlattenedTags, Keys: map[string]dbmodel.TagStrategy{
+				"foo":      dbmodel.ObjectTags,
+				"http.url": dbmodel.FlattenedTags,
+				"error":    dbmodel.NestedTags,
+				"a=b":      dbmodel.ObjectTags,
+			}},
+		},
+		{
+			name: "invalid strategy",
+			tags: escfg.TagsAsFields{DefaultStrategy: "keyword"},
+			err:  `invalid tag strategy "keyword", expected nested, object or flattened`,
+		},
+		{
+			name: "missing file",
+			tags: escfg.TagsAsFields{File: "fixtures/tags_foo.txt"},
+			err:  "open fixtures/tags_foo.txt: no such file or directory",
+		},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			strategies, err := getTagStrategies(&mockClientBuilder{Configuration: escfg.Configuration{Tags: test.tags}})
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, strategies)
+		})
+	}
+}
+
+func TestApplyTagStrategies(t *testing.T) {
+	spanMapping, _ := GetSpanServiceMappings(5, 1, 7)
+	nested := dbmodel.TagStrategies{Default: dbmodel.NestedTags, Keys: map[string]dbmodel.TagStrategy{"foo": dbmodel.ObjectTags}}
+	template, err := applyTagStrategies(spanMapping, nested, 7)
+	require.NoError(t, err)
+	assert.Equal(t, spanMapping, template)
+
+	flattened := dbmodel.TagStrategies{Default: dbmodel.NestedTags, Keys: map[string]dbmodel.TagStrategy{"foo": dbmodel.FlattenedTags}}
+	template, err = applyTagStrategies(spanMapping, flattened, 7)
+	require.NoError(t, err)
+	var parsed struct {
+		Mappings struct {
+			Properties struct {
+				FlatTag map[string]interface{} `json:"flatTag"`
+				Process struct {
+					Properties struct {
+						FlatTag map[string]interface{} `json:"flatTag"`
+					} `json:"properties"`
+				} `json:"process"`
+			} `json:"properties"`
+		} `json:"mappings"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(template), &parsed))
+	expected := map[string]interface{}{"type": "flattened", "ignore_above": float64(256)}
+	assert.Equal(t, expected, parsed.Mappings.Properties.FlatTag)
+	assert.Equal(t, expected, parsed.Mappings.Properties.Process.Properties.FlatTag)
+
+	_, err = applyTagStrategies(spanMapping, flattened, 6)
+	assert.EqualError(t, err, "flattened tags require Elasticsearch 7.3 or later")
+	_, err = applyTagStrategies("{", flattened, 7)
+	assert.EqualError(t, err, "invalid index template: unexpected end of JSON input")
+	_, err = applyTagStrategies("{}", flattened, 7)
+	assert.EqualError(t, err, "invalid index template: no span mappings")
+}
+
+func TestCreateFlattenedTags(t *testing.T) {
+	f := NewFactory()
+	f.primaryConfig = &mockClientBuilder{version: 7, Configuration: escfg.Configuration{Tags: escfg.TagsAsFields{DefaultStrategy: "flattened"}}}
+	f.archiveConfig = &mockClientBuilder{}
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	_, err := f.CreateSpanReader()
+	require.NoError(t, err)
+	_, err = f.CreateSpanWriter()
+	require.NoError(t, err)
+
+	f.primaryConfig = &mockClientBuilder{Configuration: escfg.Configuration{Tags: escfg.TagsAsFields{DefaultStrategy: "flattened"}}}
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	_, err = f.CreateSpanWriter()
+	assert.EqualError(t, err, "flattened tags require Elasticsearch 7.3 or later")
+}
@@ -25,11 +25,20 @@ import (
 
 	"github.com/jaegertracing/jaeger/plugin"
 	"github.com/jaegertracing/jaeger/plugin/storage/badger"
+	"github.com/jaegertracing/jaeger/plugin/storage/bigtable"
 	"github.com/jaegertracing/jaeger/plugin/storage/cassandra"
+	"github.com/jaegertracing/jaeger/plugin/storage/clickhouse"
+	"github.com/jaegertracing/jaeger/plugin/storage/cosmosdb"
+	"github.com/jaegertracing/jaeger/plugin/storage/dynamodb"
 	"github.com/jaegertracing/jaeger/plugin/storage/es"
 	"github.com/jaegertracing/jaeger/plugin/storage/grpc"
 	"github.com/jaegertracing/jaeger/plugin/storage/kafka"
 	"github.com/jaegertracing/jaeger/plugin/storage/memory"
+	"github.com/jaegertracing/jaeger/plugin/storage/mongodb"
+	"github.com/jaegertracing/jaeger/plugin/storage/parquet"
+	"github.com/jaegertracing/jaeger/plugin/storage/postgres"
+	"github.com/jaegertracing/jaeger/plugin/storage/redis"
+	"github.com/jaegertracing/jaeger/plugin/storage/tiered"
 	"github.com/jaegertracing/jaeger/storage"
 	"github.com/jaegertracing/jaeger/storage/dependencystore"
 	"github.com/jaegertracing/jaeger/storage/spanstore"
@@ -42,8 +51,21 @@ const (
 	kafkaStorageType         = "kafka"
 	grpcPluginStorageType    = "grpc-plugin"
 	badgerStorageType        = "badger"
+	clickhouseStorageType    = "clickhouse"
+	postgresStorageType      = "postgres"
+	dynamodbStorageType      = "dynamodb"
+	bigtableStorageType      = "bigtable"
+	parquetStorageType       = "parquet"
+	redisStorageType         = "redis"
+	mongodbStorageType       = "mongodb"
+	cosmosdbStorageType      = "cosmosdb"
+	tieredStorageType        = "tiered"
 	downsamplingRatio        = "downsampling.ratio"
 	downsamplingHashSalt     = "downsampling.hashsalt"
+	downsamplingOverrides    = "downsampling.overrides-file"
+	downsamplingReload       = "downsampling.overrides-reload-interval"
+	secondaryFlagPrefix      = "secondary."
+	secondaryStorageType     = "secondary"
 
 	// defaultDownsamplingRatio is the default downsampling ratio.
 	defaultDownsamplingRatio = 1.0
@@ -52,13 +74,18 @@ const (
 )
 
 // AllStorageTypes defines all available storage backends
-var AllStorageTypes = []string{cassandraStorageType, elasticsearchStorageType, memoryStorageType, kafkaStorageType, badgerStorageType, grpcPluginStorageType}
+var AllStorageTypes = []string{cassandraStorageType, elasticsearchStorageType, memoryStorageType, kafkaStorageType, badgerStorageType, grpcPluginStorageType, clickhouseStorageType, postgresStorageType, dynamodbStorageType, bigtableStorageType, parquetStorageType, redisStorageType, mongodbStorageType, cosmosdbStorageType, tieredStorageType}
 
 // Factory implements storage.Factory interface as a meta-factory for storage components.
 type Factory struct {
 	FactoryConfig
 	metricsFactory metrics.Factory
+	logger         *zap.Logger
 	factories      map[string]storage.Factory
+
+	// secondary is a separate instance of a backend receiving a copy of all written spans
+	secondary      storage.Factory
+	secondaryFlags []string
 }
 
 // NewFactory creates the meta-factory.
@@ -71,6 +98,15 @@ func NewFactory(config FactoryConfig) (*Factory, error) {
 	for _, storageType := range f.SpanWriterTypes {
 		uniqueTypes[storageType] = struct{}{}
 	}
+	for _, storageType := range f.FederatedSpanReaderTypes {
+		if storageType == secondaryStorageType {
+			if f.SecondarySpanWriterType == "" {
+				return nil, fmt.Errorf("the %s storage is federated but %s is not set", secondaryStorageType, SecondarySpanStorageTypeEnvVar)
+			}
+			continue
+		}
+		uniqueTypes[storageType] = struct{}{}
+	}
 	f.factories = make(map[string]storage.Factory)
 	for t := range uniqueTypes {
 		ff, err := f.getFactoryOfType(t)
@@ -79,6 +115,13 @@ func NewFactory(config FactoryConfig) (*Factory, error) {
 		}
 		f.factories[t] = ff
 	}
+	if f.SecondarySpanWriterType != "" {
+		ff, err := f.getFactoryOfType(f.SecondarySpanWriterType)
+		if err != nil {
+			return nil, err
+		}
+		f.secondary = ff
+	}
 	return f, nil
 }
 
@@ -96,29 +139,96 @@ func (f *Factory) getFactoryOfType(factoryType string) (storage.Factory, error)
 		return badger.NewFactory(), nil
 	case grpcPluginStorageType:
 		return grpc.NewFactory(), nil
+	case clickhouseStorageType:
+		return clickhouse.NewFactory(), nil
+	case postgresStorageType:
+		return postgres.NewFactory(), nil
+	case dynamodbStorageType:
+		return dynamodb.NewFactory(), nil
+	case bigtableStorageType:
+		return bigtable.NewFactory(), nil
+	case parquetStorageType:
+		return parquet.NewFactory(), nil
+	case redisStorageType:
+		return redis.NewFactory(), nil
+	case mongodbStorageType:
+		return mongodb.NewFactory(), nil
+	case cosmosdbStorageType:
+		return cosmosdb.NewFactory(), nil
+	case tieredStorageType:
+		return f.newTieredFactory()
 	default:
 		return nil, fmt.Errorf("unknown storage type %s. Valid types are %v", factoryType, AllStorageTypes)
 	}
 }
 
+// newTieredFactory creates the factory of the tiered storage, composing two other types of backends.
+func (f *Factory) newTieredFactory() (storage.Factory, error) {
+	if f.TieredHotType == "" || f.TieredColdType == "" {
+		return nil, fmt.Errorf("the %s storage requires the --%s and --%s flags", tieredStorageType, tiered.HotStorageTypeFlag, tiered.ColdStorageTypeFlag)
+	}
+	if f.TieredHotType == tieredStorageType || f.TieredColdType == tieredStorageType {
+		return nil, fmt.Errorf("the tiers of the %s storage cannot be %s storages", tieredStorageType, tieredStorageType)
+	}
+	hot, err := f.getFactoryOfType(f.TieredHotType)
+	if err != nil {
+		return nil, err
+	}
+	cold, err := f.getFactoryOfType(f.TieredColdType)
+	if err != nil {
+		return nil, err
+	}
+	return tiered.NewFactory(
+		tiered.Tier{Type: f.TieredHotType, Factory: hot},
+		tiered.Tier{Type: f.TieredColdType, Factory: cold},
+	), nil
+}
+
 // Initialize implements storage.Factory.
 func (f *Factory) Initialize(metricsFactory metrics.Factory, logger *zap.Logger) error {
 	f.metricsFactory = metricsFactory
+	f.logger = logger
 	for _, factory := range f.factories {
 		if err := factory.Initialize(metricsFactory, logger); err != nil {
 			return err
 		}
 	}
+	if f.secondary != nil {
+		secondaryMetrics := metricsFactory.Namespace(metrics.NSOptions{Name: "secondary"})
+		if err := f.secondary.Initialize(secondaryMetrics, logger.With(zap.String("storage", "secondary"))); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// CreateSpanReader implements storage.Factory.
+// CreateSpanReader implements storage.Factory. The readers of the federated backends are merged
+// with the primary one.
 func (f *Factory) CreateSpanReader() (spanstore.Reader, error) {
 	factory, ok := f.factories[f.SpanReaderType]
 	if !ok {
 		return nil, fmt.Errorf("no %s backend registered for span store", f.SpanReaderType)
 	}
-	return factory.CreateSpanReader()
+	reader, err := factory.CreateSpanReader()
+	if err != nil || len(f.FederatedSpanReaderTypes) == 0 {
+		return reader, err
+	}
+	readers := []spanstore.Reader{reader}
+	for _, storageType := range f.FederatedSpanReaderTypes {
+		factory, ok := f.factories[storageType]
+		if storageType == secondaryStorageType {
+			factory, ok = f.secondary, f.secondary != nil
+		}
+		if !ok {
+			return nil, fmt.Errorf("no %s backend registered for span store", storageType)
+		}
+		reader, err := factory.CreateSpanReader()
+		if err != nil {
+			return nil, err
+		}
+		readers = append(readers, reader)
+	}
+	return spanstore.NewFederatedReader(readers...), nil
 }
 
 // CreateSpanWriter implements storage.Factory.
@@ -135,20 +245,39 @@ func (f *Factory) CreateSpanWriter() (spanstore.Writer, error) {
 		}
 		writers = append(writers, writer)
 	}
+	if f.secondary != nil {
+		writer, err := f.secondary.CreateSpanWriter()
+		if err != nil {
+			return nil, err
+		}
+		writers = append(writers, writer)
+	}
 	var spanWriter spanstore.Writer
-	if len(f.SpanWriterTypes) == 1 {
+	if len(writers) == 1 {
 		spanWriter = writers[0]
 	} else {
 		spanWriter = spanstore.NewCompositeWriter(writers...)
 	}
-	// Turn off DownsamplingWriter entirely if ratio == defaultDownsamplingRatio.
-	if f.DownsamplingRatio == defaultDownsamplingRatio {
+	// Turn off DownsamplingWriter entirely if ratio == defaultDownsamplingRatio and there are no overrides.
+	if f.DownsamplingRatio == defaultDownsamplingRatio && f.DownsamplingOverridesFile == "" {
 		return spanWriter, nil
 	}
+	var overrides *spanstore.DownsamplingOverrides
+	if f.DownsamplingOverridesFile != "" {
+		o, err := spanstore.LoadDownsamplingOverrides(f.DownsamplingOverridesFile)
+		if err != nil {
+			return nil, err
+		}
+		overrides = o
+	}
 	return spanstore.NewDownsamplingWriter(spanWriter, spanstore.DownsamplingOptions{
-		Ratio:          f.DownsamplingRatio,
-		HashSalt:       f.DownsamplingHashSalt,
-		MetricsFactory: f.metricsFactory.Namespace(metrics.NSOptions{Name: "downsampling_writer"}),
+		Ratio:                   f.DownsamplingRatio,
+		HashSalt:                f.DownsamplingHashSalt,
+		MetricsFactory:          f.metricsFactory.Namespace(metrics.NSOptions{Name: "downsampling_writer"}),
+		Overrides:               overrides,
+		OverridesFile:           f.DownsamplingOverridesFile,
+		OverridesReloadInterval: f.DownsamplingOverridesReloadInterval,
+		Logger:                  f.logger,
 	}), nil
 }
 
@@ -168,9 +297,24 @@ func (f *Factory) AddFlags(flagSet *flag.FlagSet) {
 			conf.AddFlags(flagSet)
 		}
 	}
+	if conf, ok := f.secondary.(plugin.Configurable); ok {
+		f.addSecondaryFlags(flagSet, conf)
+	}
 	addDownsamplingFlags(flagSet)
 }
 
+// addSecondaryFlags registers the flags of the secondary backend with the "secondary." prefix,
+// so that both instances of the same backend type can be configured independently.
+func (f *Factory) addSecondaryFlags(flagSet *flag.FlagSet, conf plugin.Configurable) {
+	secondaryFlagSet := flag.NewFlagSet("secondary", flag.ContinueOnError)
+	conf.AddFlags(secondaryFlagSet)
+	f.secondaryFlags = nil
+	secondaryFlagSet.VisitAll(func(fl *flag.Flag) {
+		flagSet.Var(fl.Value, secondaryFlagPrefix+fl.Name, "(secondary storage) "+fl.Usage)
+		f.secondaryFlags = append(f.secondaryFlags, fl.Name)
+	})
+}
+
 // addDownsamplingFlags add flags for Downsampling params
 func addDownsamplingFlags(flagSet *flag.FlagSet) {
 	flagSet.Float64(
@@ -183,6 +327,16 @@ func addDownsamplingFlags(flagSet *flag.FlagSet) {
 		defaultDownsamplingHashSalt,
 		"Salt used when hashing trace id for downsampling.",
 	)
+	flagSet.String(
+		downsamplingOverrides,
+		"",
+		"Path to a JSON file with downsampling ratios of specific services and operations, overriding --"+downsamplingRatio+".",
+	)
+	flagSet.Duration(
+		downsamplingReload,
+		0,
+		"Reload interval to check and reload the downsampling overrides file. Zero value means no reloading.",
+	)
 }
 
 // InitFromViper implements plugin.Configurable
@@ -192,6 +346,13 @@ func (f *Factory) InitFromViper(v *viper.Viper) {
 			conf.InitFromViper(v)
 		}
 	}
+	if conf, ok := f.secondary.(plugin.Configurable); ok {
+		secondaryViper := viper.New()
+		for _, name := range f.secondaryFlags {
+			secondaryViper.Set(name, v.Get(secondaryFlagPrefix+name))
+		}
+		conf.InitFromViper(secondaryViper)
+	}
 	f.initDownsamplingFromViper(v)
 }
 
@@ -202,6 +363,8 @@ func (f *Factory) initDownsamplingFromViper(v *viper.Viper) {
 		f.FactoryConfig.DownsamplingRatio = 1.0
 	}
 	f.FactoryConfig.DownsamplingHashSalt = v.GetString(downsamplingHashSalt)
+	f.FactoryConfig.DownsamplingOverridesFile = v.GetString(downsamplingOverrides)
+	f.FactoryConfig.DownsamplingOverridesReloadInterval = v.GetDuration(downsamplingReload)
 }
 
 // CreateArchiveSpanReader implements storage.ArchiveFactory
@@ -217,6 +380,19 @@ func (f *Factory) CreateArchiveSpanReader() (spanstore.Reader, error) {
 	return archive.CreateArchiveSpanReader()
 }
 
+// CreateTenantSpanReader implements storage.TenantFactory
+func (f *Factory) CreateTenantSpanReader(tenant string) (spanstore.Reader, error) {
+	factory, ok := f.factories[f.SpanReaderType]
+	if !ok {
+		return nil, fmt.Errorf("no %s backend registered for span store", f.SpanReaderType)
+	}
+	tenantFactory, ok := factory.(storage.TenantFactory)
+	if !ok {
+		return nil, storage.ErrTenantStorageNotSupported
+	}
+	return tenantFactory.CreateTenantSpanReader(tenant)
+}
+
 // CreateArchiveSpanWriter implements storage.ArchiveFactory
 func (f *Factory) CreateArchiveSpanWriter() (spanstore.Writer, error) {
 	factory, ok := f.factories[f.SpanWriterTypes[0]]
@@ -20,12 +20,24 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
+
+	"github.com/jaegertracing/jaeger/plugin/storage/tiered"
 )
 
 const (
 	// SpanStorageTypeEnvVar is the name of the env var that defines the type of backend used for span storage.
 	SpanStorageTypeEnvVar = "SPAN_STORAGE_TYPE"
 
+	// SecondarySpanStorageTypeEnvVar is the name of the env var that defines the type of an additional backend
+	// receiving all written spans. It is configured independently with flags prefixed by "secondary.".
+	SecondarySpanStorageTypeEnvVar = "SECONDARY_SPAN_STORAGE_TYPE"
+
+	// FederatedSpanStorageTypesEnvVar is the name of the env var that defines the comma-separated types of
+	// additional backends queried with the span reader, their results are merged. The "secondary" type
+	// refers to the backend defined by SECONDARY_SPAN_STORAGE_TYPE.
+	FederatedSpanStorageTypesEnvVar = "FEDERATED_SPAN_STORAGE_TYPES"
+
 	// DependencyStorageTypeEnvVar is the name of the env var that defines the type of backend used for dependencies storage.
 	DependencyStorageTypeEnvVar = "DEPENDENCY_STORAGE_TYPE"
 
@@ -35,10 +47,20 @@ const (
 // FactoryConfig tells the Factory which types of backends it needs to create for different storage types.
 type FactoryConfig struct {
 	SpanWriterTypes         []string
+	SecondarySpanWriterType string
 	SpanReaderType          string
-	DependenciesStorageType string
-	DownsamplingRatio       float64
-	DownsamplingHashSalt    string
+	// FederatedSpanReaderTypes are the backends also queried for spans, the results of all readers are merged
+	FederatedSpanReaderTypes []string
+	DependenciesStorageType  string
+	DownsamplingRatio        float64
+	DownsamplingHashSalt     string
+	// DownsamplingOverridesFile holds per-service and per-operation downsampling ratios, optional
+	DownsamplingOverridesFile string
+	// DownsamplingOverridesReloadInterval is how often the overrides file is reloaded, 0 disables reloading
+	DownsamplingOverridesReloadInterval time.Duration
+	// TieredHotType and TieredColdType are the backends composed by the "tiered" storage type
+	TieredHotType  string
+	TieredColdType string
 }
 
 // FactoryConfigFromEnvAndCLI reads the desired types of storage backends from SPAN_STORAGE_TYPE and
@@ -49,6 +71,15 @@ type FactoryConfig struct {
 //   * `kafka` - built-in
 //   * `plugin` - loads a dynamic plugin that implements storage.Factory interface (not supported at the moment)
 //
+// The optional SECONDARY_SPAN_STORAGE_TYPE env var accepts the same values and adds a writer
+// to a second, independently configured instance of that backend.
+//
+// The optional FEDERATED_SPAN_STORAGE_TYPES env var lists the backends whose spans are read along with
+// the first span storage type, e.g. a cold storage or the clusters of other regions.
+//
+// The `tiered` storage type composes the backends set with the --tiered.hot-storage.type and
+// --tiered.cold-storage.type flags, which are parsed from the args.
+//
 // For backwards compatibility it also parses the args looking for deprecated --span-storage.type flag.
 // If found, it writes a deprecation warning to the log.
 func FactoryConfigFromEnvAndCLI(args []string, log io.Writer) FactoryConfig {
@@ -72,14 +103,39 @@ func FactoryConfigFromEnvAndCLI(args []string, log io.Writer) FactoryConfig {
 	if depStorageType == "" {
 		depStorageType = spanWriterTypes[0]
 	}
+	var federatedTypes []string
+	if federated := os.Getenv(FederatedSpanStorageTypesEnvVar); federated != "" {
+		federatedTypes = strings.Split(federated, ",")
+	}
 	// TODO support explicit configuration for readers
 	return FactoryConfig{
-		SpanWriterTypes:         spanWriterTypes,
-		SpanReaderType:          spanWriterTypes[0],
-		DependenciesStorageType: depStorageType,
+		SpanWriterTypes:          spanWriterTypes,
+		SecondarySpanWriterType:  os.Getenv(SecondarySpanStorageTypeEnvVar),
+		SpanReaderType:           spanWriterTypes[0],
+		FederatedSpanReaderTypes: federatedTypes,
+		DependenciesStorageType:  depStorageType,
+		TieredHotType:            flagValueFromArgs(args, tiered.HotStorageTypeFlag),
+		TieredColdType:           flagValueFromArgs(args, tiered.ColdStorageTypeFlag),
 	}
 }
 
+// flagValueFromArgs returns the value of the flag in the args, set as either "--name value" or "--name=value".
+func flagValueFromArgs(args []string, name string) string {
+	for i, token := range args {
+		if i == 0 {
+			continue // skip app name
+		}
+		token = "-" + strings.TrimLeft(token, "-")
+		if token == "-"+name && i < len(args)-1 {
+			return args[i+1]
+		}
+		if strings.HasPrefix(token, "-"+name+"=") {
+			return token[len(name)+2:]
+		}
+	}
+	return ""
+}
+
 func spanStorageTypeFromArgs(args []string, log io.Writer) string {
 	for i, token := range args {
 		if i == 0 {
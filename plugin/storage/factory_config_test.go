@@ -26,6 +26,8 @@ import (
 func clearEnv() {
 	os.Setenv(SpanStorageTypeEnvVar, "")
 	os.Setenv(DependencyStorageTypeEnvVar, "")
+	os.Setenv(SecondarySpanStorageTypeEnvVar, "")
+	os.Setenv(FederatedSpanStorageTypesEnvVar, "")
 }
 
 func TestFactoryConfigFromEnv(t *testing.T) {
@@ -54,6 +56,19 @@ func TestFactoryConfigFromEnv(t *testing.T) {
 	assert.Equal(t, []string{elasticsearchStorageType, kafkaStorageType}, f.SpanWriterTypes)
 	assert.Equal(t, elasticsearchStorageType, f.SpanReaderType)
 
+	assert.Equal(t, "", f.SecondarySpanWriterType)
+
+	os.Setenv(SecondarySpanStorageTypeEnvVar, kafkaStorageType)
+	f = FactoryConfigFromEnvAndCLI(nil, &bytes.Buffer{})
+	assert.Equal(t, kafkaStorageType, f.SecondarySpanWriterType)
+	os.Setenv(SecondarySpanStorageTypeEnvVar, "")
+
+	assert.Nil(t, f.FederatedSpanReaderTypes)
+	os.Setenv(FederatedSpanStorageTypesEnvVar, elasticsearchStorageType+",secondary")
+	f = FactoryConfigFromEnvAndCLI(nil, &bytes.Buffer{})
+	assert.Equal(t, []string{elasticsearchStorageType, "secondary"}, f.FederatedSpanReaderTypes)
+	os.Setenv(FederatedSpanStorageTypesEnvVar, "")
+
 	os.Setenv(SpanStorageTypeEnvVar, badgerStorageType)
 
 	f = FactoryConfigFromEnvAndCLI(nil, nil)
@@ -88,3 +103,18 @@ func TestFactoryConfigFromEnvDeprecated(t *testing.T) {
 		}
 	}
 }
+
+func TestFactoryConfigTieredFromCLI(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+	os.Setenv(SpanStorageTypeEnvVar, tieredStorageType)
+
+	f := FactoryConfigFromEnvAndCLI([]string{"appname", "--tiered.hot-storage.type=badger", "--tiered.cold-storage.type", "elasticsearch"}, &bytes.Buffer{})
+	assert.Equal(t, []string{tieredStorageType}, f.SpanWriterTypes)
+	assert.Equal(t, badgerStorageType, f.TieredHotType)
+	assert.Equal(t, elasticsearchStorageType, f.TieredColdType)
+
+	f = FactoryConfigFromEnvAndCLI([]string{"appname", "-tiered.hot-storage.type=memory", "--tiered.cold-storage.type"}, &bytes.Buffer{})
+	assert.Equal(t, memoryStorageType, f.TieredHotType)
+	assert.Equal(t, "", f.TieredColdType)
+}
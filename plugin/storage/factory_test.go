@@ -18,17 +18,23 @@ package storage
 import (
 	"errors"
 	"flag"
+	"io/ioutil"
+	"os"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	testifyMock "github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"github.com/uber/jaeger-lib/metrics"
 	"go.uber.org/zap"
 
 	"github.com/jaegertracing/jaeger/pkg/config"
+	"github.com/jaegertracing/jaeger/plugin/storage/es"
+	"github.com/jaegertracing/jaeger/plugin/storage/tiered"
 	"github.com/jaegertracing/jaeger/storage"
 	depStoreMocks "github.com/jaegertracing/jaeger/storage/dependencystore/mocks"
 	"github.com/jaegertracing/jaeger/storage/mocks"
@@ -178,6 +184,32 @@ func TestCreateDownsamplingWriter(t *testing.T) {
 	}
 }
 
+func TestCreateDownsamplingWriterWithOverrides(t *testing.T) {
+	f, err := NewFactory(defaultCfg())
+	require.NoError(t, err)
+	mock := new(mocks.Factory)
+	f.factories[cassandraStorageType] = mock
+	mock.On("CreateSpanWriter").Return(new(spanStoreMocks.Writer), nil)
+	mock.On("Initialize", metrics.NullFactory, zap.NewNop()).Return(nil)
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+
+	file, err := ioutil.TempFile("", "downsampling-overrides")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(`{"service_ratios": [{"service": "noisy", "ratio": 0.1}]}`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	f.DownsamplingOverridesFile = file.Name()
+	w, err := f.CreateSpanWriter()
+	require.NoError(t, err)
+	assert.IsType(t, &spanstore.DownsamplingWriter{}, w, "overrides enable downsampling with the default ratio")
+
+	f.DownsamplingOverridesFile = file.Name() + "-missing"
+	_, err = f.CreateSpanWriter()
+	assert.Error(t, err)
+}
+
 func TestCreateMulti(t *testing.T) {
 	cfg := defaultCfg()
 	cfg.SpanWriterTypes = append(cfg.SpanWriterTypes, elasticsearchStorageType)
@@ -236,6 +268,33 @@ func TestCreateArchive(t *testing.T) {
 	assert.EqualError(t, err, "archive-span-writer-error")
 }
 
+type tenantFactory map[string]spanstore.Reader
+
+func (f tenantFactory) CreateTenantSpanReader(tenant string) (spanstore.Reader, error) {
+	if reader, ok := f[tenant]; ok {
+		return reader, nil
+	}
+	return nil, errors.New("unknown tenant")
+}
+
+func TestCreateTenantSpanReader(t *testing.T) {
+	f, err := NewFactory(defaultCfg())
+	require.NoError(t, err)
+	_, err = f.CreateTenantSpanReader("acme")
+	assert.Equal(t, storage.ErrTenantStorageNotSupported, err)
+
+	reader := new(spanStoreMocks.Reader)
+	f.factories[cassandraStorageType] = &struct {
+		mocks.Factory
+		tenantFactory
+	}{tenantFactory: tenantFactory{"acme": reader}}
+	r, err := f.CreateTenantSpanReader("acme")
+	require.NoError(t, err)
+	assert.Equal(t, reader, r)
+	_, err = f.CreateTenantSpanReader("megacorp")
+	assert.EqualError(t, err, "unknown tenant")
+}
+
 func TestCreateError(t *testing.T) {
 	f, err := NewFactory(defaultCfg())
 	require.NoError(t, err)
@@ -327,8 +386,173 @@ func TestParsingDownsamplingRatio(t *testing.T) {
 	assert.Equal(t, f.FactoryConfig.DownsamplingHashSalt, "jaeger")
 
 	err = command.ParseFlags([]string{
-		"--downsampling.ratio=0.5"})
+		"--downsampling.ratio=0.5",
+		"--downsampling.overrides-file=/etc/jaeger/downsampling.json",
+		"--downsampling.overrides-reload-interval=1m"})
 	assert.NoError(t, err)
 	f.InitFromViper(v)
 	assert.Equal(t, f.FactoryConfig.DownsamplingRatio, 0.5)
+	assert.Equal(t, "/etc/jaeger/downsampling.json", f.FactoryConfig.DownsamplingOverridesFile)
+	assert.Equal(t, time.Minute, f.FactoryConfig.DownsamplingOverridesReloadInterval)
+}
+
+func TestSecondaryStorage(t *testing.T) {
+	cfg := defaultCfg()
+	cfg.SecondarySpanWriterType = elasticsearchStorageType
+	f, err := NewFactory(cfg)
+	require.NoError(t, err)
+	require.NotNil(t, f.secondary)
+
+	v, command := config.Viperize(f.AddFlags)
+	require.NoError(t, command.ParseFlags([]string{
+		"--cassandra.servers=primary",
+		"--secondary.es.server-urls=http://secondary:9200",
+	}))
+	f.InitFromViper(v)
+	assert.Equal(t, []string{"http://secondary:9200"}, f.secondary.(*es.Factory).Options.GetPrimary().Servers)
+	assert.Contains(t, f.secondaryFlags, "es.server-urls")
+
+	mock := new(mocks.Factory)
+	secondaryMock := new(mocks.Factory)
+	f.factories[cassandraStorageType] = mock
+	f.secondary = secondaryMock
+
+	m := metrics.NullFactory
+	l := zap.NewNop()
+	mock.On("Initialize", m, l).Return(nil)
+	secondaryMock.On("Initialize", testifyMock.Anything, testifyMock.Anything).Return(nil)
+	require.NoError(t, f.Initialize(m, l))
+
+	spanWriter := new(spanStoreMocks.Writer)
+	secondaryWriter := new(spanStoreMocks.Writer)
+	mock.On("CreateSpanWriter").Return(spanWriter, nil)
+	secondaryMock.On("CreateSpanWriter").Once().Return(nil, errors.New("secondary-error"))
+	_, err = f.CreateSpanWriter()
+	assert.EqualError(t, err, "secondary-error")
+
+	secondaryMock.On("CreateSpanWriter").Return(secondaryWriter, nil)
+	w, err := f.CreateSpanWriter()
+	require.NoError(t, err)
+	assert.Equal(t, spanstore.NewCompositeWriter(spanWriter, secondaryWriter), w)
+
+	// the secondary backend is not used for reading
+	spanReader := new(spanStoreMocks.Reader)
+	mock.On("CreateSpanReader").Return(spanReader, nil)
+	r, err := f.CreateSpanReader()
+	require.NoError(t, err)
+	assert.Equal(t, spanReader, r)
+}
+
+func TestFederatedStorage(t *testing.T) {
+	cfg := defaultCfg()
+	cfg.FederatedSpanReaderTypes = []string{elasticsearchStorageType}
+	f, err := NewFactory(cfg)
+	require.NoError(t, err)
+	assert.NotNil(t, f.factories[elasticsearchStorageType])
+
+	mock := new(mocks.Factory)
+	federatedMock := new(mocks.Factory)
+	f.factories[cassandraStorageType] = mock
+	f.factories[elasticsearchStorageType] = federatedMock
+
+	spanReader := new(spanStoreMocks.Reader)
+	federatedReader := new(spanStoreMocks.Reader)
+	mock.On("CreateSpanReader").Return(spanReader, nil)
+	federatedMock.On("CreateSpanReader").Once().Return(nil, errors.New("federated-error"))
+	_, err = f.CreateSpanReader()
+	assert.EqualError(t, err, "federated-error")
+
+	federatedMock.On("CreateSpanReader").Return(federatedReader, nil)
+	r, err := f.CreateSpanReader()
+	require.NoError(t, err)
+	assert.Equal(t, spanstore.NewFederatedReader(spanReader, federatedReader), r)
+
+	// the federated backend is not used for writing
+	spanWriter := new(spanStoreMocks.Writer)
+	mock.On("CreateSpanWriter").Return(spanWriter, nil)
+	w, err := f.CreateSpanWriter()
+	require.NoError(t, err)
+	assert.Equal(t, spanWriter, w)
+}
+
+func TestFederatedSecondaryStorage(t *testing.T) {
+	cfg := defaultCfg()
+	cfg.FederatedSpanReaderTypes = []string{secondaryStorageType}
+	_, err := NewFactory(cfg)
+	assert.EqualError(t, err, "the secondary storage is federated but SECONDARY_SPAN_STORAGE_TYPE is not set")
+
+	cfg.SecondarySpanWriterType = cassandraStorageType
+	f, err := NewFactory(cfg)
+	require.NoError(t, err)
+	mock := new(mocks.Factory)
+	secondaryMock := new(mocks.Factory)
+	f.factories[cassandraStorageType] = mock
+	f.secondary = secondaryMock
+
+	spanReader := new(spanStoreMocks.Reader)
+	secondaryReader := new(spanStoreMocks.Reader)
+	mock.On("CreateSpanReader").Return(spanReader, nil)
+	secondaryMock.On("CreateSpanReader").Return(secondaryReader, nil)
+	r, err := f.CreateSpanReader()
+	require.NoError(t, err)
+	assert.Equal(t, spanstore.NewFederatedReader(spanReader, secondaryReader), r)
+
+	cfg.FederatedSpanReaderTypes = []string{"foo"}
+	_, err = NewFactory(cfg)
+	assert.EqualError(t, err, "unknown storage type foo. Valid types are [cassandra elasticsearch memory kafka badger grpc-plugin clickhouse postgres dynamodb bigtable parquet redis mongodb cosmosdb tiered]")
+}
+
+func TestSecondaryStorageInvalidType(t *testing.T) {
+	cfg := defaultCfg()
+	cfg.SecondarySpanWriterType = "foo"
+	_, err := NewFactory(cfg)
+	assert.EqualError(t, err, "unknown storage type foo. Valid types are [cassandra elasticsearch memory kafka badger grpc-plugin clickhouse postgres dynamodb bigtable parquet redis mongodb cosmosdb tiered]")
+
+	cfg.SecondarySpanWriterType = elasticsearchStorageType
+	f, err := NewFactory(cfg)
+	require.NoError(t, err)
+	secondaryMock := new(mocks.Factory)
+	f.factories[cassandraStorageType] = new(mocks.Factory)
+	f.factories[cassandraStorageType].(*mocks.Factory).On("Initialize", testifyMock.Anything, testifyMock.Anything).Return(nil)
+	f.secondary = secondaryMock
+	secondaryMock.On("Initialize", testifyMock.Anything, testifyMock.Anything).Return(errors.New("init-error"))
+	assert.EqualError(t, f.Initialize(metrics.NullFactory, zap.NewNop()), "init-error")
+}
+
+func TestTieredStorage(t *testing.T) {
+	cfg := defaultCfg()
+	cfg.SpanWriterTypes = []string{tieredStorageType}
+	cfg.SpanReaderType = tieredStorageType
+	cfg.DependenciesStorageType = tieredStorageType
+	_, err := NewFactory(cfg)
+	assert.EqualError(t, err, "the tiered storage requires the --tiered.hot-storage.type and --tiered.cold-storage.type flags")
+
+	cfg.TieredHotType = tieredStorageType
+	cfg.TieredColdType = elasticsearchStorageType
+	_, err = NewFactory(cfg)
+	assert.EqualError(t, err, "the tiers of the tiered storage cannot be tiered storages")
+
+	cfg.TieredHotType = "foo"
+	_, err = NewFactory(cfg)
+	assert.EqualError(t, err, "unknown storage type foo. Valid types are [cassandra elasticsearch memory kafka badger grpc-plugin clickhouse postgres dynamodb bigtable parquet redis mongodb cosmosdb tiered]")
+
+	cfg.TieredHotType = memoryStorageType
+	cfg.TieredColdType = memoryStorageType
+	f, err := NewFactory(cfg)
+	require.NoError(t, err)
+	require.IsType(t, &tiered.Factory{}, f.factories[tieredStorageType])
+
+	v, command := config.Viperize(f.AddFlags)
+	require.NoError(t, command.ParseFlags([]string{
+		"--tiered.hot-storage.type=memory",
+		"--tiered.cold-storage.type=memory",
+		"--tiered.hot-retention=1h",
+		"--tiered.hot.memory.max-traces=100",
+	}))
+	f.InitFromViper(v)
+	assert.Equal(t, time.Hour, f.factories[tieredStorageType].(*tiered.Factory).Options.HotRetention)
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	r, err := f.CreateSpanReader()
+	require.NoError(t, err)
+	assert.IsType(t, &tiered.Reader{}, r)
 }
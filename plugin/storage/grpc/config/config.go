@@ -18,10 +18,18 @@ import (
 	"fmt"
 	"os/exec"
 	"runtime"
+	"strings"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	// register the client-side health checking function used by healthCheckConfig
+	_ "google.golang.org/grpc/health"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
 
+	"github.com/jaegertracing/jaeger/pkg/config/tlscfg"
 	"github.com/jaegertracing/jaeger/plugin/storage/grpc/shared"
 )
 
@@ -30,10 +38,20 @@ type Configuration struct {
 	PluginBinary            string `yaml:"binary" mapstructure:"binary"`
 	PluginConfigurationFile string `yaml:"configuration-file" mapstructure:"configuration_file"`
 	PluginLogLevel          string `yaml:"log-level" mapstructure:"log_level"`
+	RemoteServerAddr        string `yaml:"server" mapstructure:"server"`
+
+	RemoteTLS tlscfg.Options `yaml:"tls" mapstructure:"tls"`
 }
 
+// remoteServiceConfig balances requests across all resolved storage servers
+// and takes unhealthy ones out of rotation using the standard gRPC health service.
+const remoteServiceConfig = `{"loadBalancingPolicy":"round_robin","healthCheckConfig":{"serviceName":""}}`
+
 // Build instantiates a StoragePlugin
 func (c *Configuration) Build() (shared.StoragePlugin, error) {
+	if c.RemoteServerAddr != "" {
+		return c.buildRemote()
+	}
 	// #nosec G204
 	cmd := exec.Command(c.PluginBinary, "--config", c.PluginConfigurationFile)
 
@@ -71,6 +89,59 @@ func (c *Configuration) Build() (shared.StoragePlugin, error) {
 	return storagePlugin, nil
 }
 
+// buildRemote connects to one or more remote storage servers instead of launching a plugin binary.
+// RemoteServerAddr is either a comma-separated list of host:port, which is balanced statically,
+// or a single address, which is resolved via DNS so that all of its records are balanced.
+func (c *Configuration) buildRemote() (shared.StoragePlugin, error) {
+	var addrs []string
+	for _, addr := range strings.Split(c.RemoteServerAddr, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("invalid remote storage server address: %q", c.RemoteServerAddr)
+	}
+
+	dialOpts := []grpc.DialOption{grpc.WithDefaultServiceConfig(remoteServiceConfig)}
+	if c.RemoteTLS.Enabled {
+		tlsCfg, err := c.RemoteTLS.Config()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS config: %w", err)
+		}
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg)))
+	} else {
+		dialOpts = append(dialOpts, grpc.WithInsecure())
+	}
+
+	var dialTarget string
+	cleanup := func() {}
+	if len(addrs) > 1 {
+		var r *manual.Resolver
+		r, cleanup = manual.GenerateAndRegisterManualResolver()
+		var resolvedAddrs []resolver.Address
+		for _, addr := range addrs {
+			resolvedAddrs = append(resolvedAddrs, resolver.Address{Addr: addr})
+		}
+		r.InitialState(resolver.State{Addresses: resolvedAddrs})
+		dialTarget = r.Scheme() + ":///round_robin"
+	} else if strings.Contains(addrs[0], ":///") {
+		dialTarget = addrs[0]
+	} else {
+		dialTarget = "dns:///" + addrs[0]
+	}
+
+	conn, err := grpc.Dial(dialTarget, dialOpts...)
+	if err != nil {
+		cleanup()
+		return nil, fmt.Errorf("error connecting to remote storage: %w", err)
+	}
+	return shared.NewRemoteGRPCClient(conn, func() {
+		conn.Close()
+		cleanup()
+	}), nil
+}
+
 // PluginBuilder is used to create storage plugins
 type PluginBuilder interface {
 	Build() (shared.StoragePlugin, error)
@@ -0,0 +1,66 @@
+// Copyright (c) 2018 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/pkg/config/tlscfg"
+)
+
+func TestBuildRemote(t *testing.T) {
+	for _, addr := range []string{"localhost:17271", "localhost:17271, localhost:17272", "passthrough:///localhost:17271"} {
+		c := &Configuration{RemoteServerAddr: addr}
+		p, err := c.Build()
+		require.NoError(t, err, addr)
+		assert.NotNil(t, p.SpanReader())
+		assert.NotNil(t, p.SpanWriter())
+		assert.NotNil(t, p.DependencyReader())
+	}
+}
+
+func TestBuildRemoteInvalidAddress(t *testing.T) {
+	c := &Configuration{RemoteServerAddr: " , "}
+	_, err := c.Build()
+	assert.EqualError(t, err, `invalid remote storage server address: " , "`)
+}
+
+func TestBuildRemoteClose(t *testing.T) {
+	c := &Configuration{RemoteServerAddr: "localhost:17271, localhost:17272"}
+	p, err := c.Build()
+	require.NoError(t, err)
+	closer, ok := p.SpanWriter().(io.Closer)
+	require.True(t, ok)
+	assert.NoError(t, closer.Close())
+}
+
+func TestBuildRemoteTLS(t *testing.T) {
+	c := &Configuration{
+		RemoteServerAddr: "localhost:17271",
+		RemoteTLS:        tlscfg.Options{Enabled: true, ServerName: "storage"},
+	}
+	p, err := c.Build()
+	require.NoError(t, err)
+	assert.NotNil(t, p.SpanReader())
+
+	c.RemoteTLS.CAPath = "/does/not/exist"
+	_, err = c.Build()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to load TLS config")
+}
@@ -19,6 +19,7 @@ import (
 
 	"github.com/spf13/viper"
 
+	"github.com/jaegertracing/jaeger/pkg/config/tlscfg"
 	"github.com/jaegertracing/jaeger/plugin/storage/grpc/config"
 )
 
@@ -27,8 +28,15 @@ const (
 	pluginConfigurationFile = "grpc-storage-plugin.configuration-file"
 	pluginLogLevel          = "grpc-storage-plugin.log-level"
 	defaultPluginLogLevel   = "warn"
+	remoteServer            = "grpc-storage.server"
 )
 
+var remoteTLSFlagsConfig = tlscfg.ClientFlagsConfig{
+	Prefix:         "grpc-storage",
+	ShowEnabled:    true,
+	ShowServerName: true,
+}
+
 // Options contains GRPC plugins configs and provides the ability
 // to bind them to command line flags
 type Options struct {
@@ -40,6 +48,9 @@ func (opt *Options) AddFlags(flagSet *flag.FlagSet) {
 	flagSet.String(pluginBinary, "", "The location of the plugin binary")
 	flagSet.String(pluginConfigurationFile, "", "A path pointing to the plugin's configuration file, made available to the plugin with the --config arg")
 	flagSet.String(pluginLogLevel, defaultPluginLogLevel, "Set the log level of the plugin's logger")
+	flagSet.String(remoteServer, "", "The remote storage gRPC server address(es) as a comma-separated list of host:port or a DNS name; "+
+		"when set, the plugin binary is not started and requests are round-robin balanced across healthy servers")
+	remoteTLSFlagsConfig.AddFlags(flagSet)
 }
 
 // InitFromViper initializes Options with properties from viper
@@ -47,5 +58,6 @@ func (opt *Options) InitFromViper(v *viper.Viper) {
 	opt.Configuration.PluginBinary = v.GetString(pluginBinary)
 	opt.Configuration.PluginConfigurationFile = v.GetString(pluginConfigurationFile)
 	opt.Configuration.PluginLogLevel = v.GetString(pluginLogLevel)
-
+	opt.Configuration.RemoteServerAddr = v.GetString(remoteServer)
+	opt.Configuration.RemoteTLS = remoteTLSFlagsConfig.InitFromViper(v)
 }
@@ -36,3 +36,19 @@ func TestOptionsWithFlags(t *testing.T) {
 	assert.Equal(t, opts.Configuration.PluginConfigurationFile, "config.json")
 	assert.Equal(t, opts.Configuration.PluginLogLevel, "debug")
 }
+
+func TestRemoteOptionsWithFlags(t *testing.T) {
+	opts := &Options{}
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{
+		"--grpc-storage.server=storage-1:17271,storage-2:17271",
+		"--grpc-storage.tls.enabled=true",
+		"--grpc-storage.tls.server-name=storage",
+	})
+	opts.InitFromViper(v)
+
+	assert.Equal(t, "storage-1:17271,storage-2:17271", opts.Configuration.RemoteServerAddr)
+	assert.Equal(t, "", opts.Configuration.PluginBinary)
+	assert.True(t, opts.Configuration.RemoteTLS.Enabled)
+	assert.Equal(t, "storage", opts.Configuration.RemoteTLS.ServerName)
+}
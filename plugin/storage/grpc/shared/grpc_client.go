@@ -34,6 +34,7 @@ type grpcClient struct {
 	readerClient     storage_v1.SpanReaderPluginClient
 	writerClient     storage_v1.SpanWriterPluginClient
 	depsReaderClient storage_v1.DependenciesReaderPluginClient
+	release          func()
 }
 
 // upgradeContextWithBearerToken turns the context into a gRPC outgoing context with bearer token
@@ -66,6 +67,14 @@ func (c *grpcClient) SpanWriter() spanstore.Writer {
 }
 
 // GetTrace takes a traceID and returns a Trace associated with that traceID
+// Close releases the connection to a remote storage server, it does nothing for a plugin binary.
+func (c *grpcClient) Close() error {
+	if c.release != nil {
+		c.release()
+	}
+	return nil
+}
+
 func (c *grpcClient) GetTrace(ctx context.Context, traceID model.TraceID) (*model.Trace, error) {
 	stream, err := c.readerClient.GetTrace(upgradeContextWithBearerToken(ctx), &storage_v1.GetTraceRequest{
 		TraceID: traceID,
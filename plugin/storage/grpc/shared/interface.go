@@ -65,9 +65,22 @@ func (p *StorageGRPCPlugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server
 
 // GRPCClient is used by go-plugin to create a grpc plugin client
 func (*StorageGRPCPlugin) GRPCClient(ctx context.Context, broker *plugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
+	return NewGRPCClient(c), nil
+}
+
+// NewGRPCClient creates a StoragePlugin that talks to a remote storage server over an existing connection.
+func NewGRPCClient(c *grpc.ClientConn) StoragePlugin {
 	return &grpcClient{
 		readerClient:     storage_v1.NewSpanReaderPluginClient(c),
 		writerClient:     storage_v1.NewSpanWriterPluginClient(c),
 		depsReaderClient: storage_v1.NewDependenciesReaderPluginClient(c),
-	}, nil
+	}
+}
+
+// NewRemoteGRPCClient creates a StoragePlugin that talks to a remote storage server over an existing connection,
+// and calls release when its span writer is closed.
+func NewRemoteGRPCClient(c *grpc.ClientConn, release func()) StoragePlugin {
+	client := NewGRPCClient(c).(*grpcClient)
+	client.release = release
+	return client
 }
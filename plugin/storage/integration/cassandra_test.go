@@ -16,8 +16,10 @@
 package integration
 
 import (
+	"context"
 	"errors"
 	"os"
+	"sort"
 	"testing"
 	"time"
 
@@ -151,3 +153,53 @@ func TestCassandraStorage(t *testing.T) {
 	t.Run("GetDependencies", s1.testCassandraGetDependencies)
 	t.Run("GetDependenciesV2", s2.testCassandraGetDependencies)
 }
+
+// BenchmarkCassandraGetTrace compares the latency of the reads with and without speculative execution,
+// which only improves the tail latency when the keyspace has several replicas of the traces.
+func BenchmarkCassandraGetTrace(b *testing.B) {
+	if os.Getenv("STORAGE") != "cassandra" {
+		b.Skip("Benchmark against Cassandra skipped; set STORAGE env var to cassandra to run this")
+	}
+	for _, test := range []struct {
+		name  string
+		flags []string
+	}{
+		{name: "NoSpeculativeExecution"},
+		{name: "SpeculativeExecution", flags: []string{
+			"--cassandra.speculative-execution.attempts=1",
+			"--cassandra.speculative-execution.delay=5ms",
+		}},
+	} {
+		b.Run(test.name, func(b *testing.B) {
+			s := newCassandraStorageIntegration()
+			f, err := s.initializeCassandraFactory(append([]string{"--cassandra.keyspace=jaeger_v1_dc1"}, test.flags...))
+			require.NoError(b, err)
+			writer, err := f.CreateSpanWriter()
+			require.NoError(b, err)
+			reader, err := f.CreateSpanReader()
+			require.NoError(b, err)
+
+			traceID := model.NewTraceID(0, uint64(time.Now().UnixNano()))
+			require.NoError(b, writer.WriteSpan(&model.Span{
+				TraceID:       traceID,
+				SpanID:        model.NewSpanID(1),
+				OperationName: "benchmark",
+				StartTime:     time.Now(),
+				Duration:      time.Millisecond,
+				Process:       model.NewProcess("benchmark", nil),
+			}))
+
+			latencies := make([]time.Duration, 0, b.N)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				start := time.Now()
+				_, err := reader.GetTrace(context.Background(), traceID)
+				latencies = append(latencies, time.Since(start))
+				require.NoError(b, err)
+			}
+			b.StopTimer()
+			sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+			b.ReportMetric(float64(latencies[len(latencies)*99/100].Microseconds()), "p99-µs")
+		})
+	}
+}
@@ -24,6 +24,7 @@ import (
 
 	"github.com/jaegertracing/jaeger/storage/dependencystore"
 	"github.com/jaegertracing/jaeger/storage/spanstore"
+	"github.com/jaegertracing/jaeger/storage/viewstore"
 )
 
 // Factory implements storage.Factory and creates storage components backed by memory store.
@@ -32,6 +33,7 @@ type Factory struct {
 	metricsFactory metrics.Factory
 	logger         *zap.Logger
 	store          *Store
+	viewStore      *ViewStore
 }
 
 // NewFactory creates a new Factory.
@@ -58,6 +60,7 @@ func (f *Factory) InitFromOptions(opts Options) {
 func (f *Factory) Initialize(metricsFactory metrics.Factory, logger *zap.Logger) error {
 	f.metricsFactory, f.logger = metricsFactory, logger
 	f.store = WithConfiguration(f.options.Configuration)
+	f.viewStore = NewViewStore()
 	logger.Info("Memory storage initialized", zap.Any("configuration", f.store.config))
 	return nil
 }
@@ -76,3 +79,8 @@ func (f *Factory) CreateSpanWriter() (spanstore.Writer, error) {
 func (f *Factory) CreateDependencyReader() (dependencystore.Reader, error) {
 	return f.store, nil
 }
+
+// CreateViewStore implements storage.ViewFactory
+func (f *Factory) CreateViewStore() (viewstore.Store, error) {
+	return f.viewStore, nil
+}
@@ -26,6 +26,7 @@ import (
 )
 
 var _ storage.Factory = new(Factory)
+var _ storage.ViewFactory = new(Factory)
 
 func TestMemoryStorageFactory(t *testing.T) {
 	f := NewFactory()
@@ -40,6 +41,9 @@ func TestMemoryStorageFactory(t *testing.T) {
 	depReader, err := f.CreateDependencyReader()
 	assert.NoError(t, err)
 	assert.Equal(t, f.store, depReader)
+	viewStore, err := f.CreateViewStore()
+	assert.NoError(t, err)
+	assert.Equal(t, f.viewStore, viewStore)
 }
 
 func TestWithConfiguration(t *testing.T) {
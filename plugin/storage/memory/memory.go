@@ -276,6 +276,11 @@ func (m *Store) validSpan(span *model.Span, query *spanstore.TraceQueryParameter
 			return false
 		}
 	}
+	for _, f := range query.TagFilters {
+		if !f.Matches(spanKVs) {
+			return false
+		}
+	}
 	return true
 }
 
@@ -17,10 +17,12 @@ package memory
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/jaegertracing/jaeger/model"
 	"github.com/jaegertracing/jaeger/pkg/memory/config"
@@ -410,6 +412,43 @@ func TestStoreGetTrace(t *testing.T) {
 	}
 }
 
+func TestStoreFindTracesTagFilters(t *testing.T) {
+	testCases := []struct {
+		filters    []string
+		traceFound bool
+	}{
+		{filters: []string{"tagKey"}, traceFound: true},
+		{filters: []string{"!tagKey"}, traceFound: false},
+		{filters: []string{"!missing", "logKey=logValue"}, traceFound: true},
+		{filters: []string{"tagKey!=tagValue"}, traceFound: false},
+		{filters: []string{"span.kind=~cli.*"}, traceFound: true},
+		{filters: []string{"span.kind~serv*"}, traceFound: false},
+		{filters: []string{"tagKey>10"}, traceFound: false},
+	}
+	for _, testCase := range testCases {
+		t.Run(strings.Join(testCase.filters, ","), func(t *testing.T) {
+			withPopulatedMemoryStore(func(store *Store) {
+				query := &spanstore.TraceQueryParameters{
+					ServiceName: testingSpan.Process.ServiceName,
+					NumTraces:   10,
+				}
+				for _, expr := range testCase.filters {
+					f, err := spanstore.ParseTagFilter(expr)
+					require.NoError(t, err)
+					query.TagFilters = append(query.TagFilters, f)
+				}
+				traces, err := store.FindTraces(context.Background(), query)
+				assert.NoError(t, err)
+				if testCase.traceFound {
+					assert.Len(t, traces, 1)
+				} else {
+					assert.Empty(t, traces)
+				}
+			})
+		})
+	}
+}
+
 func TestStore_FindTraceIDs(t *testing.T) {
 	withMemoryStore(func(store *Store) {
 		traceIDs, err := store.FindTraceIDs(context.Background(), nil)
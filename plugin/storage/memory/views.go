@@ -0,0 +1,249 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/jaegertracing/jaeger/storage/viewstore"
+)
+
+// ViewStore is an in-memory store of the saved searches and the pinned traces,
+// optionally persisted to a JSON file rewritten after each change.
+type ViewStore struct {
+	sync.RWMutex
+	searches map[string]*viewstore.SavedSearch
+	pins     map[string]*viewstore.PinnedTrace
+	path     string
+}
+
+type viewsFile struct {
+	Searches     []*viewstore.SavedSearch `json:"searches"`
+	PinnedTraces []*viewstore.PinnedTrace `json:"pinnedTraces"`
+}
+
+// NewViewStore creates a view store losing its content when the process exits.
+func NewViewStore() *ViewStore {
+	return &ViewStore{
+		searches: map[string]*viewstore.SavedSearch{},
+		pins:     map[string]*viewstore.PinnedTrace{},
+	}
+}
+
+// NewFileViewStore creates a view store persisted to the given file, loading its content if it exists.
+func NewFileViewStore(path string) (*ViewStore, error) {
+	s := NewViewStore()
+	s.path = filepath.Clean(path)
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open views file: %w", err)
+	}
+	var views viewsFile
+	if err := json.Unmarshal(data, &views); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal views: %w", err)
+	}
+	for _, search := range views.Searches {
+		s.searches[search.ID] = search
+	}
+	for _, pin := range views.PinnedTraces {
+		s.pins[pin.TraceID] = pin
+	}
+	return s, nil
+}
+
+// GetSearches implements viewstore.Store
+func (s *ViewStore) GetSearches(ctx context.Context) ([]*viewstore.SavedSearch, error) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.sortedSearches(), nil
+}
+
+// GetSearch implements viewstore.Store
+func (s *ViewStore) GetSearch(ctx context.Context, id string) (*viewstore.SavedSearch, error) {
+	s.RLock()
+	defer s.RUnlock()
+	search, ok := s.searches[id]
+	if !ok {
+		return nil, viewstore.ErrNotFound
+	}
+	copied := *search
+	return &copied, nil
+}
+
+// SaveSearch implements viewstore.Store
+func (s *ViewStore) SaveSearch(ctx context.Context, search *viewstore.SavedSearch) error {
+	s.Lock()
+	defer s.Unlock()
+	copied := *search
+	previous := s.searches[search.ID]
+	s.searches[search.ID] = &copied
+	if err := s.persist(); err != nil {
+		s.restoreSearch(search.ID, previous)
+		return err
+	}
+	return nil
+}
+
+// DeleteSearch implements viewstore.Store
+func (s *ViewStore) DeleteSearch(ctx context.Context, id string) error {
+	s.Lock()
+	defer s.Unlock()
+	previous, ok := s.searches[id]
+	if !ok {
+		return viewstore.ErrNotFound
+	}
+	delete(s.searches, id)
+	if err := s.persist(); err != nil {
+		s.restoreSearch(id, previous)
+		return err
+	}
+	return nil
+}
+
+// GetPinnedTraces implements viewstore.Store
+func (s *ViewStore) GetPinnedTraces(ctx context.Context) ([]*viewstore.PinnedTrace, error) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.sortedPins(), nil
+}
+
+// GetPinnedTrace implements viewstore.Store
+func (s *ViewStore) GetPinnedTrace(ctx context.Context, traceID string) (*viewstore.PinnedTrace, error) {
+	s.RLock()
+	defer s.RUnlock()
+	pin, ok := s.pins[traceID]
+	if !ok {
+		return nil, viewstore.ErrNotFound
+	}
+	copied := *pin
+	return &copied, nil
+}
+
+// PinTrace implements viewstore.Store
+func (s *ViewStore) PinTrace(ctx context.Context, pin *viewstore.PinnedTrace) error {
+	s.Lock()
+	defer s.Unlock()
+	copied := *pin
+	previous := s.pins[pin.TraceID]
+	s.pins[pin.TraceID] = &copied
+	if err := s.persist(); err != nil {
+		s.restorePin(pin.TraceID, previous)
+		return err
+	}
+	return nil
+}
+
+// UnpinTrace implements viewstore.Store
+func (s *ViewStore) UnpinTrace(ctx context.Context, traceID string) error {
+	s.Lock()
+	defer s.Unlock()
+	previous, ok := s.pins[traceID]
+	if !ok {
+		return viewstore.ErrNotFound
+	}
+	delete(s.pins, traceID)
+	if err := s.persist(); err != nil {
+		s.restorePin(traceID, previous)
+		return err
+	}
+	return nil
+}
+
+func (s *ViewStore) sortedSearches() []*viewstore.SavedSearch {
+	searches := make([]*viewstore.SavedSearch, 0, len(s.searches))
+	for _, search := range s.searches {
+		copied := *search
+		searches = append(searches, &copied)
+	}
+	sort.Slice(searches, func(i, j int) bool {
+		if searches[i].Name != searches[j].Name {
+			return searches[i].Name < searches[j].Name
+		}
+		return searches[i].ID < searches[j].ID
+	})
+	return searches
+}
+
+func (s *ViewStore) sortedPins() []*viewstore.PinnedTrace {
+	pins := make([]*viewstore.PinnedTrace, 0, len(s.pins))
+	for _, pin := range s.pins {
+		copied := *pin
+		pins = append(pins, &copied)
+	}
+	sort.Slice(pins, func(i, j int) bool {
+		if !pins[i].PinnedAt.Equal(pins[j].PinnedAt) {
+			return pins[i].PinnedAt.After(pins[j].PinnedAt)
+		}
+		return pins[i].TraceID < pins[j].TraceID
+	})
+	return pins
+}
+
+func (s *ViewStore) restoreSearch(id string, previous *viewstore.SavedSearch) {
+	if previous == nil {
+		delete(s.searches, id)
+	} else {
+		s.searches[id] = previous
+	}
+}
+
+func (s *ViewStore) restorePin(traceID string, previous *viewstore.PinnedTrace) {
+	if previous == nil {
+		delete(s.pins, traceID)
+	} else {
+		s.pins[traceID] = previous
+	}
+}
+
+// persist writes the views to a temporary file renamed over the previous one,
+// so that the file is never left half written. It must be called with the write lock held.
+func (s *ViewStore) persist() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(viewsFile{
+		Searches:     s.sortedSearches(),
+		PinnedTraces: s.sortedPins(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write views file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write views file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write views file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write views file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/storage/viewstore"
+)
+
+func TestViewStoreSearches(t *testing.T) {
+	s := NewViewStore()
+	ctx := context.Background()
+	_, err := s.GetSearch(ctx, "a")
+	assert.Equal(t, viewstore.ErrNotFound, err)
+	assert.Equal(t, viewstore.ErrNotFound, s.DeleteSearch(ctx, "a"))
+
+	search := &viewstore.SavedSearch{ID: "a", Name: "slow checkouts", Query: map[string][]string{"service": {"checkout"}}}
+	require.NoError(t, s.SaveSearch(ctx, search))
+	require.NoError(t, s.SaveSearch(ctx, &viewstore.SavedSearch{ID: "b", Name: "errors"}))
+	search.Name = "changed after saving"
+
+	found, err := s.GetSearch(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "slow checkouts", found.Name)
+	searches, err := s.GetSearches(ctx)
+	require.NoError(t, err)
+	require.Len(t, searches, 2)
+	assert.Equal(t, "errors", searches[0].Name)
+	assert.Equal(t, "slow checkouts", searches[1].Name)
+
+	require.NoError(t, s.DeleteSearch(ctx, "b"))
+	searches, err = s.GetSearches(ctx)
+	require.NoError(t, err)
+	assert.Len(t, searches, 1)
+}
+
+func TestViewStorePinnedTraces(t *testing.T) {
+	s := NewViewStore()
+	ctx := context.Background()
+	_, err := s.GetPinnedTrace(ctx, "1")
+	assert.Equal(t, viewstore.ErrNotFound, err)
+	assert.Equal(t, viewstore.ErrNotFound, s.UnpinTrace(ctx, "1"))
+
+	now := time.Now()
+	require.NoError(t, s.PinTrace(ctx, &viewstore.PinnedTrace{TraceID: "1", PinnedAt: now.Add(-time.Hour)}))
+	require.NoError(t, s.PinTrace(ctx, &viewstore.PinnedTrace{TraceID: "2", Note: "outage", PinnedAt: now}))
+	pins, err := s.GetPinnedTraces(ctx)
+	require.NoError(t, err)
+	require.Len(t, pins, 2)
+	assert.Equal(t, "2", pins[0].TraceID)
+	assert.Equal(t, "1", pins[1].TraceID)
+
+	pin, err := s.GetPinnedTrace(ctx, "2")
+	require.NoError(t, err)
+	assert.Equal(t, "outage", pin.Note)
+	require.NoError(t, s.UnpinTrace(ctx, "2"))
+	pins, err = s.GetPinnedTraces(ctx)
+	require.NoError(t, err)
+	assert.Len(t, pins, 1)
+}
+
+func TestFileViewStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "views")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "views.json")
+	ctx := context.Background()
+
+	s, err := NewFileViewStore(path)
+	require.NoError(t, err)
+	require.NoError(t, s.SaveSearch(ctx, &viewstore.SavedSearch{ID: "a", Name: "slow checkouts"}))
+	require.NoError(t, s.SaveSearch(ctx, &viewstore.SavedSearch{ID: "b", Name: "errors"}))
+	require.NoError(t, s.DeleteSearch(ctx, "b"))
+	require.NoError(t, s.PinTrace(ctx, &viewstore.PinnedTrace{TraceID: "1", Note: "outage"}))
+
+	reloaded, err := NewFileViewStore(path)
+	require.NoError(t, err)
+	searches, err := reloaded.GetSearches(ctx)
+	require.NoError(t, err)
+	require.Len(t, searches, 1)
+	assert.Equal(t, "slow checkouts", searches[0].Name)
+	pin, err := reloaded.GetPinnedTrace(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "outage", pin.Note)
+	require.NoError(t, reloaded.UnpinTrace(ctx, "1"))
+
+	reloaded, err = NewFileViewStore(path)
+	require.NoError(t, err)
+	pins, err := reloaded.GetPinnedTraces(ctx)
+	require.NoError(t, err)
+	assert.Len(t, pins, 0)
+}
+
+func TestFileViewStoreErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "views")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+
+	_, err = NewFileViewStore(dir)
+	assert.Contains(t, err.Error(), "failed to open views file")
+
+	path := filepath.Join(dir, "views.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte("views"), 0600))
+	_, err = NewFileViewStore(path)
+	assert.Contains(t, err.Error(), "failed to unmarshal views")
+
+	s, err := NewFileViewStore(filepath.Join(dir, "missing", "views.json"))
+	require.NoError(t, err)
+	err = s.SaveSearch(ctx, &viewstore.SavedSearch{ID: "a"})
+	assert.Contains(t, err.Error(), "failed to write views file")
+	err = s.PinTrace(ctx, &viewstore.PinnedTrace{TraceID: "1"})
+	assert.Contains(t, err.Error(), "failed to write views file")
+	// the changes that could not be persisted are rolled back
+	_, err = s.GetSearch(ctx, "a")
+	assert.Equal(t, viewstore.ErrNotFound, err)
+	_, err = s.GetPinnedTrace(ctx, "1")
+	assert.Equal(t, viewstore.ErrNotFound, err)
+}
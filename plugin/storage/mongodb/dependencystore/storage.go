@@ -0,0 +1,87 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dependencystore
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/plugin/storage/mongodb/spanstore"
+)
+
+// DependencyStore computes the dependencies of the services from the spans of the time window.
+// The spans of the window are all read, the cost of the dependencies grows with their number.
+type DependencyStore struct {
+	spans spanstore.Collection
+}
+
+// NewDependencyStore returns a DependencyStore reading the spans of the collection.
+func NewDependencyStore(spans spanstore.Collection) *DependencyStore {
+	return &DependencyStore{spans: spans}
+}
+
+type spanDocument struct {
+	TraceID      string `bson:"traceID"`
+	SpanID       string `bson:"spanID"`
+	ParentSpanID string `bson:"parentSpanID"`
+	Service      string `bson:"service"`
+}
+
+type spanKey struct {
+	traceID string
+	spanID  string
+}
+
+// GetDependencies returns the calls between the services in the time window ending at endTs.
+func (s *DependencyStore) GetDependencies(endTs time.Time, lookback time.Duration) ([]model.DependencyLink, error) {
+	filter := bson.D{
+		{Key: "startTime", Value: bson.D{{Key: "$gte", Value: endTs.Add(-lookback)}, {Key: "$lte", Value: endTs}}},
+		{Key: "service", Value: bson.D{{Key: "$exists", Value: true}}},
+	}
+	opts := options.Find().SetProjection(bson.D{
+		{Key: "_id", Value: 0},
+		{Key: "traceID", Value: 1},
+		{Key: "spanID", Value: 1},
+		{Key: "parentSpanID", Value: 1},
+		{Key: "service", Value: 1},
+	})
+	var documents []spanDocument
+	if err := s.spans.Find(context.Background(), filter, opts, &documents); err != nil {
+		return nil, err
+	}
+	services := make(map[spanKey]string, len(documents))
+	for _, document := range documents {
+		services[spanKey{document.TraceID, document.SpanID}] = document.Service
+	}
+	links := make(map[[2]string]uint64)
+	for _, document := range documents {
+		if document.ParentSpanID == "" {
+			continue
+		}
+		parent, ok := services[spanKey{document.TraceID, document.ParentSpanID}]
+		if ok && parent != document.Service {
+			links[[2]string{parent, document.Service}]++
+		}
+	}
+	dependencies := make([]model.DependencyLink, 0, len(links))
+	for link, count := range links {
+		dependencies = append(dependencies, model.DependencyLink{Parent: link[0], Child: link[1], CallCount: count})
+	}
+	return dependencies, nil
+}
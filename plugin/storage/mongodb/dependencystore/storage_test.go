@@ -0,0 +1,99 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dependencystore
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+type fakeCollection struct {
+	filter    interface{}
+	documents []bson.M
+	err       error
+}
+
+func (c *fakeCollection) Upsert(ctx context.Context, id string, document interface{}) error {
+	return nil
+}
+
+func (c *fakeCollection) Find(ctx context.Context, filter interface{}, opts *options.FindOptions, results interface{}) error {
+	c.filter = filter
+	if c.err != nil {
+		return c.err
+	}
+	data, err := bson.Marshal(bson.M{"documents": c.documents})
+	if err != nil {
+		return err
+	}
+	return bson.Raw(data).Lookup("documents").Unmarshal(results)
+}
+
+func (c *fakeCollection) Distinct(ctx context.Context, field string, filter interface{}) ([]interface{}, error) {
+	return nil, nil
+}
+
+func (c *fakeCollection) Aggregate(ctx context.Context, pipeline interface{}, results interface{}) error {
+	return nil
+}
+
+func (c *fakeCollection) CreateIndexes(ctx context.Context, indexes []mongo.IndexModel) error {
+	return nil
+}
+
+func span(traceID, spanID, parentSpanID, service string) bson.M {
+	document := bson.M{"traceID": traceID, "spanID": spanID, "service": service}
+	if parentSpanID != "" {
+		document["parentSpanID"] = parentSpanID
+	}
+	return document
+}
+
+func TestGetDependencies(t *testing.T) {
+	spans := &fakeCollection{documents: []bson.M{
+		span("t1", "b", "a", "frontend"), span("t1", "a", "", "gateway"), span("t1", "c", "b", "db"),
+		// a call within a service and a parent out of the window
+		span("t2", "a", "", "gateway"), span("t2", "b", "a", "gateway"), span("t3", "b", "a", "db"),
+		span("t4", "a", "", "gateway"), span("t4", "b", "a", "frontend"),
+	}}
+	store := NewDependencyStore(spans)
+	dependencies, err := store.GetDependencies(time.Unix(100, 0), 10*time.Second)
+	require.NoError(t, err)
+	sort.Slice(dependencies, func(i, j int) bool { return dependencies[i].Parent < dependencies[j].Parent })
+	assert.Equal(t, []model.DependencyLink{
+		{Parent: "frontend", Child: "db", CallCount: 1},
+		{Parent: "gateway", Child: "frontend", CallCount: 2},
+	}, dependencies)
+	assert.Equal(t, bson.D{
+		{Key: "startTime", Value: bson.D{{Key: "$gte", Value: time.Unix(90, 0)}, {Key: "$lte", Value: time.Unix(100, 0)}}},
+		{Key: "service", Value: bson.D{{Key: "$exists", Value: true}}},
+	}, spans.filter)
+}
+
+func TestGetDependenciesError(t *testing.T) {
+	store := NewDependencyStore(&fakeCollection{err: assert.AnError})
+	_, err := store.GetDependencies(time.Unix(100, 0), time.Second)
+	assert.Equal(t, assert.AnError, err)
+}
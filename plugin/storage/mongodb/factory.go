@@ -0,0 +1,129 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb
+
+import (
+	"context"
+	"flag"
+
+	"github.com/spf13/viper"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+
+	mongoDepStore "github.com/jaegertracing/jaeger/plugin/storage/mongodb/dependencystore"
+	mongoSpanStore "github.com/jaegertracing/jaeger/plugin/storage/mongodb/spanstore"
+	"github.com/jaegertracing/jaeger/storage/dependencystore"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+// Factory implements storage.Factory for MongoDB backend.
+type Factory struct {
+	Options *Options
+
+	metricsFactory metrics.Factory
+	logger         *zap.Logger
+	collections    mongoSpanStore.Collections
+	disconnect     func() error
+
+	// connect connects to MongoDB and returns the collections and the function disconnecting, it is replaced by the tests
+	connect func(options *Options) (mongoSpanStore.Collections, func() error, error)
+}
+
+// NewFactory creates a new Factory.
+func NewFactory() *Factory {
+	return &Factory{
+		Options: NewOptions(),
+		connect: connect,
+	}
+}
+
+func connect(opts *Options) (mongoSpanStore.Collections, func() error, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(opts.URI).SetConnectTimeout(opts.Timeout))
+	if err != nil {
+		return mongoSpanStore.Collections{}, nil, err
+	}
+	disconnect := func() error {
+		return client.Disconnect(context.Background())
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		disconnect()
+		return mongoSpanStore.Collections{}, nil, err
+	}
+	database := client.Database(opts.Database)
+	return mongoSpanStore.Collections{
+		Spans:      mongoSpanStore.NewCollection(database.Collection(opts.SpansCollection)),
+		Operations: mongoSpanStore.NewCollection(database.Collection(opts.OperationsCollection)),
+	}, disconnect, nil
+}
+
+// AddFlags implements plugin.Configurable
+func (f *Factory) AddFlags(flagSet *flag.FlagSet) {
+	f.Options.AddFlags(flagSet)
+}
+
+// InitFromViper implements plugin.Configurable
+func (f *Factory) InitFromViper(v *viper.Viper) {
+	f.Options.InitFromViper(v)
+}
+
+// Initialize implements storage.Factory
+func (f *Factory) Initialize(metricsFactory metrics.Factory, logger *zap.Logger) error {
+	f.metricsFactory, f.logger = metricsFactory, logger
+	collections, disconnect, err := f.connect(f.Options)
+	if err != nil {
+		return err
+	}
+	if f.Options.CreateIndexes {
+		ctx, cancel := context.WithTimeout(context.Background(), f.Options.Timeout)
+		defer cancel()
+		if err := mongoSpanStore.CreateIndexes(ctx, collections, f.Options.TTL); err != nil {
+			disconnect()
+			return err
+		}
+	}
+	f.collections, f.disconnect = collections, disconnect
+	return nil
+}
+
+// CreateSpanReader implements storage.Factory
+func (f *Factory) CreateSpanReader() (spanstore.Reader, error) {
+	return mongoSpanStore.NewSpanReader(f.collections), nil
+}
+
+// CreateSpanWriter implements storage.Factory
+func (f *Factory) CreateSpanWriter() (spanstore.Writer, error) {
+	return mongoSpanStore.NewSpanWriter(mongoSpanStore.SpanWriterParams{
+		Collections:    f.collections,
+		MetricsFactory: f.metricsFactory,
+		TTL:            f.Options.TTL,
+	}), nil
+}
+
+// CreateDependencyReader implements storage.Factory
+func (f *Factory) CreateDependencyReader() (dependencystore.Reader, error) {
+	return mongoDepStore.NewDependencyStore(f.collections.Spans), nil
+}
+
+// Close implements io.Closer and disconnects from MongoDB.
+func (f *Factory) Close() error {
+	if f.disconnect == nil {
+		return nil
+	}
+	return f.disconnect()
+}
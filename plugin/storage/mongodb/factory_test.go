@@ -0,0 +1,110 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/pkg/config"
+	mongoSpanStore "github.com/jaegertracing/jaeger/plugin/storage/mongodb/spanstore"
+	"github.com/jaegertracing/jaeger/storage"
+)
+
+var _ storage.Factory = new(Factory)
+
+type fakeCollection struct {
+	mongoSpanStore.Collection
+	indexes [][]mongo.IndexModel
+	err     error
+}
+
+func (c *fakeCollection) CreateIndexes(ctx context.Context, indexes []mongo.IndexModel) error {
+	c.indexes = append(c.indexes, indexes)
+	return c.err
+}
+
+// newTestFactory returns a factory connecting to the collections, and counting its disconnections.
+func newTestFactory(t *testing.T, collections mongoSpanStore.Collections, flags ...string) (*Factory, *int) {
+	f := NewFactory()
+	v, command := config.Viperize(f.AddFlags)
+	command.ParseFlags(flags)
+	f.InitFromViper(v)
+	disconnections := new(int)
+	f.connect = func(options *Options) (mongoSpanStore.Collections, func() error, error) {
+		assert.Equal(t, f.Options, options)
+		return collections, func() error {
+			*disconnections++
+			return nil
+		}, nil
+	}
+	return f, disconnections
+}
+
+func TestFactory(t *testing.T) {
+	spans, operations := &fakeCollection{}, &fakeCollection{}
+	f, disconnections := newTestFactory(t, mongoSpanStore.Collections{Spans: spans, Operations: operations})
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	assert.Len(t, spans.indexes, 1)
+	assert.Len(t, operations.indexes, 1)
+
+	_, err := f.CreateSpanReader()
+	require.NoError(t, err)
+	_, err = f.CreateSpanWriter()
+	require.NoError(t, err)
+	_, err = f.CreateDependencyReader()
+	require.NoError(t, err)
+
+	require.NoError(t, f.Close())
+	assert.Equal(t, 1, *disconnections)
+}
+
+func TestFactoryWithoutIndexesCreation(t *testing.T) {
+	spans, operations := &fakeCollection{}, &fakeCollection{}
+	f, _ := newTestFactory(t, mongoSpanStore.Collections{Spans: spans, Operations: operations}, "--mongodb.create-indexes=false")
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	assert.Empty(t, spans.indexes)
+}
+
+func TestFactoryInitializeErrors(t *testing.T) {
+	f, disconnections := newTestFactory(t, mongoSpanStore.Collections{Spans: &fakeCollection{err: assert.AnError}, Operations: &fakeCollection{}})
+	assert.Error(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	assert.Equal(t, 1, *disconnections)
+	// nothing is left to disconnect
+	require.NoError(t, f.Close())
+	assert.Equal(t, 1, *disconnections)
+
+	f, _ = newTestFactory(t, mongoSpanStore.Collections{})
+	f.connect = func(options *Options) (mongoSpanStore.Collections, func() error, error) {
+		return mongoSpanStore.Collections{}, nil, assert.AnError
+	}
+	assert.Equal(t, assert.AnError, f.Initialize(metrics.NullFactory, zap.NewNop()))
+}
+
+func TestConnectErrors(t *testing.T) {
+	_, _, err := connect(&Options{URI: "invalid", Timeout: time.Second})
+	assert.Error(t, err)
+
+	// nothing listens on the port
+	_, _, err = connect(&Options{URI: "mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=100", Timeout: time.Second})
+	assert.Error(t, err)
+}
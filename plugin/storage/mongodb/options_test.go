@@ -0,0 +1,55 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongodb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jaegertracing/jaeger/pkg/config"
+)
+
+func TestDefaultOptions(t *testing.T) {
+	opts := NewOptions()
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags(nil)
+	opts.InitFromViper(v)
+	assert.Equal(t, NewOptions(), opts)
+}
+
+func TestOptionsWithFlags(t *testing.T) {
+	opts := NewOptions()
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{
+		"--mongodb.uri=mongodb://host1,host2/?replicaSet=rs0",
+		"--mongodb.database=tracing",
+		"--mongodb.spans-collection=jaeger_spans",
+		"--mongodb.operations-collection=jaeger_operations",
+		"--mongodb.create-indexes=false",
+		"--mongodb.timeout=5s",
+		"--mongodb.ttl=24h",
+	})
+	opts.InitFromViper(v)
+	assert.Equal(t, &Options{
+		URI:                  "mongodb://host1,host2/?replicaSet=rs0",
+		Database:             "tracing",
+		SpansCollection:      "jaeger_spans",
+		OperationsCollection: "jaeger_operations",
+		Timeout:              5 * time.Second,
+		TTL:                  24 * time.Hour,
+	}, opts)
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collection is the subset of the operations of a collection of MongoDB used by the stores,
+// it is implemented over *mongo.Collection by NewCollection and faked by the tests.
+type Collection interface {
+	// Upsert replaces the document of the ID, or inserts it if it does not exist.
+	Upsert(ctx context.Context, id string, document interface{}) error
+	// Find decodes the documents matching the filter into the slice pointed to by results.
+	Find(ctx context.Context, filter interface{}, opts *options.FindOptions, results interface{}) error
+	// Distinct returns the distinct values of the field in the documents matching the filter.
+	Distinct(ctx context.Context, field string, filter interface{}) ([]interface{}, error)
+	// Aggregate decodes the documents returned by the pipeline into the slice pointed to by results.
+	Aggregate(ctx context.Context, pipeline interface{}, results interface{}) error
+	// CreateIndexes creates the indexes which do not exist.
+	CreateIndexes(ctx context.Context, indexes []mongo.IndexModel) error
+}
+
+// Collections are the collection of the spans and the collection of the operations.
+//
+// The spans are found by trace ID, and searched with the compound indexes of the service, of the operation
+// and of the tags sorted by start time. The documents of both collections expire with the TTL indexes.
+type Collections struct {
+	Spans      Collection
+	Operations Collection
+}
+
+type collection struct {
+	coll *mongo.Collection
+}
+
+// NewCollection returns the Collection over the collection of MongoDB.
+func NewCollection(coll *mongo.Collection) Collection {
+	return collection{coll: coll}
+}
+
+func (c collection) Upsert(ctx context.Context, id string, document interface{}) error {
+	_, err := c.coll.ReplaceOne(ctx, bson.M{"_id": id}, document, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (c collection) Find(ctx context.Context, filter interface{}, opts *options.FindOptions, results interface{}) error {
+	cursor, err := c.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, results)
+}
+
+func (c collection) Distinct(ctx context.Context, field string, filter interface{}) ([]interface{}, error) {
+	return c.coll.Distinct(ctx, field, filter)
+}
+
+func (c collection) Aggregate(ctx context.Context, pipeline interface{}, results interface{}) error {
+	cursor, err := c.coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, results)
+}
+
+func (c collection) CreateIndexes(ctx context.Context, indexes []mongo.IndexModel) error {
+	_, err := c.coll.Indexes().CreateMany(ctx, indexes)
+	return err
+}
+
+// CreateIndexes creates the indexes of the searches and, if the TTL is set, the TTL indexes expiring
+// the spans after the TTL since their start and the operations after the TTL since their last span.
+// MongoDB rejects the TTL indexes of another TTL, they must be dropped to change the TTL.
+func CreateIndexes(ctx context.Context, collections Collections, ttl time.Duration) error {
+	spanIndexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "traceID", Value: 1}}},
+		{Keys: bson.D{{Key: "service", Value: 1}, {Key: "startTime", Value: -1}}},
+		{Keys: bson.D{{Key: "service", Value: 1}, {Key: "operation", Value: 1}, {Key: "startTime", Value: -1}}},
+		{Keys: bson.D{{Key: "service", Value: 1}, {Key: "tags", Value: 1}, {Key: "startTime", Value: -1}}},
+	}
+	operationIndexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "service", Value: 1}, {Key: "operation", Value: 1}}},
+	}
+	if ttl > 0 {
+		expireAfter := options.Index().SetExpireAfterSeconds(int32(ttl / time.Second))
+		spanIndexes = append(spanIndexes, mongo.IndexModel{Keys: bson.D{{Key: "startTime", Value: 1}}, Options: expireAfter})
+		operationIndexes = append(operationIndexes, mongo.IndexModel{Keys: bson.D{{Key: "lastSeen", Value: 1}}, Options: expireAfter})
+	}
+	if err := collections.Spans.CreateIndexes(ctx, spanIndexes); err != nil {
+		return fmt.Errorf("cannot create the indexes of the spans: %w", err)
+	}
+	if err := collections.Operations.CreateIndexes(ctx, operationIndexes); err != nil {
+		return fmt.Errorf("cannot create the indexes of the operations: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCreateIndexes(t *testing.T) {
+	spans, operations := &fakeCollection{}, &fakeCollection{}
+	require.NoError(t, CreateIndexes(context.Background(), Collections{Spans: spans, Operations: operations}, 48*time.Hour))
+
+	require.Len(t, spans.indexes, 1)
+	require.Len(t, spans.indexes[0], 5)
+	assert.Equal(t, bson.D{{Key: "service", Value: 1}, {Key: "tags", Value: 1}, {Key: "startTime", Value: -1}}, spans.indexes[0][3].Keys)
+	assert.Equal(t, bson.D{{Key: "startTime", Value: 1}}, spans.indexes[0][4].Keys)
+	assert.Equal(t, int32(48*3600), *spans.indexes[0][4].Options.ExpireAfterSeconds)
+	require.Len(t, operations.indexes, 1)
+	require.Len(t, operations.indexes[0], 2)
+	assert.Equal(t, bson.D{{Key: "lastSeen", Value: 1}}, operations.indexes[0][1].Keys)
+	assert.Equal(t, int32(48*3600), *operations.indexes[0][1].Options.ExpireAfterSeconds)
+}
+
+func TestCreateIndexesWithoutTTL(t *testing.T) {
+	spans, operations := &fakeCollection{}, &fakeCollection{}
+	require.NoError(t, CreateIndexes(context.Background(), Collections{Spans: spans, Operations: operations}, 0))
+	assert.Len(t, spans.indexes[0], 4)
+	assert.Len(t, operations.indexes[0], 1)
+}
+
+func TestCreateIndexesErrors(t *testing.T) {
+	err := CreateIndexes(context.Background(), Collections{Spans: &fakeCollection{err: assert.AnError}, Operations: &fakeCollection{}}, 0)
+	assert.EqualError(t, err, "cannot create the indexes of the spans: "+assert.AnError.Error())
+
+	err = CreateIndexes(context.Background(), Collections{Spans: &fakeCollection{}, Operations: &fakeCollection{err: assert.AnError}}, 0)
+	assert.EqualError(t, err, "cannot create the indexes of the operations: "+assert.AnError.Error())
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+// spanDocument is the document of a span, with the fields searched beside the span in protobuf.
+type spanDocument struct {
+	ID           string    `bson:"_id"`
+	TraceID      string    `bson:"traceID"`
+	SpanID       string    `bson:"spanID"`
+	ParentSpanID string    `bson:"parentSpanID,omitempty"`
+	Service      string    `bson:"service,omitempty"`
+	Operation    string    `bson:"operation"`
+	StartTime    time.Time `bson:"startTime"`
+	Duration     int64     `bson:"duration"`
+	Tags         []string  `bson:"tags,omitempty"`
+	Span         []byte    `bson:"span"`
+}
+
+// operationDocument is the document of an operation of a service, lastSeen is the time of its last span written.
+type operationDocument struct {
+	ID        string    `bson:"_id"`
+	Service   string    `bson:"service"`
+	Operation string    `bson:"operation"`
+	SpanKind  string    `bson:"spanKind"`
+	LastSeen  time.Time `bson:"lastSeen"`
+}
+
+// traceIDString returns the trace ID always on 32 hexadecimal digits, unlike TraceID.String.
+func traceIDString(traceID model.TraceID) string {
+	return fmt.Sprintf("%016x%016x", traceID.High, traceID.Low)
+}
+
+func tagValue(key, value string) string {
+	return key + "=" + value
+}
+
+func fromDomain(span *model.Span) (spanDocument, error) {
+	data, err := proto.Marshal(span)
+	if err != nil {
+		return spanDocument{}, err
+	}
+	// the spans sharing their ID, e.g. the client and the server spans of Zipkin, are told apart by their hash
+	hash := fnv.New64a()
+	hash.Write(data)
+	traceID := traceIDString(span.TraceID)
+	document := spanDocument{
+		ID:        fmt.Sprintf("%s-%s-%016x", traceID, span.SpanID, hash.Sum64()),
+		TraceID:   traceID,
+		SpanID:    span.SpanID.String(),
+		Operation: span.OperationName,
+		StartTime: span.StartTime,
+		Duration:  int64(span.Duration / time.Microsecond),
+		Span:      data,
+	}
+	if parentID := span.ParentSpanID(); parentID != 0 {
+		document.ParentSpanID = parentID.String()
+	}
+	if span.Process != nil {
+		document.Service = span.Process.ServiceName
+	}
+	tags := make(map[string]struct{})
+	addTags := func(kvs model.KeyValues) {
+		for _, kv := range kvs {
+			tags[tagValue(kv.Key, kv.AsString())] = struct{}{}
+		}
+	}
+	addTags(span.Tags)
+	if span.Process != nil {
+		addTags(span.Process.Tags)
+	}
+	for _, log := range span.Logs {
+		addTags(log.Fields)
+	}
+	for tag := range tags {
+		document.Tags = append(document.Tags, tag)
+	}
+	sort.Strings(document.Tags)
+	return document, nil
+}
+
+func toDomain(data []byte) (*model.Span, error) {
+	span := &model.Span{}
+	if err := proto.Unmarshal(data, span); err != nil {
+		return nil, fmt.Errorf("cannot decode the span: %w", err)
+	}
+	return span, nil
+}
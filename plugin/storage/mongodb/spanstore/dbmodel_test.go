@@ -0,0 +1,82 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+var testTime = time.Date(2020, 9, 13, 12, 26, 40, 123000000, time.UTC)
+
+func testSpan(traceID model.TraceID, spanID model.SpanID, service string) *model.Span {
+	return &model.Span{
+		TraceID:       traceID,
+		SpanID:        spanID,
+		OperationName: "op",
+		References:    []model.SpanRef{model.NewChildOfRef(traceID, 1)},
+		StartTime:     testTime,
+		Duration:      1500 * time.Microsecond,
+		Tags:          model.KeyValues{model.String("span.kind", "server"), model.Int64("http.status_code", 200)},
+		Process:       model.NewProcess(service, []model.KeyValue{model.String("hostname", "host1")}),
+		Logs: []model.Log{{
+			Timestamp: testTime,
+			Fields:    model.KeyValues{model.String("event", "retry"), model.String("span.kind", "server")},
+		}},
+	}
+}
+
+func TestFromDomain(t *testing.T) {
+	span := testSpan(model.NewTraceID(1, 0x10), 2, "svc")
+	document, err := fromDomain(span)
+	require.NoError(t, err)
+	assert.Regexp(t, "^00000000000000010000000000000010-0000000000000002-[0-9a-f]{16}$", document.ID)
+	assert.Equal(t, "00000000000000010000000000000010", document.TraceID)
+	assert.Equal(t, "0000000000000002", document.SpanID)
+	assert.Equal(t, "0000000000000001", document.ParentSpanID)
+	assert.Equal(t, "svc", document.Service)
+	assert.Equal(t, "op", document.Operation)
+	assert.Equal(t, testTime, document.StartTime)
+	assert.Equal(t, int64(1500), document.Duration)
+	assert.Equal(t, []string{"event=retry", "hostname=host1", "http.status_code=200", "span.kind=server"}, document.Tags)
+
+	decoded, err := toDomain(document.Span)
+	require.NoError(t, err)
+	assert.Equal(t, span, decoded)
+
+	// the ID of the spans sharing their ID differs
+	span.Process.ServiceName = "other"
+	other, err := fromDomain(span)
+	require.NoError(t, err)
+	assert.NotEqual(t, document.ID, other.ID)
+}
+
+func TestFromDomainRootSpanWithoutProcess(t *testing.T) {
+	document, err := fromDomain(&model.Span{TraceID: model.NewTraceID(0, 1), SpanID: 1, StartTime: testTime})
+	require.NoError(t, err)
+	assert.Empty(t, document.ParentSpanID)
+	assert.Empty(t, document.Service)
+	assert.Empty(t, document.Tags)
+}
+
+func TestToDomainError(t *testing.T) {
+	_, err := toDomain([]byte("\xff"))
+	assert.Error(t, err)
+}
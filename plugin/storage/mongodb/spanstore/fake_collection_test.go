@@ -0,0 +1,82 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type upsert struct {
+	id       string
+	document interface{}
+}
+
+// fakeCollection records the operations and returns the documents of the finds and of the aggregations in order.
+type fakeCollection struct {
+	upserts     []upsert
+	filters     []interface{}
+	findOptions []*options.FindOptions
+	pipelines   []interface{}
+	indexes     [][]mongo.IndexModel
+	distinct    []interface{}
+	results     [][]bson.M
+	err         error
+}
+
+func (c *fakeCollection) Upsert(ctx context.Context, id string, document interface{}) error {
+	c.upserts = append(c.upserts, upsert{id: id, document: document})
+	return c.err
+}
+
+func (c *fakeCollection) Find(ctx context.Context, filter interface{}, opts *options.FindOptions, results interface{}) error {
+	c.filters = append(c.filters, filter)
+	c.findOptions = append(c.findOptions, opts)
+	return c.decode(results)
+}
+
+func (c *fakeCollection) Distinct(ctx context.Context, field string, filter interface{}) ([]interface{}, error) {
+	c.filters = append(c.filters, filter)
+	return c.distinct, c.err
+}
+
+func (c *fakeCollection) Aggregate(ctx context.Context, pipeline interface{}, results interface{}) error {
+	c.pipelines = append(c.pipelines, pipeline)
+	return c.decode(results)
+}
+
+func (c *fakeCollection) CreateIndexes(ctx context.Context, indexes []mongo.IndexModel) error {
+	c.indexes = append(c.indexes, indexes)
+	return c.err
+}
+
+// decode decodes the next documents into results through BSON, as the cursors of MongoDB do.
+func (c *fakeCollection) decode(results interface{}) error {
+	if c.err != nil {
+		return c.err
+	}
+	var documents []bson.M
+	if len(c.results) > 0 {
+		documents, c.results = c.results[0], c.results[1:]
+	}
+	data, err := bson.Marshal(bson.M{"documents": documents})
+	if err != nil {
+		return err
+	}
+	return bson.Raw(data).Lookup("documents").Unmarshal(results)
+}
@@ -0,0 +1,255 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+const defaultNumTraces = 100
+
+var (
+	// ErrServiceNameNotSet occurs when attempting to query with an empty service name
+	ErrServiceNameNotSet = errors.New("service Name must be set")
+
+	// ErrMalformedRequestObject occurs when a request object is nil
+	ErrMalformedRequestObject = errors.New("malformed request object")
+
+	// ErrStartAndEndTimeNotSet occurs when start time and end time are not set
+	ErrStartAndEndTimeNotSet = errors.New("start and End Time must be set")
+
+	// ErrStartTimeMinGreaterThanMax occurs when start time min is above start time max
+	ErrStartTimeMinGreaterThanMax = errors.New("start Time Minimum is above Maximum")
+
+	// ErrDurationMinGreaterThanMax occurs when duration min is above duration max
+	ErrDurationMinGreaterThanMax = errors.New("duration Minimum is above Maximum")
+)
+
+// SpanReader can query for and load traces from MongoDB.
+type SpanReader struct {
+	collections Collections
+}
+
+// NewSpanReader returns a new SpanReader.
+func NewSpanReader(collections Collections) *SpanReader {
+	return &SpanReader{collections: collections}
+}
+
+type traceSpan struct {
+	TraceID string `bson:"traceID"`
+	Span    []byte `bson:"span"`
+}
+
+// GetTrace takes a traceID and returns a Trace associated with that traceID
+func (r *SpanReader) GetTrace(ctx context.Context, traceID model.TraceID) (*model.Trace, error) {
+	traces, err := r.getTraces(ctx, []string{traceIDString(traceID)})
+	if err != nil {
+		return nil, err
+	}
+	if len(traces) == 0 {
+		return nil, spanstore.ErrTraceNotFound
+	}
+	return traces[0], nil
+}
+
+// getTraces returns the traces of the IDs in their order, skipping the traces not found.
+func (r *SpanReader) getTraces(ctx context.Context, traceIDs []string) ([]*model.Trace, error) {
+	filter := bson.D{{Key: "traceID", Value: bson.D{{Key: "$in", Value: traceIDs}}}}
+	var documents []traceSpan
+	opts := options.Find().SetProjection(bson.D{{Key: "traceID", Value: 1}, {Key: "span", Value: 1}})
+	if err := r.collections.Spans.Find(ctx, filter, opts, &documents); err != nil {
+		return nil, err
+	}
+	found := make(map[string]*model.Trace)
+	for _, document := range documents {
+		span, err := toDomain(document.Span)
+		if err != nil {
+			return nil, err
+		}
+		trace, ok := found[document.TraceID]
+		if !ok {
+			trace = &model.Trace{}
+			found[document.TraceID] = trace
+		}
+		trace.Spans = append(trace.Spans, span)
+	}
+	traces := make([]*model.Trace, 0, len(found))
+	for _, traceID := range traceIDs {
+		if trace, ok := found[traceID]; ok {
+			traces = append(traces, trace)
+		}
+	}
+	return traces, nil
+}
+
+// GetServices returns all services traced by Jaeger
+func (r *SpanReader) GetServices(ctx context.Context) ([]string, error) {
+	values, err := r.collections.Operations.Distinct(ctx, "service", bson.D{})
+	if err != nil {
+		return nil, err
+	}
+	services := make([]string, 0, len(values))
+	for _, value := range values {
+		service, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid service %v", value)
+		}
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	return services, nil
+}
+
+// GetOperations returns all operations for a specific service traced by Jaeger
+func (r *SpanReader) GetOperations(ctx context.Context, query spanstore.OperationQueryParameters) ([]spanstore.Operation, error) {
+	filter := bson.D{{Key: "service", Value: query.ServiceName}}
+	if query.SpanKind != "" {
+		filter = append(filter, bson.E{Key: "spanKind", Value: query.SpanKind})
+	}
+	var documents []operationDocument
+	opts := options.Find().SetSort(bson.D{{Key: "operation", Value: 1}, {Key: "spanKind", Value: 1}})
+	if err := r.collections.Operations.Find(ctx, filter, opts, &documents); err != nil {
+		return nil, err
+	}
+	operations := make([]spanstore.Operation, 0, len(documents))
+	for _, document := range documents {
+		operations = append(operations, spanstore.Operation{Name: document.Operation, SpanKind: document.SpanKind})
+	}
+	return operations, nil
+}
+
+// FindTraces retrieves traces that match the traceQuery
+func (r *SpanReader) FindTraces(ctx context.Context, query *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
+	traceIDs, err := r.findTraceIDs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(traceIDs) == 0 {
+		return []*model.Trace{}, nil
+	}
+	// the traces expired since they were found are skipped
+	return r.getTraces(ctx, traceIDs)
+}
+
+// FindTraceIDs retrieves the IDs of the traces that match the traceQuery, the most recent first.
+// The spans are matched by service, operation, start time, duration and tag equalities with the compound
+// indexes, then grouped by trace. The other tag filters are left to the query service.
+func (r *SpanReader) FindTraceIDs(ctx context.Context, query *spanstore.TraceQueryParameters) ([]model.TraceID, error) {
+	ids, err := r.findTraceIDs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	traceIDs := make([]model.TraceID, 0, len(ids))
+	for _, id := range ids {
+		traceID, err := model.TraceIDFromString(id)
+		if err != nil {
+			return nil, err
+		}
+		traceIDs = append(traceIDs, traceID)
+	}
+	return traceIDs, nil
+}
+
+func (r *SpanReader) findTraceIDs(ctx context.Context, query *spanstore.TraceQueryParameters) ([]string, error) {
+	if err := validateQuery(query); err != nil {
+		return nil, err
+	}
+	filter := bson.D{{Key: "service", Value: query.ServiceName}}
+	if query.OperationName != "" {
+		filter = append(filter, bson.E{Key: "operation", Value: query.OperationName})
+	}
+	filter = append(filter, bson.E{Key: "startTime", Value: bson.D{
+		{Key: "$gte", Value: query.StartTimeMin},
+		{Key: "$lte", Value: query.StartTimeMax},
+	}})
+	var duration bson.D
+	if query.DurationMin != 0 {
+		duration = append(duration, bson.E{Key: "$gte", Value: int64(query.DurationMin / time.Microsecond)})
+	}
+	if query.DurationMax != 0 {
+		duration = append(duration, bson.E{Key: "$lte", Value: int64(query.DurationMax / time.Microsecond)})
+	}
+	if len(duration) > 0 {
+		filter = append(filter, bson.E{Key: "duration", Value: duration})
+	}
+	var tags []string
+	for key, value := range query.Tags {
+		tags = append(tags, tagValue(key, value))
+	}
+	for _, tagFilter := range query.TagFilters {
+		if tagFilter.Operator == spanstore.TagEquals {
+			tags = append(tags, tagValue(tagFilter.Key, tagFilter.Value))
+		}
+	}
+	if len(tags) > 0 {
+		// sorted for the queries to be deterministic
+		sort.Strings(tags)
+		filter = append(filter, bson.E{Key: "tags", Value: bson.D{{Key: "$all", Value: tags}}})
+	}
+	numTraces := query.NumTraces
+	if numTraces <= 0 {
+		numTraces = defaultNumTraces
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$traceID"},
+			{Key: "startTime", Value: bson.D{{Key: "$max", Value: "$startTime"}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "startTime", Value: -1}}}},
+		{{Key: "$limit", Value: numTraces}},
+	}
+	var documents []struct {
+		TraceID string `bson:"_id"`
+	}
+	if err := r.collections.Spans.Aggregate(ctx, pipeline, &documents); err != nil {
+		return nil, err
+	}
+	traceIDs := make([]string, 0, len(documents))
+	for _, document := range documents {
+		traceIDs = append(traceIDs, document.TraceID)
+	}
+	return traceIDs, nil
+}
+
+func validateQuery(p *spanstore.TraceQueryParameters) error {
+	if p == nil {
+		return ErrMalformedRequestObject
+	}
+	if p.ServiceName == "" {
+		return ErrServiceNameNotSet
+	}
+	if p.StartTimeMin.IsZero() || p.StartTimeMax.IsZero() {
+		return ErrStartAndEndTimeNotSet
+	}
+	if p.StartTimeMax.Before(p.StartTimeMin) {
+		return ErrStartTimeMinGreaterThanMax
+	}
+	if p.DurationMin != 0 && p.DurationMax != 0 && p.DurationMin > p.DurationMax {
+		return ErrDurationMinGreaterThanMax
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+func spanResult(t *testing.T, span *model.Span) bson.M {
+	document, err := fromDomain(span)
+	require.NoError(t, err)
+	return bson.M{"traceID": document.TraceID, "span": document.Span}
+}
+
+func TestGetTrace(t *testing.T) {
+	traceID := model.NewTraceID(0, 1)
+	spans := &fakeCollection{results: [][]bson.M{
+		{spanResult(t, testSpan(traceID, 1, "svc")), spanResult(t, testSpan(traceID, 2, "svc"))},
+		nil,
+	}}
+	reader := NewSpanReader(Collections{Spans: spans})
+
+	trace, err := reader.GetTrace(context.Background(), traceID)
+	require.NoError(t, err)
+	require.Len(t, trace.Spans, 2)
+	assert.Equal(t, model.SpanID(2), trace.Spans[1].SpanID)
+	assert.Equal(t, bson.D{{Key: "traceID", Value: bson.D{{Key: "$in", Value: []string{"00000000000000000000000000000001"}}}}}, spans.filters[0])
+
+	_, err = reader.GetTrace(context.Background(), model.NewTraceID(0, 2))
+	assert.Equal(t, spanstore.ErrTraceNotFound, err)
+}
+
+func TestGetTraceErrors(t *testing.T) {
+	reader := NewSpanReader(Collections{Spans: &fakeCollection{err: assert.AnError}})
+	_, err := reader.GetTrace(context.Background(), model.NewTraceID(0, 1))
+	assert.Equal(t, assert.AnError, err)
+
+	reader = NewSpanReader(Collections{Spans: &fakeCollection{results: [][]bson.M{
+		{{"traceID": "00000000000000000000000000000001", "span": []byte("\xff")}},
+	}}})
+	_, err = reader.GetTrace(context.Background(), model.NewTraceID(0, 1))
+	assert.Error(t, err)
+}
+
+func TestGetServices(t *testing.T) {
+	reader := NewSpanReader(Collections{Operations: &fakeCollection{distinct: []interface{}{"svc2", "svc1"}}})
+	services, err := reader.GetServices(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"svc1", "svc2"}, services)
+
+	reader = NewSpanReader(Collections{Operations: &fakeCollection{distinct: []interface{}{1}}})
+	_, err = reader.GetServices(context.Background())
+	assert.EqualError(t, err, "invalid service 1")
+
+	reader = NewSpanReader(Collections{Operations: &fakeCollection{err: assert.AnError}})
+	_, err = reader.GetServices(context.Background())
+	assert.Equal(t, assert.AnError, err)
+}
+
+func TestGetOperations(t *testing.T) {
+	operations := &fakeCollection{results: [][]bson.M{
+		{{"operation": "a", "spanKind": "server"}, {"operation": "b", "spanKind": "client"}},
+		{{"operation": "b", "spanKind": "client"}},
+		nil,
+	}}
+	reader := NewSpanReader(Collections{Operations: operations})
+
+	found, err := reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "svc"})
+	require.NoError(t, err)
+	assert.Equal(t, []spanstore.Operation{{Name: "a", SpanKind: "server"}, {Name: "b", SpanKind: "client"}}, found)
+	assert.Equal(t, bson.D{{Key: "service", Value: "svc"}}, operations.filters[0])
+	assert.Equal(t, bson.D{{Key: "operation", Value: 1}, {Key: "spanKind", Value: 1}}, operations.findOptions[0].Sort)
+
+	found, err = reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "svc", SpanKind: "client"})
+	require.NoError(t, err)
+	assert.Equal(t, []spanstore.Operation{{Name: "b", SpanKind: "client"}}, found)
+	assert.Equal(t, bson.D{{Key: "service", Value: "svc"}, {Key: "spanKind", Value: "client"}}, operations.filters[1])
+
+	found, err = reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "unknown"})
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	operations.err = assert.AnError
+	_, err = reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "svc"})
+	assert.Equal(t, assert.AnError, err)
+}
+
+func TestFindTraceIDs(t *testing.T) {
+	spans := &fakeCollection{results: [][]bson.M{
+		{{"_id": "00000000000000000000000000000002"}, {"_id": "00000000000000010000000000000001"}},
+	}}
+	reader := NewSpanReader(Collections{Spans: spans})
+	traceIDs, err := reader.FindTraceIDs(context.Background(), &spanstore.TraceQueryParameters{
+		ServiceName:   "svc",
+		OperationName: "op",
+		Tags:          map[string]string{"k2": "v2"},
+		TagFilters: []spanstore.TagFilter{
+			{Key: "k1", Operator: spanstore.TagEquals, Value: "v1"},
+			{Key: "k3", Operator: spanstore.TagNotEquals, Value: "v3"},
+		},
+		StartTimeMin: testTime,
+		StartTimeMax: testTime.Add(time.Hour),
+		DurationMin:  time.Millisecond,
+		DurationMax:  time.Second,
+		NumTraces:    10,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []model.TraceID{model.NewTraceID(0, 2), model.NewTraceID(1, 1)}, traceIDs)
+	assert.Equal(t, mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "service", Value: "svc"},
+			{Key: "operation", Value: "op"},
+			{Key: "startTime", Value: bson.D{{Key: "$gte", Value: testTime}, {Key: "$lte", Value: testTime.Add(time.Hour)}}},
+			{Key: "duration", Value: bson.D{{Key: "$gte", Value: int64(1000)}, {Key: "$lte", Value: int64(1000000)}}},
+			{Key: "tags", Value: bson.D{{Key: "$all", Value: []string{"k1=v1", "k2=v2"}}}},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$traceID"},
+			{Key: "startTime", Value: bson.D{{Key: "$max", Value: "$startTime"}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "startTime", Value: -1}}}},
+		{{Key: "$limit", Value: 10}},
+	}, spans.pipelines[0])
+}
+
+func TestFindTraceIDsDefaults(t *testing.T) {
+	spans := &fakeCollection{}
+	reader := NewSpanReader(Collections{Spans: spans})
+	traceIDs, err := reader.FindTraceIDs(context.Background(), &spanstore.TraceQueryParameters{
+		ServiceName:  "svc",
+		StartTimeMin: testTime,
+		StartTimeMax: testTime,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, traceIDs)
+	pipeline := spans.pipelines[0].(mongo.Pipeline)
+	assert.Equal(t, bson.D{
+		{Key: "service", Value: "svc"},
+		{Key: "startTime", Value: bson.D{{Key: "$gte", Value: testTime}, {Key: "$lte", Value: testTime}}},
+	}, pipeline[0][0].Value)
+	assert.Equal(t, bson.D{{Key: "$limit", Value: defaultNumTraces}}, pipeline[3])
+}
+
+func TestFindTraces(t *testing.T) {
+	spans := &fakeCollection{results: [][]bson.M{
+		{{"_id": "00000000000000000000000000000002"}, {"_id": "00000000000000000000000000000003"}, {"_id": "00000000000000000000000000000001"}},
+		// the second trace expired since it was found
+		{
+			spanResult(t, testSpan(model.NewTraceID(0, 1), 1, "svc")),
+			spanResult(t, testSpan(model.NewTraceID(0, 2), 1, "svc")),
+			spanResult(t, testSpan(model.NewTraceID(0, 1), 2, "svc")),
+		},
+	}}
+	reader := NewSpanReader(Collections{Spans: spans})
+	traces, err := reader.FindTraces(context.Background(), &spanstore.TraceQueryParameters{
+		ServiceName:  "svc",
+		StartTimeMin: testTime,
+		StartTimeMax: testTime,
+	})
+	require.NoError(t, err)
+	require.Len(t, traces, 2)
+	assert.Equal(t, model.NewTraceID(0, 2), traces[0].Spans[0].TraceID)
+	assert.Len(t, traces[0].Spans, 1)
+	assert.Equal(t, model.NewTraceID(0, 1), traces[1].Spans[0].TraceID)
+	assert.Len(t, traces[1].Spans, 2)
+
+	// no trace is read if none is found
+	traces, err = reader.FindTraces(context.Background(), &spanstore.TraceQueryParameters{
+		ServiceName:  "svc",
+		StartTimeMin: testTime,
+		StartTimeMax: testTime,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, traces)
+	assert.Len(t, spans.filters, 1)
+}
+
+func TestFindTracesErrors(t *testing.T) {
+	reader := NewSpanReader(Collections{Spans: &fakeCollection{}})
+	testCases := []struct {
+		query *spanstore.TraceQueryParameters
+		err   error
+	}{
+		{query: nil, err: ErrMalformedRequestObject},
+		{query: &spanstore.TraceQueryParameters{}, err: ErrServiceNameNotSet},
+		{query: &spanstore.TraceQueryParameters{ServiceName: "svc"}, err: ErrStartAndEndTimeNotSet},
+		{
+			query: &spanstore.TraceQueryParameters{ServiceName: "svc", StartTimeMin: testTime, StartTimeMax: testTime.Add(-time.Second)},
+			err:   ErrStartTimeMinGreaterThanMax,
+		},
+		{
+			query: &spanstore.TraceQueryParameters{ServiceName: "svc", StartTimeMin: testTime, StartTimeMax: testTime, DurationMin: 2, DurationMax: 1},
+			err:   ErrDurationMinGreaterThanMax,
+		},
+	}
+	for _, testCase := range testCases {
+		_, err := reader.FindTraces(context.Background(), testCase.query)
+		assert.Equal(t, testCase.err, err)
+		_, err = reader.FindTraceIDs(context.Background(), testCase.query)
+		assert.Equal(t, testCase.err, err)
+	}
+
+	query := &spanstore.TraceQueryParameters{ServiceName: "svc", StartTimeMin: testTime, StartTimeMax: testTime}
+	reader = NewSpanReader(Collections{Spans: &fakeCollection{err: assert.AnError}})
+	_, err := reader.FindTraces(context.Background(), query)
+	assert.Equal(t, assert.AnError, err)
+
+	reader = NewSpanReader(Collections{Spans: &fakeCollection{results: [][]bson.M{{{"_id": "invalid"}}}}})
+	_, err = reader.FindTraceIDs(context.Background(), query)
+	assert.Error(t, err)
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/cache"
+	storageMetrics "github.com/jaegertracing/jaeger/storage/spanstore/metrics"
+)
+
+// SpanWriterParams holds constructor parameters for NewSpanWriter
+type SpanWriterParams struct {
+	Collections    Collections
+	MetricsFactory metrics.Factory
+	// TTL is the retention of the spans, the operations are written again before it expires them.
+	TTL time.Duration
+}
+
+// SpanWriter writes the spans and the operations of their services into MongoDB.
+type SpanWriter struct {
+	collections Collections
+	// operations are the operations written recently, they are not written again until they are about to expire
+	operations cache.Cache
+	spans      *storageMetrics.WriteMetrics
+	// now is the time of the writes, it is replaced by the tests
+	now func() time.Time
+}
+
+// NewSpanWriter creates a SpanWriter.
+func NewSpanWriter(p SpanWriterParams) *SpanWriter {
+	operationsTTL := 12 * time.Hour
+	if p.TTL > 0 && p.TTL/2 < operationsTTL {
+		operationsTTL = p.TTL / 2
+	}
+	return &SpanWriter{
+		collections: p.Collections,
+		operations: cache.NewLRUWithOptions(100000, &cache.Options{
+			TTL: operationsTTL,
+		}),
+		spans: storageMetrics.NewWriteMetrics(p.MetricsFactory, "spans"),
+		now:   time.Now,
+	}
+}
+
+// WriteSpan writes the span, replacing the span written before with the same ID and content, then the
+// operation of the span if it was not written recently.
+func (w *SpanWriter) WriteSpan(span *model.Span) error {
+	document, err := fromDomain(span)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	err = w.collections.Spans.Upsert(context.Background(), document.ID, document)
+	w.spans.Emit(err, time.Since(start))
+	if err != nil {
+		return err
+	}
+	if document.Service == "" {
+		return nil
+	}
+	spanKind, _ := span.GetSpanKind()
+	operation := operationDocument{
+		ID:        document.Service + "#" + span.OperationName + "#" + spanKind,
+		Service:   document.Service,
+		Operation: span.OperationName,
+		SpanKind:  spanKind,
+		LastSeen:  w.now(),
+	}
+	if w.operations.Get(operation.ID) != nil {
+		return nil
+	}
+	if err := w.collections.Operations.Upsert(context.Background(), operation.ID, operation); err != nil {
+		return err
+	}
+	w.operations.Put(operation.ID, true)
+	return nil
+}
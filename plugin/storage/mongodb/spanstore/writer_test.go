@@ -0,0 +1,91 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+func newTestWriter(spans, operations *fakeCollection) (*SpanWriter, *metricstest.Factory) {
+	metricsFactory := metricstest.NewFactory(0)
+	writer := NewSpanWriter(SpanWriterParams{
+		Collections:    Collections{Spans: spans, Operations: operations},
+		MetricsFactory: metricsFactory,
+		TTL:            time.Hour,
+	})
+	writer.now = func() time.Time {
+		return testTime.Add(time.Minute)
+	}
+	return writer, metricsFactory
+}
+
+func TestWriteSpan(t *testing.T) {
+	spans, operations := &fakeCollection{}, &fakeCollection{}
+	writer, metricsFactory := newTestWriter(spans, operations)
+	traceID := model.NewTraceID(0, 1)
+
+	require.NoError(t, writer.WriteSpan(testSpan(traceID, 1, "svc")))
+	require.NoError(t, writer.WriteSpan(testSpan(traceID, 2, "svc")))
+
+	require.Len(t, spans.upserts, 2)
+	document := spans.upserts[1].document.(spanDocument)
+	assert.Equal(t, document.ID, spans.upserts[1].id)
+	assert.Equal(t, "00000000000000000000000000000001", document.TraceID)
+	assert.Equal(t, "0000000000000002", document.SpanID)
+	// the operation is written once while it is cached
+	assert.Equal(t, []upsert{{
+		id: "svc#op#server",
+		document: operationDocument{
+			ID:        "svc#op#server",
+			Service:   "svc",
+			Operation: "op",
+			SpanKind:  "server",
+			LastSeen:  testTime.Add(time.Minute),
+		},
+	}}, operations.upserts)
+
+	metricsFactory.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "spans.attempts", Value: 2},
+		metricstest.ExpectedMetric{Name: "spans.inserts", Value: 2})
+}
+
+func TestWriteSpanWithoutService(t *testing.T) {
+	spans, operations := &fakeCollection{}, &fakeCollection{}
+	writer, _ := newTestWriter(spans, operations)
+	require.NoError(t, writer.WriteSpan(&model.Span{TraceID: model.NewTraceID(0, 1), SpanID: 1}))
+	assert.Len(t, spans.upserts, 1)
+	assert.Empty(t, operations.upserts)
+}
+
+func TestWriteSpanErrors(t *testing.T) {
+	writer, metricsFactory := newTestWriter(&fakeCollection{err: assert.AnError}, &fakeCollection{})
+	assert.Equal(t, assert.AnError, writer.WriteSpan(testSpan(model.NewTraceID(0, 1), 1, "svc")))
+	metricsFactory.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "spans.errors", Value: 1})
+
+	operations := &fakeCollection{err: assert.AnError}
+	writer, _ = newTestWriter(&fakeCollection{}, operations)
+	assert.Equal(t, assert.AnError, writer.WriteSpan(testSpan(model.NewTraceID(0, 1), 1, "svc")))
+	// the operation is not cached after an error
+	operations.err = nil
+	require.NoError(t, writer.WriteSpan(testSpan(model.NewTraceID(0, 1), 1, "svc")))
+	assert.Len(t, operations.upserts, 2)
+}
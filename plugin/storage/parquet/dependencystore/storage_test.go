@@ -0,0 +1,89 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dependencystore
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/objectstore"
+	"github.com/jaegertracing/jaeger/plugin/storage/parquet/spanstore"
+)
+
+func TestGetDependencies(t *testing.T) {
+	dir, err := ioutil.TempDir("", "parquet")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	bucket := objectstore.NewFileBucket(dir)
+
+	now := time.Now()
+	traceID := model.NewTraceID(0, 1)
+	spans := []*model.Span{
+		{TraceID: traceID, SpanID: 1, StartTime: now.Add(-2 * time.Hour), Process: model.NewProcess("frontend", nil)},
+		{
+			TraceID: traceID, SpanID: 2, StartTime: now.Add(-2 * time.Hour), Process: model.NewProcess("frontend", nil),
+			References: []model.SpanRef{model.NewChildOfRef(traceID, 1)},
+		},
+		{
+			TraceID: traceID, SpanID: 3, StartTime: now.Add(-2 * time.Hour), Process: model.NewProcess("backend", nil),
+			References: []model.SpanRef{model.NewChildOfRef(traceID, 2)},
+		},
+		{
+			TraceID: traceID, SpanID: 4, StartTime: now.Add(-2 * time.Hour), Process: model.NewProcess("backend", nil),
+			References: []model.SpanRef{model.NewChildOfRef(traceID, 1)},
+		},
+		{
+			TraceID: traceID, SpanID: 5, StartTime: now.Add(-10 * time.Hour), Process: model.NewProcess("frontend", nil),
+			References: []model.SpanRef{model.NewChildOfRef(traceID, 3)},
+		},
+	}
+	writer := spanstore.NewSpanWriter(spanstore.SpanWriterParams{
+		Bucket:          bucket,
+		Logger:          zap.NewNop(),
+		MetricsFactory:  metrics.NullFactory,
+		MaxSpansPerFile: 100,
+		FlushInterval:   time.Hour,
+	})
+	for _, span := range spans {
+		require.NoError(t, writer.WriteSpan(span))
+	}
+	require.NoError(t, writer.Close())
+
+	reader := spanstore.NewSpanReader(spanstore.SpanReaderParams{Bucket: bucket, TraceLookback: time.Hour, MaxSearchWindow: time.Hour})
+	store := NewDependencyStore(reader, 6*time.Hour)
+	// the lookback is capped so the span of the backend calling the frontend is not read
+	dependencies, err := store.GetDependencies(now, 24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, []model.DependencyLink{{Parent: "frontend", Child: "backend", CallCount: 2}}, dependencies)
+
+	dependencies, err = store.GetDependencies(now, time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, dependencies)
+
+	// the index directory of the bucket cannot be listed
+	require.NoError(t, os.RemoveAll(dir))
+	require.NoError(t, os.Mkdir(dir, 0700))
+	require.NoError(t, ioutil.WriteFile(dir+"/index", []byte("invalid"), 0600))
+	_, err = store.GetDependencies(now, time.Hour)
+	assert.Error(t, err)
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parquet
+
+import (
+	"context"
+	"errors"
+	"flag"
+
+	"github.com/spf13/viper"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/pkg/objectstore"
+	pqDepStore "github.com/jaegertracing/jaeger/plugin/storage/parquet/dependencystore"
+	pqSpanStore "github.com/jaegertracing/jaeger/plugin/storage/parquet/spanstore"
+	"github.com/jaegertracing/jaeger/storage/dependencystore"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+// Factory implements storage.Factory for the Parquet files of an object storage.
+type Factory struct {
+	Options *Options
+
+	metricsFactory metrics.Factory
+	logger         *zap.Logger
+	bucket         objectstore.Bucket
+	writers        []*pqSpanStore.SpanWriter
+}
+
+// NewFactory creates a new Factory.
+func NewFactory() *Factory {
+	return &Factory{
+		Options: NewOptions(),
+	}
+}
+
+// AddFlags implements plugin.Configurable
+func (f *Factory) AddFlags(flagSet *flag.FlagSet) {
+	f.Options.AddFlags(flagSet)
+}
+
+// InitFromViper implements plugin.Configurable
+func (f *Factory) InitFromViper(v *viper.Viper) {
+	f.Options.InitFromViper(v)
+}
+
+// Initialize implements storage.Factory
+func (f *Factory) Initialize(metricsFactory metrics.Factory, logger *zap.Logger) error {
+	f.metricsFactory, f.logger = metricsFactory, logger
+	if f.Options.Bucket.URL == "" {
+		return errors.New("the URL of the bucket must be set")
+	}
+	bucket, err := f.Options.Bucket.NewBucket(context.Background())
+	if err != nil {
+		return err
+	}
+	f.bucket = bucket
+	return nil
+}
+
+// CreateSpanReader implements storage.Factory
+func (f *Factory) CreateSpanReader() (spanstore.Reader, error) {
+	return f.spanReader(), nil
+}
+
+func (f *Factory) spanReader() *pqSpanStore.SpanReader {
+	return pqSpanStore.NewSpanReader(pqSpanStore.SpanReaderParams{
+		Bucket:          f.bucket,
+		TraceLookback:   f.Options.TraceLookback,
+		MaxSearchWindow: f.Options.MaxSearchWindow,
+	})
+}
+
+// CreateSpanWriter implements storage.Factory
+func (f *Factory) CreateSpanWriter() (spanstore.Writer, error) {
+	writer := pqSpanStore.NewSpanWriter(pqSpanStore.SpanWriterParams{
+		Bucket:          f.bucket,
+		Logger:          f.logger,
+		MetricsFactory:  f.metricsFactory,
+		MaxSpansPerFile: f.Options.MaxSpansPerFile,
+		FlushInterval:   f.Options.FlushInterval,
+	})
+	f.writers = append(f.writers, writer)
+	return writer, nil
+}
+
+// CreateDependencyReader implements storage.Factory
+func (f *Factory) CreateDependencyReader() (dependencystore.Reader, error) {
+	return pqDepStore.NewDependencyStore(f.spanReader(), f.Options.MaxSearchWindow), nil
+}
+
+// Close implements io.Closer and writes the spans remaining in the buffers of the writers.
+func (f *Factory) Close() error {
+	var firstErr error
+	for _, writer := range f.writers {
+		if err := writer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	f.writers = nil
+	return firstErr
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parquet
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/config"
+	"github.com/jaegertracing/jaeger/storage"
+)
+
+var _ storage.Factory = new(Factory)
+
+func newTestFactory(flags ...string) *Factory {
+	f := NewFactory()
+	v, command := config.Viperize(f.AddFlags)
+	command.ParseFlags(flags)
+	f.InitFromViper(v)
+	return f
+}
+
+func TestFactory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "parquet")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	f := newTestFactory("--parquet.bucket-url=file://" + dir)
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	writer, err := f.CreateSpanWriter()
+	require.NoError(t, err)
+	traceID := model.NewTraceID(0, 1)
+	require.NoError(t, writer.WriteSpan(&model.Span{
+		TraceID:   traceID,
+		SpanID:    1,
+		StartTime: time.Now(),
+		Process:   model.NewProcess("frontend", nil),
+	}))
+	// the spans are written when the factory is closed
+	require.NoError(t, f.Close())
+
+	reader, err := f.CreateSpanReader()
+	require.NoError(t, err)
+	trace, err := reader.GetTrace(context.Background(), traceID)
+	require.NoError(t, err)
+	assert.Len(t, trace.Spans, 1)
+
+	dependencyReader, err := f.CreateDependencyReader()
+	require.NoError(t, err)
+	dependencies, err := dependencyReader.GetDependencies(time.Now(), time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, dependencies)
+}
+
+func TestFactoryInitializeErrors(t *testing.T) {
+	f := NewFactory()
+	assert.EqualError(t, f.Initialize(metrics.NullFactory, zap.NewNop()), "the URL of the bucket must be set")
+	assert.NoError(t, f.Close())
+
+	f = newTestFactory("--parquet.bucket-url=ftp://bucket/jaeger")
+	assert.EqualError(t, f.Initialize(metrics.NullFactory, zap.NewNop()),
+		`unsupported scheme "ftp" of the URL of the bucket, the schemes are s3, gs and file`)
+}
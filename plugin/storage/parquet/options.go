@@ -0,0 +1,89 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parquet
+
+import (
+	"flag"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/jaegertracing/jaeger/pkg/objectstore/config"
+)
+
+const (
+	namespace = "parquet"
+
+	suffixBucketURL          = ".bucket-url"
+	suffixS3Region           = ".s3.region"
+	suffixS3Endpoint         = ".s3.endpoint"
+	suffixGCSCredentialsFile = ".gcs.credentials-file"
+	suffixMaxSpansPerFile    = ".max-spans-per-file"
+	suffixFlushInterval      = ".flush-interval"
+	suffixTraceLookback      = ".trace-lookback"
+	suffixMaxSearchWindow    = ".max-search-window"
+
+	defaultMaxSpansPerFile = 100000
+	defaultFlushInterval   = time.Minute
+	defaultTraceLookback   = 72 * time.Hour
+	defaultMaxSearchWindow = 24 * time.Hour
+)
+
+// Options contains the Parquet storage configs and provides the ability
+// to bind them to command line flags
+type Options struct {
+	Bucket config.Configuration `mapstructure:"bucket"`
+	// MaxSpansPerFile is the number of spans buffered before the files are written, the larger files compress better.
+	MaxSpansPerFile int           `mapstructure:"max_spans_per_file"`
+	FlushInterval   time.Duration `mapstructure:"flush_interval"`
+	// TraceLookback is how far back the traces are looked for by ID, and the services and operations listed.
+	TraceLookback time.Duration `mapstructure:"trace_lookback"`
+	// MaxSearchWindow bounds the time range of the searches, hence the number of files read.
+	MaxSearchWindow time.Duration `mapstructure:"max_search_window"`
+}
+
+// NewOptions creates the Options with the default configuration.
+func NewOptions() *Options {
+	return &Options{
+		MaxSpansPerFile: defaultMaxSpansPerFile,
+		FlushInterval:   defaultFlushInterval,
+		TraceLookback:   defaultTraceLookback,
+		MaxSearchWindow: defaultMaxSearchWindow,
+	}
+}
+
+// AddFlags adds flags for Options
+func (opt *Options) AddFlags(flagSet *flag.FlagSet) {
+	flagSet.String(namespace+suffixBucketURL, opt.Bucket.URL, "The URL of the bucket and of the prefix of the files, e.g. s3://bucket/jaeger, gs://bucket/jaeger or file:///var/lib/jaeger")
+	flagSet.String(namespace+suffixS3Region, opt.Bucket.S3Region, "The AWS region of the S3 bucket, by default the region of the environment, e.g. AWS_REGION")
+	flagSet.String(namespace+suffixS3Endpoint, opt.Bucket.S3Endpoint, "The endpoint of an S3 compatible storage, e.g. MinIO")
+	flagSet.String(namespace+suffixGCSCredentialsFile, opt.Bucket.GCSCredentialsFile, "The JSON file of the credentials of a Google Cloud service account, by default the application default credentials")
+	flagSet.Int(namespace+suffixMaxSpansPerFile, opt.MaxSpansPerFile, "The number of spans buffered before they are written into the files of their hours")
+	flagSet.Duration(namespace+suffixFlushInterval, opt.FlushInterval, "The interval of the writes of the files which are not full")
+	flagSet.Duration(namespace+suffixTraceLookback, opt.TraceLookback, "How far back the traces are looked for by ID, and the services and operations listed")
+	flagSet.Duration(namespace+suffixMaxSearchWindow, opt.MaxSearchWindow, "The maximum time range of the searches of the traces and of the dependencies")
+}
+
+// InitFromViper initializes Options with properties from viper
+func (opt *Options) InitFromViper(v *viper.Viper) {
+	opt.Bucket.URL = v.GetString(namespace + suffixBucketURL)
+	opt.Bucket.S3Region = v.GetString(namespace + suffixS3Region)
+	opt.Bucket.S3Endpoint = v.GetString(namespace + suffixS3Endpoint)
+	opt.Bucket.GCSCredentialsFile = v.GetString(namespace + suffixGCSCredentialsFile)
+	opt.MaxSpansPerFile = v.GetInt(namespace + suffixMaxSpansPerFile)
+	opt.FlushInterval = v.GetDuration(namespace + suffixFlushInterval)
+	opt.TraceLookback = v.GetDuration(namespace + suffixTraceLookback)
+	opt.MaxSearchWindow = v.GetDuration(namespace + suffixMaxSearchWindow)
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parquet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jaegertracing/jaeger/pkg/config"
+	objectstoreConfig "github.com/jaegertracing/jaeger/pkg/objectstore/config"
+)
+
+func TestDefaultOptions(t *testing.T) {
+	opts := NewOptions()
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags(nil)
+	opts.InitFromViper(v)
+	assert.Equal(t, NewOptions(), opts)
+}
+
+func TestOptionsWithFlags(t *testing.T) {
+	opts := NewOptions()
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{
+		"--parquet.bucket-url=s3://bucket/jaeger",
+		"--parquet.s3.region=eu-west-1",
+		"--parquet.s3.endpoint=http://localhost:9000",
+		"--parquet.gcs.credentials-file=/etc/credentials.json",
+		"--parquet.max-spans-per-file=1000",
+		"--parquet.flush-interval=10s",
+		"--parquet.trace-lookback=24h",
+		"--parquet.max-search-window=6h",
+	})
+	opts.InitFromViper(v)
+	assert.Equal(t, &Options{
+		Bucket: objectstoreConfig.Configuration{
+			URL:                "s3://bucket/jaeger",
+			S3Region:           "eu-west-1",
+			S3Endpoint:         "http://localhost:9000",
+			GCSCredentialsFile: "/etc/credentials.json",
+		},
+		MaxSpansPerFile: 1000,
+		FlushInterval:   10 * time.Second,
+		TraceLookback:   24 * time.Hour,
+		MaxSearchWindow: 6 * time.Hour,
+	}, opts)
+}
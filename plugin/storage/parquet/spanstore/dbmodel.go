@@ -0,0 +1,182 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+const (
+	spansDir = "spans/"
+	indexDir = "index/"
+
+	// partitionLayout partitions the files by the hour of the start time of their spans
+	partitionLayout   = "2006/01/02/15"
+	partitionDuration = time.Hour
+
+	// bloomFalsePositiveRate is the rate of the files read needlessly by GetTrace
+	bloomFalsePositiveRate = 0.01
+)
+
+// spanRow is a row of the Parquet files of the spans, the span itself is stored in protobuf and
+// the other columns filter the rows, e.g. in the engines querying the files directly.
+type spanRow struct {
+	TraceID      string `parquet:"name=trace_id, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	SpanID       string `parquet:"name=span_id, type=UTF8"`
+	ParentSpanID string `parquet:"name=parent_span_id, type=UTF8"`
+	Service      string `parquet:"name=service, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	Operation    string `parquet:"name=operation, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	StartTime    int64  `parquet:"name=start_time, type=TIMESTAMP_MICROS"`
+	Duration     int64  `parquet:"name=duration, type=INT64"`
+	Span         string `parquet:"name=span, type=BYTE_ARRAY"`
+}
+
+// Operation is an operation of a service in the spans of a file.
+type Operation struct {
+	Service  string `json:"service"`
+	Name     string `json:"name"`
+	SpanKind string `json:"span_kind"`
+}
+
+// FileIndex is the index of a Parquet file of spans, stored next to it. It is read before the file,
+// and the files which cannot match a query are not read.
+type FileIndex struct {
+	// File is the key of the Parquet file.
+	File         string      `json:"file"`
+	StartTimeMin time.Time   `json:"start_time_min"`
+	StartTimeMax time.Time   `json:"start_time_max"`
+	Spans        int         `json:"spans"`
+	Operations   []Operation `json:"operations"`
+	// TraceIDs is the bloom filter of the trace IDs of the spans.
+	TraceIDs *BloomFilter `json:"trace_ids"`
+}
+
+// hasService returns whether the file contains spans of the service, and of the operation unless it is empty.
+func (i *FileIndex) hasService(service, operation string) bool {
+	for _, op := range i.Operations {
+		if op.Service == service && (operation == "" || op.Name == operation) {
+			return true
+		}
+	}
+	return false
+}
+
+// BloomFilter is a bloom filter of trace IDs, it tells whether a trace is in a file with a small rate of false positives.
+type BloomFilter struct {
+	Bits   []byte `json:"bits"`
+	Hashes int    `json:"hashes"`
+}
+
+// NewBloomFilter creates a BloomFilter sized for n trace IDs at the false positive rate.
+func NewBloomFilter(n int, falsePositiveRate float64) *BloomFilter {
+	if n < 1 {
+		n = 1
+	}
+	bits := math.Ceil(-float64(n) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	hashes := int(math.Ceil(bits / float64(n) * math.Ln2))
+	return &BloomFilter{Bits: make([]byte, int(bits+7)/8), Hashes: hashes}
+}
+
+// positions returns the bits of the trace ID, double hashing its FNV hash.
+func (f *BloomFilter) positions(traceID model.TraceID, fn func(bit uint64)) {
+	id := make([]byte, 16)
+	binary.BigEndian.PutUint64(id, traceID.High)
+	binary.BigEndian.PutUint64(id[8:], traceID.Low)
+	hash := fnv.New64a()
+	hash.Write(id)
+	sum := hash.Sum64()
+	h1, h2 := sum&math.MaxUint32, sum>>32
+	size := uint64(len(f.Bits)) * 8
+	for i := 0; i < f.Hashes; i++ {
+		fn((h1 + uint64(i)*h2) % size)
+	}
+}
+
+// Add adds the trace ID to the filter.
+func (f *BloomFilter) Add(traceID model.TraceID) {
+	f.positions(traceID, func(bit uint64) {
+		f.Bits[bit/8] |= 1 << (bit % 8)
+	})
+}
+
+// MayContain returns false if the trace ID was not added to the filter, and true if it was likely added.
+func (f *BloomFilter) MayContain(traceID model.TraceID) bool {
+	contains := len(f.Bits) > 0
+	f.positions(traceID, func(bit uint64) {
+		contains = contains && f.Bits[bit/8]&(1<<(bit%8)) != 0
+	})
+	return contains
+}
+
+// traceIDString returns the trace ID always on 32 hexadecimal digits, unlike TraceID.String.
+func traceIDString(traceID model.TraceID) string {
+	return fmt.Sprintf("%016x%016x", traceID.High, traceID.Low)
+}
+
+// partition returns the partition of the time, the directory of its files.
+func partition(t time.Time) string {
+	return t.UTC().Format(partitionLayout) + "/"
+}
+
+// partitions returns the partitions of the times between startTimeMin and startTimeMax, the most recent first.
+func partitions(startTimeMin, startTimeMax time.Time) []string {
+	var result []string
+	for t := startTimeMax.UTC().Truncate(partitionDuration); !t.Before(startTimeMin.UTC().Truncate(partitionDuration)); t = t.Add(-partitionDuration) {
+		result = append(result, partition(t))
+	}
+	return result
+}
+
+func micros(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Microsecond)
+}
+
+func fromDomain(span *model.Span) (spanRow, error) {
+	data, err := proto.Marshal(span)
+	if err != nil {
+		return spanRow{}, err
+	}
+	row := spanRow{
+		TraceID:   traceIDString(span.TraceID),
+		SpanID:    span.SpanID.String(),
+		Operation: span.OperationName,
+		StartTime: micros(span.StartTime),
+		Duration:  int64(span.Duration / time.Microsecond),
+		Span:      string(data),
+	}
+	if parentID := span.ParentSpanID(); parentID != 0 {
+		row.ParentSpanID = parentID.String()
+	}
+	if span.Process != nil {
+		row.Service = span.Process.ServiceName
+	}
+	return row, nil
+}
+
+func toDomain(row *spanRow) (*model.Span, error) {
+	span := &model.Span{}
+	if err := proto.Unmarshal([]byte(row.Span), span); err != nil {
+		return nil, fmt.Errorf("cannot decode the span: %w", err)
+	}
+	return span, nil
+}
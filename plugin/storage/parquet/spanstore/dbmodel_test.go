@@ -0,0 +1,111 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+func TestBloomFilter(t *testing.T) {
+	filter := NewBloomFilter(1000, 0.01)
+	assert.Equal(t, 1199, len(filter.Bits))
+	assert.Equal(t, 7, filter.Hashes)
+	for i := uint64(0); i < 1000; i++ {
+		filter.Add(model.NewTraceID(i, i*7))
+	}
+	falsePositives := 0
+	for i := uint64(0); i < 1000; i++ {
+		assert.True(t, filter.MayContain(model.NewTraceID(i, i*7)))
+		if filter.MayContain(model.NewTraceID(i, i*7+1)) {
+			falsePositives++
+		}
+	}
+	assert.True(t, falsePositives < 50, falsePositives)
+
+	empty := NewBloomFilter(0, 0.01)
+	assert.False(t, empty.MayContain(model.NewTraceID(0, 1)))
+	assert.False(t, (&BloomFilter{}).MayContain(model.NewTraceID(0, 1)))
+}
+
+func TestPartitions(t *testing.T) {
+	start := time.Date(2020, 10, 14, 22, 30, 0, 0, time.UTC)
+	assert.Equal(t, "2020/10/14/22/", partition(start))
+	assert.Equal(t, []string{"2020/10/15/01/", "2020/10/15/00/", "2020/10/14/23/", "2020/10/14/22/"}, partitions(start, start.Add(3*time.Hour)))
+	assert.Equal(t, []string{"2020/10/14/22/"}, partitions(start, start))
+	assert.Empty(t, partitions(start, start.Add(-time.Hour)))
+}
+
+func TestFileIndexHasService(t *testing.T) {
+	index := &FileIndex{Operations: []Operation{{Service: "svc", Name: "op"}}}
+	assert.True(t, index.hasService("svc", ""))
+	assert.True(t, index.hasService("svc", "op"))
+	assert.False(t, index.hasService("svc", "op2"))
+	assert.False(t, index.hasService("svc2", ""))
+}
+
+func TestFromDomain(t *testing.T) {
+	span := &model.Span{
+		TraceID:       model.NewTraceID(1, 2),
+		SpanID:        3,
+		References:    []model.SpanRef{model.NewChildOfRef(model.NewTraceID(1, 2), 4)},
+		OperationName: "op",
+		StartTime:     time.Unix(10, 0),
+		Duration:      time.Millisecond,
+		Process:       model.NewProcess("svc", nil),
+	}
+	row, err := fromDomain(span)
+	require.NoError(t, err)
+	assert.Equal(t, "00000000000000010000000000000002", row.TraceID)
+	assert.Equal(t, "0000000000000003", row.SpanID)
+	assert.Equal(t, "0000000000000004", row.ParentSpanID)
+	assert.Equal(t, "svc", row.Service)
+	assert.Equal(t, "op", row.Operation)
+	assert.Equal(t, int64(10000000), row.StartTime)
+	assert.Equal(t, int64(1000), row.Duration)
+
+	decoded, err := toDomain(&row)
+	require.NoError(t, err)
+	assert.Equal(t, span.TraceID, decoded.TraceID)
+	assert.Equal(t, span.StartTime.UTC(), decoded.StartTime.UTC())
+
+	row, err = fromDomain(&model.Span{TraceID: model.NewTraceID(0, 1)})
+	require.NoError(t, err)
+	assert.Equal(t, "", row.Service)
+	assert.Equal(t, "", row.ParentSpanID)
+
+	_, err = toDomain(&spanRow{Span: "invalid"})
+	assert.Error(t, err)
+}
+
+func TestFiles(t *testing.T) {
+	rows := []spanRow{
+		{TraceID: "1", Service: "svc", StartTime: 10, Duration: 2, Span: "\x00\x01"},
+		{TraceID: "2", SpanID: "3", ParentSpanID: "4", Operation: "op"},
+	}
+	data, err := encodeFile(rows)
+	require.NoError(t, err)
+	decoded, err := decodeFile(data)
+	require.NoError(t, err)
+	assert.Equal(t, rows, decoded)
+
+	_, err = decodeFile([]byte("invalid"))
+	assert.Error(t, err)
+}
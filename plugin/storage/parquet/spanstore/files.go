@@ -0,0 +1,62 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// encodeFile encodes the rows into a Parquet file compressed with Snappy.
+func encodeFile(rows []spanRow) ([]byte, error) {
+	file, err := buffer.NewBufferFile(nil)
+	if err != nil {
+		return nil, err
+	}
+	w, err := writer.NewParquetWriter(file, new(spanRow), 1)
+	if err != nil {
+		return nil, err
+	}
+	w.CompressionType = parquet.CompressionCodec_SNAPPY
+	for _, row := range rows {
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.WriteStop(); err != nil {
+		return nil, err
+	}
+	return file.(buffer.BufferFile).Bytes(), nil
+}
+
+// decodeFile decodes the rows of a Parquet file.
+func decodeFile(data []byte) ([]spanRow, error) {
+	file, err := buffer.NewBufferFile(data)
+	if err != nil {
+		return nil, err
+	}
+	r, err := reader.NewParquetReader(file, new(spanRow), 1)
+	if err != nil {
+		return nil, err
+	}
+	defer r.ReadStop()
+	rows := make([]spanRow, r.GetNumRows())
+	if err := r.Read(&rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
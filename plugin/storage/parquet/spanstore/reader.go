@@ -0,0 +1,440 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/cache"
+	"github.com/jaegertracing/jaeger/pkg/objectstore"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+const defaultNumTraces = 100
+
+var (
+	// ErrServiceNameNotSet occurs when attempting to query with an empty service name
+	ErrServiceNameNotSet = errors.New("service Name must be set")
+
+	// ErrMalformedRequestObject occurs when a request object is nil
+	ErrMalformedRequestObject = errors.New("malformed request object")
+
+	// ErrStartAndEndTimeNotSet occurs when start time and end time are not set
+	ErrStartAndEndTimeNotSet = errors.New("start and End Time must be set")
+
+	// ErrStartTimeMinGreaterThanMax occurs when start time min is above start time max
+	ErrStartTimeMinGreaterThanMax = errors.New("start Time Minimum is above Maximum")
+
+	// ErrDurationMinGreaterThanMax occurs when duration min is above duration max
+	ErrDurationMinGreaterThanMax = errors.New("duration Minimum is above Maximum")
+)
+
+// SpanReaderParams holds constructor parameters for NewSpanReader
+type SpanReaderParams struct {
+	Bucket objectstore.Bucket
+	// TraceLookback is how far back the traces, the services and the operations are looked for.
+	TraceLookback time.Duration
+	// MaxSearchWindow is the maximum time range of the searches of the traces, it bounds the files read.
+	MaxSearchWindow time.Duration
+}
+
+// SpanReader can query for and load traces from the Parquet files of an object storage.
+type SpanReader struct {
+	bucket          objectstore.Bucket
+	traceLookback   time.Duration
+	maxSearchWindow time.Duration
+	// indexes caches the indexes of the files, which are never modified
+	indexes cache.Cache
+	now     func() time.Time
+}
+
+// NewSpanReader returns a new SpanReader.
+func NewSpanReader(p SpanReaderParams) *SpanReader {
+	return &SpanReader{
+		bucket:          p.Bucket,
+		traceLookback:   p.TraceLookback,
+		maxSearchWindow: p.MaxSearchWindow,
+		indexes:         cache.NewLRU(10000),
+		now:             time.Now,
+	}
+}
+
+// GetTrace takes a traceID and returns a Trace associated with that traceID
+func (r *SpanReader) GetTrace(ctx context.Context, traceID model.TraceID) (*model.Trace, error) {
+	now := r.now()
+	indexes, err := r.readIndexes(ctx, now.Add(-r.traceLookback), now)
+	if err != nil {
+		return nil, err
+	}
+	traces, err := r.readTraces(ctx, indexes, []model.TraceID{traceID})
+	if err != nil {
+		return nil, err
+	}
+	trace, ok := traces[traceID]
+	if !ok {
+		return nil, spanstore.ErrTraceNotFound
+	}
+	return trace, nil
+}
+
+// GetServices returns all services traced by Jaeger within the trace lookback
+func (r *SpanReader) GetServices(ctx context.Context) ([]string, error) {
+	operations, err := r.recentOperations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	services := []string{}
+	for _, operation := range operations {
+		if len(services) == 0 || services[len(services)-1] != operation.Service {
+			services = append(services, operation.Service)
+		}
+	}
+	return services, nil
+}
+
+// GetOperations returns all operations for a specific service traced by Jaeger within the trace lookback
+func (r *SpanReader) GetOperations(ctx context.Context, query spanstore.OperationQueryParameters) ([]spanstore.Operation, error) {
+	operations, err := r.recentOperations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := []spanstore.Operation{}
+	for _, operation := range operations {
+		if operation.Service == query.ServiceName && (query.SpanKind == "" || operation.SpanKind == query.SpanKind) {
+			result = append(result, spanstore.Operation{Name: operation.Name, SpanKind: operation.SpanKind})
+		}
+	}
+	return result, nil
+}
+
+// recentOperations returns the distinct operations of the files within the trace lookback, sorted.
+func (r *SpanReader) recentOperations(ctx context.Context) ([]Operation, error) {
+	now := r.now()
+	indexes, err := r.readIndexes(ctx, now.Add(-r.traceLookback), now)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[Operation]struct{})
+	var operations []Operation
+	for _, index := range indexes {
+		for _, operation := range index.Operations {
+			if _, ok := found[operation]; !ok {
+				found[operation] = struct{}{}
+				operations = append(operations, operation)
+			}
+		}
+	}
+	sort.Slice(operations, func(i, j int) bool {
+		a, b := operations[i], operations[j]
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.SpanKind < b.SpanKind
+	})
+	return operations, nil
+}
+
+// FindTraces retrieves traces that match the traceQuery
+func (r *SpanReader) FindTraces(ctx context.Context, query *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
+	traceIDs, err := r.FindTraceIDs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(traceIDs) == 0 {
+		return []*model.Trace{}, nil
+	}
+	// the spans of the traces found are looked for in the adjacent partitions too
+	indexes, err := r.readIndexes(ctx, query.StartTimeMin.Add(-partitionDuration), query.StartTimeMax.Add(partitionDuration))
+	if err != nil {
+		return nil, err
+	}
+	found, err := r.readTraces(ctx, indexes, traceIDs)
+	if err != nil {
+		return nil, err
+	}
+	traces := make([]*model.Trace, 0, len(traceIDs))
+	for _, traceID := range traceIDs {
+		if trace, ok := found[traceID]; ok {
+			traces = append(traces, trace)
+		}
+	}
+	return traces, nil
+}
+
+// FindTraceIDs retrieves the IDs of the traces that match the traceQuery, the most recent first.
+// The files of the time range containing the service, and the operation, are read from the most recent, until enough
+// traces are found; the tag equalities are matched too, the other tag filters are left to the query service.
+func (r *SpanReader) FindTraceIDs(ctx context.Context, query *spanstore.TraceQueryParameters) ([]model.TraceID, error) {
+	if err := r.validateQuery(query); err != nil {
+		return nil, err
+	}
+	numTraces := query.NumTraces
+	if numTraces <= 0 {
+		numTraces = defaultNumTraces
+	}
+	indexes, err := r.readIndexes(ctx, query.StartTimeMin, query.StartTimeMax)
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string, len(query.Tags))
+	for key, value := range query.Tags {
+		tags[key] = value
+	}
+	for _, filter := range query.TagFilters {
+		if filter.Operator == spanstore.TagEquals {
+			tags[filter.Key] = filter.Value
+		}
+	}
+	// the start time of the most recent span matching the query of each trace
+	startTimes := make(map[model.TraceID]int64)
+	for _, index := range indexes {
+		if len(startTimes) >= numTraces {
+			break
+		}
+		if !index.hasService(query.ServiceName, query.OperationName) {
+			continue
+		}
+		rows, err := r.readFile(ctx, index)
+		if err != nil {
+			return nil, err
+		}
+		for i := range rows {
+			row := &rows[i]
+			if !matchesRow(query, row) {
+				continue
+			}
+			span, err := toDomain(row)
+			if err != nil {
+				return nil, err
+			}
+			if !matchesTags(span, tags) {
+				continue
+			}
+			if startTime, ok := startTimes[span.TraceID]; !ok || startTime < row.StartTime {
+				startTimes[span.TraceID] = row.StartTime
+			}
+		}
+	}
+	traceIDs := make([]model.TraceID, 0, len(startTimes))
+	for traceID := range startTimes {
+		traceIDs = append(traceIDs, traceID)
+	}
+	sort.Slice(traceIDs, func(i, j int) bool {
+		return startTimes[traceIDs[i]] > startTimes[traceIDs[j]]
+	})
+	if len(traceIDs) > numTraces {
+		traceIDs = traceIDs[:numTraces]
+	}
+	return traceIDs, nil
+}
+
+// ScanSpans calls fn with the spans of the files of the time range, until it returns false.
+func (r *SpanReader) ScanSpans(ctx context.Context, startTimeMin, startTimeMax time.Time, fn func(span *model.Span) bool) error {
+	indexes, err := r.readIndexes(ctx, startTimeMin, startTimeMax)
+	if err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		rows, err := r.readFile(ctx, index)
+		if err != nil {
+			return err
+		}
+		for i := range rows {
+			if rows[i].StartTime < micros(startTimeMin) || rows[i].StartTime > micros(startTimeMax) {
+				continue
+			}
+			span, err := toDomain(&rows[i])
+			if err != nil {
+				return err
+			}
+			if !fn(span) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+func matchesRow(query *spanstore.TraceQueryParameters, row *spanRow) bool {
+	if row.Service != query.ServiceName || (query.OperationName != "" && row.Operation != query.OperationName) {
+		return false
+	}
+	if row.StartTime < micros(query.StartTimeMin) || row.StartTime > micros(query.StartTimeMax) {
+		return false
+	}
+	duration := time.Duration(row.Duration) * time.Microsecond
+	if query.DurationMin != 0 && duration < query.DurationMin {
+		return false
+	}
+	return query.DurationMax == 0 || duration <= query.DurationMax
+}
+
+// matchesTags returns whether the span, its process or its logs have all the tags.
+func matchesTags(span *model.Span, tags map[string]string) bool {
+	for key, value := range tags {
+		if !hasTag(span.Tags, key, value) && !(span.Process != nil && hasTag(span.Process.Tags, key, value)) && !logsHaveTag(span.Logs, key, value) {
+			return false
+		}
+	}
+	return true
+}
+
+func logsHaveTag(logs []model.Log, key, value string) bool {
+	for _, log := range logs {
+		if hasTag(log.Fields, key, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasTag(kvs model.KeyValues, key, value string) bool {
+	for _, kv := range kvs {
+		if kv.Key == key && kv.AsString() == value {
+			return true
+		}
+	}
+	return false
+}
+
+// readTraces reads the spans of the traces from the files whose bloom filters may contain them.
+func (r *SpanReader) readTraces(ctx context.Context, indexes []*FileIndex, traceIDs []model.TraceID) (map[model.TraceID]*model.Trace, error) {
+	wanted := make(map[string]struct{}, len(traceIDs))
+	for _, traceID := range traceIDs {
+		wanted[traceIDString(traceID)] = struct{}{}
+	}
+	traces := make(map[model.TraceID]*model.Trace)
+	for _, index := range indexes {
+		if !mayContainAny(index, traceIDs) {
+			continue
+		}
+		rows, err := r.readFile(ctx, index)
+		if err != nil {
+			return nil, err
+		}
+		for i := range rows {
+			if _, ok := wanted[rows[i].TraceID]; !ok {
+				continue
+			}
+			span, err := toDomain(&rows[i])
+			if err != nil {
+				return nil, err
+			}
+			trace, ok := traces[span.TraceID]
+			if !ok {
+				trace = &model.Trace{}
+				traces[span.TraceID] = trace
+			}
+			trace.Spans = append(trace.Spans, span)
+		}
+	}
+	return traces, nil
+}
+
+func mayContainAny(index *FileIndex, traceIDs []model.TraceID) bool {
+	for _, traceID := range traceIDs {
+		if index.TraceIDs.MayContain(traceID) {
+			return true
+		}
+	}
+	return false
+}
+
+// readIndexes reads the indexes of the files with spans between startTimeMin and startTimeMax,
+// the files of the most recent spans first.
+func (r *SpanReader) readIndexes(ctx context.Context, startTimeMin, startTimeMax time.Time) ([]*FileIndex, error) {
+	var indexes []*FileIndex
+	for _, p := range partitions(startTimeMin, startTimeMax) {
+		keys, err := r.bucket.List(ctx, indexDir+p)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			index, err := r.readIndex(ctx, key)
+			if err != nil {
+				return nil, err
+			}
+			if !index.StartTimeMax.Before(startTimeMin) && !index.StartTimeMin.After(startTimeMax) {
+				indexes = append(indexes, index)
+			}
+		}
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return indexes[i].StartTimeMax.After(indexes[j].StartTimeMax)
+	})
+	return indexes, nil
+}
+
+func (r *SpanReader) readIndex(ctx context.Context, key string) (*FileIndex, error) {
+	if index, ok := r.indexes.Get(key).(*FileIndex); ok {
+		return index, nil
+	}
+	data, err := r.bucket.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	index := &FileIndex{}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("cannot decode the index %s: %w", key, err)
+	}
+	if index.TraceIDs == nil {
+		return nil, fmt.Errorf("cannot decode the index %s: no trace IDs", key)
+	}
+	r.indexes.Put(key, index)
+	return index, nil
+}
+
+func (r *SpanReader) readFile(ctx context.Context, index *FileIndex) ([]spanRow, error) {
+	data, err := r.bucket.Get(ctx, index.File)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := decodeFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode the file %s: %w", index.File, err)
+	}
+	return rows, nil
+}
+
+func (r *SpanReader) validateQuery(p *spanstore.TraceQueryParameters) error {
+	if p == nil {
+		return ErrMalformedRequestObject
+	}
+	if p.ServiceName == "" {
+		return ErrServiceNameNotSet
+	}
+	if p.StartTimeMin.IsZero() || p.StartTimeMax.IsZero() {
+		return ErrStartAndEndTimeNotSet
+	}
+	if p.StartTimeMax.Before(p.StartTimeMin) {
+		return ErrStartTimeMinGreaterThanMax
+	}
+	if p.DurationMin != 0 && p.DurationMax != 0 && p.DurationMin > p.DurationMax {
+		return ErrDurationMinGreaterThanMax
+	}
+	if window := p.StartTimeMax.Sub(p.StartTimeMin); window > r.maxSearchWindow {
+		return fmt.Errorf("the time range of the search %v is above the maximum %v", window, r.maxSearchWindow)
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/objectstore"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+var (
+	now      = time.Date(2020, 10, 14, 12, 0, 0, 0, time.UTC)
+	traceID1 = model.NewTraceID(0, 1)
+	traceID2 = model.NewTraceID(0, 2)
+	traceID3 = model.NewTraceID(0, 3)
+)
+
+// withSpans runs the test with a reader of the spans of three traces of the frontend service, written in several files:
+// traceID1 started 3 hours ago with an error, traceID2 2 hours ago with a client span and a second server span of 3s
+// in the following file, traceID3 1 hour ago with an error.
+func withSpans(t *testing.T, test func(reader *SpanReader, bucket objectstore.Bucket)) {
+	withBucket(t, func(bucket objectstore.Bucket) {
+		span1 := testSpan(traceID1, 1, now.Add(-3*time.Hour))
+		span1.Tags = append(span1.Tags, model.String("error", "true"))
+		span2 := testSpan(traceID2, 1, now.Add(-2*time.Hour))
+		span2.OperationName = "GET /orders"
+		span2.Tags = model.KeyValues{model.String("span.kind", "client")}
+		span2.Process.Tags = model.KeyValues{model.String("hostname", "h1")}
+		span3 := testSpan(traceID2, 2, now.Add(-2*time.Hour).Add(time.Second))
+		span3.Duration = 3 * time.Second
+		span3.Logs = []model.Log{{Fields: model.KeyValues{model.String("event", "retry")}}}
+		span4 := testSpan(traceID3, 1, now.Add(-time.Hour))
+		span4.Tags = append(span4.Tags, model.String("error", "true"))
+		span5 := &model.Span{TraceID: traceID3, SpanID: 2, StartTime: now.Add(-time.Hour)}
+		for _, spans := range [][]*model.Span{{span1, span2}, {span3, span4, span5}} {
+			writer := NewSpanWriter(SpanWriterParams{
+				Bucket:          bucket,
+				Logger:          zap.NewNop(),
+				MetricsFactory:  metrics.NullFactory,
+				MaxSpansPerFile: 100,
+				FlushInterval:   time.Hour,
+			})
+			for _, span := range spans {
+				require.NoError(t, writer.WriteSpan(span))
+			}
+			require.NoError(t, writer.Close())
+		}
+		reader := NewSpanReader(SpanReaderParams{Bucket: bucket, TraceLookback: 24 * time.Hour, MaxSearchWindow: 24 * time.Hour})
+		reader.now = func() time.Time { return now }
+		test(reader, bucket)
+	})
+}
+
+func TestGetTrace(t *testing.T) {
+	withSpans(t, func(reader *SpanReader, bucket objectstore.Bucket) {
+		trace, err := reader.GetTrace(context.Background(), traceID2)
+		require.NoError(t, err)
+		require.Len(t, trace.Spans, 2)
+
+		_, err = reader.GetTrace(context.Background(), model.NewTraceID(0, 4))
+		assert.Equal(t, spanstore.ErrTraceNotFound, err)
+
+		// the traces are looked for within the lookback only
+		reader.traceLookback = time.Hour
+		_, err = reader.GetTrace(context.Background(), traceID1)
+		assert.Equal(t, spanstore.ErrTraceNotFound, err)
+	})
+}
+
+func TestGetServicesAndOperations(t *testing.T) {
+	withSpans(t, func(reader *SpanReader, bucket objectstore.Bucket) {
+		services, err := reader.GetServices(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"frontend"}, services)
+
+		operations, err := reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "frontend"})
+		require.NoError(t, err)
+		assert.Equal(t, []spanstore.Operation{
+			{Name: "GET /orders", SpanKind: "client"},
+			{Name: "GET /users", SpanKind: "server"},
+		}, operations)
+
+		operations, err = reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "frontend", SpanKind: "server"})
+		require.NoError(t, err)
+		assert.Equal(t, []spanstore.Operation{{Name: "GET /users", SpanKind: "server"}}, operations)
+
+		operations, err = reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "backend"})
+		require.NoError(t, err)
+		assert.Empty(t, operations)
+	})
+}
+
+func TestFindTraceIDs(t *testing.T) {
+	withSpans(t, func(reader *SpanReader, bucket objectstore.Bucket) {
+		testCases := []struct {
+			name     string
+			query    spanstore.TraceQueryParameters
+			expected []model.TraceID
+		}{
+			{
+				name:     "service",
+				expected: []model.TraceID{traceID3, traceID2, traceID1},
+			},
+			{
+				name:     "time range",
+				query:    spanstore.TraceQueryParameters{StartTimeMin: now.Add(-150 * time.Minute), StartTimeMax: now.Add(-90 * time.Minute)},
+				expected: []model.TraceID{traceID2},
+			},
+			{
+				name:     "operation",
+				query:    spanstore.TraceQueryParameters{OperationName: "GET /orders"},
+				expected: []model.TraceID{traceID2},
+			},
+			{
+				name:     "duration",
+				query:    spanstore.TraceQueryParameters{DurationMin: 2 * time.Second},
+				expected: []model.TraceID{traceID2},
+			},
+			{
+				name:     "maximum duration",
+				query:    spanstore.TraceQueryParameters{OperationName: "GET /users", DurationMax: time.Second},
+				expected: []model.TraceID{traceID3, traceID1},
+			},
+			{
+				name:     "tags",
+				query:    spanstore.TraceQueryParameters{Tags: map[string]string{"error": "true"}},
+				expected: []model.TraceID{traceID3, traceID1},
+			},
+			{
+				name: "tag filters",
+				query: spanstore.TraceQueryParameters{
+					TagFilters: []spanstore.TagFilter{
+						{Key: "hostname", Operator: spanstore.TagEquals, Value: "h1"},
+						{Key: "error", Operator: spanstore.TagNotEquals, Value: "true"},
+					},
+				},
+				expected: []model.TraceID{traceID2},
+			},
+			{
+				name:     "log fields",
+				query:    spanstore.TraceQueryParameters{Tags: map[string]string{"event": "retry"}},
+				expected: []model.TraceID{traceID2},
+			},
+			{
+				name:     "unknown service",
+				query:    spanstore.TraceQueryParameters{ServiceName: "backend"},
+				expected: []model.TraceID{},
+			},
+			{
+				name:     "number of traces",
+				query:    spanstore.TraceQueryParameters{NumTraces: 1},
+				expected: []model.TraceID{traceID3},
+			},
+		}
+		for _, testCase := range testCases {
+			t.Run(testCase.name, func(t *testing.T) {
+				query := testCase.query
+				if query.ServiceName == "" {
+					query.ServiceName = "frontend"
+				}
+				if query.StartTimeMin.IsZero() {
+					query.StartTimeMin, query.StartTimeMax = now.Add(-12*time.Hour), now
+				}
+				traceIDs, err := reader.FindTraceIDs(context.Background(), &query)
+				require.NoError(t, err)
+				assert.Equal(t, testCase.expected, traceIDs)
+			})
+		}
+	})
+}
+
+func TestFindTraces(t *testing.T) {
+	withSpans(t, func(reader *SpanReader, bucket objectstore.Bucket) {
+		traces, err := reader.FindTraces(context.Background(), &spanstore.TraceQueryParameters{
+			ServiceName:  "frontend",
+			StartTimeMin: now.Add(-150 * time.Minute),
+			StartTimeMax: now,
+		})
+		require.NoError(t, err)
+		require.Len(t, traces, 2)
+		assert.Equal(t, traceID3, traces[0].Spans[0].TraceID)
+		assert.Len(t, traces[0].Spans, 2)
+		assert.Equal(t, traceID2, traces[1].Spans[0].TraceID)
+		assert.Len(t, traces[1].Spans, 2)
+
+		traces, err = reader.FindTraces(context.Background(), &spanstore.TraceQueryParameters{
+			ServiceName:  "backend",
+			StartTimeMin: now.Add(-time.Hour),
+			StartTimeMax: now,
+		})
+		require.NoError(t, err)
+		assert.Empty(t, traces)
+
+		_, err = reader.FindTraces(context.Background(), nil)
+		assert.Equal(t, ErrMalformedRequestObject, err)
+	})
+}
+
+func TestScanSpans(t *testing.T) {
+	withSpans(t, func(reader *SpanReader, bucket objectstore.Bucket) {
+		var spans []*model.Span
+		err := reader.ScanSpans(context.Background(), now.Add(-150*time.Minute), now, func(span *model.Span) bool {
+			spans = append(spans, span)
+			return true
+		})
+		require.NoError(t, err)
+		assert.Len(t, spans, 4)
+
+		spans = nil
+		err = reader.ScanSpans(context.Background(), now.Add(-150*time.Minute), now, func(span *model.Span) bool {
+			spans = append(spans, span)
+			return false
+		})
+		require.NoError(t, err)
+		assert.Len(t, spans, 1)
+	})
+}
+
+func TestValidateQuery(t *testing.T) {
+	reader := NewSpanReader(SpanReaderParams{MaxSearchWindow: time.Hour})
+	start := time.Unix(100, 0)
+	testCases := []struct {
+		query    *spanstore.TraceQueryParameters
+		expected string
+	}{
+		{query: nil, expected: ErrMalformedRequestObject.Error()},
+		{query: &spanstore.TraceQueryParameters{}, expected: ErrServiceNameNotSet.Error()},
+		{query: &spanstore.TraceQueryParameters{ServiceName: "svc"}, expected: ErrStartAndEndTimeNotSet.Error()},
+		{
+			query:    &spanstore.TraceQueryParameters{ServiceName: "svc", StartTimeMin: start, StartTimeMax: start.Add(-time.Second)},
+			expected: ErrStartTimeMinGreaterThanMax.Error(),
+		},
+		{
+			query: &spanstore.TraceQueryParameters{
+				ServiceName: "svc", StartTimeMin: start, StartTimeMax: start, DurationMin: time.Second, DurationMax: time.Millisecond,
+			},
+			expected: ErrDurationMinGreaterThanMax.Error(),
+		},
+		{
+			query:    &spanstore.TraceQueryParameters{ServiceName: "svc", StartTimeMin: start, StartTimeMax: start.Add(2 * time.Hour)},
+			expected: "the time range of the search 2h0m0s is above the maximum 1h0m0s",
+		},
+	}
+	for _, testCase := range testCases {
+		assert.EqualError(t, reader.validateQuery(testCase.query), testCase.expected)
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	ctx := context.Background()
+	query := &spanstore.TraceQueryParameters{ServiceName: "frontend", StartTimeMin: now.Add(-time.Hour), StartTimeMax: now}
+	reader := NewSpanReader(SpanReaderParams{Bucket: failingBucket{}, TraceLookback: time.Hour, MaxSearchWindow: time.Hour})
+	_, err := reader.GetTrace(ctx, traceID1)
+	assert.Equal(t, assert.AnError, err)
+	_, err = reader.GetServices(ctx)
+	assert.Equal(t, assert.AnError, err)
+	_, err = reader.GetOperations(ctx, spanstore.OperationQueryParameters{ServiceName: "frontend"})
+	assert.Equal(t, assert.AnError, err)
+	_, err = reader.FindTraces(ctx, query)
+	assert.Equal(t, assert.AnError, err)
+	err = reader.ScanSpans(ctx, now.Add(-time.Hour), now, nil)
+	assert.Equal(t, assert.AnError, err)
+
+	withSpans(t, func(reader *SpanReader, bucket objectstore.Bucket) {
+		// a file is invalid
+		indexes, err := reader.readIndexes(ctx, now.Add(-time.Hour), now)
+		require.NoError(t, err)
+		require.NoError(t, bucket.Put(ctx, indexes[0].File, []byte("invalid")))
+		_, err = reader.GetTrace(ctx, traceID3)
+		assert.Contains(t, err.Error(), "cannot decode the file")
+		_, err = reader.FindTraceIDs(ctx, query)
+		assert.Contains(t, err.Error(), "cannot decode the file")
+		err = reader.ScanSpans(ctx, now.Add(-time.Hour), now, nil)
+		assert.Contains(t, err.Error(), "cannot decode the file")
+
+		// an index is invalid
+		for _, data := range []string{"invalid", "{}"} {
+			require.NoError(t, bucket.Put(ctx, indexDir+partition(now)+"invalid.json", []byte(data)))
+			_, err = reader.GetServices(ctx)
+			assert.Contains(t, err.Error(), "cannot decode the index")
+		}
+	})
+}
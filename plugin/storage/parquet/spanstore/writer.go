@@ -0,0 +1,212 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/objectstore"
+	storageMetrics "github.com/jaegertracing/jaeger/storage/spanstore/metrics"
+)
+
+// SpanWriterParams holds constructor parameters for NewSpanWriter
+type SpanWriterParams struct {
+	Bucket         objectstore.Bucket
+	Logger         *zap.Logger
+	MetricsFactory metrics.Factory
+	// MaxSpansPerFile is the number of spans buffered before the files are written.
+	MaxSpansPerFile int
+	// FlushInterval is the maximum delay of the write of a span.
+	FlushInterval time.Duration
+}
+
+// SpanWriter buffers the spans and writes them as a Parquet file and its index per partition.
+type SpanWriter struct {
+	bucket          objectstore.Bucket
+	logger          *zap.Logger
+	maxSpansPerFile int
+	metrics         *storageMetrics.WriteMetrics
+	mux             sync.Mutex
+	files           map[string]*fileBuilder
+	spans           int
+	done            chan struct{}
+	closeOnce       sync.Once
+	background      sync.WaitGroup
+}
+
+// fileBuilder accumulates the rows and the index of a file.
+type fileBuilder struct {
+	rows       []spanRow
+	index      FileIndex
+	traceIDs   map[model.TraceID]struct{}
+	operations map[Operation]struct{}
+}
+
+func (b *fileBuilder) add(span *model.Span, row spanRow) {
+	if len(b.rows) == 0 || span.StartTime.Before(b.index.StartTimeMin) {
+		b.index.StartTimeMin = span.StartTime
+	}
+	if len(b.rows) == 0 || span.StartTime.After(b.index.StartTimeMax) {
+		b.index.StartTimeMax = span.StartTime
+	}
+	b.rows = append(b.rows, row)
+	b.traceIDs[span.TraceID] = struct{}{}
+	if row.Service != "" {
+		spanKind, _ := span.GetSpanKind()
+		b.operations[Operation{Service: row.Service, Name: row.Operation, SpanKind: spanKind}] = struct{}{}
+	}
+}
+
+// NewSpanWriter creates a SpanWriter and starts the periodic writes of its files.
+func NewSpanWriter(p SpanWriterParams) *SpanWriter {
+	w := &SpanWriter{
+		bucket:          p.Bucket,
+		logger:          p.Logger,
+		maxSpansPerFile: p.MaxSpansPerFile,
+		metrics:         storageMetrics.NewWriteMetrics(p.MetricsFactory, "files"),
+		files:           make(map[string]*fileBuilder),
+		done:            make(chan struct{}),
+	}
+	w.background.Add(1)
+	go w.flushPeriodically(p.FlushInterval)
+	return w
+}
+
+// WriteSpan adds the span to the file of its partition, writing the files when they are full.
+func (w *SpanWriter) WriteSpan(span *model.Span) error {
+	row, err := fromDomain(span)
+	if err != nil {
+		return err
+	}
+	key := partition(span.StartTime)
+	w.mux.Lock()
+	file, ok := w.files[key]
+	if !ok {
+		file = &fileBuilder{
+			traceIDs:   make(map[model.TraceID]struct{}),
+			operations: make(map[Operation]struct{}),
+		}
+		w.files[key] = file
+	}
+	file.add(span, row)
+	w.spans++
+	var files map[string]*fileBuilder
+	if w.spans >= w.maxSpansPerFile {
+		files = w.takeFiles()
+	}
+	w.mux.Unlock()
+	return w.write(files)
+}
+
+// Close writes the remaining spans and stops the periodic writes.
+func (w *SpanWriter) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.done)
+		w.background.Wait()
+	})
+	w.mux.Lock()
+	files := w.takeFiles()
+	w.mux.Unlock()
+	return w.write(files)
+}
+
+// takeFiles must be called with the lock held.
+func (w *SpanWriter) takeFiles() map[string]*fileBuilder {
+	files := w.files
+	w.files = make(map[string]*fileBuilder)
+	w.spans = 0
+	return files
+}
+
+func (w *SpanWriter) flushPeriodically(interval time.Duration) {
+	defer w.background.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.mux.Lock()
+			files := w.takeFiles()
+			w.mux.Unlock()
+			if err := w.write(files); err != nil {
+				w.logger.Error("Failed to write the spans into the object storage", zap.Error(err))
+			}
+		}
+	}
+}
+
+// write writes the files of the partitions, the index of a file is written after the file
+// for the readers to never read an index without its file.
+func (w *SpanWriter) write(files map[string]*fileBuilder) error {
+	var firstErr error
+	for key, file := range files {
+		start := time.Now()
+		err := w.writeFile(key, file)
+		w.metrics.Emit(err, time.Since(start))
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (w *SpanWriter) writeFile(key string, file *fileBuilder) error {
+	ctx := context.Background()
+	data, err := encodeFile(file.rows)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%016x", time.Now().UnixNano(), rand.Uint64())
+	index := file.index
+	index.File = spansDir + key + name + ".parquet"
+	index.Spans = len(file.rows)
+	index.TraceIDs = NewBloomFilter(len(file.traceIDs), bloomFalsePositiveRate)
+	for traceID := range file.traceIDs {
+		index.TraceIDs.Add(traceID)
+	}
+	for operation := range file.operations {
+		index.Operations = append(index.Operations, operation)
+	}
+	sort.Slice(index.Operations, func(i, j int) bool {
+		a, b := index.Operations[i], index.Operations[j]
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.SpanKind < b.SpanKind
+	})
+	indexData, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	if err := w.bucket.Put(ctx, index.File, data); err != nil {
+		return err
+	}
+	return w.bucket.Put(ctx, indexDir+key+name+".json", indexData)
+}
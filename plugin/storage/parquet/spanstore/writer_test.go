@@ -0,0 +1,180 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/objectstore"
+)
+
+// withBucket runs the test with a bucket of a temporary directory.
+func withBucket(t *testing.T, test func(bucket objectstore.Bucket)) {
+	dir, err := ioutil.TempDir("", "parquet")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	test(objectstore.NewFileBucket(dir))
+}
+
+type failingBucket struct {
+	objectstore.Bucket
+}
+
+func (failingBucket) Put(ctx context.Context, key string, data []byte) error {
+	return assert.AnError
+}
+
+func (failingBucket) Get(ctx context.Context, key string) ([]byte, error) {
+	return nil, assert.AnError
+}
+
+func (failingBucket) List(ctx context.Context, prefix string) ([]string, error) {
+	return nil, assert.AnError
+}
+
+func testSpan(traceID model.TraceID, spanID model.SpanID, start time.Time) *model.Span {
+	return &model.Span{
+		TraceID:       traceID,
+		SpanID:        spanID,
+		OperationName: "GET /users",
+		StartTime:     start,
+		Duration:      time.Second,
+		Tags:          model.KeyValues{model.String("span.kind", "server")},
+		Process:       model.NewProcess("frontend", nil),
+	}
+}
+
+func TestSpanWriterWritesFullFiles(t *testing.T) {
+	withBucket(t, func(bucket objectstore.Bucket) {
+		metricsFactory := metricstest.NewFactory(0)
+		writer := NewSpanWriter(SpanWriterParams{
+			Bucket:          bucket,
+			Logger:          zap.NewNop(),
+			MetricsFactory:  metricsFactory,
+			MaxSpansPerFile: 3,
+			FlushInterval:   time.Hour,
+		})
+		defer writer.Close()
+		start := time.Date(2020, 10, 14, 10, 30, 0, 0, time.UTC)
+		require.NoError(t, writer.WriteSpan(testSpan(model.NewTraceID(0, 1), 1, start)))
+		require.NoError(t, writer.WriteSpan(testSpan(model.NewTraceID(0, 1), 2, start.Add(-time.Minute))))
+		ctx := context.Background()
+		keys, err := bucket.List(ctx, "")
+		require.NoError(t, err)
+		assert.Empty(t, keys)
+
+		require.NoError(t, writer.WriteSpan(&model.Span{TraceID: model.NewTraceID(0, 2), SpanID: 1, StartTime: start.Add(time.Hour)}))
+		files, err := bucket.List(ctx, spansDir)
+		require.NoError(t, err)
+		require.Len(t, files, 2)
+		keys, err = bucket.List(ctx, indexDir+"2020/10/14/10/")
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+
+		data, err := bucket.Get(ctx, keys[0])
+		require.NoError(t, err)
+		var index FileIndex
+		require.NoError(t, json.Unmarshal(data, &index))
+		assert.Equal(t, files[0], index.File)
+		assert.Equal(t, 2, index.Spans)
+		assert.Equal(t, start.Add(-time.Minute), index.StartTimeMin.UTC())
+		assert.Equal(t, start, index.StartTimeMax.UTC())
+		assert.Equal(t, []Operation{{Service: "frontend", Name: "GET /users", SpanKind: "server"}}, index.Operations)
+		assert.True(t, index.TraceIDs.MayContain(model.NewTraceID(0, 1)))
+
+		data, err = bucket.Get(ctx, index.File)
+		require.NoError(t, err)
+		rows, err := decodeFile(data)
+		require.NoError(t, err)
+		assert.Len(t, rows, 2)
+
+		metricsFactory.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "files.attempts", Value: 2})
+	})
+}
+
+func TestSpanWriterWritesPeriodically(t *testing.T) {
+	withBucket(t, func(bucket objectstore.Bucket) {
+		writer := NewSpanWriter(SpanWriterParams{
+			Bucket:          bucket,
+			Logger:          zap.NewNop(),
+			MetricsFactory:  metrics.NullFactory,
+			MaxSpansPerFile: 100,
+			FlushInterval:   time.Millisecond,
+		})
+		defer writer.Close()
+		require.NoError(t, writer.WriteSpan(testSpan(model.NewTraceID(0, 1), 1, time.Now())))
+		for i := 0; i < 1000; i++ {
+			keys, err := bucket.List(context.Background(), indexDir)
+			require.NoError(t, err)
+			if len(keys) > 0 {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.Fatal("the file was not written")
+	})
+}
+
+func TestSpanWriterClose(t *testing.T) {
+	withBucket(t, func(bucket objectstore.Bucket) {
+		writer := NewSpanWriter(SpanWriterParams{
+			Bucket:          bucket,
+			Logger:          zap.NewNop(),
+			MetricsFactory:  metrics.NullFactory,
+			MaxSpansPerFile: 100,
+			FlushInterval:   time.Hour,
+		})
+		require.NoError(t, writer.WriteSpan(testSpan(model.NewTraceID(0, 1), 1, time.Now())))
+		require.NoError(t, writer.Close())
+		require.NoError(t, writer.Close())
+		keys, err := bucket.List(context.Background(), "")
+		require.NoError(t, err)
+		assert.Len(t, keys, 2)
+	})
+}
+
+func TestSpanWriterErrors(t *testing.T) {
+	core, logs := observer.New(zap.ErrorLevel)
+	writer := NewSpanWriter(SpanWriterParams{
+		Bucket:          failingBucket{},
+		Logger:          zap.New(core),
+		MetricsFactory:  metrics.NullFactory,
+		MaxSpansPerFile: 1,
+		FlushInterval:   time.Millisecond,
+	})
+	assert.Equal(t, assert.AnError, writer.WriteSpan(testSpan(model.NewTraceID(0, 1), 1, time.Now())))
+
+	writer.maxSpansPerFile = 100
+	require.NoError(t, writer.WriteSpan(testSpan(model.NewTraceID(0, 1), 1, time.Now())))
+	// the periodic write fails and the spans are dropped
+	for i := 0; i < 1000 && logs.Len() == 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, "Failed to write the spans into the object storage", logs.All()[0].Message)
+	assert.NoError(t, writer.Close())
+}
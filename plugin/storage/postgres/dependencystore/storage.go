@@ -0,0 +1,59 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dependencystore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+const queryDependencies = `SELECT parent.service, child.service, count(*)
+FROM jaeger_spans AS child
+INNER JOIN jaeger_spans AS parent ON child.trace_id = parent.trace_id AND child.parent_span_id = parent.span_id
+WHERE child.start_time >= $1 AND child.start_time <= $2 AND parent.start_time >= $1 AND parent.start_time <= $2
+	AND parent.service != child.service
+GROUP BY parent.service, child.service
+ORDER BY parent.service, child.service`
+
+// DependencyStore computes the dependencies of the services by joining the spans with their parents.
+type DependencyStore struct {
+	db *sql.DB
+}
+
+// NewDependencyStore returns a DependencyStore reading the spans of the database.
+func NewDependencyStore(db *sql.DB) *DependencyStore {
+	return &DependencyStore{db: db}
+}
+
+// GetDependencies returns the calls between the services in the time window ending at endTs.
+func (s *DependencyStore) GetDependencies(endTs time.Time, lookback time.Duration) ([]model.DependencyLink, error) {
+	rows, err := s.db.QueryContext(context.Background(), queryDependencies, endTs.Add(-lookback).UTC(), endTs.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	dependencies := []model.DependencyLink{}
+	for rows.Next() {
+		var link model.DependencyLink
+		if err := rows.Scan(&link.Parent, &link.Child, &link.CallCount); err != nil {
+			return nil, err
+		}
+		dependencies = append(dependencies, link)
+	}
+	return dependencies, rows.Err()
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dependencystore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+func TestGetDependencies(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	store := NewDependencyStore(db)
+	end := time.Unix(100, 0)
+
+	mock.ExpectQuery(queryDependencies).WithArgs(end.Add(-10*time.Second).UTC(), end.UTC()).
+		WillReturnRows(sqlmock.NewRows([]string{"parent", "child", "count"}).AddRow("a", "b", 3).AddRow("b", "c", 1))
+	dependencies, err := store.GetDependencies(end, 10*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, []model.DependencyLink{
+		{Parent: "a", Child: "b", CallCount: 3},
+		{Parent: "b", Child: "c", CallCount: 1},
+	}, dependencies)
+
+	mock.ExpectQuery(queryDependencies).WillReturnError(assert.AnError)
+	_, err = store.GetDependencies(end, time.Second)
+	assert.Equal(t, assert.AnError, err)
+
+	mock.ExpectQuery(queryDependencies).WillReturnRows(sqlmock.NewRows([]string{"parent"}).AddRow("a"))
+	_, err = store.GetDependencies(end, time.Second)
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+
+	// registers the PostgreSQL driver
+	_ "github.com/lib/pq"
+	"github.com/spf13/viper"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	pgDepStore "github.com/jaegertracing/jaeger/plugin/storage/postgres/dependencystore"
+	pgSpanStore "github.com/jaegertracing/jaeger/plugin/storage/postgres/spanstore"
+	"github.com/jaegertracing/jaeger/storage/dependencystore"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+// Factory implements storage.Factory for PostgreSQL backend.
+type Factory struct {
+	Options *Options
+
+	metricsFactory metrics.Factory
+	logger         *zap.Logger
+	db             *sql.DB
+
+	// openDB opens the database, it is replaced by the tests
+	openDB func(url string) (*sql.DB, error)
+}
+
+// NewFactory creates a new Factory.
+func NewFactory() *Factory {
+	return &Factory{
+		Options: NewOptions(),
+		openDB: func(url string) (*sql.DB, error) {
+			return sql.Open("postgres", url)
+		},
+	}
+}
+
+// AddFlags implements plugin.Configurable
+func (f *Factory) AddFlags(flagSet *flag.FlagSet) {
+	f.Options.AddFlags(flagSet)
+}
+
+// InitFromViper implements plugin.Configurable
+func (f *Factory) InitFromViper(v *viper.Viper) {
+	f.Options.InitFromViper(v)
+}
+
+// Initialize implements storage.Factory
+func (f *Factory) Initialize(metricsFactory metrics.Factory, logger *zap.Logger) error {
+	f.metricsFactory, f.logger = metricsFactory, logger
+	db, err := f.openDB(f.Options.URL)
+	if err != nil {
+		return err
+	}
+	db.SetMaxOpenConns(f.Options.MaxOpenConnections)
+	db.SetMaxIdleConns(f.Options.MaxIdleConnections)
+	db.SetConnMaxLifetime(f.Options.ConnMaxLifetime)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return err
+	}
+	if f.Options.Migrate {
+		if err := Migrate(context.Background(), db); err != nil {
+			db.Close()
+			return err
+		}
+	}
+	f.db = db
+	return nil
+}
+
+// CreateSpanReader implements storage.Factory
+func (f *Factory) CreateSpanReader() (spanstore.Reader, error) {
+	return pgSpanStore.NewSpanReader(f.db), nil
+}
+
+// CreateSpanWriter implements storage.Factory
+func (f *Factory) CreateSpanWriter() (spanstore.Writer, error) {
+	return pgSpanStore.NewSpanWriter(f.db, f.metricsFactory, f.logger), nil
+}
+
+// CreateDependencyReader implements storage.Factory
+func (f *Factory) CreateDependencyReader() (dependencystore.Reader, error) {
+	return pgDepStore.NewDependencyStore(f.db), nil
+}
+
+// Close implements io.Closer and closes the connections to the database.
+func (f *Factory) Close() error {
+	if f.db == nil {
+		return nil
+	}
+	return f.db.Close()
+}
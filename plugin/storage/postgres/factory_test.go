@@ -0,0 +1,100 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"database/sql"
+	"io"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/pkg/config"
+	"github.com/jaegertracing/jaeger/storage"
+)
+
+var _ storage.Factory = new(Factory)
+var _ io.Closer = new(Factory)
+
+func newTestFactory(t *testing.T, flags ...string) (*Factory, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual), sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	f := NewFactory()
+	v, command := config.Viperize(f.AddFlags)
+	command.ParseFlags(flags)
+	f.InitFromViper(v)
+	f.openDB = func(url string) (*sql.DB, error) {
+		assert.Equal(t, f.Options.URL, url)
+		return db, nil
+	}
+	return f, mock
+}
+
+func TestFactory(t *testing.T) {
+	f, mock := newTestFactory(t)
+	mock.ExpectPing()
+	expectMigrations(mock)
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+
+	_, err := f.CreateSpanReader()
+	require.NoError(t, err)
+	_, err = f.CreateSpanWriter()
+	require.NoError(t, err)
+	_, err = f.CreateDependencyReader()
+	require.NoError(t, err)
+
+	mock.ExpectClose()
+	require.NoError(t, f.Close())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFactoryWithoutMigrations(t *testing.T) {
+	f, mock := newTestFactory(t, "--postgres.migrate=false")
+	mock.ExpectPing()
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFactoryInitializeErrors(t *testing.T) {
+	f, mock := newTestFactory(t)
+	mock.ExpectPing().WillReturnError(assert.AnError)
+	mock.ExpectClose()
+	assert.Equal(t, assert.AnError, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	f, mock = newTestFactory(t)
+	mock.ExpectPing()
+	mock.ExpectBegin().WillReturnError(assert.AnError)
+	mock.ExpectClose()
+	assert.Equal(t, assert.AnError, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	f, _ = newTestFactory(t)
+	f.openDB = func(url string) (*sql.DB, error) {
+		return nil, assert.AnError
+	}
+	assert.Equal(t, assert.AnError, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	assert.NoError(t, f.Close())
+}
+
+func TestDefaultOpenDB(t *testing.T) {
+	db, err := NewFactory().openDB(defaultURL)
+	require.NoError(t, err)
+	assert.NoError(t, db.Close())
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrationsLock is the key of the advisory lock preventing concurrent migrations by several Jaeger instances.
+const migrationsLock = 7411550823
+
+// migrations are the changes of the schema, applied in order. A released migration is never modified,
+// the schema is changed by appending migrations.
+var migrations = []string{
+	`CREATE TABLE jaeger_spans (
+	trace_id TEXT NOT NULL,
+	span_id TEXT NOT NULL,
+	parent_span_id TEXT NOT NULL,
+	service TEXT NOT NULL,
+	operation TEXT NOT NULL,
+	span_kind TEXT NOT NULL,
+	start_time TIMESTAMPTZ NOT NULL,
+	duration BIGINT NOT NULL,
+	tags JSONB NOT NULL,
+	span BYTEA NOT NULL
+);
+CREATE INDEX jaeger_spans_trace_id_idx ON jaeger_spans (trace_id);
+CREATE INDEX jaeger_spans_start_time_idx ON jaeger_spans (start_time DESC);
+CREATE INDEX jaeger_spans_service_idx ON jaeger_spans (service, start_time DESC);
+CREATE INDEX jaeger_spans_service_operation_idx ON jaeger_spans (service, operation, start_time DESC);
+CREATE INDEX jaeger_spans_tags_idx ON jaeger_spans USING GIN (tags);
+CREATE TABLE jaeger_operations (
+	service TEXT NOT NULL,
+	operation TEXT NOT NULL,
+	span_kind TEXT NOT NULL,
+	PRIMARY KEY (service, operation, span_kind)
+);`,
+}
+
+// Migrate applies the migrations not applied yet to the schema, recording them in the jaeger_schema_migrations table.
+func Migrate(ctx context.Context, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", migrationsLock); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS jaeger_schema_migrations (
+	version INTEGER PRIMARY KEY,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`); err != nil {
+		return err
+	}
+	var version int
+	if err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM jaeger_schema_migrations").Scan(&version); err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("the schema version %d is more recent than the version %d of this Jaeger release", version, len(migrations))
+	}
+	for i := version; i < len(migrations); i++ {
+		if _, err := tx.ExecContext(ctx, migrations[i]); err != nil {
+			return fmt.Errorf("cannot apply the migration %d of the schema: %w", i+1, err)
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO jaeger_schema_migrations (version) VALUES ($1)", i+1); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
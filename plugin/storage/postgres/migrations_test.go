@@ -0,0 +1,111 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS jaeger_schema_migrations (
+	version INTEGER PRIMARY KEY,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`
+
+func expectMigrationsVersion(mock sqlmock.Sqlmock, version int) {
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT pg_advisory_xact_lock($1)").WithArgs(migrationsLock).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(createMigrationsTable).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT COALESCE(MAX(version), 0) FROM jaeger_schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(version))
+}
+
+func expectMigrations(mock sqlmock.Sqlmock) {
+	expectMigrationsVersion(mock, 0)
+	for i, migration := range migrations {
+		mock.ExpectExec(migration).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO jaeger_schema_migrations (version) VALUES ($1)").
+			WithArgs(i + 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+}
+
+func newMock(t *testing.T) (sqlmock.Sqlmock, func() error) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual), sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	return mock, func() error { return Migrate(context.Background(), db) }
+}
+
+func TestMigrate(t *testing.T) {
+	mock, migrate := newMock(t)
+	expectMigrations(mock)
+	require.NoError(t, migrate())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrateUpToDate(t *testing.T) {
+	mock, migrate := newMock(t)
+	expectMigrationsVersion(mock, len(migrations))
+	mock.ExpectCommit()
+	require.NoError(t, migrate())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrateMoreRecentSchema(t *testing.T) {
+	mock, migrate := newMock(t)
+	expectMigrationsVersion(mock, len(migrations)+1)
+	mock.ExpectRollback()
+	assert.EqualError(t, migrate(), "the schema version 2 is more recent than the version 1 of this Jaeger release")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrateErrors(t *testing.T) {
+	mock, migrate := newMock(t)
+	mock.ExpectBegin().WillReturnError(assert.AnError)
+	assert.Equal(t, assert.AnError, migrate())
+
+	mock, migrate = newMock(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT pg_advisory_xact_lock($1)").WillReturnError(assert.AnError)
+	assert.Equal(t, assert.AnError, migrate())
+
+	mock, migrate = newMock(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT pg_advisory_xact_lock($1)").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(createMigrationsTable).WillReturnError(assert.AnError)
+	assert.Equal(t, assert.AnError, migrate())
+
+	mock, migrate = newMock(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT pg_advisory_xact_lock($1)").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(createMigrationsTable).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT COALESCE(MAX(version), 0) FROM jaeger_schema_migrations").WillReturnError(assert.AnError)
+	assert.Equal(t, assert.AnError, migrate())
+
+	mock, migrate = newMock(t)
+	expectMigrationsVersion(mock, 0)
+	mock.ExpectExec(migrations[0]).WillReturnError(assert.AnError)
+	assert.EqualError(t, migrate(), "cannot apply the migration 1 of the schema: "+assert.AnError.Error())
+
+	mock, migrate = newMock(t)
+	expectMigrationsVersion(mock, 0)
+	mock.ExpectExec(migrations[0]).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO jaeger_schema_migrations (version) VALUES ($1)").WillReturnError(assert.AnError)
+	assert.Equal(t, assert.AnError, migrate())
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+// spanRow holds the columns of the jaeger_spans table. The span itself is stored in protobuf,
+// the other columns index it.
+type spanRow struct {
+	traceID      string
+	spanID       string
+	parentSpanID string
+	service      string
+	operation    string
+	spanKind     string
+	startTime    time.Time
+	duration     int64
+	tags         string
+	span         []byte
+}
+
+// traceIDString returns the trace ID always on 32 hexadecimal digits, unlike TraceID.String.
+func traceIDString(traceID model.TraceID) string {
+	return fmt.Sprintf("%016x%016x", traceID.High, traceID.Low)
+}
+
+func fromDomain(span *model.Span) (spanRow, error) {
+	data, err := proto.Marshal(span)
+	if err != nil {
+		return spanRow{}, err
+	}
+	row := spanRow{
+		traceID:   traceIDString(span.TraceID),
+		spanID:    span.SpanID.String(),
+		operation: span.OperationName,
+		startTime: span.StartTime.UTC(),
+		duration:  int64(span.Duration / time.Microsecond),
+		span:      data,
+	}
+	if parentID := span.ParentSpanID(); parentID != 0 {
+		row.parentSpanID = parentID.String()
+	}
+	row.spanKind, _ = span.GetSpanKind()
+	// the values of the tags, the process tags and the log fields by key
+	tags := make(map[string][]string)
+	addTags := func(kvs model.KeyValues) {
+		for _, kv := range kvs {
+			tags[kv.Key] = append(tags[kv.Key], kv.AsString())
+		}
+	}
+	addTags(span.Tags)
+	if span.Process != nil {
+		row.service = span.Process.ServiceName
+		addTags(span.Process.Tags)
+	}
+	for _, log := range span.Logs {
+		addTags(log.Fields)
+	}
+	tagsJSON, err := json.Marshal(tags)
+	if err != nil {
+		return spanRow{}, err
+	}
+	row.tags = string(tagsJSON)
+	return row, nil
+}
+
+func toDomain(data []byte) (*model.Span, error) {
+	span := &model.Span{}
+	if err := proto.Unmarshal(data, span); err != nil {
+		return nil, fmt.Errorf("cannot decode the span: %w", err)
+	}
+	return span, nil
+}
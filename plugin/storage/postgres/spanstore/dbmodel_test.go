@@ -0,0 +1,83 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+func newMock(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	return db, mock
+}
+
+func testSpan(traceID model.TraceID, spanID model.SpanID, service string) *model.Span {
+	return &model.Span{
+		TraceID:       traceID,
+		SpanID:        spanID,
+		OperationName: "op",
+		References:    []model.SpanRef{model.NewChildOfRef(traceID, 1)},
+		StartTime:     time.Date(2020, 9, 13, 12, 26, 40, 123456000, time.UTC),
+		Duration:      1500 * time.Microsecond,
+		Tags:          model.KeyValues{model.String("span.kind", "server"), model.Int64("http.status_code", 200)},
+		Process:       model.NewProcess(service, []model.KeyValue{model.String("hostname", "host1")}),
+		Logs: []model.Log{{
+			Timestamp: time.Date(2020, 9, 13, 12, 26, 40, 0, time.UTC),
+			Fields:    model.KeyValues{model.String("event", "retry"), model.String("hostname", "host2")},
+		}},
+	}
+}
+
+func TestFromDomain(t *testing.T) {
+	span := testSpan(model.NewTraceID(0, 0x10), 2, "svc")
+	row, err := fromDomain(span)
+	require.NoError(t, err)
+	assert.Equal(t, "00000000000000000000000000000010", row.traceID)
+	assert.Equal(t, "0000000000000002", row.spanID)
+	assert.Equal(t, "0000000000000001", row.parentSpanID)
+	assert.Equal(t, "svc", row.service)
+	assert.Equal(t, "op", row.operation)
+	assert.Equal(t, "server", row.spanKind)
+	assert.Equal(t, span.StartTime, row.startTime)
+	assert.Equal(t, int64(1500), row.duration)
+	assert.JSONEq(t, `{"span.kind":["server"],"http.status_code":["200"],"hostname":["host1","host2"],"event":["retry"]}`, row.tags)
+
+	decoded, err := toDomain(row.span)
+	require.NoError(t, err)
+	assert.Equal(t, span, decoded)
+}
+
+func TestFromDomainRootSpan(t *testing.T) {
+	row, err := fromDomain(&model.Span{TraceID: model.NewTraceID(1, 2), SpanID: 1, StartTime: time.Unix(0, 0)})
+	require.NoError(t, err)
+	assert.Equal(t, "00000000000000010000000000000002", row.traceID)
+	assert.Empty(t, row.parentSpanID)
+	assert.Empty(t, row.service)
+	assert.Equal(t, "{}", row.tags)
+}
+
+func TestToDomainError(t *testing.T) {
+	_, err := toDomain([]byte{0xff})
+	assert.Error(t, err)
+}
@@ -0,0 +1,259 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+const defaultNumTraces = 100
+
+var (
+	// ErrMalformedRequestObject occurs when a request object is nil
+	ErrMalformedRequestObject = errors.New("malformed request object")
+
+	// ErrStartAndEndTimeNotSet occurs when start time and end time are not set
+	ErrStartAndEndTimeNotSet = errors.New("start and End Time must be set")
+
+	// ErrStartTimeMinGreaterThanMax occurs when start time min is above start time max
+	ErrStartTimeMinGreaterThanMax = errors.New("start Time Minimum is above Maximum")
+
+	// ErrDurationMinGreaterThanMax occurs when duration min is above duration max
+	ErrDurationMinGreaterThanMax = errors.New("duration Minimum is above Maximum")
+)
+
+// SpanReader can query for and load traces from PostgreSQL.
+type SpanReader struct {
+	db *sql.DB
+}
+
+// NewSpanReader returns a new SpanReader.
+func NewSpanReader(db *sql.DB) *SpanReader {
+	return &SpanReader{db: db}
+}
+
+// GetTrace takes a traceID and returns a Trace associated with that traceID
+func (r *SpanReader) GetTrace(ctx context.Context, traceID model.TraceID) (*model.Trace, error) {
+	traces, err := r.readTraces(ctx, []model.TraceID{traceID})
+	if err != nil {
+		return nil, err
+	}
+	if len(traces) == 0 {
+		return nil, spanstore.ErrTraceNotFound
+	}
+	return traces[0], nil
+}
+
+// GetServices returns all services traced by Jaeger
+func (r *SpanReader) GetServices(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT DISTINCT service FROM jaeger_operations ORDER BY service")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var services []string
+	for rows.Next() {
+		var service string
+		if err := rows.Scan(&service); err != nil {
+			return nil, err
+		}
+		services = append(services, service)
+	}
+	return services, rows.Err()
+}
+
+// GetOperations returns all operations for a specific service traced by Jaeger
+func (r *SpanReader) GetOperations(ctx context.Context, query spanstore.OperationQueryParameters) ([]spanstore.Operation, error) {
+	statement := "SELECT operation, span_kind FROM jaeger_operations WHERE service = $1"
+	args := []interface{}{query.ServiceName}
+	if query.SpanKind != "" {
+		statement += " AND span_kind = $2"
+		args = append(args, query.SpanKind)
+	}
+	rows, err := r.db.QueryContext(ctx, statement+" ORDER BY operation, span_kind", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	operations := []spanstore.Operation{}
+	for rows.Next() {
+		var operation spanstore.Operation
+		if err := rows.Scan(&operation.Name, &operation.SpanKind); err != nil {
+			return nil, err
+		}
+		operations = append(operations, operation)
+	}
+	return operations, rows.Err()
+}
+
+// FindTraces retrieves traces that match the traceQuery
+func (r *SpanReader) FindTraces(ctx context.Context, query *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
+	traceIDs, err := r.FindTraceIDs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return r.readTraces(ctx, traceIDs)
+}
+
+// FindTraceIDs retrieves the IDs of the traces that match the traceQuery, the most recent first.
+// The equality and existence tag filters are run by PostgreSQL, the others by the query service.
+func (r *SpanReader) FindTraceIDs(ctx context.Context, query *spanstore.TraceQueryParameters) ([]model.TraceID, error) {
+	if err := validateQuery(query); err != nil {
+		return nil, err
+	}
+	q := &whereClause{}
+	q.add("start_time >= %s", query.StartTimeMin.UTC())
+	q.add("start_time <= %s", query.StartTimeMax.UTC())
+	if query.ServiceName != "" {
+		q.add("service = %s", query.ServiceName)
+	}
+	if query.OperationName != "" {
+		q.add("operation = %s", query.OperationName)
+	}
+	if query.DurationMin != 0 {
+		q.add("duration >= %s", int64(query.DurationMin/time.Microsecond))
+	}
+	if query.DurationMax != 0 {
+		q.add("duration <= %s", int64(query.DurationMax/time.Microsecond))
+	}
+	// the tags must contain the values, the condition is answered by the GIN index of the column
+	tags := make(map[string][]string)
+	for key, value := range query.Tags {
+		tags[key] = append(tags[key], value)
+	}
+	for _, filter := range query.TagFilters {
+		switch filter.Operator {
+		case spanstore.TagEquals:
+			tags[filter.Key] = append(tags[filter.Key], filter.Value)
+		case spanstore.TagExists:
+			q.add("tags ? %s", filter.Key)
+		}
+	}
+	if len(tags) > 0 {
+		tagsJSON, err := json.Marshal(tags)
+		if err != nil {
+			return nil, err
+		}
+		q.add("tags @> %s::jsonb", string(tagsJSON))
+	}
+	numTraces := query.NumTraces
+	if numTraces <= 0 {
+		numTraces = defaultNumTraces
+	}
+	q.args = append(q.args, numTraces)
+	statement := "SELECT trace_id FROM jaeger_spans WHERE " + strings.Join(q.conditions, " AND ") +
+		" GROUP BY trace_id ORDER BY max(start_time) DESC LIMIT $" + strconv.Itoa(len(q.args))
+	rows, err := r.db.QueryContext(ctx, statement, q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var traceIDs []model.TraceID
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		traceID, err := model.TraceIDFromString(id)
+		if err != nil {
+			return nil, err
+		}
+		traceIDs = append(traceIDs, traceID)
+	}
+	return traceIDs, rows.Err()
+}
+
+// readTraces returns the traces found in the order of their IDs.
+func (r *SpanReader) readTraces(ctx context.Context, traceIDs []model.TraceID) ([]*model.Trace, error) {
+	if len(traceIDs) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, len(traceIDs))
+	for i, traceID := range traceIDs {
+		ids[i] = traceIDString(traceID)
+	}
+	rows, err := r.db.QueryContext(ctx, "SELECT span FROM jaeger_spans WHERE trace_id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	traces := make(map[model.TraceID]*model.Trace, len(traceIDs))
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		span, err := toDomain(data)
+		if err != nil {
+			return nil, err
+		}
+		trace, ok := traces[span.TraceID]
+		if !ok {
+			trace = &model.Trace{}
+			traces[span.TraceID] = trace
+		}
+		trace.Spans = append(trace.Spans, span)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	result := make([]*model.Trace, 0, len(traces))
+	for _, traceID := range traceIDs {
+		if trace, ok := traces[traceID]; ok {
+			result = append(result, trace)
+		}
+	}
+	return result, nil
+}
+
+func validateQuery(p *spanstore.TraceQueryParameters) error {
+	if p == nil {
+		return ErrMalformedRequestObject
+	}
+	if p.StartTimeMin.IsZero() || p.StartTimeMax.IsZero() {
+		return ErrStartAndEndTimeNotSet
+	}
+	if p.StartTimeMax.Before(p.StartTimeMin) {
+		return ErrStartTimeMinGreaterThanMax
+	}
+	if p.DurationMin != 0 && p.DurationMax != 0 && p.DurationMin > p.DurationMax {
+		return ErrDurationMinGreaterThanMax
+	}
+	return nil
+}
+
+// whereClause builds the conditions of a query and their numbered arguments.
+type whereClause struct {
+	conditions []string
+	args       []interface{}
+}
+
+// add adds the condition on the argument, whose placeholder replaces the %s of the condition.
+func (w *whereClause) add(condition string, arg interface{}) {
+	w.args = append(w.args, arg)
+	w.conditions = append(w.conditions, fmt.Sprintf(condition, "$"+strconv.Itoa(len(w.args))))
+}
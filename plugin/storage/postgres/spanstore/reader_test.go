@@ -0,0 +1,229 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+const readTraces = "SELECT span FROM jaeger_spans WHERE trace_id = ANY($1)"
+
+func spanRows(t *testing.T, spans ...*model.Span) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"span"})
+	for _, span := range spans {
+		row, err := fromDomain(span)
+		require.NoError(t, err)
+		rows.AddRow(row.span)
+	}
+	return rows
+}
+
+func TestGetTrace(t *testing.T) {
+	db, mock := newMock(t)
+	reader := NewSpanReader(db)
+	traceID := model.NewTraceID(0, 1)
+
+	mock.ExpectQuery(readTraces).WithArgs(`{"00000000000000000000000000000001"}`).
+		WillReturnRows(spanRows(t, testSpan(traceID, 1, "a"), testSpan(traceID, 2, "b")))
+	trace, err := reader.GetTrace(context.Background(), traceID)
+	require.NoError(t, err)
+	assert.Len(t, trace.Spans, 2)
+
+	mock.ExpectQuery(readTraces).WillReturnRows(sqlmock.NewRows([]string{"span"}))
+	_, err = reader.GetTrace(context.Background(), traceID)
+	assert.Equal(t, spanstore.ErrTraceNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTraceErrors(t *testing.T) {
+	db, mock := newMock(t)
+	reader := NewSpanReader(db)
+	traceID := model.NewTraceID(0, 1)
+
+	mock.ExpectQuery(readTraces).WillReturnError(assert.AnError)
+	_, err := reader.GetTrace(context.Background(), traceID)
+	assert.Equal(t, assert.AnError, err)
+
+	mock.ExpectQuery(readTraces).WillReturnRows(sqlmock.NewRows([]string{"span"}).AddRow([]byte{0xff}))
+	_, err = reader.GetTrace(context.Background(), traceID)
+	assert.Error(t, err)
+
+	mock.ExpectQuery(readTraces).WillReturnRows(sqlmock.NewRows([]string{"span", "other"}).AddRow([]byte{}, 1))
+	_, err = reader.GetTrace(context.Background(), traceID)
+	assert.Error(t, err)
+
+	mock.ExpectQuery(readTraces).WillReturnRows(spanRows(t, testSpan(traceID, 1, "a")).RowError(0, assert.AnError))
+	_, err = reader.GetTrace(context.Background(), traceID)
+	assert.Equal(t, assert.AnError, err)
+}
+
+func TestGetServices(t *testing.T) {
+	db, mock := newMock(t)
+	reader := NewSpanReader(db)
+	query := "SELECT DISTINCT service FROM jaeger_operations ORDER BY service"
+
+	mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"service"}).AddRow("a").AddRow("b"))
+	services, err := reader.GetServices(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, services)
+
+	mock.ExpectQuery(query).WillReturnError(assert.AnError)
+	_, err = reader.GetServices(context.Background())
+	assert.Equal(t, assert.AnError, err)
+
+	mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"service", "other"}).AddRow("a", 1))
+	_, err = reader.GetServices(context.Background())
+	assert.Error(t, err)
+}
+
+func TestGetOperations(t *testing.T) {
+	db, mock := newMock(t)
+	reader := NewSpanReader(db)
+
+	mock.ExpectQuery("SELECT operation, span_kind FROM jaeger_operations WHERE service = $1 ORDER BY operation, span_kind").
+		WithArgs("a").
+		WillReturnRows(sqlmock.NewRows([]string{"operation", "span_kind"}).AddRow("get", "server"))
+	operations, err := reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "a"})
+	require.NoError(t, err)
+	assert.Equal(t, []spanstore.Operation{{Name: "get", SpanKind: "server"}}, operations)
+
+	mock.ExpectQuery("SELECT operation, span_kind FROM jaeger_operations WHERE service = $1 AND span_kind = $2 ORDER BY operation, span_kind").
+		WithArgs("a", "client").
+		WillReturnRows(sqlmock.NewRows([]string{"operation", "span_kind"}))
+	operations, err = reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "a", SpanKind: "client"})
+	require.NoError(t, err)
+	assert.Empty(t, operations)
+
+	mock.ExpectQuery("SELECT operation, span_kind FROM jaeger_operations WHERE service = $1 ORDER BY operation, span_kind").
+		WillReturnError(assert.AnError)
+	_, err = reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "a"})
+	assert.Equal(t, assert.AnError, err)
+
+	mock.ExpectQuery("SELECT operation, span_kind FROM jaeger_operations WHERE service = $1 ORDER BY operation, span_kind").
+		WillReturnRows(sqlmock.NewRows([]string{"operation"}).AddRow("get"))
+	_, err = reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "a"})
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFindTraceIDs(t *testing.T) {
+	db, mock := newMock(t)
+	reader := NewSpanReader(db)
+	exists, err := spanstore.ParseTagFilter("error")
+	require.NoError(t, err)
+	equals, err := spanstore.ParseTagFilter("peer.service=db")
+	require.NoError(t, err)
+	regex, err := spanstore.ParseTagFilter("http.url=~.*api.*")
+	require.NoError(t, err)
+	start, end := time.Unix(1, 0), time.Unix(2, 0)
+
+	mock.ExpectQuery("SELECT trace_id FROM jaeger_spans WHERE start_time >= $1 AND start_time <= $2 AND "+
+		"service = $3 AND operation = $4 AND duration >= $5 AND duration <= $6 AND tags ? $7 AND tags @> $8::jsonb "+
+		"GROUP BY trace_id ORDER BY max(start_time) DESC LIMIT $9").
+		WithArgs(start.UTC(), end.UTC(), "svc", "op", int64(1000), int64(1000000), "error",
+			`{"http.status_code":["500"],"peer.service":["db"]}`, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"trace_id"}).
+			AddRow("00000000000000000000000000000002").
+			AddRow("00000000000000010000000000000001"))
+	traceIDs, err := reader.FindTraceIDs(context.Background(), &spanstore.TraceQueryParameters{
+		ServiceName:   "svc",
+		OperationName: "op",
+		Tags:          map[string]string{"http.status_code": "500"},
+		TagFilters:    []spanstore.TagFilter{exists, equals, regex},
+		StartTimeMin:  start,
+		StartTimeMax:  end,
+		DurationMin:   time.Millisecond,
+		DurationMax:   time.Second,
+		NumTraces:     10,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []model.TraceID{model.NewTraceID(0, 2), model.NewTraceID(1, 1)}, traceIDs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFindTraceIDsErrors(t *testing.T) {
+	db, mock := newMock(t)
+	reader := NewSpanReader(db)
+	query := &spanstore.TraceQueryParameters{StartTimeMin: time.Unix(1, 0), StartTimeMax: time.Unix(2, 0)}
+	statement := "SELECT trace_id FROM jaeger_spans WHERE start_time >= $1 AND start_time <= $2 " +
+		"GROUP BY trace_id ORDER BY max(start_time) DESC LIMIT $3"
+
+	mock.ExpectQuery(statement).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), defaultNumTraces).WillReturnError(assert.AnError)
+	_, err := reader.FindTraceIDs(context.Background(), query)
+	assert.Equal(t, assert.AnError, err)
+
+	mock.ExpectQuery(statement).WillReturnRows(sqlmock.NewRows([]string{"trace_id"}).AddRow("xyz"))
+	_, err = reader.FindTraceIDs(context.Background(), query)
+	assert.Error(t, err)
+
+	mock.ExpectQuery(statement).WillReturnRows(sqlmock.NewRows([]string{"trace_id", "other"}).AddRow("1", 1))
+	_, err = reader.FindTraceIDs(context.Background(), query)
+	assert.Error(t, err)
+
+	_, err = reader.FindTraces(context.Background(), nil)
+	assert.Equal(t, ErrMalformedRequestObject, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestValidateQuery(t *testing.T) {
+	start, end := time.Unix(1, 0), time.Unix(2, 0)
+	for _, test := range []struct {
+		query *spanstore.TraceQueryParameters
+		err   error
+	}{
+		{nil, ErrMalformedRequestObject},
+		{&spanstore.TraceQueryParameters{StartTimeMin: start}, ErrStartAndEndTimeNotSet},
+		{&spanstore.TraceQueryParameters{StartTimeMin: end, StartTimeMax: start}, ErrStartTimeMinGreaterThanMax},
+		{&spanstore.TraceQueryParameters{StartTimeMin: start, StartTimeMax: end, DurationMin: time.Second, DurationMax: time.Millisecond}, ErrDurationMinGreaterThanMax},
+		{&spanstore.TraceQueryParameters{StartTimeMin: start, StartTimeMax: end}, nil},
+	} {
+		assert.Equal(t, test.err, validateQuery(test.query))
+	}
+}
+
+func TestFindTraces(t *testing.T) {
+	db, mock := newMock(t)
+	reader := NewSpanReader(db)
+	first, second := model.NewTraceID(0, 1), model.NewTraceID(0, 2)
+
+	mock.ExpectQuery("SELECT trace_id FROM jaeger_spans WHERE start_time >= $1 AND start_time <= $2 " +
+		"GROUP BY trace_id ORDER BY max(start_time) DESC LIMIT $3").
+		WillReturnRows(sqlmock.NewRows([]string{"trace_id"}).
+			AddRow("00000000000000000000000000000002").
+			AddRow("00000000000000000000000000000001"))
+	mock.ExpectQuery(readTraces).
+		WithArgs(`{"00000000000000000000000000000002","00000000000000000000000000000001"}`).
+		WillReturnRows(spanRows(t, testSpan(first, 1, "a"), testSpan(second, 1, "a"), testSpan(first, 2, "b")))
+	traces, err := reader.FindTraces(context.Background(), &spanstore.TraceQueryParameters{
+		StartTimeMin: time.Unix(1, 0),
+		StartTimeMax: time.Unix(2, 0),
+	})
+	require.NoError(t, err)
+	require.Len(t, traces, 2)
+	// the most recent trace first, as found
+	assert.Equal(t, second, traces[0].Spans[0].TraceID)
+	assert.Len(t, traces[0].Spans, 1)
+	assert.Len(t, traces[1].Spans, 2)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/cache"
+	storageMetrics "github.com/jaegertracing/jaeger/storage/spanstore/metrics"
+)
+
+const (
+	insertSpan = `INSERT INTO jaeger_spans
+	(trace_id, span_id, parent_span_id, service, operation, span_kind, start_time, duration, tags, span)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	insertOperation = `INSERT INTO jaeger_operations (service, operation, span_kind) VALUES ($1, $2, $3)
+	ON CONFLICT DO NOTHING`
+)
+
+// SpanWriter inserts the spans into PostgreSQL.
+type SpanWriter struct {
+	db     *sql.DB
+	logger *zap.Logger
+	// operations are the operations inserted recently, they are not inserted again
+	operations cache.Cache
+	spans      *storageMetrics.WriteMetrics
+}
+
+// NewSpanWriter creates a SpanWriter.
+func NewSpanWriter(db *sql.DB, metricsFactory metrics.Factory, logger *zap.Logger) *SpanWriter {
+	return &SpanWriter{
+		db:     db,
+		logger: logger,
+		operations: cache.NewLRUWithOptions(100000, &cache.Options{
+			TTL: 12 * time.Hour,
+		}),
+		spans: storageMetrics.NewWriteMetrics(metricsFactory, "spans"),
+	}
+}
+
+// WriteSpan inserts the span and its operation, unless the operation was inserted recently.
+func (w *SpanWriter) WriteSpan(span *model.Span) error {
+	row, err := fromDomain(span)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	start := time.Now()
+	_, err = w.db.ExecContext(ctx, insertSpan, row.traceID, row.spanID, row.parentSpanID, row.service,
+		row.operation, row.spanKind, row.startTime, row.duration, row.tags, row.span)
+	w.spans.Emit(err, time.Since(start))
+	if err != nil {
+		return err
+	}
+	operation := row.service + "\x00" + row.operation + "\x00" + row.spanKind
+	if w.operations.Get(operation) != nil {
+		return nil
+	}
+	if _, err := w.db.ExecContext(ctx, insertOperation, row.service, row.operation, row.spanKind); err != nil {
+		return err
+	}
+	w.operations.Put(operation, true)
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+func expectInsertSpan(t *testing.T, mock sqlmock.Sqlmock, span *model.Span) *sqlmock.ExpectedExec {
+	row, err := fromDomain(span)
+	require.NoError(t, err)
+	return mock.ExpectExec(insertSpan).WithArgs(row.traceID, row.spanID, row.parentSpanID, row.service,
+		row.operation, row.spanKind, row.startTime, row.duration, row.tags, row.span)
+}
+
+func TestWriteSpan(t *testing.T) {
+	db, mock := newMock(t)
+	metricsFactory := metricstest.NewFactory(0)
+	writer := NewSpanWriter(db, metricsFactory, zap.NewNop())
+	traceID := model.NewTraceID(0, 1)
+
+	first, second := testSpan(traceID, 1, "svc"), testSpan(traceID, 2, "svc")
+	expectInsertSpan(t, mock, first).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(insertOperation).WithArgs("svc", "op", "server").WillReturnResult(sqlmock.NewResult(0, 1))
+	// the operation is not inserted again
+	expectInsertSpan(t, mock, second).WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, writer.WriteSpan(first))
+	require.NoError(t, writer.WriteSpan(second))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	metricsFactory.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "spans.attempts", Value: 2},
+		metricstest.ExpectedMetric{Name: "spans.inserts", Value: 2})
+}
+
+func TestWriteSpanErrors(t *testing.T) {
+	db, mock := newMock(t)
+	metricsFactory := metricstest.NewFactory(0)
+	writer := NewSpanWriter(db, metricsFactory, zap.NewNop())
+	span := testSpan(model.NewTraceID(0, 1), 1, "svc")
+
+	expectInsertSpan(t, mock, span).WillReturnError(assert.AnError)
+	assert.Equal(t, assert.AnError, writer.WriteSpan(span))
+	metricsFactory.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "spans.errors", Value: 1})
+
+	expectInsertSpan(t, mock, span).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(insertOperation).WillReturnError(assert.AnError)
+	assert.Equal(t, assert.AnError, writer.WriteSpan(span))
+	// the operation is inserted with the next span
+	expectInsertSpan(t, mock, span).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(insertOperation).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, writer.WriteSpan(span))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dependencystore
+
+import (
+	"context"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/plugin/storage/redis/spanstore"
+)
+
+// DependencyStore computes the dependencies of the services from the spans of the traces of the time window.
+// The window is bounded by the maximum lookback, the cost of the dependencies grows with the traces read.
+type DependencyStore struct {
+	reader      *spanstore.SpanReader
+	maxLookback time.Duration
+}
+
+// NewDependencyStore returns a DependencyStore reading the spans with the reader.
+func NewDependencyStore(reader *spanstore.SpanReader, maxLookback time.Duration) *DependencyStore {
+	return &DependencyStore{reader: reader, maxLookback: maxLookback}
+}
+
+type spanKey struct {
+	traceID model.TraceID
+	spanID  model.SpanID
+}
+
+type child struct {
+	traceID  model.TraceID
+	parentID model.SpanID
+	service  string
+}
+
+// GetDependencies returns the calls between the services in the time window ending at endTs.
+func (s *DependencyStore) GetDependencies(endTs time.Time, lookback time.Duration) ([]model.DependencyLink, error) {
+	if lookback > s.maxLookback {
+		lookback = s.maxLookback
+	}
+	services := make(map[spanKey]string)
+	var children []child
+	err := s.reader.ScanSpans(context.Background(), endTs.Add(-lookback), endTs, func(span *model.Span) bool {
+		if span.Process == nil {
+			return true
+		}
+		services[spanKey{span.TraceID, span.SpanID}] = span.Process.ServiceName
+		if parentID := span.ParentSpanID(); parentID != 0 {
+			children = append(children, child{span.TraceID, parentID, span.Process.ServiceName})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	links := make(map[[2]string]uint64)
+	for _, child := range children {
+		parent, ok := services[spanKey{child.traceID, child.parentID}]
+		if ok && parent != child.service {
+			links[[2]string{parent, child.service}]++
+		}
+	}
+	dependencies := make([]model.DependencyLink, 0, len(links))
+	for link, count := range links {
+		dependencies = append(dependencies, model.DependencyLink{Parent: link[0], Child: link[1], CallCount: count})
+	}
+	return dependencies, nil
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dependencystore
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/plugin/storage/redis/spanstore"
+)
+
+var testTime = time.Unix(100, 0)
+
+func span(traceID uint64, spanID, parentSpanID model.SpanID, service string) *model.Span {
+	span := &model.Span{
+		TraceID:   model.NewTraceID(0, traceID),
+		SpanID:    spanID,
+		StartTime: testTime.Add(-time.Second),
+		Process:   model.NewProcess(service, nil),
+	}
+	if parentSpanID != 0 {
+		span.References = []model.SpanRef{model.NewChildOfRef(span.TraceID, parentSpanID)}
+	}
+	return span
+}
+
+func newTestStore(t *testing.T, maxLookback time.Duration) (*miniredis.Miniredis, *spanstore.SpanWriter, *DependencyStore, func()) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	keys := spanstore.Keys{Prefix: "jaeger:"}
+	writer := spanstore.NewSpanWriter(spanstore.SpanWriterParams{
+		Client:         client,
+		Keys:           keys,
+		MetricsFactory: metrics.NullFactory,
+		TTL:            100 * 365 * 24 * time.Hour,
+	})
+	store := NewDependencyStore(spanstore.NewSpanReader(client, keys), maxLookback)
+	return server, writer, store, func() {
+		client.Close()
+		server.Close()
+	}
+}
+
+func TestGetDependencies(t *testing.T) {
+	_, writer, store, closer := newTestStore(t, time.Hour)
+	defer closer()
+	old := span(5, 2, 1, "frontend")
+	old.StartTime = testTime.Add(-time.Minute)
+	for _, span := range []*model.Span{
+		span(1, 2, 1, "frontend"), span(1, 1, 0, "gateway"), span(1, 3, 2, "db"),
+		// a call within a service and a parent out of the trace
+		span(2, 1, 0, "gateway"), span(2, 2, 1, "gateway"), span(3, 2, 1, "db"),
+		span(4, 1, 0, "gateway"), span(4, 2, 1, "frontend"),
+		// a span without process and a span out of the window
+		{TraceID: model.NewTraceID(0, 4), SpanID: 3, StartTime: testTime.Add(-time.Second)}, old,
+	} {
+		require.NoError(t, writer.WriteSpan(span))
+	}
+
+	dependencies, err := store.GetDependencies(testTime, 10*time.Second)
+	require.NoError(t, err)
+	sort.Slice(dependencies, func(i, j int) bool { return dependencies[i].Parent < dependencies[j].Parent })
+	assert.Equal(t, []model.DependencyLink{
+		{Parent: "frontend", Child: "db", CallCount: 1},
+		{Parent: "gateway", Child: "frontend", CallCount: 2},
+	}, dependencies)
+}
+
+func TestGetDependenciesMaxLookback(t *testing.T) {
+	_, writer, store, closer := newTestStore(t, 10*time.Second)
+	defer closer()
+	parent := span(1, 1, 0, "gateway")
+	parent.StartTime = testTime.Add(-time.Minute)
+	child := span(1, 2, 1, "frontend")
+	child.StartTime = testTime.Add(-time.Minute)
+	require.NoError(t, writer.WriteSpan(parent))
+	require.NoError(t, writer.WriteSpan(child))
+
+	dependencies, err := store.GetDependencies(testTime, time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, dependencies)
+}
+
+func TestGetDependenciesError(t *testing.T) {
+	server, _, store, closer := newTestStore(t, time.Hour)
+	defer closer()
+	server.Close()
+	_, err := store.GetDependencies(testTime, time.Second)
+	assert.Error(t, err)
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redis
+
+import (
+	"errors"
+	"flag"
+
+	"github.com/go-redis/redis/v7"
+	"github.com/spf13/viper"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	redisDepStore "github.com/jaegertracing/jaeger/plugin/storage/redis/dependencystore"
+	redisSpanStore "github.com/jaegertracing/jaeger/plugin/storage/redis/spanstore"
+	"github.com/jaegertracing/jaeger/storage/dependencystore"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+// Factory implements storage.Factory for Redis backend.
+type Factory struct {
+	Options *Options
+
+	metricsFactory metrics.Factory
+	logger         *zap.Logger
+	client         redis.UniversalClient
+
+	// newClient creates the client of Redis, it is replaced by the tests
+	newClient func(options *Options) (redis.UniversalClient, error)
+}
+
+// NewFactory creates a new Factory.
+func NewFactory() *Factory {
+	return &Factory{
+		Options:   NewOptions(),
+		newClient: newClient,
+	}
+}
+
+// newClient returns a client of the sentinels if the master name is set, of the cluster if there are several
+// servers, else of the single server.
+func newClient(options *Options) (redis.UniversalClient, error) {
+	if len(options.Servers) == 0 {
+		return nil, errors.New("the servers of Redis must be set")
+	}
+	redisOptions := &redis.UniversalOptions{
+		Addrs:        options.Servers,
+		MasterName:   options.MasterName,
+		DB:           options.DB,
+		Password:     options.Password,
+		DialTimeout:  options.Timeout,
+		ReadTimeout:  options.Timeout,
+		WriteTimeout: options.Timeout,
+	}
+	if options.TLS.Enabled {
+		tlsConfig, err := options.TLS.Config()
+		if err != nil {
+			return nil, err
+		}
+		redisOptions.TLSConfig = tlsConfig
+	}
+	return redis.NewUniversalClient(redisOptions), nil
+}
+
+// AddFlags implements plugin.Configurable
+func (f *Factory) AddFlags(flagSet *flag.FlagSet) {
+	f.Options.AddFlags(flagSet)
+}
+
+// InitFromViper implements plugin.Configurable
+func (f *Factory) InitFromViper(v *viper.Viper) {
+	f.Options.InitFromViper(v)
+}
+
+// Initialize implements storage.Factory
+func (f *Factory) Initialize(metricsFactory metrics.Factory, logger *zap.Logger) error {
+	f.metricsFactory, f.logger = metricsFactory, logger
+	client, err := f.newClient(f.Options)
+	if err != nil {
+		return err
+	}
+	if err := client.Ping().Err(); err != nil {
+		client.Close()
+		return err
+	}
+	f.client = client
+	return nil
+}
+
+func (f *Factory) keys() redisSpanStore.Keys {
+	return redisSpanStore.Keys{Prefix: f.Options.KeyPrefix}
+}
+
+// CreateSpanReader implements storage.Factory
+func (f *Factory) CreateSpanReader() (spanstore.Reader, error) {
+	return redisSpanStore.NewSpanReader(f.client, f.keys()), nil
+}
+
+// CreateSpanWriter implements storage.Factory
+func (f *Factory) CreateSpanWriter() (spanstore.Writer, error) {
+	return redisSpanStore.NewSpanWriter(redisSpanStore.SpanWriterParams{
+		Client:         f.client,
+		Keys:           f.keys(),
+		MetricsFactory: f.metricsFactory,
+		TTL:            f.Options.TTL,
+	}), nil
+}
+
+// CreateDependencyReader implements storage.Factory
+func (f *Factory) CreateDependencyReader() (dependencystore.Reader, error) {
+	reader := redisSpanStore.NewSpanReader(f.client, f.keys())
+	return redisDepStore.NewDependencyStore(reader, f.Options.MaxLookback), nil
+}
+
+// Close implements io.Closer and closes the connections to Redis.
+func (f *Factory) Close() error {
+	if f.client == nil {
+		return nil
+	}
+	return f.client.Close()
+}
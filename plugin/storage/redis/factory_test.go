@@ -0,0 +1,90 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redis
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/pkg/config"
+	"github.com/jaegertracing/jaeger/storage"
+)
+
+var _ storage.Factory = new(Factory)
+
+func newTestFactory(flags ...string) *Factory {
+	f := NewFactory()
+	v, command := config.Viperize(f.AddFlags)
+	command.ParseFlags(flags)
+	f.InitFromViper(v)
+	return f
+}
+
+func TestFactory(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+	f := newTestFactory("--redis.servers=" + server.Addr())
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	defer f.Close()
+
+	_, err = f.CreateSpanReader()
+	require.NoError(t, err)
+	_, err = f.CreateSpanWriter()
+	require.NoError(t, err)
+	_, err = f.CreateDependencyReader()
+	require.NoError(t, err)
+}
+
+func TestFactoryInitializeErrors(t *testing.T) {
+	f := newTestFactory("--redis.servers=")
+	assert.EqualError(t, f.Initialize(metrics.NullFactory, zap.NewNop()), "the servers of Redis must be set")
+
+	// nothing listens on the address of the closed server
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	addr := server.Addr()
+	server.Close()
+	f = newTestFactory("--redis.servers=" + addr)
+	assert.Error(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+	assert.Nil(t, f.client)
+	assert.NoError(t, f.Close())
+
+	f = newTestFactory("--redis.tls.enabled=true", "--redis.tls.ca=/does/not/exist")
+	assert.Error(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+}
+
+func TestNewClient(t *testing.T) {
+	client, err := newClient(&Options{Servers: []string{"localhost:6379"}})
+	require.NoError(t, err)
+	assert.IsType(t, &redis.Client{}, client)
+	client.Close()
+
+	client, err = newClient(&Options{Servers: []string{"node1:6379", "node2:6379"}})
+	require.NoError(t, err)
+	assert.IsType(t, &redis.ClusterClient{}, client)
+	client.Close()
+
+	client, err = newClient(&Options{Servers: []string{"sentinel1:26379"}, MasterName: "master"})
+	require.NoError(t, err)
+	assert.IsType(t, &redis.Client{}, client)
+	client.Close()
+}
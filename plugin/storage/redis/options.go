@@ -0,0 +1,111 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redis
+
+import (
+	"flag"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/jaegertracing/jaeger/pkg/config/tlscfg"
+)
+
+const (
+	namespace = "redis"
+
+	suffixServers     = ".servers"
+	suffixMasterName  = ".master-name"
+	suffixDB          = ".db"
+	suffixPassword    = ".password"
+	suffixTimeout     = ".timeout"
+	suffixKeyPrefix   = ".key-prefix"
+	suffixTTL         = ".ttl"
+	suffixMaxLookback = ".max-lookback"
+
+	defaultServers     = "127.0.0.1:6379"
+	defaultTimeout     = 3 * time.Second
+	defaultKeyPrefix   = "jaeger:"
+	defaultTTL         = 6 * time.Hour
+	defaultMaxLookback = time.Hour
+)
+
+// Options contains the Redis configs and provides the ability
+// to bind them to command line flags
+type Options struct {
+	// Servers are the addresses of a single server, of the nodes of a cluster or of the sentinels.
+	Servers []string `mapstructure:"servers"`
+	// MasterName is the name of the master monitored by the sentinels, the servers are the sentinels if set.
+	MasterName string        `mapstructure:"master_name"`
+	DB         int           `mapstructure:"db"`
+	Password   string        `mapstructure:"password"`
+	Timeout    time.Duration `mapstructure:"timeout"`
+	KeyPrefix  string        `mapstructure:"key_prefix"`
+	TTL        time.Duration `mapstructure:"ttl"`
+	// MaxLookback bounds the time window of the spans read to compute the dependencies.
+	MaxLookback time.Duration  `mapstructure:"max_lookback"`
+	TLS         tlscfg.Options `mapstructure:"tls"`
+}
+
+// NewOptions creates the Options with the default configuration.
+func NewOptions() *Options {
+	return &Options{
+		Servers:     []string{defaultServers},
+		Timeout:     defaultTimeout,
+		KeyPrefix:   defaultKeyPrefix,
+		TTL:         defaultTTL,
+		MaxLookback: defaultMaxLookback,
+	}
+}
+
+func tlsFlagsConfig() tlscfg.ClientFlagsConfig {
+	return tlscfg.ClientFlagsConfig{
+		Prefix:         namespace,
+		ShowEnabled:    true,
+		ShowServerName: true,
+	}
+}
+
+// AddFlags adds flags for Options
+func (opt *Options) AddFlags(flagSet *flag.FlagSet) {
+	flagSet.String(namespace+suffixServers, strings.Join(opt.Servers, ","), "A comma-separated list of the addresses of the Redis server, of the nodes of the Redis cluster or of the sentinels")
+	flagSet.String(namespace+suffixMasterName, opt.MasterName, "The name of the master monitored by the sentinels, the servers are the sentinels if set")
+	flagSet.Int(namespace+suffixDB, opt.DB, "The database selected after connecting, not supported by Redis clusters")
+	flagSet.String(namespace+suffixPassword, opt.Password, "The password required by Redis")
+	flagSet.Duration(namespace+suffixTimeout, opt.Timeout, "The timeout of the connections, of the reads and of the writes")
+	flagSet.String(namespace+suffixKeyPrefix, opt.KeyPrefix, "The prefix of the keys, to share the database with other applications")
+	flagSet.Duration(namespace+suffixTTL, opt.TTL, "How long to keep the spans and their indexes before Redis expires them")
+	flagSet.Duration(namespace+suffixMaxLookback, opt.MaxLookback, "The maximum time window of the spans read to compute the dependencies")
+	tlsFlagsConfig().AddFlags(flagSet)
+}
+
+// InitFromViper initializes Options with properties from viper
+func (opt *Options) InitFromViper(v *viper.Viper) {
+	opt.Servers = nil
+	for _, server := range strings.Split(v.GetString(namespace+suffixServers), ",") {
+		if server = strings.TrimSpace(server); server != "" {
+			opt.Servers = append(opt.Servers, server)
+		}
+	}
+	opt.MasterName = v.GetString(namespace + suffixMasterName)
+	opt.DB = v.GetInt(namespace + suffixDB)
+	opt.Password = v.GetString(namespace + suffixPassword)
+	opt.Timeout = v.GetDuration(namespace + suffixTimeout)
+	opt.KeyPrefix = v.GetString(namespace + suffixKeyPrefix)
+	opt.TTL = v.GetDuration(namespace + suffixTTL)
+	opt.MaxLookback = v.GetDuration(namespace + suffixMaxLookback)
+	opt.TLS = tlsFlagsConfig().InitFromViper(v)
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jaegertracing/jaeger/pkg/config"
+)
+
+func TestDefaultOptions(t *testing.T) {
+	opts := NewOptions()
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags(nil)
+	opts.InitFromViper(v)
+	assert.Equal(t, NewOptions(), opts)
+}
+
+func TestOptionsWithFlags(t *testing.T) {
+	opts := NewOptions()
+	v, command := config.Viperize(opts.AddFlags)
+	command.ParseFlags([]string{
+		"--redis.servers=sentinel1:26379, sentinel2:26379",
+		"--redis.master-name=master",
+		"--redis.db=2",
+		"--redis.password=secret",
+		"--redis.timeout=1s",
+		"--redis.key-prefix=traces:",
+		"--redis.ttl=2h",
+		"--redis.max-lookback=30m",
+		"--redis.tls.enabled=true",
+	})
+	opts.InitFromViper(v)
+	assert.Equal(t, []string{"sentinel1:26379", "sentinel2:26379"}, opts.Servers)
+	assert.Equal(t, "master", opts.MasterName)
+	assert.Equal(t, 2, opts.DB)
+	assert.Equal(t, "secret", opts.Password)
+	assert.Equal(t, time.Second, opts.Timeout)
+	assert.Equal(t, "traces:", opts.KeyPrefix)
+	assert.Equal(t, 2*time.Hour, opts.TTL)
+	assert.Equal(t, 30*time.Minute, opts.MaxLookback)
+	assert.True(t, opts.TLS.Enabled)
+}
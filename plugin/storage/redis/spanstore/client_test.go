@@ -0,0 +1,36 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v7"
+	"github.com/stretchr/testify/require"
+)
+
+var testKeys = Keys{Prefix: "jaeger:"}
+
+// newTestClient returns a client of an in-memory Redis server, and the function closing them.
+func newTestClient(t *testing.T) (*miniredis.Miniredis, redis.UniversalClient, func()) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	return server, client, func() {
+		client.Close()
+		server.Close()
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+// separator separates the fields of the keys and of the members, it is not expected in the names of the services,
+// of the operations and of the tags.
+const separator = "\x00"
+
+// Keys builds the keys of Redis, all starting with the prefix:
+//   - trace:<trace ID> is the hash of the spans of the trace, in protobuf by span ID and hash;
+//   - services is the sorted set of the services, by time of their last span;
+//   - operations:<service> is the sorted set of the span kinds and names of the operations, by time of their last span;
+//   - index:<service>, index:<service>\x00<operation> and tag:<service>\x00<key>=<value> are the sorted sets of
+//     the trace IDs by start time of their last span.
+type Keys struct {
+	Prefix string
+}
+
+func (k Keys) trace(traceID model.TraceID) string {
+	return k.Prefix + "trace:" + traceIDString(traceID)
+}
+
+func (k Keys) services() string {
+	return k.Prefix + "services"
+}
+
+func (k Keys) operations(service string) string {
+	return k.Prefix + "operations:" + service
+}
+
+func (k Keys) serviceIndex(service string) string {
+	return k.Prefix + "index:" + service
+}
+
+func (k Keys) operationIndex(service, operation string) string {
+	return k.Prefix + "index:" + service + separator + operation
+}
+
+func (k Keys) tagIndex(service, key, value string) string {
+	return k.Prefix + "tag:" + service + separator + key + "=" + value
+}
+
+// traceIDString returns the trace ID always on 32 hexadecimal digits, unlike TraceID.String.
+func traceIDString(traceID model.TraceID) string {
+	return fmt.Sprintf("%016x%016x", traceID.High, traceID.Low)
+}
+
+func operationMember(spanKind, operation string) string {
+	return spanKind + separator + operation
+}
+
+func parseOperationMember(member string) (spanKind string, operation string, err error) {
+	i := strings.Index(member, separator)
+	if i < 0 {
+		return "", "", fmt.Errorf("invalid operation %q", member)
+	}
+	return member[:i], member[i+len(separator):], nil
+}
+
+func micros(t time.Time) float64 {
+	return float64(t.UnixNano() / int64(time.Microsecond))
+}
+
+// fromDomain returns the field and the value of the span in the hash of its trace.
+func fromDomain(span *model.Span) (string, []byte, error) {
+	data, err := proto.Marshal(span)
+	if err != nil {
+		return "", nil, err
+	}
+	// the spans sharing their ID, e.g. the client and the server spans of Zipkin, are told apart by their hash
+	hash := fnv.New64a()
+	hash.Write(data)
+	return fmt.Sprintf("%s-%016x", span.SpanID, hash.Sum64()), data, nil
+}
+
+func toDomain(data string) (*model.Span, error) {
+	span := &model.Span{}
+	if err := proto.Unmarshal([]byte(data), span); err != nil {
+		return nil, fmt.Errorf("cannot decode the span: %w", err)
+	}
+	return span, nil
+}
+
+// tags returns the tags of the span, of its process and of its logs as sorted key and value pairs.
+func tags(span *model.Span) [][2]string {
+	found := make(map[[2]string]struct{})
+	add := func(kvs model.KeyValues) {
+		for _, kv := range kvs {
+			found[[2]string{kv.Key, kv.AsString()}] = struct{}{}
+		}
+	}
+	add(span.Tags)
+	if span.Process != nil {
+		add(span.Process.Tags)
+	}
+	for _, log := range span.Logs {
+		add(log.Fields)
+	}
+	result := make([][2]string, 0, len(found))
+	for tag := range found {
+		result = append(result, tag)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i][0] != result[j][0] {
+			return result[i][0] < result[j][0]
+		}
+		return result[i][1] < result[j][1]
+	})
+	return result
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+var testTime = time.Date(2020, 9, 13, 12, 26, 40, 123456000, time.UTC)
+
+func testSpan(traceID model.TraceID, spanID model.SpanID, service string) *model.Span {
+	return &model.Span{
+		TraceID:       traceID,
+		SpanID:        spanID,
+		OperationName: "op",
+		References:    []model.SpanRef{model.NewChildOfRef(traceID, 1)},
+		StartTime:     testTime,
+		Duration:      1500 * time.Microsecond,
+		Tags:          model.KeyValues{model.String("span.kind", "server"), model.Int64("http.status_code", 200)},
+		Process:       model.NewProcess(service, []model.KeyValue{model.String("hostname", "host1")}),
+		Logs: []model.Log{{
+			Timestamp: testTime,
+			Fields:    model.KeyValues{model.String("event", "retry"), model.String("span.kind", "server")},
+		}},
+	}
+}
+
+func TestKeys(t *testing.T) {
+	keys := Keys{Prefix: "jaeger:"}
+	assert.Equal(t, "jaeger:trace:00000000000000010000000000000002", keys.trace(model.NewTraceID(1, 2)))
+	assert.Equal(t, "jaeger:services", keys.services())
+	assert.Equal(t, "jaeger:operations:svc", keys.operations("svc"))
+	assert.Equal(t, "jaeger:index:svc", keys.serviceIndex("svc"))
+	assert.Equal(t, "jaeger:index:svc\x00op", keys.operationIndex("svc", "op"))
+	assert.Equal(t, "jaeger:tag:svc\x00k=v", keys.tagIndex("svc", "k", "v"))
+}
+
+func TestOperationMember(t *testing.T) {
+	spanKind, operation, err := parseOperationMember(operationMember("server", "GET /"))
+	require.NoError(t, err)
+	assert.Equal(t, "server", spanKind)
+	assert.Equal(t, "GET /", operation)
+
+	_, _, err = parseOperationMember("invalid")
+	assert.EqualError(t, err, `invalid operation "invalid"`)
+}
+
+func TestFromDomain(t *testing.T) {
+	span := testSpan(model.NewTraceID(0, 0x10), 2, "svc")
+	field, data, err := fromDomain(span)
+	require.NoError(t, err)
+	assert.Regexp(t, "^0000000000000002-[0-9a-f]{16}$", field)
+
+	decoded, err := toDomain(string(data))
+	require.NoError(t, err)
+	assert.Equal(t, span, decoded)
+
+	// the field of the spans sharing their ID differs
+	span.Process.ServiceName = "other"
+	other, _, err := fromDomain(span)
+	require.NoError(t, err)
+	assert.NotEqual(t, field, other)
+}
+
+func TestToDomainError(t *testing.T) {
+	_, err := toDomain("\xff")
+	assert.Error(t, err)
+}
+
+func TestTags(t *testing.T) {
+	assert.Equal(t, [][2]string{
+		{"event", "retry"},
+		{"hostname", "host1"},
+		{"http.status_code", "200"},
+		{"span.kind", "server"},
+	}, tags(testSpan(model.NewTraceID(0, 1), 1, "svc")))
+	assert.Empty(t, tags(&model.Span{}))
+}
@@ -0,0 +1,357 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+const (
+	defaultNumTraces = 100
+
+	// limitMultiple is the number of trace IDs read from the index, per trace searched, at each page
+	limitMultiple = 3
+)
+
+var (
+	// ErrServiceNameNotSet occurs when attempting to query with an empty service name
+	ErrServiceNameNotSet = errors.New("service Name must be set")
+
+	// ErrMalformedRequestObject occurs when a request object is nil
+	ErrMalformedRequestObject = errors.New("malformed request object")
+
+	// ErrStartAndEndTimeNotSet occurs when start time and end time are not set
+	ErrStartAndEndTimeNotSet = errors.New("start and End Time must be set")
+
+	// ErrStartTimeMinGreaterThanMax occurs when start time min is above start time max
+	ErrStartTimeMinGreaterThanMax = errors.New("start Time Minimum is above Maximum")
+
+	// ErrDurationMinGreaterThanMax occurs when duration min is above duration max
+	ErrDurationMinGreaterThanMax = errors.New("duration Minimum is above Maximum")
+)
+
+// SpanReader can query for and load traces from Redis.
+type SpanReader struct {
+	client redis.UniversalClient
+	keys   Keys
+}
+
+// NewSpanReader returns a new SpanReader.
+func NewSpanReader(client redis.UniversalClient, keys Keys) *SpanReader {
+	return &SpanReader{client: client, keys: keys}
+}
+
+// GetTrace takes a traceID and returns a Trace associated with that traceID
+func (r *SpanReader) GetTrace(ctx context.Context, traceID model.TraceID) (*model.Trace, error) {
+	traces, err := r.getTraces([]model.TraceID{traceID})
+	if err != nil {
+		return nil, err
+	}
+	if len(traces) == 0 {
+		return nil, spanstore.ErrTraceNotFound
+	}
+	return traces[0], nil
+}
+
+// getTraces returns the traces of the IDs in a single pipeline, skipping the traces not found.
+func (r *SpanReader) getTraces(traceIDs []model.TraceID) ([]*model.Trace, error) {
+	cmds := make([]*redis.StringSliceCmd, len(traceIDs))
+	_, err := r.client.Pipelined(func(pipe redis.Pipeliner) error {
+		for i, traceID := range traceIDs {
+			cmds[i] = pipe.HVals(r.keys.trace(traceID))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	traces := make([]*model.Trace, 0, len(traceIDs))
+	for _, cmd := range cmds {
+		values := cmd.Val()
+		if len(values) == 0 {
+			continue
+		}
+		trace := &model.Trace{}
+		for _, value := range values {
+			span, err := toDomain(value)
+			if err != nil {
+				return nil, err
+			}
+			trace.Spans = append(trace.Spans, span)
+		}
+		traces = append(traces, trace)
+	}
+	return traces, nil
+}
+
+// GetServices returns all services traced by Jaeger
+func (r *SpanReader) GetServices(ctx context.Context) ([]string, error) {
+	services, err := r.client.ZRange(r.keys.services(), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(services)
+	return services, nil
+}
+
+// GetOperations returns all operations for a specific service traced by Jaeger
+func (r *SpanReader) GetOperations(ctx context.Context, query spanstore.OperationQueryParameters) ([]spanstore.Operation, error) {
+	members, err := r.client.ZRange(r.keys.operations(query.ServiceName), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	operations := []spanstore.Operation{}
+	for _, member := range members {
+		spanKind, name, err := parseOperationMember(member)
+		if err != nil {
+			return nil, err
+		}
+		if query.SpanKind == "" || query.SpanKind == spanKind {
+			operations = append(operations, spanstore.Operation{Name: name, SpanKind: spanKind})
+		}
+	}
+	sort.Slice(operations, func(i, j int) bool {
+		if operations[i].Name != operations[j].Name {
+			return operations[i].Name < operations[j].Name
+		}
+		return operations[i].SpanKind < operations[j].SpanKind
+	})
+	return operations, nil
+}
+
+// FindTraces retrieves traces that match the traceQuery
+func (r *SpanReader) FindTraces(ctx context.Context, query *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
+	traceIDs, err := r.FindTraceIDs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	// the traces expired since they were found are skipped
+	return r.getTraces(traceIDs)
+}
+
+// FindTraceIDs retrieves the IDs of the traces that match the traceQuery, the most recent first.
+// The trace IDs are read by pages from the index of the service, or of the operation, in the time range; they are
+// kept if they are in the indexes of the tag equalities, then if a span of the service, and of the operation, of
+// the trace has a duration in the range. The other tag filters are left to the query service.
+func (r *SpanReader) FindTraceIDs(ctx context.Context, query *spanstore.TraceQueryParameters) ([]model.TraceID, error) {
+	if err := validateQuery(query); err != nil {
+		return nil, err
+	}
+	index := r.keys.serviceIndex(query.ServiceName)
+	if query.OperationName != "" {
+		index = r.keys.operationIndex(query.ServiceName, query.OperationName)
+	}
+	var tagIndexes []string
+	for key, value := range query.Tags {
+		tagIndexes = append(tagIndexes, r.keys.tagIndex(query.ServiceName, key, value))
+	}
+	for _, filter := range query.TagFilters {
+		if filter.Operator == spanstore.TagEquals {
+			tagIndexes = append(tagIndexes, r.keys.tagIndex(query.ServiceName, filter.Key, filter.Value))
+		}
+	}
+	numTraces := query.NumTraces
+	if numTraces <= 0 {
+		numTraces = defaultNumTraces
+	}
+	pageSize := int64(numTraces * limitMultiple)
+	traceIDs := make([]model.TraceID, 0, numTraces)
+	for offset := int64(0); len(traceIDs) < numTraces; offset += pageSize {
+		members, err := r.client.ZRevRangeByScore(index, &redis.ZRangeBy{
+			Min:    strconv.FormatFloat(micros(query.StartTimeMin), 'f', -1, 64),
+			Max:    strconv.FormatFloat(micros(query.StartTimeMax), 'f', -1, 64),
+			Offset: offset,
+			Count:  pageSize,
+		}).Result()
+		if err != nil {
+			return nil, err
+		}
+		candidates := make([]model.TraceID, 0, len(members))
+		for _, member := range members {
+			traceID, err := model.TraceIDFromString(member)
+			if err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, traceID)
+		}
+		if candidates, err = r.filterTags(candidates, tagIndexes); err != nil {
+			return nil, err
+		}
+		if candidates, err = r.filterDuration(candidates, query); err != nil {
+			return nil, err
+		}
+		for _, traceID := range candidates {
+			if len(traceIDs) == numTraces {
+				break
+			}
+			traceIDs = append(traceIDs, traceID)
+		}
+		if int64(len(members)) < pageSize {
+			break
+		}
+	}
+	return traceIDs, nil
+}
+
+// filterTags returns the trace IDs found in all the tag indexes, in a single pipeline.
+func (r *SpanReader) filterTags(traceIDs []model.TraceID, tagIndexes []string) ([]model.TraceID, error) {
+	if len(tagIndexes) == 0 || len(traceIDs) == 0 {
+		return traceIDs, nil
+	}
+	cmds := make([][]*redis.FloatCmd, len(traceIDs))
+	_, err := r.client.Pipelined(func(pipe redis.Pipeliner) error {
+		for i, traceID := range traceIDs {
+			for _, tagIndex := range tagIndexes {
+				cmds[i] = append(cmds[i], pipe.ZScore(tagIndex, traceIDString(traceID)))
+			}
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	var result []model.TraceID
+	for i, traceID := range traceIDs {
+		found := true
+		for _, cmd := range cmds[i] {
+			if cmd.Err() == redis.Nil {
+				found = false
+				break
+			}
+		}
+		if found {
+			result = append(result, traceID)
+		}
+	}
+	return result, nil
+}
+
+// filterDuration returns the traces with a span of the service, and of the operation, with a duration in the range.
+func (r *SpanReader) filterDuration(traceIDs []model.TraceID, query *spanstore.TraceQueryParameters) ([]model.TraceID, error) {
+	if (query.DurationMin == 0 && query.DurationMax == 0) || len(traceIDs) == 0 {
+		return traceIDs, nil
+	}
+	traces, err := r.getTraces(traceIDs)
+	if err != nil {
+		return nil, err
+	}
+	var result []model.TraceID
+	for _, trace := range traces {
+		for _, span := range trace.Spans {
+			if matchesDuration(span, query) {
+				result = append(result, span.TraceID)
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+func matchesDuration(span *model.Span, query *spanstore.TraceQueryParameters) bool {
+	if span.Process == nil || span.Process.ServiceName != query.ServiceName {
+		return false
+	}
+	if query.OperationName != "" && span.OperationName != query.OperationName {
+		return false
+	}
+	if query.DurationMin != 0 && span.Duration < query.DurationMin {
+		return false
+	}
+	return query.DurationMax == 0 || span.Duration <= query.DurationMax
+}
+
+// ScanSpans calls fn with the spans of the traces of the services started in the time range, until it returns false.
+func (r *SpanReader) ScanSpans(ctx context.Context, startTimeMin, startTimeMax time.Time, fn func(span *model.Span) bool) error {
+	services, err := r.GetServices(ctx)
+	if err != nil {
+		return err
+	}
+	cmds := make([]*redis.StringSliceCmd, len(services))
+	_, err = r.client.Pipelined(func(pipe redis.Pipeliner) error {
+		for i, service := range services {
+			cmds[i] = pipe.ZRangeByScore(r.keys.serviceIndex(service), &redis.ZRangeBy{
+				Min: strconv.FormatFloat(micros(startTimeMin), 'f', -1, 64),
+				Max: strconv.FormatFloat(micros(startTimeMax), 'f', -1, 64),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	found := make(map[string]struct{})
+	var traceIDs []model.TraceID
+	for _, cmd := range cmds {
+		for _, member := range cmd.Val() {
+			if _, ok := found[member]; ok {
+				continue
+			}
+			found[member] = struct{}{}
+			traceID, err := model.TraceIDFromString(member)
+			if err != nil {
+				return err
+			}
+			traceIDs = append(traceIDs, traceID)
+		}
+	}
+	// the traces are loaded by batches for the pipelines to stay small
+	for len(traceIDs) > 0 {
+		batch := traceIDs
+		if len(batch) > defaultNumTraces {
+			batch = batch[:defaultNumTraces]
+		}
+		traceIDs = traceIDs[len(batch):]
+		traces, err := r.getTraces(batch)
+		if err != nil {
+			return err
+		}
+		for _, trace := range traces {
+			for _, span := range trace.Spans {
+				if !fn(span) {
+					return nil
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func validateQuery(p *spanstore.TraceQueryParameters) error {
+	if p == nil {
+		return ErrMalformedRequestObject
+	}
+	if p.ServiceName == "" {
+		return ErrServiceNameNotSet
+	}
+	if p.StartTimeMin.IsZero() || p.StartTimeMax.IsZero() {
+		return ErrStartAndEndTimeNotSet
+	}
+	if p.StartTimeMax.Before(p.StartTimeMin) {
+		return ErrStartTimeMinGreaterThanMax
+	}
+	if p.DurationMin != 0 && p.DurationMax != 0 && p.DurationMin > p.DurationMax {
+		return ErrDurationMinGreaterThanMax
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+func TestGetTrace(t *testing.T) {
+	_, client, closer := newTestClient(t)
+	defer closer()
+	writer, _ := newTestWriter(client, time.Hour)
+	reader := NewSpanReader(client, testKeys)
+	traceID := model.NewTraceID(0, 1)
+	require.NoError(t, writer.WriteSpan(testSpan(traceID, 1, "svc")))
+	require.NoError(t, writer.WriteSpan(testSpan(traceID, 2, "svc")))
+
+	trace, err := reader.GetTrace(context.Background(), traceID)
+	require.NoError(t, err)
+	assert.Len(t, trace.Spans, 2)
+
+	_, err = reader.GetTrace(context.Background(), model.NewTraceID(0, 2))
+	assert.Equal(t, spanstore.ErrTraceNotFound, err)
+}
+
+func TestGetTraceDecodingError(t *testing.T) {
+	server, client, closer := newTestClient(t)
+	defer closer()
+	reader := NewSpanReader(client, testKeys)
+	server.HSet("jaeger:trace:00000000000000000000000000000001", "span", "\xff")
+
+	_, err := reader.GetTrace(context.Background(), model.NewTraceID(0, 1))
+	assert.Error(t, err)
+}
+
+func TestGetServicesAndOperations(t *testing.T) {
+	_, client, closer := newTestClient(t)
+	defer closer()
+	writer, _ := newTestWriter(client, time.Hour)
+	reader := NewSpanReader(client, testKeys)
+	require.NoError(t, writer.WriteSpan(testSpan(model.NewTraceID(0, 1), 1, "svc2")))
+	span := testSpan(model.NewTraceID(0, 1), 2, "svc1")
+	span.Tags = model.KeyValues{model.String("span.kind", "client")}
+	require.NoError(t, writer.WriteSpan(span))
+	span = testSpan(model.NewTraceID(0, 1), 3, "svc1")
+	span.OperationName = "a"
+	require.NoError(t, writer.WriteSpan(span))
+
+	services, err := reader.GetServices(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"svc1", "svc2"}, services)
+
+	operations, err := reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "svc1"})
+	require.NoError(t, err)
+	assert.Equal(t, []spanstore.Operation{
+		{Name: "a", SpanKind: "server"},
+		{Name: "op", SpanKind: "client"},
+	}, operations)
+
+	operations, err = reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "svc1", SpanKind: "client"})
+	require.NoError(t, err)
+	assert.Equal(t, []spanstore.Operation{{Name: "op", SpanKind: "client"}}, operations)
+
+	operations, err = reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "unknown"})
+	require.NoError(t, err)
+	assert.Empty(t, operations)
+}
+
+func TestGetOperationsInvalidMember(t *testing.T) {
+	server, client, closer := newTestClient(t)
+	defer closer()
+	reader := NewSpanReader(client, testKeys)
+	server.ZAdd("jaeger:operations:svc", 1, "invalid")
+
+	_, err := reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "svc"})
+	assert.EqualError(t, err, `invalid operation "invalid"`)
+}
+
+func TestFindTraces(t *testing.T) {
+	_, client, closer := newTestClient(t)
+	defer closer()
+	writer, _ := newTestWriter(client, time.Hour)
+	reader := NewSpanReader(client, testKeys)
+	for i := 1; i <= 10; i++ {
+		span := testSpan(model.NewTraceID(0, uint64(i)), 1, "svc")
+		span.StartTime = testTime.Add(time.Duration(i) * time.Second)
+		span.Duration = time.Duration(i) * time.Millisecond
+		if i%2 == 0 {
+			span.OperationName = "even"
+			span.Tags = append(span.Tags, model.String("parity", "even"))
+		}
+		require.NoError(t, writer.WriteSpan(span))
+	}
+	query := func() *spanstore.TraceQueryParameters {
+		return &spanstore.TraceQueryParameters{
+			ServiceName:  "svc",
+			StartTimeMin: testTime,
+			StartTimeMax: testTime.Add(time.Minute),
+		}
+	}
+	traceIDs := func(q *spanstore.TraceQueryParameters) []uint64 {
+		found, err := reader.FindTraceIDs(context.Background(), q)
+		require.NoError(t, err)
+		var ids []uint64
+		for _, traceID := range found {
+			ids = append(ids, traceID.Low)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []uint64{10, 9, 8, 7, 6, 5, 4, 3, 2, 1}, traceIDs(query()))
+
+	q := query()
+	q.NumTraces = 3
+	assert.Equal(t, []uint64{10, 9, 8}, traceIDs(q))
+
+	q = query()
+	q.StartTimeMax = testTime.Add(5 * time.Second)
+	assert.Equal(t, []uint64{5, 4, 3, 2, 1}, traceIDs(q))
+
+	q = query()
+	q.OperationName = "even"
+	assert.Equal(t, []uint64{10, 8, 6, 4, 2}, traceIDs(q))
+
+	// the tag filters are read by pages smaller than the index
+	q = query()
+	q.NumTraces = 2
+	q.Tags = map[string]string{"parity": "even"}
+	assert.Equal(t, []uint64{10, 8}, traceIDs(q))
+
+	q = query()
+	q.TagFilters = []spanstore.TagFilter{
+		{Key: "parity", Operator: spanstore.TagEquals, Value: "even"},
+		{Key: "hostname", Operator: spanstore.TagNotEquals, Value: "host1"},
+	}
+	assert.Equal(t, []uint64{10, 8, 6, 4, 2}, traceIDs(q))
+
+	q = query()
+	q.DurationMin = 3 * time.Millisecond
+	q.DurationMax = 5 * time.Millisecond
+	assert.Equal(t, []uint64{5, 4, 3}, traceIDs(q))
+
+	q = query()
+	q.ServiceName = "unknown"
+	assert.Empty(t, traceIDs(q))
+
+	q = query()
+	q.NumTraces = 2
+	traces, err := reader.FindTraces(context.Background(), q)
+	require.NoError(t, err)
+	require.Len(t, traces, 2)
+	assert.Equal(t, model.NewTraceID(0, 10), traces[0].Spans[0].TraceID)
+	assert.Equal(t, model.NewTraceID(0, 9), traces[1].Spans[0].TraceID)
+}
+
+func TestFindTraceIDsInvalidMember(t *testing.T) {
+	server, client, closer := newTestClient(t)
+	defer closer()
+	reader := NewSpanReader(client, testKeys)
+	server.ZAdd("jaeger:index:svc", micros(testTime), "invalid")
+
+	_, err := reader.FindTraceIDs(context.Background(), &spanstore.TraceQueryParameters{
+		ServiceName:  "svc",
+		StartTimeMin: testTime,
+		StartTimeMax: testTime,
+	})
+	assert.Error(t, err)
+}
+
+func TestFindTracesErrors(t *testing.T) {
+	server, client, closer := newTestClient(t)
+	defer closer()
+	reader := NewSpanReader(client, testKeys)
+	testCases := []struct {
+		query *spanstore.TraceQueryParameters
+		err   error
+	}{
+		{query: nil, err: ErrMalformedRequestObject},
+		{query: &spanstore.TraceQueryParameters{}, err: ErrServiceNameNotSet},
+		{query: &spanstore.TraceQueryParameters{ServiceName: "svc"}, err: ErrStartAndEndTimeNotSet},
+		{
+			query: &spanstore.TraceQueryParameters{ServiceName: "svc", StartTimeMin: testTime, StartTimeMax: testTime.Add(-time.Second)},
+			err:   ErrStartTimeMinGreaterThanMax,
+		},
+		{
+			query: &spanstore.TraceQueryParameters{ServiceName: "svc", StartTimeMin: testTime, StartTimeMax: testTime, DurationMin: 2, DurationMax: 1},
+			err:   ErrDurationMinGreaterThanMax,
+		},
+	}
+	for _, testCase := range testCases {
+		_, err := reader.FindTraces(context.Background(), testCase.query)
+		assert.Equal(t, testCase.err, err)
+	}
+
+	server.Close()
+	_, err := reader.FindTraces(context.Background(), &spanstore.TraceQueryParameters{
+		ServiceName:  "svc",
+		StartTimeMin: testTime,
+		StartTimeMax: testTime,
+	})
+	assert.Error(t, err)
+	_, err = reader.GetServices(context.Background())
+	assert.Error(t, err)
+	_, err = reader.GetOperations(context.Background(), spanstore.OperationQueryParameters{ServiceName: "svc"})
+	assert.Error(t, err)
+	_, err = reader.GetTrace(context.Background(), model.NewTraceID(0, 1))
+	assert.Error(t, err)
+}
+
+func TestScanSpans(t *testing.T) {
+	_, client, closer := newTestClient(t)
+	defer closer()
+	writer, _ := newTestWriter(client, time.Hour)
+	reader := NewSpanReader(client, testKeys)
+	// the trace is indexed by both services but scanned once
+	require.NoError(t, writer.WriteSpan(testSpan(model.NewTraceID(0, 1), 1, "svc1")))
+	require.NoError(t, writer.WriteSpan(testSpan(model.NewTraceID(0, 1), 2, "svc2")))
+	late := testSpan(model.NewTraceID(0, 2), 1, "svc1")
+	late.StartTime = testTime.Add(time.Hour)
+	require.NoError(t, writer.WriteSpan(late))
+
+	var spans []*model.Span
+	err := reader.ScanSpans(context.Background(), testTime, testTime.Add(time.Second), func(span *model.Span) bool {
+		spans = append(spans, span)
+		return true
+	})
+	require.NoError(t, err)
+	assert.Len(t, spans, 2)
+
+	spans = nil
+	err = reader.ScanSpans(context.Background(), testTime, testTime.Add(time.Second), func(span *model.Span) bool {
+		spans = append(spans, span)
+		return false
+	})
+	require.NoError(t, err)
+	assert.Len(t, spans, 1)
+}
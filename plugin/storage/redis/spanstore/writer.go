@@ -0,0 +1,96 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/jaegertracing/jaeger/model"
+	storageMetrics "github.com/jaegertracing/jaeger/storage/spanstore/metrics"
+)
+
+// SpanWriterParams holds constructor parameters for NewSpanWriter
+type SpanWriterParams struct {
+	Client         redis.UniversalClient
+	Keys           Keys
+	MetricsFactory metrics.Factory
+	// TTL is the retention of the spans and of their indexes.
+	TTL time.Duration
+}
+
+// SpanWriter puts the spans and their indexes into Redis.
+type SpanWriter struct {
+	client redis.UniversalClient
+	keys   Keys
+	ttl    time.Duration
+	spans  *storageMetrics.WriteMetrics
+	// now is the time of the writes, it is replaced by the tests
+	now func() time.Time
+}
+
+// NewSpanWriter creates a SpanWriter.
+func NewSpanWriter(p SpanWriterParams) *SpanWriter {
+	return &SpanWriter{
+		client: p.Client,
+		keys:   p.Keys,
+		ttl:    p.TTL,
+		spans:  storageMetrics.NewWriteMetrics(p.MetricsFactory, "spans"),
+		now:    time.Now,
+	}
+}
+
+// WriteSpan puts the span into the hash of its trace, and its trace ID into the indexes of its service, of its
+// operation and of its tags, in a single pipeline. The expired trace IDs are removed from the indexes, and the
+// keys expire after the TTL since their last write.
+func (w *SpanWriter) WriteSpan(span *model.Span) error {
+	field, data, err := fromDomain(span)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	_, err = w.client.Pipelined(func(pipe redis.Pipeliner) error {
+		traceKey := w.keys.trace(span.TraceID)
+		pipe.HSet(traceKey, field, data)
+		pipe.Expire(traceKey, w.ttl)
+		if span.Process == nil || span.Process.ServiceName == "" {
+			return nil
+		}
+		service := span.Process.ServiceName
+		now := w.now()
+		expired := "(" + strconv.FormatFloat(micros(now.Add(-w.ttl)), 'f', -1, 64)
+		add := func(key string, score float64, member string) {
+			pipe.ZAdd(key, &redis.Z{Score: score, Member: member})
+			pipe.ZRemRangeByScore(key, "-inf", expired)
+			pipe.Expire(key, w.ttl)
+		}
+		spanKind, _ := span.GetSpanKind()
+		add(w.keys.services(), micros(now), service)
+		add(w.keys.operations(service), micros(now), operationMember(spanKind, span.OperationName))
+		traceID := traceIDString(span.TraceID)
+		startTime := micros(span.StartTime)
+		add(w.keys.serviceIndex(service), startTime, traceID)
+		add(w.keys.operationIndex(service, span.OperationName), startTime, traceID)
+		for _, tag := range tags(span) {
+			add(w.keys.tagIndex(service, tag[0], tag[1]), startTime, traceID)
+		}
+		return nil
+	})
+	w.spans.Emit(err, time.Since(start))
+	return err
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+func newTestWriter(client redis.UniversalClient, ttl time.Duration) (*SpanWriter, *metricstest.Factory) {
+	metricsFactory := metricstest.NewFactory(0)
+	writer := NewSpanWriter(SpanWriterParams{
+		Client:         client,
+		Keys:           testKeys,
+		MetricsFactory: metricsFactory,
+		TTL:            ttl,
+	})
+	writer.now = func() time.Time {
+		return testTime.Add(time.Minute)
+	}
+	return writer, metricsFactory
+}
+
+func TestWriteSpan(t *testing.T) {
+	server, client, closer := newTestClient(t)
+	defer closer()
+	writer, metricsFactory := newTestWriter(client, time.Hour)
+	traceID := model.NewTraceID(0, 1)
+
+	require.NoError(t, writer.WriteSpan(testSpan(traceID, 1, "svc")))
+	require.NoError(t, writer.WriteSpan(testSpan(traceID, 2, "svc")))
+
+	traceKey := "jaeger:trace:00000000000000000000000000000001"
+	fields, err := server.HKeys(traceKey)
+	require.NoError(t, err)
+	assert.Len(t, fields, 2)
+	assert.Equal(t, time.Hour, server.TTL(traceKey))
+
+	services, err := server.ZMembers("jaeger:services")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"svc"}, services)
+	operations, err := server.ZMembers("jaeger:operations:svc")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"server\x00op"}, operations)
+	for _, key := range []string{"jaeger:index:svc", "jaeger:index:svc\x00op", "jaeger:tag:svc\x00http.status_code=200", "jaeger:tag:svc\x00event=retry"} {
+		members, err := server.ZMembers(key)
+		require.NoError(t, err, key)
+		assert.Equal(t, []string{"00000000000000000000000000000001"}, members, key)
+		score, err := server.ZScore(key, members[0])
+		require.NoError(t, err)
+		assert.Equal(t, micros(testTime), score)
+		assert.Equal(t, time.Hour, server.TTL(key))
+	}
+
+	metricsFactory.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "spans.attempts", Value: 2},
+		metricstest.ExpectedMetric{Name: "spans.inserts", Value: 2})
+}
+
+func TestWriteSpanRemovesExpiredTraceIDs(t *testing.T) {
+	server, client, closer := newTestClient(t)
+	defer closer()
+	writer, _ := newTestWriter(client, time.Hour)
+
+	old := testSpan(model.NewTraceID(0, 1), 1, "svc")
+	old.StartTime = testTime.Add(-2 * time.Hour)
+	require.NoError(t, writer.WriteSpan(old))
+	require.NoError(t, writer.WriteSpan(testSpan(model.NewTraceID(0, 2), 1, "svc")))
+
+	members, err := server.ZMembers("jaeger:index:svc")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"00000000000000000000000000000002"}, members)
+}
+
+func TestWriteSpanWithoutService(t *testing.T) {
+	server, client, closer := newTestClient(t)
+	defer closer()
+	writer, _ := newTestWriter(client, time.Hour)
+	require.NoError(t, writer.WriteSpan(&model.Span{TraceID: model.NewTraceID(0, 1), SpanID: 1}))
+	// the span is not indexed
+	assert.Equal(t, []string{"jaeger:trace:00000000000000000000000000000001"}, server.Keys())
+}
+
+func TestWriteSpanError(t *testing.T) {
+	server, client, closer := newTestClient(t)
+	defer closer()
+	writer, metricsFactory := newTestWriter(client, time.Hour)
+	server.Close()
+
+	assert.Error(t, writer.WriteSpan(testSpan(model.NewTraceID(0, 1), 1, "svc")))
+	metricsFactory.AssertCounterMetrics(t, metricstest.ExpectedMetric{Name: "spans.errors", Value: 1})
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiered
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/dependencystore"
+)
+
+// DependencyReader is a dependencies Reader querying the hot backend first, and falling through
+// to the cold backend when the hot one fails or finds no dependencies.
+type DependencyReader struct {
+	hot    dependencystore.Reader
+	cold   dependencystore.Reader
+	logger *zap.Logger
+}
+
+// NewDependencyReader creates a DependencyReader.
+func NewDependencyReader(hot, cold dependencystore.Reader, logger *zap.Logger) *DependencyReader {
+	return &DependencyReader{
+		hot:    hot,
+		cold:   cold,
+		logger: logger,
+	}
+}
+
+// GetDependencies implements dependencystore.Reader
+func (r *DependencyReader) GetDependencies(endTs time.Time, lookback time.Duration) ([]model.DependencyLink, error) {
+	dependencies, err := r.hot.GetDependencies(endTs, lookback)
+	if err != nil {
+		r.logger.Warn("Failed to read the dependencies from the hot storage, falling through to the cold storage", zap.Error(err))
+	} else if len(dependencies) > 0 {
+		return dependencies, nil
+	}
+	return r.cold.GetDependencies(endTs, lookback)
+}
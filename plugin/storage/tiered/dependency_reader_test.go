@@ -0,0 +1,55 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiered
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	depStoreMocks "github.com/jaegertracing/jaeger/storage/dependencystore/mocks"
+)
+
+func TestGetDependencies(t *testing.T) {
+	endTs := time.Now()
+	hotLinks := []model.DependencyLink{{Parent: "a", Child: "b", CallCount: 1}}
+	coldLinks := []model.DependencyLink{{Parent: "a", Child: "c", CallCount: 2}}
+	testCases := []struct {
+		name     string
+		hot      []model.DependencyLink
+		hotErr   error
+		expected []model.DependencyLink
+	}{
+		{name: "hot", hot: hotLinks, expected: hotLinks},
+		{name: "no hot dependencies", expected: coldLinks},
+		{name: "hot error", hotErr: errors.New("hot-error"), expected: coldLinks},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			hot, cold := new(depStoreMocks.Reader), new(depStoreMocks.Reader)
+			hot.On("GetDependencies", endTs, time.Hour).Return(testCase.hot, testCase.hotErr)
+			cold.On("GetDependencies", endTs, time.Hour).Return(coldLinks, nil)
+			r := NewDependencyReader(hot, cold, zap.NewNop())
+			links, err := r.GetDependencies(endTs, time.Hour)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, links)
+		})
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiered
+
+import (
+	"flag"
+	"io"
+
+	"github.com/spf13/viper"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/pkg/multierror"
+	"github.com/jaegertracing/jaeger/plugin"
+	"github.com/jaegertracing/jaeger/storage"
+	"github.com/jaegertracing/jaeger/storage/dependencystore"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+// Tier is the backend of one tier of the storage.
+type Tier struct {
+	// Type is the type of the backend, only used to describe the flags.
+	Type    string
+	Factory storage.Factory
+}
+
+// Factory implements storage.Factory by composing a hot backend with a short retention, e.g. memory
+// or Badger, and a cold backend keeping the spans longer, e.g. Elasticsearch, Cassandra or Parquet.
+// The spans are written to both backends and read from the hot one first.
+type Factory struct {
+	Options *Options
+
+	hot  Tier
+	cold Tier
+
+	// hotFlags and coldFlags are the names of the flags of the backends, without their prefixes
+	hotFlags  []string
+	coldFlags []string
+
+	logger *zap.Logger
+}
+
+// NewFactory creates a new Factory.
+func NewFactory(hot, cold Tier) *Factory {
+	return &Factory{
+		Options: &Options{},
+		hot:     hot,
+		cold:    cold,
+	}
+}
+
+// AddFlags implements plugin.Configurable. The flags of the backends are registered with
+// the "tiered.hot." and "tiered.cold." prefixes.
+func (f *Factory) AddFlags(flagSet *flag.FlagSet) {
+	flagSet.String(HotStorageTypeFlag, f.hot.Type, "The type of backend of the hot tier, e.g. memory or badger")
+	flagSet.String(ColdStorageTypeFlag, f.cold.Type, "The type of backend of the cold tier, e.g. elasticsearch, cassandra or parquet")
+	f.Options.AddFlags(flagSet)
+	f.hotFlags = addPrefixedFlags(flagSet, f.hot.Factory, hotFlagPrefix, "(hot storage) ")
+	f.coldFlags = addPrefixedFlags(flagSet, f.cold.Factory, coldFlagPrefix, "(cold storage) ")
+}
+
+func addPrefixedFlags(flagSet *flag.FlagSet, factory storage.Factory, prefix, usagePrefix string) []string {
+	conf, ok := factory.(plugin.Configurable)
+	if !ok {
+		return nil
+	}
+	tierFlagSet := flag.NewFlagSet(prefix, flag.ContinueOnError)
+	conf.AddFlags(tierFlagSet)
+	var names []string
+	tierFlagSet.VisitAll(func(fl *flag.Flag) {
+		flagSet.Var(fl.Value, prefix+fl.Name, usagePrefix+fl.Usage)
+		names = append(names, fl.Name)
+	})
+	return names
+}
+
+// InitFromViper implements plugin.Configurable
+func (f *Factory) InitFromViper(v *viper.Viper) {
+	f.Options.InitFromViper(v)
+	initPrefixedFromViper(v, f.hot.Factory, hotFlagPrefix, f.hotFlags)
+	initPrefixedFromViper(v, f.cold.Factory, coldFlagPrefix, f.coldFlags)
+}
+
+func initPrefixedFromViper(v *viper.Viper, factory storage.Factory, prefix string, names []string) {
+	conf, ok := factory.(plugin.Configurable)
+	if !ok {
+		return
+	}
+	tierViper := viper.New()
+	for _, name := range names {
+		tierViper.Set(name, v.Get(prefix+name))
+	}
+	conf.InitFromViper(tierViper)
+}
+
+// Initialize implements storage.Factory
+func (f *Factory) Initialize(metricsFactory metrics.Factory, logger *zap.Logger) error {
+	f.logger = logger
+	hotMetrics := metricsFactory.Namespace(metrics.NSOptions{Name: "hot"})
+	if err := f.hot.Factory.Initialize(hotMetrics, logger.With(zap.String("storage", "hot"))); err != nil {
+		return err
+	}
+	coldMetrics := metricsFactory.Namespace(metrics.NSOptions{Name: "cold"})
+	return f.cold.Factory.Initialize(coldMetrics, logger.With(zap.String("storage", "cold")))
+}
+
+// CreateSpanReader implements storage.Factory
+func (f *Factory) CreateSpanReader() (spanstore.Reader, error) {
+	hot, err := f.hot.Factory.CreateSpanReader()
+	if err != nil {
+		return nil, err
+	}
+	cold, err := f.cold.Factory.CreateSpanReader()
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(hot, cold, f.Options.HotRetention, f.logger), nil
+}
+
+// CreateSpanWriter implements storage.Factory
+func (f *Factory) CreateSpanWriter() (spanstore.Writer, error) {
+	hot, err := f.hot.Factory.CreateSpanWriter()
+	if err != nil {
+		return nil, err
+	}
+	cold, err := f.cold.Factory.CreateSpanWriter()
+	if err != nil {
+		return nil, err
+	}
+	return spanstore.NewCompositeWriter(hot, cold), nil
+}
+
+// CreateDependencyReader implements storage.Factory
+func (f *Factory) CreateDependencyReader() (dependencystore.Reader, error) {
+	hot, err := f.hot.Factory.CreateDependencyReader()
+	if err != nil {
+		return nil, err
+	}
+	cold, err := f.cold.Factory.CreateDependencyReader()
+	if err != nil {
+		return nil, err
+	}
+	return NewDependencyReader(hot, cold, f.logger), nil
+}
+
+// Close implements io.Closer and closes the backends of both tiers.
+func (f *Factory) Close() error {
+	var errs []error
+	for _, factory := range []storage.Factory{f.hot.Factory, f.cold.Factory} {
+		if closer, ok := factory.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return multierror.Wrap(errs)
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiered
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/pkg/config"
+	"github.com/jaegertracing/jaeger/plugin/storage/es"
+	depStoreMocks "github.com/jaegertracing/jaeger/storage/dependencystore/mocks"
+	"github.com/jaegertracing/jaeger/storage/mocks"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	spanStoreMocks "github.com/jaegertracing/jaeger/storage/spanstore/mocks"
+)
+
+func TestFactoryFlags(t *testing.T) {
+	hot, cold := es.NewFactory(), es.NewFactory()
+	f := NewFactory(Tier{Type: "elasticsearch", Factory: hot}, Tier{Type: "elasticsearch", Factory: cold})
+	v, command := config.Viperize(f.AddFlags)
+	require.NoError(t, command.ParseFlags([]string{
+		"--tiered.hot-storage.type=elasticsearch",
+		"--tiered.hot-retention=24h",
+		"--tiered.hot.es.server-urls=http://hot:9200",
+		"--tiered.cold.es.server-urls=http://cold:9200",
+	}))
+	f.InitFromViper(v)
+
+	assert.Equal(t, 24*time.Hour, f.Options.HotRetention)
+	assert.Equal(t, []string{"http://hot:9200"}, hot.Options.GetPrimary().Servers)
+	assert.Equal(t, []string{"http://cold:9200"}, cold.Options.GetPrimary().Servers)
+	assert.Contains(t, f.hotFlags, "es.server-urls")
+	assert.Contains(t, f.coldFlags, "es.server-urls")
+}
+
+func TestFactory(t *testing.T) {
+	hot, cold := new(mocks.Factory), new(mocks.Factory)
+	f := NewFactory(Tier{Type: "memory", Factory: hot}, Tier{Type: "cassandra", Factory: cold})
+	v, command := config.Viperize(f.AddFlags)
+	require.NoError(t, command.ParseFlags(nil))
+	f.InitFromViper(v)
+	assert.Nil(t, f.hotFlags)
+
+	hot.On("Initialize", mock.Anything, mock.Anything).Once().Return(errors.New("hot-error"))
+	assert.EqualError(t, f.Initialize(metrics.NullFactory, zap.NewNop()), "hot-error")
+	hot.On("Initialize", mock.Anything, mock.Anything).Return(nil)
+	cold.On("Initialize", mock.Anything, mock.Anything).Return(nil)
+	require.NoError(t, f.Initialize(metrics.NullFactory, zap.NewNop()))
+
+	hotReader, coldReader := new(spanStoreMocks.Reader), new(spanStoreMocks.Reader)
+	hot.On("CreateSpanReader").Return(hotReader, nil)
+	cold.On("CreateSpanReader").Once().Return(nil, errors.New("reader-error"))
+	_, err := f.CreateSpanReader()
+	assert.EqualError(t, err, "reader-error")
+	cold.On("CreateSpanReader").Return(coldReader, nil)
+	reader, err := f.CreateSpanReader()
+	require.NoError(t, err)
+	assert.Equal(t, hotReader, reader.(*Reader).hot)
+	assert.Equal(t, coldReader, reader.(*Reader).cold)
+
+	hotWriter, coldWriter := new(spanStoreMocks.Writer), new(spanStoreMocks.Writer)
+	hot.On("CreateSpanWriter").Once().Return(nil, errors.New("writer-error"))
+	_, err = f.CreateSpanWriter()
+	assert.EqualError(t, err, "writer-error")
+	hot.On("CreateSpanWriter").Return(hotWriter, nil)
+	cold.On("CreateSpanWriter").Return(coldWriter, nil)
+	writer, err := f.CreateSpanWriter()
+	require.NoError(t, err)
+	assert.Equal(t, spanstore.NewCompositeWriter(hotWriter, coldWriter), writer)
+
+	hotDeps, coldDeps := new(depStoreMocks.Reader), new(depStoreMocks.Reader)
+	hot.On("CreateDependencyReader").Return(hotDeps, nil)
+	cold.On("CreateDependencyReader").Return(coldDeps, nil)
+	depReader, err := f.CreateDependencyReader()
+	require.NoError(t, err)
+	assert.Equal(t, NewDependencyReader(hotDeps, coldDeps, f.logger), depReader)
+
+	assert.NoError(t, f.Close())
+}
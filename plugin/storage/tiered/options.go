@@ -0,0 +1,57 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiered
+
+import (
+	"flag"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	namespace = "tiered"
+
+	// HotStorageTypeFlag is the flag defining the type of backend of the hot tier, e.g. memory or badger.
+	HotStorageTypeFlag = namespace + ".hot-storage.type"
+	// ColdStorageTypeFlag is the flag defining the type of backend of the cold tier, e.g. elasticsearch.
+	ColdStorageTypeFlag = namespace + ".cold-storage.type"
+
+	// hotFlagPrefix and coldFlagPrefix prefix the flags of the backends of the tiers, so that both
+	// tiers can use the same type of backend with different configurations.
+	hotFlagPrefix  = namespace + ".hot."
+	coldFlagPrefix = namespace + ".cold."
+
+	suffixHotRetention = ".hot-retention"
+)
+
+// Options contains the configs of the tiered storage and provides the ability
+// to bind them to command line flags
+type Options struct {
+	// HotRetention is how long the spans are kept by the hot backend. The queries reaching further back
+	// also read the cold backend, and those entirely older only read the cold backend. When zero the cold
+	// backend is only read when the hot one does not find enough traces.
+	HotRetention time.Duration `mapstructure:"hot_retention"`
+}
+
+// AddFlags adds flags for Options
+func (opt *Options) AddFlags(flagSet *flag.FlagSet) {
+	flagSet.Duration(namespace+suffixHotRetention, opt.HotRetention, "How long the spans are kept by the hot backend, the queries reaching further back also read the cold backend; 0 reads the cold backend only when the hot one does not find enough traces")
+}
+
+// InitFromViper initializes Options with properties from viper
+func (opt *Options) InitFromViper(v *viper.Viper) {
+	opt.HotRetention = v.GetDuration(namespace + suffixHotRetention)
+}
@@ -0,0 +1,199 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiered
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+// Reader is a span Reader querying the hot backend first, and falling through to the cold backend
+// when the hot one fails or does not find enough data.
+type Reader struct {
+	hot          spanstore.Reader
+	cold         spanstore.Reader
+	hotRetention time.Duration
+	logger       *zap.Logger
+
+	// timeNow returns the current time, it is replaced by the tests
+	timeNow func() time.Time
+}
+
+// NewReader creates a Reader. When the hot retention is set, the queries reaching further back
+// always read the cold backend.
+func NewReader(hot, cold spanstore.Reader, hotRetention time.Duration, logger *zap.Logger) *Reader {
+	return &Reader{
+		hot:          hot,
+		cold:         cold,
+		hotRetention: hotRetention,
+		logger:       logger,
+		timeNow:      time.Now,
+	}
+}
+
+// beyondHotRetention returns whether the spans started at t are expired from the hot backend.
+func (r *Reader) beyondHotRetention(t time.Time) bool {
+	return r.hotRetention > 0 && !t.IsZero() && t.Before(r.timeNow().Add(-r.hotRetention))
+}
+
+// needsCold returns whether the cold backend must be queried after the hot one found the given number of traces.
+func (r *Reader) needsCold(query *spanstore.TraceQueryParameters, found int) bool {
+	return query.NumTraces <= 0 || found < query.NumTraces || r.beyondHotRetention(query.StartTimeMin)
+}
+
+// GetTrace returns the trace from the hot backend, or from the cold one when it is not found.
+func (r *Reader) GetTrace(ctx context.Context, traceID model.TraceID) (*model.Trace, error) {
+	trace, err := r.hot.GetTrace(ctx, traceID)
+	if err == nil {
+		return trace, nil
+	}
+	if err != spanstore.ErrTraceNotFound {
+		r.logger.Warn("Failed to read the trace from the hot storage, falling through to the cold storage",
+			zap.Stringer("trace_id", traceID), zap.Error(err))
+	}
+	return r.cold.GetTrace(ctx, traceID)
+}
+
+// GetServices returns the services found in either backend.
+func (r *Reader) GetServices(ctx context.Context) ([]string, error) {
+	hotServices, err := r.hot.GetServices(ctx)
+	if err != nil {
+		r.logger.Warn("Failed to read the services from the hot storage, falling through to the cold storage", zap.Error(err))
+		return r.cold.GetServices(ctx)
+	}
+	coldServices, err := r.cold.GetServices(ctx)
+	if err != nil {
+		r.logger.Warn("Failed to read the services from the cold storage", zap.Error(err))
+		return hotServices, nil
+	}
+	seen := make(map[string]bool, len(hotServices))
+	for _, service := range hotServices {
+		seen[service] = true
+	}
+	services := hotServices
+	for _, service := range coldServices {
+		if !seen[service] {
+			seen[service] = true
+			services = append(services, service)
+		}
+	}
+	return services, nil
+}
+
+// GetOperations returns the operations found in either backend.
+func (r *Reader) GetOperations(ctx context.Context, query spanstore.OperationQueryParameters) ([]spanstore.Operation, error) {
+	hotOperations, err := r.hot.GetOperations(ctx, query)
+	if err != nil {
+		r.logger.Warn("Failed to read the operations from the hot storage, falling through to the cold storage", zap.Error(err))
+		return r.cold.GetOperations(ctx, query)
+	}
+	coldOperations, err := r.cold.GetOperations(ctx, query)
+	if err != nil {
+		r.logger.Warn("Failed to read the operations from the cold storage", zap.Error(err))
+		return hotOperations, nil
+	}
+	seen := make(map[spanstore.Operation]bool, len(hotOperations))
+	for _, operation := range hotOperations {
+		seen[operation] = true
+	}
+	operations := hotOperations
+	for _, operation := range coldOperations {
+		if !seen[operation] {
+			seen[operation] = true
+			operations = append(operations, operation)
+		}
+	}
+	return operations, nil
+}
+
+// FindTraces returns the traces found in the hot backend, completed with those of the cold backend
+// when there are not enough of them or the query reaches beyond the hot retention.
+func (r *Reader) FindTraces(ctx context.Context, query *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
+	if r.beyondHotRetention(query.StartTimeMax) {
+		return r.cold.FindTraces(ctx, query)
+	}
+	hotTraces, err := r.hot.FindTraces(ctx, query)
+	if err != nil {
+		r.logger.Warn("Failed to find the traces in the hot storage, falling through to the cold storage", zap.Error(err))
+		return r.cold.FindTraces(ctx, query)
+	}
+	if !r.needsCold(query, len(hotTraces)) {
+		return hotTraces, nil
+	}
+	coldTraces, err := r.cold.FindTraces(ctx, query)
+	if err != nil {
+		r.logger.Warn("Failed to find the traces in the cold storage", zap.Error(err))
+		return hotTraces, nil
+	}
+	seen := make(map[model.TraceID]bool, len(hotTraces))
+	for _, trace := range hotTraces {
+		if len(trace.Spans) > 0 {
+			seen[trace.Spans[0].TraceID] = true
+		}
+	}
+	traces := hotTraces
+	for _, trace := range coldTraces {
+		if query.NumTraces > 0 && len(traces) >= query.NumTraces {
+			break
+		}
+		if len(trace.Spans) == 0 || seen[trace.Spans[0].TraceID] {
+			continue
+		}
+		seen[trace.Spans[0].TraceID] = true
+		traces = append(traces, trace)
+	}
+	return traces, nil
+}
+
+// FindTraceIDs returns the trace IDs found in the hot backend, completed with those of the cold backend
+// when there are not enough of them or the query reaches beyond the hot retention.
+func (r *Reader) FindTraceIDs(ctx context.Context, query *spanstore.TraceQueryParameters) ([]model.TraceID, error) {
+	if r.beyondHotRetention(query.StartTimeMax) {
+		return r.cold.FindTraceIDs(ctx, query)
+	}
+	hotTraceIDs, err := r.hot.FindTraceIDs(ctx, query)
+	if err != nil {
+		r.logger.Warn("Failed to find the trace IDs in the hot storage, falling through to the cold storage", zap.Error(err))
+		return r.cold.FindTraceIDs(ctx, query)
+	}
+	if !r.needsCold(query, len(hotTraceIDs)) {
+		return hotTraceIDs, nil
+	}
+	coldTraceIDs, err := r.cold.FindTraceIDs(ctx, query)
+	if err != nil {
+		r.logger.Warn("Failed to find the trace IDs in the cold storage", zap.Error(err))
+		return hotTraceIDs, nil
+	}
+	seen := make(map[model.TraceID]bool, len(hotTraceIDs))
+	for _, traceID := range hotTraceIDs {
+		seen[traceID] = true
+	}
+	traceIDs := hotTraceIDs
+	for _, traceID := range coldTraceIDs {
+		if query.NumTraces > 0 && len(traceIDs) >= query.NumTraces {
+			break
+		}
+		if !seen[traceID] {
+			seen[traceID] = true
+			traceIDs = append(traceIDs, traceID)
+		}
+	}
+	return traceIDs, nil
+}
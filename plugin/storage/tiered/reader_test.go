@@ -0,0 +1,240 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiered
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	spanStoreMocks "github.com/jaegertracing/jaeger/storage/spanstore/mocks"
+)
+
+var now = time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestReader(hotRetention time.Duration) (*Reader, *spanStoreMocks.Reader, *spanStoreMocks.Reader) {
+	hot, cold := new(spanStoreMocks.Reader), new(spanStoreMocks.Reader)
+	r := NewReader(hot, cold, hotRetention, zap.NewNop())
+	r.timeNow = func() time.Time { return now }
+	return r, hot, cold
+}
+
+func testTrace(id uint64) *model.Trace {
+	return &model.Trace{Spans: []*model.Span{{TraceID: model.NewTraceID(0, id), SpanID: model.NewSpanID(id)}}}
+}
+
+func TestGetTrace(t *testing.T) {
+	traceID := model.NewTraceID(0, 1)
+	hotTrace, coldTrace := testTrace(1), testTrace(1)
+	coldTrace.Warnings = []string{"cold"}
+
+	r, hot, cold := newTestReader(0)
+	hot.On("GetTrace", context.Background(), traceID).Return(hotTrace, nil)
+	trace, err := r.GetTrace(context.Background(), traceID)
+	require.NoError(t, err)
+	assert.Equal(t, hotTrace, trace)
+	cold.AssertNotCalled(t, "GetTrace")
+
+	for _, hotErr := range []error{spanstore.ErrTraceNotFound, errors.New("hot-error")} {
+		r, hot, cold := newTestReader(0)
+		hot.On("GetTrace", context.Background(), traceID).Return(nil, hotErr)
+		cold.On("GetTrace", context.Background(), traceID).Return(coldTrace, nil)
+		trace, err := r.GetTrace(context.Background(), traceID)
+		require.NoError(t, err)
+		assert.Equal(t, coldTrace, trace)
+	}
+}
+
+func TestGetServices(t *testing.T) {
+	r, hot, cold := newTestReader(0)
+	hot.On("GetServices", context.Background()).Return([]string{"a", "b"}, nil)
+	cold.On("GetServices", context.Background()).Return([]string{"b", "c"}, nil)
+	services, err := r.GetServices(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, services)
+
+	r, hot, cold = newTestReader(0)
+	hot.On("GetServices", context.Background()).Return(nil, errors.New("hot-error"))
+	cold.On("GetServices", context.Background()).Return([]string{"c"}, nil)
+	services, err = r.GetServices(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c"}, services)
+
+	r, hot, cold = newTestReader(0)
+	hot.On("GetServices", context.Background()).Return([]string{"a"}, nil)
+	cold.On("GetServices", context.Background()).Return(nil, errors.New("cold-error"))
+	services, err = r.GetServices(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, services)
+}
+
+func TestGetOperations(t *testing.T) {
+	query := spanstore.OperationQueryParameters{ServiceName: "svc"}
+	r, hot, cold := newTestReader(0)
+	hot.On("GetOperations", context.Background(), query).Return([]spanstore.Operation{{Name: "op1"}}, nil)
+	cold.On("GetOperations", context.Background(), query).Return([]spanstore.Operation{{Name: "op1"}, {Name: "op1", SpanKind: "server"}}, nil)
+	operations, err := r.GetOperations(context.Background(), query)
+	require.NoError(t, err)
+	assert.Equal(t, []spanstore.Operation{{Name: "op1"}, {Name: "op1", SpanKind: "server"}}, operations)
+
+	r, hot, cold = newTestReader(0)
+	hot.On("GetOperations", context.Background(), query).Return(nil, errors.New("hot-error"))
+	cold.On("GetOperations", context.Background(), query).Return(nil, errors.New("cold-error"))
+	_, err = r.GetOperations(context.Background(), query)
+	assert.EqualError(t, err, "cold-error")
+
+	r, hot, cold = newTestReader(0)
+	hot.On("GetOperations", context.Background(), query).Return([]spanstore.Operation{{Name: "op1"}}, nil)
+	cold.On("GetOperations", context.Background(), query).Return(nil, errors.New("cold-error"))
+	operations, err = r.GetOperations(context.Background(), query)
+	require.NoError(t, err)
+	assert.Equal(t, []spanstore.Operation{{Name: "op1"}}, operations)
+}
+
+func TestFindTraces(t *testing.T) {
+	recent := &spanstore.TraceQueryParameters{
+		ServiceName:  "svc",
+		StartTimeMin: now.Add(-time.Hour),
+		StartTimeMax: now,
+		NumTraces:    2,
+	}
+	old := &spanstore.TraceQueryParameters{
+		ServiceName:  "svc",
+		StartTimeMin: now.Add(-72 * time.Hour),
+		StartTimeMax: now,
+		NumTraces:    2,
+	}
+	expired := &spanstore.TraceQueryParameters{
+		ServiceName:  "svc",
+		StartTimeMin: now.Add(-72 * time.Hour),
+		StartTimeMax: now.Add(-48 * time.Hour),
+		NumTraces:    2,
+	}
+	testCases := []struct {
+		name      string
+		query     *spanstore.TraceQueryParameters
+		hot       []*model.Trace
+		hotErr    error
+		cold      []*model.Trace
+		coldErr   error
+		expected  []*model.Trace
+		skipsHot  bool
+		skipsCold bool
+	}{
+		{
+			name:      "enough hot traces",
+			query:     recent,
+			hot:       []*model.Trace{testTrace(1), testTrace(2)},
+			expected:  []*model.Trace{testTrace(1), testTrace(2)},
+			skipsCold: true,
+		},
+		{
+			name:     "completed with cold traces",
+			query:    recent,
+			hot:      []*model.Trace{testTrace(1)},
+			cold:     []*model.Trace{testTrace(1), testTrace(3), testTrace(4)},
+			expected: []*model.Trace{testTrace(1), testTrace(3)},
+		},
+		{
+			name:     "beyond hot retention",
+			query:    old,
+			hot:      []*model.Trace{testTrace(1), testTrace(2)},
+			cold:     []*model.Trace{testTrace(3)},
+			expected: []*model.Trace{testTrace(1), testTrace(2)},
+		},
+		{
+			name:     "only cold",
+			query:    expired,
+			cold:     []*model.Trace{testTrace(3)},
+			expected: []*model.Trace{testTrace(3)},
+			skipsHot: true,
+		},
+		{
+			name:     "hot error",
+			query:    recent,
+			hotErr:   errors.New("hot-error"),
+			cold:     []*model.Trace{testTrace(3)},
+			expected: []*model.Trace{testTrace(3)},
+		},
+		{
+			name:     "cold error",
+			query:    recent,
+			hot:      []*model.Trace{testTrace(1)},
+			coldErr:  errors.New("cold-error"),
+			expected: []*model.Trace{testTrace(1)},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			r, hot, cold := newTestReader(24 * time.Hour)
+			hot.On("FindTraces", context.Background(), testCase.query).Return(testCase.hot, testCase.hotErr)
+			cold.On("FindTraces", context.Background(), testCase.query).Return(testCase.cold, testCase.coldErr)
+			traces, err := r.FindTraces(context.Background(), testCase.query)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, traces)
+			if testCase.skipsHot {
+				hot.AssertNotCalled(t, "FindTraces", context.Background(), testCase.query)
+			}
+			if testCase.skipsCold {
+				cold.AssertNotCalled(t, "FindTraces", context.Background(), testCase.query)
+			}
+		})
+	}
+}
+
+func TestFindTraceIDs(t *testing.T) {
+	traceID := func(id uint64) model.TraceID { return model.NewTraceID(0, id) }
+	query := &spanstore.TraceQueryParameters{
+		ServiceName:  "svc",
+		StartTimeMin: now.Add(-time.Hour),
+		StartTimeMax: now,
+		NumTraces:    2,
+	}
+
+	r, hot, cold := newTestReader(0)
+	hot.On("FindTraceIDs", context.Background(), query).Return([]model.TraceID{traceID(1), traceID(2)}, nil)
+	traceIDs, err := r.FindTraceIDs(context.Background(), query)
+	require.NoError(t, err)
+	assert.Equal(t, []model.TraceID{traceID(1), traceID(2)}, traceIDs)
+	cold.AssertNotCalled(t, "FindTraceIDs", context.Background(), query)
+
+	r, hot, cold = newTestReader(0)
+	hot.On("FindTraceIDs", context.Background(), query).Return([]model.TraceID{traceID(1)}, nil)
+	cold.On("FindTraceIDs", context.Background(), query).Return([]model.TraceID{traceID(1), traceID(3), traceID(4)}, nil)
+	traceIDs, err = r.FindTraceIDs(context.Background(), query)
+	require.NoError(t, err)
+	assert.Equal(t, []model.TraceID{traceID(1), traceID(3)}, traceIDs)
+
+	r, hot, cold = newTestReader(0)
+	hot.On("FindTraceIDs", context.Background(), query).Return(nil, errors.New("hot-error"))
+	cold.On("FindTraceIDs", context.Background(), query).Return([]model.TraceID{traceID(3)}, nil)
+	traceIDs, err = r.FindTraceIDs(context.Background(), query)
+	require.NoError(t, err)
+	assert.Equal(t, []model.TraceID{traceID(3)}, traceIDs)
+
+	r, hot, cold = newTestReader(0)
+	hot.On("FindTraceIDs", context.Background(), query).Return([]model.TraceID{traceID(1)}, nil)
+	cold.On("FindTraceIDs", context.Background(), query).Return(nil, errors.New("cold-error"))
+	traceIDs, err = r.FindTraceIDs(context.Background(), query)
+	require.NoError(t, err)
+	assert.Equal(t, []model.TraceID{traceID(1)}, traceIDs)
+}
@@ -37,6 +37,10 @@ const (
 	CollectorHTTP = 14268
 	// CollectorAdminHTTP is the default admin HTTP port (health check, metrics, etc.)
 	CollectorAdminHTTP = 14269
+	// CollectorOTLPGRPC is the standard port for receiving OpenTelemetry protocol spans over gRPC
+	CollectorOTLPGRPC = 4317
+	// CollectorOTLPHTTP is the standard port for receiving OpenTelemetry protocol spans over HTTP
+	CollectorOTLPHTTP = 4318
 
 	// QueryHTTP is the default port for UI and Query API (e.g. /api/* endpoints)
 	QueryHTTP = 16686
@@ -22,7 +22,9 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/jaegertracing/jaeger/storage/dependencystore"
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
 	"github.com/jaegertracing/jaeger/storage/spanstore"
+	"github.com/jaegertracing/jaeger/storage/viewstore"
 )
 
 // Factory defines an interface for a factory that can create implementations of different storage components.
@@ -52,6 +54,9 @@ var (
 
 	// ErrArchiveStorageNotSupported can be returned by the ArchiveFactory when the archive storage is not supported by the backend.
 	ErrArchiveStorageNotSupported = errors.New("archive storage not supported")
+
+	// ErrTenantStorageNotSupported can be returned by the TenantFactory when the backend has no storage dedicated to the tenants.
+	ErrTenantStorageNotSupported = errors.New("tenant storage not supported")
 )
 
 // ArchiveFactory is an additional interface that can be implemented by a factory to support trace archiving.
@@ -62,3 +67,27 @@ type ArchiveFactory interface {
 	// CreateArchiveSpanWriter creates a spanstore.Writer.
 	CreateArchiveSpanWriter() (spanstore.Writer, error)
 }
+
+// ViewFactory is an additional interface that can be implemented by a factory to persist
+// the saved searches and the pinned traces.
+type ViewFactory interface {
+	// CreateViewStore creates a viewstore.Store.
+	CreateViewStore() (viewstore.Store, error)
+}
+
+// TenantFactory is an additional interface that can be implemented by a factory to read the spans
+// of a tenant from a storage dedicated to it.
+type TenantFactory interface {
+	// CreateTenantSpanReader creates a spanstore.Reader of the spans of the tenant.
+	CreateTenantSpanReader(tenant string) (spanstore.Reader, error)
+}
+
+// MetricsFactory defines an interface for a factory that can create implementations of the storage of span metrics.
+type MetricsFactory interface {
+	// Initialize performs internal initialization of the factory, such as creating the clients of the backend.
+	// It is called after all configuration of the factory itself has been done.
+	Initialize(logger *zap.Logger) error
+
+	// CreateMetricsReader creates a metricsstore.Reader.
+	CreateMetricsReader() (metricsstore.Reader, error)
+}
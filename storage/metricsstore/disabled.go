@@ -0,0 +1,53 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metricsstore
+
+import (
+	"context"
+	"time"
+)
+
+type disabledReader struct{}
+
+// NewDisabledReader returns a Reader failing all the queries with ErrDisabled,
+// used when no metrics storage is configured.
+func NewDisabledReader() Reader {
+	return disabledReader{}
+}
+
+// GetLatencies implements Reader.
+func (disabledReader) GetLatencies(context.Context, *LatenciesQueryParameters) (*MetricFamily, error) {
+	return nil, ErrDisabled
+}
+
+// GetCallRates implements Reader.
+func (disabledReader) GetCallRates(context.Context, *CallRateQueryParameters) (*MetricFamily, error) {
+	return nil, ErrDisabled
+}
+
+// GetErrorRates implements Reader.
+func (disabledReader) GetErrorRates(context.Context, *ErrorRateQueryParameters) (*MetricFamily, error) {
+	return nil, ErrDisabled
+}
+
+// GetLatencyBuckets implements Reader.
+func (disabledReader) GetLatencyBuckets(context.Context, *LatencyBucketsQueryParameters) (*MetricFamily, error) {
+	return nil, ErrDisabled
+}
+
+// GetMinStepDuration implements Reader.
+func (disabledReader) GetMinStepDuration(context.Context, *MinStepDurationQueryParameters) (time.Duration, error) {
+	return 0, ErrDisabled
+}
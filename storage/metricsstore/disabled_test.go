@@ -0,0 +1,37 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metricsstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisabledReader(t *testing.T) {
+	r := NewDisabledReader()
+	ctx := context.Background()
+	_, err := r.GetLatencies(ctx, &LatenciesQueryParameters{})
+	assert.Equal(t, ErrDisabled, err)
+	_, err = r.GetCallRates(ctx, &CallRateQueryParameters{})
+	assert.Equal(t, ErrDisabled, err)
+	_, err = r.GetErrorRates(ctx, &ErrorRateQueryParameters{})
+	assert.Equal(t, ErrDisabled, err)
+	_, err = r.GetLatencyBuckets(ctx, &LatencyBucketsQueryParameters{})
+	assert.Equal(t, ErrDisabled, err)
+	_, err = r.GetMinStepDuration(ctx, &MinStepDurationQueryParameters{})
+	assert.Equal(t, ErrDisabled, err)
+}
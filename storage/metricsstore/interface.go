@@ -0,0 +1,83 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metricsstore
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrDisabled is returned by the Reader when no metrics storage is configured.
+var ErrDisabled = errors.New("metrics querying is currently disabled")
+
+// Reader can load the aggregated metrics of the spans from storage.
+type Reader interface {
+	// GetLatencies returns the quantile of the latencies, in milliseconds, of the spans
+	// of the given services, optionally grouped by operation.
+	GetLatencies(ctx context.Context, params *LatenciesQueryParameters) (*MetricFamily, error)
+	// GetCallRates returns the rate of the spans, per second, of the given services.
+	GetCallRates(ctx context.Context, params *CallRateQueryParameters) (*MetricFamily, error)
+	// GetErrorRates returns the ratio of the spans in error, between 0 and 1, of the given services.
+	GetErrorRates(ctx context.Context, params *ErrorRateQueryParameters) (*MetricFamily, error)
+	// GetLatencyBuckets returns the number of spans of the given services in each latency bucket
+	// over each step, the series having an "le" label with the upper bound of their bucket in
+	// milliseconds. The counts are cumulative, the bucket of an upper bound including the smaller ones.
+	GetLatencyBuckets(ctx context.Context, params *LatencyBucketsQueryParameters) (*MetricFamily, error)
+	// GetMinStepDuration returns the smallest step supported by the storage.
+	GetMinStepDuration(ctx context.Context, params *MinStepDurationQueryParameters) (time.Duration, error)
+}
+
+// BaseQueryParameters contains the parameters common to all the metrics queries.
+type BaseQueryParameters struct {
+	ServiceNames     []string
+	GroupByOperation bool
+	// SpanKinds are the span kinds to include, e.g. "server", all of them if empty
+	SpanKinds []string
+	// EndTime is the end of the period of time returned, and Lookback its length
+	EndTime  time.Time
+	Lookback time.Duration
+	// Step is the interval between two points of the time series
+	Step time.Duration
+	// RatePer is the period of time over which the rates and quantiles are computed
+	RatePer time.Duration
+}
+
+// LatenciesQueryParameters contains the parameters of GetLatencies.
+type LatenciesQueryParameters struct {
+	BaseQueryParameters
+	// Quantile is between 0 and 1, e.g. 0.95
+	Quantile float64
+}
+
+// CallRateQueryParameters contains the parameters of GetCallRates.
+type CallRateQueryParameters struct {
+	BaseQueryParameters
+}
+
+// ErrorRateQueryParameters contains the parameters of GetErrorRates.
+type ErrorRateQueryParameters struct {
+	BaseQueryParameters
+}
+
+// LatencyBucketsQueryParameters contains the parameters of GetLatencyBuckets.
+type LatencyBucketsQueryParameters struct {
+	BaseQueryParameters
+	// OperationName restricts the buckets to the spans of the operation, if not empty
+	OperationName string
+}
+
+// MinStepDurationQueryParameters contains the parameters of GetMinStepDuration.
+type MinStepDurationQueryParameters struct{}
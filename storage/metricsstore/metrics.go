@@ -0,0 +1,52 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metricsstore
+
+import (
+	"time"
+)
+
+// MetricType is the type of a MetricFamily
+type MetricType string
+
+// MetricTypeGauge is the only type of the metrics returned at the moment, the rates and quantiles
+// being computed by the storage.
+const MetricTypeGauge MetricType = "GAUGE"
+
+// MetricFamily is a set of time series of the same metric, e.g. one per service or operation.
+type MetricFamily struct {
+	Name    string     `json:"name"`
+	Type    MetricType `json:"type"`
+	Help    string     `json:"help"`
+	Metrics []*Metric  `json:"metrics"`
+}
+
+// Metric is a single time series, identified by its labels.
+type Metric struct {
+	Labels       []Label       `json:"labels"`
+	MetricPoints []MetricPoint `json:"metricPoints"`
+}
+
+// Label is a dimension of a metric, such as the service or the operation.
+type Label struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// MetricPoint is the value of a metric at a point of time.
+type MetricPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+}
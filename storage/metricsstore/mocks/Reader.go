@@ -0,0 +1,144 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
+)
+
+// Reader is an autogenerated mock type for the Reader type
+type Reader struct {
+	mock.Mock
+}
+
+// GetLatencies provides a mock function with given fields: ctx, params
+func (_m *Reader) GetLatencies(ctx context.Context, params *metricsstore.LatenciesQueryParameters) (*metricsstore.MetricFamily, error) {
+	ret := _m.Called(ctx, params)
+
+	var r0 *metricsstore.MetricFamily
+	if rf, ok := ret.Get(0).(func(context.Context, *metricsstore.LatenciesQueryParameters) *metricsstore.MetricFamily); ok {
+		r0 = rf(ctx, params)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*metricsstore.MetricFamily)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *metricsstore.LatenciesQueryParameters) error); ok {
+		r1 = rf(ctx, params)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetCallRates provides a mock function with given fields: ctx, params
+func (_m *Reader) GetCallRates(ctx context.Context, params *metricsstore.CallRateQueryParameters) (*metricsstore.MetricFamily, error) {
+	ret := _m.Called(ctx, params)
+
+	var r0 *metricsstore.MetricFamily
+	if rf, ok := ret.Get(0).(func(context.Context, *metricsstore.CallRateQueryParameters) *metricsstore.MetricFamily); ok {
+		r0 = rf(ctx, params)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*metricsstore.MetricFamily)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *metricsstore.CallRateQueryParameters) error); ok {
+		r1 = rf(ctx, params)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetErrorRates provides a mock function with given fields: ctx, params
+func (_m *Reader) GetErrorRates(ctx context.Context, params *metricsstore.ErrorRateQueryParameters) (*metricsstore.MetricFamily, error) {
+	ret := _m.Called(ctx, params)
+
+	var r0 *metricsstore.MetricFamily
+	if rf, ok := ret.Get(0).(func(context.Context, *metricsstore.ErrorRateQueryParameters) *metricsstore.MetricFamily); ok {
+		r0 = rf(ctx, params)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*metricsstore.MetricFamily)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *metricsstore.ErrorRateQueryParameters) error); ok {
+		r1 = rf(ctx, params)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetLatencyBuckets provides a mock function with given fields: ctx, params
+func (_m *Reader) GetLatencyBuckets(ctx context.Context, params *metricsstore.LatencyBucketsQueryParameters) (*metricsstore.MetricFamily, error) {
+	ret := _m.Called(ctx, params)
+
+	var r0 *metricsstore.MetricFamily
+	if rf, ok := ret.Get(0).(func(context.Context, *metricsstore.LatencyBucketsQueryParameters) *metricsstore.MetricFamily); ok {
+		r0 = rf(ctx, params)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*metricsstore.MetricFamily)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *metricsstore.LatencyBucketsQueryParameters) error); ok {
+		r1 = rf(ctx, params)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetMinStepDuration provides a mock function with given fields: ctx, params
+func (_m *Reader) GetMinStepDuration(ctx context.Context, params *metricsstore.MinStepDurationQueryParameters) (time.Duration, error) {
+	ret := _m.Called(ctx, params)
+
+	var r0 time.Duration
+	if rf, ok := ret.Get(0).(func(context.Context, *metricsstore.MinStepDurationQueryParameters) time.Duration); ok {
+		r0 = rf(ctx, params)
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *metricsstore.MinStepDurationQueryParameters) error); ok {
+		r1 = rf(ctx, params)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+var _ metricsstore.Reader = (*Reader)(nil)
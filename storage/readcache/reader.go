@@ -0,0 +1,262 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package readcache
+
+import (
+	"context"
+	"time"
+
+	"github.com/uber/jaeger-lib/metrics"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/cache"
+	"github.com/jaegertracing/jaeger/storage/dependencystore"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+)
+
+const (
+	servicesKey       = "services"
+	maxOperationKeys  = 10000
+	maxDependencyKeys = 100
+
+	// DefaultTracesQuietPeriod is the default Options.TracesQuietPeriod
+	DefaultTracesQuietPeriod = 5 * time.Minute
+)
+
+// Options configure how long the results of each type of query are cached, they are not cached when 0.
+// The results are cached in the memory of each query service, they are not shared between the instances.
+type Options struct {
+	ServicesTTL     time.Duration
+	OperationsTTL   time.Duration
+	DependenciesTTL time.Duration
+	TracesTTL       time.Duration
+	// MaxTraces is the number of traces cached, the least recently read ones are evicted first
+	MaxTraces int
+
+	// TracesQuietPeriod is how long after the end of its last span a trace is cached: the spans of the
+	// recent traces are still being written, and the cached trace would miss them until it expires
+	TracesQuietPeriod time.Duration
+}
+
+type cacheMetrics struct {
+	Hits   metrics.Counter `metric:"read_cache" tags:"result=hit"`
+	Misses metrics.Counter `metric:"read_cache" tags:"result=miss"`
+}
+
+func buildCacheMetrics(operation string, metricsFactory metrics.Factory) *cacheMetrics {
+	cMetrics := &cacheMetrics{}
+	scoped := metricsFactory.Namespace(metrics.NSOptions{Name: "", Tags: map[string]string{"operation": operation}})
+	metrics.Init(cMetrics, scoped, nil)
+	return cMetrics
+}
+
+// readCache holds the results of a type of query, it is disabled when the LRU is nil.
+type readCache struct {
+	lru     *cache.LRU
+	metrics *cacheMetrics
+	// cacheable tells whether a loaded value can be cached, all of them can when nil
+	cacheable func(value interface{}) bool
+}
+
+func newReadCache(operation string, ttl time.Duration, maxSize int, metricsFactory metrics.Factory) readCache {
+	if ttl <= 0 {
+		return readCache{}
+	}
+	return readCache{
+		lru:     cache.NewLRUWithOptions(maxSize, &cache.Options{TTL: ttl}),
+		metrics: buildCacheMetrics(operation, metricsFactory),
+	}
+}
+
+// get returns the cached value, or loads it and caches it unless there was an error.
+func (c readCache) get(key string, load func() (interface{}, error)) (interface{}, error) {
+	if c.lru == nil {
+		return load()
+	}
+	if value := c.lru.Get(key); value != nil {
+		c.metrics.Hits.Inc(1)
+		return value, nil
+	}
+	c.metrics.Misses.Inc(1)
+	value, err := load()
+	if err != nil {
+		return nil, err
+	}
+	if c.cacheable == nil || c.cacheable(value) {
+		c.lru.Put(key, value)
+	}
+	return value, nil
+}
+
+// SpanReader is a spanstore.Reader caching the services, the operations and the traces read from
+// the wrapped reader. The searches are never cached, nor the traces ended within the quiet period.
+type SpanReader struct {
+	spanReader spanstore.Reader
+	services   readCache
+	operations readCache
+	traces     readCache
+
+	quietPeriod time.Duration
+	timeNow     func() time.Time
+}
+
+// NewSpanReader returns a new SpanReader.
+func NewSpanReader(spanReader spanstore.Reader, options Options, metricsFactory metrics.Factory) *SpanReader {
+	r := &SpanReader{
+		spanReader: spanReader,
+		services:   newReadCache("get_services", options.ServicesTTL, 1, metricsFactory),
+		operations: newReadCache("get_operations", options.OperationsTTL, maxOperationKeys, metricsFactory),
+		traces:     newReadCache("get_trace", options.TracesTTL, options.MaxTraces, metricsFactory),
+
+		quietPeriod: options.TracesQuietPeriod,
+		timeNow:     time.Now,
+	}
+	r.traces.cacheable = r.isTraceQuiet
+	return r
+}
+
+// isTraceQuiet returns true if the last span of the trace ended before the quiet period.
+func (r *SpanReader) isTraceQuiet(value interface{}) bool {
+	trace, _ := value.(*model.Trace)
+	if trace == nil {
+		return false
+	}
+	var end time.Time
+	for _, span := range trace.Spans {
+		if spanEnd := span.StartTime.Add(span.Duration); spanEnd.After(end) {
+			end = spanEnd
+		}
+	}
+	return end.Add(r.quietPeriod).Before(r.timeNow())
+}
+
+// GetTrace implements spanstore.Reader#GetTrace
+func (r *SpanReader) GetTrace(ctx context.Context, traceID model.TraceID) (*model.Trace, error) {
+	value, err := r.traces.get(traceID.String(), func() (interface{}, error) {
+		return r.spanReader.GetTrace(ctx, traceID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	trace, _ := value.(*model.Trace)
+	if trace == nil {
+		return nil, nil
+	}
+	return copyTrace(trace), nil
+}
+
+// GetServices implements spanstore.Reader#GetServices
+func (r *SpanReader) GetServices(ctx context.Context) ([]string, error) {
+	value, err := r.services.get(servicesKey, func() (interface{}, error) {
+		return r.spanReader.GetServices(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]string(nil), value.([]string)...), nil
+}
+
+// GetOperations implements spanstore.Reader#GetOperations
+func (r *SpanReader) GetOperations(
+	ctx context.Context,
+	query spanstore.OperationQueryParameters,
+) ([]spanstore.Operation, error) {
+	value, err := r.operations.get(query.ServiceName+"\x00"+query.SpanKind, func() (interface{}, error) {
+		return r.spanReader.GetOperations(ctx, query)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]spanstore.Operation(nil), value.([]spanstore.Operation)...), nil
+}
+
+// FindTraces implements spanstore.Reader#FindTraces
+func (r *SpanReader) FindTraces(ctx context.Context, query *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
+	return r.spanReader.FindTraces(ctx, query)
+}
+
+// FindTracesPage implements spanstore.PageReader#FindTracesPage if the wrapped reader does
+func (r *SpanReader) FindTracesPage(
+	ctx context.Context,
+	query *spanstore.TraceQueryParameters,
+	cursor string,
+) ([]*model.Trace, string, error) {
+	return spanstore.FindTracesPage(ctx, r.spanReader, query, cursor)
+}
+
+// FindTraceIDs implements spanstore.Reader#FindTraceIDs
+func (r *SpanReader) FindTraceIDs(ctx context.Context, query *spanstore.TraceQueryParameters) ([]model.TraceID, error) {
+	return r.spanReader.FindTraceIDs(ctx, query)
+}
+
+// copyTrace returns a trace that can be modified by the adjusters without modifying the cached one.
+func copyTrace(trace *model.Trace) *model.Trace {
+	copied := &model.Trace{
+		Spans:    make([]*model.Span, len(trace.Spans)),
+		Warnings: append([]string(nil), trace.Warnings...),
+	}
+	for i, span := range trace.Spans {
+		s := *span
+		s.References = append([]model.SpanRef(nil), span.References...)
+		s.Tags = append([]model.KeyValue(nil), span.Tags...)
+		s.Warnings = append([]string(nil), span.Warnings...)
+		s.Logs = make([]model.Log, len(span.Logs))
+		for j, log := range span.Logs {
+			log.Fields = append([]model.KeyValue(nil), log.Fields...)
+			s.Logs[j] = log
+		}
+		if span.Process != nil {
+			process := *span.Process
+			process.Tags = append([]model.KeyValue(nil), span.Process.Tags...)
+			s.Process = &process
+		}
+		copied.Spans[i] = &s
+	}
+	return copied
+}
+
+// DependencyReader is a dependencystore.Reader caching the dependencies read from the wrapped reader.
+// The links are cached by the end of the time range rounded down to the TTL, so that the dashboards
+// refreshing the dependencies every few seconds read the same cached links. The storage is still
+// queried with the requested time range, so that the most recent links are not left out.
+type DependencyReader struct {
+	dependencyReader dependencystore.Reader
+	dependencies     readCache
+	ttl              time.Duration
+}
+
+// NewDependencyReader returns a new DependencyReader.
+func NewDependencyReader(dependencyReader dependencystore.Reader, options Options, metricsFactory metrics.Factory) *DependencyReader {
+	return &DependencyReader{
+		dependencyReader: dependencyReader,
+		dependencies:     newReadCache("get_dependencies", options.DependenciesTTL, maxDependencyKeys, metricsFactory),
+		ttl:              options.DependenciesTTL,
+	}
+}
+
+// GetDependencies implements dependencystore.Reader#GetDependencies
+func (r *DependencyReader) GetDependencies(endTs time.Time, lookback time.Duration) ([]model.DependencyLink, error) {
+	if r.ttl <= 0 {
+		return r.dependencyReader.GetDependencies(endTs, lookback)
+	}
+	key := endTs.Truncate(r.ttl).String() + "/" + lookback.String()
+	value, err := r.dependencies.get(key, func() (interface{}, error) {
+		return r.dependencyReader.GetDependencies(endTs, lookback)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]model.DependencyLink(nil), value.([]model.DependencyLink)...), nil
+}
@@ -0,0 +1,219 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package readcache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/jaeger-lib/metrics"
+	"github.com/uber/jaeger-lib/metrics/metricstest"
+
+	"github.com/jaegertracing/jaeger/model"
+	depsmocks "github.com/jaegertracing/jaeger/storage/dependencystore/mocks"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	spanstoremocks "github.com/jaegertracing/jaeger/storage/spanstore/mocks"
+)
+
+var errStorage = errors.New("storage error")
+
+func TestSpanReaderGetServices(t *testing.T) {
+	mockReader := &spanstoremocks.Reader{}
+	mf := metricstest.NewFactory(0)
+	r := NewSpanReader(mockReader, Options{ServicesTTL: time.Minute}, mf)
+	now := time.Unix(1600000000, 0)
+	r.services.lru.TimeNow = func() time.Time { return now }
+
+	mockReader.On("GetServices", mock.Anything).Return(nil, errStorage).Once()
+	_, err := r.GetServices(context.Background())
+	assert.Equal(t, errStorage, err)
+
+	mockReader.On("GetServices", mock.Anything).Return([]string{"a", "b"}, nil).Once()
+	for i := 0; i < 2; i++ {
+		services, err := r.GetServices(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, services)
+		services[0] = "modified"
+	}
+
+	now = now.Add(2 * time.Minute)
+	mockReader.On("GetServices", mock.Anything).Return([]string{"c"}, nil).Once()
+	services, err := r.GetServices(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c"}, services)
+	mockReader.AssertExpectations(t)
+
+	mf.AssertCounterMetrics(t,
+		metricstest.ExpectedMetric{Name: "read_cache", Tags: map[string]string{"operation": "get_services", "result": "hit"}, Value: 1},
+		metricstest.ExpectedMetric{Name: "read_cache", Tags: map[string]string{"operation": "get_services", "result": "miss"}, Value: 3},
+	)
+}
+
+func TestSpanReaderGetOperations(t *testing.T) {
+	mockReader := &spanstoremocks.Reader{}
+	r := NewSpanReader(mockReader, Options{OperationsTTL: time.Minute}, metrics.NullFactory)
+	server := spanstore.OperationQueryParameters{ServiceName: "frontend", SpanKind: "server"}
+	client := spanstore.OperationQueryParameters{ServiceName: "frontend", SpanKind: "client"}
+	mockReader.On("GetOperations", mock.Anything, server).Return([]spanstore.Operation{{Name: "GET"}}, nil).Once()
+	mockReader.On("GetOperations", mock.Anything, client).Return([]spanstore.Operation{{Name: "POST"}}, nil).Once()
+
+	for i := 0; i < 2; i++ {
+		operations, err := r.GetOperations(context.Background(), server)
+		require.NoError(t, err)
+		assert.Equal(t, []spanstore.Operation{{Name: "GET"}}, operations)
+		operations, err = r.GetOperations(context.Background(), client)
+		require.NoError(t, err)
+		assert.Equal(t, []spanstore.Operation{{Name: "POST"}}, operations)
+	}
+	mockReader.AssertExpectations(t)
+}
+
+func TestSpanReaderGetTrace(t *testing.T) {
+	mockReader := &spanstoremocks.Reader{}
+	r := NewSpanReader(mockReader, Options{TracesTTL: time.Minute, MaxTraces: 10}, metrics.NullFactory)
+	traceID := model.NewTraceID(0, 1)
+	trace := &model.Trace{Spans: []*model.Span{{
+		TraceID: traceID,
+		SpanID:  model.NewSpanID(1),
+		Tags:    []model.KeyValue{model.String("k", "v")},
+		Logs:    []model.Log{{Fields: []model.KeyValue{model.String("event", "x")}}},
+		Process: model.NewProcess("frontend", []model.KeyValue{model.String("ip", "1.2.3.4")}),
+	}}}
+	mockReader.On("GetTrace", mock.Anything, traceID).Return(trace, nil).Once()
+	mockReader.On("GetTrace", mock.Anything, model.NewTraceID(0, 2)).Return(nil, spanstore.ErrTraceNotFound).Twice()
+
+	for i := 0; i < 2; i++ {
+		res, err := r.GetTrace(context.Background(), traceID)
+		require.NoError(t, err)
+		assert.Equal(t, trace, res)
+		// the adjustments of the returned trace do not modify the cached one
+		res.Spans[0].SpanID = model.NewSpanID(2)
+		res.Spans[0].Tags[0].VStr = "modified"
+		res.Spans[0].Logs[0].Fields[0].VStr = "modified"
+		res.Spans[0].Process.Tags[0].VStr = "modified"
+	}
+	for i := 0; i < 2; i++ {
+		_, err := r.GetTrace(context.Background(), model.NewTraceID(0, 2))
+		assert.Equal(t, spanstore.ErrTraceNotFound, err)
+	}
+	mockReader.AssertExpectations(t)
+}
+
+func TestSpanReaderGetRecentTrace(t *testing.T) {
+	mockReader := &spanstoremocks.Reader{}
+	r := NewSpanReader(mockReader, Options{TracesTTL: time.Hour, MaxTraces: 10, TracesQuietPeriod: time.Minute}, metrics.NullFactory)
+	now := time.Unix(1600000000, 0)
+	r.timeNow = func() time.Time { return now }
+	traceID := model.NewTraceID(0, 1)
+	recent := &model.Trace{Spans: []*model.Span{
+		{TraceID: traceID, SpanID: model.NewSpanID(1), StartTime: now.Add(-10 * time.Minute), Duration: 9 * time.Minute},
+		{TraceID: traceID, SpanID: model.NewSpanID(2), StartTime: now.Add(-2 * time.Minute), Duration: 90 * time.Second},
+	}}
+	mockReader.On("GetTrace", mock.Anything, traceID).Return(recent, nil).Times(3)
+
+	// the trace ended 30s ago and may still receive spans
+	for i := 0; i < 2; i++ {
+		_, err := r.GetTrace(context.Background(), traceID)
+		require.NoError(t, err)
+	}
+	mockReader.AssertNumberOfCalls(t, "GetTrace", 2)
+
+	now = now.Add(time.Minute)
+	for i := 0; i < 2; i++ {
+		_, err := r.GetTrace(context.Background(), traceID)
+		require.NoError(t, err)
+	}
+	mockReader.AssertNumberOfCalls(t, "GetTrace", 3)
+}
+
+func TestSpanReaderDisabled(t *testing.T) {
+	mockReader := &spanstoremocks.Reader{}
+	r := NewSpanReader(mockReader, Options{}, metrics.NullFactory)
+	query := &spanstore.TraceQueryParameters{ServiceName: "frontend"}
+	mockReader.On("GetServices", mock.Anything).Return([]string{"a"}, nil).Twice()
+	mockReader.On("GetOperations", mock.Anything, mock.Anything).Return([]spanstore.Operation{}, nil).Twice()
+	mockReader.On("GetTrace", mock.Anything, mock.Anything).Return(&model.Trace{}, nil).Twice()
+	mockReader.On("FindTraces", mock.Anything, query).Return([]*model.Trace{}, nil).Twice()
+	mockReader.On("FindTraceIDs", mock.Anything, query).Return([]model.TraceID{}, nil).Twice()
+
+	for i := 0; i < 2; i++ {
+		_, err := r.GetServices(context.Background())
+		require.NoError(t, err)
+		_, err = r.GetOperations(context.Background(), spanstore.OperationQueryParameters{})
+		require.NoError(t, err)
+		_, err = r.GetTrace(context.Background(), model.NewTraceID(0, 1))
+		require.NoError(t, err)
+		_, err = r.FindTraces(context.Background(), query)
+		require.NoError(t, err)
+		_, err = r.FindTraceIDs(context.Background(), query)
+		require.NoError(t, err)
+	}
+	mockReader.AssertExpectations(t)
+}
+
+func TestSpanReaderFindTracesPage(t *testing.T) {
+	query := &spanstore.TraceQueryParameters{ServiceName: "frontend", NumTraces: 1}
+	r := NewSpanReader(&spanstoremocks.Reader{}, Options{}, metrics.NullFactory)
+	_, _, err := r.FindTracesPage(context.Background(), query, "")
+	assert.Equal(t, spanstore.ErrPagingNotSupported, err)
+
+	mockReader := &spanstoremocks.PageReader{}
+	r = NewSpanReader(struct {
+		*spanstoremocks.Reader
+		*spanstoremocks.PageReader
+	}{&spanstoremocks.Reader{}, mockReader}, Options{}, metrics.NullFactory)
+	mockReader.On("FindTracesPage", mock.Anything, query, "").Return([]*model.Trace{{}}, "next", nil)
+	traces, next, err := r.FindTracesPage(context.Background(), query, "")
+	require.NoError(t, err)
+	assert.Len(t, traces, 1)
+	assert.Equal(t, "next", next)
+}
+
+func TestDependencyReader(t *testing.T) {
+	mockReader := &depsmocks.Reader{}
+	r := NewDependencyReader(mockReader, Options{DependenciesTTL: time.Minute}, metrics.NullFactory)
+	endTs := time.Unix(1600000020, 0)
+	links := []model.DependencyLink{{Parent: "a", Child: "b", CallCount: 1}}
+	// the storage is queried with the requested end of the time range, not the rounded one
+	mockReader.On("GetDependencies", endTs, time.Hour).Return(links, nil).Once()
+	mockReader.On("GetDependencies", endTs, 2*time.Hour).Return(nil, errStorage).Once()
+
+	// the later requests within the same TTL read the cached links
+	for _, ts := range []time.Time{endTs, endTs.Add(10 * time.Second)} {
+		res, err := r.GetDependencies(ts, time.Hour)
+		require.NoError(t, err)
+		assert.Equal(t, links, res)
+	}
+	_, err := r.GetDependencies(endTs, 2*time.Hour)
+	assert.Equal(t, errStorage, err)
+	mockReader.AssertExpectations(t)
+}
+
+func TestDependencyReaderDisabled(t *testing.T) {
+	mockReader := &depsmocks.Reader{}
+	r := NewDependencyReader(mockReader, Options{}, metrics.NullFactory)
+	endTs := time.Unix(1600000020, 0)
+	mockReader.On("GetDependencies", endTs, time.Hour).Return([]model.DependencyLink{}, nil).Twice()
+	for i := 0; i < 2; i++ {
+		_, err := r.GetDependencies(endTs, time.Hour)
+		require.NoError(t, err)
+	}
+	mockReader.AssertExpectations(t)
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DownsamplingOverrides holds the downsampling ratios of specific services and operations,
+// overriding the global ratio. The ratios are applied to the hash of the trace ID, so a
+// trace kept with some ratio is also kept with any higher ratio: when an operation is
+// downsampled harder than its service, its spans are kept for a subset of the traces
+// whose other spans are kept.
+type DownsamplingOverrides struct {
+	ServiceRatios []ServiceDownsamplingRatio `json:"service_ratios"`
+}
+
+// ServiceDownsamplingRatio is the downsampling ratio of a service, and optionally of some of its operations.
+type ServiceDownsamplingRatio struct {
+	Service string `json:"service"`
+	// Ratio applies to the operations of the service without their own ratio, the global ratio is used when nil
+	Ratio           *float64                     `json:"ratio,omitempty"`
+	OperationRatios []OperationDownsamplingRatio `json:"operation_ratios,omitempty"`
+}
+
+// OperationDownsamplingRatio is the downsampling ratio of an operation.
+type OperationDownsamplingRatio struct {
+	Operation string  `json:"operation"`
+	Ratio     float64 `json:"ratio"`
+}
+
+// LoadDownsamplingOverrides reads the overrides from a JSON file.
+func LoadDownsamplingOverrides(path string) (*DownsamplingOverrides, error) {
+	data, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open downsampling overrides file: %w", err)
+	}
+	return parseDownsamplingOverrides(data)
+}
+
+func parseDownsamplingOverrides(data []byte) (*DownsamplingOverrides, error) {
+	var overrides DownsamplingOverrides
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal downsampling overrides: %w", err)
+	}
+	for _, s := range overrides.ServiceRatios {
+		if s.Service == "" {
+			return nil, fmt.Errorf("downsampling override without a service")
+		}
+		if s.Ratio != nil && !validRatio(*s.Ratio) {
+			return nil, fmt.Errorf("invalid downsampling ratio %v of service %s, expecting a value between 0 and 1", *s.Ratio, s.Service)
+		}
+		for _, o := range s.OperationRatios {
+			if !validRatio(o.Ratio) {
+				return nil, fmt.Errorf("invalid downsampling ratio %v of operation %s of service %s, expecting a value between 0 and 1", o.Ratio, o.Operation, s.Service)
+			}
+		}
+	}
+	return &overrides, nil
+}
+
+func validRatio(ratio float64) bool {
+	return ratio >= 0 && ratio <= 1
+}
+
+type serviceThresholds struct {
+	threshold  uint64
+	operations map[string]uint64
+}
+
+// downsamplingThresholds are the hash thresholds computed from the global ratio and the overrides
+type downsamplingThresholds struct {
+	defaultThreshold uint64
+	services         map[string]serviceThresholds
+}
+
+func newDownsamplingThresholds(defaultThreshold uint64, overrides *DownsamplingOverrides) *downsamplingThresholds {
+	t := &downsamplingThresholds{
+		defaultThreshold: defaultThreshold,
+		services:         make(map[string]serviceThresholds),
+	}
+	if overrides == nil {
+		return t
+	}
+	for _, s := range overrides.ServiceRatios {
+		st := serviceThresholds{threshold: defaultThreshold, operations: make(map[string]uint64)}
+		if s.Ratio != nil {
+			st.threshold = calculateThreshold(*s.Ratio)
+		}
+		for _, o := range s.OperationRatios {
+			st.operations[o.Operation] = calculateThreshold(o.Ratio)
+		}
+		t.services[s.Service] = st
+	}
+	return t
+}
+
+func (t *downsamplingThresholds) forSpan(service, operation string) uint64 {
+	s, ok := t.services[service]
+	if !ok {
+		return t.defaultThreshold
+	}
+	if threshold, ok := s.operations[operation]; ok {
+		return threshold
+	}
+	return s.threshold
+}
+
+// reloadOverrides checks the overrides file periodically and applies its new content.
+func (ds *DownsamplingWriter) reloadOverrides(path string, lastValue []byte, interval time.Duration, logger *zap.Logger) {
+	defer close(ds.reloadDone)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			data, err := ioutil.ReadFile(filepath.Clean(path))
+			if err != nil {
+				logger.Error("failed to load downsampling overrides", zap.String("file", path), zap.Error(err))
+				continue
+			}
+			if bytes.Equal(data, lastValue) {
+				continue
+			}
+			overrides, err := parseDownsamplingOverrides(data)
+			if err != nil {
+				logger.Error("failed to update downsampling overrides from file", zap.String("file", path), zap.Error(err))
+				continue
+			}
+			ds.UpdateOverrides(overrides)
+			lastValue = data
+			logger.Info("Updated downsampling overrides", zap.String("file", path), zap.Int("services", len(overrides.ServiceRatios)))
+		case <-ds.stopReload:
+			return
+		}
+	}
+}
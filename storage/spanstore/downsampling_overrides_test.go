@@ -0,0 +1,142 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+type countingWriter struct {
+	spans  int
+	closed bool
+}
+
+func (w *countingWriter) WriteSpan(span *model.Span) error {
+	w.spans++
+	return nil
+}
+
+func (w *countingWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func writeSpans(t *testing.T, w Writer, service, operation string) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		span := &model.Span{
+			TraceID:       model.NewTraceID(r.Uint64(), r.Uint64()),
+			OperationName: operation,
+			Process:       &model.Process{ServiceName: service},
+		}
+		require.NoError(t, w.WriteSpan(span))
+	}
+}
+
+func TestParseDownsamplingOverrides(t *testing.T) {
+	o, err := parseDownsamplingOverrides([]byte(`{"service_ratios": [
+		{"service": "noisy", "ratio": 0.1, "operation_ratios": [{"operation": "health", "ratio": 0}]},
+		{"service": "critical", "operation_ratios": [{"operation": "pay", "ratio": 1}]}
+	]}`))
+	require.NoError(t, err)
+	require.Len(t, o.ServiceRatios, 2)
+	assert.Equal(t, 0.1, *o.ServiceRatios[0].Ratio)
+	assert.Nil(t, o.ServiceRatios[1].Ratio)
+
+	testCases := []struct {
+		data string
+		err  string
+	}{
+		{data: `{`, err: "failed to unmarshal downsampling overrides: unexpected end of JSON input"},
+		{data: `{"service_ratios": [{"ratio": 0.1}]}`, err: "downsampling override without a service"},
+		{data: `{"service_ratios": [{"service": "a", "ratio": 2}]}`, err: "invalid downsampling ratio 2 of service a, expecting a value between 0 and 1"},
+		{
+			data: `{"service_ratios": [{"service": "a", "operation_ratios": [{"operation": "b", "ratio": -1}]}]}`,
+			err:  "invalid downsampling ratio -1 of operation b of service a, expecting a value between 0 and 1",
+		},
+	}
+	for _, test := range testCases {
+		_, err := parseDownsamplingOverrides([]byte(test.data))
+		assert.EqualError(t, err, test.err)
+	}
+}
+
+func TestDownsamplingWriterOverrides(t *testing.T) {
+	noisyRatio, criticalRatio := 0.1, 1.0
+	w := &countingWriter{}
+	ds := NewDownsamplingWriter(w, DownsamplingOptions{
+		Ratio: 0.5,
+		Overrides: &DownsamplingOverrides{ServiceRatios: []ServiceDownsamplingRatio{
+			{Service: "noisy", Ratio: &noisyRatio, OperationRatios: []OperationDownsamplingRatio{{Operation: "health", Ratio: 0}}},
+			{Service: "critical", Ratio: &criticalRatio},
+			{Service: "default", OperationRatios: []OperationDownsamplingRatio{{Operation: "all", Ratio: 1}}},
+		}},
+	})
+
+	counts := func(service, operation string) int {
+		w.spans = 0
+		writeSpans(t, ds, service, operation)
+		return w.spans
+	}
+	assert.InDelta(t, 500, counts("other", "op"), 100, "global ratio")
+	assert.InDelta(t, 100, counts("noisy", "op"), 50)
+	assert.Equal(t, 0, counts("noisy", "health"))
+	assert.Equal(t, 1000, counts("critical", "op"))
+	assert.InDelta(t, 500, counts("default", "op"), 100, "services without a ratio use the global ratio")
+	assert.Equal(t, 1000, counts("default", "all"))
+
+	ds.UpdateOverrides(nil)
+	assert.InDelta(t, 500, counts("critical", "op"), 100)
+
+	require.NoError(t, ds.Close())
+	assert.True(t, w.closed)
+}
+
+func TestDownsamplingWriterReloadsOverrides(t *testing.T) {
+	dir, err := ioutil.TempDir("", "downsampling")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "overrides.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"service_ratios": []}`), 0600))
+
+	w := &countingWriter{}
+	ds := NewDownsamplingWriter(w, DownsamplingOptions{
+		Ratio:                   1,
+		OverridesFile:           path,
+		OverridesReloadInterval: 10 * time.Millisecond,
+	})
+	defer ds.Close()
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"service_ratios": [{"service": "noisy", "ratio": 0}]}`), 0600))
+	for i := 0; i < 200; i++ {
+		w.spans = 0
+		writeSpans(t, ds, "noisy", "op")
+		if w.spans == 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, 0, w.spans)
+}
@@ -17,11 +17,17 @@ package spanstore
 import (
 	"hash"
 	"hash/fnv"
+	"io"
+	"io/ioutil"
 	"math"
 	"math/big"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/uber/jaeger-lib/metrics"
+	"go.uber.org/zap"
 
 	"github.com/jaegertracing/jaeger/model"
 )
@@ -44,11 +50,16 @@ type downsamplingWriterMetrics struct {
 	SpansAccepted metrics.Counter `metric:"spans_accepted"`
 }
 
-// DownsamplingWriter is a span Writer that drops spans with a predefined downsamplingRatio.
+// DownsamplingWriter is a span Writer that drops spans with a predefined downsamplingRatio,
+// optionally overridden for some services and operations.
 type DownsamplingWriter struct {
 	spanWriter Writer
 	metrics    downsamplingWriterMetrics
 	sampler    *Sampler
+	thresholds atomic.Value // holds *downsamplingThresholds
+
+	stopReload chan struct{}
+	reloadDone chan struct{}
 }
 
 // DownsamplingOptions contains the options for constructing a DownsamplingWriter.
@@ -56,22 +67,60 @@ type DownsamplingOptions struct {
 	Ratio          float64
 	HashSalt       string
 	MetricsFactory metrics.Factory
+	// Overrides are the initial per-service and per-operation ratios, optional
+	Overrides *DownsamplingOverrides
+	// OverridesFile is checked every OverridesReloadInterval and the overrides updated when its content changes
+	OverridesFile           string
+	OverridesReloadInterval time.Duration
+	Logger                  *zap.Logger
 }
 
 // NewDownsamplingWriter creates a DownsamplingWriter.
 func NewDownsamplingWriter(spanWriter Writer, downsamplingOptions DownsamplingOptions) *DownsamplingWriter {
 	writeMetrics := &downsamplingWriterMetrics{}
 	metrics.Init(writeMetrics, downsamplingOptions.MetricsFactory, nil)
-	return &DownsamplingWriter{
+	ds := &DownsamplingWriter{
 		sampler:    NewSampler(downsamplingOptions.Ratio, downsamplingOptions.HashSalt),
 		spanWriter: spanWriter,
 		metrics:    *writeMetrics,
 	}
+	ds.UpdateOverrides(downsamplingOptions.Overrides)
+	if downsamplingOptions.OverridesFile != "" && downsamplingOptions.OverridesReloadInterval > 0 {
+		logger := downsamplingOptions.Logger
+		if logger == nil {
+			logger = zap.NewNop()
+		}
+		ds.stopReload = make(chan struct{})
+		ds.reloadDone = make(chan struct{})
+		// changes are detected against the content when the writer is created
+		lastValue, _ := ioutil.ReadFile(filepath.Clean(downsamplingOptions.OverridesFile))
+		go ds.reloadOverrides(downsamplingOptions.OverridesFile, lastValue, downsamplingOptions.OverridesReloadInterval, logger)
+	}
+	return ds
+}
+
+// UpdateOverrides replaces the per-service and per-operation ratios.
+func (ds *DownsamplingWriter) UpdateOverrides(overrides *DownsamplingOverrides) {
+	ds.thresholds.Store(newDownsamplingThresholds(ds.sampler.threshold, overrides))
+}
+
+// Close stops reloading the overrides file and closes the wrapped span writer if it is a Closer.
+func (ds *DownsamplingWriter) Close() error {
+	if ds.stopReload != nil {
+		close(ds.stopReload)
+		<-ds.reloadDone
+		ds.stopReload = nil
+	}
+	if closer, ok := ds.spanWriter.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
 }
 
 // WriteSpan calls WriteSpan on wrapped span writer.
 func (ds *DownsamplingWriter) WriteSpan(span *model.Span) error {
-	if !ds.sampler.ShouldSample(span) {
+	threshold := ds.thresholds.Load().(*downsamplingThresholds).forSpan(span.Process.GetServiceName(), span.OperationName)
+	if ds.sampler.hash(span) > threshold {
 		// Drops spans when hashVal falls beyond computed threshold.
 		ds.metrics.SpansDropped.Inc(1)
 		return nil
@@ -129,11 +178,16 @@ func calculateThreshold(ratio float64) uint64 {
 
 // ShouldSample decides if a span should be sampled
 func (s *Sampler) ShouldSample(span *model.Span) bool {
+	return s.hash(span) <= s.threshold
+}
+
+// hash returns the salted hash of the trace ID of the span
+func (s *Sampler) hash(span *model.Span) uint64 {
 	hasherInstance := s.hasherPool.Get().(*hasher)
 	// Currently MarshalTo will only return err if size of traceIDBytes is smaller than 16
 	// Since we force traceIDBytes to be size of 16 metrics is not necessary here.
 	_, _ = span.TraceID.MarshalTo(hasherInstance.buffer[s.lengthOfSalt:])
 	hashVal := hasherInstance.hashBytes()
 	s.hasherPool.Put(hasherInstance)
-	return hashVal <= s.threshold
+	return hashVal
 }
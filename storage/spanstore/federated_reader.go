@@ -0,0 +1,236 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/multierror"
+)
+
+// FederatedReader is a span Reader querying several underlying span Readers, e.g. a hot and a cold
+// storage or the clusters of several regions, and merging their results. The spans found in more
+// than one of them are deduplicated.
+type FederatedReader struct {
+	spanReaders []Reader
+}
+
+// NewFederatedReader creates a FederatedReader
+func NewFederatedReader(spanReaders ...Reader) *FederatedReader {
+	return &FederatedReader{
+		spanReaders: spanReaders,
+	}
+}
+
+// forEach calls f with each span reader concurrently, and returns the errors of all the calls.
+func (f *FederatedReader) forEach(call func(i int, reader Reader) error) error {
+	errs := make([]error, len(f.spanReaders))
+	var wg sync.WaitGroup
+	wg.Add(len(f.spanReaders))
+	for i, reader := range f.spanReaders {
+		go func(i int, reader Reader) {
+			defer wg.Done()
+			errs[i] = call(i, reader)
+		}(i, reader)
+	}
+	wg.Wait()
+	var failed []error
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	return multierror.Wrap(failed)
+}
+
+// GetTrace merges the spans of the trace found in the span readers. When only some of the span readers
+// fail, the spans found in the others are returned with a warning.
+func (f *FederatedReader) GetTrace(ctx context.Context, traceID model.TraceID) (*model.Trace, error) {
+	traces := make([]*model.Trace, len(f.spanReaders))
+	err := f.forEach(func(i int, reader Reader) error {
+		trace, err := reader.GetTrace(ctx, traceID)
+		if err == ErrTraceNotFound {
+			return nil
+		}
+		traces[i] = trace
+		return err
+	})
+	merged := mergeTraces(traces)
+	if merged == nil {
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrTraceNotFound
+	}
+	if err != nil {
+		merged.Warnings = append(merged.Warnings, fmt.Sprintf("some spans of the trace may be missing: %v", err))
+	}
+	return merged, nil
+}
+
+// FindTraces merges the traces found in the span readers, keeping the most recent ones when they
+// exceed the requested number of traces. When only some of the span readers fail, the traces found
+// in the others are returned with a warning.
+func (f *FederatedReader) FindTraces(ctx context.Context, query *TraceQueryParameters) ([]*model.Trace, error) {
+	results := make([][]*model.Trace, len(f.spanReaders))
+	err := f.forEach(func(i int, reader Reader) error {
+		traces, err := reader.FindTraces(ctx, query)
+		results[i] = traces
+		return err
+	})
+	var order []model.TraceID
+	byID := make(map[model.TraceID][]*model.Trace)
+	for _, traces := range results {
+		for _, trace := range traces {
+			if len(trace.Spans) == 0 {
+				continue
+			}
+			traceID := trace.Spans[0].TraceID
+			if _, ok := byID[traceID]; !ok {
+				order = append(order, traceID)
+			}
+			byID[traceID] = append(byID[traceID], trace)
+		}
+	}
+	merged := make([]*model.Trace, 0, len(order))
+	for _, traceID := range order {
+		merged = append(merged, mergeTraces(byID[traceID]))
+	}
+	if query.NumTraces > 0 && len(merged) > query.NumTraces {
+		sort.SliceStable(merged, func(i, j int) bool {
+			return traceStartTime(merged[i]).After(traceStartTime(merged[j]))
+		})
+		merged = merged[:query.NumTraces]
+	}
+	if err != nil {
+		if len(merged) == 0 {
+			return nil, err
+		}
+		for _, trace := range merged {
+			trace.Warnings = append(trace.Warnings, fmt.Sprintf("some traces or spans of the search may be missing: %v", err))
+		}
+	}
+	return merged, nil
+}
+
+// FindTraceIDs returns the trace IDs found in any of the span readers.
+func (f *FederatedReader) FindTraceIDs(ctx context.Context, query *TraceQueryParameters) ([]model.TraceID, error) {
+	results := make([][]model.TraceID, len(f.spanReaders))
+	err := f.forEach(func(i int, reader Reader) error {
+		traceIDs, err := reader.FindTraceIDs(ctx, query)
+		results[i] = traceIDs
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	var merged []model.TraceID
+	seen := make(map[model.TraceID]bool)
+	for _, traceIDs := range results {
+		for _, traceID := range traceIDs {
+			if !seen[traceID] {
+				seen[traceID] = true
+				merged = append(merged, traceID)
+			}
+		}
+	}
+	return merged, nil
+}
+
+// GetServices returns the services found in any of the span readers.
+func (f *FederatedReader) GetServices(ctx context.Context) ([]string, error) {
+	results := make([][]string, len(f.spanReaders))
+	err := f.forEach(func(i int, reader Reader) error {
+		services, err := reader.GetServices(ctx)
+		results[i] = services
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	var merged []string
+	seen := make(map[string]bool)
+	for _, services := range results {
+		for _, service := range services {
+			if !seen[service] {
+				seen[service] = true
+				merged = append(merged, service)
+			}
+		}
+	}
+	return merged, nil
+}
+
+// GetOperations returns the operations found in any of the span readers.
+func (f *FederatedReader) GetOperations(ctx context.Context, query OperationQueryParameters) ([]Operation, error) {
+	results := make([][]Operation, len(f.spanReaders))
+	err := f.forEach(func(i int, reader Reader) error {
+		operations, err := reader.GetOperations(ctx, query)
+		results[i] = operations
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	var merged []Operation
+	seen := make(map[Operation]bool)
+	for _, operations := range results {
+		for _, operation := range operations {
+			if !seen[operation] {
+				seen[operation] = true
+				merged = append(merged, operation)
+			}
+		}
+	}
+	return merged, nil
+}
+
+// mergeTraces returns the spans of the traces without duplicates, or nil if there are no traces.
+func mergeTraces(traces []*model.Trace) *model.Trace {
+	var merged *model.Trace
+	seen := make(map[uint64]bool)
+	for _, trace := range traces {
+		if trace == nil {
+			continue
+		}
+		if merged == nil {
+			merged = &model.Trace{}
+		}
+		for _, span := range trace.Spans {
+			hash, err := model.HashCode(span)
+			if err == nil && seen[hash] {
+				continue
+			}
+			seen[hash] = true
+			merged.Spans = append(merged.Spans, span)
+		}
+		merged.Warnings = append(merged.Warnings, trace.Warnings...)
+	}
+	return merged
+}
+
+func traceStartTime(trace *model.Trace) (start time.Time) {
+	for i, span := range trace.Spans {
+		if i == 0 || span.StartTime.Before(start) {
+			start = span.StartTime
+		}
+	}
+	return start
+}
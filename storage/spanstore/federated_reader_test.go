@@ -0,0 +1,181 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+	. "github.com/jaegertracing/jaeger/storage/spanstore"
+	"github.com/jaegertracing/jaeger/storage/spanstore/mocks"
+)
+
+var errRegionDown = errors.New("region down")
+
+func federatedSpan(traceID, spanID uint64, start time.Time) *model.Span {
+	return &model.Span{
+		TraceID:   model.NewTraceID(0, traceID),
+		SpanID:    model.NewSpanID(spanID),
+		StartTime: start,
+		Process:   model.NewProcess("frontend", nil),
+	}
+}
+
+func TestFederatedReaderGetTrace(t *testing.T) {
+	hot, cold, other := &mocks.Reader{}, &mocks.Reader{}, &mocks.Reader{}
+	f := NewFederatedReader(hot, cold, other)
+	traceID := model.NewTraceID(0, 1)
+	start := time.Unix(1600000000, 0)
+	hot.On("GetTrace", mock.Anything, traceID).Return(&model.Trace{Spans: []*model.Span{
+		federatedSpan(1, 1, start), federatedSpan(1, 2, start),
+	}}, nil)
+	cold.On("GetTrace", mock.Anything, traceID).Return(&model.Trace{Spans: []*model.Span{
+		federatedSpan(1, 2, start), federatedSpan(1, 3, start),
+	}}, nil)
+	other.On("GetTrace", mock.Anything, traceID).Return(nil, ErrTraceNotFound)
+
+	trace, err := f.GetTrace(context.Background(), traceID)
+	require.NoError(t, err)
+	require.Len(t, trace.Spans, 3)
+	for i, span := range trace.Spans {
+		assert.Equal(t, model.NewSpanID(uint64(i+1)), span.SpanID)
+	}
+	assert.Empty(t, trace.Warnings)
+}
+
+func TestFederatedReaderGetTracePartial(t *testing.T) {
+	hot, cold := &mocks.Reader{}, &mocks.Reader{}
+	f := NewFederatedReader(hot, cold)
+	traceID := model.NewTraceID(0, 1)
+	hot.On("GetTrace", mock.Anything, traceID).Return(&model.Trace{Spans: []*model.Span{
+		federatedSpan(1, 1, time.Unix(0, 0)),
+	}}, nil)
+	cold.On("GetTrace", mock.Anything, traceID).Return(nil, errRegionDown)
+
+	trace, err := f.GetTrace(context.Background(), traceID)
+	require.NoError(t, err)
+	assert.Len(t, trace.Spans, 1)
+	assert.Equal(t, []string{"some spans of the trace may be missing: region down"}, trace.Warnings)
+}
+
+func TestFederatedReaderGetTraceNotFound(t *testing.T) {
+	hot, cold := &mocks.Reader{}, &mocks.Reader{}
+	f := NewFederatedReader(hot, cold)
+	hot.On("GetTrace", mock.Anything, mock.Anything).Return(nil, ErrTraceNotFound)
+	cold.On("GetTrace", mock.Anything, mock.Anything).Return(nil, ErrTraceNotFound)
+	_, err := f.GetTrace(context.Background(), model.NewTraceID(0, 1))
+	assert.Equal(t, ErrTraceNotFound, err)
+
+	failing := &mocks.Reader{}
+	failing.On("GetTrace", mock.Anything, mock.Anything).Return(nil, errRegionDown)
+	_, err = NewFederatedReader(hot, failing).GetTrace(context.Background(), model.NewTraceID(0, 1))
+	assert.Equal(t, errRegionDown, err)
+}
+
+func TestFederatedReaderFindTraces(t *testing.T) {
+	hot, cold := &mocks.Reader{}, &mocks.Reader{}
+	f := NewFederatedReader(hot, cold)
+	start := time.Unix(1600000000, 0)
+	query := &TraceQueryParameters{ServiceName: "frontend", NumTraces: 2}
+	hot.On("FindTraces", mock.Anything, query).Return([]*model.Trace{
+		{Spans: []*model.Span{federatedSpan(1, 1, start.Add(time.Minute))}},
+		{Spans: []*model.Span{federatedSpan(2, 1, start.Add(2*time.Minute))}},
+		{},
+	}, nil)
+	cold.On("FindTraces", mock.Anything, query).Return([]*model.Trace{
+		{Spans: []*model.Span{federatedSpan(1, 1, start.Add(time.Minute)), federatedSpan(1, 2, start)}},
+		{Spans: []*model.Span{federatedSpan(3, 1, start)}},
+	}, nil)
+
+	traces, err := f.FindTraces(context.Background(), query)
+	require.NoError(t, err)
+	require.Len(t, traces, 2)
+	assert.Equal(t, model.NewTraceID(0, 2), traces[0].Spans[0].TraceID)
+	assert.Equal(t, model.NewTraceID(0, 1), traces[1].Spans[0].TraceID)
+	assert.Len(t, traces[1].Spans, 2)
+
+	for _, trace := range traces {
+		assert.Empty(t, trace.Warnings)
+	}
+}
+
+func TestFederatedReaderFindTracesPartial(t *testing.T) {
+	hot, failing := &mocks.Reader{}, &mocks.Reader{}
+	query := &TraceQueryParameters{ServiceName: "frontend"}
+	hot.On("FindTraces", mock.Anything, query).Return([]*model.Trace{
+		{Spans: []*model.Span{federatedSpan(1, 1, time.Unix(0, 0))}},
+	}, nil)
+	failing.On("FindTraces", mock.Anything, query).Return(nil, errRegionDown)
+
+	traces, err := NewFederatedReader(hot, failing).FindTraces(context.Background(), query)
+	require.NoError(t, err)
+	require.Len(t, traces, 1)
+	assert.Equal(t, []string{"some traces or spans of the search may be missing: region down"}, traces[0].Warnings)
+
+	_, err = NewFederatedReader(failing, failing).FindTraces(context.Background(), query)
+	assert.EqualError(t, err, "[region down, region down]")
+}
+
+func TestFederatedReaderFindTraceIDs(t *testing.T) {
+	hot, cold := &mocks.Reader{}, &mocks.Reader{}
+	f := NewFederatedReader(hot, cold)
+	query := &TraceQueryParameters{ServiceName: "frontend"}
+	hot.On("FindTraceIDs", mock.Anything, query).Return([]model.TraceID{model.NewTraceID(0, 1), model.NewTraceID(0, 2)}, nil)
+	cold.On("FindTraceIDs", mock.Anything, query).Return([]model.TraceID{model.NewTraceID(0, 2), model.NewTraceID(0, 3)}, nil)
+
+	traceIDs, err := f.FindTraceIDs(context.Background(), query)
+	require.NoError(t, err)
+	assert.Equal(t, []model.TraceID{model.NewTraceID(0, 1), model.NewTraceID(0, 2), model.NewTraceID(0, 3)}, traceIDs)
+
+	failing := &mocks.Reader{}
+	failing.On("FindTraceIDs", mock.Anything, query).Return(nil, errRegionDown)
+	_, err = NewFederatedReader(hot, failing).FindTraceIDs(context.Background(), query)
+	assert.Equal(t, errRegionDown, err)
+}
+
+func TestFederatedReaderGetServicesAndOperations(t *testing.T) {
+	hot, cold := &mocks.Reader{}, &mocks.Reader{}
+	f := NewFederatedReader(hot, cold)
+	hot.On("GetServices", mock.Anything).Return([]string{"a", "b"}, nil)
+	cold.On("GetServices", mock.Anything).Return([]string{"b", "c"}, nil)
+	query := OperationQueryParameters{ServiceName: "a"}
+	hot.On("GetOperations", mock.Anything, query).Return([]Operation{{Name: "GET", SpanKind: "server"}}, nil)
+	cold.On("GetOperations", mock.Anything, query).Return([]Operation{
+		{Name: "GET", SpanKind: "server"},
+		{Name: "GET", SpanKind: "client"},
+	}, nil)
+
+	services, err := f.GetServices(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, services)
+	operations, err := f.GetOperations(context.Background(), query)
+	require.NoError(t, err)
+	assert.Equal(t, []Operation{{Name: "GET", SpanKind: "server"}, {Name: "GET", SpanKind: "client"}}, operations)
+
+	failing := &mocks.Reader{}
+	failing.On("GetServices", mock.Anything).Return(nil, errRegionDown)
+	failing.On("GetOperations", mock.Anything, query).Return(nil, errRegionDown)
+	_, err = NewFederatedReader(hot, failing).GetServices(context.Background())
+	assert.Equal(t, errRegionDown, err)
+	_, err = NewFederatedReader(hot, failing).GetOperations(context.Background(), query)
+	assert.Equal(t, errRegionDown, err)
+}
@@ -31,6 +31,12 @@ type Writer interface {
 var (
 	// ErrTraceNotFound is returned by Reader's GetTrace if no data is found for given trace ID.
 	ErrTraceNotFound = errors.New("trace not found")
+
+	// ErrPagingNotSupported is returned by FindTracesPage when the reader cannot page through the traces of a search.
+	ErrPagingNotSupported = errors.New("paging through the traces not supported")
+
+	// ErrInvalidPageCursor is returned by PageReader's FindTracesPage if the cursor was not returned by a previous page.
+	ErrInvalidPageCursor = errors.New("invalid page cursor")
 )
 
 // Reader finds and loads traces and other data from storage.
@@ -42,6 +48,25 @@ type Reader interface {
 	FindTraceIDs(ctx context.Context, query *TraceQueryParameters) ([]model.TraceID, error)
 }
 
+// PageReader is implemented by the readers able to page through the traces of a search in the storage,
+// rather than running the search again for each page.
+type PageReader interface {
+	// FindTracesPage returns query.NumTraces traces of the search starting at the cursor, the most recent
+	// first, and the cursor of the next page, empty when there are no more traces. The cursor of the
+	// first page is empty.
+	FindTracesPage(ctx context.Context, query *TraceQueryParameters, cursor string) ([]*model.Trace, string, error)
+}
+
+// FindTracesPage returns a page of the traces of a search if the reader is a PageReader,
+// ErrPagingNotSupported otherwise.
+func FindTracesPage(ctx context.Context, reader Reader, query *TraceQueryParameters, cursor string) ([]*model.Trace, string, error) {
+	pageReader, ok := reader.(PageReader)
+	if !ok {
+		return nil, "", ErrPagingNotSupported
+	}
+	return pageReader.FindTracesPage(ctx, query, cursor)
+}
+
 // TraceQueryParameters contains parameters of a trace query.
 type TraceQueryParameters struct {
 	ServiceName   string
@@ -52,6 +77,9 @@ type TraceQueryParameters struct {
 	DurationMin   time.Duration
 	DurationMax   time.Duration
 	NumTraces     int
+	// TagFilters are the conditions on the tags other than equality. The backends not supporting
+	// some of them return the traces regardless, they are filtered by the query service.
+	TagFilters []TagFilter
 }
 
 // OperationQueryParameters contains parameters of query operations, empty spanKind means get operations for all kinds of span.
@@ -27,12 +27,13 @@ import (
 
 // ReadMetricsDecorator wraps a spanstore.Reader and collects metrics around each read operation.
 type ReadMetricsDecorator struct {
-	spanReader           spanstore.Reader
-	findTracesMetrics    *queryMetrics
-	findTraceIDsMetrics  *queryMetrics
-	getTraceMetrics      *queryMetrics
-	getServicesMetrics   *queryMetrics
-	getOperationsMetrics *queryMetrics
+	spanReader            spanstore.Reader
+	findTracesMetrics     *queryMetrics
+	findTracesPageMetrics *queryMetrics
+	findTraceIDsMetrics   *queryMetrics
+	getTraceMetrics       *queryMetrics
+	getServicesMetrics    *queryMetrics
+	getOperationsMetrics  *queryMetrics
 }
 
 type queryMetrics struct {
@@ -57,12 +58,13 @@ func (q *queryMetrics) emit(err error, latency time.Duration, responses int) {
 // NewReadMetricsDecorator returns a new ReadMetricsDecorator.
 func NewReadMetricsDecorator(spanReader spanstore.Reader, metricsFactory metrics.Factory) *ReadMetricsDecorator {
 	return &ReadMetricsDecorator{
-		spanReader:           spanReader,
-		findTracesMetrics:    buildQueryMetrics("find_traces", metricsFactory),
-		findTraceIDsMetrics:  buildQueryMetrics("find_trace_ids", metricsFactory),
-		getTraceMetrics:      buildQueryMetrics("get_trace", metricsFactory),
-		getServicesMetrics:   buildQueryMetrics("get_services", metricsFactory),
-		getOperationsMetrics: buildQueryMetrics("get_operations", metricsFactory),
+		spanReader:            spanReader,
+		findTracesMetrics:     buildQueryMetrics("find_traces", metricsFactory),
+		findTracesPageMetrics: buildQueryMetrics("find_traces_page", metricsFactory),
+		findTraceIDsMetrics:   buildQueryMetrics("find_trace_ids", metricsFactory),
+		getTraceMetrics:       buildQueryMetrics("get_trace", metricsFactory),
+		getServicesMetrics:    buildQueryMetrics("get_services", metricsFactory),
+		getOperationsMetrics:  buildQueryMetrics("get_operations", metricsFactory),
 	}
 }
 
@@ -81,6 +83,21 @@ func (m *ReadMetricsDecorator) FindTraces(ctx context.Context, traceQuery *spans
 	return retMe, err
 }
 
+// FindTracesPage implements spanstore.PageReader#FindTracesPage if the wrapped reader does
+func (m *ReadMetricsDecorator) FindTracesPage(
+	ctx context.Context,
+	traceQuery *spanstore.TraceQueryParameters,
+	cursor string,
+) ([]*model.Trace, string, error) {
+	start := time.Now()
+	retMe, next, err := spanstore.FindTracesPage(ctx, m.spanReader, traceQuery, cursor)
+	if err == spanstore.ErrPagingNotSupported {
+		return nil, "", err
+	}
+	m.findTracesPageMetrics.emit(err, time.Since(start), len(retMe))
+	return retMe, next, err
+}
+
 // FindTraceIDs implements spanstore.Reader#FindTraceIDs
 func (m *ReadMetricsDecorator) FindTraceIDs(ctx context.Context, traceQuery *spanstore.TraceQueryParameters) ([]model.TraceID, error) {
 	start := time.Now()
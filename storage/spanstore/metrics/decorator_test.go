@@ -142,3 +142,41 @@ func TestFailingUnderlyingCalls(t *testing.T) {
 
 	checkExpectedExistingAndNonExistentCounters(t, counters, expecteds, gauges, existingKeys, nonExistentKeys)
 }
+
+type pageReader struct {
+	*mocks.Reader
+	*mocks.PageReader
+}
+
+func TestFindTracesPage(t *testing.T) {
+	mf := metricstest.NewFactory(0)
+	query := &spanstore.TraceQueryParameters{NumTraces: 2}
+
+	mrs := NewReadMetricsDecorator(&mocks.Reader{}, mf)
+	_, _, err := mrs.FindTracesPage(context.Background(), query, "")
+	assert.Equal(t, spanstore.ErrPagingNotSupported, err)
+
+	mockReader := &mocks.PageReader{}
+	mrs = NewReadMetricsDecorator(pageReader{&mocks.Reader{}, mockReader}, mf)
+	mockReader.On("FindTracesPage", context.Background(), query, "").
+		Return([]*model.Trace{{}, {}}, "next", nil)
+	mockReader.On("FindTracesPage", context.Background(), query, "next").
+		Return(nil, "", errors.New("Failure"))
+	traces, next, err := mrs.FindTracesPage(context.Background(), query, "")
+	assert.NoError(t, err)
+	assert.Len(t, traces, 2)
+	assert.Equal(t, "next", next)
+	_, _, err = mrs.FindTracesPage(context.Background(), query, "next")
+	assert.Error(t, err)
+
+	counters, gauges := mf.Snapshot()
+	expecteds := map[string]int64{
+		"requests|operation=find_traces_page|result=ok":  1,
+		"requests|operation=find_traces_page|result=err": 1,
+	}
+	existingKeys := []string{
+		"latency|operation=find_traces_page|result=ok.P50",
+		"responses|operation=find_traces_page.P50",
+	}
+	checkExpectedExistingAndNonExistentCounters(t, counters, expecteds, gauges, existingKeys, nil)
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mocks
+
+import (
+	"context"
+)
+import "github.com/stretchr/testify/mock"
+import "github.com/jaegertracing/jaeger/model"
+import "github.com/jaegertracing/jaeger/storage/spanstore"
+
+// PageReader is an autogenerated mock type for the PageReader type
+type PageReader struct {
+	mock.Mock
+}
+
+// FindTracesPage provides a mock function with given fields: ctx, query, cursor
+func (_m *PageReader) FindTracesPage(ctx context.Context, query *spanstore.TraceQueryParameters, cursor string) ([]*model.Trace, string, error) {
+	ret := _m.Called(ctx, query, cursor)
+
+	var r0 []*model.Trace
+	if rf, ok := ret.Get(0).(func(context.Context, *spanstore.TraceQueryParameters, string) []*model.Trace); ok {
+		r0 = rf(ctx, query, cursor)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Trace)
+		}
+	}
+
+	var r1 string
+	if rf, ok := ret.Get(1).(func(context.Context, *spanstore.TraceQueryParameters, string) string); ok {
+		r1 = rf(ctx, query, cursor)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, *spanstore.TraceQueryParameters, string) error); ok {
+		r2 = rf(ctx, query, cursor)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
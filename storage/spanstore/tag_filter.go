@@ -0,0 +1,228 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+// TagOperator is the comparison of a TagFilter
+type TagOperator string
+
+// The operators of the tag filters. The negations match the spans with no value of the key matching the filter,
+// including the spans without the key.
+const (
+	TagExists         TagOperator = "exists"
+	TagNotExists      TagOperator = "not-exists"
+	TagEquals         TagOperator = "="
+	TagNotEquals      TagOperator = "!="
+	TagGreater        TagOperator = ">"
+	TagGreaterOrEqual TagOperator = ">="
+	TagLess           TagOperator = "<"
+	TagLessOrEqual    TagOperator = "<="
+	TagRegex          TagOperator = "=~"
+	TagNotRegex       TagOperator = "!~"
+	TagWildcard       TagOperator = "~"
+)
+
+// TagFilter restricts the spans of a trace query by the values of a key in their tags,
+// the tags of their process or the fields of their logs.
+type TagFilter struct {
+	Key      string
+	Operator TagOperator
+	Value    string
+
+	// pattern is the compiled regular expression of the regex and wildcard operators
+	pattern *regexp.Regexp
+	// number is the value of the numeric comparisons
+	number float64
+}
+
+// NewTagFilter validates the value for the operator, e.g. that it is a number for the comparisons.
+// The regular expressions and wildcards must match the whole value; the wildcards accept * and ?.
+func NewTagFilter(key string, operator TagOperator, value string) (TagFilter, error) {
+	f := TagFilter{Key: key, Operator: operator, Value: value}
+	if key == "" {
+		return f, fmt.Errorf("tag filter %q has no key", f.String())
+	}
+	switch operator {
+	case TagExists, TagNotExists:
+		f.Value = ""
+	case TagEquals, TagNotEquals:
+	case TagGreater, TagGreaterOrEqual, TagLess, TagLessOrEqual:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return f, fmt.Errorf("tag filter %q expects a number", f.String())
+		}
+		f.number = number
+	case TagRegex, TagNotRegex:
+		pattern, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return f, fmt.Errorf("tag filter %q has an invalid regular expression: %w", f.String(), err)
+		}
+		f.pattern = pattern
+	case TagWildcard:
+		f.pattern = regexp.MustCompile("^" + wildcardToRegex(value) + "$")
+	default:
+		return f, fmt.Errorf("unknown tag filter operator %q", operator)
+	}
+	return f, nil
+}
+
+// ParseTagFilter parses a filter such as "http.status_code>=500", "error" (the key exists),
+// "!error" (the key does not exist), "http.url=~.*/api/.*" or "peer.service~redis-*".
+func ParseTagFilter(expr string) (TagFilter, error) {
+	i := strings.IndexAny(expr, "=!<>~")
+	if i < 0 {
+		return NewTagFilter(expr, TagExists, "")
+	}
+	if i == 0 && expr[0] == '!' && !strings.ContainsAny(expr[1:], "=!<>~") {
+		return NewTagFilter(expr[1:], TagNotExists, "")
+	}
+	key, rest := expr[:i], expr[i:]
+	// the longest operators first
+	for _, operator := range []TagOperator{
+		TagNotEquals, TagGreaterOrEqual, TagLessOrEqual, TagRegex, TagNotRegex,
+		TagEquals, TagGreater, TagLess, TagWildcard,
+	} {
+		if strings.HasPrefix(rest, string(operator)) {
+			return NewTagFilter(key, operator, rest[len(operator):])
+		}
+	}
+	return TagFilter{}, fmt.Errorf("malformed tag filter %q, expecting key, !key or key followed by one of "+
+		"=, !=, >, >=, <, <=, =~, !~, ~ and a value", expr)
+}
+
+// String returns the filter in the syntax of ParseTagFilter.
+func (f TagFilter) String() string {
+	switch f.Operator {
+	case TagExists:
+		return f.Key
+	case TagNotExists:
+		return "!" + f.Key
+	default:
+		return f.Key + string(f.Operator) + f.Value
+	}
+}
+
+// IsNegation returns true for the filters matching the spans without any matching value of the key.
+func (f TagFilter) IsNegation() bool {
+	return f.Operator == TagNotExists || f.Operator == TagNotEquals || f.Operator == TagNotRegex
+}
+
+// Matches returns true if the key values, e.g. the tags of a span, satisfy the filter.
+func (f TagFilter) Matches(kvs model.KeyValues) bool {
+	found := false
+	for _, kv := range kvs {
+		if kv.Key == f.Key && f.matchesValue(kv) {
+			found = true
+			break
+		}
+	}
+	return found != f.IsNegation()
+}
+
+// matchesValue returns true if the value matches the filter, or its negation for the negative operators.
+func (f TagFilter) matchesValue(kv model.KeyValue) bool {
+	switch f.Operator {
+	case TagExists, TagNotExists:
+		return true
+	case TagEquals, TagNotEquals:
+		return kv.AsString() == f.Value
+	case TagRegex, TagNotRegex, TagWildcard:
+		return f.pattern.MatchString(kv.AsString())
+	}
+	var number float64
+	switch kv.VType {
+	case model.Int64Type:
+		number = float64(kv.Int64())
+	case model.Float64Type:
+		number = kv.Float64()
+	case model.StringType:
+		n, err := strconv.ParseFloat(kv.VStr, 64)
+		if err != nil {
+			return false
+		}
+		number = n
+	default:
+		return false
+	}
+	switch f.Operator {
+	case TagGreater:
+		return number > f.number
+	case TagGreaterOrEqual:
+		return number >= f.number
+	case TagLess:
+		return number < f.number
+	default:
+		return number <= f.number
+	}
+}
+
+// MatchesSpan returns true if the tags, the process tags and the log fields of the span satisfy all the filters.
+func MatchesSpan(span *model.Span, filters []TagFilter) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	kvs := make(model.KeyValues, 0, len(span.Tags))
+	kvs = append(kvs, span.Tags...)
+	if span.Process != nil {
+		kvs = append(kvs, span.Process.Tags...)
+	}
+	for _, log := range span.Logs {
+		kvs = append(kvs, log.Fields...)
+	}
+	for _, f := range filters {
+		if !f.Matches(kvs) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchesTrace returns true if at least one span of the trace satisfies all the filters.
+func MatchesTrace(trace *model.Trace, filters []TagFilter) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	for _, span := range trace.Spans {
+		if MatchesSpan(span, filters) {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcardToRegex converts a pattern where * matches any characters and ? a single one.
+func wildcardToRegex(pattern string) string {
+	var b strings.Builder
+	for i, part := range strings.Split(pattern, "*") {
+		if i > 0 {
+			b.WriteString(".*")
+		}
+		for j, q := range strings.Split(part, "?") {
+			if j > 0 {
+				b.WriteString(".")
+			}
+			b.WriteString(regexp.QuoteMeta(q))
+		}
+	}
+	return b.String()
+}
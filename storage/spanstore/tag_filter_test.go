@@ -0,0 +1,148 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+func TestParseTagFilter(t *testing.T) {
+	testCases := []struct {
+		expr     string
+		key      string
+		operator TagOperator
+		value    string
+	}{
+		{expr: "error", key: "error", operator: TagExists},
+		{expr: "!error", key: "error", operator: TagNotExists},
+		{expr: "http.method=GET", key: "http.method", operator: TagEquals, value: "GET"},
+		{expr: "http.method!=GET", key: "http.method", operator: TagNotEquals, value: "GET"},
+		{expr: "http.status_code>499", key: "http.status_code", operator: TagGreater, value: "499"},
+		{expr: "http.status_code>=500", key: "http.status_code", operator: TagGreaterOrEqual, value: "500"},
+		{expr: "retries<3", key: "retries", operator: TagLess, value: "3"},
+		{expr: "retries<=2.5", key: "retries", operator: TagLessOrEqual, value: "2.5"},
+		{expr: "http.url=~.*/api/.*", key: "http.url", operator: TagRegex, value: ".*/api/.*"},
+		{expr: "http.url!~.*/health", key: "http.url", operator: TagNotRegex, value: ".*/health"},
+		{expr: "peer.service~redis-*", key: "peer.service", operator: TagWildcard, value: "redis-*"},
+		{expr: "query=a=b", key: "query", operator: TagEquals, value: "a=b"},
+		{expr: "empty=", key: "empty", operator: TagEquals, value: ""},
+	}
+	for _, test := range testCases {
+		t.Run(test.expr, func(t *testing.T) {
+			f, err := ParseTagFilter(test.expr)
+			require.NoError(t, err)
+			assert.Equal(t, test.key, f.Key)
+			assert.Equal(t, test.operator, f.Operator)
+			assert.Equal(t, test.value, f.Value)
+			assert.Equal(t, test.expr, f.String())
+		})
+	}
+}
+
+func TestParseTagFilterErrors(t *testing.T) {
+	testCases := []struct {
+		expr string
+		err  string
+	}{
+		{expr: "", err: `tag filter "" has no key`},
+		{expr: "=x", err: `tag filter "=x" has no key`},
+		{expr: "!", err: `tag filter "!" has no key`},
+		{expr: "!a=b", err: `malformed tag filter "!a=b", expecting key, !key or key followed by one of =, !=, >, >=, <, <=, =~, !~, ~ and a value`},
+		{expr: "code>x", err: `tag filter "code>x" expects a number`},
+		{expr: "url=~(", err: "tag filter \"url=~(\" has an invalid regular expression: error parsing regexp: missing closing ): `^(?:()$`"},
+		{expr: "a!b", err: `malformed tag filter "a!b", expecting key, !key or key followed by one of =, !=, >, >=, <, <=, =~, !~, ~ and a value`},
+	}
+	for _, test := range testCases {
+		t.Run(test.expr, func(t *testing.T) {
+			_, err := ParseTagFilter(test.expr)
+			assert.EqualError(t, err, test.err)
+		})
+	}
+	_, err := NewTagFilter("a", "?", "")
+	assert.EqualError(t, err, `unknown tag filter operator "?"`)
+}
+
+func TestTagFilterMatches(t *testing.T) {
+	kvs := model.KeyValues{
+		model.String("http.method", "GET"),
+		model.Int64("http.status_code", 503),
+		model.Float64("ratio", 0.5),
+		model.String("retries", "2"),
+		model.Bool("error", true),
+		model.String("peer.service", "redis-cache"),
+		model.String("peer.service", "mysql"),
+	}
+	testCases := []struct {
+		expr    string
+		matches bool
+	}{
+		{"error", true},
+		{"missing", false},
+		{"!error", false},
+		{"!missing", true},
+		{"http.method=GET", true},
+		{"http.method=POST", false},
+		{"http.method!=POST", true},
+		{"http.method!=GET", false},
+		{"missing!=GET", true},
+		{"http.status_code>=500", true},
+		{"http.status_code>503", false},
+		{"http.status_code<600", true},
+		{"http.status_code<=503", true},
+		{"ratio<1", true},
+		{"retries>1", true},
+		{"http.method>1", false},
+		{"error>0", false},
+		{"peer.service=~redis-.*", true},
+		{"peer.service=~redis", false},
+		{"peer.service!~redis-.*", false},
+		{"peer.service!~postgres", true},
+		{"peer.service~redis-*", true},
+		{"peer.service~my?ql", true},
+		{"peer.service~*.cache", false},
+	}
+	for _, test := range testCases {
+		t.Run(test.expr, func(t *testing.T) {
+			f, err := ParseTagFilter(test.expr)
+			require.NoError(t, err)
+			assert.Equal(t, test.matches, f.Matches(kvs))
+		})
+	}
+}
+
+func TestMatchesTrace(t *testing.T) {
+	filter := func(expr string) TagFilter {
+		f, err := ParseTagFilter(expr)
+		require.NoError(t, err)
+		return f
+	}
+	span := &model.Span{
+		Tags:    model.KeyValues{model.String("http.method", "GET")},
+		Process: model.NewProcess("frontend", []model.KeyValue{model.String("hostname", "host-1")}),
+		Logs:    []model.Log{{Fields: []model.KeyValue{model.String("event", "retry")}}},
+	}
+	trace := &model.Trace{Spans: []*model.Span{{Process: model.NewProcess("redis", nil)}, span}}
+
+	assert.True(t, MatchesTrace(trace, nil))
+	assert.True(t, MatchesTrace(trace, []TagFilter{filter("http.method=GET"), filter("hostname~host-*"), filter("event=retry")}))
+	assert.True(t, MatchesTrace(trace, []TagFilter{filter("!http.method")}))
+	assert.False(t, MatchesTrace(trace, []TagFilter{filter("http.method=GET"), filter("event=timeout")}))
+	assert.True(t, MatchesSpan(&model.Span{}, []TagFilter{filter("!error")}))
+}
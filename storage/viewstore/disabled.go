@@ -0,0 +1,67 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package viewstore
+
+import (
+	"context"
+)
+
+type disabledStore struct{}
+
+// NewDisabledStore returns a Store failing all the operations with ErrDisabled,
+// used when no view storage is configured.
+func NewDisabledStore() Store {
+	return disabledStore{}
+}
+
+// GetSearches implements Store.
+func (disabledStore) GetSearches(context.Context) ([]*SavedSearch, error) {
+	return nil, ErrDisabled
+}
+
+// GetSearch implements Store.
+func (disabledStore) GetSearch(context.Context, string) (*SavedSearch, error) {
+	return nil, ErrDisabled
+}
+
+// SaveSearch implements Store.
+func (disabledStore) SaveSearch(context.Context, *SavedSearch) error {
+	return ErrDisabled
+}
+
+// DeleteSearch implements Store.
+func (disabledStore) DeleteSearch(context.Context, string) error {
+	return ErrDisabled
+}
+
+// GetPinnedTraces implements Store.
+func (disabledStore) GetPinnedTraces(context.Context) ([]*PinnedTrace, error) {
+	return nil, ErrDisabled
+}
+
+// GetPinnedTrace implements Store.
+func (disabledStore) GetPinnedTrace(context.Context, string) (*PinnedTrace, error) {
+	return nil, ErrDisabled
+}
+
+// PinTrace implements Store.
+func (disabledStore) PinTrace(context.Context, *PinnedTrace) error {
+	return ErrDisabled
+}
+
+// UnpinTrace implements Store.
+func (disabledStore) UnpinTrace(context.Context, string) error {
+	return ErrDisabled
+}
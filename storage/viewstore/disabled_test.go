@@ -0,0 +1,39 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package viewstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisabledStore(t *testing.T) {
+	s := NewDisabledStore()
+	ctx := context.Background()
+	_, err := s.GetSearches(ctx)
+	assert.Equal(t, ErrDisabled, err)
+	_, err = s.GetSearch(ctx, "id")
+	assert.Equal(t, ErrDisabled, err)
+	assert.Equal(t, ErrDisabled, s.SaveSearch(ctx, &SavedSearch{}))
+	assert.Equal(t, ErrDisabled, s.DeleteSearch(ctx, "id"))
+	_, err = s.GetPinnedTraces(ctx)
+	assert.Equal(t, ErrDisabled, err)
+	_, err = s.GetPinnedTrace(ctx, "1")
+	assert.Equal(t, ErrDisabled, err)
+	assert.Equal(t, ErrDisabled, s.PinTrace(ctx, &PinnedTrace{}))
+	assert.Equal(t, ErrDisabled, s.UnpinTrace(ctx, "1"))
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2020 The Jaeger Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package viewstore
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrDisabled is returned by the Store when no view storage is configured.
+	ErrDisabled = errors.New("saved searches and pinned traces are currently disabled")
+
+	// ErrNotFound is returned by the Store when the saved search or the pinned trace does not exist.
+	ErrNotFound = errors.New("view not found")
+)
+
+// Store persists the views shared by the users of the UI and by automation.
+type Store interface {
+	// GetSearches returns all the saved searches, sorted by name.
+	GetSearches(ctx context.Context) ([]*SavedSearch, error)
+	// GetSearch returns the saved search with the given ID, or ErrNotFound.
+	GetSearch(ctx context.Context, id string) (*SavedSearch, error)
+	// SaveSearch creates the saved search, or replaces the one with the same ID.
+	SaveSearch(ctx context.Context, search *SavedSearch) error
+	// DeleteSearch deletes the saved search with the given ID, or returns ErrNotFound.
+	DeleteSearch(ctx context.Context, id string) error
+
+	// GetPinnedTraces returns all the pinned traces, the most recently pinned first.
+	GetPinnedTraces(ctx context.Context) ([]*PinnedTrace, error)
+	// GetPinnedTrace returns the pin of the given trace, or ErrNotFound.
+	GetPinnedTrace(ctx context.Context, traceID string) (*PinnedTrace, error)
+	// PinTrace creates the pin, or replaces the one of the same trace.
+	PinTrace(ctx context.Context, pin *PinnedTrace) error
+	// UnpinTrace deletes the pin of the given trace, or returns ErrNotFound.
+	UnpinTrace(ctx context.Context, traceID string) error
+}
+
+// SavedSearch is a named search of traces.
+type SavedSearch struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Query holds the parameters of the search, as accepted by the /api/traces endpoint
+	Query map[string][]string `json:"query"`
+	// Owner is the subject of the user who created the search, if known
+	Owner     string    `json:"owner,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// PinnedTrace is a trace kept at hand by the team, with a note telling why.
+type PinnedTrace struct {
+	// TraceID is the trace ID in hexadecimal
+	TraceID  string    `json:"traceID"`
+	Note     string    `json:"note,omitempty"`
+	Owner    string    `json:"owner,omitempty"`
+	PinnedAt time.Time `json:"pinnedAt"`
+}